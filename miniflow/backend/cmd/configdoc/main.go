@@ -0,0 +1,35 @@
+// Command configdoc generates the configuration and environment variable
+// reference from the settings table in pkg/config.
+//
+// Usage:
+//
+//	go run ./cmd/configdoc -o ../docs/Configuration-Reference.md
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"os"
+
+	"miniflow/pkg/config"
+)
+
+func main() {
+	output := flag.String("o", "", "output file (default stdout)")
+	flag.Parse()
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Fatalf("Failed to create %s: %v", *output, err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := config.WriteEnvDocs(w); err != nil {
+		log.Fatalf("Failed to write configuration reference: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+// Command doctor runs the deployment self-test against the configured
+// database and services and prints a pass/fail report. It exits with status 1
+// when a check fails, so it can gate deployment pipelines.
+//
+// Usage:
+//
+//	go run ./cmd/doctor -config ./config
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"miniflow/internal/repository"
+	"miniflow/internal/service"
+	"miniflow/pkg/config"
+	"miniflow/pkg/database"
+	"miniflow/pkg/logger"
+)
+
+func main() {
+	configPath := flag.String("config", "./config", "path to the config directory")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	appLogger, err := logger.NewLogger(cfg.Log.Level, cfg.Log.Format, cfg.Log.Output)
+	if err != nil {
+		log.Fatalf("Failed to create logger: %v", err)
+	}
+
+	db, err := database.NewDatabase(&cfg.Database, appLogger)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	selfTest := service.NewSelfTestService(
+		db,
+		cfg,
+		repository.NewProcessRepository(db, appLogger),
+		repository.NewConnectorPolicyRepository(db, appLogger),
+		repository.NewProcessInstanceRepository(db, appLogger),
+		appLogger,
+	)
+	report := selfTest.Run(context.Background())
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Fatalf("Failed to encode report: %v", err)
+		}
+	} else {
+		for _, check := range report.Checks {
+			fmt.Printf("[%s] %-22s %s\n", strings.ToUpper(check.Status), check.Name, check.Message)
+			for _, detail := range check.Details {
+				fmt.Printf("       %-22s - %s\n", "", detail)
+			}
+		}
+		fmt.Printf("\nSelf-test %s\n", report.Status)
+	}
+
+	if !report.Passed() {
+		os.Exit(1)
+	}
+}
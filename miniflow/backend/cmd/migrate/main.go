@@ -0,0 +1,84 @@
+// Command migrate applies, rolls back and lists the versioned database
+// migrations compiled into the binary.
+//
+// Usage:
+//
+//	go run ./cmd/migrate -config ./config up
+//	go run ./cmd/migrate -config ./config -steps 1 down
+//	go run ./cmd/migrate -config ./config status
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"miniflow/internal/migration"
+	"miniflow/pkg/config"
+	"miniflow/pkg/database"
+	"miniflow/pkg/logger"
+)
+
+func main() {
+	configPath := flag.String("config", "./config", "path to the config directory")
+	steps := flag.Int("steps", 1, "number of migrations to roll back with down")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] up|down|status\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	command := flag.Arg(0)
+	if flag.NArg() != 1 || (command != "up" && command != "down" && command != "status") {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *steps < 1 {
+		log.Fatalf("-steps must be at least 1")
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	appLogger, err := logger.NewLogger(cfg.Log.Level, cfg.Log.Format, cfg.Log.Output)
+	if err != nil {
+		log.Fatalf("Failed to create logger: %v", err)
+	}
+
+	db, err := database.NewDatabase(&cfg.Database, appLogger)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	migrator := migration.New(db.DB, appLogger)
+	switch command {
+	case "up":
+		count, err := migrator.Up()
+		if err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+		fmt.Printf("Applied %d migrations\n", count)
+	case "down":
+		count, err := migrator.Down(*steps)
+		if err != nil {
+			log.Fatalf("Failed to roll back migrations: %v", err)
+		}
+		fmt.Printf("Rolled back %d migrations\n", count)
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.Fatalf("Failed to read migration status: %v", err)
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.Applied {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%s  %-19s  %s\n", status.ID, appliedAt, status.Description)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Command seed creates demo users, published sample processes and running
+// instances so evaluators can explore MiniFlow without manual setup.
+//
+// Usage:
+//
+//	go run ./cmd/seed -config ./config
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+
+	"miniflow/internal/engine"
+	"miniflow/internal/migration"
+	"miniflow/internal/repository"
+	"miniflow/internal/seed"
+	"miniflow/internal/service"
+	"miniflow/pkg/config"
+	"miniflow/pkg/database"
+	"miniflow/pkg/logger"
+	"miniflow/pkg/utils"
+)
+
+func main() {
+	configPath := flag.String("config", "./config", "path to the config directory")
+	migrate := flag.Bool("migrate", true, "run database migrations before seeding")
+	flag.Parse()
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	appLogger, err := logger.NewLogger(cfg.Log.Level, cfg.Log.Format, cfg.Log.Output)
+	if err != nil {
+		log.Fatalf("Failed to create logger: %v", err)
+	}
+
+	db, err := database.NewDatabase(&cfg.Database, appLogger)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	if *migrate {
+		if _, err := migration.New(db.DB, appLogger).Up(); err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+	}
+
+	userRepo := repository.NewUserRepository(db, appLogger)
+	processRepo := repository.NewProcessRepository(db, appLogger)
+	policyRepo := repository.NewConnectorPolicyRepository(db, appLogger)
+	budgetRepo := repository.NewComplexityBudgetRepository(db, appLogger)
+	instanceRepo := repository.NewProcessInstanceRepository(db, appLogger)
+	processEngine := engine.NewProcessEngine(
+		instanceRepo,
+		repository.NewTaskRepository(db, appLogger),
+		processRepo,
+		userRepo,
+		policyRepo,
+		repository.NewIncidentRepository(db, appLogger),
+		repository.NewDuplicateRepository(db, appLogger),
+		repository.NewExecutionLogRepository(db, appLogger),
+		&cfg.Connector,
+		&cfg.Script,
+		&cfg.RBAC,
+		db,
+		engine.NewVariableStore(&cfg.Variables, &cfg.Redis, instanceRepo, appLogger),
+		engine.NewEventSystem(appLogger),
+		nil,
+		appLogger,
+	)
+
+	seeder := seed.NewSeeder(
+		service.NewUserService(userRepo, repository.NewTokenRepository(db, appLogger), utils.NewJWTManager(&cfg.JWT), appLogger),
+		userRepo,
+		service.NewProcessService(processRepo, userRepo, policyRepo, budgetRepo, appLogger),
+		processEngine,
+		appLogger,
+	)
+
+	summary, err := seeder.Run(context.Background())
+	if errors.Is(err, seed.ErrAlreadySeeded) {
+		fmt.Println("Demo data already exists, nothing to do.")
+		return
+	}
+	if err != nil {
+		log.Fatalf("Failed to seed demo data: %v", err)
+	}
+
+	printSummary(summary)
+}
+
+// printSummary prints the demo accounts and what was created
+func printSummary(summary *seed.Summary) {
+	fmt.Println("Demo data created.")
+	fmt.Println()
+	fmt.Printf("Accounts (password: %s):\n", seed.DemoPassword)
+	for _, user := range summary.Users {
+		fmt.Printf("  %-14s %-8s %s\n", user.Username, user.Role, user.DisplayName)
+	}
+
+	fmt.Println()
+	fmt.Println("Published processes:")
+	for _, key := range summary.Processes {
+		fmt.Printf("  %s\n", key)
+	}
+
+	statuses := make([]string, 0, len(summary.Instances))
+	for status := range summary.Instances {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+
+	fmt.Println()
+	fmt.Println("Instances:")
+	for _, status := range statuses {
+		fmt.Printf("  %-10s %d\n", status, summary.Instances[status])
+	}
+}
@@ -0,0 +1,74 @@
+// Command server runs the miniflow API server with its background workers.
+// It stops gracefully on SIGINT or SIGTERM.
+//
+// Usage:
+//
+//	go run ./cmd/server -config ./config
+//	go run ./cmd/server -config ./config -health-check
+//
+// With -health-check it only asks the running server on the configured port
+// for its health and exits with status 1 when it is unhealthy, for container
+// health checks in images without a shell.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"miniflow/internal/wire"
+	"miniflow/pkg/config"
+)
+
+// healthCheckTimeout bounds the -health-check request
+const healthCheckTimeout = 3 * time.Second
+
+func main() {
+	configPath := flag.String("config", "./config", "path to the config directory")
+	healthCheck := flag.Bool("health-check", false, "check the health of the running server and exit")
+	flag.Parse()
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	if *healthCheck {
+		if err := checkHealth(cfg.Server.Port); err != nil {
+			fmt.Fprintf(os.Stderr, "Unhealthy: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	srv, err := wire.InitializeServer(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize server: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := srv.Run(ctx); err != nil {
+		log.Fatalf("Server failed: %v", err)
+	}
+}
+
+// checkHealth requests the health endpoint of the server listening on port
+func checkHealth(port int) error {
+	client := &http.Client{Timeout: healthCheckTimeout}
+	resp, err := client.Get(fmt.Sprintf("http://127.0.0.1:%d/health", port))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("health endpoint returned %s", resp.Status)
+	}
+	return nil
+}
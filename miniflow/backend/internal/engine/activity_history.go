@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"miniflow/internal/model"
+	"miniflow/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+// recordActivity 追加活动历史，写入失败只记录日志，不影响流程推进
+// actorID 为 0 表示由系统触发
+func (e *ProcessEngine) recordActivity(ctx context.Context, activity *model.ActivityHistory, actorID uint, detail map[string]interface{}) {
+	if actorID != 0 {
+		activity.ActorID = &actorID
+	}
+	if len(detail) > 0 {
+		data, err := json.Marshal(detail)
+		if err != nil {
+			e.logger.Warn("Failed to encode activity detail", zap.String("type", activity.Type), zap.Error(err))
+		} else {
+			activity.Detail = string(data)
+		}
+	}
+	if err := e.instanceRepo.CreateActivity(ctx, activity); err != nil {
+		e.logger.Warn("Failed to record activity history",
+			zap.Uint("instance_id", activity.InstanceID),
+			zap.String("type", activity.Type),
+			zap.Error(err),
+		)
+	}
+}
+
+// recordNodeActivity 记录节点的进入、离开和网关决策
+func (e *ProcessEngine) recordNodeActivity(ctx context.Context, instance *model.ProcessInstance, node *model.ProcessNode, activityType string, detail map[string]interface{}) {
+	e.recordActivity(ctx, &model.ActivityHistory{
+		InstanceID: instance.ID,
+		Type:       activityType,
+		NodeID:     node.ID,
+		NodeType:   node.Type,
+	}, 0, detail)
+}
+
+// recordStateTransition 记录实例状态转换
+func (e *ProcessEngine) recordStateTransition(ctx context.Context, instance *model.ProcessInstance, from, to string, actorID uint, reason string) {
+	detail := map[string]interface{}{"from": from, "to": to}
+	if reason != "" {
+		detail["reason"] = reason
+	}
+	e.recordActivity(ctx, &model.ActivityHistory{
+		InstanceID: instance.ID,
+		Type:       model.ActivityStateTransition,
+		NodeID:     instance.CurrentNode,
+	}, actorID, detail)
+}
+
+// recordVariableChanges 记录流程变量的新增、修改和删除，每个变量一条
+func (e *ProcessEngine) recordVariableChanges(ctx context.Context, instance *model.ProcessInstance, previous, changed map[string]interface{}, removed []string) {
+	for key, value := range changed {
+		detail := map[string]interface{}{"name": key, "value": value}
+		if old, ok := previous[key]; ok {
+			detail["previous"] = old
+		}
+		e.recordActivity(ctx, &model.ActivityHistory{
+			InstanceID: instance.ID,
+			Type:       model.ActivityVariableChanged,
+		}, 0, detail)
+	}
+	for _, key := range removed {
+		e.recordActivity(ctx, &model.ActivityHistory{
+			InstanceID: instance.ID,
+			Type:       model.ActivityVariableChanged,
+		}, 0, map[string]interface{}{"name": key, "previous": previous[key], "removed": true})
+	}
+}
+
+// GetActivityHistory 获取流程实例的活动历史
+func (e *ProcessEngine) GetActivityHistory(ctx context.Context, instanceID uint, query *repository.ActivityHistoryQuery) ([]model.ActivityHistory, error) {
+	activities, err := e.instanceRepo.GetActivityHistory(ctx, instanceID, query)
+	if err != nil {
+		return nil, fmt.Errorf("获取活动历史失败: %w", err)
+	}
+	return activities, nil
+}
+
+// EachActivity 逐条读取流程实例的活动历史，用于流式输出
+func (e *ProcessEngine) EachActivity(ctx context.Context, instanceID uint, query *repository.ActivityHistoryQuery, fn func(*model.ActivityHistory) error) error {
+	return e.instanceRepo.EachActivity(ctx, instanceID, query, fn)
+}
@@ -0,0 +1,223 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/expression"
+
+	"go.uber.org/zap"
+)
+
+// assignByExpression 任务创建时按节点的处理人表达式分配任务
+//
+// 表达式可以是固定的处理人（如 role:manager、user:12、group:finance、dept_manager:sales），也可以包含
+// ${...} 表达式，在流程变量和组织数据（starter、instance）上求值，如 dept_manager:${starter.department}。求值或解析失败时任务留在任务池，
+// 同时生成异常事件，管理员可以修正数据后重试。
+func (e *ProcessEngine) assignByExpression(ctx context.Context, instance *model.ProcessInstance, node *model.ProcessNode, task *model.TaskInstance) error {
+	source := model.GetAssigneeExpression(node)
+	if source == "" {
+		return nil
+	}
+
+	assigneeID, err := e.evaluateAssignee(ctx, instance, source)
+	if err != nil {
+		e.logger.Warn("Assignee expression failed",
+			zap.Uint("task_id", task.ID),
+			zap.String("node_id", node.ID),
+			zap.String("expression", source),
+			zap.Error(err),
+		)
+		return e.raiseIncident(ctx, instance, task, node, model.IncidentTypeAssignmentFailed,
+			fmt.Errorf("处理人表达式 %s 求值失败: %v", source, err))
+	}
+
+	task.AssigneeID = &assigneeID
+	task.Status = model.TaskStatusAssigned
+	if err := e.taskRepo.Update(ctx, task); err != nil {
+		return fmt.Errorf("更新任务分配失败: %v", err)
+	}
+
+	e.logger.Info("Task assigned by expression",
+		zap.Uint("task_id", task.ID),
+		zap.String("expression", source),
+		zap.Uint("assignee_id", assigneeID),
+	)
+	e.events.Publish(taskAssignedEvent(task))
+	return nil
+}
+
+// evaluateAssignee 求值处理人表达式并解析为用户ID
+func (e *ProcessEngine) evaluateAssignee(ctx context.Context, instance *model.ProcessInstance, source string) (uint, error) {
+	var value interface{} = source
+	if expression.IsTemplate(source) {
+		tmpl, err := expression.ParseTemplate(source)
+		if err != nil {
+			return 0, err
+		}
+		context, err := e.expressionContext(ctx, instance)
+		if err != nil {
+			return 0, err
+		}
+		if value, err = tmpl.Render(context); err != nil {
+			return 0, err
+		}
+	}
+
+	spec, err := model.ParseAssigneeSpec(value)
+	if err != nil {
+		return 0, err
+	}
+	return e.resolveAssigneeSpec(ctx, spec)
+}
+
+// expressionContext 构建表达式的求值上下文：流程变量加上引擎提供的组织数据，
+// 组织数据优先，避免通过流程变量伪造发起人信息
+func (e *ProcessEngine) expressionContext(ctx context.Context, instance *model.ProcessInstance) (map[string]interface{}, error) {
+	context, err := decodeInstanceVariables(instance)
+	if err != nil {
+		return nil, err
+	}
+
+	starter, err := e.userRepo.GetByID(ctx, instance.StarterID)
+	if err != nil {
+		return nil, fmt.Errorf("获取流程发起人失败: %w", err)
+	}
+	department, err := e.userDepartmentCode(ctx, starter)
+	if err != nil {
+		return nil, err
+	}
+	context["starter"] = map[string]interface{}{
+		"id":           starter.ID,
+		"username":     starter.Username,
+		"display_name": starter.DisplayName,
+		"email":        starter.Email,
+		"role":         starter.Role,
+		"department":   department,
+	}
+	context["instance"] = map[string]interface{}{
+		"id":            instance.ID,
+		"business_key":  instance.BusinessKey,
+		"definition_id": instance.DefinitionID,
+	}
+	return context, nil
+}
+
+// userDepartmentCode 获取用户所属部门的编码，未归属部门时为空
+func (e *ProcessEngine) userDepartmentCode(ctx context.Context, user *model.User) (string, error) {
+	if user.DepartmentID == nil {
+		return "", nil
+	}
+	department, err := e.userRepo.GetDepartment(ctx, *user.DepartmentID)
+	if err != nil {
+		return "", fmt.Errorf("获取用户部门失败: %w", err)
+	}
+	if department == nil {
+		return "", nil
+	}
+	return department.Code, nil
+}
+
+// resolveAssigneeSpec 将处理人规格解析为一个可用的活跃用户，按角色或用户组分配时选择当前待办最少的用户
+func (e *ProcessEngine) resolveAssigneeSpec(ctx context.Context, spec *model.AssigneeSpec) (uint, error) {
+	switch {
+	case spec.UserID != 0:
+		user, err := e.userRepo.GetByID(ctx, spec.UserID)
+		if err != nil {
+			return 0, fmt.Errorf("用户 %d 不存在", spec.UserID)
+		}
+		if user.Status != "active" {
+			return 0, fmt.Errorf("用户 %d 未激活", spec.UserID)
+		}
+		return user.ID, nil
+
+	case spec.Username != "":
+		user, err := e.userRepo.GetByUsername(ctx, spec.Username)
+		if err != nil {
+			return 0, fmt.Errorf("用户 %s 不存在", spec.Username)
+		}
+		if user.Status != "active" {
+			return 0, fmt.Errorf("用户 %s 未激活", spec.Username)
+		}
+		return user.ID, nil
+
+	case spec.Role != "":
+		return e.selectRoleUser(ctx, spec.Role)
+
+	case spec.Group != "":
+		users, err := e.userRepo.GetUsersByGroup(ctx, spec.Group)
+		if err != nil {
+			return 0, fmt.Errorf("获取用户组成员失败: %w", err)
+		}
+		return e.selectLeastLoaded(ctx, users, fmt.Sprintf("用户组 %s", spec.Group))
+
+	case spec.DepartmentManager != "":
+		manager, err := e.userRepo.GetDepartmentManager(ctx, spec.DepartmentManager)
+		if err != nil {
+			return 0, err
+		}
+		if manager.Status != "active" {
+			return 0, fmt.Errorf("部门 %s 的负责人 %s 未激活", spec.DepartmentManager, manager.Username)
+		}
+		return manager.ID, nil
+	}
+
+	return 0, errors.New("处理人规格为空")
+}
+
+// selectRoleUser 选择角色中待办任务最少的活跃用户
+func (e *ProcessEngine) selectRoleUser(ctx context.Context, role string) (uint, error) {
+	users, err := e.userRepo.GetUsersByRole(ctx, role)
+	if err != nil {
+		return 0, fmt.Errorf("获取角色用户失败: %w", err)
+	}
+	return e.selectLeastLoaded(ctx, users, fmt.Sprintf("角色 %s", role))
+}
+
+// selectLeastLoaded 选择用户中待办任务最少的活跃用户，scope 用于错误信息
+func (e *ProcessEngine) selectLeastLoaded(ctx context.Context, users []model.User, scope string) (uint, error) {
+	var selected uint
+	minLoad := -1
+	for _, user := range users {
+		if user.Status != "active" {
+			continue
+		}
+		load, err := e.taskRepo.CountUserActiveTasks(ctx, user.ID)
+		if err != nil {
+			return 0, err
+		}
+		if minLoad < 0 || load < minLoad {
+			selected, minLoad = user.ID, load
+		}
+	}
+	if selected == 0 {
+		return 0, fmt.Errorf("%s 没有可用的用户", scope)
+	}
+	return selected, nil
+}
+
+// retryAssignment 重新按处理人表达式分配仍未分配的任务，并关闭异常事件
+func (e *ProcessEngine) retryAssignment(ctx context.Context, incident *model.Incident, instance *model.ProcessInstance, node *model.ProcessNode, userID uint) (*model.Incident, error) {
+	if node == nil || incident.TaskID == nil {
+		return nil, newEngineError(CodeNotFound, nil, "异常事件对应的任务节点不存在")
+	}
+
+	task, err := e.taskRepo.GetByID(ctx, *incident.TaskID)
+	if err != nil {
+		return nil, fmt.Errorf("获取任务失败: %w", err)
+	}
+	if task.AssigneeID != nil || task.Status != model.TaskStatusCreated {
+		// 任务已被人工处理，直接关闭异常事件
+		return incident, e.markIncidentResolved(ctx, incident, userID)
+	}
+
+	if err := e.markIncidentResolved(ctx, incident, userID); err != nil {
+		return nil, err
+	}
+	if err := e.assignByExpression(ctx, instance, node, task); err != nil {
+		return nil, fmt.Errorf("重新分配任务失败: %v", err)
+	}
+	return incident, nil
+}
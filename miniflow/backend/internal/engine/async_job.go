@@ -0,0 +1,205 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/config"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// asyncJobSuspendedDelay 暂停实例上的作业推迟执行的时长，恢复后再执行
+const asyncJobSuspendedDelay = time.Minute
+
+// enqueueAsyncJob 为异步服务任务创建立即到期的作业，由作业执行器在请求之外执行
+func (e *ProcessEngine) enqueueAsyncJob(ctx context.Context, instance *model.ProcessInstance, task *model.TaskInstance, node *model.ProcessNode) error {
+	job := &model.AsyncJob{
+		InstanceID: instance.ID,
+		NodeID:     node.ID,
+		TaskID:     task.ID,
+		Status:     model.AsyncJobStatusPending,
+		DueAt:      time.Now(),
+	}
+	if err := e.instanceRepo.CreateAsyncJob(ctx, job); err != nil {
+		return fmt.Errorf("创建异步作业失败: %v", err)
+	}
+	e.traceFor(instance).record(ctx, model.TraceCategoryWrite, node.ID, map[string]interface{}{
+		"job_id":  job.ID,
+		"task_id": task.ID,
+	}, "创建异步作业 %d", job.ID)
+
+	e.logger.Info("Async job enqueued",
+		zap.Uint("job_id", job.ID),
+		zap.Uint("instance_id", instance.ID),
+		zap.String("node_id", node.ID),
+	)
+	return nil
+}
+
+// executeAsyncJob 执行已锁定的作业并记录结果
+//
+// 服务任务失败时和同步执行一样生成异常事件，作业仍视为已完成；流程推进本身出错时作业标记为失败，
+// 可在作业面板中重试。暂停实例上的作业推迟执行，已结束实例或已关闭任务上的作业直接取消。
+func (e *ProcessEngine) executeAsyncJob(ctx context.Context, job *model.AsyncJob, owner string) {
+	status, err := e.runAsyncJob(ctx, job, owner)
+	if status == "" {
+		return
+	}
+
+	reason := ""
+	if err != nil {
+		reason = err.Error()
+		e.logger.Error("Async job failed",
+			zap.Uint("job_id", job.ID),
+			zap.Uint("instance_id", job.InstanceID),
+			zap.String("node_id", job.NodeID),
+			zap.Error(err),
+		)
+	}
+	if err := e.instanceRepo.FinishAsyncJob(ctx, job.ID, owner, status, time.Now(), reason); err != nil {
+		e.logger.Error("Failed to record async job result", zap.Uint("job_id", job.ID), zap.Error(err))
+		return
+	}
+
+	e.logger.Info("Async job finished",
+		zap.Uint("job_id", job.ID),
+		zap.Uint("instance_id", job.InstanceID),
+		zap.String("status", status),
+	)
+}
+
+// runAsyncJob 执行作业对应的服务任务，返回作业的结束状态；推迟执行时返回空状态
+func (e *ProcessEngine) runAsyncJob(ctx context.Context, job *model.AsyncJob, owner string) (string, error) {
+	instance, err := e.instanceRepo.GetByID(ctx, job.InstanceID)
+	if err != nil {
+		return model.AsyncJobStatusFailed, fmt.Errorf("获取流程实例失败: %w", err)
+	}
+
+	switch instance.Status {
+	case model.InstanceStatusRunning:
+	case model.InstanceStatusSuspended:
+		if err := e.instanceRepo.ReleaseAsyncJob(ctx, job.ID, owner, time.Now().Add(asyncJobSuspendedDelay)); err != nil {
+			e.logger.Error("Failed to postpone async job", zap.Uint("job_id", job.ID), zap.Error(err))
+		}
+		return "", nil
+	default:
+		return model.AsyncJobStatusCancelled, nil
+	}
+
+	task, err := e.taskRepo.GetByID(ctx, job.TaskID)
+	if err != nil {
+		return model.AsyncJobStatusFailed, fmt.Errorf("获取服务任务失败: %w", err)
+	}
+	if task.Status != model.TaskStatusCreated {
+		return model.AsyncJobStatusCancelled, nil
+	}
+
+	definition, err := instance.Definition.GetDefinitionData()
+	if err != nil {
+		return model.AsyncJobStatusFailed, newEngineError(CodeInvalidDefinition, err, "解析流程定义失败")
+	}
+	node := e.findNodeByID(definition.Nodes, job.NodeID)
+	if node == nil {
+		return model.AsyncJobStatusFailed, newEngineError(CodeNodeNotFound, nil, "找不到节点: %s", job.NodeID)
+	}
+
+	if err := e.runServiceTask(ctx, instance, task, node); err != nil {
+		return model.AsyncJobStatusFailed, err
+	}
+	return model.AsyncJobStatusCompleted, nil
+}
+
+// JobExecutor 后台作业执行器，用固定数量的工作协程执行到期的异步作业
+//
+// 执行器每轮最多锁定与工作协程数相同的作业，锁定后交给空闲的工作协程执行。多个服务实例
+// 各自运行执行器时按执行器标识区分锁的持有者。
+type JobExecutor struct {
+	engine *ProcessEngine
+	cfg    *config.JobExecutorConfig
+	owner  string
+	logger *logger.Logger
+}
+
+// NewJobExecutor 创建后台作业执行器
+func NewJobExecutor(engine *ProcessEngine, cfg *config.JobExecutorConfig, logger *logger.Logger) *JobExecutor {
+	host, _ := os.Hostname()
+	if host == "" {
+		host = "miniflow"
+	}
+	return &JobExecutor{
+		engine: engine,
+		cfg:    cfg,
+		owner:  fmt.Sprintf("%s-%d", host, os.Getpid()),
+		logger: logger,
+	}
+}
+
+// Start 运行作业执行器直到 ctx 取消，返回前等待正在执行的作业结束
+func (x *JobExecutor) Start(ctx context.Context) {
+	jobs := make(chan *model.AsyncJob)
+	var wg sync.WaitGroup
+	for i := 0; i < x.cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// 已领取的作业执行完成后才退出，不跟随 ctx 的取消
+			jobCtx := context.WithoutCancel(ctx)
+			for job := range jobs {
+				x.engine.executeAsyncJob(jobCtx, job, x.owner)
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(x.cfg.GetPollInterval())
+	defer func() {
+		ticker.Stop()
+		close(jobs)
+		wg.Wait()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			x.poll(ctx, now, jobs)
+		}
+	}
+}
+
+// poll 锁定到期的作业并逐个交给空闲的工作协程
+func (x *JobExecutor) poll(ctx context.Context, now time.Time, jobs chan<- *model.AsyncJob) {
+	due, err := x.engine.instanceRepo.GetDueAsyncJobs(ctx, now, x.cfg.Workers)
+	if err != nil {
+		x.logger.Error("Failed to get due async jobs", zap.Error(err))
+		return
+	}
+
+	for i := range due {
+		job := &due[i]
+		// 条件更新保证多个执行器同时运行时每个作业只执行一次
+		locked, err := x.engine.instanceRepo.LockAsyncJob(ctx, job.ID, x.owner, now, now.Add(x.cfg.GetLockTimeout()))
+		if err != nil {
+			return
+		}
+		if !locked {
+			continue
+		}
+
+		select {
+		case jobs <- job:
+		case <-ctx.Done():
+			// 停止时把已锁定但未执行的作业交还，其他执行器可以立即执行
+			if err := x.engine.instanceRepo.ReleaseAsyncJob(ctx, job.ID, x.owner, now); err != nil {
+				x.logger.Error("Failed to release async job", zap.Uint("job_id", job.ID), zap.Error(err))
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"miniflow/internal/model"
+	"miniflow/internal/repository"
+	"miniflow/pkg/config"
+	"miniflow/pkg/logger"
+	"miniflow/pkg/storage"
+
+	"go.uber.org/zap"
+)
+
+// 附件的限制
+const (
+	attachmentMaxFileName = 255
+	// attachmentSniffSize 识别文件类型读取的字节数
+	attachmentSniffSize = 512
+	// attachmentCleanupBatch 每次清理孤立附件的数量
+	attachmentCleanupBatch = 100
+)
+
+// 附件的错误
+var (
+	ErrAttachmentNotFound = &EngineError{Code: CodeNotFound, Message: "附件不存在"}
+	ErrAttachmentEmpty    = &EngineError{Code: CodeInvalidRequest, Message: "附件内容为空"}
+)
+
+// AttachmentUpload 上传的附件文件
+type AttachmentUpload struct {
+	FileName string
+	Size     int64
+	Content  io.Reader
+}
+
+// AttachmentManager 管理上传到流程实例和任务的附件
+//
+// 元数据保存在数据库中，文件内容保存在配置的附件存储中（本地目录或兼容 S3 的对象存储）。
+// 上传时按文件内容识别类型并校验大小；实例被清除后，其附件在收到清除事件时从存储中删除。
+type AttachmentManager struct {
+	engine         *ProcessEngine
+	attachmentRepo *repository.AttachmentRepository
+	storage        storage.Storage
+	cfg            *config.AttachmentConfig
+	logger         *logger.Logger
+}
+
+// NewAttachmentManager 创建附件管理器
+func NewAttachmentManager(engine *ProcessEngine, attachmentRepo *repository.AttachmentRepository, cfg *config.AttachmentConfig, logger *logger.Logger) (*AttachmentManager, error) {
+	store, err := storage.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &AttachmentManager{
+		engine:         engine,
+		attachmentRepo: attachmentRepo,
+		storage:        store,
+		cfg:            cfg,
+		logger:         logger,
+	}, nil
+}
+
+// UploadInstanceAttachment 上传流程实例的附件
+func (m *AttachmentManager) UploadInstanceAttachment(ctx context.Context, instanceID, userID uint, upload *AttachmentUpload) (*model.Attachment, error) {
+	if _, err := m.engine.instanceRepo.GetByID(ctx, instanceID); err != nil {
+		return nil, err
+	}
+	return m.upload(ctx, instanceID, nil, userID, upload)
+}
+
+// UploadTaskAttachment 上传任务的附件，附件同时属于任务所在的流程实例
+func (m *AttachmentManager) UploadTaskAttachment(ctx context.Context, taskID, userID uint, upload *AttachmentUpload) (*model.Attachment, error) {
+	task, err := m.engine.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	return m.upload(ctx, task.InstanceID, &task.ID, userID, upload)
+}
+
+// upload 校验附件后写入存储并保存元数据，元数据保存失败时删除已写入的文件
+func (m *AttachmentManager) upload(ctx context.Context, instanceID uint, taskID *uint, userID uint, upload *AttachmentUpload) (*model.Attachment, error) {
+	fileName, err := attachmentFileName(upload.FileName)
+	if err != nil {
+		return nil, err
+	}
+	if upload.Size <= 0 {
+		return nil, ErrAttachmentEmpty
+	}
+	if upload.Size > m.cfg.GetMaxSize() {
+		return nil, newEngineError(CodeInvalidRequest, nil, "附件大小超过上限 %d MB", m.cfg.MaxSizeMB)
+	}
+
+	// 按文件开头的内容识别类型，不信任客户端声明的类型
+	head := make([]byte, attachmentSniffSize)
+	n, err := io.ReadFull(upload.Content, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("读取附件失败: %w", err)
+	}
+	head = head[:n]
+	contentType := http.DetectContentType(head)
+	if !m.allowedType(contentType) {
+		return nil, newEngineError(CodeInvalidRequest, nil, "不允许上传 %s 类型的附件", contentType)
+	}
+
+	key, err := attachmentKey(instanceID)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.New()
+	content := io.TeeReader(io.MultiReader(bytes.NewReader(head), upload.Content), hash)
+	if err := m.storage.Put(ctx, key, content, upload.Size, contentType); err != nil {
+		return nil, fmt.Errorf("保存附件失败: %w", err)
+	}
+
+	attachment := &model.Attachment{
+		InstanceID:  instanceID,
+		TaskID:      taskID,
+		FileName:    fileName,
+		ContentType: contentType,
+		Size:        upload.Size,
+		Checksum:    hex.EncodeToString(hash.Sum(nil)),
+		StorageKey:  key,
+		UploadedBy:  userID,
+	}
+	if err := m.attachmentRepo.Create(ctx, attachment); err != nil {
+		m.deleteObject(context.WithoutCancel(ctx), key)
+		return nil, fmt.Errorf("保存附件失败: %w", err)
+	}
+
+	m.logger.Info("Attachment uploaded",
+		zap.Uint("attachment_id", attachment.ID),
+		zap.Uint("instance_id", instanceID),
+		zap.String("content_type", contentType),
+		zap.Int64("size", upload.Size),
+	)
+	return attachment, nil
+}
+
+// GetInstanceAttachments 获取流程实例的附件，包括实例下各任务的附件
+func (m *AttachmentManager) GetInstanceAttachments(ctx context.Context, instanceID uint) ([]model.Attachment, error) {
+	if _, err := m.engine.instanceRepo.GetByID(ctx, instanceID); err != nil {
+		return nil, err
+	}
+	return m.attachmentRepo.GetByInstance(ctx, instanceID)
+}
+
+// GetTaskAttachments 获取任务的附件
+func (m *AttachmentManager) GetTaskAttachments(ctx context.Context, taskID uint) ([]model.Attachment, error) {
+	if _, err := m.engine.taskRepo.GetByID(ctx, taskID); err != nil {
+		return nil, err
+	}
+	return m.attachmentRepo.GetByTask(ctx, taskID)
+}
+
+// OpenAttachment 获取附件元数据并打开文件内容，调用方负责关闭返回的内容
+func (m *AttachmentManager) OpenAttachment(ctx context.Context, id uint) (*model.Attachment, io.ReadCloser, error) {
+	attachment, err := m.getAttachment(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := m.storage.Open(ctx, attachment.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			m.logger.Error("Attachment content missing from storage", zap.Uint("attachment_id", id))
+			return nil, nil, ErrAttachmentNotFound
+		}
+		return nil, nil, fmt.Errorf("读取附件失败: %w", err)
+	}
+	return attachment, content, nil
+}
+
+// DeleteAttachment 删除附件，上传人和有 content_moderate 权限的用户可以删除
+func (m *AttachmentManager) DeleteAttachment(ctx context.Context, id, userID uint) error {
+	attachment, err := m.getAttachment(ctx, id)
+	if err != nil {
+		return err
+	}
+	if attachment.UploadedBy != userID {
+		if err := m.engine.checkPermission(ctx, userID, config.PermissionContentModerate, "删除他人上传的附件"); err != nil {
+			return err
+		}
+	}
+	return m.remove(ctx, attachment)
+}
+
+// Start 订阅实例清除事件并删除被清除实例的附件，直到 ctx 取消；只应调用一次
+// 启动时先清理一次所属实例已不存在的附件，补上服务停止期间错过的清除事件
+func (m *AttachmentManager) Start(ctx context.Context) {
+	m.engine.events.Subscribe(EventProcessPurged, func(event Event) {
+		if ctx.Err() != nil {
+			return
+		}
+		m.PurgeInstanceAttachments(ctx, event.InstanceID)
+	})
+
+	if _, err := m.CleanupOrphaned(ctx); err != nil {
+		m.logger.Error("Failed to clean up orphaned attachments", zap.Error(err))
+	}
+
+	<-ctx.Done()
+}
+
+// PurgeInstanceAttachments 删除流程实例的全部附件，单个附件删除失败只记录日志
+func (m *AttachmentManager) PurgeInstanceAttachments(ctx context.Context, instanceID uint) {
+	attachments, err := m.attachmentRepo.GetByInstance(ctx, instanceID)
+	if err != nil {
+		m.logger.Error("Failed to load attachments of purged instance", zap.Uint("instance_id", instanceID), zap.Error(err))
+		return
+	}
+	for i := range attachments {
+		if err := m.remove(ctx, &attachments[i]); err != nil {
+			m.logger.Error("Failed to delete attachment of purged instance",
+				zap.Uint("instance_id", instanceID),
+				zap.Uint("attachment_id", attachments[i].ID),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// CleanupOrphaned 删除所属流程实例已不存在的附件，返回删除的数量
+func (m *AttachmentManager) CleanupOrphaned(ctx context.Context) (int, error) {
+	removed := 0
+	for {
+		attachments, err := m.attachmentRepo.GetOrphaned(ctx, attachmentCleanupBatch)
+		if err != nil {
+			return removed, err
+		}
+		for i := range attachments {
+			if err := m.remove(ctx, &attachments[i]); err != nil {
+				return removed, err
+			}
+			removed++
+		}
+		if len(attachments) < attachmentCleanupBatch {
+			return removed, nil
+		}
+	}
+}
+
+// remove 先删除存储中的文件再删除元数据，文件删除失败时保留元数据以便重试
+func (m *AttachmentManager) remove(ctx context.Context, attachment *model.Attachment) error {
+	if err := m.storage.Delete(ctx, attachment.StorageKey); err != nil {
+		return fmt.Errorf("删除附件文件失败: %w", err)
+	}
+	if err := m.attachmentRepo.Delete(ctx, attachment.ID); err != nil {
+		return fmt.Errorf("删除附件失败: %w", err)
+	}
+	return nil
+}
+
+// deleteObject 删除存储中的文件，失败只记录日志
+func (m *AttachmentManager) deleteObject(ctx context.Context, key string) {
+	if err := m.storage.Delete(ctx, key); err != nil {
+		m.logger.Warn("Failed to delete attachment content", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// getAttachment 获取附件元数据
+func (m *AttachmentManager) getAttachment(ctx context.Context, id uint) (*model.Attachment, error) {
+	attachment, err := m.attachmentRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrAttachmentNotFound) {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, fmt.Errorf("获取附件失败: %w", err)
+	}
+	return attachment, nil
+}
+
+// allowedType 检查识别出的类型是否在允许的类型中，比较时忽略 charset 等参数
+func (m *AttachmentManager) allowedType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range m.cfg.AllowedTypes {
+		if strings.EqualFold(mediaType, strings.TrimSpace(allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// attachmentFileName 取上传文件名的最后一段，去掉客户端带上的路径
+func attachmentFileName(name string) (string, error) {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == "/" {
+		return "", newEngineError(CodeInvalidRequest, nil, "附件文件名不能为空")
+	}
+	if utf8.RuneCountInString(name) > attachmentMaxFileName {
+		return "", newEngineError(CodeInvalidRequest, nil, "附件文件名不能超过 %d 个字符", attachmentMaxFileName)
+	}
+	return name, nil
+}
+
+// attachmentKey 生成附件在存储中的对象键，按实例分目录，文件名使用随机值避免冲突和路径注入
+func attachmentKey(instanceID uint) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成附件键失败: %w", err)
+	}
+	return fmt.Sprintf("instances/%d/%s", instanceID, hex.EncodeToString(buf)), nil
+}
@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// applyAutoRules 任务创建时评估节点的自动处理规则，命中第一条规则时由系统完成或跳过任务并推进流程
+//
+// 返回值表示任务是否已被自动处理。条件评估出错时不会命中规则，任务按正常流程等待人工处理。
+func (e *ProcessEngine) applyAutoRules(ctx context.Context, instance *model.ProcessInstance, node *model.ProcessNode, task *model.TaskInstance) (bool, error) {
+	rules, err := model.GetAutoRules(node)
+	if err != nil {
+		e.logger.Warn("Invalid auto rules on node, ignored",
+			zap.Uint("instance_id", instance.ID),
+			zap.String("node_id", node.ID),
+			zap.Error(err),
+		)
+		return false, nil
+	}
+	if len(rules) == 0 {
+		return false, nil
+	}
+
+	variables := make(map[string]interface{})
+	if instance.Variables != "" {
+		if err := json.Unmarshal([]byte(instance.Variables), &variables); err != nil {
+			return false, fmt.Errorf("解析流程变量失败: %v", err)
+		}
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+		matched, err := e.variableEngine.EvaluateCondition(rule.Condition, variables)
+		if err != nil {
+			e.logger.Warn("Auto rule condition evaluation failed",
+				zap.Uint("task_id", task.ID),
+				zap.String("rule", rule.Reference(node.ID)),
+				zap.Error(err),
+			)
+			continue
+		}
+		if !matched {
+			continue
+		}
+
+		if err := e.autoHandleTask(ctx, instance, node, task, rule); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// autoHandleTask 按规则以系统身份完成或跳过任务，然后推进流程
+func (e *ProcessEngine) autoHandleTask(ctx context.Context, instance *model.ProcessInstance, node *model.ProcessNode, task *model.TaskInstance, rule *model.AutoRule) error {
+	now := time.Now()
+	task.CompleteTime = &now
+	task.AutoRule = rule.Reference(node.ID)
+
+	comment := rule.Comment
+	if rule.Action == model.AutoRuleActionSkip {
+		task.Status = model.TaskStatusSkipped
+		if comment == "" {
+			comment = fmt.Sprintf("系统按规则 %s 自动跳过（%s）", rule.ID, rule.Condition)
+		}
+	} else {
+		task.Status = model.TaskStatusCompleted
+		if comment == "" {
+			comment = fmt.Sprintf("系统按规则 %s 自动完成（%s）", rule.ID, rule.Condition)
+		}
+	}
+	task.Comment = comment
+
+	if err := e.taskRepo.Update(ctx, task); err != nil {
+		return fmt.Errorf("更新任务状态失败: %v", err)
+	}
+
+	e.logger.Info("User task handled by auto rule",
+		zap.Uint("instance_id", instance.ID),
+		zap.Uint("task_id", task.ID),
+		zap.String("rule", task.AutoRule),
+		zap.String("action", rule.Action),
+		zap.String("actor", model.SystemActor),
+	)
+	eventType := EventTaskCompleted
+	if task.Status == model.TaskStatusSkipped {
+		eventType = EventTaskSkipped
+	}
+	e.publishTaskEvent(eventType, task, 0, map[string]interface{}{"rule": task.AutoRule})
+
+	return e.checkAndAdvanceProcess(ctx, instance, node.ID)
+}
@@ -0,0 +1,224 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// maxCallDepth 子流程最大嵌套层数，防止流程互相调用导致无限递归
+const maxCallDepth = 10
+
+// InstanceHierarchyNode 实例层级树中的一个实例
+type InstanceHierarchyNode struct {
+	InstanceID     uint                     `json:"instance_id"`
+	DefinitionID   uint                     `json:"definition_id"`
+	DefinitionKey  string                   `json:"definition_key"`
+	DefinitionName string                   `json:"definition_name"`
+	Version        int                      `json:"version"`
+	BusinessKey    string                   `json:"business_key"`
+	Status         string                   `json:"status"`
+	ParentNodeID   string                   `json:"parent_node_id,omitempty"`
+	Current        bool                     `json:"current,omitempty"`
+	Children       []*InstanceHierarchyNode `json:"children,omitempty"`
+}
+
+// handleCallActivity 处理调用活动节点：按输入映射启动子流程实例，父实例停留在节点上等待子实例完成
+func (e *ProcessEngine) handleCallActivity(ctx context.Context, instance *model.ProcessInstance, node *model.ProcessNode) error {
+	cfg, err := model.GetCallActivityConfig(node)
+	if err != nil {
+		return newEngineError(CodeInvalidDefinition, err, "调用活动 %s 配置无效", node.ID)
+	}
+
+	var definition *model.ProcessDefinition
+	if cfg.Version > 0 {
+		definition, err = e.processRepo.GetByKeyAndVersion(ctx, cfg.ProcessKey, cfg.Version)
+		if err == nil && definition.Status != model.ProcessStatusPublished {
+			return newEngineError(CodeDefinitionNotFound, nil, "子流程 %s 版本 %d 未发布", cfg.ProcessKey, cfg.Version)
+		}
+	} else {
+		definition, err = e.processRepo.GetLatestPublishedByKey(ctx, cfg.ProcessKey)
+	}
+	if err != nil {
+		return newEngineError(CodeDefinitionNotFound, err, "获取子流程定义 %s 失败", cfg.ProcessKey)
+	}
+
+	depth, err := e.instanceDepth(ctx, instance)
+	if err != nil {
+		return fmt.Errorf("获取实例层级失败: %w", err)
+	}
+	if depth >= maxCallDepth {
+		return newEngineError(CodeInvalidDefinition, nil, "子流程嵌套超过 %d 层", maxCallDepth)
+	}
+
+	// 先记录父实例停留的节点，子流程同步完成时会立即推进父实例
+	if err := e.moveInstanceTo(ctx, instance, node.ID); err != nil {
+		return fmt.Errorf("更新流程实例当前节点失败: %v", err)
+	}
+
+	variables, err := decodeInstanceVariables(instance)
+	if err != nil {
+		return err
+	}
+
+	parentID := instance.ID
+	child, err := e.StartProcess(ctx, &StartProcessRequest{
+		DefinitionID:     definition.ID,
+		BusinessKey:      instance.BusinessKey,
+		Variables:        model.MapVariables(variables, cfg.Inputs),
+		DueDate:          instance.DueDate,
+		parentInstanceID: &parentID,
+		parentNodeID:     node.ID,
+		inheritTrace:     instance.TraceEnabled,
+	}, instance.StarterID)
+	if err != nil {
+		return fmt.Errorf("启动子流程失败: %w", err)
+	}
+
+	e.logger.Info("Call activity started child instance",
+		zap.Uint("instance_id", instance.ID),
+		zap.String("node_id", node.ID),
+		zap.Uint("child_instance_id", child.ID),
+		zap.String("child_process", definition.Key),
+		zap.Int("child_version", definition.Version),
+	)
+	return nil
+}
+
+// resumeParent 子实例完成后按输出映射回传变量并推进父实例
+// 父实例不在运行中或已离开调用节点时不推进
+func (e *ProcessEngine) resumeParent(ctx context.Context, child *model.ProcessInstance) error {
+	parent, err := e.instanceRepo.GetByID(ctx, *child.ParentInstanceID)
+	if err != nil {
+		return fmt.Errorf("获取父流程实例失败: %w", err)
+	}
+	if parent.Status != model.InstanceStatusRunning || parent.CurrentNode != child.ParentNodeID {
+		e.logger.Warn("Parent instance is not waiting for the child instance",
+			zap.Uint("instance_id", parent.ID),
+			zap.String("status", parent.Status),
+			zap.String("current_node", parent.CurrentNode),
+			zap.Uint("child_instance_id", child.ID),
+		)
+		return nil
+	}
+
+	definition, err := parent.Definition.GetDefinitionData()
+	if err != nil {
+		return newEngineError(CodeInvalidDefinition, err, "解析流程定义失败")
+	}
+	node := e.findNodeByID(definition.Nodes, child.ParentNodeID)
+	if node == nil {
+		return newEngineError(CodeNodeNotFound, nil, "找不到调用节点: %s", child.ParentNodeID)
+	}
+	cfg, err := model.GetCallActivityConfig(node)
+	if err != nil {
+		return newEngineError(CodeInvalidDefinition, err, "调用活动 %s 配置无效", node.ID)
+	}
+
+	if cfg.Outputs != nil {
+		childVariables, err := decodeInstanceVariables(child)
+		if err != nil {
+			return err
+		}
+		variables, err := decodeInstanceVariables(parent)
+		if err != nil {
+			return err
+		}
+		for name, value := range model.MapVariables(childVariables, cfg.Outputs) {
+			variables[name] = value
+		}
+		if err := e.saveInstanceVariables(ctx, parent, variables); err != nil {
+			return err
+		}
+	}
+
+	e.logger.Info("Child instance completed, resuming parent",
+		zap.Uint("instance_id", parent.ID),
+		zap.String("node_id", node.ID),
+		zap.Uint("child_instance_id", child.ID),
+	)
+
+	return e.checkAndAdvanceProcess(ctx, parent, node.ID)
+}
+
+// cancelChildInstances 以 reason 取消父实例下仍未结束的子实例，返回被取消的子实例
+func (e *ProcessEngine) cancelChildInstances(ctx context.Context, instanceID uint, reason string) ([]uint, error) {
+	children, err := e.instanceRepo.GetChildren(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	var cancelled []uint
+	for _, child := range children {
+		if child.Status != model.InstanceStatusRunning && child.Status != model.InstanceStatusSuspended {
+			continue
+		}
+		if err := e.CancelInstance(ctx, child.ID, 0, reason); err != nil {
+			e.logger.Error("Failed to cancel child instance", zap.Uint("child_instance_id", child.ID), zap.Error(err))
+			continue
+		}
+		cancelled = append(cancelled, child.ID)
+	}
+	return cancelled, nil
+}
+
+// instanceDepth 计算实例在调用层级中的深度，根实例为 0
+func (e *ProcessEngine) instanceDepth(ctx context.Context, instance *model.ProcessInstance) (int, error) {
+	depth := 0
+	parentID := instance.ParentInstanceID
+	for parentID != nil && depth <= maxCallDepth {
+		parent, err := e.instanceRepo.GetByID(ctx, *parentID)
+		if err != nil {
+			return 0, err
+		}
+		depth++
+		parentID = parent.ParentInstanceID
+	}
+	return depth, nil
+}
+
+// GetInstanceHierarchy 获取实例所在的调用层级树，从根实例开始，标记当前实例
+func (e *ProcessEngine) GetInstanceHierarchy(ctx context.Context, instance *model.ProcessInstance) (*InstanceHierarchyNode, error) {
+	root := instance
+	for depth := 0; root.ParentInstanceID != nil && depth < maxCallDepth; depth++ {
+		parent, err := e.instanceRepo.GetByID(ctx, *root.ParentInstanceID)
+		if err != nil {
+			return nil, err
+		}
+		root = parent
+	}
+	return e.buildHierarchyNode(ctx, root, instance.ID, 0)
+}
+
+// buildHierarchyNode 递归构建层级树节点
+func (e *ProcessEngine) buildHierarchyNode(ctx context.Context, instance *model.ProcessInstance, currentID uint, depth int) (*InstanceHierarchyNode, error) {
+	node := &InstanceHierarchyNode{
+		InstanceID:     instance.ID,
+		DefinitionID:   instance.DefinitionID,
+		DefinitionKey:  instance.Definition.Key,
+		DefinitionName: instance.Definition.Name,
+		Version:        instance.Definition.Version,
+		BusinessKey:    instance.BusinessKey,
+		Status:         instance.Status,
+		ParentNodeID:   instance.ParentNodeID,
+		Current:        instance.ID == currentID,
+	}
+	if depth >= maxCallDepth {
+		return node, nil
+	}
+
+	children, err := e.instanceRepo.GetChildren(ctx, instance.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range children {
+		child, err := e.buildHierarchyNode(ctx, &children[i], currentID, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		node.Children = append(node.Children, child)
+	}
+	return node, nil
+}
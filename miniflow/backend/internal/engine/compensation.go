@@ -0,0 +1,140 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// compensationStep 一个需要补偿的已完成服务任务及其补偿节点
+type compensationStep struct {
+	task    model.TaskInstance
+	handler *model.ProcessNode
+}
+
+// compensate 按完成顺序的逆序执行已完成服务任务声明的补偿节点，返回创建的补偿任务ID
+//
+// 补偿是尽力而为的：单个补偿节点执行失败时补偿任务标记为失败并记录执行日志，继续补偿其余任务，
+// 失败的补偿需要人工处理
+func (e *ProcessEngine) compensate(ctx context.Context, instance *model.ProcessInstance) ([]uint, error) {
+	definition, err := instance.Definition.GetDefinitionData()
+	if err != nil {
+		return nil, newEngineError(CodeInvalidDefinition, err, "解析流程定义失败")
+	}
+	tasks, err := e.taskRepo.GetByInstance(ctx, instance.ID)
+	if err != nil {
+		return nil, fmt.Errorf("获取流程任务失败: %w", err)
+	}
+
+	var steps []compensationStep
+	for _, task := range tasks {
+		if task.Status != model.TaskStatusCompleted || task.CompleteTime == nil {
+			continue
+		}
+		node := e.findNodeByID(definition.Nodes, task.NodeID)
+		if node == nil || node.Type != model.NodeTypeServiceTask {
+			continue
+		}
+		handlerID, err := model.GetCompensation(node)
+		if err != nil || handlerID == "" {
+			continue
+		}
+		handler := e.findNodeByID(definition.Nodes, handlerID)
+		if handler == nil {
+			e.logger.Warn("Compensation handler not found",
+				zap.Uint("instance_id", instance.ID),
+				zap.String("node_id", node.ID),
+				zap.String("handler_id", handlerID),
+			)
+			continue
+		}
+		steps = append(steps, compensationStep{task: task, handler: handler})
+	}
+
+	sort.SliceStable(steps, func(i, j int) bool {
+		a, b := steps[i].task, steps[j].task
+		if !a.CompleteTime.Equal(*b.CompleteTime) {
+			return a.CompleteTime.After(*b.CompleteTime)
+		}
+		return a.ID > b.ID
+	})
+
+	ids := make([]uint, 0, len(steps))
+	for i := range steps {
+		id, err := e.runCompensation(ctx, instance, &steps[i].task, steps[i].handler)
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// runCompensation 为已完成的服务任务创建补偿任务并执行补偿节点，返回补偿任务ID
+func (e *ProcessEngine) runCompensation(ctx context.Context, instance *model.ProcessInstance, compensated *model.TaskInstance, handler *model.ProcessNode) (uint, error) {
+	task := &model.TaskInstance{
+		InstanceID: instance.ID,
+		NodeID:     handler.ID,
+		Name:       handler.Name,
+		Status:     model.TaskStatusCreated,
+		Priority:   50,
+	}
+	if err := e.taskRepo.Create(ctx, task); err != nil {
+		return 0, fmt.Errorf("创建补偿任务失败: %v", err)
+	}
+
+	err := e.checkConnectorPolicy(ctx, instance, handler)
+	if err == nil {
+		err = e.executeServiceTask(ctx, instance, task, handler)
+	}
+
+	now := time.Now()
+	task.CompleteTime = &now
+	if err != nil {
+		task.Status = model.TaskStatusFailed
+		task.Comment = err.Error()
+		e.logger.Error("Compensation failed",
+			zap.Uint("instance_id", instance.ID),
+			zap.Uint("compensated_task_id", compensated.ID),
+			zap.String("handler_id", handler.ID),
+			zap.Error(err),
+		)
+	} else {
+		task.Status = model.TaskStatusCompleted
+		task.Comment = fmt.Sprintf("补偿任务 %d（%s）", compensated.ID, compensated.Name)
+	}
+	if err := e.taskRepo.Update(ctx, task); err != nil {
+		return task.ID, fmt.Errorf("更新补偿任务状态失败: %v", err)
+	}
+
+	e.traceFor(instance).record(ctx, model.TraceCategoryNode, handler.ID, map[string]interface{}{
+		"task_id":             task.ID,
+		"compensated_task_id": compensated.ID,
+		"status":              task.Status,
+	}, "补偿节点 %s 补偿任务 %d，结果: %s", handler.ID, compensated.ID, task.Status)
+	return task.ID, nil
+}
+
+// abortWithCompensation 声明了失败时补偿的服务任务执行失败后，将任务标记为失败并取消流程实例，
+// 取消时按逆序补偿已完成的服务任务
+func (e *ProcessEngine) abortWithCompensation(ctx context.Context, instance *model.ProcessInstance, task *model.TaskInstance, node *model.ProcessNode, cause error) error {
+	now := time.Now()
+	task.Status = model.TaskStatusFailed
+	task.CompleteTime = &now
+	task.Comment = cause.Error()
+	if err := e.taskRepo.Update(ctx, task); err != nil {
+		return fmt.Errorf("更新服务任务状态失败: %v", err)
+	}
+
+	e.logger.Warn("Service task failed, compensating instance",
+		zap.Uint("instance_id", instance.ID),
+		zap.String("node_id", node.ID),
+		zap.Error(cause),
+	)
+	return e.CancelInstance(ctx, instance.ID, 0, fmt.Sprintf("服务任务 %s 执行失败: %v", node.Name, cause))
+}
@@ -0,0 +1,233 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// 完成回调的重试配置
+const (
+	completionWebhookMaxAttempts = 5
+	completionWebhookTimeout     = 10 * time.Second
+	completionWebhookBaseBackoff = 2 * time.Second
+)
+
+// CompletionWebhookSignatureHeader 回调签名请求头，值为 "sha256=<hex>"
+const CompletionWebhookSignatureHeader = "X-MiniFlow-Signature"
+
+// CompletionPayload 流程完成回调的请求体
+type CompletionPayload struct {
+	Event             string                 `json:"event"`
+	InstanceID        uint                   `json:"instance_id"`
+	BusinessKey       string                 `json:"business_key"`
+	DefinitionID      uint                   `json:"definition_id"`
+	DefinitionKey     string                 `json:"definition_key"`
+	DefinitionVersion int                    `json:"definition_version"`
+	Status            string                 `json:"status"`
+	Outcome           string                 `json:"outcome"`
+	EndNodeID         string                 `json:"end_node_id"`
+	EndNodeName       string                 `json:"end_node_name"`
+	Variables         map[string]interface{} `json:"variables"`
+	StartTime         time.Time              `json:"start_time"`
+	EndTime           *time.Time             `json:"end_time"`
+}
+
+// CompletionWebhookSender 流程完成回调发送器
+type CompletionWebhookSender struct {
+	client      *http.Client
+	maxAttempts int
+	baseBackoff time.Duration
+	logger      *logger.Logger
+}
+
+// NewCompletionWebhookSender 创建流程完成回调发送器
+func NewCompletionWebhookSender(logger *logger.Logger) *CompletionWebhookSender {
+	return &CompletionWebhookSender{
+		client:      &http.Client{Timeout: completionWebhookTimeout},
+		maxAttempts: completionWebhookMaxAttempts,
+		baseBackoff: completionWebhookBaseBackoff,
+		logger:      logger,
+	}
+}
+
+// BuildCompletionPayload 构建流程完成回调请求体
+// 结束节点属性 outcome 可以声明业务结果，未声明时使用结束节点ID；不允许导出的数据分级不携带流程变量
+func BuildCompletionPayload(instance *model.ProcessInstance, definition *model.ProcessDefinition, node *model.ProcessNode) (*CompletionPayload, error) {
+	variables := make(map[string]interface{})
+	if instance.Variables != "" && definition.DataPolicy().AllowExport {
+		if err := json.Unmarshal([]byte(instance.Variables), &variables); err != nil {
+			return nil, fmt.Errorf("解析流程变量失败: %v", err)
+		}
+	}
+
+	outcome := node.ID
+	if value, ok := node.Props["outcome"].(string); ok && value != "" {
+		outcome = value
+	}
+
+	return &CompletionPayload{
+		Event:             "process.completed",
+		InstanceID:        instance.ID,
+		BusinessKey:       instance.BusinessKey,
+		DefinitionID:      definition.ID,
+		DefinitionKey:     definition.Key,
+		DefinitionVersion: definition.Version,
+		Status:            instance.Status,
+		Outcome:           outcome,
+		EndNodeID:         node.ID,
+		EndNodeName:       node.Name,
+		Variables:         variables,
+		StartTime:         instance.StartTime,
+		EndTime:           instance.EndTime,
+	}, nil
+}
+
+// SignPayload 使用密钥计算请求体的 HMAC-SHA256 签名
+func SignPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Deliver 投递回调记录中的请求体，失败时按指数退避重试，直到成功或达到最大次数
+// 返回本次实际尝试的次数
+func (s *CompletionWebhookSender) Deliver(delivery *model.WebhookDelivery, secret string) (int, error) {
+	body := []byte(delivery.Payload)
+
+	var lastErr error
+	backoff := s.baseBackoff
+	for attempt := 1; attempt <= s.maxAttempts; attempt++ {
+		lastErr = s.post(delivery.URL, delivery.Event, secret, body, delivery.Attempts+attempt)
+		if lastErr == nil {
+			s.logger.Info("Completion webhook delivered",
+				zap.Uint("instance_id", delivery.InstanceID),
+				zap.Uint("delivery_id", delivery.ID),
+				zap.Int("attempt", attempt),
+			)
+			return attempt, nil
+		}
+
+		s.logger.Warn("Completion webhook attempt failed",
+			zap.Uint("instance_id", delivery.InstanceID),
+			zap.Uint("delivery_id", delivery.ID),
+			zap.Int("attempt", attempt),
+			zap.Error(lastErr),
+		)
+
+		if attempt < s.maxAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+
+	return s.maxAttempts, fmt.Errorf("回调在%d次尝试后仍然失败: %v", s.maxAttempts, lastErr)
+}
+
+// post 执行一次回调请求
+func (s *CompletionWebhookSender) post(url, event, secret string, body []byte, attempt int) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-MiniFlow-Event", event)
+	req.Header.Set("X-MiniFlow-Delivery-Attempt", fmt.Sprintf("%d", attempt))
+	if secret != "" {
+		req.Header.Set(CompletionWebhookSignatureHeader, SignPayload(secret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("回调返回状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// notifyCompletion 流程到达结束节点后触发定义中配置的完成回调
+func (e *ProcessEngine) notifyCompletion(ctx context.Context, instance *model.ProcessInstance, node *model.ProcessNode) {
+	definition, err := e.processRepo.GetByID(ctx, instance.DefinitionID)
+	if err != nil {
+		e.logger.Warn("Failed to load definition for completion webhook",
+			zap.Uint("instance_id", instance.ID),
+			zap.Error(err),
+		)
+		return
+	}
+	if definition.CompletionWebhookURL == "" {
+		return
+	}
+
+	payload, err := BuildCompletionPayload(instance, definition, node)
+	if err != nil {
+		e.logger.Error("Failed to build completion payload",
+			zap.Uint("instance_id", instance.ID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		e.logger.Error("Failed to encode completion payload",
+			zap.Uint("instance_id", instance.ID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	// 先保存投递记录，失败的投递可以在作业面板中重新投递
+	delivery := &model.WebhookDelivery{
+		InstanceID: instance.ID,
+		Event:      payload.Event,
+		URL:        definition.CompletionWebhookURL,
+		Payload:    string(body),
+		Status:     model.WebhookDeliveryPending,
+	}
+	if err := e.instanceRepo.CreateWebhookDelivery(ctx, delivery); err != nil {
+		e.logger.Warn("Failed to record completion webhook delivery",
+			zap.Uint("instance_id", instance.ID),
+			zap.Error(err),
+		)
+	}
+
+	e.deliverWebhookAsync(ctx, delivery, definition.CompletionWebhookSecret)
+}
+
+// deliverWebhookAsync 异步投递回调并记录结果
+// 投递在请求结束后继续进行，因此不跟随 ctx 的取消
+func (e *ProcessEngine) deliverWebhookAsync(ctx context.Context, delivery *model.WebhookDelivery, secret string) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		attempts, err := e.completionWebhook.Deliver(delivery, secret)
+		if err != nil {
+			e.logger.Error("Completion webhook failed",
+				zap.Uint("instance_id", delivery.InstanceID),
+				zap.String("url", delivery.URL),
+				zap.Error(err),
+			)
+		}
+		if delivery.ID == 0 {
+			return
+		}
+		_ = e.instanceRepo.RecordWebhookDeliveryResult(ctx, delivery, attempts, err, time.Now())
+	}()
+}
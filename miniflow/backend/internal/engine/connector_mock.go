@@ -0,0 +1,117 @@
+package engine
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/internal/repository"
+	"miniflow/pkg/config"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// defaultStubKey 未按主题或连接器类型配置预设响应时使用的键
+const defaultStubKey = "default"
+
+// ConnectorMock 连接器模拟模式，用预设或录制的响应替代服务任务的外部调用
+// 仅用于测试和预发环境，让完整的流程路径可以在不调用真实下游系统的情况下运行
+type ConnectorMock struct {
+	cfg              *config.ConnectorMockConfig
+	executionLogRepo *repository.ExecutionLogRepository
+	logger           *logger.Logger
+}
+
+// NewConnectorMock 创建连接器模拟器
+func NewConnectorMock(cfg *config.ConnectorMockConfig, executionLogRepo *repository.ExecutionLogRepository, logger *logger.Logger) *ConnectorMock {
+	if cfg.Enabled {
+		logger.Warn("Connector mock mode is enabled, outbound service calls will not reach downstream systems",
+			zap.Strings("connectors", cfg.Connectors),
+		)
+	}
+	return &ConnectorMock{
+		cfg:              cfg,
+		executionLogRepo: executionLogRepo,
+		logger:           logger,
+	}
+}
+
+// Applies 判断请求是否需要模拟；未配置调用地址的请求本身就是空操作，不需要模拟
+func (m *ConnectorMock) Applies(req *ServiceRequest) bool {
+	if !m.cfg.Enabled || req.URL == "" {
+		return false
+	}
+	if len(m.cfg.Connectors) == 0 {
+		return true
+	}
+	for _, name := range m.cfg.Connectors {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" && (name == req.Connector || name == req.Topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// Execute 返回模拟响应：依次匹配主题、连接器类型和 default 的预设响应，
+// 都没有时回放相同请求最近一次成功的真实响应，仍没有则返回空的 200 响应
+// 预设响应的状态码不是 2xx 时视为调用失败，用于演练失败路径
+func (m *ConnectorMock) Execute(ctx context.Context, task *model.TaskInstance, req *ServiceRequest) (*ServiceResult, error) {
+	if stub, key, ok := m.findStub(req); ok {
+		if stub.DelayMs > 0 {
+			time.Sleep(time.Duration(stub.DelayMs) * time.Millisecond)
+		}
+
+		result := &ServiceResult{
+			StatusCode: stub.StatusCode,
+			Body:       model.TruncateExecutionContent(stub.Body, model.MaxExecutionResponseLength),
+			Mocked:     true,
+		}
+		if result.StatusCode == 0 {
+			result.StatusCode = http.StatusOK
+		}
+
+		m.logger.Info("Service task call stubbed",
+			zap.Uint("task_id", task.ID),
+			zap.String("stub", key),
+			zap.Int("status_code", result.StatusCode),
+		)
+		if result.StatusCode < 200 || result.StatusCode >= 300 {
+			return result, newEngineError(CodeServiceErrorResponse, nil, "模拟服务返回状态码 %d", result.StatusCode)
+		}
+		return result, nil
+	}
+
+	if m.cfg.Replay {
+		if recorded, err := m.executionLogRepo.GetLatestRecorded(ctx, req.Method, req.URL); err == nil {
+			m.logger.Info("Service task call replayed",
+				zap.Uint("task_id", task.ID),
+				zap.Uint("recorded_log_id", recorded.ID),
+			)
+			return &ServiceResult{
+				StatusCode: recorded.StatusCode,
+				Body:       recorded.Response,
+				Mocked:     true,
+			}, nil
+		}
+	}
+
+	m.logger.Info("Service task call mocked without stub", zap.Uint("task_id", task.ID))
+	return &ServiceResult{StatusCode: http.StatusOK, Mocked: true}, nil
+}
+
+// findStub 按主题、连接器类型、default 的顺序查找预设响应
+func (m *ConnectorMock) findStub(req *ServiceRequest) (config.ConnectorStub, string, bool) {
+	for _, key := range []string{req.Topic, req.Connector, defaultStubKey} {
+		if key == "" {
+			continue
+		}
+		if stub, ok := m.cfg.Stubs[key]; ok {
+			return stub, key, true
+		}
+	}
+	return config.ConnectorStub{}, "", false
+}
@@ -0,0 +1,10 @@
+package engine
+
+import "miniflow/internal/model"
+
+// redactTaskList 按流程定义的数据分级对任务列表脱敏，列表接口返回前调用
+func redactTaskList(tasks []model.TaskInstance) {
+	for i := range tasks {
+		tasks[i].RedactForList()
+	}
+}
@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// labelResolver 根据流程定义解析状态和节点的展示标签
+type labelResolver struct {
+	labels    *model.DisplayLabels
+	nodeNames map[string]string
+}
+
+// newLabelResolver 创建展示标签解析器
+func (e *ProcessEngine) newLabelResolver(definition *model.ProcessDefinition) *labelResolver {
+	resolver := &labelResolver{nodeNames: make(map[string]string)}
+
+	labels, err := definition.GetDisplayLabels()
+	if err != nil {
+		e.logger.Warn("Failed to parse display labels",
+			zap.Uint("definition_id", definition.ID),
+			zap.Error(err),
+		)
+	}
+	resolver.labels = labels
+
+	if definitionData, err := definition.GetDefinitionData(); err == nil {
+		for _, node := range definitionData.Nodes {
+			resolver.nodeNames[node.ID] = node.Name
+		}
+	}
+
+	return resolver
+}
+
+// nodeLabel 获取节点展示标签，未配置映射时使用节点名称
+func (r *labelResolver) nodeLabel(nodeID string) string {
+	if label := r.labels.NodeLabel(nodeID); label != "" {
+		return label
+	}
+	return r.nodeNames[nodeID]
+}
+
+// applyInstanceLabels 填充流程实例及其任务的展示标签
+func (e *ProcessEngine) applyInstanceLabels(instance *model.ProcessInstance) {
+	if instance.Definition.ID == 0 {
+		return
+	}
+
+	resolver := e.newLabelResolver(&instance.Definition)
+	instance.StatusLabel = resolver.labels.StatusLabel(instance.Status)
+	instance.CurrentNodeLabel = resolver.nodeLabel(instance.CurrentNode)
+
+	for i := range instance.Tasks {
+		instance.Tasks[i].StatusLabel = resolver.labels.StatusLabel(instance.Tasks[i].Status)
+		instance.Tasks[i].NodeLabel = resolver.nodeLabel(instance.Tasks[i].NodeID)
+	}
+}
+
+// applyTaskLabels 填充任务的展示标签
+func (e *ProcessEngine) applyTaskLabels(tasks ...*model.TaskInstance) {
+	resolvers := make(map[uint]*labelResolver)
+
+	for _, task := range tasks {
+		definition := &task.Instance.Definition
+		if definition.ID == 0 {
+			continue
+		}
+
+		resolver, ok := resolvers[definition.ID]
+		if !ok {
+			resolver = e.newLabelResolver(definition)
+			resolvers[definition.ID] = resolver
+		}
+
+		task.StatusLabel = resolver.labels.StatusLabel(task.Status)
+		task.NodeLabel = resolver.nodeLabel(task.NodeID)
+	}
+}
+
+// applyTaskListLabels 填充任务列表的展示标签
+func (e *ProcessEngine) applyTaskListLabels(tasks []model.TaskInstance) {
+	ptrs := make([]*model.TaskInstance, len(tasks))
+	for i := range tasks {
+		ptrs[i] = &tasks[i]
+	}
+	e.applyTaskLabels(ptrs...)
+}
@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"miniflow/internal/model"
+)
+
+// InstanceSchedule 流程实例的截止时间计划：剩余关键路径和各待办任务的截止时间
+type InstanceSchedule struct {
+	InstanceID     uint                `json:"instance_id"`
+	DueDate        *time.Time          `json:"due_date"`
+	RemainingHours float64             `json:"remaining_hours"`
+	CriticalPath   *model.CriticalPath `json:"critical_path"`
+	Tasks          []ScheduledTask     `json:"tasks"`
+}
+
+// ScheduledTask 待办任务的截止时间
+type ScheduledTask struct {
+	TaskID   uint       `json:"task_id"`
+	NodeID   string     `json:"node_id"`
+	Name     string     `json:"name"`
+	DueDate  *time.Time `json:"due_date"`
+	Overdue  bool       `json:"overdue"`
+	Estimate float64    `json:"estimated_hours"`
+}
+
+// GetInstanceSchedule 计算流程实例从当前待办节点出发的关键路径，任务截止时间在任务创建时按剩余时间分配
+func (e *ProcessEngine) GetInstanceSchedule(ctx context.Context, instanceID uint) (*InstanceSchedule, error) {
+	instance, err := e.instanceRepo.GetByID(ctx, instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("获取流程实例失败: %w", err)
+	}
+	definitionData, err := instance.Definition.GetDefinitionData()
+	if err != nil {
+		return nil, fmt.Errorf("解析流程定义失败: %v", err)
+	}
+
+	tasks, err := e.taskRepo.GetByInstance(ctx, instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("获取任务列表失败: %w", err)
+	}
+
+	now := time.Now()
+	schedule := &InstanceSchedule{
+		InstanceID:   instance.ID,
+		DueDate:      instance.DueDate,
+		CriticalPath: &model.CriticalPath{},
+		Tasks:        []ScheduledTask{},
+	}
+	if instance.DueDate != nil {
+		schedule.RemainingHours = math.Round(instance.DueDate.Sub(now).Hours()*10) / 10
+	}
+
+	for _, task := range tasks {
+		if !isOpenTaskStatus(task.Status) {
+			continue
+		}
+
+		nodeID := task.NodeID
+		if reviewNodeID, ok := model.ParseConsolidationNodeID(nodeID); ok {
+			nodeID = reviewNodeID
+		}
+		var estimate float64
+		if node := e.findNodeByID(definitionData.Nodes, nodeID); node != nil {
+			estimate = model.EstimatedHours(node)
+		}
+
+		schedule.Tasks = append(schedule.Tasks, ScheduledTask{
+			TaskID:   task.ID,
+			NodeID:   task.NodeID,
+			Name:     task.Name,
+			DueDate:  task.DueDate,
+			Overdue:  task.DueDate != nil && task.DueDate.Before(now),
+			Estimate: estimate,
+		})
+
+		// 并行分支取最长的剩余路径
+		if path := definitionData.CriticalPathFrom(nodeID); path.EstimatedHours > schedule.CriticalPath.EstimatedHours || len(schedule.CriticalPath.Nodes) == 0 {
+			schedule.CriticalPath = path
+		}
+	}
+
+	return schedule, nil
+}
+
+// isOpenTaskStatus 判断任务是否仍待处理
+func isOpenTaskStatus(status string) bool {
+	switch status {
+	case model.TaskStatusCreated, model.TaskStatusAssigned, model.TaskStatusClaimed, model.TaskStatusInProgress:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// maxDuplicateFlags 每个新实例最多标记的疑似重复实例数量
+const maxDuplicateFlags = 5
+
+// detectDuplicates 将新启动的实例与同一流程下运行中的实例比较，相似度达到阈值的记为疑似重复
+// 检测失败不影响流程启动
+func (e *ProcessEngine) detectDuplicates(ctx context.Context, instance *model.ProcessInstance) {
+	if e.duplicateRepo == nil {
+		return
+	}
+
+	candidates, err := e.duplicateRepo.GetDuplicateCandidates(ctx, instance.Definition.Key, instance.ID)
+	if err != nil {
+		e.logger.Warn("Failed to load duplicate candidates", zap.Uint("instance_id", instance.ID), zap.Error(err))
+		return
+	}
+
+	keyVariables := instance.Definition.GetDuplicateKeyVariables()
+	var flags []model.InstanceDuplicate
+	for i := range candidates {
+		match := model.MatchDuplicate(instance, &candidates[i], keyVariables)
+		if match.Score < model.DuplicateScoreThreshold {
+			continue
+		}
+		duplicate := model.InstanceDuplicate{
+			InstanceID:    instance.ID,
+			DuplicateOfID: candidates[i].ID,
+			Score:         match.Score,
+			Status:        model.DuplicateStatusOpen,
+		}
+		duplicate.SetReasons(match.Reasons)
+		flags = append(flags, duplicate)
+	}
+
+	sort.SliceStable(flags, func(i, j int) bool { return flags[i].Score > flags[j].Score })
+	if len(flags) > maxDuplicateFlags {
+		flags = flags[:maxDuplicateFlags]
+	}
+
+	for i := range flags {
+		if err := e.duplicateRepo.Create(ctx, &flags[i]); err != nil {
+			continue
+		}
+		e.logger.Info("Possible duplicate instance detected",
+			zap.Uint("instance_id", instance.ID),
+			zap.Uint("duplicate_of_id", flags[i].DuplicateOfID),
+			zap.Float64("score", flags[i].Score),
+		)
+	}
+}
+
+// GetInstanceDuplicates 获取实例的疑似重复记录，包括指向其他实例和被其他实例指向的记录
+func (e *ProcessEngine) GetInstanceDuplicates(ctx context.Context, instanceID uint) ([]model.InstanceDuplicate, error) {
+	duplicates, err := e.duplicateRepo.GetByInstance(ctx, instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("获取疑似重复记录失败: %w", err)
+	}
+	return duplicates, nil
+}
+
+// GetOpenDuplicates 获取用户发起的实例上待处理的疑似重复记录
+func (e *ProcessEngine) GetOpenDuplicates(ctx context.Context, starterID uint) ([]model.InstanceDuplicate, error) {
+	duplicates, err := e.duplicateRepo.GetOpenByStarter(ctx, starterID)
+	if err != nil {
+		return nil, fmt.Errorf("获取疑似重复记录失败: %w", err)
+	}
+	return duplicates, nil
+}
+
+// ConfirmDuplicate 确认实例是重复提交：取消该实例并在取消原因中关联原实例
+func (e *ProcessEngine) ConfirmDuplicate(ctx context.Context, instanceID, duplicateID, userID uint) (*model.InstanceDuplicate, error) {
+	duplicate, err := e.getOpenDuplicate(ctx, instanceID, duplicateID)
+	if err != nil {
+		return nil, err
+	}
+
+	reason := fmt.Sprintf("与流程实例 #%d 重复", duplicate.DuplicateOfID)
+	if err := e.CancelInstance(ctx, instanceID, userID, reason); err != nil {
+		return nil, err
+	}
+
+	if err := e.resolveDuplicate(ctx, duplicate, model.DuplicateStatusConfirmed, userID); err != nil {
+		return nil, err
+	}
+
+	e.logger.Info("Instance cancelled as duplicate",
+		zap.Uint("instance_id", instanceID),
+		zap.Uint("duplicate_of_id", duplicate.DuplicateOfID),
+		zap.Uint("user_id", userID),
+	)
+
+	return duplicate, nil
+}
+
+// DismissDuplicate 忽略疑似重复标记，实例继续运行
+func (e *ProcessEngine) DismissDuplicate(ctx context.Context, instanceID, duplicateID, userID uint) (*model.InstanceDuplicate, error) {
+	duplicate, err := e.getOpenDuplicate(ctx, instanceID, duplicateID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := e.resolveDuplicate(ctx, duplicate, model.DuplicateStatusDismissed, userID); err != nil {
+		return nil, err
+	}
+
+	return duplicate, nil
+}
+
+// getOpenDuplicate 获取属于该实例且尚未处理的疑似重复记录
+func (e *ProcessEngine) getOpenDuplicate(ctx context.Context, instanceID, duplicateID uint) (*model.InstanceDuplicate, error) {
+	duplicate, err := e.duplicateRepo.GetByID(ctx, duplicateID)
+	if err != nil {
+		return nil, err
+	}
+	if duplicate.InstanceID != instanceID {
+		return nil, newEngineError(CodeNotFound, nil, "疑似重复记录不属于该流程实例")
+	}
+	if duplicate.Status != model.DuplicateStatusOpen {
+		return nil, newEngineError(CodeInvalidStateTransition, nil, "疑似重复记录已处理")
+	}
+	return duplicate, nil
+}
+
+// resolveDuplicate 记录疑似重复的处理结果
+func (e *ProcessEngine) resolveDuplicate(ctx context.Context, duplicate *model.InstanceDuplicate, status string, userID uint) error {
+	now := time.Now()
+	duplicate.Status = status
+	duplicate.ResolvedBy = &userID
+	duplicate.ResolvedAt = &now
+	if err := e.duplicateRepo.Update(ctx, duplicate); err != nil {
+		return fmt.Errorf("更新疑似重复记录失败: %v", err)
+	}
+	return nil
+}
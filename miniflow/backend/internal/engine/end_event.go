@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// hasActiveBranches 判断实例是否还有未结束的分支：未完成的任务、等待中的定时器和消息订阅、
+// 运行中的子实例或未处理的异常事件
+func (e *ProcessEngine) hasActiveBranches(ctx context.Context, instanceID uint) (bool, error) {
+	tasks, err := e.taskRepo.GetByInstance(ctx, instanceID)
+	if err != nil {
+		return false, fmt.Errorf("获取流程任务失败: %w", err)
+	}
+	for _, task := range tasks {
+		switch task.Status {
+		case model.TaskStatusCreated, model.TaskStatusAssigned, model.TaskStatusClaimed, model.TaskStatusInProgress:
+			return true, nil
+		}
+	}
+
+	timers, err := e.instanceRepo.GetWaitingTimers(ctx, instanceID)
+	if err != nil {
+		return false, fmt.Errorf("获取等待中的定时器失败: %w", err)
+	}
+	if len(timers) > 0 {
+		return true, nil
+	}
+
+	messages, err := e.instanceRepo.GetWaitingMessages(ctx, instanceID)
+	if err != nil {
+		return false, fmt.Errorf("获取等待中的消息订阅失败: %w", err)
+	}
+	if len(messages) > 0 {
+		return true, nil
+	}
+
+	children, err := e.instanceRepo.GetChildren(ctx, instanceID)
+	if err != nil {
+		return false, fmt.Errorf("获取子流程实例失败: %w", err)
+	}
+	for _, child := range children {
+		if child.Status == model.InstanceStatusRunning || child.Status == model.InstanceStatusSuspended {
+			return true, nil
+		}
+	}
+
+	incidents, err := e.incidentRepo.GetByInstance(ctx, instanceID)
+	if err != nil {
+		return false, fmt.Errorf("获取异常事件失败: %w", err)
+	}
+	for _, incident := range incidents {
+		if incident.Status == model.IncidentStatusOpen {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// terminateBranches 终止结束事件结束实例的其他分支：跳过未完成的任务，取消定时器、消息订阅和子实例，
+// 清除汇聚网关上的到达记录；单项失败只记录日志，实例仍会完成
+func (e *ProcessEngine) terminateBranches(ctx context.Context, instance *model.ProcessInstance, node *model.ProcessNode) {
+	reason := fmt.Sprintf("到达终止结束事件 %s", node.ID)
+	skipped, err := e.cancelInstanceTasks(ctx, instance.ID, reason)
+	if err != nil {
+		e.logger.Error("Failed to skip tasks of terminated instance", zap.Uint("instance_id", instance.ID), zap.Error(err))
+	}
+	if err := e.instanceRepo.CancelTimers(ctx, instance.ID, ""); err != nil {
+		e.logger.Error("Failed to cancel timers of terminated instance", zap.Uint("instance_id", instance.ID), zap.Error(err))
+	}
+	if err := e.instanceRepo.CancelMessageSubscriptions(ctx, instance.ID, ""); err != nil {
+		e.logger.Error("Failed to cancel message subscriptions of terminated instance", zap.Uint("instance_id", instance.ID), zap.Error(err))
+	}
+	if _, err := e.cancelChildInstances(ctx, instance.ID, "父流程已终止"); err != nil {
+		e.logger.Error("Failed to cancel child instances of terminated instance", zap.Uint("instance_id", instance.ID), zap.Error(err))
+	}
+	if err := e.discardGatewayArrivals(ctx, instance.ID); err != nil {
+		e.logger.Error("Failed to discard gateway arrivals of terminated instance", zap.Uint("instance_id", instance.ID), zap.Error(err))
+	}
+
+	e.traceFor(instance).record(ctx, model.TraceCategoryNode, node.ID, map[string]interface{}{
+		"skipped_tasks": len(skipped),
+	}, "到达终止结束事件 %s，结束其他分支，跳过 %d 个任务", node.ID, len(skipped))
+}
@@ -0,0 +1,187 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"miniflow/internal/migration"
+	"miniflow/internal/model"
+	"miniflow/internal/repository"
+	"miniflow/pkg/config"
+	"miniflow/pkg/database"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// newTestEngine creates an engine on a migrated sqlite database in a temp directory.
+// Transactions begin immediately so concurrent writers queue on the busy timeout
+// instead of failing on a lock upgrade.
+func newTestEngine(t *testing.T) (*ProcessEngine, *gorm.DB) {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "engine.db") + "?_busy_timeout=10000&_txlock=immediate&_foreign_keys=on"
+	gdb, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := gdb.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	log := &logger.Logger{Logger: zap.NewNop()}
+	if _, err := migration.New(gdb, log).Up(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	db := database.Wrap(gdb, log)
+	instanceRepo := repository.NewProcessInstanceRepository(db, log)
+	rbac := config.DefaultRBACConfig()
+	e := NewProcessEngine(
+		instanceRepo,
+		repository.NewTaskRepository(db, log),
+		repository.NewProcessRepository(db, log),
+		repository.NewUserRepository(db, log),
+		repository.NewConnectorPolicyRepository(db, log),
+		repository.NewIncidentRepository(db, log),
+		repository.NewDuplicateRepository(db, log),
+		repository.NewExecutionLogRepository(db, log),
+		&config.ConnectorConfig{},
+		&config.ScriptConfig{TimeoutSeconds: 5, MaxTimeoutSeconds: 60},
+		&rbac,
+		db,
+		NewDBVariableStore(instanceRepo),
+		NewEventSystem(log),
+		nil,
+		log,
+	)
+	return e, gdb
+}
+
+// createTestUser creates an active user with the given role
+func createTestUser(t *testing.T, db *gorm.DB, username, role string) *model.User {
+	t.Helper()
+
+	user := &model.User{
+		Username: username,
+		Password: "x",
+		Email:    username + "@example.com",
+		Role:     role,
+		Status:   "active",
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("create user %s: %v", username, err)
+	}
+	return user
+}
+
+// publishTestDefinition saves data as the published version 1 of a process with the given key
+func publishTestDefinition(t *testing.T, db *gorm.DB, key string, creatorID uint, data *model.ProcessDefinitionData) *model.ProcessDefinition {
+	t.Helper()
+
+	if issues := data.ValidateGraph(); len(issues) > 0 {
+		t.Fatalf("definition %s is invalid: %+v", key, issues)
+	}
+	definition := &model.ProcessDefinition{
+		Key:       key,
+		Name:      key,
+		Version:   1,
+		Status:    model.ProcessStatusPublished,
+		CreatedBy: creatorID,
+	}
+	if err := definition.SetDefinitionData(data); err != nil {
+		t.Fatalf("encode definition %s: %v", key, err)
+	}
+	if err := db.Create(definition).Error; err != nil {
+		t.Fatalf("create definition %s: %v", key, err)
+	}
+	return definition
+}
+
+// startTestProcess starts an instance of the definition
+func startTestProcess(t *testing.T, e *ProcessEngine, definitionID, starterID uint, variables map[string]interface{}) *model.ProcessInstance {
+	t.Helper()
+
+	instance, err := e.StartProcess(context.Background(), &StartProcessRequest{
+		DefinitionID: definitionID,
+		BusinessKey:  fmt.Sprintf("test-%d", definitionID),
+		Variables:    variables,
+	}, starterID)
+	if err != nil {
+		t.Fatalf("start process: %v", err)
+	}
+	return instance
+}
+
+// openTasks returns the unfinished tasks of the instance ordered by ID
+func openTasks(t *testing.T, db *gorm.DB, instanceID uint) []model.TaskInstance {
+	t.Helper()
+
+	var tasks []model.TaskInstance
+	if err := db.Where("instance_id = ? AND status IN ?", instanceID, []string{
+		model.TaskStatusCreated,
+		model.TaskStatusAssigned,
+		model.TaskStatusClaimed,
+		model.TaskStatusInProgress,
+	}).
+		Order("id").Find(&tasks).Error; err != nil {
+		t.Fatalf("list tasks: %v", err)
+	}
+	return tasks
+}
+
+// openTaskAt returns the single unfinished task of the instance at the node
+func openTaskAt(t *testing.T, db *gorm.DB, instanceID uint, nodeID string) *model.TaskInstance {
+	t.Helper()
+
+	var found []model.TaskInstance
+	for _, task := range openTasks(t, db, instanceID) {
+		if task.NodeID == nodeID {
+			found = append(found, task)
+		}
+	}
+	if len(found) != 1 {
+		t.Fatalf("expected one open task at %s, found %d", nodeID, len(found))
+	}
+	return &found[0]
+}
+
+// claimAndComplete claims the task for the user and completes it
+func claimAndComplete(t *testing.T, e *ProcessEngine, taskID, userID uint) {
+	t.Helper()
+
+	if err := e.ClaimTask(context.Background(), taskID, userID); err != nil {
+		t.Fatalf("claim task %d: %v", taskID, err)
+	}
+	if err := e.CompleteTask(context.Background(), taskID, userID, nil, ""); err != nil {
+		t.Fatalf("complete task %d: %v", taskID, err)
+	}
+}
+
+// reloadInstance reads the instance back from the database
+func reloadInstance(t *testing.T, db *gorm.DB, instanceID uint) *model.ProcessInstance {
+	t.Helper()
+
+	var instance model.ProcessInstance
+	if err := db.First(&instance, instanceID).Error; err != nil {
+		t.Fatalf("load instance %d: %v", instanceID, err)
+	}
+	return &instance
+}
+
+// userTaskNode returns a user task node without assignment rules
+func userTaskNode(id string) model.ProcessNode {
+	return model.ProcessNode{ID: id, Type: model.NodeTypeUserTask, Name: id}
+}
+
+// flow returns a flow between two nodes
+func flow(from, to string) model.ProcessFlow {
+	return model.ProcessFlow{ID: from + "-" + to, From: from, To: to}
+}
@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"errors"
+	"net/http"
+
+	"miniflow/internal/model"
+	"miniflow/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// ErrorKind 引擎错误的类别，区分调用方造成的错误和系统故障，处理器按类别决定 HTTP 状态码
+//
+// 带失败代码的错误按代码在目录中的状态码归类，可以用 errors.Is(err, ErrNotFound) 判断；
+// 仓储返回的记录不存在、乐观锁冲突等错误没有失败代码，由 ErrorKindOf 按含义归类。
+type ErrorKind struct {
+	// Code 没有更具体的失败代码时响应中使用的代码
+	Code       string
+	HTTPStatus int
+	// UserFault 为 true 表示错误由调用方的请求造成，原样重试不会成功
+	UserFault bool
+}
+
+// Error implements the error interface
+func (k *ErrorKind) Error() string {
+	return k.Code
+}
+
+// 引擎错误的类别
+var (
+	ErrNotFound         = &ErrorKind{Code: CodeNotFound, HTTPStatus: http.StatusNotFound, UserFault: true}
+	ErrPermissionDenied = &ErrorKind{Code: CodePermissionDenied, HTTPStatus: http.StatusForbidden, UserFault: true}
+	ErrInvalidState     = &ErrorKind{Code: CodeInvalidStateTransition, HTTPStatus: http.StatusConflict, UserFault: true}
+	ErrConflict         = &ErrorKind{Code: CodeConcurrentModification, HTTPStatus: http.StatusConflict}
+	ErrInvalidRequest   = &ErrorKind{Code: CodeInvalidRequest, HTTPStatus: http.StatusUnprocessableEntity, UserFault: true}
+	ErrInternal         = &ErrorKind{Code: CodeInternal, HTTPStatus: http.StatusInternalServerError}
+)
+
+// errorKinds 所有错误类别，按代码查找类别时使用
+var errorKinds = []*ErrorKind{ErrNotFound, ErrPermissionDenied, ErrInvalidState, ErrConflict, ErrInvalidRequest, ErrInternal}
+
+// Is 让 errors.Is 可以按类别匹配带失败代码的引擎错误
+func (e *EngineError) Is(target error) bool {
+	kind, ok := target.(*ErrorKind)
+	return ok && kindOfCode(e.Code) == kind
+}
+
+// ErrorKindOf 返回错误所属的类别，无法归类的错误视为系统故障
+func ErrorKindOf(err error) *ErrorKind {
+	if code := FailureCodeOf(err); code != "" {
+		return kindOfCode(code)
+	}
+
+	var kind *ErrorKind
+	var enumErr *model.EnumError
+	switch {
+	case errors.As(err, &kind):
+		return kind
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, repository.ErrInstanceVersionConflict):
+		return ErrConflict
+	case errors.Is(err, repository.ErrTaskNotClaimable), errors.Is(err, repository.ErrInstanceNotTerminal):
+		return ErrInvalidState
+	case errors.As(err, &enumErr):
+		return ErrInvalidRequest
+	}
+	return ErrInternal
+}
+
+// kindOfCode 失败代码所属的类别，代码不是类别本身的代码时按目录中的状态码归类
+func kindOfCode(code string) *ErrorKind {
+	for _, kind := range errorKinds {
+		if kind.Code == code {
+			return kind
+		}
+	}
+
+	entry, ok := LookupFailureCode(code)
+	if !ok {
+		return ErrInternal
+	}
+	switch entry.HTTPStatus {
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusForbidden:
+		return ErrPermissionDenied
+	case http.StatusConflict:
+		return ErrInvalidState
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return ErrInvalidRequest
+	}
+	return ErrInternal
+}
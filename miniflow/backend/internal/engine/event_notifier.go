@@ -0,0 +1,116 @@
+package engine
+
+import (
+	"context"
+
+	"miniflow/internal/model"
+	"miniflow/internal/notification"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// EventNotifier 把引擎事件转换为用户通知：任务分配给用户时通知处理人，超期任务升级到用户组时通知组内成员，
+// 流程完成时通知发起人
+//
+// 通知按用户的通知偏好投递，站内通知写入用户的收件箱，前端据此显示通知铃铛。
+type EventNotifier struct {
+	engine     *ProcessEngine
+	dispatcher *notification.Dispatcher
+	logger     *logger.Logger
+}
+
+// NewEventNotifier 创建引擎事件通知器
+func NewEventNotifier(engine *ProcessEngine, dispatcher *notification.Dispatcher, logger *logger.Logger) *EventNotifier {
+	return &EventNotifier{
+		engine:     engine,
+		dispatcher: dispatcher,
+		logger:     logger,
+	}
+}
+
+// Start 订阅引擎事件并发送通知，直到 ctx 取消；只应调用一次
+func (n *EventNotifier) Start(ctx context.Context) {
+	n.engine.events.Subscribe(EventTaskAssigned, func(event Event) {
+		if ctx.Err() != nil {
+			return
+		}
+		n.notifyAssignee(ctx, event)
+	})
+	n.engine.events.Subscribe(EventTaskOverdue, func(event Event) {
+		if ctx.Err() != nil {
+			return
+		}
+		n.notifyGroup(ctx, event)
+	})
+	n.engine.events.Subscribe(EventProcessCompleted, func(event Event) {
+		if ctx.Err() != nil {
+			return
+		}
+		n.notifyStarter(ctx, event)
+	})
+
+	<-ctx.Done()
+}
+
+// notifyAssignee 通知任务的处理人，自己分配给自己的任务不通知
+func (n *EventNotifier) notifyAssignee(ctx context.Context, event Event) {
+	task, err := n.engine.taskRepo.GetByID(ctx, event.TaskID)
+	if err != nil || task.AssigneeID == nil || *task.AssigneeID == event.UserID {
+		return
+	}
+
+	data := notification.NewTemplateData(task.Assignee, &task.Instance, task)
+	if err := n.dispatcher.Notify(ctx, *task.AssigneeID, model.NotificationEventTaskAssigned, data); err != nil {
+		n.logger.Warn("Failed to notify task assignee",
+			zap.Uint("task_id", task.ID),
+			zap.Uint("user_id", *task.AssigneeID),
+			zap.Error(err),
+		)
+	}
+}
+
+// notifyGroup 通知升级到用户组的超期任务的组内成员，成员从任务池认领任务
+func (n *EventNotifier) notifyGroup(ctx context.Context, event Event) {
+	members, ok := event.Data["candidate_ids"].([]uint)
+	if !ok || len(members) == 0 {
+		return
+	}
+	task, err := n.engine.taskRepo.GetByID(ctx, event.TaskID)
+	if err != nil {
+		return
+	}
+
+	for _, memberID := range members {
+		member, err := n.engine.userRepo.GetByID(ctx, memberID)
+		if err != nil {
+			continue
+		}
+		data := notification.NewTemplateData(member, &task.Instance, task)
+		if err := n.dispatcher.Notify(ctx, memberID, model.NotificationEventTaskOverdue, data); err != nil {
+			n.logger.Warn("Failed to notify escalation group member",
+				zap.Uint("task_id", task.ID),
+				zap.Uint("user_id", memberID),
+				zap.Any("group", event.Data["group"]),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// notifyStarter 通知流程实例的发起人
+func (n *EventNotifier) notifyStarter(ctx context.Context, event Event) {
+	instance, err := n.engine.instanceRepo.GetByID(ctx, event.InstanceID)
+	if err != nil || instance.StarterID == 0 {
+		return
+	}
+
+	data := notification.NewTemplateData(&instance.Starter, instance, nil)
+	if err := n.dispatcher.Notify(ctx, instance.StarterID, model.NotificationEventProcessCompleted, data); err != nil {
+		n.logger.Warn("Failed to notify process starter",
+			zap.Uint("instance_id", instance.ID),
+			zap.Uint("user_id", instance.StarterID),
+			zap.Error(err),
+		)
+	}
+}
@@ -0,0 +1,164 @@
+package engine
+
+import (
+	"sync"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// 引擎事件类型
+const (
+	EventProcessStarted   = "process.started"
+	EventProcessCompleted = "process.completed"
+	EventProcessSuspended = "process.suspended"
+	EventProcessResumed   = "process.resumed"
+	EventProcessCancelled = "process.cancelled"
+	EventProcessMigrated  = "process.migrated"
+	EventProcessRestored  = "process.restored"
+	EventProcessModified  = "process.modified"
+	EventProcessPurged    = "process.purged"
+	EventProcessArchived  = "process.archived"
+
+	EventTaskCreated   = "task.created"
+	EventTaskAssigned  = "task.assigned"
+	EventTaskClaimed   = "task.claimed"
+	EventTaskCompleted = "task.completed"
+	EventTaskSkipped   = "task.skipped"
+	EventTaskOverdue   = "task.overdue"
+)
+
+// EventTypes 引擎事件类型的取值集合，用于校验事件订阅
+var EventTypes = model.Enum{Name: "event type", Values: []string{
+	EventProcessStarted, EventProcessCompleted, EventProcessSuspended, EventProcessResumed, EventProcessCancelled, EventProcessMigrated, EventProcessRestored, EventProcessModified, EventProcessPurged, EventProcessArchived,
+	EventTaskCreated, EventTaskAssigned, EventTaskClaimed, EventTaskCompleted, EventTaskSkipped, EventTaskOverdue,
+}}
+
+// Event 引擎发布的事件
+// UserID 为触发事件的用户，0 表示由系统触发
+type Event struct {
+	Type       string                 `json:"type"`
+	InstanceID uint                   `json:"instance_id"`
+	TaskID     uint                   `json:"task_id,omitempty"`
+	NodeID     string                 `json:"node_id,omitempty"`
+	UserID     uint                   `json:"user_id,omitempty"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+	Timestamp  time.Time              `json:"timestamp"`
+}
+
+// EventHandler 事件处理函数，在独立的 goroutine 中执行
+type EventHandler func(event Event)
+
+// EventSystem 进程内的事件发布订阅
+//
+// 事件在引擎的操作成功后发布，处理函数异步执行，不阻塞流程推进，
+// 处理函数的错误和 panic 不影响引擎。订阅只在内存中，服务重启后需要重新订阅。
+type EventSystem struct {
+	mu          sync.RWMutex
+	handlers    map[string][]EventHandler
+	allHandlers []EventHandler
+	logger      *logger.Logger
+}
+
+// NewEventSystem 创建事件系统
+func NewEventSystem(logger *logger.Logger) *EventSystem {
+	return &EventSystem{
+		handlers: make(map[string][]EventHandler),
+		logger:   logger,
+	}
+}
+
+// Subscribe 订阅指定类型的事件
+func (s *EventSystem) Subscribe(eventType string, handler EventHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[eventType] = append(s.handlers[eventType], handler)
+}
+
+// SubscribeAll 订阅所有类型的事件
+func (s *EventSystem) SubscribeAll(handler EventHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.allHandlers = append(s.allHandlers, handler)
+}
+
+// Publish 发布事件，异步调用所有订阅者
+func (s *EventSystem) Publish(event Event) {
+	if s == nil {
+		return
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	s.mu.RLock()
+	handlers := make([]EventHandler, 0, len(s.handlers[event.Type])+len(s.allHandlers))
+	handlers = append(handlers, s.handlers[event.Type]...)
+	handlers = append(handlers, s.allHandlers...)
+	s.mu.RUnlock()
+
+	s.logger.Debug("Publishing engine event",
+		zap.String("type", event.Type),
+		zap.Uint("instance_id", event.InstanceID),
+		zap.Uint("task_id", event.TaskID),
+		zap.Int("subscribers", len(handlers)),
+	)
+
+	for _, handler := range handlers {
+		go s.dispatch(handler, event)
+	}
+}
+
+// dispatch 调用单个订阅者，订阅者 panic 时只记录日志
+func (s *EventSystem) dispatch(handler EventHandler, event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("Event handler panicked",
+				zap.String("type", event.Type),
+				zap.Uint("instance_id", event.InstanceID),
+				zap.Any("panic", r),
+			)
+		}
+	}()
+	handler(event)
+}
+
+// publishInstanceEvent 发布流程实例事件
+func (e *ProcessEngine) publishInstanceEvent(eventType string, instance *model.ProcessInstance, userID uint, data map[string]interface{}) {
+	e.events.Publish(Event{
+		Type:       eventType,
+		InstanceID: instance.ID,
+		NodeID:     instance.CurrentNode,
+		UserID:     userID,
+		Data:       data,
+	})
+}
+
+// publishTaskEvent 发布任务事件
+func (e *ProcessEngine) publishTaskEvent(eventType string, task *model.TaskInstance, userID uint, data map[string]interface{}) {
+	e.events.Publish(Event{
+		Type:       eventType,
+		InstanceID: task.InstanceID,
+		TaskID:     task.ID,
+		NodeID:     task.NodeID,
+		UserID:     userID,
+		Data:       data,
+	})
+}
+
+// taskAssignedEvent 构建任务分配事件，Data 中带处理人
+func taskAssignedEvent(task *model.TaskInstance) Event {
+	event := Event{
+		Type:       EventTaskAssigned,
+		InstanceID: task.InstanceID,
+		TaskID:     task.ID,
+		NodeID:     task.NodeID,
+	}
+	if task.AssigneeID != nil {
+		event.Data = map[string]interface{}{"assignee_id": *task.AssigneeID}
+	}
+	return event
+}
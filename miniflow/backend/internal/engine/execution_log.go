@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"context"
+	"time"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// startExecutionLog 记录服务任务开始执行，写入失败只记录日志，不影响任务执行
+func (e *ProcessEngine) startExecutionLog(ctx context.Context, instance *model.ProcessInstance, task *model.TaskInstance, node *model.ProcessNode, req *ServiceRequest) *model.ExecutionLog {
+	attempts, err := e.executionLogRepo.CountByInstanceAndNode(ctx, instance.ID, node.ID)
+	if err != nil {
+		e.logger.Warn("Failed to count execution attempts", zap.Uint("task_id", task.ID), zap.Error(err))
+	}
+
+	log := &model.ExecutionLog{
+		TaskID:     task.ID,
+		InstanceID: instance.ID,
+		NodeID:     node.ID,
+		Attempt:    int(attempts) + 1,
+		Connector:  req.Connector,
+		Method:     req.Method,
+		URL:        req.URL,
+		Status:     model.ExecutionStatusRunning,
+		Request:    model.TruncateExecutionContent(string(req.Body), model.MaxExecutionRequestLength),
+		StartTime:  time.Now(),
+	}
+	if err := e.executionLogRepo.Create(ctx, log); err != nil {
+		return nil
+	}
+	return log
+}
+
+// finishExecutionLog 记录服务任务的执行结果和耗时
+func (e *ProcessEngine) finishExecutionLog(ctx context.Context, log *model.ExecutionLog, result *ServiceResult, execErr error) {
+	if log == nil {
+		return
+	}
+
+	now := time.Now()
+	log.EndTime = &now
+	log.DurationMs = now.Sub(log.StartTime).Milliseconds()
+	log.Status = model.ExecutionStatusSucceeded
+	if result != nil {
+		log.StatusCode = result.StatusCode
+		log.Response = result.Body
+		log.Mocked = result.Mocked
+	}
+	if execErr != nil {
+		log.Status = model.ExecutionStatusFailed
+		log.Error = execErr.Error()
+		log.ErrorCode = FailureCodeOf(execErr)
+	}
+
+	_ = e.executionLogRepo.Update(ctx, log)
+}
+
+// GetTaskExecutionLogs 获取服务任务的执行日志
+func (e *ProcessEngine) GetTaskExecutionLogs(ctx context.Context, taskID uint) ([]model.ExecutionLog, error) {
+	return e.executionLogRepo.GetByTask(ctx, taskID)
+}
@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/config"
+
+	"go.uber.org/zap"
+)
+
+// executionTrace 开启跟踪的实例的轨迹记录器
+// 实例未开启跟踪时为 nil，nil 上的调用不做任何事，调用方无需判断
+type executionTrace struct {
+	engine     *ProcessEngine
+	instanceID uint
+}
+
+// traceFor 返回实例的轨迹记录器，未开启跟踪时返回 nil
+func (e *ProcessEngine) traceFor(instance *model.ProcessInstance) *executionTrace {
+	if instance == nil || !instance.TraceEnabled {
+		return nil
+	}
+	return &executionTrace{engine: e, instanceID: instance.ID}
+}
+
+// record 写入一条跟踪记录，写入失败只记录日志，不影响流程推进
+func (t *executionTrace) record(ctx context.Context, category, nodeID string, data map[string]interface{}, format string, args ...interface{}) {
+	if t == nil {
+		return
+	}
+
+	message := fmt.Sprintf(format, args...)
+	if runes := []rune(message); len(runes) > model.MaxTraceMessageLength {
+		message = string(runes[:model.MaxTraceMessageLength])
+	}
+	trace := &model.ExecutionTrace{
+		InstanceID: t.instanceID,
+		Category:   category,
+		NodeID:     nodeID,
+		Message:    message,
+	}
+	if len(data) > 0 {
+		if encoded, err := json.Marshal(data); err == nil {
+			trace.Data = string(encoded)
+		}
+	}
+
+	if err := t.engine.instanceRepo.CreateTrace(ctx, trace); err != nil {
+		t.engine.logger.Warn("Failed to record execution trace",
+			zap.Uint("instance_id", t.instanceID),
+			zap.String("category", category),
+			zap.Error(err),
+		)
+	}
+}
+
+// checkTracePermission 检查用户可以开启和查看执行跟踪
+func (e *ProcessEngine) checkTracePermission(ctx context.Context, userID uint) error {
+	return e.checkPermission(ctx, userID, config.PermissionInstanceTrace, "使用执行跟踪")
+}
+
+// checkPermission 检查用户的角色在权限矩阵中被授予 permission，action 用于错误信息
+func (e *ProcessEngine) checkPermission(ctx context.Context, userID uint, permission, action string) error {
+	user, err := e.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("获取用户失败: %w", err)
+	}
+	if !e.rbac.Allows(permission, user.Role) {
+		return newEngineError(CodePermissionDenied, nil, "没有%s的权限", action)
+	}
+	return nil
+}
+
+// GetInstanceTrace 获取流程实例的执行跟踪记录，需要 instance_trace 权限
+func (e *ProcessEngine) GetInstanceTrace(ctx context.Context, instanceID, userID uint) ([]model.ExecutionTrace, error) {
+	if err := e.checkInstanceTrace(ctx, instanceID, userID); err != nil {
+		return nil, err
+	}
+
+	traces, err := e.instanceRepo.GetTraces(ctx, instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("获取执行跟踪失败: %w", err)
+	}
+	return traces, nil
+}
+
+// EachInstanceTrace 逐条读取流程实例的执行跟踪记录，用于流式输出；权限和开启状态在读取前检查
+func (e *ProcessEngine) EachInstanceTrace(ctx context.Context, instanceID, userID uint, fn func(*model.ExecutionTrace) error) error {
+	if err := e.checkInstanceTrace(ctx, instanceID, userID); err != nil {
+		return err
+	}
+	return e.instanceRepo.EachTrace(ctx, instanceID, fn)
+}
+
+// checkInstanceTrace 检查用户可以查看执行跟踪并且流程实例开启了跟踪
+func (e *ProcessEngine) checkInstanceTrace(ctx context.Context, instanceID, userID uint) error {
+	if err := e.checkTracePermission(ctx, userID); err != nil {
+		return err
+	}
+
+	instance, err := e.instanceRepo.GetByID(ctx, instanceID)
+	if err != nil {
+		return err
+	}
+	if !instance.TraceEnabled {
+		return newEngineError(CodeTraceNotEnabled, nil, "流程实例 %d 未开启执行跟踪", instanceID)
+	}
+	return nil
+}
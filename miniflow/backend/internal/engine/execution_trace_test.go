@@ -0,0 +1,36 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"miniflow/pkg/config"
+)
+
+func TestTracePermissionFollowsRBACConfig(t *testing.T) {
+	e, db := newTestEngine(t)
+	admin := createTestUser(t, db, "root", "admin")
+	manager := createTestUser(t, db, "mary", "manager")
+	definition := publishTestDefinition(t, db, "sequence", admin.ID, sequenceDefinition())
+
+	// 跟踪权限授予 manager 而不是 admin，检查不能依赖角色名
+	e.rbac = &config.RBACConfig{Permissions: map[string][]string{
+		config.PermissionInstanceTrace: {"manager"},
+	}}
+
+	req := &StartProcessRequest{DefinitionID: definition.ID, BusinessKey: "traced", Trace: true}
+	if _, err := e.StartProcess(context.Background(), req, admin.ID); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("admin started a traced instance without instance_trace: %v", err)
+	}
+	instance, err := e.StartProcess(context.Background(), req, manager.ID)
+	if err != nil {
+		t.Fatalf("manager start traced instance: %v", err)
+	}
+	if _, err := e.GetInstanceTrace(context.Background(), instance.ID, manager.ID); err != nil {
+		t.Fatalf("manager read trace: %v", err)
+	}
+	if _, err := e.GetInstanceTrace(context.Background(), instance.ID, admin.ID); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("admin read trace without instance_trace: %v", err)
+	}
+}
@@ -0,0 +1,269 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// 外部处理程序单次获取任务的限制
+const (
+	maxExternalTasksPerFetch = 100
+	maxExternalTaskLock      = 24 * time.Hour
+)
+
+// ExternalTask 外部处理程序获取并锁定的任务，定义不允许导出数据时不携带流程变量
+type ExternalTask struct {
+	ID            uint                   `json:"id"`
+	Topic         string                 `json:"topic"`
+	WorkerID      string                 `json:"worker_id"`
+	LockExpiresAt time.Time              `json:"lock_expires_at"`
+	Retries       int                    `json:"retries"`
+	ErrorMessage  string                 `json:"error_message,omitempty"`
+	InstanceID    uint                   `json:"instance_id"`
+	BusinessKey   string                 `json:"business_key"`
+	NodeID        string                 `json:"node_id"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// handleExternalTask 为外部服务任务节点创建等待外部处理程序获取的任务，流程停留在该节点直到任务完成
+//
+// 外部任务的状态为 in_progress 且没有处理人，不会出现在用户的任务池中，也不能被用户认领。
+func (e *ProcessEngine) handleExternalTask(ctx context.Context, instance *model.ProcessInstance, node *model.ProcessNode) error {
+	cfg, err := model.GetExternalTaskConfig(node)
+	if err != nil {
+		return newEngineError(CodeInvalidDefinition, err, "节点 %s 的外部任务配置无效", node.ID)
+	}
+
+	task := &model.TaskInstance{
+		InstanceID: instance.ID,
+		NodeID:     node.ID,
+		Name:       node.Name,
+		Status:     model.TaskStatusInProgress,
+		Priority:   50, // 默认优先级
+		Topic:      cfg.Topic,
+		Retries:    cfg.Retries,
+	}
+	if err := e.taskRepo.Create(ctx, task); err != nil {
+		return fmt.Errorf("创建外部任务失败: %v", err)
+	}
+	e.traceFor(instance).record(ctx, model.TraceCategoryWrite, node.ID, map[string]interface{}{
+		"task_id": task.ID,
+		"topic":   task.Topic,
+	}, "创建外部任务 %d，主题 %s", task.ID, task.Topic)
+
+	e.logger.Info("External task created",
+		zap.Uint("instance_id", instance.ID),
+		zap.Uint("task_id", task.ID),
+		zap.String("topic", task.Topic),
+	)
+	return nil
+}
+
+// FetchAndLockExternalTasks 为外部处理程序获取并锁定指定主题下的任务，最多 maxTasks 个
+//
+// 锁定期间其他处理程序获取不到该任务；处理程序需要在锁到期前完成任务或报告失败，锁过期的任务
+// 可以被任何处理程序重新获取。暂停或已结束的实例上的任务不会被获取。
+func (e *ProcessEngine) FetchAndLockExternalTasks(ctx context.Context, workerID string, topics []string, maxTasks int, lockDuration time.Duration) ([]ExternalTask, error) {
+	workerID = strings.TrimSpace(workerID)
+	if workerID == "" {
+		return nil, newEngineError(CodeInvalidRequest, nil, "外部处理程序ID不能为空")
+	}
+	normalized := make([]string, 0, len(topics))
+	for _, topic := range topics {
+		if topic = strings.ToLower(strings.TrimSpace(topic)); topic != "" {
+			normalized = append(normalized, topic)
+		}
+	}
+	if len(normalized) == 0 {
+		return nil, newEngineError(CodeInvalidRequest, nil, "至少需要指定一个主题")
+	}
+	if maxTasks < 1 || maxTasks > maxExternalTasksPerFetch {
+		return nil, newEngineError(CodeInvalidRequest, nil, "单次获取的任务数必须在 1 到 %d 之间", maxExternalTasksPerFetch)
+	}
+	if lockDuration <= 0 || lockDuration > maxExternalTaskLock {
+		return nil, newEngineError(CodeInvalidRequest, nil, "锁定时长必须大于 0 且不超过 %s", maxExternalTaskLock)
+	}
+
+	now := time.Now()
+	candidates, err := e.taskRepo.GetAvailableExternalTasks(ctx, normalized, now, maxTasks)
+	if err != nil {
+		return nil, fmt.Errorf("获取外部任务失败: %w", err)
+	}
+
+	fetched := make([]ExternalTask, 0, len(candidates))
+	for _, task := range candidates {
+		instance, err := e.instanceRepo.GetByID(ctx, task.InstanceID)
+		if err != nil {
+			e.logger.Error("Failed to get external task instance", zap.Uint("task_id", task.ID), zap.Error(err))
+			continue
+		}
+		if instance.Status != model.InstanceStatusRunning {
+			continue
+		}
+
+		variables := make(map[string]interface{})
+		if instance.Definition.DataPolicy().AllowExport {
+			if variables, err = decodeInstanceVariables(instance); err != nil {
+				e.logger.Error("Failed to decode external task variables", zap.Uint("task_id", task.ID), zap.Error(err))
+				continue
+			}
+		}
+
+		lockExpiresAt := now.Add(lockDuration)
+		locked, err := e.taskRepo.LockExternalTask(ctx, task.ID, workerID, now, lockExpiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("锁定外部任务失败: %w", err)
+		}
+		if !locked {
+			continue // 已被其他处理程序并发锁定
+		}
+
+		fetched = append(fetched, ExternalTask{
+			ID:            task.ID,
+			Topic:         task.Topic,
+			WorkerID:      workerID,
+			LockExpiresAt: lockExpiresAt,
+			Retries:       task.Retries,
+			ErrorMessage:  task.Comment,
+			InstanceID:    instance.ID,
+			BusinessKey:   instance.BusinessKey,
+			NodeID:        task.NodeID,
+			Variables:     variables,
+		})
+	}
+
+	if len(fetched) > 0 {
+		e.logger.Info("External tasks locked",
+			zap.String("worker_id", workerID),
+			zap.Strings("topics", normalized),
+			zap.Int("count", len(fetched)),
+		)
+	}
+	return fetched, nil
+}
+
+// lockedExternalTask 获取仍由外部处理程序锁定的任务及其流程实例
+func (e *ProcessEngine) lockedExternalTask(ctx context.Context, taskID uint, workerID string) (*model.TaskInstance, *model.ProcessInstance, error) {
+	task, err := e.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取外部任务失败: %w", err)
+	}
+	if task.Topic == "" {
+		return nil, nil, newEngineError(CodeNotFound, nil, "任务 %d 不是外部任务", taskID)
+	}
+	if task.Status != model.TaskStatusInProgress || task.WorkerID != workerID || task.LockExpiresAt == nil || !task.LockExpiresAt.After(time.Now()) {
+		return nil, nil, newEngineError(CodeExternalTaskNotLocked, nil, "外部任务 %d 未被处理程序 %s 锁定或锁已过期", taskID, workerID)
+	}
+
+	instance, err := e.instanceRepo.GetByID(ctx, task.InstanceID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取流程实例失败: %w", err)
+	}
+	if instance.Status != model.InstanceStatusRunning {
+		return nil, nil, newEngineError(CodeInvalidStateTransition, nil, "流程实例不在运行中，当前状态为 %s", instance.Status)
+	}
+	return task, instance, nil
+}
+
+// CompleteExternalTask 完成外部处理程序锁定的任务，variables 合并到流程变量后推进流程
+func (e *ProcessEngine) CompleteExternalTask(ctx context.Context, taskID uint, workerID string, variables map[string]interface{}) error {
+	task, instance, err := e.lockedExternalTask(ctx, taskID, workerID)
+	if err != nil {
+		return err
+	}
+
+	if len(variables) > 0 {
+		current, err := decodeInstanceVariables(instance)
+		if err != nil {
+			return err
+		}
+		for key, value := range variables {
+			current[key] = value
+		}
+		if err := e.saveInstanceVariables(ctx, instance, current); err != nil {
+			return err
+		}
+	}
+
+	completed, err := e.taskRepo.CompleteExternalTask(ctx, taskID, workerID, time.Now())
+	if err != nil {
+		return fmt.Errorf("更新外部任务状态失败: %v", err)
+	}
+	if !completed {
+		return newEngineError(CodeExternalTaskNotLocked, nil, "外部任务 %d 的锁已失效", taskID)
+	}
+
+	e.traceFor(instance).record(ctx, model.TraceCategoryNode, task.NodeID, map[string]interface{}{
+		"task_id":   task.ID,
+		"worker_id": workerID,
+		"variables": len(variables),
+	}, "外部处理程序 %s 完成外部任务 %d", workerID, task.ID)
+	e.publishTaskEvent(EventTaskCompleted, task, 0, map[string]interface{}{"worker_id": workerID})
+
+	e.logger.Info("External task completed",
+		zap.Uint("task_id", taskID),
+		zap.String("worker_id", workerID),
+	)
+	return e.checkAndAdvanceProcess(ctx, instance, task.NodeID)
+}
+
+// HandleExternalTaskFailure 记录外部处理程序报告的失败
+//
+// retries 为剩余的重试次数，为空时在任务当前的重试次数上减一。还有重试次数时释放锁，任务在
+// retryTimeout 之后可以再次获取；重试次数用尽时任务失败并生成异常事件，重试异常事件会创建新的外部任务。
+func (e *ProcessEngine) HandleExternalTaskFailure(ctx context.Context, taskID uint, workerID, errorMessage string, retries *int, retryTimeout time.Duration) error {
+	task, instance, err := e.lockedExternalTask(ctx, taskID, workerID)
+	if err != nil {
+		return err
+	}
+
+	remaining := task.Retries - 1
+	if retries != nil {
+		remaining = *retries
+	}
+	errorMessage = model.TruncateExecutionContent(errorMessage, model.MaxExecutionResponseLength)
+	now := time.Now()
+
+	if remaining > 0 {
+		released, err := e.taskRepo.ReleaseExternalTask(ctx, taskID, workerID, now, remaining, errorMessage, now.Add(retryTimeout))
+		if err != nil {
+			return fmt.Errorf("更新外部任务状态失败: %v", err)
+		}
+		if !released {
+			return newEngineError(CodeExternalTaskNotLocked, nil, "外部任务 %d 的锁已失效", taskID)
+		}
+		e.logger.Warn("External task failed, will be retried",
+			zap.Uint("task_id", taskID),
+			zap.String("worker_id", workerID),
+			zap.Int("retries", remaining),
+			zap.Duration("retry_timeout", retryTimeout),
+			zap.String("error", errorMessage),
+		)
+		return nil
+	}
+
+	failed, err := e.taskRepo.FailExternalTask(ctx, taskID, workerID, now, errorMessage)
+	if err != nil {
+		return fmt.Errorf("更新外部任务状态失败: %v", err)
+	}
+	if !failed {
+		return newEngineError(CodeExternalTaskNotLocked, nil, "外部任务 %d 的锁已失效", taskID)
+	}
+
+	definitionData, err := instance.Definition.GetDefinitionData()
+	if err != nil {
+		return newEngineError(CodeInvalidDefinition, err, "解析流程定义失败")
+	}
+	node := e.findNodeByID(definitionData.Nodes, task.NodeID)
+	if node == nil {
+		return newEngineError(CodeNodeNotFound, nil, "找不到节点: %s", task.NodeID)
+	}
+	cause := newEngineError(CodeExternalTaskFailed, nil, "外部处理程序 %s 报告失败: %s", workerID, errorMessage)
+	return e.raiseIncident(ctx, instance, task, node, model.IncidentTypeExternalFailed, cause)
+}
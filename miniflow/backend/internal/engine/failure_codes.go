@@ -0,0 +1,161 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"miniflow/internal/model"
+)
+
+// 引擎失败代码，供界面和告警按代码而不是中文消息处理失败
+const (
+	CodeDefinitionNotFound     = "DEFINITION_NOT_FOUND"
+	CodeInvalidDefinition      = "INVALID_DEFINITION"
+	CodeNoStartNode            = "NO_START_NODE"
+	CodeNoOutgoingFlow         = "NO_OUTGOING_FLOW"
+	CodeNodeNotFound           = "NODE_NOT_FOUND"
+	CodeUnsupportedNodeType    = "UNSUPPORTED_NODE_TYPE"
+	CodeGatewayNoPath          = "GATEWAY_NO_PATH"
+	CodeConditionFailed        = "CONDITION_EVALUATION_FAILED"
+	CodeInvalidStateTransition = "INVALID_STATE_TRANSITION"
+	CodeConcurrentModification = "CONCURRENT_MODIFICATION"
+	CodePermissionDenied       = "PERMISSION_DENIED"
+	CodeTraceNotEnabled        = "TRACE_NOT_ENABLED"
+	CodeInvalidSnapshot        = "INVALID_SNAPSHOT"
+	CodeSnapshotReference      = "SNAPSHOT_REFERENCE_MISSING"
+	CodeMigrationInvalid       = "MIGRATION_INVALID"
+	CodeUndoWindowExpired      = "UNDO_WINDOW_EXPIRED"
+	CodeVisitLimitExceeded     = "VISIT_LIMIT_EXCEEDED"
+	CodeTaskAlreadyCompleted   = "TASK_ALREADY_COMPLETED"
+	CodeNoReturnTarget         = "NO_RETURN_TARGET"
+	CodeAssignmentFailed       = "ASSIGNMENT_FAILED"
+	CodeConnectorPolicy        = "CONNECTOR_POLICY_VIOLATION"
+	CodeServiceRequestInvalid  = "SERVICE_REQUEST_INVALID"
+	CodeServiceTimeout         = "SERVICE_TIMEOUT"
+	CodeServiceUnavailable     = "SERVICE_UNAVAILABLE"
+	CodeServiceErrorResponse   = "SERVICE_ERROR_RESPONSE"
+	CodeScriptFailed           = "SCRIPT_FAILED"
+	CodeScriptTimeout          = "SCRIPT_TIMEOUT"
+	CodeMailFailed             = "MAIL_FAILED"
+	CodeExternalTaskFailed     = "EXTERNAL_TASK_FAILED"
+	CodeExternalTaskNotLocked  = "EXTERNAL_TASK_NOT_LOCKED"
+	CodeRecoveryRequired       = "RECOVERY_REQUIRED"
+	CodeMessageNotCorrelated   = "MESSAGE_NOT_CORRELATED"
+	CodeNotFound               = "NOT_FOUND"
+	CodeInvalidRequest         = "INVALID_REQUEST"
+	CodeInternal               = "INTERNAL_ERROR"
+)
+
+// 失败代码分类
+const (
+	FailureCategoryDefinition = "definition"
+	FailureCategoryExecution  = "execution"
+	FailureCategoryTask       = "task"
+	FailureCategoryService    = "service"
+)
+
+// FailureCode 失败代码目录条目
+type FailureCode struct {
+	Code        string `json:"code"`
+	Category    string `json:"category"`
+	HTTPStatus  int    `json:"http_status"`
+	Retryable   bool   `json:"retryable"`
+	Description string `json:"description"`
+}
+
+// FailureCatalog 所有引擎失败代码，Retryable 表示异常事件可以通过重试恢复
+var FailureCatalog = []FailureCode{
+	{CodeDefinitionNotFound, FailureCategoryDefinition, http.StatusNotFound, false, "The process definition does not exist"},
+	{CodeInvalidDefinition, FailureCategoryDefinition, http.StatusBadRequest, false, "The process definition JSON cannot be parsed"},
+	{CodeNoStartNode, FailureCategoryDefinition, http.StatusBadRequest, false, "The process definition has no start node"},
+	{CodeNoOutgoingFlow, FailureCategoryDefinition, http.StatusBadRequest, false, "A node has no outgoing flow to continue on"},
+	{CodeNodeNotFound, FailureCategoryDefinition, http.StatusBadRequest, false, "A flow points to a node that is not in the definition"},
+	{CodeUnsupportedNodeType, FailureCategoryDefinition, http.StatusBadRequest, false, "The engine cannot execute the node type"},
+	{CodeGatewayNoPath, FailureCategoryExecution, http.StatusUnprocessableEntity, false, "No outgoing flow of a gateway matched the process variables and there is no default flow"},
+	{CodeConditionFailed, FailureCategoryExecution, http.StatusUnprocessableEntity, false, "A gateway flow condition could not be evaluated against the process variables"},
+	{CodeInvalidStateTransition, FailureCategoryExecution, http.StatusConflict, false, "The instance status does not allow the operation"},
+	{CodeConcurrentModification, FailureCategoryExecution, http.StatusConflict, true, "The instance kept being modified concurrently and the update was not saved"},
+	{CodePermissionDenied, FailureCategoryExecution, http.StatusForbidden, false, "The user is not allowed to perform the operation"},
+	{CodeTraceNotEnabled, FailureCategoryExecution, http.StatusNotFound, false, "Execution tracing was not enabled when the instance was started"},
+	{CodeInvalidSnapshot, FailureCategoryExecution, http.StatusBadRequest, false, "The runtime snapshot has an unsupported format or fails its digest check"},
+	{CodeSnapshotReference, FailureCategoryExecution, http.StatusUnprocessableEntity, false, "The runtime snapshot references definitions or users missing in this environment"},
+	{CodeMigrationInvalid, FailureCategoryExecution, http.StatusUnprocessableEntity, false, "The instance's active nodes cannot be mapped onto the target definition version"},
+	{CodeUndoWindowExpired, FailureCategoryExecution, http.StatusConflict, false, "The instance was cancelled longer ago than the undo window allows"},
+	{CodeVisitLimitExceeded, FailureCategoryExecution, http.StatusUnprocessableEntity, true, "A user task was entered more often than its visit limit allows and has no escalation flow"},
+	{CodeTaskAlreadyCompleted, FailureCategoryTask, http.StatusConflict, false, "The task has already been completed"},
+	{CodeNoReturnTarget, FailureCategoryTask, http.StatusConflict, false, "The task cannot be sent back: no earlier user task was completed or other branches are still open"},
+	{CodeAssignmentFailed, FailureCategoryTask, http.StatusUnprocessableEntity, true, "The assignee expression could not be resolved to an active user"},
+	{CodeConnectorPolicy, FailureCategoryService, http.StatusForbidden, true, "The service task connector or host is not in the definition's allowlist"},
+	{CodeServiceRequestInvalid, FailureCategoryService, http.StatusBadRequest, false, "The service task request could not be built from the node properties"},
+	{CodeServiceTimeout, FailureCategoryService, http.StatusGatewayTimeout, true, "The service call did not respond in time"},
+	{CodeServiceUnavailable, FailureCategoryService, http.StatusBadGateway, true, "The service could not be reached"},
+	{CodeServiceErrorResponse, FailureCategoryService, http.StatusBadGateway, true, "The service responded with a non-2xx status"},
+	{CodeScriptFailed, FailureCategoryService, http.StatusUnprocessableEntity, true, "The script task failed or its language has no runtime installed"},
+	{CodeScriptTimeout, FailureCategoryService, http.StatusGatewayTimeout, true, "The script task did not finish within its timeout"},
+	{CodeMailFailed, FailureCategoryService, http.StatusBadGateway, true, "The mail task could not render its email, has no mail sender, or the mail channel failed to send it"},
+	{CodeExternalTaskFailed, FailureCategoryService, http.StatusUnprocessableEntity, true, "An external worker reported a failure and the external task has no retries left"},
+	{CodeExternalTaskNotLocked, FailureCategoryService, http.StatusConflict, false, "The external task is not locked by the worker, or its lock has expired"},
+	{CodeRecoveryRequired, FailureCategoryExecution, http.StatusConflict, true, "The instance was left without pending work by an interrupted advancement and could not be repaired automatically"},
+	{CodeMessageNotCorrelated, FailureCategoryExecution, http.StatusNotFound, false, "No running instance is waiting for the message with the given correlation key"},
+	{CodeNotFound, FailureCategoryExecution, http.StatusNotFound, false, "The requested instance, task or other record does not exist"},
+	{CodeInvalidRequest, FailureCategoryExecution, http.StatusUnprocessableEntity, false, "The request is well-formed but cannot be applied, e.g. comparing an instance with itself"},
+	{CodeInternal, FailureCategoryExecution, http.StatusInternalServerError, true, "An unexpected system fault such as a database error; the operation may succeed when retried"},
+}
+
+// incidentTypeCodes 异常事件类型对应的默认失败代码，原因没有携带代码时使用
+var incidentTypeCodes = map[string]string{
+	model.IncidentTypeConnectorPolicy:  CodeConnectorPolicy,
+	model.IncidentTypeAssignmentFailed: CodeAssignmentFailed,
+	model.IncidentTypeServiceFailed:    CodeServiceUnavailable,
+	model.IncidentTypeConditionFailed:  CodeConditionFailed,
+	model.IncidentTypeVisitLimit:       CodeVisitLimitExceeded,
+	model.IncidentTypeScriptFailed:     CodeScriptFailed,
+	model.IncidentTypeExternalFailed:   CodeExternalTaskFailed,
+	model.IncidentTypeMailFailed:       CodeMailFailed,
+	model.IncidentTypeRecovery:         CodeRecoveryRequired,
+}
+
+// EngineError 带失败代码的引擎错误，错误消息保持原有的中文描述
+type EngineError struct {
+	Code    string
+	Message string
+	Cause   error
+}
+
+// Error implements the error interface
+func (e *EngineError) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Cause)
+	}
+	return e.Message
+}
+
+// Unwrap returns the underlying cause
+func (e *EngineError) Unwrap() error {
+	return e.Cause
+}
+
+// newEngineError 创建带失败代码的引擎错误
+func newEngineError(code string, cause error, format string, args ...interface{}) *EngineError {
+	return &EngineError{Code: code, Message: fmt.Sprintf(format, args...), Cause: cause}
+}
+
+// FailureCodeOf 返回错误链上的失败代码，没有时返回空字符串
+func FailureCodeOf(err error) string {
+	var engineErr *EngineError
+	if errors.As(err, &engineErr) {
+		return engineErr.Code
+	}
+	return ""
+}
+
+// LookupFailureCode 在目录中查找失败代码
+func LookupFailureCode(code string) (FailureCode, bool) {
+	for _, entry := range FailureCatalog {
+		if entry.Code == code {
+			return entry, true
+		}
+	}
+	return FailureCode{}, false
+}
@@ -0,0 +1,189 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// advanceAlongFlow 沿连线推进到目标节点；目标是汇聚的并行或包容网关时先记录到达，
+// 等所有入口连线都到达后才继续推进
+func (e *ProcessEngine) advanceAlongFlow(ctx context.Context, instance *model.ProcessInstance, flow model.ProcessFlow, definition *model.ProcessDefinitionData) error {
+	e.traceFor(instance).record(ctx, model.TraceCategoryFlow, flow.From, map[string]interface{}{"flow_id": flow.ID},
+		"沿连线 %s 从 %s 推进到 %s", flow.FlowKey(), flow.From, flow.To)
+
+	target := e.findNodeByID(definition.Nodes, flow.To)
+	if target != nil && e.isJoinGateway(target, definition) {
+		ready, err := e.arriveAtJoin(ctx, instance, target, flow, definition)
+		if err != nil {
+			return err
+		}
+		if !ready {
+			return nil
+		}
+	}
+	return e.moveToNextNode(ctx, instance, flow.To)
+}
+
+// isJoinGateway 判断节点是否为有多条入口连线的并行网关或包容网关
+func (e *ProcessEngine) isJoinGateway(node *model.ProcessNode, definition *model.ProcessDefinitionData) bool {
+	if node.Type != model.NodeTypeGateway {
+		return false
+	}
+	switch model.GetGatewayType(node) {
+	case model.GatewayTypeParallel, model.GatewayTypeInclusive:
+		return len(e.findIncomingFlows(definition.Flows, node.ID)) > 1
+	default:
+		return false
+	}
+}
+
+// arriveAtJoin 记录分支到达汇聚网关，返回网关是否可以继续推进
+// 每条入口连线取最早的一条未消费记录（包括包容网关分叉时记录的跳过），全部到齐且由本次调用消费成功时才推进
+func (e *ProcessEngine) arriveAtJoin(ctx context.Context, instance *model.ProcessInstance, gateway *model.ProcessNode, flow model.ProcessFlow, definition *model.ProcessDefinitionData) (bool, error) {
+	if err := e.instanceRepo.RecordGatewayArrival(ctx, instance.ID, gateway.ID, flow.FlowKey()); err != nil {
+		return false, fmt.Errorf("记录网关到达失败: %v", err)
+	}
+
+	arrivals, err := e.instanceRepo.GetPendingGatewayArrivals(ctx, instance.ID, gateway.ID)
+	if err != nil {
+		return false, fmt.Errorf("获取网关到达记录失败: %w", err)
+	}
+
+	earliest := make(map[string]uint)
+	for _, arrival := range arrivals {
+		if _, ok := earliest[arrival.FlowKey]; !ok {
+			earliest[arrival.FlowKey] = arrival.ID
+		}
+	}
+
+	incoming := e.findIncomingFlows(definition.Flows, gateway.ID)
+	ids := make([]uint, 0, len(incoming))
+	for _, in := range incoming {
+		id, ok := earliest[in.FlowKey()]
+		if !ok {
+			e.traceFor(instance).record(ctx, model.TraceCategoryFlow, gateway.ID, map[string]interface{}{
+				"flow_key": flow.FlowKey(),
+				"arrived":  len(earliest),
+				"expected": len(incoming),
+			}, "分支到达汇聚网关 %s，已到达 %d/%d 条入口连线", gateway.ID, len(earliest), len(incoming))
+			e.logger.Info("Waiting for joining branches",
+				zap.Uint("instance_id", instance.ID),
+				zap.String("gateway_id", gateway.ID),
+				zap.Int("arrived", len(earliest)),
+				zap.Int("expected", len(incoming)),
+			)
+			return false, nil
+		}
+		ids = append(ids, id)
+	}
+
+	consumed, err := e.instanceRepo.ConsumeGatewayArrivals(ctx, ids)
+	if err != nil {
+		return false, fmt.Errorf("消费网关到达记录失败: %v", err)
+	}
+	e.traceFor(instance).record(ctx, model.TraceCategoryFlow, gateway.ID, map[string]interface{}{
+		"arrival_ids": ids,
+		"consumed":    consumed,
+	}, "汇聚网关 %s 的入口连线全部到达，消费到达记录: %t", gateway.ID, consumed)
+	if consumed {
+		e.logger.Info("Branches joined",
+			zap.Uint("instance_id", instance.ID),
+			zap.String("gateway_id", gateway.ID),
+		)
+	}
+	return consumed, nil
+}
+
+// findIncomingFlows 查找节点的入口连线
+func (e *ProcessEngine) findIncomingFlows(flows []model.ProcessFlow, nodeID string) []model.ProcessFlow {
+	var incoming []model.ProcessFlow
+	for _, flow := range flows {
+		if flow.To == nodeID {
+			incoming = append(incoming, flow)
+		}
+	}
+	return incoming
+}
+
+// skipInactiveBranches 包容网关分叉后，为下游包容汇聚网关上只能经未激活的分支到达的入口连线记录跳过的到达，
+// 汇聚网关因此只等待分叉时激活的分支；激活的分支都到达不了的汇聚网关不受影响
+func (e *ProcessEngine) skipInactiveBranches(ctx context.Context, instance *model.ProcessInstance, fork *model.ProcessNode, taken []model.ProcessFlow, definition *model.ProcessDefinitionData) error {
+	outgoing := e.findOutgoingFlows(definition.Flows, fork.ID)
+	if len(taken) == len(outgoing) {
+		return nil
+	}
+	active := make(map[string]bool, len(taken))
+	for _, flow := range taken {
+		active[flow.FlowKey()] = true
+	}
+
+	for i := range definition.Nodes {
+		join := &definition.Nodes[i]
+		if join.ID == fork.ID || model.GetGatewayType(join) != model.GatewayTypeInclusive || !e.isJoinGateway(join, definition) {
+			continue
+		}
+
+		expected := make(map[string]bool)
+		reachable := make(map[string]bool)
+		for _, flow := range outgoing {
+			for key := range e.reachableIncoming(flow, fork, join, definition) {
+				reachable[key] = true
+				if active[flow.FlowKey()] {
+					expected[key] = true
+				}
+			}
+		}
+		if len(expected) == 0 {
+			continue
+		}
+
+		var skipped []string
+		for _, in := range e.findIncomingFlows(definition.Flows, join.ID) {
+			if key := in.FlowKey(); reachable[key] && !expected[key] {
+				skipped = append(skipped, key)
+			}
+		}
+		if len(skipped) == 0 {
+			continue
+		}
+		if err := e.instanceRepo.RecordSkippedGatewayArrivals(ctx, instance.ID, join.ID, skipped); err != nil {
+			return fmt.Errorf("记录未激活的分支失败: %v", err)
+		}
+		e.traceFor(instance).record(ctx, model.TraceCategoryFlow, join.ID, map[string]interface{}{
+			"fork_id":   fork.ID,
+			"flow_keys": skipped,
+		}, "包容网关 %s 未激活的分支不会到达汇聚网关 %s，跳过 %d 条入口连线", fork.ID, join.ID, len(skipped))
+	}
+	return nil
+}
+
+// reachableIncoming 沿分叉网关的出口连线向下查找能到达的汇聚网关入口连线，
+// 不经过分叉网关本身和汇聚网关，返回入口连线的标识
+func (e *ProcessEngine) reachableIncoming(start model.ProcessFlow, fork, join *model.ProcessNode, definition *model.ProcessDefinitionData) map[string]bool {
+	reached := make(map[string]bool)
+	if start.To == join.ID {
+		reached[start.FlowKey()] = true
+		return reached
+	}
+
+	visited := map[string]bool{fork.ID: true, join.ID: true, start.To: true}
+	queue := []string{start.To}
+	for len(queue) > 0 {
+		nodeID := queue[0]
+		queue = queue[1:]
+		for _, flow := range e.findOutgoingFlows(definition.Flows, nodeID) {
+			if flow.To == join.ID {
+				reached[flow.FlowKey()] = true
+			}
+			if !visited[flow.To] {
+				visited[flow.To] = true
+				queue = append(queue, flow.To)
+			}
+		}
+	}
+	return reached
+}
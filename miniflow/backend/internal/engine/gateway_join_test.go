@@ -0,0 +1,216 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"miniflow/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// parallelGateway returns a parallel gateway node
+func parallelGateway(id string) model.ProcessNode {
+	return model.ProcessNode{ID: id, Type: model.NodeTypeGateway, Name: id, Props: map[string]interface{}{
+		"gatewayType": model.GatewayTypeParallel,
+	}}
+}
+
+// diamondDefinition start → fork → (a, b) → join → c → end
+func diamondDefinition() *model.ProcessDefinitionData {
+	return &model.ProcessDefinitionData{
+		Nodes: []model.ProcessNode{
+			{ID: "start", Type: model.NodeTypeStart, Name: "start"},
+			parallelGateway("fork"),
+			userTaskNode("a"),
+			userTaskNode("b"),
+			parallelGateway("join"),
+			userTaskNode("c"),
+			{ID: "end", Type: model.NodeTypeEnd, Name: "end"},
+		},
+		Flows: []model.ProcessFlow{
+			flow("start", "fork"),
+			flow("fork", "a"), flow("fork", "b"),
+			flow("a", "join"), flow("b", "join"),
+			flow("join", "c"),
+			flow("c", "end"),
+		},
+	}
+}
+
+// nestedDiamondDefinition start → fork → (inner diamond x → (x1, x2) → xjoin, b) → join → c → end
+func nestedDiamondDefinition() *model.ProcessDefinitionData {
+	return &model.ProcessDefinitionData{
+		Nodes: []model.ProcessNode{
+			{ID: "start", Type: model.NodeTypeStart, Name: "start"},
+			parallelGateway("fork"),
+			parallelGateway("x"),
+			userTaskNode("x1"),
+			userTaskNode("x2"),
+			parallelGateway("xjoin"),
+			userTaskNode("b"),
+			parallelGateway("join"),
+			userTaskNode("c"),
+			{ID: "end", Type: model.NodeTypeEnd, Name: "end"},
+		},
+		Flows: []model.ProcessFlow{
+			flow("start", "fork"),
+			flow("fork", "x"), flow("fork", "b"),
+			flow("x", "x1"), flow("x", "x2"),
+			flow("x1", "xjoin"), flow("x2", "xjoin"),
+			flow("xjoin", "join"), flow("b", "join"),
+			flow("join", "c"),
+			flow("c", "end"),
+		},
+	}
+}
+
+// joinEntries counts how many times the instance entered the gateway
+func joinEntries(t *testing.T, db *gorm.DB, instanceID uint, gatewayID string) int {
+	t.Helper()
+
+	var count int64
+	if err := db.Model(&model.ActivityHistory{}).
+		Where("instance_id = ? AND node_id = ? AND type = ?", instanceID, gatewayID, model.ActivityNodeEntered).
+		Count(&count).Error; err != nil {
+		t.Fatalf("count activities: %v", err)
+	}
+	return int(count)
+}
+
+// assertWaiting fails if any of the nodes has an open task
+func assertWaiting(t *testing.T, db *gorm.DB, instanceID uint, nodeIDs ...string) {
+	t.Helper()
+
+	for _, task := range openTasks(t, db, instanceID) {
+		for _, nodeID := range nodeIDs {
+			if task.NodeID == nodeID {
+				t.Fatalf("node %s was reached before every branch joined", nodeID)
+			}
+		}
+	}
+}
+
+// finishAfterJoin completes the task after the join and checks the instance ends
+func finishAfterJoin(t *testing.T, e *ProcessEngine, db *gorm.DB, instanceID, userID uint) {
+	t.Helper()
+
+	claimAndComplete(t, e, openTaskAt(t, db, instanceID, "c").ID, userID)
+	if tasks := openTasks(t, db, instanceID); len(tasks) != 0 {
+		t.Fatalf("%d tasks are still open after the process ended", len(tasks))
+	}
+	if got := reloadInstance(t, db, instanceID).Status; got != model.InstanceStatusCompleted {
+		t.Fatalf("instance status = %s, want %s", got, model.InstanceStatusCompleted)
+	}
+}
+
+func TestParallelJoinWaitsForEveryBranch(t *testing.T) {
+	for _, order := range [][]string{{"a", "b"}, {"b", "a"}} {
+		t.Run(order[0]+" first", func(t *testing.T) {
+			e, db := newTestEngine(t)
+			user := createTestUser(t, db, "alice", "user")
+			definition := publishTestDefinition(t, db, "diamond", user.ID, diamondDefinition())
+			instance := startTestProcess(t, e, definition.ID, user.ID, nil)
+
+			if tasks := openTasks(t, db, instance.ID); len(tasks) != 2 {
+				t.Fatalf("expected a task on each branch, found %d", len(tasks))
+			}
+
+			claimAndComplete(t, e, openTaskAt(t, db, instance.ID, order[0]).ID, user.ID)
+			assertWaiting(t, db, instance.ID, "c")
+			if n := joinEntries(t, db, instance.ID, "join"); n != 0 {
+				t.Fatalf("join fired %d times with one branch pending", n)
+			}
+
+			claimAndComplete(t, e, openTaskAt(t, db, instance.ID, order[1]).ID, user.ID)
+			if n := joinEntries(t, db, instance.ID, "join"); n != 1 {
+				t.Fatalf("join fired %d times, want 1", n)
+			}
+			finishAfterJoin(t, e, db, instance.ID, user.ID)
+		})
+	}
+}
+
+func TestParallelJoinFiresOnceForConcurrentBranches(t *testing.T) {
+	e, db := newTestEngine(t)
+	user := createTestUser(t, db, "alice", "user")
+	definition := publishTestDefinition(t, db, "diamond", user.ID, diamondDefinition())
+	instance := startTestProcess(t, e, definition.ID, user.ID, nil)
+
+	tasks := openTasks(t, db, instance.ID)
+	for _, task := range tasks {
+		if err := e.ClaimTask(context.Background(), task.ID, user.ID); err != nil {
+			t.Fatalf("claim task %d: %v", task.ID, err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(tasks))
+	for i, task := range tasks {
+		wg.Add(1)
+		go func(i int, taskID uint) {
+			defer wg.Done()
+			errs[i] = e.CompleteTask(context.Background(), taskID, user.ID, nil, "")
+		}(i, task.ID)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("complete task %d: %v", tasks[i].ID, err)
+		}
+	}
+
+	if n := joinEntries(t, db, instance.ID, "join"); n != 1 {
+		t.Fatalf("join fired %d times, want 1", n)
+	}
+	finishAfterJoin(t, e, db, instance.ID, user.ID)
+}
+
+func TestNestedParallelJoins(t *testing.T) {
+	for _, order := range [][]string{
+		{"x1", "x2", "b"},
+		{"b", "x2", "x1"},
+		{"x1", "b", "x2"},
+	} {
+		t.Run(order[0]+" "+order[1]+" "+order[2], func(t *testing.T) {
+			e, db := newTestEngine(t)
+			user := createTestUser(t, db, "alice", "user")
+			definition := publishTestDefinition(t, db, "nested", user.ID, nestedDiamondDefinition())
+			instance := startTestProcess(t, e, definition.ID, user.ID, nil)
+
+			if tasks := openTasks(t, db, instance.ID); len(tasks) != 3 {
+				t.Fatalf("expected tasks x1, x2 and b, found %d", len(tasks))
+			}
+
+			for i, nodeID := range order {
+				claimAndComplete(t, e, openTaskAt(t, db, instance.ID, nodeID).ID, user.ID)
+
+				innerDone := 0
+				for _, done := range order[:i+1] {
+					if done == "x1" || done == "x2" {
+						innerDone++
+					}
+				}
+				wantInner := 0
+				if innerDone == 2 {
+					wantInner = 1
+				}
+				if n := joinEntries(t, db, instance.ID, "xjoin"); n != wantInner {
+					t.Fatalf("after %v the inner join fired %d times, want %d", order[:i+1], n, wantInner)
+				}
+				if i < len(order)-1 {
+					assertWaiting(t, db, instance.ID, "c")
+					if n := joinEntries(t, db, instance.ID, "join"); n != 0 {
+						t.Fatalf("after %v the outer join fired %d times, want 0", order[:i+1], n)
+					}
+				}
+			}
+
+			if n := joinEntries(t, db, instance.ID, "join"); n != 1 {
+				t.Fatalf("outer join fired %d times, want 1", n)
+			}
+			finishAfterJoin(t, e, db, instance.ID, user.ID)
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// checkConnectorPolicy 执行前检查服务任务是否符合流程的连接器白名单
+func (e *ProcessEngine) checkConnectorPolicy(ctx context.Context, instance *model.ProcessInstance, node *model.ProcessNode) error {
+	definition := &instance.Definition
+	if definition.ID == 0 {
+		var err error
+		definition, err = e.processRepo.GetByID(ctx, instance.DefinitionID)
+		if err != nil {
+			return fmt.Errorf("获取流程定义失败: %w", err)
+		}
+	}
+
+	policy, err := e.policyRepo.GetByDefinitionKey(ctx, definition.Key)
+	if err != nil {
+		return fmt.Errorf("获取连接器白名单失败: %w", err)
+	}
+
+	return policy.CheckNode(node)
+}
+
+// failServiceTask 将服务任务标记为失败并生成异常事件，流程停留在当前节点等待处理
+func (e *ProcessEngine) failServiceTask(ctx context.Context, instance *model.ProcessInstance, task *model.TaskInstance, node *model.ProcessNode, incidentType string, cause error) error {
+	now := time.Now()
+	task.Status = model.TaskStatusFailed
+	task.CompleteTime = &now
+	task.Comment = cause.Error()
+	if err := e.taskRepo.Update(ctx, task); err != nil {
+		return fmt.Errorf("更新服务任务状态失败: %v", err)
+	}
+
+	return e.raiseIncident(ctx, instance, task, node, incidentType, cause)
+}
+
+// raiseIncident 为流程实例生成待处理的异常事件
+func (e *ProcessEngine) raiseIncident(ctx context.Context, instance *model.ProcessInstance, task *model.TaskInstance, node *model.ProcessNode, incidentType string, cause error) error {
+	incident := &model.Incident{
+		InstanceID: instance.ID,
+		NodeID:     node.ID,
+		Type:       incidentType,
+		Code:       FailureCodeOf(cause),
+		Message:    cause.Error(),
+		Status:     model.IncidentStatusOpen,
+	}
+	if incident.Code == "" {
+		incident.Code = incidentTypeCodes[incidentType]
+	}
+	if task != nil {
+		incident.TaskID = &task.ID
+	}
+	if err := e.incidentRepo.Create(ctx, incident); err != nil {
+		return fmt.Errorf("创建异常事件失败: %v", err)
+	}
+
+	e.logger.Warn("Incident raised",
+		zap.Uint("incident_id", incident.ID),
+		zap.Uint("instance_id", instance.ID),
+		zap.String("node_id", node.ID),
+		zap.String("type", incidentType),
+		zap.String("code", incident.Code),
+		zap.String("message", cause.Error()),
+	)
+
+	return nil
+}
+
+// GetIncidents 获取异常事件列表
+func (e *ProcessEngine) GetIncidents(ctx context.Context, offset, limit int, filters map[string]interface{}) ([]model.Incident, int64, error) {
+	return e.incidentRepo.List(ctx, offset, limit, filters)
+}
+
+// ResolveIncident 手动关闭异常事件，不重新执行节点
+func (e *ProcessEngine) ResolveIncident(ctx context.Context, incidentID uint, userID uint) (*model.Incident, error) {
+	incident, err := e.incidentRepo.GetByID(ctx, incidentID)
+	if err != nil {
+		return nil, err
+	}
+	if incident.Status != model.IncidentStatusOpen {
+		return nil, newEngineError(CodeInvalidStateTransition, nil, "异常事件已处理")
+	}
+
+	if err := e.markIncidentResolved(ctx, incident, userID); err != nil {
+		return nil, err
+	}
+	return incident, nil
+}
+
+// RetryIncident 重新执行异常事件所在的服务任务或脚本任务节点（处理人分配失败时重新分配），并关闭该异常事件
+func (e *ProcessEngine) RetryIncident(ctx context.Context, incidentID uint, userID uint) (*model.Incident, error) {
+	incident, err := e.incidentRepo.GetByID(ctx, incidentID)
+	if err != nil {
+		return nil, err
+	}
+	if incident.Status != model.IncidentStatusOpen {
+		return nil, newEngineError(CodeInvalidStateTransition, nil, "异常事件已处理")
+	}
+
+	instance, err := e.instanceRepo.GetByID(ctx, incident.InstanceID)
+	if err != nil {
+		return nil, fmt.Errorf("获取流程实例失败: %w", err)
+	}
+	if instance.Status != model.InstanceStatusRunning {
+		return nil, newEngineError(CodeInvalidStateTransition, nil, "只能重试运行中的流程实例")
+	}
+
+	definitionData, err := instance.Definition.GetDefinitionData()
+	if err != nil {
+		return nil, fmt.Errorf("解析流程定义失败: %v", err)
+	}
+
+	node := e.findNodeByID(definitionData.Nodes, incident.NodeID)
+	if incident.Type == model.IncidentTypeAssignmentFailed {
+		return e.retryAssignment(ctx, incident, instance, node, userID)
+	}
+	if incident.Type == model.IncidentTypeGatewayNoPath || incident.Type == model.IncidentTypeConditionFailed {
+		return e.retryGateway(ctx, incident, instance, node, definitionData, userID)
+	}
+	if incident.Type == model.IncidentTypeVisitLimit {
+		return e.retryVisitLimit(ctx, incident, instance, node, definitionData, userID)
+	}
+	if incident.Type == model.IncidentTypeRecovery {
+		return e.retryRecovery(ctx, incident, instance, node, userID)
+	}
+	if node == nil || (node.Type != model.NodeTypeServiceTask && node.Type != model.NodeTypeScriptTask && node.Type != model.NodeTypeMailTask) {
+		return nil, newEngineError(CodeNotFound, nil, "异常事件对应的服务任务节点不存在")
+	}
+
+	// 先关闭当前异常事件，重试再次失败时会生成新的异常事件
+	if err := e.markIncidentResolved(ctx, incident, userID); err != nil {
+		return nil, err
+	}
+
+	e.logger.Info("Retrying incident",
+		zap.Uint("incident_id", incident.ID),
+		zap.Uint("instance_id", instance.ID),
+		zap.String("node_id", node.ID),
+	)
+
+	if node.Type == model.NodeTypeScriptTask {
+		if err := e.handleScriptTask(ctx, instance, node); err != nil {
+			return nil, fmt.Errorf("重试脚本任务失败: %v", err)
+		}
+		return incident, nil
+	}
+	if node.Type == model.NodeTypeMailTask {
+		if err := e.handleMailTask(ctx, instance, node); err != nil {
+			return nil, fmt.Errorf("重试邮件任务失败: %v", err)
+		}
+		return incident, nil
+	}
+	if err := e.handleServiceTask(ctx, instance, node); err != nil {
+		return nil, fmt.Errorf("重试服务任务失败: %v", err)
+	}
+
+	return incident, nil
+}
+
+// retryGateway 重新评估网关条件，通常在修正流程变量后使用；仍没有可执行路径时会生成新的异常事件
+func (e *ProcessEngine) retryGateway(ctx context.Context, incident *model.Incident, instance *model.ProcessInstance, node *model.ProcessNode, definition *model.ProcessDefinitionData, userID uint) (*model.Incident, error) {
+	if node == nil || node.Type != model.NodeTypeGateway {
+		return nil, newEngineError(CodeNotFound, nil, "异常事件对应的网关节点不存在")
+	}
+
+	if err := e.markIncidentResolved(ctx, incident, userID); err != nil {
+		return nil, err
+	}
+
+	if err := e.handleGateway(ctx, instance, node, definition); err != nil {
+		return nil, fmt.Errorf("重新评估网关失败: %w", err)
+	}
+	return incident, nil
+}
+
+// markIncidentResolved 标记异常事件已处理
+func (e *ProcessEngine) markIncidentResolved(ctx context.Context, incident *model.Incident, userID uint) error {
+	now := time.Now()
+	incident.Status = model.IncidentStatusResolved
+	incident.ResolvedAt = &now
+	incident.ResolvedBy = &userID
+	if err := e.incidentRepo.Update(ctx, incident); err != nil {
+		return fmt.Errorf("更新异常事件失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,370 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/internal/repository"
+	"miniflow/pkg/config"
+	"miniflow/pkg/logger"
+	"miniflow/pkg/storage"
+
+	"go.uber.org/zap"
+)
+
+// archiveFormatVersion 归档文件的格式版本
+const archiveFormatVersion = 1
+
+// ErrInstanceArchiveNotFound 流程实例没有归档记录
+var ErrInstanceArchiveNotFound = &EngineError{Code: CodeNotFound, Message: "流程实例没有归档记录"}
+
+// ArchivedInstanceHistory 归档文件的内容，即实例归档时的完整执行历史
+type ArchivedInstanceHistory struct {
+	FormatVersion int                     `json:"format_version"`
+	ArchivedAt    time.Time               `json:"archived_at"`
+	Instance      model.ProcessInstance   `json:"instance"`
+	ChildIDs      []uint                  `json:"child_instance_ids"`
+	Tasks         []model.TaskInstance    `json:"tasks"`
+	Comments      []model.TaskComment     `json:"comments"`
+	Activities    []model.ActivityHistory `json:"activities"`
+}
+
+// InstanceArchiver 把结束超过保留期限的流程实例从运行表移到归档存储
+//
+// 顶层实例连同调用活动启动的子实例一起归档：每个实例的执行历史导出为一个 JSON 文件保存到附件存储，
+// 数据库中只保留归档记录，随后在一个事务中删除实例的运行数据。报表事实表和附件不受归档影响。
+// 归档后实例的执行历史只能通过归档接口读取。
+type InstanceArchiver struct {
+	engine  *ProcessEngine
+	storage storage.Storage
+	cfg     *config.ArchiveConfig
+	logger  *logger.Logger
+}
+
+// NewInstanceArchiver 创建流程实例归档器，归档文件与附件使用同一存储
+func NewInstanceArchiver(engine *ProcessEngine, attachmentCfg *config.AttachmentConfig, cfg *config.ArchiveConfig, logger *logger.Logger) (*InstanceArchiver, error) {
+	store, err := storage.New(attachmentCfg)
+	if err != nil {
+		return nil, err
+	}
+	return &InstanceArchiver{
+		engine:  engine,
+		storage: store,
+		cfg:     cfg,
+		logger:  logger,
+	}, nil
+}
+
+// ArchiveExpired 归档结束时间早于保留期限的顶层实例，返回归档的实例数（包括子实例）
+func (a *InstanceArchiver) ArchiveExpired(ctx context.Context, now time.Time) (int, error) {
+	if a.cfg.RetentionDays <= 0 {
+		return 0, nil
+	}
+
+	instances, err := a.engine.instanceRepo.GetArchivable(ctx, now.Add(-a.cfg.GetRetention()), a.cfg.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("获取待归档的流程实例失败: %w", err)
+	}
+
+	archived := 0
+	for i := range instances {
+		archives, err := a.archive(ctx, instances[i].ID, 0)
+		if err != nil {
+			// 单个实例失败不影响其余实例，下次扫描时重试
+			a.logger.Error("Failed to archive process instance", zap.Uint("instance_id", instances[i].ID), zap.Error(err))
+			continue
+		}
+		archived += len(archives)
+	}
+	return archived, nil
+}
+
+// ArchiveInstance 立即归档已结束的顶层实例，不受保留期限限制，需要 admin 权限
+func (a *InstanceArchiver) ArchiveInstance(ctx context.Context, instanceID, userID uint) ([]model.InstanceArchive, error) {
+	if err := a.engine.checkPermission(ctx, userID, config.PermissionAdmin, "归档流程实例"); err != nil {
+		return nil, err
+	}
+
+	instance, err := a.engine.instanceRepo.GetByID(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if instance.ParentInstanceID != nil {
+		return nil, newEngineError(CodeInvalidRequest, nil, "子实例随父实例 %d 一起归档", *instance.ParentInstanceID)
+	}
+	if !model.IsTerminalInstanceStatus(instance.Status) {
+		return nil, newEngineError(CodeInvalidStateTransition, nil, "只能归档已结束的流程实例，当前状态为 %s", instance.Status)
+	}
+	return a.archive(ctx, instanceID, userID)
+}
+
+// archive 导出实例及其子实例的执行历史，保存归档记录后删除运行数据
+// 删除失败时已上传的归档文件会被清理，实例保持原样
+func (a *InstanceArchiver) archive(ctx context.Context, instanceID, userID uint) ([]model.InstanceArchive, error) {
+	instances, err := a.engine.instanceRepo.GetInstanceTree(ctx, instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("获取流程实例失败: %w", err)
+	}
+
+	now := time.Now()
+	archives := make([]*model.InstanceArchive, 0, len(instances))
+	cleanup := func() {
+		for _, archive := range archives {
+			if err := a.storage.Delete(context.Background(), archive.StorageKey); err != nil {
+				a.logger.Warn("Failed to delete archive file", zap.String("key", archive.StorageKey), zap.Error(err))
+			}
+		}
+	}
+
+	for i := range instances {
+		archive, err := a.export(ctx, &instances[i], instances, now)
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		archives = append(archives, archive)
+	}
+
+	if err := a.engine.instanceRepo.ArchiveInstance(ctx, instanceID, archives); err != nil {
+		cleanup()
+		if errors.Is(err, repository.ErrInstanceNotTerminal) {
+			return nil, newEngineError(CodeInvalidStateTransition, err, "流程实例或其子实例尚未结束，不能归档")
+		}
+		if errors.Is(err, repository.ErrInstanceArchiveStale) {
+			return nil, newEngineError(CodeConcurrentModification, err, "流程实例在导出后发生了变化，请重试")
+		}
+		return nil, err
+	}
+
+	result := make([]model.InstanceArchive, len(archives))
+	for i, archive := range archives {
+		result[i] = *archive
+		a.engine.events.Publish(Event{
+			Type:       EventProcessArchived,
+			InstanceID: archive.InstanceID,
+			UserID:     userID,
+			Data:       map[string]interface{}{"checksum": archive.Checksum},
+		})
+	}
+	a.logger.Info("Process instance archived",
+		zap.Uint("instance_id", instanceID),
+		zap.Int("instances", len(archives)),
+		zap.Uint("user_id", userID),
+	)
+	return result, nil
+}
+
+// export 把单个实例的执行历史写入归档存储，返回尚未保存的归档记录
+func (a *InstanceArchiver) export(ctx context.Context, instance *model.ProcessInstance, tree []model.ProcessInstance, now time.Time) (*model.InstanceArchive, error) {
+	tasks, err := a.engine.taskRepo.GetByInstance(ctx, instance.ID)
+	if err != nil {
+		return nil, fmt.Errorf("获取流程任务失败: %w", err)
+	}
+	comments, err := a.engine.taskRepo.GetInstanceComments(ctx, instance.ID)
+	if err != nil {
+		return nil, fmt.Errorf("获取任务评论失败: %w", err)
+	}
+	activities, err := a.engine.GetActivityHistory(ctx, instance.ID, &repository.ActivityHistoryQuery{})
+	if err != nil {
+		return nil, err
+	}
+
+	// 只保存用户ID不保存用户资料，删除用户时由 ReassignArchivedUser 把用户ID改为占位账户
+	for i := range tasks {
+		tasks[i].Assignee = nil
+	}
+	snapshot := *instance
+	snapshot.Starter = model.User{}
+
+	history := &ArchivedInstanceHistory{
+		FormatVersion: archiveFormatVersion,
+		ArchivedAt:    now,
+		Instance:      snapshot,
+		ChildIDs:      []uint{},
+		Tasks:         tasks,
+		Comments:      comments,
+		Activities:    activities,
+	}
+	for _, child := range tree {
+		if child.ParentInstanceID != nil && *child.ParentInstanceID == instance.ID {
+			history.ChildIDs = append(history.ChildIDs, child.ID)
+		}
+	}
+
+	content, err := json.Marshal(history)
+	if err != nil {
+		return nil, fmt.Errorf("导出流程实例 %d 失败: %w", instance.ID, err)
+	}
+	sum := sha256.Sum256(content)
+
+	key := fmt.Sprintf("archives/instances/%d.json", instance.ID)
+	if err := a.storage.Put(ctx, key, bytes.NewReader(content), int64(len(content)), "application/json"); err != nil {
+		return nil, fmt.Errorf("保存归档文件失败: %w", err)
+	}
+	return model.NewInstanceArchive(instance, key, int64(len(content)), hex.EncodeToString(sum[:]), now), nil
+}
+
+// ReassignArchivedUser 把归档文件中对用户的引用改为占位账户，返回改写的归档文件数
+//
+// 删除用户时数据库中的历史记录已在事务中转给占位账户，归档文件不在事务中，需要在删除后逐个改写：
+// 改写的字段与数据库一致，即实例发起人、流程定义创建人、任务处理人和所有人、评论人、活动的操作人，
+// 改写后更新归档记录的文件大小和校验和。评论内容、表单数据和流程变量与数据库中的记录一样原样保留。
+// 单个文件失败不影响其余文件，全部遍历后返回错误；改写可以重复执行，已改写的文件不会再变化。
+func (a *InstanceArchiver) ReassignArchivedUser(ctx context.Context, userID, tombstoneID uint) (int, error) {
+	rewritten, failed := 0, 0
+	var afterID uint
+	for {
+		archives, err := a.engine.instanceRepo.ListArchivesAfter(ctx, afterID, a.cfg.BatchSize)
+		if err != nil {
+			return rewritten, fmt.Errorf("获取归档记录失败: %w", err)
+		}
+		if len(archives) == 0 {
+			break
+		}
+		for i := range archives {
+			changed, err := a.reassignUser(ctx, &archives[i], userID, tombstoneID)
+			if err != nil {
+				a.logger.Error("Failed to rewrite archive file",
+					zap.Uint("instance_id", archives[i].InstanceID),
+					zap.String("key", archives[i].StorageKey),
+					zap.Error(err),
+				)
+				failed++
+				continue
+			}
+			if changed {
+				rewritten++
+			}
+		}
+		afterID = archives[len(archives)-1].ID
+	}
+
+	if failed > 0 {
+		return rewritten, fmt.Errorf("%d 个归档文件改写失败", failed)
+	}
+	return rewritten, nil
+}
+
+// reassignUser 改写单个归档文件中对用户的引用，文件没有引用该用户时返回 false
+func (a *InstanceArchiver) reassignUser(ctx context.Context, archive *model.InstanceArchive, userID, tombstoneID uint) (bool, error) {
+	content, err := a.storage.Open(ctx, archive.StorageKey)
+	if err != nil {
+		return false, fmt.Errorf("读取归档文件失败: %w", err)
+	}
+	var history ArchivedInstanceHistory
+	err = json.NewDecoder(content).Decode(&history)
+	content.Close()
+	if err != nil {
+		return false, fmt.Errorf("解析归档文件失败: %w", err)
+	}
+
+	if !history.reassignUser(userID, tombstoneID) {
+		return false, nil
+	}
+
+	data, err := json.Marshal(&history)
+	if err != nil {
+		return false, fmt.Errorf("导出流程实例 %d 失败: %w", archive.InstanceID, err)
+	}
+	sum := sha256.Sum256(data)
+	// 先更新记录再覆盖文件：覆盖失败时文件仍引用该用户，重新执行会再次改写并得到相同的校验和
+	if err := a.engine.instanceRepo.UpdateArchiveContent(ctx, archive.ID, int64(len(data)), hex.EncodeToString(sum[:])); err != nil {
+		return false, fmt.Errorf("更新归档记录失败: %w", err)
+	}
+	if err := a.storage.Put(ctx, archive.StorageKey, bytes.NewReader(data), int64(len(data)), "application/json"); err != nil {
+		return false, fmt.Errorf("保存归档文件失败: %w", err)
+	}
+	return true, nil
+}
+
+// reassignUser 把执行历史中对用户的引用改为占位账户，返回是否有改动
+func (h *ArchivedInstanceHistory) reassignUser(userID, tombstoneID uint) bool {
+	changed := false
+	replace := func(id *uint) {
+		if id != nil && *id == userID {
+			*id = tombstoneID
+			changed = true
+		}
+	}
+
+	replace(&h.Instance.StarterID)
+	replace(&h.Instance.Definition.CreatedBy)
+	for i := range h.Tasks {
+		replace(h.Tasks[i].AssigneeID)
+		replace(h.Tasks[i].OwnerID)
+	}
+	for i := range h.Comments {
+		replace(&h.Comments[i].UserID)
+	}
+	for i := range h.Activities {
+		replace(h.Activities[i].ActorID)
+	}
+	return changed
+}
+
+// ListArchives 分页获取归档记录，最近归档的在前，需要 admin 权限
+func (a *InstanceArchiver) ListArchives(ctx context.Context, userID uint, offset, limit int, filters map[string]interface{}) ([]model.InstanceArchive, int64, error) {
+	if err := a.engine.checkPermission(ctx, userID, config.PermissionAdmin, "查看归档的流程实例"); err != nil {
+		return nil, 0, err
+	}
+	return a.engine.instanceRepo.ListArchives(ctx, offset, limit, filters)
+}
+
+// OpenArchivedHistory 读取已归档实例的执行历史，实例发起人和有 archive_view 权限的用户可以读取，调用方负责关闭返回的内容
+func (a *InstanceArchiver) OpenArchivedHistory(ctx context.Context, instanceID, userID uint) (*model.InstanceArchive, io.ReadCloser, error) {
+	archive, err := a.engine.instanceRepo.GetArchive(ctx, instanceID)
+	if err != nil {
+		if errors.Is(err, repository.ErrInstanceArchiveNotFound) {
+			return nil, nil, ErrInstanceArchiveNotFound
+		}
+		return nil, nil, fmt.Errorf("获取归档记录失败: %w", err)
+	}
+	if archive.StarterID != userID {
+		if err := a.engine.checkPermission(ctx, userID, config.PermissionArchiveView, "查看他人发起的归档实例"); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	content, err := a.storage.Open(ctx, archive.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil, newEngineError(CodeInternal, err, "流程实例 %d 的归档文件丢失", instanceID)
+		}
+		return nil, nil, fmt.Errorf("读取归档文件失败: %w", err)
+	}
+	return archive, content, nil
+}
+
+// Start 按配置的间隔归档过期的流程实例，直到 ctx 取消；保留期限为 0 时不归档
+func (a *InstanceArchiver) Start(ctx context.Context) {
+	if a.cfg.RetentionDays <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(a.cfg.GetInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			archived, err := a.ArchiveExpired(ctx, now)
+			if err != nil {
+				a.logger.Error("Failed to archive expired instances", zap.Error(err))
+				continue
+			}
+			if archived > 0 {
+				a.logger.Info("Expired process instances archived", zap.Int("instances", archived))
+			}
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package engine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/config"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// newTestArchiver creates an archiver on local storage in a temp directory
+func newTestArchiver(t *testing.T, e *ProcessEngine, batchSize int) *InstanceArchiver {
+	t.Helper()
+
+	a, err := NewInstanceArchiver(e,
+		&config.AttachmentConfig{Storage: config.StorageLocal, Local: config.LocalStorageConfig{Path: t.TempDir()}},
+		&config.ArchiveConfig{BatchSize: batchSize},
+		&logger.Logger{Logger: zap.NewNop()},
+	)
+	if err != nil {
+		t.Fatalf("new archiver: %v", err)
+	}
+	return a
+}
+
+// readArchive reads the archived history of the instance and checks it against the size and checksum of its record
+func readArchive(t *testing.T, a *InstanceArchiver, instanceID, adminID uint) *ArchivedInstanceHistory {
+	t.Helper()
+
+	archive, content, err := a.OpenArchivedHistory(context.Background(), instanceID, adminID)
+	if err != nil {
+		t.Fatalf("open archive of instance %d: %v", instanceID, err)
+	}
+	defer content.Close()
+	data, err := io.ReadAll(content)
+	if err != nil {
+		t.Fatalf("read archive of instance %d: %v", instanceID, err)
+	}
+	sum := sha256.Sum256(data)
+	if archive.Size != int64(len(data)) || archive.Checksum != hex.EncodeToString(sum[:]) {
+		t.Fatalf("archive of instance %d: record has size %d checksum %s, file has %d bytes", instanceID, archive.Size, archive.Checksum, len(data))
+	}
+
+	var history ArchivedInstanceHistory
+	if err := json.Unmarshal(data, &history); err != nil {
+		t.Fatalf("decode archive of instance %d: %v", instanceID, err)
+	}
+	return &history
+}
+
+func TestArchiveInstanceExportsHistory(t *testing.T) {
+	ctx := context.Background()
+	e, db := newTestEngine(t)
+	admin := createTestUser(t, db, "archive_admin", "admin")
+
+	definition := publishTestDefinition(t, db, "archive_export", admin.ID, &model.ProcessDefinitionData{
+		Nodes: []model.ProcessNode{
+			{ID: "start", Type: model.NodeTypeStart, Name: "start"},
+			userTaskNode("review"),
+			{ID: "end", Type: model.NodeTypeEnd, Name: "end"},
+		},
+		Flows: []model.ProcessFlow{flow("start", "review"), flow("review", "end")},
+	})
+	instance := startTestProcess(t, e, definition.ID, admin.ID, nil)
+	claimAndComplete(t, e, openTaskAt(t, db, instance.ID, "review").ID, admin.ID)
+
+	a := newTestArchiver(t, e, 10)
+	archives, err := a.ArchiveInstance(ctx, instance.ID, admin.ID)
+	if err != nil {
+		t.Fatalf("archive instance: %v", err)
+	}
+	if len(archives) != 1 || archives[0].InstanceID != instance.ID || archives[0].FinalStatus != model.InstanceStatusCompleted {
+		t.Fatalf("archives %+v, want one completed archive of instance %d", archives, instance.ID)
+	}
+
+	history := readArchive(t, a, instance.ID, admin.ID)
+	if history.Instance.ID != instance.ID || len(history.Tasks) != 1 || history.Tasks[0].NodeID != "review" {
+		t.Fatalf("archived history has instance %d and tasks %+v", history.Instance.ID, history.Tasks)
+	}
+	if len(history.Activities) == 0 {
+		t.Fatal("archived history has no activities")
+	}
+
+	var remaining int64
+	if err := db.Model(&model.ProcessInstance{}).Where("id = ?", instance.ID).Count(&remaining).Error; err != nil {
+		t.Fatalf("count instances: %v", err)
+	}
+	if remaining != 0 {
+		t.Fatal("archived instance is still in the runtime table")
+	}
+}
+
+// referencesUser reports the places in the archived history that still reference the user
+func referencesUser(h *ArchivedInstanceHistory, userID uint) []string {
+	var refs []string
+	if h.Instance.StarterID == userID {
+		refs = append(refs, "instance.starter_id")
+	}
+	for _, task := range h.Tasks {
+		if task.AssigneeID != nil && *task.AssigneeID == userID {
+			refs = append(refs, "task "+task.NodeID+".assignee_id")
+		}
+	}
+	for _, comment := range h.Comments {
+		if comment.UserID == userID {
+			refs = append(refs, "comment.user_id")
+		}
+	}
+	for _, activity := range h.Activities {
+		if activity.ActorID != nil && *activity.ActorID == userID {
+			refs = append(refs, "activity "+activity.Type+".actor_id")
+		}
+	}
+	return refs
+}
+
+func TestReassignArchivedUserRewritesArchiveFiles(t *testing.T) {
+	ctx := context.Background()
+	e, db := newTestEngine(t)
+	admin := createTestUser(t, db, "archive_admin", "admin")
+	alice := createTestUser(t, db, "archive_alice", "user")
+
+	definition := publishTestDefinition(t, db, "archive_erasure", admin.ID, &model.ProcessDefinitionData{
+		Nodes: []model.ProcessNode{
+			{ID: "start", Type: model.NodeTypeStart, Name: "start"},
+			userTaskNode("review"),
+			{ID: "end", Type: model.NodeTypeEnd, Name: "end"},
+		},
+		Flows: []model.ProcessFlow{flow("start", "review"), flow("review", "end")},
+	})
+
+	// alice 发起并处理第一个实例，第二个实例与 alice 无关
+	involved := startTestProcess(t, e, definition.ID, alice.ID, nil)
+	task := openTaskAt(t, db, involved.ID, "review")
+	if _, err := e.AddTaskComment(ctx, task.ID, alice.ID, "请尽快处理"); err != nil {
+		t.Fatalf("add comment: %v", err)
+	}
+	claimAndComplete(t, e, task.ID, alice.ID)
+	unrelated := startTestProcess(t, e, definition.ID, admin.ID, nil)
+	claimAndComplete(t, e, openTaskAt(t, db, unrelated.ID, "review").ID, admin.ID)
+
+	a := newTestArchiver(t, e, 1)
+	for _, instanceID := range []uint{involved.ID, unrelated.ID} {
+		if _, err := a.ArchiveInstance(ctx, instanceID, admin.ID); err != nil {
+			t.Fatalf("archive instance %d: %v", instanceID, err)
+		}
+	}
+	if refs := referencesUser(readArchive(t, a, involved.ID, admin.ID), alice.ID); len(refs) == 0 {
+		t.Fatal("archive of the instance alice took part in does not reference her")
+	}
+	before, err := e.instanceRepo.GetArchive(ctx, unrelated.ID)
+	if err != nil {
+		t.Fatalf("get archive: %v", err)
+	}
+
+	erasure, err := e.userRepo.EraseUser(ctx, alice.ID, admin.ID, "离职")
+	if err != nil {
+		t.Fatalf("erase user: %v", err)
+	}
+	rewritten, err := a.ReassignArchivedUser(ctx, alice.ID, erasure.TombstoneID)
+	if err != nil {
+		t.Fatalf("reassign archived user: %v", err)
+	}
+	if rewritten != 1 {
+		t.Fatalf("rewrote %d archives, want 1", rewritten)
+	}
+
+	history := readArchive(t, a, involved.ID, admin.ID)
+	if refs := referencesUser(history, alice.ID); len(refs) > 0 {
+		t.Fatalf("archive still references the erased user at %v", refs)
+	}
+	if history.Instance.StarterID != erasure.TombstoneID || len(history.Comments) != 1 || history.Comments[0].UserID != erasure.TombstoneID {
+		t.Fatalf("archive not handed over to the tombstone: starter %d, comments %+v", history.Instance.StarterID, history.Comments)
+	}
+	after, err := e.instanceRepo.GetArchive(ctx, unrelated.ID)
+	if err != nil {
+		t.Fatalf("get archive: %v", err)
+	}
+	if after.Checksum != before.Checksum {
+		t.Fatal("archive of the unrelated instance was rewritten")
+	}
+
+	// 重复执行不会再改写
+	if rewritten, err := a.ReassignArchivedUser(ctx, alice.ID, erasure.TombstoneID); err != nil || rewritten != 0 {
+		t.Fatalf("second reassign rewrote %d archives, err %v; want 0", rewritten, err)
+	}
+}
@@ -0,0 +1,244 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+
+	"miniflow/internal/model"
+)
+
+// 变量差异类型
+const (
+	VariableDiffAdded   = "added"
+	VariableDiffRemoved = "removed"
+	VariableDiffChanged = "changed"
+)
+
+// NodeVisit 实例在节点上的停留情况，由节点产生的任务推导
+type NodeVisit struct {
+	NodeID          string  `json:"node_id"`
+	NodeLabel       string  `json:"node_label,omitempty"`
+	Visits          int     `json:"visits"`
+	Status          string  `json:"status"`
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// InstanceCompareSide 对比中一个实例的路径和耗时
+type InstanceCompareSide struct {
+	InstanceID      uint        `json:"instance_id"`
+	BusinessKey     string      `json:"business_key"`
+	DefinitionID    uint        `json:"definition_id"`
+	Version         int         `json:"version"`
+	Status          string      `json:"status"`
+	StartTime       time.Time   `json:"start_time"`
+	EndTime         *time.Time  `json:"end_time"`
+	DurationSeconds float64     `json:"duration_seconds"`
+	Path            []string    `json:"path"`
+	Nodes           []NodeVisit `json:"nodes"`
+}
+
+// NodeDurationDiff 节点耗时对比，未经过该节点的一侧为空
+type NodeDurationDiff struct {
+	NodeID       string   `json:"node_id"`
+	NodeLabel    string   `json:"node_label,omitempty"`
+	Left         *float64 `json:"left_seconds"`
+	Right        *float64 `json:"right_seconds"`
+	DeltaSeconds float64  `json:"delta_seconds"`
+}
+
+// VariableDiff 流程变量差异；变量需要脱敏时不返回变量值
+type VariableDiff struct {
+	Name   string      `json:"name"`
+	Change string      `json:"change"`
+	Left   interface{} `json:"left,omitempty"`
+	Right  interface{} `json:"right,omitempty"`
+}
+
+// InstanceComparison 两个同一流程实例的对比结果
+type InstanceComparison struct {
+	Left  *InstanceCompareSide `json:"left"`
+	Right *InstanceCompareSide `json:"right"`
+	// PathDivergesAt 两条路径第一个不同节点的位置，路径相同时为 -1
+	PathDivergesAt  int                `json:"path_diverges_at"`
+	DurationDelta   float64            `json:"duration_delta_seconds"`
+	NodeDurations   []NodeDurationDiff `json:"node_durations"`
+	VariableDiffs   []VariableDiff     `json:"variable_diffs"`
+	VariablesMasked bool               `json:"variables_masked,omitempty"`
+}
+
+// CompareInstances 对比两个同一流程（可以是不同版本）的实例：执行路径、各节点耗时和变量差异
+// 节点耗时按差值绝对值从大到小排列，便于定位耗时差异最大的节点
+func (e *ProcessEngine) CompareInstances(ctx context.Context, leftID, rightID uint) (*InstanceComparison, error) {
+	if leftID == rightID {
+		return nil, newEngineError(CodeInvalidRequest, nil, "不能对比同一个流程实例")
+	}
+
+	left, err := e.instanceRepo.GetByID(ctx, leftID)
+	if err != nil {
+		return nil, fmt.Errorf("获取流程实例 %d 失败: %w", leftID, err)
+	}
+	right, err := e.instanceRepo.GetByID(ctx, rightID)
+	if err != nil {
+		return nil, fmt.Errorf("获取流程实例 %d 失败: %w", rightID, err)
+	}
+	if left.Definition.Key != right.Definition.Key {
+		return nil, newEngineError(CodeInvalidRequest, nil, "只能对比同一流程的实例")
+	}
+
+	labels := e.newLabelResolver(&right.Definition)
+	now := time.Now()
+	comparison := &InstanceComparison{
+		Left:           buildCompareSide(left, labels, now),
+		Right:          buildCompareSide(right, labels, now),
+		PathDivergesAt: -1,
+	}
+	comparison.DurationDelta = comparison.Right.DurationSeconds - comparison.Left.DurationSeconds
+
+	leftPath, rightPath := comparison.Left.Path, comparison.Right.Path
+	for i := 0; i < len(leftPath) || i < len(rightPath); i++ {
+		if i >= len(leftPath) || i >= len(rightPath) || leftPath[i] != rightPath[i] {
+			comparison.PathDivergesAt = i
+			break
+		}
+	}
+
+	comparison.NodeDurations = compareNodeDurations(comparison.Left.Nodes, comparison.Right.Nodes)
+
+	// 任一实例需要脱敏时只返回变量名
+	masked := left.Definition.DataPolicy().MaskVariablesInLists || right.Definition.DataPolicy().MaskVariablesInLists
+	leftVars, err := decodeInstanceVariables(left)
+	if err != nil {
+		return nil, err
+	}
+	rightVars, err := decodeInstanceVariables(right)
+	if err != nil {
+		return nil, err
+	}
+	comparison.VariableDiffs = compareVariables(leftVars, rightVars, masked)
+	comparison.VariablesMasked = masked
+
+	return comparison, nil
+}
+
+// buildCompareSide 根据实例的任务推导执行路径和节点耗时，未结束的任务计算到当前时间
+func buildCompareSide(instance *model.ProcessInstance, labels *labelResolver, now time.Time) *InstanceCompareSide {
+	side := &InstanceCompareSide{
+		InstanceID:   instance.ID,
+		BusinessKey:  instance.BusinessKey,
+		DefinitionID: instance.DefinitionID,
+		Version:      instance.Definition.Version,
+		Status:       instance.Status,
+		StartTime:    instance.StartTime,
+		EndTime:      instance.EndTime,
+		Path:         []string{},
+		Nodes:        []NodeVisit{},
+	}
+
+	end := now
+	if instance.EndTime != nil {
+		end = *instance.EndTime
+	}
+	side.DurationSeconds = end.Sub(instance.StartTime).Seconds()
+
+	tasks := make([]model.TaskInstance, len(instance.Tasks))
+	copy(tasks, instance.Tasks)
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].CreatedAt.Equal(tasks[j].CreatedAt) {
+			return tasks[i].ID < tasks[j].ID
+		}
+		return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
+	})
+
+	visits := make(map[string]int)
+	for _, task := range tasks {
+		i, ok := visits[task.NodeID]
+		if !ok {
+			i = len(side.Nodes)
+			visits[task.NodeID] = i
+			side.Nodes = append(side.Nodes, NodeVisit{NodeID: task.NodeID, NodeLabel: labels.nodeLabel(task.NodeID)})
+		}
+		// 同一节点连续产生的多个任务（如会签）只记一次路径
+		if len(side.Path) == 0 || side.Path[len(side.Path)-1] != task.NodeID {
+			side.Path = append(side.Path, task.NodeID)
+		}
+
+		finished := end
+		if task.CompleteTime != nil {
+			finished = *task.CompleteTime
+		}
+		side.Nodes[i].Visits++
+		side.Nodes[i].Status = task.Status
+		side.Nodes[i].DurationSeconds += finished.Sub(task.CreatedAt).Seconds()
+	}
+
+	if instance.Status == model.InstanceStatusCompleted && instance.CurrentNode != "" {
+		side.Path = append(side.Path, instance.CurrentNode)
+	}
+
+	return side
+}
+
+// compareNodeDurations 合并两侧节点耗时并按差值绝对值排序
+func compareNodeDurations(left, right []NodeVisit) []NodeDurationDiff {
+	diffs := []NodeDurationDiff{}
+	index := make(map[string]int)
+	for _, visit := range left {
+		seconds := visit.DurationSeconds
+		index[visit.NodeID] = len(diffs)
+		diffs = append(diffs, NodeDurationDiff{NodeID: visit.NodeID, NodeLabel: visit.NodeLabel, Left: &seconds})
+	}
+	for _, visit := range right {
+		seconds := visit.DurationSeconds
+		if i, ok := index[visit.NodeID]; ok {
+			diffs[i].Right = &seconds
+			continue
+		}
+		diffs = append(diffs, NodeDurationDiff{NodeID: visit.NodeID, NodeLabel: visit.NodeLabel, Right: &seconds})
+	}
+
+	for i := range diffs {
+		var l, r float64
+		if diffs[i].Left != nil {
+			l = *diffs[i].Left
+		}
+		if diffs[i].Right != nil {
+			r = *diffs[i].Right
+		}
+		diffs[i].DeltaSeconds = r - l
+	}
+	sort.SliceStable(diffs, func(i, j int) bool {
+		return math.Abs(diffs[i].DeltaSeconds) > math.Abs(diffs[j].DeltaSeconds)
+	})
+	return diffs
+}
+
+// compareVariables 比较两侧流程变量，按变量名排序
+func compareVariables(left, right map[string]interface{}, masked bool) []VariableDiff {
+	diffs := []VariableDiff{}
+	for name, l := range left {
+		r, ok := right[name]
+		switch {
+		case !ok:
+			diffs = append(diffs, VariableDiff{Name: name, Change: VariableDiffRemoved, Left: l})
+		case !reflect.DeepEqual(l, r):
+			diffs = append(diffs, VariableDiff{Name: name, Change: VariableDiffChanged, Left: l, Right: r})
+		}
+	}
+	for name, r := range right {
+		if _, ok := left[name]; !ok {
+			diffs = append(diffs, VariableDiff{Name: name, Change: VariableDiffAdded, Right: r})
+		}
+	}
+
+	if masked {
+		for i := range diffs {
+			diffs[i].Left, diffs[i].Right = nil, nil
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Name < diffs[j].Name })
+	return diffs
+}
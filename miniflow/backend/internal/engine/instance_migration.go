@@ -0,0 +1,250 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/config"
+
+	"go.uber.org/zap"
+)
+
+// migrationPosition 实例迁移时需要落到新版本流程图上的节点，source 描述它来自哪里
+type migrationPosition struct {
+	nodeID string
+	source string
+}
+
+// MigrateInstance 把运行中或暂停的流程实例迁移到同一流程的另一个已发布版本，需要 instance_migrate 权限
+//
+// 实例当前节点、未结束任务、等待中的定时器、汇聚网关到达记录和子实例所在的节点按 nodeMapping 映射到新版本，
+// 没有映射的节点沿用原ID。映射后的节点必须在新版本中存在且类型不变，边界定时器的连线和到达汇聚网关的连线
+// 也必须存在，任何一项不满足时不做修改并返回全部问题。迁移不改变实例状态和变量，也不重新执行节点。
+func (e *ProcessEngine) MigrateInstance(ctx context.Context, instanceID uint, targetVersion int, nodeMapping map[string]string, userID uint) (*model.ProcessInstance, error) {
+	if err := e.checkPermission(ctx, userID, config.PermissionInstanceMigrate, "迁移流程实例"); err != nil {
+		return nil, err
+	}
+
+	instance, err := e.instanceRepo.GetByID(ctx, instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("获取流程实例失败: %w", err)
+	}
+	if instance.Status != model.InstanceStatusRunning && instance.Status != model.InstanceStatusSuspended {
+		return nil, newEngineError(CodeInvalidStateTransition, nil, "只能迁移运行中或暂停的流程实例，当前状态为 %s", instance.Status)
+	}
+
+	target, err := e.processRepo.GetByKeyAndVersion(ctx, instance.Definition.Key, targetVersion)
+	if err != nil {
+		return nil, newEngineError(CodeDefinitionNotFound, err, "流程 %s 没有版本 %d", instance.Definition.Key, targetVersion)
+	}
+	if target.ID == instance.DefinitionID {
+		return nil, newEngineError(CodeMigrationInvalid, nil, "流程实例已经使用版本 %d", targetVersion)
+	}
+	if target.Status != model.ProcessStatusPublished {
+		return nil, newEngineError(CodeMigrationInvalid, nil, "只能迁移到已发布的版本，版本 %d 的状态为 %s", targetVersion, target.Status)
+	}
+
+	sourceData, err := instance.Definition.GetDefinitionData()
+	if err != nil {
+		return nil, newEngineError(CodeInvalidDefinition, err, "解析流程定义失败")
+	}
+	targetData, err := target.GetDefinitionData()
+	if err != nil {
+		return nil, newEngineError(CodeInvalidDefinition, err, "解析目标版本的流程定义失败")
+	}
+
+	mapping, err := e.planMigration(ctx, instance, sourceData, targetData, nodeMapping)
+	if err != nil {
+		return nil, err
+	}
+
+	fromVersion := instance.Definition.Version
+	if err := e.instanceRepo.MigrateInstance(ctx, instance, target.ID, mapping); err != nil {
+		return nil, fmt.Errorf("迁移流程实例失败: %v", err)
+	}
+
+	e.traceFor(instance).record(ctx, model.TraceCategoryWrite, instance.CurrentNode, map[string]interface{}{
+		"from_version": fromVersion,
+		"to_version":   target.Version,
+		"node_mapping": mapping,
+	}, "流程实例从版本 %d 迁移到版本 %d", fromVersion, target.Version)
+	e.publishInstanceEvent(EventProcessMigrated, instance, userID, map[string]interface{}{
+		"from_version":  fromVersion,
+		"to_version":    target.Version,
+		"definition_id": target.ID,
+		"node_mapping":  mapping,
+	})
+
+	e.logger.Info("Process instance migrated",
+		zap.Uint("instance_id", instanceID),
+		zap.Int("from_version", fromVersion),
+		zap.Int("to_version", target.Version),
+		zap.Uint("user_id", userID),
+	)
+
+	return e.GetInstance(ctx, instanceID)
+}
+
+// planMigration 校验实例的所有节点都能落到新版本上，返回实际需要改名的节点映射
+func (e *ProcessEngine) planMigration(ctx context.Context, instance *model.ProcessInstance, source, target *model.ProcessDefinitionData, nodeMapping map[string]string) (map[string]string, error) {
+	var problems []string
+
+	for from, to := range nodeMapping {
+		if e.findNodeByID(source.Nodes, from) == nil {
+			problems = append(problems, fmt.Sprintf("映射的节点 %s 不在当前版本中", from))
+		}
+		if e.findNodeByID(target.Nodes, to) == nil {
+			problems = append(problems, fmt.Sprintf("映射的目标节点 %s 不在目标版本中", to))
+		}
+	}
+
+	positions, err := e.migrationPositions(ctx, instance)
+	if err != nil {
+		return nil, err
+	}
+
+	mapping := make(map[string]string)
+	for _, position := range positions {
+		targetID := migratedNodeID(position.nodeID, nodeMapping)
+		targetNode := e.findNodeByID(target.Nodes, targetID)
+		if targetNode == nil {
+			problems = append(problems, fmt.Sprintf("%s 所在的节点 %s 不在目标版本中，请在 node_mapping 中指定", position.source, targetID))
+			continue
+		}
+		if sourceNode := e.findNodeByID(source.Nodes, position.nodeID); sourceNode != nil && sourceNode.Type != targetNode.Type {
+			problems = append(problems, fmt.Sprintf("%s 所在的节点 %s（%s）映射到的节点 %s 类型为 %s", position.source, position.nodeID, sourceNode.Type, targetID, targetNode.Type))
+			continue
+		}
+		if targetID != position.nodeID {
+			mapping[position.nodeID] = targetID
+		}
+	}
+
+	problems = append(problems, e.checkMigratedTimers(ctx, instance.ID, target, nodeMapping)...)
+	problems = append(problems, e.checkMigratedArrivals(ctx, instance.ID, target, nodeMapping)...)
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, newEngineError(CodeMigrationInvalid, nil, "流程实例无法迁移: %s", strings.Join(problems, "；"))
+	}
+	return mapping, nil
+}
+
+// migrationPositions 收集实例当前占用的所有节点，同一个节点只出现一次
+func (e *ProcessEngine) migrationPositions(ctx context.Context, instance *model.ProcessInstance) ([]migrationPosition, error) {
+	var positions []migrationPosition
+	seen := make(map[string]bool)
+	add := func(nodeID, source string) {
+		if nodeID == "" || seen[nodeID] {
+			return
+		}
+		seen[nodeID] = true
+		positions = append(positions, migrationPosition{nodeID: nodeID, source: source})
+	}
+
+	add(instance.CurrentNode, "实例当前节点")
+	for _, task := range instance.Tasks {
+		switch task.Status {
+		case model.TaskStatusCreated, model.TaskStatusAssigned, model.TaskStatusClaimed, model.TaskStatusInProgress:
+			add(task.NodeID, fmt.Sprintf("任务 %d", task.ID))
+		}
+	}
+
+	timers, err := e.instanceRepo.GetWaitingTimers(ctx, instance.ID)
+	if err != nil {
+		return nil, fmt.Errorf("获取定时器失败: %w", err)
+	}
+	for _, timer := range timers {
+		add(timer.NodeID, fmt.Sprintf("定时器 %d", timer.ID))
+	}
+
+	messages, err := e.instanceRepo.GetWaitingMessages(ctx, instance.ID)
+	if err != nil {
+		return nil, fmt.Errorf("获取消息订阅失败: %w", err)
+	}
+	for _, message := range messages {
+		add(message.NodeID, fmt.Sprintf("消息订阅 %d", message.ID))
+	}
+
+	arrivals, err := e.instanceRepo.GetInstancePendingArrivals(ctx, instance.ID)
+	if err != nil {
+		return nil, fmt.Errorf("获取汇聚网关到达记录失败: %w", err)
+	}
+	for _, arrival := range arrivals {
+		add(arrival.GatewayID, "汇聚网关到达记录")
+	}
+
+	children, err := e.instanceRepo.GetChildren(ctx, instance.ID)
+	if err != nil {
+		return nil, fmt.Errorf("获取子实例失败: %w", err)
+	}
+	for _, child := range children {
+		if child.Status == model.InstanceStatusRunning || child.Status == model.InstanceStatusSuspended {
+			add(child.ParentNodeID, fmt.Sprintf("子实例 %d", child.ID))
+		}
+	}
+
+	return positions, nil
+}
+
+// checkMigratedTimers 边界定时器到期后沿指定连线推进，连线必须是映射后节点在目标版本中的出口连线
+func (e *ProcessEngine) checkMigratedTimers(ctx context.Context, instanceID uint, target *model.ProcessDefinitionData, nodeMapping map[string]string) []string {
+	timers, err := e.instanceRepo.GetWaitingTimers(ctx, instanceID)
+	if err != nil {
+		return []string{fmt.Sprintf("获取定时器失败: %v", err)}
+	}
+
+	var problems []string
+	for _, timer := range timers {
+		if timer.Kind != model.TimerKindBoundary {
+			continue
+		}
+		nodeID := migratedNodeID(timer.NodeID, nodeMapping)
+		found := false
+		for _, flow := range e.findOutgoingFlows(target.Flows, nodeID) {
+			if flow.ID == timer.FlowID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("定时器 %d 的连线 %s 不是目标版本中节点 %s 的出口连线", timer.ID, timer.FlowID, nodeID))
+		}
+	}
+	return problems
+}
+
+// checkMigratedArrivals 已到达汇聚网关的分支必须仍是目标版本中该网关的入口连线，否则汇聚永远无法完成
+func (e *ProcessEngine) checkMigratedArrivals(ctx context.Context, instanceID uint, target *model.ProcessDefinitionData, nodeMapping map[string]string) []string {
+	arrivals, err := e.instanceRepo.GetInstancePendingArrivals(ctx, instanceID)
+	if err != nil {
+		return []string{fmt.Sprintf("获取汇聚网关到达记录失败: %v", err)}
+	}
+
+	var problems []string
+	for _, arrival := range arrivals {
+		gatewayID := migratedNodeID(arrival.GatewayID, nodeMapping)
+		found := false
+		for i := range target.Flows {
+			if target.Flows[i].To == gatewayID && target.Flows[i].FlowKey() == arrival.FlowKey {
+				found = true
+				break
+			}
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("汇聚网关 %s 已到达的连线 %s 不在目标版本中", gatewayID, arrival.FlowKey))
+		}
+	}
+	return problems
+}
+
+// migratedNodeID 返回节点迁移后的ID，没有映射时沿用原ID
+func migratedNodeID(nodeID string, nodeMapping map[string]string) string {
+	if mapped, ok := nodeMapping[nodeID]; ok {
+		return mapped
+	}
+	return nodeID
+}
@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"context"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// PurgeInstance 物理清除已结束的流程实例、其子实例及全部关联数据，返回每个实例的清除凭证
+func (e *ProcessEngine) PurgeInstance(ctx context.Context, instanceID uint, userID uint, reason string) ([]model.PurgeCertificate, error) {
+	certificates, err := e.instanceRepo.PurgeInstance(ctx, instanceID, userID, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cert := range certificates {
+		e.logger.Info("Process instance purged",
+			zap.Uint("instance_id", cert.InstanceID),
+			zap.Uint("purged_by", userID),
+			zap.String("digest", cert.Digest),
+			zap.String("rows", cert.Rows),
+		)
+		e.events.Publish(Event{
+			Type:       EventProcessPurged,
+			InstanceID: cert.InstanceID,
+			UserID:     userID,
+			Data:       map[string]interface{}{"digest": cert.Digest},
+		})
+	}
+	return certificates, nil
+}
+
+// GetPurgeCertificates 获取已清除实例的清除凭证
+func (e *ProcessEngine) GetPurgeCertificates(ctx context.Context, instanceID uint) ([]model.PurgeCertificate, error) {
+	return e.instanceRepo.GetPurgeCertificates(ctx, instanceID)
+}
@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"miniflow/internal/model"
+	"miniflow/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+// maxInstanceUpdateAttempts 实例版本冲突时最多尝试更新的次数
+const maxInstanceUpdateAttempts = 5
+
+// updateInstance 修改并以乐观锁保存流程实例
+//
+// apply 在实例上做本次操作负责的修改。保存时版本冲突说明实例已被并发的操作更新，
+// 此时重新读取实例，在最新数据上再次执行 apply 后重试，因此 apply 可能执行多次，
+// 只应修改自己负责的字段，并在需要时重新校验实例状态；apply 返回错误时放弃更新。
+// 成功后 instance 为保存后的最新数据。
+func (e *ProcessEngine) updateInstance(ctx context.Context, instance *model.ProcessInstance, apply func(*model.ProcessInstance) error) error {
+	if err := apply(instance); err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		version := instance.Version
+		err := e.instanceRepo.Update(ctx, instance)
+		e.traceFor(instance).record(ctx, model.TraceCategoryWrite, instance.CurrentNode, map[string]interface{}{
+			"attempt": attempt,
+			"version": version,
+			"status":  instance.Status,
+			"ok":      err == nil,
+		}, "更新流程实例（版本 %d，第 %d 次尝试）", version, attempt)
+		if err == nil {
+			// 实例变量可能随实例一起修改，丢弃变量存储的缓存
+			e.variableEngine.Invalidate(instance.ID)
+			return nil
+		}
+		if !errors.Is(err, repository.ErrInstanceVersionConflict) {
+			return fmt.Errorf("更新流程实例失败: %v", err)
+		}
+		if attempt >= maxInstanceUpdateAttempts {
+			return newEngineError(CodeConcurrentModification, err, "流程实例 %d 并发修改冲突，重试 %d 次后仍未成功", instance.ID, attempt)
+		}
+
+		e.logger.Info("Process instance modified concurrently, retrying update",
+			zap.Uint("instance_id", instance.ID),
+			zap.Int("attempt", attempt),
+		)
+
+		latest, err := e.instanceRepo.GetByID(ctx, instance.ID)
+		if err != nil {
+			return fmt.Errorf("重新获取流程实例失败: %v", err)
+		}
+		*instance = *latest
+		if err := apply(instance); err != nil {
+			return err
+		}
+	}
+}
+
+// moveInstanceTo 以乐观锁更新实例的当前节点
+func (e *ProcessEngine) moveInstanceTo(ctx context.Context, instance *model.ProcessInstance, nodeID string) error {
+	return e.updateInstance(ctx, instance, func(target *model.ProcessInstance) error {
+		target.CurrentNode = nodeID
+		return nil
+	})
+}
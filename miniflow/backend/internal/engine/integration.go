@@ -0,0 +1,46 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"miniflow/internal/model"
+)
+
+// GetNewUserTasks 获取游标之后新产生的用户任务，供集成平台轮询
+func (e *ProcessEngine) GetNewUserTasks(ctx context.Context, userID uint, afterID uint, limit int) ([]model.TaskInstance, error) {
+	role, err := e.candidateRole(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := e.taskRepo.GetUserTasksAfter(ctx, userID, role, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("获取新任务失败: %w", err)
+	}
+	e.applyTaskListLabels(tasks)
+	redactTaskList(tasks)
+	return tasks, nil
+}
+
+// GetCompletedInstancesSince 获取游标之后完成的、由用户发起的流程实例，供集成平台轮询
+func (e *ProcessEngine) GetCompletedInstancesSince(ctx context.Context, starterID uint, since time.Time, afterID uint, limit int) ([]model.ProcessInstance, error) {
+	instances, err := e.instanceRepo.GetCompletedSince(ctx, starterID, since, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("获取已完成流程实例失败: %w", err)
+	}
+	for i := range instances {
+		e.applyInstanceLabels(&instances[i])
+		instances[i].RedactForList()
+	}
+	return instances, nil
+}
+
+// ResolvePublishedDefinition 根据流程标识获取最新的已发布版本
+func (e *ProcessEngine) ResolvePublishedDefinition(ctx context.Context, key string) (*model.ProcessDefinition, error) {
+	definition, err := e.processRepo.GetLatestPublishedByKey(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("获取流程定义失败: %w", err)
+	}
+	return definition, nil
+}
@@ -0,0 +1,256 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/internal/repository"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 作业面板的错误
+var (
+	ErrUnknownJobType  = &EngineError{Code: CodeInvalidRequest, Message: "未知的作业类型"}
+	ErrJobNotFound     = &EngineError{Code: CodeNotFound, Message: "作业不存在"}
+	ErrJobNotRetryable = &EngineError{Code: CodeInvalidStateTransition, Message: "作业当前状态不允许该操作"}
+)
+
+// jobBacklogStatuses 计入积压的作业状态
+var jobBacklogStatuses = map[string][]string{
+	model.JobTypeTimer:        {model.TimerStatusWaiting, model.TimerStatusFailed},
+	model.JobTypeWebhook:      {model.WebhookDeliveryPending, model.WebhookDeliveryFailed},
+	model.JobTypeNotification: {model.NotificationJobPending, model.NotificationJobFailed},
+	model.JobTypeAsync:        {model.AsyncJobStatusPending, model.AsyncJobStatusFailed},
+}
+
+// jobAgeBuckets 积压时长直方图的分桶，按距离到期时间的时长划分
+var jobAgeBuckets = []struct {
+	label string
+	min   time.Duration
+	max   time.Duration
+}{
+	{label: "<5m", min: 0, max: 5 * time.Minute},
+	{label: "5m-1h", min: 5 * time.Minute, max: time.Hour},
+	{label: "1h-24h", min: time.Hour, max: 24 * time.Hour},
+	{label: ">24h", min: 24 * time.Hour},
+}
+
+// JobAgeBucket 积压时长直方图的一个分桶
+type JobAgeBucket struct {
+	Label string `json:"label"`
+	Count int64  `json:"count"`
+}
+
+// JobTypeSummary 一类后台作业的概况
+type JobTypeSummary struct {
+	Type     string                      `json:"type"`
+	Statuses []repository.JobStatusCount `json:"statuses"`
+	// NotDue 尚未到期的积压作业（如未到期的定时器），不计入直方图
+	NotDue int64          `json:"not_due"`
+	Ages   []JobAgeBucket `json:"age_histogram"`
+}
+
+// JobSummary 作业面板概况
+type JobSummary struct {
+	Jobs          []JobTypeSummary `json:"jobs"`
+	OpenIncidents int64            `json:"open_incidents"`
+	GeneratedAt   time.Time        `json:"generated_at"`
+}
+
+// JobDashboard 运维作业面板，汇总定时器、回调投递、延迟通知和异步作业等后台作业
+// 服务任务失败的重试通过异常事件处理，这里只统计未处理的数量
+type JobDashboard struct {
+	engine   *ProcessEngine
+	jobRepo  *repository.JobRepository
+	webhooks *WebhookDispatcher
+	logger   *logger.Logger
+}
+
+// NewJobDashboard 创建作业面板
+func NewJobDashboard(engine *ProcessEngine, jobRepo *repository.JobRepository, webhooks *WebhookDispatcher, logger *logger.Logger) *JobDashboard {
+	return &JobDashboard{
+		engine:   engine,
+		jobRepo:  jobRepo,
+		webhooks: webhooks,
+		logger:   logger,
+	}
+}
+
+// Summary 统计各类作业的状态分布和积压时长
+func (d *JobDashboard) Summary(ctx context.Context, now time.Time) (*JobSummary, error) {
+	summary := &JobSummary{GeneratedAt: now}
+
+	for _, jobType := range model.JobTypes {
+		statuses, err := d.jobRepo.CountByStatus(ctx, jobType)
+		if err != nil {
+			return nil, err
+		}
+
+		backlog := jobBacklogStatuses[jobType]
+		notDue, err := d.jobRepo.CountByAge(ctx, jobType, backlog, &now, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		ages := make([]JobAgeBucket, 0, len(jobAgeBuckets))
+		for _, bucket := range jobAgeBuckets {
+			// 积压时长越大，时间列越早：时长区间对应时间区间 [now-max, now-min)
+			var from *time.Time
+			if bucket.max > 0 {
+				t := now.Add(-bucket.max)
+				from = &t
+			}
+			to := now.Add(-bucket.min)
+
+			count, err := d.jobRepo.CountByAge(ctx, jobType, backlog, from, &to)
+			if err != nil {
+				return nil, err
+			}
+			ages = append(ages, JobAgeBucket{Label: bucket.label, Count: count})
+		}
+
+		summary.Jobs = append(summary.Jobs, JobTypeSummary{
+			Type:     jobType,
+			Statuses: statuses,
+			NotDue:   notDue,
+			Ages:     ages,
+		})
+	}
+
+	_, openIncidents, err := d.engine.GetIncidents(ctx, 0, 1, map[string]interface{}{"status": model.IncidentStatusOpen})
+	if err != nil {
+		return nil, err
+	}
+	summary.OpenIncidents = openIncidents
+
+	return summary, nil
+}
+
+// ListJobs 按类型和状态分页获取作业
+func (d *JobDashboard) ListJobs(ctx context.Context, jobType, status string, offset, limit int) (interface{}, int64, error) {
+	switch jobType {
+	case model.JobTypeTimer:
+		return d.jobRepo.ListTimers(ctx, status, offset, limit)
+	case model.JobTypeWebhook:
+		return d.jobRepo.ListWebhookDeliveries(ctx, status, offset, limit)
+	case model.JobTypeNotification:
+		return d.jobRepo.ListNotifications(ctx, status, offset, limit)
+	case model.JobTypeAsync:
+		return d.jobRepo.ListAsyncJobs(ctx, status, offset, limit)
+	}
+	return nil, 0, ErrUnknownJobType
+}
+
+// RetryJob 立即重试作业：定时器、延迟通知和异步作业改为立即到期，由后台循环处理；失败的回调重新投递
+func (d *JobDashboard) RetryJob(ctx context.Context, jobType string, id uint, now time.Time) error {
+	var (
+		ok  bool
+		err error
+	)
+	switch jobType {
+	case model.JobTypeTimer:
+		ok, err = d.jobRepo.RetryTimer(ctx, id, now)
+	case model.JobTypeNotification:
+		ok, err = d.jobRepo.RetryNotification(ctx, id, now)
+	case model.JobTypeAsync:
+		ok, err = d.jobRepo.RetryAsyncJob(ctx, id, now)
+	case model.JobTypeWebhook:
+		ok, err = d.retryWebhookDelivery(ctx, id)
+	default:
+		return ErrUnknownJobType
+	}
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return d.missingOrNotRetryable(ctx, jobType, id)
+	}
+
+	d.logger.Info("Job retried by operator",
+		zap.String("job_type", jobType),
+		zap.Uint("job_id", id),
+	)
+	return nil
+}
+
+// DeleteJob 删除作业：定时器和异步作业标记为已取消，延迟通知和回调投递记录直接删除
+func (d *JobDashboard) DeleteJob(ctx context.Context, jobType string, id uint) error {
+	var (
+		ok  bool
+		err error
+	)
+	switch jobType {
+	case model.JobTypeTimer:
+		ok, err = d.jobRepo.CancelTimer(ctx, id)
+	case model.JobTypeNotification:
+		ok, err = d.jobRepo.DeleteNotification(ctx, id)
+	case model.JobTypeAsync:
+		ok, err = d.jobRepo.CancelAsyncJob(ctx, id)
+	case model.JobTypeWebhook:
+		ok, err = d.jobRepo.DeleteWebhookDelivery(ctx, id)
+	default:
+		return ErrUnknownJobType
+	}
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return d.missingOrNotRetryable(ctx, jobType, id)
+	}
+
+	d.logger.Info("Job deleted by operator",
+		zap.String("job_type", jobType),
+		zap.Uint("job_id", id),
+	)
+	return nil
+}
+
+// missingOrNotRetryable 条件更新没有命中时区分作业不存在和状态不允许
+func (d *JobDashboard) missingOrNotRetryable(ctx context.Context, jobType string, id uint) error {
+	exists, err := d.jobRepo.Exists(ctx, jobType, id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrJobNotFound
+	}
+	return ErrJobNotRetryable
+}
+
+// retryWebhookDelivery 重新投递失败的回调，事件订阅的投递交给订阅投递器处理
+func (d *JobDashboard) retryWebhookDelivery(ctx context.Context, id uint) (bool, error) {
+	delivery, err := d.engine.instanceRepo.GetWebhookDelivery(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if delivery.SubscriptionID != nil {
+		return d.webhooks.RetryDelivery(ctx, delivery)
+	}
+	return d.engine.RetryWebhookDelivery(ctx, delivery)
+}
+
+// RetryWebhookDelivery 重新投递失败的完成回调，使用流程定义当前的签名密钥
+func (e *ProcessEngine) RetryWebhookDelivery(ctx context.Context, delivery *model.WebhookDelivery) (bool, error) {
+
+	instance, err := e.instanceRepo.GetByID(ctx, delivery.InstanceID)
+	if err != nil {
+		return false, err
+	}
+
+	ok, err := e.instanceRepo.RequeueWebhookDelivery(ctx, delivery.ID)
+	if err != nil || !ok {
+		return ok, err
+	}
+	delivery.Status = model.WebhookDeliveryPending
+
+	e.deliverWebhookAsync(ctx, delivery, instance.Definition.CompletionWebhookSecret)
+	return true, nil
+}
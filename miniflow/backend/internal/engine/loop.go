@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/expression"
+
+	"go.uber.org/zap"
+)
+
+// repeatLoop 声明了循环的节点完成一轮后决定是否再次执行，返回节点是否已重新执行
+//
+// 固定次数的循环执行满次数后结束；条件循环在每轮完成后评估条件，条件成立且未达到最大次数时继续。
+// 评估条件时变量 loopCounter 为已完成的轮数。循环结束后清除计数，之后再次进入节点会开始新一轮循环。
+func (e *ProcessEngine) repeatLoop(ctx context.Context, instance *model.ProcessInstance, node *model.ProcessNode) (bool, error) {
+	loop, err := model.GetLoop(node)
+	if err != nil {
+		return false, newEngineError(CodeInvalidDefinition, err, "节点 %s 的循环配置无效", node.ID)
+	}
+	if loop == nil {
+		return false, nil
+	}
+
+	iterations := 0
+	if err := e.updateInstance(ctx, instance, func(target *model.ProcessInstance) error {
+		counters := target.GetLoopCounters()
+		counters[node.ID]++
+		iterations = counters[node.ID]
+		return target.SetLoopCounters(counters)
+	}); err != nil {
+		return false, err
+	}
+
+	repeat := false
+	if loop.Times > 0 {
+		repeat = iterations < loop.Times
+	} else if iterations < loop.MaxIterations {
+		repeat, err = e.evaluateLoopCondition(ctx, instance, node, loop, iterations)
+		if err != nil {
+			if incidentErr := e.raiseIncident(ctx, instance, nil, node, model.IncidentTypeConditionFailed, err); incidentErr != nil {
+				return true, incidentErr
+			}
+			return true, err
+		}
+	} else {
+		e.logger.Warn("Loop stopped at max iterations",
+			zap.Uint("instance_id", instance.ID),
+			zap.String("node_id", node.ID),
+			zap.Int("max_iterations", loop.MaxIterations),
+		)
+	}
+
+	e.traceFor(instance).record(ctx, model.TraceCategoryNode, node.ID, map[string]interface{}{
+		"iterations": iterations,
+		"repeat":     repeat,
+	}, "循环节点 %s 已完成 %d 轮，继续循环: %t", node.ID, iterations, repeat)
+
+	if !repeat {
+		if err := e.updateInstance(ctx, instance, func(target *model.ProcessInstance) error {
+			counters := target.GetLoopCounters()
+			delete(counters, node.ID)
+			return target.SetLoopCounters(counters)
+		}); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+
+	e.recordNodeActivity(ctx, instance, node, model.ActivityNodeExited, map[string]interface{}{
+		"loop_iterations": iterations,
+	})
+	if boundary, _ := model.GetBoundaryTimer(node); boundary != nil {
+		if err := e.instanceRepo.CancelTimers(ctx, instance.ID, node.ID); err != nil {
+			return true, fmt.Errorf("取消边界定时器失败: %v", err)
+		}
+	}
+	return true, e.moveToNextNode(ctx, instance, node.ID)
+}
+
+// evaluateLoopCondition 评估循环条件，变量 loopCounter 为已完成的轮数
+func (e *ProcessEngine) evaluateLoopCondition(ctx context.Context, instance *model.ProcessInstance, node *model.ProcessNode, loop *model.Loop, iterations int) (bool, error) {
+	expr, err := expression.ParseCondition(loop.Condition)
+	if err != nil {
+		return false, newEngineError(CodeConditionFailed, err, "节点 %s 的循环条件无效", node.ID)
+	}
+	variables, err := e.variableEngine.GetVariables(ctx, instance.ID, expr.Variables())
+	if err != nil {
+		return false, err
+	}
+	variables[model.LoopVariable] = iterations
+
+	result, err := e.variableEngine.EvaluateCondition(loop.Condition, variables)
+	if err != nil {
+		return false, newEngineError(CodeConditionFailed, err, "节点 %s 的循环条件 %s 评估失败", node.ID, loop.Condition)
+	}
+	return result, nil
+}
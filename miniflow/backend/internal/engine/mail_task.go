@@ -0,0 +1,109 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"miniflow/internal/model"
+	"miniflow/internal/notification"
+
+	"go.uber.org/zap"
+)
+
+// MailSender 邮件任务的邮件发送器，服务端通过通知模块的邮件渠道发送
+type MailSender interface {
+	SendMail(ctx context.Context, to, cc []string, subject, body string) error
+}
+
+// handleMailTask 处理邮件任务节点，发送成功后自动继续；失败时生成异常事件并停留在当前节点，可通过重试恢复
+func (e *ProcessEngine) handleMailTask(ctx context.Context, instance *model.ProcessInstance, node *model.ProcessNode) error {
+	task := &model.TaskInstance{
+		InstanceID: instance.ID,
+		NodeID:     node.ID,
+		Name:       node.Name,
+		Status:     model.TaskStatusCreated,
+		Priority:   50, // 默认优先级
+	}
+	if err := e.taskRepo.Create(ctx, task); err != nil {
+		return fmt.Errorf("创建邮件任务失败: %v", err)
+	}
+	e.traceFor(instance).record(ctx, model.TraceCategoryWrite, node.ID, map[string]interface{}{"task_id": task.ID}, "创建邮件任务 %d", task.ID)
+
+	if err := e.executeMailTask(ctx, instance, task, node); err != nil {
+		e.logger.Error("Mail task execution failed", zap.Error(err))
+		return e.failServiceTask(ctx, instance, task, node, model.IncidentTypeMailFailed, err)
+	}
+
+	return e.completeServiceTask(ctx, instance, task, node)
+}
+
+// executeMailTask 使用流程变量渲染邮件并发送
+func (e *ProcessEngine) executeMailTask(ctx context.Context, instance *model.ProcessInstance, task *model.TaskInstance, node *model.ProcessNode) error {
+	cfg, err := model.GetMailTaskConfig(node)
+	if err != nil {
+		return newEngineError(CodeMailFailed, err, "节点 %s 的邮件配置无效", node.ID)
+	}
+	if e.mailSender == nil {
+		return newEngineError(CodeMailFailed, nil, "没有可用的邮件发送器")
+	}
+
+	starter, _ := e.userRepo.GetByID(ctx, instance.StarterID)
+	data := notification.NewTemplateData(starter, instance, task)
+
+	to, err := renderMailRecipients(cfg.To, data)
+	if err != nil {
+		return err
+	}
+	if len(to) == 0 {
+		return newEngineError(CodeMailFailed, nil, "节点 %s 的收件人为空", node.ID)
+	}
+	cc, err := renderMailRecipients(cfg.Cc, data)
+	if err != nil {
+		return err
+	}
+	subject, body, err := notification.RenderTemplate(cfg.Subject, cfg.Body, data)
+	if err != nil {
+		return newEngineError(CodeMailFailed, err, "渲染邮件失败")
+	}
+
+	if err := e.mailSender.SendMail(ctx, to, cc, subject, body); err != nil {
+		return newEngineError(CodeMailFailed, err, "发送邮件失败")
+	}
+
+	e.logger.Info("Mail task sent",
+		zap.Uint("task_id", task.ID),
+		zap.Int("recipients", len(to)+len(cc)),
+	)
+	e.traceFor(instance).record(ctx, model.TraceCategoryNode, node.ID, map[string]interface{}{
+		"task_id": task.ID,
+		"to":      to,
+		"cc":      cc,
+		"subject": subject,
+	}, "邮件任务 %d 已发送给 %d 个收件人", task.ID, len(to)+len(cc))
+	return nil
+}
+
+// renderMailRecipients 渲染收件人模板，渲染结果可以是逗号分隔的多个地址，每个地址必须是有效的邮箱
+func renderMailRecipients(templates []string, data *notification.TemplateData) ([]string, error) {
+	var recipients []string
+	for _, text := range templates {
+		rendered, err := notification.RenderText(text, data)
+		if err != nil {
+			return nil, newEngineError(CodeMailFailed, err, "渲染收件人 %s 失败", text)
+		}
+		for _, address := range strings.Split(rendered, ",") {
+			address = strings.TrimSpace(address)
+			if address == "" {
+				continue
+			}
+			parsed, err := mail.ParseAddress(address)
+			if err != nil {
+				return nil, newEngineError(CodeMailFailed, err, "收件人 %s 不是有效的邮箱地址", address)
+			}
+			recipients = append(recipients, parsed.Address)
+		}
+	}
+	return recipients, nil
+}
@@ -0,0 +1,196 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/expression"
+
+	"go.uber.org/zap"
+)
+
+// MessageCorrelation 一次消息投递的结果
+type MessageCorrelation struct {
+	MessageName    string `json:"message_name"`
+	CorrelationKey string `json:"correlation_key"`
+	InstanceIDs    []uint `json:"instance_ids"`
+}
+
+// handleMessageCatch 处理消息捕获节点：创建消息订阅，实例停留在节点上直到消息投递
+func (e *ProcessEngine) handleMessageCatch(ctx context.Context, instance *model.ProcessInstance, node *model.ProcessNode) error {
+	cfg, err := model.GetMessageCatchConfig(node)
+	if err != nil {
+		return newEngineError(CodeInvalidDefinition, err, "消息捕获节点 %s 配置无效", node.ID)
+	}
+	correlationKey, err := e.evaluateCorrelationKey(ctx, instance, cfg)
+	if err != nil {
+		return err
+	}
+
+	subscription := &model.MessageSubscription{
+		InstanceID:     instance.ID,
+		NodeID:         node.ID,
+		MessageName:    cfg.MessageName,
+		CorrelationKey: correlationKey,
+		Status:         model.MessageSubscriptionWaiting,
+	}
+	if err := e.instanceRepo.CreateMessageSubscription(ctx, subscription); err != nil {
+		return fmt.Errorf("创建消息订阅失败: %v", err)
+	}
+	e.traceFor(instance).record(ctx, model.TraceCategoryWrite, node.ID, map[string]interface{}{
+		"subscription_id": subscription.ID,
+		"message_name":    subscription.MessageName,
+		"correlation_key": subscription.CorrelationKey,
+	}, "等待消息 %s（关联键 %s）", subscription.MessageName, subscription.CorrelationKey)
+
+	if err := e.moveInstanceTo(ctx, instance, node.ID); err != nil {
+		return fmt.Errorf("更新流程实例当前节点失败: %v", err)
+	}
+
+	e.logger.Info("Waiting for message",
+		zap.Uint("instance_id", instance.ID),
+		zap.String("node_id", node.ID),
+		zap.String("message_name", subscription.MessageName),
+		zap.String("correlation_key", subscription.CorrelationKey),
+	)
+	return nil
+}
+
+// evaluateCorrelationKey 求值消息捕获节点的关联键，未配置时使用实例的业务键
+func (e *ProcessEngine) evaluateCorrelationKey(ctx context.Context, instance *model.ProcessInstance, cfg *model.MessageCatchConfig) (string, error) {
+	key := cfg.CorrelationKey
+	if key == "" {
+		key = instance.BusinessKey
+	} else if expression.IsTemplate(key) {
+		tmpl, err := expression.ParseTemplate(key)
+		if err != nil {
+			return "", newEngineError(CodeInvalidDefinition, err, "关联键表达式 %s 无效", key)
+		}
+		context, err := e.expressionContext(ctx, instance)
+		if err != nil {
+			return "", err
+		}
+		if key, err = tmpl.RenderString(context); err != nil {
+			return "", newEngineError(CodeInvalidRequest, err, "关联键表达式 %s 求值失败", cfg.CorrelationKey)
+		}
+	}
+
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return "", newEngineError(CodeInvalidRequest, nil, "消息 %s 的关联键为空", cfg.MessageName)
+	}
+	if len(key) > model.MaxCorrelationKeyLength {
+		return "", newEngineError(CodeInvalidRequest, nil, "消息 %s 的关联键超过 %d 个字符", cfg.MessageName, model.MaxCorrelationKeyLength)
+	}
+	return key, nil
+}
+
+// CorrelateMessage 投递消息：与消息名称和关联键匹配的等待中实例合并 variables 后沿消息捕获节点的出口连线推进
+//
+// 暂停的实例保持等待，恢复后可以再次投递；没有运行中的实例在等待该消息时返回 MESSAGE_NOT_CORRELATED。
+func (e *ProcessEngine) CorrelateMessage(ctx context.Context, messageName, correlationKey string, variables map[string]interface{}, userID uint) (*MessageCorrelation, error) {
+	messageName = strings.TrimSpace(messageName)
+	correlationKey = strings.TrimSpace(correlationKey)
+	if messageName == "" || correlationKey == "" {
+		return nil, newEngineError(CodeInvalidRequest, nil, "消息名称和关联键不能为空")
+	}
+
+	subscriptions, err := e.instanceRepo.GetWaitingMessageSubscriptions(ctx, messageName, correlationKey)
+	if err != nil {
+		return nil, fmt.Errorf("获取消息订阅失败: %w", err)
+	}
+
+	result := &MessageCorrelation{MessageName: messageName, CorrelationKey: correlationKey, InstanceIDs: []uint{}}
+	for i := range subscriptions {
+		subscription := &subscriptions[i]
+
+		instance, err := e.instanceRepo.GetByID(ctx, subscription.InstanceID)
+		if err != nil {
+			return nil, fmt.Errorf("获取流程实例失败: %w", err)
+		}
+		switch instance.Status {
+		case model.InstanceStatusRunning:
+		case model.InstanceStatusSuspended:
+			continue
+		default:
+			if err := e.instanceRepo.CancelMessageSubscriptions(ctx, instance.ID, ""); err != nil {
+				e.logger.Error("Failed to cancel message subscriptions of finished instance", zap.Uint("instance_id", instance.ID), zap.Error(err))
+			}
+			continue
+		}
+
+		// 条件更新保证并发投递时每个订阅只被关联一次
+		ok, err := e.instanceRepo.MarkMessageCorrelated(ctx, subscription.ID, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("更新消息订阅状态失败: %v", err)
+		}
+		if !ok {
+			continue
+		}
+
+		if err := e.correlateSubscription(ctx, instance, subscription, variables, userID); err != nil {
+			e.logger.Error("Failed to advance process after message correlated",
+				zap.Uint("subscription_id", subscription.ID),
+				zap.Uint("instance_id", instance.ID),
+				zap.Error(err),
+			)
+			return nil, err
+		}
+		result.InstanceIDs = append(result.InstanceIDs, instance.ID)
+	}
+
+	if len(result.InstanceIDs) == 0 {
+		return nil, newEngineError(CodeMessageNotCorrelated, nil, "没有运行中的流程实例在等待消息 %s（关联键 %s）", messageName, correlationKey)
+	}
+
+	e.logger.Info("Message correlated",
+		zap.String("message_name", messageName),
+		zap.String("correlation_key", correlationKey),
+		zap.Uints("instance_ids", result.InstanceIDs),
+		zap.Uint("user_id", userID),
+	)
+	return result, nil
+}
+
+// correlateSubscription 合并消息携带的变量，沿消息捕获节点的出口连线推进
+func (e *ProcessEngine) correlateSubscription(ctx context.Context, instance *model.ProcessInstance, subscription *model.MessageSubscription, variables map[string]interface{}, userID uint) error {
+	if len(variables) > 0 {
+		current, err := decodeInstanceVariables(instance)
+		if err != nil {
+			return err
+		}
+		for key, value := range variables {
+			current[key] = value
+		}
+		if err := e.saveInstanceVariables(ctx, instance, current); err != nil {
+			return err
+		}
+	}
+
+	definition, err := instance.Definition.GetDefinitionData()
+	if err != nil {
+		return newEngineError(CodeInvalidDefinition, err, "解析流程定义失败")
+	}
+	outgoingFlows := e.findOutgoingFlows(definition.Flows, subscription.NodeID)
+	if len(outgoingFlows) == 0 {
+		return newEngineError(CodeNoOutgoingFlow, nil, "消息捕获节点 %s 没有出口连线", subscription.NodeID)
+	}
+
+	e.traceFor(instance).record(ctx, model.TraceCategoryNode, subscription.NodeID, map[string]interface{}{
+		"subscription_id": subscription.ID,
+		"user_id":         userID,
+		"variables":       len(variables),
+	}, "收到消息 %s（关联键 %s）", subscription.MessageName, subscription.CorrelationKey)
+	if node := e.findNodeByID(definition.Nodes, subscription.NodeID); node != nil {
+		e.recordNodeActivity(ctx, instance, node, model.ActivityNodeExited, map[string]interface{}{"subscription_id": subscription.ID})
+	}
+	for _, flow := range outgoingFlows {
+		if err := e.advanceAlongFlow(ctx, instance, flow, definition); err != nil {
+			return fmt.Errorf("流程推进失败: %w", err)
+		}
+	}
+	return nil
+}
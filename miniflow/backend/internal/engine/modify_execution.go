@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/config"
+
+	"go.uber.org/zap"
+)
+
+// ModifyExecution 把运行中的流程实例强制移动到指定节点，需要 admin 权限
+//
+// 用于跳过卡住的节点、退回到之前的节点或重新执行某个节点。实例上未结束的任务、等待中的定时器和消息订阅、
+// 汇聚网关上未消费的到达记录以及调用活动启动的子实例全部关闭，然后从目标节点重新执行，目标节点为用户任务时
+// 创建新任务。操作原因必填，和关闭的工作一起记录到活动历史中。
+func (e *ProcessEngine) ModifyExecution(ctx context.Context, instanceID uint, targetNodeID, reason string, userID uint) (*model.ProcessInstance, error) {
+	if err := e.checkPermission(ctx, userID, config.PermissionAdmin, "修改流程实例的执行位置"); err != nil {
+		return nil, err
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, newEngineError(CodeInvalidRequest, nil, "修改执行位置必须填写原因")
+	}
+
+	instance, err := e.instanceRepo.GetByID(ctx, instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("获取流程实例失败: %w", err)
+	}
+	if instance.Status != model.InstanceStatusRunning {
+		return nil, newEngineError(CodeInvalidStateTransition, nil, "只能修改运行中的流程实例，当前状态为 %s", instance.Status)
+	}
+
+	definitionData, err := instance.Definition.GetDefinitionData()
+	if err != nil {
+		return nil, newEngineError(CodeInvalidDefinition, err, "解析流程定义失败")
+	}
+	target := e.findNodeByID(definitionData.Nodes, targetNodeID)
+	if target == nil {
+		return nil, newEngineError(CodeNodeNotFound, nil, "找不到节点: %s", targetNodeID)
+	}
+	if target.Type == model.NodeTypeStart {
+		return nil, newEngineError(CodeInvalidRequest, nil, "不能移动到开始节点")
+	}
+
+	fromNode := instance.CurrentNode
+	closeReason := fmt.Sprintf("管理员修改执行位置: %s", reason)
+	detail := map[string]interface{}{
+		"from":   fromNode,
+		"to":     target.ID,
+		"reason": reason,
+	}
+
+	tasks, err := e.cancelInstanceTasks(ctx, instanceID, closeReason)
+	if err != nil {
+		return nil, fmt.Errorf("关闭未完成的任务失败: %v", err)
+	}
+	if len(tasks) > 0 {
+		ids := make([]uint, 0, len(tasks))
+		for id := range tasks {
+			ids = append(ids, id)
+		}
+		detail["skipped_tasks"] = ids
+	}
+	if err := e.instanceRepo.CancelTimers(ctx, instanceID, ""); err != nil {
+		return nil, fmt.Errorf("取消等待中的定时器失败: %v", err)
+	}
+	if err := e.instanceRepo.CancelMessageSubscriptions(ctx, instanceID, ""); err != nil {
+		return nil, fmt.Errorf("取消等待中的消息订阅失败: %v", err)
+	}
+	if err := e.discardGatewayArrivals(ctx, instanceID); err != nil {
+		return nil, err
+	}
+	children, err := e.cancelChildInstances(ctx, instanceID, closeReason)
+	if err != nil {
+		return nil, fmt.Errorf("取消子实例失败: %v", err)
+	}
+	if len(children) > 0 {
+		detail["cancelled_children"] = children
+	}
+
+	e.recordActivity(ctx, &model.ActivityHistory{
+		InstanceID: instance.ID,
+		Type:       model.ActivityExecutionMoved,
+		NodeID:     target.ID,
+		NodeType:   target.Type,
+	}, userID, detail)
+	e.traceFor(instance).record(ctx, model.TraceCategoryWrite, target.ID, detail,
+		"管理员将执行位置从 %s 移动到 %s", fromNode, target.ID)
+	e.publishInstanceEvent(EventProcessModified, instance, userID, detail)
+
+	e.logger.Info("Process instance execution modified",
+		zap.Uint("instance_id", instanceID),
+		zap.String("from_node", fromNode),
+		zap.String("to_node", target.ID),
+		zap.Uint("user_id", userID),
+		zap.String("reason", reason),
+	)
+
+	if err := e.moveInstanceTo(ctx, instance, target.ID); err != nil {
+		return nil, err
+	}
+	if err := e.moveToNextNode(ctx, instance, target.ID); err != nil {
+		return nil, err
+	}
+	return e.GetInstance(ctx, instanceID)
+}
+
+// discardGatewayArrivals 消费实例所有汇聚网关上未消费的到达记录，执行位置被修改后这些分支不再汇聚
+func (e *ProcessEngine) discardGatewayArrivals(ctx context.Context, instanceID uint) error {
+	arrivals, err := e.instanceRepo.GetInstancePendingArrivals(ctx, instanceID)
+	if err != nil {
+		return fmt.Errorf("获取汇聚网关到达记录失败: %v", err)
+	}
+	if len(arrivals) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(arrivals))
+	for _, arrival := range arrivals {
+		ids = append(ids, arrival.ID)
+	}
+	if _, err := e.instanceRepo.ConsumeGatewayArrivals(ctx, ids); err != nil {
+		return fmt.Errorf("清除汇聚网关到达记录失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// handleMultiInstanceTask 处理配置了多实例（会签）的用户任务：并行模式为每个处理人创建任务，
+// 顺序模式只为第一个处理人创建任务；运行状态保存在流程变量中
+func (e *ProcessEngine) handleMultiInstanceTask(ctx context.Context, instance *model.ProcessInstance, node *model.ProcessNode, cfg *model.MultiInstanceConfig) error {
+	variables, err := decodeInstanceVariables(instance)
+	if err != nil {
+		return err
+	}
+
+	assignees, err := resolveUserList(cfg.Assignees, cfg.AssigneesVariable, variables, "会签处理人")
+	if err != nil {
+		return newEngineError(CodeAssignmentFailed, err, "解析会签处理人失败")
+	}
+	if len(assignees) == 0 {
+		return newEngineError(CodeAssignmentFailed, nil, "会签节点 %s 没有处理人", node.ID)
+	}
+
+	state := &model.MultiInstanceState{
+		Mode:      cfg.Mode,
+		Assignees: assignees,
+		Required:  cfg.Required,
+	}
+
+	count := len(assignees)
+	if cfg.Mode == model.MultiInstanceSequential {
+		count = 1
+	}
+	for i := 0; i < count; i++ {
+		task, err := e.createReviewTask(ctx, instance, node.ID, node.Name, assignees[i])
+		if err != nil {
+			return fmt.Errorf("创建会签任务失败: %v", err)
+		}
+		if i == 0 {
+			state.FirstTaskID = task.ID
+		}
+	}
+
+	// 每次进入节点都重新记录运行状态，循环回到节点时不会计入上一轮的任务
+	variables[model.MultiInstanceStateVariable(node.ID)] = state
+	if err := e.saveInstanceVariables(ctx, instance, variables); err != nil {
+		return err
+	}
+
+	e.logger.Info("Multi-instance task started",
+		zap.Uint("instance_id", instance.ID),
+		zap.String("node_id", node.ID),
+		zap.String("mode", cfg.Mode),
+		zap.Int("assignees", len(assignees)),
+		zap.Int("required", state.RequiredCount()),
+	)
+	return nil
+}
+
+// advanceMultiInstance 会签任务完成后判断完成条件，返回任务是否属于多实例节点
+// 满足条件时跳过其余任务并推进流程；顺序模式未满足时为下一个处理人创建任务
+func (e *ProcessEngine) advanceMultiInstance(ctx context.Context, instance *model.ProcessInstance, task *model.TaskInstance) (bool, error) {
+	definitionData, err := instance.Definition.GetDefinitionData()
+	if err != nil {
+		return false, nil
+	}
+	node := e.findNodeByID(definitionData.Nodes, task.NodeID)
+	if node == nil || node.Type != model.NodeTypeUserTask {
+		return false, nil
+	}
+	if cfg, err := model.GetMultiInstanceConfig(node); err != nil || cfg == nil {
+		return false, nil
+	}
+
+	variables, err := decodeInstanceVariables(instance)
+	if err != nil {
+		return true, err
+	}
+	state, ok := multiInstanceStateFromVariables(variables, node.ID)
+	if !ok {
+		return false, nil
+	}
+
+	tasks, err := e.taskRepo.GetByInstanceAndNode(ctx, instance.ID, node.ID, []string{
+		model.TaskStatusCompleted,
+		model.TaskStatusCreated,
+		model.TaskStatusAssigned,
+		model.TaskStatusClaimed,
+		model.TaskStatusInProgress,
+	})
+	if err != nil {
+		return true, fmt.Errorf("检查会签任务失败: %v", err)
+	}
+
+	// 只统计本轮进入节点后创建的任务
+	var pending []model.TaskInstance
+	completed, created := 0, 0
+	for _, t := range tasks {
+		if t.ID < state.FirstTaskID {
+			continue
+		}
+		created++
+		if t.Status == model.TaskStatusCompleted {
+			completed++
+		} else {
+			pending = append(pending, t)
+		}
+	}
+
+	// 写回完成数。并发完成的任务可能统计到相同的完成数，实例版本冲突时在最新状态上重新判断，
+	// 只有使完成数首次达到要求的一方继续推进节点
+	reached := false
+	err = e.updateInstance(ctx, instance, func(target *model.ProcessInstance) error {
+		current, err := decodeInstanceVariables(target)
+		if err != nil {
+			return err
+		}
+		stored, ok := multiInstanceStateFromVariables(current, node.ID)
+		if !ok || stored.FirstTaskID != state.FirstTaskID {
+			return fmt.Errorf("节点 %s 的会签状态已变化", node.ID)
+		}
+		reached = stored.Completed < stored.RequiredCount() && completed >= stored.RequiredCount()
+		if completed > stored.Completed {
+			stored.Completed = completed
+		}
+		current[model.MultiInstanceStateVariable(node.ID)] = stored
+		return setInstanceVariables(target, current)
+	})
+	if err != nil {
+		return true, err
+	}
+
+	if completed < state.RequiredCount() {
+		if state.Mode == model.MultiInstanceSequential && len(pending) == 0 && created < len(state.Assignees) {
+			next := state.Assignees[created]
+			if _, err := e.createReviewTask(ctx, instance, node.ID, node.Name, next); err != nil {
+				return true, fmt.Errorf("创建会签任务失败: %v", err)
+			}
+			e.logger.Info("Multi-instance task handed to next assignee",
+				zap.Uint("instance_id", instance.ID),
+				zap.String("node_id", node.ID),
+				zap.Uint("assignee_id", next),
+				zap.Int("completed", completed),
+			)
+		}
+		return true, nil
+	}
+
+	if !reached {
+		e.logger.Info("Multi-instance completion already handled by a concurrent completion",
+			zap.Uint("instance_id", instance.ID),
+			zap.String("node_id", node.ID),
+		)
+		return true, nil
+	}
+
+	// 完成条件已满足，其余处理人的任务不再需要
+	now := time.Now()
+	for i := range pending {
+		pending[i].Status = model.TaskStatusSkipped
+		pending[i].CompleteTime = &now
+		pending[i].Comment = "会签已满足完成条件，系统自动跳过"
+		if err := e.taskRepo.Update(ctx, &pending[i]); err != nil {
+			return true, fmt.Errorf("更新任务状态失败: %v", err)
+		}
+		e.publishTaskEvent(EventTaskSkipped, &pending[i], 0, map[string]interface{}{"reason": "会签已满足完成条件"})
+	}
+
+	e.logger.Info("Multi-instance completion condition met",
+		zap.Uint("instance_id", instance.ID),
+		zap.String("node_id", node.ID),
+		zap.Int("completed", completed),
+		zap.Int("required", state.RequiredCount()),
+		zap.Int("skipped", len(pending)),
+	)
+
+	return true, e.checkAndAdvanceProcess(ctx, instance, node.ID)
+}
+
+// multiInstanceStateFromVariables 从流程变量中读取多实例节点的运行状态
+func multiInstanceStateFromVariables(variables map[string]interface{}, nodeID string) (*model.MultiInstanceState, bool) {
+	raw, ok := variables[model.MultiInstanceStateVariable(nodeID)]
+	if !ok || raw == nil {
+		return nil, false
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, false
+	}
+	var state model.MultiInstanceState
+	if err := json.Unmarshal(data, &state); err != nil || len(state.Assignees) == 0 {
+		return nil, false
+	}
+	return &state, true
+}
@@ -0,0 +1,259 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"miniflow/internal/model"
+)
+
+// maxNextStepDepth 预览时连续穿过网关的最大层数，防止网关之间的回环
+const maxNextStepDepth = 10
+
+// 路径预览结果
+const (
+	NextStepTaken           = "taken"             // 无条件连线或并行网关
+	NextStepConditionMet    = "condition_met"     // 条件满足
+	NextStepConditionNotMet = "condition_not_met" // 条件不满足
+	NextStepNotEvaluated    = "not_evaluated"     // 排他网关已选中前面的路径
+	NextStepDefault         = "default"           // 排他网关没有条件满足，走默认路径
+	NextStepBoundaryTimer   = "boundary_timer"    // 只由边界定时器触发
+	NextStepVisitLimit      = "visit_limit"       // 只在节点超过访问上限时使用
+	NextStepError           = "error"             // 条件评估失败，网关会生成异常事件
+)
+
+// NextStep 从节点出发的一条连线及其按当前变量的预览结果
+type NextStep struct {
+	FlowID    string     `json:"flow_id"`
+	Label     string     `json:"label,omitempty"`
+	Condition string     `json:"condition,omitempty"`
+	NodeID    string     `json:"node_id"`
+	NodeName  string     `json:"node_name"`
+	NodeType  string     `json:"node_type"`
+	Taken     bool       `json:"taken"`
+	Result    string     `json:"result"`
+	Error     string     `json:"error,omitempty"`
+	Next      []NextStep `json:"next,omitempty"` // 目标为网关时，网关之后的路径
+}
+
+// ActiveNodeSteps 一个活动节点的后续路径
+type ActiveNodeSteps struct {
+	NodeID   string     `json:"node_id"`
+	NodeName string     `json:"node_name"`
+	NodeType string     `json:"node_type"`
+	Steps    []NextStep `json:"steps"`
+}
+
+// NextStepsPreview 运行中实例当前可走的路径预览
+type NextStepsPreview struct {
+	InstanceID uint                   `json:"instance_id"`
+	Variables  map[string]interface{} `json:"variables"`
+	Nodes      []ActiveNodeSteps      `json:"nodes"`
+}
+
+// PreviewNextSteps 按实例当前变量评估活动节点的出口连线，返回此刻完成节点后会走的路径
+//
+// 只做评估，不修改实例。节点完成时写入的新变量不会反映在预览结果中。
+func (e *ProcessEngine) PreviewNextSteps(ctx context.Context, instanceID uint) (*NextStepsPreview, error) {
+	instance, err := e.instanceRepo.GetByID(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if instance.Status != model.InstanceStatusRunning {
+		return nil, newEngineError(CodeInvalidStateTransition, nil, "只能预览运行中流程实例的后续路径")
+	}
+
+	definitionData, err := instance.Definition.GetDefinitionData()
+	if err != nil {
+		return nil, fmt.Errorf("解析流程定义失败: %v", err)
+	}
+
+	variables := make(map[string]interface{})
+	if instance.Variables != "" {
+		if err := json.Unmarshal([]byte(instance.Variables), &variables); err != nil {
+			return nil, fmt.Errorf("解析流程变量失败: %v", err)
+		}
+	}
+
+	nodeIDs, err := e.activeNodeIDs(ctx, instance)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &NextStepsPreview{
+		InstanceID: instance.ID,
+		Variables:  variables,
+		Nodes:      []ActiveNodeSteps{},
+	}
+	for _, nodeID := range nodeIDs {
+		node := e.findNodeByID(definitionData.Nodes, nodeID)
+		if node == nil {
+			continue
+		}
+		preview.Nodes = append(preview.Nodes, ActiveNodeSteps{
+			NodeID:   node.ID,
+			NodeName: node.Name,
+			NodeType: node.Type,
+			Steps:    e.previewOutgoing(ctx, node, definitionData, variables, 0),
+		})
+	}
+
+	return preview, nil
+}
+
+// activeNodeIDs 获取实例当前停留的节点：有待办任务的节点，没有时为实例的当前节点
+func (e *ProcessEngine) activeNodeIDs(ctx context.Context, instance *model.ProcessInstance) ([]string, error) {
+	tasks, err := e.taskRepo.GetByInstance(ctx, instance.ID)
+	if err != nil {
+		return nil, fmt.Errorf("获取任务列表失败: %w", err)
+	}
+
+	var nodeIDs []string
+	seen := make(map[string]bool)
+	for _, task := range tasks {
+		if !isOpenTaskStatus(task.Status) {
+			continue
+		}
+		nodeID := task.NodeID
+		if reviewNodeID, ok := model.ParseConsolidationNodeID(nodeID); ok {
+			nodeID = reviewNodeID
+		}
+		if !seen[nodeID] {
+			seen[nodeID] = true
+			nodeIDs = append(nodeIDs, nodeID)
+		}
+	}
+
+	if len(nodeIDs) == 0 && instance.CurrentNode != "" {
+		nodeIDs = append(nodeIDs, instance.CurrentNode)
+	}
+	return nodeIDs, nil
+}
+
+// previewOutgoing 预览节点的出口连线，网关按引擎的路由规则评估条件，其他节点走所有出口连线
+func (e *ProcessEngine) previewOutgoing(ctx context.Context, node *model.ProcessNode, definition *model.ProcessDefinitionData, variables map[string]interface{}, depth int) []NextStep {
+	flows := e.findOutgoingFlows(definition.Flows, node.ID)
+	steps := make([]NextStep, len(flows))
+	for i, flow := range flows {
+		steps[i] = NextStep{
+			FlowID:    flow.ID,
+			Label:     flow.Label,
+			Condition: flow.Condition,
+			NodeID:    flow.To,
+		}
+		if target := e.findNodeByID(definition.Nodes, flow.To); target != nil {
+			steps[i].NodeName = target.Name
+			steps[i].NodeType = target.Type
+		}
+	}
+
+	if node.Type == model.NodeTypeGateway {
+		e.previewGateway(ctx, node, flows, steps, variables)
+	} else {
+		boundaryFlow, escalationFlow := "", ""
+		if boundary, _ := model.GetBoundaryTimer(node); boundary != nil {
+			boundaryFlow = boundary.Flow
+		}
+		if limit, _ := model.GetVisitLimit(node); limit != nil {
+			escalationFlow = limit.Flow
+		}
+		for i := range steps {
+			if steps[i].FlowID == boundaryFlow {
+				steps[i].Result = NextStepBoundaryTimer
+				continue
+			}
+			if escalationFlow != "" && steps[i].FlowID == escalationFlow {
+				steps[i].Result = NextStepVisitLimit
+				continue
+			}
+			steps[i].Taken = true
+			steps[i].Result = NextStepTaken
+		}
+	}
+
+	if depth >= maxNextStepDepth {
+		return steps
+	}
+	for i := range steps {
+		if !steps[i].Taken || steps[i].NodeType != model.NodeTypeGateway {
+			continue
+		}
+		if target := e.findNodeByID(definition.Nodes, steps[i].NodeID); target != nil {
+			steps[i].Next = e.previewOutgoing(ctx, target, definition, variables, depth+1)
+		}
+	}
+	return steps
+}
+
+// previewGateway 按 evaluateGatewayConditions 的规则标记网关出口连线，任一条件评估失败时网关不会走任何路径
+func (e *ProcessEngine) previewGateway(ctx context.Context, gateway *model.ProcessNode, flows []model.ProcessFlow, steps []NextStep, variables map[string]interface{}) {
+	switch model.GetGatewayType(gateway) {
+	case model.GatewayTypeParallel:
+		for i := range steps {
+			steps[i].Taken = true
+			steps[i].Result = NextStepTaken
+		}
+	case model.GatewayTypeInclusive:
+		failed := false
+		for i, flow := range flows {
+			matched, err := e.evaluateCondition(ctx, gateway, flow, variables, nil)
+			switch {
+			case err != nil:
+				steps[i].Result = NextStepError
+				steps[i].Error = err.Error()
+				failed = true
+			case flow.Condition == "":
+				steps[i].Taken = true
+				steps[i].Result = NextStepTaken
+			case matched:
+				steps[i].Taken = true
+				steps[i].Result = NextStepConditionMet
+			default:
+				steps[i].Result = NextStepConditionNotMet
+			}
+		}
+		if failed {
+			for i := range steps {
+				steps[i].Taken = false
+			}
+		}
+	case model.GatewayTypeExclusive:
+		selected, failed := -1, false
+		for i, flow := range flows {
+			if flow.Condition == "" {
+				continue
+			}
+			if selected >= 0 || failed {
+				steps[i].Result = NextStepNotEvaluated
+				continue
+			}
+			matched, err := e.evaluateCondition(ctx, gateway, flow, variables, nil)
+			switch {
+			case err != nil:
+				steps[i].Result = NextStepError
+				steps[i].Error = err.Error()
+				failed = true
+			case matched:
+				selected = i
+				steps[i].Result = NextStepConditionMet
+			default:
+				steps[i].Result = NextStepConditionNotMet
+			}
+		}
+		for i, flow := range flows {
+			if flow.Condition != "" {
+				continue
+			}
+			if selected < 0 && !failed {
+				selected = i
+				steps[i].Result = NextStepDefault
+			} else {
+				steps[i].Result = NextStepNotEvaluated
+			}
+		}
+		if selected >= 0 && !failed {
+			steps[selected].Taken = true
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"context"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/expression"
+
+	"go.uber.org/zap"
+)
+
+// taskInstructions 返回任务所在节点的办理说明，文本中的 ${...} 按流程变量插值；
+// 插值失败的片段保留原文，不影响任务表单的加载
+func (e *ProcessEngine) taskInstructions(ctx context.Context, task *model.TaskInstance) *model.NodeInstructions {
+	definitionData, err := task.Instance.Definition.GetDefinitionData()
+	if err != nil {
+		return nil
+	}
+	node := e.findNodeByID(definitionData.Nodes, task.NodeID)
+	if node == nil {
+		return nil
+	}
+
+	instructions, err := model.GetNodeInstructions(node)
+	if err != nil || instructions == nil {
+		return nil
+	}
+
+	var context map[string]interface{}
+	for _, text := range instructions.Texts() {
+		if !expression.IsTemplate(*text) {
+			continue
+		}
+		if context == nil {
+			if context, err = e.expressionContext(ctx, &task.Instance); err != nil {
+				e.logger.Warn("Failed to build instruction context", zap.Uint("task_id", task.ID), zap.Error(err))
+				return instructions
+			}
+		}
+		*text = interpolateText(*text, context)
+	}
+
+	return instructions
+}
+
+// interpolateText 渲染文本中的表达式，解析或求值失败时返回原文
+func interpolateText(source string, context map[string]interface{}) string {
+	tmpl, err := expression.ParseTemplate(source)
+	if err != nil {
+		return source
+	}
+	rendered, err := tmpl.RenderString(context)
+	if err != nil {
+		return source
+	}
+	return rendered
+}
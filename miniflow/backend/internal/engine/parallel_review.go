@@ -0,0 +1,387 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// handleParallelReview 处理并行评审组合节点：为每个评审人创建评审任务，全部完成后再创建汇总任务
+func (e *ProcessEngine) handleParallelReview(ctx context.Context, instance *model.ProcessInstance, node *model.ProcessNode) error {
+	cfg, err := model.GetParallelReviewConfig(node)
+	if err != nil {
+		return fmt.Errorf("并行评审节点配置错误: %v", err)
+	}
+
+	variables, err := decodeInstanceVariables(instance)
+	if err != nil {
+		return err
+	}
+
+	reviewers, err := resolveReviewers(cfg, variables)
+	if err != nil {
+		return err
+	}
+
+	// 每次进入节点都重新收集评审结果
+	variables[cfg.OutcomeVariable] = []model.ReviewOutcome{}
+	if err := e.saveInstanceVariables(ctx, instance, variables); err != nil {
+		return err
+	}
+
+	for _, reviewerID := range reviewers {
+		if _, err := e.createReviewTask(ctx, instance, node.ID, node.Name, reviewerID); err != nil {
+			return fmt.Errorf("创建评审任务失败: %v", err)
+		}
+	}
+
+	e.logger.Info("Parallel review started",
+		zap.Uint("instance_id", instance.ID),
+		zap.String("node_id", node.ID),
+		zap.Int("reviewers", len(reviewers)),
+	)
+
+	return nil
+}
+
+// advanceParallelReview 并行评审节点的任务完成后由组合节点自行推进，返回任务是否属于并行评审节点
+func (e *ProcessEngine) advanceParallelReview(ctx context.Context, instance *model.ProcessInstance, task *model.TaskInstance, formData map[string]interface{}, comment string) (bool, error) {
+	definitionData, err := instance.Definition.GetDefinitionData()
+	if err != nil {
+		return false, nil
+	}
+
+	// 汇总任务完成，推进到评审节点的出口
+	if reviewNodeID, ok := model.ParseConsolidationNodeID(task.NodeID); ok {
+		if node := e.findNodeByID(definitionData.Nodes, reviewNodeID); node != nil && node.Type == model.NodeTypeParallelReview {
+			return true, e.checkAndAdvanceProcess(ctx, instance, reviewNodeID)
+		}
+		return false, nil
+	}
+
+	node := e.findNodeByID(definitionData.Nodes, task.NodeID)
+	if node == nil || node.Type != model.NodeTypeParallelReview {
+		return false, nil
+	}
+
+	cfg, err := model.GetParallelReviewConfig(node)
+	if err != nil {
+		return true, fmt.Errorf("并行评审节点配置错误: %v", err)
+	}
+
+	outcome := model.ReviewOutcome{
+		TaskID:      task.ID,
+		Reviewer:    displayName(task.Assignee),
+		Comment:     comment,
+		Data:        formData,
+		CompletedAt: time.Now(),
+	}
+	if task.AssigneeID != nil {
+		outcome.ReviewerID = *task.AssigneeID
+	}
+	if value, ok := formData["outcome"].(string); ok {
+		outcome.Outcome = value
+	}
+	responses, complete, err := e.appendReviewOutcome(ctx, instance, node.ID, cfg.OutcomeVariable, outcome)
+	if err != nil {
+		return true, err
+	}
+	if !complete {
+		return true, nil
+	}
+
+	variables, err := decodeInstanceVariables(instance)
+	if err != nil {
+		return true, err
+	}
+	ownerID := resolveReviewOwner(cfg, variables, instance.StarterID)
+	if _, err := e.createReviewTask(ctx, instance, model.ConsolidationNodeID(node.ID), cfg.ConsolidationName, ownerID); err != nil {
+		return true, fmt.Errorf("创建汇总任务失败: %v", err)
+	}
+
+	e.logger.Info("Parallel review consolidated",
+		zap.Uint("instance_id", instance.ID),
+		zap.String("node_id", node.ID),
+		zap.Uint("owner_id", ownerID),
+		zap.Int("responses", responses),
+	)
+
+	return true, nil
+}
+
+// appendReviewOutcome 把评审结果追加到实例的结果变量，返回追加后的结果数以及本轮评审是否由这次追加收齐
+// 追加在实例的乐观锁更新中完成，版本冲突时在最新的结果列表上重新追加，
+// 并发完成的评审任务不会覆盖彼此的结果；同一任务的结果只追加一次。
+// 结果列表按版本串行增长，只有补上最后一条结果的追加会判定收齐，汇总任务因此只创建一次
+func (e *ProcessEngine) appendReviewOutcome(ctx context.Context, instance *model.ProcessInstance, nodeID, name string, outcome model.ReviewOutcome) (int, bool, error) {
+	var previous, outcomes []model.ReviewOutcome
+	complete := false
+	err := e.updateInstance(ctx, instance, func(target *model.ProcessInstance) error {
+		variables, err := decodeInstanceVariables(target)
+		if err != nil {
+			return err
+		}
+		previous = reviewOutcomesFromVariables(variables, name)
+		outcomes = previous
+		complete = false
+		for _, existing := range previous {
+			if existing.TaskID == outcome.TaskID {
+				return nil
+			}
+		}
+		outcomes = append(append([]model.ReviewOutcome{}, previous...), outcome)
+		if complete, err = e.reviewRoundComplete(ctx, target.ID, nodeID, outcomes); err != nil {
+			return err
+		}
+		variables[name] = outcomes
+		return setInstanceVariables(target, variables)
+	})
+	if err != nil {
+		return 0, false, err
+	}
+
+	e.recordVariableChanges(ctx, instance, map[string]interface{}{name: previous}, map[string]interface{}{name: outcomes}, nil)
+	e.traceFor(instance).record(ctx, model.TraceCategoryWrite, nodeID, map[string]interface{}{"changed": []string{name}},
+		"追加评审结果，共 %d 条", len(outcomes))
+	return len(outcomes), complete, nil
+}
+
+// reviewRoundComplete 判断本轮评审任务是否全部完成且结果都已在列表中
+// 上一次汇总任务之后创建的评审任务属于本轮
+func (e *ProcessEngine) reviewRoundComplete(ctx context.Context, instanceID uint, nodeID string, outcomes []model.ReviewOutcome) (bool, error) {
+	consolidations, err := e.taskRepo.GetByInstanceAndNode(ctx, instanceID, model.ConsolidationNodeID(nodeID), nil)
+	if err != nil {
+		return false, fmt.Errorf("查询汇总任务失败: %v", err)
+	}
+	var roundStart uint
+	for _, task := range consolidations {
+		if task.ID > roundStart {
+			roundStart = task.ID
+		}
+	}
+
+	tasks, err := e.taskRepo.GetByInstanceAndNode(ctx, instanceID, nodeID, nil)
+	if err != nil {
+		return false, fmt.Errorf("检查待处理评审任务失败: %v", err)
+	}
+	recorded := make(map[uint]bool, len(outcomes))
+	for _, outcome := range outcomes {
+		recorded[outcome.TaskID] = true
+	}
+	for _, task := range tasks {
+		if task.ID <= roundStart {
+			continue
+		}
+		switch task.Status {
+		case model.TaskStatusCreated, model.TaskStatusAssigned, model.TaskStatusClaimed, model.TaskStatusInProgress:
+			return false, nil
+		case model.TaskStatusCompleted:
+			// 已完成但结果尚未追加的任务会在自己的追加中收齐
+			if !recorded[task.ID] {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// consolidationReviews 返回汇总任务需要展示的全部评审结果，非汇总任务返回nil
+func (e *ProcessEngine) consolidationReviews(task *model.TaskInstance) []model.ReviewOutcome {
+	reviewNodeID, ok := model.ParseConsolidationNodeID(task.NodeID)
+	if !ok {
+		return nil
+	}
+
+	definitionData, err := task.Instance.Definition.GetDefinitionData()
+	if err != nil {
+		return nil
+	}
+	node := e.findNodeByID(definitionData.Nodes, reviewNodeID)
+	if node == nil {
+		return nil
+	}
+	cfg, err := model.GetParallelReviewConfig(node)
+	if err != nil {
+		return nil
+	}
+
+	variables, err := decodeInstanceVariables(&task.Instance)
+	if err != nil {
+		return nil
+	}
+	return reviewOutcomesFromVariables(variables, cfg.OutcomeVariable)
+}
+
+// createReviewTask 创建并直接分配评审或汇总任务
+func (e *ProcessEngine) createReviewTask(ctx context.Context, instance *model.ProcessInstance, nodeID, name string, assigneeID uint) (*model.TaskInstance, error) {
+	task, err := e.taskLifecycle.CreateTask(ctx, instance, nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	if name != "" {
+		task.Name = name
+	}
+	task.AssigneeID = &assigneeID
+	task.Status = model.TaskStatusAssigned
+	if err := e.taskRepo.Update(ctx, task); err != nil {
+		return nil, err
+	}
+	e.traceFor(instance).record(ctx, model.TraceCategoryWrite, nodeID, map[string]interface{}{
+		"task_id":     task.ID,
+		"assignee_id": assigneeID,
+	}, "创建任务 %d 并分配给用户 %d", task.ID, assigneeID)
+	e.events.Publish(taskAssignedEvent(task))
+	return task, nil
+}
+
+// saveInstanceVariables 序列化并保存流程变量
+// 只把相对实例当前变量新增、修改和删除的变量写回，实例被并发修改时合并到最新的变量上，
+// 不会覆盖其他操作写入的变量
+func (e *ProcessEngine) saveInstanceVariables(ctx context.Context, instance *model.ProcessInstance, variables map[string]interface{}) error {
+	original, err := decodeInstanceVariables(instance)
+	if err != nil {
+		return err
+	}
+	changed := make(map[string]interface{})
+	for key, value := range variables {
+		if previous, ok := original[key]; !ok || !sameVariableValue(previous, value) {
+			changed[key] = value
+		}
+	}
+	var removed []string
+	for key := range original {
+		if _, ok := variables[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+
+	err = e.updateInstance(ctx, instance, func(target *model.ProcessInstance) error {
+		current, err := decodeInstanceVariables(target)
+		if err != nil {
+			return err
+		}
+		for key, value := range changed {
+			current[key] = value
+		}
+		for _, key := range removed {
+			delete(current, key)
+		}
+		return setInstanceVariables(target, current)
+	})
+	if err != nil {
+		return err
+	}
+	e.recordVariableChanges(ctx, instance, original, changed, removed)
+	e.traceFor(instance).record(ctx, model.TraceCategoryWrite, "", map[string]interface{}{
+		"changed": changed,
+		"removed": removed,
+	}, "写入流程变量，修改 %d 个，删除 %d 个", len(changed), len(removed))
+	return nil
+}
+
+// setInstanceVariables 序列化流程变量写入实例，不保存
+func setInstanceVariables(instance *model.ProcessInstance, variables map[string]interface{}) error {
+	data, err := json.Marshal(variables)
+	if err != nil {
+		return fmt.Errorf("序列化流程变量失败: %v", err)
+	}
+	instance.Variables = string(data)
+	return nil
+}
+
+// sameVariableValue 按 JSON 序列化结果比较两个变量值
+func sameVariableValue(a, b interface{}) bool {
+	left, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	right, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return string(left) == string(right)
+}
+
+// decodeInstanceVariables 解析流程实例变量，变量为空时返回空映射
+func decodeInstanceVariables(instance *model.ProcessInstance) (map[string]interface{}, error) {
+	return decodeVariables(instance.Variables)
+}
+
+// resolveReviewers 解析评审人列表
+func resolveReviewers(cfg *model.ParallelReviewConfig, variables map[string]interface{}) ([]uint, error) {
+	reviewers, err := resolveUserList(cfg.Reviewers, cfg.ReviewersVariable, variables, "评审人")
+	if err != nil {
+		return nil, err
+	}
+	if len(reviewers) == 0 {
+		return nil, errors.New("并行评审节点没有评审人")
+	}
+	return reviewers, nil
+}
+
+// resolveUserList 解析固定配置或变量中的用户列表，变量中的用户可以是ID数组或单个ID，重复的用户只保留一个
+func resolveUserList(fixed []uint, variable string, variables map[string]interface{}, label string) ([]uint, error) {
+	users := fixed
+	if variable != "" {
+		raw, ok := variables[variable]
+		if !ok {
+			return nil, fmt.Errorf("%s变量 %s 不存在", label, variable)
+		}
+		values, isList := raw.([]interface{})
+		if !isList {
+			values = []interface{}{raw}
+		}
+		ids, err := model.ParseUserIDs(values)
+		if err != nil {
+			return nil, fmt.Errorf("%s变量 %s 格式错误: %v", label, variable, err)
+		}
+		users = ids
+	}
+
+	seen := make(map[uint]bool)
+	var unique []uint
+	for _, id := range users {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique, nil
+}
+
+// resolveReviewOwner 解析汇总任务的负责人，未配置时为流程发起人
+func resolveReviewOwner(cfg *model.ParallelReviewConfig, variables map[string]interface{}, starterID uint) uint {
+	if cfg.OwnerVariable != "" {
+		if raw, ok := variables[cfg.OwnerVariable]; ok {
+			if ids, err := model.ParseUserIDs([]interface{}{raw}); err == nil {
+				return ids[0]
+			}
+		}
+	}
+	if cfg.OwnerID != 0 {
+		return cfg.OwnerID
+	}
+	return starterID
+}
+
+// reviewOutcomesFromVariables 从流程变量中读取已收集的评审结果
+func reviewOutcomesFromVariables(variables map[string]interface{}, name string) []model.ReviewOutcome {
+	outcomes := []model.ReviewOutcome{}
+	raw, ok := variables[name]
+	if !ok || raw == nil {
+		return outcomes
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return outcomes
+	}
+	_ = json.Unmarshal(data, &outcomes)
+	return outcomes
+}
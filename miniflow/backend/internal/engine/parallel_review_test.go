@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"miniflow/internal/model"
+)
+
+func TestParallelReviewKeepsConcurrentOutcomes(t *testing.T) {
+	e, db := newTestEngine(t)
+	starter := createTestUser(t, db, "starter", "user")
+	reviewers := []*model.User{
+		createTestUser(t, db, "r1", "user"),
+		createTestUser(t, db, "r2", "user"),
+		createTestUser(t, db, "r3", "user"),
+	}
+
+	// 开始节点直接进入并行评审节点
+	definition := publishTestDefinition(t, db, "review", starter.ID, &model.ProcessDefinitionData{
+		Nodes: []model.ProcessNode{
+			{ID: "start", Type: model.NodeTypeStart, Name: "start"},
+			{ID: "review", Type: model.NodeTypeParallelReview, Name: "review", Props: map[string]interface{}{
+				"reviewers": []interface{}{float64(reviewers[0].ID), float64(reviewers[1].ID), float64(reviewers[2].ID)},
+			}},
+			{ID: "end", Type: model.NodeTypeEnd, Name: "end"},
+		},
+		Flows: []model.ProcessFlow{flow("start", "review"), flow("review", "end")},
+	})
+	instance := startTestProcess(t, e, definition.ID, starter.ID, nil)
+
+	tasks := openTasks(t, db, instance.ID)
+	if len(tasks) != len(reviewers) {
+		t.Fatalf("expected %d review tasks, found %d", len(reviewers), len(tasks))
+	}
+	for _, task := range tasks {
+		if err := e.ClaimTask(context.Background(), task.ID, *task.AssigneeID); err != nil {
+			t.Fatalf("claim task %d: %v", task.ID, err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(tasks))
+	for i, task := range tasks {
+		wg.Add(1)
+		go func(i int, task model.TaskInstance) {
+			defer wg.Done()
+			errs[i] = e.CompleteTask(context.Background(), task.ID, *task.AssigneeID,
+				map[string]interface{}{"outcome": "approve"}, "")
+		}(i, task)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("complete review task %d: %v", tasks[i].ID, err)
+		}
+	}
+
+	variables, err := decodeInstanceVariables(reloadInstance(t, db, instance.ID))
+	if err != nil {
+		t.Fatalf("decode variables: %v", err)
+	}
+	outcomes := reviewOutcomesFromVariables(variables, "review_reviews")
+	if len(outcomes) != len(reviewers) {
+		t.Fatalf("expected %d review outcomes, found %d: %+v", len(reviewers), len(outcomes), outcomes)
+	}
+	seen := make(map[uint]bool)
+	for _, outcome := range outcomes {
+		seen[outcome.TaskID] = true
+	}
+	for _, task := range tasks {
+		if !seen[task.ID] {
+			t.Errorf("outcome of task %d is missing", task.ID)
+		}
+	}
+
+	consolidation := openTaskAt(t, db, instance.ID, model.ConsolidationNodeID("review"))
+	claimAndComplete(t, e, consolidation.ID, starter.ID)
+	if got := reloadInstance(t, db, instance.ID).Status; got != model.InstanceStatusCompleted {
+		t.Fatalf("instance status = %s, want %s", got, model.InstanceStatusCompleted)
+	}
+}
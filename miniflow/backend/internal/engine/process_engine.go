@@ -1,30 +1,47 @@
 package engine
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"miniflow/internal/model"
 	"miniflow/internal/repository"
+	"miniflow/pkg/config"
 	"miniflow/pkg/database"
+	"miniflow/pkg/expression"
 	"miniflow/pkg/logger"
 
 	"go.uber.org/zap"
 )
 
+// ErrTaskAlreadyCompleted 任务已被完成，重复提交时返回
+var ErrTaskAlreadyCompleted = &EngineError{Code: CodeTaskAlreadyCompleted, Message: "任务已完成，请勿重复提交"}
+
 // ProcessEngine 流程执行引擎
 type ProcessEngine struct {
-	instanceRepo    *repository.ProcessInstanceRepository
-	taskRepo        *repository.TaskRepository
-	processRepo     *repository.ProcessRepository
-	userRepo        *repository.UserRepository
-	logger          *logger.Logger
-	variableEngine  *VariableEngine
-	serviceExecutor *ServiceExecutor
-	stateMachine    *ProcessStateMachine
-	taskLifecycle   *TaskLifecycleManager
+	instanceRepo     *repository.ProcessInstanceRepository
+	taskRepo         *repository.TaskRepository
+	processRepo      *repository.ProcessRepository
+	userRepo         *repository.UserRepository
+	policyRepo       *repository.ConnectorPolicyRepository
+	incidentRepo     *repository.IncidentRepository
+	duplicateRepo    *repository.DuplicateRepository
+	executionLogRepo *repository.ExecutionLogRepository
+	logger           *logger.Logger
+	variableEngine   *VariableEngine
+	serviceExecutor  *ServiceExecutor
+	connectorMock    *ConnectorMock
+	stateMachine     *ProcessStateMachine
+	taskLifecycle    *TaskLifecycleManager
+	events           *EventSystem
+	taskSignal       *taskChangeSignal
+	mailSender       MailSender
+	rbac             *config.RBACConfig
+
+	completionWebhook *CompletionWebhookSender
 }
 
 // NewProcessEngine 创建新的流程执行引擎
@@ -33,23 +50,45 @@ func NewProcessEngine(
 	taskRepo *repository.TaskRepository,
 	processRepo *repository.ProcessRepository,
 	userRepo *repository.UserRepository,
+	policyRepo *repository.ConnectorPolicyRepository,
+	incidentRepo *repository.IncidentRepository,
+	duplicateRepo *repository.DuplicateRepository,
+	executionLogRepo *repository.ExecutionLogRepository,
+	connectorCfg *config.ConnectorConfig,
+	scriptCfg *config.ScriptConfig,
+	rbacCfg *config.RBACConfig,
 	db *database.Database,
+	variableStore VariableStore,
+	events *EventSystem,
+	mailSender MailSender,
 	logger *logger.Logger,
 ) *ProcessEngine {
 	stateMachine := NewProcessStateMachine(nil, logger)
-	taskLifecycle := NewTaskLifecycleManager(taskRepo, logger)
+	taskLifecycle := NewTaskLifecycleManager(taskRepo, events, logger)
 
 	engine := &ProcessEngine{
-		instanceRepo:    instanceRepo,
-		taskRepo:        taskRepo,
-		processRepo:     processRepo,
-		userRepo:        userRepo,
-		logger:          logger,
-		variableEngine:  NewVariableEngine(logger),
-		serviceExecutor: NewServiceExecutor(db, logger),
-		stateMachine:    stateMachine,
-		taskLifecycle:   taskLifecycle,
-	}
+		instanceRepo:     instanceRepo,
+		taskRepo:         taskRepo,
+		processRepo:      processRepo,
+		userRepo:         userRepo,
+		policyRepo:       policyRepo,
+		incidentRepo:     incidentRepo,
+		duplicateRepo:    duplicateRepo,
+		executionLogRepo: executionLogRepo,
+		logger:           logger,
+		variableEngine:   NewVariableEngine(variableStore, instanceRepo, logger),
+		serviceExecutor:  NewServiceExecutor(db, scriptCfg, logger),
+		connectorMock:    NewConnectorMock(&connectorCfg.Mock, executionLogRepo, logger),
+		stateMachine:     stateMachine,
+		taskLifecycle:    taskLifecycle,
+		events:           events,
+		taskSignal:       newTaskChangeSignal(),
+		mailSender:       mailSender,
+		rbac:             rbacCfg,
+
+		completionWebhook: NewCompletionWebhookSender(logger),
+	}
+	engine.watchTaskEvents()
 
 	return engine
 }
@@ -59,32 +98,56 @@ type StartProcessRequest struct {
 	DefinitionID uint                   `json:"definition_id" validate:"required"`
 	BusinessKey  string                 `json:"business_key" validate:"required,min=1,max=255"`
 	Variables    map[string]interface{} `json:"variables"`
+	DueDate      *time.Time             `json:"due_date"`
+
+	// 记录详细的执行跟踪，需要 instance_trace 权限
+	Trace bool `json:"trace"`
+
+	// 调用活动启动子实例时设置，子实例沿用父实例的跟踪设置
+	parentInstanceID *uint
+	parentNodeID     string
+	inheritTrace     bool
 }
 
 // StartProcess 启动流程实例
-func (e *ProcessEngine) StartProcess(req *StartProcessRequest, starterID uint) (*model.ProcessInstance, error) {
+func (e *ProcessEngine) StartProcess(ctx context.Context, req *StartProcessRequest, starterID uint) (*model.ProcessInstance, error) {
 	e.logger.Info("Starting process instance",
 		zap.Uint("definition_id", req.DefinitionID),
 		zap.String("business_key", req.BusinessKey),
 		zap.Uint("starter_id", starterID),
 	)
 
+	if req.Trace {
+		if err := e.checkTracePermission(ctx, starterID); err != nil {
+			return nil, err
+		}
+	}
+
 	// 获取流程定义
-	definition, err := e.processRepo.GetByID(req.DefinitionID)
+	definition, err := e.processRepo.GetByID(ctx, req.DefinitionID)
 	if err != nil {
-		return nil, fmt.Errorf("获取流程定义失败: %v", err)
+		return nil, newEngineError(CodeDefinitionNotFound, err, "获取流程定义失败")
+	}
+
+	// 只有已发布的版本可以启动新实例，草稿、已弃用和已归档的版本不能启动
+	if definition.Status != model.ProcessStatusPublished {
+		return nil, newEngineError(CodeInvalidStateTransition, nil, "流程定义 %s 版本 %d 的状态为 %s，不能启动新实例",
+			definition.Key, definition.Version, definition.Status)
 	}
 
+	// 灰度发布中的流程按比例或条件路由到新旧版本
+	definition = e.routeRollout(ctx, definition, req)
+
 	// 解析流程定义
 	definitionData, err := definition.GetDefinitionData()
 	if err != nil {
-		return nil, fmt.Errorf("解析流程定义失败: %v", err)
+		return nil, newEngineError(CodeInvalidDefinition, err, "解析流程定义失败")
 	}
 
 	// 查找开始节点
 	startNode := e.findStartNode(definitionData.Nodes)
 	if startNode == nil {
-		return nil, errors.New("流程定义中没有开始节点")
+		return nil, newEngineError(CodeNoStartNode, nil, "流程定义中没有开始节点")
 	}
 
 	// 序列化变量
@@ -95,24 +158,38 @@ func (e *ProcessEngine) StartProcess(req *StartProcessRequest, starterID uint) (
 
 	// 创建流程实例
 	instance := &model.ProcessInstance{
-		DefinitionID: req.DefinitionID,
+		DefinitionID: definition.ID,
 		BusinessKey:  req.BusinessKey,
 		CurrentNode:  startNode.ID,
 		Status:       model.InstanceStatusRunning,
 		Variables:    string(variablesJSON),
 		StartTime:    time.Now(),
 		StarterID:    starterID,
+		DueDate:      req.DueDate,
+
+		ParentInstanceID: req.parentInstanceID,
+		ParentNodeID:     req.parentNodeID,
+		TraceEnabled:     req.Trace || req.inheritTrace,
 	}
 
 	// 保存流程实例
-	if err := e.instanceRepo.Create(instance); err != nil {
+	if err := e.instanceRepo.Create(ctx, instance); err != nil {
 		return nil, fmt.Errorf("创建流程实例失败: %v", err)
 	}
 
+	// 实例已保存，之后的推进不随请求取消中断，否则实例会停在开始节点
+	ctx = context.WithoutCancel(ctx)
+
 	e.logger.Info("Process instance created successfully",
 		zap.Uint("instance_id", instance.ID),
 		zap.String("current_node", instance.CurrentNode),
 	)
+	e.recordStateTransition(ctx, instance, "", model.InstanceStatusRunning, starterID, "")
+	e.traceFor(instance).record(ctx, model.TraceCategoryWrite, startNode.ID, map[string]interface{}{
+		"definition_id":      definition.ID,
+		"definition_version": definition.Version,
+		"variables":          req.Variables,
+	}, "创建流程实例，流程定义 %s 版本 %d", definition.Key, definition.Version)
 
 	// 设置Definition关联，供后续使用
 	instance.Definition = *definition
@@ -122,74 +199,139 @@ func (e *ProcessEngine) StartProcess(req *StartProcessRequest, starterID uint) (
 		zap.Uint("instance_id", instance.ID),
 		zap.Uint("starter_id", starterID),
 	)
+	e.publishInstanceEvent(EventProcessStarted, instance, starterID, map[string]interface{}{
+		"definition_id": definition.ID,
+		"business_key":  instance.BusinessKey,
+	})
 
 	// 推进到第一个节点
-	if err := e.moveToNextNode(instance, startNode.ID); err != nil {
+	if err := e.moveToNextNode(ctx, instance, startNode.ID); err != nil {
 		e.logger.Error("Failed to move to first node",
 			zap.Uint("instance_id", instance.ID),
 			zap.String("start_node", startNode.ID),
 			zap.Error(err),
 		)
 		// 这是关键错误，应该返回错误
-		return nil, fmt.Errorf("流程推进失败: %v", err)
+		return nil, fmt.Errorf("流程推进失败: %w", err)
+	}
+
+	// 检测疑似重复提交
+	if instance.Status == model.InstanceStatusRunning {
+		e.detectDuplicates(ctx, instance)
 	}
 
 	return instance, nil
 }
 
 // CompleteTask 完成任务
-func (e *ProcessEngine) CompleteTask(taskID uint, userID uint, formData map[string]interface{}, comment string) error {
+func (e *ProcessEngine) CompleteTask(ctx context.Context, taskID uint, userID uint, formData map[string]interface{}, comment string) error {
 	e.logger.Info("Completing task",
 		zap.Uint("task_id", taskID),
 		zap.Uint("user_id", userID),
 	)
 
 	// 获取任务实例
-	task, err := e.taskRepo.GetByID(taskID)
+	task, err := e.taskRepo.GetByID(ctx, taskID)
 	if err != nil {
-		return fmt.Errorf("获取任务失败: %v", err)
+		return fmt.Errorf("获取任务失败: %w", err)
 	}
 
 	// 验证任务状态
+	if task.Status == model.TaskStatusCompleted {
+		return ErrTaskAlreadyCompleted
+	}
 	if task.Status != model.TaskStatusClaimed && task.Status != model.TaskStatusInProgress {
-		return errors.New("任务状态不允许完成操作")
+		return newEngineError(CodeInvalidStateTransition, nil, "任务状态不允许完成操作")
 	}
 
 	// 验证用户权限
 	if task.AssigneeID != nil && *task.AssigneeID != userID {
-		return errors.New("用户没有权限完成此任务")
+		return newEngineError(CodePermissionDenied, nil, "用户没有权限完成此任务")
+	}
+	if task.DelegationState == model.DelegationStatePending {
+		return newEngineError(CodeInvalidStateTransition, nil, "任务委派中，需要受托人先归还给所有人")
 	}
 
-	// 序列化表单数据
+	// 以条件更新完成任务，并发的重复提交只有一个会成功
+	task.Comment = comment
 	if formData != nil {
-		if formDataJSON, err := json.Marshal(formData); err == nil {
-			task.Comment = string(formDataJSON)
+		formDataJSON, err := json.Marshal(formData)
+		if err != nil {
+			return newEngineError(CodeInvalidRequest, err, "表单数据格式错误")
 		}
+		task.FormData = string(formDataJSON)
 	}
-
-	// 更新任务状态
-	now := time.Now()
-	task.Status = model.TaskStatusCompleted
-	task.CompleteTime = &now
-	task.Comment = comment
-
-	if err := e.taskRepo.Update(task); err != nil {
-		return fmt.Errorf("更新服务任务状态失败: %v", err)
+	completed, remaining, err := e.taskRepo.CompleteTask(ctx, task, userID)
+	if err != nil {
+		return fmt.Errorf("更新任务状态失败: %v", err)
+	}
+	if !completed {
+		return e.completionConflict(ctx, taskID)
 	}
 
+	// 任务已完成，之后的推进不随请求取消中断，否则实例会停在已完成的任务上
+	ctx = context.WithoutCancel(ctx)
+
 	e.logger.Info("Task completed successfully",
 		zap.Uint("task_id", taskID),
 		zap.Uint("user_id", userID),
 	)
+	e.recordActivity(ctx, &model.ActivityHistory{
+		InstanceID: task.InstanceID,
+		Type:       model.ActivityTaskCompleted,
+		NodeID:     task.NodeID,
+		NodeType:   model.NodeTypeUserTask,
+		TaskID:     &task.ID,
+	}, userID, map[string]interface{}{"task_name": task.Name})
+	e.publishTaskEvent(EventTaskCompleted, task, userID, nil)
+
+	// 完成意见同时记入任务讨论，不随之后的表单保存被覆盖
+	if strings.TrimSpace(comment) != "" {
+		if _, err := e.addTaskComment(ctx, task, userID, comment); err != nil {
+			e.logger.Warn("Failed to record completion comment", zap.Uint("task_id", task.ID), zap.Error(err))
+		}
+	}
 
 	// 获取流程实例并推进流程
-	instance, err := e.instanceRepo.GetByID(task.InstanceID)
+	instance, err := e.instanceRepo.GetByID(ctx, task.InstanceID)
 	if err != nil {
-		return fmt.Errorf("获取流程实例失败: %v", err)
+		return fmt.Errorf("获取流程实例失败: %w", err)
+	}
+	e.traceFor(instance).record(ctx, model.TraceCategoryWrite, task.NodeID, map[string]interface{}{
+		"task_id":   task.ID,
+		"user_id":   userID,
+		"remaining": remaining,
+	}, "完成任务 %d，节点剩余 %d 个未完成任务", task.ID, remaining)
+
+	// 并行评审节点的任务由组合节点自行推进
+	if handled, err := e.advanceParallelReview(ctx, instance, task, formData, comment); handled {
+		if err != nil {
+			e.logger.Error("Failed to advance parallel review", zap.Error(err))
+		}
+		return nil
+	}
+
+	// 会签任务按完成条件推进
+	if handled, err := e.advanceMultiInstance(ctx, instance, task); handled {
+		if err != nil {
+			e.logger.Error("Failed to advance multi-instance task", zap.Error(err))
+		}
+		return nil
+	}
+
+	// 节点上还有未完成的任务时不推进。完成任务时锁定了实例，
+	// 同一节点并发完成的任务中只有最后完成的一个会推进流程
+	if remaining > 0 {
+		e.logger.Info("Node still has open tasks, waiting",
+			zap.Uint("instance_id", instance.ID),
+			zap.String("node_id", task.NodeID),
+			zap.Int64("remaining", remaining),
+		)
+		return nil
 	}
 
 	// 检查当前节点的所有任务是否都已完成
-	if err := e.checkAndAdvanceProcess(instance, task.NodeID); err != nil {
+	if err := e.checkAndAdvanceProcess(ctx, instance, task.NodeID); err != nil {
 		e.logger.Error("Failed to advance process", zap.Error(err))
 		// 不返回错误，任务已完成成功
 	}
@@ -197,25 +339,41 @@ func (e *ProcessEngine) CompleteTask(taskID uint, userID uint, formData map[stri
 	return nil
 }
 
-// SuspendInstance 暂停流程实例
-func (e *ProcessEngine) SuspendInstance(instanceID uint, reason string) error {
-	instance, err := e.instanceRepo.GetByID(instanceID)
+// completionConflict 条件更新未命中时重新读取任务，区分重复提交和其他状态变化
+func (e *ProcessEngine) completionConflict(ctx context.Context, taskID uint) error {
+	current, err := e.taskRepo.GetByID(ctx, taskID)
 	if err != nil {
-		return fmt.Errorf("获取流程实例失败: %v", err)
+		return fmt.Errorf("获取任务失败: %w", err)
 	}
-
-	if instance.Status != model.InstanceStatusRunning {
-		return errors.New("只能暂停运行中的流程实例")
+	if current.Status == model.TaskStatusCompleted {
+		e.logger.Warn("Duplicate task completion rejected", zap.Uint("task_id", taskID))
+		return ErrTaskAlreadyCompleted
 	}
+	return newEngineError(CodeInvalidStateTransition, nil, "任务状态已变化，不允许完成操作")
+}
 
-	// 使用状态机转换状态
-	if err := e.stateMachine.TransitionTo(instance, model.InstanceStatusSuspended, reason); err != nil {
-		return fmt.Errorf("状态转换失败: %v", err)
+// SuspendInstance 暂停流程实例，userID 为操作人，系统操作时为 0
+func (e *ProcessEngine) SuspendInstance(ctx context.Context, instanceID, userID uint, reason string) error {
+	instance, err := e.instanceRepo.GetByID(ctx, instanceID)
+	if err != nil {
+		return fmt.Errorf("获取流程实例失败: %w", err)
 	}
 
-	if err := e.instanceRepo.Update(instance); err != nil {
-		return fmt.Errorf("更新流程实例状态失败: %v", err)
+	// 使用状态机转换状态，并发修改时在最新状态上重新校验
+	err = e.updateInstance(ctx, instance, func(target *model.ProcessInstance) error {
+		if target.Status != model.InstanceStatusRunning {
+			return newEngineError(CodeInvalidStateTransition, nil, "只能暂停运行中的流程实例")
+		}
+		if err := e.stateMachine.TransitionTo(target, model.InstanceStatusSuspended, reason); err != nil {
+			return fmt.Errorf("状态转换失败: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
+	e.recordStateTransition(ctx, instance, model.InstanceStatusRunning, model.InstanceStatusSuspended, userID, reason)
+	e.publishInstanceEvent(EventProcessSuspended, instance, userID, map[string]interface{}{"reason": reason})
 
 	e.logger.Info("Process instance suspended",
 		zap.Uint("instance_id", instanceID),
@@ -225,25 +383,28 @@ func (e *ProcessEngine) SuspendInstance(instanceID uint, reason string) error {
 	return nil
 }
 
-// ResumeInstance 恢复流程实例
-func (e *ProcessEngine) ResumeInstance(instanceID uint) error {
-	instance, err := e.instanceRepo.GetByID(instanceID)
+// ResumeInstance 恢复流程实例，userID 为操作人，系统操作时为 0
+func (e *ProcessEngine) ResumeInstance(ctx context.Context, instanceID, userID uint) error {
+	instance, err := e.instanceRepo.GetByID(ctx, instanceID)
 	if err != nil {
-		return fmt.Errorf("获取流程实例失败: %v", err)
+		return fmt.Errorf("获取流程实例失败: %w", err)
 	}
 
-	if instance.Status != model.InstanceStatusSuspended {
-		return errors.New("只能恢复暂停的流程实例")
-	}
-
-	// 使用状态机转换状态
-	if err := e.stateMachine.TransitionTo(instance, model.InstanceStatusRunning, ""); err != nil {
-		return fmt.Errorf("状态转换失败: %v", err)
-	}
-
-	if err := e.instanceRepo.Update(instance); err != nil {
-		return fmt.Errorf("更新流程实例状态失败: %v", err)
+	// 使用状态机转换状态，并发修改时在最新状态上重新校验
+	err = e.updateInstance(ctx, instance, func(target *model.ProcessInstance) error {
+		if target.Status != model.InstanceStatusSuspended {
+			return newEngineError(CodeInvalidStateTransition, nil, "只能恢复暂停的流程实例")
+		}
+		if err := e.stateMachine.TransitionTo(target, model.InstanceStatusRunning, ""); err != nil {
+			return fmt.Errorf("状态转换失败: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
+	e.recordStateTransition(ctx, instance, model.InstanceStatusSuspended, model.InstanceStatusRunning, userID, "")
+	e.publishInstanceEvent(EventProcessResumed, instance, userID, nil)
 
 	e.logger.Info("Process instance resumed",
 		zap.Uint("instance_id", instanceID),
@@ -252,29 +413,79 @@ func (e *ProcessEngine) ResumeInstance(instanceID uint) error {
 	return nil
 }
 
-// CancelInstance 取消流程实例
-func (e *ProcessEngine) CancelInstance(instanceID uint, reason string) error {
-	instance, err := e.instanceRepo.GetByID(instanceID)
+// CancelInstance 取消流程实例，userID 为操作人，系统操作时为 0
+func (e *ProcessEngine) CancelInstance(ctx context.Context, instanceID, userID uint, reason string) error {
+	instance, err := e.instanceRepo.GetByID(ctx, instanceID)
+	if err != nil {
+		return fmt.Errorf("获取流程实例失败: %w", err)
+	}
+
+	// 使用状态机转换状态，并发修改时在最新状态上重新校验
+	var previousStatus string
+	err = e.updateInstance(ctx, instance, func(target *model.ProcessInstance) error {
+		previousStatus = target.Status
+		if target.Status == model.InstanceStatusCompleted || target.Status == model.InstanceStatusCancelled {
+			return newEngineError(CodeInvalidStateTransition, nil, "流程实例已完成或已取消，无法取消")
+		}
+		if err := e.stateMachine.TransitionTo(target, model.InstanceStatusCancelled, reason); err != nil {
+			return fmt.Errorf("状态转换失败: %v", err)
+		}
+		now := time.Now()
+		target.CancelledAt = &now
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("获取流程实例失败: %v", err)
+		return err
 	}
+	e.recordStateTransition(ctx, instance, previousStatus, model.InstanceStatusCancelled, userID, reason)
+	e.publishInstanceEvent(EventProcessCancelled, instance, userID, map[string]interface{}{"reason": reason})
+
+	// 记录取消关闭的任务、定时器、消息订阅和子实例，撤销取消时据此恢复
+	snapshot := &model.CancellationSnapshot{Status: previousStatus}
 
-	if instance.Status == model.InstanceStatusCompleted || instance.Status == model.InstanceStatusCancelled {
-		return errors.New("流程实例已完成或已取消，无法取消")
+	// 取消所有未完成的任务
+	if snapshot.Tasks, err = e.cancelInstanceTasks(ctx, instanceID, "流程取消"); err != nil {
+		e.logger.Error("Failed to cancel instance tasks", zap.Error(err))
 	}
 
-	// 使用状态机转换状态
-	if err := e.stateMachine.TransitionTo(instance, model.InstanceStatusCancelled, reason); err != nil {
-		return fmt.Errorf("状态转换失败: %v", err)
+	// 取消等待中的定时器
+	if timers, err := e.instanceRepo.GetWaitingTimers(ctx, instanceID); err != nil {
+		e.logger.Error("Failed to get waiting timers", zap.Error(err))
+	} else {
+		for _, timer := range timers {
+			snapshot.Timers = append(snapshot.Timers, timer.ID)
+		}
+	}
+	if err := e.instanceRepo.CancelTimers(ctx, instanceID, ""); err != nil {
+		e.logger.Error("Failed to cancel instance timers", zap.Error(err))
 	}
 
-	if err := e.instanceRepo.Update(instance); err != nil {
-		return fmt.Errorf("更新流程实例状态失败: %v", err)
+	// 取消等待中的消息订阅
+	if messages, err := e.instanceRepo.GetWaitingMessages(ctx, instanceID); err != nil {
+		e.logger.Error("Failed to get waiting message subscriptions", zap.Error(err))
+	} else {
+		for _, message := range messages {
+			snapshot.Messages = append(snapshot.Messages, message.ID)
+		}
+	}
+	if err := e.instanceRepo.CancelMessageSubscriptions(ctx, instanceID, ""); err != nil {
+		e.logger.Error("Failed to cancel instance message subscriptions", zap.Error(err))
 	}
 
-	// 取消所有未完成的任务
-	if err := e.cancelInstanceTasks(instanceID); err != nil {
-		e.logger.Error("Failed to cancel instance tasks", zap.Error(err))
+	// 取消调用活动启动的子实例
+	if snapshot.Children, err = e.cancelChildInstances(ctx, instanceID, "父流程已取消"); err != nil {
+		e.logger.Error("Failed to cancel child instances", zap.Error(err))
+	}
+
+	// 按完成顺序的逆序补偿已完成的服务任务
+	if snapshot.Compensations, err = e.compensate(ctx, instance); err != nil {
+		e.logger.Error("Failed to compensate instance", zap.Uint("instance_id", instanceID), zap.Error(err))
+	}
+
+	if err := e.updateInstance(ctx, instance, func(target *model.ProcessInstance) error {
+		return target.SetCancellation(snapshot)
+	}); err != nil {
+		e.logger.Error("Failed to save cancellation snapshot", zap.Uint("instance_id", instanceID), zap.Error(err))
 	}
 
 	e.logger.Info("Process instance cancelled",
@@ -298,38 +509,61 @@ func (e *ProcessEngine) findStartNode(nodes []model.ProcessNode) *model.ProcessN
 }
 
 // moveToNextNode 推进到下一个节点
-func (e *ProcessEngine) moveToNextNode(instance *model.ProcessInstance, currentNodeID string) error {
+func (e *ProcessEngine) moveToNextNode(ctx context.Context, instance *model.ProcessInstance, currentNodeID string) error {
 	// 获取流程定义
 	definitionData, err := instance.Definition.GetDefinitionData()
 	if err != nil {
-		return fmt.Errorf("解析流程定义失败: %v", err)
+		return newEngineError(CodeInvalidDefinition, err, "解析流程定义失败")
 	}
 
 	// 查找当前节点
 	currentNode := e.findNodeByID(definitionData.Nodes, currentNodeID)
 	if currentNode == nil {
-		return fmt.Errorf("找不到节点: %s", currentNodeID)
+		return newEngineError(CodeNodeNotFound, nil, "找不到节点: %s", currentNodeID)
+	}
+	e.recordNodeActivity(ctx, instance, currentNode, model.ActivityNodeEntered, nil)
+	e.traceFor(instance).record(ctx, model.TraceCategoryNode, currentNode.ID, nil, "进入节点 %s（%s）", currentNode.ID, currentNode.Type)
+
+	// 超过访问上限的节点不再执行，改走升级连线或生成异常事件
+	if blocked, err := e.checkVisitLimit(ctx, instance, currentNode, definitionData); blocked || err != nil {
+		return err
 	}
 
-	// 根据节点类型处理
+	return e.executeNode(ctx, instance, currentNode, definitionData)
+}
+
+// executeNode 根据节点类型执行节点
+func (e *ProcessEngine) executeNode(ctx context.Context, instance *model.ProcessInstance, currentNode *model.ProcessNode, definitionData *model.ProcessDefinitionData) error {
 	switch currentNode.Type {
 	case "start":
-		return e.handleStartNode(instance, currentNode, definitionData)
+		return e.handleStartNode(ctx, instance, currentNode, definitionData)
 	case "userTask":
-		return e.handleUserTask(instance, currentNode)
+		return e.handleUserTask(ctx, instance, currentNode)
 	case "serviceTask":
-		return e.handleServiceTask(instance, currentNode)
+		return e.handleServiceTask(ctx, instance, currentNode)
+	case model.NodeTypeScriptTask:
+		return e.handleScriptTask(ctx, instance, currentNode)
+	case model.NodeTypeMailTask:
+		return e.handleMailTask(ctx, instance, currentNode)
 	case "gateway":
-		return e.handleGateway(instance, currentNode, definitionData)
+		return e.handleGateway(ctx, instance, currentNode, definitionData)
+	case model.NodeTypeParallelReview:
+		return e.handleParallelReview(ctx, instance, currentNode)
+	case model.NodeTypeTimer:
+		return e.handleTimer(ctx, instance, currentNode)
+	case model.NodeTypeMessageCatch:
+		return e.handleMessageCatch(ctx, instance, currentNode)
+	case model.NodeTypeCallActivity:
+		return e.handleCallActivity(ctx, instance, currentNode)
 	case "end":
-		return e.handleEndNode(instance, currentNode)
+		return e.handleEndNode(ctx, instance, currentNode)
 	default:
-		return fmt.Errorf("不支持的节点类型: %s", currentNode.Type)
+		return newEngineError(CodeUnsupportedNodeType, nil, "不支持的节点类型: %s", currentNode.Type)
 	}
 }
 
 // handleStartNode 处理开始节点
-func (e *ProcessEngine) handleStartNode(instance *model.ProcessInstance, node *model.ProcessNode, definition *model.ProcessDefinitionData) error {
+func (e *ProcessEngine) handleStartNode(ctx context.Context, instance *model.ProcessInstance, node *model.ProcessNode, definition *model.ProcessDefinitionData) error {
 	e.logger.Info("Handling start node",
 		zap.Uint("instance_id", instance.ID),
 		zap.String("node_id", node.ID),
@@ -338,7 +572,7 @@ func (e *ProcessEngine) handleStartNode(instance *model.ProcessInstance, node *m
 	// 查找开始节点的出口连线
 	outgoingFlows := e.findOutgoingFlows(definition.Flows, node.ID)
 	if len(outgoingFlows) == 0 {
-		return errors.New("开始节点没有出口连线")
+		return newEngineError(CodeNoOutgoingFlow, nil, "开始节点没有出口连线")
 	}
 
 	e.logger.Info("Found outgoing flows from start node",
@@ -348,17 +582,17 @@ func (e *ProcessEngine) handleStartNode(instance *model.ProcessInstance, node *m
 
 	// 推进到下一个节点
 	nextNodeID := outgoingFlows[0].To
+	e.recordNodeActivity(ctx, instance, node, model.ActivityNodeExited, nil)
 
 	// 更新当前节点到下一个节点
-	instance.CurrentNode = nextNodeID
-	if err := e.instanceRepo.Update(instance); err != nil {
+	if err := e.moveInstanceTo(ctx, instance, nextNodeID); err != nil {
 		return fmt.Errorf("更新流程实例当前节点失败: %v", err)
 	}
 
 	// 处理下一个节点
 	nextNode := e.findNodeByID(definition.Nodes, nextNodeID)
 	if nextNode == nil {
-		return fmt.Errorf("找不到下一个节点: %s", nextNodeID)
+		return newEngineError(CodeNodeNotFound, nil, "找不到下一个节点: %s", nextNodeID)
 	}
 
 	e.logger.Info("Processing next node",
@@ -367,54 +601,41 @@ func (e *ProcessEngine) handleStartNode(instance *model.ProcessInstance, node *m
 		zap.String("node_name", nextNode.Name),
 	)
 
-	// 根据下一个节点类型处理
-	switch nextNode.Type {
-	case "userTask":
-		e.logger.Info("Calling handleUserTask")
-		return e.handleUserTask(instance, nextNode)
-	case "serviceTask":
-		e.logger.Info("Calling handleServiceTask")
-		return e.handleServiceTask(instance, nextNode)
-	case "gateway":
-		e.logger.Info("Calling handleGateway")
-		return e.handleGateway(instance, nextNode, definition)
-	case "end":
-		e.logger.Info("Calling handleEndNode")
-		return e.handleEndNode(instance, nextNode)
-	default:
-		e.logger.Error("Unsupported node type",
-			zap.String("node_type", nextNode.Type),
-			zap.String("node_id", nextNode.ID),
-		)
-		return fmt.Errorf("不支持的节点类型: %s", nextNode.Type)
-	}
+	e.recordNodeActivity(ctx, instance, nextNode, model.ActivityNodeEntered, nil)
+	e.traceFor(instance).record(ctx, model.TraceCategoryNode, nextNode.ID, nil, "进入节点 %s（%s）", nextNode.ID, nextNode.Type)
+
+	return e.executeNode(ctx, instance, nextNode, definition)
 }
 
 // handleUserTask 处理用户任务节点
-func (e *ProcessEngine) handleUserTask(instance *model.ProcessInstance, node *model.ProcessNode) error {
+func (e *ProcessEngine) handleUserTask(ctx context.Context, instance *model.ProcessInstance, node *model.ProcessNode) error {
 	e.logger.Info("Handling user task node",
 		zap.Uint("instance_id", instance.ID),
 		zap.String("node_id", node.ID),
 		zap.String("task_name", node.Name),
 	)
 
+	// 多实例（会签）任务按处理人创建多个任务
+	multiInstance, err := model.GetMultiInstanceConfig(node)
+	if err != nil {
+		return newEngineError(CodeInvalidDefinition, err, "节点 %s 的会签配置无效", node.ID)
+	}
+	if multiInstance != nil {
+		if err := e.handleMultiInstanceTask(ctx, instance, node, multiInstance); err != nil {
+			return err
+		}
+		return e.startBoundaryTimer(ctx, instance, node)
+	}
+
 	// 使用任务生命周期管理器创建任务
-	task, err := e.taskLifecycle.CreateTask(instance, node.ID)
+	task, err := e.taskLifecycle.CreateTask(ctx, instance, node.ID)
 	if err != nil {
 		return fmt.Errorf("创建用户任务失败: %v", err)
 	}
+	e.traceFor(instance).record(ctx, model.TraceCategoryWrite, node.ID, map[string]interface{}{"task_id": task.ID}, "创建用户任务 %d", task.ID)
 
-	// 更新流程实例统计
 	// 注意：CurrentNode已经在handleStartNode中更新了，这里不需要重复更新
 
-	if err := e.instanceRepo.Update(instance); err != nil {
-		e.logger.Error("Failed to update process instance",
-			zap.Uint("instance_id", instance.ID),
-			zap.Error(err),
-		)
-		return fmt.Errorf("更新流程实例失败: %v", err)
-	}
-
 	// 发布任务创建事件
 	e.logger.Info("User task created",
 		zap.Uint("instance_id", instance.ID),
@@ -422,11 +643,36 @@ func (e *ProcessEngine) handleUserTask(instance *model.ProcessInstance, node *mo
 		zap.String("node_id", node.ID),
 	)
 
+	// 配置了候选人时只向候选角色和候选用户开放认领
+	if err := e.offerToCandidates(ctx, instance, node, task); err != nil {
+		return err
+	}
+
+	// 配置了处理人表达式时立即分配任务
+	if err := e.assignByExpression(ctx, instance, node, task); err != nil {
+		return err
+	}
+
+	// 配置了边界定时器时开始计时，到期仍未完成则沿超时连线推进
+	if err := e.startBoundaryTimer(ctx, instance, node); err != nil {
+		return err
+	}
+
+	// 自动处理规则命中时由系统完成或跳过任务
+	if _, err := e.applyAutoRules(ctx, instance, node, task); err != nil {
+		return fmt.Errorf("执行自动处理规则失败: %v", err)
+	}
+
 	return nil
 }
 
 // handleServiceTask 处理服务任务节点
-func (e *ProcessEngine) handleServiceTask(instance *model.ProcessInstance, node *model.ProcessNode) error {
+func (e *ProcessEngine) handleServiceTask(ctx context.Context, instance *model.ProcessInstance, node *model.ProcessNode) error {
+	// 外部任务由外部处理程序获取并完成，引擎不调用外部系统
+	if model.IsExternalTask(node) {
+		return e.handleExternalTask(ctx, instance, node)
+	}
+
 	// 创建服务任务
 	task := &model.TaskInstance{
 		InstanceID: instance.ID,
@@ -437,43 +683,95 @@ func (e *ProcessEngine) handleServiceTask(instance *model.ProcessInstance, node
 	}
 
 	// 保存任务
-	if err := e.taskRepo.Create(task); err != nil {
+	if err := e.taskRepo.Create(ctx, task); err != nil {
 		return fmt.Errorf("创建服务任务失败: %v", err)
 	}
+	e.traceFor(instance).record(ctx, model.TraceCategoryWrite, node.ID, map[string]interface{}{"task_id": task.ID}, "创建服务任务 %d", task.ID)
 
-	// 立即执行服务任务
-	if err := e.executeServiceTask(task, node); err != nil {
+	// 异步节点交给后台作业执行器执行，实例停留在当前节点直到作业完成
+	if model.IsAsyncNode(node) {
+		return e.enqueueAsyncJob(ctx, instance, task, node)
+	}
+	return e.runServiceTask(ctx, instance, task, node)
+}
+
+// runServiceTask 执行服务任务并推进流程
+func (e *ProcessEngine) runServiceTask(ctx context.Context, instance *model.ProcessInstance, task *model.TaskInstance, node *model.ProcessNode) error {
+	// 检查连接器白名单，违规时生成异常事件并停留在当前节点
+	if err := e.checkConnectorPolicy(ctx, instance, node); err != nil {
+		return e.failServiceTask(ctx, instance, task, node, model.IncidentTypeConnectorPolicy, err)
+	}
+
+	// 立即执行服务任务，失败时生成异常事件并停留在当前节点，可通过重试恢复
+	if err := e.executeServiceTask(ctx, instance, task, node); err != nil {
 		e.logger.Error("Service task execution failed", zap.Error(err))
-		return err
+		// 声明了失败时补偿的任务不生成异常事件，直接取消实例并补偿已完成的服务任务
+		if model.CompensatesOnFailure(node) {
+			return e.abortWithCompensation(ctx, instance, task, node, err)
+		}
+		return e.failServiceTask(ctx, instance, task, node, model.IncidentTypeServiceFailed, err)
 	}
 
 	// 任务执行成功，推进流程
-	return e.completeServiceTask(instance, task, node)
+	return e.completeServiceTask(ctx, instance, task, node)
 }
 
 // handleGateway 处理网关节点
-func (e *ProcessEngine) handleGateway(instance *model.ProcessInstance, node *model.ProcessNode, definition *model.ProcessDefinitionData) error {
-	// 获取流程变量
-	variables, err := e.getInstanceVariables(instance.ID)
+func (e *ProcessEngine) handleGateway(ctx context.Context, instance *model.ProcessInstance, node *model.ProcessNode, definition *model.ProcessDefinitionData) error {
+	// 一次读取出口条件引用的全部变量
+	variables, err := e.variableEngine.GetVariables(ctx, instance.ID, conditionVariables(e.findOutgoingFlows(definition.Flows, node.ID)))
 	if err != nil {
-		return fmt.Errorf("获取流程变量失败: %v", err)
+		return err
 	}
 
-	// 评估网关条件
-	nextNodeIDs, err := e.evaluateGatewayConditions(node, definition.Flows, variables)
+	// 评估网关条件，条件无法评估时生成异常事件，流程停留在网关等待修正变量后重试
+	nextNodeIDs, err := e.evaluateGatewayConditions(ctx, node, definition.Flows, variables, e.traceFor(instance))
 	if err != nil {
-		return fmt.Errorf("评估网关条件失败: %v", err)
+		if incidentErr := e.raiseIncident(ctx, instance, nil, node, model.IncidentTypeConditionFailed, err); incidentErr != nil {
+			return incidentErr
+		}
+		return err
 	}
 
+	// 没有可执行的路径时生成异常事件，流程停留在网关等待处理
 	if len(nextNodeIDs) == 0 {
-		return errors.New("网关条件评估后没有可执行的路径")
+		cause := newEngineError(CodeGatewayNoPath, nil, "网关 %s 条件评估后没有可执行的路径", node.ID)
+		if err := e.raiseIncident(ctx, instance, nil, node, model.IncidentTypeGatewayNoPath, cause); err != nil {
+			return err
+		}
+		return cause
 	}
 
+	e.recordNodeActivity(ctx, instance, node, model.ActivityGatewayDecision, map[string]interface{}{
+		"gateway_type": model.GetGatewayType(node),
+		"targets":      nextNodeIDs,
+	})
+	e.recordNodeActivity(ctx, instance, node, model.ActivityNodeExited, nil)
+
 	// 推进到所有满足条件的节点
+	selected := make(map[string]bool, len(nextNodeIDs))
 	for _, nodeID := range nextNodeIDs {
-		if err := e.moveToNextNode(instance, nodeID); err != nil {
+		selected[nodeID] = true
+	}
+	var taken []model.ProcessFlow
+	for _, flow := range e.findOutgoingFlows(definition.Flows, node.ID) {
+		if selected[flow.To] {
+			delete(selected, flow.To)
+			taken = append(taken, flow)
+		}
+	}
+
+	// 包容网关分叉时记录未激活的分支，下游的包容汇聚网关只等待激活的分支
+	if model.GetGatewayType(node) == model.GatewayTypeInclusive {
+		if err := e.skipInactiveBranches(ctx, instance, node, taken, definition); err != nil {
+			return err
+		}
+	}
+
+	for _, flow := range taken {
+		if err := e.advanceAlongFlow(ctx, instance, flow, definition); err != nil {
 			e.logger.Error("Failed to move to next node",
-				zap.String("node_id", nodeID),
+				zap.String("node_id", flow.To),
 				zap.Error(err),
 			)
 		}
@@ -483,29 +781,64 @@ func (e *ProcessEngine) handleGateway(instance *model.ProcessInstance, node *mod
 }
 
 // handleEndNode 处理结束节点
-func (e *ProcessEngine) handleEndNode(instance *model.ProcessInstance, node *model.ProcessNode) error {
-	now := time.Now()
-
-	// 使用状态机转换状态
-	if err := e.stateMachine.TransitionTo(instance, model.InstanceStatusCompleted, ""); err != nil {
-		return fmt.Errorf("状态转换失败: %v", err)
+func (e *ProcessEngine) handleEndNode(ctx context.Context, instance *model.ProcessInstance, node *model.ProcessNode) error {
+	// 终止结束事件立即结束其他分支；普通结束事件只结束当前分支，其他分支未结束时实例继续运行
+	if model.IsTerminateEnd(node) {
+		e.terminateBranches(ctx, instance, node)
+	} else {
+		active, err := e.hasActiveBranches(ctx, instance.ID)
+		if err != nil {
+			return err
+		}
+		if active {
+			e.traceFor(instance).record(ctx, model.TraceCategoryNode, node.ID, nil,
+				"分支到达结束节点 %s，实例还有未结束的分支", node.ID)
+			e.logger.Info("Branch ended, waiting for other branches",
+				zap.Uint("instance_id", instance.ID),
+				zap.String("end_node", node.ID),
+			)
+			return nil
+		}
 	}
 
-	instance.EndTime = &now
-	instance.CurrentNode = node.ID
-
-	// 更新执行路径
-	// e.updateExecutionPath(instance, node.ID) // 移除此行
+	now := time.Now()
 
-	if err := e.instanceRepo.Update(instance); err != nil {
-		return fmt.Errorf("更新流程实例状态失败: %v", err)
+	// 使用状态机转换状态，并发修改时在最新状态上重新校验，已被取消的实例不会再被完成
+	var previousStatus string
+	err := e.updateInstance(ctx, instance, func(target *model.ProcessInstance) error {
+		previousStatus = target.Status
+		if err := e.stateMachine.TransitionTo(target, model.InstanceStatusCompleted, ""); err != nil {
+			return fmt.Errorf("状态转换失败: %v", err)
+		}
+		target.EndTime = &now
+		target.CurrentNode = node.ID
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
+	e.recordStateTransition(ctx, instance, previousStatus, model.InstanceStatusCompleted, 0, "")
+	e.publishInstanceEvent(EventProcessCompleted, instance, 0, nil)
+
 	e.logger.Info("Process instance completed",
 		zap.Uint("instance_id", instance.ID),
 		zap.String("end_node", node.ID),
 	)
 
+	e.notifyCompletion(ctx, instance, node)
+
+	// 子实例完成后继续推进父实例
+	if instance.ParentInstanceID != nil {
+		if err := e.resumeParent(ctx, instance); err != nil {
+			e.logger.Error("Failed to resume parent instance",
+				zap.Uint("instance_id", instance.ID),
+				zap.Uint("parent_instance_id", *instance.ParentInstanceID),
+				zap.Error(err),
+			)
+		}
+	}
+
 	return nil
 }
 
@@ -532,21 +865,6 @@ func (e *ProcessEngine) findOutgoingFlows(flows []model.ProcessFlow, nodeID stri
 	return outgoing
 }
 
-// getInstanceVariables 获取流程实例变量
-func (e *ProcessEngine) getInstanceVariables(instanceID uint) (map[string]interface{}, error) {
-	instance, err := e.instanceRepo.GetByID(instanceID)
-	if err != nil {
-		return nil, err
-	}
-
-	var variables map[string]interface{}
-	if err := json.Unmarshal([]byte(instance.Variables), &variables); err != nil {
-		return nil, err
-	}
-
-	return variables, nil
-}
-
 // estimateProcessDuration 估算流程执行时间
 func (e *ProcessEngine) estimateProcessDuration(definition *model.ProcessDefinitionData) int {
 	// 简单估算：用户任务1小时，服务任务1分钟
@@ -555,42 +873,54 @@ func (e *ProcessEngine) estimateProcessDuration(definition *model.ProcessDefinit
 		switch node.Type {
 		case "userTask":
 			duration += 3600 // 1小时
-		case "serviceTask":
+		case "serviceTask", model.NodeTypeScriptTask, model.NodeTypeMailTask:
 			duration += 60 // 1分钟
 		}
 	}
 	return duration
 }
 
-// executeServiceTask 执行服务任务
-func (e *ProcessEngine) executeServiceTask(task *model.TaskInstance, node *model.ProcessNode) error {
+// executeServiceTask 执行服务任务，每次执行都记录执行日志
+func (e *ProcessEngine) executeServiceTask(ctx context.Context, instance *model.ProcessInstance, task *model.TaskInstance, node *model.ProcessNode) error {
 	e.logger.Info("Executing service task",
 		zap.Uint("task_id", task.ID),
 		zap.String("node_id", node.ID),
 	)
 
-	// 使用简化后的服务执行器
-	return e.serviceExecutor.ExecuteService(task)
+	req, err := BuildServiceRequest(instance, task, node)
+	if err != nil {
+		return err
+	}
+
+	log := e.startExecutionLog(ctx, instance, task, node, req)
+	var result *ServiceResult
+	if e.connectorMock.Applies(req) {
+		result, err = e.connectorMock.Execute(ctx, task, req)
+	} else {
+		result, err = e.serviceExecutor.ExecuteService(ctx, task, req)
+	}
+	e.finishExecutionLog(ctx, log, result, err)
+	return err
 }
 
 // completeServiceTask 完成服务任务
-func (e *ProcessEngine) completeServiceTask(instance *model.ProcessInstance, task *model.TaskInstance, node *model.ProcessNode) error {
+func (e *ProcessEngine) completeServiceTask(ctx context.Context, instance *model.ProcessInstance, task *model.TaskInstance, node *model.ProcessNode) error {
 	now := time.Now()
 	task.Status = model.TaskStatusCompleted
 	task.CompleteTime = &now
 
-	if err := e.taskRepo.Update(task); err != nil {
+	if err := e.taskRepo.Update(ctx, task); err != nil {
 		return fmt.Errorf("更新服务任务状态失败: %v", err)
 	}
 
 	// 推进流程到下一个节点
-	return e.checkAndAdvanceProcess(instance, node.ID)
+	return e.checkAndAdvanceProcess(ctx, instance, node.ID)
 }
 
 // checkAndAdvanceProcess 检查并推进流程
-func (e *ProcessEngine) checkAndAdvanceProcess(instance *model.ProcessInstance, nodeID string) error {
+func (e *ProcessEngine) checkAndAdvanceProcess(ctx context.Context, instance *model.ProcessInstance, nodeID string) error {
 	// 检查当前节点的所有任务是否都已完成
-	pendingTasks, err := e.taskRepo.GetByInstanceAndNode(instance.ID, nodeID, []string{
+	pendingTasks, err := e.taskRepo.GetByInstanceAndNode(ctx, instance.ID, nodeID, []string{
 		model.TaskStatusCreated,
 		model.TaskStatusAssigned,
 		model.TaskStatusClaimed,
@@ -616,6 +946,13 @@ func (e *ProcessEngine) checkAndAdvanceProcess(instance *model.ProcessInstance,
 		return fmt.Errorf("解析流程定义失败: %v", err)
 	}
 
+	// 声明了循环的节点继续循环时重新执行，不沿出口连线推进
+	if node := e.findNodeByID(definitionData.Nodes, nodeID); node != nil {
+		if repeated, err := e.repeatLoop(ctx, instance, node); repeated || err != nil {
+			return err
+		}
+	}
+
 	// 查找出口连线
 	outgoingFlows := e.findOutgoingFlows(definitionData.Flows, nodeID)
 	if len(outgoingFlows) == 0 {
@@ -623,9 +960,30 @@ func (e *ProcessEngine) checkAndAdvanceProcess(instance *model.ProcessInstance,
 		return nil
 	}
 
+	// 任务正常完成时取消边界定时器，超时连线只由定时器触发，访问上限的升级连线只在超过上限时使用
+	boundaryFlow, escalationFlow := "", ""
+	if node := e.findNodeByID(definitionData.Nodes, nodeID); node != nil {
+		e.recordNodeActivity(ctx, instance, node, model.ActivityNodeExited, nil)
+		if boundary, _ := model.GetBoundaryTimer(node); boundary != nil {
+			boundaryFlow = boundary.Flow
+			if err := e.instanceRepo.CancelTimers(ctx, instance.ID, nodeID); err != nil {
+				return fmt.Errorf("取消边界定时器失败: %v", err)
+			}
+		}
+		if limit, _ := model.GetVisitLimit(node); limit != nil {
+			escalationFlow = limit.Flow
+		}
+	}
+
 	// 推进到所有满足条件的节点
 	for _, flow := range outgoingFlows {
-		if err := e.moveToNextNode(instance, flow.To); err != nil {
+		if boundaryFlow != "" && flow.ID == boundaryFlow {
+			continue
+		}
+		if escalationFlow != "" && flow.ID == escalationFlow {
+			continue
+		}
+		if err := e.advanceAlongFlow(ctx, instance, flow, definitionData); err != nil {
 			e.logger.Error("Failed to move to next node",
 				zap.String("node_id", flow.To),
 				zap.Error(err),
@@ -636,21 +994,24 @@ func (e *ProcessEngine) checkAndAdvanceProcess(instance *model.ProcessInstance,
 	return nil
 }
 
-// evaluateGatewayConditions 评估网关条件
-func (e *ProcessEngine) evaluateGatewayConditions(gateway *model.ProcessNode, flows []model.ProcessFlow, variables map[string]interface{}) ([]string, error) {
-	gatewayType := "exclusive" // 默认排他网关
-	if gType, ok := gateway.Props["gatewayType"].(string); ok {
-		gatewayType = gType
-	}
-
+// evaluateGatewayConditions 评估网关条件，任一条件评估失败时返回错误而不是选择该路径
+// trace 不为 nil 时记录每次条件评估和选择的路径
+func (e *ProcessEngine) evaluateGatewayConditions(ctx context.Context, gateway *model.ProcessNode, flows []model.ProcessFlow, variables map[string]interface{}, trace *executionTrace) ([]string, error) {
 	outgoingFlows := e.findOutgoingFlows(flows, gateway.ID)
 	var nextNodes []string
 
-	switch gatewayType {
-	case "exclusive":
+	switch model.GetGatewayType(gateway) {
+	case model.GatewayTypeExclusive:
 		// 排他网关：只选择第一个满足条件的路径
 		for _, flow := range outgoingFlows {
-			if e.evaluateCondition(flow.Condition, variables) {
+			if flow.Condition == "" {
+				continue
+			}
+			matched, err := e.evaluateCondition(ctx, gateway, flow, variables, trace)
+			if err != nil {
+				return nil, err
+			}
+			if matched {
 				nextNodes = append(nextNodes, flow.To)
 				break
 			}
@@ -664,64 +1025,154 @@ func (e *ProcessEngine) evaluateGatewayConditions(gateway *model.ProcessNode, fl
 				}
 			}
 		}
-	case "parallel":
+	case model.GatewayTypeParallel:
 		// 并行网关：所有路径都执行
 		for _, flow := range outgoingFlows {
 			nextNodes = append(nextNodes, flow.To)
 		}
-	case "inclusive":
+	case model.GatewayTypeInclusive:
 		// 包容网关：所有满足条件的路径都执行
 		for _, flow := range outgoingFlows {
-			if flow.Condition == "" || e.evaluateCondition(flow.Condition, variables) {
+			matched, err := e.evaluateCondition(ctx, gateway, flow, variables, trace)
+			if err != nil {
+				return nil, err
+			}
+			if matched {
 				nextNodes = append(nextNodes, flow.To)
 			}
 		}
 	}
 
+	trace.record(ctx, model.TraceCategoryFlow, gateway.ID, map[string]interface{}{
+		"gateway_type": model.GetGatewayType(gateway),
+		"targets":      nextNodes,
+	}, "网关 %s 选择了 %d 条路径", gateway.ID, len(nextNodes))
 	return nextNodes, nil
 }
 
-// evaluateCondition 评估条件表达式
-func (e *ProcessEngine) evaluateCondition(condition string, variables map[string]interface{}) bool {
-	if condition == "" {
-		return true
+// evaluateCondition 评估连线条件，空条件视为满足
+func (e *ProcessEngine) evaluateCondition(ctx context.Context, gateway *model.ProcessNode, flow model.ProcessFlow, variables map[string]interface{}, trace *executionTrace) (bool, error) {
+	if flow.Condition == "" {
+		return true, nil
 	}
 
-	// 使用VariableEngine评估条件
-	result, err := e.variableEngine.EvaluateCondition(condition, variables)
+	result, err := e.variableEngine.EvaluateCondition(flow.Condition, variables)
 	if err != nil {
-		e.logger.Error("Condition evaluation failed",
-			zap.String("condition", condition),
-			zap.Any("variables", variables),
-			zap.Error(err),
-		)
-		// 条件评估失败时默认返回true
-		return true
-	}
+		trace.record(ctx, model.TraceCategoryCondition, gateway.ID, map[string]interface{}{
+			"flow_id":   flow.ID,
+			"condition": flow.Condition,
+			"error":     err.Error(),
+			"variables": variables,
+		}, "连线 %s 的条件 %s 评估失败", flow.ID, flow.Condition)
+		return false, newEngineError(CodeConditionFailed, err, "网关 %s 的连线 %s 条件评估失败", gateway.ID, flow.ID)
+	}
+	trace.record(ctx, model.TraceCategoryCondition, gateway.ID, map[string]interface{}{
+		"flow_id":   flow.ID,
+		"condition": flow.Condition,
+		"result":    result,
+		"variables": variables,
+	}, "连线 %s 的条件 %s 评估结果为 %t", flow.ID, flow.Condition, result)
+	return result, nil
+}
 
-	return result
+// conditionVariables 收集连线条件引用的变量名，无法解析的条件留到评估时报错
+func conditionVariables(flows []model.ProcessFlow) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, flow := range flows {
+		if strings.TrimSpace(flow.Condition) == "" {
+			continue
+		}
+		expr, err := expression.ParseCondition(flow.Condition)
+		if err != nil {
+			continue
+		}
+		for _, name := range expr.Variables() {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
 }
 
 // GetInstance 获取流程实例
-func (e *ProcessEngine) GetInstance(instanceID uint) (*model.ProcessInstance, error) {
-	return e.instanceRepo.GetByID(instanceID)
+func (e *ProcessEngine) GetInstance(ctx context.Context, instanceID uint) (*model.ProcessInstance, error) {
+	instance, err := e.instanceRepo.GetByID(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	e.applyInstanceLabels(instance)
+	return instance, nil
 }
 
 // GetInstances 获取流程实例列表
-func (e *ProcessEngine) GetInstances(offset, limit int, filters map[string]interface{}) ([]model.ProcessInstance, int64, error) {
-	return e.instanceRepo.List(offset, limit, filters)
+func (e *ProcessEngine) GetInstances(ctx context.Context, offset, limit int, filters map[string]interface{}) ([]model.ProcessInstance, int64, error) {
+	instances, total, err := e.instanceRepo.List(ctx, offset, limit, filters)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range instances {
+		e.applyInstanceLabels(&instances[i])
+		instances[i].RedactForList()
+	}
+	return instances, total, nil
+}
+
+// GetInstancesAfter 按游标获取流程实例列表，after 为上一页最后一个实例的启动时间和ID
+func (e *ProcessEngine) GetInstancesAfter(ctx context.Context, after repository.Keyset, limit int, filters map[string]interface{}) ([]model.ProcessInstance, error) {
+	instances, err := e.instanceRepo.ListAfter(ctx, after, limit, filters)
+	if err != nil {
+		return nil, err
+	}
+	for i := range instances {
+		e.applyInstanceLabels(&instances[i])
+		instances[i].RedactForList()
+	}
+	return instances, nil
+}
+
+// GetInstanceHistory 获取流程实例执行历史，activities 为引擎写入的活动历史
+func (e *ProcessEngine) GetInstanceHistory(ctx context.Context, instanceID uint, query *repository.ActivityHistoryQuery) (interface{}, error) {
+	history, err := e.GetInstanceHistorySummary(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 节点进出、网关决策、变量变化和状态转换
+	activities, err := e.GetActivityHistory(ctx, instanceID, query)
+	if err != nil {
+		return nil, err
+	}
+	history["activities"] = activities
+
+	return history, nil
 }
 
-// GetInstanceHistory 获取流程实例执行历史
-func (e *ProcessEngine) GetInstanceHistory(instanceID uint) (interface{}, error) {
+// GetInstanceHistorySummary 获取流程实例执行历史中除活动历史以外的部分，
+// 活动历史数量可能很大，由调用方通过 EachActivity 逐条读取
+func (e *ProcessEngine) GetInstanceHistorySummary(ctx context.Context, instanceID uint) (map[string]interface{}, error) {
 	// 获取流程实例
-	instance, err := e.instanceRepo.GetByID(instanceID)
+	instance, err := e.instanceRepo.GetByID(ctx, instanceID)
 	if err != nil {
 		return nil, err
 	}
 
 	// 获取所有任务
-	tasks, err := e.taskRepo.GetByInstance(instanceID)
+	tasks, err := e.taskRepo.GetByInstance(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 调用活动形成的父子实例层级
+	hierarchy, err := e.GetInstanceHierarchy(ctx, instance)
+	if err != nil {
+		return nil, err
+	}
+
+	// 任务讨论记录
+	comments, err := e.taskRepo.GetInstanceComments(ctx, instanceID)
 	if err != nil {
 		return nil, err
 	}
@@ -730,6 +1181,8 @@ func (e *ProcessEngine) GetInstanceHistory(instanceID uint) (interface{}, error)
 	history := map[string]interface{}{
 		"instance":   instance,
 		"tasks":      tasks,
+		"comments":   comments,
+		"hierarchy":  hierarchy,
 		"created_at": instance.CreatedAt,
 		"start_time": instance.StartTime,
 		"end_time":   instance.EndTime,
@@ -738,81 +1191,136 @@ func (e *ProcessEngine) GetInstanceHistory(instanceID uint) (interface{}, error)
 	return history, nil
 }
 
-// cancelInstanceTasks 取消流程实例的所有任务
-func (e *ProcessEngine) cancelInstanceTasks(instanceID uint) error {
-	tasks, err := e.taskRepo.GetByInstance(instanceID)
+// cancelInstanceTasks 取消流程实例的所有任务，返回被跳过的任务及其原状态
+func (e *ProcessEngine) cancelInstanceTasks(ctx context.Context, instanceID uint, reason string) (map[uint]string, error) {
+	tasks, err := e.taskRepo.GetByInstance(ctx, instanceID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	skipped := make(map[uint]string)
 	for _, task := range tasks {
-		if task.Status != model.TaskStatusCompleted && task.Status != model.TaskStatusFailed {
+		if task.Status != model.TaskStatusCompleted && task.Status != model.TaskStatusFailed && task.Status != model.TaskStatusSkipped {
+			previous := task.Status
 			task.Status = model.TaskStatusSkipped
-			if err := e.taskRepo.Update(&task); err != nil {
+			if err := e.taskRepo.Update(ctx, &task); err != nil {
 				e.logger.Error("Failed to cancel task", zap.Uint("task_id", task.ID), zap.Error(err))
+				continue
 			}
+			skipped[task.ID] = previous
 
 			// 发布任务跳过事件
 			e.logger.Info("Task skipped",
 				zap.Uint("instance_id", instanceID),
 				zap.Uint("task_id", task.ID),
-				zap.String("reason", "流程取消"),
+				zap.String("reason", reason),
 			)
+			e.publishTaskEvent(EventTaskSkipped, &task, 0, map[string]interface{}{"reason": reason})
 		}
 	}
 
-	return nil
+	return skipped, nil
 }
 
 // GetUserTasks 获取用户任务列表
-func (e *ProcessEngine) GetUserTasks(userID uint, status string, offset, limit int) ([]model.TaskInstance, int64, error) {
-	return e.taskRepo.GetUserTasks(userID, status, offset, limit)
+func (e *ProcessEngine) GetUserTasks(ctx context.Context, userID uint, status string, offset, limit int) ([]model.TaskInstance, int64, error) {
+	role, err := e.candidateRole(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	tasks, total, err := e.taskRepo.GetUserTasks(ctx, userID, role, status, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	e.applyTaskListLabels(tasks)
+	redactTaskList(tasks)
+	return tasks, total, nil
 }
 
 // GetTask 获取任务详情
-func (e *ProcessEngine) GetTask(taskID uint) (*model.TaskInstance, error) {
-	return e.taskRepo.GetByID(taskID)
+func (e *ProcessEngine) GetTask(ctx context.Context, taskID uint) (*model.TaskInstance, error) {
+	task, err := e.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	e.applyTaskLabels(task)
+	return task, nil
 }
 
-// ClaimTask 认领任务
-func (e *ProcessEngine) ClaimTask(taskID uint, userID uint) error {
-	return e.taskRepo.ClaimTask(taskID, userID)
-}
+// ClaimTask 认领任务，任务池中限定了候选人的任务只有候选人可以认领
+// 升级到用户组的任务由组内成员竞争认领，条件更新保证只有一个成员成功，并记录认领的成员；
+// 候选人检查之后任务被升级时认领失败，返回 repository.ErrTaskNotClaimable
+func (e *ProcessEngine) ClaimTask(ctx context.Context, taskID uint, userID uint) error {
+	task, err := e.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	if err := e.checkCandidate(ctx, task, userID); err != nil {
+		return err
+	}
 
-// ReleaseTask 释放任务
-func (e *ProcessEngine) ReleaseTask(taskID uint, userID uint) error {
-	return e.taskRepo.ReleaseTask(taskID, userID)
+	if err := e.taskRepo.ClaimTask(ctx, task, userID); err != nil {
+		return err
+	}
+	if task.EscalationGroup != "" {
+		e.recordActivity(ctx, &model.ActivityHistory{
+			InstanceID: task.InstanceID,
+			Type:       model.ActivityTaskClaimed,
+			NodeID:     task.NodeID,
+			NodeType:   model.NodeTypeUserTask,
+			TaskID:     &task.ID,
+		}, userID, map[string]interface{}{
+			"group":            task.EscalationGroup,
+			"escalation_level": task.EscalationLevel,
+		})
+	}
+	if task, err := e.taskRepo.GetByID(ctx, taskID); err == nil {
+		e.publishTaskEvent(EventTaskClaimed, task, userID, nil)
+	}
+	return nil
 }
 
-// DelegateTask 委派任务
-func (e *ProcessEngine) DelegateTask(taskID uint, fromUserID uint, toUserID uint, comment string) error {
-	return e.taskRepo.DelegateTask(taskID, fromUserID, toUserID)
+// ReleaseTask 释放任务
+func (e *ProcessEngine) ReleaseTask(ctx context.Context, taskID uint, userID uint) error {
+	return e.taskRepo.ReleaseTask(ctx, taskID, userID)
 }
 
 // GetTaskForm 获取任务表单定义
-func (e *ProcessEngine) GetTaskForm(taskID uint) (interface{}, error) {
-	task, err := e.taskRepo.GetByID(taskID)
+func (e *ProcessEngine) GetTaskForm(ctx context.Context, taskID uint) (interface{}, error) {
+	task, err := e.taskRepo.GetByID(ctx, taskID)
 	if err != nil {
 		return nil, err
 	}
 
 	// 简化处理，直接返回任务信息
-	return map[string]interface{}{
+	form := map[string]interface{}{
 		"task":      task,
-		"form_data": task.Comment,
-	}, nil
+		"form_data": task.FormData,
+	}
+
+	// 节点办理说明
+	if instructions := e.taskInstructions(ctx, task); instructions != nil {
+		form["instructions"] = instructions
+	}
+
+	// 汇总任务展示全部评审结果
+	if reviews := e.consolidationReviews(task); reviews != nil {
+		form["reviews"] = reviews
+	}
+
+	return form, nil
 }
 
 // SaveTaskForm 保存任务表单数据
-func (e *ProcessEngine) SaveTaskForm(taskID uint, userID uint, formData map[string]interface{}) error {
-	task, err := e.taskRepo.GetByID(taskID)
+func (e *ProcessEngine) SaveTaskForm(ctx context.Context, taskID uint, userID uint, formData map[string]interface{}) error {
+	task, err := e.taskRepo.GetByID(ctx, taskID)
 	if err != nil {
 		return err
 	}
 
 	// 验证用户权限
 	if task.AssigneeID == nil || *task.AssigneeID != userID {
-		return errors.New("用户没有权限保存此任务表单")
+		return newEngineError(CodePermissionDenied, nil, "用户没有权限保存此任务表单")
 	}
 
 	// 序列化表单数据
@@ -822,11 +1330,45 @@ func (e *ProcessEngine) SaveTaskForm(taskID uint, userID uint, formData map[stri
 	}
 
 	// 更新任务表单数据
-	task.Comment = string(formDataJSON)
-	return e.taskRepo.Update(task)
+	task.FormData = string(formDataJSON)
+	return e.taskRepo.Update(ctx, task)
 }
 
 // GetTasksByStatus 根据状态获取任务列表
-func (e *ProcessEngine) GetTasksByStatus(status string, offset, limit int) ([]model.TaskInstance, int64, error) {
-	return e.taskRepo.GetTasksByStatus(status, offset, limit)
+func (e *ProcessEngine) GetTasksByStatus(ctx context.Context, status string, offset, limit int) ([]model.TaskInstance, int64, error) {
+	tasks, total, err := e.taskRepo.GetTasksByStatus(ctx, status, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	e.applyTaskListLabels(tasks)
+	redactTaskList(tasks)
+	return tasks, total, nil
+}
+
+// QueryTasks 按任务查询条件获取任务列表
+func (e *ProcessEngine) QueryTasks(ctx context.Context, query *repository.TaskQuery) ([]model.TaskInstance, int64, error) {
+	if err := e.withCandidateRole(ctx, query); err != nil {
+		return nil, 0, err
+	}
+	tasks, total, err := e.taskRepo.Query(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+	e.applyTaskListLabels(tasks)
+	redactTaskList(tasks)
+	return tasks, total, nil
+}
+
+// QueryTasksAfter 按游标查询任务，after 为上一页最后一个任务的创建时间和ID
+func (e *ProcessEngine) QueryTasksAfter(ctx context.Context, query *repository.TaskQuery, after repository.Keyset) ([]model.TaskInstance, error) {
+	if err := e.withCandidateRole(ctx, query); err != nil {
+		return nil, err
+	}
+	tasks, err := e.taskRepo.QueryAfter(ctx, query, after)
+	if err != nil {
+		return nil, err
+	}
+	e.applyTaskListLabels(tasks)
+	redactTaskList(tasks)
+	return tasks, nil
 }
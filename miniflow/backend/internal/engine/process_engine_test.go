@@ -0,0 +1,168 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"miniflow/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// sequenceDefinition start → a → b → end
+func sequenceDefinition() *model.ProcessDefinitionData {
+	return &model.ProcessDefinitionData{
+		Nodes: []model.ProcessNode{
+			{ID: "start", Type: model.NodeTypeStart, Name: "start"},
+			userTaskNode("a"),
+			userTaskNode("b"),
+			{ID: "end", Type: model.NodeTypeEnd, Name: "end"},
+		},
+		Flows: []model.ProcessFlow{flow("start", "a"), flow("a", "b"), flow("b", "end")},
+	}
+}
+
+// cancelOnActivity cancels the context when an activity of the given type is recorded,
+// which happens right after the engine has committed the operation
+func cancelOnActivity(t *testing.T, db *gorm.DB, activityType string, cancel context.CancelFunc) {
+	t.Helper()
+
+	err := db.Callback().Create().Before("gorm:create").Register("test:cancel_on_activity", func(tx *gorm.DB) {
+		if activity, ok := tx.Statement.Dest.(*model.ActivityHistory); ok && activity.Type == activityType {
+			cancel()
+		}
+	})
+	if err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+}
+
+func TestCompleteTaskAdvancesAfterRequestCancelled(t *testing.T) {
+	e, db := newTestEngine(t)
+	user := createTestUser(t, db, "alice", "user")
+	definition := publishTestDefinition(t, db, "sequence", user.ID, sequenceDefinition())
+	instance := startTestProcess(t, e, definition.ID, user.ID, nil)
+
+	task := openTaskAt(t, db, instance.ID, "a")
+	if err := e.ClaimTask(context.Background(), task.ID, user.ID); err != nil {
+		t.Fatalf("claim task: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cancelOnActivity(t, db, model.ActivityTaskCompleted, cancel)
+
+	if err := e.CompleteTask(ctx, task.ID, user.ID, nil, ""); err != nil {
+		t.Fatalf("complete task: %v", err)
+	}
+	if ctx.Err() == nil {
+		t.Fatal("context was not cancelled after the task completed")
+	}
+
+	openTaskAt(t, db, instance.ID, "b")
+}
+
+func TestStartProcessAdvancesAfterRequestCancelled(t *testing.T) {
+	e, db := newTestEngine(t)
+	user := createTestUser(t, db, "alice", "user")
+	definition := publishTestDefinition(t, db, "sequence", user.ID, sequenceDefinition())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cancelOnActivity(t, db, model.ActivityStateTransition, cancel)
+
+	instance, err := e.StartProcess(ctx, &StartProcessRequest{DefinitionID: definition.ID, BusinessKey: "cancelled"}, user.ID)
+	if err != nil {
+		t.Fatalf("start process: %v", err)
+	}
+	if ctx.Err() == nil {
+		t.Fatal("context was not cancelled after the instance was created")
+	}
+
+	openTaskAt(t, db, instance.ID, "a")
+}
+
+// waitForTaskReads holds the first n reads of a task until all of them have happened,
+// so concurrent calls all pass their status checks before any of them writes
+func waitForTaskReads(t *testing.T, db *gorm.DB, n int) {
+	t.Helper()
+
+	var mu sync.Mutex
+	arrived := 0
+	release := make(chan struct{})
+	err := db.Callback().Query().After("gorm:query").Register("test:wait_for_task_reads", func(tx *gorm.DB) {
+		if _, ok := tx.Statement.Dest.(*model.TaskInstance); !ok {
+			return
+		}
+		mu.Lock()
+		arrived++
+		if arrived > n {
+			mu.Unlock()
+			return
+		}
+		if arrived == n {
+			close(release)
+		}
+		mu.Unlock()
+
+		select {
+		case <-release:
+		case <-time.After(5 * time.Second):
+		}
+	})
+	if err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+}
+
+func TestConcurrentCompleteTaskSucceedsOnce(t *testing.T) {
+	e, db := newTestEngine(t)
+	user := createTestUser(t, db, "alice", "user")
+	definition := publishTestDefinition(t, db, "sequence", user.ID, sequenceDefinition())
+	instance := startTestProcess(t, e, definition.ID, user.ID, nil)
+
+	task := openTaskAt(t, db, instance.ID, "a")
+	if err := e.ClaimTask(context.Background(), task.ID, user.ID); err != nil {
+		t.Fatalf("claim task: %v", err)
+	}
+	waitForTaskReads(t, db, 2)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = e.CompleteTask(context.Background(), task.ID, user.ID, nil, "")
+		}(i)
+	}
+	wg.Wait()
+
+	succeeded, duplicates := 0, 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case errors.Is(err, ErrTaskAlreadyCompleted):
+			duplicates++
+		default:
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if succeeded != 1 || duplicates != 1 {
+		t.Fatalf("succeeded %d, already completed %d; want one of each", succeeded, duplicates)
+	}
+
+	// 流程只推进一次
+	openTaskAt(t, db, instance.ID, "b")
+	var activities int64
+	db.Model(&model.ActivityHistory{}).
+		Where("instance_id = ? AND type = ?", instance.ID, model.ActivityTaskCompleted).
+		Count(&activities)
+	if activities != 1 {
+		t.Fatalf("recorded %d task completions, want 1", activities)
+	}
+}
@@ -0,0 +1,72 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"miniflow/internal/model"
+)
+
+// PublicInstanceStatus 对外公开的流程实例状态，只包含进度信息，不包含流程变量和处理人
+type PublicInstanceStatus struct {
+	ProcessName  string     `json:"process_name"`
+	BusinessKey  string     `json:"business_key"`
+	Status       string     `json:"status"`
+	StatusLabel  string     `json:"status_label,omitempty"`
+	CurrentStage []string   `json:"current_stage"`
+	StartedAt    time.Time  `json:"started_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	EndedAt      *time.Time `json:"ended_at,omitempty"`
+}
+
+// GetPublicStatus 获取流程实例的公开状态视图，当前阶段取待办任务所在节点的展示名称
+func (e *ProcessEngine) GetPublicStatus(ctx context.Context, instanceID uint) (*PublicInstanceStatus, error) {
+	instance, err := e.instanceRepo.GetByID(ctx, instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("获取流程实例失败: %w", err)
+	}
+
+	tasks, err := e.taskRepo.GetByInstance(ctx, instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("获取任务列表失败: %w", err)
+	}
+
+	resolver := e.newLabelResolver(&instance.Definition)
+	status := &PublicInstanceStatus{
+		ProcessName:  instance.Definition.Name,
+		BusinessKey:  instance.BusinessKey,
+		Status:       instance.Status,
+		StatusLabel:  resolver.labels.StatusLabel(instance.Status),
+		CurrentStage: []string{},
+		StartedAt:    instance.StartTime,
+		UpdatedAt:    instance.UpdatedAt,
+		EndedAt:      instance.EndTime,
+	}
+
+	seen := make(map[string]bool)
+	for _, task := range tasks {
+		if task.UpdatedAt.After(status.UpdatedAt) {
+			status.UpdatedAt = task.UpdatedAt
+		}
+		if !isOpenTaskStatus(task.Status) {
+			continue
+		}
+		nodeID := task.NodeID
+		if reviewNodeID, ok := model.ParseConsolidationNodeID(nodeID); ok {
+			nodeID = reviewNodeID
+		}
+		if stage := resolver.nodeLabel(nodeID); stage != "" && !seen[stage] {
+			seen[stage] = true
+			status.CurrentStage = append(status.CurrentStage, stage)
+		}
+	}
+
+	if len(status.CurrentStage) == 0 && instance.Status == model.InstanceStatusRunning {
+		if stage := resolver.nodeLabel(instance.CurrentNode); stage != "" {
+			status.CurrentStage = append(status.CurrentStage, stage)
+		}
+	}
+
+	return status, nil
+}
@@ -0,0 +1,255 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// 启动恢复的参数
+const (
+	// recoveryGracePeriod 最近更新的实例可能仍在其他服务实例中推进，不检查
+	recoveryGracePeriod = 2 * time.Minute
+	recoveryBatchSize   = 200
+)
+
+// 启动恢复发现的问题类型
+const (
+	RecoveryKindStaleJob        = "stale_job"
+	RecoveryKindStalledInstance = "stalled_instance"
+)
+
+// 启动恢复采取的动作
+const (
+	// RecoveryActionRequeued 锁已过期的作业重新排队
+	RecoveryActionRequeued = "requeued"
+	// RecoveryActionAdvanced 最近的任务已完成但流程没有推进，继续推进
+	RecoveryActionAdvanced = "advanced"
+	// RecoveryActionReentered 进入节点后没有创建任何任务，重新进入当前节点
+	RecoveryActionReentered = "reentered"
+	// RecoveryActionIncident 无法自动修复或修复失败，生成异常事件等待人工处理
+	RecoveryActionIncident = "incident"
+	// RecoveryActionFailed 修复失败且无法生成异常事件，只记录在报告中
+	RecoveryActionFailed = "failed"
+)
+
+// RecoveryFinding 启动恢复发现的一处不一致及其处理结果
+type RecoveryFinding struct {
+	Kind       string `json:"kind"`
+	InstanceID uint   `json:"instance_id"`
+	NodeID     string `json:"node_id,omitempty"`
+	JobID      uint   `json:"job_id,omitempty"`
+	Action     string `json:"action"`
+	Detail     string `json:"detail,omitempty"`
+}
+
+// RecoveryReport 一次启动恢复的报告
+type RecoveryReport struct {
+	StartedAt        time.Time         `json:"started_at"`
+	FinishedAt       time.Time         `json:"finished_at"`
+	StaleJobs        int               `json:"stale_jobs"`
+	StalledInstances int               `json:"stalled_instances"`
+	Repaired         int               `json:"repaired"`
+	Incidents        int               `json:"incidents"`
+	Errors           []string          `json:"errors,omitempty"`
+	Findings         []RecoveryFinding `json:"findings"`
+}
+
+// add 记录一处发现并更新计数
+func (r *RecoveryReport) add(finding RecoveryFinding) {
+	switch finding.Kind {
+	case RecoveryKindStaleJob:
+		r.StaleJobs++
+	case RecoveryKindStalledInstance:
+		r.StalledInstances++
+	}
+	switch finding.Action {
+	case RecoveryActionRequeued, RecoveryActionAdvanced, RecoveryActionReentered:
+		r.Repaired++
+	case RecoveryActionIncident:
+		r.Incidents++
+	}
+	r.Findings = append(r.Findings, finding)
+}
+
+// Recovery 服务启动时修复推进过程中服务中断留下的不一致
+//
+// 执行中但持有者已失效（锁已过期）的异步作业重新排队；运行中但没有任何待处理工作的实例按最近的任务修复：
+// 任务已完成时继续推进，没有任务时重新进入当前节点，其他情况生成异常事件。最近一次的报告保存在内存中供查询。
+type Recovery struct {
+	engine *ProcessEngine
+	logger *logger.Logger
+
+	mu   sync.Mutex
+	last *RecoveryReport
+}
+
+// NewRecovery 创建启动恢复
+func NewRecovery(engine *ProcessEngine, logger *logger.Logger) *Recovery {
+	return &Recovery{
+		engine: engine,
+		logger: logger,
+	}
+}
+
+// Start 服务启动时运行一次恢复，应在作业执行器和定时器调度器开始轮询之前调用
+func (r *Recovery) Start(ctx context.Context) {
+	if ctx.Err() != nil {
+		return
+	}
+	r.Run(ctx, time.Now())
+}
+
+// Run 扫描并修复不一致，返回恢复报告；同一时间只运行一次
+func (r *Recovery) Run(ctx context.Context, now time.Time) *RecoveryReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := &RecoveryReport{StartedAt: now, Findings: []RecoveryFinding{}}
+
+	requeued, err := r.engine.instanceRepo.RequeueExpiredAsyncJobs(ctx, now)
+	if err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("重新排队过期作业失败: %v", err))
+	}
+	for _, job := range requeued {
+		report.add(RecoveryFinding{
+			Kind:       RecoveryKindStaleJob,
+			InstanceID: job.InstanceID,
+			NodeID:     job.NodeID,
+			JobID:      job.ID,
+			Action:     RecoveryActionRequeued,
+			Detail:     fmt.Sprintf("执行器 %s 的锁已过期", job.LockedBy),
+		})
+	}
+
+	before := now.Add(-recoveryGracePeriod)
+	var afterID uint
+	for {
+		instances, err := r.engine.instanceRepo.GetStalledInstances(ctx, before, afterID, recoveryBatchSize)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("查找停滞实例失败: %v", err))
+			break
+		}
+		for i := range instances {
+			report.add(r.recoverInstance(ctx, &instances[i]))
+			afterID = instances[i].ID
+		}
+		if len(instances) < recoveryBatchSize {
+			break
+		}
+	}
+
+	report.FinishedAt = time.Now()
+	r.last = report
+
+	for _, finding := range report.Findings {
+		r.logger.Warn("Recovery finding",
+			zap.String("kind", finding.Kind),
+			zap.Uint("instance_id", finding.InstanceID),
+			zap.String("node_id", finding.NodeID),
+			zap.Uint("job_id", finding.JobID),
+			zap.String("action", finding.Action),
+			zap.String("detail", finding.Detail),
+		)
+	}
+	r.logger.Info("Recovery finished",
+		zap.Int("stale_jobs", report.StaleJobs),
+		zap.Int("stalled_instances", report.StalledInstances),
+		zap.Int("repaired", report.Repaired),
+		zap.Int("incidents", report.Incidents),
+		zap.Strings("errors", report.Errors),
+		zap.Duration("duration", report.FinishedAt.Sub(report.StartedAt)),
+	)
+	return report
+}
+
+// LastReport 获取最近一次的恢复报告，尚未运行时返回 nil
+func (r *Recovery) LastReport() *RecoveryReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.last
+}
+
+// recoverInstance 按最近的任务修复停滞的实例
+func (r *Recovery) recoverInstance(ctx context.Context, instance *model.ProcessInstance) RecoveryFinding {
+	finding := RecoveryFinding{
+		Kind:       RecoveryKindStalledInstance,
+		InstanceID: instance.ID,
+		NodeID:     instance.CurrentNode,
+	}
+
+	definition, err := instance.Definition.GetDefinitionData()
+	if err != nil {
+		finding.Action = RecoveryActionFailed
+		finding.Detail = fmt.Sprintf("解析流程定义失败: %v", err)
+		return finding
+	}
+	task, err := r.engine.taskRepo.GetLatestTask(ctx, instance.ID)
+	if err != nil {
+		finding.Action = RecoveryActionFailed
+		finding.Detail = fmt.Sprintf("获取任务失败: %v", err)
+		return finding
+	}
+
+	var repairErr error
+	switch {
+	case task == nil:
+		// 进入节点后、创建任务前中断
+		finding.Action = RecoveryActionReentered
+		finding.Detail = "实例没有任何任务，重新进入当前节点"
+		repairErr = r.engine.moveToNextNode(ctx, instance, instance.CurrentNode)
+	case task.Status == model.TaskStatusCompleted:
+		// 任务完成后、流程推进前中断
+		finding.NodeID = task.NodeID
+		finding.Action = RecoveryActionAdvanced
+		finding.Detail = fmt.Sprintf("任务 %d 已完成但流程没有推进，继续推进", task.ID)
+		repairErr = r.engine.checkAndAdvanceProcess(ctx, instance, task.NodeID)
+	default:
+		finding.NodeID = task.NodeID
+		repairErr = newEngineError(CodeRecoveryRequired, nil, "任务 %d 的状态为 %s，实例没有待处理的工作", task.ID, task.Status)
+	}
+	if repairErr == nil {
+		return finding
+	}
+
+	node := r.engine.findNodeByID(definition.Nodes, finding.NodeID)
+	if node == nil {
+		finding.Action = RecoveryActionFailed
+		finding.Detail = repairErr.Error()
+		return finding
+	}
+	var incidentTask *model.TaskInstance
+	if task != nil && task.NodeID == node.ID {
+		incidentTask = task
+	}
+	if err := r.engine.raiseIncident(ctx, instance, incidentTask, node, model.IncidentTypeRecovery, repairErr); err != nil {
+		finding.Action = RecoveryActionFailed
+		finding.Detail = fmt.Sprintf("%v；%v", repairErr, err)
+		return finding
+	}
+	finding.Action = RecoveryActionIncident
+	finding.Detail = repairErr.Error()
+	return finding
+}
+
+// retryRecovery 人工确认后重新进入启动恢复无法修复的节点
+func (e *ProcessEngine) retryRecovery(ctx context.Context, incident *model.Incident, instance *model.ProcessInstance, node *model.ProcessNode, userID uint) (*model.Incident, error) {
+	if node == nil {
+		return nil, newEngineError(CodeNotFound, nil, "异常事件对应的节点不存在")
+	}
+
+	if err := e.markIncidentResolved(ctx, incident, userID); err != nil {
+		return nil, err
+	}
+
+	if err := e.moveToNextNode(ctx, instance, node.ID); err != nil {
+		return nil, fmt.Errorf("重新进入节点失败: %w", err)
+	}
+	return incident, nil
+}
@@ -0,0 +1,149 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// 灰度发布中的版本角色
+const (
+	RolloutRoleCandidate = "candidate"
+	RolloutRoleBaseline  = "baseline"
+)
+
+// RolloutVersionMetrics 灰度发布中单个版本的实例指标
+type RolloutVersionMetrics struct {
+	DefinitionID       uint    `json:"definition_id"`
+	Version            int     `json:"version"`
+	Role               string  `json:"role"`
+	Total              int64   `json:"total"`
+	Running            int64   `json:"running"`
+	Suspended          int64   `json:"suspended"`
+	Completed          int64   `json:"completed"`
+	Failed             int64   `json:"failed"`
+	Cancelled          int64   `json:"cancelled"`
+	CompletionRate     float64 `json:"completion_rate"`
+	FailureRate        float64 `json:"failure_rate"`
+	AvgDurationSeconds float64 `json:"avg_duration_seconds"`
+}
+
+// RolloutReport 灰度发布状态和新旧版本的指标对比
+type RolloutReport struct {
+	Key        string                 `json:"key"`
+	Enabled    bool                   `json:"enabled"`
+	Percentage int                    `json:"percentage"`
+	Condition  string                 `json:"condition"`
+	Candidate  *RolloutVersionMetrics `json:"candidate"`
+	Baseline   *RolloutVersionMetrics `json:"baseline,omitempty"`
+}
+
+// routeRollout 灰度发布路由：流程标识的最新已发布版本处于灰度中时，
+// 启动新旧两个版本之一的请求按灰度条件和比例路由，其余请求（如指定更早的版本）保持不变
+// 满足变量条件的实例进入新版本；否则按业务键分桶，比例内的进入新版本
+func (e *ProcessEngine) routeRollout(ctx context.Context, definition *model.ProcessDefinition, req *StartProcessRequest) *model.ProcessDefinition {
+	candidate, err := e.processRepo.GetLatestPublishedByKey(ctx, definition.Key)
+	if err != nil || !candidate.RolloutEnabled {
+		return definition
+	}
+	baseline, err := e.processRepo.GetPreviousPublished(ctx, definition.Key, candidate.Version)
+	if err != nil {
+		return definition
+	}
+	if definition.ID != candidate.ID && definition.ID != baseline.ID {
+		return definition
+	}
+
+	target := baseline
+	if candidate.RolloutCondition != "" {
+		matched, err := e.variableEngine.EvaluateCondition(candidate.RolloutCondition, req.Variables)
+		if err != nil {
+			e.logger.Warn("Rollout condition evaluation failed, using bucket",
+				zap.String("key", candidate.Key),
+				zap.String("condition", candidate.RolloutCondition),
+				zap.Error(err),
+			)
+		}
+		if matched {
+			target = candidate
+		}
+	}
+	if target == baseline && model.RolloutBucket(req.BusinessKey) < candidate.RolloutPercentage {
+		target = candidate
+	}
+
+	e.logger.Info("Process start routed by rollout",
+		zap.String("key", candidate.Key),
+		zap.String("business_key", req.BusinessKey),
+		zap.Int("version", target.Version),
+	)
+	return target
+}
+
+// GetRolloutReport 获取流程标识的灰度发布状态，并对比新旧版本的实例指标
+func (e *ProcessEngine) GetRolloutReport(ctx context.Context, processID uint) (*RolloutReport, error) {
+	definition, err := e.processRepo.GetByID(ctx, processID)
+	if err != nil {
+		return nil, fmt.Errorf("获取流程定义失败: %w", err)
+	}
+
+	candidate, err := e.processRepo.GetLatestPublishedByKey(ctx, definition.Key)
+	if err != nil {
+		return nil, fmt.Errorf("获取流程定义失败: %w", err)
+	}
+
+	report := &RolloutReport{
+		Key:        candidate.Key,
+		Enabled:    candidate.RolloutEnabled,
+		Percentage: candidate.RolloutPercentage,
+		Condition:  candidate.RolloutCondition,
+		Candidate:  &RolloutVersionMetrics{DefinitionID: candidate.ID, Version: candidate.Version, Role: RolloutRoleCandidate},
+	}
+	versions := map[uint]*RolloutVersionMetrics{candidate.ID: report.Candidate}
+
+	if baseline, err := e.processRepo.GetPreviousPublished(ctx, candidate.Key, candidate.Version); err == nil {
+		report.Baseline = &RolloutVersionMetrics{DefinitionID: baseline.ID, Version: baseline.Version, Role: RolloutRoleBaseline}
+		versions[baseline.ID] = report.Baseline
+	}
+
+	ids := make([]uint, 0, len(versions))
+	for id := range versions {
+		ids = append(ids, id)
+	}
+	counts, err := e.instanceRepo.GetVersionStatusCounts(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("统计版本实例失败: %v", err)
+	}
+
+	for _, row := range counts {
+		metrics := versions[row.DefinitionID]
+		if metrics == nil {
+			continue
+		}
+		metrics.Total += row.Count
+		switch row.Status {
+		case model.InstanceStatusRunning:
+			metrics.Running = row.Count
+		case model.InstanceStatusSuspended:
+			metrics.Suspended = row.Count
+		case model.InstanceStatusCompleted:
+			metrics.Completed = row.Count
+			metrics.AvgDurationSeconds = row.AvgDurationSeconds
+		case model.InstanceStatusFailed:
+			metrics.Failed = row.Count
+		case model.InstanceStatusCancelled:
+			metrics.Cancelled = row.Count
+		}
+	}
+	for _, metrics := range versions {
+		if metrics.Total > 0 {
+			metrics.CompletionRate = float64(metrics.Completed) / float64(metrics.Total)
+			metrics.FailureRate = float64(metrics.Failed) / float64(metrics.Total)
+		}
+	}
+
+	return report, nil
+}
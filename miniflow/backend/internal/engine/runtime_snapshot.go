@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/internal/repository"
+	"miniflow/pkg/config"
+
+	"go.uber.org/zap"
+)
+
+// SnapshotImportResult 运行时快照的导入结果
+type SnapshotImportResult struct {
+	DryRun     bool             `json:"dry_run"`
+	ExportedAt time.Time        `json:"exported_at"`
+	Sequences  map[string]uint  `json:"sequences"`
+	Rows       map[string]int64 `json:"rows"`
+}
+
+// ExportRuntimeSnapshot 导出运行时状态快照，需要 admin 权限
+func (e *ProcessEngine) ExportRuntimeSnapshot(ctx context.Context, userID uint) (*model.RuntimeSnapshot, error) {
+	if err := e.checkPermission(ctx, userID, config.PermissionAdmin, "导出运行时快照"); err != nil {
+		return nil, err
+	}
+
+	snapshot, err := e.instanceRepo.ExportRuntimeSnapshot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("导出运行时快照失败: %v", err)
+	}
+	if snapshot.Digest, err = snapshot.ComputeDigest(); err != nil {
+		return nil, fmt.Errorf("计算快照摘要失败: %v", err)
+	}
+
+	e.logger.Info("Runtime snapshot exported",
+		zap.Uint("user_id", userID),
+		zap.Any("rows", snapshot.RowCounts()),
+		zap.Any("sequences", snapshot.Sequences),
+	)
+	return snapshot, nil
+}
+
+// ImportRuntimeSnapshot 在备用环境导入运行时快照，需要 admin 权限
+//
+// 快照引用的流程定义和用户必须已在当前环境存在。导入后等待中的定时器和发件箱由本环境的
+// 后台任务继续处理，因此只应在主环境停止处理后导入，或先以 dryRun 校验。
+func (e *ProcessEngine) ImportRuntimeSnapshot(ctx context.Context, snapshot *model.RuntimeSnapshot, userID uint, dryRun bool) (*SnapshotImportResult, error) {
+	if err := e.checkPermission(ctx, userID, config.PermissionAdmin, "导入运行时快照"); err != nil {
+		return nil, err
+	}
+
+	if snapshot.Format != model.RuntimeSnapshotFormat {
+		return nil, newEngineError(CodeInvalidSnapshot, nil, "不支持的快照格式 %d，当前格式为 %d", snapshot.Format, model.RuntimeSnapshotFormat)
+	}
+	digest, err := snapshot.ComputeDigest()
+	if err != nil {
+		return nil, newEngineError(CodeInvalidSnapshot, err, "计算快照摘要失败")
+	}
+	if digest != snapshot.Digest {
+		return nil, newEngineError(CodeInvalidSnapshot, nil, "快照摘要校验失败，快照可能被截断或修改")
+	}
+
+	rows, err := e.instanceRepo.ImportRuntimeSnapshot(ctx, snapshot, dryRun)
+	if err != nil {
+		if errors.Is(err, repository.ErrSnapshotReferenceMissing) {
+			return nil, newEngineError(CodeSnapshotReference, err, "快照无法导入")
+		}
+		return nil, fmt.Errorf("导入运行时快照失败: %v", err)
+	}
+
+	e.logger.Info("Runtime snapshot imported",
+		zap.Uint("user_id", userID),
+		zap.Bool("dry_run", dryRun),
+		zap.Time("exported_at", snapshot.ExportedAt),
+		zap.Any("rows", rows),
+	)
+	return &SnapshotImportResult{
+		DryRun:     dryRun,
+		ExportedAt: snapshot.ExportedAt,
+		Sequences:  snapshot.Sequences,
+		Rows:       rows,
+	}, nil
+}
@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/dop251/goja"
+)
+
+// javaScriptRuntime 基于 goja 的 JavaScript 运行时
+// goja 只实现 ECMAScript 标准库，没有 require、文件、网络和进程等宿主能力；每次运行使用新的虚拟机
+type javaScriptRuntime struct{}
+
+// Run 把流程变量设置为全局变量后执行脚本，脚本通过赋值全局变量写回流程变量
+// let 和 const 声明的顶层变量不是全局对象的属性，不会写回；函数类型的全局变量被忽略
+func (javaScriptRuntime) Run(ctx context.Context, script string, variables map[string]interface{}) (map[string]interface{}, error) {
+	vm := goja.New()
+	global := vm.GlobalObject()
+
+	// 变量经 JSON 转换为原生 JavaScript 值，脚本修改对象和数组不会影响调用方的副本
+	parse, ok := goja.AssertFunction(vm.Get("JSON").ToObject(vm).Get("parse"))
+	if !ok {
+		return nil, fmt.Errorf("JSON.parse 不可用")
+	}
+	for name, value := range variables {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("变量 %s 无法传入脚本: %v", name, err)
+		}
+		parsed, err := parse(goja.Undefined(), vm.ToValue(string(data)))
+		if err != nil {
+			return nil, fmt.Errorf("变量 %s 无法传入脚本: %v", name, err)
+		}
+		if err := global.Set(name, parsed); err != nil {
+			return nil, err
+		}
+	}
+
+	stop := context.AfterFunc(ctx, func() { vm.Interrupt(ctx.Err()) })
+	defer stop()
+	if _, err := vm.RunString(script); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	globals := make(map[string]interface{})
+	for _, name := range global.Keys() {
+		value := global.Get(name)
+		if _, isFunction := goja.AssertFunction(value); isFunction {
+			continue
+		}
+		globals[name] = value.Export()
+	}
+	return scriptAssignments(variables, globals)
+}
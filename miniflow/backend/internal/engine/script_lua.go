@@ -0,0 +1,171 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// luaMaxTableDepth 写回流程变量的表的最大嵌套层数，同时用于拒绝循环引用的表
+const luaMaxTableDepth = 64
+
+// luaLibraries 脚本可用的标准库，不加载 io、os、package、debug、channel 和 coroutine
+var luaLibraries = []struct {
+	name string
+	open lua.LGFunction
+}{
+	{lua.BaseLibName, lua.OpenBase},
+	{lua.TabLibName, lua.OpenTable},
+	{lua.StringLibName, lua.OpenString},
+	{lua.MathLibName, lua.OpenMath},
+}
+
+// luaRemovedGlobals base 库中读取文件、加载代码或写标准输出的函数
+var luaRemovedGlobals = []string{"dofile", "loadfile", "load", "loadstring", "require", "module", "print", "_printregs"}
+
+// luaScriptRuntime 基于 gopher-lua 的 Lua 5.1 运行时，每次运行使用新的解释器
+type luaScriptRuntime struct{}
+
+// Run 把流程变量设置为全局变量后执行脚本，脚本通过赋值全局变量写回流程变量
+// local 变量和函数类型的全局变量不会写回；数组中的 null 在 Lua 中无法表示，传入脚本时被丢弃
+func (luaScriptRuntime) Run(ctx context.Context, script string, variables map[string]interface{}) (map[string]interface{}, error) {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	defer L.Close()
+	for _, lib := range luaLibraries {
+		if err := L.CallByParam(lua.P{Fn: L.NewFunction(lib.open), Protect: true}, lua.LString(lib.name)); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range luaRemovedGlobals {
+		L.SetGlobal(name, lua.LNil)
+	}
+	builtins := make(map[string]bool)
+	L.G.Global.ForEach(func(key, _ lua.LValue) {
+		builtins[key.String()] = true
+	})
+
+	// 记录由数组创建的表，脚本没有修改的空数组写回时仍是数组
+	arrays := make(map[*lua.LTable]bool)
+	for name, value := range variables {
+		// 变量先经 JSON 转换，只剩下 JSON 的值类型
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("变量 %s 无法传入脚本: %v", name, err)
+		}
+		var normalized interface{}
+		if err := json.Unmarshal(data, &normalized); err != nil {
+			return nil, fmt.Errorf("变量 %s 无法传入脚本: %v", name, err)
+		}
+		L.SetGlobal(name, toLuaValue(L, normalized, arrays))
+	}
+
+	L.SetContext(ctx)
+	if err := L.DoString(script); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	globals := make(map[string]interface{})
+	var convertErr error
+	L.G.Global.ForEach(func(key, value lua.LValue) {
+		name := key.String()
+		if convertErr != nil || value.Type() == lua.LTFunction {
+			return
+		}
+		if builtins[name] {
+			if _, isVariable := variables[name]; !isVariable {
+				return
+			}
+		}
+		converted, err := fromLuaValue(value, arrays, 0)
+		if err != nil {
+			convertErr = fmt.Errorf("变量 %s 的值无法保存: %v", name, err)
+			return
+		}
+		globals[name] = converted
+	})
+	if convertErr != nil {
+		return nil, convertErr
+	}
+	return scriptAssignments(variables, globals)
+}
+
+// toLuaValue 把 JSON 值转换为 Lua 值
+func toLuaValue(L *lua.LState, value interface{}, arrays map[*lua.LTable]bool) lua.LValue {
+	switch v := value.(type) {
+	case bool:
+		return lua.LBool(v)
+	case float64:
+		return lua.LNumber(v)
+	case string:
+		return lua.LString(v)
+	case []interface{}:
+		table := L.CreateTable(len(v), 0)
+		for _, item := range v {
+			table.Append(toLuaValue(L, item, arrays))
+		}
+		arrays[table] = true
+		return table
+	case map[string]interface{}:
+		table := L.CreateTable(0, len(v))
+		for key, item := range v {
+			table.RawSetString(key, toLuaValue(L, item, arrays))
+		}
+		return table
+	default:
+		return lua.LNil
+	}
+}
+
+// fromLuaValue 把 Lua 值转换为 JSON 值；键为 1..n 的表转换为数组，其他表转换为对象
+func fromLuaValue(value lua.LValue, arrays map[*lua.LTable]bool, depth int) (interface{}, error) {
+	switch v := value.(type) {
+	case *lua.LNilType:
+		return nil, nil
+	case lua.LBool:
+		return bool(v), nil
+	case lua.LNumber:
+		return float64(v), nil
+	case lua.LString:
+		return string(v), nil
+	case *lua.LTable:
+		if depth >= luaMaxTableDepth {
+			return nil, fmt.Errorf("表的嵌套超过 %d 层", luaMaxTableDepth)
+		}
+		size := 0
+		v.ForEach(func(lua.LValue, lua.LValue) { size++ })
+		if size == 0 && arrays[v] || size > 0 && size == v.Len() {
+			list := make([]interface{}, 0, size)
+			for i := 1; i <= size; i++ {
+				item, err := fromLuaValue(v.RawGetInt(i), arrays, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, item)
+			}
+			return list, nil
+		}
+		object := make(map[string]interface{}, size)
+		var err error
+		v.ForEach(func(key, item lua.LValue) {
+			if err != nil {
+				return
+			}
+			if key.Type() != lua.LTString && key.Type() != lua.LTNumber {
+				err = fmt.Errorf("不支持 %s 类型的键", key.Type())
+				return
+			}
+			object[key.String()], err = fromLuaValue(item, arrays, depth+1)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return object, nil
+	default:
+		return nil, fmt.Errorf("不支持 %s 类型的值", value.Type())
+	}
+}
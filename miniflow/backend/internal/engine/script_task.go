@@ -0,0 +1,160 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/expression"
+
+	"go.uber.org/zap"
+)
+
+// ScriptRuntime 脚本任务的语言运行时
+//
+// Run 收到流程变量的副本，返回脚本写入的变量，只有返回的变量会写回流程实例。运行时必须
+// 在沙箱中执行脚本：不提供文件、网络、进程、环境变量和模块加载等内置能力，并在 ctx 结束时
+// 尽快中止脚本。
+type ScriptRuntime interface {
+	Run(ctx context.Context, script string, variables map[string]interface{}) (map[string]interface{}, error)
+}
+
+// expressionScriptRuntime 内置的赋值脚本运行时，表达式语言本身没有任何副作用
+type expressionScriptRuntime struct{}
+
+// Run 逐行执行赋值语句
+func (expressionScriptRuntime) Run(ctx context.Context, script string, variables map[string]interface{}) (map[string]interface{}, error) {
+	parsed, err := expression.ParseScript(script)
+	if err != nil {
+		return nil, err
+	}
+	return parsed.Run(ctx, variables)
+}
+
+// scriptAssignments 对比脚本运行前后的全局变量，返回脚本新增或修改的变量
+// 变量值经过 JSON 转换，与流程变量的存储格式一致；无法保存为 JSON 的值（如 NaN）视为脚本错误
+func scriptAssignments(variables, globals map[string]interface{}) (map[string]interface{}, error) {
+	assigned := make(map[string]interface{})
+	for name, value := range globals {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("变量 %s 的值无法保存: %v", name, err)
+		}
+		if original, ok := variables[name]; ok {
+			if before, err := json.Marshal(original); err == nil && bytes.Equal(before, data) {
+				continue
+			}
+		}
+		var normalized interface{}
+		if err := json.Unmarshal(data, &normalized); err != nil {
+			return nil, fmt.Errorf("变量 %s 的值无法保存: %v", name, err)
+		}
+		assigned[name] = normalized
+	}
+	return assigned, nil
+}
+
+// ExecuteScript 在超时限制内执行脚本任务，返回脚本写入的变量；ctx 的截止时间早于超时限制时以 ctx 为准
+func (e *ServiceExecutor) ExecuteScript(ctx context.Context, task *model.TaskInstance, cfg *model.ScriptTaskConfig, variables map[string]interface{}) (map[string]interface{}, error) {
+	runtime, ok := e.scripts[cfg.Language]
+	if !ok {
+		return nil, newEngineError(CodeScriptFailed, nil, "脚本语言 %s 没有可用的运行时", cfg.Language)
+	}
+
+	timeout := e.scriptCfg.GetTimeout(cfg.TimeoutSeconds)
+	e.logger.Info("Executing script task",
+		zap.Uint("task_id", task.ID),
+		zap.String("language", cfg.Language),
+		zap.Duration("timeout", timeout),
+	)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type outcome struct {
+		assigned map[string]interface{}
+		err      error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- outcome{err: fmt.Errorf("脚本运行时异常: %v", r)}
+			}
+		}()
+		assigned, err := runtime.Run(ctx, cfg.Script, variables)
+		done <- outcome{assigned: assigned, err: err}
+	}()
+
+	// 运行时没有及时响应 ctx 时不再等待，脚本的结果被丢弃
+	select {
+	case result := <-done:
+		if errors.Is(result.err, context.DeadlineExceeded) {
+			return nil, newEngineError(CodeScriptTimeout, nil, "脚本执行超过 %s", timeout)
+		}
+		if result.err != nil {
+			return nil, newEngineError(CodeScriptFailed, result.err, "脚本执行失败")
+		}
+		return result.assigned, nil
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return nil, newEngineError(CodeScriptFailed, ctx.Err(), "脚本执行被取消")
+		}
+		return nil, newEngineError(CodeScriptTimeout, nil, "脚本执行超过 %s", timeout)
+	}
+}
+
+// handleScriptTask 处理脚本任务节点，执行失败时生成异常事件并停留在当前节点，可通过重试恢复
+func (e *ProcessEngine) handleScriptTask(ctx context.Context, instance *model.ProcessInstance, node *model.ProcessNode) error {
+	task := &model.TaskInstance{
+		InstanceID: instance.ID,
+		NodeID:     node.ID,
+		Name:       node.Name,
+		Status:     model.TaskStatusCreated,
+		Priority:   50, // 默认优先级
+	}
+	if err := e.taskRepo.Create(ctx, task); err != nil {
+		return fmt.Errorf("创建脚本任务失败: %v", err)
+	}
+	e.traceFor(instance).record(ctx, model.TraceCategoryWrite, node.ID, map[string]interface{}{"task_id": task.ID}, "创建脚本任务 %d", task.ID)
+
+	if err := e.executeScriptTask(ctx, instance, task, node); err != nil {
+		e.logger.Error("Script task execution failed", zap.Error(err))
+		return e.failServiceTask(ctx, instance, task, node, model.IncidentTypeScriptFailed, err)
+	}
+
+	return e.completeServiceTask(ctx, instance, task, node)
+}
+
+// executeScriptTask 执行脚本并把脚本写入的变量合并到流程变量
+func (e *ProcessEngine) executeScriptTask(ctx context.Context, instance *model.ProcessInstance, task *model.TaskInstance, node *model.ProcessNode) error {
+	cfg, err := model.GetScriptTaskConfig(node)
+	if err != nil {
+		return newEngineError(CodeScriptFailed, err, "节点 %s 的脚本配置无效", node.ID)
+	}
+
+	variables, err := decodeInstanceVariables(instance)
+	if err != nil {
+		return err
+	}
+	assigned, err := e.serviceExecutor.ExecuteScript(ctx, task, cfg, variables)
+	if err != nil {
+		return err
+	}
+	e.traceFor(instance).record(ctx, model.TraceCategoryNode, node.ID, map[string]interface{}{
+		"task_id":  task.ID,
+		"language": cfg.Language,
+		"assigned": assigned,
+	}, "脚本任务 %d 写入 %d 个变量", task.ID, len(assigned))
+	if len(assigned) == 0 {
+		return nil
+	}
+
+	for key, value := range assigned {
+		variables[key] = value
+	}
+	return e.saveInstanceVariables(ctx, instance, variables)
+}
@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/config"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// scriptVariables 脚本测试使用的流程变量，形式与 decodeVariables 的结果一致
+func scriptVariables() map[string]interface{} {
+	return map[string]interface{}{
+		"amount": float64(120),
+		"owner":  map[string]interface{}{"name": "alice"},
+		"items":  []interface{}{float64(1), float64(2)},
+		"empty":  []interface{}{},
+	}
+}
+
+func TestScriptRuntimesReadAndWriteVariables(t *testing.T) {
+	tests := []struct {
+		language string
+		script   string
+	}{
+		{model.ScriptLanguageJavaScript, `
+			function double(n) { return n * 2; }
+			let scratch = 1;
+			total = double(amount);
+			owner.name = "bob";
+			approved = items.length === 2 && empty.length === 0;`},
+		{model.ScriptLanguageLua, `
+			function double(n) return n * 2 end
+			local scratch = 1
+			total = double(amount)
+			owner.name = "bob"
+			approved = #items == 2 and #empty == 0`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.language, func(t *testing.T) {
+			executor := NewServiceExecutor(nil, &config.ScriptConfig{TimeoutSeconds: 5, MaxTimeoutSeconds: 5}, &logger.Logger{Logger: zap.NewNop()})
+			variables := scriptVariables()
+			cfg := &model.ScriptTaskConfig{Language: tt.language, Script: tt.script}
+			assigned, err := executor.ExecuteScript(context.Background(), &model.TaskInstance{}, cfg, variables)
+			if err != nil {
+				t.Fatalf("run script: %v", err)
+			}
+
+			// 只返回新增或修改的变量，局部变量、函数和未修改的变量不写回
+			want := map[string]interface{}{
+				"total":    float64(240),
+				"owner":    map[string]interface{}{"name": "bob"},
+				"approved": true,
+			}
+			if !reflect.DeepEqual(assigned, want) {
+				t.Fatalf("assigned %#v, want %#v", assigned, want)
+			}
+			if !reflect.DeepEqual(variables, scriptVariables()) {
+				t.Fatalf("script modified the caller's variables: %#v", variables)
+			}
+		})
+	}
+}
+
+func TestScriptRuntimesSandbox(t *testing.T) {
+	tests := []struct {
+		language string
+		script   string
+	}{
+		{model.ScriptLanguageJavaScript, `require("fs")`},
+		{model.ScriptLanguageJavaScript, `process.env`},
+		{model.ScriptLanguageJavaScript, `console.log("x")`},
+		{model.ScriptLanguageLua, `io.open("/etc/passwd")`},
+		{model.ScriptLanguageLua, `os.execute("id")`},
+		{model.ScriptLanguageLua, `os.getenv("HOME")`},
+		{model.ScriptLanguageLua, `dofile("/etc/passwd")`},
+		{model.ScriptLanguageLua, `loadfile("/etc/passwd")`},
+		{model.ScriptLanguageLua, `loadstring("return 1")`},
+		{model.ScriptLanguageLua, `require("os")`},
+		{model.ScriptLanguageLua, `package.loadlib("libc.so.6", "system")`},
+		{model.ScriptLanguageLua, `debug.getregistry()`},
+		{model.ScriptLanguageLua, `print("x")`},
+	}
+	runtimes := map[string]ScriptRuntime{
+		model.ScriptLanguageJavaScript: javaScriptRuntime{},
+		model.ScriptLanguageLua:        luaScriptRuntime{},
+	}
+	for _, tt := range tests {
+		t.Run(tt.language+"/"+tt.script, func(t *testing.T) {
+			if _, err := runtimes[tt.language].Run(context.Background(), tt.script, map[string]interface{}{}); err == nil {
+				t.Fatalf("sandboxed script %q ran", tt.script)
+			}
+		})
+	}
+}
+
+func TestScriptRuntimesStopAtDeadline(t *testing.T) {
+	tests := []struct {
+		language string
+		runtime  ScriptRuntime
+		script   string
+	}{
+		{model.ScriptLanguageJavaScript, javaScriptRuntime{}, `while (true) {}`},
+		{model.ScriptLanguageJavaScript, javaScriptRuntime{}, `while (true) { try { for (;;) {} } catch (e) {} }`},
+		{model.ScriptLanguageLua, luaScriptRuntime{}, `while true do end`},
+		{model.ScriptLanguageLua, luaScriptRuntime{}, `while true do pcall(function() while true do end end) end`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.language+"/"+tt.script, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			// 运行时自身必须中止脚本，而不只是由 ExecuteScript 放弃等待
+			started := time.Now()
+			_, err := tt.runtime.Run(ctx, tt.script, map[string]interface{}{})
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("run: %v, want context.DeadlineExceeded", err)
+			}
+			if elapsed := time.Since(started); elapsed > time.Second {
+				t.Fatalf("script stopped after %s", elapsed)
+			}
+		})
+	}
+}
+
+func TestExecuteScriptTimeout(t *testing.T) {
+	executor := NewServiceExecutor(nil, &config.ScriptConfig{TimeoutSeconds: 1, MaxTimeoutSeconds: 1}, &logger.Logger{Logger: zap.NewNop()})
+	for _, cfg := range []*model.ScriptTaskConfig{
+		{Language: model.ScriptLanguageJavaScript, Script: `for (;;) {}`},
+		{Language: model.ScriptLanguageLua, Script: `repeat until false`},
+	} {
+		t.Run(cfg.Language, func(t *testing.T) {
+			_, err := executor.ExecuteScript(context.Background(), &model.TaskInstance{}, cfg, map[string]interface{}{})
+			var engineErr *EngineError
+			if !errors.As(err, &engineErr) || engineErr.Code != CodeScriptTimeout {
+				t.Fatalf("run: %v, want %s", err, CodeScriptTimeout)
+			}
+		})
+	}
+}
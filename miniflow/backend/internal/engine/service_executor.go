@@ -1,40 +1,178 @@
 package engine
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
 	"time"
 
 	"miniflow/internal/model"
+	"miniflow/pkg/config"
 	"miniflow/pkg/database"
 	"miniflow/pkg/logger"
 
 	"go.uber.org/zap"
 )
 
-// ServiceExecutor 服务任务执行器
+// serviceTaskTimeout 服务任务单次调用的超时时间
+const serviceTaskTimeout = 10 * time.Second
+
+// ServiceTaskPayload 服务任务调用的请求体，不允许导出的数据分级不携带流程变量
+type ServiceTaskPayload struct {
+	InstanceID  uint                   `json:"instance_id"`
+	BusinessKey string                 `json:"business_key"`
+	TaskID      uint                   `json:"task_id"`
+	NodeID      string                 `json:"node_id"`
+	Variables   map[string]interface{} `json:"variables"`
+}
+
+// ServiceRequest 服务任务的调用请求，URL 为空时是不调用外部系统的空操作
+type ServiceRequest struct {
+	Connector string
+	Topic     string
+	Method    string
+	URL       string
+	Body      []byte
+}
+
+// ServiceResult 服务任务的调用结果
+type ServiceResult struct {
+	StatusCode int
+	Body       string
+	Mocked     bool
+}
+
+// ServiceExecutor 服务任务执行器，同时负责执行脚本任务
+// 脚本运行时在创建时确定，之后只读，并发执行脚本无需加锁
 type ServiceExecutor struct {
-	db     *database.Database
-	logger *logger.Logger
+	db        *database.Database
+	client    *http.Client
+	scriptCfg *config.ScriptConfig
+	scripts   map[string]ScriptRuntime
+	logger    *logger.Logger
 }
 
-// NewServiceExecutor 创建服务任务执行器
-func NewServiceExecutor(db *database.Database, logger *logger.Logger) *ServiceExecutor {
+// NewServiceExecutor 创建服务任务执行器，内置 expression、javascript 和 lua 脚本运行时
+func NewServiceExecutor(db *database.Database, scriptCfg *config.ScriptConfig, logger *logger.Logger) *ServiceExecutor {
 	return &ServiceExecutor{
-		db:     db,
+		db:        db,
+		client:    &http.Client{Timeout: serviceTaskTimeout},
+		scriptCfg: scriptCfg,
+		scripts: map[string]ScriptRuntime{
+			model.ScriptLanguageExpression: expressionScriptRuntime{},
+			model.ScriptLanguageJavaScript: javaScriptRuntime{},
+			model.ScriptLanguageLua:        luaScriptRuntime{},
+		},
 		logger: logger,
 	}
 }
 
-// ExecuteService 执行服务任务
-func (e *ServiceExecutor) ExecuteService(task *model.TaskInstance) error {
-	e.logger.Info("Executing service task", zap.Uint("task_id", task.ID))
+// BuildServiceRequest 根据节点属性构建服务任务请求
+// 节点属性 url 为调用地址，method 为请求方法（默认 POST），topic 为可选的业务主题
+func BuildServiceRequest(instance *model.ProcessInstance, task *model.TaskInstance, node *model.ProcessNode) (*ServiceRequest, error) {
+	connector := model.GetServiceConnector(node)
+	req := &ServiceRequest{Connector: connector.Type}
+	if raw, ok := node.Props["topic"].(string); ok {
+		req.Topic = strings.ToLower(strings.TrimSpace(raw))
+	}
 
-	// 简单的占位符实现
-	// 实际使用时可以根据需要扩展
-	time.Sleep(100 * time.Millisecond) // 模拟执行时间
+	if raw, ok := node.Props["url"].(string); ok {
+		req.URL = strings.TrimSpace(raw)
+	}
+	if req.URL == "" {
+		return req, nil
+	}
 
-	e.logger.Info("Service task completed successfully",
+	req.Method = http.MethodPost
+	if raw, ok := node.Props["method"].(string); ok && raw != "" {
+		req.Method = strings.ToUpper(strings.TrimSpace(raw))
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodDelete:
+		return req, nil
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		return nil, newEngineError(CodeServiceRequestInvalid, nil, "不支持的请求方法: %s", req.Method)
+	}
+
+	variables := make(map[string]interface{})
+	if instance.Definition.DataPolicy().AllowExport {
+		decoded, err := decodeInstanceVariables(instance)
+		if err != nil {
+			return nil, newEngineError(CodeServiceRequestInvalid, err, "构建请求数据失败")
+		}
+		variables = decoded
+	}
+
+	body, err := json.Marshal(&ServiceTaskPayload{
+		InstanceID:  instance.ID,
+		BusinessKey: instance.BusinessKey,
+		TaskID:      task.ID,
+		NodeID:      node.ID,
+		Variables:   variables,
+	})
+	if err != nil {
+		return nil, newEngineError(CodeServiceRequestInvalid, err, "序列化请求数据失败")
+	}
+	req.Body = body
+	return req, nil
+}
+
+// ExecuteService 执行服务任务，返回外部系统的响应；非 2xx 响应视为失败
+// 调用在 ctx 取消或到达截止时间时中止，单次调用仍受 serviceTaskTimeout 限制
+func (e *ServiceExecutor) ExecuteService(ctx context.Context, task *model.TaskInstance, req *ServiceRequest) (*ServiceResult, error) {
+	e.logger.Info("Executing service task",
 		zap.Uint("task_id", task.ID),
+		zap.String("connector", req.Connector),
+		zap.String("url", req.URL),
 	)
 
-	return nil
+	// 未配置调用地址的节点不调用外部系统
+	if req.URL == "" {
+		return &ServiceResult{}, nil
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return nil, newEngineError(CodeServiceRequestInvalid, err, "创建请求失败")
+	}
+	if len(req.Body) > 0 {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	httpReq.Header.Set("User-Agent", "MiniFlow-ServiceTask/1.0")
+
+	resp, err := e.client.Do(httpReq)
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return nil, newEngineError(CodeServiceTimeout, err, "调用服务超时")
+		}
+		if errors.Is(err, context.Canceled) {
+			return nil, newEngineError(CodeServiceUnavailable, err, "调用服务被取消")
+		}
+		return nil, newEngineError(CodeServiceUnavailable, err, "调用服务失败")
+	}
+	defer resp.Body.Close()
+
+	// 只保留响应开头用于排查问题
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, model.MaxExecutionResponseLength+1))
+	result := &ServiceResult{
+		StatusCode: resp.StatusCode,
+		Body:       model.TruncateExecutionContent(string(snippet), model.MaxExecutionResponseLength),
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return result, newEngineError(CodeServiceErrorResponse, nil, "服务返回状态码 %d", resp.StatusCode)
+	}
+
+	e.logger.Info("Service task completed successfully",
+		zap.Uint("task_id", task.ID),
+		zap.Int("status_code", resp.StatusCode),
+	)
+	return result, nil
 }
@@ -1,14 +1,30 @@
 package engine
 
 import (
-	"fmt"
-
 	"miniflow/internal/model"
 	"miniflow/pkg/logger"
 
 	"go.uber.org/zap"
 )
 
+// allowedTransitions 允许的流程实例状态转换，已取消的实例在撤销期限内可以恢复到取消前的状态
+var allowedTransitions = map[string][]string{
+	model.InstanceStatusRunning: {
+		model.InstanceStatusCompleted,
+		model.InstanceStatusSuspended,
+		model.InstanceStatusCancelled,
+	},
+	model.InstanceStatusSuspended: {
+		model.InstanceStatusRunning,
+		model.InstanceStatusCancelled,
+	},
+	model.InstanceStatusCancelled: {
+		model.InstanceStatusRunning,
+		model.InstanceStatusSuspended,
+	},
+	model.InstanceStatusCompleted: {},
+}
+
 // ProcessStateMachine 流程实例状态机
 type ProcessStateMachine struct {
 	logger *logger.Logger
@@ -25,7 +41,7 @@ func NewProcessStateMachine(engine *ProcessEngine, logger *logger.Logger) *Proce
 func (sm *ProcessStateMachine) TransitionTo(instance *model.ProcessInstance, newStatus string, reason string) error {
 	// 验证状态转换是否有效
 	if !sm.CanTransition(instance.Status, newStatus) {
-		return fmt.Errorf("无效的状态转换: %s -> %s", instance.Status, newStatus)
+		return newEngineError(CodeInvalidStateTransition, nil, "无效的状态转换: %s -> %s", instance.Status, newStatus)
 	}
 
 	instance.Status = newStatus
@@ -49,14 +65,6 @@ func (sm *ProcessStateMachine) TransitionTo(instance *model.ProcessInstance, new
 
 // CanTransition 检查是否可以进行状态转换
 func (sm *ProcessStateMachine) CanTransition(from, to string) bool {
-	// 定义允许的状态转换
-	allowedTransitions := map[string][]string{
-		model.InstanceStatusRunning: {
-			model.InstanceStatusCompleted,
-		},
-		model.InstanceStatusCompleted: {},
-	}
-
 	// 允许转换到自身状态
 	if from == to {
 		return true
@@ -76,13 +84,6 @@ func (sm *ProcessStateMachine) CanTransition(from, to string) bool {
 
 // GetAvailableTransitions 获取可用的状态转换
 func (sm *ProcessStateMachine) GetAvailableTransitions(currentStatus string) []string {
-	allowedTransitions := map[string][]string{
-		model.InstanceStatusRunning: {
-			model.InstanceStatusCompleted,
-		},
-		model.InstanceStatusCompleted: {},
-	}
-
 	if transitions, exists := allowedTransitions[currentStatus]; exists {
 		return transitions
 	}
@@ -120,7 +121,7 @@ func (sm *ProcessStateMachine) handleCompletedState(instance *model.ProcessInsta
 // ValidateTransition 验证状态转换
 func (sm *ProcessStateMachine) ValidateTransition(from, to string) error {
 	if !sm.CanTransition(from, to) {
-		return fmt.Errorf("无效的状态转换: %s -> %s", from, to)
+		return newEngineError(CodeInvalidStateTransition, nil, "无效的状态转换: %s -> %s", from, to)
 	}
 	return nil
 }
@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -32,11 +33,11 @@ func NewTaskAssignmentManager(
 }
 
 // AssignTask 分配任务
-func (m *TaskAssignmentManager) AssignTask(task *model.TaskInstance) error {
+func (m *TaskAssignmentManager) AssignTask(ctx context.Context, task *model.TaskInstance) error {
 	// 获取可分配的用户
-	availableUsers, err := m.getAvailableUsers(task)
+	availableUsers, err := m.getAvailableUsers(ctx, task)
 	if err != nil {
-		return fmt.Errorf("获取可分配用户失败: %v", err)
+		return fmt.Errorf("获取可分配用户失败: %w", err)
 	}
 
 	if len(availableUsers) == 0 {
@@ -48,7 +49,7 @@ func (m *TaskAssignmentManager) AssignTask(task *model.TaskInstance) error {
 	task.AssigneeID = &selectedUser.ID
 	task.Status = model.TaskStatusAssigned
 
-	if err := m.taskRepo.Update(task); err != nil {
+	if err := m.taskRepo.Update(ctx, task); err != nil {
 		return fmt.Errorf("更新任务分配失败: %v", err)
 	}
 
@@ -61,9 +62,9 @@ func (m *TaskAssignmentManager) AssignTask(task *model.TaskInstance) error {
 }
 
 // getAvailableUsers 获取可分配的用户
-func (m *TaskAssignmentManager) getAvailableUsers(task *model.TaskInstance) ([]*model.User, error) {
+func (m *TaskAssignmentManager) getAvailableUsers(ctx context.Context, task *model.TaskInstance) ([]*model.User, error) {
 	// 获取所有活跃用户
-	users, err := m.userRepo.GetActiveUsers()
+	users, err := m.userRepo.GetActiveUsers(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"miniflow/internal/model"
+	"miniflow/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+// offerToCandidates 按节点的候选人配置限制任务池中可以认领任务的用户
+// 候选用户不存在或未激活时跳过该用户，候选人全部无效时生成异常事件，任务仍留在任务池
+func (e *ProcessEngine) offerToCandidates(ctx context.Context, instance *model.ProcessInstance, node *model.ProcessNode, task *model.TaskInstance) error {
+	config, err := model.GetCandidateConfig(node)
+	if err != nil {
+		return newEngineError(CodeInvalidDefinition, err, "节点 %s 的候选人配置无效", node.ID)
+	}
+	if config == nil {
+		return nil
+	}
+
+	userIDs := make([]uint, 0, len(config.Users))
+	for i := range config.Users {
+		// 用户组展开为组内所有活跃成员
+		if group := config.Users[i].Group; group != "" {
+			members, err := e.userRepo.GetUsersByGroup(ctx, group)
+			if err != nil {
+				return fmt.Errorf("获取用户组成员失败: %v", err)
+			}
+			for _, member := range members {
+				userIDs = append(userIDs, member.ID)
+			}
+			continue
+		}
+		userID, err := e.resolveAssigneeSpec(ctx, &config.Users[i])
+		if err != nil {
+			e.logger.Warn("Skipping unavailable task candidate",
+				zap.Uint("task_id", task.ID),
+				zap.String("node_id", node.ID),
+				zap.Error(err),
+			)
+			continue
+		}
+		userIDs = append(userIDs, userID)
+	}
+	if len(config.Roles) == 0 && len(userIDs) == 0 {
+		return e.raiseIncident(ctx, instance, task, node, model.IncidentTypeAssignmentFailed,
+			fmt.Errorf("节点 %s 的候选用户都不可用", node.ID))
+	}
+
+	task.SetCandidates(config.Roles, userIDs)
+	if err := e.taskRepo.Update(ctx, task); err != nil {
+		return fmt.Errorf("更新任务候选人失败: %v", err)
+	}
+
+	e.logger.Info("Task offered to candidates",
+		zap.Uint("task_id", task.ID),
+		zap.Strings("roles", config.Roles),
+		zap.Int("users", len(userIDs)),
+	)
+	return nil
+}
+
+// checkCandidate 校验用户可以认领任务池中的任务，已分配的任务由认领条件校验处理人
+func (e *ProcessEngine) checkCandidate(ctx context.Context, task *model.TaskInstance, userID uint) error {
+	if task.AssigneeID != nil || !task.HasCandidates() {
+		return nil
+	}
+	user, err := e.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("获取用户失败: %w", err)
+	}
+	if !task.IsCandidate(user) {
+		return newEngineError(CodePermissionDenied, nil, "用户不是任务 %d 的候选人", task.ID)
+	}
+	return nil
+}
+
+// candidateRole 获取用户的角色，用于查询任务池中候选角色匹配的任务
+func (e *ProcessEngine) candidateRole(ctx context.Context, userID uint) (string, error) {
+	user, err := e.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("获取用户失败: %w", err)
+	}
+	return user.Role, nil
+}
+
+// withCandidateRole 为按候选人查询的任务查询条件补充用户角色
+func (e *ProcessEngine) withCandidateRole(ctx context.Context, query *repository.TaskQuery) error {
+	if query.CandidateID == nil || query.CandidateRole != "" {
+		return nil
+	}
+	role, err := e.candidateRole(ctx, *query.CandidateID)
+	if err != nil {
+		return err
+	}
+	query.CandidateRole = role
+	return nil
+}
@@ -0,0 +1,179 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"miniflow/internal/model"
+)
+
+// taskChangePollInterval 长轮询期间检查事件日志的间隔
+const taskChangePollInterval = time.Second
+
+// taskStreamPollInterval 推送流在没有收到本进程任务事件时检查事件日志的间隔，
+// 用于发现其他服务节点产生的变更
+const taskStreamPollInterval = 5 * time.Second
+
+// taskStreamEventTypes 唤醒推送流的引擎事件
+var taskStreamEventTypes = []string{
+	EventTaskCreated, EventTaskAssigned, EventTaskClaimed, EventTaskCompleted, EventTaskSkipped, EventTaskOverdue,
+}
+
+// taskChangeSignal 在引擎发布任务事件时唤醒所有等待中的推送流
+type taskChangeSignal struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+// newTaskChangeSignal 创建任务变更信号
+func newTaskChangeSignal() *taskChangeSignal {
+	return &taskChangeSignal{ch: make(chan struct{})}
+}
+
+// wait 返回下一次通知时关闭的通道
+func (s *taskChangeSignal) wait() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ch
+}
+
+// notify 唤醒当前所有等待者
+func (s *taskChangeSignal) notify() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	close(s.ch)
+	s.ch = make(chan struct{})
+}
+
+// watchTaskEvents 订阅任务事件，有任务变更时唤醒推送流
+func (e *ProcessEngine) watchTaskEvents() {
+	if e.events == nil {
+		return
+	}
+	for _, eventType := range taskStreamEventTypes {
+		e.events.Subscribe(eventType, func(Event) {
+			e.taskSignal.notify()
+		})
+	}
+}
+
+// TaskChanges 任务增量变更，Cursor 为下一次轮询的游标
+type TaskChanges struct {
+	Changes     []model.TaskEvent `json:"changes"`
+	Cursor      uint              `json:"cursor"`
+	ActiveCount int               `json:"active_count"`
+}
+
+// WaitForTaskChanges 长轮询用户的任务变更：有新事件立即返回，否则等待直到超时或请求取消
+// cursor 为 0 时表示首次同步，直接返回最新游标和当前待办数量
+func (e *ProcessEngine) WaitForTaskChanges(ctx context.Context, userID uint, cursor uint, limit int, timeout time.Duration) (*TaskChanges, error) {
+	if cursor == 0 {
+		latest, err := e.taskRepo.GetLatestTaskEventID(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("获取任务变更游标失败: %w", err)
+		}
+		return e.buildTaskChanges(ctx, userID, nil, latest)
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(taskChangePollInterval)
+	defer ticker.Stop()
+
+	for {
+		events, err := e.taskRepo.GetUserTaskEventsAfter(ctx, userID, cursor, limit)
+		if err != nil {
+			return nil, fmt.Errorf("获取任务变更失败: %w", err)
+		}
+		if len(events) > 0 {
+			return e.buildTaskChanges(ctx, userID, events, events[len(events)-1].ID)
+		}
+
+		select {
+		case <-ctx.Done():
+			return e.buildTaskChanges(ctx, userID, nil, cursor)
+		case <-deadline.C:
+			return e.buildTaskChanges(ctx, userID, nil, cursor)
+		case <-ticker.C:
+		}
+	}
+}
+
+// StreamTaskChanges 持续推送用户的任务变更，直到请求取消或 emit 返回错误
+// 先推送一次当前游标和待办数量（cursor 为 0 时从最新事件开始），之后每有新事件推送一批变更。
+// 本进程发布任务事件时立即检查事件日志，其他节点的变更按 taskStreamPollInterval 轮询发现
+func (e *ProcessEngine) StreamTaskChanges(ctx context.Context, userID uint, cursor uint, limit int, emit func(*TaskChanges) error) error {
+	if cursor == 0 {
+		latest, err := e.taskRepo.GetLatestTaskEventID(ctx)
+		if err != nil {
+			return fmt.Errorf("获取任务变更游标失败: %w", err)
+		}
+		cursor = latest
+	}
+
+	changes, err := e.buildTaskChanges(ctx, userID, nil, cursor)
+	if err != nil {
+		return err
+	}
+	if err := emit(changes); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(taskStreamPollInterval)
+	defer ticker.Stop()
+
+	for {
+		// 先取得信号再查询，避免查询之后、等待之前发布的事件被错过
+		signal := e.taskSignal.wait()
+
+		events, err := e.taskRepo.GetUserTaskEventsAfter(ctx, userID, cursor, limit)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("获取任务变更失败: %w", err)
+		}
+		if len(events) > 0 {
+			cursor = events[len(events)-1].ID
+			changes, err := e.buildTaskChanges(ctx, userID, events, cursor)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+			if err := emit(changes); err != nil {
+				return err
+			}
+			// 一批没有取完时继续读取，不等待
+			if len(events) == limit {
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-signal:
+		case <-ticker.C:
+		}
+	}
+}
+
+// buildTaskChanges 组装增量变更结果，附带用户当前的待办数量用于角标展示
+func (e *ProcessEngine) buildTaskChanges(ctx context.Context, userID uint, events []model.TaskEvent, cursor uint) (*TaskChanges, error) {
+	count, err := e.taskRepo.CountUserActiveTasks(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("统计待办任务失败: %v", err)
+	}
+	if events == nil {
+		events = []model.TaskEvent{}
+	}
+	return &TaskChanges{
+		Changes:     events,
+		Cursor:      cursor,
+		ActiveCount: count,
+	}, nil
+}
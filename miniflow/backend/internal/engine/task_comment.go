@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"miniflow/internal/model"
+	"miniflow/internal/repository"
+	"miniflow/pkg/config"
+)
+
+// ErrTaskCommentNotFound 任务评论不存在
+var ErrTaskCommentNotFound = &EngineError{Code: CodeNotFound, Message: "任务评论不存在"}
+
+// GetTaskComments 获取任务的讨论记录，按发表顺序排列
+func (e *ProcessEngine) GetTaskComments(ctx context.Context, taskID uint) ([]model.TaskComment, error) {
+	if _, err := e.taskRepo.GetByID(ctx, taskID); err != nil {
+		return nil, err
+	}
+	comments, err := e.taskRepo.GetComments(ctx, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("获取任务评论失败: %w", err)
+	}
+	return comments, nil
+}
+
+// AddTaskComment 在任务下发表评论，已结束的任务仍可以补充评论
+func (e *ProcessEngine) AddTaskComment(ctx context.Context, taskID, userID uint, body string) (*model.TaskComment, error) {
+	task, err := e.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	return e.addTaskComment(ctx, task, userID, body)
+}
+
+// addTaskComment 保存任务评论，内容去掉首尾空白后不能为空
+func (e *ProcessEngine) addTaskComment(ctx context.Context, task *model.TaskInstance, userID uint, body string) (*model.TaskComment, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, newEngineError(CodeInvalidRequest, nil, "评论内容不能为空")
+	}
+
+	comment := &model.TaskComment{
+		TaskID:     task.ID,
+		InstanceID: task.InstanceID,
+		UserID:     userID,
+		Body:       body,
+	}
+	if err := e.taskRepo.CreateComment(ctx, comment); err != nil {
+		return nil, fmt.Errorf("保存任务评论失败: %w", err)
+	}
+	return comment, nil
+}
+
+// UpdateTaskComment 修改评论内容，只有评论作者可以修改
+func (e *ProcessEngine) UpdateTaskComment(ctx context.Context, taskID, commentID, userID uint, body string) (*model.TaskComment, error) {
+	comment, err := e.getTaskComment(ctx, taskID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.UserID != userID {
+		return nil, newEngineError(CodePermissionDenied, nil, "只有评论作者可以修改评论")
+	}
+
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, newEngineError(CodeInvalidRequest, nil, "评论内容不能为空")
+	}
+	comment.Body = body
+	if err := e.taskRepo.UpdateComment(ctx, comment); err != nil {
+		return nil, fmt.Errorf("修改任务评论失败: %w", err)
+	}
+	return comment, nil
+}
+
+// DeleteTaskComment 删除评论，评论作者和有 content_moderate 权限的用户可以删除
+func (e *ProcessEngine) DeleteTaskComment(ctx context.Context, taskID, commentID, userID uint) error {
+	comment, err := e.getTaskComment(ctx, taskID, commentID)
+	if err != nil {
+		return err
+	}
+	if comment.UserID != userID {
+		if err := e.checkPermission(ctx, userID, config.PermissionContentModerate, "删除他人的评论"); err != nil {
+			return err
+		}
+	}
+
+	if err := e.taskRepo.DeleteComment(ctx, comment.ID); err != nil {
+		return fmt.Errorf("删除任务评论失败: %w", err)
+	}
+	return nil
+}
+
+// getTaskComment 获取任务下的评论
+func (e *ProcessEngine) getTaskComment(ctx context.Context, taskID, commentID uint) (*model.TaskComment, error) {
+	comment, err := e.taskRepo.GetComment(ctx, taskID, commentID)
+	if err != nil {
+		if errors.Is(err, repository.ErrTaskCommentNotFound) {
+			return nil, ErrTaskCommentNotFound
+		}
+		return nil, fmt.Errorf("获取任务评论失败: %w", err)
+	}
+	return comment, nil
+}
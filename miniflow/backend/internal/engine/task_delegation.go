@@ -0,0 +1,117 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// DelegateTask 委派任务：处理人把任务交给受托人处理，受托人处理完成后归还给任务所有人，由所有人完成任务
+//
+// 首次委派时处理人成为任务所有人；受托人可以继续委派，所有人保持不变。每次委派都记录活动历史，
+// 委派链可以从实例历史中按 task_delegated 和 task_resolved 查看。
+func (e *ProcessEngine) DelegateTask(ctx context.Context, taskID uint, fromUserID uint, toUserID uint, comment string) error {
+	if toUserID == fromUserID {
+		return newEngineError(CodeInvalidRequest, nil, "不能把任务委派给自己")
+	}
+	delegate, err := e.userRepo.GetByID(ctx, toUserID)
+	if err != nil {
+		return newEngineError(CodeNotFound, err, "受托人 %d 不存在", toUserID)
+	}
+	if delegate.Status != "active" {
+		return newEngineError(CodeInvalidRequest, nil, "受托人 %d 未激活", toUserID)
+	}
+
+	if err := e.taskRepo.DelegateTask(ctx, taskID, fromUserID, toUserID); err != nil {
+		return err
+	}
+
+	task, err := e.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return fmt.Errorf("获取任务失败: %w", err)
+	}
+	detail := map[string]interface{}{
+		"task_name": task.Name,
+		"from":      fromUserID,
+		"to":        toUserID,
+		"comment":   comment,
+	}
+	if task.OwnerID != nil {
+		detail["owner"] = *task.OwnerID
+	}
+	e.recordActivity(ctx, &model.ActivityHistory{
+		InstanceID: task.InstanceID,
+		Type:       model.ActivityTaskDelegated,
+		NodeID:     task.NodeID,
+		NodeType:   model.NodeTypeUserTask,
+		TaskID:     &task.ID,
+	}, fromUserID, detail)
+	e.events.Publish(taskAssignedEvent(task))
+
+	e.logger.Info("Task delegated",
+		zap.Uint("task_id", taskID),
+		zap.Uint("from_user_id", fromUserID),
+		zap.Uint("to_user_id", toUserID),
+	)
+	return nil
+}
+
+// ResolveTask 受托人处理完成后把委派中的任务归还给所有人，formData 不为空时保存为任务表单数据供所有人查看
+func (e *ProcessEngine) ResolveTask(ctx context.Context, taskID uint, userID uint, formData map[string]interface{}, comment string) error {
+	task, err := e.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return fmt.Errorf("获取任务失败: %w", err)
+	}
+	if task.DelegationState != model.DelegationStatePending || task.OwnerID == nil {
+		return newEngineError(CodeInvalidStateTransition, nil, "任务不在委派中，无需归还")
+	}
+	if task.AssigneeID == nil || *task.AssigneeID != userID {
+		return newEngineError(CodePermissionDenied, nil, "只有受托人可以归还任务")
+	}
+
+	if formData != nil {
+		formDataJSON, err := json.Marshal(formData)
+		if err != nil {
+			return newEngineError(CodeInvalidRequest, err, "表单数据格式错误")
+		}
+		task.FormData = string(formDataJSON)
+		if err := e.taskRepo.Update(ctx, task); err != nil {
+			return fmt.Errorf("保存任务表单失败: %v", err)
+		}
+	}
+
+	resolved, err := e.taskRepo.ResolveTask(ctx, taskID, userID)
+	if err != nil {
+		return fmt.Errorf("归还任务失败: %v", err)
+	}
+	if !resolved {
+		return newEngineError(CodeInvalidStateTransition, nil, "任务状态已变化，不允许归还")
+	}
+
+	owner := *task.OwnerID
+	task.AssigneeID = &owner
+	e.recordActivity(ctx, &model.ActivityHistory{
+		InstanceID: task.InstanceID,
+		Type:       model.ActivityTaskResolved,
+		NodeID:     task.NodeID,
+		NodeType:   model.NodeTypeUserTask,
+		TaskID:     &task.ID,
+	}, userID, map[string]interface{}{
+		"task_name": task.Name,
+		"from":      userID,
+		"to":        owner,
+		"comment":   comment,
+	})
+	e.events.Publish(taskAssignedEvent(task))
+
+	e.logger.Info("Task resolved to owner",
+		zap.Uint("task_id", taskID),
+		zap.Uint("delegate_id", userID),
+		zap.Uint("owner_id", owner),
+	)
+	return nil
+}
@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/config"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// EscalateOverdueTasks 升级所有超过截止时间的任务，返回升级的数量
+//
+// 每个任务在每个截止时间之后只升级一次：升级次数加一，group 不为空时把任务放回任务池，只有组内活跃成员可以认领，
+// 并通知组内成员；否则 role 不为空时转交给该角色中待办最少的活跃用户，然后发布 task.overdue 事件。
+// 暂停实例上的任务等恢复后再升级；用户组或角色中没有可用用户时只标记升级。
+func (e *ProcessEngine) EscalateOverdueTasks(ctx context.Context, now time.Time, role, group string) (int, error) {
+	tasks, err := e.taskRepo.GetOverdueTasks(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("获取超期任务失败: %w", err)
+	}
+
+	var members []uint
+	if group != "" && len(tasks) > 0 {
+		users, err := e.userRepo.GetUsersByGroup(ctx, group)
+		if err != nil {
+			return 0, fmt.Errorf("获取用户组成员失败: %v", err)
+		}
+		for _, user := range users {
+			members = append(members, user.ID)
+		}
+		if len(members) == 0 {
+			e.logger.Warn("No escalation group member available", zap.String("group", group))
+		}
+	}
+
+	escalated := 0
+	for i := range tasks {
+		task := &tasks[i]
+		if task.Instance.Status != model.InstanceStatusRunning {
+			continue
+		}
+
+		data := map[string]interface{}{
+			"escalation_level": task.EscalationLevel + 1,
+			"due_date":         task.DueDate,
+		}
+		if task.AssigneeID != nil {
+			data["previous_assignee_id"] = *task.AssigneeID
+		}
+
+		var ok bool
+		var assigneeID *uint
+		if len(members) > 0 {
+			ok, err = e.taskRepo.EscalateTaskToGroup(ctx, task.ID, task.EscalationLevel, group, members, now)
+			data["group"] = group
+			data["candidate_ids"] = members
+		} else {
+			if role != "" {
+				userID, err := e.selectRoleUser(ctx, role)
+				if err != nil {
+					e.logger.Warn("No escalation assignee available",
+						zap.Uint("task_id", task.ID),
+						zap.String("role", role),
+						zap.Error(err),
+					)
+				} else if task.AssigneeID == nil || *task.AssigneeID != userID {
+					assigneeID = &userID
+					data["assignee_id"] = userID
+				}
+			}
+			ok, err = e.taskRepo.EscalateTask(ctx, task.ID, task.EscalationLevel, assigneeID, now)
+		}
+		if err != nil {
+			return escalated, fmt.Errorf("升级超期任务失败: %v", err)
+		}
+		if !ok {
+			continue
+		}
+		escalated++
+
+		e.logger.Info("Overdue task escalated",
+			zap.Uint("task_id", task.ID),
+			zap.Uint("instance_id", task.InstanceID),
+			zap.String("node_id", task.NodeID),
+			zap.Int("escalation_level", task.EscalationLevel+1),
+			zap.Duration("overdue", now.Sub(*task.DueDate)),
+		)
+		e.recordActivity(ctx, &model.ActivityHistory{
+			InstanceID: task.InstanceID,
+			Type:       model.ActivityTaskEscalated,
+			NodeID:     task.NodeID,
+			NodeType:   model.NodeTypeUserTask,
+			TaskID:     &task.ID,
+		}, 0, data)
+		e.publishTaskEvent(EventTaskOverdue, task, 0, data)
+		if assigneeID != nil {
+			task.AssigneeID = assigneeID
+			e.events.Publish(taskAssignedEvent(task))
+		}
+	}
+
+	return escalated, nil
+}
+
+// OverdueScheduler 后台超期任务调度器，定期升级超过截止时间的任务
+type OverdueScheduler struct {
+	engine *ProcessEngine
+	cfg    *config.EscalationConfig
+	logger *logger.Logger
+}
+
+// NewOverdueScheduler 创建超期任务调度器
+func NewOverdueScheduler(engine *ProcessEngine, cfg *config.EscalationConfig, logger *logger.Logger) *OverdueScheduler {
+	return &OverdueScheduler{
+		engine: engine,
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+// Start 按配置的间隔运行超期任务检查循环直到 ctx 取消
+func (s *OverdueScheduler) Start(ctx context.Context) {
+	interval := s.cfg.GetInterval()
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := s.engine.EscalateOverdueTasks(ctx, now, s.cfg.Role, s.cfg.Group); err != nil {
+				s.logger.Error("Failed to escalate overdue tasks", zap.Error(err))
+			}
+		}
+	}
+}
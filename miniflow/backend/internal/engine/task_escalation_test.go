@@ -0,0 +1,193 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// createTestGroup creates a user group with the given members
+func createTestGroup(t *testing.T, db *gorm.DB, code string, members ...*model.User) {
+	t.Helper()
+
+	group := &model.Group{Code: code, Name: code}
+	if err := db.Create(group).Error; err != nil {
+		t.Fatalf("create group %s: %v", code, err)
+	}
+	for _, member := range members {
+		if err := db.Create(&model.GroupMember{GroupID: group.ID, UserID: member.ID}).Error; err != nil {
+			t.Fatalf("add %s to group %s: %v", member.Username, code, err)
+		}
+	}
+}
+
+// taskActivities returns the activities of the given type recorded for the task
+func taskActivities(t *testing.T, db *gorm.DB, taskID uint, activityType string) []model.ActivityHistory {
+	t.Helper()
+
+	var activities []model.ActivityHistory
+	if err := db.Where("task_id = ? AND type = ?", taskID, activityType).Order("id").Find(&activities).Error; err != nil {
+		t.Fatalf("list activities: %v", err)
+	}
+	return activities
+}
+
+func TestEscalateOverdueTaskToGroup(t *testing.T) {
+	e, db := newTestEngine(t)
+	alice := createTestUser(t, db, "alice", "user")
+	bob := createTestUser(t, db, "bob", "user")
+	carol := createTestUser(t, db, "carol", "user")
+	dave := createTestUser(t, db, "dave", "user")
+	createTestGroup(t, db, "ops", bob, carol)
+
+	definition := publishTestDefinition(t, db, "sequence", alice.ID, sequenceDefinition())
+	instance := startTestProcess(t, e, definition.ID, alice.ID, nil)
+	task := openTaskAt(t, db, instance.ID, "a")
+	if err := e.ClaimTask(context.Background(), task.ID, alice.ID); err != nil {
+		t.Fatalf("claim task: %v", err)
+	}
+	now := time.Now()
+	if err := db.Model(&model.TaskInstance{}).Where("id = ?", task.ID).
+		Update("due_date", now.Add(-time.Hour)).Error; err != nil {
+		t.Fatalf("set due date: %v", err)
+	}
+
+	escalated, err := e.EscalateOverdueTasks(context.Background(), now, "admin", "ops")
+	if err != nil || escalated != 1 {
+		t.Fatalf("escalate: escalated %d, err %v", escalated, err)
+	}
+	// 同一截止时间只升级一次
+	if escalated, err := e.EscalateOverdueTasks(context.Background(), now, "admin", "ops"); err != nil || escalated != 0 {
+		t.Fatalf("escalate again: escalated %d, err %v", escalated, err)
+	}
+
+	task = openTaskAt(t, db, instance.ID, "a")
+	if task.AssigneeID != nil || task.Status != model.TaskStatusCreated {
+		t.Fatalf("escalated task assignee %v status %s, want back in the pool", task.AssigneeID, task.Status)
+	}
+	if task.EscalationGroup != "ops" || task.EscalationLevel != 1 {
+		t.Fatalf("escalation group %q level %d, want ops 1", task.EscalationGroup, task.EscalationLevel)
+	}
+	for _, user := range []*model.User{alice, dave} {
+		if !task.HasCandidates() || task.IsCandidate(user) {
+			t.Fatalf("%s may claim a task escalated to a group they are not in", user.Username)
+		}
+	}
+	if len(taskActivities(t, db, task.ID, model.ActivityTaskEscalated)) != 1 {
+		t.Fatal("escalation was not recorded")
+	}
+
+	// 不在组内的用户不能认领，组内成员竞争认领时只有一个成功
+	if err := e.ClaimTask(context.Background(), task.ID, dave.ID); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("claim by non-member: %v", err)
+	}
+	waitForTaskReads(t, db, 2)
+	members := []*model.User{bob, carol}
+	errs := make([]error, len(members))
+	var wg sync.WaitGroup
+	for i, member := range members {
+		wg.Add(1)
+		go func(i int, userID uint) {
+			defer wg.Done()
+			errs[i] = e.ClaimTask(context.Background(), task.ID, userID)
+		}(i, member.ID)
+	}
+	wg.Wait()
+
+	var winner *model.User
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			if winner != nil {
+				t.Fatal("both group members claimed the task")
+			}
+			winner = members[i]
+		case !errors.Is(err, repository.ErrTaskNotClaimable):
+			t.Fatalf("claim by %s: %v", members[i].Username, err)
+		}
+	}
+	if winner == nil {
+		t.Fatal("no group member claimed the task")
+	}
+
+	claims := taskActivities(t, db, task.ID, model.ActivityTaskClaimed)
+	if len(claims) != 1 || claims[0].ActorID == nil || *claims[0].ActorID != winner.ID {
+		t.Fatalf("claim activities %+v, want one by %s", claims, winner.Username)
+	}
+	var detail map[string]interface{}
+	if err := json.Unmarshal([]byte(claims[0].Detail), &detail); err != nil || detail["group"] != "ops" {
+		t.Fatalf("claim detail %s, want group ops", claims[0].Detail)
+	}
+
+	if err := e.CompleteTask(context.Background(), task.ID, winner.ID, nil, ""); err != nil {
+		t.Fatalf("complete task: %v", err)
+	}
+	openTaskAt(t, db, instance.ID, "b")
+}
+
+// escalateBeforeClaim escalates the overdue tasks to the group when the first claim
+// update is about to run, i.e. after ClaimTask has checked the candidates
+func escalateBeforeClaim(t *testing.T, e *ProcessEngine, db *gorm.DB, now time.Time, group string) {
+	t.Helper()
+
+	var fired atomic.Bool
+	err := db.Callback().Update().Before("gorm:begin_transaction").Register("test:escalate_before_claim", func(tx *gorm.DB) {
+		values, ok := tx.Statement.Dest.(map[string]interface{})
+		if !ok || values["status"] != model.TaskStatusClaimed || !fired.CompareAndSwap(false, true) {
+			return
+		}
+		if _, err := e.EscalateOverdueTasks(context.Background(), now, "", group); err != nil {
+			t.Errorf("escalate: %v", err)
+		}
+	})
+	if err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+}
+
+func TestClaimFailsWhenTaskIsEscalatedAfterCandidateCheck(t *testing.T) {
+	e, db := newTestEngine(t)
+	alice := createTestUser(t, db, "alice", "user")
+	bob := createTestUser(t, db, "bob", "user")
+	createTestGroup(t, db, "ops", bob)
+
+	definition := publishTestDefinition(t, db, "sequence", alice.ID, sequenceDefinition())
+	instance := startTestProcess(t, e, definition.ID, alice.ID, nil)
+	task := openTaskAt(t, db, instance.ID, "a")
+	now := time.Now()
+	// 任务在任务池中，对所有用户开放认领，并且已经超期
+	if err := db.Model(&model.TaskInstance{}).Where("id = ?", task.ID).Updates(map[string]interface{}{
+		"assignee_id": nil,
+		"status":      model.TaskStatusCreated,
+		"due_date":    now.Add(-time.Hour),
+	}).Error; err != nil {
+		t.Fatalf("put task in the pool: %v", err)
+	}
+
+	// alice 通过候选人检查后、认领更新前，任务升级到只有 bob 的用户组
+	escalateBeforeClaim(t, e, db, now, "ops")
+	if err := e.ClaimTask(context.Background(), task.ID, alice.ID); !errors.Is(err, repository.ErrTaskNotClaimable) {
+		t.Fatalf("claim after escalation: %v, want ErrTaskNotClaimable", err)
+	}
+
+	task = openTaskAt(t, db, instance.ID, "a")
+	if task.AssigneeID != nil || task.EscalationGroup != "ops" {
+		t.Fatalf("task assignee %v group %q, want unassigned in the ops pool", task.AssigneeID, task.EscalationGroup)
+	}
+	if err := e.ClaimTask(context.Background(), task.ID, bob.ID); err != nil {
+		t.Fatalf("claim by group member: %v", err)
+	}
+	claims := taskActivities(t, db, task.ID, model.ActivityTaskClaimed)
+	if len(claims) != 1 || *claims[0].ActorID != bob.ID {
+		t.Fatalf("claim activities %+v, want one by bob", claims)
+	}
+}
@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/internal/repository"
+)
+
+// ErrSavedTaskFilterNotFound 保存的任务筛选器不存在或属于其他用户
+var ErrSavedTaskFilterNotFound = &EngineError{Code: CodeNotFound, Message: "任务筛选器不存在"}
+
+// SavedTaskFilterRequest 保存或修改任务筛选器的请求
+type SavedTaskFilterRequest struct {
+	Name     string                   `json:"name" validate:"required,max=100"`
+	Criteria model.TaskFilterCriteria `json:"criteria"`
+}
+
+// SavedTaskFilterView 任务筛选器的返回数据
+type SavedTaskFilterView struct {
+	ID        uint                     `json:"id"`
+	Name      string                   `json:"name"`
+	Criteria  model.TaskFilterCriteria `json:"criteria"`
+	CreatedAt time.Time                `json:"created_at"`
+	UpdatedAt time.Time                `json:"updated_at"`
+}
+
+// GetSavedTaskFilters 获取用户保存的任务筛选器
+func (e *ProcessEngine) GetSavedTaskFilters(ctx context.Context, userID uint) ([]*SavedTaskFilterView, error) {
+	filters, err := e.taskRepo.GetSavedFilters(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取任务筛选器失败: %w", err)
+	}
+	views := make([]*SavedTaskFilterView, len(filters))
+	for i := range filters {
+		views[i] = toSavedTaskFilterView(&filters[i])
+	}
+	return views, nil
+}
+
+// GetSavedTaskFilterCriteria 获取用户保存的任务筛选器的查询条件
+func (e *ProcessEngine) GetSavedTaskFilterCriteria(ctx context.Context, userID, filterID uint) (model.TaskFilterCriteria, error) {
+	filter, err := e.getSavedTaskFilter(ctx, userID, filterID)
+	if err != nil {
+		return model.TaskFilterCriteria{}, err
+	}
+	return filter.GetCriteria(), nil
+}
+
+// CreateSavedTaskFilter 保存任务筛选器，每个用户最多保存 model.MaxSavedTaskFilters 个
+func (e *ProcessEngine) CreateSavedTaskFilter(ctx context.Context, userID uint, req *SavedTaskFilterRequest) (*SavedTaskFilterView, error) {
+	name, err := e.validateSavedTaskFilter(ctx, userID, 0, req)
+	if err != nil {
+		return nil, err
+	}
+	count, err := e.taskRepo.CountSavedFilters(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("统计任务筛选器失败: %w", err)
+	}
+	if count >= model.MaxSavedTaskFilters {
+		return nil, newEngineError(CodeInvalidRequest, nil, "最多保存 %d 个任务筛选器", model.MaxSavedTaskFilters)
+	}
+
+	filter := &model.SavedTaskFilter{UserID: userID, Name: name}
+	if err := filter.SetCriteria(req.Criteria); err != nil {
+		return nil, newEngineError(CodeInvalidRequest, err, "查询条件格式错误")
+	}
+	if err := e.taskRepo.CreateSavedFilter(ctx, filter); err != nil {
+		return nil, fmt.Errorf("保存任务筛选器失败: %w", err)
+	}
+	return toSavedTaskFilterView(filter), nil
+}
+
+// UpdateSavedTaskFilter 修改任务筛选器的名称和查询条件
+func (e *ProcessEngine) UpdateSavedTaskFilter(ctx context.Context, userID, filterID uint, req *SavedTaskFilterRequest) (*SavedTaskFilterView, error) {
+	filter, err := e.getSavedTaskFilter(ctx, userID, filterID)
+	if err != nil {
+		return nil, err
+	}
+	name, err := e.validateSavedTaskFilter(ctx, userID, filter.ID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	filter.Name = name
+	if err := filter.SetCriteria(req.Criteria); err != nil {
+		return nil, newEngineError(CodeInvalidRequest, err, "查询条件格式错误")
+	}
+	if err := e.taskRepo.UpdateSavedFilter(ctx, filter); err != nil {
+		return nil, fmt.Errorf("修改任务筛选器失败: %w", err)
+	}
+	return toSavedTaskFilterView(filter), nil
+}
+
+// DeleteSavedTaskFilter 删除任务筛选器
+func (e *ProcessEngine) DeleteSavedTaskFilter(ctx context.Context, userID, filterID uint) error {
+	filter, err := e.getSavedTaskFilter(ctx, userID, filterID)
+	if err != nil {
+		return err
+	}
+	if err := e.taskRepo.DeleteSavedFilter(ctx, filter.ID); err != nil {
+		return fmt.Errorf("删除任务筛选器失败: %w", err)
+	}
+	return nil
+}
+
+// validateSavedTaskFilter 校验筛选器名称和查询条件，返回去掉首尾空白的名称
+func (e *ProcessEngine) validateSavedTaskFilter(ctx context.Context, userID, filterID uint, req *SavedTaskFilterRequest) (string, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return "", newEngineError(CodeInvalidRequest, nil, "筛选器名称不能为空")
+	}
+	if err := req.Criteria.Validate(); err != nil {
+		return "", newEngineError(CodeInvalidRequest, err, "查询条件无效")
+	}
+	exists, err := e.taskRepo.SavedFilterNameExists(ctx, userID, name, filterID)
+	if err != nil {
+		return "", fmt.Errorf("检查任务筛选器名称失败: %w", err)
+	}
+	if exists {
+		return "", newEngineError(CodeInvalidRequest, nil, "已存在名为 %s 的任务筛选器", name)
+	}
+	return name, nil
+}
+
+// getSavedTaskFilter 获取用户的任务筛选器
+func (e *ProcessEngine) getSavedTaskFilter(ctx context.Context, userID, filterID uint) (*model.SavedTaskFilter, error) {
+	filter, err := e.taskRepo.GetSavedFilter(ctx, userID, filterID)
+	if err != nil {
+		if errors.Is(err, repository.ErrSavedTaskFilterNotFound) {
+			return nil, ErrSavedTaskFilterNotFound
+		}
+		return nil, fmt.Errorf("获取任务筛选器失败: %w", err)
+	}
+	return filter, nil
+}
+
+// toSavedTaskFilterView 转换为包含解析后查询条件的返回数据
+func toSavedTaskFilterView(filter *model.SavedTaskFilter) *SavedTaskFilterView {
+	return &SavedTaskFilterView{
+		ID:        filter.ID,
+		Name:      filter.Name,
+		Criteria:  filter.GetCriteria(),
+		CreatedAt: filter.CreatedAt,
+		UpdatedAt: filter.UpdatedAt,
+	}
+}
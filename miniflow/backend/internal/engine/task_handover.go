@@ -0,0 +1,175 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// TaskHandover 任务交接包，汇总新处理人接手任务所需的上下文
+type TaskHandover struct {
+	Task       *model.TaskInstance    `json:"task"`
+	Instance   HandoverInstance       `json:"instance"`
+	PriorTasks []HandoverTaskOutcome  `json:"prior_tasks"`
+	Variables  map[string]interface{} `json:"variables"`
+}
+
+// HandoverInstance 交接包中的流程实例摘要
+type HandoverInstance struct {
+	ID                uint       `json:"id"`
+	BusinessKey       string     `json:"business_key"`
+	DefinitionID      uint       `json:"definition_id"`
+	DefinitionName    string     `json:"definition_name"`
+	DefinitionVersion int        `json:"definition_version"`
+	Status            string     `json:"status"`
+	CurrentNode       string     `json:"current_node"`
+	StarterID         uint       `json:"starter_id"`
+	StarterName       string     `json:"starter_name"`
+	StartTime         time.Time  `json:"start_time"`
+	EndTime           *time.Time `json:"end_time"`
+}
+
+// HandoverTaskOutcome 之前任务的处理结果
+type HandoverTaskOutcome struct {
+	TaskID       uint       `json:"task_id"`
+	NodeID       string     `json:"node_id"`
+	Name         string     `json:"name"`
+	Status       string     `json:"status"`
+	AssigneeID   *uint      `json:"assignee_id"`
+	AssigneeName string     `json:"assignee_name"`
+	Comment      string     `json:"comment"`
+	CompleteTime *time.Time `json:"complete_time"`
+}
+
+// GetTaskHandover 生成任务交接包
+// 节点属性 handoverVariables 可以声明需要交接的变量列表，未声明时返回全部流程变量
+func (e *ProcessEngine) GetTaskHandover(ctx context.Context, taskID uint) (*TaskHandover, error) {
+	task, err := e.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("获取任务失败: %w", err)
+	}
+
+	instance, err := e.instanceRepo.GetByID(ctx, task.InstanceID)
+	if err != nil {
+		return nil, fmt.Errorf("获取流程实例失败: %w", err)
+	}
+
+	tasks, err := e.taskRepo.GetByInstance(ctx, instance.ID)
+	if err != nil {
+		return nil, fmt.Errorf("获取流程任务失败: %w", err)
+	}
+
+	e.applyTaskLabels(task)
+
+	handover := &TaskHandover{
+		Task:       task,
+		Instance:   e.buildHandoverInstance(instance),
+		PriorTasks: make([]HandoverTaskOutcome, 0, len(tasks)),
+		Variables:  make(map[string]interface{}),
+	}
+
+	// 汇总之前已结束的任务结果
+	for _, t := range tasks {
+		if t.ID == task.ID || t.CompleteTime == nil {
+			continue
+		}
+		outcome := HandoverTaskOutcome{
+			TaskID:       t.ID,
+			NodeID:       t.NodeID,
+			Name:         t.Name,
+			Status:       t.Status,
+			AssigneeID:   t.AssigneeID,
+			Comment:      t.Comment,
+			CompleteTime: t.CompleteTime,
+		}
+		if t.Assignee != nil {
+			outcome.AssigneeName = displayName(t.Assignee)
+		}
+		handover.PriorTasks = append(handover.PriorTasks, outcome)
+	}
+
+	// 筛选相关流程变量
+	variables := make(map[string]interface{})
+	if instance.Variables != "" {
+		if err := json.Unmarshal([]byte(instance.Variables), &variables); err != nil {
+			return nil, fmt.Errorf("解析流程变量失败: %v", err)
+		}
+	}
+	keys := e.handoverVariableKeys(instance, task.NodeID)
+	if keys == nil {
+		handover.Variables = variables
+	} else {
+		for _, key := range keys {
+			if value, ok := variables[key]; ok {
+				handover.Variables[key] = value
+			}
+		}
+	}
+
+	e.logger.Info("Task handover generated",
+		zap.Uint("task_id", taskID),
+		zap.Uint("instance_id", instance.ID),
+		zap.Int("prior_tasks", len(handover.PriorTasks)),
+	)
+
+	return handover, nil
+}
+
+// buildHandoverInstance 构建流程实例摘要
+func (e *ProcessEngine) buildHandoverInstance(instance *model.ProcessInstance) HandoverInstance {
+	return HandoverInstance{
+		ID:                instance.ID,
+		BusinessKey:       instance.BusinessKey,
+		DefinitionID:      instance.DefinitionID,
+		DefinitionName:    instance.Definition.Name,
+		DefinitionVersion: instance.Definition.Version,
+		Status:            instance.Status,
+		CurrentNode:       instance.CurrentNode,
+		StarterID:         instance.StarterID,
+		StarterName:       displayName(&instance.Starter),
+		StartTime:         instance.StartTime,
+		EndTime:           instance.EndTime,
+	}
+}
+
+// handoverVariableKeys 读取节点声明的交接变量，未声明时返回nil
+func (e *ProcessEngine) handoverVariableKeys(instance *model.ProcessInstance, nodeID string) []string {
+	definitionData, err := instance.Definition.GetDefinitionData()
+	if err != nil {
+		return nil
+	}
+
+	node := e.findNodeByID(definitionData.Nodes, nodeID)
+	if node == nil {
+		return nil
+	}
+
+	rawKeys, ok := node.Props["handoverVariables"].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	keys := make([]string, 0, len(rawKeys))
+	for _, raw := range rawKeys {
+		if key, ok := raw.(string); ok && key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// displayName 获取用户显示名称
+func displayName(user *model.User) string {
+	if user == nil {
+		return ""
+	}
+	if user.DisplayName != "" {
+		return user.DisplayName
+	}
+	return user.Username
+}
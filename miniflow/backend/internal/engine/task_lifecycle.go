@@ -1,8 +1,8 @@
 package engine
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"time"
 
@@ -16,22 +16,25 @@ import (
 // TaskLifecycleManager 任务生命周期管理器
 type TaskLifecycleManager struct {
 	taskRepo *repository.TaskRepository
+	events   *EventSystem
 	logger   *logger.Logger
 }
 
 // NewTaskLifecycleManager 创建任务生命周期管理器
 func NewTaskLifecycleManager(
 	taskRepo *repository.TaskRepository,
+	events *EventSystem,
 	logger *logger.Logger,
 ) *TaskLifecycleManager {
 	return &TaskLifecycleManager{
 		taskRepo: taskRepo,
+		events:   events,
 		logger:   logger,
 	}
 }
 
 // CreateTask 创建任务
-func (m *TaskLifecycleManager) CreateTask(instance *model.ProcessInstance, nodeID string) (*model.TaskInstance, error) {
+func (m *TaskLifecycleManager) CreateTask(ctx context.Context, instance *model.ProcessInstance, nodeID string) (*model.TaskInstance, error) {
 	m.logger.Info("Creating task",
 		zap.Uint("instance_id", instance.ID),
 		zap.String("node_id", nodeID),
@@ -46,8 +49,19 @@ func (m *TaskLifecycleManager) CreateTask(instance *model.ProcessInstance, nodeI
 		Priority:   50, // 默认优先级
 	}
 
+	// 按流程截止时间和剩余关键路径分配任务截止时间
+	if instance.DueDate != nil {
+		if definitionData, err := instance.Definition.GetDefinitionData(); err == nil {
+			pathNodeID := nodeID
+			if reviewNodeID, ok := model.ParseConsolidationNodeID(nodeID); ok {
+				pathNodeID = reviewNodeID
+			}
+			task.DueDate = model.ComputeTaskDueDate(instance, definitionData, pathNodeID, time.Now())
+		}
+	}
+
 	// 保存任务
-	if err := m.taskRepo.Create(task); err != nil {
+	if err := m.taskRepo.Create(ctx, task); err != nil {
 		return nil, fmt.Errorf("创建任务失败: %v", err)
 	}
 
@@ -56,27 +70,33 @@ func (m *TaskLifecycleManager) CreateTask(instance *model.ProcessInstance, nodeI
 		zap.Uint("instance_id", task.InstanceID),
 		zap.String("node_id", task.NodeID),
 	)
+	m.events.Publish(Event{
+		Type:       EventTaskCreated,
+		InstanceID: task.InstanceID,
+		TaskID:     task.ID,
+		NodeID:     task.NodeID,
+	})
 
 	return task, nil
 }
 
 // AssignTask 分配任务
-func (m *TaskLifecycleManager) AssignTask(taskID uint, assigneeID uint) error {
+func (m *TaskLifecycleManager) AssignTask(ctx context.Context, taskID uint, assigneeID uint) error {
 	m.logger.Info("Assigning task",
 		zap.Uint("task_id", taskID),
 		zap.Uint("assignee_id", assigneeID),
 	)
 
-	task, err := m.taskRepo.GetByID(taskID)
+	task, err := m.taskRepo.GetByID(ctx, taskID)
 	if err != nil {
-		return fmt.Errorf("获取任务失败: %v", err)
+		return fmt.Errorf("获取任务失败: %w", err)
 	}
 
 	// 更新任务
 	task.AssigneeID = &assigneeID
 	task.Status = model.TaskStatusAssigned
 
-	if err := m.taskRepo.Update(task); err != nil {
+	if err := m.taskRepo.Update(ctx, task); err != nil {
 		return fmt.Errorf("更新任务失败: %v", err)
 	}
 
@@ -84,25 +104,26 @@ func (m *TaskLifecycleManager) AssignTask(taskID uint, assigneeID uint) error {
 		zap.Uint("task_id", taskID),
 		zap.Uint("assignee_id", assigneeID),
 	)
+	m.events.Publish(taskAssignedEvent(task))
 
 	return nil
 }
 
 // CompleteTask 完成任务
-func (m *TaskLifecycleManager) CompleteTask(taskID uint, userID uint, formData map[string]interface{}, comment string) error {
+func (m *TaskLifecycleManager) CompleteTask(ctx context.Context, taskID uint, userID uint, formData map[string]interface{}, comment string) error {
 	m.logger.Info("Completing task",
 		zap.Uint("task_id", taskID),
 		zap.Uint("user_id", userID),
 	)
 
-	task, err := m.taskRepo.GetByID(taskID)
+	task, err := m.taskRepo.GetByID(ctx, taskID)
 	if err != nil {
-		return fmt.Errorf("获取任务失败: %v", err)
+		return fmt.Errorf("获取任务失败: %w", err)
 	}
 
 	// 验证用户权限
 	if task.AssigneeID == nil || *task.AssigneeID != userID {
-		return errors.New("用户没有权限完成此任务")
+		return newEngineError(CodePermissionDenied, nil, "用户没有权限完成此任务")
 	}
 
 	// 更新任务
@@ -114,11 +135,11 @@ func (m *TaskLifecycleManager) CompleteTask(taskID uint, userID uint, formData m
 	// 序列化表单数据
 	if formData != nil {
 		if formDataJSON, err := json.Marshal(formData); err == nil {
-			task.Comment = string(formDataJSON)
+			task.FormData = string(formDataJSON)
 		}
 	}
 
-	if err := m.taskRepo.Update(task); err != nil {
+	if err := m.taskRepo.Update(ctx, task); err != nil {
 		return fmt.Errorf("更新任务失败: %v", err)
 	}
 
@@ -126,6 +147,13 @@ func (m *TaskLifecycleManager) CompleteTask(taskID uint, userID uint, formData m
 		zap.Uint("task_id", taskID),
 		zap.Uint("user_id", userID),
 	)
+	m.events.Publish(Event{
+		Type:       EventTaskCompleted,
+		InstanceID: task.InstanceID,
+		TaskID:     task.ID,
+		NodeID:     task.NodeID,
+		UserID:     userID,
+	})
 
 	return nil
 }
@@ -137,6 +165,10 @@ func (m *TaskLifecycleManager) getTaskType(nodeType string) string {
 		return model.TaskTypeUser
 	case "serviceTask":
 		return model.TaskTypeService
+	case model.NodeTypeScriptTask:
+		return model.TaskTypeScript
+	case model.NodeTypeMailTask:
+		return model.TaskTypeMail
 	default:
 		return model.TaskTypeUser
 	}
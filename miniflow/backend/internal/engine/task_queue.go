@@ -0,0 +1,192 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/internal/repository"
+	"miniflow/pkg/config"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// 组队列的分配模式
+const (
+	// QueueModePriority 按优先级和创建时间取下一个任务
+	QueueModePriority = "priority"
+	// QueueModeFairShare 成员的份额超过组内公平份额时不再分配新任务
+	QueueModeFairShare = "fair_share"
+)
+
+// QueueModes 组队列分配模式的取值集合
+var QueueModes = model.Enum{Name: "queue mode", Values: []string{QueueModePriority, QueueModeFairShare}}
+
+// queueClaimAttempts 自动分配时最多尝试认领的任务数，前面的任务可能已被其他成员并发认领
+const queueClaimAttempts = 5
+
+// QueueMemberShare 组成员的工作份额
+type QueueMemberShare struct {
+	UserID uint `json:"user_id"`
+	// Open 已分配给该成员但未完成的任务数
+	Open int `json:"open"`
+	// Completed 统计窗口内完成的任务数
+	Completed int `json:"completed"`
+	// Share 份额，为 Open 与 Completed 之和
+	Share int `json:"share"`
+}
+
+// QueueDispatch 从组队列获取下一个任务的结果，没有可分配的任务时 Task 为空并说明原因
+type QueueDispatch struct {
+	Group    string              `json:"group"`
+	Mode     string              `json:"mode"`
+	Pending  int64               `json:"pending"`
+	Task     *model.TaskInstance `json:"task"`
+	Assigned bool                `json:"assigned"`
+	Reason   string              `json:"reason,omitempty"`
+
+	// 公平分配模式下当前用户的份额和组内的公平份额
+	Share     *QueueMemberShare `json:"share,omitempty"`
+	FairShare int               `json:"fair_share,omitempty"`
+}
+
+// TaskQueue 组任务队列，组为任务的候选角色，组成员为该角色的活跃用户
+type TaskQueue struct {
+	engine *ProcessEngine
+	cfg    *config.QueueConfig
+	logger *logger.Logger
+}
+
+// NewTaskQueue 创建组任务队列
+func NewTaskQueue(engine *ProcessEngine, cfg *config.QueueConfig, logger *logger.Logger) *TaskQueue {
+	return &TaskQueue{
+		engine: engine,
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+// Next 为用户获取组队列中的下一个任务，assign 为 true 时直接认领给该用户，否则只给出建议
+//
+// 公平分配模式下，成员的份额为未完成的任务数加统计窗口内完成的任务数，公平份额为全组份额与
+// 待认领任务数之和按成员平均（向上取整）；份额已达到公平份额的成员不分配新任务，留给其他成员。
+func (q *TaskQueue) Next(ctx context.Context, group string, userID uint, mode string, assign bool) (*QueueDispatch, error) {
+	if mode == "" {
+		mode = QueueModePriority
+	}
+	if err := QueueModes.Validate(mode); err != nil {
+		return nil, err
+	}
+
+	user, err := q.engine.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户失败: %w", err)
+	}
+	if user.Role != group {
+		return nil, newEngineError(CodePermissionDenied, nil, "用户不是组 %s 的成员", group)
+	}
+
+	pending, err := q.engine.taskRepo.CountQueueTasks(ctx, group)
+	if err != nil {
+		return nil, fmt.Errorf("统计队列任务失败: %v", err)
+	}
+	dispatch := &QueueDispatch{Group: group, Mode: mode, Pending: pending}
+	if pending == 0 {
+		dispatch.Reason = "队列中没有待认领的任务"
+		return dispatch, nil
+	}
+
+	if mode == QueueModeFairShare {
+		share, fairShare, err := q.memberShare(ctx, group, userID, pending, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		dispatch.Share, dispatch.FairShare = share, fairShare
+		if share.Share >= fairShare {
+			dispatch.Reason = fmt.Sprintf("当前份额 %d 已达到组内公平份额 %d，任务留给其他成员", share.Share, fairShare)
+			return dispatch, nil
+		}
+	}
+
+	tasks, err := q.engine.taskRepo.GetQueueTasks(ctx, group, queueClaimAttempts)
+	if err != nil {
+		return nil, fmt.Errorf("获取队列任务失败: %w", err)
+	}
+	if len(tasks) == 0 {
+		dispatch.Reason = "队列中没有待认领的任务"
+		return dispatch, nil
+	}
+	if !assign {
+		dispatch.Task = &tasks[0]
+		return dispatch, nil
+	}
+
+	for i := range tasks {
+		err := q.engine.ClaimTask(ctx, tasks[i].ID, userID)
+		if errors.Is(err, repository.ErrTaskNotClaimable) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		task, err := q.engine.taskRepo.GetByID(ctx, tasks[i].ID)
+		if err != nil {
+			return nil, fmt.Errorf("获取任务失败: %w", err)
+		}
+		dispatch.Task, dispatch.Assigned = task, true
+		q.logger.Info("Queue task assigned",
+			zap.String("group", group),
+			zap.String("mode", mode),
+			zap.Uint("task_id", task.ID),
+			zap.Uint("user_id", userID),
+		)
+		return dispatch, nil
+	}
+
+	dispatch.Reason = "队列前面的任务已被其他成员认领，请重试"
+	return dispatch, nil
+}
+
+// memberShare 计算用户的份额和组内的公平份额
+func (q *TaskQueue) memberShare(ctx context.Context, group string, userID uint, pending int64, now time.Time) (*QueueMemberShare, int, error) {
+	users, err := q.engine.userRepo.GetUsersByRole(ctx, group)
+	if err != nil {
+		return nil, 0, fmt.Errorf("获取组成员失败: %w", err)
+	}
+	memberIDs := make([]uint, 0, len(users))
+	for _, user := range users {
+		if user.Status == "active" {
+			memberIDs = append(memberIDs, user.ID)
+		}
+	}
+	if len(memberIDs) == 0 {
+		memberIDs = append(memberIDs, userID)
+	}
+
+	open, err := q.engine.taskRepo.CountOpenTasksByAssignee(ctx, memberIDs)
+	if err != nil {
+		return nil, 0, fmt.Errorf("统计成员待办任务失败: %v", err)
+	}
+	completed, err := q.engine.taskRepo.CountCompletedTasksByAssignee(ctx, memberIDs, now.Add(-q.cfg.GetFairShareWindow()))
+	if err != nil {
+		return nil, 0, fmt.Errorf("统计成员完成任务失败: %v", err)
+	}
+
+	total := int(pending)
+	for _, id := range memberIDs {
+		total += open[id] + completed[id]
+	}
+	fairShare := (total + len(memberIDs) - 1) / len(memberIDs)
+
+	share := &QueueMemberShare{
+		UserID:    userID,
+		Open:      open[userID],
+		Completed: completed[userID],
+	}
+	share.Share = share.Open + share.Completed
+	return share, fairShare, nil
+}
@@ -0,0 +1,203 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"miniflow/internal/model"
+	"miniflow/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+// ReturnTask 驳回任务，退回到上一个用户任务节点
+//
+// 当前任务以 rejected 结果完成，同一节点上其他未完成的任务被跳过，然后根据活动历史找到最近一次完成的其他
+// 用户任务节点重新执行，新任务沿用上次完成时的表单数据；表达式和自动规则没有分配处理人时交还给上次的处理人。
+// 实例还有其他分支上的未完成任务时不能驳回，避免同一路径上出现两个执行位置。返回重新创建的任务。
+func (e *ProcessEngine) ReturnTask(ctx context.Context, taskID, userID uint, comment string) (*model.TaskInstance, error) {
+	task, err := e.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("获取任务失败: %w", err)
+	}
+	if task.Status == model.TaskStatusCompleted {
+		return nil, ErrTaskAlreadyCompleted
+	}
+	if task.Status != model.TaskStatusClaimed && task.Status != model.TaskStatusInProgress {
+		return nil, newEngineError(CodeInvalidStateTransition, nil, "任务状态不允许驳回操作")
+	}
+	if task.AssigneeID != nil && *task.AssigneeID != userID {
+		return nil, newEngineError(CodePermissionDenied, nil, "用户没有权限驳回此任务")
+	}
+
+	instance, err := e.instanceRepo.GetByID(ctx, task.InstanceID)
+	if err != nil {
+		return nil, fmt.Errorf("获取流程实例失败: %w", err)
+	}
+	if instance.Status != model.InstanceStatusRunning {
+		return nil, newEngineError(CodeInvalidStateTransition, nil, "流程实例不在运行中，当前状态为 %s", instance.Status)
+	}
+	definitionData, err := instance.Definition.GetDefinitionData()
+	if err != nil {
+		return nil, newEngineError(CodeInvalidDefinition, err, "解析流程定义失败")
+	}
+	if node := e.findNodeByID(definitionData.Nodes, task.NodeID); node == nil || node.Type != model.NodeTypeUserTask {
+		return nil, newEngineError(CodeInvalidRequest, nil, "只有用户任务可以驳回")
+	}
+
+	target, previous, err := e.returnTarget(ctx, instance, task, definitionData)
+	if err != nil {
+		return nil, err
+	}
+
+	task.Comment = comment
+	task.Outcome = model.TaskOutcomeRejected
+	completed, _, err := e.taskRepo.CompleteTask(ctx, task, userID)
+	if err != nil {
+		return nil, fmt.Errorf("更新任务状态失败: %v", err)
+	}
+	if !completed {
+		return nil, e.completionConflict(ctx, taskID)
+	}
+
+	reason := fmt.Sprintf("任务 %d 驳回到 %s", task.ID, target.ID)
+	skipped, err := e.skipNodeTasks(ctx, instance.ID, task.NodeID, reason)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.instanceRepo.CancelTimers(ctx, instance.ID, task.NodeID); err != nil {
+		return nil, fmt.Errorf("取消节点定时器失败: %v", err)
+	}
+
+	detail := map[string]interface{}{
+		"task_name": task.Name,
+		"to":        target.ID,
+		"comment":   comment,
+	}
+	if len(skipped) > 0 {
+		detail["skipped_tasks"] = skipped
+	}
+	e.recordActivity(ctx, &model.ActivityHistory{
+		InstanceID: instance.ID,
+		Type:       model.ActivityTaskReturned,
+		NodeID:     task.NodeID,
+		NodeType:   model.NodeTypeUserTask,
+		TaskID:     &task.ID,
+	}, userID, detail)
+	e.publishTaskEvent(EventTaskCompleted, task, userID, map[string]interface{}{
+		"outcome": model.TaskOutcomeRejected,
+		"to":      target.ID,
+	})
+	e.traceFor(instance).record(ctx, model.TraceCategoryWrite, task.NodeID, detail, "任务 %d 驳回到节点 %s", task.ID, target.ID)
+
+	e.logger.Info("Task returned to previous step",
+		zap.Uint("task_id", task.ID),
+		zap.Uint("instance_id", instance.ID),
+		zap.String("from_node", task.NodeID),
+		zap.String("to_node", target.ID),
+		zap.Uint("user_id", userID),
+	)
+
+	if err := e.moveInstanceTo(ctx, instance, target.ID); err != nil {
+		return nil, err
+	}
+	if err := e.moveToNextNode(ctx, instance, target.ID); err != nil {
+		return nil, err
+	}
+	return e.restoreReturnedTask(ctx, instance.ID, target.ID, previous)
+}
+
+// returnTarget 根据活动历史找到驳回的目标节点和该节点上次完成的任务，实例在其他节点上还有未完成的任务时不能驳回
+func (e *ProcessEngine) returnTarget(ctx context.Context, instance *model.ProcessInstance, task *model.TaskInstance, definitionData *model.ProcessDefinitionData) (*model.ProcessNode, *model.TaskInstance, error) {
+	tasks, err := e.taskRepo.GetByInstance(ctx, instance.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取实例任务失败: %v", err)
+	}
+	for _, other := range tasks {
+		if other.NodeID != task.NodeID && isOpenTaskStatus(other.Status) {
+			return nil, nil, newEngineError(CodeNoReturnTarget, nil, "节点 %s 上还有未完成的任务，流程存在并行分支时不能驳回", other.NodeID)
+		}
+	}
+
+	// 被驳回的任务记录为 task_returned，不会被当作上一步
+	completions, err := e.instanceRepo.GetActivityHistory(ctx, instance.ID, &repository.ActivityHistoryQuery{
+		Types:      []string{model.ActivityTaskCompleted},
+		Descending: true,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取活动历史失败: %v", err)
+	}
+	for _, completion := range completions {
+		if completion.NodeID == task.NodeID || completion.TaskID == nil {
+			continue
+		}
+		node := e.findNodeByID(definitionData.Nodes, completion.NodeID)
+		if node == nil || node.Type != model.NodeTypeUserTask {
+			continue
+		}
+		previous, err := e.taskRepo.GetByID(ctx, *completion.TaskID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("获取上一步任务失败: %w", err)
+		}
+		return node, previous, nil
+	}
+	return nil, nil, newEngineError(CodeNoReturnTarget, nil, "任务 %d 之前没有已完成的用户任务，无法驳回", task.ID)
+}
+
+// skipNodeTasks 跳过节点上其他未完成的任务（如会签任务），返回被跳过的任务
+func (e *ProcessEngine) skipNodeTasks(ctx context.Context, instanceID uint, nodeID, reason string) ([]uint, error) {
+	tasks, err := e.taskRepo.GetByInstanceAndNode(ctx, instanceID, nodeID, []string{
+		model.TaskStatusCreated,
+		model.TaskStatusAssigned,
+		model.TaskStatusClaimed,
+		model.TaskStatusInProgress,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取节点任务失败: %v", err)
+	}
+	var skipped []uint
+	for i := range tasks {
+		task := &tasks[i]
+		task.Status = model.TaskStatusSkipped
+		if err := e.taskRepo.Update(ctx, task); err != nil {
+			return skipped, fmt.Errorf("跳过任务失败: %v", err)
+		}
+		skipped = append(skipped, task.ID)
+		e.publishTaskEvent(EventTaskSkipped, task, 0, map[string]interface{}{"reason": reason})
+	}
+	return skipped, nil
+}
+
+// restoreReturnedTask 把上次完成时的表单数据带到重新创建的任务上，没有处理人时交还给上次的处理人
+func (e *ProcessEngine) restoreReturnedTask(ctx context.Context, instanceID uint, nodeID string, previous *model.TaskInstance) (*model.TaskInstance, error) {
+	tasks, err := e.taskRepo.GetByInstanceAndNode(ctx, instanceID, nodeID, []string{
+		model.TaskStatusCreated,
+		model.TaskStatusAssigned,
+		model.TaskStatusClaimed,
+		model.TaskStatusInProgress,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取重新创建的任务失败: %v", err)
+	}
+	// 自动处理规则可能已经完成了新任务
+	if len(tasks) == 0 {
+		return nil, nil
+	}
+
+	var restored *model.TaskInstance
+	for i := range tasks {
+		if restored == nil || tasks[i].ID > restored.ID {
+			restored = &tasks[i]
+		}
+	}
+	restored.FormData = previous.FormData
+	if err := e.taskRepo.Update(ctx, restored); err != nil {
+		return nil, fmt.Errorf("恢复任务表单数据失败: %v", err)
+	}
+	if restored.AssigneeID == nil && previous.AssigneeID != nil {
+		if err := e.taskLifecycle.AssignTask(ctx, restored.ID, *previous.AssigneeID); err != nil {
+			return nil, err
+		}
+	}
+	return e.taskRepo.GetByID(ctx, restored.ID)
+}
@@ -0,0 +1,281 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"miniflow/internal/model"
+)
+
+// 时间线条目分类，用于类型筛选
+const (
+	TimelineCategoryInstance = "instance"
+	TimelineCategoryNode     = "node"
+	TimelineCategoryTask     = "task"
+	TimelineCategoryIncident = "incident"
+)
+
+// 时间线条目类型
+const (
+	TimelineInstanceStarted  = "instance.started"
+	TimelineInstanceEnded    = "instance.ended"
+	TimelineNodeEntered      = "node.entered"
+	TimelineTaskCreated      = "task.created"
+	TimelineTaskClaimed      = "task.claimed"
+	TimelineTaskCompleted    = "task.completed"
+	TimelineTaskFailed       = "task.failed"
+	TimelineTaskSkipped      = "task.skipped"
+	TimelineIncidentRaised   = "incident.raised"
+	TimelineIncidentResolved = "incident.resolved"
+)
+
+// TimelineEntry 流程实例时间线条目
+type TimelineEntry struct {
+	Category  string                 `json:"category"`
+	Type      string                 `json:"type"`
+	Timestamp time.Time              `json:"timestamp"`
+	NodeID    string                 `json:"node_id,omitempty"`
+	NodeLabel string                 `json:"node_label,omitempty"`
+	TaskID    *uint                  `json:"task_id,omitempty"`
+	ActorID   *uint                  `json:"actor_id,omitempty"`
+	Summary   string                 `json:"summary"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+// TimelineQuery 时间线查询条件
+type TimelineQuery struct {
+	Categories []string
+	Descending bool
+	Offset     int
+	Limit      int
+}
+
+// TimelinePage 时间线分页结果
+type TimelinePage struct {
+	Entries []TimelineEntry `json:"entries"`
+	Total   int             `json:"total"`
+}
+
+// timelineSource 时间线数据来源，每个来源负责把一类记录转换为时间线条目
+type timelineSource func(ctx context.Context, instance *model.ProcessInstance, labels *labelResolver) ([]TimelineEntry, error)
+
+// GetInstanceTimeline 获取按时间排序的流程实例时间线，合并实例状态、节点、任务和异常事件
+func (e *ProcessEngine) GetInstanceTimeline(ctx context.Context, instanceID uint, query *TimelineQuery) (*TimelinePage, error) {
+	instance, err := e.instanceRepo.GetByID(ctx, instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("获取流程实例失败: %w", err)
+	}
+
+	labels := e.newLabelResolver(&instance.Definition)
+
+	sources := map[string]timelineSource{
+		TimelineCategoryInstance: e.timelineInstanceEntries,
+		TimelineCategoryNode:     e.timelineNodeEntries,
+		TimelineCategoryTask:     e.timelineTaskEntries,
+		TimelineCategoryIncident: e.timelineIncidentEntries,
+	}
+
+	categories := query.Categories
+	if len(categories) == 0 {
+		categories = []string{
+			TimelineCategoryInstance,
+			TimelineCategoryNode,
+			TimelineCategoryTask,
+			TimelineCategoryIncident,
+		}
+	}
+
+	var entries []TimelineEntry
+	for _, category := range categories {
+		source, ok := sources[category]
+		if !ok {
+			return nil, newEngineError(CodeInvalidRequest, nil, "未知的时间线类型: %s", category)
+		}
+		sourceEntries, err := source(ctx, instance, labels)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, sourceEntries...)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if query.Descending {
+			return entries[i].Timestamp.After(entries[j].Timestamp)
+		}
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+
+	page := &TimelinePage{Total: len(entries), Entries: []TimelineEntry{}}
+	if query.Offset < len(entries) {
+		end := len(entries)
+		if query.Limit > 0 && query.Offset+query.Limit < end {
+			end = query.Offset + query.Limit
+		}
+		page.Entries = entries[query.Offset:end]
+	}
+
+	return page, nil
+}
+
+// timelineInstanceEntries 流程实例启动和结束
+func (e *ProcessEngine) timelineInstanceEntries(ctx context.Context, instance *model.ProcessInstance, labels *labelResolver) ([]TimelineEntry, error) {
+	starterID := instance.StarterID
+	entries := []TimelineEntry{{
+		Category:  TimelineCategoryInstance,
+		Type:      TimelineInstanceStarted,
+		Timestamp: instance.StartTime,
+		ActorID:   &starterID,
+		Summary:   fmt.Sprintf("%s 发起了流程", displayName(&instance.Starter)),
+		Data: map[string]interface{}{
+			"business_key": instance.BusinessKey,
+		},
+	}}
+
+	if instance.EndTime != nil {
+		entries = append(entries, TimelineEntry{
+			Category:  TimelineCategoryInstance,
+			Type:      TimelineInstanceEnded,
+			Timestamp: *instance.EndTime,
+			NodeID:    instance.CurrentNode,
+			NodeLabel: labels.nodeLabel(instance.CurrentNode),
+			Summary:   fmt.Sprintf("流程结束，状态：%s", statusText(labels, instance.Status)),
+			Data: map[string]interface{}{
+				"status": instance.Status,
+			},
+		})
+	}
+
+	return entries, nil
+}
+
+// timelineNodeEntries 节点进入记录，根据节点产生的任务推导（任务已随实例预加载）
+func (e *ProcessEngine) timelineNodeEntries(ctx context.Context, instance *model.ProcessInstance, labels *labelResolver) ([]TimelineEntry, error) {
+	var entries []TimelineEntry
+	for _, task := range instance.Tasks {
+		entries = append(entries, TimelineEntry{
+			Category:  TimelineCategoryNode,
+			Type:      TimelineNodeEntered,
+			Timestamp: task.CreatedAt,
+			NodeID:    task.NodeID,
+			NodeLabel: labels.nodeLabel(task.NodeID),
+			Summary:   fmt.Sprintf("进入节点 %s", labelOrID(labels, task.NodeID)),
+		})
+	}
+	return entries, nil
+}
+
+// timelineTaskEntries 任务生命周期变化
+func (e *ProcessEngine) timelineTaskEntries(ctx context.Context, instance *model.ProcessInstance, labels *labelResolver) ([]TimelineEntry, error) {
+	var entries []TimelineEntry
+	for i := range instance.Tasks {
+		task := &instance.Tasks[i]
+		taskID := task.ID
+		base := TimelineEntry{
+			Category:  TimelineCategoryTask,
+			NodeID:    task.NodeID,
+			NodeLabel: labels.nodeLabel(task.NodeID),
+			TaskID:    &taskID,
+			ActorID:   task.AssigneeID,
+		}
+
+		created := base
+		created.Type = TimelineTaskCreated
+		created.Timestamp = task.CreatedAt
+		created.ActorID = nil
+		created.Summary = fmt.Sprintf("创建任务 %s", task.Name)
+		entries = append(entries, created)
+
+		if task.ClaimTime != nil {
+			claimed := base
+			claimed.Type = TimelineTaskClaimed
+			claimed.Timestamp = *task.ClaimTime
+			claimed.Summary = fmt.Sprintf("%s 认领了任务 %s", displayName(task.Assignee), task.Name)
+			entries = append(entries, claimed)
+		}
+
+		if task.CompleteTime != nil {
+			finished := base
+			finished.Timestamp = *task.CompleteTime
+			finished.Data = map[string]interface{}{"status": task.Status}
+			switch {
+			case task.Status == model.TaskStatusFailed:
+				finished.Type = TimelineTaskFailed
+				finished.Summary = fmt.Sprintf("任务 %s 执行失败", task.Name)
+			case task.Status == model.TaskStatusSkipped:
+				finished.Type = TimelineTaskSkipped
+				finished.Summary = fmt.Sprintf("任务 %s 已跳过", task.Name)
+			default:
+				finished.Type = TimelineTaskCompleted
+				finished.Summary = fmt.Sprintf("任务 %s 已完成", task.Name)
+			}
+			if task.AutoRule != "" {
+				finished.ActorID = nil
+				finished.Summary = fmt.Sprintf("%s（系统规则 %s）", finished.Summary, task.AutoRule)
+				finished.Data["actor"] = model.SystemActor
+				finished.Data["auto_rule"] = task.AutoRule
+			}
+			entries = append(entries, finished)
+		}
+	}
+	return entries, nil
+}
+
+// timelineIncidentEntries 异常事件产生和处理
+func (e *ProcessEngine) timelineIncidentEntries(ctx context.Context, instance *model.ProcessInstance, labels *labelResolver) ([]TimelineEntry, error) {
+	incidents, err := e.incidentRepo.GetByInstance(ctx, instance.ID)
+	if err != nil {
+		return nil, fmt.Errorf("获取异常事件失败: %w", err)
+	}
+
+	var entries []TimelineEntry
+	for _, incident := range incidents {
+		data := map[string]interface{}{
+			"incident_id": incident.ID,
+			"type":        incident.Type,
+			"code":        incident.Code,
+		}
+		entries = append(entries, TimelineEntry{
+			Category:  TimelineCategoryIncident,
+			Type:      TimelineIncidentRaised,
+			Timestamp: incident.CreatedAt,
+			NodeID:    incident.NodeID,
+			NodeLabel: labels.nodeLabel(incident.NodeID),
+			TaskID:    incident.TaskID,
+			Summary:   incident.Message,
+			Data:      data,
+		})
+
+		if incident.ResolvedAt != nil {
+			entries = append(entries, TimelineEntry{
+				Category:  TimelineCategoryIncident,
+				Type:      TimelineIncidentResolved,
+				Timestamp: *incident.ResolvedAt,
+				NodeID:    incident.NodeID,
+				NodeLabel: labels.nodeLabel(incident.NodeID),
+				TaskID:    incident.TaskID,
+				ActorID:   incident.ResolvedBy,
+				Summary:   "异常事件已处理",
+				Data:      data,
+			})
+		}
+	}
+	return entries, nil
+}
+
+// labelOrID 返回节点展示名称，没有时返回节点ID
+func labelOrID(labels *labelResolver, nodeID string) string {
+	if label := labels.nodeLabel(nodeID); label != "" {
+		return label
+	}
+	return nodeID
+}
+
+// statusText 返回状态展示标签，没有时返回状态值
+func statusText(labels *labelResolver, status string) string {
+	if label := labels.labels.StatusLabel(status); label != "" {
+		return label
+	}
+	return status
+}
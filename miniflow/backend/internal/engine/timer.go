@@ -0,0 +1,271 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// dueTimerBatchSize 每轮最多触发的定时器数量，剩余的在下一轮处理
+const dueTimerBatchSize = 100
+
+// handleTimer 处理定时器节点：创建等待中的定时器，实例停留在节点上直到到期
+func (e *ProcessEngine) handleTimer(ctx context.Context, instance *model.ProcessInstance, node *model.ProcessNode) error {
+	spec, err := model.GetTimerSpec(node)
+	if err != nil {
+		return newEngineError(CodeInvalidDefinition, err, "定时器节点 %s 配置无效", node.ID)
+	}
+
+	timer := &model.ProcessTimer{
+		InstanceID: instance.ID,
+		NodeID:     node.ID,
+		Kind:       model.TimerKindIntermediate,
+		DueAt:      spec.DueAt(time.Now()),
+		Status:     model.TimerStatusWaiting,
+	}
+	if err := e.instanceRepo.CreateTimer(ctx, timer); err != nil {
+		return fmt.Errorf("创建定时器失败: %v", err)
+	}
+	e.traceFor(instance).record(ctx, model.TraceCategoryWrite, node.ID, map[string]interface{}{
+		"timer_id": timer.ID,
+		"due_at":   timer.DueAt,
+	}, "创建定时器 %d", timer.ID)
+
+	if err := e.moveInstanceTo(ctx, instance, node.ID); err != nil {
+		return fmt.Errorf("更新流程实例当前节点失败: %v", err)
+	}
+
+	e.logger.Info("Timer started",
+		zap.Uint("instance_id", instance.ID),
+		zap.String("node_id", node.ID),
+		zap.Time("due_at", timer.DueAt),
+	)
+	return nil
+}
+
+// startBoundaryTimer 用户任务配置了边界定时器时创建定时器
+func (e *ProcessEngine) startBoundaryTimer(ctx context.Context, instance *model.ProcessInstance, node *model.ProcessNode) error {
+	boundary, err := model.GetBoundaryTimer(node)
+	if err != nil {
+		return newEngineError(CodeInvalidDefinition, err, "节点 %s 的边界定时器配置无效", node.ID)
+	}
+	if boundary == nil {
+		return nil
+	}
+
+	timer := &model.ProcessTimer{
+		InstanceID: instance.ID,
+		NodeID:     node.ID,
+		Kind:       model.TimerKindBoundary,
+		FlowID:     boundary.Flow,
+		DueAt:      boundary.DueAt(time.Now()),
+		Status:     model.TimerStatusWaiting,
+	}
+	if err := e.instanceRepo.CreateTimer(ctx, timer); err != nil {
+		return fmt.Errorf("创建边界定时器失败: %v", err)
+	}
+	e.traceFor(instance).record(ctx, model.TraceCategoryWrite, node.ID, map[string]interface{}{
+		"timer_id": timer.ID,
+		"flow_id":  boundary.Flow,
+		"due_at":   timer.DueAt,
+	}, "创建边界定时器 %d", timer.ID)
+
+	e.logger.Info("Boundary timer started",
+		zap.Uint("instance_id", instance.ID),
+		zap.String("node_id", node.ID),
+		zap.String("flow_id", boundary.Flow),
+		zap.Time("due_at", timer.DueAt),
+	)
+	return nil
+}
+
+// FireDueTimers 触发所有已到期的定时器并推进对应实例，返回触发的数量
+// 暂停实例上的定时器保持等待，恢复后的下一轮再触发；已结束实例上的定时器直接取消
+func (e *ProcessEngine) FireDueTimers(ctx context.Context, now time.Time) (int, error) {
+	timers, err := e.instanceRepo.GetDueTimers(ctx, now, dueTimerBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("获取到期定时器失败: %w", err)
+	}
+
+	fired := 0
+	for i := range timers {
+		// 停止时不再处理剩余的定时器，下次轮询继续
+		if ctx.Err() != nil {
+			return fired, ctx.Err()
+		}
+		timer := &timers[i]
+
+		instance, err := e.instanceRepo.GetByID(ctx, timer.InstanceID)
+		if err != nil {
+			e.logger.Error("Failed to load timer instance", zap.Uint("timer_id", timer.ID), zap.Error(err))
+			continue
+		}
+
+		switch instance.Status {
+		case model.InstanceStatusRunning:
+		case model.InstanceStatusSuspended:
+			continue
+		default:
+			if err := e.instanceRepo.CancelTimers(ctx, instance.ID, ""); err != nil {
+				e.logger.Error("Failed to cancel timers of finished instance", zap.Uint("instance_id", instance.ID), zap.Error(err))
+			}
+			continue
+		}
+
+		// 定时器标记为已触发后必须推进完成，不跟随 ctx 的取消
+		fireCtx := context.WithoutCancel(ctx)
+
+		// 条件更新保证多个调度器同时运行时每个定时器只触发一次
+		ok, err := e.instanceRepo.MarkTimerFired(fireCtx, timer.ID, now)
+		if err != nil {
+			return fired, fmt.Errorf("更新定时器状态失败: %v", err)
+		}
+		if !ok {
+			continue
+		}
+		fired++
+
+		e.logger.Info("Timer fired",
+			zap.Uint("timer_id", timer.ID),
+			zap.Uint("instance_id", instance.ID),
+			zap.String("node_id", timer.NodeID),
+			zap.String("kind", timer.Kind),
+			zap.Duration("delay", now.Sub(timer.DueAt)),
+		)
+
+		if err := e.fireTimer(fireCtx, instance, timer); err != nil {
+			e.logger.Error("Failed to advance process after timer fired",
+				zap.Uint("timer_id", timer.ID),
+				zap.Uint("instance_id", instance.ID),
+				zap.Error(err),
+			)
+			// 标记为失败，运维可以在作业面板中重试
+			if err := e.instanceRepo.MarkTimerFailed(fireCtx, timer.ID, err.Error()); err != nil {
+				e.logger.Error("Failed to mark timer failed", zap.Uint("timer_id", timer.ID), zap.Error(err))
+			}
+		}
+	}
+
+	return fired, nil
+}
+
+// fireTimer 按定时器类型推进流程
+func (e *ProcessEngine) fireTimer(ctx context.Context, instance *model.ProcessInstance, timer *model.ProcessTimer) error {
+	definition, err := instance.Definition.GetDefinitionData()
+	if err != nil {
+		return newEngineError(CodeInvalidDefinition, err, "解析流程定义失败")
+	}
+
+	if timer.Kind == model.TimerKindBoundary {
+		return e.fireBoundaryTimer(ctx, instance, timer, definition)
+	}
+
+	outgoingFlows := e.findOutgoingFlows(definition.Flows, timer.NodeID)
+	if len(outgoingFlows) == 0 {
+		return newEngineError(CodeNoOutgoingFlow, nil, "定时器节点 %s 没有出口连线", timer.NodeID)
+	}
+	if node := e.findNodeByID(definition.Nodes, timer.NodeID); node != nil {
+		e.recordNodeActivity(ctx, instance, node, model.ActivityNodeExited, map[string]interface{}{"timer_id": timer.ID})
+	}
+	for _, flow := range outgoingFlows {
+		if err := e.advanceAlongFlow(ctx, instance, flow, definition); err != nil {
+			return fmt.Errorf("流程推进失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// fireBoundaryTimer 跳过节点上未完成的任务，沿边界定时器指定的连线推进
+func (e *ProcessEngine) fireBoundaryTimer(ctx context.Context, instance *model.ProcessInstance, timer *model.ProcessTimer, definition *model.ProcessDefinitionData) error {
+	pendingTasks, err := e.taskRepo.GetByInstanceAndNode(ctx, instance.ID, timer.NodeID, []string{
+		model.TaskStatusCreated,
+		model.TaskStatusAssigned,
+		model.TaskStatusClaimed,
+		model.TaskStatusInProgress,
+	})
+	if err != nil {
+		return fmt.Errorf("检查待处理任务失败: %v", err)
+	}
+	// 任务已经全部完成，流程已沿正常连线推进
+	if len(pendingTasks) == 0 {
+		return nil
+	}
+
+	var flow *model.ProcessFlow
+	for i := range definition.Flows {
+		if definition.Flows[i].ID == timer.FlowID && definition.Flows[i].From == timer.NodeID {
+			flow = &definition.Flows[i]
+			break
+		}
+	}
+	if flow == nil {
+		return newEngineError(CodeNoOutgoingFlow, nil, "找不到边界定时器连线: %s", timer.FlowID)
+	}
+
+	now := time.Now()
+	for i := range pendingTasks {
+		task := &pendingTasks[i]
+		task.Status = model.TaskStatusSkipped
+		task.CompleteTime = &now
+		task.Comment = "边界定时器到期，系统自动跳过"
+		if err := e.taskRepo.Update(ctx, task); err != nil {
+			return fmt.Errorf("更新任务状态失败: %v", err)
+		}
+
+		e.logger.Info("Task skipped",
+			zap.Uint("instance_id", instance.ID),
+			zap.Uint("task_id", task.ID),
+			zap.String("reason", "边界定时器到期"),
+		)
+		e.publishTaskEvent(EventTaskSkipped, task, 0, map[string]interface{}{"reason": "边界定时器到期"})
+	}
+
+	if node := e.findNodeByID(definition.Nodes, timer.NodeID); node != nil {
+		e.recordNodeActivity(ctx, instance, node, model.ActivityNodeExited, map[string]interface{}{
+			"timer_id": timer.ID,
+			"flow_id":  flow.ID,
+			"skipped":  len(pendingTasks),
+		})
+	}
+	return e.advanceAlongFlow(ctx, instance, *flow, definition)
+}
+
+// TimerScheduler 后台定时器调度器，定期触发到期的流程定时器
+type TimerScheduler struct {
+	engine *ProcessEngine
+	logger *logger.Logger
+}
+
+// NewTimerScheduler 创建定时器调度器
+func NewTimerScheduler(engine *ProcessEngine, logger *logger.Logger) *TimerScheduler {
+	return &TimerScheduler{
+		engine: engine,
+		logger: logger,
+	}
+}
+
+// Start 运行定时器检查循环直到 ctx 取消
+func (s *TimerScheduler) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := s.engine.FireDueTimers(ctx, now); err != nil {
+				s.logger.Error("Failed to fire due timers", zap.Error(err))
+			}
+		}
+	}
+}
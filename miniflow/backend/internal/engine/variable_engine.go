@@ -1,55 +1,148 @@
 package engine
 
 import (
-	"encoding/json"
-	"errors"
+	"context"
 	"fmt"
-	"regexp"
 	"strings"
 
+	"miniflow/internal/repository"
+	"miniflow/pkg/expression"
 	"miniflow/pkg/logger"
 
 	"go.uber.org/zap"
 )
 
 // VariableEngine 流程变量和条件引擎
+//
+// 变量按实例存放在 VariableStore 中。读取时先在实例自身的作用域查找，
+// 找不到的变量沿父实例链向上解析，子实例的同名变量覆盖父实例的变量；写入只影响实例自身。
 type VariableEngine struct {
-	logger *logger.Logger
+	store        VariableStore
+	instanceRepo *repository.ProcessInstanceRepository
+	logger       *logger.Logger
 }
 
 // NewVariableEngine 创建变量引擎
-func NewVariableEngine(logger *logger.Logger) *VariableEngine {
+func NewVariableEngine(store VariableStore, instanceRepo *repository.ProcessInstanceRepository, logger *logger.Logger) *VariableEngine {
 	return &VariableEngine{
-		logger: logger,
+		store:        store,
+		instanceRepo: instanceRepo,
+		logger:       logger,
 	}
 }
 
 // SetVariable 设置流程变量
-func (e *VariableEngine) SetVariable(instanceID uint, key string, value interface{}) error {
-	e.logger.Info("Setting process variable",
+func (e *VariableEngine) SetVariable(ctx context.Context, instanceID uint, key string, value interface{}) error {
+	return e.SetVariables(ctx, instanceID, map[string]interface{}{key: value}, nil)
+}
+
+// SetVariables 一次写入修改和删除的流程变量
+func (e *VariableEngine) SetVariables(ctx context.Context, instanceID uint, changed map[string]interface{}, removed []string) error {
+	e.logger.Info("Setting process variables",
 		zap.Uint("instance_id", instanceID),
-		zap.String("key", key),
-		zap.Any("value", value),
+		zap.Int("changed", len(changed)),
+		zap.Strings("removed", removed),
 	)
-	// 简单实现，实际存储到数据库
+	if err := e.store.SetMany(ctx, instanceID, changed, removed); err != nil {
+		return fmt.Errorf("保存流程变量失败: %v", err)
+	}
 	return nil
 }
 
-// GetVariable 获取单个流程变量
-func (e *VariableEngine) GetVariable(instanceID uint, key string) (interface{}, error) {
-	// 简单实现，实际从数据库获取
-	return nil, nil
+// GetVariable 获取单个流程变量，变量在所有作用域中都不存在时返回 nil
+func (e *VariableEngine) GetVariable(ctx context.Context, instanceID uint, key string) (interface{}, error) {
+	variables, err := e.GetVariables(ctx, instanceID, []string{key})
+	if err != nil {
+		return nil, err
+	}
+	return variables[key], nil
+}
+
+// GetVariables 批量获取流程变量，每个作用域只读取一次，不存在的变量不出现在结果中
+func (e *VariableEngine) GetVariables(ctx context.Context, instanceID uint, names []string) (map[string]interface{}, error) {
+	result := make(map[string]interface{}, len(names))
+	if len(names) == 0 {
+		return result, nil
+	}
+	missing := names
+	err := e.walkScopes(ctx, instanceID, func(scopeID uint) (bool, error) {
+		variables, err := e.store.GetMany(ctx, scopeID, missing)
+		if err != nil {
+			return false, err
+		}
+		var rest []string
+		for _, name := range missing {
+			if value, ok := variables[name]; ok {
+				result[name] = value
+			} else {
+				rest = append(rest, name)
+			}
+		}
+		missing = rest
+		return len(missing) > 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetAllVariables 获取流程实例可见的所有变量
+func (e *VariableEngine) GetAllVariables(ctx context.Context, instanceID uint) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	err := e.walkScopes(ctx, instanceID, func(scopeID uint) (bool, error) {
+		variables, err := e.store.GetAll(ctx, scopeID)
+		if err != nil {
+			return false, err
+		}
+		for key, value := range variables {
+			if _, ok := result[key]; !ok {
+				result[key] = value
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Invalidate 实例被直接更新后丢弃存储缓存的变量
+func (e *VariableEngine) Invalidate(instanceID uint) {
+	e.store.Invalidate(instanceID)
 }
 
-// GetAllVariables 获取流程实例的所有变量
-func (e *VariableEngine) GetAllVariables(instanceID uint) (map[string]interface{}, error) {
-	// 简单实现，实际从数据库获取
-	return make(map[string]interface{}), nil
+// walkScopes 从实例自身开始沿父实例链依次访问各作用域，visit 返回 false 时停止
+// 只有需要继续向上时才读取实例获取父实例，变量都在自身作用域时只访问一次存储
+func (e *VariableEngine) walkScopes(ctx context.Context, instanceID uint, visit func(scopeID uint) (bool, error)) error {
+	visited := make(map[uint]bool)
+	for scopeID := instanceID; !visited[scopeID]; {
+		visited[scopeID] = true
+		more, err := visit(scopeID)
+		if err != nil {
+			return fmt.Errorf("获取流程变量失败: %w", err)
+		}
+		if !more {
+			return nil
+		}
+
+		instance, err := e.instanceRepo.GetByID(ctx, scopeID)
+		if err != nil {
+			return fmt.Errorf("获取流程实例失败: %w", err)
+		}
+		if instance.ParentInstanceID == nil {
+			return nil
+		}
+		scopeID = *instance.ParentInstanceID
+	}
+	return nil
 }
 
-// EvaluateCondition 评估条件表达式
+// EvaluateCondition 评估条件表达式，支持比较、逻辑运算和字符串/数字/布尔类型
+// 变量不存在、类型不匹配或结果不是布尔值时返回错误，由调用方决定如何处理
 func (e *VariableEngine) EvaluateCondition(condition string, variables map[string]interface{}) (bool, error) {
-	if condition == "" {
+	if strings.TrimSpace(condition) == "" {
 		return true, nil
 	}
 
@@ -58,21 +151,13 @@ func (e *VariableEngine) EvaluateCondition(condition string, variables map[strin
 		zap.Any("variables", variables),
 	)
 
-	// 替换变量引用
-	processedCondition, err := e.replaceVariables(condition, variables)
-	if err != nil {
-		return false, fmt.Errorf("变量替换失败: %v", err)
-	}
-
-	// 简单的条件评估，只支持基本的相等比较
-	result, err := e.evaluateSimpleCondition(processedCondition)
+	result, err := expression.EvaluateCondition(condition, variables)
 	if err != nil {
 		e.logger.Error("Condition evaluation failed",
 			zap.String("condition", condition),
-			zap.String("processed", processedCondition),
 			zap.Error(err),
 		)
-		return false, err
+		return false, fmt.Errorf("条件 %q 评估失败: %v", condition, err)
 	}
 
 	e.logger.Debug("Condition evaluation result",
@@ -82,87 +167,3 @@ func (e *VariableEngine) EvaluateCondition(condition string, variables map[strin
 
 	return result, nil
 }
-
-// replaceVariables 替换条件表达式中的变量引用
-func (e *VariableEngine) replaceVariables(condition string, variables map[string]interface{}) (string, error) {
-	// 匹配 ${variable} 格式的变量引用
-	varPattern := regexp.MustCompile(`\$\{([^}]+)\}`)
-
-	result := varPattern.ReplaceAllStringFunc(condition, func(match string) string {
-		// 提取变量名
-		varName := strings.Trim(match[2:len(match)-1], " ")
-
-		// 查找变量值
-		if value, exists := variables[varName]; exists {
-			return e.formatValue(value)
-		}
-
-		// 变量不存在时返回原始字符串
-		e.logger.Warn("Variable not found in condition",
-			zap.String("variable", varName),
-			zap.String("condition", condition),
-		)
-		return match
-	})
-
-	return result, nil
-}
-
-// formatValue 格式化变量值为字符串
-func (e *VariableEngine) formatValue(value interface{}) string {
-	if value == nil {
-		return "null"
-	}
-
-	switch v := value.(type) {
-	case string:
-		return fmt.Sprintf("'%s'", strings.ReplaceAll(v, "'", "\\'"))
-	case bool:
-		if v {
-			return "true"
-		}
-		return "false"
-	case int, int8, int16, int32, int64:
-		return fmt.Sprintf("%d", v)
-	case uint, uint8, uint16, uint32, uint64:
-		return fmt.Sprintf("%d", v)
-	case float32, float64:
-		return fmt.Sprintf("%g", v)
-	default:
-		// 对于复杂类型，转换为JSON字符串
-		if jsonBytes, err := json.Marshal(v); err == nil {
-			return fmt.Sprintf("'%s'", strings.ReplaceAll(string(jsonBytes), "'", "\\'"))
-		}
-		return fmt.Sprintf("'%v'", v)
-	}
-}
-
-// evaluateSimpleCondition 评估简单的条件表达式
-func (e *VariableEngine) evaluateSimpleCondition(expression string) (bool, error) {
-	// 去除首尾空格
-	expression = strings.TrimSpace(expression)
-
-	// 只支持基本的相等比较
-	if strings.Contains(expression, "==") {
-		parts := strings.SplitN(expression, "==", 2)
-		if len(parts) != 2 {
-			return false, errors.New("无效的相等比较表达式")
-		}
-
-		left := strings.TrimSpace(parts[0])
-		right := strings.TrimSpace(parts[1])
-
-		// 简单的字符串比较
-		return left == right, nil
-	}
-
-	// 如果不包含比较操作符，尝试解析为布尔值
-	switch strings.ToLower(expression) {
-	case "true", "1", "yes", "on":
-		return true, nil
-	case "false", "0", "no", "off":
-		return false, nil
-	default:
-		return false, fmt.Errorf("无法解析为布尔值: %s", expression)
-	}
-}
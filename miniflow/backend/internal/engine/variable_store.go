@@ -0,0 +1,178 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"miniflow/internal/repository"
+	"miniflow/pkg/cache"
+	"miniflow/pkg/config"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// VariableStore 流程变量存储，只负责单个实例自身作用域的变量，作用域解析由 VariableEngine 完成
+type VariableStore interface {
+	// GetAll 获取实例的所有变量
+	GetAll(ctx context.Context, instanceID uint) (map[string]interface{}, error)
+	// GetMany 一次读取实例的多个变量，不存在的变量不出现在结果中
+	GetMany(ctx context.Context, instanceID uint, names []string) (map[string]interface{}, error)
+	// SetMany 一次写入修改和删除的变量
+	SetMany(ctx context.Context, instanceID uint, changed map[string]interface{}, removed []string) error
+	// Invalidate 实例变量被绕过存储修改后调用，丢弃缓存的数据
+	Invalidate(instanceID uint)
+}
+
+// NewVariableStore 按配置创建变量存储，redis 存储在数据库存储之上增加读缓存
+func NewVariableStore(cfg *config.VariablesConfig, redisCfg *config.RedisConfig, instanceRepo *repository.ProcessInstanceRepository, logger *logger.Logger) VariableStore {
+	store := NewDBVariableStore(instanceRepo)
+	if cfg.Store == "redis" {
+		return NewCachedVariableStore(store, cache.NewRedisClient(redisCfg), cfg.GetCacheTTL(), logger)
+	}
+	return store
+}
+
+// DBVariableStore 以流程实例的变量列存储变量
+type DBVariableStore struct {
+	instanceRepo *repository.ProcessInstanceRepository
+}
+
+// NewDBVariableStore 创建数据库变量存储
+func NewDBVariableStore(instanceRepo *repository.ProcessInstanceRepository) *DBVariableStore {
+	return &DBVariableStore{instanceRepo: instanceRepo}
+}
+
+// GetAll 获取实例的所有变量
+func (s *DBVariableStore) GetAll(ctx context.Context, instanceID uint) (map[string]interface{}, error) {
+	data, err := s.instanceRepo.GetVariables(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	return decodeVariables(data)
+}
+
+// GetMany 一次读取实例的多个变量
+func (s *DBVariableStore) GetMany(ctx context.Context, instanceID uint, names []string) (map[string]interface{}, error) {
+	variables, err := s.GetAll(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	return pickVariables(variables, names), nil
+}
+
+// SetMany 在最新的变量上合并修改并保存
+func (s *DBVariableStore) SetMany(ctx context.Context, instanceID uint, changed map[string]interface{}, removed []string) error {
+	return s.instanceRepo.UpdateVariables(ctx, instanceID, changed, removed)
+}
+
+// Invalidate 数据库存储没有缓存
+func (s *DBVariableStore) Invalidate(instanceID uint) {}
+
+// CachedVariableStore 在变量存储之上增加 Redis 读缓存
+//
+// 缓存以实例为单位保存全部变量的 JSON，读取未命中时从下层存储加载并写入缓存，
+// 写入和失效时删除缓存。Redis 不可用时直接读写下层存储，只记录警告。
+type CachedVariableStore struct {
+	store  VariableStore
+	client *cache.RedisClient
+	ttl    time.Duration
+	logger *logger.Logger
+}
+
+// NewCachedVariableStore 创建带 Redis 缓存的变量存储
+func NewCachedVariableStore(store VariableStore, client *cache.RedisClient, ttl time.Duration, logger *logger.Logger) *CachedVariableStore {
+	return &CachedVariableStore{
+		store:  store,
+		client: client,
+		ttl:    ttl,
+		logger: logger,
+	}
+}
+
+// GetAll 获取实例的所有变量，优先读取缓存
+func (s *CachedVariableStore) GetAll(ctx context.Context, instanceID uint) (map[string]interface{}, error) {
+	key := variableCacheKey(instanceID)
+	data, err := s.client.Get(key)
+	if err == nil {
+		variables, err := decodeVariables(string(data))
+		if err == nil {
+			return variables, nil
+		}
+		s.logger.Warn("Discarding corrupt cached variables", zap.Uint("instance_id", instanceID), zap.Error(err))
+	} else if !errors.Is(err, cache.ErrNil) {
+		s.logger.Warn("Variable cache unavailable", zap.Uint("instance_id", instanceID), zap.Error(err))
+	}
+
+	variables, err := s.store.GetAll(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if encoded, err := json.Marshal(variables); err == nil {
+		if err := s.client.Set(key, encoded, s.ttl); err != nil {
+			s.logger.Warn("Failed to cache variables", zap.Uint("instance_id", instanceID), zap.Error(err))
+		}
+	}
+	return variables, nil
+}
+
+// GetMany 一次读取实例的多个变量，缓存命中时不访问数据库
+func (s *CachedVariableStore) GetMany(ctx context.Context, instanceID uint, names []string) (map[string]interface{}, error) {
+	variables, err := s.GetAll(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	return pickVariables(variables, names), nil
+}
+
+// SetMany 写入下层存储后删除缓存
+func (s *CachedVariableStore) SetMany(ctx context.Context, instanceID uint, changed map[string]interface{}, removed []string) error {
+	if err := s.store.SetMany(ctx, instanceID, changed, removed); err != nil {
+		return err
+	}
+	s.Invalidate(instanceID)
+	return nil
+}
+
+// Invalidate 删除实例的缓存
+func (s *CachedVariableStore) Invalidate(instanceID uint) {
+	s.store.Invalidate(instanceID)
+	if err := s.client.Del(variableCacheKey(instanceID)); err != nil {
+		s.logger.Warn("Failed to invalidate cached variables", zap.Uint("instance_id", instanceID), zap.Error(err))
+	}
+}
+
+// variableCacheKey 实例变量的缓存键
+func variableCacheKey(instanceID uint) string {
+	return fmt.Sprintf("miniflow:variables:%d", instanceID)
+}
+
+// decodeVariables 解析变量 JSON，为空时返回空映射
+func decodeVariables(data string) (map[string]interface{}, error) {
+	variables := make(map[string]interface{})
+	if data == "" {
+		return variables, nil
+	}
+	if err := json.Unmarshal([]byte(data), &variables); err != nil {
+		return nil, fmt.Errorf("解析流程变量失败: %v", err)
+	}
+	// 没有变量启动的实例保存为 null
+	if variables == nil {
+		variables = make(map[string]interface{})
+	}
+	return variables, nil
+}
+
+// pickVariables 取出指定名称的变量，不存在的名称被忽略
+func pickVariables(variables map[string]interface{}, names []string) map[string]interface{} {
+	picked := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		if value, ok := variables[name]; ok {
+			picked[name] = value
+		}
+	}
+	return picked
+}
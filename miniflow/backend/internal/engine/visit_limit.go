@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// checkVisitLimit 记录声明了访问上限的用户任务被进入的次数，返回节点是否因超过上限被拦截
+//
+// 驳回把工作来回退给同一个节点时，每次进入都计数。超过上限后不再创建任务：配置了升级连线时
+// 沿该连线推进，否则生成异常事件，实例停在该节点等待管理员处理。
+func (e *ProcessEngine) checkVisitLimit(ctx context.Context, instance *model.ProcessInstance, node *model.ProcessNode, definition *model.ProcessDefinitionData) (bool, error) {
+	if node.Type != model.NodeTypeUserTask {
+		return false, nil
+	}
+	limit, err := model.GetVisitLimit(node)
+	if err != nil {
+		return false, newEngineError(CodeInvalidDefinition, err, "节点 %s 的访问上限配置无效", node.ID)
+	}
+	if limit == nil {
+		return false, nil
+	}
+
+	visits := 0
+	if err := e.updateInstance(ctx, instance, func(target *model.ProcessInstance) error {
+		counts := target.GetNodeVisits()
+		counts[node.ID]++
+		visits = counts[node.ID]
+		return target.SetNodeVisits(counts)
+	}); err != nil {
+		return false, err
+	}
+	e.traceFor(instance).record(ctx, model.TraceCategoryNode, node.ID, map[string]interface{}{
+		"visits":     visits,
+		"max_visits": limit.MaxVisits,
+	}, "节点 %s 第 %d 次进入，上限 %d 次", node.ID, visits, limit.MaxVisits)
+	if visits <= limit.MaxVisits {
+		return false, nil
+	}
+
+	e.logger.Warn("Node visit limit exceeded",
+		zap.Uint("instance_id", instance.ID),
+		zap.String("node_id", node.ID),
+		zap.Int("visits", visits),
+		zap.Int("max_visits", limit.MaxVisits),
+	)
+
+	if limit.Flow != "" {
+		for _, flow := range e.findOutgoingFlows(definition.Flows, node.ID) {
+			if flow.ID != limit.Flow {
+				continue
+			}
+			e.recordNodeActivity(ctx, instance, node, model.ActivityNodeExited, map[string]interface{}{
+				"flow_id": flow.ID,
+				"visits":  visits,
+			})
+			return true, e.advanceAlongFlow(ctx, instance, flow, definition)
+		}
+		return true, newEngineError(CodeNoOutgoingFlow, nil, "找不到访问上限的升级连线: %s", limit.Flow)
+	}
+
+	if err := e.moveInstanceTo(ctx, instance, node.ID); err != nil {
+		return true, fmt.Errorf("更新流程实例当前节点失败: %v", err)
+	}
+	cause := newEngineError(CodeVisitLimitExceeded, nil, "节点 %s 已进入 %d 次，超过上限 %d 次", node.ID, visits, limit.MaxVisits)
+	return true, e.raiseIncident(ctx, instance, nil, node, model.IncidentTypeVisitLimit, cause)
+}
+
+// retryVisitLimit 管理员确认后再执行一次超过访问上限的节点，计数保留，再次被退回时会生成新的异常事件
+func (e *ProcessEngine) retryVisitLimit(ctx context.Context, incident *model.Incident, instance *model.ProcessInstance, node *model.ProcessNode, definition *model.ProcessDefinitionData, userID uint) (*model.Incident, error) {
+	if node == nil || node.Type != model.NodeTypeUserTask {
+		return nil, newEngineError(CodeNotFound, nil, "异常事件对应的用户任务节点不存在")
+	}
+
+	if err := e.markIncidentResolved(ctx, incident, userID); err != nil {
+		return nil, err
+	}
+
+	e.logger.Info("Retrying node over visit limit",
+		zap.Uint("incident_id", incident.ID),
+		zap.Uint("instance_id", instance.ID),
+		zap.String("node_id", node.ID),
+	)
+
+	if err := e.executeNode(ctx, instance, node, definition); err != nil {
+		return nil, fmt.Errorf("重新执行节点失败: %w", err)
+	}
+	return incident, nil
+}
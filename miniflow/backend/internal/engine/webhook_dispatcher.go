@@ -0,0 +1,336 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/internal/repository"
+	"miniflow/pkg/config"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 事件订阅的限制
+const (
+	webhookSubscriptionMaxURL    = 500
+	webhookSubscriptionMaxSecret = 255
+)
+
+// 事件订阅的错误
+var (
+	ErrWebhookSubscriptionNotFound = &EngineError{Code: CodeNotFound, Message: "事件订阅不存在"}
+	ErrInvalidWebhookSubscription  = &EngineError{Code: CodeInvalidRequest, Message: "事件订阅参数无效"}
+)
+
+// WebhookEventPayload 事件订阅回调的请求体
+type WebhookEventPayload struct {
+	Event          string                 `json:"event"`
+	SubscriptionID uint                   `json:"subscription_id"`
+	InstanceID     uint                   `json:"instance_id"`
+	TaskID         uint                   `json:"task_id,omitempty"`
+	NodeID         string                 `json:"node_id,omitempty"`
+	UserID         uint                   `json:"user_id,omitempty"`
+	Data           map[string]interface{} `json:"data,omitempty"`
+	OccurredAt     time.Time              `json:"occurred_at"`
+}
+
+// WebhookSubscriptionRequest 创建或修改事件订阅的请求
+// 修改时 Secret 为空表示保留原密钥，Active 为空表示不修改
+type WebhookSubscriptionRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Secret     string   `json:"secret"`
+	Active     *bool    `json:"active"`
+}
+
+// WebhookSubscriptionView 事件订阅的返回数据，不包含签名密钥
+type WebhookSubscriptionView struct {
+	ID         uint      `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	HasSecret  bool      `json:"has_secret"`
+	Active     bool      `json:"active"`
+	CreatedBy  uint      `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// WebhookDispatcher 把引擎事件投递到外部系统注册的回调地址
+//
+// 每个匹配的订阅先保存一条投递记录，再异步投递签名的请求体，失败时按完成回调的退避策略重试，
+// 最终失败的投递可以在作业面板中重新投递。
+type WebhookDispatcher struct {
+	engine  *ProcessEngine
+	subRepo *repository.WebhookSubscriptionRepository
+	logger  *logger.Logger
+}
+
+// NewWebhookDispatcher 创建事件订阅投递器
+func NewWebhookDispatcher(engine *ProcessEngine, subRepo *repository.WebhookSubscriptionRepository, logger *logger.Logger) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		engine:  engine,
+		subRepo: subRepo,
+		logger:  logger,
+	}
+}
+
+// Start 订阅引擎事件并投递到回调地址，直到 ctx 取消；只应调用一次
+func (d *WebhookDispatcher) Start(ctx context.Context) {
+	d.engine.events.SubscribeAll(func(event Event) {
+		if ctx.Err() != nil {
+			return
+		}
+		d.Dispatch(ctx, event)
+	})
+
+	<-ctx.Done()
+}
+
+// Dispatch 为事件匹配的每个启用的订阅创建投递记录并异步投递
+func (d *WebhookDispatcher) Dispatch(ctx context.Context, event Event) {
+	subscriptions, err := d.subRepo.GetActive(ctx)
+	if err != nil {
+		d.logger.Error("Failed to load webhook subscriptions",
+			zap.String("event", event.Type),
+			zap.Error(err),
+		)
+		return
+	}
+
+	for i := range subscriptions {
+		subscription := &subscriptions[i]
+		if !subscription.Matches(event.Type) {
+			continue
+		}
+		d.deliver(ctx, subscription, event)
+	}
+}
+
+// deliver 保存一条投递记录并异步投递
+func (d *WebhookDispatcher) deliver(ctx context.Context, subscription *model.WebhookSubscription, event Event) {
+	body, err := json.Marshal(WebhookEventPayload{
+		Event:          event.Type,
+		SubscriptionID: subscription.ID,
+		InstanceID:     event.InstanceID,
+		TaskID:         event.TaskID,
+		NodeID:         event.NodeID,
+		UserID:         event.UserID,
+		Data:           event.Data,
+		OccurredAt:     event.Timestamp,
+	})
+	if err != nil {
+		d.logger.Error("Failed to encode webhook event payload",
+			zap.Uint("subscription_id", subscription.ID),
+			zap.String("event", event.Type),
+			zap.Error(err),
+		)
+		return
+	}
+
+	subscriptionID := subscription.ID
+	delivery := &model.WebhookDelivery{
+		InstanceID:     event.InstanceID,
+		SubscriptionID: &subscriptionID,
+		Event:          event.Type,
+		URL:            subscription.URL,
+		Payload:        string(body),
+		Status:         model.WebhookDeliveryPending,
+	}
+	if err := d.engine.instanceRepo.CreateWebhookDelivery(ctx, delivery); err != nil {
+		d.logger.Warn("Failed to record webhook event delivery",
+			zap.Uint("subscription_id", subscription.ID),
+			zap.String("event", event.Type),
+			zap.Error(err),
+		)
+	}
+
+	d.engine.deliverWebhookAsync(ctx, delivery, subscription.Secret)
+}
+
+// RetryDelivery 重新投递失败的事件订阅回调，使用订阅当前的签名密钥；订阅已删除时不允许重试
+func (d *WebhookDispatcher) RetryDelivery(ctx context.Context, delivery *model.WebhookDelivery) (bool, error) {
+	subscription, err := d.subRepo.GetByID(ctx, *delivery.SubscriptionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	ok, err := d.engine.instanceRepo.RequeueWebhookDelivery(ctx, delivery.ID)
+	if err != nil || !ok {
+		return ok, err
+	}
+	delivery.Status = model.WebhookDeliveryPending
+
+	d.engine.deliverWebhookAsync(ctx, delivery, subscription.Secret)
+	return true, nil
+}
+
+// ListSubscriptions 获取全部事件订阅，需要 webhook_manage 权限
+func (d *WebhookDispatcher) ListSubscriptions(ctx context.Context, userID uint) ([]*WebhookSubscriptionView, error) {
+	if err := d.engine.checkPermission(ctx, userID, config.PermissionWebhookManage, "查看事件订阅"); err != nil {
+		return nil, err
+	}
+	subscriptions, err := d.subRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]*WebhookSubscriptionView, len(subscriptions))
+	for i := range subscriptions {
+		views[i] = toSubscriptionView(&subscriptions[i])
+	}
+	return views, nil
+}
+
+// CreateSubscription 注册事件订阅，需要 webhook_manage 权限
+func (d *WebhookDispatcher) CreateSubscription(ctx context.Context, req *WebhookSubscriptionRequest, userID uint) (*WebhookSubscriptionView, error) {
+	if err := d.engine.checkPermission(ctx, userID, config.PermissionWebhookManage, "注册事件订阅"); err != nil {
+		return nil, err
+	}
+	if err := validateWebhookSubscription(req.URL, req.EventTypes, req.Secret); err != nil {
+		return nil, err
+	}
+
+	subscription := &model.WebhookSubscription{
+		URL:       req.URL,
+		Secret:    req.Secret,
+		Active:    req.Active == nil || *req.Active,
+		CreatedBy: userID,
+	}
+	subscription.SetEventTypes(req.EventTypes)
+	if err := d.subRepo.Create(ctx, subscription); err != nil {
+		return nil, err
+	}
+
+	d.logger.Info("Webhook subscription created",
+		zap.Uint("subscription_id", subscription.ID),
+		zap.String("url", subscription.URL),
+		zap.Strings("event_types", req.EventTypes),
+		zap.Uint("user_id", userID),
+	)
+	return toSubscriptionView(subscription), nil
+}
+
+// UpdateSubscription 修改事件订阅的地址、事件类型、密钥或启用状态
+func (d *WebhookDispatcher) UpdateSubscription(ctx context.Context, id uint, req *WebhookSubscriptionRequest, userID uint) (*WebhookSubscriptionView, error) {
+	if err := d.engine.checkPermission(ctx, userID, config.PermissionWebhookManage, "修改事件订阅"); err != nil {
+		return nil, err
+	}
+	subscription, err := d.getSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := subscription.Secret
+	if req.Secret != "" {
+		secret = req.Secret
+	}
+	if err := validateWebhookSubscription(req.URL, req.EventTypes, secret); err != nil {
+		return nil, err
+	}
+
+	subscription.URL = req.URL
+	subscription.Secret = secret
+	subscription.SetEventTypes(req.EventTypes)
+	if req.Active != nil {
+		subscription.Active = *req.Active
+	}
+	if err := d.subRepo.Update(ctx, subscription); err != nil {
+		return nil, err
+	}
+
+	d.logger.Info("Webhook subscription updated",
+		zap.Uint("subscription_id", subscription.ID),
+		zap.Bool("active", subscription.Active),
+		zap.Uint("user_id", userID),
+	)
+	return toSubscriptionView(subscription), nil
+}
+
+// DeleteSubscription 删除事件订阅，已有的投递记录保留
+func (d *WebhookDispatcher) DeleteSubscription(ctx context.Context, id, userID uint) error {
+	if err := d.engine.checkPermission(ctx, userID, config.PermissionWebhookManage, "删除事件订阅"); err != nil {
+		return err
+	}
+	if _, err := d.getSubscription(ctx, id); err != nil {
+		return err
+	}
+	if err := d.subRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	d.logger.Info("Webhook subscription deleted",
+		zap.Uint("subscription_id", id),
+		zap.Uint("user_id", userID),
+	)
+	return nil
+}
+
+// ListDeliveries 分页获取事件订阅的投递记录
+func (d *WebhookDispatcher) ListDeliveries(ctx context.Context, id, userID uint, offset, limit int) ([]model.WebhookDelivery, int64, error) {
+	if err := d.engine.checkPermission(ctx, userID, config.PermissionWebhookManage, "查看事件订阅的投递记录"); err != nil {
+		return nil, 0, err
+	}
+	if _, err := d.getSubscription(ctx, id); err != nil {
+		return nil, 0, err
+	}
+	return d.subRepo.ListDeliveries(ctx, id, offset, limit)
+}
+
+// getSubscription 获取事件订阅，不存在时返回 ErrWebhookSubscriptionNotFound
+func (d *WebhookDispatcher) getSubscription(ctx context.Context, id uint) (*model.WebhookSubscription, error) {
+	subscription, err := d.subRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookSubscriptionNotFound
+		}
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// toSubscriptionView 转换为不含签名密钥的返回数据
+func toSubscriptionView(subscription *model.WebhookSubscription) *WebhookSubscriptionView {
+	return &WebhookSubscriptionView{
+		ID:         subscription.ID,
+		URL:        subscription.URL,
+		EventTypes: subscription.GetEventTypes(),
+		HasSecret:  subscription.Secret != "",
+		Active:     subscription.Active,
+		CreatedBy:  subscription.CreatedBy,
+		CreatedAt:  subscription.CreatedAt,
+		UpdatedAt:  subscription.UpdatedAt,
+	}
+}
+
+// validateWebhookSubscription 校验回调地址、事件类型和密钥
+func validateWebhookSubscription(rawURL string, eventTypes []string, secret string) error {
+	if rawURL == "" || len(rawURL) > webhookSubscriptionMaxURL {
+		return fmt.Errorf("%w: 回调地址不能为空且不超过%d个字符", ErrInvalidWebhookSubscription, webhookSubscriptionMaxURL)
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%w: 回调地址必须是 http 或 https 地址", ErrInvalidWebhookSubscription)
+	}
+	if len(secret) > webhookSubscriptionMaxSecret {
+		return fmt.Errorf("%w: 签名密钥不能超过%d个字符", ErrInvalidWebhookSubscription, webhookSubscriptionMaxSecret)
+	}
+	if len(eventTypes) == 0 {
+		return fmt.Errorf("%w: 至少需要订阅一种事件类型", ErrInvalidWebhookSubscription)
+	}
+	for _, eventType := range eventTypes {
+		if err := EventTypes.Validate(eventType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
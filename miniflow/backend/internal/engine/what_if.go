@@ -0,0 +1,260 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"miniflow/internal/model"
+)
+
+// 假设分析的样本数量限制
+const (
+	defaultWhatIfSampleSize = 500
+	maxWhatIfSampleSize     = 5000
+)
+
+// WhatIfRequest 假设分析请求
+type WhatIfRequest struct {
+	BaselineDefinitionID uint `json:"baseline_definition_id"`
+	Limit                int  `json:"limit"`
+}
+
+// WhatIfNodeStat 单个节点在基线版本和新版本下的命中统计
+type WhatIfNodeStat struct {
+	NodeID        string  `json:"node_id"`
+	NodeName      string  `json:"node_name"`
+	NodeType      string  `json:"node_type"`
+	Presence      string  `json:"presence"` // both, added, removed
+	BaselineCount int     `json:"baseline_count"`
+	ProposedCount int     `json:"proposed_count"`
+	BaselineRate  float64 `json:"baseline_rate"`
+	ProposedRate  float64 `json:"proposed_rate"`
+	Change        float64 `json:"change"` // 百分点
+}
+
+// WhatIfReport 假设分析报告
+type WhatIfReport struct {
+	ProposedDefinitionID uint             `json:"proposed_definition_id"`
+	BaselineDefinitionID uint             `json:"baseline_definition_id"`
+	SampleSize           int              `json:"sample_size"`
+	SkippedInstances     int              `json:"skipped_instances"`
+	BaselineUnrouted     int              `json:"baseline_unrouted"`
+	ProposedUnrouted     int              `json:"proposed_unrouted"`
+	Nodes                []WhatIfNodeStat `json:"nodes"`
+	Summary              []string         `json:"summary"`
+}
+
+// AnalyzeWhatIf 用历史实例记录的变量模拟新版本的网关路由，报告路由分布的变化
+//
+// 基线版本和新版本使用相同的变量分别模拟，结果可以直接对比。模拟使用实例当前
+// 保存的变量（即流程结束时的值），与网关实际执行时的值可能存在差异。
+func (e *ProcessEngine) AnalyzeWhatIf(ctx context.Context, proposedID uint, req *WhatIfRequest) (*WhatIfReport, error) {
+	proposed, err := e.processRepo.GetByID(ctx, proposedID)
+	if err != nil {
+		return nil, fmt.Errorf("流程定义不存在: %w", err)
+	}
+
+	baseline, err := e.resolveWhatIfBaseline(ctx, proposed, req.BaselineDefinitionID)
+	if err != nil {
+		return nil, err
+	}
+
+	proposedData, err := proposed.GetDefinitionData()
+	if err != nil {
+		return nil, fmt.Errorf("解析新版本流程定义失败: %v", err)
+	}
+	baselineData, err := baseline.GetDefinitionData()
+	if err != nil {
+		return nil, fmt.Errorf("解析基线流程定义失败: %v", err)
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultWhatIfSampleSize
+	}
+	if limit > maxWhatIfSampleSize {
+		limit = maxWhatIfSampleSize
+	}
+
+	instances, _, err := e.instanceRepo.List(ctx, 0, limit, map[string]interface{}{
+		"definition_id": baseline.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取历史实例失败: %w", err)
+	}
+
+	report := &WhatIfReport{
+		ProposedDefinitionID: proposed.ID,
+		BaselineDefinitionID: baseline.ID,
+		Nodes:                []WhatIfNodeStat{},
+		Summary:              []string{},
+	}
+	baselineHits := make(map[string]int)
+	proposedHits := make(map[string]int)
+
+	for i := range instances {
+		variables := make(map[string]interface{})
+		if instances[i].Variables != "" {
+			if err := json.Unmarshal([]byte(instances[i].Variables), &variables); err != nil {
+				report.SkippedInstances++
+				continue
+			}
+		}
+		report.SampleSize++
+
+		visited, ended := e.simulateRoute(ctx, baselineData, variables)
+		if !ended {
+			report.BaselineUnrouted++
+		}
+		for nodeID := range visited {
+			baselineHits[nodeID]++
+		}
+
+		visited, ended = e.simulateRoute(ctx, proposedData, variables)
+		if !ended {
+			report.ProposedUnrouted++
+		}
+		for nodeID := range visited {
+			proposedHits[nodeID]++
+		}
+	}
+
+	report.Nodes = buildWhatIfNodeStats(baselineData, proposedData, baselineHits, proposedHits, report.SampleSize)
+	report.Summary = summarizeWhatIf(report.Nodes)
+	return report, nil
+}
+
+// resolveWhatIfBaseline 确定对比的基线版本，未指定时使用同一流程键最新发布的版本
+func (e *ProcessEngine) resolveWhatIfBaseline(ctx context.Context, proposed *model.ProcessDefinition, baselineID uint) (*model.ProcessDefinition, error) {
+	if baselineID != 0 {
+		if baselineID == proposed.ID {
+			return nil, newEngineError(CodeInvalidRequest, nil, "基线版本不能与新版本相同")
+		}
+		baseline, err := e.processRepo.GetByID(ctx, baselineID)
+		if err != nil {
+			return nil, fmt.Errorf("基线流程定义不存在: %w", err)
+		}
+		return baseline, nil
+	}
+
+	baseline, err := e.processRepo.GetLatestPublishedByKey(ctx, proposed.Key)
+	if err != nil || baseline.ID == proposed.ID {
+		return nil, newEngineError(CodeInvalidRequest, nil, "没有可对比的已发布版本，请指定基线版本")
+	}
+	return baseline, nil
+}
+
+// simulateRoute 按给定变量从开始节点模拟流转，返回经过的节点以及是否到达结束节点
+func (e *ProcessEngine) simulateRoute(ctx context.Context, definition *model.ProcessDefinitionData, variables map[string]interface{}) (map[string]bool, bool) {
+	visited := make(map[string]bool)
+	start := e.findStartNode(definition.Nodes)
+	if start == nil {
+		return visited, false
+	}
+
+	ended := false
+	queue := []string{start.ID}
+	for len(queue) > 0 {
+		nodeID := queue[0]
+		queue = queue[1:]
+
+		// 每个节点只访问一次，避免回退连线造成死循环
+		if visited[nodeID] {
+			continue
+		}
+		node := e.findNodeByID(definition.Nodes, nodeID)
+		if node == nil {
+			continue
+		}
+		visited[nodeID] = true
+
+		switch node.Type {
+		case "end":
+			ended = true
+		case "gateway":
+			nextNodeIDs, _ := e.evaluateGatewayConditions(ctx, node, definition.Flows, variables, nil)
+			queue = append(queue, nextNodeIDs...)
+		default:
+			for _, flow := range e.findOutgoingFlows(definition.Flows, nodeID) {
+				queue = append(queue, flow.To)
+			}
+		}
+	}
+
+	return visited, ended
+}
+
+// buildWhatIfNodeStats 合并两个版本的节点，计算命中率变化
+func buildWhatIfNodeStats(baseline, proposed *model.ProcessDefinitionData, baselineHits, proposedHits map[string]int, sampleSize int) []WhatIfNodeStat {
+	statsByID := make(map[string]*WhatIfNodeStat)
+	var order []string
+
+	for _, node := range baseline.Nodes {
+		statsByID[node.ID] = &WhatIfNodeStat{NodeID: node.ID, NodeName: node.Name, NodeType: node.Type, Presence: "removed"}
+		order = append(order, node.ID)
+	}
+	for _, node := range proposed.Nodes {
+		if stat, ok := statsByID[node.ID]; ok {
+			stat.Presence = "both"
+			stat.NodeName = node.Name
+			stat.NodeType = node.Type
+			continue
+		}
+		statsByID[node.ID] = &WhatIfNodeStat{NodeID: node.ID, NodeName: node.Name, NodeType: node.Type, Presence: "added"}
+		order = append(order, node.ID)
+	}
+
+	stats := make([]WhatIfNodeStat, 0, len(order))
+	for _, nodeID := range order {
+		stat := statsByID[nodeID]
+		stat.BaselineCount = baselineHits[nodeID]
+		stat.ProposedCount = proposedHits[nodeID]
+		stat.BaselineRate = hitRate(stat.BaselineCount, sampleSize)
+		stat.ProposedRate = hitRate(stat.ProposedCount, sampleSize)
+		stat.Change = roundRate(stat.ProposedRate - stat.BaselineRate)
+		stats = append(stats, *stat)
+	}
+
+	sort.SliceStable(stats, func(i, j int) bool {
+		return math.Abs(stats[i].Change) > math.Abs(stats[j].Change)
+	})
+	return stats
+}
+
+// summarizeWhatIf 为命中率变化明显的业务节点生成说明
+func summarizeWhatIf(stats []WhatIfNodeStat) []string {
+	summary := []string{}
+	for _, stat := range stats {
+		if stat.NodeType == "start" || stat.NodeType == "gateway" || math.Abs(stat.Change) < 1 {
+			continue
+		}
+
+		name := stat.NodeName
+		if name == "" {
+			name = stat.NodeID
+		}
+		direction := "增加"
+		if stat.Change < 0 {
+			direction = "减少"
+		}
+		summary = append(summary, fmt.Sprintf("经过「%s」的实例将%s %.1f 个百分点（%.1f%% → %.1f%%）",
+			name, direction, math.Abs(stat.Change), stat.BaselineRate, stat.ProposedRate))
+	}
+	return summary
+}
+
+// hitRate 计算百分比命中率，保留一位小数
+func hitRate(count, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return roundRate(float64(count) * 100 / float64(total))
+}
+
+// roundRate 保留一位小数
+func roundRate(value float64) float64 {
+	return math.Round(value*10) / 10
+}
@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"miniflow/internal/middleware"
+	"miniflow/internal/service"
+	"miniflow/pkg/logger"
+	"miniflow/pkg/pagination"
+	"miniflow/pkg/utils"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// AnnouncementHandler handles announcement-related HTTP requests
+type AnnouncementHandler struct {
+	announcementService *service.AnnouncementService
+	logger              *logger.Logger
+	validator           *utils.CustomValidator
+}
+
+// NewAnnouncementHandler creates a new announcement handler
+func NewAnnouncementHandler(announcementService *service.AnnouncementService, logger *logger.Logger) *AnnouncementHandler {
+	return &AnnouncementHandler{
+		announcementService: announcementService,
+		logger:              logger,
+		validator:           utils.NewCustomValidator(),
+	}
+}
+
+// CreateAnnouncement handles broadcasting a new announcement (admin only)
+func (h *AnnouncementHandler) CreateAnnouncement(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "用户认证信息无效",
+			"code":  "INVALID_USER_CONTEXT",
+		})
+	}
+
+	var req service.CreateAnnouncementRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn("Invalid request body for announcement", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数格式错误",
+			"code":  "INVALID_REQUEST_FORMAT",
+		})
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		h.logger.Warn("Announcement validation failed", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数验证失败",
+			"code":  "VALIDATION_FAILED",
+		})
+	}
+
+	announcement, err := h.announcementService.CreateAnnouncement(ctx, &req, userID)
+	if err != nil {
+		h.logger.Warn("Failed to create announcement", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "CREATE_ANNOUNCEMENT_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message": "公告发布成功",
+		"data":    announcement,
+	})
+}
+
+// ListAnnouncements handles listing all announcements (admin only)
+func (h *AnnouncementHandler) ListAnnouncements(c echo.Context) error {
+	ctx := c.Request().Context()
+	pageReq, err := pagination.Parse(c.QueryParams(), pagination.Default)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "INVALID_PAGINATION",
+		})
+	}
+
+	announcements, total, err := h.announcementService.ListAnnouncements(ctx, pageReq.Page, pageReq.PageSize)
+	if err != nil {
+		h.logger.Error("Failed to list announcements", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+			"code":  "LIST_ANNOUNCEMENTS_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "获取公告列表成功",
+		"data":    pageReq.Result("announcements", announcements, total),
+	})
+}
+
+// DeleteAnnouncement handles withdrawing an announcement (admin only)
+func (h *AnnouncementHandler) DeleteAnnouncement(c echo.Context) error {
+	ctx := c.Request().Context()
+	announcementID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "无效的公告ID",
+			"code":  "INVALID_ANNOUNCEMENT_ID",
+		})
+	}
+
+	if err := h.announcementService.DeleteAnnouncement(ctx, uint(announcementID)); err != nil {
+		h.logger.Warn("Failed to delete announcement",
+			zap.Uint("announcement_id", uint(announcementID)),
+			zap.Error(err),
+		)
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "DELETE_ANNOUNCEMENT_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "公告已撤回",
+	})
+}
+
+// GetActiveAnnouncements handles getting the announcements visible to the current user
+func (h *AnnouncementHandler) GetActiveAnnouncements(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "用户认证信息无效",
+			"code":  "INVALID_USER_CONTEXT",
+		})
+	}
+
+	announcements, err := h.announcementService.GetActiveAnnouncements(ctx, userID)
+	if err != nil {
+		h.logger.Error("Failed to get active announcements",
+			zap.Uint("user_id", userID),
+			zap.Error(err),
+		)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+			"code":  "GET_ANNOUNCEMENTS_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "获取公告成功",
+		"data":    announcements,
+	})
+}
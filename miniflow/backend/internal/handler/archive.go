@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"miniflow/internal/engine"
+	"miniflow/internal/model"
+	"miniflow/pkg/logger"
+	"miniflow/pkg/pagination"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// ArchiveHandler 流程实例归档API处理器
+type ArchiveHandler struct {
+	archiver *engine.InstanceArchiver
+	logger   *logger.Logger
+}
+
+// NewArchiveHandler 创建流程实例归档处理器
+func NewArchiveHandler(archiver *engine.InstanceArchiver, logger *logger.Logger) *ArchiveHandler {
+	return &ArchiveHandler{
+		archiver: archiver,
+		logger:   logger,
+	}
+}
+
+// ListArchives 获取归档记录，支持按 definition_key、business_key、starter_id、final_status 过滤
+// GET /api/v1/admin/archives
+func (h *ArchiveHandler) ListArchives(c echo.Context) error {
+	ctx := c.Request().Context()
+	pageReq, err := pagination.Parse(c.QueryParams(), pagination.Default)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	filters := make(map[string]interface{})
+	for _, key := range []string{"definition_key", "business_key"} {
+		if value := c.QueryParam(key); value != "" {
+			filters[key] = value
+		}
+	}
+	if status := c.QueryParam("final_status"); status != "" {
+		if httpErr := validateEnumParam(model.InstanceStatuses, status); httpErr != nil {
+			return httpErr
+		}
+		filters["final_status"] = status
+	}
+	if raw := c.QueryParam("starter_id"); raw != "" {
+		starterID, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid starter_id")
+		}
+		filters["starter_id"] = uint(starterID)
+	}
+
+	archives, total, err := h.archiver.ListArchives(ctx, getUserIDFromContext(c), pageReq.Offset(), pageReq.Limit(), filters)
+	if err != nil {
+		h.logger.Error("Failed to list instance archives", zap.Error(err))
+		return engineHTTPError("Failed to list instance archives: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    pageReq.Result("archives", archives, total),
+	})
+}
+
+// ArchiveInstance 立即归档已结束的顶层实例及其子实例，不等待保留期限
+// POST /api/v1/admin/archives/:id
+func (h *ArchiveHandler) ArchiveInstance(c echo.Context) error {
+	ctx := c.Request().Context()
+	instanceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid instance ID")
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	archives, err := h.archiver.ArchiveInstance(ctx, uint(instanceID), userID)
+	if err != nil {
+		h.logger.Error("Failed to archive instance", zap.Uint("instance_id", uint(instanceID)), zap.Error(err))
+		return engineHTTPError("Failed to archive instance: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data": map[string]interface{}{
+			"archives": archives,
+		},
+	})
+}
+
+// GetArchivedHistory 读取已归档实例的执行历史，返回归档时导出的 JSON 文件
+// GET /api/v1/instance/:id/archive
+func (h *ArchiveHandler) GetArchivedHistory(c echo.Context) error {
+	ctx := c.Request().Context()
+	instanceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid instance ID")
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	archive, content, err := h.archiver.OpenArchivedHistory(ctx, uint(instanceID), userID)
+	if err != nil {
+		h.logger.Error("Failed to open archived history", zap.Uint("instance_id", uint(instanceID)), zap.Error(err))
+		return engineHTTPError("Failed to get archived history: ", err)
+	}
+	defer content.Close()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentLength, strconv.FormatInt(archive.Size, 10))
+	res.Header().Set("X-Checksum-Sha256", archive.Checksum)
+	return c.Stream(http.StatusOK, echo.MIMEApplicationJSON, content)
+}
@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+
+	"miniflow/internal/engine"
+	"miniflow/internal/model"
+	"miniflow/pkg/logger"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// AttachmentHandler 附件API处理器，处理流程实例和任务附件的上传、下载和删除
+type AttachmentHandler struct {
+	attachments *engine.AttachmentManager
+	logger      *logger.Logger
+}
+
+// NewAttachmentHandler 创建附件处理器
+func NewAttachmentHandler(attachments *engine.AttachmentManager, logger *logger.Logger) *AttachmentHandler {
+	return &AttachmentHandler{
+		attachments: attachments,
+		logger:      logger,
+	}
+}
+
+// UploadInstanceAttachment 上传流程实例的附件，multipart 表单字段为 file
+// POST /api/v1/instance/:id/attachments
+func (h *AttachmentHandler) UploadInstanceAttachment(c echo.Context) error {
+	ctx := c.Request().Context()
+	instanceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid instance ID")
+	}
+	return h.upload(c, func(userID uint, upload *engine.AttachmentUpload) (*model.Attachment, error) {
+		return h.attachments.UploadInstanceAttachment(ctx, uint(instanceID), userID, upload)
+	})
+}
+
+// UploadTaskAttachment 上传任务的附件，multipart 表单字段为 file
+// POST /api/v1/task/:id/attachments
+func (h *AttachmentHandler) UploadTaskAttachment(c echo.Context) error {
+	ctx := c.Request().Context()
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid task ID")
+	}
+	return h.upload(c, func(userID uint, upload *engine.AttachmentUpload) (*model.Attachment, error) {
+		return h.attachments.UploadTaskAttachment(ctx, uint(taskID), userID, upload)
+	})
+}
+
+// upload 读取 multipart 表单中的文件并交给 save 保存
+func (h *AttachmentHandler) upload(c echo.Context, save func(userID uint, upload *engine.AttachmentUpload) (*model.Attachment, error)) error {
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Missing file")
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid file")
+	}
+	defer file.Close()
+
+	attachment, err := save(userID, &engine.AttachmentUpload{
+		FileName: fileHeader.Filename,
+		Size:     fileHeader.Size,
+		Content:  file,
+	})
+	if err != nil {
+		h.logger.Error("Failed to upload attachment",
+			zap.String("file_name", fileHeader.Filename),
+			zap.Int64("size", fileHeader.Size),
+			zap.Uint("user_id", userID),
+			zap.Error(err),
+		)
+		return engineHTTPError("Failed to upload attachment: ", err)
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"data":    attachment,
+	})
+}
+
+// GetInstanceAttachments 获取流程实例的附件，包括实例下各任务的附件
+// GET /api/v1/instance/:id/attachments
+func (h *AttachmentHandler) GetInstanceAttachments(c echo.Context) error {
+	ctx := c.Request().Context()
+	instanceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid instance ID")
+	}
+
+	attachments, err := h.attachments.GetInstanceAttachments(ctx, uint(instanceID))
+	if err != nil {
+		h.logger.Error("Failed to get instance attachments", zap.Uint("instance_id", uint(instanceID)), zap.Error(err))
+		return engineHTTPError("Failed to get instance attachments: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    attachments,
+	})
+}
+
+// GetTaskAttachments 获取任务的附件
+// GET /api/v1/task/:id/attachments
+func (h *AttachmentHandler) GetTaskAttachments(c echo.Context) error {
+	ctx := c.Request().Context()
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid task ID")
+	}
+
+	attachments, err := h.attachments.GetTaskAttachments(ctx, uint(taskID))
+	if err != nil {
+		h.logger.Error("Failed to get task attachments", zap.Uint("task_id", uint(taskID)), zap.Error(err))
+		return engineHTTPError("Failed to get task attachments: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    attachments,
+	})
+}
+
+// DownloadAttachment 下载附件内容
+// GET /api/v1/attachments/:id/download
+func (h *AttachmentHandler) DownloadAttachment(c echo.Context) error {
+	ctx := c.Request().Context()
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid attachment ID")
+	}
+
+	attachment, content, err := h.attachments.OpenAttachment(ctx, uint(id))
+	if err != nil {
+		h.logger.Error("Failed to open attachment", zap.Uint("attachment_id", uint(id)), zap.Error(err))
+		return engineHTTPError("Failed to download attachment: ", err)
+	}
+	defer content.Close()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+	res.Header().Set(echo.HeaderContentLength, strconv.FormatInt(attachment.Size, 10))
+	res.Header().Set("X-Checksum-Sha256", attachment.Checksum)
+	return c.Stream(http.StatusOK, attachment.ContentType, content)
+}
+
+// DeleteAttachment 删除附件，上传人和有 content_moderate 权限的用户可以删除
+// DELETE /api/v1/attachments/:id
+func (h *AttachmentHandler) DeleteAttachment(c echo.Context) error {
+	ctx := c.Request().Context()
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid attachment ID")
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	if err := h.attachments.DeleteAttachment(ctx, uint(id), userID); err != nil {
+		h.logger.Error("Failed to delete attachment",
+			zap.Uint("attachment_id", uint(id)),
+			zap.Uint("user_id", userID),
+			zap.Error(err),
+		)
+		return engineHTTPError("Failed to delete attachment: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Attachment deleted successfully",
+	})
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"miniflow/internal/service"
+	"miniflow/pkg/logger"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// CapacityHandler handles capacity planning analytics HTTP requests
+type CapacityHandler struct {
+	capacityService *service.CapacityService
+	logger          *logger.Logger
+}
+
+// NewCapacityHandler creates a new capacity handler
+func NewCapacityHandler(capacityService *service.CapacityService, logger *logger.Logger) *CapacityHandler {
+	return &CapacityHandler{
+		capacityService: capacityService,
+		logger:          logger,
+	}
+}
+
+// GetCapacity handles getting the daily started/completed counts and task concurrency per definition version
+// GET /api/v1/analytics/capacity?key=&days=
+func (h *CapacityHandler) GetCapacity(c echo.Context) error {
+	ctx := c.Request().Context()
+	days := 0
+	if raw := c.QueryParam("days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "无效的统计天数",
+				"code":  "INVALID_DAYS",
+			})
+		}
+		days = parsed
+	}
+
+	series, err := h.capacityService.GetCapacity(ctx, c.QueryParam("key"), days)
+	if err != nil {
+		h.logger.Warn("Failed to get capacity analytics", zap.Int("days", days), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "GET_CAPACITY_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "获取容量统计成功",
+		"data":    series,
+	})
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"net/http"
+
+	"miniflow/internal/middleware"
+	"miniflow/internal/service"
+	"miniflow/pkg/logger"
+	"miniflow/pkg/utils"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// ConnectorPolicyHandler handles connector allowlist HTTP requests (admin)
+type ConnectorPolicyHandler struct {
+	policyService *service.ConnectorPolicyService
+	logger        *logger.Logger
+	validator     *utils.CustomValidator
+}
+
+// NewConnectorPolicyHandler creates a new connector policy handler
+func NewConnectorPolicyHandler(policyService *service.ConnectorPolicyService, logger *logger.Logger) *ConnectorPolicyHandler {
+	return &ConnectorPolicyHandler{
+		policyService: policyService,
+		logger:        logger,
+		validator:     utils.NewCustomValidator(),
+	}
+}
+
+// ListPolicies handles listing connector allowlists
+func (h *ConnectorPolicyHandler) ListPolicies(c echo.Context) error {
+	ctx := c.Request().Context()
+	policies, err := h.policyService.ListPolicies(ctx)
+	if err != nil {
+		h.logger.Error("Failed to list connector policies", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+			"code":  "LIST_CONNECTOR_POLICIES_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "获取连接器白名单成功",
+		"data":    policies,
+	})
+}
+
+// GetPolicy handles getting the connector allowlist of a definition key
+func (h *ConnectorPolicyHandler) GetPolicy(c echo.Context) error {
+	ctx := c.Request().Context()
+	policy, err := h.policyService.GetPolicy(ctx, c.Param("key"))
+	if err != nil {
+		h.logger.Error("Failed to get connector policy", zap.String("key", c.Param("key")), zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+			"code":  "GET_CONNECTOR_POLICY_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "获取连接器白名单成功",
+		"data":    policy,
+	})
+}
+
+// UpdatePolicy handles replacing the connector allowlist of a definition key
+func (h *ConnectorPolicyHandler) UpdatePolicy(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "用户认证信息无效",
+			"code":  "INVALID_USER_CONTEXT",
+		})
+	}
+
+	var req service.ConnectorPolicyRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn("Invalid request body for connector policy", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数格式错误",
+			"code":  "INVALID_REQUEST_FORMAT",
+		})
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		h.logger.Warn("Connector policy validation failed", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数验证失败",
+			"code":  "VALIDATION_FAILED",
+		})
+	}
+
+	policy, err := h.policyService.UpdatePolicy(ctx, c.Param("key"), &req, userID)
+	if err != nil {
+		h.logger.Warn("Failed to update connector policy", zap.String("key", c.Param("key")), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "UPDATE_CONNECTOR_POLICY_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "连接器白名单更新成功",
+		"data":    policy,
+	})
+}
+
+// DeletePolicy handles removing the connector allowlist of a definition key
+func (h *ConnectorPolicyHandler) DeletePolicy(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "用户认证信息无效",
+			"code":  "INVALID_USER_CONTEXT",
+		})
+	}
+
+	if err := h.policyService.DeletePolicy(ctx, c.Param("key"), userID); err != nil {
+		h.logger.Error("Failed to delete connector policy", zap.String("key", c.Param("key")), zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+			"code":  "DELETE_CONNECTOR_POLICY_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "连接器白名单已删除",
+	})
+}
@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"miniflow/internal/middleware"
+	"miniflow/internal/service"
+	"miniflow/pkg/logger"
+	"miniflow/pkg/utils"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// DeploymentHandler handles environment deployment and promotion HTTP requests
+type DeploymentHandler struct {
+	deploymentService *service.DeploymentService
+	logger            *logger.Logger
+	validator         *utils.CustomValidator
+}
+
+// NewDeploymentHandler creates a new deployment handler
+func NewDeploymentHandler(deploymentService *service.DeploymentService, logger *logger.Logger) *DeploymentHandler {
+	return &DeploymentHandler{
+		deploymentService: deploymentService,
+		logger:            logger,
+		validator:         utils.NewCustomValidator(),
+	}
+}
+
+// Deploy handles deploying a published definition version to the dev environment
+func (h *DeploymentHandler) Deploy(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "用户认证信息无效",
+			"code":  "INVALID_USER_CONTEXT",
+		})
+	}
+
+	processID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "无效的流程ID",
+			"code":  "INVALID_PROCESS_ID",
+		})
+	}
+
+	var req service.DeployRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn("Invalid request body for deployment", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数格式错误",
+			"code":  "INVALID_REQUEST_FORMAT",
+		})
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数验证失败",
+			"code":  "VALIDATION_FAILED",
+		})
+	}
+
+	deployment, err := h.deploymentService.Deploy(ctx, uint(processID), userID, &req)
+	if err != nil {
+		h.logger.Warn("Deployment failed", zap.Uint("process_id", uint(processID)), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "DEPLOY_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message": "部署成功",
+		"data":    deployment,
+	})
+}
+
+// ListDeployments handles listing the deployments of a process, optionally filtered by environment
+func (h *DeploymentHandler) ListDeployments(c echo.Context) error {
+	ctx := c.Request().Context()
+	processID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "无效的流程ID",
+			"code":  "INVALID_PROCESS_ID",
+		})
+	}
+
+	deployments, err := h.deploymentService.ListDeployments(ctx, uint(processID), c.QueryParam("environment"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "LIST_DEPLOYMENTS_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "获取部署记录成功",
+		"data":    deployments,
+	})
+}
+
+// Promote handles promoting a deployment to the next environment
+func (h *DeploymentHandler) Promote(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "用户认证信息无效",
+			"code":  "INVALID_USER_CONTEXT",
+		})
+	}
+
+	deploymentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "无效的部署ID",
+			"code":  "INVALID_DEPLOYMENT_ID",
+		})
+	}
+
+	var req service.PromoteRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn("Invalid request body for promotion", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数格式错误",
+			"code":  "INVALID_REQUEST_FORMAT",
+		})
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数验证失败",
+			"code":  "VALIDATION_FAILED",
+		})
+	}
+
+	deployment, err := h.deploymentService.Promote(ctx, uint(deploymentID), userID, &req)
+	if err != nil {
+		h.logger.Warn("Promotion failed", zap.Uint("deployment_id", uint(deploymentID)), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "PROMOTE_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message": "晋升成功",
+		"data":    deployment,
+	})
+}
+
+// ExportPackage handles exporting the package of a deployment with its checksum
+func (h *DeploymentHandler) ExportPackage(c echo.Context) error {
+	ctx := c.Request().Context()
+	deploymentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "无效的部署ID",
+			"code":  "INVALID_DEPLOYMENT_ID",
+		})
+	}
+
+	pkg, err := h.deploymentService.ExportPackage(ctx, uint(deploymentID))
+	if err != nil {
+		h.logger.Warn("Package export failed", zap.Uint("deployment_id", uint(deploymentID)), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "EXPORT_PACKAGE_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "导出部署包成功",
+		"data":    pkg,
+	})
+}
+
+// ImportPackage handles importing a package exported by another installation
+func (h *DeploymentHandler) ImportPackage(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "用户认证信息无效",
+			"code":  "INVALID_USER_CONTEXT",
+		})
+	}
+
+	var req service.ImportPackageRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn("Invalid request body for package import", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数格式错误",
+			"code":  "INVALID_REQUEST_FORMAT",
+		})
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数验证失败",
+			"code":  "VALIDATION_FAILED",
+		})
+	}
+
+	deployment, err := h.deploymentService.ImportPackage(ctx, userID, &req)
+	if err != nil {
+		h.logger.Warn("Package import failed", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "IMPORT_PACKAGE_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message": "导入部署包成功",
+		"data":    deployment,
+	})
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"miniflow/internal/engine"
+	"miniflow/pkg/logger"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// ExternalTaskHandler 外部任务API处理器，供流程引擎之外的处理程序获取并完成外部服务任务
+type ExternalTaskHandler struct {
+	engine *engine.ProcessEngine
+	logger *logger.Logger
+}
+
+// NewExternalTaskHandler 创建外部任务处理器
+func NewExternalTaskHandler(engine *engine.ProcessEngine, logger *logger.Logger) *ExternalTaskHandler {
+	return &ExternalTaskHandler{
+		engine: engine,
+		logger: logger,
+	}
+}
+
+// FetchAndLockRequest 获取并锁定外部任务请求，max_tasks 默认为 1
+type FetchAndLockRequest struct {
+	WorkerID            string   `json:"worker_id" validate:"required,max=128"`
+	Topics              []string `json:"topics" validate:"required,min=1"`
+	MaxTasks            int      `json:"max_tasks"`
+	LockDurationSeconds int      `json:"lock_duration_seconds" validate:"required,min=1"`
+}
+
+// CompleteExternalTaskRequest 完成外部任务请求，variables 合并到流程变量
+type CompleteExternalTaskRequest struct {
+	WorkerID  string                 `json:"worker_id" validate:"required,max=128"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+// ExternalTaskFailureRequest 外部任务失败请求，retries 为剩余重试次数，省略时在当前次数上减一
+type ExternalTaskFailureRequest struct {
+	WorkerID            string `json:"worker_id" validate:"required,max=128"`
+	ErrorMessage        string `json:"error_message" validate:"required"`
+	Retries             *int   `json:"retries" validate:"omitempty,min=0"`
+	RetryTimeoutSeconds int    `json:"retry_timeout_seconds" validate:"min=0"`
+}
+
+// FetchAndLock 获取并锁定指定主题下的外部任务，没有可获取的任务时返回空数组
+// POST /api/v1/external-tasks/fetch-and-lock
+func (h *ExternalTaskHandler) FetchAndLock(c echo.Context) error {
+	ctx := c.Request().Context()
+	var req FetchAndLockRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if req.MaxTasks == 0 {
+		req.MaxTasks = 1
+	}
+
+	tasks, err := h.engine.FetchAndLockExternalTasks(ctx, req.WorkerID, req.Topics, req.MaxTasks, time.Duration(req.LockDurationSeconds)*time.Second)
+	if err != nil {
+		h.logger.Error("Failed to fetch external tasks", zap.String("worker_id", req.WorkerID), zap.Error(err))
+		return engineHTTPError("Failed to fetch external tasks: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    tasks,
+	})
+}
+
+// Complete 完成外部处理程序锁定的任务并推进流程
+// POST /api/v1/external-tasks/:id/complete
+func (h *ExternalTaskHandler) Complete(c echo.Context) error {
+	ctx := c.Request().Context()
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid task ID")
+	}
+
+	var req CompleteExternalTaskRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.engine.CompleteExternalTask(ctx, uint(taskID), req.WorkerID, req.Variables); err != nil {
+		h.logger.Error("Failed to complete external task",
+			zap.Uint64("task_id", taskID),
+			zap.String("worker_id", req.WorkerID),
+			zap.Error(err),
+		)
+		return engineHTTPError("Failed to complete external task: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "External task completed successfully",
+	})
+}
+
+// Failure 报告外部任务失败，还有重试次数时稍后可再次获取，否则生成异常事件
+// POST /api/v1/external-tasks/:id/failure
+func (h *ExternalTaskHandler) Failure(c echo.Context) error {
+	ctx := c.Request().Context()
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid task ID")
+	}
+
+	var req ExternalTaskFailureRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	retryTimeout := time.Duration(req.RetryTimeoutSeconds) * time.Second
+	if err := h.engine.HandleExternalTaskFailure(ctx, uint(taskID), req.WorkerID, req.ErrorMessage, req.Retries, retryTimeout); err != nil {
+		h.logger.Error("Failed to report external task failure",
+			zap.Uint64("task_id", taskID),
+			zap.String("worker_id", req.WorkerID),
+			zap.Error(err),
+		)
+		return engineHTTPError("Failed to report external task failure: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "External task failure recorded",
+	})
+}
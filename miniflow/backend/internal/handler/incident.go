@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"miniflow/internal/engine"
+	"miniflow/internal/model"
+	"miniflow/pkg/logger"
+	"miniflow/pkg/pagination"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// IncidentHandler 异常事件API处理器
+type IncidentHandler struct {
+	engine *engine.ProcessEngine
+	logger *logger.Logger
+}
+
+// NewIncidentHandler 创建异常事件处理器
+func NewIncidentHandler(engine *engine.ProcessEngine, logger *logger.Logger) *IncidentHandler {
+	return &IncidentHandler{
+		engine: engine,
+		logger: logger,
+	}
+}
+
+// GetIncidents 获取异常事件列表
+// GET /api/v1/admin/incidents
+func (h *IncidentHandler) GetIncidents(c echo.Context) error {
+	ctx := c.Request().Context()
+	pageReq, err := pagination.Parse(c.QueryParams(), pagination.Default)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	filters := make(map[string]interface{})
+	if status := c.QueryParam("status"); status != "" {
+		if err := validateEnumParam(model.IncidentStatuses, status); err != nil {
+			return err
+		}
+		filters["status"] = status
+	}
+	if incidentType := c.QueryParam("type"); incidentType != "" {
+		if err := validateEnumParam(model.IncidentTypes, incidentType); err != nil {
+			return err
+		}
+		filters["type"] = incidentType
+	}
+	if code := c.QueryParam("code"); code != "" {
+		filters["code"] = code
+	}
+	if instanceID := c.QueryParam("instance_id"); instanceID != "" {
+		if id, err := strconv.ParseUint(instanceID, 10, 32); err == nil {
+			filters["instance_id"] = uint(id)
+		}
+	}
+
+	incidents, total, err := h.engine.GetIncidents(ctx, pageReq.Offset(), pageReq.Limit(), filters)
+	if err != nil {
+		h.logger.Error("Failed to get incidents", zap.Error(err))
+		return engineHTTPError("Failed to get incidents: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    pageReq.Result("incidents", incidents, total),
+	})
+}
+
+// ResolveIncident 手动关闭异常事件
+// POST /api/v1/admin/incidents/:id/resolve
+func (h *IncidentHandler) ResolveIncident(c echo.Context) error {
+	ctx := c.Request().Context()
+	incidentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid incident ID")
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	incident, err := h.engine.ResolveIncident(ctx, uint(incidentID), userID)
+	if err != nil {
+		h.logger.Error("Failed to resolve incident", zap.Uint64("incident_id", incidentID), zap.Error(err))
+		return engineHTTPError("Failed to resolve incident: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    incident,
+	})
+}
+
+// RetryIncident 重新执行异常事件所在节点
+// POST /api/v1/admin/incidents/:id/retry
+func (h *IncidentHandler) RetryIncident(c echo.Context) error {
+	ctx := c.Request().Context()
+	incidentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid incident ID")
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	incident, err := h.engine.RetryIncident(ctx, uint(incidentID), userID)
+	if err != nil {
+		h.logger.Error("Failed to retry incident", zap.Uint64("incident_id", incidentID), zap.Error(err))
+		return engineHTTPError("Failed to retry incident: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    incident,
+	})
+}
+
+// GetFailureCodes 获取引擎失败代码目录，界面和告警按代码处理失败
+// GET /api/v1/error-codes
+func (h *IncidentHandler) GetFailureCodes(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    engine.FailureCatalog,
+	})
+}
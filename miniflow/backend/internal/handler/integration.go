@@ -0,0 +1,435 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"miniflow/internal/engine"
+	"miniflow/internal/model"
+	"miniflow/pkg/logger"
+	"miniflow/pkg/pagination"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// integrationPagination 集成API的轮询数量限制
+var integrationPagination = pagination.Options{DefaultPageSize: 50, MaxPageSize: 100}
+
+// IntegrationHandler 面向Zapier等低代码平台的集成API处理器
+// 只暴露稳定的扁平化数据结构，字段变更需要保持向后兼容
+type IntegrationHandler struct {
+	engine *engine.ProcessEngine
+	logger *logger.Logger
+}
+
+// NewIntegrationHandler 创建集成API处理器
+func NewIntegrationHandler(engine *engine.ProcessEngine, logger *logger.Logger) *IntegrationHandler {
+	return &IntegrationHandler{
+		engine: engine,
+		logger: logger,
+	}
+}
+
+// IntegrationTask 扁平化的任务数据
+type IntegrationTask struct {
+	ID             uint       `json:"id"`
+	Name           string     `json:"name"`
+	NodeID         string     `json:"node_id"`
+	Status         string     `json:"status"`
+	Priority       int        `json:"priority"`
+	DueDate        *time.Time `json:"due_date"`
+	CreatedAt      time.Time  `json:"created_at"`
+	AssigneeID     *uint      `json:"assignee_id"`
+	InstanceID     uint       `json:"instance_id"`
+	BusinessKey    string     `json:"business_key"`
+	ProcessKey     string     `json:"process_key"`
+	ProcessName    string     `json:"process_name"`
+	ProcessVersion int        `json:"process_version"`
+}
+
+// IntegrationTriggerResponse 轮询触发器响应
+type IntegrationTriggerResponse struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"next_cursor"`
+}
+
+// IntegrationStartProcessRequest 启动流程动作请求
+type IntegrationStartProcessRequest struct {
+	ProcessKey  string                 `json:"process_key" validate:"required"`
+	BusinessKey string                 `json:"business_key" validate:"required,min=1,max=255"`
+	Variables   map[string]interface{} `json:"variables"`
+	DueDate     *time.Time             `json:"due_date"`
+}
+
+// IntegrationCompleteTaskRequest 完成任务动作请求
+type IntegrationCompleteTaskRequest struct {
+	TaskID    uint                   `json:"task_id" validate:"required"`
+	Comment   string                 `json:"comment"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+// IntegrationCatalogEntry 触发器/动作目录项
+type IntegrationCatalogEntry struct {
+	Key         string   `json:"key"`
+	Type        string   `json:"type"`
+	Label       string   `json:"label"`
+	Description string   `json:"description"`
+	Method      string   `json:"method"`
+	Path        string   `json:"path"`
+	InputFields []string `json:"input_fields,omitempty"`
+	SamplePath  string   `json:"sample_path"`
+}
+
+// integrationCatalog 集成目录
+var integrationCatalog = []IntegrationCatalogEntry{
+	{
+		Key:         "new_task",
+		Type:        "trigger",
+		Label:       "New Task",
+		Description: "Triggers when a new task is assigned to or claimable by the user.",
+		Method:      http.MethodGet,
+		Path:        "/api/integrations/v1/triggers/new-tasks",
+		InputFields: []string{"cursor", "limit"},
+		SamplePath:  "/api/integrations/v1/samples/new_task",
+	},
+	{
+		Key:         "instance_completed",
+		Type:        "trigger",
+		Label:       "Process Completed",
+		Description: "Triggers when a process instance started by the user completes.",
+		Method:      http.MethodGet,
+		Path:        "/api/integrations/v1/triggers/completed-instances",
+		InputFields: []string{"cursor", "limit"},
+		SamplePath:  "/api/integrations/v1/samples/instance_completed",
+	},
+	{
+		Key:         "start_process",
+		Type:        "action",
+		Label:       "Start Process",
+		Description: "Starts the latest published version of a process.",
+		Method:      http.MethodPost,
+		Path:        "/api/integrations/v1/actions/start-process",
+		InputFields: []string{"process_key", "business_key", "variables"},
+		SamplePath:  "/api/integrations/v1/samples/start_process",
+	},
+	{
+		Key:         "complete_task",
+		Type:        "action",
+		Label:       "Complete Task",
+		Description: "Claims the task if needed and completes it.",
+		Method:      http.MethodPost,
+		Path:        "/api/integrations/v1/actions/complete-task",
+		InputFields: []string{"task_id", "comment", "variables"},
+		SamplePath:  "/api/integrations/v1/samples/complete_task",
+	},
+}
+
+// integrationSamples 静态示例数据，供集成平台在没有真实数据时配置字段映射
+var integrationSamples = map[string]interface{}{
+	"new_task": IntegrationTask{
+		ID:             1024,
+		Name:           "Manager Approval",
+		NodeID:         "approve",
+		Status:         model.TaskStatusAssigned,
+		Priority:       50,
+		CreatedAt:      time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC),
+		InstanceID:     256,
+		BusinessKey:    "LEAVE-2025-0001",
+		ProcessKey:     "leave_request",
+		ProcessName:    "Leave Request",
+		ProcessVersion: 3,
+	},
+	"instance_completed": map[string]interface{}{
+		"id":              256,
+		"business_key":    "LEAVE-2025-0001",
+		"process_key":     "leave_request",
+		"process_name":    "Leave Request",
+		"process_version": 3,
+		"status":          model.InstanceStatusCompleted,
+		"current_node":    "end",
+		"starter_id":      1,
+		"start_time":      time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC),
+		"end_time":        time.Date(2025, 1, 2, 17, 30, 0, 0, time.UTC),
+		"var_days":        3,
+		"var_approved":    true,
+	},
+	"start_process": map[string]interface{}{
+		"id":              256,
+		"business_key":    "LEAVE-2025-0001",
+		"process_key":     "leave_request",
+		"process_name":    "Leave Request",
+		"process_version": 3,
+		"status":          model.InstanceStatusRunning,
+		"current_node":    "approve",
+		"starter_id":      1,
+		"start_time":      time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC),
+		"end_time":        nil,
+	},
+	"complete_task": map[string]interface{}{
+		"task_id":     1024,
+		"instance_id": 256,
+		"status":      model.TaskStatusCompleted,
+	},
+}
+
+// GetMe 返回当前认证用户，供集成平台测试连接
+// GET /api/integrations/v1/me
+func (h *IntegrationHandler) GetMe(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	username, _ := c.Get("username").(string)
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"id":       userID,
+		"username": username,
+	})
+}
+
+// GetCatalog 返回触发器和动作目录
+// GET /api/integrations/v1/catalog
+func (h *IntegrationHandler) GetCatalog(c echo.Context) error {
+	return c.JSON(http.StatusOK, integrationCatalog)
+}
+
+// GetSample 返回触发器或动作的静态示例数据
+// GET /api/integrations/v1/samples/:key
+func (h *IntegrationHandler) GetSample(c echo.Context) error {
+	sample, ok := integrationSamples[c.Param("key")]
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, "Sample not found")
+	}
+	// 返回数组以符合轮询触发器的格式
+	return c.JSON(http.StatusOK, []interface{}{sample})
+}
+
+// PollNewTasks 轮询游标之后的新任务
+// GET /api/integrations/v1/triggers/new-tasks?cursor=&limit=
+func (h *IntegrationHandler) PollNewTasks(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	pageReq, err := pagination.ParseCursor(c.QueryParams(), integrationPagination)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	var afterID uint64
+	if pageReq.Cursor != "" {
+		afterID, err = strconv.ParseUint(pageReq.Cursor, 10, 32)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid cursor")
+		}
+	}
+
+	tasks, err := h.engine.GetNewUserTasks(ctx, userID, uint(afterID), pageReq.Limit())
+	if err != nil {
+		h.logger.Error("Failed to poll new tasks", zap.Uint("user_id", userID), zap.Error(err))
+		return engineHTTPError("Failed to poll new tasks: ", err)
+	}
+
+	items := make([]IntegrationTask, len(tasks))
+	for i := range tasks {
+		items[i] = toIntegrationTask(&tasks[i])
+	}
+
+	nextCursor := strconv.FormatUint(afterID, 10)
+	if len(tasks) > 0 {
+		nextCursor = strconv.FormatUint(uint64(tasks[len(tasks)-1].ID), 10)
+	}
+
+	return c.JSON(http.StatusOK, IntegrationTriggerResponse{
+		Items:      items,
+		NextCursor: nextCursor,
+	})
+}
+
+// PollCompletedInstances 轮询游标之后完成的流程实例
+// GET /api/integrations/v1/triggers/completed-instances?cursor=&limit=
+func (h *IntegrationHandler) PollCompletedInstances(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	pageReq, err := pagination.ParseCursor(c.QueryParams(), integrationPagination)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	since, afterID, err := pagination.DecodeCursor(pageReq.Cursor)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid cursor")
+	}
+
+	instances, err := h.engine.GetCompletedInstancesSince(ctx, userID, since, afterID, pageReq.Limit())
+	if err != nil {
+		h.logger.Error("Failed to poll completed instances", zap.Uint("user_id", userID), zap.Error(err))
+		return engineHTTPError("Failed to poll completed instances: ", err)
+	}
+
+	items := make([]map[string]interface{}, len(instances))
+	for i := range instances {
+		items[i] = toIntegrationInstance(&instances[i], true)
+	}
+
+	nextCursor := pageReq.Cursor
+	if len(instances) > 0 {
+		last := instances[len(instances)-1]
+		if last.EndTime != nil {
+			nextCursor = pagination.EncodeCursor(*last.EndTime, last.ID)
+		}
+	}
+
+	return c.JSON(http.StatusOK, IntegrationTriggerResponse{
+		Items:      items,
+		NextCursor: nextCursor,
+	})
+}
+
+// StartProcess 按流程标识启动最新发布版本
+// POST /api/integrations/v1/actions/start-process
+func (h *IntegrationHandler) StartProcess(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	var req IntegrationStartProcessRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	definition, err := h.engine.ResolvePublishedDefinition(ctx, req.ProcessKey)
+	if err != nil {
+		return engineHTTPError("Failed to resolve published process "+req.ProcessKey+": ", err)
+	}
+
+	instance, err := h.engine.StartProcess(ctx, &engine.StartProcessRequest{
+		DefinitionID: definition.ID,
+		BusinessKey:  req.BusinessKey,
+		Variables:    req.Variables,
+		DueDate:      req.DueDate,
+	}, userID)
+	if err != nil {
+		h.logger.Error("Failed to start process via integration",
+			zap.String("process_key", req.ProcessKey),
+			zap.Uint("user_id", userID),
+			zap.Error(err),
+		)
+		return engineHTTPError("Failed to start process: ", err)
+	}
+
+	instance.Definition = *definition
+	return c.JSON(http.StatusCreated, toIntegrationInstance(instance, false))
+}
+
+// CompleteTask 完成任务，未认领的任务会先由当前用户认领
+// POST /api/integrations/v1/actions/complete-task
+func (h *IntegrationHandler) CompleteTask(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	var req IntegrationCompleteTaskRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	task, err := h.engine.GetTask(ctx, req.TaskID)
+	if err != nil {
+		return engineHTTPError("Failed to get task: ", err)
+	}
+
+	if task.Status == model.TaskStatusCreated || task.Status == model.TaskStatusAssigned {
+		if err := h.engine.ClaimTask(ctx, req.TaskID, userID); err != nil {
+			return engineHTTPError("Failed to claim task: ", err)
+		}
+	}
+
+	if err := h.engine.CompleteTask(ctx, req.TaskID, userID, req.Variables, req.Comment); err != nil {
+		if errors.Is(err, engine.ErrTaskAlreadyCompleted) {
+			return echo.NewHTTPError(http.StatusConflict, map[string]interface{}{
+				"message": "Task already completed",
+				"code":    engine.CodeTaskAlreadyCompleted,
+			})
+		}
+		h.logger.Error("Failed to complete task via integration",
+			zap.Uint("task_id", req.TaskID),
+			zap.Uint("user_id", userID),
+			zap.Error(err),
+		)
+		return engineHTTPError("Failed to complete task: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"task_id":     req.TaskID,
+		"instance_id": task.InstanceID,
+		"status":      model.TaskStatusCompleted,
+	})
+}
+
+// toIntegrationTask 转换为扁平化任务数据
+func toIntegrationTask(task *model.TaskInstance) IntegrationTask {
+	return IntegrationTask{
+		ID:             task.ID,
+		Name:           task.Name,
+		NodeID:         task.NodeID,
+		Status:         task.Status,
+		Priority:       task.Priority,
+		DueDate:        task.DueDate,
+		CreatedAt:      task.CreatedAt,
+		AssigneeID:     task.AssigneeID,
+		InstanceID:     task.InstanceID,
+		BusinessKey:    task.Instance.BusinessKey,
+		ProcessKey:     task.Instance.Definition.Key,
+		ProcessName:    task.Instance.Definition.Name,
+		ProcessVersion: task.Instance.Definition.Version,
+	}
+}
+
+// toIntegrationInstance 转换为扁平化流程实例数据，流程变量以 var_ 前缀展开
+// 数据分级不允许导出或变量已脱敏时不展开流程变量
+func toIntegrationInstance(instance *model.ProcessInstance, withVariables bool) map[string]interface{} {
+	item := map[string]interface{}{
+		"id":              instance.ID,
+		"business_key":    instance.BusinessKey,
+		"process_key":     instance.Definition.Key,
+		"process_name":    instance.Definition.Name,
+		"process_version": instance.Definition.Version,
+		"status":          instance.Status,
+		"current_node":    instance.CurrentNode,
+		"starter_id":      instance.StarterID,
+		"start_time":      instance.StartTime,
+		"end_time":        instance.EndTime,
+	}
+
+	if withVariables && instance.Definition.DataPolicy().AllowExport && !instance.VariablesMasked {
+		variables := make(map[string]interface{})
+		if instance.Variables != "" && json.Unmarshal([]byte(instance.Variables), &variables) == nil {
+			for key, value := range variables {
+				item["var_"+key] = value
+			}
+		}
+	}
+
+	return item
+}
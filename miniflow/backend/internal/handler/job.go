@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"miniflow/internal/engine"
+	"miniflow/internal/repository"
+	"miniflow/pkg/logger"
+	"miniflow/pkg/pagination"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// JobHandler 后台作业面板API处理器
+type JobHandler struct {
+	dashboard *engine.JobDashboard
+	recovery  *engine.Recovery
+	logger    *logger.Logger
+}
+
+// NewJobHandler 创建后台作业面板处理器
+func NewJobHandler(dashboard *engine.JobDashboard, recovery *engine.Recovery, logger *logger.Logger) *JobHandler {
+	return &JobHandler{
+		dashboard: dashboard,
+		recovery:  recovery,
+		logger:    logger,
+	}
+}
+
+// GetJobSummary 获取各类后台作业的状态分布和积压时长直方图
+// GET /api/v1/admin/jobs
+func (h *JobHandler) GetJobSummary(c echo.Context) error {
+	ctx := c.Request().Context()
+	summary, err := h.dashboard.Summary(ctx, time.Now())
+	if err != nil {
+		h.logger.Error("Failed to get job summary", zap.Error(err))
+		return engineHTTPError("Failed to get job summary: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    summary,
+	})
+}
+
+// ListJobs 按类型分页获取后台作业，可按状态过滤
+// GET /api/v1/admin/jobs/:type
+func (h *JobHandler) ListJobs(c echo.Context) error {
+	ctx := c.Request().Context()
+	jobType := c.Param("type")
+	if !repository.IsJobType(jobType) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Unknown job type")
+	}
+
+	pageReq, err := pagination.Parse(c.QueryParams(), pagination.Default)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	status := c.QueryParam("status")
+	if err := validateEnumParam(repository.JobStatuses(jobType), status); err != nil {
+		return err
+	}
+
+	jobs, total, err := h.dashboard.ListJobs(ctx, jobType, status, pageReq.Offset(), pageReq.Limit())
+	if err != nil {
+		h.logger.Error("Failed to list jobs", zap.String("job_type", jobType), zap.Error(err))
+		return engineHTTPError("Failed to list jobs: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    pageReq.Result("jobs", jobs, total),
+	})
+}
+
+// RetryJob 立即重试后台作业
+// POST /api/v1/admin/jobs/:type/:id/retry
+func (h *JobHandler) RetryJob(c echo.Context) error {
+	ctx := c.Request().Context()
+	jobType, jobID, err := parseJobParams(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.dashboard.RetryJob(ctx, jobType, jobID, time.Now()); err != nil {
+		h.logger.Error("Failed to retry job", zap.String("job_type", jobType), zap.Uint("job_id", jobID), zap.Error(err))
+		return engineHTTPError("Failed to retry job: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Job retry scheduled",
+	})
+}
+
+// DeleteJob 删除后台作业，定时器会被取消
+// DELETE /api/v1/admin/jobs/:type/:id
+func (h *JobHandler) DeleteJob(c echo.Context) error {
+	ctx := c.Request().Context()
+	jobType, jobID, err := parseJobParams(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.dashboard.DeleteJob(ctx, jobType, jobID); err != nil {
+		h.logger.Error("Failed to delete job", zap.String("job_type", jobType), zap.Uint("job_id", jobID), zap.Error(err))
+		return engineHTTPError("Failed to delete job: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Job deleted",
+	})
+}
+
+// GetRecoveryReport 获取最近一次启动恢复的报告
+// GET /api/v1/admin/recovery
+func (h *JobHandler) GetRecoveryReport(c echo.Context) error {
+	report := h.recovery.LastReport()
+	if report == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Recovery has not run yet")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    report,
+	})
+}
+
+// RunRecovery 立即扫描并修复推进中断留下的不一致
+// POST /api/v1/admin/recovery/run
+func (h *JobHandler) RunRecovery(c echo.Context) error {
+	ctx := c.Request().Context()
+	report := h.recovery.Run(ctx, time.Now())
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    report,
+	})
+}
+
+// parseJobParams 解析作业类型和ID路径参数
+func parseJobParams(c echo.Context) (string, uint, error) {
+	jobType := c.Param("type")
+	if !repository.IsJobType(jobType) {
+		return "", 0, echo.NewHTTPError(http.StatusBadRequest, "Unknown job type")
+	}
+
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return "", 0, echo.NewHTTPError(http.StatusBadRequest, "Invalid job ID")
+	}
+	return jobType, uint(jobID), nil
+}
@@ -0,0 +1,217 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"miniflow/internal/middleware"
+	"miniflow/internal/service"
+	"miniflow/pkg/logger"
+	"miniflow/pkg/utils"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// KPIHandler handles process KPI HTTP requests
+type KPIHandler struct {
+	kpiService *service.KPIService
+	logger     *logger.Logger
+	validator  *utils.CustomValidator
+}
+
+// NewKPIHandler creates a new KPI handler
+func NewKPIHandler(kpiService *service.KPIService, logger *logger.Logger) *KPIHandler {
+	return &KPIHandler{
+		kpiService: kpiService,
+		logger:     logger,
+		validator:  utils.NewCustomValidator(),
+	}
+}
+
+// ListKPIs handles listing the KPIs of a process
+func (h *KPIHandler) ListKPIs(c echo.Context) error {
+	ctx := c.Request().Context()
+	processID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "无效的流程ID",
+			"code":  "INVALID_PROCESS_ID",
+		})
+	}
+
+	kpis, err := h.kpiService.ListKPIs(ctx, uint(processID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": err.Error(),
+			"code":  "PROCESS_NOT_FOUND",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "获取KPI列表成功",
+		"data":    kpis,
+	})
+}
+
+// CreateKPI handles defining a new KPI for a process (process owner only)
+func (h *KPIHandler) CreateKPI(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "用户认证信息无效",
+			"code":  "INVALID_USER_CONTEXT",
+		})
+	}
+
+	processID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "无效的流程ID",
+			"code":  "INVALID_PROCESS_ID",
+		})
+	}
+
+	var req service.KPIRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn("Invalid request body for KPI", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数格式错误",
+			"code":  "INVALID_REQUEST_FORMAT",
+		})
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		h.logger.Warn("KPI validation failed", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数验证失败",
+			"code":  "VALIDATION_FAILED",
+		})
+	}
+
+	kpi, err := h.kpiService.CreateKPI(ctx, uint(processID), userID, &req)
+	if err != nil {
+		h.logger.Warn("Process KPI creation failed", zap.Uint("process_id", uint(processID)), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "KPI_CREATE_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message": "KPI创建成功",
+		"data":    kpi,
+	})
+}
+
+// UpdateKPI handles updating a KPI
+func (h *KPIHandler) UpdateKPI(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "用户认证信息无效",
+			"code":  "INVALID_USER_CONTEXT",
+		})
+	}
+
+	kpiID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "无效的KPI ID",
+			"code":  "INVALID_KPI_ID",
+		})
+	}
+
+	var req service.KPIRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn("Invalid request body for KPI", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数格式错误",
+			"code":  "INVALID_REQUEST_FORMAT",
+		})
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		h.logger.Warn("KPI validation failed", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数验证失败",
+			"code":  "VALIDATION_FAILED",
+		})
+	}
+
+	kpi, err := h.kpiService.UpdateKPI(ctx, uint(kpiID), userID, &req)
+	if err != nil {
+		h.logger.Warn("Process KPI update failed", zap.Uint("kpi_id", uint(kpiID)), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "KPI_UPDATE_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "KPI更新成功",
+		"data":    kpi,
+	})
+}
+
+// DeleteKPI handles deleting a KPI
+func (h *KPIHandler) DeleteKPI(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "用户认证信息无效",
+			"code":  "INVALID_USER_CONTEXT",
+		})
+	}
+
+	kpiID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "无效的KPI ID",
+			"code":  "INVALID_KPI_ID",
+		})
+	}
+
+	if err := h.kpiService.DeleteKPI(ctx, uint(kpiID), userID); err != nil {
+		h.logger.Warn("Process KPI deletion failed", zap.Uint("kpi_id", uint(kpiID)), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "KPI_DELETE_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "KPI删除成功",
+	})
+}
+
+// GetAttainment handles getting the current attainment and trend of the KPIs of a process
+func (h *KPIHandler) GetAttainment(c echo.Context) error {
+	ctx := c.Request().Context()
+	processID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "无效的流程ID",
+			"code":  "INVALID_PROCESS_ID",
+		})
+	}
+
+	trendDays, _ := strconv.Atoi(c.QueryParam("trend_days"))
+
+	attainment, err := h.kpiService.GetAttainment(ctx, uint(processID), trendDays)
+	if err != nil {
+		h.logger.Error("Failed to get KPI attainment", zap.Uint("process_id", uint(processID)), zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+			"code":  "GET_KPI_ATTAINMENT_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "获取KPI达成情况成功",
+		"data":    attainment,
+	})
+}
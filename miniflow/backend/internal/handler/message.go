@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"net/http"
+
+	"miniflow/internal/engine"
+	"miniflow/pkg/logger"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// MessageHandler 消息投递API处理器，外部系统通过消息唤醒在消息捕获节点等待的流程实例
+type MessageHandler struct {
+	engine *engine.ProcessEngine
+	logger *logger.Logger
+}
+
+// NewMessageHandler 创建消息投递处理器
+func NewMessageHandler(engine *engine.ProcessEngine, logger *logger.Logger) *MessageHandler {
+	return &MessageHandler{
+		engine: engine,
+		logger: logger,
+	}
+}
+
+// CorrelateMessageRequest 投递消息请求，variables 合并到被唤醒实例的流程变量
+type CorrelateMessageRequest struct {
+	MessageName    string                 `json:"message_name" validate:"required,max=128"`
+	CorrelationKey string                 `json:"correlation_key" validate:"required,max=255"`
+	Variables      map[string]interface{} `json:"variables"`
+}
+
+// Correlate 投递消息，唤醒等待该消息和关联键的流程实例
+// POST /api/v1/messages/correlate
+func (h *MessageHandler) Correlate(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	var req CorrelateMessageRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	result, err := h.engine.CorrelateMessage(ctx, req.MessageName, req.CorrelationKey, req.Variables, userID)
+	if err != nil {
+		h.logger.Warn("Failed to correlate message",
+			zap.String("message_name", req.MessageName),
+			zap.String("correlation_key", req.CorrelationKey),
+			zap.Error(err),
+		)
+		return engineHTTPError("Failed to correlate message: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    result,
+	})
+}
@@ -0,0 +1,409 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"miniflow/internal/middleware"
+	"miniflow/internal/service"
+	"miniflow/pkg/logger"
+	"miniflow/pkg/pagination"
+	"miniflow/pkg/utils"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// NotificationHandler handles notification-related HTTP requests
+type NotificationHandler struct {
+	notificationService *service.NotificationService
+	logger              *logger.Logger
+	validator           *utils.CustomValidator
+}
+
+// NewNotificationHandler creates a new notification handler
+func NewNotificationHandler(notificationService *service.NotificationService, logger *logger.Logger) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: notificationService,
+		logger:              logger,
+		validator:           utils.NewCustomValidator(),
+	}
+}
+
+// GetPreferences handles getting the current user's notification preferences
+func (h *NotificationHandler) GetPreferences(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "用户认证信息无效",
+			"code":  "INVALID_USER_CONTEXT",
+		})
+	}
+
+	prefs, err := h.notificationService.GetPreferences(ctx, userID)
+	if err != nil {
+		h.logger.Error("Failed to get notification preferences",
+			zap.Uint("user_id", userID),
+			zap.Error(err),
+		)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+			"code":  "GET_NOTIFICATION_PREFERENCES_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "获取通知偏好成功",
+		"data":    prefs,
+	})
+}
+
+// UpdatePreferences handles updating the current user's notification preferences
+func (h *NotificationHandler) UpdatePreferences(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "用户认证信息无效",
+			"code":  "INVALID_USER_CONTEXT",
+		})
+	}
+
+	var req service.UpdateNotificationPreferenceRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn("Invalid request body for notification preferences", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数格式错误",
+			"code":  "INVALID_REQUEST_FORMAT",
+		})
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		h.logger.Warn("Notification preferences validation failed", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数验证失败",
+			"code":  "VALIDATION_FAILED",
+		})
+	}
+
+	prefs, err := h.notificationService.UpdatePreferences(ctx, userID, &req)
+	if err != nil {
+		h.logger.Warn("Failed to update notification preferences",
+			zap.Uint("user_id", userID),
+			zap.Error(err),
+		)
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "UPDATE_NOTIFICATION_PREFERENCES_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "通知偏好更新成功",
+		"data":    prefs,
+	})
+}
+
+// ListInbox handles listing the current user's in-app notifications with the unread count
+// GET /api/v1/user/notifications?unread=true&page=1&page_size=20
+func (h *NotificationHandler) ListInbox(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "用户认证信息无效",
+			"code":  "INVALID_USER_CONTEXT",
+		})
+	}
+
+	pageReq, err := pagination.Parse(c.QueryParams(), pagination.Default)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "INVALID_PAGINATION",
+		})
+	}
+	unreadOnly := c.QueryParam("unread") == "true"
+
+	notifications, total, unread, err := h.notificationService.ListInbox(ctx, userID, unreadOnly, pageReq.Page, pageReq.PageSize)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+			"code":  "LIST_NOTIFICATIONS_FAILED",
+		})
+	}
+
+	data := pageReq.Result("notifications", notifications, total)
+	data["unread_count"] = unread
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "获取通知列表成功",
+		"data":    data,
+	})
+}
+
+// GetUnreadCount handles getting the current user's unread in-app notification count
+// GET /api/v1/user/notifications/unread-count
+func (h *NotificationHandler) GetUnreadCount(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "用户认证信息无效",
+			"code":  "INVALID_USER_CONTEXT",
+		})
+	}
+
+	unread, err := h.notificationService.CountUnread(ctx, userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+			"code":  "COUNT_UNREAD_NOTIFICATIONS_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "获取未读通知数成功",
+		"data":    map[string]interface{}{"unread_count": unread},
+	})
+}
+
+// MarkRead handles marking one of the current user's in-app notifications as read
+// POST /api/v1/user/notifications/:id/read
+func (h *NotificationHandler) MarkRead(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "用户认证信息无效",
+			"code":  "INVALID_USER_CONTEXT",
+		})
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "无效的通知ID",
+			"code":  "INVALID_NOTIFICATION_ID",
+		})
+	}
+
+	if err := h.notificationService.MarkRead(ctx, userID, uint(id)); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrNotificationNotFound) {
+			status = http.StatusNotFound
+		}
+		return c.JSON(status, map[string]string{
+			"error": err.Error(),
+			"code":  "MARK_NOTIFICATION_READ_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "通知已读",
+	})
+}
+
+// MarkAllRead handles marking all of the current user's in-app notifications as read
+// POST /api/v1/user/notifications/read-all
+func (h *NotificationHandler) MarkAllRead(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "用户认证信息无效",
+			"code":  "INVALID_USER_CONTEXT",
+		})
+	}
+
+	marked, err := h.notificationService.MarkAllRead(ctx, userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+			"code":  "MARK_NOTIFICATIONS_READ_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "全部通知已读",
+		"data":    map[string]interface{}{"marked": marked},
+	})
+}
+
+// ListTemplates handles listing notification templates (admin)
+func (h *NotificationHandler) ListTemplates(c echo.Context) error {
+	ctx := c.Request().Context()
+	templates, err := h.notificationService.ListTemplates(ctx, c.QueryParam("event_type"))
+	if err != nil {
+		h.logger.Error("Failed to list notification templates", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+			"code":  "LIST_NOTIFICATION_TEMPLATES_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "获取通知模板成功",
+		"data":    templates,
+	})
+}
+
+// CreateTemplate handles creating a notification template (admin)
+func (h *NotificationHandler) CreateTemplate(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "用户认证信息无效",
+			"code":  "INVALID_USER_CONTEXT",
+		})
+	}
+
+	var req service.NotificationTemplateRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn("Invalid request body for notification template", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数格式错误",
+			"code":  "INVALID_REQUEST_FORMAT",
+		})
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		h.logger.Warn("Notification template validation failed", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数验证失败",
+			"code":  "VALIDATION_FAILED",
+		})
+	}
+
+	tmpl, err := h.notificationService.CreateTemplate(ctx, &req, userID)
+	if err != nil {
+		h.logger.Warn("Failed to create notification template", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "CREATE_NOTIFICATION_TEMPLATE_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message": "通知模板创建成功",
+		"data":    tmpl,
+	})
+}
+
+// UpdateTemplate handles updating a notification template (admin)
+func (h *NotificationHandler) UpdateTemplate(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "用户认证信息无效",
+			"code":  "INVALID_USER_CONTEXT",
+		})
+	}
+
+	templateID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "无效的模板ID",
+			"code":  "INVALID_TEMPLATE_ID",
+		})
+	}
+
+	var req service.NotificationTemplateRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn("Invalid request body for notification template", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数格式错误",
+			"code":  "INVALID_REQUEST_FORMAT",
+		})
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		h.logger.Warn("Notification template validation failed", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数验证失败",
+			"code":  "VALIDATION_FAILED",
+		})
+	}
+
+	tmpl, err := h.notificationService.UpdateTemplate(ctx, uint(templateID), &req, userID)
+	if err != nil {
+		h.logger.Warn("Failed to update notification template",
+			zap.Uint("template_id", uint(templateID)),
+			zap.Error(err),
+		)
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "UPDATE_NOTIFICATION_TEMPLATE_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "通知模板更新成功",
+		"data":    tmpl,
+	})
+}
+
+// DeleteTemplate handles deleting a notification template (admin)
+func (h *NotificationHandler) DeleteTemplate(c echo.Context) error {
+	ctx := c.Request().Context()
+	templateID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "无效的模板ID",
+			"code":  "INVALID_TEMPLATE_ID",
+		})
+	}
+
+	if err := h.notificationService.DeleteTemplate(ctx, uint(templateID)); err != nil {
+		h.logger.Warn("Failed to delete notification template",
+			zap.Uint("template_id", uint(templateID)),
+			zap.Error(err),
+		)
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "DELETE_NOTIFICATION_TEMPLATE_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "通知模板删除成功",
+	})
+}
+
+// PreviewTemplate handles test-rendering a notification template (admin)
+func (h *NotificationHandler) PreviewTemplate(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "用户认证信息无效",
+			"code":  "INVALID_USER_CONTEXT",
+		})
+	}
+
+	var req service.PreviewNotificationTemplateRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn("Invalid request body for template preview", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数格式错误",
+			"code":  "INVALID_REQUEST_FORMAT",
+		})
+	}
+
+	preview, err := h.notificationService.PreviewTemplate(ctx, &req, userID)
+	if err != nil {
+		h.logger.Warn("Failed to preview notification template", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "PREVIEW_NOTIFICATION_TEMPLATE_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "通知模板渲染成功",
+		"data":    preview,
+	})
+}
@@ -0,0 +1,432 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"miniflow/internal/service"
+	"miniflow/pkg/logger"
+	"miniflow/pkg/utils"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// OrganizationHandler handles department and user group HTTP requests
+type OrganizationHandler struct {
+	orgService *service.OrganizationService
+	logger     *logger.Logger
+	validator  *utils.CustomValidator
+}
+
+// NewOrganizationHandler creates a new organization handler
+func NewOrganizationHandler(orgService *service.OrganizationService, logger *logger.Logger) *OrganizationHandler {
+	return &OrganizationHandler{
+		orgService: orgService,
+		logger:     logger,
+		validator:  utils.NewCustomValidator(),
+	}
+}
+
+// ListDepartments handles listing every department
+func (h *OrganizationHandler) ListDepartments(c echo.Context) error {
+	ctx := c.Request().Context()
+	departments, err := h.orgService.ListDepartments(ctx)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+			"code":  "LIST_DEPARTMENTS_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "获取部门列表成功",
+		"data":    departments,
+	})
+}
+
+// GetDepartmentMembers handles listing the users of a department
+func (h *OrganizationHandler) GetDepartmentMembers(c echo.Context) error {
+	ctx := c.Request().Context()
+	departmentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "无效的部门ID",
+			"code":  "INVALID_DEPARTMENT_ID",
+		})
+	}
+
+	members, err := h.orgService.GetDepartmentMembers(ctx, uint(departmentID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": err.Error(),
+			"code":  "GET_DEPARTMENT_MEMBERS_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "获取部门成员成功",
+		"data":    members,
+	})
+}
+
+// CreateDepartment handles creating a department (admin only)
+func (h *OrganizationHandler) CreateDepartment(c echo.Context) error {
+	ctx := c.Request().Context()
+	var req service.DepartmentRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn("Invalid request body for organization", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数格式错误",
+			"code":  "INVALID_REQUEST_FORMAT",
+		})
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		h.logger.Warn("Organization request validation failed", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数验证失败",
+			"code":  "VALIDATION_FAILED",
+		})
+	}
+
+	department, err := h.orgService.CreateDepartment(ctx, &req)
+	if err != nil {
+		h.logger.Warn("Failed to create department", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "CREATE_DEPARTMENT_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message": "部门创建成功",
+		"data":    department,
+	})
+}
+
+// UpdateDepartment handles updating a department (admin only)
+func (h *OrganizationHandler) UpdateDepartment(c echo.Context) error {
+	ctx := c.Request().Context()
+	departmentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "无效的部门ID",
+			"code":  "INVALID_DEPARTMENT_ID",
+		})
+	}
+
+	var req service.DepartmentRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn("Invalid request body for organization", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数格式错误",
+			"code":  "INVALID_REQUEST_FORMAT",
+		})
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		h.logger.Warn("Organization request validation failed", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数验证失败",
+			"code":  "VALIDATION_FAILED",
+		})
+	}
+
+	department, err := h.orgService.UpdateDepartment(ctx, uint(departmentID), &req)
+	if err != nil {
+		h.logger.Warn("Failed to update department", zap.Uint("department_id", uint(departmentID)), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "UPDATE_DEPARTMENT_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "部门更新成功",
+		"data":    department,
+	})
+}
+
+// DeleteDepartment handles deleting a department (admin only)
+func (h *OrganizationHandler) DeleteDepartment(c echo.Context) error {
+	ctx := c.Request().Context()
+	departmentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "无效的部门ID",
+			"code":  "INVALID_DEPARTMENT_ID",
+		})
+	}
+
+	if err := h.orgService.DeleteDepartment(ctx, uint(departmentID)); err != nil {
+		h.logger.Warn("Failed to delete department", zap.Uint("department_id", uint(departmentID)), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "DELETE_DEPARTMENT_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "部门已删除",
+	})
+}
+
+// SetUserDepartment handles moving a user into or out of a department (admin only)
+func (h *OrganizationHandler) SetUserDepartment(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "无效的用户ID",
+			"code":  "INVALID_USER_ID",
+		})
+	}
+
+	var req service.UserDepartmentRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn("Invalid request body for organization", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数格式错误",
+			"code":  "INVALID_REQUEST_FORMAT",
+		})
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		h.logger.Warn("Organization request validation failed", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数验证失败",
+			"code":  "VALIDATION_FAILED",
+		})
+	}
+
+	if err := h.orgService.SetUserDepartment(ctx, uint(userID), &req); err != nil {
+		h.logger.Warn("Failed to set user department", zap.Uint("user_id", uint(userID)), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "SET_USER_DEPARTMENT_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "用户部门已更新",
+	})
+}
+
+// ListGroups handles listing every user group
+func (h *OrganizationHandler) ListGroups(c echo.Context) error {
+	ctx := c.Request().Context()
+	groups, err := h.orgService.ListGroups(ctx)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+			"code":  "LIST_GROUPS_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "获取用户组列表成功",
+		"data":    groups,
+	})
+}
+
+// GetGroupMembers handles listing the members of a user group
+func (h *OrganizationHandler) GetGroupMembers(c echo.Context) error {
+	ctx := c.Request().Context()
+	groupID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "无效的用户组ID",
+			"code":  "INVALID_GROUP_ID",
+		})
+	}
+
+	members, err := h.orgService.GetGroupMembers(ctx, uint(groupID))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": err.Error(),
+			"code":  "GET_GROUP_MEMBERS_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "获取用户组成员成功",
+		"data":    members,
+	})
+}
+
+// CreateGroup handles creating a user group (admin only)
+func (h *OrganizationHandler) CreateGroup(c echo.Context) error {
+	ctx := c.Request().Context()
+	var req service.GroupRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn("Invalid request body for organization", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数格式错误",
+			"code":  "INVALID_REQUEST_FORMAT",
+		})
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		h.logger.Warn("Organization request validation failed", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数验证失败",
+			"code":  "VALIDATION_FAILED",
+		})
+	}
+
+	group, err := h.orgService.CreateGroup(ctx, &req)
+	if err != nil {
+		h.logger.Warn("Failed to create group", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "CREATE_GROUP_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message": "用户组创建成功",
+		"data":    group,
+	})
+}
+
+// UpdateGroup handles updating a user group (admin only)
+func (h *OrganizationHandler) UpdateGroup(c echo.Context) error {
+	ctx := c.Request().Context()
+	groupID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "无效的用户组ID",
+			"code":  "INVALID_GROUP_ID",
+		})
+	}
+
+	var req service.GroupRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn("Invalid request body for organization", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数格式错误",
+			"code":  "INVALID_REQUEST_FORMAT",
+		})
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		h.logger.Warn("Organization request validation failed", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数验证失败",
+			"code":  "VALIDATION_FAILED",
+		})
+	}
+
+	group, err := h.orgService.UpdateGroup(ctx, uint(groupID), &req)
+	if err != nil {
+		h.logger.Warn("Failed to update group", zap.Uint("group_id", uint(groupID)), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "UPDATE_GROUP_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "用户组更新成功",
+		"data":    group,
+	})
+}
+
+// DeleteGroup handles deleting a user group (admin only)
+func (h *OrganizationHandler) DeleteGroup(c echo.Context) error {
+	ctx := c.Request().Context()
+	groupID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "无效的用户组ID",
+			"code":  "INVALID_GROUP_ID",
+		})
+	}
+
+	if err := h.orgService.DeleteGroup(ctx, uint(groupID)); err != nil {
+		h.logger.Warn("Failed to delete group", zap.Uint("group_id", uint(groupID)), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "DELETE_GROUP_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "用户组已删除",
+	})
+}
+
+// AddGroupMembers handles adding users to a user group (admin only)
+func (h *OrganizationHandler) AddGroupMembers(c echo.Context) error {
+	ctx := c.Request().Context()
+	groupID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "无效的用户组ID",
+			"code":  "INVALID_GROUP_ID",
+		})
+	}
+
+	var req service.GroupMembersRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn("Invalid request body for organization", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数格式错误",
+			"code":  "INVALID_REQUEST_FORMAT",
+		})
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		h.logger.Warn("Organization request validation failed", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数验证失败",
+			"code":  "VALIDATION_FAILED",
+		})
+	}
+
+	members, err := h.orgService.AddGroupMembers(ctx, uint(groupID), &req)
+	if err != nil {
+		h.logger.Warn("Failed to add group members", zap.Uint("group_id", uint(groupID)), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "ADD_GROUP_MEMBERS_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "用户组成员已添加",
+		"data":    members,
+	})
+}
+
+// RemoveGroupMember handles removing a user from a user group (admin only)
+func (h *OrganizationHandler) RemoveGroupMember(c echo.Context) error {
+	ctx := c.Request().Context()
+	groupID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "无效的用户组ID",
+			"code":  "INVALID_GROUP_ID",
+		})
+	}
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "无效的用户ID",
+			"code":  "INVALID_USER_ID",
+		})
+	}
+
+	if err := h.orgService.RemoveGroupMember(ctx, uint(groupID), uint(userID)); err != nil {
+		h.logger.Warn("Failed to remove group member", zap.Uint("group_id", uint(groupID)), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "REMOVE_GROUP_MEMBER_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "用户组成员已移除",
+	})
+}
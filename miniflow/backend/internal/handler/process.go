@@ -1,12 +1,15 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"miniflow/internal/middleware"
+	"miniflow/internal/model"
 	"miniflow/internal/service"
 	"miniflow/pkg/logger"
+	"miniflow/pkg/pagination"
 	"miniflow/pkg/utils"
 
 	"github.com/labstack/echo/v4"
@@ -31,6 +34,7 @@ func NewProcessHandler(processService *service.ProcessService, logger *logger.Lo
 
 // CreateProcess handles process creation
 func (h *ProcessHandler) CreateProcess(c echo.Context) error {
+	ctx := c.Request().Context()
 	userID, ok := middleware.GetUserIDFromContext(c)
 	if !ok {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
@@ -60,13 +64,10 @@ func (h *ProcessHandler) CreateProcess(c echo.Context) error {
 	}
 
 	// Call service to create process
-	process, err := h.processService.CreateProcess(userID, &req)
+	process, err := h.processService.CreateProcess(ctx, userID, &req)
 	if err != nil {
 		h.logger.Error("Process creation failed", zap.Error(err))
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": err.Error(),
-			"code":  "PROCESS_CREATION_FAILED",
-		})
+		return c.JSON(http.StatusBadRequest, processErrorResponse(err, "PROCESS_CREATION_FAILED"))
 	}
 
 	h.logger.Info("Process created successfully via API", 
@@ -81,8 +82,26 @@ func (h *ProcessHandler) CreateProcess(c echo.Context) error {
 	})
 }
 
+// ValidateDefinition handles validating a process definition without saving it,
+// so the designer can highlight graph problems while editing
+func (h *ProcessHandler) ValidateDefinition(c echo.Context) error {
+	var req service.ValidateDefinitionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数格式错误",
+			"code":  "INVALID_REQUEST_FORMAT",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "流程定义验证完成",
+		"data":    h.processService.ValidateDefinition(&req.Definition),
+	})
+}
+
 // GetProcess handles getting process details
 func (h *ProcessHandler) GetProcess(c echo.Context) error {
+	ctx := c.Request().Context()
 	processIDStr := c.Param("id")
 	processID, err := strconv.ParseUint(processIDStr, 10, 32)
 	if err != nil {
@@ -92,7 +111,7 @@ func (h *ProcessHandler) GetProcess(c echo.Context) error {
 		})
 	}
 
-	process, err := h.processService.GetProcess(uint(processID))
+	process, err := h.processService.GetProcess(ctx, uint(processID))
 	if err != nil {
 		h.logger.Error("Failed to get process", 
 			zap.Uint("process_id", uint(processID)),
@@ -110,8 +129,52 @@ func (h *ProcessHandler) GetProcess(c echo.Context) error {
 	})
 }
 
+// ImportTemplate handles importing a Feishu or DingTalk approval template as a draft process
+func (h *ProcessHandler) ImportTemplate(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "用户认证信息无效",
+			"code":  "INVALID_USER_CONTEXT",
+		})
+	}
+
+	var req service.ImportTemplateRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn("Invalid request body for template import", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数格式错误",
+			"code":  "INVALID_REQUEST_FORMAT",
+		})
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		h.logger.Warn("Template import validation failed", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数验证失败",
+			"code":  "VALIDATION_FAILED",
+		})
+	}
+
+	result, err := h.processService.ImportTemplate(ctx, userID, &req)
+	if err != nil {
+		h.logger.Warn("Template import failed", zap.String("source", req.Source), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "TEMPLATE_IMPORT_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message": "导入审批模板成功",
+		"data":    result,
+	})
+}
+
 // UpdateProcess handles process updates
 func (h *ProcessHandler) UpdateProcess(c echo.Context) error {
+	ctx := c.Request().Context()
 	userID, ok := middleware.GetUserIDFromContext(c)
 	if !ok {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
@@ -150,16 +213,13 @@ func (h *ProcessHandler) UpdateProcess(c echo.Context) error {
 	}
 
 	// Call service to update process
-	process, err := h.processService.UpdateProcess(uint(processID), userID, &req)
+	process, err := h.processService.UpdateProcess(ctx, uint(processID), userID, &req)
 	if err != nil {
 		h.logger.Error("Process update failed", 
 			zap.Uint("process_id", uint(processID)),
 			zap.Error(err),
 		)
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": err.Error(),
-			"code":  "PROCESS_UPDATE_FAILED",
-		})
+		return c.JSON(http.StatusBadRequest, processErrorResponse(err, "PROCESS_UPDATE_FAILED"))
 	}
 
 	h.logger.Info("Process updated successfully via API", 
@@ -175,6 +235,7 @@ func (h *ProcessHandler) UpdateProcess(c echo.Context) error {
 
 // DeleteProcess handles process deletion
 func (h *ProcessHandler) DeleteProcess(c echo.Context) error {
+	ctx := c.Request().Context()
 	userID, ok := middleware.GetUserIDFromContext(c)
 	if !ok {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
@@ -192,7 +253,7 @@ func (h *ProcessHandler) DeleteProcess(c echo.Context) error {
 		})
 	}
 
-	err = h.processService.DeleteProcess(uint(processID), userID)
+	err = h.processService.DeleteProcess(ctx, uint(processID), userID)
 	if err != nil {
 		h.logger.Error("Process deletion failed", 
 			zap.Uint("process_id", uint(processID)),
@@ -216,6 +277,7 @@ func (h *ProcessHandler) DeleteProcess(c echo.Context) error {
 
 // GetProcesses handles getting process list
 func (h *ProcessHandler) GetProcesses(c echo.Context) error {
+	ctx := c.Request().Context()
 	userID, ok := middleware.GetUserIDFromContext(c)
 	if !ok {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
@@ -225,14 +287,12 @@ func (h *ProcessHandler) GetProcesses(c echo.Context) error {
 	}
 
 	// Get pagination parameters
-	page, _ := strconv.Atoi(c.QueryParam("page"))
-	pageSize, _ := strconv.Atoi(c.QueryParam("page_size"))
-	
-	if page < 1 {
-		page = 1
-	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 20
+	pageReq, err := pagination.Parse(c.QueryParams(), pagination.Default)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "INVALID_PAGINATION",
+		})
 	}
 
 	// Get filter parameters
@@ -244,6 +304,13 @@ func (h *ProcessHandler) GetProcesses(c echo.Context) error {
 		filters["category"] = category
 	}
 	if status := c.QueryParam("status"); status != "" {
+		if err := model.ProcessStatuses.Validate(status); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error":   err.Error(),
+				"code":    "INVALID_STATUS",
+				"allowed": model.ProcessStatuses.Values,
+			})
+		}
 		filters["status"] = status
 	}
 	
@@ -252,7 +319,7 @@ func (h *ProcessHandler) GetProcesses(c echo.Context) error {
 	filters["created_by"] = userID
 
 	// Call service to get processes
-	result, err := h.processService.GetProcesses(page, pageSize, filters)
+	result, err := h.processService.GetProcesses(ctx, pageReq.Page, pageReq.PageSize, filters)
 	if err != nil {
 		h.logger.Error("Failed to get processes", zap.Error(err))
 		return c.JSON(http.StatusInternalServerError, map[string]string{
@@ -269,6 +336,7 @@ func (h *ProcessHandler) GetProcesses(c echo.Context) error {
 
 // CopyProcess handles process copying
 func (h *ProcessHandler) CopyProcess(c echo.Context) error {
+	ctx := c.Request().Context()
 	userID, ok := middleware.GetUserIDFromContext(c)
 	if !ok {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
@@ -286,7 +354,7 @@ func (h *ProcessHandler) CopyProcess(c echo.Context) error {
 		})
 	}
 
-	process, err := h.processService.CopyProcess(uint(processID), userID)
+	process, err := h.processService.CopyProcess(ctx, uint(processID), userID)
 	if err != nil {
 		h.logger.Error("Process copy failed", 
 			zap.Uint("process_id", uint(processID)),
@@ -312,6 +380,7 @@ func (h *ProcessHandler) CopyProcess(c echo.Context) error {
 
 // PublishProcess handles process publishing
 func (h *ProcessHandler) PublishProcess(c echo.Context) error {
+	ctx := c.Request().Context()
 	userID, ok := middleware.GetUserIDFromContext(c)
 	if !ok {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
@@ -329,31 +398,238 @@ func (h *ProcessHandler) PublishProcess(c echo.Context) error {
 		})
 	}
 
-	err = h.processService.PublishProcess(uint(processID), userID)
+	err = h.processService.PublishProcess(ctx, uint(processID), userID)
 	if err != nil {
 		h.logger.Error("Process publish failed", 
 			zap.Uint("process_id", uint(processID)),
 			zap.Error(err),
 		)
+		return c.JSON(http.StatusBadRequest, processErrorResponse(err, "PROCESS_PUBLISH_FAILED"))
+	}
+
+	h.logger.Info("Process published successfully via API", 
+		zap.Uint("process_id", uint(processID)),
+		zap.Uint("user_id", userID),
+	)
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "流程发布成功",
+	})
+}
+
+// CreateNewVersion handles creating a draft of the next process version
+func (h *ProcessHandler) CreateNewVersion(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "用户认证信息无效",
+			"code":  "INVALID_USER_CONTEXT",
+		})
+	}
+
+	processIDStr := c.Param("id")
+	processID, err := strconv.ParseUint(processIDStr, 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "无效的流程ID",
+			"code":  "INVALID_PROCESS_ID",
+		})
+	}
+
+	process, err := h.processService.CreateNewVersion(ctx, uint(processID), userID)
+	if err != nil {
+		h.logger.Error("Process new version failed",
+			zap.Uint("process_id", uint(processID)),
+			zap.Error(err),
+		)
 		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": err.Error(),
-			"code":  "PROCESS_PUBLISH_FAILED",
+			"code":  "PROCESS_NEW_VERSION_FAILED",
 		})
 	}
 
-	h.logger.Info("Process published successfully via API", 
+	h.logger.Info("Process version created successfully via API",
+		zap.Uint("source_process_id", uint(processID)),
+		zap.Uint("new_process_id", process.ID),
+		zap.Int("version", process.Version),
+		zap.Uint("user_id", userID),
+	)
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message": "新版本创建成功",
+		"data":    process,
+	})
+}
+
+// DeprecateProcess handles process deprecation; running instances continue on the deprecated version
+func (h *ProcessHandler) DeprecateProcess(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "用户认证信息无效",
+			"code":  "INVALID_USER_CONTEXT",
+		})
+	}
+
+	processIDStr := c.Param("id")
+	processID, err := strconv.ParseUint(processIDStr, 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "无效的流程ID",
+			"code":  "INVALID_PROCESS_ID",
+		})
+	}
+
+	active, err := h.processService.DeprecateProcess(ctx, uint(processID), userID)
+	if err != nil {
+		h.logger.Error("Process deprecate failed",
+			zap.Uint("process_id", uint(processID)),
+			zap.Error(err),
+		)
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "PROCESS_DEPRECATE_FAILED",
+		})
+	}
+
+	h.logger.Info("Process deprecated successfully via API",
+		zap.Uint("process_id", uint(processID)),
+		zap.Uint("user_id", userID),
+	)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "流程已弃用",
+		"data": map[string]interface{}{
+			"active_instances": active,
+		},
+	})
+}
+
+// ArchiveProcess handles process archiving, refused while instances of the version are running or suspended
+func (h *ProcessHandler) ArchiveProcess(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "用户认证信息无效",
+			"code":  "INVALID_USER_CONTEXT",
+		})
+	}
+
+	processIDStr := c.Param("id")
+	processID, err := strconv.ParseUint(processIDStr, 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "无效的流程ID",
+			"code":  "INVALID_PROCESS_ID",
+		})
+	}
+
+	err = h.processService.ArchiveProcess(ctx, uint(processID), userID)
+	if err != nil {
+		h.logger.Error("Process archive failed",
+			zap.Uint("process_id", uint(processID)),
+			zap.Error(err),
+		)
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "PROCESS_ARCHIVE_FAILED",
+		})
+	}
+
+	h.logger.Info("Process archived successfully via API",
 		zap.Uint("process_id", uint(processID)),
 		zap.Uint("user_id", userID),
 	)
 
 	return c.JSON(http.StatusOK, map[string]string{
-		"message": "流程发布成功",
+		"message": "流程归档成功",
+	})
+}
+
+// GetProcessMetadata handles getting process metadata
+func (h *ProcessHandler) GetProcessMetadata(c echo.Context) error {
+	ctx := c.Request().Context()
+	processIDStr := c.Param("id")
+	processID, err := strconv.ParseUint(processIDStr, 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "无效的流程ID",
+			"code":  "INVALID_PROCESS_ID",
+		})
+	}
+
+	metadata, err := h.processService.GetProcessMetadata(ctx, uint(processID))
+	if err != nil {
+		h.logger.Error("Failed to get process metadata",
+			zap.Uint("process_id", uint(processID)),
+			zap.Error(err),
+		)
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": err.Error(),
+			"code":  "PROCESS_NOT_FOUND",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "获取流程元数据成功",
+		"data":    metadata,
+	})
+}
+
+// UpdateProcessMetadata handles process metadata updates
+func (h *ProcessHandler) UpdateProcessMetadata(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "用户认证信息无效",
+			"code":  "INVALID_USER_CONTEXT",
+		})
+	}
+
+	processIDStr := c.Param("id")
+	processID, err := strconv.ParseUint(processIDStr, 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "无效的流程ID",
+			"code":  "INVALID_PROCESS_ID",
+		})
+	}
+
+	var req service.ProcessMetadataRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn("Invalid request body for process metadata update", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数格式错误",
+			"code":  "INVALID_REQUEST_FORMAT",
+		})
+	}
+
+	metadata, err := h.processService.UpdateProcessMetadata(ctx, uint(processID), userID, &req)
+	if err != nil {
+		h.logger.Error("Process metadata update failed",
+			zap.Uint("process_id", uint(processID)),
+			zap.Error(err),
+		)
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "PROCESS_METADATA_UPDATE_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "流程元数据更新成功",
+		"data":    metadata,
 	})
 }
 
 // GetProcessStats handles getting process statistics
 func (h *ProcessHandler) GetProcessStats(c echo.Context) error {
-	stats, err := h.processService.GetProcessStats()
+	ctx := c.Request().Context()
+	stats, err := h.processService.GetProcessStats(ctx)
 	if err != nil {
 		h.logger.Error("Failed to get process stats", zap.Error(err))
 		return c.JSON(http.StatusInternalServerError, map[string]string{
@@ -367,3 +643,167 @@ func (h *ProcessHandler) GetProcessStats(c echo.Context) error {
 		"data":    stats,
 	})
 }
+
+// GetUserReferenceIssues handles listing definitions that reference inactive or deleted users (admin only)
+func (h *ProcessHandler) GetUserReferenceIssues(c echo.Context) error {
+	ctx := c.Request().Context()
+	var userID uint64
+	if userIDStr := c.QueryParam("user_id"); userIDStr != "" {
+		var err error
+		userID, err = strconv.ParseUint(userIDStr, 10, 32)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "无效的用户ID",
+				"code":  "INVALID_USER_ID",
+			})
+		}
+	}
+
+	reports, err := h.processService.ScanUserReferences(ctx, uint(userID))
+	if err != nil {
+		h.logger.Error("Failed to scan user references", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+			"code":  "SCAN_USER_REFERENCES_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "获取用户引用检查结果成功",
+		"data":    reports,
+	})
+}
+
+// RemapUserReferences handles bulk replacement of user references in definitions (admin only)
+func (h *ProcessHandler) RemapUserReferences(c echo.Context) error {
+	ctx := c.Request().Context()
+	var req service.RemapUserReferencesRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn("Invalid request body for user reference remap", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数格式错误",
+			"code":  "INVALID_REQUEST_FORMAT",
+		})
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		h.logger.Warn("User reference remap validation failed", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数验证失败",
+			"code":  "VALIDATION_FAILED",
+		})
+	}
+
+	results, err := h.processService.RemapUserReferences(ctx, &req)
+	if err != nil {
+		h.logger.Error("User reference remap failed", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "REMAP_USER_REFERENCES_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "用户引用替换成功",
+		"data":    results,
+	})
+}
+
+// ListComplexityBudgets handles listing complexity budgets
+func (h *ProcessHandler) ListComplexityBudgets(c echo.Context) error {
+	ctx := c.Request().Context()
+	budgets, err := h.processService.ListComplexityBudgets(ctx)
+	if err != nil {
+		h.logger.Error("Failed to list complexity budgets", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+			"code":  "LIST_COMPLEXITY_BUDGETS_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "获取复杂度预算成功",
+		"data":    budgets,
+	})
+}
+
+// UpdateComplexityBudget handles setting the complexity budget of a definition key ("*" for the default)
+func (h *ProcessHandler) UpdateComplexityBudget(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "用户认证信息无效",
+			"code":  "INVALID_USER_CONTEXT",
+		})
+	}
+
+	var req service.ComplexityBudgetRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn("Invalid request body for complexity budget", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数格式错误",
+			"code":  "INVALID_REQUEST_FORMAT",
+		})
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		h.logger.Warn("Complexity budget validation failed", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数验证失败",
+			"code":  "VALIDATION_FAILED",
+		})
+	}
+
+	budget, err := h.processService.UpdateComplexityBudget(ctx, c.Param("key"), &req, userID)
+	if err != nil {
+		h.logger.Warn("Failed to update complexity budget", zap.String("key", c.Param("key")), zap.Error(err))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "UPDATE_COMPLEXITY_BUDGET_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "复杂度预算更新成功",
+		"data":    budget,
+	})
+}
+
+// DeleteComplexityBudget handles removing the complexity budget of a definition key
+func (h *ProcessHandler) DeleteComplexityBudget(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "用户认证信息无效",
+			"code":  "INVALID_USER_CONTEXT",
+		})
+	}
+
+	if err := h.processService.DeleteComplexityBudget(ctx, c.Param("key"), userID); err != nil {
+		h.logger.Error("Failed to delete complexity budget", zap.String("key", c.Param("key")), zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+			"code":  "DELETE_COMPLEXITY_BUDGET_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "复杂度预算已删除",
+	})
+}
+
+// processErrorResponse builds the error body of a process operation; definition
+// validation errors also carry the graph issues for the designer to highlight
+func processErrorResponse(err error, code string) map[string]interface{} {
+	body := map[string]interface{}{
+		"error": err.Error(),
+		"code":  code,
+	}
+	var validationErr *service.DefinitionValidationError
+	if errors.As(err, &validationErr) {
+		body["issues"] = validationErr.Issues
+	}
+	return body
+}
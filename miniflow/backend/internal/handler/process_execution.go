@@ -1,12 +1,17 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"miniflow/internal/engine"
+	"miniflow/internal/model"
+	"miniflow/internal/repository"
 	"miniflow/pkg/logger"
+	"miniflow/pkg/pagination"
 
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
@@ -35,11 +40,13 @@ type StartProcessRequest struct {
 	Priority    int                    `json:"priority" validate:"min=1,max=100"`
 	DueDate     *time.Time             `json:"due_date"`
 	Tags        []string               `json:"tags"`
+	Trace       bool                   `json:"trace"` // 记录详细的执行跟踪，需要 instance_trace 权限
 }
 
 // StartProcess 启动流程实例
 // POST /api/v1/process/:id/start
 func (h *ProcessExecutionHandler) StartProcess(c echo.Context) error {
+	ctx := c.Request().Context()
 	h.logger.Info("Starting process execution API call")
 
 	// 解析流程定义ID
@@ -75,17 +82,19 @@ func (h *ProcessExecutionHandler) StartProcess(c echo.Context) error {
 		DefinitionID: uint(processID),
 		BusinessKey:  req.BusinessKey,
 		Variables:    req.Variables,
+		DueDate:      req.DueDate,
+		Trace:        req.Trace,
 	}
 
 	// 启动流程实例
-	instance, err := h.engine.StartProcess(startReq, userID)
+	instance, err := h.engine.StartProcess(ctx, startReq, userID)
 	if err != nil {
 		h.logger.Error("Failed to start process",
 			zap.Uint("process_id", uint(processID)),
 			zap.Uint("user_id", userID),
 			zap.Error(err),
 		)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to start process: "+err.Error())
+		return engineHTTPError("Failed to start process: ", err)
 	}
 
 	h.logger.Info("Process started successfully",
@@ -94,16 +103,24 @@ func (h *ProcessExecutionHandler) StartProcess(c echo.Context) error {
 		zap.Uint("user_id", userID),
 	)
 
+	// 疑似重复提交提示给发起人，检测失败不影响启动结果
+	duplicates, err := h.engine.GetInstanceDuplicates(ctx, instance.ID)
+	if err != nil {
+		h.logger.Warn("Failed to get possible duplicates", zap.Uint("instance_id", instance.ID), zap.Error(err))
+	}
+
 	return c.JSON(http.StatusCreated, map[string]interface{}{
-		"success": true,
-		"message": "Process started successfully",
-		"data":    instance,
+		"success":             true,
+		"message":             "Process started successfully",
+		"data":                instance,
+		"possible_duplicates": duplicates,
 	})
 }
 
 // GetInstance 获取流程实例详情
 // GET /api/v1/instance/:id
 func (h *ProcessExecutionHandler) GetInstance(c echo.Context) error {
+	ctx := c.Request().Context()
 	// 解析实例ID
 	instanceIDStr := c.Param("id")
 	instanceID, err := strconv.ParseUint(instanceIDStr, 10, 32)
@@ -113,10 +130,10 @@ func (h *ProcessExecutionHandler) GetInstance(c echo.Context) error {
 	}
 
 	// 获取流程实例
-	instance, err := h.engine.GetInstance(uint(instanceID))
+	instance, err := h.engine.GetInstance(ctx, uint(instanceID))
 	if err != nil {
 		h.logger.Error("Failed to get instance", zap.Uint("instance_id", uint(instanceID)), zap.Error(err))
-		return echo.NewHTTPError(http.StatusNotFound, "Instance not found")
+		return engineHTTPError("Failed to get instance: ", err)
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
@@ -127,11 +144,10 @@ func (h *ProcessExecutionHandler) GetInstance(c echo.Context) error {
 
 // GetInstancesRequest 获取实例列表请求
 type GetInstancesRequest struct {
-	Page         int    `query:"page" validate:"min=1"`
-	PageSize     int    `query:"page_size" validate:"min=1,max=100"`
 	Status       string `query:"status"`
 	DefinitionID uint   `query:"definition_id"`
 	StarterID    uint   `query:"starter_id"`
+	ParentID     uint   `query:"parent_instance_id"`
 	StartDate    string `query:"start_date"`
 	EndDate      string `query:"end_date"`
 }
@@ -139,17 +155,14 @@ type GetInstancesRequest struct {
 // GetInstances 获取流程实例列表
 // GET /api/v1/instances
 func (h *ProcessExecutionHandler) GetInstances(c echo.Context) error {
+	ctx := c.Request().Context()
 	var req GetInstancesRequest
 	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid query parameters")
 	}
 
-	// 设置默认值
-	if req.Page == 0 {
-		req.Page = 1
-	}
-	if req.PageSize == 0 {
-		req.PageSize = 20
+	if err := validateEnumParam(model.InstanceStatuses, req.Status); err != nil {
+		return err
 	}
 
 	// 构建过滤条件
@@ -163,6 +176,9 @@ func (h *ProcessExecutionHandler) GetInstances(c echo.Context) error {
 	if req.StarterID != 0 {
 		filters["starter_id"] = req.StarterID
 	}
+	if req.ParentID != 0 {
+		filters["parent_instance_id"] = req.ParentID
+	}
 
 	// 处理日期过滤
 	if req.StartDate != "" {
@@ -176,22 +192,54 @@ func (h *ProcessExecutionHandler) GetInstances(c echo.Context) error {
 		}
 	}
 
+	if pagination.UsesCursor(c.QueryParams()) {
+		return h.getInstancesAfter(c, filters)
+	}
+
+	pageReq, err := pagination.Parse(c.QueryParams(), pagination.Default)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
 	// 获取实例列表
-	instances, total, err := h.engine.GetInstances((req.Page-1)*req.PageSize, req.PageSize, filters)
+	instances, total, err := h.engine.GetInstances(ctx, pageReq.Offset(), pageReq.Limit(), filters)
 	if err != nil {
 		h.logger.Error("Failed to get instances", zap.Error(err))
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get instances")
+		return engineHTTPError("Failed to get instances: ", err)
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"success": true,
-		"data": map[string]interface{}{
-			"instances":   instances,
-			"total":       total,
-			"page":        req.Page,
-			"page_size":   req.PageSize,
-			"total_pages": (total + int64(req.PageSize) - 1) / int64(req.PageSize),
-		},
+		"data":    pageReq.Result("instances", instances, total),
+	})
+}
+
+// getInstancesAfter 按游标分页获取流程实例列表，按启动时间和ID倒序排列，不返回总数
+func (h *ProcessExecutionHandler) getInstancesAfter(c echo.Context, filters map[string]interface{}) error {
+	pageReq, err := pagination.ParseCursor(c.QueryParams(), pagination.Default)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	at, afterID, err := pagination.DecodeCursor(pageReq.Cursor)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid cursor")
+	}
+
+	instances, err := h.engine.GetInstancesAfter(c.Request().Context(), repository.Keyset{At: at, ID: afterID}, pageReq.Limit(), filters)
+	if err != nil {
+		h.logger.Error("Failed to get instances", zap.Error(err))
+		return engineHTTPError("Failed to get instances: ", err)
+	}
+
+	var nextCursor string
+	if len(instances) > 0 {
+		last := instances[len(instances)-1]
+		nextCursor = pagination.EncodeCursor(last.StartTime, last.ID)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    pageReq.CursorResult("instances", instances, len(instances), nextCursor),
 	})
 }
 
@@ -203,6 +251,7 @@ type SuspendInstanceRequest struct {
 // SuspendInstance 暂停流程实例
 // POST /api/v1/instance/:id/suspend
 func (h *ProcessExecutionHandler) SuspendInstance(c echo.Context) error {
+	ctx := c.Request().Context()
 	// 解析实例ID
 	instanceIDStr := c.Param("id")
 	instanceID, err := strconv.ParseUint(instanceIDStr, 10, 32)
@@ -221,9 +270,9 @@ func (h *ProcessExecutionHandler) SuspendInstance(c echo.Context) error {
 	}
 
 	// 暂停流程实例
-	if err := h.engine.SuspendInstance(uint(instanceID), req.Reason); err != nil {
+	if err := h.engine.SuspendInstance(ctx, uint(instanceID), getUserIDFromContext(c), req.Reason); err != nil {
 		h.logger.Error("Failed to suspend instance", zap.Uint("instance_id", uint(instanceID)), zap.Error(err))
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to suspend instance: "+err.Error())
+		return engineHTTPError("Failed to suspend instance: ", err)
 	}
 
 	h.logger.Info("Instance suspended successfully", zap.Uint("instance_id", uint(instanceID)))
@@ -237,6 +286,7 @@ func (h *ProcessExecutionHandler) SuspendInstance(c echo.Context) error {
 // ResumeInstance 恢复流程实例
 // POST /api/v1/instance/:id/resume
 func (h *ProcessExecutionHandler) ResumeInstance(c echo.Context) error {
+	ctx := c.Request().Context()
 	// 解析实例ID
 	instanceIDStr := c.Param("id")
 	instanceID, err := strconv.ParseUint(instanceIDStr, 10, 32)
@@ -245,9 +295,9 @@ func (h *ProcessExecutionHandler) ResumeInstance(c echo.Context) error {
 	}
 
 	// 恢复流程实例
-	if err := h.engine.ResumeInstance(uint(instanceID)); err != nil {
+	if err := h.engine.ResumeInstance(ctx, uint(instanceID), getUserIDFromContext(c)); err != nil {
 		h.logger.Error("Failed to resume instance", zap.Uint("instance_id", uint(instanceID)), zap.Error(err))
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to resume instance: "+err.Error())
+		return engineHTTPError("Failed to resume instance: ", err)
 	}
 
 	h.logger.Info("Instance resumed successfully", zap.Uint("instance_id", uint(instanceID)))
@@ -266,6 +316,7 @@ type CancelInstanceRequest struct {
 // CancelInstance 取消流程实例
 // POST /api/v1/instance/:id/cancel
 func (h *ProcessExecutionHandler) CancelInstance(c echo.Context) error {
+	ctx := c.Request().Context()
 	// 解析实例ID
 	instanceIDStr := c.Param("id")
 	instanceID, err := strconv.ParseUint(instanceIDStr, 10, 32)
@@ -284,9 +335,9 @@ func (h *ProcessExecutionHandler) CancelInstance(c echo.Context) error {
 	}
 
 	// 取消流程实例
-	if err := h.engine.CancelInstance(uint(instanceID), req.Reason); err != nil {
+	if err := h.engine.CancelInstance(ctx, uint(instanceID), getUserIDFromContext(c), req.Reason); err != nil {
 		h.logger.Error("Failed to cancel instance", zap.Uint("instance_id", uint(instanceID)), zap.Error(err))
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to cancel instance: "+err.Error())
+		return engineHTTPError("Failed to cancel instance: ", err)
 	}
 
 	h.logger.Info("Instance cancelled successfully", zap.Uint("instance_id", uint(instanceID)))
@@ -297,9 +348,186 @@ func (h *ProcessExecutionHandler) CancelInstance(c echo.Context) error {
 	})
 }
 
-// GetInstanceHistory 获取流程执行历史
-// GET /api/v1/instance/:id/history
+// MigrateInstanceRequest 实例版本迁移请求，node_mapping 把当前版本的节点ID映射到目标版本的节点ID
+type MigrateInstanceRequest struct {
+	TargetVersion int               `json:"target_version" validate:"required,min=1"`
+	NodeMapping   map[string]string `json:"node_mapping"`
+}
+
+// MigrateInstance 把流程实例迁移到同一流程的另一个已发布版本，需要 instance_migrate 权限
+// POST /api/v1/instance/:id/migrate
+func (h *ProcessExecutionHandler) MigrateInstance(c echo.Context) error {
+	ctx := c.Request().Context()
+	instanceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid instance ID")
+	}
+
+	var req MigrateInstanceRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	instance, err := h.engine.MigrateInstance(ctx, uint(instanceID), req.TargetVersion, req.NodeMapping, getUserIDFromContext(c))
+	if err != nil {
+		h.logger.Error("Failed to migrate instance", zap.Uint("instance_id", uint(instanceID)), zap.Error(err))
+		return engineHTTPError("Failed to migrate instance: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    instance,
+	})
+}
+
+// ModifyExecutionRequest 修改实例执行位置请求，reason 记录到活动历史中
+type ModifyExecutionRequest struct {
+	TargetNodeID string `json:"target_node_id" validate:"required,max=64"`
+	Reason       string `json:"reason" validate:"required,max=500"`
+}
+
+// ModifyExecution 把运行中的流程实例强制移动到指定节点，只有管理员可以操作
+// POST /api/v1/admin/instance/:id/modify-execution
+func (h *ProcessExecutionHandler) ModifyExecution(c echo.Context) error {
+	ctx := c.Request().Context()
+	instanceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid instance ID")
+	}
+
+	var req ModifyExecutionRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	instance, err := h.engine.ModifyExecution(ctx, uint(instanceID), req.TargetNodeID, req.Reason, getUserIDFromContext(c))
+	if err != nil {
+		h.logger.Error("Failed to modify instance execution", zap.Uint("instance_id", uint(instanceID)), zap.Error(err))
+		return engineHTTPError("Failed to modify instance execution: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    instance,
+	})
+}
+
+// ExportRuntimeSnapshot 导出运行时状态快照，用于灾备演练，只有管理员可以导出
+// GET /api/v1/admin/runtime-snapshot
+func (h *ProcessExecutionHandler) ExportRuntimeSnapshot(c echo.Context) error {
+	ctx := c.Request().Context()
+	snapshot, err := h.engine.ExportRuntimeSnapshot(ctx, getUserIDFromContext(c))
+	if err != nil {
+		h.logger.Error("Failed to export runtime snapshot", zap.Error(err))
+		return engineHTTPError("Failed to export runtime snapshot: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    snapshot,
+	})
+}
+
+// ImportRuntimeSnapshot 在备用环境导入运行时状态快照，请求体为导出接口返回的 data，
+// dry_run=true 时只校验并统计行数，不写入，只有管理员可以导入
+// POST /api/v1/admin/runtime-snapshot/import
+func (h *ProcessExecutionHandler) ImportRuntimeSnapshot(c echo.Context) error {
+	ctx := c.Request().Context()
+	var snapshot model.RuntimeSnapshot
+	if err := c.Bind(&snapshot); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid snapshot")
+	}
+	dryRun := c.QueryParam("dry_run") == "true"
+
+	result, err := h.engine.ImportRuntimeSnapshot(ctx, &snapshot, getUserIDFromContext(c), dryRun)
+	if err != nil {
+		h.logger.Error("Failed to import runtime snapshot", zap.Bool("dry_run", dryRun), zap.Error(err))
+		return engineHTTPError("Failed to import runtime snapshot: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// PurgeInstanceRequest 清除流程实例请求
+type PurgeInstanceRequest struct {
+	Reason string `json:"reason" validate:"max=500"`
+}
+
+// PurgeInstance 物理清除已结束的流程实例及其关联数据，并记录清除凭证
+// DELETE /api/v1/admin/instance/:id
+func (h *ProcessExecutionHandler) PurgeInstance(c echo.Context) error {
+	ctx := c.Request().Context()
+	instanceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid instance ID")
+	}
+
+	var req PurgeInstanceRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	certificates, err := h.engine.PurgeInstance(ctx, uint(instanceID), userID, req.Reason)
+	if err != nil {
+		h.logger.Error("Failed to purge instance", zap.Uint("instance_id", uint(instanceID)), zap.Error(err))
+		return engineHTTPError("Failed to purge instance: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data": map[string]interface{}{
+			"certificates": certificates,
+		},
+	})
+}
+
+// GetPurgeCertificates 获取已清除实例的清除凭证
+// GET /api/v1/admin/instance/:id/purge-certificates
+func (h *ProcessExecutionHandler) GetPurgeCertificates(c echo.Context) error {
+	ctx := c.Request().Context()
+	instanceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid instance ID")
+	}
+
+	certificates, err := h.engine.GetPurgeCertificates(ctx, uint(instanceID))
+	if err != nil {
+		h.logger.Error("Failed to get purge certificates", zap.Uint("instance_id", uint(instanceID)), zap.Error(err))
+		return engineHTTPError("Failed to get purge certificates: ", err)
+	}
+	if len(certificates) == 0 {
+		return echo.NewHTTPError(http.StatusNotFound, "Purge certificate not found")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    certificates,
+	})
+}
+
+// GetInstanceHistory 获取流程执行历史，活动历史默认按发生顺序排列
+// GET /api/v1/instance/:id/history?types=node_entered,gateway_decision&order=desc
 func (h *ProcessExecutionHandler) GetInstanceHistory(c echo.Context) error {
+	ctx := c.Request().Context()
 	// 解析实例ID
 	instanceIDStr := c.Param("id")
 	instanceID, err := strconv.ParseUint(instanceIDStr, 10, 32)
@@ -307,16 +535,316 @@ func (h *ProcessExecutionHandler) GetInstanceHistory(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid instance ID")
 	}
 
-	// 获取执行历史
-	history, err := h.engine.GetInstanceHistory(uint(instanceID))
+	query := &repository.ActivityHistoryQuery{
+		Descending: c.QueryParam("order") == "desc",
+	}
+	if types := c.QueryParam("types"); types != "" {
+		for _, t := range strings.Split(types, ",") {
+			if t = strings.TrimSpace(t); t == "" {
+				continue
+			}
+			if httpErr := validateEnumParam(model.ActivityTypes, t); httpErr != nil {
+				return httpErr
+			}
+			query.Types = append(query.Types, t)
+		}
+	}
+
+	// 获取执行历史，活动历史逐条写出
+	history, err := h.engine.GetInstanceHistorySummary(ctx, uint(instanceID))
 	if err != nil {
 		h.logger.Error("Failed to get instance history", zap.Uint("instance_id", uint(instanceID)), zap.Error(err))
+		return engineHTTPError("Failed to get instance history: ", err)
+	}
+
+	err = streamJSONArray(c, history, "activities", func(emit func(interface{}) error) error {
+		return h.engine.EachActivity(ctx, uint(instanceID), query, func(activity *model.ActivityHistory) error {
+			return emit(activity)
+		})
+	})
+	if err != nil {
+		h.logger.Error("Failed to stream instance history", zap.Uint("instance_id", uint(instanceID)), zap.Error(err))
+		if c.Response().Committed {
+			return err
+		}
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get instance history")
 	}
+	return nil
+}
+
+// GetInstanceSchedule 获取流程实例的截止时间计划和剩余关键路径
+// GET /api/v1/instance/:id/schedule
+func (h *ProcessExecutionHandler) GetInstanceSchedule(c echo.Context) error {
+	ctx := c.Request().Context()
+	instanceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid instance ID")
+	}
+
+	schedule, err := h.engine.GetInstanceSchedule(ctx, uint(instanceID))
+	if err != nil {
+		h.logger.Error("Failed to get instance schedule", zap.Uint("instance_id", uint(instanceID)), zap.Error(err))
+		return engineHTTPError("Failed to get instance schedule: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    schedule,
+	})
+}
+
+// GetInstanceNextSteps 按当前变量预览运行中实例完成当前节点后会走的路径
+// GET /api/v1/instance/:id/next-steps
+func (h *ProcessExecutionHandler) GetInstanceNextSteps(c echo.Context) error {
+	ctx := c.Request().Context()
+	instanceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid instance ID")
+	}
+
+	preview, err := h.engine.PreviewNextSteps(ctx, uint(instanceID))
+	if err != nil {
+		h.logger.Error("Failed to preview next steps", zap.Uint("instance_id", uint(instanceID)), zap.Error(err))
+		return engineHTTPError("Failed to preview next steps: ", err)
+	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"success": true,
-		"data":    history,
+		"data":    preview,
+	})
+}
+
+// GetInstanceTrace 获取流程实例的执行跟踪记录，需要 instance_trace 权限，实例需在启动时开启跟踪
+// GET /api/v1/instance/:id/trace
+func (h *ProcessExecutionHandler) GetInstanceTrace(c echo.Context) error {
+	ctx := c.Request().Context()
+	instanceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid instance ID")
+	}
+
+	// 跟踪记录逐条写出，data 为跟踪记录数组
+	err = streamJSONArray(c, nil, "", func(emit func(interface{}) error) error {
+		return h.engine.EachInstanceTrace(ctx, uint(instanceID), getUserIDFromContext(c), func(trace *model.ExecutionTrace) error {
+			return emit(trace)
+		})
+	})
+	if err != nil {
+		if c.Response().Committed {
+			h.logger.Error("Failed to stream instance trace", zap.Uint("instance_id", uint(instanceID)), zap.Error(err))
+			return err
+		}
+		h.logger.Error("Failed to get instance trace", zap.Uint("instance_id", uint(instanceID)), zap.Error(err))
+		return engineHTTPError("Failed to get instance trace: ", err)
+	}
+	return nil
+}
+
+// GetInstanceDuplicates 获取流程实例的疑似重复记录，发起人和流程负责人可见
+// GET /api/v1/instance/:id/duplicates
+func (h *ProcessExecutionHandler) GetInstanceDuplicates(c echo.Context) error {
+	ctx := c.Request().Context()
+	instanceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid instance ID")
+	}
+
+	if err := h.checkDuplicateAccess(c, uint(instanceID)); err != nil {
+		return err
+	}
+
+	duplicates, err := h.engine.GetInstanceDuplicates(ctx, uint(instanceID))
+	if err != nil {
+		h.logger.Error("Failed to get instance duplicates", zap.Uint("instance_id", uint(instanceID)), zap.Error(err))
+		return engineHTTPError("Failed to get instance duplicates: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    duplicates,
+	})
+}
+
+// GetUserDuplicates 获取当前用户发起的实例上待处理的疑似重复记录
+// GET /api/v1/user/duplicates
+func (h *ProcessExecutionHandler) GetUserDuplicates(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	duplicates, err := h.engine.GetOpenDuplicates(ctx, userID)
+	if err != nil {
+		h.logger.Error("Failed to get user duplicates", zap.Uint("user_id", userID), zap.Error(err))
+		return engineHTTPError("Failed to get possible duplicates: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    duplicates,
+	})
+}
+
+// ConfirmDuplicate 确认实例为重复提交并取消，取消原因关联原实例
+// POST /api/v1/instance/:id/duplicates/:dupId/confirm
+func (h *ProcessExecutionHandler) ConfirmDuplicate(c echo.Context) error {
+	return h.resolveDuplicate(c, true)
+}
+
+// DismissDuplicate 忽略疑似重复标记
+// POST /api/v1/instance/:id/duplicates/:dupId/dismiss
+func (h *ProcessExecutionHandler) DismissDuplicate(c echo.Context) error {
+	return h.resolveDuplicate(c, false)
+}
+
+// resolveDuplicate 处理疑似重复标记：confirm 为 true 时取消实例，否则忽略标记
+func (h *ProcessExecutionHandler) resolveDuplicate(c echo.Context, confirm bool) error {
+	ctx := c.Request().Context()
+	instanceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid instance ID")
+	}
+	duplicateID, err := strconv.ParseUint(c.Param("dupId"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid duplicate ID")
+	}
+
+	if err := h.checkDuplicateAccess(c, uint(instanceID)); err != nil {
+		return err
+	}
+
+	userID := getUserIDFromContext(c)
+	var duplicate *model.InstanceDuplicate
+	if confirm {
+		duplicate, err = h.engine.ConfirmDuplicate(ctx, uint(instanceID), uint(duplicateID), userID)
+	} else {
+		duplicate, err = h.engine.DismissDuplicate(ctx, uint(instanceID), uint(duplicateID), userID)
+	}
+	if err != nil {
+		h.logger.Warn("Failed to resolve duplicate",
+			zap.Uint("instance_id", uint(instanceID)),
+			zap.Uint("duplicate_id", uint(duplicateID)),
+			zap.Bool("confirm", confirm),
+			zap.Error(err),
+		)
+		return engineHTTPError("Failed to resolve duplicate: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    duplicate,
+	})
+}
+
+// checkDuplicateAccess 只有实例发起人和流程负责人可以查看和处理疑似重复记录
+func (h *ProcessExecutionHandler) checkDuplicateAccess(c echo.Context, instanceID uint) error {
+	ctx := c.Request().Context()
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	instance, err := h.engine.GetInstance(ctx, instanceID)
+	if err != nil {
+		return engineHTTPError("Failed to get instance: ", err)
+	}
+	if instance.StarterID != userID && instance.Definition.CreatedBy != userID {
+		return echo.NewHTTPError(http.StatusForbidden, "Only the starter or the process owner can manage duplicates")
+	}
+	return nil
+}
+
+// timelinePagination 时间线分页参数，单页条目较多
+var timelinePagination = pagination.Options{DefaultPageSize: 50, MaxPageSize: 200}
+
+// GetInstanceTimeline 获取流程实例时间线
+// GET /api/v1/instance/:id/timeline?types=task,incident&order=desc&page=1&page_size=50
+func (h *ProcessExecutionHandler) GetInstanceTimeline(c echo.Context) error {
+	ctx := c.Request().Context()
+	instanceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid instance ID")
+	}
+
+	pageReq, err := pagination.Parse(c.QueryParams(), timelinePagination)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	query := &engine.TimelineQuery{
+		Descending: c.QueryParam("order") == "desc",
+		Offset:     pageReq.Offset(),
+		Limit:      pageReq.Limit(),
+	}
+	if types := c.QueryParam("types"); types != "" {
+		for _, t := range strings.Split(types, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				query.Categories = append(query.Categories, t)
+			}
+		}
+	}
+
+	timeline, err := h.engine.GetInstanceTimeline(ctx, uint(instanceID), query)
+	if err != nil {
+		h.logger.Error("Failed to get instance timeline", zap.Uint("instance_id", uint(instanceID)), zap.Error(err))
+		return engineHTTPError("Failed to get instance timeline: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    pageReq.Result("entries", timeline.Entries, int64(timeline.Total)),
+	})
+}
+
+// engineHTTPError 构建引擎错误响应 {"message", "code", "fault"}
+// 状态码和代码按错误类别统一决定：带失败代码时使用目录中该代码的状态码，记录不存在为 404，
+// 并发冲突为 409，取值非法按 enumHTTPError 返回 400，无法归类的错误为系统故障，返回 500。
+// fault 为 user 表示请求本身有问题，为 system 表示系统故障，可以稍后重试
+func engineHTTPError(message string, err error) *echo.HTTPError {
+	if httpErr := enumHTTPError(err); httpErr != nil {
+		return httpErr
+	}
+
+	kind := engine.ErrorKindOf(err)
+	status, code := kind.HTTPStatus, engine.FailureCodeOf(err)
+	if entry, ok := engine.LookupFailureCode(code); ok {
+		status = entry.HTTPStatus
+	} else {
+		code = kind.Code
+	}
+	fault := "system"
+	if kind.UserFault {
+		fault = "user"
+	}
+	return echo.NewHTTPError(status, map[string]interface{}{
+		"message": message + err.Error(),
+		"code":    code,
+		"fault":   fault,
+	})
+}
+
+// validateEnumParam 校验枚举类型的查询参数，取值非法时返回 400 并列出允许的取值，空值不校验
+func validateEnumParam(enum model.Enum, value string) *echo.HTTPError {
+	if value == "" {
+		return nil
+	}
+	if err := enum.Validate(value); err != nil {
+		return enumHTTPError(err)
+	}
+	return nil
+}
+
+// enumHTTPError 构建取值非法的错误响应 {"message", "code", "allowed"}，错误链上没有 EnumError 时返回 nil
+func enumHTTPError(err error) *echo.HTTPError {
+	var enumErr *model.EnumError
+	if !errors.As(err, &enumErr) {
+		return nil
+	}
+	return echo.NewHTTPError(http.StatusBadRequest, map[string]interface{}{
+		"message": enumErr.Error(),
+		"code":    "INVALID_ENUM_VALUE",
+		"allowed": enumErr.Allowed,
 	})
 }
 
@@ -329,3 +857,89 @@ func getUserIDFromContext(c echo.Context) uint {
 	}
 	return 0
 }
+
+// AnalyzeWhatIf 假设分析：用历史实例变量模拟新版本的路由分布
+// POST /api/v1/process/:id/what-if
+func (h *ProcessExecutionHandler) AnalyzeWhatIf(c echo.Context) error {
+	ctx := c.Request().Context()
+	processID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid process ID")
+	}
+
+	var req engine.WhatIfRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	report, err := h.engine.AnalyzeWhatIf(ctx, uint(processID), &req)
+	if err != nil {
+		h.logger.Error("Failed to analyze what-if",
+			zap.Uint("process_id", uint(processID)),
+			zap.Error(err),
+		)
+		return engineHTTPError("What-if analysis failed: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    report,
+	})
+}
+
+// GetRolloutReport 获取流程的灰度发布状态和新旧版本指标对比
+func (h *ProcessExecutionHandler) GetRolloutReport(c echo.Context) error {
+	ctx := c.Request().Context()
+	processID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid process ID")
+	}
+
+	report, err := h.engine.GetRolloutReport(ctx, uint(processID))
+	if err != nil {
+		h.logger.Error("Failed to get rollout report",
+			zap.Uint("process_id", uint(processID)),
+			zap.Error(err),
+		)
+		return engineHTTPError("Rollout report not available: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    report,
+	})
+}
+
+// CompareInstances 对比两个同一流程的实例
+// GET /api/v1/instances/compare?ids=1,2
+func (h *ProcessExecutionHandler) CompareInstances(c echo.Context) error {
+	ctx := c.Request().Context()
+	parts := strings.Split(c.QueryParam("ids"), ",")
+	if len(parts) != 2 {
+		return echo.NewHTTPError(http.StatusBadRequest, "ids must contain exactly two instance IDs")
+	}
+
+	ids := make([]uint, 0, len(parts))
+	for _, part := range parts {
+		id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 32)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid instance ID: "+part)
+		}
+		ids = append(ids, uint(id))
+	}
+
+	comparison, err := h.engine.CompareInstances(ctx, ids[0], ids[1])
+	if err != nil {
+		h.logger.Error("Failed to compare instances",
+			zap.Uint("left_id", ids[0]),
+			zap.Uint("right_id", ids[1]),
+			zap.Error(err),
+		)
+		return engineHTTPError("Instance comparison failed: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    comparison,
+	})
+}
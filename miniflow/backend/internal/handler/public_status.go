@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"miniflow/internal/engine"
+	"miniflow/pkg/logger"
+	"miniflow/pkg/utils"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// 公开状态链接有效期
+const (
+	defaultStatusLinkHours = 24 * 30
+	maxStatusLinkHours     = 24 * 365
+)
+
+// PublicStatusHandler 流程实例公开状态链接处理器
+type PublicStatusHandler struct {
+	engine     *engine.ProcessEngine
+	jwtManager *utils.JWTManager
+	logger     *logger.Logger
+}
+
+// NewPublicStatusHandler 创建公开状态链接处理器
+func NewPublicStatusHandler(engine *engine.ProcessEngine, jwtManager *utils.JWTManager, logger *logger.Logger) *PublicStatusHandler {
+	return &PublicStatusHandler{
+		engine:     engine,
+		jwtManager: jwtManager,
+		logger:     logger,
+	}
+}
+
+// CreateStatusLinkRequest 创建公开状态链接请求
+type CreateStatusLinkRequest struct {
+	ExpiresInHours int `json:"expires_in_hours" validate:"min=0,max=8760"`
+}
+
+// StatusLinkResponse 公开状态链接
+type StatusLinkResponse struct {
+	Token     string    `json:"token"`
+	Path      string    `json:"path"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CreateStatusLink 为流程实例生成签名的只读状态链接，只有流程发起人可以分享
+// POST /api/v1/instance/:id/status-link
+func (h *PublicStatusHandler) CreateStatusLink(c echo.Context) error {
+	ctx := c.Request().Context()
+	instanceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid instance ID")
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	var req CreateStatusLinkRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	instance, err := h.engine.GetInstance(ctx, uint(instanceID))
+	if err != nil {
+		return engineHTTPError("Failed to get instance: ", err)
+	}
+	if instance.StarterID != userID {
+		return echo.NewHTTPError(http.StatusForbidden, "Only the starter can share the instance status")
+	}
+
+	hours := req.ExpiresInHours
+	if hours == 0 {
+		hours = defaultStatusLinkHours
+	}
+	if hours > maxStatusLinkHours {
+		hours = maxStatusLinkHours
+	}
+
+	token, expiresAt, err := h.jwtManager.GenerateStatusLinkToken(instance.ID, time.Duration(hours)*time.Hour)
+	if err != nil {
+		h.logger.Error("Failed to generate status link", zap.Uint("instance_id", instance.ID), zap.Error(err))
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to generate status link")
+	}
+
+	h.logger.Info("Instance status link created",
+		zap.Uint("instance_id", instance.ID),
+		zap.Uint("user_id", userID),
+		zap.Time("expires_at", expiresAt),
+	)
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"data": &StatusLinkResponse{
+			Token:     token,
+			Path:      "/api/v1/public/status/" + token,
+			ExpiresAt: expiresAt,
+		},
+	})
+}
+
+// GetPublicStatus 通过签名链接查看流程实例的只读状态，无需登录
+// GET /api/v1/public/status/:token
+func (h *PublicStatusHandler) GetPublicStatus(c echo.Context) error {
+	ctx := c.Request().Context()
+	instanceID, err := h.jwtManager.ParseStatusLinkToken(c.Param("token"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Status link is invalid or expired")
+	}
+
+	status, err := h.engine.GetPublicStatus(ctx, instanceID)
+	if err != nil {
+		h.logger.Warn("Failed to get public instance status", zap.Uint("instance_id", instanceID), zap.Error(err))
+		return echo.NewHTTPError(http.StatusNotFound, "Status link is invalid or expired")
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    status,
+	})
+}
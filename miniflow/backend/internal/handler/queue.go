@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"net/http"
+
+	"miniflow/internal/engine"
+	"miniflow/pkg/logger"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// QueueHandler 组任务队列API处理器
+type QueueHandler struct {
+	queue  *engine.TaskQueue
+	logger *logger.Logger
+}
+
+// NewQueueHandler 创建组任务队列处理器
+func NewQueueHandler(queue *engine.TaskQueue, logger *logger.Logger) *QueueHandler {
+	return &QueueHandler{
+		queue:  queue,
+		logger: logger,
+	}
+}
+
+// NextQueueTaskRequest 获取下一个任务请求，mode 为 priority（默认）或 fair_share，assign 为 true 时直接认领
+type NextQueueTaskRequest struct {
+	Mode   string `json:"mode"`
+	Assign bool   `json:"assign"`
+}
+
+// NextTask 为当前用户获取组队列中的下一个任务，没有可分配的任务时 data.task 为空并说明原因
+// POST /api/v1/queues/:group/next
+func (h *QueueHandler) NextTask(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	var req NextQueueTaskRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if err := validateEnumParam(engine.QueueModes, req.Mode); err != nil {
+		return err
+	}
+
+	group := c.Param("group")
+	dispatch, err := h.queue.Next(ctx, group, userID, req.Mode, req.Assign)
+	if err != nil {
+		h.logger.Error("Failed to get next queue task",
+			zap.String("group", group),
+			zap.Uint("user_id", userID),
+			zap.Error(err),
+		)
+		return engineHTTPError("Failed to get next queue task: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    dispatch,
+	})
+}
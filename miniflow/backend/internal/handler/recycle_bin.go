@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"miniflow/internal/engine"
+	"miniflow/pkg/logger"
+	"miniflow/pkg/pagination"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// RecycleBinHandler 已取消流程实例回收站API处理器
+type RecycleBinHandler struct {
+	recycleBin *engine.RecycleBin
+	logger     *logger.Logger
+}
+
+// NewRecycleBinHandler 创建回收站处理器
+func NewRecycleBinHandler(recycleBin *engine.RecycleBin, logger *logger.Logger) *RecycleBinHandler {
+	return &RecycleBinHandler{
+		recycleBin: recycleBin,
+		logger:     logger,
+	}
+}
+
+// ListCancelled 获取仍可撤销取消的流程实例，restorable_until 为撤销的截止时间
+// GET /api/v1/admin/recycle-bin
+func (h *RecycleBinHandler) ListCancelled(c echo.Context) error {
+	ctx := c.Request().Context()
+	pageReq, err := pagination.Parse(c.QueryParams(), pagination.Default)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	entries, total, err := h.recycleBin.List(ctx, getUserIDFromContext(c), pageReq.Offset(), pageReq.Limit())
+	if err != nil {
+		h.logger.Error("Failed to list recycle bin", zap.Error(err))
+		return engineHTTPError("Failed to list recycle bin: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    pageReq.Result("instances", entries, total),
+	})
+}
+
+// RestoreInstance 撤销流程实例的取消，恢复取消前的状态并重新打开被跳过的任务
+// POST /api/v1/admin/recycle-bin/:id/restore
+func (h *RecycleBinHandler) RestoreInstance(c echo.Context) error {
+	ctx := c.Request().Context()
+	instanceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid instance ID")
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	instance, err := h.recycleBin.Restore(ctx, uint(instanceID), userID)
+	if err != nil {
+		h.logger.Error("Failed to restore cancelled instance", zap.Uint64("instance_id", instanceID), zap.Error(err))
+		return engineHTTPError("Failed to restore instance: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    instance,
+	})
+}
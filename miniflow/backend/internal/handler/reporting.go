@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+
+	"miniflow/internal/service"
+	"miniflow/pkg/logger"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// ReportingHandler handles reporting schema HTTP requests (admin)
+type ReportingHandler struct {
+	reportingService *service.ReportingService
+	logger           *logger.Logger
+}
+
+// NewReportingHandler creates a new reporting handler
+func NewReportingHandler(reportingService *service.ReportingService, logger *logger.Logger) *ReportingHandler {
+	return &ReportingHandler{
+		reportingService: reportingService,
+		logger:           logger,
+	}
+}
+
+// GetStatus handles getting the reporting refresh status
+func (h *ReportingHandler) GetStatus(c echo.Context) error {
+	ctx := c.Request().Context()
+	status, err := h.reportingService.GetStatus(ctx)
+	if err != nil {
+		h.logger.Error("Failed to get reporting status", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+			"code":  "GET_REPORTING_STATUS_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "获取报表状态成功",
+		"data":    status,
+	})
+}
+
+// Refresh handles rebuilding the reporting tables on demand
+func (h *ReportingHandler) Refresh(c echo.Context) error {
+	ctx := c.Request().Context()
+	result, err := h.reportingService.Refresh(ctx)
+	if err != nil {
+		h.logger.Error("Failed to refresh reporting tables", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+			"code":  "REFRESH_REPORTING_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "报表数据刷新成功",
+		"data":    result,
+	})
+}
@@ -1,10 +1,11 @@
 package handler
 
 import (
+	"miniflow/internal/engine"
 	"miniflow/internal/middleware"
 	"miniflow/internal/service"
+	"miniflow/pkg/config"
 	"miniflow/pkg/logger"
-	"miniflow/pkg/utils"
 
 	"github.com/labstack/echo/v4"
 	echomiddleware "github.com/labstack/echo/v4/middleware"
@@ -16,7 +17,28 @@ type Router struct {
 	processHandler          *ProcessHandler
 	processExecutionHandler *ProcessExecutionHandler
 	taskManagementHandler   *TaskManagementHandler
+	notificationHandler     *NotificationHandler
+	announcementHandler     *AnnouncementHandler
+	integrationHandler      *IntegrationHandler
+	incidentHandler         *IncidentHandler
+	jobHandler              *JobHandler
+	webhookHandler          *WebhookHandler
+	publicStatusHandler     *PublicStatusHandler
+	queueHandler            *QueueHandler
+	recycleBinHandler       *RecycleBinHandler
+	externalTaskHandler     *ExternalTaskHandler
+	messageHandler          *MessageHandler
+	attachmentHandler       *AttachmentHandler
+	archiveHandler          *ArchiveHandler
+	connectorPolicyHandler  *ConnectorPolicyHandler
+	reportingHandler        *ReportingHandler
+	kpiHandler              *KPIHandler
+	capacityHandler         *CapacityHandler
+	deploymentHandler       *DeploymentHandler
+	selfTestHandler         *SelfTestHandler
+	organizationHandler     *OrganizationHandler
 	authMiddleware          *middleware.AuthMiddleware
+	idempotency             *middleware.IdempotencyMiddleware
 	logger                  *logger.Logger
 }
 
@@ -24,21 +46,72 @@ type Router struct {
 func NewRouter(
 	userService *service.UserService,
 	processService *service.ProcessService,
+	notificationService *service.NotificationService,
+	announcementService *service.AnnouncementService,
+	connectorPolicyService *service.ConnectorPolicyService,
+	reportingService *service.ReportingService,
+	kpiService *service.KPIService,
+	capacityService *service.CapacityService,
+	deploymentService *service.DeploymentService,
+	selfTestService *service.SelfTestService,
+	organizationService *service.OrganizationService,
 	processExecutionHandler *ProcessExecutionHandler,
 	taskManagementHandler *TaskManagementHandler,
-	jwtManager *utils.JWTManager,
+	integrationHandler *IntegrationHandler,
+	incidentHandler *IncidentHandler,
+	jobHandler *JobHandler,
+	webhookHandler *WebhookHandler,
+	publicStatusHandler *PublicStatusHandler,
+	queueHandler *QueueHandler,
+	recycleBinHandler *RecycleBinHandler,
+	externalTaskHandler *ExternalTaskHandler,
+	messageHandler *MessageHandler,
+	attachmentHandler *AttachmentHandler,
+	archiveHandler *ArchiveHandler,
+	archiver *engine.InstanceArchiver,
+	authMiddleware *middleware.AuthMiddleware,
+	idempotency *middleware.IdempotencyMiddleware,
 	logger *logger.Logger,
 ) *Router {
-	userHandler := NewUserHandler(userService, logger)
+	userHandler := NewUserHandler(userService, processService, archiver, logger)
 	processHandler := NewProcessHandler(processService, logger)
-	authMiddleware := middleware.NewAuthMiddleware(jwtManager, logger)
+	notificationHandler := NewNotificationHandler(notificationService, logger)
+	announcementHandler := NewAnnouncementHandler(announcementService, logger)
+	connectorPolicyHandler := NewConnectorPolicyHandler(connectorPolicyService, logger)
+	reportingHandler := NewReportingHandler(reportingService, logger)
+	kpiHandler := NewKPIHandler(kpiService, logger)
+	capacityHandler := NewCapacityHandler(capacityService, logger)
+	deploymentHandler := NewDeploymentHandler(deploymentService, logger)
+	selfTestHandler := NewSelfTestHandler(selfTestService, logger)
+	organizationHandler := NewOrganizationHandler(organizationService, logger)
 
 	return &Router{
 		userHandler:             userHandler,
 		processHandler:          processHandler,
 		processExecutionHandler: processExecutionHandler,
 		taskManagementHandler:   taskManagementHandler,
+		notificationHandler:     notificationHandler,
+		announcementHandler:     announcementHandler,
+		integrationHandler:      integrationHandler,
+		incidentHandler:         incidentHandler,
+		jobHandler:              jobHandler,
+		webhookHandler:          webhookHandler,
+		publicStatusHandler:     publicStatusHandler,
+		queueHandler:            queueHandler,
+		recycleBinHandler:       recycleBinHandler,
+		externalTaskHandler:     externalTaskHandler,
+		messageHandler:          messageHandler,
+		attachmentHandler:       attachmentHandler,
+		archiveHandler:          archiveHandler,
+		connectorPolicyHandler:  connectorPolicyHandler,
+		reportingHandler:        reportingHandler,
+		kpiHandler:              kpiHandler,
+		capacityHandler:         capacityHandler,
+		deploymentHandler:       deploymentHandler,
+		selfTestHandler:         selfTestHandler,
+		organizationHandler:     organizationHandler,
 		authMiddleware:          authMiddleware,
+		idempotency:             idempotency,
 		logger:                  logger,
 	}
 }
@@ -56,6 +129,9 @@ func (r *Router) SetupRoutes(e *echo.Echo) {
 	// Security headers
 	e.Use(echomiddleware.Secure())
 
+	// Response compression with per-route minimum sizes
+	e.Use(middleware.Compression(middleware.DefaultCompressionRoutes))
+
 	// API versioning
 	api := e.Group("/api/v1")
 
@@ -63,11 +139,22 @@ func (r *Router) SetupRoutes(e *echo.Echo) {
 	e.GET("/health", r.healthCheck)
 	api.GET("/health", r.healthCheck)
 
+	// Engine failure code catalog (no authentication required)
+	api.GET("/error-codes", r.incidentHandler.GetFailureCodes)
+
 	// Public routes (no authentication required)
 	auth := api.Group("/auth")
 	{
 		auth.POST("/register", r.userHandler.Register)
 		auth.POST("/login", r.userHandler.Login)
+		auth.POST("/refresh", r.userHandler.RefreshToken)
+		auth.POST("/logout", r.userHandler.Logout, r.authMiddleware.JWTAuth())
+	}
+
+	// Read-only instance status for external requesters (signed link, no account)
+	public := api.Group("/public")
+	{
+		public.GET("/status/:token", r.publicStatusHandler.GetPublicStatus)
 	}
 
 	// Protected routes (authentication required)
@@ -77,6 +164,9 @@ func (r *Router) SetupRoutes(e *echo.Echo) {
 		protected.GET("/profile", r.userHandler.GetProfile)
 		protected.PUT("/profile", r.userHandler.UpdateProfile)
 		protected.POST("/change-password", r.userHandler.ChangePassword)
+		protected.GET("/notification-preferences", r.notificationHandler.GetPreferences)
+		protected.PUT("/notification-preferences", r.notificationHandler.UpdatePreferences)
+		protected.GET("/announcements", r.announcementHandler.GetActiveAnnouncements)
 	}
 
 	// Process routes (authentication required)
@@ -85,15 +175,28 @@ func (r *Router) SetupRoutes(e *echo.Echo) {
 	{
 		process.GET("", r.processHandler.GetProcesses)
 		process.POST("", r.processHandler.CreateProcess)
+		process.POST("/import-template", r.processHandler.ImportTemplate)
+		process.POST("/validate", r.processHandler.ValidateDefinition)
 		process.GET("/:id", r.processHandler.GetProcess)
 		process.PUT("/:id", r.processHandler.UpdateProcess)
 		process.DELETE("/:id", r.processHandler.DeleteProcess)
 		process.POST("/:id/copy", r.processHandler.CopyProcess)
 		process.POST("/:id/publish", r.processHandler.PublishProcess)
+		process.POST("/:id/new-version", r.processHandler.CreateNewVersion)
+		process.POST("/:id/deprecate", r.processHandler.DeprecateProcess)
+		process.POST("/:id/archive", r.processHandler.ArchiveProcess)
+		process.GET("/:id/metadata", r.processHandler.GetProcessMetadata)
+		process.PUT("/:id/metadata", r.processHandler.UpdateProcessMetadata, r.authMiddleware.RequirePermission(config.PermissionProcessMetadata))
 		process.GET("/stats", r.processHandler.GetProcessStats)
+		process.GET("/:id/kpis", r.kpiHandler.ListKPIs)
+		process.POST("/:id/kpis", r.kpiHandler.CreateKPI, r.authMiddleware.RequirePermission(config.PermissionKPIManage))
+		process.GET("/:id/deployments", r.deploymentHandler.ListDeployments)
+		process.POST("/:id/deployments", r.deploymentHandler.Deploy, r.authMiddleware.RequirePermission(config.PermissionDeployCreate))
 
 		// 流程执行API (新增)
-		process.POST("/:id/start", r.processExecutionHandler.StartProcess)
+		process.POST("/:id/start", r.processExecutionHandler.StartProcess, r.idempotency.Handle())
+		process.POST("/:id/what-if", r.processExecutionHandler.AnalyzeWhatIf)
+		process.GET("/:id/rollout", r.processExecutionHandler.GetRolloutReport)
 	}
 
 	// 流程实例管理API (新增)
@@ -101,10 +204,22 @@ func (r *Router) SetupRoutes(e *echo.Echo) {
 	instance.Use(r.authMiddleware.JWTAuth())
 	{
 		instance.GET("/:id", r.processExecutionHandler.GetInstance)
-		instance.POST("/:id/suspend", r.processExecutionHandler.SuspendInstance)
-		instance.POST("/:id/resume", r.processExecutionHandler.ResumeInstance)
-		instance.POST("/:id/cancel", r.processExecutionHandler.CancelInstance)
+		instance.POST("/:id/suspend", r.processExecutionHandler.SuspendInstance, r.authMiddleware.RequireInstancePermission(config.PermissionInstanceSuspend))
+		instance.POST("/:id/resume", r.processExecutionHandler.ResumeInstance, r.authMiddleware.RequireInstancePermission(config.PermissionInstanceSuspend))
+		instance.POST("/:id/cancel", r.processExecutionHandler.CancelInstance, r.authMiddleware.RequireInstancePermission(config.PermissionInstanceCancel))
+		instance.POST("/:id/migrate", r.processExecutionHandler.MigrateInstance, r.authMiddleware.RequirePermission(config.PermissionInstanceMigrate))
 		instance.GET("/:id/history", r.processExecutionHandler.GetInstanceHistory)
+		instance.GET("/:id/timeline", r.processExecutionHandler.GetInstanceTimeline)
+		instance.GET("/:id/schedule", r.processExecutionHandler.GetInstanceSchedule)
+		instance.GET("/:id/next-steps", r.processExecutionHandler.GetInstanceNextSteps)
+		instance.GET("/:id/trace", r.processExecutionHandler.GetInstanceTrace)
+		instance.POST("/:id/status-link", r.publicStatusHandler.CreateStatusLink)
+		instance.GET("/:id/duplicates", r.processExecutionHandler.GetInstanceDuplicates)
+		instance.POST("/:id/duplicates/:dupId/confirm", r.processExecutionHandler.ConfirmDuplicate)
+		instance.POST("/:id/duplicates/:dupId/dismiss", r.processExecutionHandler.DismissDuplicate)
+		instance.GET("/:id/attachments", r.attachmentHandler.GetInstanceAttachments)
+		instance.POST("/:id/attachments", r.attachmentHandler.UploadInstanceAttachment)
+		instance.GET("/:id/archive", r.archiveHandler.GetArchivedHistory)
 	}
 
 	// 流程实例列表API (新增)
@@ -112,6 +227,78 @@ func (r *Router) SetupRoutes(e *echo.Echo) {
 	instances.Use(r.authMiddleware.JWTAuth())
 	{
 		instances.GET("", r.processExecutionHandler.GetInstances)
+		instances.GET("/compare", r.processExecutionHandler.CompareInstances)
+	}
+
+	// Process KPIs
+	kpis := api.Group("/kpis")
+	kpis.Use(r.authMiddleware.JWTAuth())
+	kpis.Use(r.authMiddleware.RequirePermission(config.PermissionKPIManage))
+	{
+		kpis.PUT("/:id", r.kpiHandler.UpdateKPI)
+		kpis.DELETE("/:id", r.kpiHandler.DeleteKPI)
+	}
+
+	// Environment deployments (dev → staging → prod)
+	deployments := api.Group("/deployments")
+	deployments.Use(r.authMiddleware.JWTAuth())
+	{
+		deployments.POST("/import", r.deploymentHandler.ImportPackage, r.authMiddleware.RequirePermission(config.PermissionDeployImport))
+		deployments.GET("/:id/package", r.deploymentHandler.ExportPackage, r.authMiddleware.RequirePermission(config.PermissionDeployExport))
+		deployments.POST("/:id/promote", r.deploymentHandler.Promote, r.authMiddleware.RequirePermission(config.PermissionDeployPromote))
+	}
+
+	// Webhook subscriptions for engine events
+	webhooks := api.Group("/webhooks")
+	webhooks.Use(r.authMiddleware.JWTAuth())
+	webhooks.Use(r.authMiddleware.RequirePermission(config.PermissionWebhookManage))
+	{
+		webhooks.GET("", r.webhookHandler.ListSubscriptions)
+		webhooks.POST("", r.webhookHandler.CreateSubscription)
+		webhooks.PUT("/:id", r.webhookHandler.UpdateSubscription)
+		webhooks.DELETE("/:id", r.webhookHandler.DeleteSubscription)
+		webhooks.GET("/:id/deliveries", r.webhookHandler.ListDeliveries)
+	}
+
+	// Analytics
+	analytics := api.Group("/analytics")
+	analytics.Use(r.authMiddleware.JWTAuth())
+	{
+		analytics.GET("/process/:id/kpis", r.kpiHandler.GetAttainment)
+		analytics.GET("/capacity", r.capacityHandler.GetCapacity, r.authMiddleware.RequirePermission(config.PermissionCapacityView))
+	}
+
+	// 组任务队列API，按优先级或公平分配获取下一个任务
+	queues := api.Group("/queues")
+	queues.Use(r.authMiddleware.JWTAuth())
+	queues.Use(r.authMiddleware.RequirePermission(config.PermissionQueueNext))
+	{
+		queues.POST("/:group/next", r.queueHandler.NextTask)
+	}
+
+	// 外部任务API，流程引擎之外的处理程序按主题获取并完成外部服务任务
+	externalTasks := api.Group("/external-tasks")
+	externalTasks.Use(r.authMiddleware.JWTAuth())
+	externalTasks.Use(r.authMiddleware.RequirePermission(config.PermissionExternalTask))
+	{
+		externalTasks.POST("/fetch-and-lock", r.externalTaskHandler.FetchAndLock)
+		externalTasks.POST("/:id/complete", r.externalTaskHandler.Complete)
+		externalTasks.POST("/:id/failure", r.externalTaskHandler.Failure)
+	}
+
+	// 消息投递API，外部系统按消息名称和关联键唤醒在消息捕获节点等待的流程实例
+	messages := api.Group("/messages")
+	messages.Use(r.authMiddleware.JWTAuth())
+	{
+		messages.POST("/correlate", r.messageHandler.Correlate, r.authMiddleware.RequirePermission(config.PermissionMessageCorrelate), r.idempotency.Handle())
+	}
+
+	// 附件API，上传由实例和任务路由处理
+	attachments := api.Group("/attachments")
+	attachments.Use(r.authMiddleware.JWTAuth())
+	{
+		attachments.GET("/:id/download", r.attachmentHandler.DownloadAttachment)
+		attachments.DELETE("/:id", r.attachmentHandler.DeleteAttachment)
 	}
 
 	// 任务管理API (新增)
@@ -120,11 +307,20 @@ func (r *Router) SetupRoutes(e *echo.Echo) {
 	{
 		task.GET("/:id", r.taskManagementHandler.GetTask)
 		task.POST("/:id/claim", r.taskManagementHandler.ClaimTask)
-		task.POST("/:id/complete", r.taskManagementHandler.CompleteTask)
+		task.POST("/:id/complete", r.taskManagementHandler.CompleteTask, r.idempotency.Handle())
+		task.POST("/:id/return", r.taskManagementHandler.ReturnTask, r.idempotency.Handle())
 		task.POST("/:id/release", r.taskManagementHandler.ReleaseTask)
 		task.POST("/:id/delegate", r.taskManagementHandler.DelegateTask)
+		task.POST("/:id/resolve", r.taskManagementHandler.ResolveTask)
+		task.GET("/:id/handover", r.taskManagementHandler.GetTaskHandover)
 		task.GET("/:id/form", r.taskManagementHandler.GetTaskForm)
 		task.POST("/:id/form", r.taskManagementHandler.SubmitTaskForm)
+		task.GET("/:id/comments", r.taskManagementHandler.GetTaskComments)
+		task.POST("/:id/comments", r.taskManagementHandler.AddTaskComment)
+		task.PUT("/:id/comments/:commentId", r.taskManagementHandler.UpdateTaskComment)
+		task.DELETE("/:id/comments/:commentId", r.taskManagementHandler.DeleteTaskComment)
+		task.GET("/:id/attachments", r.attachmentHandler.GetTaskAttachments)
+		task.POST("/:id/attachments", r.attachmentHandler.UploadTaskAttachment)
 	}
 
 	// 用户任务API (新增)
@@ -132,11 +328,33 @@ func (r *Router) SetupRoutes(e *echo.Echo) {
 	user.Use(r.authMiddleware.JWTAuth())
 	{
 		user.GET("/tasks", r.taskManagementHandler.GetUserTasks)
+		user.GET("/tasks/changes", r.taskManagementHandler.GetTaskChanges)
+		user.GET("/tasks/stream", r.taskManagementHandler.StreamTaskChanges)
+		user.GET("/task-filters", r.taskManagementHandler.GetSavedTaskFilters)
+		user.POST("/task-filters", r.taskManagementHandler.CreateSavedTaskFilter)
+		user.PUT("/task-filters/:id", r.taskManagementHandler.UpdateSavedTaskFilter)
+		user.DELETE("/task-filters/:id", r.taskManagementHandler.DeleteSavedTaskFilter)
+		user.GET("/duplicates", r.processExecutionHandler.GetUserDuplicates)
+		user.GET("/notifications", r.notificationHandler.ListInbox)
+		user.GET("/notifications/unread-count", r.notificationHandler.GetUnreadCount)
+		user.POST("/notifications/read-all", r.notificationHandler.MarkAllRead)
+		user.POST("/notifications/:id/read", r.notificationHandler.MarkRead)
+	}
+
+	// 组织架构API：部门和用户组，供流程设计时选择处理人
+	org := api.Group("/org")
+	org.Use(r.authMiddleware.JWTAuth())
+	{
+		org.GET("/departments", r.organizationHandler.ListDepartments)
+		org.GET("/departments/:id/members", r.organizationHandler.GetDepartmentMembers)
+		org.GET("/groups", r.organizationHandler.ListGroups)
+		org.GET("/groups/:id/members", r.organizationHandler.GetGroupMembers)
 	}
 
 	// 任务状态API (管理员功能，新增)
 	tasks := api.Group("/tasks")
 	tasks.Use(r.authMiddleware.JWTAuth())
+	tasks.Use(r.authMiddleware.RequirePermission(config.PermissionTaskStatus))
 	{
 		tasks.GET("/status/:status", r.taskManagementHandler.GetTasksByStatus)
 	}
@@ -144,11 +362,105 @@ func (r *Router) SetupRoutes(e *echo.Echo) {
 	// Admin routes (authentication + admin role required)
 	admin := api.Group("/admin")
 	admin.Use(r.authMiddleware.JWTAuth())
-	// admin.Use(r.authMiddleware.RequireRole("admin")) // TODO: Implement role check
+	admin.Use(r.authMiddleware.RequirePermission(config.PermissionAdmin))
 	{
 		admin.GET("/users", r.userHandler.GetUsers)
 		admin.POST("/users/:id/deactivate", r.userHandler.DeactivateUser)
+		admin.DELETE("/users/:id", r.userHandler.EraseUser)
+		admin.GET("/users/:id/erasures", r.userHandler.GetUserErasures)
 		admin.GET("/stats/users", r.userHandler.GetUserStats)
+		admin.PUT("/users/:id/department", r.organizationHandler.SetUserDepartment)
+
+		// Departments and user groups
+		admin.POST("/departments", r.organizationHandler.CreateDepartment)
+		admin.PUT("/departments/:id", r.organizationHandler.UpdateDepartment)
+		admin.DELETE("/departments/:id", r.organizationHandler.DeleteDepartment)
+		admin.POST("/groups", r.organizationHandler.CreateGroup)
+		admin.PUT("/groups/:id", r.organizationHandler.UpdateGroup)
+		admin.DELETE("/groups/:id", r.organizationHandler.DeleteGroup)
+		admin.POST("/groups/:id/members", r.organizationHandler.AddGroupMembers)
+		admin.DELETE("/groups/:id/members/:user_id", r.organizationHandler.RemoveGroupMember)
+
+		// Definition references to deactivated or deleted users
+		admin.GET("/user-references", r.processHandler.GetUserReferenceIssues)
+		admin.POST("/user-references/remap", r.processHandler.RemapUserReferences)
+
+		// Notification template management
+		admin.GET("/notification-templates", r.notificationHandler.ListTemplates)
+		admin.POST("/notification-templates", r.notificationHandler.CreateTemplate)
+		admin.POST("/notification-templates/preview", r.notificationHandler.PreviewTemplate)
+		admin.PUT("/notification-templates/:id", r.notificationHandler.UpdateTemplate)
+		admin.DELETE("/notification-templates/:id", r.notificationHandler.DeleteTemplate)
+
+		// Broadcast announcements
+		admin.GET("/announcements", r.announcementHandler.ListAnnouncements)
+		admin.POST("/announcements", r.announcementHandler.CreateAnnouncement)
+		admin.DELETE("/announcements/:id", r.announcementHandler.DeleteAnnouncement)
+
+		// Connector allowlists (per definition key)
+		admin.GET("/connector-policies", r.connectorPolicyHandler.ListPolicies)
+		admin.GET("/connector-policies/:key", r.connectorPolicyHandler.GetPolicy)
+		admin.PUT("/connector-policies/:key", r.connectorPolicyHandler.UpdatePolicy)
+		admin.DELETE("/connector-policies/:key", r.connectorPolicyHandler.DeletePolicy)
+
+		// Complexity budgets (per definition key, "*" for the default)
+		admin.GET("/complexity-budgets", r.processHandler.ListComplexityBudgets)
+		admin.PUT("/complexity-budgets/:key", r.processHandler.UpdateComplexityBudget)
+		admin.DELETE("/complexity-budgets/:key", r.processHandler.DeleteComplexityBudget)
+
+		// Incidents
+		admin.GET("/incidents", r.incidentHandler.GetIncidents)
+		admin.POST("/incidents/:id/resolve", r.incidentHandler.ResolveIncident)
+		admin.POST("/incidents/:id/retry", r.incidentHandler.RetryIncident)
+
+		// Instance purge (terminal instances only, leaves a purge certificate)
+		admin.DELETE("/instance/:id", r.processExecutionHandler.PurgeInstance)
+		admin.GET("/instance/:id/purge-certificates", r.processExecutionHandler.GetPurgeCertificates)
+
+		// Modify execution (force-move a running instance to another node)
+		admin.POST("/instance/:id/modify-execution", r.processExecutionHandler.ModifyExecution)
+
+		// Recycle bin (cancelled instances that can still be restored)
+		admin.GET("/recycle-bin", r.recycleBinHandler.ListCancelled)
+		admin.POST("/recycle-bin/:id/restore", r.recycleBinHandler.RestoreInstance)
+
+		// Archived instances (ended instances moved out of the hot tables into cold storage)
+		admin.GET("/archives", r.archiveHandler.ListArchives)
+		admin.POST("/archives/:id", r.archiveHandler.ArchiveInstance)
+
+		// Deployment self-test (schema, indexes, Redis, secrets, scheduler, allowlists, clock)
+		admin.GET("/selftest", r.selfTestHandler.RunSelfTest)
+
+		// Runtime state snapshot for disaster recovery drills
+		admin.GET("/runtime-snapshot", r.processExecutionHandler.ExportRuntimeSnapshot)
+		admin.POST("/runtime-snapshot/import", r.processExecutionHandler.ImportRuntimeSnapshot)
+
+		// Background jobs (timers, webhook deliveries, queued notifications)
+		admin.GET("/jobs", r.jobHandler.GetJobSummary)
+		admin.GET("/jobs/:type", r.jobHandler.ListJobs)
+		admin.POST("/jobs/:type/:id/retry", r.jobHandler.RetryJob)
+		admin.DELETE("/jobs/:type/:id", r.jobHandler.DeleteJob)
+
+		// Startup recovery (stalled instances, jobs orphaned by dead workers)
+		admin.GET("/recovery", r.jobHandler.GetRecoveryReport)
+		admin.POST("/recovery/run", r.jobHandler.RunRecovery)
+
+		// Reporting star schema for BI tools
+		admin.GET("/reporting/status", r.reportingHandler.GetStatus)
+		admin.POST("/reporting/refresh", r.reportingHandler.Refresh)
+	}
+
+	// Integration API for Zapier/low-code connectors (stable, flat payloads)
+	integrations := e.Group("/api/integrations/v1")
+	integrations.Use(r.authMiddleware.JWTAuth())
+	{
+		integrations.GET("/me", r.integrationHandler.GetMe)
+		integrations.GET("/catalog", r.integrationHandler.GetCatalog)
+		integrations.GET("/samples/:key", r.integrationHandler.GetSample)
+		integrations.GET("/triggers/new-tasks", r.integrationHandler.PollNewTasks)
+		integrations.GET("/triggers/completed-instances", r.integrationHandler.PollCompletedInstances)
+		integrations.POST("/actions/start-process", r.integrationHandler.StartProcess, r.idempotency.Handle())
+		integrations.POST("/actions/complete-task", r.integrationHandler.CompleteTask, r.idempotency.Handle())
 	}
 
 	// API documentation route (development only)
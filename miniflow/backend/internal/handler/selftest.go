@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"net/http"
+
+	"miniflow/internal/service"
+	"miniflow/pkg/logger"
+
+	"github.com/labstack/echo/v4"
+)
+
+// SelfTestHandler handles deployment self-test HTTP requests (admin)
+type SelfTestHandler struct {
+	selfTestService *service.SelfTestService
+	logger          *logger.Logger
+}
+
+// NewSelfTestHandler creates a new self-test handler
+func NewSelfTestHandler(selfTestService *service.SelfTestService, logger *logger.Logger) *SelfTestHandler {
+	return &SelfTestHandler{
+		selfTestService: selfTestService,
+		logger:          logger,
+	}
+}
+
+// RunSelfTest handles running the deployment self-test; responds 503 when a check fails
+func (h *SelfTestHandler) RunSelfTest(c echo.Context) error {
+	report := h.selfTestService.Run(c.Request().Context())
+
+	status := http.StatusOK
+	message := "自检通过"
+	if !report.Passed() {
+		status = http.StatusServiceUnavailable
+		message = "自检未通过"
+	}
+
+	return c.JSON(status, map[string]interface{}{
+		"message": message,
+		"data":    report,
+	})
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// streamBufferSize 流式响应的写缓冲大小，缓冲写满时发送给客户端
+const streamBufferSize = 32 * 1024
+
+// streamJSONArray 流式写出 {"success": true, "data": {<fields>, "<key>": [...]}} 响应，
+// key 为空时 data 就是数组本身，fields 被忽略
+//
+// fields 先整体编码，数组元素由 each 通过 emit 逐个编码写出，不在内存中构建完整的响应。
+// 第一个元素写出前 each 返回的错误仍按普通错误响应处理；之后出错时响应已经开始，
+// 客户端会收到不完整的 JSON。
+func streamJSONArray(c echo.Context, fields map[string]interface{}, key string, each func(emit func(interface{}) error) error) error {
+	// 数组之前和之后的部分
+	prefix, suffix := []byte(`{"success":true,"data":[`), `]}`
+	if key != "" {
+		if fields == nil {
+			fields = map[string]interface{}{}
+		}
+		head, err := json.Marshal(fields)
+		if err != nil {
+			return err
+		}
+		name, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+		prefix = append([]byte(`{"success":true,"data":`), head[:len(head)-1]...)
+		if len(fields) > 0 {
+			prefix = append(prefix, ',')
+		}
+		prefix = append(append(prefix, name...), ':', '[')
+		suffix = `]}}`
+	}
+
+	var buf *bufio.Writer
+	var enc *json.Encoder
+	begin := func() error {
+		res := c.Response()
+		res.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res.WriteHeader(http.StatusOK)
+
+		buf = bufio.NewWriterSize(res, streamBufferSize)
+		enc = json.NewEncoder(buf)
+		_, err := buf.Write(prefix)
+		return err
+	}
+
+	emit := func(value interface{}) error {
+		if buf == nil {
+			if err := begin(); err != nil {
+				return err
+			}
+		} else if err := buf.WriteByte(','); err != nil {
+			return err
+		}
+		return enc.Encode(value)
+	}
+
+	if err := each(emit); err != nil {
+		return err
+	}
+	if buf == nil {
+		if err := begin(); err != nil {
+			return err
+		}
+	}
+	if _, err := buf.WriteString(suffix); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
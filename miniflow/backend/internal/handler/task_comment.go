@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// TaskCommentRequest 发表或修改任务评论的请求
+type TaskCommentRequest struct {
+	Body string `json:"body" validate:"required,max=2000"`
+}
+
+// GetTaskComments 获取任务的讨论记录
+// GET /api/v1/task/:id/comments
+func (h *TaskManagementHandler) GetTaskComments(c echo.Context) error {
+	ctx := c.Request().Context()
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid task ID")
+	}
+
+	comments, err := h.engine.GetTaskComments(ctx, uint(taskID))
+	if err != nil {
+		h.logger.Error("Failed to get task comments", zap.Uint("task_id", uint(taskID)), zap.Error(err))
+		return engineHTTPError("Failed to get task comments: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    comments,
+	})
+}
+
+// AddTaskComment 在任务下发表评论
+// POST /api/v1/task/:id/comments
+func (h *TaskManagementHandler) AddTaskComment(c echo.Context) error {
+	ctx := c.Request().Context()
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid task ID")
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	var req TaskCommentRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	comment, err := h.engine.AddTaskComment(ctx, uint(taskID), userID, req.Body)
+	if err != nil {
+		h.logger.Error("Failed to add task comment",
+			zap.Uint("task_id", uint(taskID)),
+			zap.Uint("user_id", userID),
+			zap.Error(err),
+		)
+		return engineHTTPError("Failed to add task comment: ", err)
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"data":    comment,
+	})
+}
+
+// UpdateTaskComment 修改自己发表的任务评论
+// PUT /api/v1/task/:id/comments/:commentId
+func (h *TaskManagementHandler) UpdateTaskComment(c echo.Context) error {
+	ctx := c.Request().Context()
+	taskID, commentID, err := parseTaskCommentIDs(c)
+	if err != nil {
+		return err
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	var req TaskCommentRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	comment, err := h.engine.UpdateTaskComment(ctx, taskID, commentID, userID, req.Body)
+	if err != nil {
+		h.logger.Error("Failed to update task comment",
+			zap.Uint("task_id", taskID),
+			zap.Uint("comment_id", commentID),
+			zap.Error(err),
+		)
+		return engineHTTPError("Failed to update task comment: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    comment,
+	})
+}
+
+// DeleteTaskComment 删除任务评论，作者和有 content_moderate 权限的用户可以删除
+// DELETE /api/v1/task/:id/comments/:commentId
+func (h *TaskManagementHandler) DeleteTaskComment(c echo.Context) error {
+	ctx := c.Request().Context()
+	taskID, commentID, err := parseTaskCommentIDs(c)
+	if err != nil {
+		return err
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	if err := h.engine.DeleteTaskComment(ctx, taskID, commentID, userID); err != nil {
+		h.logger.Error("Failed to delete task comment",
+			zap.Uint("task_id", taskID),
+			zap.Uint("comment_id", commentID),
+			zap.Error(err),
+		)
+		return engineHTTPError("Failed to delete task comment: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Task comment deleted successfully",
+	})
+}
+
+// parseTaskCommentIDs 解析路径中的任务ID和评论ID
+func parseTaskCommentIDs(c echo.Context) (uint, uint, error) {
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return 0, 0, echo.NewHTTPError(http.StatusBadRequest, "Invalid task ID")
+	}
+	commentID, err := strconv.ParseUint(c.Param("commentId"), 10, 32)
+	if err != nil {
+		return 0, 0, echo.NewHTTPError(http.StatusBadRequest, "Invalid comment ID")
+	}
+	return uint(taskID), uint(commentID), nil
+}
@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"miniflow/internal/engine"
+	"miniflow/internal/model"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// parseTaskFilterCriteria 解析任务收件箱的查询参数，列表参数用逗号分隔，started_by=me 表示当前用户
+//
+//	?status=assigned,claimed&definition_key=leave&node_id=approve&priority_min=50&priority_max=100
+//	&due_after=2026-01-01T00:00:00Z&due_before=2026-02-01T00:00:00Z&business_key=LEAVE-1&q=报销&started_by=me
+func parseTaskFilterCriteria(values url.Values, userID uint) (*model.TaskFilterCriteria, error) {
+	criteria := &model.TaskFilterCriteria{
+		Statuses:       splitQueryList(values.Get("status")),
+		DefinitionKeys: splitQueryList(values.Get("definition_key")),
+		NodeIDs:        splitQueryList(values.Get("node_id")),
+		BusinessKey:    strings.TrimSpace(values.Get("business_key")),
+		Text:           strings.TrimSpace(values.Get("q")),
+	}
+
+	for param, target := range map[string]**int{"priority_min": &criteria.MinPriority, "priority_max": &criteria.MaxPriority} {
+		if raw := values.Get(param); raw != "" {
+			priority, err := strconv.Atoi(raw)
+			if err != nil {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid "+param)
+			}
+			*target = &priority
+		}
+	}
+	for param, target := range map[string]**time.Time{"due_after": &criteria.DueAfter, "due_before": &criteria.DueBefore} {
+		if raw := values.Get(param); raw != "" {
+			due, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid "+param+", expected RFC 3339 time")
+			}
+			*target = &due
+		}
+	}
+	if raw := values.Get("started_by"); raw != "" {
+		starterID := userID
+		if raw != "me" {
+			parsed, err := strconv.ParseUint(raw, 10, 32)
+			if err != nil {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid started_by")
+			}
+			starterID = uint(parsed)
+		}
+		criteria.StartedBy = &starterID
+	}
+
+	if err := criteria.Validate(); err != nil {
+		if httpErr := enumHTTPError(err); httpErr != nil {
+			return nil, httpErr
+		}
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return criteria, nil
+}
+
+// splitQueryList 拆分逗号分隔的查询参数，忽略空项
+func splitQueryList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// GetSavedTaskFilters 获取当前用户保存的任务筛选器
+// GET /api/v1/user/task-filters
+func (h *TaskManagementHandler) GetSavedTaskFilters(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	filters, err := h.engine.GetSavedTaskFilters(ctx, userID)
+	if err != nil {
+		h.logger.Error("Failed to get saved task filters", zap.Uint("user_id", userID), zap.Error(err))
+		return engineHTTPError("Failed to get saved task filters: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    filters,
+	})
+}
+
+// CreateSavedTaskFilter 保存任务筛选器，之后可以通过 GET /user/tasks?saved_filter=id 使用
+// POST /api/v1/user/task-filters
+func (h *TaskManagementHandler) CreateSavedTaskFilter(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	var req engine.SavedTaskFilterRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	filter, err := h.engine.CreateSavedTaskFilter(ctx, userID, &req)
+	if err != nil {
+		h.logger.Error("Failed to create saved task filter", zap.Uint("user_id", userID), zap.Error(err))
+		return engineHTTPError("Failed to create saved task filter: ", err)
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"data":    filter,
+	})
+}
+
+// UpdateSavedTaskFilter 修改任务筛选器的名称和查询条件
+// PUT /api/v1/user/task-filters/:id
+func (h *TaskManagementHandler) UpdateSavedTaskFilter(c echo.Context) error {
+	ctx := c.Request().Context()
+	filterID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid filter ID")
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	var req engine.SavedTaskFilterRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	filter, err := h.engine.UpdateSavedTaskFilter(ctx, userID, uint(filterID), &req)
+	if err != nil {
+		h.logger.Error("Failed to update saved task filter",
+			zap.Uint("filter_id", uint(filterID)),
+			zap.Uint("user_id", userID),
+			zap.Error(err),
+		)
+		return engineHTTPError("Failed to update saved task filter: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    filter,
+	})
+}
+
+// DeleteSavedTaskFilter 删除任务筛选器
+// DELETE /api/v1/user/task-filters/:id
+func (h *TaskManagementHandler) DeleteSavedTaskFilter(c echo.Context) error {
+	ctx := c.Request().Context()
+	filterID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid filter ID")
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	if err := h.engine.DeleteSavedTaskFilter(ctx, userID, uint(filterID)); err != nil {
+		h.logger.Error("Failed to delete saved task filter",
+			zap.Uint("filter_id", uint(filterID)),
+			zap.Uint("user_id", userID),
+			zap.Error(err),
+		)
+		return engineHTTPError("Failed to delete saved task filter: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Saved task filter deleted successfully",
+	})
+}
@@ -1,12 +1,16 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"miniflow/internal/engine"
 	"miniflow/internal/model"
+	"miniflow/internal/repository"
 	"miniflow/pkg/logger"
+	"miniflow/pkg/pagination"
 
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
@@ -26,58 +30,156 @@ func NewTaskManagementHandler(engine *engine.ProcessEngine, logger *logger.Logge
 	}
 }
 
-// GetUserTasksRequest 获取用户任务请求
-type GetUserTasksRequest struct {
-	Page     int    `query:"page" validate:"min=1"`
-	PageSize int    `query:"page_size" validate:"min=1,max=100"`
-	Status   string `query:"status"`
-	Priority string `query:"priority"`
-}
-
-// GetUserTasks 获取用户任务列表
-// GET /api/v1/user/tasks
+// GetUserTasks 获取用户任务列表，支持 sort 和 filter[field][op] 参数以及收件箱查询参数，
+// saved_filter 指定保存的筛选器时，请求中的查询参数覆盖筛选器中的同名条件
+// GET /api/v1/user/tasks?saved_filter=id&status=assigned,claimed&definition_key=leave&q=报销
 func (h *TaskManagementHandler) GetUserTasks(c echo.Context) error {
+	ctx := c.Request().Context()
 	// 获取当前用户ID
 	userID := getUserIDFromContext(c)
 	if userID == 0 {
 		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
 	}
 
-	var req GetUserTasksRequest
-	if err := c.Bind(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid query parameters")
+	criteria, err := parseTaskFilterCriteria(c.QueryParams(), userID)
+	if err != nil {
+		return err
+	}
+	if raw := c.QueryParam("saved_filter"); raw != "" {
+		filterID, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid saved filter ID")
+		}
+		saved, err := h.engine.GetSavedTaskFilterCriteria(ctx, userID, uint(filterID))
+		if err != nil {
+			return engineHTTPError("Failed to get saved task filter: ", err)
+		}
+		merged := saved.Merge(criteria)
+		criteria = &merged
 	}
 
-	// 设置默认值
-	if req.Page == 0 {
-		req.Page = 1
+	// 校验排序和过滤参数
+	params, err := repository.TaskListSchema.Bind(c.QueryParams())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
-	if req.PageSize == 0 {
-		req.PageSize = 20
+
+	query := &repository.TaskQuery{
+		CandidateID: &userID,
+		Params:      params,
 	}
+	query.ApplyCriteria(criteria)
+
+	if pagination.UsesCursor(c.QueryParams()) {
+		return h.getUserTasksAfter(c, userID, query)
+	}
+
+	pageReq, err := pagination.Parse(c.QueryParams(), pagination.Default)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	query.Offset = pageReq.Offset()
+	query.Limit = pageReq.Limit()
 
 	// 获取用户任务列表
-	tasks, total, err := h.engine.GetUserTasks(userID, req.Status, (req.Page-1)*req.PageSize, req.PageSize)
+	tasks, total, err := h.engine.QueryTasks(ctx, query)
 	if err != nil {
 		h.logger.Error("Failed to get user tasks", zap.Uint("user_id", userID), zap.Error(err))
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get user tasks")
+		return engineHTTPError("Failed to get user tasks: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    pageReq.Result("tasks", tasks, total),
+	})
+}
+
+// getUserTasksAfter 按游标分页获取用户任务列表，按创建时间和ID倒序排列，不返回总数
+func (h *TaskManagementHandler) getUserTasksAfter(c echo.Context, userID uint, query *repository.TaskQuery) error {
+	if len(query.Params.Sorts) > 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "sort is not supported with cursor pagination")
+	}
+	pageReq, err := pagination.ParseCursor(c.QueryParams(), pagination.Default)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	at, afterID, err := pagination.DecodeCursor(pageReq.Cursor)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid cursor")
+	}
+	query.Limit = pageReq.Limit()
+
+	tasks, err := h.engine.QueryTasksAfter(c.Request().Context(), query, repository.Keyset{At: at, ID: afterID})
+	if err != nil {
+		h.logger.Error("Failed to get user tasks", zap.Uint("user_id", userID), zap.Error(err))
+		return engineHTTPError("Failed to get user tasks: ", err)
+	}
+
+	var nextCursor string
+	if len(tasks) > 0 {
+		last := tasks[len(tasks)-1]
+		nextCursor = pagination.EncodeCursor(last.CreatedAt, last.ID)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    pageReq.CursorResult("tasks", tasks, len(tasks), nextCursor),
+	})
+}
+
+// 任务变更长轮询参数
+const (
+	taskChangesDefaultWait = 25 * time.Second
+	taskChangesMaxWait     = 60 * time.Second
+	taskChangesLimit       = 100
+)
+
+// GetTaskChanges 长轮询当前用户的任务变更，供无法使用 WebSocket 的客户端刷新待办角标
+// GET /api/v1/user/tasks/changes?since=cursor&wait=seconds
+func (h *TaskManagementHandler) GetTaskChanges(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
 	}
 
+	var since uint64
+	if raw := c.QueryParam("since"); raw != "" {
+		parsed, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid cursor")
+		}
+		since = parsed
+	}
+
+	wait := taskChangesDefaultWait
+	if raw := c.QueryParam("wait"); raw != "" {
+		seconds, err := strconv.Atoi(raw)
+		if err != nil || seconds < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid wait duration")
+		}
+		wait = time.Duration(seconds) * time.Second
+		if wait > taskChangesMaxWait {
+			wait = taskChangesMaxWait
+		}
+	}
+
+	changes, err := h.engine.WaitForTaskChanges(c.Request().Context(), userID, uint(since), taskChangesLimit, wait)
+	if err != nil {
+		h.logger.Error("Failed to get task changes", zap.Uint("user_id", userID), zap.Error(err))
+		return engineHTTPError("Failed to get task changes: ", err)
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"success": true,
-		"data": map[string]interface{}{
-			"tasks":       tasks,
-			"total":       total,
-			"page":        req.Page,
-			"page_size":   req.PageSize,
-			"total_pages": (total + int64(req.PageSize) - 1) / int64(req.PageSize),
-		},
+		"data":    changes,
 	})
 }
 
 // GetTask 获取任务详情
 // GET /api/v1/task/:id
 func (h *TaskManagementHandler) GetTask(c echo.Context) error {
+	ctx := c.Request().Context()
 	// 解析任务ID
 	taskIDStr := c.Param("id")
 	taskID, err := strconv.ParseUint(taskIDStr, 10, 32)
@@ -86,10 +188,10 @@ func (h *TaskManagementHandler) GetTask(c echo.Context) error {
 	}
 
 	// 获取任务详情
-	task, err := h.engine.GetTask(uint(taskID))
+	task, err := h.engine.GetTask(ctx, uint(taskID))
 	if err != nil {
 		h.logger.Error("Failed to get task", zap.Uint("task_id", uint(taskID)), zap.Error(err))
-		return echo.NewHTTPError(http.StatusNotFound, "Task not found")
+		return engineHTTPError("Failed to get task: ", err)
 	}
 
 	// 验证用户权限（用户只能查看分配给自己的任务或者是管理员）
@@ -98,6 +200,13 @@ func (h *TaskManagementHandler) GetTask(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusForbidden, "Access denied")
 	}
 
+	// 附带服务任务的执行记录，便于排查集成问题
+	logs, err := h.engine.GetTaskExecutionLogs(ctx, task.ID)
+	if err != nil {
+		h.logger.Warn("Failed to get task execution logs", zap.Uint("task_id", task.ID), zap.Error(err))
+	}
+	task.ExecutionLogs = logs
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"success": true,
 		"data":    task,
@@ -107,6 +216,7 @@ func (h *TaskManagementHandler) GetTask(c echo.Context) error {
 // ClaimTask 认领任务
 // POST /api/v1/task/:id/claim
 func (h *TaskManagementHandler) ClaimTask(c echo.Context) error {
+	ctx := c.Request().Context()
 	// 解析任务ID
 	taskIDStr := c.Param("id")
 	taskID, err := strconv.ParseUint(taskIDStr, 10, 32)
@@ -121,13 +231,13 @@ func (h *TaskManagementHandler) ClaimTask(c echo.Context) error {
 	}
 
 	// 认领任务
-	if err := h.engine.ClaimTask(uint(taskID), userID); err != nil {
+	if err := h.engine.ClaimTask(ctx, uint(taskID), userID); err != nil {
 		h.logger.Error("Failed to claim task",
 			zap.Uint("task_id", uint(taskID)),
 			zap.Uint("user_id", userID),
 			zap.Error(err),
 		)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to claim task: "+err.Error())
+		return engineHTTPError("Failed to claim task: ", err)
 	}
 
 	h.logger.Info("Task claimed successfully",
@@ -150,6 +260,7 @@ type CompleteTaskRequest struct {
 // CompleteTask 完成任务
 // POST /api/v1/task/:id/complete
 func (h *TaskManagementHandler) CompleteTask(c echo.Context) error {
+	ctx := c.Request().Context()
 	// 解析任务ID
 	taskIDStr := c.Param("id")
 	taskID, err := strconv.ParseUint(taskIDStr, 10, 32)
@@ -174,13 +285,19 @@ func (h *TaskManagementHandler) CompleteTask(c echo.Context) error {
 	}
 
 	// 完成任务
-	if err := h.engine.CompleteTask(uint(taskID), userID, req.FormData, req.Comment); err != nil {
+	if err := h.engine.CompleteTask(ctx, uint(taskID), userID, req.FormData, req.Comment); err != nil {
+		if errors.Is(err, engine.ErrTaskAlreadyCompleted) {
+			return echo.NewHTTPError(http.StatusConflict, map[string]interface{}{
+				"message": "Task already completed",
+				"code":    engine.CodeTaskAlreadyCompleted,
+			})
+		}
 		h.logger.Error("Failed to complete task",
 			zap.Uint("task_id", uint(taskID)),
 			zap.Uint("user_id", userID),
 			zap.Error(err),
 		)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to complete task: "+err.Error())
+		return engineHTTPError("Failed to complete task: ", err)
 	}
 
 	h.logger.Info("Task completed successfully",
@@ -194,9 +311,57 @@ func (h *TaskManagementHandler) CompleteTask(c echo.Context) error {
 	})
 }
 
+// ReturnTaskRequest 驳回任务请求
+type ReturnTaskRequest struct {
+	Comment string `json:"comment" validate:"max=1000"`
+}
+
+// ReturnTask 驳回任务，退回到上一个用户任务节点
+// POST /api/v1/task/:id/return
+func (h *TaskManagementHandler) ReturnTask(c echo.Context) error {
+	ctx := c.Request().Context()
+	// 解析任务ID
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid task ID")
+	}
+
+	// 获取当前用户ID
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	var req ReturnTaskRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	returned, err := h.engine.ReturnTask(ctx, uint(taskID), userID, req.Comment)
+	if err != nil {
+		h.logger.Error("Failed to return task",
+			zap.Uint("task_id", uint(taskID)),
+			zap.Uint("user_id", userID),
+			zap.Error(err),
+		)
+		return engineHTTPError("Failed to return task: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Task returned successfully",
+		"data":    returned,
+	})
+}
+
 // ReleaseTask 释放任务
 // POST /api/v1/task/:id/release
 func (h *TaskManagementHandler) ReleaseTask(c echo.Context) error {
+	ctx := c.Request().Context()
 	// 解析任务ID
 	taskIDStr := c.Param("id")
 	taskID, err := strconv.ParseUint(taskIDStr, 10, 32)
@@ -211,13 +376,13 @@ func (h *TaskManagementHandler) ReleaseTask(c echo.Context) error {
 	}
 
 	// 释放任务
-	if err := h.engine.ReleaseTask(uint(taskID), userID); err != nil {
+	if err := h.engine.ReleaseTask(ctx, uint(taskID), userID); err != nil {
 		h.logger.Error("Failed to release task",
 			zap.Uint("task_id", uint(taskID)),
 			zap.Uint("user_id", userID),
 			zap.Error(err),
 		)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to release task: "+err.Error())
+		return engineHTTPError("Failed to release task: ", err)
 	}
 
 	h.logger.Info("Task released successfully",
@@ -240,6 +405,7 @@ type DelegateTaskRequest struct {
 // DelegateTask 委派任务
 // POST /api/v1/task/:id/delegate
 func (h *TaskManagementHandler) DelegateTask(c echo.Context) error {
+	ctx := c.Request().Context()
 	// 解析任务ID
 	taskIDStr := c.Param("id")
 	taskID, err := strconv.ParseUint(taskIDStr, 10, 32)
@@ -264,14 +430,14 @@ func (h *TaskManagementHandler) DelegateTask(c echo.Context) error {
 	}
 
 	// 委派任务
-	if err := h.engine.DelegateTask(uint(taskID), userID, req.ToUserID, req.Comment); err != nil {
+	if err := h.engine.DelegateTask(ctx, uint(taskID), userID, req.ToUserID, req.Comment); err != nil {
 		h.logger.Error("Failed to delegate task",
 			zap.Uint("task_id", uint(taskID)),
 			zap.Uint("from_user_id", userID),
 			zap.Uint("to_user_id", req.ToUserID),
 			zap.Error(err),
 		)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delegate task: "+err.Error())
+		return engineHTTPError("Failed to delegate task: ", err)
 	}
 
 	h.logger.Info("Task delegated successfully",
@@ -280,15 +446,105 @@ func (h *TaskManagementHandler) DelegateTask(c echo.Context) error {
 		zap.Uint("to_user_id", req.ToUserID),
 	)
 
+	// 为新处理人生成交接包，生成失败不影响委派结果
+	handover, err := h.engine.GetTaskHandover(ctx, uint(taskID))
+	if err != nil {
+		h.logger.Warn("Failed to build task handover", zap.Uint("task_id", uint(taskID)), zap.Error(err))
+	}
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"success": true,
 		"message": "Task delegated successfully",
+		"data":    handover,
+	})
+}
+
+// ResolveTaskRequest 归还委派任务请求
+type ResolveTaskRequest struct {
+	FormData map[string]interface{} `json:"form_data"`
+	Comment  string                 `json:"comment" validate:"max=1000"`
+}
+
+// ResolveTask 受托人把委派中的任务归还给任务所有人
+// POST /api/v1/task/:id/resolve
+func (h *TaskManagementHandler) ResolveTask(c echo.Context) error {
+	ctx := c.Request().Context()
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid task ID")
+	}
+
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	var req ResolveTaskRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.engine.ResolveTask(ctx, uint(taskID), userID, req.FormData, req.Comment); err != nil {
+		h.logger.Error("Failed to resolve task",
+			zap.Uint("task_id", uint(taskID)),
+			zap.Uint("user_id", userID),
+			zap.Error(err),
+		)
+		return engineHTTPError("Failed to resolve task: ", err)
+	}
+
+	h.logger.Info("Task resolved to owner",
+		zap.Uint("task_id", uint(taskID)),
+		zap.Uint("user_id", userID),
+	)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Task resolved successfully",
+	})
+}
+
+// GetTaskHandover 获取任务交接包
+// GET /api/v1/task/:id/handover
+func (h *TaskManagementHandler) GetTaskHandover(c echo.Context) error {
+	ctx := c.Request().Context()
+	// 解析任务ID
+	taskIDStr := c.Param("id")
+	taskID, err := strconv.ParseUint(taskIDStr, 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid task ID")
+	}
+
+	task, err := h.engine.GetTask(ctx, uint(taskID))
+	if err != nil {
+		h.logger.Error("Failed to get task", zap.Uint("task_id", uint(taskID)), zap.Error(err))
+		return engineHTTPError("Failed to get task: ", err)
+	}
+
+	userID := getUserIDFromContext(c)
+	if !h.canUserAccessTask(userID, task) {
+		return echo.NewHTTPError(http.StatusForbidden, "Access denied")
+	}
+
+	handover, err := h.engine.GetTaskHandover(ctx, uint(taskID))
+	if err != nil {
+		h.logger.Error("Failed to get task handover", zap.Uint("task_id", uint(taskID)), zap.Error(err))
+		return engineHTTPError("Failed to get task handover: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    handover,
 	})
 }
 
 // GetTaskForm 获取任务表单定义
 // GET /api/v1/task/:id/form
 func (h *TaskManagementHandler) GetTaskForm(c echo.Context) error {
+	ctx := c.Request().Context()
 	// 解析任务ID
 	taskIDStr := c.Param("id")
 	taskID, err := strconv.ParseUint(taskIDStr, 10, 32)
@@ -297,10 +553,10 @@ func (h *TaskManagementHandler) GetTaskForm(c echo.Context) error {
 	}
 
 	// 获取任务表单定义
-	form, err := h.engine.GetTaskForm(uint(taskID))
+	form, err := h.engine.GetTaskForm(ctx, uint(taskID))
 	if err != nil {
 		h.logger.Error("Failed to get task form", zap.Uint("task_id", uint(taskID)), zap.Error(err))
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get task form")
+		return engineHTTPError("Failed to get task form: ", err)
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
@@ -319,6 +575,7 @@ type SubmitTaskFormRequest struct {
 // SubmitTaskForm 提交任务表单
 // POST /api/v1/task/:id/form
 func (h *TaskManagementHandler) SubmitTaskForm(c echo.Context) error {
+	ctx := c.Request().Context()
 	// 解析任务ID
 	taskIDStr := c.Param("id")
 	taskID, err := strconv.ParseUint(taskIDStr, 10, 32)
@@ -346,13 +603,13 @@ func (h *TaskManagementHandler) SubmitTaskForm(c echo.Context) error {
 	switch req.Action {
 	case "save":
 		// 保存表单数据但不完成任务
-		if err := h.engine.SaveTaskForm(uint(taskID), userID, req.FormData); err != nil {
+		if err := h.engine.SaveTaskForm(ctx, uint(taskID), userID, req.FormData); err != nil {
 			h.logger.Error("Failed to save task form",
 				zap.Uint("task_id", uint(taskID)),
 				zap.Uint("user_id", userID),
 				zap.Error(err),
 			)
-			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save task form: "+err.Error())
+			return engineHTTPError("Failed to save task form: ", err)
 		}
 
 		return c.JSON(http.StatusOK, map[string]interface{}{
@@ -362,13 +619,13 @@ func (h *TaskManagementHandler) SubmitTaskForm(c echo.Context) error {
 
 	case "complete":
 		// 提交表单并完成任务
-		if err := h.engine.CompleteTask(uint(taskID), userID, req.FormData, req.Comment); err != nil {
+		if err := h.engine.CompleteTask(ctx, uint(taskID), userID, req.FormData, req.Comment); err != nil {
 			h.logger.Error("Failed to complete task with form",
 				zap.Uint("task_id", uint(taskID)),
 				zap.Uint("user_id", userID),
 				zap.Error(err),
 			)
-			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to complete task: "+err.Error())
+			return engineHTTPError("Failed to complete task: ", err)
 		}
 
 		return c.JSON(http.StatusOK, map[string]interface{}{
@@ -384,42 +641,28 @@ func (h *TaskManagementHandler) SubmitTaskForm(c echo.Context) error {
 // GetTasksByStatus 根据状态获取任务列表（管理员功能）
 // GET /api/v1/tasks/status/:status
 func (h *TaskManagementHandler) GetTasksByStatus(c echo.Context) error {
-	// 验证管理员权限
-	if !h.isAdmin(c) {
-		return echo.NewHTTPError(http.StatusForbidden, "Admin access required")
-	}
-
+	ctx := c.Request().Context()
 	status := c.Param("status")
 	if status == "" {
 		return echo.NewHTTPError(http.StatusBadRequest, "Status parameter required")
 	}
 
 	// 获取分页参数
-	page, _ := strconv.Atoi(c.QueryParam("page"))
-	if page < 1 {
-		page = 1
-	}
-	pageSize, _ := strconv.Atoi(c.QueryParam("page_size"))
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 20
+	pageReq, err := pagination.Parse(c.QueryParams(), pagination.Default)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
 	// 获取任务列表
-	tasks, total, err := h.engine.GetTasksByStatus(status, (page-1)*pageSize, pageSize)
+	tasks, total, err := h.engine.GetTasksByStatus(ctx, status, pageReq.Offset(), pageReq.Limit())
 	if err != nil {
 		h.logger.Error("Failed to get tasks by status", zap.String("status", status), zap.Error(err))
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get tasks")
+		return engineHTTPError("Failed to get tasks: ", err)
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"success": true,
-		"data": map[string]interface{}{
-			"tasks":       tasks,
-			"total":       total,
-			"page":        page,
-			"page_size":   pageSize,
-			"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
-		},
+		"data":    pageReq.Result("tasks", tasks, total),
 	})
 }
 
@@ -446,10 +689,3 @@ func (h *TaskManagementHandler) canUserAccessTask(userID uint, task *model.TaskI
 	// TODO: 实现更精细的权限控制
 	return true
 }
-
-// isAdmin 检查用户是否为管理员
-func (h *TaskManagementHandler) isAdmin(c echo.Context) bool {
-	// TODO: 实现角色检查，这里暂时返回true用于测试
-	// 实际实现中需要从数据库查询用户角色
-	return true
-}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"miniflow/internal/engine"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// taskStreamKeepAlive 推送流空闲时发送注释行的间隔，避免代理断开空闲连接
+const taskStreamKeepAlive = 25 * time.Second
+
+// taskStreamInbox 每批变更之后推送的待办摘要
+type taskStreamInbox struct {
+	Cursor      uint `json:"cursor"`
+	ActiveCount int  `json:"active_count"`
+}
+
+// StreamTaskChanges 以 Server-Sent Events 推送当前用户的任务变更，前端待办列表据此实时刷新
+// GET /api/v1/user/tasks/stream?since=cursor
+//
+// 每个变更是一条 task.<type> 事件（如 task.created、task.assigned、task.completed），id 为事件游标；
+// 连接建立时和每批变更之后推送一条 inbox 事件，带最新游标和待办数量。
+// 断线重连时浏览器通过 Last-Event-ID 带回最后收到的游标，从该游标之后继续推送
+func (h *TaskManagementHandler) StreamTaskChanges(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		return echo.NewHTTPError(http.StatusUnauthorized, "User not authenticated")
+	}
+
+	raw := c.Request().Header.Get("Last-Event-ID")
+	if raw == "" {
+		raw = c.QueryParam("since")
+	}
+	var since uint64
+	if raw != "" {
+		parsed, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid cursor")
+		}
+		since = parsed
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set("Cache-Control", "no-store")
+	res.Header().Set("Connection", "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	// 推送和保活在不同的 goroutine 中写响应，写入需要互斥
+	var mu sync.Mutex
+	send := func(id, event string, data interface{}) error {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if id != "" {
+			if _, err := fmt.Fprintf(res, "id: %s\n", id); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(res, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+			return err
+		}
+		res.Flush()
+		return nil
+	}
+
+	ctx := c.Request().Context()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(taskStreamKeepAlive)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				mu.Lock()
+				_, err := fmt.Fprint(res, ": keep-alive\n\n")
+				if err == nil {
+					res.Flush()
+				}
+				mu.Unlock()
+				if err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	err := h.engine.StreamTaskChanges(ctx, userID, uint(since), taskChangesLimit, func(changes *engine.TaskChanges) error {
+		for _, change := range changes.Changes {
+			if err := send(strconv.FormatUint(uint64(change.ID), 10), "task."+change.Type, change); err != nil {
+				return err
+			}
+		}
+		return send(strconv.FormatUint(uint64(changes.Cursor), 10), "inbox", taskStreamInbox{
+			Cursor:      changes.Cursor,
+			ActiveCount: changes.ActiveCount,
+		})
+	})
+	if err != nil && ctx.Err() == nil {
+		// 响应已经开始，只能记录日志并结束推送流，客户端会自动重连
+		h.logger.Warn("Task change stream ended", zap.Uint("user_id", userID), zap.Error(err))
+	}
+	return nil
+}
@@ -1,12 +1,15 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
+	"miniflow/internal/engine"
 	"miniflow/internal/middleware"
 	"miniflow/internal/service"
 	"miniflow/pkg/logger"
+	"miniflow/pkg/pagination"
 	"miniflow/pkg/utils"
 
 	"github.com/labstack/echo/v4"
@@ -15,22 +18,27 @@ import (
 
 // UserHandler handles user-related HTTP requests
 type UserHandler struct {
-	userService *service.UserService
-	logger      *logger.Logger
-	validator   *utils.CustomValidator
+	userService    *service.UserService
+	processService *service.ProcessService
+	archiver       *engine.InstanceArchiver
+	logger         *logger.Logger
+	validator      *utils.CustomValidator
 }
 
 // NewUserHandler creates a new user handler
-func NewUserHandler(userService *service.UserService, logger *logger.Logger) *UserHandler {
+func NewUserHandler(userService *service.UserService, processService *service.ProcessService, archiver *engine.InstanceArchiver, logger *logger.Logger) *UserHandler {
 	return &UserHandler{
-		userService: userService,
-		logger:      logger,
-		validator:   utils.NewCustomValidator(),
+		userService:    userService,
+		processService: processService,
+		archiver:       archiver,
+		logger:         logger,
+		validator:      utils.NewCustomValidator(),
 	}
 }
 
 // Register handles user registration
 func (h *UserHandler) Register(c echo.Context) error {
+	ctx := c.Request().Context()
 	var req service.RegisterRequest
 
 	// Bind request data
@@ -52,7 +60,7 @@ func (h *UserHandler) Register(c echo.Context) error {
 	}
 
 	// Call service to register user
-	user, err := h.userService.Register(&req)
+	user, err := h.userService.Register(ctx, &req)
 	if err != nil {
 		h.logger.Error("Registration failed", zap.Error(err))
 		return c.JSON(http.StatusBadRequest, map[string]string{
@@ -74,6 +82,7 @@ func (h *UserHandler) Register(c echo.Context) error {
 
 // Login handles user authentication
 func (h *UserHandler) Login(c echo.Context) error {
+	ctx := c.Request().Context()
 	var req service.LoginRequest
 
 	// Bind request data
@@ -95,7 +104,7 @@ func (h *UserHandler) Login(c echo.Context) error {
 	}
 
 	// Call service to authenticate user
-	loginResp, err := h.userService.Login(&req)
+	loginResp, err := h.userService.Login(ctx, &req)
 	if err != nil {
 		h.logger.Warn("Login failed",
 			zap.String("username", req.Username),
@@ -118,8 +127,77 @@ func (h *UserHandler) Login(c echo.Context) error {
 	})
 }
 
+// RefreshToken handles exchanging a refresh token for new tokens
+func (h *UserHandler) RefreshToken(c echo.Context) error {
+	ctx := c.Request().Context()
+	var req service.RefreshTokenRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数格式错误",
+			"code":  "INVALID_REQUEST_FORMAT",
+		})
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数验证失败",
+			"code":  "VALIDATION_FAILED",
+		})
+	}
+
+	loginResp, err := h.userService.RefreshToken(ctx, &req)
+	if err != nil {
+		h.logger.Warn("Token refresh failed", zap.Error(err))
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": err.Error(),
+			"code":  "REFRESH_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "刷新登录凭证成功",
+		"data":    loginResp,
+	})
+}
+
+// Logout handles revoking the current access token and refresh tokens
+func (h *UserHandler) Logout(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "用户认证信息无效",
+			"code":  "INVALID_USER_CONTEXT",
+		})
+	}
+
+	var req service.LogoutRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数格式错误",
+			"code":  "INVALID_REQUEST_FORMAT",
+		})
+	}
+
+	tokenID, expiresAt := middleware.GetTokenFromContext(c)
+	if err := h.userService.Logout(ctx, userID, tokenID, expiresAt, &req); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+			"code":  "LOGOUT_FAILED",
+		})
+	}
+
+	h.logger.Info("User logged out via API", zap.Uint("user_id", userID))
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "已退出登录",
+	})
+}
+
 // GetProfile handles getting user profile
 func (h *UserHandler) GetProfile(c echo.Context) error {
+	ctx := c.Request().Context()
 	userID, ok := middleware.GetUserIDFromContext(c)
 	if !ok {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
@@ -128,7 +206,7 @@ func (h *UserHandler) GetProfile(c echo.Context) error {
 		})
 	}
 
-	user, err := h.userService.GetProfile(userID)
+	user, err := h.userService.GetProfile(ctx, userID)
 	if err != nil {
 		h.logger.Error("Failed to get user profile",
 			zap.Uint("user_id", userID),
@@ -148,6 +226,7 @@ func (h *UserHandler) GetProfile(c echo.Context) error {
 
 // UpdateProfile handles updating user profile
 func (h *UserHandler) UpdateProfile(c echo.Context) error {
+	ctx := c.Request().Context()
 	userID, ok := middleware.GetUserIDFromContext(c)
 	if !ok {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
@@ -177,7 +256,7 @@ func (h *UserHandler) UpdateProfile(c echo.Context) error {
 	}
 
 	// Call service to update profile
-	user, err := h.userService.UpdateProfile(userID, &req)
+	user, err := h.userService.UpdateProfile(ctx, userID, &req)
 	if err != nil {
 		h.logger.Error("Failed to update user profile",
 			zap.Uint("user_id", userID),
@@ -201,6 +280,7 @@ func (h *UserHandler) UpdateProfile(c echo.Context) error {
 
 // ChangePassword handles password change
 func (h *UserHandler) ChangePassword(c echo.Context) error {
+	ctx := c.Request().Context()
 	userID, ok := middleware.GetUserIDFromContext(c)
 	if !ok {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
@@ -230,7 +310,7 @@ func (h *UserHandler) ChangePassword(c echo.Context) error {
 	}
 
 	// Call service to change password
-	err := h.userService.ChangePassword(userID, req.OldPassword, req.NewPassword)
+	err := h.userService.ChangePassword(ctx, userID, req.OldPassword, req.NewPassword)
 	if err != nil {
 		h.logger.Warn("Password change failed",
 			zap.Uint("user_id", userID),
@@ -251,19 +331,18 @@ func (h *UserHandler) ChangePassword(c echo.Context) error {
 
 // GetUsers handles getting users list (admin only)
 func (h *UserHandler) GetUsers(c echo.Context) error {
+	ctx := c.Request().Context()
 	// Get pagination parameters
-	page, _ := strconv.Atoi(c.QueryParam("page"))
-	pageSize, _ := strconv.Atoi(c.QueryParam("page_size"))
-
-	if page < 1 {
-		page = 1
-	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 20
+	pageReq, err := pagination.Parse(c.QueryParams(), pagination.Default)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+			"code":  "INVALID_PAGINATION",
+		})
 	}
 
 	// Call service to get users
-	users, total, err := h.userService.GetUsers(page, pageSize)
+	users, total, err := h.userService.GetUsers(ctx, pageReq.Page, pageReq.PageSize)
 	if err != nil {
 		h.logger.Error("Failed to get users list", zap.Error(err))
 		return c.JSON(http.StatusInternalServerError, map[string]string{
@@ -274,17 +353,13 @@ func (h *UserHandler) GetUsers(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"message": "获取用户列表成功",
-		"data": map[string]interface{}{
-			"users":     users,
-			"total":     total,
-			"page":      page,
-			"page_size": pageSize,
-		},
+		"data":    pageReq.Result("users", users, total),
 	})
 }
 
 // DeactivateUser handles user deactivation (admin only)
 func (h *UserHandler) DeactivateUser(c echo.Context) error {
+	ctx := c.Request().Context()
 	// Get user ID from path parameter
 	userIDStr := c.Param("id")
 	userID, err := strconv.ParseUint(userIDStr, 10, 32)
@@ -296,7 +371,7 @@ func (h *UserHandler) DeactivateUser(c echo.Context) error {
 	}
 
 	// Call service to deactivate user
-	err = h.userService.DeactivateUser(uint(userID))
+	err = h.userService.DeactivateUser(ctx, uint(userID))
 	if err != nil {
 		h.logger.Error("Failed to deactivate user",
 			zap.Uint("target_user_id", uint(userID)),
@@ -312,14 +387,148 @@ func (h *UserHandler) DeactivateUser(c echo.Context) error {
 		zap.Uint("target_user_id", uint(userID)),
 	)
 
-	return c.JSON(http.StatusOK, map[string]string{
+	// Report definitions that still reference the deactivated user
+	impacted, err := h.processService.ScanUserReferences(ctx, uint(userID))
+	if err != nil {
+		h.logger.Warn("Failed to check definitions referencing deactivated user",
+			zap.Uint("target_user_id", uint(userID)),
+			zap.Error(err),
+		)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
 		"message": "用户停用成功",
+		"data": map[string]interface{}{
+			"impacted_definitions": impacted,
+		},
+	})
+}
+
+// EraseUser handles hard-deleting a user's personal data (admin only)
+// DELETE /api/v1/admin/users/:id
+func (h *UserHandler) EraseUser(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "无效的用户ID",
+			"code":  "INVALID_USER_ID",
+		})
+	}
+
+	adminID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "用户认证信息无效",
+			"code":  "INVALID_USER_CONTEXT",
+		})
+	}
+
+	var req service.EraseUserRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数格式错误",
+			"code":  "INVALID_REQUEST_FORMAT",
+		})
+	}
+	if err := h.validator.Validate(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "请求参数验证失败",
+			"code":  "VALIDATION_FAILED",
+		})
+	}
+
+	// Scan before erasing: references by username can no longer be attributed once the user is gone
+	impacted, err := h.processService.ScanUserReferences(ctx, uint(userID))
+	if err != nil {
+		h.logger.Warn("Failed to check definitions referencing erased user",
+			zap.Uint("target_user_id", uint(userID)),
+			zap.Error(err),
+		)
+	}
+
+	erasure, err := h.userService.EraseUser(ctx, uint(userID), adminID, req.Reason)
+	if err != nil {
+		h.logger.Error("Failed to erase user",
+			zap.Uint("target_user_id", uint(userID)),
+			zap.Error(err),
+		)
+		status, code := http.StatusInternalServerError, "ERASE_USER_FAILED"
+		switch {
+		case errors.Is(err, service.ErrUserNotFound):
+			status, code = http.StatusNotFound, "USER_NOT_FOUND"
+		case errors.Is(err, service.ErrEraseSelf), errors.Is(err, service.ErrTombstoneUser):
+			status, code = http.StatusBadRequest, "ERASE_USER_NOT_ALLOWED"
+		case errors.Is(err, service.ErrUserHasOpenTasks):
+			status, code = http.StatusConflict, "USER_HAS_OPEN_TASKS"
+		}
+		return c.JSON(status, map[string]string{
+			"error": err.Error(),
+			"code":  code,
+		})
+	}
+
+	// Archive files live outside the database transaction, so they are rewritten once the erasure is committed
+	archives, err := h.archiver.ReassignArchivedUser(ctx, erasure.UserID, erasure.TombstoneID)
+	if err != nil {
+		h.logger.Error("Failed to rewrite archives of erased user",
+			zap.Uint("target_user_id", uint(userID)),
+			zap.Int("rewritten", archives),
+			zap.Error(err),
+		)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "用户数据已删除，但改写归档文件失败: " + err.Error(),
+			"code":  "ERASE_ARCHIVES_FAILED",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "用户数据已删除",
+		"data": map[string]interface{}{
+			"erasure":              erasure,
+			"impacted_definitions": impacted,
+			"archives_rewritten":   archives,
+		},
+	})
+}
+
+// GetUserErasures handles getting the erasure records of a user (admin only)
+// GET /api/v1/admin/users/:id/erasures
+func (h *UserHandler) GetUserErasures(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "无效的用户ID",
+			"code":  "INVALID_USER_ID",
+		})
+	}
+
+	erasures, err := h.userService.GetUserErasures(ctx, uint(userID))
+	if err != nil {
+		h.logger.Error("Failed to get user erasures", zap.Uint("target_user_id", uint(userID)), zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "获取用户删除记录失败",
+			"code":  "GET_ERASURES_FAILED",
+		})
+	}
+	if len(erasures) == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "用户删除记录不存在",
+			"code":  "ERASURE_NOT_FOUND",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "获取用户删除记录成功",
+		"data":    erasures,
 	})
 }
 
 // GetUserStats handles getting user statistics (admin only)
 func (h *UserHandler) GetUserStats(c echo.Context) error {
-	stats, err := h.userService.GetUserStats()
+	ctx := c.Request().Context()
+	stats, err := h.userService.GetUserStats(ctx)
 	if err != nil {
 		h.logger.Error("Failed to get user stats", zap.Error(err))
 		return c.JSON(http.StatusInternalServerError, map[string]string{
@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"miniflow/internal/engine"
+	"miniflow/pkg/logger"
+	"miniflow/pkg/pagination"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// WebhookHandler 事件订阅API处理器
+type WebhookHandler struct {
+	dispatcher *engine.WebhookDispatcher
+	logger     *logger.Logger
+}
+
+// NewWebhookHandler 创建事件订阅处理器
+func NewWebhookHandler(dispatcher *engine.WebhookDispatcher, logger *logger.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		dispatcher: dispatcher,
+		logger:     logger,
+	}
+}
+
+// ListSubscriptions 获取全部事件订阅
+// GET /api/v1/webhooks
+func (h *WebhookHandler) ListSubscriptions(c echo.Context) error {
+	ctx := c.Request().Context()
+	subscriptions, err := h.dispatcher.ListSubscriptions(ctx, getUserIDFromContext(c))
+	if err != nil {
+		h.logger.Error("Failed to list webhook subscriptions", zap.Error(err))
+		return engineHTTPError("Failed to list webhook subscriptions: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    subscriptions,
+	})
+}
+
+// CreateSubscription 注册事件订阅
+// POST /api/v1/webhooks
+func (h *WebhookHandler) CreateSubscription(c echo.Context) error {
+	ctx := c.Request().Context()
+	var req engine.WebhookSubscriptionRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	subscription, err := h.dispatcher.CreateSubscription(ctx, &req, getUserIDFromContext(c))
+	if err != nil {
+		h.logger.Error("Failed to create webhook subscription", zap.Error(err))
+		return engineHTTPError("Failed to create webhook subscription: ", err)
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"data":    subscription,
+	})
+}
+
+// UpdateSubscription 修改事件订阅，可用于停用或重新启用
+// PUT /api/v1/webhooks/:id
+func (h *WebhookHandler) UpdateSubscription(c echo.Context) error {
+	ctx := c.Request().Context()
+	subscriptionID, err := parseSubscriptionID(c)
+	if err != nil {
+		return err
+	}
+
+	var req engine.WebhookSubscriptionRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	subscription, err := h.dispatcher.UpdateSubscription(ctx, subscriptionID, &req, getUserIDFromContext(c))
+	if err != nil {
+		h.logger.Error("Failed to update webhook subscription", zap.Uint("subscription_id", subscriptionID), zap.Error(err))
+		return engineHTTPError("Failed to update webhook subscription: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    subscription,
+	})
+}
+
+// DeleteSubscription 删除事件订阅
+// DELETE /api/v1/webhooks/:id
+func (h *WebhookHandler) DeleteSubscription(c echo.Context) error {
+	ctx := c.Request().Context()
+	subscriptionID, err := parseSubscriptionID(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.dispatcher.DeleteSubscription(ctx, subscriptionID, getUserIDFromContext(c)); err != nil {
+		h.logger.Error("Failed to delete webhook subscription", zap.Uint("subscription_id", subscriptionID), zap.Error(err))
+		return engineHTTPError("Failed to delete webhook subscription: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Webhook subscription deleted",
+	})
+}
+
+// ListDeliveries 分页获取事件订阅的投递记录
+// GET /api/v1/webhooks/:id/deliveries
+func (h *WebhookHandler) ListDeliveries(c echo.Context) error {
+	ctx := c.Request().Context()
+	subscriptionID, err := parseSubscriptionID(c)
+	if err != nil {
+		return err
+	}
+
+	pageReq, err := pagination.Parse(c.QueryParams(), pagination.Default)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	deliveries, total, err := h.dispatcher.ListDeliveries(ctx, subscriptionID, getUserIDFromContext(c), pageReq.Offset(), pageReq.Limit())
+	if err != nil {
+		h.logger.Error("Failed to list webhook deliveries", zap.Uint("subscription_id", subscriptionID), zap.Error(err))
+		return engineHTTPError("Failed to list webhook deliveries: ", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    pageReq.Result("deliveries", deliveries, total),
+	})
+}
+
+// parseSubscriptionID 解析事件订阅ID路径参数
+func parseSubscriptionID(c echo.Context) (uint, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "Invalid subscription ID")
+	}
+	return uint(id), nil
+}
@@ -1,27 +1,49 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"miniflow/internal/repository"
+	"miniflow/pkg/config"
 	"miniflow/pkg/logger"
 	"miniflow/pkg/utils"
 
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
-// AuthMiddleware handles JWT authentication
+// AuthMiddleware handles JWT authentication and role-based authorization
 type AuthMiddleware struct {
-	jwtManager *utils.JWTManager
-	logger     *logger.Logger
+	jwtManager   *utils.JWTManager
+	userRepo     *repository.UserRepository
+	instanceRepo *repository.ProcessInstanceRepository
+	tokenRepo    *repository.TokenRepository
+	rbac         *config.RBACConfig
+	logger       *logger.Logger
 }
 
 // NewAuthMiddleware creates a new auth middleware
-func NewAuthMiddleware(jwtManager *utils.JWTManager, logger *logger.Logger) *AuthMiddleware {
+func NewAuthMiddleware(
+	jwtManager *utils.JWTManager,
+	userRepo *repository.UserRepository,
+	instanceRepo *repository.ProcessInstanceRepository,
+	tokenRepo *repository.TokenRepository,
+	rbac *config.RBACConfig,
+	logger *logger.Logger,
+) *AuthMiddleware {
 	return &AuthMiddleware{
-		jwtManager: jwtManager,
-		logger:     logger,
+		jwtManager:   jwtManager,
+		userRepo:     userRepo,
+		instanceRepo: instanceRepo,
+		tokenRepo:    tokenRepo,
+		rbac:         rbac,
+		logger:       logger,
 	}
 }
 
@@ -63,7 +85,7 @@ func (m *AuthMiddleware) JWTAuth() echo.MiddlewareFunc {
 			}
 
 			// Validate token
-			userID, username, err := m.jwtManager.ValidateToken(tokenString)
+			claims, err := m.jwtManager.ParseToken(tokenString)
 			if err != nil {
 				m.logger.Warn("Invalid JWT token", 
 					zap.String("error", err.Error()),
@@ -75,13 +97,32 @@ func (m *AuthMiddleware) JWTAuth() echo.MiddlewareFunc {
 				})
 			}
 
+			// Reject tokens revoked by logout
+			revoked, err := m.isRevoked(c.Request().Context(), claims)
+			if err != nil {
+				m.logger.Error("Failed to check token revocation", zap.Error(err))
+				return c.JSON(http.StatusInternalServerError, map[string]string{
+					"error": "认证检查失败",
+					"code":  "AUTH_CHECK_FAILED",
+				})
+			}
+			if revoked {
+				m.logger.Warn("Revoked JWT token", 
+					zap.Uint("user_id", claims.UserID),
+					zap.String("path", c.Request().URL.Path),
+				)
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error": "认证信息已注销",
+					"code":  "TOKEN_REVOKED",
+				})
+			}
+
 			// Set user info in context
-			c.Set("user_id", userID)
-			c.Set("username", username)
+			setClaims(c, claims)
 
 			m.logger.Debug("User authenticated successfully", 
-				zap.Uint("user_id", userID),
-				zap.String("username", username),
+				zap.Uint("user_id", claims.UserID),
+				zap.String("username", claims.Username),
 				zap.String("path", c.Request().URL.Path),
 			)
 
@@ -112,38 +153,148 @@ func (m *AuthMiddleware) OptionalJWTAuth() echo.MiddlewareFunc {
 			}
 
 			// Try to validate token
-			userID, username, err := m.jwtManager.ValidateToken(tokenString)
+			claims, err := m.jwtManager.ParseToken(tokenString)
 			if err != nil {
 				// Invalid token, but continue without authentication
 				m.logger.Debug("Optional auth failed", zap.Error(err))
 				return next(c)
 			}
+			if revoked, err := m.isRevoked(c.Request().Context(), claims); err != nil || revoked {
+				return next(c)
+			}
 
 			// Set user info in context if token is valid
-			c.Set("user_id", userID)
-			c.Set("username", username)
+			setClaims(c, claims)
 
 			return next(c)
 		}
 	}
 }
 
-// RequireRole returns role-based authorization middleware
-func (m *AuthMiddleware) RequireRole(requiredRole string) echo.MiddlewareFunc {
+// isRevoked reports whether the token was revoked by logout; tokens issued
+// before tokens carried an ID can't be revoked individually
+func (m *AuthMiddleware) isRevoked(ctx context.Context, claims *utils.Claims) (bool, error) {
+	if claims.ID == "" {
+		return false, nil
+	}
+	return m.tokenRepo.IsAccessTokenRevoked(ctx, claims.ID)
+}
+
+// setClaims sets the authenticated user and token in the Echo context
+func setClaims(c echo.Context, claims *utils.Claims) {
+	c.Set("user_id", claims.UserID)
+	c.Set("username", claims.Username)
+	c.Set("token_id", claims.ID)
+	if claims.ExpiresAt != nil {
+		c.Set("token_expires_at", claims.ExpiresAt.Time)
+	}
+}
+
+// Start runs the expired token cleanup loop until ctx is cancelled
+func (m *AuthMiddleware) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := m.tokenRepo.DeleteExpired(ctx, now); err != nil {
+				m.logger.Error("Failed to delete expired tokens", zap.Error(err))
+			}
+		}
+	}
+}
+
+// RequireRole returns role-based authorization middleware allowing the given roles
+// This middleware should be used after JWTAuth
+func (m *AuthMiddleware) RequireRole(roles ...string) echo.MiddlewareFunc {
+	return m.authorize(func(c echo.Context, userID uint, role string) bool {
+		for _, allowed := range roles {
+			if role == allowed {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// RequirePermission returns authorization middleware allowing the roles granted
+// permission in the RBAC permission matrix
+func (m *AuthMiddleware) RequirePermission(permission string) echo.MiddlewareFunc {
+	return m.authorize(func(c echo.Context, userID uint, role string) bool {
+		return m.rbac.Allows(permission, role)
+	})
+}
+
+// RequireInstancePermission is RequirePermission for routes on the process
+// instance in the :id path parameter; the instance's starter is always allowed
+func (m *AuthMiddleware) RequireInstancePermission(permission string) echo.MiddlewareFunc {
+	return m.authorize(func(c echo.Context, userID uint, role string) bool {
+		if m.rbac.Allows(permission, role) {
+			return true
+		}
+		instanceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			return false
+		}
+		instance, err := m.instanceRepo.GetByID(c.Request().Context(), uint(instanceID))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// 实例不存在时交给处理器返回 404
+			return true
+		}
+		if err != nil {
+			// 无法确认发起人时拒绝访问
+			m.logger.Error("Failed to load instance for permission check",
+				zap.Uint64("instance_id", instanceID),
+				zap.Error(err),
+			)
+			return false
+		}
+		return instance.StarterID == userID
+	})
+}
+
+// authorize loads the authenticated user's role and rejects the request
+// unless allowed returns true for it
+func (m *AuthMiddleware) authorize(allowed func(c echo.Context, userID uint, role string) bool) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			// This middleware should be used after JWTAuth
-			userID := c.Get("user_id")
-			if userID == nil {
+			userID, ok := GetUserIDFromContext(c)
+			if !ok {
 				return c.JSON(http.StatusUnauthorized, map[string]string{
 					"error": "需要认证",
 					"code":  "AUTHENTICATION_REQUIRED",
 				})
 			}
 
-			// TODO: Get user role from database or JWT claims
-			// For now, we'll implement this when we have user service in handlers
-			// This is a placeholder for role-based access control
+			// 角色从数据库读取，修改角色或停用用户后立即生效
+			user, err := m.userRepo.GetByID(c.Request().Context(), userID)
+			if err != nil || user.Status != "active" {
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error": "用户不存在或已停用",
+					"code":  "USER_INACTIVE",
+				})
+			}
+			c.Set("role", user.Role)
+
+			if !allowed(c, userID, user.Role) {
+				m.logger.Warn("Permission denied",
+					zap.Uint("user_id", userID),
+					zap.String("role", user.Role),
+					zap.String("path", c.Request().URL.Path),
+					zap.String("method", c.Request().Method),
+				)
+				return c.JSON(http.StatusForbidden, map[string]string{
+					"error": "没有权限执行此操作",
+					"code":  "PERMISSION_DENIED",
+				})
+			}
 
 			return next(c)
 		}
@@ -177,3 +328,10 @@ func GetUsernameFromContext(c echo.Context) (string, bool) {
 	
 	return "", false
 }
+
+// GetTokenFromContext extracts the access token ID and expiry from Echo context
+func GetTokenFromContext(c echo.Context) (string, time.Time) {
+	tokenID, _ := c.Get("token_id").(string)
+	expiresAt, _ := c.Get("token_expires_at").(time.Time)
+	return tokenID, expiresAt
+}
@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"miniflow/internal/migration"
+	"miniflow/internal/model"
+	"miniflow/internal/repository"
+	"miniflow/pkg/config"
+	"miniflow/pkg/database"
+	"miniflow/pkg/logger"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// newTestAuthMiddleware creates the middleware on a migrated sqlite database in a temp directory
+func newTestAuthMiddleware(t *testing.T, rbac *config.RBACConfig) (*AuthMiddleware, *gorm.DB) {
+	t.Helper()
+
+	gdb, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "auth.db")), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := gdb.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	log := &logger.Logger{Logger: zap.NewNop()}
+	if _, err := migration.New(gdb, log).Up(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	db := database.Wrap(gdb, log)
+	m := NewAuthMiddleware(nil,
+		repository.NewUserRepository(db, log),
+		repository.NewProcessInstanceRepository(db, log),
+		repository.NewTokenRepository(db, log),
+		rbac, log)
+	return m, gdb
+}
+
+// serveInstanceRoute calls a route on the instance guarded by RequireInstancePermission as the user
+func serveInstanceRoute(m *AuthMiddleware, userID uint, instanceID string) int {
+	e := echo.New()
+	e.POST("/instance/:id/cancel", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set("user_id", userID)
+			return next(c)
+		}
+	}, m.RequireInstancePermission(config.PermissionInstanceCancel))
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/instance/"+instanceID+"/cancel", nil))
+	return rec.Code
+}
+
+func TestRequireInstancePermission(t *testing.T) {
+	m, db := newTestAuthMiddleware(t, &config.RBACConfig{Permissions: map[string][]string{
+		config.PermissionInstanceCancel: {"admin"},
+	}})
+
+	var users []*model.User
+	for _, name := range []string{"admin", "starter", "other"} {
+		role := "user"
+		if name == "admin" {
+			role = "admin"
+		}
+		user := &model.User{Username: name, Password: "x", Email: name + "@example.com", Role: role, Status: "active"}
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("create user %s: %v", name, err)
+		}
+		users = append(users, user)
+	}
+	admin, starter, other := users[0], users[1], users[2]
+
+	definition := &model.ProcessDefinition{Key: "p", Name: "p", Version: 1, Status: model.ProcessStatusPublished, CreatedBy: admin.ID}
+	if err := db.Create(definition).Error; err != nil {
+		t.Fatalf("create definition: %v", err)
+	}
+	instance := &model.ProcessInstance{DefinitionID: definition.ID, BusinessKey: "b", Status: model.InstanceStatusRunning, StarterID: starter.ID}
+	if err := db.Create(instance).Error; err != nil {
+		t.Fatalf("create instance: %v", err)
+	}
+	instanceID := strconv.FormatUint(uint64(instance.ID), 10)
+
+	tests := []struct {
+		name       string
+		userID     uint
+		instanceID string
+		want       int
+	}{
+		{"granted role", admin.ID, instanceID, http.StatusOK},
+		{"starter", starter.ID, instanceID, http.StatusOK},
+		{"other user", other.ID, instanceID, http.StatusForbidden},
+		// 实例不存在时交给处理器返回 404
+		{"missing instance", other.ID, "999", http.StatusOK},
+		{"invalid id", other.ID, "abc", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serveInstanceRoute(m, tt.userID, tt.instanceID); got != tt.want {
+				t.Fatalf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	// 读取实例失败时无法确认发起人，拒绝访问
+	err := db.Callback().Query().After("gorm:query").Register("test:fail_instance_query", func(tx *gorm.DB) {
+		if tx.Statement.Table == "process_instances" {
+			tx.AddError(errors.New("database unavailable"))
+		}
+	})
+	if err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+	if got := serveInstanceRoute(m, other.ID, instanceID); got != http.StatusForbidden {
+		t.Fatalf("status on lookup error = %d, want %d", got, http.StatusForbidden)
+	}
+	if got := serveInstanceRoute(m, starter.ID, instanceID); got != http.StatusForbidden {
+		t.Fatalf("status for the starter on lookup error = %d, want %d", got, http.StatusForbidden)
+	}
+}
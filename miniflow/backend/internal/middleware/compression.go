@@ -0,0 +1,260 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// compressionLevel trades a little CPU for most of the size reduction on JSON
+	compressionLevel = gzip.DefaultCompression
+	// brotliLevel is below the brotli default, which costs several times the CPU of gzip
+	// on dynamic responses; level 4 is about as fast as gzip's default and compresses JSON
+	// at least as well
+	brotliLevel = 4
+	// compressionMinLength leaves small responses uncompressed, where the framing outweighs the savings
+	compressionMinLength = 1024
+)
+
+// Content codings the middleware can produce
+const (
+	encodingBrotli = "br"
+	encodingGzip   = "gzip"
+)
+
+// CompressionRoute overrides response compression for requests whose path starts with Prefix
+type CompressionRoute struct {
+	Prefix string
+	// MinLength is the smallest response body that is compressed; 0 uses the default
+	MinLength int
+	// Disabled turns compression off, e.g. for long polling where latency matters more than size
+	Disabled bool
+}
+
+// DefaultCompressionRoutes lists the per-route overrides; the first matching prefix wins,
+// so more specific prefixes must come first
+var DefaultCompressionRoutes = []CompressionRoute{
+	{Prefix: "/health", Disabled: true},
+	{Prefix: "/api/v1/health", Disabled: true},
+	{Prefix: "/api/v1/user/tasks/changes", Disabled: true},
+	{Prefix: "/api/v1/user/tasks/stream", Disabled: true},
+	// Attachments are mostly already compressed (images, PDF, Office documents)
+	{Prefix: "/api/v1/attachments", Disabled: true},
+	// Snapshots and deployment packages are large and compress well; start compressing early
+	{Prefix: "/api/v1/admin/runtime-snapshot", MinLength: 256},
+	{Prefix: "/api/v1/deployments", MinLength: 256},
+}
+
+// compressor is the part of gzip.Writer and brotli.Writer the middleware uses
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// encoders pool the compressors of each supported content coding
+var encoders = map[string]*sync.Pool{
+	encodingBrotli: {New: func() interface{} {
+		return brotli.NewWriterLevel(io.Discard, brotliLevel)
+	}},
+	encodingGzip: {New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(io.Discard, compressionLevel)
+		return w
+	}},
+}
+
+// compressionBuffers pools the buffers holding a response until it reaches the minimum length
+var compressionBuffers = sync.Pool{New: func() interface{} { return &bytes.Buffer{} }}
+
+// Compression returns a brotli and gzip middleware honouring the per-route overrides.
+// The coding is negotiated from Accept-Encoding; brotli is preferred when the client
+// accepts both with the same quality, and responses are sent uncompressed when it
+// accepts neither.
+func Compression(routes []CompressionRoute) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			minLength := compressionMinLength
+			path := c.Request().URL.Path
+			for _, route := range routes {
+				if strings.HasPrefix(path, route.Prefix) {
+					if route.Disabled {
+						return next(c)
+					}
+					if route.MinLength > 0 {
+						minLength = route.MinLength
+					}
+					break
+				}
+			}
+
+			res := c.Response()
+			res.Header().Add(echo.HeaderVary, echo.HeaderAcceptEncoding)
+			encoding := negotiateEncoding(c.Request().Header.Get(echo.HeaderAcceptEncoding))
+			if encoding == "" {
+				return next(c)
+			}
+
+			pool := encoders[encoding]
+			w := pool.Get().(compressor)
+			rw := res.Writer
+			w.Reset(rw)
+			buf := compressionBuffers.Get().(*bytes.Buffer)
+			buf.Reset()
+
+			cw := &compressResponseWriter{Writer: w, ResponseWriter: rw, encoding: encoding, minLength: minLength, buffer: buf}
+			defer func() {
+				switch {
+				case !cw.wroteBody:
+					// No body (204, redirects) or the handler failed: write the status as is and
+					// restore the plain writer so the error handler can write its response
+					if res.Header().Get(echo.HeaderContentEncoding) == encoding {
+						res.Header().Del(echo.HeaderContentEncoding)
+					}
+					if cw.wroteHeader {
+						rw.WriteHeader(cw.code)
+					}
+					res.Writer = rw
+					w.Reset(io.Discard)
+				case !cw.minLengthExceeded:
+					// The body stayed below the minimum length; send it uncompressed
+					res.Writer = rw
+					if cw.wroteHeader {
+						rw.WriteHeader(cw.code)
+					}
+					buf.WriteTo(rw)
+					w.Reset(io.Discard)
+				}
+				w.Close()
+				compressionBuffers.Put(buf)
+				pool.Put(w)
+			}()
+			res.Writer = cw
+			return next(c)
+		}
+	}
+}
+
+// negotiateEncoding picks the content coding for an Accept-Encoding header, or ""
+// when the client accepts neither brotli nor gzip. Codings with q=0 are refused;
+// "*" stands for every coding the header does not list.
+func negotiateEncoding(header string) string {
+	quality := map[string]float64{}
+	wildcard := -1.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(strings.TrimSpace(key), "q") {
+				parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				if err != nil {
+					parsed = 0
+				}
+				q = parsed
+			}
+		}
+		if name == "*" {
+			wildcard = q
+		} else {
+			quality[name] = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range []string{encodingBrotli, encodingGzip} {
+		q, listed := quality[encoding]
+		if !listed {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// compressResponseWriter buffers the response until it reaches the minimum length,
+// then sets Content-Encoding and streams the rest through the compressor
+type compressResponseWriter struct {
+	io.Writer
+	http.ResponseWriter
+	encoding          string
+	wroteHeader       bool
+	wroteBody         bool
+	minLength         int
+	minLengthExceeded bool
+	buffer            *bytes.Buffer
+	code              int
+}
+
+// WriteHeader delays the status code until it is known whether the response is compressed
+func (w *compressResponseWriter) WriteHeader(code int) {
+	// The compressed length differs from whatever the handler set
+	w.Header().Del(echo.HeaderContentLength)
+	w.wroteHeader = true
+	w.code = code
+}
+
+// Write buffers the body until it reaches the minimum length, then compresses it
+func (w *compressResponseWriter) Write(b []byte) (int, error) {
+	if w.Header().Get(echo.HeaderContentType) == "" {
+		w.Header().Set(echo.HeaderContentType, http.DetectContentType(b))
+	}
+	w.wroteBody = true
+
+	if w.minLengthExceeded {
+		return w.Writer.Write(b)
+	}
+	n, err := w.buffer.Write(b)
+	if w.buffer.Len() < w.minLength {
+		return n, err
+	}
+	w.startCompressing()
+	if _, err := w.Writer.Write(w.buffer.Bytes()); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// startCompressing sets Content-Encoding and writes the delayed status code
+func (w *compressResponseWriter) startCompressing() {
+	w.minLengthExceeded = true
+	w.Header().Set(echo.HeaderContentEncoding, w.encoding)
+	if w.wroteHeader {
+		w.ResponseWriter.WriteHeader(w.code)
+	}
+}
+
+// Flush compresses what has been buffered, since more data may follow, and flushes it to the client
+func (w *compressResponseWriter) Flush() {
+	if !w.minLengthExceeded {
+		w.startCompressing()
+		w.Writer.Write(w.buffer.Bytes())
+	}
+	w.Writer.(compressor).Flush()
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying writer for http.ResponseController
+func (w *compressResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Hijack lets websocket upgrades take over the connection
+func (w *compressResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
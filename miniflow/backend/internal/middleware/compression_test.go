@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/labstack/echo/v4"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"deflate", ""},
+		{"gzip", encodingGzip},
+		{"br", encodingBrotli},
+		{"gzip, deflate, br", encodingBrotli},
+		{"GZIP, BR", encodingBrotli},
+		{"gzip;q=1.0, br;q=0.8", encodingGzip},
+		{"gzip;q=0.5, br;q=0.5", encodingBrotli},
+		{"br;q=0, gzip", encodingGzip},
+		{"gzip;q=0", ""},
+		{"*", encodingBrotli},
+		{"gzip, *;q=0", encodingGzip},
+		{"br;q=0, *", encodingGzip},
+		{"*;q=0", ""},
+		{"gzip;q=bad", ""},
+		{" gzip ; q=0.3 , br ; q=0.2 ", encodingGzip},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := negotiateEncoding(tt.header); got != tt.want {
+				t.Fatalf("encoding = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// serveCompressed calls a route returning body through the compression middleware
+func serveCompressed(t *testing.T, path, acceptEncoding, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	e := echo.New()
+	e.Use(Compression([]CompressionRoute{
+		{Prefix: "/off", Disabled: true},
+		{Prefix: "/early", MinLength: 8},
+	}))
+	e.GET(path, func(c echo.Context) error {
+		return c.String(http.StatusOK, body)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if acceptEncoding != "" {
+		req.Header.Set(echo.HeaderAcceptEncoding, acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+// decodeBody decompresses the response body according to its Content-Encoding
+func decodeBody(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+
+	var r io.Reader = rec.Body
+	switch rec.Header().Get(echo.HeaderContentEncoding) {
+	case encodingBrotli:
+		r = brotli.NewReader(rec.Body)
+	case encodingGzip:
+		gr, err := gzip.NewReader(rec.Body)
+		if err != nil {
+			t.Fatalf("open gzip body: %v", err)
+		}
+		r = gr
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	return string(body)
+}
+
+func TestCompression(t *testing.T) {
+	large := strings.Repeat(`{"name":"task","status":"assigned"},`, 100)
+
+	tests := []struct {
+		name           string
+		path           string
+		acceptEncoding string
+		body           string
+		wantEncoding   string
+	}{
+		{"brotli preferred", "/api", "gzip, deflate, br", large, encodingBrotli},
+		{"gzip only", "/api", "gzip", large, encodingGzip},
+		{"gzip by quality", "/api", "br;q=0.1, gzip", large, encodingGzip},
+		{"no accepted coding", "/api", "deflate", large, ""},
+		{"no header", "/api", "", large, ""},
+		{"below minimum length", "/api", "br", "short", ""},
+		{"route minimum length", "/early", "br", "not so short", encodingBrotli},
+		{"disabled route", "/off", "br, gzip", large, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveCompressed(t, tt.path, tt.acceptEncoding, tt.body)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d", rec.Code)
+			}
+			if got := rec.Header().Get(echo.HeaderContentEncoding); got != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if tt.wantEncoding != "" && rec.Body.Len() >= len(tt.body) && len(tt.body) > 100 {
+				t.Fatalf("compressed body has %d bytes, plain body %d", rec.Body.Len(), len(tt.body))
+			}
+			if got := decodeBody(t, rec); got != tt.body {
+				t.Fatalf("body = %q, want %q", got, tt.body)
+			}
+			vary := rec.Header().Get(echo.HeaderVary)
+			if disabled := tt.path == "/off"; disabled == (vary == echo.HeaderAcceptEncoding) {
+				t.Fatalf("Vary = %q on path %s", vary, tt.path)
+			}
+		})
+	}
+}
+
+func TestCompressionKeepsStatusWithoutBody(t *testing.T) {
+	e := echo.New()
+	e.Use(Compression(nil))
+	e.GET("/none", func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})
+	e.GET("/fail", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusBadRequest, "bad")
+	})
+
+	for path, want := range map[string]int{"/none": http.StatusNoContent, "/fail": http.StatusBadRequest} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(echo.HeaderAcceptEncoding, "br")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Fatalf("%s: status = %d, want %d", path, rec.Code, want)
+		}
+		if got := rec.Header().Get(echo.HeaderContentEncoding); got != "" {
+			t.Fatalf("%s: Content-Encoding = %q", path, got)
+		}
+	}
+}
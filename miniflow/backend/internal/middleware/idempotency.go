@@ -0,0 +1,239 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/internal/repository"
+	"miniflow/pkg/logger"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header carrying the client generated key
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayHeader is set on responses replayed from a stored result
+	IdempotentReplayHeader = "Idempotent-Replayed"
+
+	// idempotencyTTL is how long a stored response is replayed
+	idempotencyTTL = 24 * time.Hour
+	// idempotencyStaleAfter is how long an unfinished request holds its key before a retry may take it over
+	idempotencyStaleAfter = 5 * time.Minute
+	// maxIdempotencyKeyLength matches the key column size
+	maxIdempotencyKeyLength = 255
+)
+
+// IdempotencyMiddleware replays the stored response of a mutating request when
+// a client retries it with the same Idempotency-Key header
+type IdempotencyMiddleware struct {
+	repo   *repository.IdempotencyRepository
+	logger *logger.Logger
+}
+
+// NewIdempotencyMiddleware creates a new idempotency middleware
+func NewIdempotencyMiddleware(repo *repository.IdempotencyRepository, logger *logger.Logger) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// Handle returns the idempotency middleware. It must run after JWTAuth so keys
+// are scoped per user. Requests without the header are passed through.
+func (m *IdempotencyMiddleware) Handle() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				return next(c)
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "幂等键过长",
+					"code":  "INVALID_IDEMPOTENCY_KEY",
+				})
+			}
+
+			userID, _ := GetUserIDFromContext(c)
+
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "读取请求体失败",
+					"code":  "INVALID_REQUEST_BODY",
+				})
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+			hash := requestHash(c.Request().Method, c.Request().URL.Path, body)
+
+			record, owner, err := m.acquire(c, userID, key, hash)
+			if err != nil {
+				m.logger.Error("Failed to acquire idempotency key", zap.Uint("user_id", userID), zap.Error(err))
+				return c.JSON(http.StatusInternalServerError, map[string]string{
+					"error": "幂等请求处理失败",
+					"code":  "IDEMPOTENCY_FAILED",
+				})
+			}
+
+			if record.RequestHash != hash {
+				return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+					"error": "幂等键已用于不同的请求",
+					"code":  "IDEMPOTENCY_KEY_REUSED",
+				})
+			}
+			if record.IsCompleted() {
+				return m.replay(c, record)
+			}
+			if !owner {
+				return c.JSON(http.StatusConflict, map[string]string{
+					"error": "相同幂等键的请求正在处理中",
+					"code":  "IDEMPOTENCY_KEY_IN_PROGRESS",
+				})
+			}
+
+			// 记录响应内容，处理完成后保存
+			recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = recorder
+
+			if err := next(c); err != nil {
+				// 由错误处理器写出响应，以便记录错误响应
+				c.Error(err)
+			}
+
+			m.complete(c, record, recorder.body.Bytes())
+			return nil
+		}
+	}
+}
+
+// acquire returns the record for the key and whether this request created it.
+// Expired records and records abandoned by a crashed request are replaced.
+func (m *IdempotencyMiddleware) acquire(c echo.Context, userID uint, key, hash string) (*model.IdempotencyRecord, bool, error) {
+	ctx := c.Request().Context()
+	now := time.Now()
+	for attempt := 0; attempt < 2; attempt++ {
+		existing, err := m.repo.Get(ctx, userID, key)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if existing != nil {
+			stale := !existing.IsCompleted() && now.Sub(existing.CreatedAt) > idempotencyStaleAfter
+			if now.Before(existing.ExpiresAt) && !stale {
+				return existing, false, nil
+			}
+			if err := m.repo.Delete(ctx, existing.ID); err != nil {
+				return nil, false, err
+			}
+		}
+
+		record := &model.IdempotencyRecord{
+			UserID:      userID,
+			Key:         key,
+			Method:      c.Request().Method,
+			Path:        c.Request().URL.Path,
+			RequestHash: hash,
+			ExpiresAt:   now.Add(idempotencyTTL),
+		}
+		if err := m.repo.Create(ctx, record); err == nil {
+			return record, true, nil
+		}
+		// 并发请求先创建了记录，重新读取
+	}
+
+	existing, err := m.repo.Get(ctx, userID, key)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing == nil {
+		return nil, false, errors.New("idempotency record could not be created")
+	}
+	return existing, false, nil
+}
+
+// complete stores the response of the first request. Server errors are not
+// stored so the client can retry with the same key.
+func (m *IdempotencyMiddleware) complete(c echo.Context, record *model.IdempotencyRecord, body []byte) {
+	ctx := c.Request().Context()
+	status := c.Response().Status
+	if status >= http.StatusInternalServerError {
+		if err := m.repo.Delete(ctx, record.ID); err != nil {
+			m.logger.Warn("Failed to release idempotency key", zap.Uint("id", record.ID), zap.Error(err))
+		}
+		return
+	}
+
+	record.StatusCode = status
+	record.ContentType = c.Response().Header().Get(echo.HeaderContentType)
+	record.ResponseBody = string(body)
+	if err := m.repo.Update(ctx, record); err != nil {
+		// 保存失败时释放键，避免后续重试一直等待
+		_ = m.repo.Delete(ctx, record.ID)
+	}
+}
+
+// replay writes the stored response of the first request
+func (m *IdempotencyMiddleware) replay(c echo.Context, record *model.IdempotencyRecord) error {
+	m.logger.Info("Replaying idempotent response",
+		zap.Uint("user_id", record.UserID),
+		zap.String("path", record.Path),
+		zap.Int("status", record.StatusCode),
+	)
+
+	c.Response().Header().Set(IdempotentReplayHeader, "true")
+	contentType := record.ContentType
+	if contentType == "" {
+		contentType = echo.MIMEApplicationJSONCharsetUTF8
+	}
+	return c.Blob(record.StatusCode, contentType, []byte(record.ResponseBody))
+}
+
+// Start runs the expired record cleanup loop until ctx is cancelled
+func (m *IdempotencyMiddleware) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := m.repo.DeleteExpired(ctx, now); err != nil {
+				m.logger.Error("Failed to delete expired idempotency records", zap.Error(err))
+			}
+		}
+	}
+}
+
+// requestHash fingerprints a request so a key reused for a different request is rejected
+func requestHash(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder copies the response body while writing it to the client
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write writes to the client and keeps a copy
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,246 @@
+package migration
+
+import (
+	"encoding/json"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// migrations lists every schema migration in the order they are applied. IDs
+// are timestamps so that migrations added on different branches sort by
+// creation time; never change or reorder a migration that has been released,
+// append a new one instead.
+//
+// Every migration works on frozen snapshots of the tables it touches (see
+// schema_initial.go and schema_snapshots.go), never on the live models, so it
+// creates the same schema however the models change later. Running every
+// migration on an empty database creates the schema of the current models;
+// a model change without a migration fails the migrator tests.
+var migrations = []Migration{
+	{
+		ID:          "20261016000001",
+		Description: "Initial schema",
+		Up:          createInitialSchema,
+		Down:        dropInitialSchema,
+	},
+	{
+		ID:          "20261016000002",
+		Description: "Add task comments",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&taskComment0002{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&taskComment0002{})
+		},
+	},
+	{
+		ID:          "20261016000003",
+		Description: "Add attachments",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&attachment0003{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&attachment0003{})
+		},
+	},
+	{
+		ID:          "20261016000004",
+		Description: "Move task form data off comment",
+		Up:          moveTaskFormData,
+		Down: func(tx *gorm.DB) error {
+			return dropColumn(tx, &taskFormData0004{}, "FormData")
+		},
+	},
+	{
+		ID:          "20261016000005",
+		Description: "Track skipped inclusive gateway branches",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&gatewayArrival0005{}, "Skipped") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&gatewayArrival0005{}, "Skipped")
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumn(tx, &gatewayArrival0005{}, "Skipped")
+		},
+	},
+	{
+		ID:          "20261016000006",
+		Description: "Add node loop counters",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&instanceLoopCounters0006{}, "LoopCounters") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&instanceLoopCounters0006{}, "LoopCounters")
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumn(tx, &instanceLoopCounters0006{}, "LoopCounters")
+		},
+	},
+	{
+		ID:          "20261016000007",
+		Description: "Add task reminders",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&taskReminder0007{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&taskReminder0007{})
+		},
+	},
+	{
+		ID:          "20261016000008",
+		Description: "Add notification inbox",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&notification0008{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&notification0008{})
+		},
+	},
+	{
+		ID:          "20261016000009",
+		Description: "Track task owner for delegation",
+		Up: func(tx *gorm.DB) error {
+			for _, field := range []string{"OwnerID", "DelegationState"} {
+				if tx.Migrator().HasColumn(&taskDelegation0009{}, field) {
+					continue
+				}
+				if err := tx.Migrator().AddColumn(&taskDelegation0009{}, field); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, field := range []string{"DelegationState", "OwnerID"} {
+				if err := dropColumn(tx, &taskDelegation0009{}, field); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		ID:          "20261016000010",
+		Description: "Add saved task filters",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&savedTaskFilter0010{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&savedTaskFilter0010{})
+		},
+	},
+	{
+		ID:          "20261016000011",
+		Description: "Add instance archives",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&instanceArchive0011{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&instanceArchive0011{})
+		},
+	},
+	{
+		ID:          "20261016000012",
+		Description: "Add user erasure records",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&userErasure0012{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&userErasure0012{})
+		},
+	},
+	{
+		ID:          "20261016000013",
+		Description: "Index task owner",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasIndex(&taskOwnerIndex0013{}, "OwnerID") {
+				return nil
+			}
+			return tx.Migrator().CreateIndex(&taskOwnerIndex0013{}, "OwnerID")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropIndex(&taskOwnerIndex0013{}, "OwnerID")
+		},
+	},
+	{
+		ID:          "20261016000014",
+		Description: "Add task escalation group",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&taskEscalationGroup0014{}, "EscalationGroup") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&taskEscalationGroup0014{}, "EscalationGroup")
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumn(tx, &taskEscalationGroup0014{}, "EscalationGroup")
+		},
+	},
+}
+
+// moveTaskFormData adds the form_data column to task_instances and moves form
+// data that earlier versions stored in the comment column into it. Only
+// comments holding a JSON object are moved; plain text comments stay.
+func moveTaskFormData(tx *gorm.DB) error {
+	if !tx.Migrator().HasColumn(&taskFormData0004{}, "FormData") {
+		if err := tx.Migrator().AddColumn(&taskFormData0004{}, "FormData"); err != nil {
+			return err
+		}
+	}
+
+	var tasks []taskFormData0004
+	if err := tx.Model(&taskFormData0004{}).Unscoped().
+		Select("id", "comment").
+		Where("comment LIKE ?", "{%").
+		Find(&tasks).Error; err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		var values map[string]interface{}
+		if json.Unmarshal([]byte(task.Comment), &values) != nil {
+			continue
+		}
+		if err := tx.Model(&taskFormData0004{}).Unscoped().
+			Where("id = ?", task.ID).
+			UpdateColumns(map[string]interface{}{
+				"form_data": task.Comment,
+				"comment":   "",
+			}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropColumn drops a column added by a migration. The sqlite migrator drops a
+// column by rebuilding the table, which loses the indexes of the table, so on
+// sqlite the column is dropped in place like on the other databases.
+func dropColumn(tx *gorm.DB, value interface{}, field string) error {
+	if tx.Dialector.Name() != "sqlite" {
+		return tx.Migrator().DropColumn(value, field)
+	}
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(value); err != nil {
+		return err
+	}
+	column := field
+	if f := stmt.Schema.LookUpField(field); f != nil {
+		column = f.DBName
+	}
+	return tx.Exec("ALTER TABLE ? DROP COLUMN ?", clause.Table{Name: stmt.Table}, clause.Column{Name: column}).Error
+}
+
+// createInitialSchema creates the tables of the initial schema
+func createInitialSchema(tx *gorm.DB) error {
+	return tx.AutoMigrate(initialSchema()...)
+}
+
+// dropInitialSchema drops the tables of the initial schema, dependents first
+func dropInitialSchema(tx *gorm.DB) error {
+	tables := initialSchema()
+	for i, j := 0, len(tables)-1; i < j; i, j = i+1, j-1 {
+		tables[i], tables[j] = tables[j], tables[i]
+	}
+	return tx.Migrator().DropTable(tables...)
+}
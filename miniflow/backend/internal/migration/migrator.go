@@ -0,0 +1,206 @@
+// Package migration applies the versioned schema migrations compiled into the
+// binary and records them in the schema_migrations table, so that schema
+// changes between releases are applied in a fixed order and can be rolled back.
+package migration
+
+import (
+	"fmt"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Migration is a single reversible schema change
+type Migration struct {
+	// ID orders the migrations and is stored once the migration is applied
+	ID          string
+	Description string
+	Up          func(tx *gorm.DB) error
+	// Down reverts Up; nil marks the migration as irreversible
+	Down func(tx *gorm.DB) error
+}
+
+// Status reports whether a migration has been applied
+type Status struct {
+	ID          string     `json:"id"`
+	Description string     `json:"description"`
+	Applied     bool       `json:"applied"`
+	AppliedAt   *time.Time `json:"applied_at,omitempty"`
+}
+
+// Migrator applies and rolls back migrations on a database
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+	logger     *logger.Logger
+}
+
+// New creates a migrator for the migrations compiled into the binary
+func New(db *gorm.DB, logger *logger.Logger) *Migrator {
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		logger:     logger,
+	}
+}
+
+// Up applies every pending migration in order and returns how many were applied
+func (m *Migrator) Up() (int, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return 0, err
+	}
+
+	if len(applied) == 0 && len(m.migrations) > 0 {
+		return m.initSchema()
+	}
+
+	count := 0
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.ID]; ok {
+			continue
+		}
+		if err := m.apply(migration); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Down rolls back the last steps applied migrations and returns how many were
+// rolled back
+func (m *Migrator) Down(steps int) (int, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(m.migrations) - 1; i >= 0 && count < steps; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.ID]; !ok {
+			continue
+		}
+		if err := m.rollback(migration); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Status lists every migration with the time it was applied
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, len(m.migrations))
+	for i, migration := range m.migrations {
+		statuses[i] = Status{ID: migration.ID, Description: migration.Description}
+		if record, ok := applied[migration.ID]; ok {
+			appliedAt := record.AppliedAt
+			statuses[i].Applied = true
+			statuses[i].AppliedAt = &appliedAt
+		}
+	}
+	return statuses, nil
+}
+
+// applied creates the migrations table when missing and loads the applied
+// migrations. It fails when the database has migrations this binary does not
+// know, i.e. it was migrated by a newer release.
+func (m *Migrator) applied() (map[string]model.SchemaMigration, error) {
+	if err := m.db.AutoMigrate(&model.SchemaMigration{}); err != nil {
+		return nil, fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	var records []model.SchemaMigration
+	if err := m.db.Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to load applied migrations: %w", err)
+	}
+
+	known := make(map[string]bool, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.ID] = true
+	}
+	applied := make(map[string]model.SchemaMigration, len(records))
+	for _, record := range records {
+		if !known[record.ID] {
+			return nil, fmt.Errorf("database has unknown migration %s, it was migrated by a newer release", record.ID)
+		}
+		applied[record.ID] = record
+	}
+	return applied, nil
+}
+
+// initSchema creates the schema on a database without applied migrations by
+// running every migration in a single transaction, so a failed initialization
+// leaves nothing behind on databases with transactional DDL
+func (m *Migrator) initSchema() (int, error) {
+	m.logger.Info("Initializing database schema", zap.Int("migrations", len(m.migrations)))
+
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		for _, migration := range m.migrations {
+			if err := migration.Up(tx); err != nil {
+				return fmt.Errorf("migration %s failed: %w", migration.ID, err)
+			}
+			if err := tx.Create(&model.SchemaMigration{
+				ID:          migration.ID,
+				Description: migration.Description,
+				AppliedAt:   now,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(m.migrations), nil
+}
+
+// apply runs a migration and records it in the same transaction
+func (m *Migrator) apply(migration Migration) error {
+	m.logger.Info("Applying migration",
+		zap.String("migration", migration.ID),
+		zap.String("description", migration.Description),
+	)
+
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		if err := migration.Up(tx); err != nil {
+			return fmt.Errorf("migration %s failed: %w", migration.ID, err)
+		}
+		return tx.Create(&model.SchemaMigration{
+			ID:          migration.ID,
+			Description: migration.Description,
+			AppliedAt:   time.Now(),
+		}).Error
+	})
+}
+
+// rollback reverts a migration and removes its record in the same transaction
+func (m *Migrator) rollback(migration Migration) error {
+	if migration.Down == nil {
+		return fmt.Errorf("migration %s is irreversible", migration.ID)
+	}
+	m.logger.Info("Rolling back migration",
+		zap.String("migration", migration.ID),
+		zap.String("description", migration.Description),
+	)
+
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		if err := migration.Down(tx); err != nil {
+			return fmt.Errorf("rollback of migration %s failed: %w", migration.ID, err)
+		}
+		return tx.Where("id = ?", migration.ID).Delete(&model.SchemaMigration{}).Error
+	})
+}
@@ -0,0 +1,293 @@
+package migration
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// openTestDB opens an empty sqlite database in a temp directory
+func openTestDB(t *testing.T, name string) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), name)), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func newTestMigrator(db *gorm.DB, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations, logger: &logger.Logger{Logger: zap.NewNop()}}
+}
+
+// schemaOf describes every table of a sqlite database: columns with type,
+// nullability and default, indexes with their columns, and foreign keys
+func schemaOf(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+
+	var tables []string
+	if err := db.Raw("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'").
+		Scan(&tables).Error; err != nil {
+		t.Fatalf("list tables: %v", err)
+	}
+
+	var schema []string
+	for _, table := range tables {
+		var columns []struct {
+			Name      string
+			Type      string
+			NotNull   bool `gorm:"column:notnull"`
+			DfltValue *string
+			Pk        int
+		}
+		db.Raw(fmt.Sprintf("PRAGMA table_info(%q)", table)).Scan(&columns)
+		for _, c := range columns {
+			dflt := "-"
+			if c.DfltValue != nil {
+				dflt = *c.DfltValue
+			}
+			schema = append(schema, fmt.Sprintf("%s column %s %s notnull=%v default=%s pk=%d", table, c.Name, c.Type, c.NotNull, dflt, c.Pk))
+		}
+
+		var indexes []struct {
+			Name   string
+			Unique bool
+		}
+		db.Raw(fmt.Sprintf("PRAGMA index_list(%q)", table)).Scan(&indexes)
+		for _, index := range indexes {
+			var names []string
+			db.Raw(fmt.Sprintf("SELECT name FROM pragma_index_info(%q) ORDER BY seqno", index.Name)).Scan(&names)
+			schema = append(schema, fmt.Sprintf("%s index %s unique=%v %v", table, index.Name, index.Unique, names))
+		}
+
+		var keys []struct {
+			Table    string
+			From     string
+			To       string
+			OnDelete string
+		}
+		db.Raw(fmt.Sprintf("PRAGMA foreign_key_list(%q)", table)).Scan(&keys)
+		for _, key := range keys {
+			schema = append(schema, fmt.Sprintf("%s foreign key %s -> %s.%s on delete %s", table, key.From, key.Table, key.To, key.OnDelete))
+		}
+	}
+	sort.Strings(schema)
+	return schema
+}
+
+// assertSameSchema fails with the lines that only one of the schemas has
+func assertSameSchema(t *testing.T, got, want []string) {
+	t.Helper()
+
+	inGot := make(map[string]bool, len(got))
+	for _, line := range got {
+		inGot[line] = true
+	}
+	inWant := make(map[string]bool, len(want))
+	for _, line := range want {
+		inWant[line] = true
+	}
+	for _, line := range want {
+		if !inGot[line] {
+			t.Errorf("missing: %s", line)
+		}
+	}
+	for _, line := range got {
+		if !inWant[line] {
+			t.Errorf("unexpected: %s", line)
+		}
+	}
+}
+
+// modelSchema returns the schema gorm creates for the current models
+func modelSchema(t *testing.T) []string {
+	t.Helper()
+
+	db := openTestDB(t, "models.db")
+	if err := db.AutoMigrate(append(model.AllModels(), &model.SchemaMigration{})...); err != nil {
+		t.Fatalf("migrate models: %v", err)
+	}
+	return schemaOf(t, db)
+}
+
+func TestInitSchemaCreatesCurrentModels(t *testing.T) {
+	db := openTestDB(t, "init.db")
+
+	count, err := New(db, &logger.Logger{Logger: zap.NewNop()}).Up()
+	if err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if count != len(migrations) {
+		t.Fatalf("applied %d migrations, want %d", count, len(migrations))
+	}
+
+	statuses, err := New(db, &logger.Logger{Logger: zap.NewNop()}).Status()
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	for _, status := range statuses {
+		if !status.Applied {
+			t.Errorf("migration %s is not recorded as applied", status.ID)
+		}
+	}
+
+	// 模型变更但没有对应的迁移时，这里的差异会指出缺少的列或索引
+	assertSameSchema(t, schemaOf(t, db), modelSchema(t))
+}
+
+func TestUpUpgradesEveryEarlierRelease(t *testing.T) {
+	want := modelSchema(t)
+
+	for released := 1; released < len(migrations); released++ {
+		t.Run(migrations[released-1].ID, func(t *testing.T) {
+			db := openTestDB(t, "upgrade.db")
+			if _, err := newTestMigrator(db, migrations[:released]).Up(); err != nil {
+				t.Fatalf("install release: %v", err)
+			}
+
+			count, err := New(db, &logger.Logger{Logger: zap.NewNop()}).Up()
+			if err != nil {
+				t.Fatalf("upgrade: %v", err)
+			}
+			if count != len(migrations)-released {
+				t.Fatalf("applied %d migrations, want %d", count, len(migrations)-released)
+			}
+			assertSameSchema(t, schemaOf(t, db), want)
+		})
+	}
+}
+
+func TestUpMovesFormDataOutOfComment(t *testing.T) {
+	db := openTestDB(t, "formdata.db")
+	formDataMigration := 0
+	for i, migration := range migrations {
+		if migration.ID == "20261016000004" {
+			formDataMigration = i
+		}
+	}
+	if _, err := newTestMigrator(db, migrations[:formDataMigration]).Up(); err != nil {
+		t.Fatalf("install release: %v", err)
+	}
+
+	tasks := []initialTaskInstance{
+		{InstanceID: 1, NodeID: "a", Name: "form", Status: "completed", Comment: `{"amount":100}`},
+		{InstanceID: 1, NodeID: "b", Name: "text", Status: "completed", Comment: "{looks like json"},
+		{InstanceID: 1, NodeID: "c", Name: "plain", Status: "completed", Comment: "approved"},
+	}
+	if err := db.Create(&tasks).Error; err != nil {
+		t.Fatalf("create tasks: %v", err)
+	}
+
+	if _, err := New(db, &logger.Logger{Logger: zap.NewNop()}).Up(); err != nil {
+		t.Fatalf("upgrade: %v", err)
+	}
+
+	want := map[string][2]string{
+		"form":  {"", `{"amount":100}`},
+		"text":  {"{looks like json", ""},
+		"plain": {"approved", ""},
+	}
+	var rows []model.TaskInstance
+	if err := db.Find(&rows).Error; err != nil {
+		t.Fatalf("load tasks: %v", err)
+	}
+	for _, row := range rows {
+		if got := [2]string{row.Comment, row.FormData}; got != want[row.Name] {
+			t.Errorf("task %s: comment, form data = %q, want %q", row.Name, got, want[row.Name])
+		}
+	}
+}
+
+func TestDownRollsBackInReverseOrder(t *testing.T) {
+	db := openTestDB(t, "down.db")
+	migrator := New(db, &logger.Logger{Logger: zap.NewNop()})
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+
+	// 回滚最后两个迁移后的结构与只安装之前迁移的结构相同
+	if count, err := migrator.Down(2); err != nil || count != 2 {
+		t.Fatalf("down 2: count %d, err %v", count, err)
+	}
+	previous := openTestDB(t, "previous.db")
+	if _, err := newTestMigrator(previous, migrations[:len(migrations)-2]).Up(); err != nil {
+		t.Fatalf("install previous release: %v", err)
+	}
+	assertSameSchema(t, schemaOf(t, db), schemaOf(t, previous))
+
+	statuses, err := migrator.Status()
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	for i, status := range statuses {
+		if applied := i < len(migrations)-2; status.Applied != applied {
+			t.Errorf("migration %s applied = %v, want %v", status.ID, status.Applied, applied)
+		}
+	}
+
+	if count, err := migrator.Up(); err != nil || count != 2 {
+		t.Fatalf("reapply: count %d, err %v", count, err)
+	}
+
+	// 全部回滚后只剩迁移记录表
+	if count, err := migrator.Down(len(migrations)); err != nil || count != len(migrations) {
+		t.Fatalf("down all: count %d, err %v", count, err)
+	}
+	var tables []string
+	db.Raw("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'").Scan(&tables)
+	if len(tables) != 1 || tables[0] != "schema_migrations" {
+		t.Fatalf("tables after rolling back everything: %v", tables)
+	}
+}
+
+func TestDownRestoresSchemaOfEachEarlierRelease(t *testing.T) {
+	db := openTestDB(t, "stepdown.db")
+	migrator := New(db, &logger.Logger{Logger: zap.NewNop()})
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+
+	// 每回滚一个迁移，结构（包括未回滚表上的索引）都与只安装之前迁移的结构相同
+	for released := len(migrations) - 1; released >= 1; released-- {
+		if count, err := migrator.Down(1); err != nil || count != 1 {
+			t.Fatalf("roll back %s: count %d, err %v", migrations[released].ID, count, err)
+		}
+		previous := openTestDB(t, fmt.Sprintf("release%d.db", released))
+		if _, err := newTestMigrator(previous, migrations[:released]).Up(); err != nil {
+			t.Fatalf("install release %s: %v", migrations[released-1].ID, err)
+		}
+		t.Run(migrations[released].ID, func(t *testing.T) {
+			assertSameSchema(t, schemaOf(t, db), schemaOf(t, previous))
+		})
+	}
+}
+
+func TestUpRejectsDatabaseOfNewerRelease(t *testing.T) {
+	db := openTestDB(t, "newer.db")
+	migrator := New(db, &logger.Logger{Logger: zap.NewNop()})
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if err := db.Create(&model.SchemaMigration{ID: "29991231000000", Description: "From the future"}).Error; err != nil {
+		t.Fatalf("record migration: %v", err)
+	}
+
+	if _, err := migrator.Up(); err == nil {
+		t.Fatal("up succeeded on a database migrated by a newer release")
+	}
+}
@@ -0,0 +1,619 @@
+package migration
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The types below are a frozen copy of the models as they were when migration
+// 20261016000001 was released. Migrations must not depend on the live models,
+// which keep changing; never edit these types, add a migration with its own
+// snapshot instead.
+
+// BaseModel is the frozen model.BaseModel; it is exported because gorm only
+// picks up the columns of exported embedded structs
+type BaseModel struct {
+	ID        uint           `gorm:"primaryKey;autoIncrement"`
+	CreatedAt time.Time      `gorm:"not null;index"`
+	UpdatedAt time.Time      `gorm:"not null"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+type initialUser struct {
+	BaseModel
+	Username     string `gorm:"type:varchar(100);not null;uniqueIndex"`
+	Password     string `gorm:"type:varchar(255);not null"`
+	DisplayName  string `gorm:"type:varchar(255)"`
+	Email        string `gorm:"type:varchar(255);uniqueIndex"`
+	Phone        string `gorm:"type:varchar(50)"`
+	Role         string `gorm:"type:varchar(50);not null;default:user;index"`
+	Status       string `gorm:"type:varchar(20);not null;default:active;index"`
+	Avatar       string `gorm:"type:varchar(500)"`
+	DepartmentID *uint  `gorm:"index"`
+	LastLoginAt  *time.Time
+}
+
+func (initialUser) TableName() string { return "users" }
+
+type initialProcessDefinition struct {
+	BaseModel
+	Key                     string                   `gorm:"column:key;type:varchar(100);not null;uniqueIndex:idx_key_version,composite:key"`
+	Name                    string                   `gorm:"type:varchar(255);not null;index"`
+	Version                 int                      `gorm:"not null;default:1;uniqueIndex:idx_key_version,composite:version"`
+	Description             string                   `gorm:"type:text"`
+	Category                string                   `gorm:"type:varchar(50);index"`
+	DefinitionJSON          string                   `gorm:"type:json;not null"`
+	Status                  string                   `gorm:"type:varchar(20);not null;default:draft;index"`
+	DataClassification      string                   `gorm:"type:varchar(20);not null;default:internal;index"`
+	DisplayLabels           string                   `gorm:"type:text"`
+	CreatedBy               uint                     `gorm:"not null;index;constraint:OnDelete:RESTRICT"`
+	CompletionWebhookURL    string                   `gorm:"type:varchar(500)"`
+	CompletionWebhookSecret string                   `gorm:"type:varchar(255)"`
+	ClaimExpiryHours        int                      `gorm:"not null;default:0"`
+	DuplicateKeyVariables   string                   `gorm:"type:text"`
+	RolloutEnabled          bool                     `gorm:"not null;default:false"`
+	RolloutPercentage       int                      `gorm:"not null;default:0"`
+	RolloutCondition        string                   `gorm:"type:varchar(500)"`
+	ComplexityScore         int                      `gorm:"not null;default:0;index"`
+	ComplexityReport        string                   `gorm:"type:text"`
+	Creator                 initialUser              `gorm:"foreignKey:CreatedBy"`
+	Instances               []initialProcessInstance `gorm:"foreignKey:DefinitionID;constraint:OnDelete:CASCADE"`
+}
+
+func (initialProcessDefinition) TableName() string { return "process_definitions" }
+
+type initialProcessInstance struct {
+	BaseModel
+	DefinitionID     uint                     `gorm:"not null;index"`
+	BusinessKey      string                   `gorm:"type:varchar(255);index"`
+	CurrentNode      string                   `gorm:"type:varchar(64);index"`
+	Status           string                   `gorm:"type:varchar(20);not null;default:running;index"`
+	Variables        string                   `gorm:"type:json"`
+	StartTime        time.Time                `gorm:"not null;index"`
+	EndTime          *time.Time               `gorm:"index"`
+	StarterID        uint                     `gorm:"not null;index"`
+	Version          int                      `gorm:"not null;default:1"`
+	TraceEnabled     bool                     `gorm:"not null;default:false"`
+	DueDate          *time.Time               `gorm:"index"`
+	ParentInstanceID *uint                    `gorm:"index"`
+	ParentNodeID     string                   `gorm:"type:varchar(64)"`
+	NodeVisits       string                   `gorm:"type:text"`
+	CancelledAt      *time.Time               `gorm:"index"`
+	Cancellation     string                   `gorm:"type:text"`
+	Definition       initialProcessDefinition `gorm:"foreignKey:DefinitionID"`
+	Starter          initialUser              `gorm:"foreignKey:StarterID"`
+	Tasks            []initialTaskInstance    `gorm:"foreignKey:InstanceID;constraint:OnDelete:CASCADE"`
+}
+
+func (initialProcessInstance) TableName() string { return "process_instances" }
+
+type initialTaskInstance struct {
+	BaseModel
+	InstanceID      uint       `gorm:"not null;index"`
+	NodeID          string     `gorm:"type:varchar(64);not null;index"`
+	Name            string     `gorm:"type:varchar(255);not null"`
+	AssigneeID      *uint      `gorm:"index"`
+	Status          string     `gorm:"type:varchar(20);not null;default:created;index"`
+	Priority        int        `gorm:"not null;default:50;index"`
+	DueDate         *time.Time `gorm:"index"`
+	ClaimTime       *time.Time
+	CompleteTime    *time.Time
+	Comment         string `gorm:"type:text"`
+	Outcome         string `gorm:"type:varchar(20)"`
+	AutoRule        string `gorm:"type:varchar(255)"`
+	ClaimExpiries   int    `gorm:"not null;default:0"`
+	EscalationLevel int    `gorm:"not null;default:0"`
+	EscalatedAt     *time.Time
+	CandidateRoles  string                 `gorm:"type:text"`
+	CandidateUsers  string                 `gorm:"type:text"`
+	Topic           string                 `gorm:"type:varchar(128);index"`
+	Retries         int                    `gorm:"not null;default:0"`
+	WorkerID        string                 `gorm:"type:varchar(128)"`
+	LockExpiresAt   *time.Time             `gorm:"index"`
+	Instance        initialProcessInstance `gorm:"foreignKey:InstanceID"`
+	Assignee        *initialUser           `gorm:"foreignKey:AssigneeID"`
+	ExecutionLogs   []initialExecutionLog  `gorm:"foreignKey:TaskID"`
+}
+
+func (initialTaskInstance) TableName() string { return "task_instances" }
+
+type initialNotificationPreference struct {
+	BaseModel
+	UserID          uint   `gorm:"not null;uniqueIndex"`
+	Channels        string `gorm:"type:text"`
+	MutedEvents     string `gorm:"type:text"`
+	DeliveryMode    string `gorm:"type:varchar(20);not null;default:immediate"`
+	QuietHoursStart string `gorm:"type:varchar(5)"`
+	QuietHoursEnd   string `gorm:"type:varchar(5)"`
+	Timezone        string `gorm:"type:varchar(64)"`
+	Locale          string `gorm:"type:varchar(20)"`
+}
+
+func (initialNotificationPreference) TableName() string { return "notification_preferences" }
+
+type initialNotificationQueueItem struct {
+	BaseModel
+	UserID       uint       `gorm:"not null;index"`
+	Channel      string     `gorm:"type:varchar(20);not null"`
+	EventType    string     `gorm:"type:varchar(50);not null"`
+	Subject      string     `gorm:"type:varchar(255)"`
+	Body         string     `gorm:"type:text"`
+	DeliverAfter time.Time  `gorm:"not null;index"`
+	SentAt       *time.Time `gorm:"index"`
+	Attempts     int        `gorm:"not null;default:0"`
+	LastError    string     `gorm:"type:text"`
+}
+
+func (initialNotificationQueueItem) TableName() string { return "notification_queue" }
+
+type initialNotificationTemplate struct {
+	BaseModel
+	EventType string `gorm:"type:varchar(50);not null;uniqueIndex:idx_event_locale,composite:event_type"`
+	Locale    string `gorm:"type:varchar(20);not null;uniqueIndex:idx_event_locale,composite:locale"`
+	Subject   string `gorm:"type:varchar(255);not null"`
+	Body      string `gorm:"type:text;not null"`
+	UpdatedBy uint   `gorm:"index"`
+}
+
+func (initialNotificationTemplate) TableName() string { return "notification_templates" }
+
+type initialAnnouncement struct {
+	BaseModel
+	Title       string     `gorm:"type:varchar(255);not null"`
+	Content     string     `gorm:"type:text"`
+	Level       string     `gorm:"type:varchar(20);not null;default:info"`
+	TargetRoles string     `gorm:"type:text"`
+	PublishAt   time.Time  `gorm:"not null;index"`
+	ExpiresAt   *time.Time `gorm:"index"`
+	NotifiedAt  *time.Time `gorm:"index"`
+	CreatedBy   uint       `gorm:"not null;index"`
+}
+
+func (initialAnnouncement) TableName() string { return "announcements" }
+
+type initialConnectorPolicy struct {
+	BaseModel
+	DefinitionKey string `gorm:"type:varchar(100);not null;uniqueIndex"`
+	AllowedTypes  string `gorm:"type:text"`
+	AllowedHosts  string `gorm:"type:text"`
+	UpdatedBy     uint   `gorm:"index"`
+}
+
+func (initialConnectorPolicy) TableName() string { return "connector_policies" }
+
+type initialIncident struct {
+	BaseModel
+	InstanceID uint   `gorm:"not null;index"`
+	TaskID     *uint  `gorm:"index"`
+	NodeID     string `gorm:"type:varchar(64);index"`
+	Type       string `gorm:"type:varchar(50);not null;index"`
+	Code       string `gorm:"type:varchar(50);index"`
+	Message    string `gorm:"type:text"`
+	Status     string `gorm:"type:varchar(20);not null;default:open;index"`
+	ResolvedAt *time.Time
+	ResolvedBy *uint
+	Instance   initialProcessInstance `gorm:"foreignKey:InstanceID"`
+}
+
+func (initialIncident) TableName() string { return "incidents" }
+
+type initialReportDimDefinition struct {
+	DefinitionID       uint      `gorm:"primaryKey;autoIncrement:false"`
+	Key                string    `gorm:"column:key;type:varchar(100);not null;index"`
+	Name               string    `gorm:"type:varchar(255);not null"`
+	Version            int       `gorm:"not null"`
+	Category           string    `gorm:"type:varchar(50)"`
+	Status             string    `gorm:"type:varchar(20);not null"`
+	DataClassification string    `gorm:"type:varchar(20);not null"`
+	RefreshedAt        time.Time `gorm:"not null"`
+}
+
+func (initialReportDimDefinition) TableName() string { return "rpt_dim_definition" }
+
+type initialReportDimUser struct {
+	UserID      uint      `gorm:"primaryKey;autoIncrement:false"`
+	Username    string    `gorm:"type:varchar(100);not null"`
+	DisplayName string    `gorm:"type:varchar(255)"`
+	Role        string    `gorm:"type:varchar(50);not null;index"`
+	Status      string    `gorm:"type:varchar(20);not null"`
+	RefreshedAt time.Time `gorm:"not null"`
+}
+
+func (initialReportDimUser) TableName() string { return "rpt_dim_user" }
+
+type initialReportDimDate struct {
+	DateKey   int       `gorm:"primaryKey;autoIncrement:false"`
+	Date      time.Time `gorm:"type:date;not null;uniqueIndex"`
+	Year      int       `gorm:"not null;index"`
+	Quarter   int       `gorm:"not null"`
+	Month     int       `gorm:"not null"`
+	Day       int       `gorm:"not null"`
+	Weekday   int       `gorm:"not null"`
+	IsWeekend bool      `gorm:"not null"`
+}
+
+func (initialReportDimDate) TableName() string { return "rpt_dim_date" }
+
+type initialReportFactInstance struct {
+	InstanceID      uint   `gorm:"primaryKey;autoIncrement:false"`
+	DefinitionID    uint   `gorm:"not null;index"`
+	StarterID       uint   `gorm:"not null;index"`
+	StartDateKey    int    `gorm:"not null;index"`
+	EndDateKey      *int   `gorm:"index"`
+	Status          string `gorm:"type:varchar(20);not null;index"`
+	DurationSeconds *int64
+	TaskCount       int       `gorm:"not null"`
+	IncidentCount   int       `gorm:"not null"`
+	RefreshedAt     time.Time `gorm:"not null"`
+}
+
+func (initialReportFactInstance) TableName() string { return "rpt_fact_instance" }
+
+type initialReportFactTask struct {
+	TaskID           uint   `gorm:"primaryKey;autoIncrement:false"`
+	InstanceID       uint   `gorm:"not null;index"`
+	DefinitionID     uint   `gorm:"not null;index"`
+	AssigneeID       *uint  `gorm:"index"`
+	NodeID           string `gorm:"type:varchar(64);not null"`
+	Status           string `gorm:"type:varchar(20);not null;index"`
+	Priority         int    `gorm:"not null"`
+	CreatedDateKey   int    `gorm:"not null;index"`
+	CompletedDateKey *int   `gorm:"index"`
+	WaitSeconds      *int64
+	HandleSeconds    *int64
+	Overdue          bool      `gorm:"not null"`
+	ClaimExpiries    int       `gorm:"not null"`
+	RefreshedAt      time.Time `gorm:"not null"`
+}
+
+func (initialReportFactTask) TableName() string { return "rpt_fact_task" }
+
+type initialProcessKPI struct {
+	BaseModel
+	DefinitionKey   string  `gorm:"type:varchar(100);not null;index"`
+	Name            string  `gorm:"type:varchar(255);not null"`
+	Description     string  `gorm:"type:text"`
+	Metric          string  `gorm:"type:varchar(30);not null"`
+	ThresholdHours  float64 `gorm:"not null;default:0"`
+	TargetPercent   float64 `gorm:"not null"`
+	WindowDays      int     `gorm:"not null;default:30"`
+	Enabled         bool    `gorm:"not null;default:true;index"`
+	CreatedBy       uint    `gorm:"not null;index"`
+	LastAttainment  *float64
+	LastSampleSize  int `gorm:"not null;default:0"`
+	LastEvaluatedAt *time.Time
+	Breached        bool `gorm:"not null;default:false"`
+}
+
+func (initialProcessKPI) TableName() string { return "process_kpis" }
+
+type initialProcessKPIMeasurement struct {
+	ID         uint      `gorm:"primarykey"`
+	KPIID      uint      `gorm:"not null;index:idx_kpi_measured,priority:1"`
+	MeasuredAt time.Time `gorm:"not null;index:idx_kpi_measured,priority:2"`
+	SampleSize int       `gorm:"not null"`
+	Attainment float64   `gorm:"not null"`
+	Met        bool      `gorm:"not null"`
+}
+
+func (initialProcessKPIMeasurement) TableName() string { return "process_kpi_measurements" }
+
+type initialDeployment struct {
+	BaseModel
+	DefinitionID       uint   `gorm:"not null;index"`
+	DefinitionKey      string `gorm:"type:varchar(100);not null;index:idx_deployment_key_env,priority:1"`
+	Version            int    `gorm:"not null"`
+	Environment        string `gorm:"type:varchar(20);not null;index:idx_deployment_key_env,priority:2"`
+	Checksum           string `gorm:"type:varchar(64);not null;index"`
+	Package            string `gorm:"not null"`
+	SourceDeploymentID *uint  `gorm:"index"`
+	DeployedBy         uint   `gorm:"not null;index"`
+	Note               string `gorm:"type:varchar(500)"`
+}
+
+func (initialDeployment) TableName() string { return "deployments" }
+
+type initialInstanceDuplicate struct {
+	BaseModel
+	InstanceID    uint    `gorm:"not null;index"`
+	DuplicateOfID uint    `gorm:"not null;index"`
+	Score         float64 `gorm:"not null"`
+	Reasons       string  `gorm:"type:text"`
+	Status        string  `gorm:"type:varchar(20);not null;default:open;index"`
+	ResolvedBy    *uint
+	ResolvedAt    *time.Time
+	Instance      initialProcessInstance `gorm:"foreignKey:InstanceID"`
+	DuplicateOf   initialProcessInstance `gorm:"foreignKey:DuplicateOfID"`
+}
+
+func (initialInstanceDuplicate) TableName() string { return "instance_duplicates" }
+
+type initialTaskEvent struct {
+	BaseModel
+	TaskID     uint   `gorm:"not null;index"`
+	InstanceID uint   `gorm:"not null;index"`
+	UserID     *uint  `gorm:"index"`
+	Type       string `gorm:"type:varchar(20);not null"`
+	Status     string `gorm:"type:varchar(20);not null"`
+}
+
+func (initialTaskEvent) TableName() string { return "task_events" }
+
+type initialIdempotencyRecord struct {
+	BaseModel
+	UserID       uint   `gorm:"not null;uniqueIndex:idx_idempotency_user_key,priority:1"`
+	Key          string `gorm:"type:varchar(255);not null;uniqueIndex:idx_idempotency_user_key,priority:2"`
+	Method       string `gorm:"type:varchar(10);not null"`
+	Path         string `gorm:"type:varchar(255);not null"`
+	RequestHash  string `gorm:"type:varchar(64);not null"`
+	StatusCode   int    `gorm:"not null;default:0"`
+	ContentType  string `gorm:"type:varchar(100)"`
+	ResponseBody string
+	ExpiresAt    time.Time `gorm:"not null;index"`
+}
+
+func (initialIdempotencyRecord) TableName() string { return "idempotency_records" }
+
+type initialExecutionLog struct {
+	BaseModel
+	TaskID     uint   `gorm:"not null;index"`
+	InstanceID uint   `gorm:"not null;index:idx_execution_instance_node,priority:1"`
+	NodeID     string `gorm:"type:varchar(64);not null;index:idx_execution_instance_node,priority:2"`
+	Attempt    int    `gorm:"not null;default:1"`
+	Connector  string `gorm:"type:varchar(50)"`
+	Method     string `gorm:"type:varchar(10)"`
+	URL        string `gorm:"type:varchar(1000)"`
+	Status     string `gorm:"type:varchar(20);not null;index"`
+	StatusCode int
+	Mocked     bool      `gorm:"not null;default:false"`
+	Request    string    `gorm:"type:text"`
+	Response   string    `gorm:"type:text"`
+	Error      string    `gorm:"type:text"`
+	ErrorCode  string    `gorm:"type:varchar(50)"`
+	StartTime  time.Time `gorm:"not null"`
+	EndTime    *time.Time
+	DurationMs int64
+}
+
+func (initialExecutionLog) TableName() string { return "execution_logs" }
+
+type initialGatewayArrival struct {
+	BaseModel
+	InstanceID uint   `gorm:"not null;index:idx_arrival_gateway,priority:1"`
+	GatewayID  string `gorm:"type:varchar(64);not null;index:idx_arrival_gateway,priority:2"`
+	FlowKey    string `gorm:"type:varchar(255);not null"`
+	Consumed   bool   `gorm:"not null;default:false;index"`
+}
+
+func (initialGatewayArrival) TableName() string { return "gateway_arrivals" }
+
+type initialProcessTimer struct {
+	BaseModel
+	InstanceID uint      `gorm:"not null;index"`
+	NodeID     string    `gorm:"type:varchar(64);not null"`
+	Kind       string    `gorm:"type:varchar(20);not null"`
+	FlowID     string    `gorm:"type:varchar(64)"`
+	DueAt      time.Time `gorm:"not null;index:idx_timer_due,priority:2"`
+	Status     string    `gorm:"type:varchar(20);not null;default:waiting;index:idx_timer_due,priority:1"`
+	FiredAt    *time.Time
+	LastError  string `gorm:"type:text"`
+}
+
+func (initialProcessTimer) TableName() string { return "process_timers" }
+
+type initialWebhookDelivery struct {
+	BaseModel
+	InstanceID     uint   `gorm:"not null;index"`
+	SubscriptionID *uint  `gorm:"index"`
+	Event          string `gorm:"type:varchar(50);not null"`
+	URL            string `gorm:"type:varchar(500);not null"`
+	Payload        string `gorm:"type:text"`
+	Status         string `gorm:"type:varchar(20);not null;default:pending;index"`
+	Attempts       int    `gorm:"not null;default:0"`
+	LastError      string `gorm:"type:text"`
+	DeliveredAt    *time.Time
+}
+
+func (initialWebhookDelivery) TableName() string { return "webhook_deliveries" }
+
+type initialWebhookSubscription struct {
+	BaseModel
+	URL        string `gorm:"type:varchar(500);not null"`
+	EventTypes string `gorm:"type:text;not null"`
+	Secret     string `gorm:"type:varchar(255)"`
+	Active     bool   `gorm:"not null;default:true;index"`
+	CreatedBy  uint   `gorm:"not null;index"`
+}
+
+func (initialWebhookSubscription) TableName() string { return "webhook_subscriptions" }
+
+type initialComplexityBudget struct {
+	BaseModel
+	DefinitionKey string `gorm:"type:varchar(100);not null;uniqueIndex"`
+	MaxScore      int    `gorm:"not null"`
+	UpdatedBy     uint   `gorm:"index"`
+}
+
+func (initialComplexityBudget) TableName() string { return "complexity_budgets" }
+
+type initialPurgeCertificate struct {
+	BaseModel
+	InstanceID        uint   `gorm:"not null;index"`
+	ParentInstanceID  *uint  `gorm:"index"`
+	DefinitionID      uint   `gorm:"not null"`
+	DefinitionKey     string `gorm:"type:varchar(100)"`
+	DefinitionVersion int
+	FinalStatus       string `gorm:"type:varchar(20);not null"`
+	BusinessKeyHash   string `gorm:"type:varchar(64)"`
+	StartTime         time.Time
+	EndTime           *time.Time
+	PurgedBy          uint      `gorm:"not null;index"`
+	PurgedAt          time.Time `gorm:"not null;index"`
+	Reason            string    `gorm:"type:text"`
+	Rows              string    `gorm:"type:text"`
+	Digest            string    `gorm:"type:varchar(64);not null"`
+}
+
+func (initialPurgeCertificate) TableName() string { return "purge_certificates" }
+
+type initialActivityHistory struct {
+	BaseModel
+	InstanceID uint   `gorm:"not null;index"`
+	Type       string `gorm:"type:varchar(30);not null;index"`
+	NodeID     string `gorm:"type:varchar(64)"`
+	NodeType   string `gorm:"type:varchar(30)"`
+	TaskID     *uint  `gorm:"index"`
+	ActorID    *uint  `gorm:"index"`
+	Detail     string `gorm:"type:json"`
+}
+
+func (initialActivityHistory) TableName() string { return "activity_histories" }
+
+type initialExecutionTrace struct {
+	BaseModel
+	InstanceID uint   `gorm:"not null;index"`
+	Category   string `gorm:"type:varchar(20);not null"`
+	NodeID     string `gorm:"type:varchar(64)"`
+	Message    string `gorm:"type:varchar(500);not null"`
+	Data       string `gorm:"type:json"`
+}
+
+func (initialExecutionTrace) TableName() string { return "execution_traces" }
+
+type initialCapacityStat struct {
+	ID                      uint      `gorm:"primaryKey;autoIncrement"`
+	DefinitionID            uint      `gorm:"not null;uniqueIndex:idx_capacity_definition_day,priority:1"`
+	Day                     time.Time `gorm:"type:date;not null;uniqueIndex:idx_capacity_definition_day,priority:2;index"`
+	Started                 int       `gorm:"not null;default:0"`
+	Completed               int       `gorm:"not null;default:0"`
+	ActiveTasks             int       `gorm:"not null;default:0"`
+	PeakActiveTasks         int       `gorm:"not null;default:0"`
+	ActiveExternalTasks     int       `gorm:"not null;default:0"`
+	PeakActiveExternalTasks int       `gorm:"not null;default:0"`
+	SampledAt               time.Time `gorm:"not null"`
+}
+
+func (initialCapacityStat) TableName() string { return "process_capacity_stats" }
+
+type initialAsyncJob struct {
+	BaseModel
+	InstanceID    uint      `gorm:"not null;index"`
+	NodeID        string    `gorm:"type:varchar(64);not null"`
+	TaskID        uint      `gorm:"not null;index"`
+	Status        string    `gorm:"type:varchar(20);not null;default:pending;index:idx_async_job_due,priority:1"`
+	DueAt         time.Time `gorm:"not null;index:idx_async_job_due,priority:2"`
+	LockedBy      string    `gorm:"type:varchar(128)"`
+	LockExpiresAt *time.Time
+	Attempts      int    `gorm:"not null;default:0"`
+	LastError     string `gorm:"type:text"`
+	CompletedAt   *time.Time
+}
+
+func (initialAsyncJob) TableName() string { return "async_jobs" }
+
+type initialMessageSubscription struct {
+	BaseModel
+	InstanceID     uint   `gorm:"not null;index"`
+	NodeID         string `gorm:"type:varchar(64);not null"`
+	MessageName    string `gorm:"type:varchar(128);not null;index:idx_message_correlation,priority:1"`
+	CorrelationKey string `gorm:"type:varchar(255);not null;index:idx_message_correlation,priority:2"`
+	Status         string `gorm:"type:varchar(20);not null;default:waiting;index:idx_message_correlation,priority:3"`
+	CorrelatedAt   *time.Time
+}
+
+func (initialMessageSubscription) TableName() string { return "message_subscriptions" }
+
+type initialDepartment struct {
+	BaseModel
+	Code        string `gorm:"type:varchar(100);not null;uniqueIndex"`
+	Name        string `gorm:"type:varchar(255);not null"`
+	ParentID    *uint  `gorm:"index"`
+	ManagerID   *uint  `gorm:"index"`
+	Description string `gorm:"type:text"`
+}
+
+func (initialDepartment) TableName() string { return "departments" }
+
+type initialGroup struct {
+	BaseModel
+	Code        string `gorm:"type:varchar(100);not null;uniqueIndex"`
+	Name        string `gorm:"type:varchar(255);not null"`
+	Description string `gorm:"type:text"`
+}
+
+func (initialGroup) TableName() string { return "user_groups" }
+
+type initialGroupMember struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	GroupID   uint      `gorm:"not null;uniqueIndex:idx_group_member"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_group_member;index"`
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+func (initialGroupMember) TableName() string { return "user_group_members" }
+
+type initialRefreshToken struct {
+	BaseModel
+	UserID       uint       `gorm:"not null;index"`
+	TokenHash    string     `gorm:"type:varchar(64);not null;uniqueIndex"`
+	ExpiresAt    time.Time  `gorm:"not null;index"`
+	RevokedAt    *time.Time `gorm:"index"`
+	ReplacedByID *uint
+}
+
+func (initialRefreshToken) TableName() string { return "refresh_tokens" }
+
+type initialRevokedAccessToken struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	TokenID   string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	UserID    uint      `gorm:"not null;index"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+func (initialRevokedAccessToken) TableName() string { return "revoked_access_tokens" }
+
+// initialSchema lists the tables of migration 20261016000001 in creation order
+func initialSchema() []interface{} {
+	return []interface{}{
+		&initialUser{},
+		&initialProcessDefinition{},
+		&initialProcessInstance{},
+		&initialTaskInstance{},
+		&initialNotificationPreference{},
+		&initialNotificationQueueItem{},
+		&initialNotificationTemplate{},
+		&initialAnnouncement{},
+		&initialConnectorPolicy{},
+		&initialIncident{},
+		&initialReportDimDefinition{},
+		&initialReportDimUser{},
+		&initialReportDimDate{},
+		&initialReportFactInstance{},
+		&initialReportFactTask{},
+		&initialProcessKPI{},
+		&initialProcessKPIMeasurement{},
+		&initialDeployment{},
+		&initialInstanceDuplicate{},
+		&initialTaskEvent{},
+		&initialIdempotencyRecord{},
+		&initialExecutionLog{},
+		&initialGatewayArrival{},
+		&initialProcessTimer{},
+		&initialWebhookDelivery{},
+		&initialWebhookSubscription{},
+		&initialComplexityBudget{},
+		&initialPurgeCertificate{},
+		&initialActivityHistory{},
+		&initialExecutionTrace{},
+		&initialCapacityStat{},
+		&initialAsyncJob{},
+		&initialMessageSubscription{},
+		&initialDepartment{},
+		&initialGroup{},
+		&initialGroupMember{},
+		&initialRefreshToken{},
+		&initialRevokedAccessToken{},
+	}
+}
@@ -0,0 +1,149 @@
+package migration
+
+import (
+	"time"
+)
+
+// Frozen snapshots of the tables and columns that migrations after the initial
+// schema add, taken when each migration was released. Like the initial schema
+// they must never change.
+
+// taskComment0002 is the table created by migration 20261016000002
+type taskComment0002 struct {
+	BaseModel
+	TaskID     uint   `gorm:"not null;index"`
+	InstanceID uint   `gorm:"not null;index"`
+	UserID     uint   `gorm:"not null;index"`
+	Body       string `gorm:"type:text;not null"`
+}
+
+func (taskComment0002) TableName() string { return "task_comments" }
+
+// attachment0003 is the table created by migration 20261016000003
+type attachment0003 struct {
+	BaseModel
+	InstanceID  uint   `gorm:"not null;index"`
+	TaskID      *uint  `gorm:"index"`
+	FileName    string `gorm:"type:varchar(255);not null"`
+	ContentType string `gorm:"type:varchar(100);not null"`
+	Size        int64  `gorm:"not null"`
+	Checksum    string `gorm:"type:varchar(64);not null"`
+	StorageKey  string `gorm:"type:varchar(255);not null;uniqueIndex"`
+	UploadedBy  uint   `gorm:"not null;index"`
+}
+
+func (attachment0003) TableName() string { return "attachments" }
+
+// taskFormData0004 is task_instances with the column added by migration 20261016000004
+type taskFormData0004 struct {
+	ID       uint   `gorm:"primaryKey"`
+	Comment  string `gorm:"type:text"`
+	FormData string `gorm:"type:text"`
+}
+
+func (taskFormData0004) TableName() string { return "task_instances" }
+
+// gatewayArrival0005 is gateway_arrivals with the column added by migration 20261016000005
+type gatewayArrival0005 struct {
+	Skipped bool `gorm:"not null;default:false"`
+}
+
+func (gatewayArrival0005) TableName() string { return "gateway_arrivals" }
+
+// instanceLoopCounters0006 is process_instances with the column added by migration 20261016000006
+type instanceLoopCounters0006 struct {
+	LoopCounters string `gorm:"type:text"`
+}
+
+func (instanceLoopCounters0006) TableName() string { return "process_instances" }
+
+// taskReminder0007 is the table created by migration 20261016000007
+type taskReminder0007 struct {
+	BaseModel
+	InstanceID    uint      `gorm:"not null;index"`
+	TaskID        uint      `gorm:"not null;uniqueIndex:idx_task_reminder"`
+	DueDate       time.Time `gorm:"not null;uniqueIndex:idx_task_reminder"`
+	OffsetSeconds int64     `gorm:"not null;uniqueIndex:idx_task_reminder"`
+}
+
+func (taskReminder0007) TableName() string { return "task_reminders" }
+
+// notification0008 is the table created by migration 20261016000008
+type notification0008 struct {
+	BaseModel
+	UserID    uint       `gorm:"not null;index:idx_notification_user_read"`
+	EventType string     `gorm:"type:varchar(50);not null"`
+	Subject   string     `gorm:"type:varchar(255)"`
+	Body      string     `gorm:"type:text"`
+	ReadAt    *time.Time `gorm:"index:idx_notification_user_read"`
+}
+
+func (notification0008) TableName() string { return "notifications" }
+
+// taskDelegation0009 is task_instances with the columns added by migration 20261016000009
+type taskDelegation0009 struct {
+	OwnerID         *uint  `gorm:"index"`
+	DelegationState string `gorm:"type:varchar(20)"`
+}
+
+func (taskDelegation0009) TableName() string { return "task_instances" }
+
+// savedTaskFilter0010 is the table created by migration 20261016000010
+type savedTaskFilter0010 struct {
+	BaseModel
+	UserID   uint   `gorm:"not null;index"`
+	Name     string `gorm:"type:varchar(100);not null"`
+	Criteria string `gorm:"type:text;not null"`
+}
+
+func (savedTaskFilter0010) TableName() string { return "saved_task_filters" }
+
+// instanceArchive0011 is the table created by migration 20261016000011
+type instanceArchive0011 struct {
+	BaseModel
+	InstanceID        uint   `gorm:"not null;uniqueIndex"`
+	ParentInstanceID  *uint  `gorm:"index"`
+	DefinitionID      uint   `gorm:"not null;index"`
+	DefinitionKey     string `gorm:"type:varchar(100);index"`
+	DefinitionVersion int
+	BusinessKey       string `gorm:"type:varchar(255);index"`
+	StarterID         uint   `gorm:"not null;index"`
+	FinalStatus       string `gorm:"type:varchar(20);not null"`
+	StartTime         time.Time
+	EndTime           *time.Time
+	ArchivedAt        time.Time `gorm:"not null;index"`
+	StorageKey        string    `gorm:"type:varchar(255);not null"`
+	Size              int64     `gorm:"not null"`
+	Checksum          string    `gorm:"type:varchar(64);not null"`
+}
+
+func (instanceArchive0011) TableName() string { return "instance_archives" }
+
+// userErasure0012 is the table created by migration 20261016000012
+type userErasure0012 struct {
+	BaseModel
+	UserID       uint      `gorm:"not null;index"`
+	UsernameHash string    `gorm:"type:varchar(64);not null"`
+	TombstoneID  uint      `gorm:"not null"`
+	ErasedBy     uint      `gorm:"not null;index"`
+	ErasedAt     time.Time `gorm:"not null;index"`
+	Reason       string    `gorm:"type:text"`
+	Rows         string    `gorm:"type:text"`
+	Digest       string    `gorm:"type:varchar(64);not null"`
+}
+
+func (userErasure0012) TableName() string { return "user_erasures" }
+
+// taskOwnerIndex0013 is task_instances with the index added by migration 20261016000013
+type taskOwnerIndex0013 struct {
+	OwnerID *uint `gorm:"index"`
+}
+
+func (taskOwnerIndex0013) TableName() string { return "task_instances" }
+
+// taskEscalationGroup0014 is task_instances with the column added by migration 20261016000014
+type taskEscalationGroup0014 struct {
+	EscalationGroup string `gorm:"type:varchar(64)"`
+}
+
+func (taskEscalationGroup0014) TableName() string { return "task_instances" }
@@ -0,0 +1,35 @@
+package model
+
+// 活动历史类型常量
+const (
+	ActivityNodeEntered     = "node_entered"
+	ActivityNodeExited      = "node_exited"
+	ActivityGatewayDecision = "gateway_decision"
+	ActivityTaskCompleted   = "task_completed"
+	ActivityVariableChanged = "variable_changed"
+	ActivityStateTransition = "state_transition"
+	ActivityExecutionMoved  = "execution_moved"
+	ActivityTaskReturned    = "task_returned"
+	ActivityTaskDelegated   = "task_delegated"
+	ActivityTaskResolved    = "task_resolved"
+	ActivityTaskEscalated   = "task_escalated"
+	ActivityTaskClaimed     = "task_claimed"
+)
+
+// ActivityHistory 流程实例的活动历史（审计轨迹），由引擎在推进流程时追加写入，不修改
+// ActorID 为触发活动的用户，为空表示由系统触发；Detail 为各类型的详细信息，JSON 对象
+type ActivityHistory struct {
+	BaseModel
+	InstanceID uint   `gorm:"not null;index" json:"instance_id"`
+	Type       string `gorm:"type:varchar(30);not null;index" json:"type"`
+	NodeID     string `gorm:"type:varchar(64)" json:"node_id,omitempty"`
+	NodeType   string `gorm:"type:varchar(30)" json:"node_type,omitempty"`
+	TaskID     *uint  `gorm:"index" json:"task_id,omitempty"`
+	ActorID    *uint  `gorm:"index" json:"actor_id,omitempty"`
+	Detail     string `gorm:"type:json" json:"detail,omitempty"`
+}
+
+// TableName returns the table name for ActivityHistory model
+func (ActivityHistory) TableName() string {
+	return "activity_histories"
+}
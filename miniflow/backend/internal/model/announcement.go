@@ -0,0 +1,60 @@
+package model
+
+import "time"
+
+// 公告级别常量
+const (
+	AnnouncementLevelInfo     = "info"
+	AnnouncementLevelWarning  = "warning"
+	AnnouncementLevelCritical = "critical"
+)
+
+// Announcement 管理员发布的系统公告
+type Announcement struct {
+	BaseModel
+	Title       string     `gorm:"type:varchar(255);not null" json:"title"`
+	Content     string     `gorm:"type:text" json:"content"`
+	Level       string     `gorm:"type:varchar(20);not null;default:info" json:"level"`
+	TargetRoles string     `gorm:"type:text" json:"target_roles"`
+	PublishAt   time.Time  `gorm:"not null;index" json:"publish_at"`
+	ExpiresAt   *time.Time `gorm:"index" json:"expires_at"`
+	NotifiedAt  *time.Time `gorm:"index" json:"notified_at"`
+	CreatedBy   uint       `gorm:"not null;index" json:"created_by"`
+}
+
+// TableName returns the table name for Announcement model
+func (Announcement) TableName() string {
+	return "announcements"
+}
+
+// GetTargetRoles parses the target role list, empty means all users
+func (a *Announcement) GetTargetRoles() []string {
+	return parseStringList(a.TargetRoles)
+}
+
+// SetTargetRoles sets the target role list
+func (a *Announcement) SetTargetRoles(roles []string) {
+	a.TargetRoles = formatStringList(roles)
+}
+
+// IsActive checks whether the announcement is visible at the given time
+func (a *Announcement) IsActive(now time.Time) bool {
+	if now.Before(a.PublishAt) {
+		return false
+	}
+	return a.ExpiresAt == nil || now.Before(*a.ExpiresAt)
+}
+
+// TargetsRole checks whether the announcement is visible to the given role
+func (a *Announcement) TargetsRole(role string) bool {
+	roles := a.GetTargetRoles()
+	if len(roles) == 0 {
+		return true
+	}
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package model
+
+import "time"
+
+// InstanceArchive 已归档的流程实例，实例的执行历史导出为 JSON 文件保存在附件存储中，
+// 数据库中只保留检索所需的字段
+type InstanceArchive struct {
+	BaseModel
+	InstanceID        uint       `gorm:"not null;uniqueIndex" json:"instance_id"`
+	ParentInstanceID  *uint      `gorm:"index" json:"parent_instance_id,omitempty"`
+	DefinitionID      uint       `gorm:"not null;index" json:"definition_id"`
+	DefinitionKey     string     `gorm:"type:varchar(100);index" json:"definition_key"`
+	DefinitionVersion int        `json:"definition_version"`
+	BusinessKey       string     `gorm:"type:varchar(255);index" json:"business_key"`
+	StarterID         uint       `gorm:"not null;index" json:"starter_id"`
+	FinalStatus       string     `gorm:"type:varchar(20);not null" json:"final_status"`
+	StartTime         time.Time  `json:"start_time"`
+	EndTime           *time.Time `json:"end_time"`
+	ArchivedAt        time.Time  `gorm:"not null;index" json:"archived_at"`
+	StorageKey        string     `gorm:"type:varchar(255);not null" json:"-"`
+	Size              int64      `gorm:"not null" json:"size"`
+	Checksum          string     `gorm:"type:varchar(64);not null" json:"checksum"`
+}
+
+// TableName returns the table name for InstanceArchive model
+func (InstanceArchive) TableName() string {
+	return "instance_archives"
+}
+
+// NewInstanceArchive builds the archive record of an instance whose history is stored under key
+func NewInstanceArchive(instance *ProcessInstance, key string, size int64, checksum string, now time.Time) *InstanceArchive {
+	return &InstanceArchive{
+		InstanceID:        instance.ID,
+		ParentInstanceID:  instance.ParentInstanceID,
+		DefinitionID:      instance.DefinitionID,
+		DefinitionKey:     instance.Definition.Key,
+		DefinitionVersion: instance.Definition.Version,
+		BusinessKey:       instance.BusinessKey,
+		StarterID:         instance.StarterID,
+		FinalStatus:       instance.Status,
+		StartTime:         instance.StartTime,
+		EndTime:           instance.EndTime,
+		ArchivedAt:        now,
+		StorageKey:        key,
+		Size:              size,
+		Checksum:          checksum,
+	}
+}
@@ -0,0 +1,98 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 处理人规格前缀
+const (
+	AssigneePrefixUser              = "user:"
+	AssigneePrefixUsername          = "username:"
+	AssigneePrefixRole              = "role:"
+	AssigneePrefixGroup             = "group:"        // 用户组编码
+	AssigneePrefixDepartmentManager = "dept_manager:" // 部门编码，分配给部门负责人
+)
+
+// AssigneeSpec 解析后的处理人规格，只有一个字段有值
+type AssigneeSpec struct {
+	UserID            uint
+	Username          string
+	Role              string
+	Group             string
+	DepartmentManager string
+}
+
+// IsUser reports whether the spec names a concrete user rather than a role, group or department
+func (s *AssigneeSpec) IsUser() bool {
+	return s.UserID != 0 || s.Username != ""
+}
+
+// GetAssigneeExpression returns the assignee expression of a user task node, or empty if unset
+func GetAssigneeExpression(node *ProcessNode) string {
+	if value, ok := node.Props["assignee"].(string); ok {
+		return strings.TrimSpace(value)
+	}
+	return ""
+}
+
+// ParseAssigneeSpec converts an evaluated assignee value into a spec.
+// Accepted values: a user ID (number or numeric string), "user:<id>",
+// "username:<name>", "role:<role>", "group:<code>", "dept_manager:<code>",
+// or an object with an "id" field.
+func ParseAssigneeSpec(value interface{}) (*AssigneeSpec, error) {
+	switch v := value.(type) {
+	case float64:
+		ids, err := ParseUserIDs([]interface{}{v})
+		if err != nil {
+			return nil, err
+		}
+		return &AssigneeSpec{UserID: ids[0]}, nil
+	case map[string]interface{}:
+		id, ok := v["id"]
+		if !ok {
+			return nil, errors.New("处理人对象缺少 id 字段")
+		}
+		return ParseAssigneeSpec(id)
+	case string:
+		text := strings.TrimSpace(v)
+		switch {
+		case strings.HasPrefix(text, AssigneePrefixUser):
+			return ParseAssigneeSpec(strings.TrimPrefix(text, AssigneePrefixUser))
+		case strings.HasPrefix(text, AssigneePrefixUsername):
+			name := strings.TrimSpace(strings.TrimPrefix(text, AssigneePrefixUsername))
+			if name == "" {
+				return nil, errors.New("处理人用户名为空")
+			}
+			return &AssigneeSpec{Username: name}, nil
+		case strings.HasPrefix(text, AssigneePrefixRole):
+			role := strings.TrimSpace(strings.TrimPrefix(text, AssigneePrefixRole))
+			if role == "" {
+				return nil, errors.New("处理人角色为空")
+			}
+			return &AssigneeSpec{Role: role}, nil
+		case strings.HasPrefix(text, AssigneePrefixGroup):
+			group := strings.TrimSpace(strings.TrimPrefix(text, AssigneePrefixGroup))
+			if group == "" {
+				return nil, errors.New("处理人用户组为空")
+			}
+			return &AssigneeSpec{Group: group}, nil
+		case strings.HasPrefix(text, AssigneePrefixDepartmentManager):
+			department := strings.TrimSpace(strings.TrimPrefix(text, AssigneePrefixDepartmentManager))
+			if department == "" {
+				return nil, errors.New("处理人部门为空")
+			}
+			return &AssigneeSpec{DepartmentManager: department}, nil
+		}
+		ids, err := ParseUserIDs([]interface{}{text})
+		if err != nil {
+			return nil, fmt.Errorf("无法识别的处理人 %q", text)
+		}
+		return &AssigneeSpec{UserID: ids[0]}, nil
+	case nil:
+		return nil, errors.New("处理人表达式结果为空")
+	default:
+		return nil, fmt.Errorf("无法识别的处理人 %v", v)
+	}
+}
@@ -0,0 +1,48 @@
+package model
+
+import "time"
+
+// 异步作业状态常量
+const (
+	AsyncJobStatusPending   = "pending"
+	AsyncJobStatusRunning   = "running"
+	AsyncJobStatusCompleted = "completed"
+	// AsyncJobStatusFailed 作业执行后流程推进失败，可由运维在作业面板中重试
+	AsyncJobStatusFailed = "failed"
+	// AsyncJobStatusCancelled 实例已结束或任务已关闭，作业不再执行
+	AsyncJobStatusCancelled = "cancelled"
+)
+
+// AsyncJob 异步执行的流程续行，由后台作业执行器在请求之外执行并推进流程
+//
+// 配置了 async 的服务任务节点进入时只创建服务任务和作业，调用连接器和后续推进都由
+// 执行器完成，前一个任务的完成请求不再等待连接器返回。执行器通过条件更新锁定作业，
+// 多个执行器同时运行时每个作业只执行一次。
+type AsyncJob struct {
+	BaseModel
+	InstanceID    uint       `gorm:"not null;index" json:"instance_id"`
+	NodeID        string     `gorm:"type:varchar(64);not null" json:"node_id"`
+	TaskID        uint       `gorm:"not null;index" json:"task_id"`
+	Status        string     `gorm:"type:varchar(20);not null;default:pending;index:idx_async_job_due,priority:1" json:"status"`
+	DueAt         time.Time  `gorm:"not null;index:idx_async_job_due,priority:2" json:"due_at"`
+	LockedBy      string     `gorm:"type:varchar(128)" json:"locked_by,omitempty"`
+	LockExpiresAt *time.Time `json:"lock_expires_at,omitempty"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	LastError     string     `gorm:"type:text" json:"last_error,omitempty"`
+	CompletedAt   *time.Time `json:"completed_at"`
+}
+
+// TableName returns the table name for AsyncJob model
+func (AsyncJob) TableName() string {
+	return "async_jobs"
+}
+
+// IsAsyncNode reports whether a service task node is executed by the background
+// job executor, i.e. its "async" prop is true. External tasks are never async.
+func IsAsyncNode(node *ProcessNode) bool {
+	if node.Type != NodeTypeServiceTask || IsExternalTask(node) {
+		return false
+	}
+	async, _ := node.Props["async"].(bool)
+	return async
+}
@@ -0,0 +1,20 @@
+package model
+
+// Attachment 上传到流程实例或任务的附件
+// TaskID 为空表示附件属于实例本身；文件内容保存在附件存储中，StorageKey 为存储中的对象键
+type Attachment struct {
+	BaseModel
+	InstanceID  uint   `gorm:"not null;index" json:"instance_id"`
+	TaskID      *uint  `gorm:"index" json:"task_id,omitempty"`
+	FileName    string `gorm:"type:varchar(255);not null" json:"file_name"`
+	ContentType string `gorm:"type:varchar(100);not null" json:"content_type"`
+	Size        int64  `gorm:"not null" json:"size"`
+	Checksum    string `gorm:"type:varchar(64);not null" json:"checksum"`
+	StorageKey  string `gorm:"type:varchar(255);not null;uniqueIndex" json:"-"`
+	UploadedBy  uint   `gorm:"not null;index" json:"uploaded_by"`
+}
+
+// TableName returns the table name for Attachment model
+func (Attachment) TableName() string {
+	return "attachments"
+}
@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// RefreshToken 服务端保存的刷新令牌，只保存令牌的 SHA-256 摘要
+// 每次刷新都会作废旧令牌并签发新令牌，ReplacedByID 指向替换它的令牌
+type RefreshToken struct {
+	BaseModel
+	UserID       uint       `gorm:"not null;index" json:"user_id"`
+	TokenHash    string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	ExpiresAt    time.Time  `gorm:"not null;index" json:"expires_at"`
+	RevokedAt    *time.Time `gorm:"index" json:"revoked_at"`
+	ReplacedByID *uint      `json:"replaced_by_id"`
+}
+
+// TableName returns the table name for RefreshToken model
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// IsActive reports whether the refresh token can still be exchanged at the given time
+func (t *RefreshToken) IsActive(now time.Time) bool {
+	return t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
+
+// RevokedAccessToken 已注销的访问令牌（按 jti 记录），过期后可以删除
+type RevokedAccessToken struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	TokenID   string    `gorm:"type:varchar(64);not null;uniqueIndex" json:"token_id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+}
+
+// TableName returns the table name for RevokedAccessToken model
+func (RevokedAccessToken) TableName() string {
+	return "revoked_access_tokens"
+}
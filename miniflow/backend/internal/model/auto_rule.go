@@ -0,0 +1,67 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// 自动处理规则动作常量
+const (
+	AutoRuleActionComplete = "complete"
+	AutoRuleActionSkip     = "skip"
+)
+
+// SystemActor 系统自动处理任务时记录的操作人
+const SystemActor = "system"
+
+// AutoRule 用户任务节点的自动处理规则，任务创建时条件满足即由系统自动完成或跳过
+type AutoRule struct {
+	ID        string `json:"id"`
+	Condition string `json:"condition"`
+	Action    string `json:"action"`
+	Comment   string `json:"comment,omitempty"`
+}
+
+// Reference returns the rule reference recorded on tasks handled by this rule
+func (r *AutoRule) Reference(nodeID string) string {
+	return nodeID + "#" + r.ID
+}
+
+// GetAutoRules reads the auto-complete/skip rules of a user task node.
+// Rules come from the "autoRules" prop; the "autoComplete" and "autoSkip"
+// props are shorthands holding a single condition.
+func GetAutoRules(node *ProcessNode) ([]AutoRule, error) {
+	var rules []AutoRule
+
+	if raw, ok := node.Props["autoRules"]; ok && raw != nil {
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &rules); err != nil {
+			return nil, fmt.Errorf("autoRules 格式错误: %v", err)
+		}
+	}
+	if condition, ok := node.Props["autoComplete"].(string); ok && strings.TrimSpace(condition) != "" {
+		rules = append(rules, AutoRule{ID: "autoComplete", Condition: condition, Action: AutoRuleActionComplete})
+	}
+	if condition, ok := node.Props["autoSkip"].(string); ok && strings.TrimSpace(condition) != "" {
+		rules = append(rules, AutoRule{ID: "autoSkip", Condition: condition, Action: AutoRuleActionSkip})
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+		if rule.ID == "" {
+			rule.ID = fmt.Sprintf("rule-%d", i+1)
+		}
+		if strings.TrimSpace(rule.Condition) == "" {
+			return nil, fmt.Errorf("规则 %s 缺少条件", rule.ID)
+		}
+		if rule.Action != AutoRuleActionComplete && rule.Action != AutoRuleActionSkip {
+			return nil, fmt.Errorf("规则 %s 的动作 %q 无效", rule.ID, rule.Action)
+		}
+	}
+
+	return rules, nil
+}
@@ -31,3 +31,55 @@ func (base *BaseModel) BeforeUpdate(tx *gorm.DB) error {
 	base.UpdatedAt = time.Now()
 	return nil
 }
+
+// AllModels returns every model whose table the database migrations create;
+// the migrator tests check that the migrations produce the same schema
+func AllModels() []interface{} {
+	return []interface{}{
+		&User{},
+		&ProcessDefinition{},
+		&ProcessInstance{},
+		&TaskInstance{},
+		&NotificationPreference{},
+		&NotificationQueueItem{},
+		&NotificationTemplate{},
+		&Notification{},
+		&Announcement{},
+		&ConnectorPolicy{},
+		&Incident{},
+		&ReportDimDefinition{},
+		&ReportDimUser{},
+		&ReportDimDate{},
+		&ReportFactInstance{},
+		&ReportFactTask{},
+		&ProcessKPI{},
+		&ProcessKPIMeasurement{},
+		&Deployment{},
+		&InstanceDuplicate{},
+		&TaskEvent{},
+		&IdempotencyRecord{},
+		&ExecutionLog{},
+		&GatewayArrival{},
+		&ProcessTimer{},
+		&WebhookDelivery{},
+		&WebhookSubscription{},
+		&ComplexityBudget{},
+		&PurgeCertificate{},
+		&ActivityHistory{},
+		&ExecutionTrace{},
+		&CapacityStat{},
+		&AsyncJob{},
+		&MessageSubscription{},
+		&Department{},
+		&Group{},
+		&GroupMember{},
+		&RefreshToken{},
+		&RevokedAccessToken{},
+		&TaskComment{},
+		&TaskReminder{},
+		&SavedTaskFilter{},
+		&InstanceArchive{},
+		&UserErasure{},
+		&Attachment{},
+	}
+}
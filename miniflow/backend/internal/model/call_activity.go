@@ -0,0 +1,83 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+// CallActivityConfig 子流程（调用活动）节点配置
+type CallActivityConfig struct {
+	ProcessKey string
+	// Version 为 0 时调用最新发布的版本
+	Version int
+	// Inputs 子流程变量名 -> 父流程变量名，为 nil 时传入父流程的全部变量
+	Inputs map[string]string
+	// Outputs 父流程变量名 -> 子流程变量名，为 nil 时不回传变量
+	Outputs map[string]string
+}
+
+// GetCallActivityConfig reads the configuration of a call activity node from the
+// "processKey", "version", "inputs" and "outputs" props.
+func GetCallActivityConfig(node *ProcessNode) (*CallActivityConfig, error) {
+	key, _ := node.Props["processKey"].(string)
+	if key == "" {
+		return nil, errors.New("processKey is required")
+	}
+	cfg := &CallActivityConfig{ProcessKey: key}
+
+	if raw, ok := node.Props["version"]; ok && raw != nil {
+		version, isNumber := raw.(float64)
+		if !isNumber || version < 0 || version != float64(int(version)) {
+			return nil, errors.New("version must be a non-negative integer")
+		}
+		cfg.Version = int(version)
+	}
+
+	var err error
+	if cfg.Inputs, err = parseVariableMapping(node.Props["inputs"]); err != nil {
+		return nil, fmt.Errorf("inputs: %v", err)
+	}
+	if cfg.Outputs, err = parseVariableMapping(node.Props["outputs"]); err != nil {
+		return nil, fmt.Errorf("outputs: %v", err)
+	}
+	return cfg, nil
+}
+
+// MapVariables copies variables from source into a new map following mapping
+// (target name -> source name). A nil mapping copies every variable; missing
+// source variables are skipped.
+func MapVariables(source map[string]interface{}, mapping map[string]string) map[string]interface{} {
+	result := make(map[string]interface{})
+	if mapping == nil {
+		for name, value := range source {
+			result[name] = value
+		}
+		return result
+	}
+	for target, from := range mapping {
+		if value, ok := source[from]; ok {
+			result[target] = value
+		}
+	}
+	return result
+}
+
+// parseVariableMapping parses a {"target": "source"} object of variable names
+func parseVariableMapping(raw interface{}) (map[string]string, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	values, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("must be an object of variable names")
+	}
+	mapping := make(map[string]string, len(values))
+	for target, value := range values {
+		source, ok := value.(string)
+		if !ok || source == "" || target == "" {
+			return nil, fmt.Errorf("mapping for %q must be a variable name", target)
+		}
+		mapping[target] = source
+	}
+	return mapping, nil
+}
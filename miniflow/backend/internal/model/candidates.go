@@ -0,0 +1,98 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CandidateConfig 用户任务的候选人配置，任务未分配时只向候选角色和候选用户开放认领
+// 节点属性：candidateRoles 为角色列表，candidateUsers 为用户列表（用户ID、user:<id>、username:<name>、
+// dept_manager:<code>，或 group:<code> 表示组内所有成员）
+type CandidateConfig struct {
+	Roles []string
+	Users []AssigneeSpec
+}
+
+// GetCandidateConfig parses the candidate roles and users of a user task node, returning nil if unset
+func GetCandidateConfig(node *ProcessNode) (*CandidateConfig, error) {
+	config := &CandidateConfig{}
+
+	if raw, ok := node.Props["candidateRoles"]; ok && raw != nil {
+		values, ok := raw.([]interface{})
+		if !ok {
+			return nil, errors.New("candidateRoles 必须是角色列表")
+		}
+		for _, value := range values {
+			role, ok := value.(string)
+			if !ok || strings.TrimSpace(role) == "" {
+				return nil, fmt.Errorf("无效的候选角色 %v", value)
+			}
+			config.Roles = append(config.Roles, strings.TrimSpace(role))
+		}
+	}
+
+	if raw, ok := node.Props["candidateUsers"]; ok && raw != nil {
+		values, ok := raw.([]interface{})
+		if !ok {
+			return nil, errors.New("candidateUsers 必须是用户列表")
+		}
+		for _, value := range values {
+			spec, err := ParseAssigneeSpec(value)
+			if err != nil {
+				return nil, fmt.Errorf("无效的候选用户: %v", err)
+			}
+			if spec.Role != "" {
+				return nil, fmt.Errorf("候选用户不能是角色 %s，请使用 candidateRoles", spec.Role)
+			}
+			config.Users = append(config.Users, *spec)
+		}
+	}
+
+	if len(config.Roles) == 0 && len(config.Users) == 0 {
+		return nil, nil
+	}
+	return config, nil
+}
+
+// SetCandidates records the candidate roles and users of the task
+func (t *TaskInstance) SetCandidates(roles []string, userIDs []uint) {
+	t.CandidateRoles = ""
+	if len(roles) > 0 {
+		t.CandidateRoles = formatStringList(roles)
+	}
+	t.CandidateUsers = ""
+	if len(userIDs) > 0 {
+		values := make([]string, len(userIDs))
+		for i, id := range userIDs {
+			values[i] = strconv.FormatUint(uint64(id), 10)
+		}
+		t.CandidateUsers = formatStringList(values)
+	}
+}
+
+// HasCandidates reports whether the task is restricted to candidate roles or users
+func (t *TaskInstance) HasCandidates() bool {
+	return t.CandidateRoles != "" || t.CandidateUsers != ""
+}
+
+// IsCandidate reports whether the user may claim the task from the pool.
+// Tasks without candidates are open to every user.
+func (t *TaskInstance) IsCandidate(user *User) bool {
+	if !t.HasCandidates() {
+		return true
+	}
+	for _, role := range parseStringList(t.CandidateRoles) {
+		if role == user.Role {
+			return true
+		}
+	}
+	id := strconv.FormatUint(uint64(user.ID), 10)
+	for _, value := range parseStringList(t.CandidateUsers) {
+		if value == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// 容量统计的默认和最大查询天数
+const (
+	DefaultCapacityDays = 30
+	MaxCapacityDays     = 366
+)
+
+// CapacityStat 流程定义版本每天的容量统计，供人工步骤排班和连接器容量规划使用
+//
+// 每个定义版本每天一行，由定时采样从业务表汇总：启动和完成的实例数在当天内持续更新，
+// 并发任务数记录最近一次采样的值和当天的峰值。统计行不随流程实例清理而删除。
+type CapacityStat struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	DefinitionID uint      `gorm:"not null;uniqueIndex:idx_capacity_definition_day,priority:1" json:"definition_id"`
+	Day          time.Time `gorm:"type:date;not null;uniqueIndex:idx_capacity_definition_day,priority:2;index" json:"day"`
+	Started      int       `gorm:"not null;default:0" json:"started"`
+	Completed    int       `gorm:"not null;default:0" json:"completed"`
+	// 用户任务（人工步骤）的并发数
+	ActiveTasks     int `gorm:"not null;default:0" json:"active_tasks"`
+	PeakActiveTasks int `gorm:"not null;default:0" json:"peak_active_tasks"`
+	// 等待外部处理程序的外部任务并发数
+	ActiveExternalTasks     int       `gorm:"not null;default:0" json:"active_external_tasks"`
+	PeakActiveExternalTasks int       `gorm:"not null;default:0" json:"peak_active_external_tasks"`
+	SampledAt               time.Time `gorm:"not null" json:"sampled_at"`
+}
+
+// TableName returns the table name for CapacityStat model
+func (CapacityStat) TableName() string {
+	return "process_capacity_stats"
+}
+
+// CapacityDay truncates t to the start of its day in t's location
+func CapacityDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// ClaimExpiry returns how long a claimed task on the node may stay idle before it is
+// released back to the pool. The node prop "claimExpiryHours" overrides the definition
+// setting; zero means claims never expire.
+func (p *ProcessDefinition) ClaimExpiry(nodeID string) time.Duration {
+	hours := float64(p.ClaimExpiryHours)
+
+	if data, err := p.GetDefinitionData(); err == nil {
+		for _, node := range data.Nodes {
+			if node.ID != nodeID {
+				continue
+			}
+			if value, ok := node.Props["claimExpiryHours"].(float64); ok {
+				hours = value
+			}
+			break
+		}
+	}
+
+	if hours <= 0 {
+		return 0
+	}
+	return time.Duration(hours * float64(time.Hour))
+}
@@ -0,0 +1,100 @@
+package model
+
+import "encoding/json"
+
+// 数据分级常量
+const (
+	DataClassificationPublic       = "public"
+	DataClassificationInternal     = "internal"
+	DataClassificationConfidential = "confidential"
+)
+
+// MaskedValue 脱敏后的变量值
+const MaskedValue = "******"
+
+// DataPolicy 数据分级对应的处理策略
+type DataPolicy struct {
+	Classification       string `json:"classification"`
+	MaskVariablesInLists bool   `json:"mask_variables_in_lists"`
+	AllowExport          bool   `json:"allow_export"`
+	RetentionDays        int    `json:"retention_days"` // 0 表示不限制
+}
+
+// DataPolicyFor returns the policy for a classification; unknown values fall back to internal
+func DataPolicyFor(classification string) DataPolicy {
+	switch classification {
+	case DataClassificationPublic:
+		return DataPolicy{
+			Classification: DataClassificationPublic,
+			AllowExport:    true,
+		}
+	case DataClassificationConfidential:
+		return DataPolicy{
+			Classification:       DataClassificationConfidential,
+			MaskVariablesInLists: true,
+			AllowExport:          false,
+			RetentionDays:        180,
+		}
+	default:
+		return DataPolicy{
+			Classification: DataClassificationInternal,
+			AllowExport:    true,
+			RetentionDays:  730,
+		}
+	}
+}
+
+// IsValidDataClassification checks whether the classification is supported
+func IsValidDataClassification(classification string) bool {
+	switch classification {
+	case DataClassificationPublic, DataClassificationInternal, DataClassificationConfidential:
+		return true
+	}
+	return false
+}
+
+// DataPolicy returns the data handling policy of the definition
+func (p *ProcessDefinition) DataPolicy() DataPolicy {
+	return DataPolicyFor(p.DataClassification)
+}
+
+// RedactForList masks variables of confidential instances before they are returned by list APIs.
+// The definition must be preloaded; instances without it are left untouched.
+func (i *ProcessInstance) RedactForList() {
+	if i.Definition.ID == 0 || !i.Definition.DataPolicy().MaskVariablesInLists {
+		return
+	}
+	i.Variables = maskJSONObject(i.Variables)
+	i.VariablesMasked = true
+	for t := range i.Tasks {
+		i.Tasks[t].FormData = maskJSONObject(i.Tasks[t].FormData)
+	}
+}
+
+// RedactForList masks the embedded instance and any form data stored on the task
+func (t *TaskInstance) RedactForList() {
+	if t.Instance.Definition.ID == 0 || !t.Instance.Definition.DataPolicy().MaskVariablesInLists {
+		return
+	}
+	t.Instance.RedactForList()
+	t.FormData = maskJSONObject(t.FormData)
+}
+
+// maskJSONObject keeps the keys of a JSON object and replaces every value; non-object text is returned as is
+func maskJSONObject(raw string) string {
+	if raw == "" {
+		return raw
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &values); err != nil {
+		return raw
+	}
+	for key := range values {
+		values[key] = MaskedValue
+	}
+	masked, err := json.Marshal(values)
+	if err != nil {
+		return raw
+	}
+	return string(masked)
+}
@@ -0,0 +1,33 @@
+package model
+
+import "errors"
+
+// GetCompensation returns the ID of the compensation handler declared by the
+// "compensation" prop of a service task, or an empty string when unset. The
+// handler is a service task marked with "isForCompensation" that undoes the
+// side effects of the task once the instance is cancelled.
+func GetCompensation(node *ProcessNode) (string, error) {
+	raw, ok := node.Props["compensation"]
+	if !ok || raw == nil {
+		return "", nil
+	}
+	handler, ok := raw.(string)
+	if !ok || handler == "" {
+		return "", errors.New("compensation must be the ID of a compensation handler node")
+	}
+	return handler, nil
+}
+
+// IsCompensationHandler reports whether the node is a compensation handler.
+// Handlers are not connected by flows and only run when compensating.
+func IsCompensationHandler(node *ProcessNode) bool {
+	handler, _ := node.Props["isForCompensation"].(bool)
+	return handler
+}
+
+// CompensatesOnFailure reports whether a failing service task cancels the
+// instance and compensates the completed tasks instead of raising an incident
+func CompensatesOnFailure(node *ProcessNode) bool {
+	compensate, _ := node.Props["compensateOnFailure"].(bool)
+	return compensate
+}
@@ -0,0 +1,321 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 复杂度评分的权重：节点数 + 网关数×3 + 最长路径 + 无界循环数×10
+const (
+	complexityGatewayWeight       = 3
+	complexityUnboundedLoopWeight = 10
+)
+
+// 检查规则
+const (
+	LintRuleUnboundedLoop           = "unbounded-loop"
+	LintRuleUnreachableNode         = "unreachable-node"
+	LintRuleSinglePathGateway       = "single-path-gateway"
+	LintRuleGatewayMissingCondition = "gateway-missing-condition"
+)
+
+// 检查结果的严重程度
+const (
+	LintSeverityWarning = "warning"
+	LintSeverityInfo    = "info"
+)
+
+// ComplexityBudgetDefaultKey 对所有未单独配置的流程生效的复杂度预算
+const ComplexityBudgetDefaultKey = "*"
+
+// LintFinding 流程定义的一条检查结果，不阻止保存
+type LintFinding struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	NodeID   string `json:"node_id,omitempty"`
+	Message  string `json:"message"`
+}
+
+// DefinitionComplexity 流程定义的复杂度评分和检查结果，保存流程定义时计算
+type DefinitionComplexity struct {
+	Score          int     `json:"score"`
+	NodeCount      int     `json:"node_count"`
+	FlowCount      int     `json:"flow_count"`
+	GatewayCount   int     `json:"gateway_count"`
+	GatewayDensity float64 `json:"gateway_density"`
+	// MaxPathLength 从开始节点出发不重复经过节点的最长路径（节点数）
+	MaxPathLength int `json:"max_path_length"`
+	// UnboundedLoops 没有用户任务参与或没有出口的循环数
+	UnboundedLoops int           `json:"unbounded_loops"`
+	Findings       []LintFinding `json:"findings"`
+}
+
+// ComplexityBudget 复杂度预算，评分超过 MaxScore 的流程不能发布
+// DefinitionKey 为 "*" 时作用于所有未单独配置的流程
+type ComplexityBudget struct {
+	BaseModel
+	DefinitionKey string `gorm:"type:varchar(100);not null;uniqueIndex" json:"definition_key"`
+	MaxScore      int    `gorm:"not null" json:"max_score"`
+	UpdatedBy     uint   `gorm:"index" json:"updated_by"`
+}
+
+// TableName returns the table name for ComplexityBudget model
+func (ComplexityBudget) TableName() string {
+	return "complexity_budgets"
+}
+
+// Complexity computes the complexity score and lint findings of the definition
+func (d *ProcessDefinitionData) Complexity() *DefinitionComplexity {
+	result := &DefinitionComplexity{
+		NodeCount: len(d.Nodes),
+		FlowCount: len(d.Flows),
+		Findings:  []LintFinding{},
+	}
+
+	nodes := make(map[string]*ProcessNode, len(d.Nodes))
+	outgoing := make(map[string][]ProcessFlow)
+	for i := range d.Nodes {
+		nodes[d.Nodes[i].ID] = &d.Nodes[i]
+	}
+	for _, flow := range d.Flows {
+		if nodes[flow.From] == nil || nodes[flow.To] == nil {
+			continue
+		}
+		outgoing[flow.From] = append(outgoing[flow.From], flow)
+	}
+
+	for i := range d.Nodes {
+		node := &d.Nodes[i]
+		if node.Type != NodeTypeGateway {
+			continue
+		}
+		result.GatewayCount++
+		result.Findings = append(result.Findings, lintGateway(node, outgoing[node.ID])...)
+	}
+	if result.NodeCount > 0 {
+		result.GatewayDensity = float64(result.GatewayCount) / float64(result.NodeCount)
+	}
+
+	reachable := make(map[string]bool)
+	for i := range d.Nodes {
+		if d.Nodes[i].Type == NodeTypeStart {
+			markReachable(d.Nodes[i].ID, outgoing, reachable)
+			if length := longestPath(d.Nodes[i].ID, outgoing); length > result.MaxPathLength {
+				result.MaxPathLength = length
+			}
+		}
+	}
+	for i := range d.Nodes {
+		if !reachable[d.Nodes[i].ID] && !IsCompensationHandler(&d.Nodes[i]) {
+			result.Findings = append(result.Findings, LintFinding{
+				Rule:     LintRuleUnreachableNode,
+				Severity: LintSeverityWarning,
+				NodeID:   d.Nodes[i].ID,
+				Message:  fmt.Sprintf("节点 '%s' 从开始节点不可达", d.Nodes[i].Name),
+			})
+		}
+	}
+
+	for _, loop := range findLoops(d.Nodes, outgoing) {
+		if !isUnboundedLoop(loop, nodes, outgoing) {
+			continue
+		}
+		result.UnboundedLoops++
+		names := make([]string, len(loop))
+		for i, id := range loop {
+			names[i] = nodes[id].Name
+		}
+		result.Findings = append(result.Findings, LintFinding{
+			Rule:     LintRuleUnboundedLoop,
+			Severity: LintSeverityWarning,
+			NodeID:   loop[0],
+			Message:  fmt.Sprintf("循环 %s 没有用户任务参与或没有出口，可能无限执行", strings.Join(names, " → ")),
+		})
+	}
+
+	result.Score = result.NodeCount +
+		result.GatewayCount*complexityGatewayWeight +
+		result.MaxPathLength +
+		result.UnboundedLoops*complexityUnboundedLoopWeight
+	return result
+}
+
+// lintGateway checks the outgoing flows of a gateway
+func lintGateway(node *ProcessNode, flows []ProcessFlow) []LintFinding {
+	if len(flows) == 1 {
+		return []LintFinding{{
+			Rule:     LintRuleSinglePathGateway,
+			Severity: LintSeverityInfo,
+			NodeID:   node.ID,
+			Message:  fmt.Sprintf("网关 '%s' 只有一条出口连线，可以去掉", node.Name),
+		}}
+	}
+	if GetGatewayType(node) != GatewayTypeExclusive {
+		return nil
+	}
+
+	unconditioned := 0
+	for _, flow := range flows {
+		if strings.TrimSpace(flow.Condition) == "" {
+			unconditioned++
+		}
+	}
+	if unconditioned > 1 {
+		return []LintFinding{{
+			Rule:     LintRuleGatewayMissingCondition,
+			Severity: LintSeverityWarning,
+			NodeID:   node.ID,
+			Message:  fmt.Sprintf("排他网关 '%s' 有 %d 条没有条件的出口连线，只会选择其中一条", node.Name, unconditioned),
+		}}
+	}
+	return nil
+}
+
+// markReachable marks every node reachable from the given node
+func markReachable(id string, outgoing map[string][]ProcessFlow, reachable map[string]bool) {
+	if reachable[id] {
+		return
+	}
+	reachable[id] = true
+	for _, flow := range outgoing[id] {
+		markReachable(flow.To, outgoing, reachable)
+	}
+}
+
+// longestPath returns the number of nodes on the longest path from the given node,
+// ignoring the back edges of loops so that every node is visited at most once per path
+func longestPath(start string, outgoing map[string][]ProcessFlow) int {
+	onStack := make(map[string]bool)
+	memo := make(map[string]int)
+
+	var visit func(id string) int
+	visit = func(id string) int {
+		if length, ok := memo[id]; ok {
+			return length
+		}
+		onStack[id] = true
+		longest := 0
+		for _, flow := range outgoing[id] {
+			if onStack[flow.To] {
+				continue
+			}
+			if length := visit(flow.To); length > longest {
+				longest = length
+			}
+		}
+		onStack[id] = false
+		memo[id] = longest + 1
+		return longest + 1
+	}
+	return visit(start)
+}
+
+// findLoops returns the strongly connected components that form loops, each sorted by node order
+func findLoops(nodes []ProcessNode, outgoing map[string][]ProcessFlow) [][]string {
+	order := make(map[string]int, len(nodes))
+	for i := range nodes {
+		order[nodes[i].ID] = i
+	}
+
+	index := make(map[string]int)
+	lowlink := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var loops [][]string
+	next := 0
+
+	var connect func(id string)
+	connect = func(id string) {
+		index[id] = next
+		lowlink[id] = next
+		next++
+		stack = append(stack, id)
+		onStack[id] = true
+
+		for _, flow := range outgoing[id] {
+			if _, visited := index[flow.To]; !visited {
+				connect(flow.To)
+				lowlink[id] = minInt(lowlink[id], lowlink[flow.To])
+			} else if onStack[flow.To] {
+				lowlink[id] = minInt(lowlink[id], index[flow.To])
+			}
+		}
+
+		if lowlink[id] != index[id] {
+			return
+		}
+		var component []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == id {
+				break
+			}
+		}
+		if len(component) > 1 || hasSelfLoop(id, outgoing) {
+			sort.Slice(component, func(i, j int) bool { return order[component[i]] < order[component[j]] })
+			loops = append(loops, component)
+		}
+	}
+
+	for i := range nodes {
+		if _, visited := index[nodes[i].ID]; !visited {
+			connect(nodes[i].ID)
+		}
+	}
+	sort.Slice(loops, func(i, j int) bool { return order[loops[i][0]] < order[loops[j][0]] })
+	return loops
+}
+
+// hasSelfLoop reports whether a node has a flow back to itself
+func hasSelfLoop(id string, outgoing map[string][]ProcessFlow) bool {
+	for _, flow := range outgoing[id] {
+		if flow.To == id {
+			return true
+		}
+	}
+	return false
+}
+
+// isUnboundedLoop reports whether a loop can repeat without a human decision:
+// no user task or parallel review takes part in it, or no flow leaves it
+func isUnboundedLoop(loop []string, nodes map[string]*ProcessNode, outgoing map[string][]ProcessFlow) bool {
+	members := make(map[string]bool, len(loop))
+	for _, id := range loop {
+		members[id] = true
+	}
+
+	humanStep, hasExit := false, false
+	for _, id := range loop {
+		switch nodes[id].Type {
+		case NodeTypeUserTask, NodeTypeParallelReview:
+			humanStep = true
+		}
+		for _, flow := range outgoing[id] {
+			if !members[flow.To] {
+				hasExit = true
+			}
+		}
+	}
+	return !humanStep || !hasExit
+}
+
+// GetComplexity returns the complexity computed when the definition was saved,
+// computing it from the definition for rows saved before complexity was recorded
+func (p *ProcessDefinition) GetComplexity() (*DefinitionComplexity, error) {
+	if p.ComplexityReport != "" {
+		var complexity DefinitionComplexity
+		if err := json.Unmarshal([]byte(p.ComplexityReport), &complexity); err == nil {
+			return &complexity, nil
+		}
+	}
+	data, err := p.GetDefinitionData()
+	if err != nil {
+		return nil, err
+	}
+	return data.Complexity(), nil
+}
@@ -0,0 +1,109 @@
+package model
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ConnectorPolicy 流程定义的连接器白名单，按流程标识作用于所有版本
+type ConnectorPolicy struct {
+	BaseModel
+	DefinitionKey string `gorm:"type:varchar(100);not null;uniqueIndex" json:"definition_key"`
+	AllowedTypes  string `gorm:"type:text" json:"allowed_types"`
+	AllowedHosts  string `gorm:"type:text" json:"allowed_hosts"`
+	UpdatedBy     uint   `gorm:"index" json:"updated_by"`
+}
+
+// TableName returns the table name for ConnectorPolicy model
+func (ConnectorPolicy) TableName() string {
+	return "connector_policies"
+}
+
+// GetAllowedTypes parses the allowed connector type list
+func (p *ConnectorPolicy) GetAllowedTypes() []string {
+	return parseStringList(p.AllowedTypes)
+}
+
+// SetAllowedTypes sets the allowed connector type list
+func (p *ConnectorPolicy) SetAllowedTypes(types []string) {
+	p.AllowedTypes = formatStringList(types)
+}
+
+// GetAllowedHosts parses the allowed target host list
+func (p *ConnectorPolicy) GetAllowedHosts() []string {
+	return parseStringList(p.AllowedHosts)
+}
+
+// SetAllowedHosts sets the allowed target host list
+func (p *ConnectorPolicy) SetAllowedHosts(hosts []string) {
+	p.AllowedHosts = formatStringList(hosts)
+}
+
+// ServiceConnector describes the external call a service task node makes
+type ServiceConnector struct {
+	Type string
+	Host string
+}
+
+// GetServiceConnector extracts the connector type and target host from a service task node.
+// Nodes without a connector prop use the built-in no-op executor and have an empty type.
+func GetServiceConnector(node *ProcessNode) ServiceConnector {
+	connector := ServiceConnector{}
+	if value, ok := node.Props["connector"].(string); ok {
+		connector.Type = strings.ToLower(strings.TrimSpace(value))
+	}
+	if raw, ok := node.Props["url"].(string); ok && raw != "" {
+		if parsed, err := url.Parse(raw); err == nil {
+			connector.Host = strings.ToLower(parsed.Hostname())
+		}
+	}
+	return connector
+}
+
+// CheckNode validates a service task node against the policy.
+// A nil policy means the definition is unrestricted.
+func (p *ConnectorPolicy) CheckNode(node *ProcessNode) error {
+	if p == nil {
+		return nil
+	}
+
+	connector := GetServiceConnector(node)
+	if connector.Type == "" && connector.Host == "" {
+		return nil
+	}
+
+	if connector.Type != "" && !containsFold(p.GetAllowedTypes(), connector.Type) {
+		return fmt.Errorf("节点 '%s' 使用的连接器类型 '%s' 不在白名单中", node.ID, connector.Type)
+	}
+
+	if connector.Host != "" && !hostAllowed(p.GetAllowedHosts(), connector.Host) {
+		return fmt.Errorf("节点 '%s' 的目标主机 '%s' 不在白名单中", node.ID, connector.Host)
+	}
+
+	return nil
+}
+
+// hostAllowed checks the host against exact entries and "*.example.com" wildcard entries
+func hostAllowed(allowed []string, host string) bool {
+	for _, entry := range allowed {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == host {
+			return true
+		}
+		if strings.HasPrefix(entry, "*.") && strings.HasSuffix(host, entry[1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFold checks whether the list contains the value, ignoring case
+func containsFold(values []string, target string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, target) {
+			return true
+		}
+	}
+	return false
+}
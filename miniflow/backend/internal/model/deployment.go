@@ -0,0 +1,192 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+)
+
+// 部署环境常量，按晋升顺序排列
+const (
+	EnvironmentDev     = "dev"
+	EnvironmentStaging = "staging"
+	EnvironmentProd    = "prod"
+)
+
+// DeploymentPackageFormat 部署包格式版本
+const DeploymentPackageFormat = 1
+
+// environmentOrder 环境晋升顺序
+var environmentOrder = []string{EnvironmentDev, EnvironmentStaging, EnvironmentProd}
+
+// Deployment 流程定义版本在某个环境的部署记录，部署包和校验和随记录保存
+type Deployment struct {
+	BaseModel
+	DefinitionID       uint   `gorm:"not null;index" json:"definition_id"`
+	DefinitionKey      string `gorm:"type:varchar(100);not null;index:idx_deployment_key_env,priority:1" json:"definition_key"`
+	Version            int    `gorm:"not null" json:"version"`
+	Environment        string `gorm:"type:varchar(20);not null;index:idx_deployment_key_env,priority:2" json:"environment"`
+	Checksum           string `gorm:"type:varchar(64);not null;index" json:"checksum"`
+	Package            string `gorm:"not null" json:"-"`
+	SourceDeploymentID *uint  `gorm:"index" json:"source_deployment_id"`
+	DeployedBy         uint   `gorm:"not null;index" json:"deployed_by"`
+	Note               string `gorm:"type:varchar(500)" json:"note"`
+}
+
+// TableName returns the table name for Deployment model
+func (Deployment) TableName() string {
+	return "deployments"
+}
+
+// DeploymentPackage 可在环境之间迁移的部署包：流程定义和它依赖的表单、决策表、连接器配置
+type DeploymentPackage struct {
+	Format       int                    `json:"format"`
+	Definition   PackagedDefinition     `json:"definition"`
+	Dependencies DeploymentDependencies `json:"dependencies"`
+}
+
+// PackagedDefinition 部署包中的流程定义，不包含回调密钥等环境相关的敏感配置
+type PackagedDefinition struct {
+	Key                  string `json:"key"`
+	Name                 string `json:"name"`
+	Version              int    `json:"version"`
+	Description          string `json:"description"`
+	Category             string `json:"category"`
+	DefinitionJSON       string `json:"definition_json"`
+	DataClassification   string `json:"data_classification"`
+	DisplayLabels        string `json:"display_labels"`
+	ClaimExpiryHours     int    `json:"claim_expiry_hours"`
+	CompletionWebhookURL string `json:"completion_webhook_url"`
+}
+
+// DeploymentDependencies 部署包的依赖项
+// 表单和决策表以节点属性的形式内嵌在流程定义中，这里按节点列出以便审阅；连接器白名单单独打包
+type DeploymentDependencies struct {
+	Forms           map[string]interface{}   `json:"forms,omitempty"`
+	DecisionTables  map[string]interface{}   `json:"decision_tables,omitempty"`
+	Connectors      []PackagedConnector      `json:"connectors,omitempty"`
+	ConnectorPolicy *PackagedConnectorPolicy `json:"connector_policy,omitempty"`
+}
+
+// PackagedConnector 服务任务节点使用的连接器
+type PackagedConnector struct {
+	NodeID string `json:"node_id"`
+	Type   string `json:"type"`
+	Host   string `json:"host,omitempty"`
+}
+
+// PackagedConnectorPolicy 部署包中的连接器白名单
+type PackagedConnectorPolicy struct {
+	AllowedTypes []string `json:"allowed_types"`
+	AllowedHosts []string `json:"allowed_hosts"`
+}
+
+// IsValidEnvironment checks whether the environment label is supported
+func IsValidEnvironment(environment string) bool {
+	for _, env := range environmentOrder {
+		if env == environment {
+			return true
+		}
+	}
+	return false
+}
+
+// NextEnvironment returns the environment a deployment in the given environment is promoted to
+func NextEnvironment(environment string) (string, bool) {
+	for i, env := range environmentOrder {
+		if env == environment && i+1 < len(environmentOrder) {
+			return environmentOrder[i+1], true
+		}
+	}
+	return "", false
+}
+
+// NewDeploymentPackage packages a definition together with its dependencies.
+// policy may be nil when the process has no connector allowlist.
+func NewDeploymentPackage(definition *ProcessDefinition, policy *ConnectorPolicy) (*DeploymentPackage, error) {
+	data, err := definition.GetDefinitionData()
+	if err != nil {
+		return nil, err
+	}
+
+	pkg := &DeploymentPackage{
+		Format: DeploymentPackageFormat,
+		Definition: PackagedDefinition{
+			Key:                  definition.Key,
+			Name:                 definition.Name,
+			Version:              definition.Version,
+			Description:          definition.Description,
+			Category:             definition.Category,
+			DefinitionJSON:       definition.DefinitionJSON,
+			DataClassification:   definition.DataClassification,
+			DisplayLabels:        definition.DisplayLabels,
+			ClaimExpiryHours:     definition.ClaimExpiryHours,
+			CompletionWebhookURL: definition.CompletionWebhookURL,
+		},
+	}
+
+	deps := &pkg.Dependencies
+	for i := range data.Nodes {
+		node := &data.Nodes[i]
+		if form, ok := node.Props["form"]; ok && form != nil {
+			if deps.Forms == nil {
+				deps.Forms = make(map[string]interface{})
+			}
+			deps.Forms[node.ID] = form
+		}
+		if table, ok := node.Props["decisionTable"]; ok && table != nil {
+			if deps.DecisionTables == nil {
+				deps.DecisionTables = make(map[string]interface{})
+			}
+			deps.DecisionTables[node.ID] = table
+		}
+		if node.Type == NodeTypeServiceTask {
+			if connector := GetServiceConnector(node); connector.Type != "" {
+				deps.Connectors = append(deps.Connectors, PackagedConnector{
+					NodeID: node.ID,
+					Type:   connector.Type,
+					Host:   connector.Host,
+				})
+			}
+		}
+	}
+	sort.Slice(deps.Connectors, func(i, j int) bool { return deps.Connectors[i].NodeID < deps.Connectors[j].NodeID })
+
+	if policy != nil {
+		deps.ConnectorPolicy = &PackagedConnectorPolicy{
+			AllowedTypes: policy.GetAllowedTypes(),
+			AllowedHosts: policy.GetAllowedHosts(),
+		}
+	}
+
+	return pkg, nil
+}
+
+// Marshal returns the canonical JSON encoding of the package
+func (p *DeploymentPackage) Marshal() (string, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Checksum returns the SHA-256 of the canonical JSON encoding of the package
+func (p *DeploymentPackage) Checksum() (string, error) {
+	data, err := p.Marshal()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// GetPackage parses the stored deployment package
+func (d *Deployment) GetPackage() (*DeploymentPackage, error) {
+	var pkg DeploymentPackage
+	if err := json.Unmarshal([]byte(d.Package), &pkg); err != nil {
+		return nil, err
+	}
+	return &pkg, nil
+}
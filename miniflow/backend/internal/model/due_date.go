@@ -0,0 +1,103 @@
+package model
+
+import (
+	"time"
+)
+
+// DefaultUserTaskEstimatedHours 未配置预计时长的人工节点默认按 8 小时估算
+const DefaultUserTaskEstimatedHours = 8.0
+
+// CriticalPath 从某节点到结束节点的关键路径（预计耗时最长的路径）
+type CriticalPath struct {
+	Nodes          []string `json:"nodes"`
+	EstimatedHours float64  `json:"estimated_hours"`
+}
+
+// EstimatedHours returns the expected duration of a node from its "estimatedHours" prop.
+// Human nodes default to DefaultUserTaskEstimatedHours; automatic nodes take no time.
+func EstimatedHours(node *ProcessNode) float64 {
+	if value, ok := node.Props["estimatedHours"].(float64); ok && value >= 0 {
+		return value
+	}
+	switch node.Type {
+	case NodeTypeUserTask, NodeTypeParallelReview:
+		return DefaultUserTaskEstimatedHours
+	}
+	return 0
+}
+
+// CriticalPathFrom computes the longest estimated path from the node to an end node.
+// Loops are cut at the first revisit, so every node counts at most once per path.
+func (d *ProcessDefinitionData) CriticalPathFrom(nodeID string) *CriticalPath {
+	nodes := make(map[string]*ProcessNode, len(d.Nodes))
+	for i := range d.Nodes {
+		nodes[d.Nodes[i].ID] = &d.Nodes[i]
+	}
+	outgoing := make(map[string][]string)
+	for _, flow := range d.Flows {
+		outgoing[flow.From] = append(outgoing[flow.From], flow.To)
+	}
+
+	onPath := make(map[string]bool)
+	var walk func(id string) *CriticalPath
+	walk = func(id string) *CriticalPath {
+		node, ok := nodes[id]
+		if !ok || onPath[id] {
+			return &CriticalPath{}
+		}
+		onPath[id] = true
+		defer delete(onPath, id)
+
+		best := &CriticalPath{}
+		for _, next := range outgoing[id] {
+			if path := walk(next); path.EstimatedHours > best.EstimatedHours || len(best.Nodes) == 0 {
+				best = path
+			}
+		}
+		return &CriticalPath{
+			Nodes:          append([]string{id}, best.Nodes...),
+			EstimatedHours: EstimatedHours(node) + best.EstimatedHours,
+		}
+	}
+
+	return walk(nodeID)
+}
+
+// ComputeTaskDueDate distributes the time left until the instance due date over the
+// critical path starting at the task's node, giving the task its proportional share.
+// Returns nil when the instance has no due date.
+func ComputeTaskDueDate(instance *ProcessInstance, definition *ProcessDefinitionData, nodeID string, now time.Time) *time.Time {
+	if instance.DueDate == nil {
+		return nil
+	}
+	due := *instance.DueDate
+
+	remaining := due.Sub(now)
+	if remaining <= 0 {
+		return &due
+	}
+
+	node := definition.findNode(nodeID)
+	if node == nil {
+		return &due
+	}
+	estimate := EstimatedHours(node)
+	path := definition.CriticalPathFrom(nodeID)
+	if estimate <= 0 || path.EstimatedHours <= 0 {
+		return &due
+	}
+
+	share := estimate / path.EstimatedHours
+	taskDue := now.Add(time.Duration(float64(remaining) * share)).Truncate(time.Minute)
+	return &taskDue
+}
+
+// findNode returns the node with the given ID, or nil if absent
+func (d *ProcessDefinitionData) findNode(nodeID string) *ProcessNode {
+	for i := range d.Nodes {
+		if d.Nodes[i].ID == nodeID {
+			return &d.Nodes[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// 疑似重复实例状态常量
+const (
+	DuplicateStatusOpen      = "open"
+	DuplicateStatusDismissed = "dismissed"
+	DuplicateStatusConfirmed = "confirmed"
+)
+
+// DuplicateScoreThreshold 相似度达到该值的运行中实例被标记为疑似重复
+const DuplicateScoreThreshold = 0.85
+
+// InstanceDuplicate 新启动的实例与另一个运行中实例疑似重复的标记
+// 确认重复后新实例被取消，两条实例通过该记录互相关联
+type InstanceDuplicate struct {
+	BaseModel
+	InstanceID    uint       `gorm:"not null;index" json:"instance_id"`
+	DuplicateOfID uint       `gorm:"not null;index" json:"duplicate_of_id"`
+	Score         float64    `gorm:"not null" json:"score"`
+	Reasons       string     `gorm:"type:text" json:"reasons"`
+	Status        string     `gorm:"type:varchar(20);not null;default:open;index" json:"status"`
+	ResolvedBy    *uint      `json:"resolved_by"`
+	ResolvedAt    *time.Time `json:"resolved_at"`
+
+	// 关联关系
+	Instance    ProcessInstance `gorm:"foreignKey:InstanceID" json:"instance,omitempty"`
+	DuplicateOf ProcessInstance `gorm:"foreignKey:DuplicateOfID" json:"duplicate_of,omitempty"`
+}
+
+// TableName returns the table name for InstanceDuplicate model
+func (InstanceDuplicate) TableName() string {
+	return "instance_duplicates"
+}
+
+// GetReasons parses the list of matched heuristics
+func (d *InstanceDuplicate) GetReasons() []string {
+	return parseStringList(d.Reasons)
+}
+
+// SetReasons sets the list of matched heuristics
+func (d *InstanceDuplicate) SetReasons(reasons []string) {
+	d.Reasons = formatStringList(reasons)
+}
+
+// GetDuplicateKeyVariables parses the variables compared when detecting duplicate instances
+func (p *ProcessDefinition) GetDuplicateKeyVariables() []string {
+	return parseStringList(p.DuplicateKeyVariables)
+}
+
+// SetDuplicateKeyVariables sets the variables compared when detecting duplicate instances
+func (p *ProcessDefinition) SetDuplicateKeyVariables(names []string) {
+	p.DuplicateKeyVariables = formatStringList(names)
+}
+
+// DuplicateMatch is the similarity between two instances and the heuristics that matched
+type DuplicateMatch struct {
+	Score   float64
+	Reasons []string
+}
+
+// MatchDuplicate compares a new instance with a running one. Business keys are compared
+// after normalization (case, punctuation and whitespace are ignored) and by edit distance;
+// key variables match when all of them are present and equal after normalization.
+func MatchDuplicate(candidate, existing *ProcessInstance, keyVariables []string) DuplicateMatch {
+	var match DuplicateMatch
+
+	a, b := NormalizeBusinessKey(candidate.BusinessKey), NormalizeBusinessKey(existing.BusinessKey)
+	if a != "" && b != "" {
+		if a == b {
+			match.Score = 1
+			match.Reasons = append(match.Reasons, "business_key_equal")
+		} else if similarity := stringSimilarity(a, b); similarity >= DuplicateScoreThreshold {
+			match.Score = similarity
+			match.Reasons = append(match.Reasons, fmt.Sprintf("business_key_similar:%.2f", similarity))
+		}
+	}
+
+	if len(keyVariables) > 0 && variablesMatch(candidate.Variables, existing.Variables, keyVariables) {
+		if match.Score < 0.95 {
+			match.Score = 0.95
+		}
+		match.Reasons = append(match.Reasons, "key_variables_equal:"+strings.Join(keyVariables, ","))
+	}
+
+	return match
+}
+
+// NormalizeBusinessKey lowercases the key and drops everything except letters and digits
+func NormalizeBusinessKey(key string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(key) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// variablesMatch reports whether every key variable is present in both instances with equal values
+func variablesMatch(rawA, rawB string, names []string) bool {
+	var a, b map[string]interface{}
+	if json.Unmarshal([]byte(rawA), &a) != nil || json.Unmarshal([]byte(rawB), &b) != nil {
+		return false
+	}
+	for _, name := range names {
+		va, okA := a[name]
+		vb, okB := b[name]
+		if !okA || !okB || va == nil || vb == nil {
+			return false
+		}
+		if NormalizeBusinessKey(fmt.Sprint(va)) != NormalizeBusinessKey(fmt.Sprint(vb)) {
+			return false
+		}
+	}
+	return true
+}
+
+// stringSimilarity returns 1 - levenshtein(a, b) / max(len(a), len(b))
+func stringSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return 1 - float64(prev[len(rb)])/float64(longest)
+}
+
+// minInt returns the smallest of the values
+func minInt(values ...int) int {
+	result := values[0]
+	for _, v := range values[1:] {
+		if v < result {
+			result = v
+		}
+	}
+	return result
+}
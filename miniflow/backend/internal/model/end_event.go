@@ -0,0 +1,9 @@
+package model
+
+// IsTerminateEnd reports whether an end node declares "terminate", ending every
+// other active branch of the instance when reached. A plain end node only ends
+// its own branch.
+func IsTerminateEnd(node *ProcessNode) bool {
+	terminate, _ := node.Props["terminate"].(bool)
+	return terminate
+}
@@ -0,0 +1,114 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 网关类型常量，未配置 gatewayType 时按排他网关处理
+const (
+	GatewayTypeExclusive = "exclusive"
+	GatewayTypeParallel  = "parallel"
+	GatewayTypeInclusive = "inclusive"
+)
+
+// Enum 字段允许的取值集合，用于在 API 边界和查询条件中校验状态、类型等字符串
+type Enum struct {
+	Name   string
+	Values []string
+}
+
+// 各字段的取值集合
+var (
+	InstanceStatuses = Enum{Name: "instance status", Values: []string{
+		InstanceStatusRunning, InstanceStatusSuspended, InstanceStatusCompleted, InstanceStatusFailed, InstanceStatusCancelled,
+	}}
+	TaskStatuses = Enum{Name: "task status", Values: []string{
+		TaskStatusCreated, TaskStatusAssigned, TaskStatusClaimed, TaskStatusInProgress,
+		TaskStatusCompleted, TaskStatusFailed, TaskStatusSkipped, TaskStatusEscalated,
+	}}
+	ProcessStatuses = Enum{Name: "process status", Values: []string{
+		ProcessStatusDraft, ProcessStatusPublished, ProcessStatusDeprecated, ProcessStatusArchived,
+	}}
+	IncidentStatuses = Enum{Name: "incident status", Values: []string{
+		IncidentStatusOpen, IncidentStatusResolved,
+	}}
+	IncidentTypes = Enum{Name: "incident type", Values: []string{
+		IncidentTypeConnectorPolicy, IncidentTypeAssignmentFailed, IncidentTypeServiceFailed,
+		IncidentTypeGatewayNoPath, IncidentTypeConditionFailed, IncidentTypeVisitLimit,
+		IncidentTypeScriptFailed, IncidentTypeExternalFailed, IncidentTypeMailFailed, IncidentTypeRecovery,
+	}}
+	GatewayTypes = Enum{Name: "gateway type", Values: []string{
+		GatewayTypeExclusive, GatewayTypeParallel, GatewayTypeInclusive,
+	}}
+	ScriptLanguages = Enum{Name: "script language", Values: []string{
+		ScriptLanguageExpression, ScriptLanguageJavaScript, ScriptLanguageLua,
+	}}
+	TimerStatuses = Enum{Name: "timer status", Values: []string{
+		TimerStatusWaiting, TimerStatusFired, TimerStatusCancelled, TimerStatusFailed,
+	}}
+	WebhookDeliveryStatuses = Enum{Name: "webhook delivery status", Values: []string{
+		WebhookDeliveryPending, WebhookDeliveryDelivered, WebhookDeliveryFailed,
+	}}
+	NotificationJobStatuses = Enum{Name: "notification status", Values: []string{
+		NotificationJobPending, NotificationJobFailed, NotificationJobSent,
+	}}
+	AsyncJobStatuses = Enum{Name: "async job status", Values: []string{
+		AsyncJobStatusPending, AsyncJobStatusRunning, AsyncJobStatusCompleted, AsyncJobStatusFailed, AsyncJobStatusCancelled,
+	}}
+	MessageSubscriptionStatuses = Enum{Name: "message subscription status", Values: []string{
+		MessageSubscriptionWaiting, MessageSubscriptionCorrelated, MessageSubscriptionCancelled,
+	}}
+	ActivityTypes = Enum{Name: "activity type", Values: []string{
+		ActivityNodeEntered, ActivityNodeExited, ActivityGatewayDecision,
+		ActivityTaskCompleted, ActivityVariableChanged, ActivityStateTransition, ActivityExecutionMoved, ActivityTaskReturned,
+		ActivityTaskDelegated, ActivityTaskResolved, ActivityTaskEscalated, ActivityTaskClaimed,
+	}}
+)
+
+// EnumError 取值不在允许范围内
+type EnumError struct {
+	Name    string
+	Value   string
+	Allowed []string
+}
+
+// Error implements the error interface
+func (e *EnumError) Error() string {
+	return fmt.Sprintf("invalid %s %q, allowed values: %s", e.Name, e.Value, strings.Join(e.Allowed, ", "))
+}
+
+// Contains reports whether the value is one of the allowed values
+func (e Enum) Contains(value string) bool {
+	for _, v := range e.Values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate returns an *EnumError when the value is not allowed
+func (e Enum) Validate(value string) error {
+	if e.Contains(value) {
+		return nil
+	}
+	return &EnumError{Name: e.Name, Value: value, Allowed: e.Values}
+}
+
+// ValidateFilter validates an optional filter value; empty or non-string values are left to the caller
+func (e Enum) ValidateFilter(value interface{}) error {
+	s, ok := value.(string)
+	if !ok || s == "" {
+		return nil
+	}
+	return e.Validate(s)
+}
+
+// GetGatewayType returns the gateway type of a gateway node, defaulting to exclusive
+func GetGatewayType(node *ProcessNode) string {
+	if gatewayType, ok := node.Props["gatewayType"].(string); ok && gatewayType != "" {
+		return gatewayType
+	}
+	return GatewayTypeExclusive
+}
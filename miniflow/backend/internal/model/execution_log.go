@@ -0,0 +1,53 @@
+package model
+
+import "time"
+
+// 服务任务执行状态常量
+const (
+	ExecutionStatusRunning   = "running"
+	ExecutionStatusSucceeded = "succeeded"
+	ExecutionStatusFailed    = "failed"
+)
+
+// 执行日志中请求和响应内容的最大长度，超出部分截断
+const (
+	MaxExecutionRequestLength  = 8192
+	MaxExecutionResponseLength = 2048
+)
+
+// ExecutionLog 服务任务的一次执行记录，用于排查集成问题
+// 执行开始时写入 running 状态，结束后更新结果和耗时；Attempt 为同一实例同一节点的第几次执行
+// Mocked 表示响应来自连接器模拟模式而非真实调用
+type ExecutionLog struct {
+	BaseModel
+	TaskID     uint       `gorm:"not null;index" json:"task_id"`
+	InstanceID uint       `gorm:"not null;index:idx_execution_instance_node,priority:1" json:"instance_id"`
+	NodeID     string     `gorm:"type:varchar(64);not null;index:idx_execution_instance_node,priority:2" json:"node_id"`
+	Attempt    int        `gorm:"not null;default:1" json:"attempt"`
+	Connector  string     `gorm:"type:varchar(50)" json:"connector"`
+	Method     string     `gorm:"type:varchar(10)" json:"method"`
+	URL        string     `gorm:"type:varchar(1000)" json:"url"`
+	Status     string     `gorm:"type:varchar(20);not null;index" json:"status"`
+	StatusCode int        `json:"status_code"`
+	Mocked     bool       `gorm:"not null;default:false" json:"mocked"`
+	Request    string     `gorm:"type:text" json:"request"`
+	Response   string     `gorm:"type:text" json:"response"`
+	Error      string     `gorm:"type:text" json:"error"`
+	ErrorCode  string     `gorm:"type:varchar(50)" json:"error_code"`
+	StartTime  time.Time  `gorm:"not null" json:"start_time"`
+	EndTime    *time.Time `json:"end_time"`
+	DurationMs int64      `json:"duration_ms"`
+}
+
+// TableName returns the table name for ExecutionLog model
+func (ExecutionLog) TableName() string {
+	return "execution_logs"
+}
+
+// TruncateExecutionContent shortens request or response content stored in an execution log
+func TruncateExecutionContent(content string, max int) string {
+	if len(content) <= max {
+		return content
+	}
+	return content[:max] + "...(truncated)"
+}
@@ -0,0 +1,28 @@
+package model
+
+// 执行跟踪分类常量
+const (
+	TraceCategoryNode      = "node"      // 进入节点
+	TraceCategoryCondition = "condition" // 连线条件评估
+	TraceCategoryFlow      = "flow"      // 选择连线和汇聚网关到达
+	TraceCategoryWrite     = "write"     // 引擎写入实例、任务和定时器
+)
+
+// MaxTraceMessageLength 跟踪消息的最大长度，超出部分截断
+const MaxTraceMessageLength = 500
+
+// ExecutionTrace 开启跟踪的流程实例的详细推进记录，用于排查路由问题
+// 只有管理员启动实例时可以开启跟踪，调用活动启动的子实例沿用父实例的设置；Data 为 JSON 对象
+type ExecutionTrace struct {
+	BaseModel
+	InstanceID uint   `gorm:"not null;index" json:"instance_id"`
+	Category   string `gorm:"type:varchar(20);not null" json:"category"`
+	NodeID     string `gorm:"type:varchar(64)" json:"node_id,omitempty"`
+	Message    string `gorm:"type:varchar(500);not null" json:"message"`
+	Data       string `gorm:"type:json" json:"data,omitempty"`
+}
+
+// TableName returns the table name for ExecutionTrace model
+func (ExecutionTrace) TableName() string {
+	return "execution_traces"
+}
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"errors"
+	"strings"
+)
+
+// ServiceTaskTypeExternal 由外部处理程序获取并完成的服务任务
+const ServiceTaskTypeExternal = "external"
+
+// ExternalTaskConfig 外部任务节点配置
+type ExternalTaskConfig struct {
+	// Topic 外部处理程序按主题获取任务
+	Topic string
+	// Retries 外部处理程序报告失败后可以重试的次数，为 0 时第一次失败就生成异常事件
+	Retries int
+}
+
+// IsExternalTask reports whether a service task node is processed by external
+// workers, i.e. its "type" prop is "external"
+func IsExternalTask(node *ProcessNode) bool {
+	if node.Type != NodeTypeServiceTask {
+		return false
+	}
+	value, _ := node.Props["type"].(string)
+	return strings.EqualFold(strings.TrimSpace(value), ServiceTaskTypeExternal)
+}
+
+// GetExternalTaskConfig reads the "topic" and optional "retries" props of an
+// external service task node. Topics are matched case-insensitively.
+func GetExternalTaskConfig(node *ProcessNode) (*ExternalTaskConfig, error) {
+	cfg := &ExternalTaskConfig{}
+	if value, ok := node.Props["topic"].(string); ok {
+		cfg.Topic = strings.ToLower(strings.TrimSpace(value))
+	}
+	if cfg.Topic == "" {
+		return nil, errors.New("topic is required for external tasks")
+	}
+	if len(cfg.Topic) > 128 {
+		return nil, errors.New("topic must be at most 128 characters")
+	}
+
+	if raw, ok := node.Props["retries"]; ok && raw != nil {
+		retries, isNumber := raw.(float64)
+		if !isNumber || retries < 0 || retries != float64(int(retries)) {
+			return nil, errors.New("retries must be a non-negative integer")
+		}
+		cfg.Retries = int(retries)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,28 @@
+package model
+
+// GatewayArrival 分支经入口连线到达汇聚网关的记录（令牌）
+// 汇聚网关在每条入口连线都有未消费的到达记录后才继续推进，推进时消费这些记录；
+// 循环再次经过同一网关时会产生新的记录
+type GatewayArrival struct {
+	BaseModel
+	InstanceID uint   `gorm:"not null;index:idx_arrival_gateway,priority:1" json:"instance_id"`
+	GatewayID  string `gorm:"type:varchar(64);not null;index:idx_arrival_gateway,priority:2" json:"gateway_id"`
+	FlowKey    string `gorm:"type:varchar(255);not null" json:"flow_key"`
+	Consumed   bool   `gorm:"not null;default:false;index" json:"consumed"`
+
+	// 包容网关分叉时未激活的分支不会到达汇聚网关，分叉时为这些分支对应的入口连线记录跳过的到达
+	Skipped bool `gorm:"not null;default:false" json:"skipped,omitempty"`
+}
+
+// TableName returns the table name for GatewayArrival model
+func (GatewayArrival) TableName() string {
+	return "gateway_arrivals"
+}
+
+// FlowKey identifies a flow for join tracking; flows without an ID are identified by their endpoints
+func (f *ProcessFlow) FlowKey() string {
+	if f.ID != "" {
+		return f.ID
+	}
+	return f.From + "->" + f.To
+}
@@ -0,0 +1,268 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 流程图校验规则，违反任一规则的流程定义不能保存
+const (
+	GraphRuleMissingNodeID    = "missing-node-id"
+	GraphRuleDuplicateNodeID  = "duplicate-node-id"
+	GraphRuleDuplicateFlowID  = "duplicate-flow-id"
+	GraphRuleUnknownFlowNode  = "unknown-flow-node"
+	GraphRuleUnreachableNode  = "unreachable-node"
+	GraphRuleNoPathToEnd      = "no-path-to-end"
+	GraphRuleInfiniteCycle    = "infinite-cycle"
+	GraphRuleGatewayCondition = "gateway-missing-condition"
+)
+
+// GraphIssue 流程图的一处结构错误，NodeIDs 和 FlowIDs 是设计器需要高亮的节点和连线
+// 没有ID的连线用 FlowKey（源节点->目标节点）标识
+type GraphIssue struct {
+	Rule    string   `json:"rule"`
+	NodeIDs []string `json:"node_ids,omitempty"`
+	FlowIDs []string `json:"flow_ids,omitempty"`
+	Message string   `json:"message"`
+}
+
+// ValidateGraph checks the structure of the definition graph: node and flow IDs are
+// unique, every node is reachable from the start node and can reach an end node,
+// loops can be left, and exclusive gateways choose between conditioned flows.
+// Compensation handlers are not connected to the graph and are skipped.
+// Returns nil when the graph is valid.
+func (d *ProcessDefinitionData) ValidateGraph() []GraphIssue {
+	issues := d.validateGraphIDs()
+	if len(issues) > 0 {
+		// 节点或连线无法唯一确定时，不再分析可达性
+		return issues
+	}
+
+	nodes := make(map[string]*ProcessNode, len(d.Nodes))
+	outgoing := make(map[string][]ProcessFlow)
+	incoming := make(map[string][]ProcessFlow)
+	for i := range d.Nodes {
+		nodes[d.Nodes[i].ID] = &d.Nodes[i]
+	}
+	for _, flow := range d.Flows {
+		outgoing[flow.From] = append(outgoing[flow.From], flow)
+		incoming[flow.To] = append(incoming[flow.To], flow)
+	}
+
+	for i := range d.Nodes {
+		node := &d.Nodes[i]
+		if node.Type == NodeTypeGateway {
+			if issue := checkGatewayConditions(node, outgoing[node.ID]); issue != nil {
+				issues = append(issues, *issue)
+			}
+		}
+	}
+
+	reachable := make(map[string]bool)
+	for i := range d.Nodes {
+		if d.Nodes[i].Type == NodeTypeStart {
+			markReachable(d.Nodes[i].ID, outgoing, reachable)
+		}
+	}
+	reachesEnd := make(map[string]bool)
+	for i := range d.Nodes {
+		if d.Nodes[i].Type == NodeTypeEnd {
+			markReachingEnd(d.Nodes[i].ID, incoming, reachesEnd)
+		}
+	}
+
+	trapped := make(map[string]bool)
+	for _, loop := range findLoops(d.Nodes, outgoing) {
+		issue, noExit := checkLoop(loop, nodes, outgoing)
+		if issue == nil {
+			continue
+		}
+		issues = append(issues, *issue)
+		if noExit {
+			for _, id := range loop {
+				trapped[id] = true
+			}
+		}
+	}
+
+	for i := range d.Nodes {
+		node := &d.Nodes[i]
+		if IsCompensationHandler(node) {
+			continue
+		}
+		switch {
+		case !reachable[node.ID]:
+			issues = append(issues, GraphIssue{
+				Rule:    GraphRuleUnreachableNode,
+				NodeIDs: []string{node.ID},
+				Message: fmt.Sprintf("节点 '%s' 从开始节点不可达", node.Name),
+			})
+		case !reachesEnd[node.ID] && !trapped[node.ID]:
+			issues = append(issues, GraphIssue{
+				Rule:    GraphRuleNoPathToEnd,
+				NodeIDs: []string{node.ID},
+				Message: fmt.Sprintf("节点 '%s' 没有到达结束节点的路径", node.Name),
+			})
+		}
+	}
+	return issues
+}
+
+// validateGraphIDs checks that nodes have unique IDs, that flow IDs are unique and
+// that flows connect existing nodes
+func (d *ProcessDefinitionData) validateGraphIDs() []GraphIssue {
+	var issues []GraphIssue
+
+	nodeIDs := make(map[string]bool, len(d.Nodes))
+	reported := make(map[string]bool)
+	for i := range d.Nodes {
+		node := &d.Nodes[i]
+		if node.ID == "" {
+			issues = append(issues, GraphIssue{
+				Rule:    GraphRuleMissingNodeID,
+				Message: fmt.Sprintf("节点 '%s' 缺少ID", node.Name),
+			})
+			continue
+		}
+		if nodeIDs[node.ID] && !reported[node.ID] {
+			reported[node.ID] = true
+			issues = append(issues, GraphIssue{
+				Rule:    GraphRuleDuplicateNodeID,
+				NodeIDs: []string{node.ID},
+				Message: fmt.Sprintf("节点ID '%s' 重复", node.ID),
+			})
+		}
+		nodeIDs[node.ID] = true
+	}
+
+	flowIDs := make(map[string]bool, len(d.Flows))
+	for _, flow := range d.Flows {
+		if flow.ID != "" {
+			if flowIDs[flow.ID] && !reported["flow:"+flow.ID] {
+				reported["flow:"+flow.ID] = true
+				issues = append(issues, GraphIssue{
+					Rule:    GraphRuleDuplicateFlowID,
+					FlowIDs: []string{flow.ID},
+					Message: fmt.Sprintf("连线ID '%s' 重复", flow.ID),
+				})
+			}
+			flowIDs[flow.ID] = true
+		}
+
+		if !nodeIDs[flow.From] {
+			issues = append(issues, GraphIssue{
+				Rule:    GraphRuleUnknownFlowNode,
+				FlowIDs: []string{flow.FlowKey()},
+				Message: fmt.Sprintf("连线的源节点 '%s' 不存在", flow.From),
+			})
+		}
+		if !nodeIDs[flow.To] {
+			issues = append(issues, GraphIssue{
+				Rule:    GraphRuleUnknownFlowNode,
+				FlowIDs: []string{flow.FlowKey()},
+				Message: fmt.Sprintf("连线的目标节点 '%s' 不存在", flow.To),
+			})
+		}
+	}
+	return issues
+}
+
+// checkGatewayConditions checks that an exclusive gateway with several outgoing flows
+// puts conditions on all of them except at most one default flow
+func checkGatewayConditions(node *ProcessNode, flows []ProcessFlow) *GraphIssue {
+	if GetGatewayType(node) != GatewayTypeExclusive || len(flows) < 2 {
+		return nil
+	}
+
+	var unconditioned []string
+	for _, flow := range flows {
+		if strings.TrimSpace(flow.Condition) == "" {
+			unconditioned = append(unconditioned, flow.FlowKey())
+		}
+	}
+	if len(unconditioned) <= 1 {
+		return nil
+	}
+	return &GraphIssue{
+		Rule:    GraphRuleGatewayCondition,
+		NodeIDs: []string{node.ID},
+		FlowIDs: unconditioned,
+		Message: fmt.Sprintf("排他网关 '%s' 有 %d 条没有条件的出口连线，最多只能有一条默认连线", node.Name, len(unconditioned)),
+	}
+}
+
+// checkLoop reports a loop that can never be left: no flow leaves it, or it has no
+// wait state and no condition, so the same path repeats without anything deciding
+// to leave. noExit is true when no flow leaves the loop.
+func checkLoop(loop []string, nodes map[string]*ProcessNode, outgoing map[string][]ProcessFlow) (issue *GraphIssue, noExit bool) {
+	members := make(map[string]bool, len(loop))
+	for _, id := range loop {
+		members[id] = true
+	}
+
+	waits, conditioned, hasExit := false, false, false
+	var flowIDs []string
+	for _, id := range loop {
+		node := nodes[id]
+		if isWaitState(node) {
+			waits = true
+		}
+		// 只有排他网关和包容网关会评估连线条件
+		decides := node.Type == NodeTypeGateway && GetGatewayType(node) != GatewayTypeParallel
+		for _, flow := range outgoing[id] {
+			if decides && strings.TrimSpace(flow.Condition) != "" {
+				conditioned = true
+			}
+			if members[flow.To] {
+				flowIDs = append(flowIDs, flow.FlowKey())
+			} else {
+				hasExit = true
+			}
+		}
+	}
+
+	names := make([]string, len(loop))
+	for i, id := range loop {
+		names[i] = nodes[id].Name
+	}
+	path := strings.Join(names, " → ")
+
+	if !hasExit {
+		return &GraphIssue{
+			Rule:    GraphRuleInfiniteCycle,
+			NodeIDs: loop,
+			FlowIDs: flowIDs,
+			Message: fmt.Sprintf("循环 %s 没有出口连线，进入后无法结束", path),
+		}, true
+	}
+	if !waits && !conditioned {
+		return &GraphIssue{
+			Rule:    GraphRuleInfiniteCycle,
+			NodeIDs: loop,
+			FlowIDs: flowIDs,
+			Message: fmt.Sprintf("循环 %s 没有等待节点也没有条件，会无限执行", path),
+		}, false
+	}
+	return nil, false
+}
+
+// isWaitState reports whether the instance waits at the node for a person, a timer,
+// a message or another instance before it continues
+func isWaitState(node *ProcessNode) bool {
+	switch node.Type {
+	case NodeTypeUserTask, NodeTypeParallelReview, NodeTypeTimer, NodeTypeMessageCatch, NodeTypeCallActivity:
+		return true
+	}
+	return IsExternalTask(node)
+}
+
+// markReachingEnd marks every node from which the given node is reachable
+func markReachingEnd(id string, incoming map[string][]ProcessFlow, marked map[string]bool) {
+	if marked[id] {
+		return
+	}
+	marked[id] = true
+	for _, flow := range incoming[id] {
+		markReachingEnd(flow.From, incoming, marked)
+	}
+}
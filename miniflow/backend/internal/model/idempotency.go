@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// IdempotencyRecord 带 Idempotency-Key 请求头的写操作记录，重试时直接返回首次请求的响应
+// StatusCode 为 0 表示首次请求仍在处理中
+type IdempotencyRecord struct {
+	BaseModel
+	UserID       uint      `gorm:"not null;uniqueIndex:idx_idempotency_user_key,priority:1" json:"user_id"`
+	Key          string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_idempotency_user_key,priority:2" json:"key"`
+	Method       string    `gorm:"type:varchar(10);not null" json:"method"`
+	Path         string    `gorm:"type:varchar(255);not null" json:"path"`
+	RequestHash  string    `gorm:"type:varchar(64);not null" json:"request_hash"`
+	StatusCode   int       `gorm:"not null;default:0" json:"status_code"`
+	ContentType  string    `gorm:"type:varchar(100)" json:"content_type"`
+	ResponseBody string    `json:"-"`
+	ExpiresAt    time.Time `gorm:"not null;index" json:"expires_at"`
+}
+
+// TableName returns the table name for IdempotencyRecord model
+func (IdempotencyRecord) TableName() string {
+	return "idempotency_records"
+}
+
+// IsCompleted reports whether the first request finished and its response was stored
+func (r *IdempotencyRecord) IsCompleted() bool {
+	return r.StatusCode != 0
+}
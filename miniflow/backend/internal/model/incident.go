@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// 异常事件状态常量
+const (
+	IncidentStatusOpen     = "open"
+	IncidentStatusResolved = "resolved"
+)
+
+// 异常事件类型常量
+const (
+	IncidentTypeConnectorPolicy  = "connector_policy_violation"
+	IncidentTypeAssignmentFailed = "assignment_failed"
+	IncidentTypeServiceFailed    = "service_failed"
+	IncidentTypeGatewayNoPath    = "gateway_no_path"
+	IncidentTypeConditionFailed  = "condition_failed"
+	IncidentTypeVisitLimit       = "visit_limit_exceeded"
+	IncidentTypeScriptFailed     = "script_failed"
+	IncidentTypeExternalFailed   = "external_task_failed"
+	IncidentTypeMailFailed       = "mail_failed"
+	// IncidentTypeRecovery 启动恢复发现的无法自动修复的停滞实例，重试时重新进入节点
+	IncidentTypeRecovery = "recovery_required"
+)
+
+// Incident 流程执行过程中需要人工处理的异常事件
+type Incident struct {
+	BaseModel
+	InstanceID uint       `gorm:"not null;index" json:"instance_id"`
+	TaskID     *uint      `gorm:"index" json:"task_id"`
+	NodeID     string     `gorm:"type:varchar(64);index" json:"node_id"`
+	Type       string     `gorm:"type:varchar(50);not null;index" json:"type"`
+	Code       string     `gorm:"type:varchar(50);index" json:"code"`
+	Message    string     `gorm:"type:text" json:"message"`
+	Status     string     `gorm:"type:varchar(20);not null;default:open;index" json:"status"`
+	ResolvedAt *time.Time `json:"resolved_at"`
+	ResolvedBy *uint      `json:"resolved_by"`
+
+	// 关联关系
+	Instance ProcessInstance `gorm:"foreignKey:InstanceID" json:"instance,omitempty"`
+}
+
+// TableName returns the table name for Incident model
+func (Incident) TableName() string {
+	return "incidents"
+}
@@ -0,0 +1,47 @@
+package model
+
+import "encoding/json"
+
+// CancellationSnapshot records what cancelling an instance closed, so that the
+// cancellation can be undone within the recycle bin's undo window
+type CancellationSnapshot struct {
+	// Status is the instance status before it was cancelled
+	Status string `json:"status"`
+	// Tasks maps each task skipped by the cancellation to its previous status
+	Tasks map[uint]string `json:"tasks,omitempty"`
+	// Timers lists the waiting timers cancelled with the instance
+	Timers []uint `json:"timers,omitempty"`
+	// Children lists the sub-process instances cancelled with the instance
+	Children []uint `json:"children,omitempty"`
+	// Messages lists the waiting message subscriptions cancelled with the instance
+	Messages []uint `json:"messages,omitempty"`
+	// Compensations lists the compensation tasks run by the cancellation; their
+	// side effects cannot be undone, so such a cancellation cannot be restored
+	Compensations []uint `json:"compensations,omitempty"`
+}
+
+// GetCancellation decodes the cancellation snapshot, returning nil when none was recorded
+func (p *ProcessInstance) GetCancellation() *CancellationSnapshot {
+	if p.Cancellation == "" {
+		return nil
+	}
+	var snapshot CancellationSnapshot
+	if err := json.Unmarshal([]byte(p.Cancellation), &snapshot); err != nil {
+		return nil
+	}
+	return &snapshot
+}
+
+// SetCancellation encodes the cancellation snapshot, clearing it when nil
+func (p *ProcessInstance) SetCancellation(snapshot *CancellationSnapshot) error {
+	if snapshot == nil {
+		p.Cancellation = ""
+		return nil
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	p.Cancellation = string(data)
+	return nil
+}
@@ -0,0 +1,48 @@
+package model
+
+import "time"
+
+// 后台作业类型常量，用于运维作业面板
+const (
+	JobTypeTimer        = "timer"
+	JobTypeWebhook      = "webhook"
+	JobTypeNotification = "notification"
+	JobTypeAsync        = "async"
+)
+
+// JobTypes 作业面板支持的作业类型
+var JobTypes = []string{JobTypeTimer, JobTypeWebhook, JobTypeNotification, JobTypeAsync}
+
+// 回调投递状态常量
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// 延迟通知的作业状态，由 sent_at 和 last_error 推导
+const (
+	NotificationJobPending = "pending"
+	NotificationJobFailed  = "failed"
+	NotificationJobSent    = "sent"
+)
+
+// WebhookDelivery 流程完成回调和事件订阅回调的投递记录，失败后可由运维重新投递
+type WebhookDelivery struct {
+	BaseModel
+	InstanceID uint `gorm:"not null;index" json:"instance_id"`
+	// SubscriptionID 事件订阅的投递记录所属的订阅，流程完成回调为空
+	SubscriptionID *uint      `gorm:"index" json:"subscription_id,omitempty"`
+	Event          string     `gorm:"type:varchar(50);not null" json:"event"`
+	URL            string     `gorm:"type:varchar(500);not null" json:"url"`
+	Payload        string     `gorm:"type:text" json:"payload"`
+	Status         string     `gorm:"type:varchar(20);not null;default:pending;index" json:"status"`
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`
+	LastError      string     `gorm:"type:text" json:"last_error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+}
+
+// TableName returns the table name for WebhookDelivery model
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
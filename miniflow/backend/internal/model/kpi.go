@@ -0,0 +1,74 @@
+package model
+
+import "time"
+
+// KPI 指标类型常量
+const (
+	// KPIMetricCycleTime 在阈值时长内完成的实例占已完成实例的比例
+	KPIMetricCycleTime = "cycle_time"
+	// KPIMetricCompletionRate 已结束实例中正常完成的比例
+	KPIMetricCompletionRate = "completion_rate"
+	// KPIMetricTaskOnTime 已完成任务中在截止时间前完成的比例
+	KPIMetricTaskOnTime = "task_on_time"
+)
+
+// DefaultKPIWindowDays KPI 默认统计最近 30 天
+const DefaultKPIWindowDays = 30
+
+// ProcessKPI 流程负责人为流程定义的 KPI 目标，按流程 key 保存，对所有版本生效
+type ProcessKPI struct {
+	BaseModel
+	DefinitionKey  string  `gorm:"type:varchar(100);not null;index" json:"definition_key"`
+	Name           string  `gorm:"type:varchar(255);not null" json:"name"`
+	Description    string  `gorm:"type:text" json:"description"`
+	Metric         string  `gorm:"type:varchar(30);not null" json:"metric"`
+	ThresholdHours float64 `gorm:"not null;default:0" json:"threshold_hours"`
+	TargetPercent  float64 `gorm:"not null" json:"target_percent"`
+	WindowDays     int     `gorm:"not null;default:30" json:"window_days"`
+	Enabled        bool    `gorm:"not null;default:true;index" json:"enabled"`
+	CreatedBy      uint    `gorm:"not null;index" json:"created_by"`
+
+	// 最近一次评估结果
+	LastAttainment  *float64   `json:"last_attainment"`
+	LastSampleSize  int        `gorm:"not null;default:0" json:"last_sample_size"`
+	LastEvaluatedAt *time.Time `json:"last_evaluated_at"`
+	Breached        bool       `gorm:"not null;default:false" json:"breached"`
+}
+
+// TableName returns the table name for ProcessKPI model
+func (ProcessKPI) TableName() string {
+	return "process_kpis"
+}
+
+// ProcessKPIMeasurement KPI 的历史评估记录，用于展示趋势
+type ProcessKPIMeasurement struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	KPIID      uint      `gorm:"not null;index:idx_kpi_measured,priority:1" json:"kpi_id"`
+	MeasuredAt time.Time `gorm:"not null;index:idx_kpi_measured,priority:2" json:"measured_at"`
+	SampleSize int       `gorm:"not null" json:"sample_size"`
+	Attainment float64   `gorm:"not null" json:"attainment"`
+	Met        bool      `gorm:"not null" json:"met"`
+}
+
+// TableName returns the table name for ProcessKPIMeasurement model
+func (ProcessKPIMeasurement) TableName() string {
+	return "process_kpi_measurements"
+}
+
+// IsValidKPIMetric checks whether the metric is supported
+func IsValidKPIMetric(metric string) bool {
+	switch metric {
+	case KPIMetricCycleTime, KPIMetricCompletionRate, KPIMetricTaskOnTime:
+		return true
+	}
+	return false
+}
+
+// Window returns the start of the evaluation window ending at now
+func (k *ProcessKPI) Window(now time.Time) time.Time {
+	days := k.WindowDays
+	if days <= 0 {
+		days = DefaultKPIWindowDays
+	}
+	return now.AddDate(0, 0, -days)
+}
@@ -0,0 +1,84 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// DefaultLoopMaxIterations caps condition loops that do not declare maxIterations
+const DefaultLoopMaxIterations = 10
+
+// LoopVariable is the variable holding the number of completed iterations while
+// a loop condition is evaluated
+const LoopVariable = "loopCounter"
+
+// Loop repeats a node after it completes, either a fixed number of times or
+// while a condition holds, so that "resubmit until approved" does not need a
+// hand-drawn cycle
+type Loop struct {
+	// Times runs the node exactly this many times; zero for condition loops
+	Times int
+	// Condition is evaluated after every iteration; the node runs again while it is true
+	Condition string
+	// MaxIterations stops a condition loop after this many iterations even if
+	// the condition still holds
+	MaxIterations int
+}
+
+// GetLoop parses the "loop" prop of a node, returning nil when unset. The prop
+// holds either "times" or a "condition" with an optional "maxIterations".
+func GetLoop(node *ProcessNode) (*Loop, error) {
+	raw, ok := node.Props["loop"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	props, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("loop must be an object")
+	}
+
+	condition, _ := props["condition"].(string)
+	condition = strings.TrimSpace(condition)
+	rawTimes, hasTimes := props["times"]
+	if hasTimes == (condition != "") {
+		return nil, errors.New("loop requires either times or condition")
+	}
+
+	if hasTimes {
+		times, ok := rawTimes.(float64)
+		if !ok || times < 1 || times != float64(int(times)) {
+			return nil, errors.New("loop.times must be a positive integer")
+		}
+		return &Loop{Times: int(times)}, nil
+	}
+
+	loop := &Loop{Condition: condition, MaxIterations: DefaultLoopMaxIterations}
+	if raw, ok := props["maxIterations"]; ok && raw != nil {
+		max, ok := raw.(float64)
+		if !ok || max < 1 || max != float64(int(max)) {
+			return nil, errors.New("loop.maxIterations must be a positive integer")
+		}
+		loop.MaxIterations = int(max)
+	}
+	return loop, nil
+}
+
+// GetLoopCounters decodes how many iterations each looping node has completed
+func (p *ProcessInstance) GetLoopCounters() map[string]int {
+	counters := make(map[string]int)
+	if p.LoopCounters != "" {
+		_ = json.Unmarshal([]byte(p.LoopCounters), &counters)
+	}
+	return counters
+}
+
+// SetLoopCounters encodes the per-node iteration counts
+func (p *ProcessInstance) SetLoopCounters(counters map[string]int) error {
+	data, err := json.Marshal(counters)
+	if err != nil {
+		return err
+	}
+	p.LoopCounters = string(data)
+	return nil
+}
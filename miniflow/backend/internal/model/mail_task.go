@@ -0,0 +1,75 @@
+package model
+
+import (
+	"errors"
+	"strings"
+)
+
+// MailTaskConfig 邮件任务节点配置，收件人、主题和正文都是通知模板，渲染时可以引用流程变量
+type MailTaskConfig struct {
+	To      []string
+	Cc      []string
+	Subject string
+	Body    string
+}
+
+// GetMailTaskConfig reads the configuration of a mail task node from the "to"
+// (required), optional "cc", "subject" (required) and "body" props. Recipients
+// are given as an array or a comma separated string; each entry may be a
+// template such as {{.Variables.applicantEmail}}.
+func GetMailTaskConfig(node *ProcessNode) (*MailTaskConfig, error) {
+	to, err := mailRecipients(node.Props["to"])
+	if err != nil {
+		return nil, errors.New("to " + err.Error())
+	}
+	if len(to) == 0 {
+		return nil, errors.New("to is required")
+	}
+	cc, err := mailRecipients(node.Props["cc"])
+	if err != nil {
+		return nil, errors.New("cc " + err.Error())
+	}
+
+	cfg := &MailTaskConfig{To: to, Cc: cc}
+	cfg.Subject, _ = node.Props["subject"].(string)
+	if strings.TrimSpace(cfg.Subject) == "" {
+		return nil, errors.New("subject is required")
+	}
+	if raw, ok := node.Props["body"]; ok && raw != nil {
+		body, isString := raw.(string)
+		if !isString {
+			return nil, errors.New("body must be a string")
+		}
+		cfg.Body = body
+	}
+	return cfg, nil
+}
+
+// mailRecipients reads a recipient list given as an array or a comma separated string
+func mailRecipients(raw interface{}) ([]string, error) {
+	var values []string
+	switch v := raw.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		values = strings.Split(v, ",")
+	case []interface{}:
+		for _, item := range v {
+			value, ok := item.(string)
+			if !ok {
+				return nil, errors.New("must be a list of strings")
+			}
+			values = append(values, value)
+		}
+	default:
+		return nil, errors.New("must be a string or a list of strings")
+	}
+
+	recipients := make([]string, 0, len(values))
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			recipients = append(recipients, value)
+		}
+	}
+	return recipients, nil
+}
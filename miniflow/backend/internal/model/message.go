@@ -0,0 +1,72 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// 消息订阅状态常量
+const (
+	MessageSubscriptionWaiting    = "waiting"
+	MessageSubscriptionCorrelated = "correlated"
+	MessageSubscriptionCancelled  = "cancelled"
+)
+
+// 消息名称和关联键的长度上限
+const (
+	MaxMessageNameLength    = 128
+	MaxCorrelationKeyLength = 255
+)
+
+// MessageSubscription 消息捕获节点上等待外部消息的订阅
+//
+// 实例进入消息捕获节点时创建订阅并停留在节点上，外部系统按消息名称和关联键投递消息后
+// 订阅被关联，实例沿节点的出口连线继续推进。
+type MessageSubscription struct {
+	BaseModel
+	InstanceID     uint       `gorm:"not null;index" json:"instance_id"`
+	NodeID         string     `gorm:"type:varchar(64);not null" json:"node_id"`
+	MessageName    string     `gorm:"type:varchar(128);not null;index:idx_message_correlation,priority:1" json:"message_name"`
+	CorrelationKey string     `gorm:"type:varchar(255);not null;index:idx_message_correlation,priority:2" json:"correlation_key"`
+	Status         string     `gorm:"type:varchar(20);not null;default:waiting;index:idx_message_correlation,priority:3" json:"status"`
+	CorrelatedAt   *time.Time `json:"correlated_at"`
+}
+
+// TableName returns the table name for MessageSubscription model
+func (MessageSubscription) TableName() string {
+	return "message_subscriptions"
+}
+
+// MessageCatchConfig 消息捕获节点配置
+type MessageCatchConfig struct {
+	// MessageName 等待的消息名称
+	MessageName string
+	// CorrelationKey 关联键表达式，进入节点时求值；为空时使用实例的业务键
+	CorrelationKey string
+}
+
+// GetMessageCatchConfig reads the "messageName" and optional "correlationKey" props of a
+// message catch node. The correlation key may contain ${...} expressions evaluated when the
+// node is entered; without it the instance's business key is used.
+func GetMessageCatchConfig(node *ProcessNode) (*MessageCatchConfig, error) {
+	cfg := &MessageCatchConfig{}
+	if value, ok := node.Props["messageName"].(string); ok {
+		cfg.MessageName = strings.TrimSpace(value)
+	}
+	if cfg.MessageName == "" {
+		return nil, errors.New("messageName is required for message catch nodes")
+	}
+	if len(cfg.MessageName) > MaxMessageNameLength {
+		return nil, errors.New("messageName must be at most 128 characters")
+	}
+
+	if raw, ok := node.Props["correlationKey"]; ok && raw != nil {
+		value, isString := raw.(string)
+		if !isString {
+			return nil, errors.New("correlationKey must be a string")
+		}
+		cfg.CorrelationKey = strings.TrimSpace(value)
+	}
+	return cfg, nil
+}
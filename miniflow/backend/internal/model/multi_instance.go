@@ -0,0 +1,125 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 多实例（会签）模式常量
+const (
+	// MultiInstanceParallel 同时为所有处理人创建任务
+	MultiInstanceParallel = "parallel"
+	// MultiInstanceSequential 按处理人顺序逐个创建任务，上一个完成后再创建下一个
+	MultiInstanceSequential = "sequential"
+)
+
+// MultiInstanceStateSuffix 多实例节点运行状态变量名的后缀
+const MultiInstanceStateSuffix = "_multiInstance"
+
+// MultiInstanceConfig 用户任务的多实例（会签）配置
+type MultiInstanceConfig struct {
+	Mode              string
+	Assignees         []uint
+	AssigneesVariable string
+	// Completion 完成条件原文，如 "all"、"any"、"2 of 3"
+	Completion string
+	// Required 满足完成条件需要完成的任务数，0 表示全部
+	Required int
+}
+
+// MultiInstanceState 多实例节点的运行状态，保存在流程变量中
+type MultiInstanceState struct {
+	Mode        string `json:"mode"`
+	Assignees   []uint `json:"assignees"`
+	Required    int    `json:"required"`
+	Completed   int    `json:"completed"`
+	FirstTaskID uint   `json:"first_task_id"`
+}
+
+// MultiInstanceStateVariable returns the variable holding the runtime state of a multi-instance node
+func MultiInstanceStateVariable(nodeID string) string {
+	return nodeID + MultiInstanceStateSuffix
+}
+
+// GetMultiInstanceConfig reads the "multiInstance" prop of a user task, returning nil when unset.
+// The prop holds "mode" (parallel or sequential), the assignees as "assignees" (user IDs) or
+// "assigneesVariable", and the "completion" condition ("all", "any" or "<n> of <m>").
+func GetMultiInstanceConfig(node *ProcessNode) (*MultiInstanceConfig, error) {
+	raw, ok := node.Props["multiInstance"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	props, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("multiInstance 必须是对象")
+	}
+
+	cfg := &MultiInstanceConfig{Mode: MultiInstanceParallel, Completion: "all"}
+	if mode, ok := props["mode"].(string); ok && mode != "" {
+		if mode != MultiInstanceParallel && mode != MultiInstanceSequential {
+			return nil, fmt.Errorf("mode 必须是 %s 或 %s", MultiInstanceParallel, MultiInstanceSequential)
+		}
+		cfg.Mode = mode
+	}
+
+	if values, ok := props["assignees"].([]interface{}); ok {
+		ids, err := ParseUserIDs(values)
+		if err != nil {
+			return nil, fmt.Errorf("assignees 格式错误: %v", err)
+		}
+		cfg.Assignees = ids
+	}
+	if value, ok := props["assigneesVariable"].(string); ok {
+		cfg.AssigneesVariable = strings.TrimSpace(value)
+	}
+	if len(cfg.Assignees) == 0 && cfg.AssigneesVariable == "" {
+		return nil, errors.New("必须配置处理人 assignees 或 assigneesVariable")
+	}
+
+	if value, ok := props["completion"].(string); ok && strings.TrimSpace(value) != "" {
+		cfg.Completion = strings.TrimSpace(value)
+	}
+	required, err := ParseCompletionCondition(cfg.Completion)
+	if err != nil {
+		return nil, err
+	}
+	if required > 0 && len(cfg.Assignees) > 0 && cfg.AssigneesVariable == "" && required > len(cfg.Assignees) {
+		return nil, fmt.Errorf("完成条件 %q 需要的人数超过处理人数量 %d", cfg.Completion, len(cfg.Assignees))
+	}
+	cfg.Required = required
+
+	return cfg, nil
+}
+
+// ParseCompletionCondition parses a multi-instance completion condition and returns the
+// number of completed tasks it requires, 0 meaning all of them. Supported forms are
+// "all", "any" and "<n> of <m>" (m is informational, the assignee count is authoritative).
+func ParseCompletionCondition(condition string) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(condition)) {
+	case "", "all":
+		return 0, nil
+	case "any":
+		return 1, nil
+	}
+
+	parts := strings.Fields(condition)
+	if len(parts) != 3 || strings.ToLower(parts[1]) != "of" {
+		return 0, fmt.Errorf("无效的完成条件 %q，应为 all、any 或 \"n of m\"", condition)
+	}
+	n, errN := strconv.Atoi(parts[0])
+	m, errM := strconv.Atoi(parts[2])
+	if errN != nil || errM != nil || n <= 0 || m <= 0 || n > m {
+		return 0, fmt.Errorf("无效的完成条件 %q，应为 all、any 或 \"n of m\"", condition)
+	}
+	return n, nil
+}
+
+// RequiredCount returns how many of total tasks must complete to satisfy the condition
+func (s *MultiInstanceState) RequiredCount() int {
+	if s.Required <= 0 || s.Required > len(s.Assignees) {
+		return len(s.Assignees)
+	}
+	return s.Required
+}
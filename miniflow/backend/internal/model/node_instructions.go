@@ -0,0 +1,124 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// 节点说明内容格式常量
+const (
+	InstructionFormatMarkdown = "markdown"
+	InstructionFormatHTML     = "html"
+	InstructionFormatText     = "text"
+)
+
+// NodeInstructions 节点的办理说明，随流程定义保存，在任务表单中展示给处理人
+type NodeInstructions struct {
+	Format    string             `json:"format"`
+	Content   string             `json:"content"`
+	Links     []InstructionLink  `json:"links,omitempty"`
+	Checklist []InstructionCheck `json:"checklist,omitempty"`
+}
+
+// InstructionLink 说明中的参考链接
+type InstructionLink struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// InstructionCheck 办理检查项
+type InstructionCheck struct {
+	ID       string `json:"id"`
+	Text     string `json:"text"`
+	Required bool   `json:"required,omitempty"`
+}
+
+// GetNodeInstructions reads the "instructions" prop of a node. The prop is either
+// a plain string (markdown content) or an object with content, links and checklist.
+// Returns nil when the node has no instructions.
+func GetNodeInstructions(node *ProcessNode) (*NodeInstructions, error) {
+	raw, ok := node.Props["instructions"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	instructions := &NodeInstructions{}
+	if content, isString := raw.(string); isString {
+		if strings.TrimSpace(content) == "" {
+			return nil, nil
+		}
+		instructions.Content = content
+	} else {
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, instructions); err != nil {
+			return nil, fmt.Errorf("instructions 格式错误: %v", err)
+		}
+	}
+
+	if instructions.Format == "" {
+		instructions.Format = InstructionFormatMarkdown
+	}
+	if err := instructions.validate(); err != nil {
+		return nil, err
+	}
+	return instructions, nil
+}
+
+// Texts returns pointers to every text field that supports variable interpolation
+func (n *NodeInstructions) Texts() []*string {
+	texts := []*string{&n.Content}
+	for i := range n.Links {
+		texts = append(texts, &n.Links[i].Title, &n.Links[i].URL)
+	}
+	for i := range n.Checklist {
+		texts = append(texts, &n.Checklist[i].Text)
+	}
+	return texts
+}
+
+// validate checks the format, links and checklist and fills default checklist IDs
+func (n *NodeInstructions) validate() error {
+	switch n.Format {
+	case InstructionFormatMarkdown, InstructionFormatHTML, InstructionFormatText:
+	default:
+		return fmt.Errorf("说明格式 %q 无效", n.Format)
+	}
+
+	for i, link := range n.Links {
+		if strings.TrimSpace(link.URL) == "" {
+			return fmt.Errorf("第 %d 个链接缺少地址", i+1)
+		}
+		// 包含变量的链接在任务创建后才能确定，只检查固定链接
+		if strings.Contains(link.URL, "${") {
+			continue
+		}
+		parsed, err := url.Parse(link.URL)
+		if err != nil {
+			return fmt.Errorf("链接 %s 无效: %v", link.URL, err)
+		}
+		if parsed.Scheme != "" && parsed.Scheme != "http" && parsed.Scheme != "https" {
+			return fmt.Errorf("链接 %s 只支持 http 或 https", link.URL)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i := range n.Checklist {
+		item := &n.Checklist[i]
+		if strings.TrimSpace(item.Text) == "" {
+			return fmt.Errorf("第 %d 个检查项缺少内容", i+1)
+		}
+		if item.ID == "" {
+			item.ID = fmt.Sprintf("check-%d", i+1)
+		}
+		if seen[item.ID] {
+			return fmt.Errorf("检查项 %s 重复", item.ID)
+		}
+		seen[item.ID] = true
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 通知渠道常量
+const (
+	NotificationChannelEmail = "email"
+	NotificationChannelInApp = "in_app"
+	NotificationChannelChat  = "chat"
+)
+
+// 通知投递模式常量
+const (
+	NotificationDeliveryImmediate = "immediate"
+	NotificationDeliveryDigest    = "digest"
+)
+
+// 通知事件类型常量
+const (
+	NotificationEventTaskCreated      = "task.created"
+	NotificationEventTaskAssigned     = "task.assigned"
+	NotificationEventTaskOverdue      = "task.overdue"
+	NotificationEventTaskReminder     = "task.reminder"
+	NotificationEventProcessCompleted = "process.completed"
+	NotificationEventProcessFailed    = "process.failed"
+	NotificationEventAnnouncement     = "announcement.published"
+	NotificationEventTaskClaimExpired = "task.claim_expired"
+	NotificationEventKPIBreached      = "process.kpi_breached"
+	NotificationEventDigest           = "notification.digest"
+)
+
+// NotificationPreference 用户通知偏好
+type NotificationPreference struct {
+	BaseModel
+	UserID          uint   `gorm:"not null;uniqueIndex" json:"user_id"`
+	Channels        string `gorm:"type:text" json:"channels"`
+	MutedEvents     string `gorm:"type:text" json:"muted_events"`
+	DeliveryMode    string `gorm:"type:varchar(20);not null;default:immediate" json:"delivery_mode"`
+	QuietHoursStart string `gorm:"type:varchar(5)" json:"quiet_hours_start"`
+	QuietHoursEnd   string `gorm:"type:varchar(5)" json:"quiet_hours_end"`
+	Timezone        string `gorm:"type:varchar(64)" json:"timezone"`
+	Locale          string `gorm:"type:varchar(20)" json:"locale"`
+}
+
+// TableName returns the table name for NotificationPreference model
+func (NotificationPreference) TableName() string {
+	return "notification_preferences"
+}
+
+// GetChannels parses the enabled channel list
+func (p *NotificationPreference) GetChannels() []string {
+	return parseStringList(p.Channels)
+}
+
+// SetChannels sets the enabled channel list
+func (p *NotificationPreference) SetChannels(channels []string) {
+	p.Channels = formatStringList(channels)
+}
+
+// GetMutedEvents parses the muted event type list
+func (p *NotificationPreference) GetMutedEvents() []string {
+	return parseStringList(p.MutedEvents)
+}
+
+// SetMutedEvents sets the muted event type list
+func (p *NotificationPreference) SetMutedEvents(events []string) {
+	p.MutedEvents = formatStringList(events)
+}
+
+// DefaultNotificationPreference returns the preference used when a user has not configured one
+func DefaultNotificationPreference(userID uint) *NotificationPreference {
+	pref := &NotificationPreference{
+		UserID:       userID,
+		DeliveryMode: NotificationDeliveryImmediate,
+	}
+	pref.SetChannels([]string{NotificationChannelInApp, NotificationChannelEmail})
+	return pref
+}
+
+// NotificationQueueItem 延迟投递的通知（摘要模式或免打扰时段）
+type NotificationQueueItem struct {
+	BaseModel
+	UserID       uint       `gorm:"not null;index" json:"user_id"`
+	Channel      string     `gorm:"type:varchar(20);not null" json:"channel"`
+	EventType    string     `gorm:"type:varchar(50);not null" json:"event_type"`
+	Subject      string     `gorm:"type:varchar(255)" json:"subject"`
+	Body         string     `gorm:"type:text" json:"body"`
+	DeliverAfter time.Time  `gorm:"not null;index" json:"deliver_after"`
+	SentAt       *time.Time `gorm:"index" json:"sent_at"`
+	Attempts     int        `gorm:"not null;default:0" json:"attempts"`
+	LastError    string     `gorm:"type:text" json:"last_error,omitempty"`
+}
+
+// TableName returns the table name for NotificationQueueItem model
+func (NotificationQueueItem) TableName() string {
+	return "notification_queue"
+}
+
+// Notification 站内通知，写入用户的收件箱，ReadAt 为空表示未读
+type Notification struct {
+	BaseModel
+	UserID    uint       `gorm:"not null;index:idx_notification_user_read" json:"user_id"`
+	EventType string     `gorm:"type:varchar(50);not null" json:"event_type"`
+	Subject   string     `gorm:"type:varchar(255)" json:"subject"`
+	Body      string     `gorm:"type:text" json:"body"`
+	ReadAt    *time.Time `gorm:"index:idx_notification_user_read" json:"read_at"`
+}
+
+// TableName returns the table name for Notification model
+func (Notification) TableName() string {
+	return "notifications"
+}
+
+// NotificationTemplate 通知模板，按事件类型和语言区分
+type NotificationTemplate struct {
+	BaseModel
+	EventType string `gorm:"type:varchar(50);not null;uniqueIndex:idx_event_locale,composite:event_type" json:"event_type"`
+	Locale    string `gorm:"type:varchar(20);not null;uniqueIndex:idx_event_locale,composite:locale" json:"locale"`
+	Subject   string `gorm:"type:varchar(255);not null" json:"subject"`
+	Body      string `gorm:"type:text;not null" json:"body"`
+	UpdatedBy uint   `gorm:"index" json:"updated_by"`
+}
+
+// TableName returns the table name for NotificationTemplate model
+func (NotificationTemplate) TableName() string {
+	return "notification_templates"
+}
+
+// parseStringList parses a JSON string array column
+func parseStringList(raw string) []string {
+	var values []string
+	if raw == "" {
+		return values
+	}
+	if err := json.Unmarshal([]byte(raw), &values); err != nil {
+		return nil
+	}
+	return values
+}
+
+// formatStringList formats a string slice as a JSON array column
+func formatStringList(values []string) string {
+	if values == nil {
+		values = []string{}
+	}
+	data, _ := json.Marshal(values)
+	return string(data)
+}
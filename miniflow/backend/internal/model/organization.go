@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// Department 组织部门，ParentID 为空的是顶级部门；用户通过 User.DepartmentID 归属一个部门
+type Department struct {
+	BaseModel
+	Code        string `gorm:"type:varchar(100);not null;uniqueIndex" json:"code"`
+	Name        string `gorm:"type:varchar(255);not null" json:"name"`
+	ParentID    *uint  `gorm:"index" json:"parent_id"`
+	ManagerID   *uint  `gorm:"index" json:"manager_id"`
+	Description string `gorm:"type:text" json:"description"`
+}
+
+// TableName returns the table name for Department model
+func (Department) TableName() string {
+	return "departments"
+}
+
+// Group 用户组，一个用户可以属于多个组
+type Group struct {
+	BaseModel
+	Code        string `gorm:"type:varchar(100);not null;uniqueIndex" json:"code"`
+	Name        string `gorm:"type:varchar(255);not null" json:"name"`
+	Description string `gorm:"type:text" json:"description"`
+}
+
+// TableName returns the table name for Group model
+func (Group) TableName() string {
+	return "user_groups"
+}
+
+// GroupMember 用户组成员关系
+type GroupMember struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	GroupID   uint      `gorm:"not null;uniqueIndex:idx_group_member" json:"group_id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_group_member;index" json:"user_id"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+}
+
+// TableName returns the table name for GroupMember model
+func (GroupMember) TableName() string {
+	return "user_group_members"
+}
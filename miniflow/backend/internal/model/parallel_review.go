@@ -0,0 +1,112 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConsolidationNodeSuffix 并行评审节点汇总任务的节点ID后缀
+const ConsolidationNodeSuffix = "#consolidate"
+
+// ParallelReviewConfig 并行评审组合节点配置
+type ParallelReviewConfig struct {
+	Reviewers         []uint
+	ReviewersVariable string
+	OwnerID           uint
+	OwnerVariable     string
+	OutcomeVariable   string
+	ConsolidationName string
+}
+
+// ReviewOutcome 单个评审人的结构化评审结果，按完成顺序追加到结果变量数组
+type ReviewOutcome struct {
+	TaskID      uint                   `json:"task_id"`
+	ReviewerID  uint                   `json:"reviewer_id"`
+	Reviewer    string                 `json:"reviewer"`
+	Outcome     string                 `json:"outcome"`
+	Comment     string                 `json:"comment,omitempty"`
+	Data        map[string]interface{} `json:"data,omitempty"`
+	CompletedAt time.Time              `json:"completed_at"`
+}
+
+// ConsolidationNodeID returns the task node ID used by the consolidation task of a review node
+func ConsolidationNodeID(nodeID string) string {
+	return nodeID + ConsolidationNodeSuffix
+}
+
+// ParseConsolidationNodeID returns the review node ID if the task node ID belongs to a consolidation task
+func ParseConsolidationNodeID(taskNodeID string) (string, bool) {
+	if !strings.HasSuffix(taskNodeID, ConsolidationNodeSuffix) {
+		return "", false
+	}
+	return strings.TrimSuffix(taskNodeID, ConsolidationNodeSuffix), true
+}
+
+// GetParallelReviewConfig reads the configuration of a parallel review node.
+// Reviewers come from the "reviewers" prop (user IDs) or the "reviewersVariable" prop;
+// the owner from "ownerId" or "ownerVariable" and defaults to the process starter.
+func GetParallelReviewConfig(node *ProcessNode) (*ParallelReviewConfig, error) {
+	cfg := &ParallelReviewConfig{
+		OutcomeVariable:   node.ID + "_reviews",
+		ConsolidationName: "汇总评审意见",
+	}
+
+	if raw, ok := node.Props["reviewers"].([]interface{}); ok {
+		ids, err := ParseUserIDs(raw)
+		if err != nil {
+			return nil, fmt.Errorf("reviewers 格式错误: %v", err)
+		}
+		cfg.Reviewers = ids
+	}
+	if value, ok := node.Props["reviewersVariable"].(string); ok {
+		cfg.ReviewersVariable = strings.TrimSpace(value)
+	}
+	if len(cfg.Reviewers) == 0 && cfg.ReviewersVariable == "" {
+		return nil, errors.New("必须配置评审人 reviewers 或 reviewersVariable")
+	}
+
+	if value, ok := node.Props["ownerId"]; ok && value != nil {
+		ids, err := ParseUserIDs([]interface{}{value})
+		if err != nil {
+			return nil, fmt.Errorf("ownerId 格式错误: %v", err)
+		}
+		cfg.OwnerID = ids[0]
+	}
+	if value, ok := node.Props["ownerVariable"].(string); ok {
+		cfg.OwnerVariable = strings.TrimSpace(value)
+	}
+	if value, ok := node.Props["outcomeVariable"].(string); ok && strings.TrimSpace(value) != "" {
+		cfg.OutcomeVariable = strings.TrimSpace(value)
+	}
+	if value, ok := node.Props["consolidationName"].(string); ok && strings.TrimSpace(value) != "" {
+		cfg.ConsolidationName = strings.TrimSpace(value)
+	}
+
+	return cfg, nil
+}
+
+// ParseUserIDs converts JSON decoded values (numbers or numeric strings) into user IDs
+func ParseUserIDs(values []interface{}) ([]uint, error) {
+	ids := make([]uint, 0, len(values))
+	for _, value := range values {
+		switch v := value.(type) {
+		case float64:
+			if v <= 0 || v != float64(uint(v)) {
+				return nil, fmt.Errorf("无效的用户ID %v", v)
+			}
+			ids = append(ids, uint(v))
+		case string:
+			id, err := strconv.ParseUint(strings.TrimSpace(v), 10, 32)
+			if err != nil || id == 0 {
+				return nil, fmt.Errorf("无效的用户ID %q", v)
+			}
+			ids = append(ids, uint(id))
+		default:
+			return nil, fmt.Errorf("无效的用户ID %v", v)
+		}
+	}
+	return ids, nil
+}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"encoding/json"
+	"hash/fnv"
 	"time"
 )
 
@@ -26,6 +27,17 @@ const (
 	TaskStatusEscalated  = "escalated"
 )
 
+// 任务处理结果常量
+const (
+	TaskOutcomeRejected = "rejected"
+)
+
+// 任务委派状态常量
+const (
+	DelegationStatePending  = "pending"
+	DelegationStateResolved = "resolved"
+)
+
 // 任务类型常量
 const (
 	TaskTypeUser    = "userTask"
@@ -38,14 +50,35 @@ const (
 // ProcessDefinition represents a process definition in the system
 type ProcessDefinition struct {
 	BaseModel
-	Key            string `gorm:"column:key;type:varchar(100);not null;uniqueIndex:idx_key_version,composite:key" json:"key"`
-	Name           string `gorm:"type:varchar(255);not null;index" json:"name"`
-	Version        int    `gorm:"not null;default:1;uniqueIndex:idx_key_version,composite:version" json:"version"`
-	Description    string `gorm:"type:text" json:"description"`
-	Category       string `gorm:"type:varchar(50);index" json:"category"`
-	DefinitionJSON string `gorm:"type:json;not null" json:"definition_json"`
-	Status         string `gorm:"type:varchar(20);not null;default:draft;index" json:"status"`
-	CreatedBy      uint   `gorm:"not null;index;constraint:OnDelete:RESTRICT" json:"created_by"`
+	Key                string `gorm:"column:key;type:varchar(100);not null;uniqueIndex:idx_key_version,composite:key" json:"key"`
+	Name               string `gorm:"type:varchar(255);not null;index" json:"name"`
+	Version            int    `gorm:"not null;default:1;uniqueIndex:idx_key_version,composite:version" json:"version"`
+	Description        string `gorm:"type:text" json:"description"`
+	Category           string `gorm:"type:varchar(50);index" json:"category"`
+	DefinitionJSON     string `gorm:"type:json;not null" json:"definition_json"`
+	Status             string `gorm:"type:varchar(20);not null;default:draft;index" json:"status"`
+	DataClassification string `gorm:"type:varchar(20);not null;default:internal;index" json:"data_classification"`
+	DisplayLabels      string `gorm:"type:text" json:"display_labels"`
+	CreatedBy          uint   `gorm:"not null;index;constraint:OnDelete:RESTRICT" json:"created_by"`
+
+	// 流程完成回调
+	CompletionWebhookURL    string `gorm:"type:varchar(500)" json:"completion_webhook_url"`
+	CompletionWebhookSecret string `gorm:"type:varchar(255)" json:"-"`
+
+	// 认领超时（小时），认领后无操作超过该时长的任务自动释放，0表示不限制；节点属性 claimExpiryHours 可覆盖
+	ClaimExpiryHours int `gorm:"not null;default:0" json:"claim_expiry_hours"`
+
+	// 重复检测关键变量（JSON数组），新实例这些变量与运行中实例全部相同时标记为疑似重复
+	DuplicateKeyVariables string `gorm:"type:text" json:"duplicate_key_variables"`
+
+	// 灰度发布：作为最新已发布版本时，按变量条件或业务键比例接收新实例，其余实例仍使用上一个已发布版本
+	RolloutEnabled    bool   `gorm:"not null;default:false" json:"rollout_enabled"`
+	RolloutPercentage int    `gorm:"not null;default:0" json:"rollout_percentage"`
+	RolloutCondition  string `gorm:"type:varchar(500)" json:"rollout_condition"`
+
+	// 复杂度评分和检查结果（JSON），保存流程定义时计算
+	ComplexityScore  int    `gorm:"not null;default:0;index" json:"complexity_score"`
+	ComplexityReport string `gorm:"type:text" json:"-"`
 
 	// 关联关系
 	Creator   User              `gorm:"foreignKey:CreatedBy" json:"creator,omitempty"`
@@ -60,7 +93,7 @@ func (ProcessDefinition) TableName() string {
 // ProcessNode represents a node in the process definition
 type ProcessNode struct {
 	ID    string                 `json:"id"`
-	Type  string                 `json:"type"` // start, end, userTask, serviceTask, gateway
+	Type  string                 `json:"type"` // start, end, userTask, serviceTask, gateway, timer
 	Name  string                 `json:"name"`
 	X     float64                `json:"x"`
 	Y     float64                `json:"y"`
@@ -82,6 +115,28 @@ type ProcessDefinitionData struct {
 	Flows []ProcessFlow `json:"flows"`
 }
 
+// DisplayLabels maps engine statuses and node IDs to domain-specific display labels
+type DisplayLabels struct {
+	Statuses map[string]string `json:"statuses,omitempty"`
+	Nodes    map[string]string `json:"nodes,omitempty"`
+}
+
+// StatusLabel returns the display label for an engine status, or empty if unmapped
+func (l *DisplayLabels) StatusLabel(status string) string {
+	if l == nil {
+		return ""
+	}
+	return l.Statuses[status]
+}
+
+// NodeLabel returns the display label for a node ID, or empty if unmapped
+func (l *DisplayLabels) NodeLabel(nodeID string) string {
+	if l == nil {
+		return ""
+	}
+	return l.Nodes[nodeID]
+}
+
 // ProcessInstance represents a running instance of a process
 type ProcessInstance struct {
 	BaseModel
@@ -94,6 +149,36 @@ type ProcessInstance struct {
 	EndTime      *time.Time `gorm:"index" json:"end_time"`
 	StarterID    uint       `gorm:"not null;index" json:"starter_id"`
 
+	// 乐观锁版本号，每次更新实例时递增
+	Version int `gorm:"not null;default:1" json:"version"`
+
+	// 是否记录详细的执行跟踪，只有管理员启动实例时可以开启
+	TraceEnabled bool `gorm:"not null;default:false" json:"trace_enabled,omitempty"`
+
+	// 流程截止时间，任务截止时间按剩余关键路径从中分配
+	DueDate *time.Time `gorm:"index" json:"due_date"`
+
+	// 由调用活动启动的子实例记录父实例和父实例上的调用节点
+	ParentInstanceID *uint  `gorm:"index" json:"parent_instance_id,omitempty"`
+	ParentNodeID     string `gorm:"type:varchar(64)" json:"parent_node_id,omitempty"`
+
+	// 声明了访问上限的节点被进入的次数（JSON，节点ID到次数）
+	NodeVisits string `gorm:"type:text" json:"node_visits,omitempty"`
+
+	// 声明了循环的节点在本轮循环中已完成的次数（JSON，节点ID到次数），循环结束后清除
+	LoopCounters string `gorm:"type:text" json:"loop_counters,omitempty"`
+
+	// 取消时间和取消时关闭的任务、定时器、子实例（JSON），撤销期限内可以据此撤销取消
+	CancelledAt  *time.Time `gorm:"index" json:"cancelled_at,omitempty"`
+	Cancellation string     `gorm:"type:text" json:"-"`
+
+	// 展示标签（不持久化，根据流程定义的标签映射填充）
+	StatusLabel      string `gorm:"-" json:"status_label,omitempty"`
+	CurrentNodeLabel string `gorm:"-" json:"current_node_label,omitempty"`
+
+	// 变量是否已按数据分级脱敏（不持久化）
+	VariablesMasked bool `gorm:"-" json:"variables_masked,omitempty"`
+
 	// 关联关系
 	Definition ProcessDefinition `gorm:"foreignKey:DefinitionID" json:"definition,omitempty"`
 	Starter    User              `gorm:"foreignKey:StarterID" json:"starter,omitempty"`
@@ -119,9 +204,50 @@ type TaskInstance struct {
 	CompleteTime *time.Time `json:"complete_time"`
 	Comment      string     `gorm:"type:text" json:"comment"`
 
+	// 办理人保存或提交的表单数据（JSON对象），与处理意见分开保存
+	FormData string `gorm:"type:text" json:"form_data,omitempty"`
+
+	// 任务的处理结果，驳回到上一步时为 rejected，正常完成时为空
+	Outcome string `gorm:"type:varchar(20)" json:"outcome,omitempty"`
+
+	// 系统按自动处理规则完成或跳过任务时记录规则引用（节点ID#规则ID）
+	AutoRule string `gorm:"type:varchar(255)" json:"auto_rule,omitempty"`
+
+	// 委派前的处理人（任务所有人）和委派状态：pending 表示受托人处理中，需要归还给所有人后由所有人完成；
+	// resolved 表示受托人已归还，再次委派时所有人保持不变
+	OwnerID         *uint  `gorm:"index" json:"owner_id,omitempty"`
+	DelegationState string `gorm:"type:varchar(20)" json:"delegation_state,omitempty"`
+
+	// 认领超时被自动释放的次数
+	ClaimExpiries int `gorm:"not null;default:0" json:"claim_expiries"`
+
+	// 超期升级的次数和最近一次升级时间，每个截止时间只升级一次；
+	// 升级到用户组时记录组编码，任务回到任务池由组内成员认领
+	EscalationLevel int        `gorm:"not null;default:0" json:"escalation_level"`
+	EscalatedAt     *time.Time `json:"escalated_at,omitempty"`
+	EscalationGroup string     `gorm:"type:varchar(64)" json:"escalation_group,omitempty"`
+
+	// 候选角色和候选用户ID（JSON数组），未分配时只有候选人可以从任务池认领，都为空时所有用户可以认领
+	CandidateRoles string `gorm:"type:text" json:"candidate_roles,omitempty"`
+	CandidateUsers string `gorm:"type:text" json:"candidate_users,omitempty"`
+
+	// 外部任务的主题、剩余重试次数，以及锁定任务的外部处理程序和锁的到期时间；
+	// 锁过期前只有该处理程序可以完成任务，报告失败后在锁到期时间之前不会再被获取
+	Topic         string     `gorm:"type:varchar(128);index" json:"topic,omitempty"`
+	Retries       int        `gorm:"not null;default:0" json:"retries,omitempty"`
+	WorkerID      string     `gorm:"type:varchar(128)" json:"worker_id,omitempty"`
+	LockExpiresAt *time.Time `gorm:"index" json:"lock_expires_at,omitempty"`
+
+	// 展示标签（不持久化，根据流程定义的标签映射填充）
+	StatusLabel string `gorm:"-" json:"status_label,omitempty"`
+	NodeLabel   string `gorm:"-" json:"node_label,omitempty"`
+
 	// 关联关系
 	Instance ProcessInstance `gorm:"foreignKey:InstanceID" json:"instance,omitempty"`
 	Assignee *User           `gorm:"foreignKey:AssigneeID" json:"assignee,omitempty"`
+
+	// 服务任务的执行记录，仅在任务详情中返回
+	ExecutionLogs []ExecutionLog `gorm:"foreignKey:TaskID" json:"execution_logs,omitempty"`
 }
 
 // TableName returns the table name for TaskInstance model
@@ -138,16 +264,54 @@ func (p *ProcessDefinition) GetDefinitionData() (*ProcessDefinitionData, error)
 	return &data, nil
 }
 
-// SetDefinitionData sets the process definition from ProcessDefinitionData
+// SetDefinitionData sets the process definition from ProcessDefinitionData and records its complexity
 func (p *ProcessDefinition) SetDefinitionData(data *ProcessDefinitionData) error {
 	jsonData, err := json.Marshal(data)
 	if err != nil {
 		return err
 	}
 	p.DefinitionJSON = string(jsonData)
+
+	complexity := data.Complexity()
+	report, err := json.Marshal(complexity)
+	if err != nil {
+		return err
+	}
+	p.ComplexityScore = complexity.Score
+	p.ComplexityReport = string(report)
 	return nil
 }
 
+// GetDisplayLabels parses the display label mapping, returning an empty mapping if unset
+func (p *ProcessDefinition) GetDisplayLabels() (*DisplayLabels, error) {
+	labels := &DisplayLabels{}
+	if p.DisplayLabels == "" {
+		return labels, nil
+	}
+	if err := json.Unmarshal([]byte(p.DisplayLabels), labels); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// SetDisplayLabels sets the display label mapping
+func (p *ProcessDefinition) SetDisplayLabels(labels *DisplayLabels) error {
+	jsonData, err := json.Marshal(labels)
+	if err != nil {
+		return err
+	}
+	p.DisplayLabels = string(jsonData)
+	return nil
+}
+
+// RolloutBucket maps a business key to a stable bucket in [0, 100), so a
+// business key is always routed to the same version during a rollout
+func RolloutBucket(businessKey string) int {
+	h := fnv.New32a()
+	h.Write([]byte(businessKey))
+	return int(h.Sum32() % 100)
+}
+
 // IsLatestVersion checks if this is the latest version of the process
 func (p *ProcessDefinition) IsLatestVersion() bool {
 	// This would need to be implemented with a repository query
@@ -169,7 +333,9 @@ func (p *ProcessDefinition) CanDelete() bool {
 const (
 	ProcessStatusDraft     = "draft"
 	ProcessStatusPublished = "published"
-	ProcessStatusArchived  = "archived"
+	// ProcessStatusDeprecated versions can no longer start instances; running instances continue
+	ProcessStatusDeprecated = "deprecated"
+	ProcessStatusArchived   = "archived"
 )
 
 // ProcessNodeType constants
@@ -179,6 +345,21 @@ const (
 	NodeTypeUserTask    = "userTask"
 	NodeTypeServiceTask = "serviceTask"
 	NodeTypeGateway     = "gateway"
+	NodeTypeTimer       = "timer"
+	// NodeTypeCallActivity starts a child instance of another published process and waits for it to complete
+	NodeTypeCallActivity = "callActivity"
+
+	// NodeTypeParallelReview is a composite node: parallel review tasks followed by a consolidation task
+	NodeTypeParallelReview = "parallelReview"
+
+	// NodeTypeScriptTask runs a script against the process variables and continues immediately
+	NodeTypeScriptTask = "scriptTask"
+
+	// NodeTypeMailTask renders an email from its props and the process variables, sends it and continues
+	NodeTypeMailTask = "mailTask"
+
+	// NodeTypeMessageCatch parks the instance until a message with a matching correlation key is delivered
+	NodeTypeMessageCatch = "messageCatch"
 )
 
 // 注意：状态常量已在文件开头定义，这里删除重复定义
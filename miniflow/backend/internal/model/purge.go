@@ -0,0 +1,86 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// PurgeCertificate 流程实例清除凭证，证明实例及其关联数据已被删除或匿名化
+// 凭证不保存业务数据，业务键只保存摘要，供审计核对
+type PurgeCertificate struct {
+	BaseModel
+	InstanceID        uint       `gorm:"not null;index" json:"instance_id"`
+	ParentInstanceID  *uint      `gorm:"index" json:"parent_instance_id,omitempty"`
+	DefinitionID      uint       `gorm:"not null" json:"definition_id"`
+	DefinitionKey     string     `gorm:"type:varchar(100)" json:"definition_key"`
+	DefinitionVersion int        `json:"definition_version"`
+	FinalStatus       string     `gorm:"type:varchar(20);not null" json:"final_status"`
+	BusinessKeyHash   string     `gorm:"type:varchar(64)" json:"business_key_hash"`
+	StartTime         time.Time  `json:"start_time"`
+	EndTime           *time.Time `json:"end_time"`
+	PurgedBy          uint       `gorm:"not null;index" json:"purged_by"`
+	PurgedAt          time.Time  `gorm:"not null;index" json:"purged_at"`
+	Reason            string     `gorm:"type:text" json:"reason"`
+	// Rows 各表删除或匿名化的行数，JSON 对象
+	Rows   string `gorm:"type:text" json:"rows"`
+	Digest string `gorm:"type:varchar(64);not null" json:"digest"`
+}
+
+// TableName returns the table name for PurgeCertificate model
+func (PurgeCertificate) TableName() string {
+	return "purge_certificates"
+}
+
+// NewPurgeCertificate builds the certificate for a purged instance and seals it with a digest
+func NewPurgeCertificate(instance *ProcessInstance, purgedBy uint, reason string, rows map[string]int64, now time.Time) *PurgeCertificate {
+	rowsJSON, _ := json.Marshal(rows)
+	keyHash := sha256.Sum256([]byte(instance.BusinessKey))
+
+	cert := &PurgeCertificate{
+		InstanceID:        instance.ID,
+		ParentInstanceID:  instance.ParentInstanceID,
+		DefinitionID:      instance.DefinitionID,
+		DefinitionKey:     instance.Definition.Key,
+		DefinitionVersion: instance.Definition.Version,
+		FinalStatus:       instance.Status,
+		BusinessKeyHash:   hex.EncodeToString(keyHash[:]),
+		StartTime:         instance.StartTime,
+		EndTime:           instance.EndTime,
+		PurgedBy:          purgedBy,
+		PurgedAt:          now,
+		Reason:            reason,
+		Rows:              string(rowsJSON),
+	}
+	cert.Digest = cert.ComputeDigest()
+	return cert
+}
+
+// ComputeDigest returns the SHA-256 digest over the certificate fields, used to detect tampering
+func (c *PurgeCertificate) ComputeDigest() string {
+	content := fmt.Sprintf("%d|%d|%s|%d|%s|%s|%d|%s|%s|%s",
+		c.InstanceID,
+		c.DefinitionID,
+		c.DefinitionKey,
+		c.DefinitionVersion,
+		c.FinalStatus,
+		c.BusinessKeyHash,
+		c.PurgedBy,
+		c.PurgedAt.UTC().Format(time.RFC3339Nano),
+		c.Reason,
+		c.Rows,
+	)
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsTerminalInstanceStatus reports whether an instance in the status will not change any more
+func IsTerminalInstanceStatus(status string) bool {
+	switch status {
+	case InstanceStatusCompleted, InstanceStatusCancelled, InstanceStatusFailed:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package model
+
+import "time"
+
+// 报表星型模型（只读，供BI工具查询）
+//
+// 这些表由定时任务从业务表全量重建，BI工具只应查询 rpt_ 前缀的表，
+// 避免直接访问高频读写的业务表。流程变量不会同步到报表表中。
+
+// ReportDimDefinition 流程定义维度
+type ReportDimDefinition struct {
+	DefinitionID       uint      `gorm:"primaryKey;autoIncrement:false" json:"definition_id"`
+	Key                string    `gorm:"column:key;type:varchar(100);not null;index" json:"key"`
+	Name               string    `gorm:"type:varchar(255);not null" json:"name"`
+	Version            int       `gorm:"not null" json:"version"`
+	Category           string    `gorm:"type:varchar(50)" json:"category"`
+	Status             string    `gorm:"type:varchar(20);not null" json:"status"`
+	DataClassification string    `gorm:"type:varchar(20);not null" json:"data_classification"`
+	RefreshedAt        time.Time `gorm:"not null" json:"refreshed_at"`
+}
+
+// TableName returns the table name for ReportDimDefinition model
+func (ReportDimDefinition) TableName() string {
+	return "rpt_dim_definition"
+}
+
+// ReportDimUser 用户维度，角色列同时作为用户分组使用
+type ReportDimUser struct {
+	UserID      uint      `gorm:"primaryKey;autoIncrement:false" json:"user_id"`
+	Username    string    `gorm:"type:varchar(100);not null" json:"username"`
+	DisplayName string    `gorm:"type:varchar(255)" json:"display_name"`
+	Role        string    `gorm:"type:varchar(50);not null;index" json:"role"`
+	Status      string    `gorm:"type:varchar(20);not null" json:"status"`
+	RefreshedAt time.Time `gorm:"not null" json:"refreshed_at"`
+}
+
+// TableName returns the table name for ReportDimUser model
+func (ReportDimUser) TableName() string {
+	return "rpt_dim_user"
+}
+
+// ReportDimDate 日期维度，主键为 yyyymmdd 格式的整数
+type ReportDimDate struct {
+	DateKey   int       `gorm:"primaryKey;autoIncrement:false" json:"date_key"`
+	Date      time.Time `gorm:"type:date;not null;uniqueIndex" json:"date"`
+	Year      int       `gorm:"not null;index" json:"year"`
+	Quarter   int       `gorm:"not null" json:"quarter"`
+	Month     int       `gorm:"not null" json:"month"`
+	Day       int       `gorm:"not null" json:"day"`
+	Weekday   int       `gorm:"not null" json:"weekday"`
+	IsWeekend bool      `gorm:"not null" json:"is_weekend"`
+}
+
+// TableName returns the table name for ReportDimDate model
+func (ReportDimDate) TableName() string {
+	return "rpt_dim_date"
+}
+
+// ReportFactInstance 流程实例事实表，每个流程实例一行
+type ReportFactInstance struct {
+	InstanceID      uint      `gorm:"primaryKey;autoIncrement:false" json:"instance_id"`
+	DefinitionID    uint      `gorm:"not null;index" json:"definition_id"`
+	StarterID       uint      `gorm:"not null;index" json:"starter_id"`
+	StartDateKey    int       `gorm:"not null;index" json:"start_date_key"`
+	EndDateKey      *int      `gorm:"index" json:"end_date_key"`
+	Status          string    `gorm:"type:varchar(20);not null;index" json:"status"`
+	DurationSeconds *int64    `json:"duration_seconds"`
+	TaskCount       int       `gorm:"not null" json:"task_count"`
+	IncidentCount   int       `gorm:"not null" json:"incident_count"`
+	RefreshedAt     time.Time `gorm:"not null" json:"refreshed_at"`
+}
+
+// TableName returns the table name for ReportFactInstance model
+func (ReportFactInstance) TableName() string {
+	return "rpt_fact_instance"
+}
+
+// ReportFactTask 任务事实表，每个任务实例一行
+type ReportFactTask struct {
+	TaskID           uint      `gorm:"primaryKey;autoIncrement:false" json:"task_id"`
+	InstanceID       uint      `gorm:"not null;index" json:"instance_id"`
+	DefinitionID     uint      `gorm:"not null;index" json:"definition_id"`
+	AssigneeID       *uint     `gorm:"index" json:"assignee_id"`
+	NodeID           string    `gorm:"type:varchar(64);not null" json:"node_id"`
+	Status           string    `gorm:"type:varchar(20);not null;index" json:"status"`
+	Priority         int       `gorm:"not null" json:"priority"`
+	CreatedDateKey   int       `gorm:"not null;index" json:"created_date_key"`
+	CompletedDateKey *int      `gorm:"index" json:"completed_date_key"`
+	WaitSeconds      *int64    `json:"wait_seconds"`
+	HandleSeconds    *int64    `json:"handle_seconds"`
+	Overdue          bool      `gorm:"not null" json:"overdue"`
+	ClaimExpiries    int       `gorm:"not null" json:"claim_expiries"`
+	RefreshedAt      time.Time `gorm:"not null" json:"refreshed_at"`
+}
+
+// TableName returns the table name for ReportFactTask model
+func (ReportFactTask) TableName() string {
+	return "rpt_fact_task"
+}
+
+// NewReportDimDate builds the date dimension row for the given day
+func NewReportDimDate(day time.Time) ReportDimDate {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	weekday := int(day.Weekday())
+	return ReportDimDate{
+		DateKey:   day.Year()*10000 + int(day.Month())*100 + day.Day(),
+		Date:      day,
+		Year:      day.Year(),
+		Quarter:   (int(day.Month())-1)/3 + 1,
+		Month:     int(day.Month()),
+		Day:       day.Day(),
+		Weekday:   weekday,
+		IsWeekend: weekday == 0 || weekday == 6,
+	}
+}
@@ -0,0 +1,73 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// RuntimeSnapshotFormat 运行时快照的格式版本，格式不兼容时递增
+const RuntimeSnapshotFormat = 1
+
+// 快照序列标记的表名
+const (
+	SnapshotSeqInstances     = "process_instances"
+	SnapshotSeqTasks         = "task_instances"
+	SnapshotSeqArrivals      = "gateway_arrivals"
+	SnapshotSeqTimers        = "process_timers"
+	SnapshotSeqWebhooks      = "webhook_deliveries"
+	SnapshotSeqNotifications = "notification_queue"
+	SnapshotSeqTaskEvents    = "task_events"
+	SnapshotSeqMessages      = "message_subscriptions"
+)
+
+// RuntimeSnapshot 运行时状态快照，用于灾备演练时在备用环境恢复运行中的流程
+//
+// 快照在同一个只读事务中导出，各部分相互一致：未结束的实例及其任务和汇聚令牌、
+// 等待中的定时器和消息订阅、未投递的回调和通知（发件箱）。流程定义和用户不在快照中，
+// 备用环境需要先通过数据库备份或部署恢复。
+// Sequences 记录导出时各表的最大ID，导入后可据此核对备用环境的数据不早于快照，
+// 任务变更订阅方从 task_events 标记重新开始拉取。
+type RuntimeSnapshot struct {
+	Format     int             `json:"format"`
+	ExportedAt time.Time       `json:"exported_at"`
+	Sequences  map[string]uint `json:"sequences"`
+
+	Instances       []ProcessInstance       `json:"instances"`
+	Tasks           []TaskInstance          `json:"tasks"`
+	GatewayArrivals []GatewayArrival        `json:"gateway_arrivals"`
+	Timers          []ProcessTimer          `json:"timers"`
+	Webhooks        []WebhookDelivery       `json:"webhooks"`
+	Notifications   []NotificationQueueItem `json:"notifications"`
+	// Messages 等待中的消息订阅，较早格式的快照没有这一部分
+	Messages []MessageSubscription `json:"messages,omitempty"`
+
+	// Digest 快照内容的摘要，导入时校验快照未被截断或修改
+	Digest string `json:"digest"`
+}
+
+// ComputeDigest returns the SHA-256 digest of the snapshot content without the digest itself
+func (s *RuntimeSnapshot) ComputeDigest() (string, error) {
+	content := *s
+	content.Digest = ""
+	data, err := json.Marshal(&content)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// RowCounts returns the number of rows in each part of the snapshot
+func (s *RuntimeSnapshot) RowCounts() map[string]int {
+	return map[string]int{
+		SnapshotSeqInstances:     len(s.Instances),
+		SnapshotSeqTasks:         len(s.Tasks),
+		SnapshotSeqArrivals:      len(s.GatewayArrivals),
+		SnapshotSeqTimers:        len(s.Timers),
+		SnapshotSeqWebhooks:      len(s.Webhooks),
+		SnapshotSeqNotifications: len(s.Notifications),
+		SnapshotSeqMessages:      len(s.Messages),
+	}
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// SchemaMigration records a versioned schema migration applied to the database.
+// The table is owned by the migration runner and is not part of AllModels.
+type SchemaMigration struct {
+	ID          string    `gorm:"primaryKey;type:varchar(64)" json:"id"`
+	Description string    `gorm:"type:varchar(255)" json:"description"`
+	AppliedAt   time.Time `gorm:"not null" json:"applied_at"`
+}
+
+// TableName returns the table name for SchemaMigration model
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"errors"
+	"strings"
+)
+
+// 脚本任务支持的脚本语言，只有内置运行时的语言可以通过校验
+const (
+	// ScriptLanguageExpression 内置的赋值脚本，每行一条 name = 表达式
+	ScriptLanguageExpression = "expression"
+	// ScriptLanguageJavaScript ECMAScript 5.1 和大部分 ES6 语法，由 goja 执行
+	ScriptLanguageJavaScript = "javascript"
+	// ScriptLanguageLua Lua 5.1，由 gopher-lua 执行
+	ScriptLanguageLua = "lua"
+)
+
+// ScriptTaskConfig 脚本任务节点配置
+type ScriptTaskConfig struct {
+	Language string
+	Script   string
+	// TimeoutSeconds 为 0 时使用配置的默认超时
+	TimeoutSeconds int
+}
+
+// GetScriptTaskConfig reads the configuration of a script task node from the
+// "language" (expression, javascript or lua; default expression), "script" and optional "timeoutSeconds" props.
+func GetScriptTaskConfig(node *ProcessNode) (*ScriptTaskConfig, error) {
+	cfg := &ScriptTaskConfig{Language: ScriptLanguageExpression}
+	if value, ok := node.Props["language"].(string); ok && strings.TrimSpace(value) != "" {
+		cfg.Language = strings.ToLower(strings.TrimSpace(value))
+	}
+	if err := ScriptLanguages.Validate(cfg.Language); err != nil {
+		return nil, err
+	}
+
+	cfg.Script, _ = node.Props["script"].(string)
+	if strings.TrimSpace(cfg.Script) == "" {
+		return nil, errors.New("script is required")
+	}
+
+	if raw, ok := node.Props["timeoutSeconds"]; ok && raw != nil {
+		timeout, isNumber := raw.(float64)
+		if !isNumber || timeout < 1 || timeout != float64(int(timeout)) {
+			return nil, errors.New("timeoutSeconds must be a positive integer")
+		}
+		cfg.TimeoutSeconds = int(timeout)
+	}
+	return cfg, nil
+}
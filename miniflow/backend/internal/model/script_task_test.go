@@ -0,0 +1,39 @@
+package model
+
+import "testing"
+
+func TestGetScriptTaskConfigLanguages(t *testing.T) {
+	tests := []struct {
+		language string
+		want     string
+		wantErr  bool
+	}{
+		{language: "", want: ScriptLanguageExpression},
+		{language: " Expression ", want: ScriptLanguageExpression},
+		{language: "JavaScript", want: ScriptLanguageJavaScript},
+		{language: "lua", want: ScriptLanguageLua},
+		// 没有内置运行时的语言在校验时拒绝，而不是发布后运行失败
+		{language: "python", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.language, func(t *testing.T) {
+			node := &ProcessNode{ID: "script", Type: NodeTypeScriptTask, Props: map[string]interface{}{
+				"language": tt.language,
+				"script":   "total = 1",
+			}}
+			cfg, err := GetScriptTaskConfig(node)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("language %q was accepted", tt.language)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("language %q: %v", tt.language, err)
+			}
+			if cfg.Language != tt.want {
+				t.Fatalf("language = %q, want %q", cfg.Language, tt.want)
+			}
+		})
+	}
+}
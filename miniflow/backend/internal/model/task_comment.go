@@ -0,0 +1,16 @@
+package model
+
+// TaskComment 任务讨论区中的一条评论
+// InstanceID 冗余保存，用于在实例历史中按实例读取评论和清除实例数据
+type TaskComment struct {
+	BaseModel
+	TaskID     uint   `gorm:"not null;index" json:"task_id"`
+	InstanceID uint   `gorm:"not null;index" json:"instance_id"`
+	UserID     uint   `gorm:"not null;index" json:"user_id"`
+	Body       string `gorm:"type:text;not null" json:"body"`
+}
+
+// TableName returns the table name for TaskComment model
+func (TaskComment) TableName() string {
+	return "task_comments"
+}
@@ -0,0 +1,47 @@
+package model
+
+// 任务变更事件类型常量
+const (
+	TaskEventCreated    = "created"
+	TaskEventAssigned   = "assigned"
+	TaskEventUnassigned = "unassigned"
+	TaskEventClaimed    = "claimed"
+	TaskEventReleased   = "released"
+	TaskEventCompleted  = "completed"
+	TaskEventRemoved    = "removed"
+	TaskEventUpdated    = "updated"
+)
+
+// TaskEvent 任务变更事件日志，ID 单调递增，作为客户端增量同步的游标
+// UserID 为受影响的处理人，为空表示任务池中的未分配任务
+type TaskEvent struct {
+	BaseModel
+	TaskID     uint   `gorm:"not null;index" json:"task_id"`
+	InstanceID uint   `gorm:"not null;index" json:"instance_id"`
+	UserID     *uint  `gorm:"index" json:"user_id"`
+	Type       string `gorm:"type:varchar(20);not null" json:"type"`
+	Status     string `gorm:"type:varchar(20);not null" json:"status"`
+}
+
+// TableName returns the table name for TaskEvent model
+func (TaskEvent) TableName() string {
+	return "task_events"
+}
+
+// TaskEventTypeForStatus maps a task status after an update to the change reported to clients
+func TaskEventTypeForStatus(status string) string {
+	switch status {
+	case TaskStatusCreated:
+		return TaskEventCreated
+	case TaskStatusAssigned:
+		return TaskEventAssigned
+	case TaskStatusClaimed, TaskStatusInProgress:
+		return TaskEventClaimed
+	case TaskStatusCompleted:
+		return TaskEventCompleted
+	case TaskStatusSkipped, TaskStatusFailed, TaskStatusEscalated:
+		return TaskEventRemoved
+	default:
+		return TaskEventUpdated
+	}
+}
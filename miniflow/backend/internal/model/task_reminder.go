@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// TaskReminder 已发送的任务到期提醒
+// 同一任务、同一到期时间的每个提醒时间点只发送一次，修改到期时间后重新提醒
+type TaskReminder struct {
+	BaseModel
+	InstanceID    uint      `gorm:"not null;index" json:"instance_id"`
+	TaskID        uint      `gorm:"not null;uniqueIndex:idx_task_reminder" json:"task_id"`
+	DueDate       time.Time `gorm:"not null;uniqueIndex:idx_task_reminder" json:"due_date"`
+	OffsetSeconds int64     `gorm:"not null;uniqueIndex:idx_task_reminder" json:"offset_seconds"`
+}
+
+// TableName returns the table name for TaskReminder model
+func (TaskReminder) TableName() string {
+	return "task_reminders"
+}
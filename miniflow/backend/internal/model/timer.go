@@ -0,0 +1,120 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"miniflow/pkg/utils"
+)
+
+// 定时器状态常量
+const (
+	TimerStatusWaiting   = "waiting"
+	TimerStatusFired     = "fired"
+	TimerStatusCancelled = "cancelled"
+	// TimerStatusFailed 定时器已触发但流程推进失败，可由运维重试
+	TimerStatusFailed = "failed"
+)
+
+// 定时器类型常量
+const (
+	// TimerKindIntermediate 定时器节点：实例在节点上等待，到期后沿出口连线推进
+	TimerKindIntermediate = "intermediate"
+	// TimerKindBoundary 用户任务上的边界定时器：到期时任务仍未完成则跳过任务，沿指定连线推进
+	TimerKindBoundary = "boundary"
+)
+
+// ProcessTimer 等待到期的流程定时器，由后台调度器触发
+type ProcessTimer struct {
+	BaseModel
+	InstanceID uint       `gorm:"not null;index" json:"instance_id"`
+	NodeID     string     `gorm:"type:varchar(64);not null" json:"node_id"`
+	Kind       string     `gorm:"type:varchar(20);not null" json:"kind"`
+	FlowID     string     `gorm:"type:varchar(64)" json:"flow_id,omitempty"`
+	DueAt      time.Time  `gorm:"not null;index:idx_timer_due,priority:2" json:"due_at"`
+	Status     string     `gorm:"type:varchar(20);not null;default:waiting;index:idx_timer_due,priority:1" json:"status"`
+	FiredAt    *time.Time `json:"fired_at"`
+	LastError  string     `gorm:"type:text" json:"last_error,omitempty"`
+}
+
+// TableName returns the table name for ProcessTimer model
+func (ProcessTimer) TableName() string {
+	return "process_timers"
+}
+
+// TimerSpec describes when a timer fires: after an ISO 8601 duration or at a fixed date
+type TimerSpec struct {
+	Duration utils.ISODuration
+	Date     *time.Time
+}
+
+// DueAt returns the time the timer fires when started at from
+func (s *TimerSpec) DueAt(from time.Time) time.Time {
+	if s.Date != nil {
+		return *s.Date
+	}
+	return s.Duration.AddTo(from)
+}
+
+// BoundaryTimer is a timer attached to a user task; when it fires the
+// unfinished task is skipped and the process continues along Flow
+type BoundaryTimer struct {
+	TimerSpec
+	Flow string
+}
+
+// GetTimerSpec parses the "duration" (ISO 8601, e.g. PT2H) or "date" (RFC 3339) prop of a timer node
+func GetTimerSpec(node *ProcessNode) (*TimerSpec, error) {
+	return parseTimerSpec(node.Props)
+}
+
+// GetBoundaryTimer parses the "boundaryTimer" prop of a user task, returning nil when unset.
+// The prop holds a duration or date plus the ID of the outgoing flow taken on timeout.
+func GetBoundaryTimer(node *ProcessNode) (*BoundaryTimer, error) {
+	raw, ok := node.Props["boundaryTimer"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	props, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("boundaryTimer must be an object")
+	}
+
+	spec, err := parseTimerSpec(props)
+	if err != nil {
+		return nil, err
+	}
+	flow, _ := props["flow"].(string)
+	if flow == "" {
+		return nil, errors.New("boundaryTimer.flow is required")
+	}
+	return &BoundaryTimer{TimerSpec: *spec, Flow: flow}, nil
+}
+
+// parseTimerSpec reads exactly one of "duration" and "date"
+func parseTimerSpec(props map[string]interface{}) (*TimerSpec, error) {
+	duration, _ := props["duration"].(string)
+	date, _ := props["date"].(string)
+
+	switch {
+	case duration != "" && date != "":
+		return nil, errors.New("only one of duration and date may be set")
+	case duration != "":
+		parsed, err := utils.ParseISODuration(duration)
+		if err != nil {
+			return nil, err
+		}
+		if parsed.IsZero() {
+			return nil, errors.New("duration must be greater than zero")
+		}
+		return &TimerSpec{Duration: parsed}, nil
+	case date != "":
+		parsed, err := time.Parse(time.RFC3339, date)
+		if err != nil {
+			return nil, fmt.Errorf("date must be RFC 3339, e.g. 2025-01-31T09:00:00+08:00: %v", err)
+		}
+		return &TimerSpec{Date: &parsed}, nil
+	}
+	return nil, errors.New("duration or date is required")
+}
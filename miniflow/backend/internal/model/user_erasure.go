@@ -0,0 +1,82 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// TombstoneUsername is the username of the shared placeholder account that
+// takes over the historical records of erased users
+const TombstoneUsername = "deleted_user"
+
+// UserStatusErased marks the tombstone account, which can never log in
+const UserStatusErased = "erased"
+
+// NewTombstoneUser returns the placeholder account; the password is not a
+// bcrypt hash, so no password can match it
+func NewTombstoneUser() *User {
+	return &User{
+		Username:    TombstoneUsername,
+		Password:    "!",
+		DisplayName: "已删除用户",
+		Email:       TombstoneUsername + "@invalid",
+		Role:        "user",
+		Status:      UserStatusErased,
+	}
+}
+
+// UserErasure 用户数据删除记录，证明用户的个人数据已被删除、历史记录已转给占位账户
+// 记录不保存个人数据，用户名只保存摘要，供审计核对
+type UserErasure struct {
+	BaseModel
+	UserID       uint      `gorm:"not null;index" json:"user_id"`
+	UsernameHash string    `gorm:"type:varchar(64);not null" json:"username_hash"`
+	TombstoneID  uint      `gorm:"not null" json:"tombstone_id"`
+	ErasedBy     uint      `gorm:"not null;index" json:"erased_by"`
+	ErasedAt     time.Time `gorm:"not null;index" json:"erased_at"`
+	Reason       string    `gorm:"type:text" json:"reason"`
+	// Rows 各表删除或转给占位账户的行数，JSON 对象
+	Rows   string `gorm:"type:text" json:"rows"`
+	Digest string `gorm:"type:varchar(64);not null" json:"digest"`
+}
+
+// TableName returns the table name for UserErasure model
+func (UserErasure) TableName() string {
+	return "user_erasures"
+}
+
+// NewUserErasure builds the record for an erased user and seals it with a digest
+func NewUserErasure(user *User, tombstoneID, erasedBy uint, reason string, rows map[string]int64, now time.Time) *UserErasure {
+	rowsJSON, _ := json.Marshal(rows)
+	nameHash := sha256.Sum256([]byte(user.Username))
+
+	erasure := &UserErasure{
+		UserID:       user.ID,
+		UsernameHash: hex.EncodeToString(nameHash[:]),
+		TombstoneID:  tombstoneID,
+		ErasedBy:     erasedBy,
+		ErasedAt:     now,
+		Reason:       reason,
+		Rows:         string(rowsJSON),
+	}
+	erasure.Digest = erasure.ComputeDigest()
+	return erasure
+}
+
+// ComputeDigest returns the SHA-256 digest over the record fields, used to detect tampering
+func (e *UserErasure) ComputeDigest() string {
+	content := fmt.Sprintf("%d|%s|%d|%d|%s|%s|%s",
+		e.UserID,
+		e.UsernameHash,
+		e.TombstoneID,
+		e.ErasedBy,
+		e.ErasedAt.UTC().Format(time.RFC3339Nano),
+		e.Reason,
+		e.Rows,
+	)
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
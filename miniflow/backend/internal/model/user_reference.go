@@ -0,0 +1,168 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+)
+
+// 流程定义中引用用户的位置
+const (
+	UserRefCreatedBy             = "created_by"              // 定义的创建人
+	UserRefAssignee              = "assignee"                // 用户任务的固定处理人
+	UserRefMultiInstanceAssignee = "multi_instance_assignee" // 会签处理人
+	UserRefCandidateUser         = "candidate_user"          // 用户任务的候选用户
+	UserRefReviewer              = "reviewer"                // 并行评审的评审人
+	UserRefReviewOwner           = "review_owner"            // 并行评审的汇总人
+)
+
+// UserReference 流程定义对某个具体用户的一处引用，UserID 和 Username 只有一个有值
+// 表达式、角色、用户组、部门负责人和流程变量不指向具体用户，不算作引用
+type UserReference struct {
+	NodeID   string `json:"node_id,omitempty"`
+	NodeName string `json:"node_name,omitempty"`
+	Field    string `json:"field"`
+	UserID   uint   `json:"user_id,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+// UserRemap 用户引用的替换关系
+type UserRemap struct {
+	From User
+	To   User
+}
+
+// UserReferences returns the references to concrete users in the node configuration.
+// Values that fail to parse are skipped; definition validation reports them.
+func (d *ProcessDefinitionData) UserReferences() []UserReference {
+	var refs []UserReference
+	for i := range d.Nodes {
+		node := &d.Nodes[i]
+		add := func(field string, value interface{}) {
+			if spec := parseUserReference(value); spec != nil {
+				refs = append(refs, UserReference{
+					NodeID:   node.ID,
+					NodeName: node.Name,
+					Field:    field,
+					UserID:   spec.UserID,
+					Username: spec.Username,
+				})
+			}
+		}
+
+		switch node.Type {
+		case NodeTypeUserTask:
+			if source := GetAssigneeExpression(node); source != "" {
+				add(UserRefAssignee, source)
+			}
+			if props, ok := node.Props["multiInstance"].(map[string]interface{}); ok {
+				if values, ok := props["assignees"].([]interface{}); ok {
+					for _, value := range values {
+						add(UserRefMultiInstanceAssignee, value)
+					}
+				}
+			}
+			if values, ok := node.Props["candidateUsers"].([]interface{}); ok {
+				for _, value := range values {
+					add(UserRefCandidateUser, value)
+				}
+			}
+		case NodeTypeParallelReview:
+			if values, ok := node.Props["reviewers"].([]interface{}); ok {
+				for _, value := range values {
+					add(UserRefReviewer, value)
+				}
+			}
+			if value, ok := node.Props["ownerId"]; ok && value != nil {
+				add(UserRefReviewOwner, value)
+			}
+		}
+	}
+	return refs
+}
+
+// RemapUserReferences replaces references to the From users with the To users, keeping the
+// original form of each value (number, "user:<id>" or "username:<name>"), and returns the
+// replaced references as they were before the change.
+func (d *ProcessDefinitionData) RemapUserReferences(remaps []UserRemap) []UserReference {
+	var replaced []UserReference
+	for i := range d.Nodes {
+		node := &d.Nodes[i]
+		remap := func(field string, value interface{}) interface{} {
+			spec := parseUserReference(value)
+			if spec == nil {
+				return value
+			}
+			for _, m := range remaps {
+				if (spec.UserID != 0 && spec.UserID == m.From.ID) || (spec.Username != "" && spec.Username == m.From.Username) {
+					replaced = append(replaced, UserReference{
+						NodeID:   node.ID,
+						NodeName: node.Name,
+						Field:    field,
+						UserID:   spec.UserID,
+						Username: spec.Username,
+					})
+					return replaceUserValue(value, &m.To)
+				}
+			}
+			return value
+		}
+		remapList := func(field string, values []interface{}) {
+			for j, value := range values {
+				values[j] = remap(field, value)
+			}
+		}
+
+		switch node.Type {
+		case NodeTypeUserTask:
+			if source := GetAssigneeExpression(node); source != "" {
+				node.Props["assignee"] = remap(UserRefAssignee, source)
+			}
+			if props, ok := node.Props["multiInstance"].(map[string]interface{}); ok {
+				if values, ok := props["assignees"].([]interface{}); ok {
+					remapList(UserRefMultiInstanceAssignee, values)
+				}
+			}
+			if values, ok := node.Props["candidateUsers"].([]interface{}); ok {
+				remapList(UserRefCandidateUser, values)
+			}
+		case NodeTypeParallelReview:
+			if values, ok := node.Props["reviewers"].([]interface{}); ok {
+				remapList(UserRefReviewer, values)
+			}
+			if value, ok := node.Props["ownerId"]; ok && value != nil {
+				node.Props["ownerId"] = remap(UserRefReviewOwner, value)
+			}
+		}
+	}
+	return replaced
+}
+
+// parseUserReference returns the user a configured value points to, or nil for
+// templates, roles, groups, departments and values that cannot be parsed
+func parseUserReference(value interface{}) *AssigneeSpec {
+	if text, ok := value.(string); ok && strings.Contains(text, "${") {
+		return nil
+	}
+	spec, err := ParseAssigneeSpec(value)
+	if err != nil || !spec.IsUser() {
+		return nil
+	}
+	return spec
+}
+
+// replaceUserValue returns a value pointing to user in the same form as value
+func replaceUserValue(value interface{}, user *User) interface{} {
+	text, ok := value.(string)
+	if !ok {
+		return float64(user.ID)
+	}
+	text = strings.TrimSpace(text)
+	switch {
+	case strings.HasPrefix(text, AssigneePrefixUser):
+		return AssigneePrefixUser + strconv.FormatUint(uint64(user.ID), 10)
+	case strings.HasPrefix(text, AssigneePrefixUsername):
+		return AssigneePrefixUsername + user.Username
+	default:
+		return strconv.FormatUint(uint64(user.ID), 10)
+	}
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// VisitLimit caps how many times an instance may enter a user task, stopping
+// reject loops that send the work back and forth indefinitely
+type VisitLimit struct {
+	MaxVisits int
+	// Flow is the outgoing flow taken once the limit is exceeded; when empty an
+	// incident is raised and the instance waits at the node
+	Flow string
+}
+
+// GetVisitLimit parses the "visitLimit" prop of a user task, returning nil when unset.
+// The prop holds "maxVisits" and the optional ID of the escalation "flow".
+func GetVisitLimit(node *ProcessNode) (*VisitLimit, error) {
+	raw, ok := node.Props["visitLimit"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	props, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("visitLimit must be an object")
+	}
+
+	maxVisits, ok := props["maxVisits"].(float64)
+	if !ok || maxVisits < 1 || maxVisits != float64(int(maxVisits)) {
+		return nil, errors.New("visitLimit.maxVisits must be a positive integer")
+	}
+	flow, _ := props["flow"].(string)
+	return &VisitLimit{MaxVisits: int(maxVisits), Flow: flow}, nil
+}
+
+// GetNodeVisits decodes how many times each visit-limited node has been entered
+func (p *ProcessInstance) GetNodeVisits() map[string]int {
+	visits := make(map[string]int)
+	if p.NodeVisits != "" {
+		_ = json.Unmarshal([]byte(p.NodeVisits), &visits)
+	}
+	return visits
+}
+
+// SetNodeVisits encodes the per-node visit counts
+func (p *ProcessInstance) SetNodeVisits(visits map[string]int) error {
+	data, err := json.Marshal(visits)
+	if err != nil {
+		return err
+	}
+	p.NodeVisits = string(data)
+	return nil
+}
@@ -0,0 +1,38 @@
+package model
+
+// WebhookSubscription 外部系统订阅引擎事件的回调地址
+// 事件发生后向 URL 投递签名的 JSON 请求体，投递记录保存在 webhook_deliveries
+type WebhookSubscription struct {
+	BaseModel
+	URL string `gorm:"type:varchar(500);not null" json:"url"`
+	// EventTypes 订阅的事件类型，JSON 数组
+	EventTypes string `gorm:"type:text;not null" json:"-"`
+	Secret     string `gorm:"type:varchar(255)" json:"-"`
+	Active     bool   `gorm:"not null;default:true;index" json:"active"`
+	CreatedBy  uint   `gorm:"not null;index" json:"created_by"`
+}
+
+// TableName returns the table name for WebhookSubscription model
+func (WebhookSubscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// GetEventTypes parses the subscribed event types
+func (s *WebhookSubscription) GetEventTypes() []string {
+	return parseStringList(s.EventTypes)
+}
+
+// SetEventTypes sets the subscribed event types
+func (s *WebhookSubscription) SetEventTypes(eventTypes []string) {
+	s.EventTypes = formatStringList(eventTypes)
+}
+
+// Matches reports whether the subscription receives the event type
+func (s *WebhookSubscription) Matches(eventType string) bool {
+	for _, value := range s.GetEventTypes() {
+		if value == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/internal/repository"
+	"miniflow/pkg/config"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// Channel 通知投递渠道
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, user *model.User, msg *Message) error
+}
+
+// inAppChannel 站内通知渠道，通知写入用户的收件箱
+type inAppChannel struct {
+	repo   *repository.NotificationRepository
+	logger *logger.Logger
+}
+
+// Name 返回渠道名称
+func (c *inAppChannel) Name() string {
+	return model.NotificationChannelInApp
+}
+
+// Send 投递站内通知
+func (c *inAppChannel) Send(ctx context.Context, user *model.User, msg *Message) error {
+	if err := c.repo.CreateNotification(ctx, &model.Notification{
+		UserID:    user.ID,
+		EventType: msg.EventType,
+		Subject:   msg.Subject,
+		Body:      msg.Body,
+	}); err != nil {
+		return fmt.Errorf("写入站内通知失败: %v", err)
+	}
+
+	c.logger.Debug("In-app notification",
+		zap.Uint("user_id", user.ID),
+		zap.String("event_type", msg.EventType),
+		zap.String("subject", msg.Subject),
+	)
+	return nil
+}
+
+// emailChannel 邮件通知渠道
+type emailChannel struct {
+	cfg *config.EmailConfig
+}
+
+// Name 返回渠道名称
+func (c *emailChannel) Name() string {
+	return model.NotificationChannelEmail
+}
+
+// Send 发送邮件通知
+func (c *emailChannel) Send(_ context.Context, user *model.User, msg *Message) error {
+	if user.Email == "" {
+		return errors.New("用户未设置邮箱")
+	}
+	return c.sendMail([]string{user.Email}, nil, msg.Subject, msg.Body)
+}
+
+// sendMail 通过SMTP发送纯文本邮件，抄送人同样作为收件人投递
+func (c *emailChannel) sendMail(to, cc []string, subject, body string) error {
+	var auth smtp.Auth
+	if c.cfg.Username != "" {
+		auth = smtp.PlainAuth("", c.cfg.Username, c.cfg.Password, c.cfg.SMTPHost)
+	}
+
+	headers := []string{
+		"From: " + c.cfg.From,
+		"To: " + strings.Join(to, ", "),
+	}
+	if len(cc) > 0 {
+		headers = append(headers, "Cc: "+strings.Join(cc, ", "))
+	}
+	content := strings.Join(append(headers,
+		"Subject: "+subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	), "\r\n")
+
+	recipients := append(append([]string{}, to...), cc...)
+	if err := smtp.SendMail(c.cfg.GetSMTPAddr(), auth, c.cfg.From, recipients, []byte(content)); err != nil {
+		return fmt.Errorf("发送邮件失败: %v", err)
+	}
+	return nil
+}
+
+// chatChannel 即时通讯（群机器人Webhook）通知渠道
+type chatChannel struct {
+	cfg    *config.ChatConfig
+	client *http.Client
+}
+
+// Name 返回渠道名称
+func (c *chatChannel) Name() string {
+	return model.NotificationChannelChat
+}
+
+// Send 推送即时通讯消息
+func (c *chatChannel) Send(ctx context.Context, user *model.User, msg *Message) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"user":    user.Username,
+		"subject": msg.Subject,
+		"body":    msg.Body,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.WebhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("推送消息失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("推送消息失败，状态码: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// newChannels 根据配置创建可用的通知渠道
+func newChannels(cfg *config.NotificationConfig, repo *repository.NotificationRepository, logger *logger.Logger) map[string]Channel {
+	channels := map[string]Channel{
+		model.NotificationChannelInApp: &inAppChannel{repo: repo, logger: logger},
+	}
+	if cfg.Email.Enabled {
+		channels[model.NotificationChannelEmail] = &emailChannel{cfg: &cfg.Email}
+	}
+	if cfg.Chat.Enabled && cfg.Chat.WebhookURL != "" {
+		channels[model.NotificationChannelChat] = &chatChannel{
+			cfg:    &cfg.Chat,
+			client: &http.Client{Timeout: 5 * time.Second},
+		}
+	}
+	return channels
+}
@@ -0,0 +1,335 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/internal/repository"
+	"miniflow/pkg/config"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// Message 待投递的通知消息
+type Message struct {
+	UserID    uint
+	EventType string
+	Subject   string
+	Body      string
+}
+
+// Dispatcher 通知分发器，投递前根据用户偏好选择渠道和投递时间
+type Dispatcher struct {
+	cfg      *config.NotificationConfig
+	repo     *repository.NotificationRepository
+	userRepo *repository.UserRepository
+	renderer *Renderer
+	channels map[string]Channel
+	logger   *logger.Logger
+}
+
+// NewDispatcher 创建通知分发器
+func NewDispatcher(
+	cfg *config.NotificationConfig,
+	repo *repository.NotificationRepository,
+	userRepo *repository.UserRepository,
+	renderer *Renderer,
+	logger *logger.Logger,
+) *Dispatcher {
+	return &Dispatcher{
+		cfg:      cfg,
+		repo:     repo,
+		userRepo: userRepo,
+		renderer: renderer,
+		channels: newChannels(cfg, repo, logger),
+		logger:   logger,
+	}
+}
+
+// AvailableChannels 返回当前已启用的渠道名称
+func (d *Dispatcher) AvailableChannels() []string {
+	names := make([]string, 0, len(d.channels))
+	for _, name := range []string{model.NotificationChannelInApp, model.NotificationChannelEmail, model.NotificationChannelChat} {
+		if _, ok := d.channels[name]; ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// IsCritical 判断事件是否为管理员指定的关键事件
+func (d *Dispatcher) IsCritical(eventType string) bool {
+	return containsString(d.cfg.CriticalEvents, eventType)
+}
+
+// Notify 按用户语言渲染事件模板后分发通知
+func (d *Dispatcher) Notify(ctx context.Context, userID uint, eventType string, data *TemplateData) error {
+	user, pref, err := d.loadRecipient(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if data == nil {
+		data = NewTemplateData(user, nil, nil)
+	} else if len(data.User) == 0 {
+		data.User = NewTemplateData(user, nil, nil).User
+	}
+
+	subject, body, err := d.renderer.Render(ctx, eventType, pref.Locale, data)
+	if err != nil {
+		return fmt.Errorf("渲染通知失败: %v", err)
+	}
+
+	return d.dispatch(ctx, user, pref, &Message{
+		UserID:    userID,
+		EventType: eventType,
+		Subject:   subject,
+		Body:      body,
+	})
+}
+
+// SendMail 通过邮件渠道直接发送邮件，不经过用户的通知偏好，用于流程中的邮件任务
+func (d *Dispatcher) SendMail(ctx context.Context, to, cc []string, subject, body string) error {
+	channel, ok := d.channels[model.NotificationChannelEmail].(*emailChannel)
+	if !ok {
+		return errors.New("邮件渠道未启用")
+	}
+	return channel.sendMail(to, cc, subject, body)
+}
+
+// Dispatch 分发通知
+// 普通事件遵循用户的渠道、屏蔽、摘要和免打扰设置；关键事件立即投递，且至少通过管理员指定的渠道投递
+func (d *Dispatcher) Dispatch(ctx context.Context, msg *Message) error {
+	user, pref, err := d.loadRecipient(ctx, msg.UserID)
+	if err != nil {
+		return err
+	}
+	return d.dispatch(ctx, user, pref, msg)
+}
+
+// loadRecipient 获取通知接收人及其通知偏好
+func (d *Dispatcher) loadRecipient(ctx context.Context, userID uint) (*model.User, *model.NotificationPreference, error) {
+	user, err := d.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取通知接收人失败: %v", err)
+	}
+
+	pref, err := d.repo.GetPreference(ctx, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取通知偏好失败: %v", err)
+	}
+	if pref == nil {
+		pref = model.DefaultNotificationPreference(userID)
+	}
+	return user, pref, nil
+}
+
+// dispatch 按偏好投递通知
+func (d *Dispatcher) dispatch(ctx context.Context, user *model.User, pref *model.NotificationPreference, msg *Message) error {
+	critical := d.IsCritical(msg.EventType)
+	channels := d.resolveChannels(pref, msg.EventType, critical)
+	if len(channels) == 0 {
+		d.logger.Debug("Notification suppressed by preference",
+			zap.Uint("user_id", msg.UserID),
+			zap.String("event_type", msg.EventType),
+		)
+		return nil
+	}
+
+	now := time.Now()
+	deliverAfter := d.deferUntil(pref, now)
+
+	for _, name := range channels {
+		// 站内通知由用户主动查看，直接写入收件箱，不受摘要和免打扰时段影响
+		if !critical && name != model.NotificationChannelInApp && deliverAfter.After(now) {
+			item := &model.NotificationQueueItem{
+				UserID:       msg.UserID,
+				Channel:      name,
+				EventType:    msg.EventType,
+				Subject:      msg.Subject,
+				Body:         msg.Body,
+				DeliverAfter: deliverAfter,
+			}
+			if err := d.repo.Enqueue(ctx, item); err != nil {
+				d.logger.Error("Failed to defer notification", zap.String("channel", name), zap.Error(err))
+			}
+			continue
+		}
+
+		if err := d.channels[name].Send(ctx, user, msg); err != nil {
+			d.logger.Error("Failed to deliver notification",
+				zap.Uint("user_id", msg.UserID),
+				zap.String("channel", name),
+				zap.String("event_type", msg.EventType),
+				zap.Error(err),
+			)
+		}
+	}
+
+	return nil
+}
+
+// FlushQueue 投递已到期的延迟通知，同一用户同一渠道的多条通知合并为一条摘要
+func (d *Dispatcher) FlushQueue(ctx context.Context, now time.Time) error {
+	items, err := d.repo.GetDueQueueItems(ctx, now, 500)
+	if err != nil {
+		return err
+	}
+
+	type groupKey struct {
+		userID  uint
+		channel string
+	}
+	groups := make(map[groupKey][]model.NotificationQueueItem)
+	var order []groupKey
+	for _, item := range items {
+		key := groupKey{userID: item.UserID, channel: item.Channel}
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], item)
+	}
+
+	for _, key := range order {
+		group := groups[key]
+		ids := make([]uint, len(group))
+		for i, item := range group {
+			ids[i] = item.ID
+		}
+
+		channel, ok := d.channels[key.channel]
+		if !ok {
+			// 渠道已被停用，直接丢弃
+			_ = d.repo.MarkQueueItemsSent(ctx, ids, now)
+			continue
+		}
+
+		user, err := d.userRepo.GetByID(ctx, key.userID)
+		if err != nil {
+			d.logger.Warn("Dropping notifications for missing user", zap.Uint("user_id", key.userID))
+			_ = d.repo.MarkQueueItemsSent(ctx, ids, now)
+			continue
+		}
+
+		msg := &Message{
+			UserID:    key.userID,
+			EventType: group[0].EventType,
+			Subject:   group[0].Subject,
+			Body:      group[0].Body,
+		}
+		if len(group) > 1 {
+			msg.EventType = model.NotificationEventDigest
+			msg.Subject, msg.Body = buildDigest(group)
+		}
+
+		if sendErr := channel.Send(ctx, user, msg); sendErr != nil {
+			d.logger.Error("Failed to deliver queued notifications",
+				zap.Uint("user_id", key.userID),
+				zap.String("channel", key.channel),
+				zap.Error(sendErr),
+			)
+			// 保留在队列中，下一轮继续重试
+			if err := d.repo.MarkQueueItemsFailed(ctx, ids, sendErr.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := d.repo.MarkQueueItemsSent(ctx, ids, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Start 启动延迟通知的后台投递循环，直到ctx取消
+func (d *Dispatcher) Start(ctx context.Context) {
+	interval := d.cfg.GetFlushInterval()
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := d.FlushQueue(ctx, now); err != nil {
+				d.logger.Error("Failed to flush notification queue", zap.Error(err))
+			}
+		}
+	}
+}
+
+// resolveChannels 计算本次通知需要投递的渠道
+func (d *Dispatcher) resolveChannels(pref *model.NotificationPreference, eventType string, critical bool) []string {
+	var channels []string
+
+	if critical || !containsString(pref.GetMutedEvents(), eventType) {
+		for _, name := range pref.GetChannels() {
+			if _, ok := d.channels[name]; ok && !containsString(channels, name) {
+				channels = append(channels, name)
+			}
+		}
+	}
+
+	// 关键事件强制追加管理员指定的最低渠道
+	if critical {
+		for _, name := range d.cfg.CriticalChannels {
+			if _, ok := d.channels[name]; ok && !containsString(channels, name) {
+				channels = append(channels, name)
+			}
+		}
+	}
+
+	return channels
+}
+
+// deferUntil 计算普通通知的投递时间，立即投递时返回now
+func (d *Dispatcher) deferUntil(pref *model.NotificationPreference, now time.Time) time.Time {
+	loc := preferenceLocation(pref)
+	local := now.In(loc)
+
+	if pref.DeliveryMode == model.NotificationDeliveryDigest {
+		return nextClockTime(local, d.cfg.DigestHour, 0)
+	}
+
+	start, okStart := parseClock(pref.QuietHoursStart)
+	end, okEnd := parseClock(pref.QuietHoursEnd)
+	if okStart && okEnd && inQuietHours(local, start, end) {
+		return nextClockTime(local, end/60, end%60)
+	}
+
+	return now
+}
+
+// buildDigest 将多条通知合并为摘要
+func buildDigest(items []model.NotificationQueueItem) (string, string) {
+	var body strings.Builder
+	for i, item := range items {
+		fmt.Fprintf(&body, "%d. %s\n", i+1, item.Subject)
+		if item.Body != "" {
+			fmt.Fprintf(&body, "   %s\n", item.Body)
+		}
+	}
+	return fmt.Sprintf("MiniFlow 通知摘要（%d条）", len(items)), body.String()
+}
+
+// containsString 判断切片是否包含指定字符串
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
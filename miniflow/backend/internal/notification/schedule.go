@@ -0,0 +1,62 @@
+package notification
+
+import (
+	"fmt"
+	"time"
+
+	"miniflow/internal/model"
+)
+
+// ParseClock 解析 "HH:MM" 格式的时间，返回自零点起的分钟数
+func ParseClock(value string) (int, error) {
+	minutes, ok := parseClock(value)
+	if !ok {
+		return 0, fmt.Errorf("无效的时间格式: %s", value)
+	}
+	return minutes, nil
+}
+
+// parseClock 解析 "HH:MM" 格式的时间
+func parseClock(value string) (int, bool) {
+	if value == "" {
+		return 0, false
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// inQuietHours 判断当前时间是否处于免打扰时段，支持跨零点的时段
+func inQuietHours(now time.Time, start, end int) bool {
+	current := now.Hour()*60 + now.Minute()
+	if start == end {
+		return false
+	}
+	if start < end {
+		return current >= start && current < end
+	}
+	return current >= start || current < end
+}
+
+// nextClockTime 返回下一个指定时刻
+func nextClockTime(now time.Time, hour, minute int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// preferenceLocation 获取用户偏好中的时区，无效时使用本地时区
+func preferenceLocation(pref *model.NotificationPreference) *time.Location {
+	if pref.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(pref.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
@@ -0,0 +1,278 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/internal/repository"
+	"miniflow/pkg/config"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// maxRenderedSize 模板渲染结果的最大字节数
+const maxRenderedSize = 64 * 1024
+
+// TemplateData 模板渲染数据
+type TemplateData struct {
+	User      map[string]interface{} `json:"user"`
+	Instance  map[string]interface{} `json:"instance"`
+	Task      map[string]interface{} `json:"task"`
+	Variables map[string]interface{} `json:"variables"`
+	Extra     map[string]interface{} `json:"extra"`
+}
+
+// NewTemplateData 根据用户、流程实例和任务构建模板数据，参数均可为nil
+func NewTemplateData(user *model.User, instance *model.ProcessInstance, task *model.TaskInstance) *TemplateData {
+	data := &TemplateData{
+		User:      map[string]interface{}{},
+		Instance:  map[string]interface{}{},
+		Task:      map[string]interface{}{},
+		Variables: map[string]interface{}{},
+		Extra:     map[string]interface{}{},
+	}
+
+	if user != nil {
+		name := user.DisplayName
+		if name == "" {
+			name = user.Username
+		}
+		data.User = map[string]interface{}{
+			"id":           user.ID,
+			"username":     user.Username,
+			"display_name": name,
+			"email":        user.Email,
+		}
+	}
+
+	if instance != nil {
+		data.Instance = map[string]interface{}{
+			"id":              instance.ID,
+			"business_key":    instance.BusinessKey,
+			"status":          instance.Status,
+			"current_node":    instance.CurrentNode,
+			"definition_name": instance.Definition.Name,
+			"definition_key":  instance.Definition.Key,
+			"start_time":      instance.StartTime,
+		}
+		if instance.Variables != "" {
+			_ = json.Unmarshal([]byte(instance.Variables), &data.Variables)
+		}
+	}
+
+	if task != nil {
+		data.Task = map[string]interface{}{
+			"id":       task.ID,
+			"name":     task.Name,
+			"node_id":  task.NodeID,
+			"status":   task.Status,
+			"priority": task.Priority,
+		}
+		if task.DueDate != nil {
+			data.Task["due_date"] = *task.DueDate
+		}
+	}
+
+	return data
+}
+
+// defaultTemplates 内置默认模板（zh-CN），数据库中没有配置时使用
+var defaultTemplates = map[string][2]string{
+	model.NotificationEventTaskCreated: {
+		"新任务：{{.Task.name}}",
+		"流程「{{.Instance.definition_name}}」({{.Instance.business_key}}) 产生了新任务「{{.Task.name}}」。",
+	},
+	model.NotificationEventTaskAssigned: {
+		"任务已分配给您：{{.Task.name}}",
+		"{{.User.display_name}}，流程「{{.Instance.definition_name}}」({{.Instance.business_key}}) 的任务「{{.Task.name}}」已分配给您，请及时处理。",
+	},
+	model.NotificationEventTaskOverdue: {
+		"任务已超期：{{.Task.name}}",
+		"任务「{{.Task.name}}」已超过截止时间 {{date .Task.due_date}}，请尽快处理。",
+	},
+	model.NotificationEventTaskReminder: {
+		"任务即将到期：{{.Task.name}}",
+		"任务「{{.Task.name}}」将于 {{date .Task.due_date}} 到期。",
+	},
+	model.NotificationEventProcessCompleted: {
+		"流程已完成：{{.Instance.definition_name}}",
+		"您发起的流程「{{.Instance.definition_name}}」({{.Instance.business_key}}) 已完成。",
+	},
+	model.NotificationEventProcessFailed: {
+		"流程执行失败：{{.Instance.definition_name}}",
+		"流程「{{.Instance.definition_name}}」({{.Instance.business_key}}) 执行失败，请联系管理员。",
+	},
+	model.NotificationEventTaskClaimExpired: {
+		"任务认领已过期：{{.Task.name}}",
+		"您认领的任务「{{.Task.name}}」长时间无操作，已自动释放回任务池。如仍需处理，请重新认领。",
+	},
+	model.NotificationEventKPIBreached: {
+		"流程 KPI 未达标：{{.Extra.kpi_name}}",
+		"流程「{{.Extra.definition_key}}」的 KPI「{{.Extra.kpi_name}}」最近 {{.Extra.window_days}} 天达成率为 {{.Extra.attainment}}%，低于目标 {{.Extra.target_percent}}%（样本数 {{.Extra.sample_size}}）。",
+	},
+	model.NotificationEventAnnouncement: {
+		"【系统公告】{{.Extra.title}}",
+		"{{.Extra.content}}",
+	},
+}
+
+// safeFuncs 模板可用的函数白名单，只包含无副作用的格式化函数
+var safeFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+	"join": func(sep string, values []interface{}) string {
+		parts := make([]string, len(values))
+		for i, v := range values {
+			parts[i] = fmt.Sprint(v)
+		}
+		return strings.Join(parts, sep)
+	},
+	"default": func(fallback interface{}, value interface{}) interface{} {
+		if value == nil || value == "" {
+			return fallback
+		}
+		return value
+	},
+	"truncate": func(length int, value string) string {
+		runes := []rune(value)
+		if len(runes) <= length {
+			return value
+		}
+		return string(runes[:length]) + "..."
+	},
+	"date": func(value interface{}) string {
+		switch v := value.(type) {
+		case time.Time:
+			return v.Format("2006-01-02 15:04")
+		case *time.Time:
+			if v == nil {
+				return ""
+			}
+			return v.Format("2006-01-02 15:04")
+		case nil:
+			return ""
+		default:
+			return fmt.Sprint(v)
+		}
+	},
+}
+
+// Renderer 通知模板渲染器
+type Renderer struct {
+	repo          *repository.NotificationRepository
+	defaultLocale string
+	logger        *logger.Logger
+}
+
+// NewRenderer 创建通知模板渲染器
+func NewRenderer(cfg *config.NotificationConfig, repo *repository.NotificationRepository, logger *logger.Logger) *Renderer {
+	locale := cfg.DefaultLocale
+	if locale == "" {
+		locale = "zh-CN"
+	}
+	return &Renderer{
+		repo:          repo,
+		defaultLocale: locale,
+		logger:        logger,
+	}
+}
+
+// DefaultLocale 返回默认语言
+func (r *Renderer) DefaultLocale() string {
+	return r.defaultLocale
+}
+
+// Render 渲染指定事件的通知，按 用户语言 -> 默认语言 -> 内置模板 的顺序查找模板
+func (r *Renderer) Render(ctx context.Context, eventType, locale string, data *TemplateData) (string, string, error) {
+	subject, body, err := r.lookup(ctx, eventType, locale)
+	if err != nil {
+		return "", "", err
+	}
+	return RenderTemplate(subject, body, data)
+}
+
+// lookup 查找模板内容
+func (r *Renderer) lookup(ctx context.Context, eventType, locale string) (string, string, error) {
+	locales := []string{r.defaultLocale}
+	if locale != "" && locale != r.defaultLocale {
+		locales = []string{locale, r.defaultLocale}
+	}
+
+	for _, l := range locales {
+		tmpl, err := r.repo.GetTemplate(ctx, eventType, l)
+		if err != nil {
+			return "", "", err
+		}
+		if tmpl != nil {
+			return tmpl.Subject, tmpl.Body, nil
+		}
+	}
+
+	if builtin, ok := defaultTemplates[eventType]; ok {
+		return builtin[0], builtin[1], nil
+	}
+
+	r.logger.Warn("No notification template found", zap.String("event_type", eventType))
+	return "", "", fmt.Errorf("事件 %s 没有可用的通知模板", eventType)
+}
+
+// ValidateTemplate 校验模板语法
+func ValidateTemplate(text string) error {
+	_, err := template.New("validate").Funcs(safeFuncs).Option("missingkey=zero").Parse(text)
+	return err
+}
+
+// RenderTemplate 使用给定的主题和正文模板渲染通知
+func RenderTemplate(subjectText, bodyText string, data *TemplateData) (string, string, error) {
+	subject, err := renderText("subject", subjectText, data)
+	if err != nil {
+		return "", "", fmt.Errorf("渲染通知主题失败: %v", err)
+	}
+	body, err := renderText("body", bodyText, data)
+	if err != nil {
+		return "", "", fmt.Errorf("渲染通知正文失败: %v", err)
+	}
+	return subject, body, nil
+}
+
+// RenderText 渲染单个模板字符串，用于邮件任务的收件人等字段
+func RenderText(text string, data *TemplateData) (string, error) {
+	return renderText("text", text, data)
+}
+
+// renderText 渲染单个模板
+func renderText(name, text string, data *TemplateData) (string, error) {
+	tmpl, err := template.New(name).Funcs(safeFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	buf := &limitedBuffer{limit: maxRenderedSize}
+	if err := tmpl.Execute(buf, data); err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(buf.String(), "<no value>", ""), nil
+}
+
+// limitedBuffer 限制写入大小的缓冲区，防止模板生成超大内容
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+// Write 写入数据，超过限制时返回错误
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, errors.New("渲染结果超过大小限制")
+	}
+	return b.Buffer.Write(p)
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ActivityHistoryQuery 活动历史查询条件
+type ActivityHistoryQuery struct {
+	Types      []string
+	Descending bool
+}
+
+// CreateActivity 追加一条活动历史
+func (r *ProcessInstanceRepository) CreateActivity(ctx context.Context, activity *model.ActivityHistory) error {
+	if err := r.db.WithContext(ctx).Create(activity).Error; err != nil {
+		r.logger.Error("Failed to create activity history",
+			zap.Uint("instance_id", activity.InstanceID),
+			zap.String("type", activity.Type),
+			zap.Error(err),
+		)
+		return err
+	}
+	return nil
+}
+
+// GetActivityHistory 获取流程实例的活动历史，按发生顺序排列；
+// 同一时间写入的记录按ID排序，ID即写入顺序
+func (r *ProcessInstanceRepository) GetActivityHistory(ctx context.Context, instanceID uint, query *ActivityHistoryQuery) ([]model.ActivityHistory, error) {
+	db, err := r.activityHistoryQuery(ctx, instanceID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var activities []model.ActivityHistory
+	if err := db.Find(&activities).Error; err != nil {
+		r.logger.Error("Failed to get activity history", zap.Uint("instance_id", instanceID), zap.Error(err))
+		return nil, err
+	}
+	return activities, nil
+}
+
+// EachActivity 按 GetActivityHistory 的条件和顺序逐条读取活动历史，
+// 不在内存中保留全部记录，fn 返回错误时停止读取
+func (r *ProcessInstanceRepository) EachActivity(ctx context.Context, instanceID uint, query *ActivityHistoryQuery, fn func(*model.ActivityHistory) error) error {
+	db, err := r.activityHistoryQuery(ctx, instanceID, query)
+	if err != nil {
+		return err
+	}
+
+	rows, err := db.Model(&model.ActivityHistory{}).Rows()
+	if err != nil {
+		r.logger.Error("Failed to read activity history", zap.Uint("instance_id", instanceID), zap.Error(err))
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var activity model.ActivityHistory
+		if err := r.db.WithContext(ctx).ScanRows(rows, &activity); err != nil {
+			return err
+		}
+		if err := fn(&activity); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// activityHistoryQuery 构建活动历史的查询条件和排序
+func (r *ProcessInstanceRepository) activityHistoryQuery(ctx context.Context, instanceID uint, query *ActivityHistoryQuery) (*gorm.DB, error) {
+	for _, activityType := range query.Types {
+		if err := model.ActivityTypes.Validate(activityType); err != nil {
+			return nil, err
+		}
+	}
+
+	db := r.db.WithContext(ctx).Where("instance_id = ?", instanceID)
+	if len(query.Types) > 0 {
+		db = db.Where("type IN ?", query.Types)
+	}
+	if query.Descending {
+		db = db.Order("created_at DESC").Order("id DESC")
+	} else {
+		db = db.Order("created_at ASC").Order("id ASC")
+	}
+	return db, nil
+}
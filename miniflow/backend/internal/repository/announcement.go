@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/database"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// AnnouncementRepository 公告数据访问层
+type AnnouncementRepository struct {
+	db     *database.Database
+	logger *logger.Logger
+}
+
+// NewAnnouncementRepository 创建新的公告仓库
+func NewAnnouncementRepository(db *database.Database, logger *logger.Logger) *AnnouncementRepository {
+	return &AnnouncementRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Create 创建公告
+func (r *AnnouncementRepository) Create(ctx context.Context, announcement *model.Announcement) error {
+	if err := r.db.WithContext(ctx).Create(announcement).Error; err != nil {
+		r.logger.Error("Failed to create announcement", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetByID 根据ID获取公告
+func (r *AnnouncementRepository) GetByID(ctx context.Context, id uint) (*model.Announcement, error) {
+	var announcement model.Announcement
+	if err := r.db.WithContext(ctx).First(&announcement, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("公告不存在")
+		}
+		return nil, err
+	}
+	return &announcement, nil
+}
+
+// Update 更新公告
+func (r *AnnouncementRepository) Update(ctx context.Context, announcement *model.Announcement) error {
+	return r.db.WithContext(ctx).Save(announcement).Error
+}
+
+// Delete 删除公告
+func (r *AnnouncementRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.Announcement{}, id).Error
+}
+
+// List 分页获取全部公告
+func (r *AnnouncementRepository) List(ctx context.Context, offset, limit int) ([]model.Announcement, int64, error) {
+	var announcements []model.Announcement
+	var total int64
+
+	if err := r.db.WithContext(ctx).Model(&model.Announcement{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := r.db.WithContext(ctx).Order("publish_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&announcements).Error
+
+	return announcements, total, err
+}
+
+// GetActive 获取指定时间有效的公告
+func (r *AnnouncementRepository) GetActive(ctx context.Context, now time.Time) ([]model.Announcement, error) {
+	var announcements []model.Announcement
+	err := r.db.WithContext(ctx).Where("publish_at <= ? AND (expires_at IS NULL OR expires_at > ?)", now, now).
+		Order("publish_at DESC").
+		Find(&announcements).Error
+
+	if err != nil {
+		r.logger.Error("Failed to get active announcements", zap.Error(err))
+		return nil, err
+	}
+
+	return announcements, nil
+}
+
+// GetPendingNotify 获取已到发布时间但尚未推送通知的公告
+func (r *AnnouncementRepository) GetPendingNotify(ctx context.Context, now time.Time) ([]model.Announcement, error) {
+	var announcements []model.Announcement
+	err := r.db.WithContext(ctx).Where("notified_at IS NULL AND publish_at <= ? AND (expires_at IS NULL OR expires_at > ?)", now, now).
+		Order("publish_at ASC").
+		Find(&announcements).Error
+
+	if err != nil {
+		r.logger.Error("Failed to get pending announcements", zap.Error(err))
+		return nil, err
+	}
+
+	return announcements, nil
+}
+
+// MarkNotified 标记公告已推送，返回是否由本次调用标记成功
+func (r *AnnouncementRepository) MarkNotified(ctx context.Context, id uint, notifiedAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.Announcement{}).
+		Where("id = ? AND notified_at IS NULL", id).
+		Update("notified_at", notifiedAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// CreateAsyncJob 创建异步作业
+func (r *ProcessInstanceRepository) CreateAsyncJob(ctx context.Context, job *model.AsyncJob) error {
+	if err := r.db.WithContext(ctx).Create(job).Error; err != nil {
+		r.logger.Error("Failed to create async job",
+			zap.Uint("instance_id", job.InstanceID),
+			zap.String("node_id", job.NodeID),
+			zap.Error(err),
+		)
+		return err
+	}
+	return nil
+}
+
+// GetDueAsyncJobs 获取已到期且等待执行的异步作业，按到期时间排列
+func (r *ProcessInstanceRepository) GetDueAsyncJobs(ctx context.Context, now time.Time, limit int) ([]model.AsyncJob, error) {
+	var jobs []model.AsyncJob
+	err := r.db.WithContext(ctx).Where("status = ? AND due_at <= ?", model.AsyncJobStatusPending, now).
+		Order("due_at ASC, id ASC").
+		Limit(limit).
+		Find(&jobs).Error
+	return jobs, err
+}
+
+// LockAsyncJob 为执行器锁定等待中的异步作业并计入一次执行，作业已被其他执行器锁定时返回 false
+func (r *ProcessInstanceRepository) LockAsyncJob(ctx context.Context, id uint, owner string, now, until time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.AsyncJob{}).
+		Where("id = ? AND status = ? AND due_at <= ?", id, model.AsyncJobStatusPending, now).
+		Updates(map[string]interface{}{
+			"status":          model.AsyncJobStatusRunning,
+			"locked_by":       owner,
+			"lock_expires_at": until,
+			"attempts":        gorm.Expr("attempts + 1"),
+		})
+	if result.Error != nil {
+		r.logger.Error("Failed to lock async job", zap.Uint("job_id", id), zap.Error(result.Error))
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ReleaseAsyncJob 释放执行器锁定的作业，作业在 dueAt 之后重新等待执行
+func (r *ProcessInstanceRepository) ReleaseAsyncJob(ctx context.Context, id uint, owner string, dueAt time.Time) error {
+	return r.lockedAsyncJob(ctx, id, owner).
+		Updates(map[string]interface{}{
+			"status":          model.AsyncJobStatusPending,
+			"due_at":          dueAt,
+			"locked_by":       "",
+			"lock_expires_at": nil,
+		}).Error
+}
+
+// FinishAsyncJob 记录执行器锁定的作业的执行结果，status 为已完成、失败或已取消
+func (r *ProcessInstanceRepository) FinishAsyncJob(ctx context.Context, id uint, owner, status string, now time.Time, reason string) error {
+	return r.lockedAsyncJob(ctx, id, owner).
+		Updates(map[string]interface{}{
+			"status":          status,
+			"completed_at":    now,
+			"last_error":      reason,
+			"locked_by":       "",
+			"lock_expires_at": nil,
+		}).Error
+}
+
+// lockedAsyncJob 仍由指定执行器锁定的作业
+func (r *ProcessInstanceRepository) lockedAsyncJob(ctx context.Context, id uint, owner string) *gorm.DB {
+	return r.db.WithContext(ctx).Model(&model.AsyncJob{}).
+		Where("id = ? AND status = ? AND locked_by = ?", id, model.AsyncJobStatusRunning, owner)
+}
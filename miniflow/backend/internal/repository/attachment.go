@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/database"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrAttachmentNotFound 附件不存在
+var ErrAttachmentNotFound = errors.New("附件不存在")
+
+// AttachmentRepository 附件元数据访问层，文件内容保存在附件存储中
+type AttachmentRepository struct {
+	db     *database.Database
+	logger *logger.Logger
+}
+
+// NewAttachmentRepository 创建新的附件仓库
+func NewAttachmentRepository(db *database.Database, logger *logger.Logger) *AttachmentRepository {
+	return &AttachmentRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Create 保存附件元数据
+func (r *AttachmentRepository) Create(ctx context.Context, attachment *model.Attachment) error {
+	if err := r.db.WithContext(ctx).Create(attachment).Error; err != nil {
+		r.logger.Error("Failed to create attachment", zap.Uint("instance_id", attachment.InstanceID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetByID 根据ID获取附件
+func (r *AttachmentRepository) GetByID(ctx context.Context, id uint) (*model.Attachment, error) {
+	var attachment model.Attachment
+	if err := r.db.WithContext(ctx).First(&attachment, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// GetByInstance 获取流程实例的附件，包括实例下各任务的附件，按上传顺序排列
+func (r *AttachmentRepository) GetByInstance(ctx context.Context, instanceID uint) ([]model.Attachment, error) {
+	var attachments []model.Attachment
+	err := r.db.WithContext(ctx).Where("instance_id = ?", instanceID).Order("id ASC").Find(&attachments).Error
+	return attachments, err
+}
+
+// GetByTask 获取任务的附件，按上传顺序排列
+func (r *AttachmentRepository) GetByTask(ctx context.Context, taskID uint) ([]model.Attachment, error) {
+	var attachments []model.Attachment
+	err := r.db.WithContext(ctx).Where("task_id = ?", taskID).Order("id ASC").Find(&attachments).Error
+	return attachments, err
+}
+
+// GetOrphaned 获取所属流程实例已不存在的附件，最多返回 limit 条
+func (r *AttachmentRepository) GetOrphaned(ctx context.Context, limit int) ([]model.Attachment, error) {
+	var attachments []model.Attachment
+	err := r.db.WithContext(ctx).
+		Where("NOT EXISTS (SELECT 1 FROM process_instances WHERE process_instances.id = attachments.instance_id)").
+		Order("id ASC").
+		Limit(limit).
+		Find(&attachments).Error
+	return attachments, err
+}
+
+// Delete 物理删除附件元数据，调用方负责删除存储中的文件
+func (r *AttachmentRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Unscoped().Delete(&model.Attachment{}, id).Error
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/database"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TokenRepository 刷新令牌和已注销访问令牌的数据访问层
+type TokenRepository struct {
+	db     *database.Database
+	logger *logger.Logger
+}
+
+// NewTokenRepository 创建新的令牌仓库
+func NewTokenRepository(db *database.Database, logger *logger.Logger) *TokenRepository {
+	return &TokenRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// CreateRefreshToken 保存刷新令牌
+func (r *TokenRepository) CreateRefreshToken(ctx context.Context, token *model.RefreshToken) error {
+	if err := r.db.WithContext(ctx).Create(token).Error; err != nil {
+		r.logger.Error("Failed to create refresh token", zap.Uint("user_id", token.UserID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetRefreshTokenByHash 根据令牌摘要获取刷新令牌
+func (r *TokenRepository) GetRefreshTokenByHash(ctx context.Context, hash string) (*model.RefreshToken, error) {
+	var token model.RefreshToken
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("刷新令牌不存在")
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// RevokeRefreshToken 作废未作废的刷新令牌，replacedByID 为替换它的新令牌
+// 返回是否由本次调用作废，并发刷新同一个令牌时只有一个请求成功
+func (r *TokenRepository) RevokeRefreshToken(ctx context.Context, id uint, replacedByID *uint, now time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"revoked_at":     now,
+			"replaced_by_id": replacedByID,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RevokeUserRefreshTokens 作废用户所有未作废的刷新令牌，返回作废数量
+func (r *TokenRepository) RevokeUserRefreshTokens(ctx context.Context, userID uint, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&model.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now)
+	return result.RowsAffected, result.Error
+}
+
+// RevokeAccessToken 把访问令牌加入注销名单，重复注销忽略
+func (r *TokenRepository) RevokeAccessToken(ctx context.Context, tokenID string, userID uint, expiresAt time.Time) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&model.RevokedAccessToken{
+		TokenID:   tokenID,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}).Error
+}
+
+// IsAccessTokenRevoked 检查访问令牌是否已注销
+func (r *TokenRepository) IsAccessTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.RevokedAccessToken{}).Where("token_id = ?", tokenID).Count(&count).Error
+	return count > 0, err
+}
+
+// DeleteExpired 删除已过期的刷新令牌和注销记录，返回删除数量
+func (r *TokenRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	refresh := r.db.WithContext(ctx).Unscoped().Where("expires_at < ?", now).Delete(&model.RefreshToken{})
+	if refresh.Error != nil {
+		return 0, refresh.Error
+	}
+	revoked := r.db.WithContext(ctx).Where("expires_at < ?", now).Delete(&model.RevokedAccessToken{})
+	if revoked.Error != nil {
+		return refresh.RowsAffected, revoked.Error
+	}
+	return refresh.RowsAffected + revoked.RowsAffected, nil
+}
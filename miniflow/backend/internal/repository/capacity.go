@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/database"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 计入并发数的未完成任务状态
+var capacityOpenTaskStatuses = []string{
+	model.TaskStatusCreated,
+	model.TaskStatusAssigned,
+	model.TaskStatusClaimed,
+	model.TaskStatusInProgress,
+}
+
+// CapacityRow 容量统计行及其流程定义版本
+type CapacityRow struct {
+	model.CapacityStat
+	Key     string `json:"key"`
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+}
+
+// CapacityRepository 容量统计数据访问层
+type CapacityRepository struct {
+	db     *database.Database
+	logger *logger.Logger
+}
+
+// NewCapacityRepository 创建新的容量统计仓库
+func NewCapacityRepository(db *database.Database, logger *logger.Logger) *CapacityRepository {
+	return &CapacityRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Record 汇总 day 当天每个定义版本启动和完成的实例数并写入统计行
+//
+// sampleTasks 为 true 时同时采样当前的并发任务数并更新当天的峰值，只应对当天使用；
+// 补齐前一天的计数时为 false，保留前一天最后一次采样的并发数。
+func (r *CapacityRepository) Record(ctx context.Context, day, now time.Time, sampleTasks bool) error {
+	day = model.CapacityDay(day)
+	next := day.AddDate(0, 0, 1)
+	stats := make(map[uint]*model.CapacityStat)
+	stat := func(definitionID uint) *model.CapacityStat {
+		if s, ok := stats[definitionID]; ok {
+			return s
+		}
+		s := &model.CapacityStat{DefinitionID: definitionID, Day: day, SampledAt: now}
+		stats[definitionID] = s
+		return s
+	}
+
+	started, err := r.countByDefinition(r.db.WithContext(ctx).Model(&model.ProcessInstance{}).
+		Where("start_time >= ? AND start_time < ?", day, next))
+	if err != nil {
+		return err
+	}
+	for definitionID, count := range started {
+		stat(definitionID).Started = count
+	}
+
+	completed, err := r.countByDefinition(r.db.WithContext(ctx).Model(&model.ProcessInstance{}).
+		Where("status = ? AND end_time >= ? AND end_time < ?", model.InstanceStatusCompleted, day, next))
+	if err != nil {
+		return err
+	}
+	for definitionID, count := range completed {
+		stat(definitionID).Completed = count
+	}
+
+	columns := []string{"started", "completed", "sampled_at"}
+	updates := map[string]interface{}{}
+	if sampleTasks {
+		manual, err := r.countByDefinition(r.openTasks(ctx).Where("t.topic = ''"))
+		if err != nil {
+			return err
+		}
+		for definitionID, count := range manual {
+			s := stat(definitionID)
+			s.ActiveTasks, s.PeakActiveTasks = count, count
+		}
+
+		external, err := r.countByDefinition(r.openTasks(ctx).Where("t.topic <> ''"))
+		if err != nil {
+			return err
+		}
+		for definitionID, count := range external {
+			s := stat(definitionID)
+			s.ActiveExternalTasks, s.PeakActiveExternalTasks = count, count
+		}
+
+		// 没有未完成任务的定义版本当天已有的行也要把并发数归零
+		if err := r.db.WithContext(ctx).Model(&model.CapacityStat{}).
+			Where("day = ?", day).
+			Updates(map[string]interface{}{"active_tasks": 0, "active_external_tasks": 0}).Error; err != nil {
+			return err
+		}
+		columns = append(columns, "active_tasks", "active_external_tasks")
+		updates["peak_active_tasks"] = gorm.Expr("GREATEST(peak_active_tasks, VALUES(active_tasks))")
+		updates["peak_active_external_tasks"] = gorm.Expr("GREATEST(peak_active_external_tasks, VALUES(active_external_tasks))")
+	}
+	if len(stats) == 0 {
+		return nil
+	}
+
+	rows := make([]model.CapacityStat, 0, len(stats))
+	for _, s := range stats {
+		rows = append(rows, *s)
+	}
+	assignments := clause.AssignmentColumns(columns)
+	assignments = append(assignments, clause.Assignments(updates)...)
+	err = r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "definition_id"}, {Name: "day"}},
+		DoUpdates: assignments,
+	}).CreateInBatches(rows, 500).Error
+	if err != nil {
+		r.logger.Error("Failed to record capacity stats", zap.Time("day", day), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// List 获取 since 之后的容量统计，key 为空时返回全部流程，按版本和日期排列
+func (r *CapacityRepository) List(ctx context.Context, key string, since time.Time) ([]CapacityRow, error) {
+	var rows []CapacityRow
+	query := r.db.WithContext(ctx).Table("process_capacity_stats s").
+		Select("s.*, "+r.db.Quote("d.key")+" AS "+r.db.Quote("key")+", d.name AS name, d.version AS version").
+		Joins("JOIN process_definitions d ON d.id = s.definition_id").
+		Where("s.day >= ?", model.CapacityDay(since))
+	if key != "" {
+		query = query.Where(r.db.Quote("d.key")+" = ?", key)
+	}
+	if err := query.Order(r.db.Quote("d.key") + " ASC, d.version ASC, s.day ASC").Scan(&rows).Error; err != nil {
+		r.logger.Error("Failed to list capacity stats", zap.String("key", key), zap.Error(err))
+		return nil, err
+	}
+	return rows, nil
+}
+
+// openTasks 未完成的任务及其流程实例
+func (r *CapacityRepository) openTasks(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Table("task_instances t").
+		Joins("JOIN process_instances i ON i.id = t.instance_id").
+		Where("t.status IN ? AND t.deleted_at IS NULL AND i.deleted_at IS NULL", capacityOpenTaskStatuses)
+}
+
+// countByDefinition 按流程定义版本分组计数，查询中只有流程实例表带 definition_id 列
+func (r *CapacityRepository) countByDefinition(query *gorm.DB) (map[uint]int, error) {
+	var rows []struct {
+		DefinitionID uint
+		Count        int
+	}
+	if err := query.Select("definition_id, COUNT(*) AS count").Group("definition_id").Scan(&rows).Error; err != nil {
+		r.logger.Error("Failed to count by definition", zap.Error(err))
+		return nil, err
+	}
+	counts := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		counts[row.DefinitionID] = row.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/database"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ComplexityBudgetRepository 复杂度预算数据访问层
+type ComplexityBudgetRepository struct {
+	db     *database.Database
+	logger *logger.Logger
+}
+
+// NewComplexityBudgetRepository 创建新的复杂度预算仓库
+func NewComplexityBudgetRepository(db *database.Database, logger *logger.Logger) *ComplexityBudgetRepository {
+	return &ComplexityBudgetRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// GetByDefinitionKey 获取复杂度预算，未配置时返回nil
+func (r *ComplexityBudgetRepository) GetByDefinitionKey(ctx context.Context, key string) (*model.ComplexityBudget, error) {
+	var budget model.ComplexityBudget
+	err := r.db.WithContext(ctx).Where("definition_key = ?", key).First(&budget).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.logger.Error("Failed to get complexity budget", zap.String("definition_key", key), zap.Error(err))
+		return nil, err
+	}
+	return &budget, nil
+}
+
+// GetEffective 获取流程生效的复杂度预算：优先使用流程单独配置的预算，其次使用默认预算，都未配置时返回nil
+func (r *ComplexityBudgetRepository) GetEffective(ctx context.Context, key string) (*model.ComplexityBudget, error) {
+	budget, err := r.GetByDefinitionKey(ctx, key)
+	if err != nil || budget != nil {
+		return budget, err
+	}
+	return r.GetByDefinitionKey(ctx, model.ComplexityBudgetDefaultKey)
+}
+
+// List 获取全部复杂度预算
+func (r *ComplexityBudgetRepository) List(ctx context.Context) ([]model.ComplexityBudget, error) {
+	var budgets []model.ComplexityBudget
+	err := r.db.WithContext(ctx).Order("definition_key ASC").Find(&budgets).Error
+	return budgets, err
+}
+
+// Save 保存复杂度预算
+func (r *ComplexityBudgetRepository) Save(ctx context.Context, budget *model.ComplexityBudget) error {
+	if err := r.db.WithContext(ctx).Save(budget).Error; err != nil {
+		r.logger.Error("Failed to save complexity budget", zap.String("definition_key", budget.DefinitionKey), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// DeleteByDefinitionKey 删除复杂度预算
+func (r *ComplexityBudgetRepository) DeleteByDefinitionKey(ctx context.Context, key string) error {
+	return r.db.WithContext(ctx).Unscoped().Where("definition_key = ?", key).Delete(&model.ComplexityBudget{}).Error
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/database"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ConnectorPolicyRepository 连接器白名单数据访问层
+type ConnectorPolicyRepository struct {
+	db     *database.Database
+	logger *logger.Logger
+}
+
+// NewConnectorPolicyRepository 创建新的连接器白名单仓库
+func NewConnectorPolicyRepository(db *database.Database, logger *logger.Logger) *ConnectorPolicyRepository {
+	return &ConnectorPolicyRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// GetByDefinitionKey 获取流程的连接器白名单，未配置时返回nil
+func (r *ConnectorPolicyRepository) GetByDefinitionKey(ctx context.Context, key string) (*model.ConnectorPolicy, error) {
+	var policy model.ConnectorPolicy
+	err := r.db.WithContext(ctx).Where("definition_key = ?", key).First(&policy).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.logger.Error("Failed to get connector policy", zap.String("definition_key", key), zap.Error(err))
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// List 获取全部连接器白名单
+func (r *ConnectorPolicyRepository) List(ctx context.Context) ([]model.ConnectorPolicy, error) {
+	var policies []model.ConnectorPolicy
+	err := r.db.WithContext(ctx).Order("definition_key ASC").Find(&policies).Error
+	return policies, err
+}
+
+// Save 保存连接器白名单
+func (r *ConnectorPolicyRepository) Save(ctx context.Context, policy *model.ConnectorPolicy) error {
+	if err := r.db.WithContext(ctx).Save(policy).Error; err != nil {
+		r.logger.Error("Failed to save connector policy", zap.String("definition_key", policy.DefinitionKey), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// DeleteByDefinitionKey 删除流程的连接器白名单
+func (r *ConnectorPolicyRepository) DeleteByDefinitionKey(ctx context.Context, key string) error {
+	return r.db.WithContext(ctx).Unscoped().Where("definition_key = ?", key).Delete(&model.ConnectorPolicy{}).Error
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/database"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// DeploymentRepository 部署记录数据访问层
+type DeploymentRepository struct {
+	db     *database.Database
+	logger *logger.Logger
+}
+
+// NewDeploymentRepository 创建新的部署记录仓库
+func NewDeploymentRepository(db *database.Database, logger *logger.Logger) *DeploymentRepository {
+	return &DeploymentRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Create 创建部署记录
+func (r *DeploymentRepository) Create(ctx context.Context, deployment *model.Deployment) error {
+	if err := r.db.WithContext(ctx).Create(deployment).Error; err != nil {
+		r.logger.Error("Failed to create deployment",
+			zap.String("definition_key", deployment.DefinitionKey),
+			zap.String("environment", deployment.Environment),
+			zap.Error(err),
+		)
+		return err
+	}
+	return nil
+}
+
+// GetByID 根据ID获取部署记录
+func (r *DeploymentRepository) GetByID(ctx context.Context, id uint) (*model.Deployment, error) {
+	var deployment model.Deployment
+	if err := r.db.WithContext(ctx).First(&deployment, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("部署记录不存在")
+		}
+		return nil, err
+	}
+	return &deployment, nil
+}
+
+// ListByDefinitionKey 获取流程的部署记录，environment 为空时返回全部环境
+func (r *DeploymentRepository) ListByDefinitionKey(ctx context.Context, key, environment string) ([]model.Deployment, error) {
+	var deployments []model.Deployment
+	query := r.db.WithContext(ctx).Where("definition_key = ?", key)
+	if environment != "" {
+		query = query.Where("environment = ?", environment)
+	}
+	err := query.Order("created_at DESC").Find(&deployments).Error
+	return deployments, err
+}
+
+// GetCurrent 获取流程在某个环境当前生效的部署（最近一次部署），没有部署时返回nil
+func (r *DeploymentRepository) GetCurrent(ctx context.Context, key, environment string) (*model.Deployment, error) {
+	var deployment model.Deployment
+	err := r.db.WithContext(ctx).Where("definition_key = ? AND environment = ?", key, environment).
+		Order("created_at DESC, id DESC").
+		First(&deployment).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &deployment, nil
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/database"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// duplicateCandidateLimit 每次重复检测最多比较的运行中实例数量
+const duplicateCandidateLimit = 500
+
+// DuplicateRepository 疑似重复实例数据访问层
+type DuplicateRepository struct {
+	db     *database.Database
+	logger *logger.Logger
+}
+
+// NewDuplicateRepository 创建新的疑似重复实例仓库
+func NewDuplicateRepository(db *database.Database, logger *logger.Logger) *DuplicateRepository {
+	return &DuplicateRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Create 创建疑似重复记录
+func (r *DuplicateRepository) Create(ctx context.Context, duplicate *model.InstanceDuplicate) error {
+	if err := r.db.WithContext(ctx).Create(duplicate).Error; err != nil {
+		r.logger.Error("Failed to create instance duplicate",
+			zap.Uint("instance_id", duplicate.InstanceID),
+			zap.Uint("duplicate_of_id", duplicate.DuplicateOfID),
+			zap.Error(err),
+		)
+		return err
+	}
+	return nil
+}
+
+// GetByID 根据ID获取疑似重复记录
+func (r *DuplicateRepository) GetByID(ctx context.Context, id uint) (*model.InstanceDuplicate, error) {
+	var duplicate model.InstanceDuplicate
+	if err := r.db.WithContext(ctx).First(&duplicate, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("疑似重复记录不存在")
+		}
+		return nil, err
+	}
+	return &duplicate, nil
+}
+
+// Update 更新疑似重复记录
+func (r *DuplicateRepository) Update(ctx context.Context, duplicate *model.InstanceDuplicate) error {
+	return r.db.WithContext(ctx).Save(duplicate).Error
+}
+
+// GetByInstance 获取与实例相关的疑似重复记录，包括该实例被标记和被其他实例指向的记录
+func (r *DuplicateRepository) GetByInstance(ctx context.Context, instanceID uint) ([]model.InstanceDuplicate, error) {
+	var duplicates []model.InstanceDuplicate
+	err := r.db.WithContext(ctx).Preload("Instance").
+		Preload("DuplicateOf").
+		Where("instance_id = ? OR duplicate_of_id = ?", instanceID, instanceID).
+		Order("score DESC, created_at DESC").
+		Find(&duplicates).Error
+	return duplicates, err
+}
+
+// GetOpenByStarter 获取用户发起的实例上待处理的疑似重复记录
+func (r *DuplicateRepository) GetOpenByStarter(ctx context.Context, starterID uint) ([]model.InstanceDuplicate, error) {
+	var duplicates []model.InstanceDuplicate
+	err := r.db.WithContext(ctx).Preload("Instance").
+		Preload("DuplicateOf").
+		Joins("JOIN process_instances ON process_instances.id = instance_duplicates.instance_id").
+		Where("instance_duplicates.status = ? AND process_instances.starter_id = ?", model.DuplicateStatusOpen, starterID).
+		Order("instance_duplicates.created_at DESC").
+		Find(&duplicates).Error
+	return duplicates, err
+}
+
+// GetDuplicateCandidates 获取同一流程（任意版本）下其他运行中的实例，用于重复检测
+func (r *DuplicateRepository) GetDuplicateCandidates(ctx context.Context, definitionKey string, excludeInstanceID uint) ([]model.ProcessInstance, error) {
+	var instances []model.ProcessInstance
+	err := r.db.WithContext(ctx).Joins("JOIN process_definitions ON process_definitions.id = process_instances.definition_id").
+		Where(r.db.Quote("process_definitions.key")+" = ? AND process_instances.status = ? AND process_instances.id <> ?",
+			definitionKey, model.InstanceStatusRunning, excludeInstanceID).
+		Order("process_instances.start_time DESC").
+		Limit(duplicateCandidateLimit).
+		Find(&instances).Error
+	if err != nil {
+		r.logger.Error("Failed to get duplicate candidates", zap.String("definition_key", definitionKey), zap.Error(err))
+		return nil, err
+	}
+	return instances, nil
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/database"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// ExecutionLogRepository 服务任务执行日志数据访问层
+type ExecutionLogRepository struct {
+	db     *database.Database
+	logger *logger.Logger
+}
+
+// NewExecutionLogRepository 创建新的执行日志仓库
+func NewExecutionLogRepository(db *database.Database, logger *logger.Logger) *ExecutionLogRepository {
+	return &ExecutionLogRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Create 创建执行日志
+func (r *ExecutionLogRepository) Create(ctx context.Context, log *model.ExecutionLog) error {
+	if err := r.db.WithContext(ctx).Create(log).Error; err != nil {
+		r.logger.Error("Failed to create execution log", zap.Uint("task_id", log.TaskID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Update 更新执行日志
+func (r *ExecutionLogRepository) Update(ctx context.Context, log *model.ExecutionLog) error {
+	if err := r.db.WithContext(ctx).Save(log).Error; err != nil {
+		r.logger.Error("Failed to update execution log", zap.Uint("id", log.ID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetByTask 获取任务的执行日志，按执行顺序排列
+func (r *ExecutionLogRepository) GetByTask(ctx context.Context, taskID uint) ([]model.ExecutionLog, error) {
+	var logs []model.ExecutionLog
+	err := r.db.WithContext(ctx).Where("task_id = ?", taskID).
+		Order("id ASC").
+		Find(&logs).Error
+	if err != nil {
+		r.logger.Error("Failed to get execution logs", zap.Uint("task_id", taskID), zap.Error(err))
+		return nil, err
+	}
+	return logs, nil
+}
+
+// CountByInstanceAndNode 统计流程实例在节点上的执行次数，用于计算重试序号
+func (r *ExecutionLogRepository) CountByInstanceAndNode(ctx context.Context, instanceID uint, nodeID string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.ExecutionLog{}).
+		Where("instance_id = ? AND node_id = ?", instanceID, nodeID).
+		Count(&count).Error
+	return count, err
+}
+
+// GetLatestRecorded 获取相同请求最近一次成功的真实调用记录，用于模拟模式回放
+func (r *ExecutionLogRepository) GetLatestRecorded(ctx context.Context, method, url string) (*model.ExecutionLog, error) {
+	var log model.ExecutionLog
+	err := r.db.WithContext(ctx).Where("method = ? AND url = ? AND status = ? AND mocked = ?", method, url, model.ExecutionStatusSucceeded, false).
+		Order("id DESC").
+		First(&log).Error
+	if err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+
+	"miniflow/internal/model"
+)
+
+// CreateTrace 追加一条执行跟踪记录
+func (r *ProcessInstanceRepository) CreateTrace(ctx context.Context, trace *model.ExecutionTrace) error {
+	return r.db.WithContext(ctx).Create(trace).Error
+}
+
+// GetTraces 获取流程实例的执行跟踪记录，按写入顺序排列
+func (r *ProcessInstanceRepository) GetTraces(ctx context.Context, instanceID uint) ([]model.ExecutionTrace, error) {
+	var traces []model.ExecutionTrace
+	err := r.db.WithContext(ctx).Where("instance_id = ?", instanceID).
+		Order("id ASC").
+		Find(&traces).Error
+	return traces, err
+}
+
+// EachTrace 按写入顺序逐条读取流程实例的执行跟踪记录，fn 返回错误时停止读取
+func (r *ProcessInstanceRepository) EachTrace(ctx context.Context, instanceID uint, fn func(*model.ExecutionTrace) error) error {
+	rows, err := r.db.WithContext(ctx).Model(&model.ExecutionTrace{}).
+		Where("instance_id = ?", instanceID).
+		Order("id ASC").
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var trace model.ExecutionTrace
+		if err := r.db.WithContext(ctx).ScanRows(rows, &trace); err != nil {
+			return err
+		}
+		if err := fn(&trace); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// availableExternalTasks 等待外部处理程序获取的任务：未完成且没有有效的锁
+func (r *TaskRepository) availableExternalTasks(ctx context.Context, now time.Time) *gorm.DB {
+	return r.db.WithContext(ctx).Model(&model.TaskInstance{}).
+		Where("topic <> '' AND status = ?", model.TaskStatusInProgress).
+		Where("lock_expires_at IS NULL OR lock_expires_at <= ?", now)
+}
+
+// GetAvailableExternalTasks 获取指定主题下可以锁定的外部任务，按优先级和创建顺序排列
+func (r *TaskRepository) GetAvailableExternalTasks(ctx context.Context, topics []string, now time.Time, limit int) ([]model.TaskInstance, error) {
+	var tasks []model.TaskInstance
+	err := r.availableExternalTasks(ctx, now).
+		Where("topic IN ?", topics).
+		Order("priority DESC, id ASC").
+		Limit(limit).
+		Find(&tasks).Error
+	if err != nil {
+		r.logger.Error("Failed to get available external tasks", zap.Strings("topics", topics), zap.Error(err))
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// LockExternalTask 为外部处理程序锁定任务，任务已被其他处理程序锁定时返回 false
+func (r *TaskRepository) LockExternalTask(ctx context.Context, taskID uint, workerID string, now, until time.Time) (bool, error) {
+	result := r.availableExternalTasks(ctx, now).
+		Where("id = ?", taskID).
+		Updates(map[string]interface{}{
+			"worker_id":       workerID,
+			"lock_expires_at": until,
+		})
+	if result.Error != nil {
+		r.logger.Error("Failed to lock external task", zap.Uint("task_id", taskID), zap.Error(result.Error))
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// lockedExternalTask 仍由指定外部处理程序锁定的任务
+func (r *TaskRepository) lockedExternalTask(ctx context.Context, taskID uint, workerID string, now time.Time) *gorm.DB {
+	return r.db.WithContext(ctx).Model(&model.TaskInstance{}).
+		Where("id = ? AND topic <> '' AND status = ?", taskID, model.TaskStatusInProgress).
+		Where("worker_id = ? AND lock_expires_at > ?", workerID, now)
+}
+
+// CompleteExternalTask 完成外部处理程序锁定的任务，锁已失效或任务已完成时返回 false
+func (r *TaskRepository) CompleteExternalTask(ctx context.Context, taskID uint, workerID string, now time.Time) (bool, error) {
+	result := r.lockedExternalTask(ctx, taskID, workerID, now).
+		Updates(map[string]interface{}{
+			"status":        model.TaskStatusCompleted,
+			"complete_time": now,
+		})
+	if result.Error != nil {
+		r.logger.Error("Failed to complete external task", zap.Uint("task_id", taskID), zap.Error(result.Error))
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	r.recordTaskChangeByID(ctx, taskID, nil, model.TaskEventCompleted)
+	return true, nil
+}
+
+// ReleaseExternalTask 记录外部处理程序报告的失败并释放锁，任务在 availableAt 之后可以再次获取
+func (r *TaskRepository) ReleaseExternalTask(ctx context.Context, taskID uint, workerID string, now time.Time, retries int, errorMessage string, availableAt time.Time) (bool, error) {
+	result := r.lockedExternalTask(ctx, taskID, workerID, now).
+		Updates(map[string]interface{}{
+			"worker_id":       "",
+			"lock_expires_at": availableAt,
+			"retries":         retries,
+			"comment":         errorMessage,
+		})
+	if result.Error != nil {
+		r.logger.Error("Failed to release external task", zap.Uint("task_id", taskID), zap.Error(result.Error))
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// FailExternalTask 将外部处理程序锁定的任务标记为失败，重试次数用尽时使用
+func (r *TaskRepository) FailExternalTask(ctx context.Context, taskID uint, workerID string, now time.Time, errorMessage string) (bool, error) {
+	result := r.lockedExternalTask(ctx, taskID, workerID, now).
+		Updates(map[string]interface{}{
+			"status":        model.TaskStatusFailed,
+			"complete_time": now,
+			"retries":       0,
+			"comment":       errorMessage,
+		})
+	if result.Error != nil {
+		r.logger.Error("Failed to fail external task", zap.Uint("task_id", taskID), zap.Error(result.Error))
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	r.recordTaskChangeByID(ctx, taskID, nil, model.TaskEventRemoved)
+	return true, nil
+}
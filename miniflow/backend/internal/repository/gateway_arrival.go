@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// errGatewayArrivalTaken 到达记录已被并发的分支消费，用于回滚消费事务
+var errGatewayArrivalTaken = errors.New("汇聚网关到达记录已被消费")
+
+// RecordGatewayArrival 记录分支到达汇聚网关
+func (r *ProcessInstanceRepository) RecordGatewayArrival(ctx context.Context, instanceID uint, gatewayID, flowKey string) error {
+	arrival := &model.GatewayArrival{
+		InstanceID: instanceID,
+		GatewayID:  gatewayID,
+		FlowKey:    flowKey,
+	}
+	if err := r.db.WithContext(ctx).Create(arrival).Error; err != nil {
+		r.logger.Error("Failed to record gateway arrival",
+			zap.Uint("instance_id", instanceID),
+			zap.String("gateway_id", gatewayID),
+			zap.Error(err),
+		)
+		return err
+	}
+	return nil
+}
+
+// RecordSkippedGatewayArrivals 为包容网关分叉时未激活的分支记录跳过的到达，汇聚网关不再等待这些入口连线
+func (r *ProcessInstanceRepository) RecordSkippedGatewayArrivals(ctx context.Context, instanceID uint, gatewayID string, flowKeys []string) error {
+	if len(flowKeys) == 0 {
+		return nil
+	}
+	arrivals := make([]model.GatewayArrival, 0, len(flowKeys))
+	for _, flowKey := range flowKeys {
+		arrivals = append(arrivals, model.GatewayArrival{
+			InstanceID: instanceID,
+			GatewayID:  gatewayID,
+			FlowKey:    flowKey,
+			Skipped:    true,
+		})
+	}
+	if err := r.db.WithContext(ctx).Create(&arrivals).Error; err != nil {
+		r.logger.Error("Failed to record skipped gateway arrivals",
+			zap.Uint("instance_id", instanceID),
+			zap.String("gateway_id", gatewayID),
+			zap.Error(err),
+		)
+		return err
+	}
+	return nil
+}
+
+// GetPendingGatewayArrivals 获取汇聚网关上未消费的到达记录，按到达顺序排列
+func (r *ProcessInstanceRepository) GetPendingGatewayArrivals(ctx context.Context, instanceID uint, gatewayID string) ([]model.GatewayArrival, error) {
+	var arrivals []model.GatewayArrival
+	err := r.db.WithContext(ctx).Where("instance_id = ? AND gateway_id = ? AND consumed = ?", instanceID, gatewayID, false).
+		Order("id ASC").
+		Find(&arrivals).Error
+	return arrivals, err
+}
+
+// GetInstancePendingArrivals 获取实例所有汇聚网关上未消费的到达记录
+func (r *ProcessInstanceRepository) GetInstancePendingArrivals(ctx context.Context, instanceID uint) ([]model.GatewayArrival, error) {
+	var arrivals []model.GatewayArrival
+	err := r.db.WithContext(ctx).Where("instance_id = ? AND consumed = ?", instanceID, false).
+		Order("id ASC").
+		Find(&arrivals).Error
+	return arrivals, err
+}
+
+// ConsumeGatewayArrivals 消费汇聚网关的到达记录，只有全部记录都由本次调用消费时才返回 true；
+// 并发到达的分支中只有一个能完成消费并推进流程
+func (r *ProcessInstanceRepository) ConsumeGatewayArrivals(ctx context.Context, ids []uint) (bool, error) {
+	consumed := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.GatewayArrival{}).
+			Where("id IN ? AND consumed = ?", ids, false).
+			Update("consumed", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != int64(len(ids)) {
+			return errGatewayArrivalTaken
+		}
+		consumed = true
+		return nil
+	})
+	if errors.Is(err, errGatewayArrivalTaken) {
+		return false, nil
+	}
+	return consumed, err
+}
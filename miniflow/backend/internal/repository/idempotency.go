@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/database"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// IdempotencyRepository 幂等请求记录数据访问层
+type IdempotencyRepository struct {
+	db     *database.Database
+	logger *logger.Logger
+}
+
+// NewIdempotencyRepository 创建新的幂等请求记录仓库
+func NewIdempotencyRepository(db *database.Database, logger *logger.Logger) *IdempotencyRepository {
+	return &IdempotencyRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Get 获取用户的幂等请求记录，不存在时返回 nil
+func (r *IdempotencyRepository) Get(ctx context.Context, userID uint, key string) (*model.IdempotencyRecord, error) {
+	var records []model.IdempotencyRecord
+	err := r.db.WithContext(ctx).Where("user_id = ? AND "+r.db.Quote("key")+" = ?", userID, key).
+		Limit(1).
+		Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	return &records[0], nil
+}
+
+// Create 创建幂等请求记录，唯一索引保证同一个键只有一个请求能创建成功
+func (r *IdempotencyRepository) Create(ctx context.Context, record *model.IdempotencyRecord) error {
+	return r.db.WithContext(ctx).Create(record).Error
+}
+
+// Update 保存首次请求的响应
+func (r *IdempotencyRepository) Update(ctx context.Context, record *model.IdempotencyRecord) error {
+	if err := r.db.WithContext(ctx).Save(record).Error; err != nil {
+		r.logger.Error("Failed to save idempotent response", zap.Uint("id", record.ID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Delete 物理删除幂等请求记录，释放唯一索引
+func (r *IdempotencyRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Unscoped().Delete(&model.IdempotencyRecord{}, id).Error
+}
+
+// DeleteExpired 删除已过期的幂等请求记录，返回删除数量
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Unscoped().Where("expires_at < ?", now).Delete(&model.IdempotencyRecord{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/database"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// IncidentRepository 异常事件数据访问层
+type IncidentRepository struct {
+	db     *database.Database
+	logger *logger.Logger
+}
+
+// NewIncidentRepository 创建新的异常事件仓库
+func NewIncidentRepository(db *database.Database, logger *logger.Logger) *IncidentRepository {
+	return &IncidentRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Create 创建异常事件
+func (r *IncidentRepository) Create(ctx context.Context, incident *model.Incident) error {
+	if err := r.db.WithContext(ctx).Create(incident).Error; err != nil {
+		r.logger.Error("Failed to create incident", zap.Uint("instance_id", incident.InstanceID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetByID 根据ID获取异常事件
+func (r *IncidentRepository) GetByID(ctx context.Context, id uint) (*model.Incident, error) {
+	var incident model.Incident
+	if err := r.db.WithContext(ctx).First(&incident, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("异常事件不存在")
+		}
+		return nil, err
+	}
+	return &incident, nil
+}
+
+// Update 更新异常事件
+func (r *IncidentRepository) Update(ctx context.Context, incident *model.Incident) error {
+	return r.db.WithContext(ctx).Save(incident).Error
+}
+
+// List 分页获取异常事件
+func (r *IncidentRepository) List(ctx context.Context, offset, limit int, filters map[string]interface{}) ([]model.Incident, int64, error) {
+	var incidents []model.Incident
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&model.Incident{})
+	for key, value := range filters {
+		switch key {
+		case "status":
+			if err := model.IncidentStatuses.ValidateFilter(value); err != nil {
+				return nil, 0, err
+			}
+			query = query.Where("status = ?", value)
+		case "type":
+			if err := model.IncidentTypes.ValidateFilter(value); err != nil {
+				return nil, 0, err
+			}
+			query = query.Where("type = ?", value)
+		case "code":
+			query = query.Where("code = ?", value)
+		case "instance_id":
+			query = query.Where("instance_id = ?", value)
+		}
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Offset(offset).
+		Limit(limit).
+		Order("created_at DESC").
+		Find(&incidents).Error
+
+	if err != nil {
+		r.logger.Error("Failed to list incidents", zap.Error(err))
+		return nil, 0, err
+	}
+
+	return incidents, total, nil
+}
+
+// GetByInstance 获取流程实例的全部异常事件
+func (r *IncidentRepository) GetByInstance(ctx context.Context, instanceID uint) ([]model.Incident, error) {
+	var incidents []model.Incident
+	err := r.db.WithContext(ctx).Where("instance_id = ?", instanceID).
+		Order("created_at ASC").
+		Find(&incidents).Error
+	return incidents, err
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 归档的错误
+var (
+	// ErrInstanceArchiveNotFound 流程实例没有归档记录
+	ErrInstanceArchiveNotFound = errors.New("流程实例没有归档记录")
+	// ErrInstanceArchiveStale 导出后实例的子实例发生了变化，需要重新导出
+	ErrInstanceArchiveStale = errors.New("流程实例在导出后发生了变化")
+)
+
+// GetArchivable 获取结束时间早于 endedBefore 的顶层流程实例，最早结束的在前，子实例随父实例一起归档
+func (r *ProcessInstanceRepository) GetArchivable(ctx context.Context, endedBefore time.Time, limit int) ([]model.ProcessInstance, error) {
+	var instances []model.ProcessInstance
+	err := r.db.WithContext(ctx).
+		Where("parent_instance_id IS NULL").
+		Where("status IN ?", []string{model.InstanceStatusCompleted, model.InstanceStatusCancelled, model.InstanceStatusFailed}).
+		Where("end_time < ?", endedBefore).
+		Order("end_time ASC, id ASC").
+		Limit(limit).
+		Find(&instances).Error
+	if err != nil {
+		r.logger.Error("Failed to get archivable instances", zap.Error(err))
+		return nil, err
+	}
+	return instances, nil
+}
+
+// GetInstanceTree 获取实例及其全部子实例，按层级从上到下排列
+func (r *ProcessInstanceRepository) GetInstanceTree(ctx context.Context, id uint) ([]model.ProcessInstance, error) {
+	return loadInstanceTree(r.db.WithContext(ctx), id, false)
+}
+
+// ArchiveInstance 保存实例及其子实例的归档记录并从运行表中删除这些实例
+// 在事务中锁定实例行后再检查状态，与并发的流程推进互斥；锁定的实例必须与导出的实例一致
+func (r *ProcessInstanceRepository) ArchiveInstance(ctx context.Context, id uint, archives []*model.InstanceArchive) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		instances, err := lockInstanceTree(tx, id)
+		if err != nil {
+			return err
+		}
+		byInstance := make(map[uint]*model.InstanceArchive, len(archives))
+		for _, archive := range archives {
+			byInstance[archive.InstanceID] = archive
+		}
+		if len(instances) != len(byInstance) {
+			return ErrInstanceArchiveStale
+		}
+		for _, instance := range instances {
+			if !model.IsTerminalInstanceStatus(instance.Status) {
+				return ErrInstanceNotTerminal
+			}
+			if byInstance[instance.ID] == nil {
+				return ErrInstanceArchiveStale
+			}
+		}
+
+		// 从最深的子实例开始删除
+		for i := len(instances) - 1; i >= 0; i-- {
+			if err := tx.Create(byInstance[instances[i].ID]).Error; err != nil {
+				return err
+			}
+			if err := deleteInstanceRows(tx, instances[i].ID, make(map[string]int64)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, ErrInstanceNotTerminal) && !errors.Is(err, ErrInstanceArchiveStale) && !errors.Is(err, gorm.ErrRecordNotFound) {
+		r.logger.Error("Failed to archive process instance", zap.Uint("instance_id", id), zap.Error(err))
+	}
+	return err
+}
+
+// GetArchive 获取流程实例的归档记录
+func (r *ProcessInstanceRepository) GetArchive(ctx context.Context, instanceID uint) (*model.InstanceArchive, error) {
+	var archive model.InstanceArchive
+	err := r.db.WithContext(ctx).Where("instance_id = ?", instanceID).First(&archive).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInstanceArchiveNotFound
+		}
+		return nil, err
+	}
+	return &archive, nil
+}
+
+// ListArchives 分页获取归档记录，最近归档的在前
+func (r *ProcessInstanceRepository) ListArchives(ctx context.Context, offset, limit int, filters map[string]interface{}) ([]model.InstanceArchive, int64, error) {
+	var archives []model.InstanceArchive
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&model.InstanceArchive{})
+	for key, value := range filters {
+		switch key {
+		case "definition_key", "business_key", "starter_id", "final_status":
+			query = query.Where(key+" = ?", value)
+		}
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("archived_at DESC, id DESC").Offset(offset).Limit(limit).Find(&archives).Error
+	if err != nil {
+		r.logger.Error("Failed to list instance archives", zap.Error(err))
+		return nil, 0, err
+	}
+	return archives, total, nil
+}
+
+// ListArchivesAfter 按ID顺序获取ID大于 afterID 的归档记录，用于分批遍历全部归档
+func (r *ProcessInstanceRepository) ListArchivesAfter(ctx context.Context, afterID uint, limit int) ([]model.InstanceArchive, error) {
+	var archives []model.InstanceArchive
+	err := r.db.WithContext(ctx).Where("id > ?", afterID).Order("id ASC").Limit(limit).Find(&archives).Error
+	if err != nil {
+		r.logger.Error("Failed to list instance archives", zap.Uint("after_id", afterID), zap.Error(err))
+		return nil, err
+	}
+	return archives, nil
+}
+
+// UpdateArchiveContent 归档文件被改写后更新归档记录中的文件大小和校验和
+func (r *ProcessInstanceRepository) UpdateArchiveContent(ctx context.Context, id uint, size int64, checksum string) error {
+	err := r.db.WithContext(ctx).Model(&model.InstanceArchive{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"size": size, "checksum": checksum}).Error
+	if err != nil {
+		r.logger.Error("Failed to update instance archive", zap.Uint("archive_id", id), zap.Error(err))
+	}
+	return err
+}
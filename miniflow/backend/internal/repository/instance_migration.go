@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// migratedTaskStatuses 迁移时随实例改到新节点的任务状态，已结束的任务保留原节点作为历史
+var migratedTaskStatuses = []string{
+	model.TaskStatusCreated,
+	model.TaskStatusAssigned,
+	model.TaskStatusClaimed,
+	model.TaskStatusInProgress,
+}
+
+// MigrateInstance 在一个事务中把流程实例迁移到另一个流程定义版本
+//
+// 实例以乐观锁更新定义和当前节点，版本不一致时返回 ErrInstanceVersionConflict；
+// 未结束的任务、等待中的定时器、未消费的汇聚网关到达记录和子实例的调用节点按 nodeMapping 改为新节点。
+// 成功后 instance 的定义、当前节点和版本号为保存后的值。
+func (r *ProcessInstanceRepository) MigrateInstance(ctx context.Context, instance *model.ProcessInstance, definitionID uint, nodeMapping map[string]string) error {
+	currentNode := instance.CurrentNode
+	if mapped, ok := nodeMapping[currentNode]; ok {
+		currentNode = mapped
+	}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.ProcessInstance{}).
+			Where("id = ? AND version = ?", instance.ID, instance.Version).
+			Updates(map[string]interface{}{
+				"definition_id": definitionID,
+				"current_node":  currentNode,
+				"version":       instance.Version + 1,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInstanceVersionConflict
+		}
+		if len(nodeMapping) == 0 {
+			return nil
+		}
+
+		remaps := []struct {
+			model  interface{}
+			column string
+			where  string
+			args   []interface{}
+		}{
+			{&model.TaskInstance{}, "node_id", "instance_id = ? AND status IN ?", []interface{}{instance.ID, migratedTaskStatuses}},
+			{&model.ProcessTimer{}, "node_id", "instance_id = ? AND status = ?", []interface{}{instance.ID, model.TimerStatusWaiting}},
+			{&model.MessageSubscription{}, "node_id", "instance_id = ? AND status = ?", []interface{}{instance.ID, model.MessageSubscriptionWaiting}},
+			{&model.GatewayArrival{}, "gateway_id", "instance_id = ? AND consumed = ?", []interface{}{instance.ID, false}},
+			{&model.ProcessInstance{}, "parent_node_id", "parent_instance_id = ?", []interface{}{instance.ID}},
+		}
+		for _, remap := range remaps {
+			if err := remapNodeColumn(tx, remap.model, remap.column, remap.where, remap.args, nodeMapping); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, ErrInstanceVersionConflict) {
+			r.logger.Error("Failed to migrate process instance", zap.Uint("id", instance.ID), zap.Error(err))
+		}
+		return err
+	}
+
+	instance.DefinitionID = definitionID
+	instance.CurrentNode = currentNode
+	instance.Version++
+	return nil
+}
+
+// remapNodeColumn 把满足 where 条件的行中 column 列的节点ID按 nodeMapping 改为新ID
+// 先按原ID取出行再按主键更新，映射中互换的两个节点不会互相覆盖
+func remapNodeColumn(tx *gorm.DB, m interface{}, column, where string, args []interface{}, nodeMapping map[string]string) error {
+	oldIDs := make([]string, 0, len(nodeMapping))
+	for oldID := range nodeMapping {
+		oldIDs = append(oldIDs, oldID)
+	}
+
+	var rows []struct {
+		ID     uint
+		NodeID string
+	}
+	err := tx.Model(m).
+		Select("id, "+column+" AS node_id").
+		Where(where, args...).
+		Where(column+" IN ?", oldIDs).
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	byTarget := make(map[string][]uint)
+	for _, row := range rows {
+		if newID := nodeMapping[row.NodeID]; newID != row.NodeID {
+			byTarget[newID] = append(byTarget[newID], row.ID)
+		}
+	}
+	for newID, ids := range byTarget {
+		if err := tx.Model(m).Where("id IN ?", ids).Update(column, newID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInstanceNotTerminal 实例或其子实例尚未结束，不能清除
+var ErrInstanceNotTerminal = errors.New("流程实例尚未结束，不能清除")
+
+// purgeInstanceTables 按实例ID物理删除的关联表，先删除依赖任务的记录
+var purgeInstanceTables = []struct {
+	name  string
+	model interface{}
+}{
+	{"task_events", &model.TaskEvent{}},
+	{"execution_logs", &model.ExecutionLog{}},
+	{"incidents", &model.Incident{}},
+	{"gateway_arrivals", &model.GatewayArrival{}},
+	{"process_timers", &model.ProcessTimer{}},
+	{"message_subscriptions", &model.MessageSubscription{}},
+	{"webhook_deliveries", &model.WebhookDelivery{}},
+	{"activity_histories", &model.ActivityHistory{}},
+	{"execution_traces", &model.ExecutionTrace{}},
+	{"task_comments", &model.TaskComment{}},
+	{"task_reminders", &model.TaskReminder{}},
+	{"task_instances", &model.TaskInstance{}},
+}
+
+// PurgeInstance 物理删除已结束的流程实例及其子实例和全部关联数据，并为每个实例记录清除凭证
+// 在事务中锁定实例行后再检查状态，与并发的流程推进互斥；报表事实表为保留统计口径只做匿名化
+func (r *ProcessInstanceRepository) PurgeInstance(ctx context.Context, id uint, purgedBy uint, reason string) ([]model.PurgeCertificate, error) {
+	var certificates []model.PurgeCertificate
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		instances, err := lockInstanceTree(tx, id)
+		if err != nil {
+			return err
+		}
+		for _, instance := range instances {
+			if !model.IsTerminalInstanceStatus(instance.Status) {
+				return ErrInstanceNotTerminal
+			}
+		}
+
+		now := time.Now()
+		// 从最深的子实例开始删除
+		for i := len(instances) - 1; i >= 0; i-- {
+			instance := &instances[i]
+			rows, err := purgeInstanceRows(tx, instance.ID)
+			if err != nil {
+				return err
+			}
+
+			cert := model.NewPurgeCertificate(instance, purgedBy, reason, rows, now)
+			if err := tx.Create(cert).Error; err != nil {
+				return err
+			}
+			certificates = append(certificates, *cert)
+		}
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, ErrInstanceNotTerminal) && !errors.Is(err, gorm.ErrRecordNotFound) {
+			r.logger.Error("Failed to purge process instance", zap.Uint("instance_id", id), zap.Error(err))
+		}
+		return nil, err
+	}
+
+	return certificates, nil
+}
+
+// GetPurgeCertificates 获取实例的清除凭证
+func (r *ProcessInstanceRepository) GetPurgeCertificates(ctx context.Context, instanceID uint) ([]model.PurgeCertificate, error) {
+	var certificates []model.PurgeCertificate
+	err := r.db.WithContext(ctx).Where("instance_id = ?", instanceID).Order("id ASC").Find(&certificates).Error
+	return certificates, err
+}
+
+// lockInstanceTree 锁定实例及其全部子实例，返回按层级从上到下排列的实例
+func lockInstanceTree(tx *gorm.DB, id uint) ([]model.ProcessInstance, error) {
+	return loadInstanceTree(tx, id, true)
+}
+
+// loadInstanceTree 读取实例及其全部子实例，返回按层级从上到下排列的实例，lock 为 true 时锁定读取的行
+func loadInstanceTree(db *gorm.DB, id uint, lock bool) ([]model.ProcessInstance, error) {
+	query := func() *gorm.DB {
+		if lock {
+			return db.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Definition")
+		}
+		return db.Preload("Definition")
+	}
+
+	var root model.ProcessInstance
+	if err := query().First(&root, id).Error; err != nil {
+		return nil, err
+	}
+
+	instances := []model.ProcessInstance{root}
+	parents := []uint{root.ID}
+	for len(parents) > 0 {
+		var children []model.ProcessInstance
+		err := query().
+			Where("parent_instance_id IN ?", parents).
+			Find(&children).Error
+		if err != nil {
+			return nil, err
+		}
+
+		parents = parents[:0]
+		for _, child := range children {
+			instances = append(instances, child)
+			parents = append(parents, child.ID)
+		}
+	}
+	return instances, nil
+}
+
+// purgeInstanceRows 删除单个实例的关联数据，返回各表受影响的行数
+func purgeInstanceRows(tx *gorm.DB, instanceID uint) (map[string]int64, error) {
+	rows := make(map[string]int64)
+
+	// 报表事实表保留统计数据，去掉与人员的关联
+	result := tx.Model(&model.ReportFactInstance{}).Where("instance_id = ?", instanceID).Update("starter_id", 0)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	rows["rpt_fact_instance_anonymized"] = result.RowsAffected
+
+	result = tx.Model(&model.ReportFactTask{}).Where("instance_id = ?", instanceID).Update("assignee_id", nil)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	rows["rpt_fact_task_anonymized"] = result.RowsAffected
+
+	if err := deleteInstanceRows(tx, instanceID, rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// deleteInstanceRows 物理删除单个实例及其运行数据，各表删除的行数记入 rows
+func deleteInstanceRows(tx *gorm.DB, instanceID uint, rows map[string]int64) error {
+	for _, table := range purgeInstanceTables {
+		result := tx.Unscoped().Where("instance_id = ?", instanceID).Delete(table.model)
+		if result.Error != nil {
+			return result.Error
+		}
+		rows[table.name] = result.RowsAffected
+	}
+
+	// 重复标记两端都可能引用该实例
+	result := tx.Unscoped().Where("instance_id = ? OR duplicate_of_id = ?", instanceID, instanceID).Delete(&model.InstanceDuplicate{})
+	if result.Error != nil {
+		return result.Error
+	}
+	rows["instance_duplicates"] = result.RowsAffected
+
+	result = tx.Unscoped().Delete(&model.ProcessInstance{}, instanceID)
+	if result.Error != nil {
+		return result.Error
+	}
+	rows["process_instances"] = result.RowsAffected
+	return nil
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// GetStalledInstances 获取 before 之前最后更新、已没有任何待处理工作的运行中实例，按ID排列
+//
+// 待处理工作包括未完成的任务、未处理的异常事件、等待中或失败的定时器、等待中的消息订阅、未结束或失败的异步作业，
+// 以及运行中或暂停的子实例。运行中的实例没有任何待处理工作时不会再被推进，通常是推进过程中服务中断造成的。
+func (r *ProcessInstanceRepository) GetStalledInstances(ctx context.Context, before time.Time, afterID uint, limit int) ([]model.ProcessInstance, error) {
+	var instances []model.ProcessInstance
+	err := r.db.WithContext(ctx).Preload("Definition").
+		Where("process_instances.status = ? AND process_instances.updated_at < ? AND process_instances.id > ?",
+			model.InstanceStatusRunning, before, afterID).
+		Where("NOT EXISTS (SELECT 1 FROM task_instances t WHERE t.instance_id = process_instances.id AND t.status IN ? AND t.deleted_at IS NULL)",
+			[]string{model.TaskStatusCreated, model.TaskStatusAssigned, model.TaskStatusClaimed, model.TaskStatusInProgress}).
+		Where("NOT EXISTS (SELECT 1 FROM incidents n WHERE n.instance_id = process_instances.id AND n.status = ? AND n.deleted_at IS NULL)",
+			model.IncidentStatusOpen).
+		Where("NOT EXISTS (SELECT 1 FROM process_timers m WHERE m.instance_id = process_instances.id AND m.status IN ? AND m.deleted_at IS NULL)",
+			[]string{model.TimerStatusWaiting, model.TimerStatusFailed}).
+		Where("NOT EXISTS (SELECT 1 FROM message_subscriptions s WHERE s.instance_id = process_instances.id AND s.status = ? AND s.deleted_at IS NULL)",
+			model.MessageSubscriptionWaiting).
+		Where("NOT EXISTS (SELECT 1 FROM async_jobs j WHERE j.instance_id = process_instances.id AND j.status IN ? AND j.deleted_at IS NULL)",
+			[]string{model.AsyncJobStatusPending, model.AsyncJobStatusRunning, model.AsyncJobStatusFailed}).
+		Where("NOT EXISTS (SELECT 1 FROM process_instances c WHERE c.parent_instance_id = process_instances.id AND c.status IN ? AND c.deleted_at IS NULL)",
+			[]string{model.InstanceStatusRunning, model.InstanceStatusSuspended}).
+		Order("process_instances.id ASC").
+		Limit(limit).
+		Find(&instances).Error
+	if err != nil {
+		r.logger.Error("Failed to get stalled process instances", zap.Error(err))
+		return nil, err
+	}
+	return instances, nil
+}
+
+// RequeueExpiredAsyncJobs 把锁已过期的执行中作业改回等待执行，返回被重新排队的作业
+//
+// 执行器锁定作业后异常退出时作业停留在执行中，锁过期后视为持有者已失效。
+func (r *ProcessInstanceRepository) RequeueExpiredAsyncJobs(ctx context.Context, now time.Time) ([]model.AsyncJob, error) {
+	var expired []model.AsyncJob
+	err := r.db.WithContext(ctx).Where("status = ? AND lock_expires_at < ?", model.AsyncJobStatusRunning, now).
+		Order("id ASC").
+		Find(&expired).Error
+	if err != nil {
+		return nil, err
+	}
+
+	requeued := make([]model.AsyncJob, 0, len(expired))
+	for _, job := range expired {
+		result := r.db.WithContext(ctx).Model(&model.AsyncJob{}).
+			Where("id = ? AND status = ? AND locked_by = ? AND lock_expires_at < ?", job.ID, model.AsyncJobStatusRunning, job.LockedBy, now).
+			Updates(map[string]interface{}{
+				"status":          model.AsyncJobStatusPending,
+				"due_at":          now,
+				"locked_by":       "",
+				"lock_expires_at": nil,
+			})
+		if result.Error != nil {
+			r.logger.Error("Failed to requeue async job", zap.Uint("job_id", job.ID), zap.Error(result.Error))
+			return requeued, result.Error
+		}
+		if result.RowsAffected == 1 {
+			requeued = append(requeued, job)
+		}
+	}
+	return requeued, nil
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// GetCancelledSince 获取 since 之后取消的流程实例，最近取消的在前
+func (r *ProcessInstanceRepository) GetCancelledSince(ctx context.Context, since time.Time, offset, limit int) ([]model.ProcessInstance, int64, error) {
+	var instances []model.ProcessInstance
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&model.ProcessInstance{}).
+		Where("status = ? AND cancelled_at >= ?", model.InstanceStatusCancelled, since)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("Definition").
+		Preload("Starter").
+		Order("cancelled_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&instances).Error
+	if err != nil {
+		r.logger.Error("Failed to get cancelled process instances", zap.Error(err))
+		return nil, 0, err
+	}
+	return instances, total, nil
+}
+
+// RestoreTimers 把随实例取消的定时器恢复为等待中，已到期的定时器由调度器随后触发
+func (r *ProcessInstanceRepository) RestoreTimers(ctx context.Context, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Model(&model.ProcessTimer{}).
+		Where("id IN ? AND status = ?", ids, model.TimerStatusCancelled).
+		Update("status", model.TimerStatusWaiting).Error
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// maxVariableUpdateAttempts 变量写入遇到并发修改时的最大尝试次数
+const maxVariableUpdateAttempts = 5
+
+// GetVariables 只读取流程实例的变量列（JSON），实例不存在时返回 gorm.ErrRecordNotFound
+func (r *ProcessInstanceRepository) GetVariables(ctx context.Context, instanceID uint) (string, error) {
+	var rows []struct {
+		Variables string
+	}
+	err := r.db.WithContext(ctx).Model(&model.ProcessInstance{}).
+		Select("variables").
+		Where("id = ?", instanceID).
+		Limit(1).
+		Scan(&rows).Error
+	if err != nil {
+		return "", err
+	}
+	if len(rows) == 0 {
+		return "", gorm.ErrRecordNotFound
+	}
+	return rows[0].Variables, nil
+}
+
+// UpdateVariables 把修改和删除的变量合并到实例最新的变量上
+// 以版本号做乐观锁并递增版本，其他操作持有的旧版本实例会在写回时发现冲突并重试
+func (r *ProcessInstanceRepository) UpdateVariables(ctx context.Context, instanceID uint, changed map[string]interface{}, removed []string) error {
+	for attempt := 1; ; attempt++ {
+		var instance model.ProcessInstance
+		err := r.db.WithContext(ctx).Select("id", "version", "variables").First(&instance, instanceID).Error
+		if err != nil {
+			return err
+		}
+
+		variables := make(map[string]interface{})
+		if instance.Variables != "" {
+			if err := json.Unmarshal([]byte(instance.Variables), &variables); err != nil {
+				return fmt.Errorf("解析流程变量失败: %v", err)
+			}
+		}
+		for key, value := range changed {
+			variables[key] = value
+		}
+		for _, key := range removed {
+			delete(variables, key)
+		}
+		data, err := json.Marshal(variables)
+		if err != nil {
+			return fmt.Errorf("序列化流程变量失败: %v", err)
+		}
+
+		result := r.db.WithContext(ctx).Model(&model.ProcessInstance{}).
+			Where("id = ? AND version = ?", instanceID, instance.Version).
+			Updates(map[string]interface{}{
+				"variables": string(data),
+				"version":   instance.Version + 1,
+			})
+		if result.Error != nil {
+			r.logger.Error("Failed to update process variables", zap.Uint("instance_id", instanceID), zap.Error(result.Error))
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			return nil
+		}
+		if attempt >= maxVariableUpdateAttempts {
+			return ErrInstanceVersionConflict
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/database"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// notificationJobStatusExpr 延迟通知没有状态列，按投递时间和失败原因推导作业状态
+const notificationJobStatusExpr = "CASE WHEN sent_at IS NOT NULL THEN 'sent' WHEN last_error <> '' THEN 'failed' ELSE 'pending' END"
+
+// jobTable 描述一类后台作业所在的表、状态表达式和计算积压时长的时间列
+type jobTable struct {
+	model      interface{}
+	statusExpr string
+	ageColumn  string
+	statuses   model.Enum
+}
+
+// jobTables 作业面板支持的作业类型
+var jobTables = map[string]jobTable{
+	model.JobTypeTimer:        {model: &model.ProcessTimer{}, statusExpr: "status", ageColumn: "due_at", statuses: model.TimerStatuses},
+	model.JobTypeWebhook:      {model: &model.WebhookDelivery{}, statusExpr: "status", ageColumn: "created_at", statuses: model.WebhookDeliveryStatuses},
+	model.JobTypeNotification: {model: &model.NotificationQueueItem{}, statusExpr: notificationJobStatusExpr, ageColumn: "deliver_after", statuses: model.NotificationJobStatuses},
+	model.JobTypeAsync:        {model: &model.AsyncJob{}, statusExpr: "status", ageColumn: "due_at", statuses: model.AsyncJobStatuses},
+}
+
+// JobStatusCount 按状态统计的作业数量
+type JobStatusCount struct {
+	Status string `json:"status"`
+	Count  int64  `json:"count"`
+}
+
+// JobRepository 后台作业数据访问层，为运维作业面板提供跨表的统计和操作
+type JobRepository struct {
+	db     *database.Database
+	logger *logger.Logger
+}
+
+// NewJobRepository 创建新的后台作业仓库
+func NewJobRepository(db *database.Database, logger *logger.Logger) *JobRepository {
+	return &JobRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// JobStatuses 返回指定类型作业的状态取值集合
+func JobStatuses(jobType string) model.Enum {
+	return jobTables[jobType].statuses
+}
+
+// IsJobType 判断是否为作业面板支持的作业类型
+func IsJobType(jobType string) bool {
+	_, ok := jobTables[jobType]
+	return ok
+}
+
+// CountByStatus 按状态统计指定类型的作业数量
+func (r *JobRepository) CountByStatus(ctx context.Context, jobType string) ([]JobStatusCount, error) {
+	table := jobTables[jobType]
+
+	var counts []JobStatusCount
+	err := r.db.WithContext(ctx).Model(table.model).
+		Select(table.statusExpr + " AS status, COUNT(*) AS count").
+		Group("status").
+		Order("status").
+		Scan(&counts).Error
+	if err != nil {
+		r.logger.Error("Failed to count jobs by status", zap.String("job_type", jobType), zap.Error(err))
+		return nil, err
+	}
+	return counts, nil
+}
+
+// CountByAge 统计指定状态下时间列落在 [from, to) 区间内的作业数量，边界为 nil 时不限制
+func (r *JobRepository) CountByAge(ctx context.Context, jobType string, statuses []string, from, to *time.Time) (int64, error) {
+	table := jobTables[jobType]
+
+	query := r.db.WithContext(ctx).Model(table.model).Where(table.statusExpr+" IN ?", statuses)
+	if from != nil {
+		query = query.Where(table.ageColumn+" >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where(table.ageColumn+" < ?", *to)
+	}
+
+	var count int64
+	err := query.Count(&count).Error
+	return count, err
+}
+
+// ListTimers 分页获取流程定时器，按到期时间排列
+func (r *JobRepository) ListTimers(ctx context.Context, status string, offset, limit int) ([]model.ProcessTimer, int64, error) {
+	var timers []model.ProcessTimer
+	total, err := r.list(ctx, model.JobTypeTimer, status, "due_at ASC", offset, limit, &timers)
+	return timers, total, err
+}
+
+// ListWebhookDeliveries 分页获取回调投递记录，最新的在前
+func (r *JobRepository) ListWebhookDeliveries(ctx context.Context, status string, offset, limit int) ([]model.WebhookDelivery, int64, error) {
+	var deliveries []model.WebhookDelivery
+	total, err := r.list(ctx, model.JobTypeWebhook, status, "created_at DESC", offset, limit, &deliveries)
+	return deliveries, total, err
+}
+
+// ListNotifications 分页获取延迟通知，按投递时间排列
+func (r *JobRepository) ListNotifications(ctx context.Context, status string, offset, limit int) ([]model.NotificationQueueItem, int64, error) {
+	var items []model.NotificationQueueItem
+	total, err := r.list(ctx, model.JobTypeNotification, status, "deliver_after ASC", offset, limit, &items)
+	return items, total, err
+}
+
+// ListAsyncJobs 分页获取异步作业，按到期时间排列
+func (r *JobRepository) ListAsyncJobs(ctx context.Context, status string, offset, limit int) ([]model.AsyncJob, int64, error) {
+	var jobs []model.AsyncJob
+	total, err := r.list(ctx, model.JobTypeAsync, status, "due_at ASC", offset, limit, &jobs)
+	return jobs, total, err
+}
+
+// list 按状态分页查询作业
+func (r *JobRepository) list(ctx context.Context, jobType, status, order string, offset, limit int, dest interface{}) (int64, error) {
+	table := jobTables[jobType]
+
+	query := r.db.WithContext(ctx).Model(table.model)
+	if status != "" {
+		if err := table.statuses.Validate(status); err != nil {
+			return 0, err
+		}
+		query = query.Where(table.statusExpr+" = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		r.logger.Error("Failed to count jobs", zap.String("job_type", jobType), zap.Error(err))
+		return 0, err
+	}
+
+	if err := query.Order(order).Offset(offset).Limit(limit).Find(dest).Error; err != nil {
+		r.logger.Error("Failed to list jobs", zap.String("job_type", jobType), zap.Error(err))
+		return 0, err
+	}
+	return total, nil
+}
+
+// RetryTimer 将等待中或失败的定时器改为立即到期，由调度器在下一轮触发
+func (r *JobRepository) RetryTimer(ctx context.Context, id uint, now time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.ProcessTimer{}).
+		Where("id = ? AND status IN ?", id, []string{model.TimerStatusWaiting, model.TimerStatusFailed}).
+		Updates(map[string]interface{}{
+			"status": model.TimerStatusWaiting,
+			"due_at": now,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// CancelTimer 取消等待中或失败的定时器
+func (r *JobRepository) CancelTimer(ctx context.Context, id uint) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.ProcessTimer{}).
+		Where("id = ? AND status IN ?", id, []string{model.TimerStatusWaiting, model.TimerStatusFailed}).
+		Update("status", model.TimerStatusCancelled)
+	return result.RowsAffected == 1, result.Error
+}
+
+// RetryAsyncJob 将失败的异步作业改为立即到期，由执行器在下一轮执行
+func (r *JobRepository) RetryAsyncJob(ctx context.Context, id uint, now time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.AsyncJob{}).
+		Where("id = ? AND status = ?", id, model.AsyncJobStatusFailed).
+		Updates(map[string]interface{}{
+			"status":       model.AsyncJobStatusPending,
+			"due_at":       now,
+			"completed_at": nil,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// CancelAsyncJob 取消等待中或失败的异步作业
+func (r *JobRepository) CancelAsyncJob(ctx context.Context, id uint) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.AsyncJob{}).
+		Where("id = ? AND status IN ?", id, []string{model.AsyncJobStatusPending, model.AsyncJobStatusFailed}).
+		Update("status", model.AsyncJobStatusCancelled)
+	return result.RowsAffected == 1, result.Error
+}
+
+// RetryNotification 将未投递的延迟通知改为立即投递，由下一轮队列投递发送
+func (r *JobRepository) RetryNotification(ctx context.Context, id uint, now time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.NotificationQueueItem{}).
+		Where("id = ? AND sent_at IS NULL", id).
+		Update("deliver_after", now)
+	return result.RowsAffected == 1, result.Error
+}
+
+// DeleteNotification 删除未投递的延迟通知
+func (r *JobRepository) DeleteNotification(ctx context.Context, id uint) (bool, error) {
+	result := r.db.WithContext(ctx).Where("id = ? AND sent_at IS NULL", id).Delete(&model.NotificationQueueItem{})
+	return result.RowsAffected == 1, result.Error
+}
+
+// DeleteWebhookDelivery 删除不在投递中的回调投递记录
+func (r *JobRepository) DeleteWebhookDelivery(ctx context.Context, id uint) (bool, error) {
+	result := r.db.WithContext(ctx).Where("id = ? AND status <> ?", id, model.WebhookDeliveryPending).Delete(&model.WebhookDelivery{})
+	return result.RowsAffected == 1, result.Error
+}
+
+// Exists 判断指定类型的作业是否存在
+func (r *JobRepository) Exists(ctx context.Context, jobType string, id uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(jobTables[jobType].model).Where("id = ?", id).Count(&count).Error
+	return count > 0, err
+}
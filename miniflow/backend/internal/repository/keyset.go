@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Keyset 游标分页的位置，即上一页最后一行的排序时间和ID，零值表示第一页
+//
+// 游标分页按 (时间, ID) 倒序排列，ID 保证同一时间的行顺序稳定，翻页时不会重复或遗漏，
+// 也不需要像偏移分页那样扫描并跳过前面的行
+type Keyset struct {
+	At time.Time
+	ID uint
+}
+
+// IsZero 是否为第一页
+func (k Keyset) IsZero() bool {
+	return k.At.IsZero() && k.ID == 0
+}
+
+// apply 添加位置之后的条件和 (时间, ID) 倒序排序
+func (k Keyset) apply(db *gorm.DB, table, timeColumn string) *gorm.DB {
+	at := clause.Column{Table: table, Name: timeColumn}
+	id := clause.Column{Table: table, Name: "id"}
+	if !k.IsZero() {
+		db = db.Where(clause.Expr{
+			SQL:  "(? < ? OR (? = ? AND ? < ?))",
+			Vars: []interface{}{at, k.At, at, k.At, id, k.ID},
+		})
+	}
+	return db.Order(clause.OrderByColumn{Column: at, Desc: true}).
+		Order(clause.OrderByColumn{Column: id, Desc: true})
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/database"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// KPISample KPI 评估的样本统计：样本总数和达标数
+type KPISample struct {
+	Total int64 `json:"total"`
+	Met   int64 `json:"met"`
+}
+
+// KPIRepository 流程KPI数据访问层
+type KPIRepository struct {
+	db     *database.Database
+	logger *logger.Logger
+}
+
+// NewKPIRepository 创建新的流程KPI仓库
+func NewKPIRepository(db *database.Database, logger *logger.Logger) *KPIRepository {
+	return &KPIRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Create 创建KPI
+func (r *KPIRepository) Create(ctx context.Context, kpi *model.ProcessKPI) error {
+	if err := r.db.WithContext(ctx).Create(kpi).Error; err != nil {
+		r.logger.Error("Failed to create process KPI", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetByID 根据ID获取KPI
+func (r *KPIRepository) GetByID(ctx context.Context, id uint) (*model.ProcessKPI, error) {
+	var kpi model.ProcessKPI
+	if err := r.db.WithContext(ctx).First(&kpi, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("KPI不存在")
+		}
+		return nil, err
+	}
+	return &kpi, nil
+}
+
+// Update 更新KPI
+func (r *KPIRepository) Update(ctx context.Context, kpi *model.ProcessKPI) error {
+	return r.db.WithContext(ctx).Save(kpi).Error
+}
+
+// Delete 删除KPI及其历史评估记录
+func (r *KPIRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("kpi_id = ?", id).Delete(&model.ProcessKPIMeasurement{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.ProcessKPI{}, id).Error
+	})
+}
+
+// ListByDefinitionKey 获取流程的全部KPI
+func (r *KPIRepository) ListByDefinitionKey(ctx context.Context, key string) ([]model.ProcessKPI, error) {
+	var kpis []model.ProcessKPI
+	err := r.db.WithContext(ctx).Where("definition_key = ?", key).Order("id ASC").Find(&kpis).Error
+	return kpis, err
+}
+
+// ListEnabled 获取全部启用的KPI
+func (r *KPIRepository) ListEnabled(ctx context.Context) ([]model.ProcessKPI, error) {
+	var kpis []model.ProcessKPI
+	err := r.db.WithContext(ctx).Where("enabled = ?", true).Order("id ASC").Find(&kpis).Error
+	return kpis, err
+}
+
+// AddMeasurement 记录一次KPI评估结果
+func (r *KPIRepository) AddMeasurement(ctx context.Context, measurement *model.ProcessKPIMeasurement) error {
+	return r.db.WithContext(ctx).Create(measurement).Error
+}
+
+// ListMeasurements 获取KPI最近的评估记录，按时间正序返回
+func (r *KPIRepository) ListMeasurements(ctx context.Context, kpiID uint, since time.Time) ([]model.ProcessKPIMeasurement, error) {
+	var measurements []model.ProcessKPIMeasurement
+	err := r.db.WithContext(ctx).Where("kpi_id = ? AND measured_at >= ?", kpiID, since).
+		Order("measured_at ASC").
+		Find(&measurements).Error
+	return measurements, err
+}
+
+// Sample 统计KPI在时间窗口内的样本数和达标数
+func (r *KPIRepository) Sample(ctx context.Context, kpi *model.ProcessKPI, since time.Time) (*KPISample, error) {
+	var sample KPISample
+	var query *gorm.DB
+
+	switch kpi.Metric {
+	case model.KPIMetricCycleTime:
+		query = r.db.WithContext(ctx).Table("process_instances i").
+			Select("COUNT(*) AS total, COALESCE(SUM(CASE WHEN "+r.db.SecondsBetween("i.start_time", "i.end_time")+" <= ? THEN 1 ELSE 0 END), 0) AS met",
+				int64(kpi.ThresholdHours*3600)).
+			Joins("JOIN process_definitions d ON d.id = i.definition_id").
+			Where(r.db.Quote("d.key")+" = ? AND i.status = ? AND i.end_time >= ? AND i.deleted_at IS NULL",
+				kpi.DefinitionKey, model.InstanceStatusCompleted, since)
+
+	case model.KPIMetricCompletionRate:
+		query = r.db.WithContext(ctx).Table("process_instances i").
+			Select("COUNT(*) AS total, COALESCE(SUM(CASE WHEN i.status = ? THEN 1 ELSE 0 END), 0) AS met",
+				model.InstanceStatusCompleted).
+			Joins("JOIN process_definitions d ON d.id = i.definition_id").
+			Where(r.db.Quote("d.key")+" = ? AND i.status IN ? AND i.end_time >= ? AND i.deleted_at IS NULL",
+				kpi.DefinitionKey,
+				[]string{model.InstanceStatusCompleted, model.InstanceStatusFailed, model.InstanceStatusCancelled},
+				since)
+
+	case model.KPIMetricTaskOnTime:
+		query = r.db.WithContext(ctx).Table("task_instances t").
+			Select("COUNT(*) AS total, COALESCE(SUM(CASE WHEN t.complete_time <= t.due_date THEN 1 ELSE 0 END), 0) AS met").
+			Joins("JOIN process_instances i ON i.id = t.instance_id").
+			Joins("JOIN process_definitions d ON d.id = i.definition_id").
+			Where(r.db.Quote("d.key")+" = ? AND t.status = ? AND t.due_date IS NOT NULL AND t.complete_time >= ? AND t.deleted_at IS NULL",
+				kpi.DefinitionKey, model.TaskStatusCompleted, since)
+
+	default:
+		return nil, fmt.Errorf("不支持的KPI指标: %s", kpi.Metric)
+	}
+
+	if err := query.Scan(&sample).Error; err != nil {
+		r.logger.Error("Failed to sample process KPI", zap.Uint("kpi_id", kpi.ID), zap.Error(err))
+		return nil, err
+	}
+	return &sample, nil
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// CreateMessageSubscription 创建消息订阅
+func (r *ProcessInstanceRepository) CreateMessageSubscription(ctx context.Context, subscription *model.MessageSubscription) error {
+	if err := r.db.WithContext(ctx).Create(subscription).Error; err != nil {
+		r.logger.Error("Failed to create message subscription",
+			zap.Uint("instance_id", subscription.InstanceID),
+			zap.String("node_id", subscription.NodeID),
+			zap.Error(err),
+		)
+		return err
+	}
+	return nil
+}
+
+// GetWaitingMessageSubscriptions 获取与消息名称和关联键匹配的等待中订阅，按创建顺序排列
+func (r *ProcessInstanceRepository) GetWaitingMessageSubscriptions(ctx context.Context, messageName, correlationKey string) ([]model.MessageSubscription, error) {
+	var subscriptions []model.MessageSubscription
+	err := r.db.WithContext(ctx).Where("message_name = ? AND correlation_key = ? AND status = ?",
+		messageName, correlationKey, model.MessageSubscriptionWaiting).
+		Order("id ASC").
+		Find(&subscriptions).Error
+	if err != nil {
+		r.logger.Error("Failed to get message subscriptions", zap.String("message_name", messageName), zap.Error(err))
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// MarkMessageCorrelated 将等待中的订阅标记为已关联，订阅已被并发关联或已取消时返回 false
+func (r *ProcessInstanceRepository) MarkMessageCorrelated(ctx context.Context, id uint, now time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.MessageSubscription{}).
+		Where("id = ? AND status = ?", id, model.MessageSubscriptionWaiting).
+		Updates(map[string]interface{}{
+			"status":        model.MessageSubscriptionCorrelated,
+			"correlated_at": now,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// GetWaitingMessages 获取实例上等待中的消息订阅
+func (r *ProcessInstanceRepository) GetWaitingMessages(ctx context.Context, instanceID uint) ([]model.MessageSubscription, error) {
+	var subscriptions []model.MessageSubscription
+	err := r.db.WithContext(ctx).Where("instance_id = ? AND status = ?", instanceID, model.MessageSubscriptionWaiting).
+		Order("id ASC").
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// CancelMessageSubscriptions 取消实例上等待中的消息订阅，nodeID 为空时取消实例的全部订阅
+func (r *ProcessInstanceRepository) CancelMessageSubscriptions(ctx context.Context, instanceID uint, nodeID string) error {
+	query := r.db.WithContext(ctx).Model(&model.MessageSubscription{}).
+		Where("instance_id = ? AND status = ?", instanceID, model.MessageSubscriptionWaiting)
+	if nodeID != "" {
+		query = query.Where("node_id = ?", nodeID)
+	}
+	return query.Update("status", model.MessageSubscriptionCancelled).Error
+}
+
+// RestoreMessageSubscriptions 把随实例取消的消息订阅恢复为等待中
+func (r *ProcessInstanceRepository) RestoreMessageSubscriptions(ctx context.Context, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Model(&model.MessageSubscription{}).
+		Where("id IN ? AND status = ?", ids, model.MessageSubscriptionCancelled).
+		Update("status", model.MessageSubscriptionWaiting).Error
+}
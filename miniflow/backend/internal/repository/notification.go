@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/database"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// NotificationRepository 通知数据访问层
+type NotificationRepository struct {
+	db     *database.Database
+	logger *logger.Logger
+}
+
+// NewNotificationRepository 创建新的通知仓库
+func NewNotificationRepository(db *database.Database, logger *logger.Logger) *NotificationRepository {
+	return &NotificationRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// GetPreference 获取用户通知偏好，未配置时返回nil
+func (r *NotificationRepository) GetPreference(ctx context.Context, userID uint) (*model.NotificationPreference, error) {
+	var pref model.NotificationPreference
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&pref).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.logger.Error("Failed to get notification preference", zap.Uint("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+	return &pref, nil
+}
+
+// SavePreference 保存用户通知偏好
+func (r *NotificationRepository) SavePreference(ctx context.Context, pref *model.NotificationPreference) error {
+	if err := r.db.WithContext(ctx).Save(pref).Error; err != nil {
+		r.logger.Error("Failed to save notification preference", zap.Uint("user_id", pref.UserID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Enqueue 加入延迟投递队列
+func (r *NotificationRepository) Enqueue(ctx context.Context, item *model.NotificationQueueItem) error {
+	if err := r.db.WithContext(ctx).Create(item).Error; err != nil {
+		r.logger.Error("Failed to enqueue notification", zap.Uint("user_id", item.UserID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetDueQueueItems 获取已到投递时间的队列通知
+func (r *NotificationRepository) GetDueQueueItems(ctx context.Context, now time.Time, limit int) ([]model.NotificationQueueItem, error) {
+	var items []model.NotificationQueueItem
+	err := r.db.WithContext(ctx).Where("sent_at IS NULL AND deliver_after <= ?", now).
+		Order("user_id ASC, channel ASC, created_at ASC").
+		Limit(limit).
+		Find(&items).Error
+
+	if err != nil {
+		r.logger.Error("Failed to get due notifications", zap.Error(err))
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// MarkQueueItemsSent 标记队列通知已投递
+func (r *NotificationRepository) MarkQueueItemsSent(ctx context.Context, ids []uint, sentAt time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	err := r.db.WithContext(ctx).Model(&model.NotificationQueueItem{}).
+		Where("id IN ?", ids).
+		Update("sent_at", sentAt).Error
+
+	if err != nil {
+		r.logger.Error("Failed to mark notifications sent", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// MarkQueueItemsFailed 记录队列通知投递失败，通知保留在队列中等待下一轮重试
+func (r *NotificationRepository) MarkQueueItemsFailed(ctx context.Context, ids []uint, reason string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	err := r.db.WithContext(ctx).Model(&model.NotificationQueueItem{}).
+		Where("id IN ?", ids).
+		Updates(map[string]interface{}{
+			"attempts":   gorm.Expr("attempts + 1"),
+			"last_error": reason,
+		}).Error
+
+	if err != nil {
+		r.logger.Error("Failed to mark notifications failed", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// CreateNotification 写入站内通知
+func (r *NotificationRepository) CreateNotification(ctx context.Context, notification *model.Notification) error {
+	if err := r.db.WithContext(ctx).Create(notification).Error; err != nil {
+		r.logger.Error("Failed to create notification", zap.Uint("user_id", notification.UserID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// ListNotifications 分页获取用户的站内通知，最新的在前；unreadOnly 为 true 时只返回未读通知
+func (r *NotificationRepository) ListNotifications(ctx context.Context, userID uint, unreadOnly bool, offset, limit int) ([]model.Notification, int64, error) {
+	var notifications []model.Notification
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&model.Notification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("created_at DESC, id DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&notifications).Error
+
+	return notifications, total, err
+}
+
+// CountUnreadNotifications 统计用户的未读站内通知数
+func (r *NotificationRepository) CountUnreadNotifications(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// MarkNotificationRead 将用户的一条站内通知标记为已读，返回通知是否存在；已读的通知保留原已读时间
+func (r *NotificationRepository) MarkNotificationRead(ctx context.Context, userID, id uint, readAt time.Time) (bool, error) {
+	var notification model.Notification
+	err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&notification).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if notification.ReadAt != nil {
+		return true, nil
+	}
+
+	err = r.db.WithContext(ctx).Model(&model.Notification{}).
+		Where("id = ? AND read_at IS NULL", id).
+		Update("read_at", readAt).Error
+	return true, err
+}
+
+// MarkAllNotificationsRead 将用户的全部未读站内通知标记为已读，返回标记的数量
+func (r *NotificationRepository) MarkAllNotificationsRead(ctx context.Context, userID uint, readAt time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&model.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", readAt)
+
+	if result.Error != nil {
+		r.logger.Error("Failed to mark notifications read", zap.Uint("user_id", userID), zap.Error(result.Error))
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// GetTemplate 获取指定事件类型和语言的模板，不存在时返回nil
+func (r *NotificationRepository) GetTemplate(ctx context.Context, eventType, locale string) (*model.NotificationTemplate, error) {
+	var tmpl model.NotificationTemplate
+	err := r.db.WithContext(ctx).Where("event_type = ? AND locale = ?", eventType, locale).First(&tmpl).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.logger.Error("Failed to get notification template",
+			zap.String("event_type", eventType),
+			zap.String("locale", locale),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+	return &tmpl, nil
+}
+
+// GetTemplateByID 根据ID获取通知模板
+func (r *NotificationRepository) GetTemplateByID(ctx context.Context, id uint) (*model.NotificationTemplate, error) {
+	var tmpl model.NotificationTemplate
+	if err := r.db.WithContext(ctx).First(&tmpl, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("通知模板不存在")
+		}
+		return nil, err
+	}
+	return &tmpl, nil
+}
+
+// ListTemplates 获取通知模板列表
+func (r *NotificationRepository) ListTemplates(ctx context.Context, eventType string) ([]model.NotificationTemplate, error) {
+	var templates []model.NotificationTemplate
+	query := r.db.WithContext(ctx).Model(&model.NotificationTemplate{})
+	if eventType != "" {
+		query = query.Where("event_type = ?", eventType)
+	}
+	err := query.Order("event_type ASC, locale ASC").Find(&templates).Error
+	return templates, err
+}
+
+// SaveTemplate 保存通知模板
+func (r *NotificationRepository) SaveTemplate(ctx context.Context, tmpl *model.NotificationTemplate) error {
+	if err := r.db.WithContext(ctx).Save(tmpl).Error; err != nil {
+		r.logger.Error("Failed to save notification template", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// DeleteTemplate 删除通知模板
+func (r *NotificationRepository) DeleteTemplate(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Unscoped().Delete(&model.NotificationTemplate{}, id).Error
+}
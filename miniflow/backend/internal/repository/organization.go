@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/database"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OrganizationRepository 部门和用户组数据访问层
+type OrganizationRepository struct {
+	db     *database.Database
+	logger *logger.Logger
+}
+
+// NewOrganizationRepository 创建新的组织数据仓库
+func NewOrganizationRepository(db *database.Database, logger *logger.Logger) *OrganizationRepository {
+	return &OrganizationRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// CreateDepartment 创建部门
+func (r *OrganizationRepository) CreateDepartment(ctx context.Context, department *model.Department) error {
+	if err := r.db.WithContext(ctx).Create(department).Error; err != nil {
+		r.logger.Error("Failed to create department", zap.String("code", department.Code), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetDepartmentByID 根据ID获取部门
+func (r *OrganizationRepository) GetDepartmentByID(ctx context.Context, id uint) (*model.Department, error) {
+	var department model.Department
+	if err := r.db.WithContext(ctx).First(&department, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("部门不存在")
+		}
+		return nil, err
+	}
+	return &department, nil
+}
+
+// ExistsDepartmentCode 检查部门编码是否已被其他部门使用
+func (r *OrganizationRepository) ExistsDepartmentCode(ctx context.Context, code string, excludeID uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.Department{}).Where("code = ? AND id <> ?", code, excludeID).Count(&count).Error
+	return count > 0, err
+}
+
+// UpdateDepartment 更新部门
+func (r *OrganizationRepository) UpdateDepartment(ctx context.Context, department *model.Department) error {
+	return r.db.WithContext(ctx).Save(department).Error
+}
+
+// DeleteDepartment 删除部门
+func (r *OrganizationRepository) DeleteDepartment(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.Department{}, id).Error
+}
+
+// ListDepartments 获取全部部门，按编码排序
+func (r *OrganizationRepository) ListDepartments(ctx context.Context) ([]model.Department, error) {
+	var departments []model.Department
+	err := r.db.WithContext(ctx).Order("code ASC").Find(&departments).Error
+	return departments, err
+}
+
+// CountChildDepartments 统计部门的直接下级部门数量
+func (r *OrganizationRepository) CountChildDepartments(ctx context.Context, id uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.Department{}).Where("parent_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+// CountDepartmentUsers 统计归属部门的用户数量
+func (r *OrganizationRepository) CountDepartmentUsers(ctx context.Context, id uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.User{}).Where("department_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+// GetDepartmentUsers 获取归属部门的用户
+func (r *OrganizationRepository) GetDepartmentUsers(ctx context.Context, id uint) ([]model.User, error) {
+	var users []model.User
+	err := r.db.WithContext(ctx).Where("department_id = ?", id).Order("username ASC").Find(&users).Error
+	return users, err
+}
+
+// SetUserDepartment 设置用户所属部门，departmentID 为 nil 时移出部门
+func (r *OrganizationRepository) SetUserDepartment(ctx context.Context, userID uint, departmentID *uint) error {
+	return r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).Update("department_id", departmentID).Error
+}
+
+// CreateGroup 创建用户组
+func (r *OrganizationRepository) CreateGroup(ctx context.Context, group *model.Group) error {
+	if err := r.db.WithContext(ctx).Create(group).Error; err != nil {
+		r.logger.Error("Failed to create group", zap.String("code", group.Code), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetGroupByID 根据ID获取用户组
+func (r *OrganizationRepository) GetGroupByID(ctx context.Context, id uint) (*model.Group, error) {
+	var group model.Group
+	if err := r.db.WithContext(ctx).First(&group, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("用户组不存在")
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+// ExistsGroupCode 检查用户组编码是否已被其他用户组使用
+func (r *OrganizationRepository) ExistsGroupCode(ctx context.Context, code string, excludeID uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.Group{}).Where("code = ? AND id <> ?", code, excludeID).Count(&count).Error
+	return count > 0, err
+}
+
+// UpdateGroup 更新用户组
+func (r *OrganizationRepository) UpdateGroup(ctx context.Context, group *model.Group) error {
+	return r.db.WithContext(ctx).Save(group).Error
+}
+
+// DeleteGroup 删除用户组及其成员关系
+func (r *OrganizationRepository) DeleteGroup(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("group_id = ?", id).Delete(&model.GroupMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Group{}, id).Error
+	})
+}
+
+// ListGroups 获取全部用户组，按编码排序
+func (r *OrganizationRepository) ListGroups(ctx context.Context) ([]model.Group, error) {
+	var groups []model.Group
+	err := r.db.WithContext(ctx).Order("code ASC").Find(&groups).Error
+	return groups, err
+}
+
+// CountGroupMembers 统计每个用户组的成员数量，返回用户组ID到数量的映射
+func (r *OrganizationRepository) CountGroupMembers(ctx context.Context) (map[uint]int64, error) {
+	var rows []struct {
+		GroupID uint
+		Count   int64
+	}
+	err := r.db.WithContext(ctx).Model(&model.GroupMember{}).
+		Select("group_id, COUNT(*) AS count").
+		Group("group_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.GroupID] = row.Count
+	}
+	return counts, nil
+}
+
+// GetGroupMembers 获取用户组的成员
+func (r *OrganizationRepository) GetGroupMembers(ctx context.Context, groupID uint) ([]model.User, error) {
+	var users []model.User
+	err := r.db.WithContext(ctx).Joins("JOIN user_group_members ON user_group_members.user_id = users.id").
+		Where("user_group_members.group_id = ?", groupID).
+		Order("users.username ASC").
+		Find(&users).Error
+	return users, err
+}
+
+// AddGroupMembers 把用户加入用户组，已是成员的用户忽略
+func (r *OrganizationRepository) AddGroupMembers(ctx context.Context, groupID uint, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	now := time.Now()
+	members := make([]model.GroupMember, len(userIDs))
+	for i, userID := range userIDs {
+		members[i] = model.GroupMember{GroupID: groupID, UserID: userID, CreatedAt: now}
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&members).Error
+}
+
+// RemoveGroupMember 把用户移出用户组
+func (r *OrganizationRepository) RemoveGroupMember(ctx context.Context, groupID, userID uint) error {
+	return r.db.WithContext(ctx).Where("group_id = ? AND user_id = ?", groupID, userID).Delete(&model.GroupMember{}).Error
+}
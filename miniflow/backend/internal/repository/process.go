@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -10,6 +11,14 @@ import (
 	"miniflow/pkg/logger"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrProcessStatusChanged 流程版本的状态在检查后被并发修改
+	ErrProcessStatusChanged = errors.New("流程版本状态已变化，请刷新后重试")
+	// ErrProcessHasActiveInstances 流程版本仍有运行中或挂起的实例
+	ErrProcessHasActiveInstances = errors.New("流程版本仍有运行中或挂起的实例")
 )
 
 // ProcessRepository handles process definition data access
@@ -27,11 +36,11 @@ func NewProcessRepository(db *database.Database, logger *logger.Logger) *Process
 }
 
 // Create creates a new process definition
-func (r *ProcessRepository) Create(process *model.ProcessDefinition) error {
+func (r *ProcessRepository) Create(ctx context.Context, process *model.ProcessDefinition) error {
 	// Check if key already exists
 	var count int64
-	if err := r.db.Model(&model.ProcessDefinition{}).
-		Where("`key` = ?", process.Key).
+	if err := r.db.WithContext(ctx).Model(&model.ProcessDefinition{}).
+		Where(r.db.Quote("key")+" = ?", process.Key).
 		Count(&count).Error; err != nil {
 		return err
 	}
@@ -39,8 +48,8 @@ func (r *ProcessRepository) Create(process *model.ProcessDefinition) error {
 	if count > 0 {
 		// Get the latest version for this key
 		var latestVersion int
-		if err := r.db.Model(&model.ProcessDefinition{}).
-			Where("`key` = ?", process.Key).
+		if err := r.db.WithContext(ctx).Model(&model.ProcessDefinition{}).
+			Where(r.db.Quote("key")+" = ?", process.Key).
 			Select("COALESCE(MAX(version), 0)").
 			Scan(&latestVersion).Error; err != nil {
 			return err
@@ -51,13 +60,13 @@ func (r *ProcessRepository) Create(process *model.ProcessDefinition) error {
 		process.Version = 1
 	}
 
-	return r.db.Create(process).Error
+	return r.db.WithContext(ctx).Create(process).Error
 }
 
 // GetByID retrieves a process definition by ID
-func (r *ProcessRepository) GetByID(id uint) (*model.ProcessDefinition, error) {
+func (r *ProcessRepository) GetByID(ctx context.Context, id uint) (*model.ProcessDefinition, error) {
 	var process model.ProcessDefinition
-	err := r.db.Preload("Creator").First(&process, id).Error
+	err := r.db.WithContext(ctx).Preload("Creator").First(&process, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("流程定义不存在")
@@ -68,10 +77,10 @@ func (r *ProcessRepository) GetByID(id uint) (*model.ProcessDefinition, error) {
 }
 
 // GetByKey retrieves a process definition by key (latest version)
-func (r *ProcessRepository) GetByKey(key string) (*model.ProcessDefinition, error) {
+func (r *ProcessRepository) GetByKey(ctx context.Context, key string) (*model.ProcessDefinition, error) {
 	var process model.ProcessDefinition
-	err := r.db.Preload("Creator").
-		Where("`key` = ?", key).
+	err := r.db.WithContext(ctx).Preload("Creator").
+		Where(r.db.Quote("key")+" = ?", key).
 		Order("version DESC").
 		First(&process).Error
 	if err != nil {
@@ -83,11 +92,41 @@ func (r *ProcessRepository) GetByKey(key string) (*model.ProcessDefinition, erro
 	return &process, nil
 }
 
+// GetLatestPublishedByKey retrieves the latest published version of a process definition
+func (r *ProcessRepository) GetLatestPublishedByKey(ctx context.Context, key string) (*model.ProcessDefinition, error) {
+	var process model.ProcessDefinition
+	err := r.db.WithContext(ctx).Where(r.db.Quote("key")+" = ? AND status = ?", key, model.ProcessStatusPublished).
+		Order("version DESC").
+		First(&process).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("没有已发布的流程定义")
+		}
+		return nil, err
+	}
+	return &process, nil
+}
+
+// GetPreviousPublished retrieves the latest published version older than the given version
+func (r *ProcessRepository) GetPreviousPublished(ctx context.Context, key string, version int) (*model.ProcessDefinition, error) {
+	var process model.ProcessDefinition
+	err := r.db.WithContext(ctx).Where(r.db.Quote("key")+" = ? AND status = ? AND version < ?", key, model.ProcessStatusPublished, version).
+		Order("version DESC").
+		First(&process).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("没有更早的已发布版本")
+		}
+		return nil, err
+	}
+	return &process, nil
+}
+
 // GetByKeyAndVersion retrieves a specific version of a process definition
-func (r *ProcessRepository) GetByKeyAndVersion(key string, version int) (*model.ProcessDefinition, error) {
+func (r *ProcessRepository) GetByKeyAndVersion(ctx context.Context, key string, version int) (*model.ProcessDefinition, error) {
 	var process model.ProcessDefinition
-	err := r.db.Preload("Creator").
-		Where("`key` = ? AND version = ?", key, version).
+	err := r.db.WithContext(ctx).Preload("Creator").
+		Where(r.db.Quote("key")+" = ? AND version = ?", key, version).
 		First(&process).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -99,21 +138,21 @@ func (r *ProcessRepository) GetByKeyAndVersion(key string, version int) (*model.
 }
 
 // Update updates a process definition
-func (r *ProcessRepository) Update(process *model.ProcessDefinition) error {
-	return r.db.Save(process).Error
+func (r *ProcessRepository) Update(ctx context.Context, process *model.ProcessDefinition) error {
+	return r.db.WithContext(ctx).Save(process).Error
 }
 
 // Delete soft deletes a process definition
-func (r *ProcessRepository) Delete(id uint) error {
-	return r.db.Delete(&model.ProcessDefinition{}, id).Error
+func (r *ProcessRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.ProcessDefinition{}, id).Error
 }
 
 // List retrieves process definitions with pagination and filters
-func (r *ProcessRepository) List(offset, limit int, filters map[string]interface{}) ([]*model.ProcessDefinition, int64, error) {
+func (r *ProcessRepository) List(ctx context.Context, offset, limit int, filters map[string]interface{}) ([]*model.ProcessDefinition, int64, error) {
 	var processes []*model.ProcessDefinition
 	var total int64
 
-	query := r.db.Model(&model.ProcessDefinition{}).Preload("Creator")
+	query := r.db.WithContext(ctx).Model(&model.ProcessDefinition{}).Preload("Creator")
 
 	// Apply filters
 	if createdBy, ok := filters["created_by"]; ok {
@@ -123,11 +162,14 @@ func (r *ProcessRepository) List(offset, limit int, filters map[string]interface
 		query = query.Where("category = ?", category)
 	}
 	if status, ok := filters["status"]; ok && status != "" {
+		if err := model.ProcessStatuses.ValidateFilter(status); err != nil {
+			return nil, 0, err
+		}
 		query = query.Where("status = ?", status)
 	}
 	if search, ok := filters["search"]; ok && search != "" {
 		searchTerm := fmt.Sprintf("%%%s%%", strings.ToLower(search.(string)))
-		query = query.Where("LOWER(name) LIKE ? OR LOWER(key) LIKE ? OR LOWER(description) LIKE ?",
+		query = query.Where("LOWER(name) LIKE ? OR LOWER("+r.db.Quote("key")+") LIKE ? OR LOWER(description) LIKE ?",
 			searchTerm, searchTerm, searchTerm)
 	}
 
@@ -149,10 +191,10 @@ func (r *ProcessRepository) List(offset, limit int, filters map[string]interface
 }
 
 // GetLatestVersion gets the latest version of a process by key
-func (r *ProcessRepository) GetLatestVersion(key string) (*model.ProcessDefinition, error) {
+func (r *ProcessRepository) GetLatestVersion(ctx context.Context, key string) (*model.ProcessDefinition, error) {
 	var process model.ProcessDefinition
-	err := r.db.Preload("Creator").
-		Where("`key` = ?", key).
+	err := r.db.WithContext(ctx).Preload("Creator").
+		Where(r.db.Quote("key")+" = ?", key).
 		Order("version DESC").
 		First(&process).Error
 	if err != nil {
@@ -165,19 +207,19 @@ func (r *ProcessRepository) GetLatestVersion(key string) (*model.ProcessDefiniti
 }
 
 // GetVersions gets all versions of a process by key
-func (r *ProcessRepository) GetVersions(key string) ([]*model.ProcessDefinition, error) {
+func (r *ProcessRepository) GetVersions(ctx context.Context, key string) ([]*model.ProcessDefinition, error) {
 	var processes []*model.ProcessDefinition
-	err := r.db.Preload("Creator").
-		Where("`key` = ?", key).
+	err := r.db.WithContext(ctx).Preload("Creator").
+		Where(r.db.Quote("key")+" = ?", key).
 		Order("version DESC").
 		Find(&processes).Error
 	return processes, err
 }
 
 // GetByCategory retrieves processes by category
-func (r *ProcessRepository) GetByCategory(category string) ([]*model.ProcessDefinition, error) {
+func (r *ProcessRepository) GetByCategory(ctx context.Context, category string) ([]*model.ProcessDefinition, error) {
 	var processes []*model.ProcessDefinition
-	err := r.db.Preload("Creator").
+	err := r.db.WithContext(ctx).Preload("Creator").
 		Where("category = ?", category).
 		Order("updated_at DESC").
 		Find(&processes).Error
@@ -185,9 +227,9 @@ func (r *ProcessRepository) GetByCategory(category string) ([]*model.ProcessDefi
 }
 
 // GetByCreator retrieves processes created by a specific user
-func (r *ProcessRepository) GetByCreator(createdBy uint) ([]*model.ProcessDefinition, error) {
+func (r *ProcessRepository) GetByCreator(ctx context.Context, createdBy uint) ([]*model.ProcessDefinition, error) {
 	var processes []*model.ProcessDefinition
-	err := r.db.Preload("Creator").
+	err := r.db.WithContext(ctx).Preload("Creator").
 		Where("created_by = ?", createdBy).
 		Order("updated_at DESC").
 		Find(&processes).Error
@@ -195,12 +237,12 @@ func (r *ProcessRepository) GetByCreator(createdBy uint) ([]*model.ProcessDefini
 }
 
 // Search searches processes by keyword
-func (r *ProcessRepository) Search(keyword string) ([]*model.ProcessDefinition, error) {
+func (r *ProcessRepository) Search(ctx context.Context, keyword string) ([]*model.ProcessDefinition, error) {
 	var processes []*model.ProcessDefinition
 	searchTerm := fmt.Sprintf("%%%s%%", strings.ToLower(keyword))
 
-	err := r.db.Preload("Creator").
-		Where("LOWER(name) LIKE ? OR LOWER(key) LIKE ? OR LOWER(description) LIKE ?",
+	err := r.db.WithContext(ctx).Preload("Creator").
+		Where("LOWER(name) LIKE ? OR LOWER("+r.db.Quote("key")+") LIKE ? OR LOWER(description) LIKE ?",
 			searchTerm, searchTerm, searchTerm).
 		Order("updated_at DESC").
 		Find(&processes).Error
@@ -208,35 +250,87 @@ func (r *ProcessRepository) Search(keyword string) ([]*model.ProcessDefinition,
 }
 
 // ExistsByKey checks if a process with the given key exists
-func (r *ProcessRepository) ExistsByKey(key string) (bool, error) {
+func (r *ProcessRepository) ExistsByKey(ctx context.Context, key string) (bool, error) {
 	var count int64
-	err := r.db.Model(&model.ProcessDefinition{}).
-		Where("`key` = ?", key).
+	err := r.db.WithContext(ctx).Model(&model.ProcessDefinition{}).
+		Where(r.db.Quote("key")+" = ?", key).
 		Count(&count).Error
 	return count > 0, err
 }
 
 // GetMaxVersion gets the maximum version number for a process key
-func (r *ProcessRepository) GetMaxVersion(key string) (int, error) {
+func (r *ProcessRepository) GetMaxVersion(ctx context.Context, key string) (int, error) {
 	var maxVersion int
-	err := r.db.Model(&model.ProcessDefinition{}).
-		Where("`key` = ?", key).
+	err := r.db.WithContext(ctx).Model(&model.ProcessDefinition{}).
+		Where(r.db.Quote("key")+" = ?", key).
 		Select("COALESCE(MAX(version), 0)").
 		Scan(&maxVersion).Error
 	return maxVersion, err
 }
 
 // UpdateStatus updates the status of a process definition
-func (r *ProcessRepository) UpdateStatus(id uint, status string) error {
-	return r.db.Model(&model.ProcessDefinition{}).
+func (r *ProcessRepository) UpdateStatus(ctx context.Context, id uint, status string) error {
+	return r.db.WithContext(ctx).Model(&model.ProcessDefinition{}).
 		Where("id = ?", id).
 		Update("status", status).Error
 }
 
+// TransitionStatus changes the status of a process definition only while it is in one of the from statuses.
+// Returns ErrProcessStatusChanged when the status was changed concurrently.
+func (r *ProcessRepository) TransitionStatus(ctx context.Context, id uint, from []string, to string) error {
+	result := r.db.WithContext(ctx).Model(&model.ProcessDefinition{}).
+		Where("id = ? AND status IN ?", id, from).
+		Update("status", to)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrProcessStatusChanged
+	}
+	return nil
+}
+
+// CountActiveInstances counts the running and suspended instances of a process definition version
+func (r *ProcessRepository) CountActiveInstances(ctx context.Context, definitionID uint) (int64, error) {
+	return countActiveInstances(r.db.WithContext(ctx), definitionID)
+}
+
+// ArchiveVersion archives a published or deprecated version that has no running or suspended instances.
+// The definition row is locked while counting, so instances created concurrently either are counted
+// or wait for the archive to commit.
+func (r *ProcessRepository) ArchiveVersion(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var process model.ProcessDefinition
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&process, id).Error; err != nil {
+			return err
+		}
+		if process.Status != model.ProcessStatusPublished && process.Status != model.ProcessStatusDeprecated {
+			return ErrProcessStatusChanged
+		}
+
+		active, err := countActiveInstances(tx, id)
+		if err != nil {
+			return err
+		}
+		if active > 0 {
+			return ErrProcessHasActiveInstances
+		}
+		return tx.Model(&process).Update("status", model.ProcessStatusArchived).Error
+	})
+}
+
+func countActiveInstances(db *gorm.DB, definitionID uint) (int64, error) {
+	var count int64
+	err := db.Model(&model.ProcessInstance{}).
+		Where("definition_id = ? AND status IN ?", definitionID, []string{model.InstanceStatusRunning, model.InstanceStatusSuspended}).
+		Count(&count).Error
+	return count, err
+}
+
 // GetPublishedProcesses gets all published process definitions
-func (r *ProcessRepository) GetPublishedProcesses() ([]*model.ProcessDefinition, error) {
+func (r *ProcessRepository) GetPublishedProcesses(ctx context.Context) ([]*model.ProcessDefinition, error) {
 	var processes []*model.ProcessDefinition
-	err := r.db.Preload("Creator").
+	err := r.db.WithContext(ctx).Preload("Creator").
 		Where("status = ?", model.ProcessStatusPublished).
 		Order("updated_at DESC").
 		Find(&processes).Error
@@ -244,19 +338,28 @@ func (r *ProcessRepository) GetPublishedProcesses() ([]*model.ProcessDefinition,
 }
 
 // CountByStatus counts processes by status
-func (r *ProcessRepository) CountByStatus(status string) (int64, error) {
+func (r *ProcessRepository) CountByStatus(ctx context.Context, status string) (int64, error) {
 	var count int64
-	err := r.db.Model(&model.ProcessDefinition{}).
+	err := r.db.WithContext(ctx).Model(&model.ProcessDefinition{}).
 		Where("status = ?", status).
 		Count(&count).Error
 	return count, err
 }
 
 // CountByCreator counts processes by creator
-func (r *ProcessRepository) CountByCreator(createdBy uint) (int64, error) {
+func (r *ProcessRepository) CountByCreator(ctx context.Context, createdBy uint) (int64, error) {
 	var count int64
-	err := r.db.Model(&model.ProcessDefinition{}).
+	err := r.db.WithContext(ctx).Model(&model.ProcessDefinition{}).
 		Where("created_by = ?", createdBy).
 		Count(&count).Error
 	return count, err
 }
+
+// GetUnarchivedProcesses gets all draft, published and deprecated process definitions
+func (r *ProcessRepository) GetUnarchivedProcesses(ctx context.Context) ([]*model.ProcessDefinition, error) {
+	var processes []*model.ProcessDefinition
+	err := r.db.WithContext(ctx).Where("status IN ?", []string{model.ProcessStatusDraft, model.ProcessStatusPublished, model.ProcessStatusDeprecated}).
+		Order("id ASC").
+		Find(&processes).Error
+	return processes, err
+}
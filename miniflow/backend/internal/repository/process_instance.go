@@ -1,14 +1,21 @@
 package repository
 
 import (
+	"context"
+	"errors"
 	"miniflow/internal/model"
 	"miniflow/pkg/database"
 	"miniflow/pkg/logger"
 	"time"
 
 	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrInstanceVersionConflict 实例在读取后已被其他操作修改，需要重新读取后再更新
+var ErrInstanceVersionConflict = errors.New("流程实例已被其他操作修改")
+
 // ProcessInstanceRepository 流程实例数据访问层
 type ProcessInstanceRepository struct {
 	db     *database.Database
@@ -24,8 +31,11 @@ func NewProcessInstanceRepository(db *database.Database, logger *logger.Logger)
 }
 
 // Create 创建流程实例
-func (r *ProcessInstanceRepository) Create(instance *model.ProcessInstance) error {
-	if err := r.db.Create(instance).Error; err != nil {
+func (r *ProcessInstanceRepository) Create(ctx context.Context, instance *model.ProcessInstance) error {
+	if instance.Version == 0 {
+		instance.Version = 1
+	}
+	if err := r.db.WithContext(ctx).Create(instance).Error; err != nil {
 		r.logger.Error("Failed to create process instance", zap.Error(err))
 		return err
 	}
@@ -33,9 +43,9 @@ func (r *ProcessInstanceRepository) Create(instance *model.ProcessInstance) erro
 }
 
 // GetByID 根据ID获取流程实例
-func (r *ProcessInstanceRepository) GetByID(id uint) (*model.ProcessInstance, error) {
+func (r *ProcessInstanceRepository) GetByID(ctx context.Context, id uint) (*model.ProcessInstance, error) {
 	var instance model.ProcessInstance
-	err := r.db.Preload("Definition").
+	err := r.db.WithContext(ctx).Preload("Definition").
 		Preload("Starter").
 		Preload("Tasks").
 		Preload("Tasks.Assignee").
@@ -49,18 +59,36 @@ func (r *ProcessInstanceRepository) GetByID(id uint) (*model.ProcessInstance, er
 	return &instance, nil
 }
 
-// Update 更新流程实例
-func (r *ProcessInstanceRepository) Update(instance *model.ProcessInstance) error {
-	if err := r.db.Save(instance).Error; err != nil {
-		r.logger.Error("Failed to update process instance", zap.Uint("id", instance.ID), zap.Error(err))
-		return err
+// Update 以乐观锁更新流程实例
+// 只有数据库中的版本号与读取时一致才会更新，并把版本号加一；
+// 版本号不一致时不修改实例并返回 ErrInstanceVersionConflict，关联数据不随实例保存
+func (r *ProcessInstanceRepository) Update(ctx context.Context, instance *model.ProcessInstance) error {
+	version := instance.Version
+	instance.Version = version + 1
+	result := r.db.WithContext(ctx).Model(instance).
+		Where("version = ?", version).
+		Select("*").
+		Omit("id", "created_at", clause.Associations).
+		Updates(instance)
+	if result.Error != nil {
+		instance.Version = version
+		r.logger.Error("Failed to update process instance", zap.Uint("id", instance.ID), zap.Error(result.Error))
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		instance.Version = version
+		r.logger.Warn("Process instance version conflict",
+			zap.Uint("id", instance.ID),
+			zap.Int("version", version),
+		)
+		return ErrInstanceVersionConflict
 	}
 	return nil
 }
 
 // Delete 删除流程实例
-func (r *ProcessInstanceRepository) Delete(id uint) error {
-	if err := r.db.Delete(&model.ProcessInstance{}, id).Error; err != nil {
+func (r *ProcessInstanceRepository) Delete(ctx context.Context, id uint) error {
+	if err := r.db.WithContext(ctx).Delete(&model.ProcessInstance{}, id).Error; err != nil {
 		r.logger.Error("Failed to delete process instance", zap.Uint("id", id), zap.Error(err))
 		return err
 	}
@@ -68,9 +96,9 @@ func (r *ProcessInstanceRepository) Delete(id uint) error {
 }
 
 // GetByBusinessKey 根据业务键获取流程实例
-func (r *ProcessInstanceRepository) GetByBusinessKey(businessKey string) (*model.ProcessInstance, error) {
+func (r *ProcessInstanceRepository) GetByBusinessKey(ctx context.Context, businessKey string) (*model.ProcessInstance, error) {
 	var instance model.ProcessInstance
-	err := r.db.Preload("Definition").
+	err := r.db.WithContext(ctx).Preload("Definition").
 		Preload("Starter").
 		Where("business_key = ?", businessKey).
 		First(&instance).Error
@@ -85,21 +113,69 @@ func (r *ProcessInstanceRepository) GetByBusinessKey(businessKey string) (*model
 }
 
 // List 获取流程实例列表
-func (r *ProcessInstanceRepository) List(offset, limit int, filters map[string]interface{}) ([]model.ProcessInstance, int64, error) {
+func (r *ProcessInstanceRepository) List(ctx context.Context, offset, limit int, filters map[string]interface{}) ([]model.ProcessInstance, int64, error) {
 	var instances []model.ProcessInstance
 	var total int64
 
-	query := r.db.Preload("Definition").Preload("Starter")
+	query, err := applyInstanceFilters(r.db.WithContext(ctx).Preload("Definition").Preload("Starter"), filters)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 获取总数
+	if err := query.Model(&model.ProcessInstance{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 获取分页数据
+	err = query.Offset(offset).
+		Limit(limit).
+		Order("start_time DESC, id DESC").
+		Find(&instances).Error
+
+	if err != nil {
+		r.logger.Error("Failed to list process instances", zap.Error(err))
+		return nil, 0, err
+	}
+
+	return instances, total, nil
+}
+
+// ListAfter 获取游标位置之后的一页流程实例，按启动时间和ID倒序排列，不统计总数
+func (r *ProcessInstanceRepository) ListAfter(ctx context.Context, after Keyset, limit int, filters map[string]interface{}) ([]model.ProcessInstance, error) {
+	var instances []model.ProcessInstance
+
+	query, err := applyInstanceFilters(r.db.WithContext(ctx).Preload("Definition").Preload("Starter"), filters)
+	if err != nil {
+		return nil, err
+	}
+
+	err = after.apply(query.Model(&model.ProcessInstance{}), "process_instances", "start_time").
+		Limit(limit).
+		Find(&instances).Error
+	if err != nil {
+		r.logger.Error("Failed to list process instances after cursor", zap.Uint("after_id", after.ID), zap.Error(err))
+		return nil, err
+	}
+
+	return instances, nil
+}
 
-	// 应用过滤条件
+// applyInstanceFilters 应用流程实例列表的过滤条件
+func applyInstanceFilters(query *gorm.DB, filters map[string]interface{}) (*gorm.DB, error) {
 	for key, value := range filters {
 		switch key {
 		case "status":
+			if err := model.InstanceStatuses.ValidateFilter(value); err != nil {
+				return nil, err
+			}
 			query = query.Where("status = ?", value)
 		case "definition_id":
 			query = query.Where("definition_id = ?", value)
 		case "starter_id":
 			query = query.Where("starter_id = ?", value)
+		case "parent_instance_id":
+			query = query.Where("parent_instance_id = ?", value)
 		case "priority":
 			query = query.Where("priority = ?", value)
 		case "start_date_from":
@@ -108,30 +184,29 @@ func (r *ProcessInstanceRepository) List(offset, limit int, filters map[string]i
 			query = query.Where("start_time <= ?", value)
 		}
 	}
+	return query, nil
+}
 
-	// 获取总数
-	if err := query.Model(&model.ProcessInstance{}).Count(&total).Error; err != nil {
-		return nil, 0, err
-	}
-
-	// 获取分页数据
-	err := query.Offset(offset).
-		Limit(limit).
-		Order("start_time DESC").
+// GetChildren 获取调用活动启动的子实例，按启动时间排列
+func (r *ProcessInstanceRepository) GetChildren(ctx context.Context, parentID uint) ([]model.ProcessInstance, error) {
+	var instances []model.ProcessInstance
+	err := r.db.WithContext(ctx).Preload("Definition").
+		Where("parent_instance_id = ?", parentID).
+		Order("start_time ASC").
 		Find(&instances).Error
 
 	if err != nil {
-		r.logger.Error("Failed to list process instances", zap.Error(err))
-		return nil, 0, err
+		r.logger.Error("Failed to get child instances", zap.Uint("parent_id", parentID), zap.Error(err))
+		return nil, err
 	}
 
-	return instances, total, nil
+	return instances, nil
 }
 
 // GetByStatus 根据状态获取流程实例
-func (r *ProcessInstanceRepository) GetByStatus(status string) ([]model.ProcessInstance, error) {
+func (r *ProcessInstanceRepository) GetByStatus(ctx context.Context, status string) ([]model.ProcessInstance, error) {
 	var instances []model.ProcessInstance
-	err := r.db.Preload("Definition").
+	err := r.db.WithContext(ctx).Preload("Definition").
 		Preload("Starter").
 		Where("status = ?", status).
 		Order("start_time DESC").
@@ -145,17 +220,38 @@ func (r *ProcessInstanceRepository) GetByStatus(status string) ([]model.ProcessI
 	return instances, nil
 }
 
+// GetCompletedSince 获取用户启动的、在游标之后完成的流程实例，按完成时间升序返回
+func (r *ProcessInstanceRepository) GetCompletedSince(ctx context.Context, starterID uint, since time.Time, afterID uint, limit int) ([]model.ProcessInstance, error) {
+	var instances []model.ProcessInstance
+	err := r.db.WithContext(ctx).Preload("Definition").
+		Where("starter_id = ? AND status = ?", starterID, model.InstanceStatusCompleted).
+		Where("end_time > ? OR (end_time = ? AND id > ?)", since, since, afterID).
+		Order("end_time ASC, id ASC").
+		Limit(limit).
+		Find(&instances).Error
+
+	if err != nil {
+		r.logger.Error("Failed to get completed instances since cursor",
+			zap.Uint("starter_id", starterID),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	return instances, nil
+}
+
 // GetRunningInstances 获取运行中的流程实例
-func (r *ProcessInstanceRepository) GetRunningInstances() ([]model.ProcessInstance, error) {
-	return r.GetByStatus(model.InstanceStatusRunning)
+func (r *ProcessInstanceRepository) GetRunningInstances(ctx context.Context) ([]model.ProcessInstance, error) {
+	return r.GetByStatus(ctx, model.InstanceStatusRunning)
 }
 
 // GetUserInstances 获取用户启动的流程实例
-func (r *ProcessInstanceRepository) GetUserInstances(userID uint, offset, limit int) ([]model.ProcessInstance, int64, error) {
+func (r *ProcessInstanceRepository) GetUserInstances(ctx context.Context, userID uint, offset, limit int) ([]model.ProcessInstance, int64, error) {
 	var instances []model.ProcessInstance
 	var total int64
 
-	query := r.db.Preload("Definition").
+	query := r.db.WithContext(ctx).Preload("Definition").
 		Where("starter_id = ?", userID)
 
 	// 获取总数
@@ -178,7 +274,7 @@ func (r *ProcessInstanceRepository) GetUserInstances(userID uint, offset, limit
 }
 
 // GetInstanceStatistics 获取流程实例统计信息
-func (r *ProcessInstanceRepository) GetInstanceStatistics() (*InstanceStatistics, error) {
+func (r *ProcessInstanceRepository) GetInstanceStatistics(ctx context.Context) (*InstanceStatistics, error) {
 	var stats InstanceStatistics
 
 	// 统计各状态实例数量
@@ -187,7 +283,7 @@ func (r *ProcessInstanceRepository) GetInstanceStatistics() (*InstanceStatistics
 		Count  int64
 	}{}
 
-	err := r.db.Model(&model.ProcessInstance{}).
+	err := r.db.WithContext(ctx).Model(&model.ProcessInstance{}).
 		Select("status, COUNT(*) as count").
 		Group("status").
 		Find(&statusCounts).Error
@@ -218,7 +314,7 @@ func (r *ProcessInstanceRepository) GetInstanceStatistics() (*InstanceStatistics
 
 	// 统计今日启动的实例数
 	today := time.Now().Truncate(24 * time.Hour)
-	if err := r.db.Model(&model.ProcessInstance{}).
+	if err := r.db.WithContext(ctx).Model(&model.ProcessInstance{}).
 		Where("start_time >= ?", today).
 		Count(&stats.TodayStarted).Error; err != nil {
 		r.logger.Warn("Failed to count today started instances", zap.Error(err))
@@ -227,10 +323,33 @@ func (r *ProcessInstanceRepository) GetInstanceStatistics() (*InstanceStatistics
 	return &stats, nil
 }
 
+// VersionStatusCount 流程版本各状态的实例数量和已结束实例的平均耗时
+type VersionStatusCount struct {
+	DefinitionID       uint
+	Status             string
+	Count              int64
+	AvgDurationSeconds float64
+}
+
+// GetVersionStatusCounts 按流程版本和状态统计实例，用于灰度发布的版本对比
+func (r *ProcessInstanceRepository) GetVersionStatusCounts(ctx context.Context, definitionIDs []uint) ([]VersionStatusCount, error) {
+	var counts []VersionStatusCount
+	err := r.db.WithContext(ctx).Model(&model.ProcessInstance{}).
+		Select("definition_id, status, COUNT(*) as count, COALESCE(AVG("+r.db.SecondsBetween("start_time", "end_time")+"), 0) as avg_duration_seconds").
+		Where("definition_id IN ?", definitionIDs).
+		Group("definition_id, status").
+		Find(&counts).Error
+	if err != nil {
+		r.logger.Error("Failed to get version status counts", zap.Error(err))
+		return nil, err
+	}
+	return counts, nil
+}
+
 // GetInstancesByDateRange 根据时间范围获取流程实例
-func (r *ProcessInstanceRepository) GetInstancesByDateRange(startDate, endDate time.Time) ([]model.ProcessInstance, error) {
+func (r *ProcessInstanceRepository) GetInstancesByDateRange(ctx context.Context, startDate, endDate time.Time) ([]model.ProcessInstance, error) {
 	var instances []model.ProcessInstance
-	err := r.db.Preload("Definition").
+	err := r.db.WithContext(ctx).Preload("Definition").
 		Preload("Starter").
 		Where("start_time BETWEEN ? AND ?", startDate, endDate).
 		Order("start_time DESC").
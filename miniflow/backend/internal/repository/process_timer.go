@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// CreateTimer 创建流程定时器
+func (r *ProcessInstanceRepository) CreateTimer(ctx context.Context, timer *model.ProcessTimer) error {
+	if err := r.db.WithContext(ctx).Create(timer).Error; err != nil {
+		r.logger.Error("Failed to create process timer",
+			zap.Uint("instance_id", timer.InstanceID),
+			zap.String("node_id", timer.NodeID),
+			zap.Error(err),
+		)
+		return err
+	}
+	return nil
+}
+
+// GetDueTimers 获取已到期且仍在等待的定时器，按到期时间排列
+func (r *ProcessInstanceRepository) GetDueTimers(ctx context.Context, now time.Time, limit int) ([]model.ProcessTimer, error) {
+	var timers []model.ProcessTimer
+	err := r.db.WithContext(ctx).Where("status = ? AND due_at <= ?", model.TimerStatusWaiting, now).
+		Order("due_at ASC").
+		Limit(limit).
+		Find(&timers).Error
+	return timers, err
+}
+
+// CountDueTimers 统计到期时间早于 before 且仍在等待的定时器数量
+func (r *ProcessInstanceRepository) CountDueTimers(ctx context.Context, before time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.ProcessTimer{}).
+		Where("status = ? AND due_at <= ?", model.TimerStatusWaiting, before).
+		Count(&count).Error
+	return count, err
+}
+
+// MarkTimerFired 将等待中的定时器标记为已触发，定时器已被其他调度器触发或已取消时返回 false
+func (r *ProcessInstanceRepository) MarkTimerFired(ctx context.Context, id uint, now time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.ProcessTimer{}).
+		Where("id = ? AND status = ?", id, model.TimerStatusWaiting).
+		Updates(map[string]interface{}{
+			"status":   model.TimerStatusFired,
+			"fired_at": now,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// MarkTimerFailed 记录定时器触发后流程推进失败
+func (r *ProcessInstanceRepository) MarkTimerFailed(ctx context.Context, id uint, reason string) error {
+	return r.db.WithContext(ctx).Model(&model.ProcessTimer{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":     model.TimerStatusFailed,
+			"last_error": reason,
+		}).Error
+}
+
+// CancelTimers 取消实例上等待中的定时器，nodeID 为空时取消实例的全部定时器
+func (r *ProcessInstanceRepository) CancelTimers(ctx context.Context, instanceID uint, nodeID string) error {
+	query := r.db.WithContext(ctx).Model(&model.ProcessTimer{}).
+		Where("instance_id = ? AND status = ?", instanceID, model.TimerStatusWaiting)
+	if nodeID != "" {
+		query = query.Where("node_id = ?", nodeID)
+	}
+	return query.Update("status", model.TimerStatusCancelled).Error
+}
+
+// GetWaitingTimers 获取实例上等待中的定时器
+func (r *ProcessInstanceRepository) GetWaitingTimers(ctx context.Context, instanceID uint) ([]model.ProcessTimer, error) {
+	var timers []model.ProcessTimer
+	err := r.db.WithContext(ctx).Where("instance_id = ? AND status = ?", instanceID, model.TimerStatusWaiting).
+		Order("id ASC").
+		Find(&timers).Error
+	return timers, err
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/database"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// reportingRefreshStatements 报表表全量重建语句，按顺序在同一事务内执行，参数为刷新时间
+// 日期键和时长表达式按数据库方言生成
+func reportingRefreshStatements(db *database.Database) []string {
+	return []string{
+		"DELETE FROM rpt_dim_definition",
+		fmt.Sprintf(`INSERT INTO rpt_dim_definition (definition_id, %[1]s, name, version, category, status, data_classification, refreshed_at)
+			SELECT id, %[1]s, name, version, category, status, data_classification, @refreshed_at
+			FROM process_definitions WHERE deleted_at IS NULL`, db.Quote("key")),
+
+		"DELETE FROM rpt_dim_user",
+		`INSERT INTO rpt_dim_user (user_id, username, display_name, role, status, refreshed_at)
+			SELECT id, username, display_name, role, status, @refreshed_at
+			FROM users WHERE deleted_at IS NULL`,
+
+		"DELETE FROM rpt_fact_instance",
+		fmt.Sprintf(`INSERT INTO rpt_fact_instance (instance_id, definition_id, starter_id, start_date_key, end_date_key,
+				status, duration_seconds, task_count, incident_count, refreshed_at)
+			SELECT i.id, i.definition_id, i.starter_id,
+				%s,
+				%s,
+				i.status,
+				%s,
+				(SELECT COUNT(*) FROM task_instances t WHERE t.instance_id = i.id AND t.deleted_at IS NULL),
+				(SELECT COUNT(*) FROM incidents n WHERE n.instance_id = i.id AND n.deleted_at IS NULL),
+				@refreshed_at
+			FROM process_instances i WHERE i.deleted_at IS NULL`,
+			db.DateKey("i.start_time"),
+			db.DateKey("i.end_time"),
+			db.SecondsBetween("i.start_time", "i.end_time")),
+
+		"DELETE FROM rpt_fact_task",
+		fmt.Sprintf(`INSERT INTO rpt_fact_task (task_id, instance_id, definition_id, assignee_id, node_id, status, priority,
+				created_date_key, completed_date_key, wait_seconds, handle_seconds, overdue, claim_expiries, refreshed_at)
+			SELECT t.id, t.instance_id, i.definition_id, t.assignee_id, t.node_id, t.status, t.priority,
+				%s,
+				%s,
+				%s,
+				%s,
+				t.due_date IS NOT NULL AND COALESCE(t.complete_time, @refreshed_at) > t.due_date,
+				t.claim_expiries,
+				@refreshed_at
+			FROM task_instances t
+			JOIN process_instances i ON i.id = t.instance_id
+			WHERE t.deleted_at IS NULL AND i.deleted_at IS NULL`,
+			db.DateKey("t.created_at"),
+			db.DateKey("t.complete_time"),
+			db.SecondsBetween("t.created_at", "t.claim_time"),
+			db.SecondsBetween("COALESCE(t.claim_time, t.created_at)", "t.complete_time")),
+	}
+}
+
+// ReportingRepository 报表星型模型数据访问层
+type ReportingRepository struct {
+	db     *database.Database
+	logger *logger.Logger
+}
+
+// NewReportingRepository 创建新的报表仓库
+func NewReportingRepository(db *database.Database, logger *logger.Logger) *ReportingRepository {
+	return &ReportingRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Rebuild 在一个事务内从业务表全量重建维度表和事实表
+func (r *ReportingRepository) Rebuild(ctx context.Context, refreshedAt time.Time) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		named := map[string]interface{}{"refreshed_at": refreshedAt}
+		for _, stmt := range reportingRefreshStatements(r.db) {
+			// 只给使用刷新时间的语句传参，驱动会拒绝多余的参数
+			var args []interface{}
+			if strings.Contains(stmt, "@refreshed_at") {
+				args = append(args, named)
+			}
+			if err := tx.Exec(stmt, args...).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		r.logger.Error("Failed to rebuild reporting tables", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// EnsureDateRange 补齐日期维度，已存在的日期保持不变
+func (r *ReportingRepository) EnsureDateRange(ctx context.Context, from, to time.Time) error {
+	var rows []model.ReportDimDate
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		rows = append(rows, model.NewReportDimDate(day))
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(rows, 500).Error
+	if err != nil {
+		r.logger.Error("Failed to fill date dimension",
+			zap.Time("from", from),
+			zap.Time("to", to),
+			zap.Error(err),
+		)
+		return err
+	}
+	return nil
+}
+
+// GetEarliestActivity 获取业务数据中最早的日期，没有数据时返回nil
+func (r *ReportingRepository) GetEarliestActivity(ctx context.Context) (*time.Time, error) {
+	var result struct {
+		Earliest *time.Time
+	}
+	err := r.db.WithContext(ctx).Model(&model.ProcessInstance{}).
+		Select("MIN(start_time) AS earliest").
+		Scan(&result).Error
+	if err != nil {
+		return nil, err
+	}
+	return result.Earliest, nil
+}
+
+// ReportingCounts 报表表的行数统计
+type ReportingCounts struct {
+	Definitions int64 `json:"definitions"`
+	Users       int64 `json:"users"`
+	Dates       int64 `json:"dates"`
+	Instances   int64 `json:"instances"`
+	Tasks       int64 `json:"tasks"`
+}
+
+// GetCounts 统计各报表表的行数
+func (r *ReportingRepository) GetCounts(ctx context.Context) (*ReportingCounts, error) {
+	var counts ReportingCounts
+	targets := []struct {
+		model interface{}
+		dest  *int64
+	}{
+		{&model.ReportDimDefinition{}, &counts.Definitions},
+		{&model.ReportDimUser{}, &counts.Users},
+		{&model.ReportDimDate{}, &counts.Dates},
+		{&model.ReportFactInstance{}, &counts.Instances},
+		{&model.ReportFactTask{}, &counts.Tasks},
+	}
+
+	for _, target := range targets {
+		if err := r.db.WithContext(ctx).Model(target.model).Count(target.dest).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &counts, nil
+}
@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrSnapshotReferenceMissing 快照引用的流程定义或用户在当前环境不存在
+var ErrSnapshotReferenceMissing = errors.New("快照引用的数据在当前环境不存在")
+
+// errSnapshotDryRun 试运行导入时用于回滚事务
+var errSnapshotDryRun = errors.New("snapshot dry run")
+
+// snapshotBatchSize 导入快照时每批写入的行数
+const snapshotBatchSize = 200
+
+// snapshotSequenceTables 导出时记录最大ID的表
+var snapshotSequenceTables = []struct {
+	name  string
+	model interface{}
+}{
+	{model.SnapshotSeqInstances, &model.ProcessInstance{}},
+	{model.SnapshotSeqTasks, &model.TaskInstance{}},
+	{model.SnapshotSeqArrivals, &model.GatewayArrival{}},
+	{model.SnapshotSeqTimers, &model.ProcessTimer{}},
+	{model.SnapshotSeqWebhooks, &model.WebhookDelivery{}},
+	{model.SnapshotSeqNotifications, &model.NotificationQueueItem{}},
+	{model.SnapshotSeqTaskEvents, &model.TaskEvent{}},
+	{model.SnapshotSeqMessages, &model.MessageSubscription{}},
+}
+
+// ExportRuntimeSnapshot 在一个只读的可重复读事务中导出运行时状态快照
+func (r *ProcessInstanceRepository) ExportRuntimeSnapshot(ctx context.Context) (*model.RuntimeSnapshot, error) {
+	snapshot := &model.RuntimeSnapshot{
+		Format:    model.RuntimeSnapshotFormat,
+		Sequences: make(map[string]uint),
+	}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		snapshot.ExportedAt = time.Now()
+
+		for _, table := range snapshotSequenceTables {
+			var maxID sql.NullInt64
+			if err := tx.Unscoped().Model(table.model).Select("MAX(id)").Scan(&maxID).Error; err != nil {
+				return err
+			}
+			snapshot.Sequences[table.name] = uint(maxID.Int64)
+		}
+
+		err := tx.Where("status IN ?", []string{model.InstanceStatusRunning, model.InstanceStatusSuspended}).
+			Order("id ASC").Find(&snapshot.Instances).Error
+		if err != nil {
+			return err
+		}
+		instanceIDs := make([]uint, len(snapshot.Instances))
+		for i, instance := range snapshot.Instances {
+			instanceIDs[i] = instance.ID
+		}
+
+		if len(instanceIDs) > 0 {
+			if err := tx.Where("instance_id IN ?", instanceIDs).Order("id ASC").Find(&snapshot.Tasks).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("instance_id IN ?", instanceIDs).Order("id ASC").Find(&snapshot.GatewayArrivals).Error; err != nil {
+				return err
+			}
+			err := tx.Where("instance_id IN ? AND status IN ?", instanceIDs, []string{model.TimerStatusWaiting, model.TimerStatusFailed}).
+				Order("id ASC").Find(&snapshot.Timers).Error
+			if err != nil {
+				return err
+			}
+			err = tx.Where("instance_id IN ? AND status = ?", instanceIDs, model.MessageSubscriptionWaiting).
+				Order("id ASC").Find(&snapshot.Messages).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		// 发件箱：未投递成功的回调和未发送的通知，已结束实例的回调也需要在备用环境继续投递
+		err = tx.Where("status IN ?", []string{model.WebhookDeliveryPending, model.WebhookDeliveryFailed}).
+			Order("id ASC").Find(&snapshot.Webhooks).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("sent_at IS NULL").Order("id ASC").Find(&snapshot.Notifications).Error
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		r.logger.Error("Failed to export runtime snapshot", zap.Error(err))
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// ImportRuntimeSnapshot 在一个事务中按主键写入快照，已存在的行被快照内容覆盖
+// dryRun 为 true 时完成全部校验和写入后回滚，返回各表将写入的行数
+func (r *ProcessInstanceRepository) ImportRuntimeSnapshot(ctx context.Context, snapshot *model.RuntimeSnapshot, dryRun bool) (map[string]int64, error) {
+	rows := make(map[string]int64)
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkSnapshotReferences(tx, snapshot); err != nil {
+			return err
+		}
+
+		upsert := func(name string, values interface{}, count int) error {
+			if count == 0 {
+				rows[name] = 0
+				return nil
+			}
+			result := tx.Omit(clause.Associations).
+				Clauses(clause.OnConflict{UpdateAll: true}).
+				CreateInBatches(values, snapshotBatchSize)
+			if result.Error != nil {
+				return fmt.Errorf("写入 %s 失败: %w", name, result.Error)
+			}
+			rows[name] = int64(count)
+			return nil
+		}
+
+		// 先写实例，再写依赖实例的记录
+		if err := upsert(model.SnapshotSeqInstances, &snapshot.Instances, len(snapshot.Instances)); err != nil {
+			return err
+		}
+		if err := upsert(model.SnapshotSeqTasks, &snapshot.Tasks, len(snapshot.Tasks)); err != nil {
+			return err
+		}
+		if err := upsert(model.SnapshotSeqArrivals, &snapshot.GatewayArrivals, len(snapshot.GatewayArrivals)); err != nil {
+			return err
+		}
+		if err := upsert(model.SnapshotSeqTimers, &snapshot.Timers, len(snapshot.Timers)); err != nil {
+			return err
+		}
+		if err := upsert(model.SnapshotSeqMessages, &snapshot.Messages, len(snapshot.Messages)); err != nil {
+			return err
+		}
+		if err := upsert(model.SnapshotSeqWebhooks, &snapshot.Webhooks, len(snapshot.Webhooks)); err != nil {
+			return err
+		}
+		if err := upsert(model.SnapshotSeqNotifications, &snapshot.Notifications, len(snapshot.Notifications)); err != nil {
+			return err
+		}
+
+		if dryRun {
+			return errSnapshotDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errSnapshotDryRun) {
+		if !errors.Is(err, ErrSnapshotReferenceMissing) {
+			r.logger.Error("Failed to import runtime snapshot", zap.Error(err))
+		}
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// checkSnapshotReferences 检查快照引用的流程定义和用户在当前环境都存在
+func checkSnapshotReferences(tx *gorm.DB, snapshot *model.RuntimeSnapshot) error {
+	definitionIDs := make(map[uint]bool)
+	userIDs := make(map[uint]bool)
+	for _, instance := range snapshot.Instances {
+		definitionIDs[instance.DefinitionID] = true
+		userIDs[instance.StarterID] = true
+	}
+	for _, task := range snapshot.Tasks {
+		if task.AssigneeID != nil {
+			userIDs[*task.AssigneeID] = true
+		}
+	}
+	for _, item := range snapshot.Notifications {
+		userIDs[item.UserID] = true
+	}
+
+	missing, err := missingIDs(tx, &model.ProcessDefinition{}, definitionIDs)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: 流程定义 %v", ErrSnapshotReferenceMissing, missing)
+	}
+	if missing, err = missingIDs(tx, &model.User{}, userIDs); err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: 用户 %v", ErrSnapshotReferenceMissing, missing)
+	}
+	return nil
+}
+
+// missingIDs 返回表中不存在的ID
+func missingIDs(tx *gorm.DB, table interface{}, ids map[uint]bool) ([]uint, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	wanted := make([]uint, 0, len(ids))
+	for id := range ids {
+		wanted = append(wanted, id)
+	}
+
+	var found []uint
+	if err := tx.Model(table).Where("id IN ?", wanted).Pluck("id", &found).Error; err != nil {
+		return nil, err
+	}
+	existing := make(map[uint]bool, len(found))
+	for _, id := range found {
+		existing[id] = true
+	}
+
+	var missing []uint
+	for _, id := range wanted {
+		if !existing[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing, nil
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -9,8 +10,13 @@ import (
 	"miniflow/pkg/logger"
 
 	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrTaskNotClaimable 任务不存在、已被认领或状态不允许认领
+var ErrTaskNotClaimable = errors.New("任务不存在或状态不允许认领")
+
 // TaskRepository 任务数据访问层
 type TaskRepository struct {
 	db     *database.Database
@@ -26,21 +32,21 @@ func NewTaskRepository(db *database.Database, logger *logger.Logger) *TaskReposi
 }
 
 // Create 创建任务实例
-func (r *TaskRepository) Create(task *model.TaskInstance) error {
-	if err := r.db.Create(task).Error; err != nil {
+func (r *TaskRepository) Create(ctx context.Context, task *model.TaskInstance) error {
+	if err := r.db.WithContext(ctx).Create(task).Error; err != nil {
 		r.logger.Error("Failed to create task instance", zap.Error(err))
 		return err
 	}
+	r.recordTaskEvent(ctx, task, task.AssigneeID, model.TaskEventCreated)
 	return nil
 }
 
 // GetByID 根据ID获取任务实例
-func (r *TaskRepository) GetByID(id uint) (*model.TaskInstance, error) {
+func (r *TaskRepository) GetByID(ctx context.Context, id uint) (*model.TaskInstance, error) {
 	var task model.TaskInstance
-	err := r.db.Preload("Instance").
+	err := r.db.WithContext(ctx).Preload("Instance").
+		Preload("Instance.Definition").
 		Preload("Assignee").
-		Preload("ClaimedUser").
-		Preload("CompletedUser").
 		First(&task, id).Error
 
 	if err != nil {
@@ -52,17 +58,19 @@ func (r *TaskRepository) GetByID(id uint) (*model.TaskInstance, error) {
 }
 
 // Update 更新任务实例
-func (r *TaskRepository) Update(task *model.TaskInstance) error {
-	if err := r.db.Save(task).Error; err != nil {
+func (r *TaskRepository) Update(ctx context.Context, task *model.TaskInstance) error {
+	previousAssignee := r.currentAssignee(ctx, task.ID)
+	if err := r.db.WithContext(ctx).Save(task).Error; err != nil {
 		r.logger.Error("Failed to update task instance", zap.Uint("id", task.ID), zap.Error(err))
 		return err
 	}
+	r.recordTaskChange(ctx, task, previousAssignee)
 	return nil
 }
 
 // Delete 删除任务实例
-func (r *TaskRepository) Delete(id uint) error {
-	if err := r.db.Delete(&model.TaskInstance{}, id).Error; err != nil {
+func (r *TaskRepository) Delete(ctx context.Context, id uint) error {
+	if err := r.db.WithContext(ctx).Delete(&model.TaskInstance{}, id).Error; err != nil {
 		r.logger.Error("Failed to delete task instance", zap.Uint("id", id), zap.Error(err))
 		return err
 	}
@@ -70,11 +78,9 @@ func (r *TaskRepository) Delete(id uint) error {
 }
 
 // GetByInstance 获取流程实例的所有任务
-func (r *TaskRepository) GetByInstance(instanceID uint) ([]model.TaskInstance, error) {
+func (r *TaskRepository) GetByInstance(ctx context.Context, instanceID uint) ([]model.TaskInstance, error) {
 	var tasks []model.TaskInstance
-	err := r.db.Preload("Assignee").
-		Preload("ClaimedUser").
-		Preload("CompletedUser").
+	err := r.db.WithContext(ctx).Preload("Assignee").
 		Where("instance_id = ?", instanceID).
 		Order("created_at ASC").
 		Find(&tasks).Error
@@ -88,11 +94,9 @@ func (r *TaskRepository) GetByInstance(instanceID uint) ([]model.TaskInstance, e
 }
 
 // GetByInstanceAndNode 获取指定流程实例和节点的任务
-func (r *TaskRepository) GetByInstanceAndNode(instanceID uint, nodeID string, statuses []string) ([]model.TaskInstance, error) {
+func (r *TaskRepository) GetByInstanceAndNode(ctx context.Context, instanceID uint, nodeID string, statuses []string) ([]model.TaskInstance, error) {
 	var tasks []model.TaskInstance
-	query := r.db.Preload("Assignee").
-		Preload("ClaimedUser").
-		Preload("CompletedUser").
+	query := r.db.WithContext(ctx).Preload("Assignee").
 		Where("instance_id = ? AND node_id = ?", instanceID, nodeID)
 
 	if len(statuses) > 0 {
@@ -113,42 +117,48 @@ func (r *TaskRepository) GetByInstanceAndNode(instanceID uint, nodeID string, st
 }
 
 // GetUserTasks 获取用户的任务列表
-func (r *TaskRepository) GetUserTasks(userID uint, status string, offset, limit int) ([]model.TaskInstance, int64, error) {
-	var tasks []model.TaskInstance
-	var total int64
-
-	query := r.db.Preload("Instance").
-		Preload("Instance.Definition").
-		Preload("Assignee").
-		Where("assignee_id = ? OR (assignee_id IS NULL AND status = 'created')", userID)
-
+func (r *TaskRepository) GetUserTasks(ctx context.Context, userID uint, role string, status string, offset, limit int) ([]model.TaskInstance, int64, error) {
+	query := &TaskQuery{CandidateID: &userID, CandidateRole: role, Offset: offset, Limit: limit}
 	if status != "" {
-		query = query.Where("status = ?", status)
+		query.Statuses = []string{status}
 	}
 
-	// 获取总数
-	if err := query.Model(&model.TaskInstance{}).Count(&total).Error; err != nil {
+	tasks, total, err := r.Query(ctx, query)
+	if err != nil {
+		r.logger.Error("Failed to get user tasks", zap.Uint("user_id", userID), zap.Error(err))
 		return nil, 0, err
 	}
 
-	// 获取分页数据
-	err := query.Offset(offset).
+	return tasks, total, nil
+}
+
+// GetUserTasksAfter 获取ID大于指定游标的用户任务，按ID升序返回，用于轮询新任务
+func (r *TaskRepository) GetUserTasksAfter(ctx context.Context, userID uint, role string, afterID uint, limit int) ([]model.TaskInstance, error) {
+	var tasks []model.TaskInstance
+	err := r.db.WithContext(ctx).Preload("Instance").
+		Preload("Instance.Definition").
+		Where("id > ?", afterID).
+		Where(candidateTaskCondition(r.db.WithContext(ctx), userID, role)).
+		Order("id ASC").
 		Limit(limit).
-		Order("priority DESC, created_at DESC").
 		Find(&tasks).Error
 
 	if err != nil {
-		r.logger.Error("Failed to get user tasks", zap.Uint("user_id", userID), zap.Error(err))
-		return nil, 0, err
+		r.logger.Error("Failed to get user tasks after cursor",
+			zap.Uint("user_id", userID),
+			zap.Uint("after_id", afterID),
+			zap.Error(err),
+		)
+		return nil, err
 	}
 
-	return tasks, total, nil
+	return tasks, nil
 }
 
 // CountUserActiveTasks 统计用户活跃任务数
-func (r *TaskRepository) CountUserActiveTasks(userID uint) (int, error) {
+func (r *TaskRepository) CountUserActiveTasks(ctx context.Context, userID uint) (int, error) {
 	var count int64
-	err := r.db.Model(&model.TaskInstance{}).
+	err := r.db.WithContext(ctx).Model(&model.TaskInstance{}).
 		Where("assignee_id = ? AND status IN ?", userID, []string{
 			model.TaskStatusAssigned,
 			model.TaskStatusClaimed,
@@ -165,26 +175,8 @@ func (r *TaskRepository) CountUserActiveTasks(userID uint) (int, error) {
 }
 
 // GetTasksByStatus 根据状态获取任务列表
-func (r *TaskRepository) GetTasksByStatus(status string, offset, limit int) ([]model.TaskInstance, int64, error) {
-	var tasks []model.TaskInstance
-	var total int64
-
-	query := r.db.Preload("Instance").
-		Preload("Instance.Definition").
-		Preload("Assignee").
-		Where("status = ?", status)
-
-	// 获取总数
-	if err := query.Model(&model.TaskInstance{}).Count(&total).Error; err != nil {
-		return nil, 0, err
-	}
-
-	// 获取分页数据
-	err := query.Offset(offset).
-		Limit(limit).
-		Order("priority DESC, created_at DESC").
-		Find(&tasks).Error
-
+func (r *TaskRepository) GetTasksByStatus(ctx context.Context, status string, offset, limit int) ([]model.TaskInstance, int64, error) {
+	tasks, total, err := r.Query(ctx, &TaskQuery{Statuses: []string{status}, Offset: offset, Limit: limit})
 	if err != nil {
 		r.logger.Error("Failed to get tasks by status", zap.String("status", status), zap.Error(err))
 		return nil, 0, err
@@ -193,18 +185,20 @@ func (r *TaskRepository) GetTasksByStatus(status string, offset, limit int) ([]m
 	return tasks, total, nil
 }
 
-// GetOverdueTasks 获取超期任务
-func (r *TaskRepository) GetOverdueTasks() ([]model.TaskInstance, error) {
+// GetOverdueTasks 获取超期且在当前截止时间之后还未升级的任务
+func (r *TaskRepository) GetOverdueTasks(ctx context.Context) ([]model.TaskInstance, error) {
 	var tasks []model.TaskInstance
 	now := time.Now()
 
-	err := r.db.Preload("Instance").
+	err := r.db.WithContext(ctx).Preload("Instance").
 		Preload("Assignee").
 		Where("due_date < ? AND status IN ?", now, []string{
+			model.TaskStatusCreated,
 			model.TaskStatusAssigned,
 			model.TaskStatusClaimed,
 			model.TaskStatusInProgress,
 		}).
+		Where("escalated_at IS NULL OR escalated_at < due_date").
 		Find(&tasks).Error
 
 	if err != nil {
@@ -215,17 +209,227 @@ func (r *TaskRepository) GetOverdueTasks() ([]model.TaskInstance, error) {
 	return tasks, nil
 }
 
-// ClaimTask 认领任务
-func (r *TaskRepository) ClaimTask(taskID uint, userID uint) error {
+// EscalateTask 标记超期任务已升级并提升升级次数，assigneeID 不为空时转交给该用户
+// 以升级次数作为条件更新，多个调度器同时运行时每次超期只升级一次
+func (r *TaskRepository) EscalateTask(ctx context.Context, taskID uint, level int, assigneeID *uint, now time.Time) (bool, error) {
+	previousAssignee := r.currentAssignee(ctx, taskID)
+	updates := map[string]interface{}{
+		"escalation_level": level + 1,
+		"escalated_at":     now,
+	}
+	if assigneeID != nil {
+		updates["assignee_id"] = *assigneeID
+		updates["status"] = model.TaskStatusAssigned
+		updates["claim_time"] = nil
+		updates["escalation_group"] = ""
+	}
+
+	result := r.db.WithContext(ctx).Model(&model.TaskInstance{}).
+		Where("id = ? AND escalation_level = ? AND status IN ?", taskID, level, []string{
+			model.TaskStatusCreated,
+			model.TaskStatusAssigned,
+			model.TaskStatusClaimed,
+			model.TaskStatusInProgress,
+		}).
+		Updates(updates)
+
+	if result.Error != nil {
+		r.logger.Error("Failed to escalate task", zap.Uint("task_id", taskID), zap.Error(result.Error))
+		return false, result.Error
+	}
+
+	if result.RowsAffected > 0 && assigneeID != nil {
+		r.recordTaskChangeByID(ctx, taskID, previousAssignee, model.TaskEventAssigned)
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// EscalateTaskToGroup 标记超期任务已升级并把任务放回任务池，只有用户组成员 memberIDs 可以认领
+// 与 EscalateTask 相同以升级次数作为条件更新，处理人、状态和候选人在同一条语句中修改，
+// 不会出现已取消分配但还没有候选人、任何用户都能认领的中间状态
+func (r *TaskRepository) EscalateTaskToGroup(ctx context.Context, taskID uint, level int, group string, memberIDs []uint, now time.Time) (bool, error) {
+	previousAssignee := r.currentAssignee(ctx, taskID)
+	candidates := &model.TaskInstance{}
+	candidates.SetCandidates(nil, memberIDs)
+
+	result := r.db.WithContext(ctx).Model(&model.TaskInstance{}).
+		Where("id = ? AND escalation_level = ? AND status IN ?", taskID, level, []string{
+			model.TaskStatusCreated,
+			model.TaskStatusAssigned,
+			model.TaskStatusClaimed,
+			model.TaskStatusInProgress,
+		}).
+		Updates(map[string]interface{}{
+			"escalation_level": level + 1,
+			"escalated_at":     now,
+			"escalation_group": group,
+			"assignee_id":      nil,
+			"status":           model.TaskStatusCreated,
+			"claim_time":       nil,
+			"candidate_roles":  candidates.CandidateRoles,
+			"candidate_users":  candidates.CandidateUsers,
+		})
+
+	if result.Error != nil {
+		r.logger.Error("Failed to escalate task to group",
+			zap.Uint("task_id", taskID),
+			zap.String("group", group),
+			zap.Error(result.Error),
+		)
+		return false, result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		r.recordTaskChangeByID(ctx, taskID, previousAssignee, model.TaskEventCreated)
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// GetHeldTasks 获取已认领或处理中的任务，用于检查认领超时
+func (r *TaskRepository) GetHeldTasks(ctx context.Context) ([]model.TaskInstance, error) {
+	var tasks []model.TaskInstance
+	err := r.db.WithContext(ctx).Preload("Instance").
+		Preload("Instance.Definition").
+		Where("status IN ?", []string{model.TaskStatusClaimed, model.TaskStatusInProgress}).
+		Find(&tasks).Error
+
+	if err != nil {
+		r.logger.Error("Failed to get held tasks", zap.Error(err))
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// GetTasksDueBefore 获取到期时间在 until 之前且尚未到期的待处理任务，用于发送到期提醒
+// 已认领、处理中或已结束的任务不再提醒
+func (r *TaskRepository) GetTasksDueBefore(ctx context.Context, now, until time.Time) ([]model.TaskInstance, error) {
+	var tasks []model.TaskInstance
+	err := r.db.WithContext(ctx).Preload("Instance").
+		Preload("Instance.Definition").
+		Where("due_date > ? AND due_date <= ?", now, until).
+		Where("status IN ? AND assignee_id IS NOT NULL", []string{model.TaskStatusCreated, model.TaskStatusAssigned}).
+		Find(&tasks).Error
+
+	if err != nil {
+		r.logger.Error("Failed to get tasks due soon", zap.Error(err))
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// RecordReminder 记录已发送的到期提醒，同一提醒已记录时不重复插入，返回是否新记录
+func (r *TaskRepository) RecordReminder(ctx context.Context, reminder *model.TaskReminder) (bool, error) {
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(reminder)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// CompleteTask 以条件更新完成任务：只有仍处于认领或处理中状态、且未分配或分配给该用户的任务才会被更新
+// 并发的重复提交只有一个能更新成功，返回是否更新成功
+//
+// 完成任务时锁定所属流程实例行，并在同一事务中统计该节点剩余的未完成任务：
+// 同一实例的并发完成按顺序提交，只有最后完成的任务会看到剩余数为0，由它推进流程
+func (r *TaskRepository) CompleteTask(ctx context.Context, task *model.TaskInstance, userID uint) (bool, int64, error) {
 	now := time.Now()
-	result := r.db.Model(&model.TaskInstance{}).
-		Where("id = ? AND status = ? AND (assignee_id = ? OR assignee_id IS NULL)",
-			taskID, model.TaskStatusAssigned, userID).
+	completed := false
+	var remaining int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var instance model.ProcessInstance
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").
+			First(&instance, task.InstanceID).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&model.TaskInstance{}).
+			Where("id = ? AND status IN ? AND (assignee_id IS NULL OR assignee_id = ?)", task.ID,
+				[]string{model.TaskStatusClaimed, model.TaskStatusInProgress}, userID).
+			Updates(map[string]interface{}{
+				"status":        model.TaskStatusCompleted,
+				"complete_time": now,
+				"comment":       task.Comment,
+				"form_data":     task.FormData,
+				"outcome":       task.Outcome,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		completed = true
+
+		return tx.Model(&model.TaskInstance{}).
+			Where("instance_id = ? AND node_id = ? AND status IN ?", task.InstanceID, task.NodeID, []string{
+				model.TaskStatusCreated,
+				model.TaskStatusAssigned,
+				model.TaskStatusClaimed,
+				model.TaskStatusInProgress,
+			}).
+			Count(&remaining).Error
+	})
+
+	if err != nil {
+		r.logger.Error("Failed to complete task", zap.Uint("task_id", task.ID), zap.Error(err))
+		return false, 0, err
+	}
+	if !completed {
+		return false, 0, nil
+	}
+
+	task.Status = model.TaskStatusCompleted
+	task.CompleteTime = &now
+	r.recordTaskEvent(ctx, task, task.AssigneeID, model.TaskEventCompleted)
+	return true, remaining, nil
+}
+
+// ExpireClaim 释放认领超时的任务回任务池，lastActivity 用于防止与并发操作冲突，返回是否释放成功
+func (r *TaskRepository) ExpireClaim(ctx context.Context, taskID uint, lastActivity time.Time) (bool, error) {
+	previousAssignee := r.currentAssignee(ctx, taskID)
+	result := r.db.WithContext(ctx).Model(&model.TaskInstance{}).
+		Where("id = ? AND status IN ? AND updated_at = ?", taskID,
+			[]string{model.TaskStatusClaimed, model.TaskStatusInProgress}, lastActivity).
 		Updates(map[string]interface{}{
-			"status":     model.TaskStatusClaimed,
-			"claimed_by": userID,
-			"claim_time": now,
-			"start_time": now,
+			"status":         model.TaskStatusAssigned,
+			"assignee_id":    nil,
+			"claim_time":     nil,
+			"claim_expiries": gorm.Expr("claim_expiries + 1"),
+		})
+
+	if result.Error != nil {
+		r.logger.Error("Failed to expire task claim", zap.Uint("task_id", taskID), zap.Error(result.Error))
+		return false, result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		r.recordTaskChangeByID(ctx, taskID, previousAssignee, model.TaskEventReleased)
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// ClaimTask 认领任务，task 是调用方检查候选人时读取的任务
+// 更新条件同时比较读取时的升级次数和候选人：检查之后任务被升级或改派了候选人时认领失败，
+// 不会让已不是候选人的用户认领
+func (r *TaskRepository) ClaimTask(ctx context.Context, task *model.TaskInstance, userID uint) error {
+	taskID := task.ID
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&model.TaskInstance{}).
+		Where("id = ?", taskID).
+		Where("(status = ? AND (assignee_id = ? OR assignee_id IS NULL)) OR (status = ? AND assignee_id IS NULL)",
+			model.TaskStatusAssigned, userID, model.TaskStatusCreated).
+		Where("escalation_level = ? AND COALESCE(candidate_roles, '') = ? AND COALESCE(candidate_users, '') = ?",
+			task.EscalationLevel, task.CandidateRoles, task.CandidateUsers).
+		Updates(map[string]interface{}{
+			"assignee_id": userID,
+			"status":      model.TaskStatusClaimed,
+			"claim_time":  now,
 		})
 
 	if result.Error != nil {
@@ -234,22 +438,21 @@ func (r *TaskRepository) ClaimTask(taskID uint, userID uint) error {
 	}
 
 	if result.RowsAffected == 0 {
-		return errors.New("任务不存在或状态不允许认领")
+		return ErrTaskNotClaimable
 	}
 
+	r.recordTaskChangeByID(ctx, taskID, nil, model.TaskEventClaimed)
 	return nil
 }
 
 // ReleaseTask 释放任务
-func (r *TaskRepository) ReleaseTask(taskID uint, userID uint) error {
-	result := r.db.Model(&model.TaskInstance{}).
-		Where("id = ? AND claimed_by = ? AND status = ?",
+func (r *TaskRepository) ReleaseTask(ctx context.Context, taskID uint, userID uint) error {
+	result := r.db.WithContext(ctx).Model(&model.TaskInstance{}).
+		Where("id = ? AND assignee_id = ? AND status = ?",
 			taskID, userID, model.TaskStatusClaimed).
 		Updates(map[string]interface{}{
 			"status":     model.TaskStatusAssigned,
-			"claimed_by": nil,
 			"claim_time": nil,
-			"start_time": nil,
 		})
 
 	if result.Error != nil {
@@ -261,18 +464,25 @@ func (r *TaskRepository) ReleaseTask(taskID uint, userID uint) error {
 		return errors.New("任务不存在或状态不允许释放")
 	}
 
+	r.recordTaskChangeByID(ctx, taskID, nil, model.TaskEventReleased)
 	return nil
 }
 
-// DelegateTask 委派任务
-func (r *TaskRepository) DelegateTask(taskID uint, fromUserID uint, toUserID uint) error {
-	result := r.db.Model(&model.TaskInstance{}).
-		Where("id = ? AND assignee_id = ?", taskID, fromUserID).
+// DelegateTask 委派任务：处理人把未完成的任务交给受托人处理，首次委派时记录处理人为任务所有人，
+// 受托人再次委派时所有人保持不变
+func (r *TaskRepository) DelegateTask(ctx context.Context, taskID uint, fromUserID uint, toUserID uint) error {
+	result := r.db.WithContext(ctx).Model(&model.TaskInstance{}).
+		Where("id = ? AND assignee_id = ? AND status IN ?", taskID, fromUserID, []string{
+			model.TaskStatusAssigned,
+			model.TaskStatusClaimed,
+			model.TaskStatusInProgress,
+		}).
 		Updates(map[string]interface{}{
-			"assignee_id": toUserID,
-			"status":      model.TaskStatusAssigned,
-			"claimed_by":  nil,
-			"claim_time":  nil,
+			"owner_id":         gorm.Expr("COALESCE(owner_id, ?)", fromUserID),
+			"delegation_state": model.DelegationStatePending,
+			"assignee_id":      toUserID,
+			"status":           model.TaskStatusAssigned,
+			"claim_time":       nil,
 		})
 
 	if result.Error != nil {
@@ -289,11 +499,40 @@ func (r *TaskRepository) DelegateTask(taskID uint, fromUserID uint, toUserID uin
 		return errors.New("任务不存在或用户没有权限委派")
 	}
 
+	r.recordTaskChangeByID(ctx, taskID, &fromUserID, model.TaskEventAssigned)
 	return nil
 }
 
+// ResolveTask 受托人把委派中的任务归还给所有人，以条件更新防止并发的重复归还，返回是否归还成功
+func (r *TaskRepository) ResolveTask(ctx context.Context, taskID uint, userID uint) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.TaskInstance{}).
+		Where("id = ? AND assignee_id = ? AND delegation_state = ? AND owner_id IS NOT NULL",
+			taskID, userID, model.DelegationStatePending).
+		Updates(map[string]interface{}{
+			"assignee_id":      gorm.Expr("owner_id"),
+			"delegation_state": model.DelegationStateResolved,
+			"status":           model.TaskStatusAssigned,
+			"claim_time":       nil,
+		})
+
+	if result.Error != nil {
+		r.logger.Error("Failed to resolve task",
+			zap.Uint("task_id", taskID),
+			zap.Uint("user_id", userID),
+			zap.Error(result.Error),
+		)
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	r.recordTaskChangeByID(ctx, taskID, &userID, model.TaskEventAssigned)
+	return true, nil
+}
+
 // GetTaskStatistics 获取任务统计信息
-func (r *TaskRepository) GetTaskStatistics() (*TaskStatistics, error) {
+func (r *TaskRepository) GetTaskStatistics(ctx context.Context) (*TaskStatistics, error) {
 	var stats TaskStatistics
 
 	// 统计各状态任务数量
@@ -302,7 +541,7 @@ func (r *TaskRepository) GetTaskStatistics() (*TaskStatistics, error) {
 		Count  int64
 	}{}
 
-	err := r.db.Model(&model.TaskInstance{}).
+	err := r.db.WithContext(ctx).Model(&model.TaskInstance{}).
 		Select("status, COUNT(*) as count").
 		Group("status").
 		Find(&statusCounts).Error
@@ -346,3 +585,15 @@ type TaskStatistics struct {
 	CompletedCount  int `json:"completed_count"`
 	FailedCount     int `json:"failed_count"`
 }
+
+// GetLatestTask 获取实例最近创建的任务，实例没有任务时返回 nil
+func (r *TaskRepository) GetLatestTask(ctx context.Context, instanceID uint) (*model.TaskInstance, error) {
+	var tasks []model.TaskInstance
+	if err := r.db.WithContext(ctx).Where("instance_id = ?", instanceID).Order("id DESC").Limit(1).Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return nil, nil
+	}
+	return &tasks[0], nil
+}
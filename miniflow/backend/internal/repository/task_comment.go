@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrTaskCommentNotFound 任务评论不存在
+var ErrTaskCommentNotFound = errors.New("任务评论不存在")
+
+// CreateComment 保存任务评论
+func (r *TaskRepository) CreateComment(ctx context.Context, comment *model.TaskComment) error {
+	if err := r.db.WithContext(ctx).Create(comment).Error; err != nil {
+		r.logger.Error("Failed to create task comment", zap.Uint("task_id", comment.TaskID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetComment 获取任务下的一条评论
+func (r *TaskRepository) GetComment(ctx context.Context, taskID, commentID uint) (*model.TaskComment, error) {
+	var comment model.TaskComment
+	err := r.db.WithContext(ctx).Where("id = ? AND task_id = ?", commentID, taskID).First(&comment).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTaskCommentNotFound
+		}
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// GetComments 获取任务的评论，按发表顺序排列
+func (r *TaskRepository) GetComments(ctx context.Context, taskID uint) ([]model.TaskComment, error) {
+	var comments []model.TaskComment
+	err := r.db.WithContext(ctx).Where("task_id = ?", taskID).Order("id ASC").Find(&comments).Error
+	return comments, err
+}
+
+// GetInstanceComments 获取流程实例所有任务的评论，按发表顺序排列
+func (r *TaskRepository) GetInstanceComments(ctx context.Context, instanceID uint) ([]model.TaskComment, error) {
+	var comments []model.TaskComment
+	err := r.db.WithContext(ctx).Where("instance_id = ?", instanceID).Order("id ASC").Find(&comments).Error
+	return comments, err
+}
+
+// UpdateComment 修改评论内容
+func (r *TaskRepository) UpdateComment(ctx context.Context, comment *model.TaskComment) error {
+	return r.db.WithContext(ctx).Model(comment).Update("body", comment.Body).Error
+}
+
+// DeleteComment 删除评论
+func (r *TaskRepository) DeleteComment(ctx context.Context, commentID uint) error {
+	return r.db.WithContext(ctx).Delete(&model.TaskComment{}, commentID).Error
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// recordTaskEvent 写入任务变更事件，写入失败只记录日志，不影响任务操作本身
+func (r *TaskRepository) recordTaskEvent(ctx context.Context, task *model.TaskInstance, userID *uint, eventType string) {
+	event := &model.TaskEvent{
+		TaskID:     task.ID,
+		InstanceID: task.InstanceID,
+		UserID:     userID,
+		Type:       eventType,
+		Status:     task.Status,
+	}
+	if err := r.db.WithContext(ctx).Create(event).Error; err != nil {
+		r.logger.Warn("Failed to record task event",
+			zap.Uint("task_id", task.ID),
+			zap.String("type", eventType),
+			zap.Error(err),
+		)
+	}
+}
+
+// recordTaskChange 根据任务保存前后的处理人记录变更事件：原处理人收到 unassigned，新处理人收到状态对应的事件
+func (r *TaskRepository) recordTaskChange(ctx context.Context, task *model.TaskInstance, previousAssignee *uint) {
+	if previousAssignee != nil && (task.AssigneeID == nil || *task.AssigneeID != *previousAssignee) {
+		r.recordTaskEvent(ctx, task, previousAssignee, model.TaskEventUnassigned)
+	}
+	r.recordTaskEvent(ctx, task, task.AssigneeID, model.TaskEventTypeForStatus(task.Status))
+}
+
+// recordTaskChangeByID 重新加载任务后记录变更事件，用于按条件批量更新的操作
+func (r *TaskRepository) recordTaskChangeByID(ctx context.Context, taskID uint, previousAssignee *uint, eventType string) {
+	var task model.TaskInstance
+	if err := r.db.WithContext(ctx).First(&task, taskID).Error; err != nil {
+		r.logger.Warn("Failed to load task for event", zap.Uint("task_id", taskID), zap.Error(err))
+		return
+	}
+	if previousAssignee != nil && (task.AssigneeID == nil || *task.AssigneeID != *previousAssignee) {
+		r.recordTaskEvent(ctx, &task, previousAssignee, model.TaskEventUnassigned)
+	}
+	r.recordTaskEvent(ctx, &task, task.AssigneeID, eventType)
+}
+
+// currentAssignee 获取任务当前的处理人
+func (r *TaskRepository) currentAssignee(ctx context.Context, taskID uint) *uint {
+	var task model.TaskInstance
+	if err := r.db.WithContext(ctx).Select("id", "assignee_id").First(&task, taskID).Error; err != nil {
+		return nil
+	}
+	return task.AssigneeID
+}
+
+// GetUserTaskEventsAfter 获取游标之后与用户相关的任务变更事件（包括任务池中的未分配任务），按ID升序返回
+func (r *TaskRepository) GetUserTaskEventsAfter(ctx context.Context, userID uint, afterID uint, limit int) ([]model.TaskEvent, error) {
+	var events []model.TaskEvent
+	err := r.db.WithContext(ctx).Where("id > ? AND (user_id = ? OR user_id IS NULL)", afterID, userID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&events).Error
+
+	if err != nil {
+		r.logger.Error("Failed to get task events after cursor",
+			zap.Uint("user_id", userID),
+			zap.Uint("after_id", afterID),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// GetLatestTaskEventID 获取最新的任务变更事件ID，客户端首次同步时以此作为游标
+func (r *TaskRepository) GetLatestTaskEventID(ctx context.Context) (uint, error) {
+	var latest uint
+	err := r.db.WithContext(ctx).Model(&model.TaskEvent{}).
+		Select("COALESCE(MAX(id), 0)").
+		Scan(&latest).Error
+	return latest, err
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrSavedTaskFilterNotFound 保存的任务筛选器不存在
+var ErrSavedTaskFilterNotFound = errors.New("任务筛选器不存在")
+
+// CreateSavedFilter 保存任务筛选器
+func (r *TaskRepository) CreateSavedFilter(ctx context.Context, filter *model.SavedTaskFilter) error {
+	if err := r.db.WithContext(ctx).Create(filter).Error; err != nil {
+		r.logger.Error("Failed to create saved task filter", zap.Uint("user_id", filter.UserID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetSavedFilter 获取用户的一个任务筛选器
+func (r *TaskRepository) GetSavedFilter(ctx context.Context, userID, filterID uint) (*model.SavedTaskFilter, error) {
+	var filter model.SavedTaskFilter
+	err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", filterID, userID).First(&filter).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSavedTaskFilterNotFound
+		}
+		return nil, err
+	}
+	return &filter, nil
+}
+
+// GetSavedFilters 获取用户的任务筛选器，按名称排列
+func (r *TaskRepository) GetSavedFilters(ctx context.Context, userID uint) ([]model.SavedTaskFilter, error) {
+	var filters []model.SavedTaskFilter
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("name ASC").Find(&filters).Error
+	return filters, err
+}
+
+// SavedFilterNameExists 检查用户是否已有同名的任务筛选器，excludeID 为修改中的筛选器
+func (r *TaskRepository) SavedFilterNameExists(ctx context.Context, userID uint, name string, excludeID uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.SavedTaskFilter{}).
+		Where("user_id = ? AND name = ? AND id <> ?", userID, name, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// CountSavedFilters 统计用户保存的任务筛选器数量
+func (r *TaskRepository) CountSavedFilters(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.SavedTaskFilter{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// UpdateSavedFilter 修改任务筛选器的名称和查询条件
+func (r *TaskRepository) UpdateSavedFilter(ctx context.Context, filter *model.SavedTaskFilter) error {
+	return r.db.WithContext(ctx).Model(filter).Updates(map[string]interface{}{
+		"name":     filter.Name,
+		"criteria": filter.Criteria,
+	}).Error
+}
+
+// DeleteSavedFilter 删除任务筛选器
+func (r *TaskRepository) DeleteSavedFilter(ctx context.Context, filterID uint) error {
+	return r.db.WithContext(ctx).Delete(&model.SavedTaskFilter{}, filterID).Error
+}
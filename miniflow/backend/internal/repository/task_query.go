@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/listquery"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// TaskListSchema 任务列表允许排序和过滤的字段
+var TaskListSchema = &listquery.Schema{
+	Fields: map[string]listquery.Field{
+		"id":          {Table: "task_instances", Column: "id", Type: listquery.Int, Sortable: true, Operators: []listquery.Operator{listquery.OpEq, listquery.OpIn}},
+		"name":        {Table: "task_instances", Column: "name", Type: listquery.String, Sortable: true, Operators: []listquery.Operator{listquery.OpEq, listquery.OpLike}},
+		"node_id":     {Table: "task_instances", Column: "node_id", Type: listquery.String, Operators: []listquery.Operator{listquery.OpEq, listquery.OpIn}},
+		"status":      {Table: "task_instances", Column: "status", Type: listquery.String, Sortable: true, Operators: []listquery.Operator{listquery.OpEq, listquery.OpNe, listquery.OpIn}, Values: model.TaskStatuses.Values},
+		"priority":    {Table: "task_instances", Column: "priority", Type: listquery.Int, Sortable: true, Operators: []listquery.Operator{listquery.OpEq, listquery.OpGt, listquery.OpGte, listquery.OpLt, listquery.OpLte}},
+		"due_date":    {Table: "task_instances", Column: "due_date", Type: listquery.Time, Sortable: true, Operators: []listquery.Operator{listquery.OpGt, listquery.OpGte, listquery.OpLt, listquery.OpLte}},
+		"instance_id": {Table: "task_instances", Column: "instance_id", Type: listquery.Int, Sortable: true, Operators: []listquery.Operator{listquery.OpEq, listquery.OpIn}},
+		"created_at":  {Table: "task_instances", Column: "created_at", Type: listquery.Time, Sortable: true, Operators: []listquery.Operator{listquery.OpGte, listquery.OpLte}},
+		"updated_at":  {Table: "task_instances", Column: "updated_at", Type: listquery.Time, Sortable: true, Operators: []listquery.Operator{listquery.OpGte, listquery.OpLte}},
+	},
+	DefaultSort: []listquery.Sort{
+		{Field: "priority", Desc: true},
+		{Field: "created_at", Desc: true},
+		{Field: "id", Desc: true},
+	},
+}
+
+// TaskQuery 任务查询条件，零值字段不参与过滤，所有条件以参数绑定方式转换为SQL
+type TaskQuery struct {
+	// AssigneeID 只查询分配给该用户的任务
+	AssigneeID *uint
+	// CandidateID 查询该用户可以处理的任务：分配给该用户的任务和任务池中该用户可以认领的任务
+	CandidateID *uint
+	// CandidateRole 与 CandidateID 一起使用，该用户的角色，用于匹配任务池中的候选角色
+	CandidateRole string
+	// Statuses 任务状态，任一匹配即可
+	Statuses []string
+	// MinPriority、MaxPriority 优先级范围（闭区间）
+	MinPriority *int
+	MaxPriority *int
+	// DueAfter、DueBefore 截止时间窗口（闭区间），设置后没有截止时间的任务不会返回
+	DueAfter  *time.Time
+	DueBefore *time.Time
+	// DefinitionKeys 流程标识，匹配该流程的所有版本
+	DefinitionKeys []string
+	// InstanceIDs 流程实例ID
+	InstanceIDs []uint
+	// NodeIDs 任务所在的节点ID
+	NodeIDs []string
+	// BusinessKey 流程实例的业务键，精确匹配
+	BusinessKey string
+	// StarterID 只查询该用户发起的流程实例中的任务
+	StarterID *uint
+	// Text 按任务名称或业务键模糊匹配
+	Text string
+	// Params 请求中经 TaskListSchema 校验的排序和过滤参数
+	Params *listquery.Params
+
+	Offset int
+	Limit  int
+}
+
+// ApplyCriteria 将收件箱查询条件合并到任务查询上
+func (q *TaskQuery) ApplyCriteria(criteria *model.TaskFilterCriteria) {
+	if len(criteria.Statuses) > 0 {
+		q.Statuses = criteria.Statuses
+	}
+	q.DefinitionKeys = criteria.DefinitionKeys
+	q.NodeIDs = criteria.NodeIDs
+	q.MinPriority = criteria.MinPriority
+	q.MaxPriority = criteria.MaxPriority
+	q.DueAfter = criteria.DueAfter
+	q.DueBefore = criteria.DueBefore
+	q.BusinessKey = criteria.BusinessKey
+	q.StarterID = criteria.StartedBy
+	q.Text = criteria.Text
+}
+
+// Apply 将查询条件应用到任务查询上
+func (q *TaskQuery) Apply(db *gorm.DB) *gorm.DB {
+	if q.AssigneeID != nil {
+		db = db.Where("task_instances.assignee_id = ?", *q.AssigneeID)
+	}
+	if q.CandidateID != nil {
+		db = db.Where(candidateTaskCondition(db, *q.CandidateID, q.CandidateRole))
+	}
+	if len(q.Statuses) > 0 {
+		db = db.Where("task_instances.status IN ?", q.Statuses)
+	}
+	if q.MinPriority != nil {
+		db = db.Where("task_instances.priority >= ?", *q.MinPriority)
+	}
+	if q.MaxPriority != nil {
+		db = db.Where("task_instances.priority <= ?", *q.MaxPriority)
+	}
+	if q.DueAfter != nil {
+		db = db.Where("task_instances.due_date >= ?", *q.DueAfter)
+	}
+	if q.DueBefore != nil {
+		db = db.Where("task_instances.due_date <= ?", *q.DueBefore)
+	}
+	if len(q.DefinitionKeys) > 0 {
+		db = db.Where("task_instances.instance_id IN (?)",
+			db.Session(&gorm.Session{NewDB: true}).
+				Model(&model.ProcessInstance{}).
+				Select("process_instances.id").
+				Joins("JOIN process_definitions ON process_definitions.id = process_instances.definition_id").
+				Where(db.Statement.Quote("process_definitions.key")+" IN ?", q.DefinitionKeys))
+	}
+	if len(q.InstanceIDs) > 0 {
+		db = db.Where("task_instances.instance_id IN ?", q.InstanceIDs)
+	}
+	if len(q.NodeIDs) > 0 {
+		db = db.Where("task_instances.node_id IN ?", q.NodeIDs)
+	}
+	if key := strings.TrimSpace(q.BusinessKey); key != "" || q.StarterID != nil {
+		instances := db.Session(&gorm.Session{NewDB: true}).
+			Model(&model.ProcessInstance{}).
+			Select("id")
+		if key != "" {
+			instances = instances.Where("business_key = ?", key)
+		}
+		if q.StarterID != nil {
+			instances = instances.Where("starter_id = ?", *q.StarterID)
+		}
+		db = db.Where("task_instances.instance_id IN (?)", instances)
+	}
+	if text := strings.TrimSpace(q.Text); text != "" {
+		pattern := "%" + listquery.EscapeLike(text) + "%"
+		db = db.Where("(task_instances.name LIKE ? ESCAPE ? OR task_instances.instance_id IN (?))", pattern, listquery.LikeEscape,
+			db.Session(&gorm.Session{NewDB: true}).
+				Model(&model.ProcessInstance{}).
+				Select("id").
+				Where("business_key LIKE ? ESCAPE ?", pattern, listquery.LikeEscape))
+	}
+	if q.Params != nil {
+		db = q.Params.ApplyFilters(db)
+	}
+	return db
+}
+
+// candidateTaskCondition 用户可以处理的任务：分配给该用户的任务，以及任务池中没有候选人限制
+// 或候选角色、候选用户包含该用户的未分配任务
+func candidateTaskCondition(db *gorm.DB, userID uint, role string) *gorm.DB {
+	pool := db.Session(&gorm.Session{NewDB: true}).
+		Where("COALESCE(task_instances.candidate_roles, '') = '' AND COALESCE(task_instances.candidate_users, '') = ''").
+		Or("task_instances.candidate_users LIKE ? ESCAPE ?", candidatePattern(strconv.FormatUint(uint64(userID), 10)), listquery.LikeEscape)
+	if role != "" {
+		pool = pool.Or("task_instances.candidate_roles LIKE ? ESCAPE ?", candidatePattern(role), listquery.LikeEscape)
+	}
+
+	return db.Session(&gorm.Session{NewDB: true}).
+		Where("task_instances.assignee_id = ?", userID).
+		Or(db.Session(&gorm.Session{NewDB: true}).
+			Where("task_instances.assignee_id IS NULL AND task_instances.status = ?", model.TaskStatusCreated).
+			Where(pool))
+}
+
+// candidatePattern 匹配候选人JSON数组中的一个元素，需要配合 ESCAPE listquery.LikeEscape 使用
+func candidatePattern(value string) string {
+	element, _ := json.Marshal(value)
+	return "%" + listquery.EscapeLike(string(element)) + "%"
+}
+
+// Query 按任务查询条件分页获取任务，默认按优先级和创建时间倒序
+func (r *TaskRepository) Query(ctx context.Context, q *TaskQuery) ([]model.TaskInstance, int64, error) {
+	var tasks []model.TaskInstance
+	var total int64
+
+	query := q.Apply(r.db.WithContext(ctx).Model(&model.TaskInstance{}))
+
+	// 获取总数
+	if err := query.Count(&total).Error; err != nil {
+		r.logger.Error("Failed to count tasks by query", zap.Error(err))
+		return nil, 0, err
+	}
+
+	query = query.Preload("Instance").
+		Preload("Instance.Definition").
+		Preload("Assignee")
+	if q.Params != nil {
+		query = q.Params.ApplySort(query)
+	} else {
+		query = query.Order("task_instances.priority DESC, task_instances.created_at DESC, task_instances.id DESC")
+	}
+	if q.Limit > 0 {
+		query = query.Offset(q.Offset).Limit(q.Limit)
+	}
+
+	if err := query.Find(&tasks).Error; err != nil {
+		r.logger.Error("Failed to query tasks", zap.Error(err))
+		return nil, 0, err
+	}
+
+	return tasks, total, nil
+}
+
+// QueryAfter 按任务查询条件获取游标位置之后的一页任务，按创建时间和ID倒序排列，不统计总数
+// 游标分页忽略 Params 中的排序和 Offset
+func (r *TaskRepository) QueryAfter(ctx context.Context, q *TaskQuery, after Keyset) ([]model.TaskInstance, error) {
+	var tasks []model.TaskInstance
+
+	query := q.Apply(r.db.WithContext(ctx).Model(&model.TaskInstance{}))
+	query = after.apply(query, "task_instances", "created_at").
+		Preload("Instance").
+		Preload("Instance.Definition").
+		Preload("Assignee").
+		Limit(q.Limit)
+
+	if err := query.Find(&tasks).Error; err != nil {
+		r.logger.Error("Failed to query tasks after cursor", zap.Uint("after_id", after.ID), zap.Error(err))
+		return nil, err
+	}
+
+	return tasks, nil
+}
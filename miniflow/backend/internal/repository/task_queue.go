@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/listquery"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// queueTasks 组队列中的任务：候选角色包含该组、尚未分配、所属实例运行中的任务池任务
+func (r *TaskRepository) queueTasks(ctx context.Context, group string) *gorm.DB {
+	return r.db.WithContext(ctx).Model(&model.TaskInstance{}).
+		Joins("JOIN process_instances ON process_instances.id = task_instances.instance_id").
+		Where("task_instances.assignee_id IS NULL AND task_instances.status = ?", model.TaskStatusCreated).
+		Where("task_instances.candidate_roles LIKE ? ESCAPE ?", candidatePattern(group), listquery.LikeEscape).
+		Where("process_instances.status = ?", model.InstanceStatusRunning)
+}
+
+// GetQueueTasks 按优先级从高到低、创建时间从早到晚获取组队列中的任务
+func (r *TaskRepository) GetQueueTasks(ctx context.Context, group string, limit int) ([]model.TaskInstance, error) {
+	var tasks []model.TaskInstance
+	err := r.queueTasks(ctx, group).
+		Preload("Instance").
+		Order("task_instances.priority DESC, task_instances.created_at ASC, task_instances.id ASC").
+		Limit(limit).
+		Find(&tasks).Error
+	if err != nil {
+		r.logger.Error("Failed to get queue tasks", zap.String("group", group), zap.Error(err))
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// CountQueueTasks 统计组队列中等待认领的任务数
+func (r *TaskRepository) CountQueueTasks(ctx context.Context, group string) (int64, error) {
+	var count int64
+	err := r.queueTasks(ctx, group).Count(&count).Error
+	return count, err
+}
+
+// CountOpenTasksByAssignee 按处理人统计已分配但未完成的任务数
+func (r *TaskRepository) CountOpenTasksByAssignee(ctx context.Context, userIDs []uint) (map[uint]int, error) {
+	return r.countByAssignee(r.db.WithContext(ctx).Model(&model.TaskInstance{}).
+		Where("assignee_id IN ? AND status IN ?", userIDs, []string{
+			model.TaskStatusAssigned,
+			model.TaskStatusClaimed,
+			model.TaskStatusInProgress,
+		}))
+}
+
+// CountCompletedTasksByAssignee 按处理人统计 since 之后完成的任务数
+func (r *TaskRepository) CountCompletedTasksByAssignee(ctx context.Context, userIDs []uint, since time.Time) (map[uint]int, error) {
+	return r.countByAssignee(r.db.WithContext(ctx).Model(&model.TaskInstance{}).
+		Where("assignee_id IN ? AND status = ? AND complete_time >= ?", userIDs, model.TaskStatusCompleted, since))
+}
+
+// countByAssignee 按处理人分组计数，没有任务的处理人不出现在结果中
+func (r *TaskRepository) countByAssignee(query *gorm.DB) (map[uint]int, error) {
+	var rows []struct {
+		AssigneeID uint
+		Count      int
+	}
+	if err := query.Select("assignee_id, COUNT(*) AS count").Group("assignee_id").Scan(&rows).Error; err != nil {
+		r.logger.Error("Failed to count tasks by assignee", zap.Error(err))
+		return nil, err
+	}
+	counts := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		counts[row.AssigneeID] = row.Count
+	}
+	return counts, nil
+}
@@ -1,7 +1,11 @@
 package repository
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"time"
+
 	"miniflow/internal/model"
 	"miniflow/pkg/database"
 	"miniflow/pkg/logger"
@@ -25,14 +29,14 @@ func NewUserRepository(db *database.Database, logger *logger.Logger) *UserReposi
 }
 
 // Create creates a new user
-func (r *UserRepository) Create(user *model.User) error {
-	return r.db.Create(user).Error
+func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
 }
 
 // GetByID retrieves a user by ID
-func (r *UserRepository) GetByID(id uint) (*model.User, error) {
+func (r *UserRepository) GetByID(ctx context.Context, id uint) (*model.User, error) {
 	var user model.User
-	err := r.db.First(&user, id).Error
+	err := r.db.WithContext(ctx).First(&user, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("用户不存在")
@@ -43,9 +47,9 @@ func (r *UserRepository) GetByID(id uint) (*model.User, error) {
 }
 
 // GetByUsername retrieves a user by username
-func (r *UserRepository) GetByUsername(username string) (*model.User, error) {
+func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
 	var user model.User
-	err := r.db.Where("username = ? AND status = ?", username, "active").First(&user).Error
+	err := r.db.WithContext(ctx).Where("username = ? AND status = ?", username, "active").First(&user).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("用户不存在")
@@ -56,9 +60,9 @@ func (r *UserRepository) GetByUsername(username string) (*model.User, error) {
 }
 
 // GetByEmail retrieves a user by email
-func (r *UserRepository) GetByEmail(email string) (*model.User, error) {
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
 	var user model.User
-	err := r.db.Where("email = ? AND status = ?", email, "active").First(&user).Error
+	err := r.db.WithContext(ctx).Where("email = ? AND status = ?", email, "active").First(&user).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("用户不存在")
@@ -69,27 +73,27 @@ func (r *UserRepository) GetByEmail(email string) (*model.User, error) {
 }
 
 // Update updates a user
-func (r *UserRepository) Update(user *model.User) error {
-	return r.db.Save(user).Error
+func (r *UserRepository) Update(ctx context.Context, user *model.User) error {
+	return r.db.WithContext(ctx).Save(user).Error
 }
 
 // Delete soft deletes a user
-func (r *UserRepository) Delete(id uint) error {
-	return r.db.Delete(&model.User{}, id).Error
+func (r *UserRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.User{}, id).Error
 }
 
 // List retrieves users with pagination
-func (r *UserRepository) List(offset, limit int) ([]*model.User, int64, error) {
+func (r *UserRepository) List(ctx context.Context, offset, limit int) ([]*model.User, int64, error) {
 	var users []*model.User
 	var total int64
 
 	// Count total records
-	if err := r.db.Model(&model.User{}).Count(&total).Error; err != nil {
+	if err := r.db.WithContext(ctx).Model(&model.User{}).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// Get paginated records
-	err := r.db.Offset(offset).Limit(limit).Find(&users).Error
+	err := r.db.WithContext(ctx).Offset(offset).Limit(limit).Find(&users).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -98,28 +102,28 @@ func (r *UserRepository) List(offset, limit int) ([]*model.User, int64, error) {
 }
 
 // ExistsByUsername checks if username already exists
-func (r *UserRepository) ExistsByUsername(username string) (bool, error) {
+func (r *UserRepository) ExistsByUsername(ctx context.Context, username string) (bool, error) {
 	var count int64
-	err := r.db.Model(&model.User{}).Where("username = ?", username).Count(&count).Error
+	err := r.db.WithContext(ctx).Model(&model.User{}).Where("username = ?", username).Count(&count).Error
 	return count > 0, err
 }
 
 // ExistsByEmail checks if email already exists
-func (r *UserRepository) ExistsByEmail(email string) (bool, error) {
+func (r *UserRepository) ExistsByEmail(ctx context.Context, email string) (bool, error) {
 	var count int64
-	err := r.db.Model(&model.User{}).Where("email = ?", email).Count(&count).Error
+	err := r.db.WithContext(ctx).Model(&model.User{}).Where("email = ?", email).Count(&count).Error
 	return count > 0, err
 }
 
 // UpdateLastLoginTime updates user's last login time
-func (r *UserRepository) UpdateLastLoginTime(id uint) error {
-	return r.db.Model(&model.User{}).Where("id = ?", id).Update("last_login_at", gorm.Expr("NOW()")).Error
+func (r *UserRepository) UpdateLastLoginTime(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).Update("last_login_at", time.Now()).Error
 }
 
 // GetActiveUsers retrieves all active users
-func (r *UserRepository) GetActiveUsers() ([]model.User, error) {
+func (r *UserRepository) GetActiveUsers(ctx context.Context) ([]model.User, error) {
 	var users []model.User
-	err := r.db.Where("status = ?", "active").
+	err := r.db.WithContext(ctx).Where("status = ?", "active").
 		Order("username ASC").
 		Find(&users).Error
 	
@@ -132,9 +136,9 @@ func (r *UserRepository) GetActiveUsers() ([]model.User, error) {
 }
 
 // GetUsersByRole 根据角色获取用户
-func (r *UserRepository) GetUsersByRole(role string) ([]model.User, error) {
+func (r *UserRepository) GetUsersByRole(ctx context.Context, role string) ([]model.User, error) {
 	var users []model.User
-	err := r.db.Where("role = ? AND status = ?", role, "active").
+	err := r.db.WithContext(ctx).Where("role = ? AND status = ?", role, "active").
 		Order("username ASC").
 		Find(&users).Error
 	
@@ -147,8 +151,72 @@ func (r *UserRepository) GetUsersByRole(role string) ([]model.User, error) {
 }
 
 // CountByRole counts users by role
-func (r *UserRepository) CountByRole(role string) (int64, error) {
+func (r *UserRepository) CountByRole(ctx context.Context, role string) (int64, error) {
 	var count int64
-	err := r.db.Model(&model.User{}).Where("role = ? AND status = ?", role, "active").Count(&count).Error
+	err := r.db.WithContext(ctx).Model(&model.User{}).Where("role = ? AND status = ?", role, "active").Count(&count).Error
 	return count, err
 }
+
+// FindByIDs retrieves users by IDs, including inactive and deleted users
+func (r *UserRepository) FindByIDs(ctx context.Context, ids []uint) ([]model.User, error) {
+	var users []model.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	err := r.db.WithContext(ctx).Unscoped().Where("id IN ?", ids).Find(&users).Error
+	return users, err
+}
+
+// FindByUsernames retrieves users by usernames, including inactive and deleted users
+func (r *UserRepository) FindByUsernames(ctx context.Context, usernames []string) ([]model.User, error) {
+	var users []model.User
+	if len(usernames) == 0 {
+		return users, nil
+	}
+	err := r.db.WithContext(ctx).Unscoped().Where("username IN ?", usernames).Find(&users).Error
+	return users, err
+}
+
+// GetUsersByGroup 获取用户组中的活跃用户
+func (r *UserRepository) GetUsersByGroup(ctx context.Context, groupCode string) ([]model.User, error) {
+	var users []model.User
+	err := r.db.WithContext(ctx).Joins("JOIN user_group_members ON user_group_members.user_id = users.id").
+		Joins("JOIN user_groups ON user_groups.id = user_group_members.group_id AND user_groups.deleted_at IS NULL").
+		Where("user_groups.code = ? AND users.status = ?", groupCode, "active").
+		Order("users.username ASC").
+		Find(&users).Error
+
+	if err != nil {
+		r.logger.Error("Failed to get users by group", zap.String("group", groupCode), zap.Error(err))
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// GetDepartment 根据ID获取部门，部门不存在或已删除时返回 nil
+func (r *UserRepository) GetDepartment(ctx context.Context, id uint) (*model.Department, error) {
+	var department model.Department
+	if err := r.db.WithContext(ctx).First(&department, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &department, nil
+}
+
+// GetDepartmentManager 获取部门的负责人，部门不存在或没有设置负责人时返回错误
+func (r *UserRepository) GetDepartmentManager(ctx context.Context, departmentCode string) (*model.User, error) {
+	var department model.Department
+	if err := r.db.WithContext(ctx).Where("code = ?", departmentCode).First(&department).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("部门 %s 不存在", departmentCode)
+		}
+		return nil, err
+	}
+	if department.ManagerID == nil {
+		return nil, fmt.Errorf("部门 %s 没有设置负责人", departmentCode)
+	}
+	return r.GetByID(ctx, *department.ManagerID)
+}
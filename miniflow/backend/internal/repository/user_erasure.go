@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrUserHasOpenTasks 用户仍有未完成的任务，需要先转办后才能删除
+	ErrUserHasOpenTasks = errors.New("用户仍有未完成的任务，请先转办")
+	// ErrTombstoneUser 占位账户不能删除，用户名被普通账户占用时也返回该错误
+	ErrTombstoneUser = errors.New("占位账户不能删除")
+)
+
+// erasePersonalTables 按 user_id 物理删除的个人数据表
+var erasePersonalTables = []struct {
+	name  string
+	model interface{}
+}{
+	{"refresh_tokens", &model.RefreshToken{}},
+	{"revoked_access_tokens", &model.RevokedAccessToken{}},
+	{"notification_preferences", &model.NotificationPreference{}},
+	{"notification_queue", &model.NotificationQueueItem{}},
+	{"notifications", &model.Notification{}},
+	{"user_group_members", &model.GroupMember{}},
+	{"saved_task_filters", &model.SavedTaskFilter{}},
+	{"idempotency_records", &model.IdempotencyRecord{}},
+	{"rpt_dim_user", &model.ReportDimUser{}},
+}
+
+// eraseHistoryColumns 历史记录中引用用户的列，删除用户时转给占位账户以保留记录的完整性
+var eraseHistoryColumns = []struct {
+	name   string
+	model  interface{}
+	column string
+}{
+	{"process_instances.starter_id", &model.ProcessInstance{}, "starter_id"},
+	{"task_instances.assignee_id", &model.TaskInstance{}, "assignee_id"},
+	{"task_instances.owner_id", &model.TaskInstance{}, "owner_id"},
+	{"task_comments.user_id", &model.TaskComment{}, "user_id"},
+	{"task_events.user_id", &model.TaskEvent{}, "user_id"},
+	{"activity_histories.actor_id", &model.ActivityHistory{}, "actor_id"},
+	{"attachments.uploaded_by", &model.Attachment{}, "uploaded_by"},
+	{"instance_archives.starter_id", &model.InstanceArchive{}, "starter_id"},
+	{"rpt_fact_instance.starter_id", &model.ReportFactInstance{}, "starter_id"},
+	{"rpt_fact_task.assignee_id", &model.ReportFactTask{}, "assignee_id"},
+	{"process_definitions.created_by", &model.ProcessDefinition{}, "created_by"},
+	{"deployments.deployed_by", &model.Deployment{}, "deployed_by"},
+	{"announcements.created_by", &model.Announcement{}, "created_by"},
+	{"process_kpis.created_by", &model.ProcessKPI{}, "created_by"},
+	{"webhook_subscriptions.created_by", &model.WebhookSubscription{}, "created_by"},
+	{"purge_certificates.purged_by", &model.PurgeCertificate{}, "purged_by"},
+	{"instance_duplicates.resolved_by", &model.InstanceDuplicate{}, "resolved_by"},
+	{"incidents.resolved_by", &model.Incident{}, "resolved_by"},
+	{"connector_policies.updated_by", &model.ConnectorPolicy{}, "updated_by"},
+	{"complexity_budgets.updated_by", &model.ComplexityBudget{}, "updated_by"},
+	{"notification_templates.updated_by", &model.NotificationTemplate{}, "updated_by"},
+}
+
+// EraseUser 物理删除用户及其个人数据，把历史记录中对用户的引用转给占位账户，并记录删除凭证
+// 占位账户不存在时在同一事务中创建；用户仍有未完成的任务时返回 ErrUserHasOpenTasks
+func (r *UserRepository) EraseUser(ctx context.Context, id, erasedBy uint, reason string) (*model.UserErasure, error) {
+	var erasure *model.UserErasure
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user model.User
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, id).Error; err != nil {
+			return err
+		}
+		if user.Username == model.TombstoneUsername {
+			return ErrTombstoneUser
+		}
+
+		var open int64
+		err := tx.Model(&model.TaskInstance{}).
+			Where("assignee_id = ? OR owner_id = ?", id, id).
+			Where("status IN ?", []string{
+				model.TaskStatusCreated, model.TaskStatusAssigned, model.TaskStatusClaimed,
+				model.TaskStatusInProgress, model.TaskStatusEscalated,
+			}).
+			Count(&open).Error
+		if err != nil {
+			return err
+		}
+		if open > 0 {
+			return ErrUserHasOpenTasks
+		}
+
+		tombstone, err := getOrCreateTombstone(tx)
+		if err != nil {
+			return err
+		}
+
+		rows := make(map[string]int64)
+		for _, table := range erasePersonalTables {
+			result := tx.Unscoped().Where("user_id = ?", id).Delete(table.model)
+			if result.Error != nil {
+				return result.Error
+			}
+			rows[table.name] = result.RowsAffected
+		}
+		for _, ref := range eraseHistoryColumns {
+			result := tx.Model(ref.model).Unscoped().
+				Where(ref.column+" = ?", id).
+				UpdateColumn(ref.column, tombstone.ID)
+			if result.Error != nil {
+				return result.Error
+			}
+			rows[ref.name] = result.RowsAffected
+		}
+
+		// 部门负责人不转给占位账户，清空后由管理员重新指定
+		result := tx.Model(&model.Department{}).Unscoped().Where("manager_id = ?", id).UpdateColumn("manager_id", nil)
+		if result.Error != nil {
+			return result.Error
+		}
+		rows["departments.manager_id"] = result.RowsAffected
+
+		if err := tx.Unscoped().Delete(&user).Error; err != nil {
+			return err
+		}
+		rows["users"] = 1
+
+		erasure = model.NewUserErasure(&user, tombstone.ID, erasedBy, reason, rows, time.Now())
+		return tx.Create(erasure).Error
+	})
+	if err != nil {
+		if !errors.Is(err, ErrUserHasOpenTasks) && !errors.Is(err, ErrTombstoneUser) && !errors.Is(err, gorm.ErrRecordNotFound) {
+			r.logger.Error("Failed to erase user", zap.Uint("user_id", id), zap.Error(err))
+		}
+		return nil, err
+	}
+	return erasure, nil
+}
+
+// GetUserErasures 获取用户的删除凭证
+func (r *UserRepository) GetUserErasures(ctx context.Context, userID uint) ([]model.UserErasure, error) {
+	var erasures []model.UserErasure
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id ASC").Find(&erasures).Error
+	return erasures, err
+}
+
+// getOrCreateTombstone 获取占位账户，不存在时创建
+func getOrCreateTombstone(tx *gorm.DB) (*model.User, error) {
+	var tombstone model.User
+	err := tx.Unscoped().Where("username = ?", model.TombstoneUsername).First(&tombstone).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		tombstone = *model.NewTombstoneUser()
+		if err := tx.Create(&tombstone).Error; err != nil {
+			return nil, err
+		}
+		return &tombstone, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tombstone.Status != model.UserStatusErased {
+		return nil, ErrTombstoneUser
+	}
+	return &tombstone, nil
+}
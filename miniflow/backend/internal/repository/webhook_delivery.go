@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// CreateWebhookDelivery 创建回调投递记录
+func (r *ProcessInstanceRepository) CreateWebhookDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	if err := r.db.WithContext(ctx).Create(delivery).Error; err != nil {
+		r.logger.Error("Failed to create webhook delivery",
+			zap.Uint("instance_id", delivery.InstanceID),
+			zap.Error(err),
+		)
+		return err
+	}
+	return nil
+}
+
+// GetWebhookDelivery 根据ID获取回调投递记录
+func (r *ProcessInstanceRepository) GetWebhookDelivery(ctx context.Context, id uint) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	if err := r.db.WithContext(ctx).First(&delivery, id).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// RequeueWebhookDelivery 将失败的回调投递重新置为待投递，投递已在进行或已成功时返回 false
+func (r *ProcessInstanceRepository) RequeueWebhookDelivery(ctx context.Context, id uint) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.WebhookDelivery{}).
+		Where("id = ? AND status = ?", id, model.WebhookDeliveryFailed).
+		Update("status", model.WebhookDeliveryPending)
+	return result.RowsAffected == 1, result.Error
+}
+
+// RecordWebhookDeliveryResult 记录一次投递（含内部重试）的结果
+func (r *ProcessInstanceRepository) RecordWebhookDeliveryResult(ctx context.Context, delivery *model.WebhookDelivery, attempts int, deliveryErr error, now time.Time) error {
+	delivery.Attempts += attempts
+	if deliveryErr != nil {
+		delivery.Status = model.WebhookDeliveryFailed
+		delivery.LastError = deliveryErr.Error()
+	} else {
+		delivery.Status = model.WebhookDeliveryDelivered
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+	}
+
+	err := r.db.WithContext(ctx).Model(delivery).Updates(map[string]interface{}{
+		"attempts":     delivery.Attempts,
+		"status":       delivery.Status,
+		"last_error":   delivery.LastError,
+		"delivered_at": delivery.DeliveredAt,
+	}).Error
+	if err != nil {
+		r.logger.Error("Failed to record webhook delivery result",
+			zap.Uint("delivery_id", delivery.ID),
+			zap.Error(err),
+		)
+	}
+	return err
+}
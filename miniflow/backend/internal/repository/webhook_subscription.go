@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/database"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// WebhookSubscriptionRepository 事件订阅数据访问层
+type WebhookSubscriptionRepository struct {
+	db     *database.Database
+	logger *logger.Logger
+}
+
+// NewWebhookSubscriptionRepository 创建新的事件订阅仓库
+func NewWebhookSubscriptionRepository(db *database.Database, logger *logger.Logger) *WebhookSubscriptionRepository {
+	return &WebhookSubscriptionRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Create 创建事件订阅
+func (r *WebhookSubscriptionRepository) Create(ctx context.Context, subscription *model.WebhookSubscription) error {
+	if err := r.db.WithContext(ctx).Create(subscription).Error; err != nil {
+		r.logger.Error("Failed to create webhook subscription", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetByID 根据ID获取事件订阅
+func (r *WebhookSubscriptionRepository) GetByID(ctx context.Context, id uint) (*model.WebhookSubscription, error) {
+	var subscription model.WebhookSubscription
+	if err := r.db.WithContext(ctx).First(&subscription, id).Error; err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// Update 更新事件订阅
+func (r *WebhookSubscriptionRepository) Update(ctx context.Context, subscription *model.WebhookSubscription) error {
+	return r.db.WithContext(ctx).Save(subscription).Error
+}
+
+// Delete 删除事件订阅，已有的投递记录保留
+func (r *WebhookSubscriptionRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.WebhookSubscription{}, id).Error
+}
+
+// List 获取全部事件订阅
+func (r *WebhookSubscriptionRepository) List(ctx context.Context) ([]model.WebhookSubscription, error) {
+	var subscriptions []model.WebhookSubscription
+	err := r.db.WithContext(ctx).Order("id ASC").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// GetActive 获取启用的事件订阅
+func (r *WebhookSubscriptionRepository) GetActive(ctx context.Context) ([]model.WebhookSubscription, error) {
+	var subscriptions []model.WebhookSubscription
+	err := r.db.WithContext(ctx).Where("active = ?", true).Order("id ASC").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// ListDeliveries 分页获取订阅的投递记录，最新的在前
+func (r *WebhookSubscriptionRepository) ListDeliveries(ctx context.Context, subscriptionID uint, offset, limit int) ([]model.WebhookDelivery, int64, error) {
+	var deliveries []model.WebhookDelivery
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&model.WebhookDelivery{}).Where("subscription_id = ?", subscriptionID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&deliveries).Error
+	return deliveries, total, err
+}
@@ -0,0 +1,279 @@
+// Package seed creates demo data so a fresh installation can be explored
+// without manual setup.
+//
+// All data is created through the regular services and the process engine, so
+// demo instances and tasks go through the same state transitions, events and
+// assignment rules as real ones.
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"miniflow/internal/engine"
+	"miniflow/internal/model"
+	"miniflow/internal/repository"
+	"miniflow/internal/service"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// DemoPassword is the password of every demo account
+const DemoPassword = "demo123456"
+
+// ErrAlreadySeeded is returned when the demo data has already been created
+var ErrAlreadySeeded = errors.New("demo data already exists")
+
+// DemoUser describes a demo account. Roles double as assignment groups:
+// tasks assigned to "role:manager" go to the least loaded manager.
+type DemoUser struct {
+	Username    string
+	DisplayName string
+	Email       string
+	Role        string
+}
+
+// DemoUsers are the accounts created by the seeder. The first one is the
+// administrator that owns the sample processes.
+var DemoUsers = []DemoUser{
+	{Username: "demo_admin", DisplayName: "演示管理员", Email: "demo_admin@miniflow.local", Role: "admin"},
+	{Username: "demo_manager", DisplayName: "王经理", Email: "demo_manager@miniflow.local", Role: "manager"},
+	{Username: "demo_finance", DisplayName: "李会计", Email: "demo_finance@miniflow.local", Role: "finance"},
+	{Username: "demo_alice", DisplayName: "Alice", Email: "demo_alice@miniflow.local", Role: "user"},
+	{Username: "demo_bob", DisplayName: "Bob", Email: "demo_bob@miniflow.local", Role: "user"},
+}
+
+// Summary reports what the seeder created
+type Summary struct {
+	Users     []DemoUser
+	Processes []string
+	// Instances counts the demo instances by status
+	Instances map[string]int
+}
+
+// Seeder creates demo users, published sample processes and instances in
+// various states
+type Seeder struct {
+	userService    *service.UserService
+	userRepo       *repository.UserRepository
+	processService *service.ProcessService
+	engine         *engine.ProcessEngine
+	logger         *logger.Logger
+}
+
+// NewSeeder creates a new demo data seeder
+func NewSeeder(
+	userService *service.UserService,
+	userRepo *repository.UserRepository,
+	processService *service.ProcessService,
+	engine *engine.ProcessEngine,
+	logger *logger.Logger,
+) *Seeder {
+	return &Seeder{
+		userService:    userService,
+		userRepo:       userRepo,
+		processService: processService,
+		engine:         engine,
+		logger:         logger,
+	}
+}
+
+// Run creates the demo data. It returns ErrAlreadySeeded when the demo
+// administrator already exists, so running it twice is harmless.
+func (s *Seeder) Run(ctx context.Context) (*Summary, error) {
+	exists, err := s.userRepo.ExistsByUsername(ctx, DemoUsers[0].Username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check demo data: %w", err)
+	}
+	if exists {
+		return nil, ErrAlreadySeeded
+	}
+
+	users, err := s.seedUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	processes, err := s.seedProcesses(ctx, users[DemoUsers[0].Username])
+	if err != nil {
+		return nil, err
+	}
+
+	instances, err := s.seedInstances(ctx, users, processes)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{
+		Users:     DemoUsers,
+		Instances: instances,
+	}
+	for _, process := range demoProcesses {
+		summary.Processes = append(summary.Processes, process.Key)
+	}
+
+	s.logger.Info("Demo data created",
+		zap.Int("users", len(summary.Users)),
+		zap.Int("processes", len(summary.Processes)),
+		zap.Any("instances", summary.Instances),
+	)
+	return summary, nil
+}
+
+// seedUsers registers the demo accounts and returns their IDs by username
+func (s *Seeder) seedUsers(ctx context.Context) (map[string]uint, error) {
+	ids := make(map[string]uint, len(DemoUsers))
+	for _, demo := range DemoUsers {
+		resp, err := s.userService.Register(ctx, &service.RegisterRequest{
+			Username:    demo.Username,
+			Password:    DemoPassword,
+			DisplayName: demo.DisplayName,
+			Email:       demo.Email,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create demo user %s: %w", demo.Username, err)
+		}
+
+		// Registration always creates plain users
+		if demo.Role != "user" {
+			user, err := s.userRepo.GetByID(ctx, resp.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load demo user %s: %w", demo.Username, err)
+			}
+			user.Role = demo.Role
+			if err := s.userRepo.Update(ctx, user); err != nil {
+				return nil, fmt.Errorf("failed to set role of demo user %s: %w", demo.Username, err)
+			}
+		}
+
+		ids[demo.Username] = resp.ID
+	}
+	return ids, nil
+}
+
+// seedProcesses creates and publishes the sample processes and returns their IDs by key
+func (s *Seeder) seedProcesses(ctx context.Context, ownerID uint) (map[string]uint, error) {
+	ids := make(map[string]uint, len(demoProcesses))
+	for _, req := range demoProcesses {
+		process, err := s.processService.CreateProcess(ctx, ownerID, req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create demo process %s: %w", req.Key, err)
+		}
+		if err := s.processService.PublishProcess(ctx, process.ID, ownerID); err != nil {
+			return nil, fmt.Errorf("failed to publish demo process %s: %w", req.Key, err)
+		}
+		ids[req.Key] = process.ID
+	}
+	return ids, nil
+}
+
+// demoInstance describes a demo instance and how far it is driven
+type demoInstance struct {
+	process     string
+	starter     string
+	businessKey string
+	variables   map[string]interface{}
+	// complete is the number of tasks completed after the start
+	complete int
+	// claim claims the next open task after completing the tasks above
+	claim bool
+	// suspend or cancel the instance with this reason
+	suspend string
+	cancel  string
+}
+
+// demoInstances cover every instance status and the main task states
+var demoInstances = []demoInstance{
+	{process: "demo_leave_request", starter: "demo_alice", businessKey: "年假申请-Alice-国庆出游",
+		variables: map[string]interface{}{"days": 3, "reason": "国庆出游"}},
+	{process: "demo_leave_request", starter: "demo_bob", businessKey: "病假申请-Bob-感冒",
+		variables: map[string]interface{}{"days": 1, "reason": "感冒"}, claim: true},
+	{process: "demo_leave_request", starter: "demo_alice", businessKey: "事假申请-Alice-搬家",
+		variables: map[string]interface{}{"days": 2, "reason": "搬家"}, complete: 1},
+	{process: "demo_expense_claim", starter: "demo_bob", businessKey: "差旅报销-上海客户拜访",
+		variables: map[string]interface{}{"amount": 12800, "level": "high"}, complete: 1},
+	{process: "demo_expense_claim", starter: "demo_alice", businessKey: "办公用品报销-打印纸",
+		variables: map[string]interface{}{"amount": 260, "level": "normal"}, complete: 1},
+	{process: "demo_purchase_request", starter: "demo_alice", businessKey: "采购-开发笔记本电脑",
+		variables: map[string]interface{}{"item": "笔记本电脑", "quantity": 2}, complete: 1, claim: true},
+	{process: "demo_purchase_request", starter: "demo_bob", businessKey: "采购-会议室投影仪",
+		variables: map[string]interface{}{"item": "投影仪", "quantity": 1}, suspend: "等待供应商报价"},
+	{process: "demo_purchase_request", starter: "demo_alice", businessKey: "采购-人体工学椅",
+		variables: map[string]interface{}{"item": "人体工学椅", "quantity": 5}, cancel: "预算调整，取消采购"},
+}
+
+// seedInstances starts the demo instances and drives them into their target
+// states. It returns the number of instances by final status.
+func (s *Seeder) seedInstances(ctx context.Context, users map[string]uint, processes map[string]uint) (map[string]int, error) {
+	counts := make(map[string]int)
+	for _, demo := range demoInstances {
+		instance, err := s.engine.StartProcess(ctx, &engine.StartProcessRequest{
+			DefinitionID: processes[demo.process],
+			BusinessKey:  demo.businessKey,
+			Variables:    demo.variables,
+		}, users[demo.starter])
+		if err != nil {
+			return nil, fmt.Errorf("failed to start demo instance %s: %w", demo.businessKey, err)
+		}
+
+		for i := 0; i < demo.complete; i++ {
+			if err := s.completeNextTask(ctx, instance.ID); err != nil {
+				return nil, fmt.Errorf("failed to advance demo instance %s: %w", demo.businessKey, err)
+			}
+		}
+		if demo.claim {
+			if _, err := s.claimNextTask(ctx, instance.ID); err != nil {
+				return nil, fmt.Errorf("failed to claim task of demo instance %s: %w", demo.businessKey, err)
+			}
+		}
+		if demo.suspend != "" {
+			if err := s.engine.SuspendInstance(ctx, instance.ID, 0, demo.suspend); err != nil {
+				return nil, fmt.Errorf("failed to suspend demo instance %s: %w", demo.businessKey, err)
+			}
+		}
+		if demo.cancel != "" {
+			if err := s.engine.CancelInstance(ctx, instance.ID, 0, demo.cancel); err != nil {
+				return nil, fmt.Errorf("failed to cancel demo instance %s: %w", demo.businessKey, err)
+			}
+		}
+
+		current, err := s.engine.GetInstance(ctx, instance.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load demo instance %s: %w", demo.businessKey, err)
+		}
+		counts[current.Status]++
+	}
+	return counts, nil
+}
+
+// claimNextTask claims the open task of an instance on behalf of its assignee
+func (s *Seeder) claimNextTask(ctx context.Context, instanceID uint) (*model.TaskInstance, error) {
+	tasks, _, err := s.engine.QueryTasks(ctx, &repository.TaskQuery{
+		InstanceIDs: []uint{instanceID},
+		Statuses:    []string{model.TaskStatusAssigned},
+		Limit:       1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 || tasks[0].AssigneeID == nil {
+		return nil, fmt.Errorf("instance %d has no assigned task", instanceID)
+	}
+
+	task := &tasks[0]
+	if err := s.engine.ClaimTask(ctx, task.ID, *task.AssigneeID); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// completeNextTask claims and completes the open task of an instance on behalf of its assignee
+func (s *Seeder) completeNextTask(ctx context.Context, instanceID uint) error {
+	task, err := s.claimNextTask(ctx, instanceID)
+	if err != nil {
+		return err
+	}
+	return s.engine.CompleteTask(ctx, task.ID, *task.AssigneeID, map[string]interface{}{"approved": true}, "同意")
+}
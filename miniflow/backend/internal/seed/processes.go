@@ -0,0 +1,75 @@
+package seed
+
+import (
+	"miniflow/internal/model"
+	"miniflow/internal/service"
+)
+
+// demoProcesses are the sample processes published by the seeder. Tasks are
+// assigned by role so they show up in the inboxes of the demo accounts.
+var demoProcesses = []*service.CreateProcessRequest{
+	{
+		Key:         "demo_leave_request",
+		Name:        "请假申请",
+		Description: "员工提交请假申请，由部门经理审批",
+		Category:    "人事",
+		Definition: model.ProcessDefinitionData{
+			Nodes: []model.ProcessNode{
+				{ID: "start", Type: model.NodeTypeStart, Name: "开始", X: 100, Y: 200},
+				{ID: "manager_approve", Type: model.NodeTypeUserTask, Name: "经理审批", X: 300, Y: 200,
+					Props: map[string]interface{}{"assignee": "role:manager", "estimatedHours": float64(4)}},
+				{ID: "end", Type: model.NodeTypeEnd, Name: "结束", X: 500, Y: 200},
+			},
+			Flows: []model.ProcessFlow{
+				{ID: "flow_1", From: "start", To: "manager_approve"},
+				{ID: "flow_2", From: "manager_approve", To: "end"},
+			},
+		},
+	},
+	{
+		Key:         "demo_expense_claim",
+		Name:        "费用报销",
+		Description: "经理审批后，大额报销需要财务复核",
+		Category:    "财务",
+		Definition: model.ProcessDefinitionData{
+			Nodes: []model.ProcessNode{
+				{ID: "start", Type: model.NodeTypeStart, Name: "开始", X: 100, Y: 200},
+				{ID: "manager_approve", Type: model.NodeTypeUserTask, Name: "经理审批", X: 250, Y: 200,
+					Props: map[string]interface{}{"assignee": "role:manager"}},
+				{ID: "amount_check", Type: model.NodeTypeGateway, Name: "金额判断", X: 400, Y: 200,
+					Props: map[string]interface{}{"gatewayType": "exclusive"}},
+				{ID: "finance_review", Type: model.NodeTypeUserTask, Name: "财务复核", X: 550, Y: 120,
+					Props: map[string]interface{}{"assignee": "role:finance"}},
+				{ID: "end", Type: model.NodeTypeEnd, Name: "结束", X: 700, Y: 200},
+			},
+			Flows: []model.ProcessFlow{
+				{ID: "flow_1", From: "start", To: "manager_approve"},
+				{ID: "flow_2", From: "manager_approve", To: "amount_check"},
+				{ID: "flow_3", From: "amount_check", To: "finance_review", Condition: "${level} == high", Label: "大额"},
+				{ID: "flow_4", From: "amount_check", To: "end", Label: "普通"},
+				{ID: "flow_5", From: "finance_review", To: "end"},
+			},
+		},
+	},
+	{
+		Key:         "demo_purchase_request",
+		Name:        "采购申请",
+		Description: "采购申请依次经过经理审批和财务审批",
+		Category:    "行政",
+		Definition: model.ProcessDefinitionData{
+			Nodes: []model.ProcessNode{
+				{ID: "start", Type: model.NodeTypeStart, Name: "开始", X: 100, Y: 200},
+				{ID: "manager_approve", Type: model.NodeTypeUserTask, Name: "经理审批", X: 300, Y: 200,
+					Props: map[string]interface{}{"assignee": "role:manager"}},
+				{ID: "finance_approve", Type: model.NodeTypeUserTask, Name: "财务审批", X: 500, Y: 200,
+					Props: map[string]interface{}{"assignee": "role:finance"}},
+				{ID: "end", Type: model.NodeTypeEnd, Name: "结束", X: 700, Y: 200},
+			},
+			Flows: []model.ProcessFlow{
+				{ID: "flow_1", From: "start", To: "manager_approve"},
+				{ID: "flow_2", From: "manager_approve", To: "finance_approve"},
+				{ID: "flow_3", From: "finance_approve", To: "end"},
+			},
+		},
+	},
+}
@@ -0,0 +1,179 @@
+// Package server runs the HTTP API together with the background workers of
+// the engine and services, and shuts both down gracefully.
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"miniflow/internal/engine"
+	"miniflow/internal/handler"
+	"miniflow/internal/middleware"
+	"miniflow/internal/notification"
+	"miniflow/internal/service"
+	"miniflow/pkg/config"
+	"miniflow/pkg/database"
+	"miniflow/pkg/logger"
+	"miniflow/pkg/utils"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// Intervals of the background loops that take one; the others read theirs from the config
+const (
+	timerInterval              = 30 * time.Second
+	claimExpiryInterval        = 5 * time.Minute
+	announcementInterval       = time.Minute
+	capacityInterval           = 5 * time.Minute
+	kpiInterval                = time.Hour
+	reportingInterval          = time.Hour
+	tokenCleanupInterval       = time.Hour
+	idempotencyCleanupInterval = time.Hour
+)
+
+// shutdownTimeout bounds how long in-flight requests may take to finish on shutdown
+const shutdownTimeout = 30 * time.Second
+
+// worker is a background loop that runs until its context is cancelled
+type worker struct {
+	name string
+	run  func(ctx context.Context)
+}
+
+// Server is the miniflow API server
+type Server struct {
+	cfg      *config.ServerConfig
+	echo     *echo.Echo
+	db       *database.Database
+	recovery *engine.Recovery
+	// subscribers register engine event handlers; they start before recovery and the pollers
+	subscribers []worker
+	// workers poll for due work and start after recovery
+	workers []worker
+	logger  *logger.Logger
+}
+
+// NewServer creates the server and registers the routes
+func NewServer(
+	cfg *config.Config,
+	db *database.Database,
+	router *handler.Router,
+	authMiddleware *middleware.AuthMiddleware,
+	idempotency *middleware.IdempotencyMiddleware,
+	dispatcher *notification.Dispatcher,
+	recovery *engine.Recovery,
+	timers *engine.TimerScheduler,
+	jobs *engine.JobExecutor,
+	overdue *engine.OverdueScheduler,
+	notifier *engine.EventNotifier,
+	webhooks *engine.WebhookDispatcher,
+	attachments *engine.AttachmentManager,
+	archiver *engine.InstanceArchiver,
+	claimExpiry *service.ClaimExpiryService,
+	reminders *service.TaskReminderService,
+	announcements *service.AnnouncementService,
+	capacity *service.CapacityService,
+	kpis *service.KPIService,
+	reporting *service.ReportingService,
+	logger *logger.Logger,
+) *Server {
+	e := echo.New()
+	e.HideBanner = true
+	e.HidePort = true
+	e.Debug = cfg.Server.Debug
+	// Handlers check request bodies with c.Validate
+	e.Validator = utils.NewCustomValidator()
+	router.SetupRoutes(e)
+
+	return &Server{
+		cfg:      &cfg.Server,
+		echo:     e,
+		db:       db,
+		recovery: recovery,
+		subscribers: []worker{
+			{"event notifier", notifier.Start},
+			{"webhook dispatcher", webhooks.Start},
+			{"attachment cleanup", attachments.Start},
+		},
+		workers: []worker{
+			{"timer scheduler", func(ctx context.Context) { timers.Start(ctx, timerInterval) }},
+			{"job executor", jobs.Start},
+			{"overdue scheduler", overdue.Start},
+			{"instance archiver", archiver.Start},
+			{"notification dispatcher", dispatcher.Start},
+			{"claim expiry", func(ctx context.Context) { claimExpiry.Start(ctx, claimExpiryInterval) }},
+			{"task reminders", reminders.Start},
+			{"announcements", func(ctx context.Context) { announcements.Start(ctx, announcementInterval) }},
+			{"capacity sampling", func(ctx context.Context) { capacity.Start(ctx, capacityInterval) }},
+			{"kpi evaluation", func(ctx context.Context) { kpis.Start(ctx, kpiInterval) }},
+			{"reporting refresh", func(ctx context.Context) { reporting.Start(ctx, reportingInterval) }},
+			{"token cleanup", func(ctx context.Context) { authMiddleware.Start(ctx, tokenCleanupInterval) }},
+			{"idempotency cleanup", func(ctx context.Context) { idempotency.Start(ctx, idempotencyCleanupInterval) }},
+		},
+		logger: logger,
+	}
+}
+
+// Handler returns the HTTP handler of the API
+func (s *Server) Handler() http.Handler {
+	return s.echo
+}
+
+// Run serves the API and runs the background workers until ctx is cancelled
+// or the listener fails. Recovery repairs instances left behind by a previous
+// run before the workers start polling. On return in-flight requests and
+// running jobs have finished and the database is closed.
+func (s *Server) Run(ctx context.Context) error {
+	defer s.db.Close()
+
+	// Workers outlive ctx until the in-flight requests are done, so the events
+	// those requests emit are still delivered
+	workerCtx, stopWorkers := context.WithCancel(context.WithoutCancel(ctx))
+	var wg sync.WaitGroup
+	start := func(workers []worker) {
+		for _, w := range workers {
+			wg.Add(1)
+			go func(w worker) {
+				defer wg.Done()
+				w.run(workerCtx)
+				s.logger.Debug("Background worker stopped", zap.String("worker", w.name))
+			}(w)
+		}
+	}
+	defer func() {
+		stopWorkers()
+		wg.Wait()
+	}()
+
+	start(s.subscribers)
+	s.recovery.Start(ctx)
+	start(s.workers)
+
+	addr := s.cfg.GetServerAddr()
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.echo.Start(addr)
+	}()
+	s.logger.Info("Server started", zap.String("addr", addr))
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	s.logger.Info("Shutting down server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := s.echo.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,348 @@
+package server_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"miniflow/internal/migration"
+	"miniflow/internal/model"
+	"miniflow/internal/wire"
+	"miniflow/pkg/config"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// testConfig is the config.yaml of the test server; %s is the sqlite database
+const testConfig = `
+server:
+  debug: true
+database:
+  driver: sqlite
+  database: "%s"
+jwt:
+  secret: "end-to-end-test-secret-of-at-least-32-characters"
+log:
+  level: error
+`
+
+// newTestServer builds the server with the wire injector on a migrated sqlite
+// database, as cmd/server does, and serves its API. The returned handle on the
+// same database is for asserting what the requests stored.
+func newTestServer(t *testing.T) (*httptest.Server, *gorm.DB) {
+	t.Helper()
+
+	dir := t.TempDir()
+	dsn := "file:" + filepath.Join(dir, "miniflow.db") + "?_busy_timeout=5000&_txlock=immediate"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if _, err := migration.New(db, &logger.Logger{Logger: zap.NewNop()}).Up(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(fmt.Sprintf(testConfig, dsn)), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg, err := config.LoadConfig(dir)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	srv, err := wire.InitializeServer(cfg)
+	if err != nil {
+		t.Fatalf("initialize server: %v", err)
+	}
+
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+	return ts, db
+}
+
+// apiClient calls the API as one user
+type apiClient struct {
+	t      *testing.T
+	url    string
+	token  string
+	userID uint
+}
+
+// send sends a JSON request and returns the status and body of the response
+func (c *apiClient) send(method, path string, body interface{}) (int, []byte) {
+	c.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			c.t.Fatalf("%s %s: encode body: %v", method, path, err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.url+"/api/v1"+path, reader)
+	if err != nil {
+		c.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.t.Fatalf("%s %s: read body: %v", method, path, err)
+	}
+	return resp.StatusCode, raw
+}
+
+// call sends a JSON request, checks the status and decodes the data of the response into out
+func (c *apiClient) call(method, path string, body interface{}, wantStatus int, out interface{}) {
+	c.t.Helper()
+
+	status, raw := c.send(method, path, body)
+	if status != wantStatus {
+		c.t.Fatalf("%s %s: status %d, want %d: %s", method, path, status, wantStatus, raw)
+	}
+	if out == nil {
+		return
+	}
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		c.t.Fatalf("%s %s: decode response: %v", method, path, err)
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		c.t.Fatalf("%s %s: decode data: %v: %s", method, path, err, envelope.Data)
+	}
+}
+
+// registerUser registers a user and logs in as them
+func registerUser(t *testing.T, url, username string) *apiClient {
+	t.Helper()
+
+	c := &apiClient{t: t, url: url}
+	password := "testpass123"
+	c.call(http.MethodPost, "/auth/register", map[string]string{
+		"username": username,
+		"password": password,
+		"email":    username + "@example.com",
+	}, http.StatusCreated, nil)
+
+	var login struct {
+		Token string `json:"token"`
+		User  struct {
+			ID uint `json:"id"`
+		} `json:"user"`
+	}
+	c.call(http.MethodPost, "/auth/login", map[string]string{"username": username, "password": password}, http.StatusOK, &login)
+	c.token, c.userID = login.Token, login.User.ID
+	return c
+}
+
+// approvalDefinition is submit → exclusive gateway → manager approval when level is
+// high, otherwise straight to the end; both tasks go to the starter
+func approvalDefinition() map[string]interface{} {
+	return map[string]interface{}{
+		"nodes": []map[string]interface{}{
+			{"id": "start", "type": "start", "name": "开始", "x": 100, "y": 100},
+			{"id": "submit", "type": "userTask", "name": "提交申请", "x": 250, "y": 100, "props": map[string]interface{}{"assignee": "${starter.id}"}},
+			{"id": "check", "type": "gateway", "name": "金额判断", "x": 400, "y": 100, "props": map[string]interface{}{"gatewayType": "exclusive"}},
+			{"id": "manager", "type": "userTask", "name": "经理审批", "x": 550, "y": 50, "props": map[string]interface{}{"assignee": "${starter.id}"}},
+			{"id": "end", "type": "end", "name": "结束", "x": 700, "y": 100},
+		},
+		"flows": []map[string]interface{}{
+			{"id": "f1", "from": "start", "to": "submit"},
+			{"id": "f2", "from": "submit", "to": "check"},
+			{"id": "f3", "from": "check", "to": "manager", "condition": "${level} == 'high'"},
+			{"id": "f4", "from": "check", "to": "end"},
+			{"id": "f5", "from": "manager", "to": "end"},
+		},
+	}
+}
+
+// openTask returns the open task of the instance at the node, failing when there is none
+func openTask(t *testing.T, db *gorm.DB, instanceID uint, nodeID string) *model.TaskInstance {
+	t.Helper()
+
+	var task model.TaskInstance
+	err := db.Where("instance_id = ? AND node_id = ? AND status IN ?", instanceID, nodeID,
+		[]string{model.TaskStatusCreated, model.TaskStatusAssigned, model.TaskStatusClaimed}).First(&task).Error
+	if err != nil {
+		t.Fatalf("open task at %s: %v", nodeID, err)
+	}
+	return &task
+}
+
+// claimAndComplete claims and completes the task over the API and returns the task
+// change events the user received meanwhile
+func (c *apiClient) claimAndComplete(taskID uint) []string {
+	c.t.Helper()
+
+	var before struct {
+		Cursor uint `json:"cursor"`
+	}
+	c.call(http.MethodGet, "/user/tasks/changes?wait=0", nil, http.StatusOK, &before)
+
+	c.call(http.MethodPost, fmt.Sprintf("/task/%d/claim", taskID), nil, http.StatusOK, nil)
+	c.call(http.MethodPost, fmt.Sprintf("/task/%d/complete", taskID), map[string]string{"comment": "同意"}, http.StatusOK, nil)
+	// 重复完成被拒绝
+	c.call(http.MethodPost, fmt.Sprintf("/task/%d/complete", taskID), map[string]string{"comment": "同意"}, http.StatusConflict, nil)
+
+	var changes struct {
+		Changes []struct {
+			TaskID uint   `json:"task_id"`
+			Type   string `json:"type"`
+		} `json:"changes"`
+	}
+	c.call(http.MethodGet, fmt.Sprintf("/user/tasks/changes?since=%d&wait=0", before.Cursor), nil, http.StatusOK, &changes)
+	var events []string
+	for _, change := range changes.Changes {
+		if change.TaskID == taskID {
+			events = append(events, change.Type)
+		}
+	}
+	return events
+}
+
+func TestProcessFlowEndToEnd(t *testing.T) {
+	ts, db := newTestServer(t)
+
+	for _, tt := range []struct {
+		level string
+		nodes []string
+	}{
+		{level: "high", nodes: []string{"submit", "manager"}},
+		{level: "low", nodes: []string{"submit"}},
+	} {
+		t.Run(tt.level, func(t *testing.T) {
+			c := registerUser(t, ts.URL, "e2e_"+tt.level)
+
+			var process struct {
+				ID     uint   `json:"id"`
+				Status string `json:"status"`
+			}
+			c.call(http.MethodPost, "/process", map[string]interface{}{
+				"key":        "e2e_approval_" + tt.level,
+				"name":       "端到端审批流程",
+				"category":   "test",
+				"definition": approvalDefinition(),
+			}, http.StatusCreated, &process)
+			if process.Status != model.ProcessStatusDraft {
+				t.Fatalf("new process status %q, want draft", process.Status)
+			}
+			c.call(http.MethodPost, fmt.Sprintf("/process/%d/publish", process.ID), nil, http.StatusOK, nil)
+
+			var instance struct {
+				ID     uint   `json:"id"`
+				Status string `json:"status"`
+			}
+			c.call(http.MethodPost, fmt.Sprintf("/process/%d/start", process.ID), map[string]interface{}{
+				"business_key": "E2E-" + tt.level,
+				"title":        "端到端测试申请",
+				"variables":    map[string]interface{}{"level": tt.level},
+				"priority":     50,
+			}, http.StatusCreated, &instance)
+			if instance.Status != model.InstanceStatusRunning {
+				t.Fatalf("started instance status %q, want running", instance.Status)
+			}
+
+			for _, node := range tt.nodes {
+				task := openTask(t, db, instance.ID, node)
+				if task.AssigneeID == nil || *task.AssigneeID != c.userID {
+					t.Fatalf("task at %s assigned to %v, want the starter %d", node, task.AssigneeID, c.userID)
+				}
+				events := c.claimAndComplete(task.ID)
+				if len(events) == 0 || events[0] != "claimed" || !slices.Contains(events, "completed") {
+					t.Fatalf("task at %s events %v, want claimed first and completed", node, events)
+				}
+
+				var stored model.TaskInstance
+				if err := db.First(&stored, task.ID).Error; err != nil {
+					t.Fatalf("load task: %v", err)
+				}
+				if stored.Status != model.TaskStatusCompleted || stored.AssigneeID == nil || *stored.AssigneeID != c.userID {
+					t.Fatalf("task at %s stored as %s by %v, want completed by %d", node, stored.Status, stored.AssigneeID, c.userID)
+				}
+			}
+
+			var stored model.ProcessInstance
+			if err := db.First(&stored, instance.ID).Error; err != nil {
+				t.Fatalf("load instance: %v", err)
+			}
+			if stored.Status != model.InstanceStatusCompleted || stored.CurrentNode != "end" {
+				t.Fatalf("instance %s at %s, want completed at end", stored.Status, stored.CurrentNode)
+			}
+			// 默认分支不经过经理审批
+			var managerTasks int64
+			if err := db.Model(&model.TaskInstance{}).Where("instance_id = ? AND node_id = ?", instance.ID, "manager").Count(&managerTasks).Error; err != nil {
+				t.Fatalf("count manager tasks: %v", err)
+			}
+			if want := int64(len(tt.nodes) - 1); managerTasks != want {
+				t.Fatalf("manager tasks %d, want %d", managerTasks, want)
+			}
+		})
+	}
+}
+
+func TestRestrictedRoutesCheckRBACPermissions(t *testing.T) {
+	ts, db := newTestServer(t)
+	c := registerUser(t, ts.URL, "rbac_user")
+
+	routes := []struct {
+		method string
+		path   string
+		body   interface{}
+		// role is granted the route's permission by default, user is not
+		role string
+	}{
+		{http.MethodPut, "/process/1/metadata", map[string]interface{}{}, "manager"},
+		{http.MethodPost, "/process/1/deployments", map[string]interface{}{"environment": "dev"}, "manager"},
+		{http.MethodPost, "/instance/1/migrate", map[string]interface{}{"target_version": 2}, "admin"},
+		{http.MethodPost, "/deployments/import", map[string]interface{}{}, "admin"},
+		{http.MethodGet, "/deployments/1/package", nil, "manager"},
+		{http.MethodGet, "/webhooks", nil, "admin"},
+		{http.MethodPost, "/webhooks", map[string]interface{}{"url": "https://example.com/hooks", "event_types": []string{"task.created"}}, "admin"},
+		{http.MethodGet, "/webhooks/1/deliveries", nil, "admin"},
+		{http.MethodGet, "/analytics/capacity?days=7", nil, "manager"},
+	}
+	for _, route := range routes {
+		if status, body := c.send(route.method, route.path, route.body); status != http.StatusForbidden {
+			t.Errorf("user %s %s: status %d, want 403: %s", route.method, route.path, status, body)
+		}
+	}
+
+	// 角色每次请求从数据库读取，授予权限后立即通过检查
+	for _, role := range []string{"manager", "admin"} {
+		if err := db.Model(&model.User{}).Where("id = ?", c.userID).Update("role", role).Error; err != nil {
+			t.Fatalf("set role %s: %v", role, err)
+		}
+		for _, route := range routes {
+			if route.role != role && role != "admin" {
+				continue
+			}
+			if status, body := c.send(route.method, route.path, route.body); status == http.StatusForbidden {
+				t.Errorf("%s %s %s: status 403: %s", role, route.method, route.path, body)
+			}
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/internal/notification"
+	"miniflow/internal/repository"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// AnnouncementService handles admin broadcast announcements
+type AnnouncementService struct {
+	announcementRepo *repository.AnnouncementRepository
+	userRepo         *repository.UserRepository
+	dispatcher       *notification.Dispatcher
+	logger           *logger.Logger
+}
+
+// NewAnnouncementService creates a new announcement service
+func NewAnnouncementService(
+	announcementRepo *repository.AnnouncementRepository,
+	userRepo *repository.UserRepository,
+	dispatcher *notification.Dispatcher,
+	logger *logger.Logger,
+) *AnnouncementService {
+	return &AnnouncementService{
+		announcementRepo: announcementRepo,
+		userRepo:         userRepo,
+		dispatcher:       dispatcher,
+		logger:           logger,
+	}
+}
+
+// CreateAnnouncementRequest represents announcement creation request
+type CreateAnnouncementRequest struct {
+	Title       string     `json:"title" validate:"required,max=255"`
+	Content     string     `json:"content" validate:"required"`
+	Level       string     `json:"level" validate:"omitempty,oneof=info warning critical"`
+	TargetRoles []string   `json:"target_roles"`
+	PublishAt   *time.Time `json:"publish_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+// AnnouncementResponse represents announcement response data
+type AnnouncementResponse struct {
+	ID          uint       `json:"id"`
+	Title       string     `json:"title"`
+	Content     string     `json:"content"`
+	Level       string     `json:"level"`
+	TargetRoles []string   `json:"target_roles"`
+	PublishAt   time.Time  `json:"publish_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	NotifiedAt  *time.Time `json:"notified_at"`
+	CreatedBy   uint       `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// CreateAnnouncement creates an announcement; announcements due now are pushed immediately,
+// scheduled ones are pushed by the background loop once their publish time arrives
+func (s *AnnouncementService) CreateAnnouncement(ctx context.Context, req *CreateAnnouncementRequest, userID uint) (*AnnouncementResponse, error) {
+	now := time.Now()
+
+	publishAt := now
+	if req.PublishAt != nil {
+		publishAt = *req.PublishAt
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(publishAt) {
+		return nil, errors.New("过期时间必须晚于发布时间")
+	}
+
+	level := req.Level
+	if level == "" {
+		level = model.AnnouncementLevelInfo
+	}
+
+	announcement := &model.Announcement{
+		Title:     req.Title,
+		Content:   req.Content,
+		Level:     level,
+		PublishAt: publishAt,
+		ExpiresAt: req.ExpiresAt,
+		CreatedBy: userID,
+	}
+	announcement.SetTargetRoles(req.TargetRoles)
+
+	if err := s.announcementRepo.Create(ctx, announcement); err != nil {
+		return nil, errors.New("创建公告失败")
+	}
+
+	s.logger.Info("Announcement created",
+		zap.Uint("announcement_id", announcement.ID),
+		zap.Uint("created_by", userID),
+		zap.Time("publish_at", publishAt),
+	)
+
+	if !publishAt.After(now) {
+		// Publishing outlives the request, so it must not be cancelled with it
+		publishCtx := context.WithoutCancel(ctx)
+		go func() {
+			if err := s.PublishDue(publishCtx, time.Now()); err != nil {
+				s.logger.Error("Failed to publish announcements", zap.Error(err))
+			}
+		}()
+	}
+
+	return s.toAnnouncementResponse(announcement), nil
+}
+
+// ListAnnouncements retrieves all announcements with pagination (admin)
+func (s *AnnouncementService) ListAnnouncements(ctx context.Context, page, pageSize int) ([]*AnnouncementResponse, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	offset := (page - 1) * pageSize
+	announcements, total, err := s.announcementRepo.List(ctx, offset, pageSize)
+	if err != nil {
+		s.logger.Error("Failed to list announcements", zap.Error(err))
+		return nil, 0, errors.New("获取公告列表失败")
+	}
+
+	responses := make([]*AnnouncementResponse, len(announcements))
+	for i := range announcements {
+		responses[i] = s.toAnnouncementResponse(&announcements[i])
+	}
+
+	return responses, total, nil
+}
+
+// GetActiveAnnouncements returns the announcements currently visible to the user, used for banners
+func (s *AnnouncementService) GetActiveAnnouncements(ctx context.Context, userID uint) ([]*AnnouncementResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, errors.New("用户不存在")
+	}
+
+	announcements, err := s.announcementRepo.GetActive(ctx, time.Now())
+	if err != nil {
+		return nil, errors.New("获取公告失败")
+	}
+
+	responses := make([]*AnnouncementResponse, 0, len(announcements))
+	for i := range announcements {
+		if announcements[i].TargetsRole(user.Role) {
+			responses = append(responses, s.toAnnouncementResponse(&announcements[i]))
+		}
+	}
+
+	return responses, nil
+}
+
+// DeleteAnnouncement withdraws an announcement
+func (s *AnnouncementService) DeleteAnnouncement(ctx context.Context, id uint) error {
+	if _, err := s.announcementRepo.GetByID(ctx, id); err != nil {
+		return err
+	}
+	if err := s.announcementRepo.Delete(ctx, id); err != nil {
+		return errors.New("删除公告失败")
+	}
+	s.logger.Info("Announcement deleted", zap.Uint("announcement_id", id))
+	return nil
+}
+
+// PublishDue pushes in-app notifications for announcements whose publish time has arrived
+func (s *AnnouncementService) PublishDue(ctx context.Context, now time.Time) error {
+	announcements, err := s.announcementRepo.GetPendingNotify(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	for i := range announcements {
+		announcement := &announcements[i]
+
+		// 先标记再推送，避免多个实例重复推送
+		claimed, err := s.announcementRepo.MarkNotified(ctx, announcement.ID, now)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		recipients, err := s.recipients(ctx, announcement)
+		if err != nil {
+			s.logger.Error("Failed to resolve announcement recipients",
+				zap.Uint("announcement_id", announcement.ID),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		for j := range recipients {
+			data := notification.NewTemplateData(&recipients[j], nil, nil)
+			data.Extra["title"] = announcement.Title
+			data.Extra["content"] = announcement.Content
+			data.Extra["level"] = announcement.Level
+			if err := s.dispatcher.Notify(ctx, recipients[j].ID, model.NotificationEventAnnouncement, data); err != nil {
+				s.logger.Warn("Failed to notify announcement",
+					zap.Uint("announcement_id", announcement.ID),
+					zap.Uint("user_id", recipients[j].ID),
+					zap.Error(err),
+				)
+			}
+		}
+
+		s.logger.Info("Announcement published",
+			zap.Uint("announcement_id", announcement.ID),
+			zap.Int("recipients", len(recipients)),
+		)
+	}
+
+	return nil
+}
+
+// Start runs the scheduled announcement publishing loop until ctx is cancelled
+func (s *AnnouncementService) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := s.PublishDue(ctx, now); err != nil {
+				s.logger.Error("Failed to publish scheduled announcements", zap.Error(err))
+			}
+		}
+	}
+}
+
+// recipients resolves the active users targeted by an announcement
+func (s *AnnouncementService) recipients(ctx context.Context, announcement *model.Announcement) ([]model.User, error) {
+	roles := announcement.GetTargetRoles()
+	if len(roles) == 0 {
+		return s.userRepo.GetActiveUsers(ctx)
+	}
+
+	var users []model.User
+	for _, role := range roles {
+		roleUsers, err := s.userRepo.GetUsersByRole(ctx, role)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, roleUsers...)
+	}
+	return users, nil
+}
+
+// toAnnouncementResponse converts Announcement to AnnouncementResponse
+func (s *AnnouncementService) toAnnouncementResponse(announcement *model.Announcement) *AnnouncementResponse {
+	return &AnnouncementResponse{
+		ID:          announcement.ID,
+		Title:       announcement.Title,
+		Content:     announcement.Content,
+		Level:       announcement.Level,
+		TargetRoles: announcement.GetTargetRoles(),
+		PublishAt:   announcement.PublishAt,
+		ExpiresAt:   announcement.ExpiresAt,
+		NotifiedAt:  announcement.NotifiedAt,
+		CreatedBy:   announcement.CreatedBy,
+		CreatedAt:   announcement.CreatedAt,
+	}
+}
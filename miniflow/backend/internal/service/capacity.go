@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/internal/repository"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// CapacityService samples per-version throughput and task concurrency for capacity planning
+type CapacityService struct {
+	capacityRepo *repository.CapacityRepository
+	logger       *logger.Logger
+}
+
+// NewCapacityService creates a new capacity service
+func NewCapacityService(capacityRepo *repository.CapacityRepository, logger *logger.Logger) *CapacityService {
+	return &CapacityService{
+		capacityRepo: capacityRepo,
+		logger:       logger,
+	}
+}
+
+// CapacitySeries represents the daily capacity time series of one definition version
+type CapacitySeries struct {
+	DefinitionID uint   `json:"definition_id"`
+	Key          string `json:"key"`
+	Name         string `json:"name"`
+	Version      int    `json:"version"`
+	// 区间内的合计和并发峰值
+	Started                 int                  `json:"started"`
+	Completed               int                  `json:"completed"`
+	PeakActiveTasks         int                  `json:"peak_active_tasks"`
+	PeakActiveExternalTasks int                  `json:"peak_active_external_tasks"`
+	Points                  []model.CapacityStat `json:"points"`
+}
+
+// Sample records today's counts and current concurrency, and settles yesterday's
+// counts so instances finishing after the last sample of the day are not lost
+func (s *CapacityService) Sample(ctx context.Context, now time.Time) error {
+	if err := s.capacityRepo.Record(ctx, now.AddDate(0, 0, -1), now, false); err != nil {
+		return err
+	}
+	return s.capacityRepo.Record(ctx, now, now, true)
+}
+
+// GetCapacity returns the daily series of the last days days per definition version,
+// limited to the versions of the process key when key is not empty
+func (s *CapacityService) GetCapacity(ctx context.Context, key string, days int) ([]CapacitySeries, error) {
+	if days <= 0 {
+		days = model.DefaultCapacityDays
+	}
+	if days > model.MaxCapacityDays {
+		return nil, fmt.Errorf("统计天数不能超过 %d 天", model.MaxCapacityDays)
+	}
+
+	since := time.Now().AddDate(0, 0, -(days - 1))
+	rows, err := s.capacityRepo.List(ctx, key, since)
+	if err != nil {
+		return nil, errors.New("获取容量统计失败")
+	}
+
+	series := make([]CapacitySeries, 0)
+	for _, row := range rows {
+		if len(series) == 0 || series[len(series)-1].DefinitionID != row.DefinitionID {
+			series = append(series, CapacitySeries{
+				DefinitionID: row.DefinitionID,
+				Key:          row.Key,
+				Name:         row.Name,
+				Version:      row.Version,
+			})
+		}
+		current := &series[len(series)-1]
+		current.Started += row.Started
+		current.Completed += row.Completed
+		if row.PeakActiveTasks > current.PeakActiveTasks {
+			current.PeakActiveTasks = row.PeakActiveTasks
+		}
+		if row.PeakActiveExternalTasks > current.PeakActiveExternalTasks {
+			current.PeakActiveExternalTasks = row.PeakActiveExternalTasks
+		}
+		current.Points = append(current.Points, row.CapacityStat)
+	}
+	return series, nil
+}
+
+// Start samples capacity immediately and then periodically until ctx is cancelled
+func (s *CapacityService) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+
+	if err := s.Sample(ctx, time.Now()); err != nil {
+		s.logger.Error("Failed to sample capacity", zap.Error(err))
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := s.Sample(ctx, now); err != nil {
+				s.logger.Error("Failed to sample capacity", zap.Error(err))
+			}
+		}
+	}
+}
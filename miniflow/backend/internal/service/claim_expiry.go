@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/internal/notification"
+	"miniflow/internal/repository"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// ClaimExpiryService releases claimed tasks that have been idle longer than their claim expiry
+type ClaimExpiryService struct {
+	taskRepo   *repository.TaskRepository
+	dispatcher *notification.Dispatcher
+	logger     *logger.Logger
+}
+
+// NewClaimExpiryService creates a new claim expiry service
+func NewClaimExpiryService(
+	taskRepo *repository.TaskRepository,
+	dispatcher *notification.Dispatcher,
+	logger *logger.Logger,
+) *ClaimExpiryService {
+	return &ClaimExpiryService{
+		taskRepo:   taskRepo,
+		dispatcher: dispatcher,
+		logger:     logger,
+	}
+}
+
+// ReleaseExpired releases every held task whose last activity is older than its claim expiry
+// and returns the number of released tasks
+func (s *ClaimExpiryService) ReleaseExpired(ctx context.Context, now time.Time) (int, error) {
+	tasks, err := s.taskRepo.GetHeldTasks(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for i := range tasks {
+		task := &tasks[i]
+		expiry := task.Instance.Definition.ClaimExpiry(task.NodeID)
+		if expiry <= 0 || now.Sub(task.UpdatedAt) < expiry {
+			continue
+		}
+
+		// 以最后活动时间作为条件更新，期间有新操作的任务不会被释放
+		ok, err := s.taskRepo.ExpireClaim(ctx, task.ID, task.UpdatedAt)
+		if err != nil {
+			return released, err
+		}
+		if !ok {
+			continue
+		}
+		released++
+
+		s.logger.Info("Task claim expired",
+			zap.String("event", model.NotificationEventTaskClaimExpired),
+			zap.Uint("task_id", task.ID),
+			zap.Uint("instance_id", task.InstanceID),
+			zap.Uint("definition_id", task.Instance.DefinitionID),
+			zap.String("node_id", task.NodeID),
+			zap.Duration("idle", now.Sub(task.UpdatedAt)),
+		)
+
+		if task.AssigneeID != nil {
+			data := notification.NewTemplateData(nil, &task.Instance, task)
+			if err := s.dispatcher.Notify(ctx, *task.AssigneeID, model.NotificationEventTaskClaimExpired, data); err != nil {
+				s.logger.Warn("Failed to notify claim expiry",
+					zap.Uint("task_id", task.ID),
+					zap.Uint("user_id", *task.AssigneeID),
+					zap.Error(err),
+				)
+			}
+		}
+	}
+
+	return released, nil
+}
+
+// Start runs the claim expiry check loop until ctx is cancelled
+func (s *ClaimExpiryService) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := s.ReleaseExpired(ctx, now); err != nil {
+				s.logger.Error("Failed to release expired task claims", zap.Error(err))
+			}
+		}
+	}
+}
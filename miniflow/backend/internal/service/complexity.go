@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// ComplexityBudgetRequest represents complexity budget update request
+type ComplexityBudgetRequest struct {
+	MaxScore int `json:"max_score" validate:"required,min=1"`
+}
+
+// ListComplexityBudgets returns every configured complexity budget
+func (s *ProcessService) ListComplexityBudgets(ctx context.Context) ([]model.ComplexityBudget, error) {
+	budgets, err := s.budgetRepo.List(ctx)
+	if err != nil {
+		return nil, errors.New("获取复杂度预算失败")
+	}
+	return budgets, nil
+}
+
+// UpdateComplexityBudget creates or replaces the complexity budget of a definition key,
+// or the default budget when key is "*"
+func (s *ProcessService) UpdateComplexityBudget(ctx context.Context, key string, req *ComplexityBudgetRequest, userID uint) (*model.ComplexityBudget, error) {
+	if key != model.ComplexityBudgetDefaultKey {
+		exists, err := s.processRepo.ExistsByKey(ctx, key)
+		if err != nil {
+			return nil, errors.New("获取流程定义失败")
+		}
+		if !exists {
+			return nil, errors.New("流程定义不存在")
+		}
+	}
+
+	budget, err := s.budgetRepo.GetByDefinitionKey(ctx, key)
+	if err != nil {
+		return nil, errors.New("获取复杂度预算失败")
+	}
+	if budget == nil {
+		budget = &model.ComplexityBudget{DefinitionKey: key}
+	}
+	budget.MaxScore = req.MaxScore
+	budget.UpdatedBy = userID
+
+	if err := s.budgetRepo.Save(ctx, budget); err != nil {
+		return nil, errors.New("保存复杂度预算失败")
+	}
+
+	s.logger.Info("Complexity budget updated",
+		zap.String("definition_key", key),
+		zap.Int("max_score", req.MaxScore),
+		zap.Uint("user_id", userID),
+	)
+	return budget, nil
+}
+
+// DeleteComplexityBudget removes the complexity budget of a definition key
+func (s *ProcessService) DeleteComplexityBudget(ctx context.Context, key string, userID uint) error {
+	if err := s.budgetRepo.DeleteByDefinitionKey(ctx, key); err != nil {
+		return errors.New("删除复杂度预算失败")
+	}
+	s.logger.Info("Complexity budget removed",
+		zap.String("definition_key", key),
+		zap.Uint("user_id", userID),
+	)
+	return nil
+}
+
+// checkComplexityBudget rejects definitions scoring above the effective complexity budget
+func (s *ProcessService) checkComplexityBudget(ctx context.Context, process *model.ProcessDefinition) error {
+	budget, err := s.budgetRepo.GetEffective(ctx, process.Key)
+	if err != nil {
+		return errors.New("获取复杂度预算失败")
+	}
+	if budget == nil {
+		return nil
+	}
+
+	complexity, err := process.GetComplexity()
+	if err != nil {
+		return errors.New("流程定义格式错误")
+	}
+	if complexity.Score > budget.MaxScore {
+		return fmt.Errorf("复杂度评分 %d 超过预算 %d", complexity.Score, budget.MaxScore)
+	}
+	return nil
+}
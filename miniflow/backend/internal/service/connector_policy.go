@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"miniflow/internal/model"
+	"miniflow/internal/repository"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// ConnectorPolicyService handles per-definition connector allowlists
+type ConnectorPolicyService struct {
+	policyRepo  *repository.ConnectorPolicyRepository
+	processRepo *repository.ProcessRepository
+	logger      *logger.Logger
+}
+
+// NewConnectorPolicyService creates a new connector policy service
+func NewConnectorPolicyService(
+	policyRepo *repository.ConnectorPolicyRepository,
+	processRepo *repository.ProcessRepository,
+	logger *logger.Logger,
+) *ConnectorPolicyService {
+	return &ConnectorPolicyService{
+		policyRepo:  policyRepo,
+		processRepo: processRepo,
+		logger:      logger,
+	}
+}
+
+// ConnectorPolicyRequest represents connector allowlist update request
+type ConnectorPolicyRequest struct {
+	AllowedTypes []string `json:"allowed_types" validate:"dive,min=1,max=50"`
+	AllowedHosts []string `json:"allowed_hosts" validate:"dive,min=1,max=255"`
+}
+
+// ConnectorPolicyResponse represents connector allowlist response data
+type ConnectorPolicyResponse struct {
+	DefinitionKey string   `json:"definition_key"`
+	Restricted    bool     `json:"restricted"`
+	AllowedTypes  []string `json:"allowed_types"`
+	AllowedHosts  []string `json:"allowed_hosts"`
+	UpdatedBy     uint     `json:"updated_by"`
+}
+
+// ListPolicies returns every configured connector allowlist
+func (s *ConnectorPolicyService) ListPolicies(ctx context.Context) ([]*ConnectorPolicyResponse, error) {
+	policies, err := s.policyRepo.List(ctx)
+	if err != nil {
+		return nil, errors.New("获取连接器白名单失败")
+	}
+
+	responses := make([]*ConnectorPolicyResponse, len(policies))
+	for i := range policies {
+		responses[i] = s.toPolicyResponse(policies[i].DefinitionKey, &policies[i])
+	}
+	return responses, nil
+}
+
+// GetPolicy returns the connector allowlist of a definition key; unrestricted if none is configured
+func (s *ConnectorPolicyService) GetPolicy(ctx context.Context, key string) (*ConnectorPolicyResponse, error) {
+	policy, err := s.policyRepo.GetByDefinitionKey(ctx, key)
+	if err != nil {
+		return nil, errors.New("获取连接器白名单失败")
+	}
+	return s.toPolicyResponse(key, policy), nil
+}
+
+// UpdatePolicy creates or replaces the connector allowlist of a definition key
+func (s *ConnectorPolicyService) UpdatePolicy(ctx context.Context, key string, req *ConnectorPolicyRequest, userID uint) (*ConnectorPolicyResponse, error) {
+	exists, err := s.processRepo.ExistsByKey(ctx, key)
+	if err != nil {
+		return nil, errors.New("获取流程定义失败")
+	}
+	if !exists {
+		return nil, errors.New("流程定义不存在")
+	}
+
+	policy, err := s.policyRepo.GetByDefinitionKey(ctx, key)
+	if err != nil {
+		return nil, errors.New("获取连接器白名单失败")
+	}
+	if policy == nil {
+		policy = &model.ConnectorPolicy{DefinitionKey: key}
+	}
+
+	policy.SetAllowedTypes(normalizeList(req.AllowedTypes))
+	policy.SetAllowedHosts(normalizeList(req.AllowedHosts))
+	policy.UpdatedBy = userID
+
+	if err := s.policyRepo.Save(ctx, policy); err != nil {
+		return nil, errors.New("保存连接器白名单失败")
+	}
+
+	s.logger.Info("Connector policy updated",
+		zap.String("definition_key", key),
+		zap.Uint("user_id", userID),
+	)
+
+	return s.toPolicyResponse(key, policy), nil
+}
+
+// DeletePolicy removes the connector allowlist, making the definition unrestricted
+func (s *ConnectorPolicyService) DeletePolicy(ctx context.Context, key string, userID uint) error {
+	if err := s.policyRepo.DeleteByDefinitionKey(ctx, key); err != nil {
+		return errors.New("删除连接器白名单失败")
+	}
+	s.logger.Info("Connector policy removed",
+		zap.String("definition_key", key),
+		zap.Uint("user_id", userID),
+	)
+	return nil
+}
+
+// toPolicyResponse converts ConnectorPolicy to ConnectorPolicyResponse
+func (s *ConnectorPolicyService) toPolicyResponse(key string, policy *model.ConnectorPolicy) *ConnectorPolicyResponse {
+	if policy == nil {
+		return &ConnectorPolicyResponse{
+			DefinitionKey: key,
+			AllowedTypes:  []string{},
+			AllowedHosts:  []string{},
+		}
+	}
+	return &ConnectorPolicyResponse{
+		DefinitionKey: policy.DefinitionKey,
+		Restricted:    true,
+		AllowedTypes:  policy.GetAllowedTypes(),
+		AllowedHosts:  policy.GetAllowedHosts(),
+		UpdatedBy:     policy.UpdatedBy,
+	}
+}
+
+// normalizeList lowercases, trims and de-duplicates list entries
+func normalizeList(values []string) []string {
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		result = append(result, v)
+	}
+	return result
+}
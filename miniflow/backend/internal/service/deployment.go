@@ -0,0 +1,315 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"miniflow/internal/model"
+	"miniflow/internal/repository"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// DeploymentService deploys definition versions to environments and promotes them dev → staging → prod
+type DeploymentService struct {
+	deploymentRepo *repository.DeploymentRepository
+	processRepo    *repository.ProcessRepository
+	policyRepo     *repository.ConnectorPolicyRepository
+	processService *ProcessService
+	logger         *logger.Logger
+}
+
+// NewDeploymentService creates a new deployment service
+func NewDeploymentService(
+	deploymentRepo *repository.DeploymentRepository,
+	processRepo *repository.ProcessRepository,
+	policyRepo *repository.ConnectorPolicyRepository,
+	processService *ProcessService,
+	logger *logger.Logger,
+) *DeploymentService {
+	return &DeploymentService{
+		deploymentRepo: deploymentRepo,
+		processRepo:    processRepo,
+		policyRepo:     policyRepo,
+		processService: processService,
+		logger:         logger,
+	}
+}
+
+// DeployRequest represents a request to deploy a definition version to the first environment
+type DeployRequest struct {
+	Note string `json:"note" validate:"max=500"`
+}
+
+// PromoteRequest represents a request to promote a deployment to the next environment
+type PromoteRequest struct {
+	TargetEnvironment string `json:"target_environment" validate:"required,oneof=staging prod"`
+	// 调用方确认的部署包校验和，必须与源部署一致
+	Checksum string `json:"checksum" validate:"required,len=64"`
+	Note     string `json:"note" validate:"max=500"`
+}
+
+// ImportPackageRequest represents a request to import a package exported by another installation
+type ImportPackageRequest struct {
+	Environment string          `json:"environment" validate:"required,oneof=dev staging prod"`
+	Checksum    string          `json:"checksum" validate:"required,len=64"`
+	Package     json.RawMessage `json:"package" validate:"required"`
+	Note        string          `json:"note" validate:"max=500"`
+}
+
+// DeploymentPackageResponse represents an exported deployment package
+type DeploymentPackageResponse struct {
+	Deployment *model.Deployment        `json:"deployment"`
+	Checksum   string                   `json:"checksum"`
+	Package    *model.DeploymentPackage `json:"package"`
+}
+
+// Deploy packages a published definition version with its dependencies and deploys it to dev.
+// Later environments are only reachable through promotion.
+func (s *DeploymentService) Deploy(ctx context.Context, definitionID, userID uint, req *DeployRequest) (*model.Deployment, error) {
+	definition, err := s.processRepo.GetByID(ctx, definitionID)
+	if err != nil {
+		return nil, err
+	}
+	if definition.CreatedBy != userID {
+		return nil, errors.New("只能部署自己创建的流程")
+	}
+	if definition.Status != model.ProcessStatusPublished {
+		return nil, errors.New("只能部署已发布的流程版本")
+	}
+
+	policy, err := s.policyRepo.GetByDefinitionKey(ctx, definition.Key)
+	if err != nil {
+		return nil, errors.New("获取连接器白名单失败")
+	}
+	pkg, err := model.NewDeploymentPackage(definition, policy)
+	if err != nil {
+		return nil, errors.New("流程定义格式错误")
+	}
+
+	return s.createDeployment(ctx, definition, pkg, model.EnvironmentDev, nil, userID, req.Note)
+}
+
+// Promote copies a deployment to the next environment after verifying the package checksum
+func (s *DeploymentService) Promote(ctx context.Context, deploymentID, userID uint, req *PromoteRequest) (*model.Deployment, error) {
+	source, err := s.deploymentRepo.GetByID(ctx, deploymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	next, ok := model.NextEnvironment(source.Environment)
+	if !ok || next != req.TargetEnvironment {
+		return nil, fmt.Errorf("%s 环境的部署只能晋升到下一个环境", source.Environment)
+	}
+
+	pkg, err := s.verifyDeployment(source)
+	if err != nil {
+		return nil, err
+	}
+	if req.Checksum != source.Checksum {
+		return nil, errors.New("部署包校验和不匹配，请确认晋升的版本")
+	}
+
+	definition, err := s.processRepo.GetByID(ctx, source.DefinitionID)
+	if err != nil {
+		return nil, err
+	}
+	if definition.Status != model.ProcessStatusPublished {
+		return nil, errors.New("流程版本已不是发布状态，不能晋升")
+	}
+
+	return s.createDeployment(ctx, definition, pkg, req.TargetEnvironment, &source.ID, userID, req.Note)
+}
+
+// ExportPackage returns the package of a deployment for transfer to another installation
+func (s *DeploymentService) ExportPackage(ctx context.Context, deploymentID uint) (*DeploymentPackageResponse, error) {
+	deployment, err := s.deploymentRepo.GetByID(ctx, deploymentID)
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := s.verifyDeployment(deployment)
+	if err != nil {
+		return nil, err
+	}
+	return &DeploymentPackageResponse{
+		Deployment: deployment,
+		Checksum:   deployment.Checksum,
+		Package:    pkg,
+	}, nil
+}
+
+// ImportPackage verifies a package exported by another installation and deploys it to the
+// given environment, creating a published definition version and its connector allowlist
+func (s *DeploymentService) ImportPackage(ctx context.Context, userID uint, req *ImportPackageRequest) (*model.Deployment, error) {
+	var pkg model.DeploymentPackage
+	if err := json.Unmarshal(req.Package, &pkg); err != nil {
+		return nil, errors.New("部署包格式错误")
+	}
+	if pkg.Format != model.DeploymentPackageFormat {
+		return nil, fmt.Errorf("不支持的部署包格式版本: %d", pkg.Format)
+	}
+	checksum, err := pkg.Checksum()
+	if err != nil {
+		return nil, errors.New("计算部署包校验和失败")
+	}
+	if checksum != req.Checksum {
+		return nil, errors.New("部署包校验和不匹配，部署包可能已被修改")
+	}
+
+	// 同一部署包已部署到该环境时直接返回
+	current, err := s.deploymentRepo.GetCurrent(ctx, pkg.Definition.Key, req.Environment)
+	if err != nil {
+		return nil, errors.New("获取部署记录失败")
+	}
+	if current != nil && current.Checksum == checksum {
+		return current, nil
+	}
+
+	var definitionData model.ProcessDefinitionData
+	if err := json.Unmarshal([]byte(pkg.Definition.DefinitionJSON), &definitionData); err != nil {
+		return nil, errors.New("部署包中的流程定义格式错误")
+	}
+	if err := s.processService.validateProcessDefinition(&definitionData); err != nil {
+		return nil, fmt.Errorf("流程定义验证失败: %v", err)
+	}
+
+	if err := s.importConnectorPolicy(ctx, pkg.Definition.Key, pkg.Dependencies.ConnectorPolicy, userID); err != nil {
+		return nil, err
+	}
+	if err := s.processService.checkConnectorPolicy(ctx, pkg.Definition.Key, &definitionData); err != nil {
+		return nil, fmt.Errorf("连接器白名单检查失败: %v", err)
+	}
+
+	definition, err := s.importDefinition(ctx, &pkg.Definition, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.createDeployment(ctx, definition, &pkg, req.Environment, nil, userID, req.Note)
+}
+
+// ListDeployments lists the deployments of the process of the given definition
+func (s *DeploymentService) ListDeployments(ctx context.Context, definitionID uint, environment string) ([]model.Deployment, error) {
+	if environment != "" && !model.IsValidEnvironment(environment) {
+		return nil, errors.New("无效的部署环境")
+	}
+	definition, err := s.processRepo.GetByID(ctx, definitionID)
+	if err != nil {
+		return nil, err
+	}
+	return s.deploymentRepo.ListByDefinitionKey(ctx, definition.Key, environment)
+}
+
+// createDeployment stores a deployment record with the package and its checksum
+func (s *DeploymentService) createDeployment(ctx context.Context, definition *model.ProcessDefinition, pkg *model.DeploymentPackage, environment string, sourceID *uint, userID uint, note string) (*model.Deployment, error) {
+	data, err := pkg.Marshal()
+	if err != nil {
+		return nil, errors.New("序列化部署包失败")
+	}
+	checksum, err := pkg.Checksum()
+	if err != nil {
+		return nil, errors.New("计算部署包校验和失败")
+	}
+
+	deployment := &model.Deployment{
+		DefinitionID:       definition.ID,
+		DefinitionKey:      definition.Key,
+		Version:            definition.Version,
+		Environment:        environment,
+		Checksum:           checksum,
+		Package:            data,
+		SourceDeploymentID: sourceID,
+		DeployedBy:         userID,
+		Note:               note,
+	}
+	if err := s.deploymentRepo.Create(ctx, deployment); err != nil {
+		return nil, errors.New("创建部署记录失败")
+	}
+
+	s.logger.Info("Process definition deployed",
+		zap.Uint("deployment_id", deployment.ID),
+		zap.String("definition_key", deployment.DefinitionKey),
+		zap.Int("version", deployment.Version),
+		zap.String("environment", environment),
+		zap.String("checksum", checksum),
+	)
+
+	return deployment, nil
+}
+
+// verifyDeployment parses a stored package and checks that it still matches its checksum
+func (s *DeploymentService) verifyDeployment(deployment *model.Deployment) (*model.DeploymentPackage, error) {
+	pkg, err := deployment.GetPackage()
+	if err != nil {
+		return nil, errors.New("部署包格式错误")
+	}
+	checksum, err := pkg.Checksum()
+	if err != nil {
+		return nil, errors.New("计算部署包校验和失败")
+	}
+	if checksum != deployment.Checksum {
+		s.logger.Error("Deployment package checksum mismatch",
+			zap.Uint("deployment_id", deployment.ID),
+			zap.String("expected", deployment.Checksum),
+			zap.String("actual", checksum),
+		)
+		return nil, errors.New("部署包校验失败，部署记录可能已被修改")
+	}
+	return pkg, nil
+}
+
+// importDefinition reuses the latest published version when it has the same content,
+// otherwise creates a new published version from the package
+func (s *DeploymentService) importDefinition(ctx context.Context, packaged *model.PackagedDefinition, userID uint) (*model.ProcessDefinition, error) {
+	if latest, err := s.processRepo.GetLatestPublishedByKey(ctx, packaged.Key); err == nil && latest.DefinitionJSON == packaged.DefinitionJSON {
+		return latest, nil
+	}
+
+	definition := &model.ProcessDefinition{
+		Key:                  packaged.Key,
+		Name:                 packaged.Name,
+		Description:          packaged.Description,
+		Category:             packaged.Category,
+		DefinitionJSON:       packaged.DefinitionJSON,
+		Status:               model.ProcessStatusPublished,
+		DataClassification:   packaged.DataClassification,
+		DisplayLabels:        packaged.DisplayLabels,
+		ClaimExpiryHours:     packaged.ClaimExpiryHours,
+		CompletionWebhookURL: packaged.CompletionWebhookURL,
+		CreatedBy:            userID,
+	}
+	if definition.DataClassification == "" {
+		definition.DataClassification = model.DataClassificationInternal
+	}
+	if err := s.processRepo.Create(ctx, definition); err != nil {
+		s.logger.Error("Failed to import process definition", zap.String("key", packaged.Key), zap.Error(err))
+		return nil, errors.New("导入流程定义失败")
+	}
+	return definition, nil
+}
+
+// importConnectorPolicy replaces the connector allowlist of the process with the packaged one
+func (s *DeploymentService) importConnectorPolicy(ctx context.Context, key string, packaged *model.PackagedConnectorPolicy, userID uint) error {
+	if packaged == nil {
+		return nil
+	}
+
+	policy, err := s.policyRepo.GetByDefinitionKey(ctx, key)
+	if err != nil {
+		return errors.New("获取连接器白名单失败")
+	}
+	if policy == nil {
+		policy = &model.ConnectorPolicy{DefinitionKey: key}
+	}
+	policy.SetAllowedTypes(packaged.AllowedTypes)
+	policy.SetAllowedHosts(packaged.AllowedHosts)
+	policy.UpdatedBy = userID
+
+	if err := s.policyRepo.Save(ctx, policy); err != nil {
+		return errors.New("保存连接器白名单失败")
+	}
+	return nil
+}
@@ -0,0 +1,331 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/internal/notification"
+	"miniflow/internal/repository"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// KPI 趋势方向常量
+const (
+	KPITrendUp     = "up"
+	KPITrendDown   = "down"
+	KPITrendFlat   = "flat"
+	KPITrendNoData = "no_data"
+)
+
+// KPIService manages process KPIs and evaluates them against instance history
+type KPIService struct {
+	kpiRepo     *repository.KPIRepository
+	processRepo *repository.ProcessRepository
+	dispatcher  *notification.Dispatcher
+	logger      *logger.Logger
+}
+
+// NewKPIService creates a new KPI service
+func NewKPIService(
+	kpiRepo *repository.KPIRepository,
+	processRepo *repository.ProcessRepository,
+	dispatcher *notification.Dispatcher,
+	logger *logger.Logger,
+) *KPIService {
+	return &KPIService{
+		kpiRepo:     kpiRepo,
+		processRepo: processRepo,
+		dispatcher:  dispatcher,
+		logger:      logger,
+	}
+}
+
+// KPIRequest represents KPI create/update request data
+type KPIRequest struct {
+	Name           string  `json:"name" validate:"required,min=1,max=255"`
+	Description    string  `json:"description" validate:"max=1000"`
+	Metric         string  `json:"metric" validate:"required"`
+	ThresholdHours float64 `json:"threshold_hours" validate:"min=0"`
+	TargetPercent  float64 `json:"target_percent" validate:"gt=0,max=100"`
+	WindowDays     int     `json:"window_days" validate:"min=0,max=365"`
+	Enabled        *bool   `json:"enabled"`
+}
+
+// KPIAttainmentResponse represents the current attainment and trend of a KPI
+type KPIAttainmentResponse struct {
+	KPI   *model.ProcessKPI             `json:"kpi"`
+	Trend []model.ProcessKPIMeasurement `json:"trend"`
+	// 趋势方向：与趋势区间内第一次评估相比
+	Direction string `json:"direction"`
+}
+
+// CreateKPI defines a new KPI for the process of the given definition
+func (s *KPIService) CreateKPI(ctx context.Context, definitionID, userID uint, req *KPIRequest) (*model.ProcessKPI, error) {
+	definition, err := s.getOwnedDefinition(ctx, definitionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	kpi := &model.ProcessKPI{
+		DefinitionKey: definition.Key,
+		Enabled:       true,
+		CreatedBy:     userID,
+	}
+	if err := applyKPIRequest(kpi, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.kpiRepo.Create(ctx, kpi); err != nil {
+		return nil, errors.New("创建KPI失败")
+	}
+
+	s.logger.Info("Process KPI created",
+		zap.Uint("kpi_id", kpi.ID),
+		zap.String("definition_key", kpi.DefinitionKey),
+		zap.String("metric", kpi.Metric),
+	)
+
+	return kpi, nil
+}
+
+// UpdateKPI updates a KPI; only the process owner may change it
+func (s *KPIService) UpdateKPI(ctx context.Context, kpiID, userID uint, req *KPIRequest) (*model.ProcessKPI, error) {
+	kpi, err := s.getOwnedKPI(ctx, kpiID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyKPIRequest(kpi, req); err != nil {
+		return nil, err
+	}
+	// 目标变化后重新判断是否未达标
+	kpi.Breached = false
+
+	if err := s.kpiRepo.Update(ctx, kpi); err != nil {
+		return nil, errors.New("更新KPI失败")
+	}
+	return kpi, nil
+}
+
+// DeleteKPI deletes a KPI and its measurement history
+func (s *KPIService) DeleteKPI(ctx context.Context, kpiID, userID uint) error {
+	if _, err := s.getOwnedKPI(ctx, kpiID, userID); err != nil {
+		return err
+	}
+	if err := s.kpiRepo.Delete(ctx, kpiID); err != nil {
+		return errors.New("删除KPI失败")
+	}
+	return nil
+}
+
+// ListKPIs lists the KPIs of the process of the given definition
+func (s *KPIService) ListKPIs(ctx context.Context, definitionID uint) ([]model.ProcessKPI, error) {
+	definition, err := s.processRepo.GetByID(ctx, definitionID)
+	if err != nil {
+		return nil, err
+	}
+	return s.kpiRepo.ListByDefinitionKey(ctx, definition.Key)
+}
+
+// GetAttainment returns the current attainment and the trend over the last trendDays of every KPI of the process
+func (s *KPIService) GetAttainment(ctx context.Context, definitionID uint, trendDays int) ([]KPIAttainmentResponse, error) {
+	kpis, err := s.ListKPIs(ctx, definitionID)
+	if err != nil {
+		return nil, err
+	}
+	if trendDays <= 0 {
+		trendDays = model.DefaultKPIWindowDays
+	}
+	since := time.Now().AddDate(0, 0, -trendDays)
+
+	result := make([]KPIAttainmentResponse, 0, len(kpis))
+	for i := range kpis {
+		measurements, err := s.kpiRepo.ListMeasurements(ctx, kpis[i].ID, since)
+		if err != nil {
+			return nil, errors.New("获取KPI趋势失败")
+		}
+		result = append(result, KPIAttainmentResponse{
+			KPI:       &kpis[i],
+			Trend:     measurements,
+			Direction: kpiTrendDirection(measurements),
+		})
+	}
+	return result, nil
+}
+
+// Evaluate measures a KPI over its window, records the measurement and notifies
+// the KPI owner when the KPI changes from attained to breached
+func (s *KPIService) Evaluate(ctx context.Context, kpi *model.ProcessKPI, now time.Time) (*model.ProcessKPIMeasurement, error) {
+	sample, err := s.kpiRepo.Sample(ctx, kpi, kpi.Window(now))
+	if err != nil {
+		return nil, err
+	}
+
+	// 没有样本时不判断是否达标，避免新流程误报
+	if sample.Total == 0 {
+		kpi.LastAttainment = nil
+		kpi.LastSampleSize = 0
+		kpi.LastEvaluatedAt = &now
+		return nil, s.kpiRepo.Update(ctx, kpi)
+	}
+
+	attainment := math.Round(float64(sample.Met)/float64(sample.Total)*1000) / 10
+	measurement := &model.ProcessKPIMeasurement{
+		KPIID:      kpi.ID,
+		MeasuredAt: now,
+		SampleSize: int(sample.Total),
+		Attainment: attainment,
+		Met:        attainment >= kpi.TargetPercent,
+	}
+	if err := s.kpiRepo.AddMeasurement(ctx, measurement); err != nil {
+		return nil, err
+	}
+
+	newlyBreached := !measurement.Met && !kpi.Breached
+	kpi.LastAttainment = &attainment
+	kpi.LastSampleSize = measurement.SampleSize
+	kpi.LastEvaluatedAt = &now
+	kpi.Breached = !measurement.Met
+	if err := s.kpiRepo.Update(ctx, kpi); err != nil {
+		return nil, err
+	}
+
+	if newlyBreached {
+		s.notifyBreach(ctx, kpi, measurement)
+	}
+	return measurement, nil
+}
+
+// EvaluateAll evaluates every enabled KPI and returns the number evaluated
+func (s *KPIService) EvaluateAll(ctx context.Context, now time.Time) (int, error) {
+	kpis, err := s.kpiRepo.ListEnabled(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	evaluated := 0
+	for i := range kpis {
+		if _, err := s.Evaluate(ctx, &kpis[i], now); err != nil {
+			s.logger.Error("Failed to evaluate process KPI", zap.Uint("kpi_id", kpis[i].ID), zap.Error(err))
+			continue
+		}
+		evaluated++
+	}
+	return evaluated, nil
+}
+
+// Start runs the periodic KPI evaluation loop until ctx is cancelled
+func (s *KPIService) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := s.EvaluateAll(ctx, now); err != nil {
+				s.logger.Error("Failed to evaluate process KPIs", zap.Error(err))
+			}
+		}
+	}
+}
+
+// notifyBreach notifies the KPI owner that the KPI is no longer attained
+func (s *KPIService) notifyBreach(ctx context.Context, kpi *model.ProcessKPI, measurement *model.ProcessKPIMeasurement) {
+	s.logger.Warn("Process KPI breached",
+		zap.String("event", model.NotificationEventKPIBreached),
+		zap.Uint("kpi_id", kpi.ID),
+		zap.String("definition_key", kpi.DefinitionKey),
+		zap.Float64("attainment", measurement.Attainment),
+		zap.Float64("target_percent", kpi.TargetPercent),
+	)
+
+	data := notification.NewTemplateData(nil, nil, nil)
+	data.Extra = map[string]interface{}{
+		"kpi_id":         kpi.ID,
+		"kpi_name":       kpi.Name,
+		"definition_key": kpi.DefinitionKey,
+		"metric":         kpi.Metric,
+		"attainment":     measurement.Attainment,
+		"target_percent": kpi.TargetPercent,
+		"sample_size":    measurement.SampleSize,
+		"window_days":    kpi.WindowDays,
+	}
+	if err := s.dispatcher.Notify(ctx, kpi.CreatedBy, model.NotificationEventKPIBreached, data); err != nil {
+		s.logger.Warn("Failed to notify KPI breach", zap.Uint("kpi_id", kpi.ID), zap.Error(err))
+	}
+}
+
+// getOwnedDefinition loads a definition and checks that the user owns it
+func (s *KPIService) getOwnedDefinition(ctx context.Context, definitionID, userID uint) (*model.ProcessDefinition, error) {
+	definition, err := s.processRepo.GetByID(ctx, definitionID)
+	if err != nil {
+		return nil, err
+	}
+	if definition.CreatedBy != userID {
+		return nil, errors.New("只能为自己创建的流程定义KPI")
+	}
+	return definition, nil
+}
+
+// getOwnedKPI loads a KPI and checks that the user owns it
+func (s *KPIService) getOwnedKPI(ctx context.Context, kpiID, userID uint) (*model.ProcessKPI, error) {
+	kpi, err := s.kpiRepo.GetByID(ctx, kpiID)
+	if err != nil {
+		return nil, err
+	}
+	if kpi.CreatedBy != userID {
+		return nil, errors.New("只能修改自己定义的KPI")
+	}
+	return kpi, nil
+}
+
+// applyKPIRequest validates the request and copies it onto the KPI
+func applyKPIRequest(kpi *model.ProcessKPI, req *KPIRequest) error {
+	if !model.IsValidKPIMetric(req.Metric) {
+		return errors.New("不支持的KPI指标")
+	}
+	if req.Metric == model.KPIMetricCycleTime && req.ThresholdHours <= 0 {
+		return errors.New("完成时长指标必须设置阈值")
+	}
+
+	kpi.Name = req.Name
+	kpi.Description = req.Description
+	kpi.Metric = req.Metric
+	kpi.ThresholdHours = req.ThresholdHours
+	kpi.TargetPercent = req.TargetPercent
+	kpi.WindowDays = req.WindowDays
+	if kpi.WindowDays == 0 {
+		kpi.WindowDays = model.DefaultKPIWindowDays
+	}
+	if req.Enabled != nil {
+		kpi.Enabled = *req.Enabled
+	}
+	return nil
+}
+
+// kpiTrendDirection compares the latest measurement with the first one in the trend window
+func kpiTrendDirection(measurements []model.ProcessKPIMeasurement) string {
+	if len(measurements) < 2 {
+		return KPITrendNoData
+	}
+	delta := measurements[len(measurements)-1].Attainment - measurements[0].Attainment
+	switch {
+	case delta > 0.5:
+		return KPITrendUp
+	case delta < -0.5:
+		return KPITrendDown
+	default:
+		return KPITrendFlat
+	}
+}
@@ -0,0 +1,393 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/internal/notification"
+	"miniflow/internal/repository"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// ErrNotificationNotFound is returned when an in-app notification does not exist or belongs to another user
+var ErrNotificationNotFound = errors.New("通知不存在")
+
+// NotificationService handles notification preference business logic
+type NotificationService struct {
+	notificationRepo *repository.NotificationRepository
+	taskRepo         *repository.TaskRepository
+	userRepo         *repository.UserRepository
+	dispatcher       *notification.Dispatcher
+	renderer         *notification.Renderer
+	logger           *logger.Logger
+}
+
+// NewNotificationService creates a new notification service
+func NewNotificationService(
+	notificationRepo *repository.NotificationRepository,
+	taskRepo *repository.TaskRepository,
+	userRepo *repository.UserRepository,
+	dispatcher *notification.Dispatcher,
+	renderer *notification.Renderer,
+	logger *logger.Logger,
+) *NotificationService {
+	return &NotificationService{
+		notificationRepo: notificationRepo,
+		taskRepo:         taskRepo,
+		userRepo:         userRepo,
+		dispatcher:       dispatcher,
+		renderer:         renderer,
+		logger:           logger,
+	}
+}
+
+// UpdateNotificationPreferenceRequest represents notification preference update request
+type UpdateNotificationPreferenceRequest struct {
+	Channels        []string `json:"channels" validate:"dive,oneof=email in_app chat"`
+	MutedEvents     []string `json:"muted_events"`
+	DeliveryMode    string   `json:"delivery_mode" validate:"omitempty,oneof=immediate digest"`
+	QuietHoursStart string   `json:"quiet_hours_start"`
+	QuietHoursEnd   string   `json:"quiet_hours_end"`
+	Timezone        string   `json:"timezone"`
+	Locale          string   `json:"locale" validate:"omitempty,max=20"`
+}
+
+// NotificationPreferenceResponse represents notification preference response data
+type NotificationPreferenceResponse struct {
+	Channels          []string `json:"channels"`
+	MutedEvents       []string `json:"muted_events"`
+	DeliveryMode      string   `json:"delivery_mode"`
+	QuietHoursStart   string   `json:"quiet_hours_start"`
+	QuietHoursEnd     string   `json:"quiet_hours_end"`
+	Timezone          string   `json:"timezone"`
+	Locale            string   `json:"locale"`
+	AvailableChannels []string `json:"available_channels"`
+	CriticalEvents    []string `json:"critical_events"`
+}
+
+// GetPreferences returns the user's notification preferences, falling back to defaults
+func (s *NotificationService) GetPreferences(ctx context.Context, userID uint) (*NotificationPreferenceResponse, error) {
+	pref, err := s.notificationRepo.GetPreference(ctx, userID)
+	if err != nil {
+		return nil, errors.New("获取通知偏好失败")
+	}
+	if pref == nil {
+		pref = model.DefaultNotificationPreference(userID)
+	}
+
+	return s.toPreferenceResponse(pref), nil
+}
+
+// UpdatePreferences updates the user's notification preferences
+func (s *NotificationService) UpdatePreferences(ctx context.Context, userID uint, req *UpdateNotificationPreferenceRequest) (*NotificationPreferenceResponse, error) {
+	s.logger.Info("Updating notification preferences", zap.Uint("user_id", userID))
+
+	if (req.QuietHoursStart == "") != (req.QuietHoursEnd == "") {
+		return nil, errors.New("免打扰时段需要同时设置开始和结束时间")
+	}
+	if req.QuietHoursStart != "" {
+		if _, err := notification.ParseClock(req.QuietHoursStart); err != nil {
+			return nil, err
+		}
+		if _, err := notification.ParseClock(req.QuietHoursEnd); err != nil {
+			return nil, err
+		}
+	}
+	if req.Timezone != "" {
+		if _, err := time.LoadLocation(req.Timezone); err != nil {
+			return nil, fmt.Errorf("无效的时区: %s", req.Timezone)
+		}
+	}
+
+	pref, err := s.notificationRepo.GetPreference(ctx, userID)
+	if err != nil {
+		return nil, errors.New("获取通知偏好失败")
+	}
+	if pref == nil {
+		pref = model.DefaultNotificationPreference(userID)
+	}
+
+	pref.SetChannels(req.Channels)
+	pref.SetMutedEvents(req.MutedEvents)
+	pref.QuietHoursStart = req.QuietHoursStart
+	pref.QuietHoursEnd = req.QuietHoursEnd
+	pref.Timezone = req.Timezone
+	pref.Locale = req.Locale
+	if req.DeliveryMode != "" {
+		pref.DeliveryMode = req.DeliveryMode
+	}
+
+	if err := s.notificationRepo.SavePreference(ctx, pref); err != nil {
+		return nil, errors.New("保存通知偏好失败")
+	}
+
+	s.logger.Info("Notification preferences updated", zap.Uint("user_id", userID))
+
+	return s.toPreferenceResponse(pref), nil
+}
+
+// toPreferenceResponse converts NotificationPreference to NotificationPreferenceResponse
+func (s *NotificationService) toPreferenceResponse(pref *model.NotificationPreference) *NotificationPreferenceResponse {
+	var critical []string
+	for _, event := range []string{
+		model.NotificationEventTaskCreated,
+		model.NotificationEventTaskAssigned,
+		model.NotificationEventTaskOverdue,
+		model.NotificationEventTaskReminder,
+		model.NotificationEventTaskClaimExpired,
+		model.NotificationEventProcessCompleted,
+		model.NotificationEventProcessFailed,
+		model.NotificationEventKPIBreached,
+	} {
+		if s.dispatcher.IsCritical(event) {
+			critical = append(critical, event)
+		}
+	}
+
+	return &NotificationPreferenceResponse{
+		Channels:          pref.GetChannels(),
+		MutedEvents:       pref.GetMutedEvents(),
+		DeliveryMode:      pref.DeliveryMode,
+		QuietHoursStart:   pref.QuietHoursStart,
+		QuietHoursEnd:     pref.QuietHoursEnd,
+		Timezone:          pref.Timezone,
+		Locale:            pref.Locale,
+		AvailableChannels: s.dispatcher.AvailableChannels(),
+		CriticalEvents:    critical,
+	}
+}
+
+// ListInbox returns a page of the user's in-app notifications, newest first, with the unread count
+func (s *NotificationService) ListInbox(ctx context.Context, userID uint, unreadOnly bool, page, pageSize int) ([]model.Notification, int64, int64, error) {
+	notifications, total, err := s.notificationRepo.ListNotifications(ctx, userID, unreadOnly, (page-1)*pageSize, pageSize)
+	if err != nil {
+		s.logger.Error("Failed to list notifications", zap.Uint("user_id", userID), zap.Error(err))
+		return nil, 0, 0, errors.New("获取通知列表失败")
+	}
+
+	unread, err := s.notificationRepo.CountUnreadNotifications(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to count unread notifications", zap.Uint("user_id", userID), zap.Error(err))
+		return nil, 0, 0, errors.New("获取未读通知数失败")
+	}
+
+	return notifications, total, unread, nil
+}
+
+// CountUnread returns the number of unread in-app notifications of the user
+func (s *NotificationService) CountUnread(ctx context.Context, userID uint) (int64, error) {
+	unread, err := s.notificationRepo.CountUnreadNotifications(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to count unread notifications", zap.Uint("user_id", userID), zap.Error(err))
+		return 0, errors.New("获取未读通知数失败")
+	}
+	return unread, nil
+}
+
+// MarkRead marks one of the user's in-app notifications as read
+func (s *NotificationService) MarkRead(ctx context.Context, userID, id uint) error {
+	found, err := s.notificationRepo.MarkNotificationRead(ctx, userID, id, time.Now())
+	if err != nil {
+		s.logger.Error("Failed to mark notification read",
+			zap.Uint("user_id", userID),
+			zap.Uint("notification_id", id),
+			zap.Error(err),
+		)
+		return errors.New("标记通知已读失败")
+	}
+	if !found {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+// MarkAllRead marks all unread in-app notifications of the user as read and returns how many were marked
+func (s *NotificationService) MarkAllRead(ctx context.Context, userID uint) (int64, error) {
+	marked, err := s.notificationRepo.MarkAllNotificationsRead(ctx, userID, time.Now())
+	if err != nil {
+		return 0, errors.New("标记通知已读失败")
+	}
+	return marked, nil
+}
+
+// NotificationTemplateRequest represents notification template create/update request
+type NotificationTemplateRequest struct {
+	EventType string `json:"event_type" validate:"required,max=50"`
+	Locale    string `json:"locale" validate:"omitempty,max=20"`
+	Subject   string `json:"subject" validate:"required,max=255"`
+	Body      string `json:"body" validate:"required"`
+}
+
+// PreviewNotificationTemplateRequest represents template preview request
+// Either TemplateID or Subject/Body must be provided; data comes from TaskID or SampleData
+type PreviewNotificationTemplateRequest struct {
+	TemplateID uint                       `json:"template_id"`
+	Subject    string                     `json:"subject"`
+	Body       string                     `json:"body"`
+	TaskID     uint                       `json:"task_id"`
+	SampleData *notification.TemplateData `json:"sample_data"`
+}
+
+// PreviewNotificationTemplateResponse represents rendered template preview
+type PreviewNotificationTemplateResponse struct {
+	Subject string                     `json:"subject"`
+	Body    string                     `json:"body"`
+	Data    *notification.TemplateData `json:"data"`
+}
+
+// ListTemplates returns notification templates, optionally filtered by event type
+func (s *NotificationService) ListTemplates(ctx context.Context, eventType string) ([]model.NotificationTemplate, error) {
+	templates, err := s.notificationRepo.ListTemplates(ctx, eventType)
+	if err != nil {
+		return nil, errors.New("获取通知模板失败")
+	}
+	return templates, nil
+}
+
+// CreateTemplate creates a notification template for an event type and locale
+func (s *NotificationService) CreateTemplate(ctx context.Context, req *NotificationTemplateRequest, userID uint) (*model.NotificationTemplate, error) {
+	tmpl := &model.NotificationTemplate{}
+	if err := s.applyTemplateRequest(tmpl, req, userID); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.notificationRepo.GetTemplate(ctx, tmpl.EventType, tmpl.Locale)
+	if err != nil {
+		return nil, errors.New("获取通知模板失败")
+	}
+	if existing != nil {
+		return nil, errors.New("该事件类型和语言的模板已存在")
+	}
+
+	if err := s.notificationRepo.SaveTemplate(ctx, tmpl); err != nil {
+		return nil, errors.New("创建通知模板失败")
+	}
+
+	s.logger.Info("Notification template created",
+		zap.Uint("template_id", tmpl.ID),
+		zap.String("event_type", tmpl.EventType),
+		zap.String("locale", tmpl.Locale),
+	)
+
+	return tmpl, nil
+}
+
+// UpdateTemplate updates an existing notification template
+func (s *NotificationService) UpdateTemplate(ctx context.Context, id uint, req *NotificationTemplateRequest, userID uint) (*model.NotificationTemplate, error) {
+	tmpl, err := s.notificationRepo.GetTemplateByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applyTemplateRequest(tmpl, req, userID); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.notificationRepo.GetTemplate(ctx, tmpl.EventType, tmpl.Locale)
+	if err != nil {
+		return nil, errors.New("获取通知模板失败")
+	}
+	if existing != nil && existing.ID != tmpl.ID {
+		return nil, errors.New("该事件类型和语言的模板已存在")
+	}
+
+	if err := s.notificationRepo.SaveTemplate(ctx, tmpl); err != nil {
+		return nil, errors.New("更新通知模板失败")
+	}
+
+	s.logger.Info("Notification template updated", zap.Uint("template_id", tmpl.ID))
+
+	return tmpl, nil
+}
+
+// DeleteTemplate deletes a notification template; the built-in default is used afterwards
+func (s *NotificationService) DeleteTemplate(ctx context.Context, id uint) error {
+	if _, err := s.notificationRepo.GetTemplateByID(ctx, id); err != nil {
+		return err
+	}
+	if err := s.notificationRepo.DeleteTemplate(ctx, id); err != nil {
+		return errors.New("删除通知模板失败")
+	}
+	s.logger.Info("Notification template deleted", zap.Uint("template_id", id))
+	return nil
+}
+
+// PreviewTemplate renders a stored or inline template against a real task or sample data
+func (s *NotificationService) PreviewTemplate(ctx context.Context, req *PreviewNotificationTemplateRequest, userID uint) (*PreviewNotificationTemplateResponse, error) {
+	subject, body := req.Subject, req.Body
+	if req.TemplateID != 0 {
+		tmpl, err := s.notificationRepo.GetTemplateByID(ctx, req.TemplateID)
+		if err != nil {
+			return nil, err
+		}
+		subject, body = tmpl.Subject, tmpl.Body
+	}
+	if subject == "" && body == "" {
+		return nil, errors.New("请提供模板ID或模板内容")
+	}
+
+	data, err := s.previewData(ctx, req, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	renderedSubject, renderedBody, err := notification.RenderTemplate(subject, body, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PreviewNotificationTemplateResponse{
+		Subject: renderedSubject,
+		Body:    renderedBody,
+		Data:    data,
+	}, nil
+}
+
+// previewData builds template data for preview from a task or the supplied sample data
+func (s *NotificationService) previewData(ctx context.Context, req *PreviewNotificationTemplateRequest, userID uint) (*notification.TemplateData, error) {
+	if req.TaskID != 0 {
+		task, err := s.taskRepo.GetByID(ctx, req.TaskID)
+		if err != nil {
+			return nil, errors.New("任务不存在")
+		}
+		recipient := task.Assignee
+		if recipient == nil {
+			recipient, _ = s.userRepo.GetByID(ctx, userID)
+		}
+		return notification.NewTemplateData(recipient, &task.Instance, task), nil
+	}
+
+	if req.SampleData != nil {
+		return req.SampleData, nil
+	}
+
+	user, _ := s.userRepo.GetByID(ctx, userID)
+	return notification.NewTemplateData(user, nil, nil), nil
+}
+
+// applyTemplateRequest validates template syntax and copies request fields onto the template
+func (s *NotificationService) applyTemplateRequest(tmpl *model.NotificationTemplate, req *NotificationTemplateRequest, userID uint) error {
+	if err := notification.ValidateTemplate(req.Subject); err != nil {
+		return fmt.Errorf("主题模板语法错误: %v", err)
+	}
+	if err := notification.ValidateTemplate(req.Body); err != nil {
+		return fmt.Errorf("正文模板语法错误: %v", err)
+	}
+
+	locale := req.Locale
+	if locale == "" {
+		locale = s.renderer.DefaultLocale()
+	}
+
+	tmpl.EventType = req.EventType
+	tmpl.Locale = locale
+	tmpl.Subject = req.Subject
+	tmpl.Body = req.Body
+	tmpl.UpdatedBy = userID
+	return nil
+}
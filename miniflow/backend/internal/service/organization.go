@@ -0,0 +1,412 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/internal/repository"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// OrganizationService manages departments, user groups and their members,
+// which user tasks reference through dept_manager:<code> and group:<code> assignees
+type OrganizationService struct {
+	orgRepo  *repository.OrganizationRepository
+	userRepo *repository.UserRepository
+	logger   *logger.Logger
+}
+
+// NewOrganizationService creates a new organization service
+func NewOrganizationService(
+	orgRepo *repository.OrganizationRepository,
+	userRepo *repository.UserRepository,
+	logger *logger.Logger,
+) *OrganizationService {
+	return &OrganizationService{
+		orgRepo:  orgRepo,
+		userRepo: userRepo,
+		logger:   logger,
+	}
+}
+
+// DepartmentRequest represents department create and update request
+type DepartmentRequest struct {
+	Code        string `json:"code" validate:"required,max=100"`
+	Name        string `json:"name" validate:"required,max=255"`
+	ParentID    *uint  `json:"parent_id"`
+	ManagerID   *uint  `json:"manager_id"`
+	Description string `json:"description"`
+}
+
+// GroupRequest represents user group create and update request
+type GroupRequest struct {
+	Code        string `json:"code" validate:"required,max=100"`
+	Name        string `json:"name" validate:"required,max=255"`
+	Description string `json:"description"`
+}
+
+// GroupMembersRequest represents adding users to a group
+type GroupMembersRequest struct {
+	UserIDs []uint `json:"user_ids" validate:"required,min=1"`
+}
+
+// UserDepartmentRequest represents moving a user into a department, or out of any when DepartmentID is nil
+type UserDepartmentRequest struct {
+	DepartmentID *uint `json:"department_id"`
+}
+
+// DepartmentResponse represents department response data
+type DepartmentResponse struct {
+	ID          uint      `json:"id"`
+	Code        string    `json:"code"`
+	Name        string    `json:"name"`
+	ParentID    *uint     `json:"parent_id"`
+	ManagerID   *uint     `json:"manager_id"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// GroupResponse represents user group response data
+type GroupResponse struct {
+	ID          uint      `json:"id"`
+	Code        string    `json:"code"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	MemberCount int64     `json:"member_count"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// OrgMemberResponse represents a department or group member
+type OrgMemberResponse struct {
+	ID          uint   `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	Role        string `json:"role"`
+	Status      string `json:"status"`
+}
+
+// ListDepartments returns every department ordered by code
+func (s *OrganizationService) ListDepartments(ctx context.Context) ([]*DepartmentResponse, error) {
+	departments, err := s.orgRepo.ListDepartments(ctx)
+	if err != nil {
+		s.logger.Error("Failed to list departments", zap.Error(err))
+		return nil, errors.New("获取部门列表失败")
+	}
+
+	responses := make([]*DepartmentResponse, len(departments))
+	for i := range departments {
+		responses[i] = toDepartmentResponse(&departments[i])
+	}
+	return responses, nil
+}
+
+// CreateDepartment creates a department
+func (s *OrganizationService) CreateDepartment(ctx context.Context, req *DepartmentRequest) (*DepartmentResponse, error) {
+	department := &model.Department{}
+	if err := s.applyDepartment(ctx, department, req); err != nil {
+		return nil, err
+	}
+	if err := s.orgRepo.CreateDepartment(ctx, department); err != nil {
+		return nil, errors.New("创建部门失败")
+	}
+
+	s.logger.Info("Department created", zap.Uint("department_id", department.ID), zap.String("code", department.Code))
+	return toDepartmentResponse(department), nil
+}
+
+// UpdateDepartment updates a department
+func (s *OrganizationService) UpdateDepartment(ctx context.Context, id uint, req *DepartmentRequest) (*DepartmentResponse, error) {
+	department, err := s.orgRepo.GetDepartmentByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyDepartment(ctx, department, req); err != nil {
+		return nil, err
+	}
+	if err := s.orgRepo.UpdateDepartment(ctx, department); err != nil {
+		return nil, errors.New("更新部门失败")
+	}
+
+	s.logger.Info("Department updated", zap.Uint("department_id", department.ID), zap.String("code", department.Code))
+	return toDepartmentResponse(department), nil
+}
+
+// DeleteDepartment deletes a department without sub-departments or users
+func (s *OrganizationService) DeleteDepartment(ctx context.Context, id uint) error {
+	if _, err := s.orgRepo.GetDepartmentByID(ctx, id); err != nil {
+		return err
+	}
+	children, err := s.orgRepo.CountChildDepartments(ctx, id)
+	if err != nil {
+		return errors.New("删除部门失败")
+	}
+	if children > 0 {
+		return errors.New("部门下还有下级部门，不能删除")
+	}
+	users, err := s.orgRepo.CountDepartmentUsers(ctx, id)
+	if err != nil {
+		return errors.New("删除部门失败")
+	}
+	if users > 0 {
+		return errors.New("部门下还有用户，不能删除")
+	}
+	if err := s.orgRepo.DeleteDepartment(ctx, id); err != nil {
+		return errors.New("删除部门失败")
+	}
+
+	s.logger.Info("Department deleted", zap.Uint("department_id", id))
+	return nil
+}
+
+// GetDepartmentMembers returns the users of a department
+func (s *OrganizationService) GetDepartmentMembers(ctx context.Context, id uint) ([]*OrgMemberResponse, error) {
+	if _, err := s.orgRepo.GetDepartmentByID(ctx, id); err != nil {
+		return nil, err
+	}
+	users, err := s.orgRepo.GetDepartmentUsers(ctx, id)
+	if err != nil {
+		return nil, errors.New("获取部门成员失败")
+	}
+	return toOrgMemberResponses(users), nil
+}
+
+// SetUserDepartment moves a user into a department, or out of any when DepartmentID is nil
+func (s *OrganizationService) SetUserDepartment(ctx context.Context, userID uint, req *UserDepartmentRequest) error {
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return err
+	}
+	if req.DepartmentID != nil {
+		if _, err := s.orgRepo.GetDepartmentByID(ctx, *req.DepartmentID); err != nil {
+			return err
+		}
+	}
+	if err := s.orgRepo.SetUserDepartment(ctx, userID, req.DepartmentID); err != nil {
+		return errors.New("设置用户部门失败")
+	}
+
+	s.logger.Info("User department changed", zap.Uint("user_id", userID), zap.Uintp("department_id", req.DepartmentID))
+	return nil
+}
+
+// applyDepartment validates req and copies it onto department
+func (s *OrganizationService) applyDepartment(ctx context.Context, department *model.Department, req *DepartmentRequest) error {
+	exists, err := s.orgRepo.ExistsDepartmentCode(ctx, req.Code, department.ID)
+	if err != nil {
+		return errors.New("检查部门编码失败")
+	}
+	if exists {
+		return fmt.Errorf("部门编码 %s 已存在", req.Code)
+	}
+
+	if req.ManagerID != nil {
+		manager, err := s.userRepo.GetByID(ctx, *req.ManagerID)
+		if err != nil {
+			return fmt.Errorf("部门负责人 %d 不存在", *req.ManagerID)
+		}
+		if manager.Status != "active" {
+			return fmt.Errorf("部门负责人 %s 未激活", manager.Username)
+		}
+	}
+
+	if req.ParentID != nil {
+		// 沿上级链检查，避免部门成为自己的下级
+		for parentID := req.ParentID; parentID != nil; {
+			if department.ID != 0 && *parentID == department.ID {
+				return errors.New("上级部门不能是部门自身或其下级部门")
+			}
+			parent, err := s.orgRepo.GetDepartmentByID(ctx, *parentID)
+			if err != nil {
+				return fmt.Errorf("上级部门 %d 不存在", *parentID)
+			}
+			parentID = parent.ParentID
+		}
+	}
+
+	department.Code = req.Code
+	department.Name = req.Name
+	department.ParentID = req.ParentID
+	department.ManagerID = req.ManagerID
+	department.Description = req.Description
+	return nil
+}
+
+// ListGroups returns every user group with its member count
+func (s *OrganizationService) ListGroups(ctx context.Context) ([]*GroupResponse, error) {
+	groups, err := s.orgRepo.ListGroups(ctx)
+	if err != nil {
+		s.logger.Error("Failed to list groups", zap.Error(err))
+		return nil, errors.New("获取用户组列表失败")
+	}
+	counts, err := s.orgRepo.CountGroupMembers(ctx)
+	if err != nil {
+		s.logger.Error("Failed to count group members", zap.Error(err))
+		return nil, errors.New("获取用户组列表失败")
+	}
+
+	responses := make([]*GroupResponse, len(groups))
+	for i := range groups {
+		responses[i] = toGroupResponse(&groups[i], counts[groups[i].ID])
+	}
+	return responses, nil
+}
+
+// CreateGroup creates a user group
+func (s *OrganizationService) CreateGroup(ctx context.Context, req *GroupRequest) (*GroupResponse, error) {
+	group := &model.Group{}
+	if err := s.applyGroup(ctx, group, req); err != nil {
+		return nil, err
+	}
+	if err := s.orgRepo.CreateGroup(ctx, group); err != nil {
+		return nil, errors.New("创建用户组失败")
+	}
+
+	s.logger.Info("Group created", zap.Uint("group_id", group.ID), zap.String("code", group.Code))
+	return toGroupResponse(group, 0), nil
+}
+
+// UpdateGroup updates a user group
+func (s *OrganizationService) UpdateGroup(ctx context.Context, id uint, req *GroupRequest) (*GroupResponse, error) {
+	group, err := s.orgRepo.GetGroupByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyGroup(ctx, group, req); err != nil {
+		return nil, err
+	}
+	if err := s.orgRepo.UpdateGroup(ctx, group); err != nil {
+		return nil, errors.New("更新用户组失败")
+	}
+	members, err := s.orgRepo.GetGroupMembers(ctx, id)
+	if err != nil {
+		return nil, errors.New("获取用户组成员失败")
+	}
+
+	s.logger.Info("Group updated", zap.Uint("group_id", group.ID), zap.String("code", group.Code))
+	return toGroupResponse(group, int64(len(members))), nil
+}
+
+// DeleteGroup deletes a user group and its memberships
+func (s *OrganizationService) DeleteGroup(ctx context.Context, id uint) error {
+	if _, err := s.orgRepo.GetGroupByID(ctx, id); err != nil {
+		return err
+	}
+	if err := s.orgRepo.DeleteGroup(ctx, id); err != nil {
+		return errors.New("删除用户组失败")
+	}
+
+	s.logger.Info("Group deleted", zap.Uint("group_id", id))
+	return nil
+}
+
+// GetGroupMembers returns the members of a user group
+func (s *OrganizationService) GetGroupMembers(ctx context.Context, id uint) ([]*OrgMemberResponse, error) {
+	if _, err := s.orgRepo.GetGroupByID(ctx, id); err != nil {
+		return nil, err
+	}
+	users, err := s.orgRepo.GetGroupMembers(ctx, id)
+	if err != nil {
+		return nil, errors.New("获取用户组成员失败")
+	}
+	return toOrgMemberResponses(users), nil
+}
+
+// AddGroupMembers adds users to a user group and returns its members
+func (s *OrganizationService) AddGroupMembers(ctx context.Context, id uint, req *GroupMembersRequest) ([]*OrgMemberResponse, error) {
+	if _, err := s.orgRepo.GetGroupByID(ctx, id); err != nil {
+		return nil, err
+	}
+	users, err := s.userRepo.FindByIDs(ctx, req.UserIDs)
+	if err != nil {
+		return nil, errors.New("获取用户失败")
+	}
+	found := make(map[uint]bool, len(users))
+	for _, user := range users {
+		if user.DeletedAt.Valid {
+			continue
+		}
+		found[user.ID] = true
+	}
+	for _, userID := range req.UserIDs {
+		if !found[userID] {
+			return nil, fmt.Errorf("用户 %d 不存在", userID)
+		}
+	}
+
+	if err := s.orgRepo.AddGroupMembers(ctx, id, req.UserIDs); err != nil {
+		return nil, errors.New("添加用户组成员失败")
+	}
+
+	s.logger.Info("Group members added", zap.Uint("group_id", id), zap.Int("users", len(req.UserIDs)))
+	return s.GetGroupMembers(ctx, id)
+}
+
+// RemoveGroupMember removes a user from a user group
+func (s *OrganizationService) RemoveGroupMember(ctx context.Context, id, userID uint) error {
+	if _, err := s.orgRepo.GetGroupByID(ctx, id); err != nil {
+		return err
+	}
+	if err := s.orgRepo.RemoveGroupMember(ctx, id, userID); err != nil {
+		return errors.New("移除用户组成员失败")
+	}
+
+	s.logger.Info("Group member removed", zap.Uint("group_id", id), zap.Uint("user_id", userID))
+	return nil
+}
+
+// applyGroup validates req and copies it onto group
+func (s *OrganizationService) applyGroup(ctx context.Context, group *model.Group, req *GroupRequest) error {
+	exists, err := s.orgRepo.ExistsGroupCode(ctx, req.Code, group.ID)
+	if err != nil {
+		return errors.New("检查用户组编码失败")
+	}
+	if exists {
+		return fmt.Errorf("用户组编码 %s 已存在", req.Code)
+	}
+
+	group.Code = req.Code
+	group.Name = req.Name
+	group.Description = req.Description
+	return nil
+}
+
+func toDepartmentResponse(department *model.Department) *DepartmentResponse {
+	return &DepartmentResponse{
+		ID:          department.ID,
+		Code:        department.Code,
+		Name:        department.Name,
+		ParentID:    department.ParentID,
+		ManagerID:   department.ManagerID,
+		Description: department.Description,
+		CreatedAt:   department.CreatedAt,
+	}
+}
+
+func toGroupResponse(group *model.Group, memberCount int64) *GroupResponse {
+	return &GroupResponse{
+		ID:          group.ID,
+		Code:        group.Code,
+		Name:        group.Name,
+		Description: group.Description,
+		MemberCount: memberCount,
+		CreatedAt:   group.CreatedAt,
+	}
+}
+
+func toOrgMemberResponses(users []model.User) []*OrgMemberResponse {
+	responses := make([]*OrgMemberResponse, len(users))
+	for i := range users {
+		responses[i] = &OrgMemberResponse{
+			ID:          users[i].ID,
+			Username:    users[i].Username,
+			DisplayName: users[i].DisplayName,
+			Role:        users[i].Role,
+			Status:      users[i].Status,
+		}
+	}
+	return responses
+}
@@ -1,13 +1,19 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	"miniflow/internal/model"
+	"miniflow/internal/notification"
 	"miniflow/internal/repository"
+	"miniflow/pkg/expression"
 	"miniflow/pkg/logger"
+	"miniflow/pkg/pagination"
 
 	"go.uber.org/zap"
 )
@@ -16,6 +22,8 @@ import (
 type ProcessService struct {
 	processRepo *repository.ProcessRepository
 	userRepo    *repository.UserRepository
+	policyRepo  *repository.ConnectorPolicyRepository
+	budgetRepo  *repository.ComplexityBudgetRepository
 	logger      *logger.Logger
 }
 
@@ -23,11 +31,15 @@ type ProcessService struct {
 func NewProcessService(
 	processRepo *repository.ProcessRepository,
 	userRepo *repository.UserRepository,
+	policyRepo *repository.ConnectorPolicyRepository,
+	budgetRepo *repository.ComplexityBudgetRepository,
 	logger *logger.Logger,
 ) *ProcessService {
 	return &ProcessService{
 		processRepo: processRepo,
 		userRepo:    userRepo,
+		policyRepo:  policyRepo,
+		budgetRepo:  budgetRepo,
 		logger:      logger,
 	}
 }
@@ -39,6 +51,8 @@ type CreateProcessRequest struct {
 	Description string                      `json:"description"`
 	Category    string                      `json:"category"`
 	Definition  model.ProcessDefinitionData `json:"definition"`
+
+	DataClassification string `json:"data_classification" validate:"omitempty,oneof=public internal confidential"`
 }
 
 // UpdateProcessRequest represents process update request
@@ -51,30 +65,92 @@ type UpdateProcessRequest struct {
 
 // ProcessResponse represents process response data
 type ProcessResponse struct {
-	ID          uint                        `json:"id"`
-	Key         string                      `json:"key"`
-	Name        string                      `json:"name"`
-	Version     int                         `json:"version"`
-	Description string                      `json:"description"`
-	Category    string                      `json:"category"`
-	Status      string                      `json:"status"`
-	Definition  model.ProcessDefinitionData `json:"definition"`
-	CreatedBy   uint                        `json:"created_by"`
-	CreatorName string                      `json:"creator_name"`
-	CreatedAt   time.Time                   `json:"created_at"`
-	UpdatedAt   time.Time                   `json:"updated_at"`
+	ID            uint                        `json:"id"`
+	Key           string                      `json:"key"`
+	Name          string                      `json:"name"`
+	Version       int                         `json:"version"`
+	Description   string                      `json:"description"`
+	Category      string                      `json:"category"`
+	Status        string                      `json:"status"`
+	Definition    model.ProcessDefinitionData `json:"definition"`
+	DisplayLabels *model.DisplayLabels        `json:"display_labels"`
+	DataPolicy    model.DataPolicy            `json:"data_policy"`
+	Complexity    *model.DefinitionComplexity `json:"complexity"`
+	CreatedBy     uint                        `json:"created_by"`
+	CreatorName   string                      `json:"creator_name"`
+	CreatedAt     time.Time                   `json:"created_at"`
+	UpdatedAt     time.Time                   `json:"updated_at"`
+}
+
+// ProcessMetadataRequest represents process metadata update request
+type ProcessMetadataRequest struct {
+	DisplayLabels     model.DisplayLabels        `json:"display_labels"`
+	CompletionWebhook *CompletionWebhookSettings `json:"completion_webhook"`
+
+	// DataClassification is only changed when non-empty
+	DataClassification string `json:"data_classification"`
+
+	// ClaimExpiryHours is only changed when provided; 0 disables claim expiry
+	ClaimExpiryHours *int `json:"claim_expiry_hours"`
+
+	// DuplicateKeyVariables is only changed when provided; an empty list disables
+	// variable-based duplicate detection (business keys are still compared)
+	DuplicateKeyVariables *[]string `json:"duplicate_key_variables"`
+
+	// Rollout is only changed when provided
+	Rollout *RolloutSettings `json:"rollout"`
+}
+
+// RolloutSettings represents the gradual rollout of a version. While enabled and
+// this is the latest published version, new instances matching the condition or
+// whose business key falls within the percentage start on it; the rest start on
+// the previous published version. Disabling the rollout promotes the version to
+// all new instances; 0 percent without a condition rolls back to the previous one.
+type RolloutSettings struct {
+	Enabled    bool   `json:"enabled"`
+	Percentage int    `json:"percentage"`
+	Condition  string `json:"condition"`
+}
+
+// CompletionWebhookSettings represents the per-definition completion callback.
+// An empty URL disables the callback; an empty secret keeps the existing one.
+type CompletionWebhookSettings struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret"`
+}
+
+// CompletionWebhookResponse represents the completion callback settings without the secret
+type CompletionWebhookResponse struct {
+	URL       string `json:"url"`
+	HasSecret bool   `json:"has_secret"`
+}
+
+// ProcessMetadataResponse represents process metadata response data
+type ProcessMetadataResponse struct {
+	ID            uint                 `json:"id"`
+	Key           string               `json:"key"`
+	Version       int                  `json:"version"`
+	DisplayLabels *model.DisplayLabels `json:"display_labels"`
+
+	CompletionWebhook CompletionWebhookResponse `json:"completion_webhook"`
+	DataPolicy        model.DataPolicy          `json:"data_policy"`
+	ClaimExpiryHours  int                       `json:"claim_expiry_hours"`
+
+	DuplicateKeyVariables []string        `json:"duplicate_key_variables"`
+	Rollout               RolloutSettings `json:"rollout"`
 }
 
 // ProcessListResponse represents process list response
 type ProcessListResponse struct {
-	Processes []*ProcessResponse `json:"processes"`
-	Total     int64              `json:"total"`
-	Page      int                `json:"page"`
-	PageSize  int                `json:"page_size"`
+	Processes  []*ProcessResponse `json:"processes"`
+	Total      int64              `json:"total"`
+	Page       int                `json:"page"`
+	PageSize   int                `json:"page_size"`
+	TotalPages int64              `json:"total_pages"`
 }
 
 // CreateProcess creates a new process definition
-func (s *ProcessService) CreateProcess(userID uint, req *CreateProcessRequest) (*ProcessResponse, error) {
+func (s *ProcessService) CreateProcess(ctx context.Context, userID uint, req *CreateProcessRequest) (*ProcessResponse, error) {
 	s.logger.Info("Creating process definition",
 		zap.String("key", req.Key),
 		zap.String("name", req.Name),
@@ -84,11 +160,11 @@ func (s *ProcessService) CreateProcess(userID uint, req *CreateProcessRequest) (
 	// Validate process definition
 	if err := s.validateProcessDefinition(&req.Definition); err != nil {
 		s.logger.Warn("Process definition validation failed", zap.Error(err))
-		return nil, fmt.Errorf("流程定义验证失败: %v", err)
+		return nil, fmt.Errorf("流程定义验证失败: %w", err)
 	}
 
 	// Check if key already exists
-	exists, err := s.processRepo.ExistsByKey(req.Key)
+	exists, err := s.processRepo.ExistsByKey(ctx, req.Key)
 	if err != nil {
 		s.logger.Error("Failed to check process key existence", zap.Error(err))
 		return nil, fmt.Errorf("检查流程标识失败: %v", err)
@@ -106,6 +182,11 @@ func (s *ProcessService) CreateProcess(userID uint, req *CreateProcessRequest) (
 		Status:      model.ProcessStatusDraft,
 		CreatedBy:   userID,
 		Version:     1,
+
+		DataClassification: model.DataClassificationInternal,
+	}
+	if req.DataClassification != "" {
+		process.DataClassification = req.DataClassification
 	}
 
 	// Set definition data
@@ -114,7 +195,7 @@ func (s *ProcessService) CreateProcess(userID uint, req *CreateProcessRequest) (
 		return nil, errors.New("流程定义格式错误")
 	}
 
-	if err := s.processRepo.Create(process); err != nil {
+	if err := s.processRepo.Create(ctx, process); err != nil {
 		s.logger.Error("Failed to create process definition", zap.Error(err))
 		return nil, fmt.Errorf("创建流程定义失败: %v", err)
 	}
@@ -128,8 +209,8 @@ func (s *ProcessService) CreateProcess(userID uint, req *CreateProcessRequest) (
 }
 
 // GetProcess retrieves a process definition by ID
-func (s *ProcessService) GetProcess(processID uint) (*ProcessResponse, error) {
-	process, err := s.processRepo.GetByID(processID)
+func (s *ProcessService) GetProcess(ctx context.Context, processID uint) (*ProcessResponse, error) {
+	process, err := s.processRepo.GetByID(ctx, processID)
 	if err != nil {
 		s.logger.Error("Failed to get process definition",
 			zap.Uint("process_id", processID),
@@ -142,14 +223,14 @@ func (s *ProcessService) GetProcess(processID uint) (*ProcessResponse, error) {
 }
 
 // UpdateProcess updates a process definition
-func (s *ProcessService) UpdateProcess(processID uint, userID uint, req *UpdateProcessRequest) (*ProcessResponse, error) {
+func (s *ProcessService) UpdateProcess(ctx context.Context, processID uint, userID uint, req *UpdateProcessRequest) (*ProcessResponse, error) {
 	s.logger.Info("Updating process definition",
 		zap.Uint("process_id", processID),
 		zap.Uint("user_id", userID),
 	)
 
 	// Get existing process
-	process, err := s.processRepo.GetByID(processID)
+	process, err := s.processRepo.GetByID(ctx, processID)
 	if err != nil {
 		return nil, err
 	}
@@ -172,7 +253,7 @@ func (s *ProcessService) UpdateProcess(processID uint, userID uint, req *UpdateP
 	// Validate process definition
 	if err := s.validateProcessDefinition(&req.Definition); err != nil {
 		s.logger.Warn("Process definition validation failed", zap.Error(err))
-		return nil, fmt.Errorf("流程定义验证失败: %v", err)
+		return nil, fmt.Errorf("流程定义验证失败: %w", err)
 	}
 
 	// Update fields
@@ -186,7 +267,7 @@ func (s *ProcessService) UpdateProcess(processID uint, userID uint, req *UpdateP
 		return nil, errors.New("流程定义格式错误")
 	}
 
-	if err := s.processRepo.Update(process); err != nil {
+	if err := s.processRepo.Update(ctx, process); err != nil {
 		s.logger.Error("Failed to update process definition", zap.Error(err))
 		return nil, errors.New("更新流程定义失败")
 	}
@@ -197,14 +278,14 @@ func (s *ProcessService) UpdateProcess(processID uint, userID uint, req *UpdateP
 }
 
 // DeleteProcess deletes a process definition
-func (s *ProcessService) DeleteProcess(processID uint, userID uint) error {
+func (s *ProcessService) DeleteProcess(ctx context.Context, processID uint, userID uint) error {
 	s.logger.Info("Deleting process definition",
 		zap.Uint("process_id", processID),
 		zap.Uint("user_id", userID),
 	)
 
 	// Get existing process
-	process, err := s.processRepo.GetByID(processID)
+	process, err := s.processRepo.GetByID(ctx, processID)
 	if err != nil {
 		return err
 	}
@@ -224,7 +305,7 @@ func (s *ProcessService) DeleteProcess(processID uint, userID uint) error {
 		return errors.New("已发布的流程不能删除，请先归档")
 	}
 
-	if err := s.processRepo.Delete(processID); err != nil {
+	if err := s.processRepo.Delete(ctx, processID); err != nil {
 		s.logger.Error("Failed to delete process definition", zap.Error(err))
 		return errors.New("删除流程定义失败")
 	}
@@ -234,7 +315,7 @@ func (s *ProcessService) DeleteProcess(processID uint, userID uint) error {
 }
 
 // GetUserProcesses retrieves processes created by a user with pagination
-func (s *ProcessService) GetUserProcesses(userID uint, page, pageSize int) (*ProcessListResponse, error) {
+func (s *ProcessService) GetUserProcesses(ctx context.Context, userID uint, page, pageSize int) (*ProcessListResponse, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -247,7 +328,7 @@ func (s *ProcessService) GetUserProcesses(userID uint, page, pageSize int) (*Pro
 		"created_by": userID,
 	}
 
-	processes, total, err := s.processRepo.List(offset, pageSize, filters)
+	processes, total, err := s.processRepo.List(ctx, offset, pageSize, filters)
 	if err != nil {
 		s.logger.Error("Failed to get user processes", zap.Error(err))
 		return nil, err
@@ -259,15 +340,16 @@ func (s *ProcessService) GetUserProcesses(userID uint, page, pageSize int) (*Pro
 	}
 
 	return &ProcessListResponse{
-		Processes: processResponses,
-		Total:     total,
-		Page:      page,
-		PageSize:  pageSize,
+		Processes:  processResponses,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: pagination.TotalPages(total, pageSize),
 	}, nil
 }
 
 // GetProcesses retrieves all processes with pagination and filters
-func (s *ProcessService) GetProcesses(page, pageSize int, filters map[string]interface{}) (*ProcessListResponse, error) {
+func (s *ProcessService) GetProcesses(ctx context.Context, page, pageSize int, filters map[string]interface{}) (*ProcessListResponse, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -276,7 +358,7 @@ func (s *ProcessService) GetProcesses(page, pageSize int, filters map[string]int
 	}
 
 	offset := (page - 1) * pageSize
-	processes, total, err := s.processRepo.List(offset, pageSize, filters)
+	processes, total, err := s.processRepo.List(ctx, offset, pageSize, filters)
 	if err != nil {
 		s.logger.Error("Failed to get processes", zap.Error(err))
 		return nil, err
@@ -288,22 +370,23 @@ func (s *ProcessService) GetProcesses(page, pageSize int, filters map[string]int
 	}
 
 	return &ProcessListResponse{
-		Processes: processResponses,
-		Total:     total,
-		Page:      page,
-		PageSize:  pageSize,
+		Processes:  processResponses,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: pagination.TotalPages(total, pageSize),
 	}, nil
 }
 
 // CopyProcess creates a copy of an existing process
-func (s *ProcessService) CopyProcess(processID uint, userID uint) (*ProcessResponse, error) {
+func (s *ProcessService) CopyProcess(ctx context.Context, processID uint, userID uint) (*ProcessResponse, error) {
 	s.logger.Info("Copying process definition",
 		zap.Uint("process_id", processID),
 		zap.Uint("user_id", userID),
 	)
 
 	// Get original process
-	originalProcess, err := s.processRepo.GetByID(processID)
+	originalProcess, err := s.processRepo.GetByID(ctx, processID)
 	if err != nil {
 		return nil, err
 	}
@@ -322,20 +405,22 @@ func (s *ProcessService) CopyProcess(processID uint, userID uint) (*ProcessRespo
 		Description: originalProcess.Description,
 		Category:    originalProcess.Category,
 		Definition:  *definitionData,
+
+		DataClassification: originalProcess.DataClassification,
 	}
 
-	return s.CreateProcess(userID, copyReq)
+	return s.CreateProcess(ctx, userID, copyReq)
 }
 
 // PublishProcess publishes a process definition
-func (s *ProcessService) PublishProcess(processID uint, userID uint) error {
+func (s *ProcessService) PublishProcess(ctx context.Context, processID uint, userID uint) error {
 	s.logger.Info("Publishing process definition",
 		zap.Uint("process_id", processID),
 		zap.Uint("user_id", userID),
 	)
 
 	// Get process
-	process, err := s.processRepo.GetByID(processID)
+	process, err := s.processRepo.GetByID(ctx, processID)
 	if err != nil {
 		return err
 	}
@@ -357,11 +442,29 @@ func (s *ProcessService) PublishProcess(processID uint, userID uint) error {
 	}
 
 	if err := s.validateProcessDefinition(definitionData); err != nil {
-		return fmt.Errorf("流程定义验证失败: %v", err)
+		return fmt.Errorf("流程定义验证失败: %w", err)
+	}
+
+	if err := s.checkConnectorPolicy(ctx, process.Key, definitionData); err != nil {
+		return fmt.Errorf("连接器白名单检查失败: %v", err)
+	}
+
+	if err := s.checkComplexityBudget(ctx, process); err != nil {
+		return fmt.Errorf("复杂度预算检查失败: %v", err)
+	}
+
+	// Check referenced users can still take work
+	issues, err := s.checkUserReferences(ctx, process)
+	if err != nil {
+		s.logger.Error("Failed to check user references", zap.Error(err))
+		return errors.New("检查流程引用的用户失败")
+	}
+	if len(issues) > 0 {
+		return fmt.Errorf("流程定义引用了不可用的用户: %s", formatReferenceIssues(issues))
 	}
 
 	// Update status
-	if err := s.processRepo.UpdateStatus(processID, model.ProcessStatusPublished); err != nil {
+	if err := s.processRepo.UpdateStatus(ctx, processID, model.ProcessStatusPublished); err != nil {
 		s.logger.Error("Failed to publish process", zap.Error(err))
 		return errors.New("发布流程失败")
 	}
@@ -370,6 +473,416 @@ func (s *ProcessService) PublishProcess(processID uint, userID uint) error {
 	return nil
 }
 
+// CreateNewVersion creates a draft of the next version from the latest version of the process key.
+// Published versions cannot be edited, so changes to a published process go through a new version.
+func (s *ProcessService) CreateNewVersion(ctx context.Context, processID uint, userID uint) (*ProcessResponse, error) {
+	s.logger.Info("Creating new process version",
+		zap.Uint("process_id", processID),
+		zap.Uint("user_id", userID),
+	)
+
+	process, err := s.processRepo.GetByID(ctx, processID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check ownership
+	if process.CreatedBy != userID {
+		return nil, errors.New("只能为自己创建的流程创建新版本")
+	}
+
+	latest, err := s.processRepo.GetLatestVersion(ctx, process.Key)
+	if err != nil {
+		return nil, err
+	}
+	if latest.Status == model.ProcessStatusDraft {
+		return nil, fmt.Errorf("已存在草稿版本 v%d，请直接编辑该版本", latest.Version)
+	}
+
+	// Rollout settings are not copied; the new version starts a rollout only when configured after publishing
+	draft := &model.ProcessDefinition{
+		Key:                     latest.Key,
+		Name:                    latest.Name,
+		Description:             latest.Description,
+		Category:                latest.Category,
+		DefinitionJSON:          latest.DefinitionJSON,
+		Status:                  model.ProcessStatusDraft,
+		DataClassification:      latest.DataClassification,
+		DisplayLabels:           latest.DisplayLabels,
+		CreatedBy:               userID,
+		CompletionWebhookURL:    latest.CompletionWebhookURL,
+		CompletionWebhookSecret: latest.CompletionWebhookSecret,
+		ClaimExpiryHours:        latest.ClaimExpiryHours,
+		DuplicateKeyVariables:   latest.DuplicateKeyVariables,
+		ComplexityScore:         latest.ComplexityScore,
+		ComplexityReport:        latest.ComplexityReport,
+	}
+
+	// Version is assigned by the repository as the current maximum plus one
+	if err := s.processRepo.Create(ctx, draft); err != nil {
+		s.logger.Error("Failed to create process version", zap.String("key", latest.Key), zap.Error(err))
+		return nil, errors.New("创建新版本失败")
+	}
+
+	s.logger.Info("Process version created successfully",
+		zap.Uint("process_id", draft.ID),
+		zap.String("key", draft.Key),
+		zap.Int("version", draft.Version),
+		zap.Int("source_version", latest.Version),
+	)
+
+	return s.toProcessResponse(draft), nil
+}
+
+// DeprecateProcess stops a published version from starting new instances.
+// Running instances continue on the version; returns their number.
+func (s *ProcessService) DeprecateProcess(ctx context.Context, processID uint, userID uint) (int64, error) {
+	s.logger.Info("Deprecating process definition",
+		zap.Uint("process_id", processID),
+		zap.Uint("user_id", userID),
+	)
+
+	process, err := s.processRepo.GetByID(ctx, processID)
+	if err != nil {
+		return 0, err
+	}
+
+	// Check ownership
+	if process.CreatedBy != userID {
+		return 0, errors.New("只能弃用自己创建的流程")
+	}
+
+	if process.Status != model.ProcessStatusPublished {
+		return 0, errors.New("只能弃用已发布的流程版本")
+	}
+
+	if err := s.processRepo.TransitionStatus(ctx, processID, []string{model.ProcessStatusPublished}, model.ProcessStatusDeprecated); err != nil {
+		if errors.Is(err, repository.ErrProcessStatusChanged) {
+			return 0, err
+		}
+		s.logger.Error("Failed to deprecate process", zap.Error(err))
+		return 0, errors.New("弃用流程失败")
+	}
+
+	active, err := s.processRepo.CountActiveInstances(ctx, processID)
+	if err != nil {
+		s.logger.Warn("Failed to count active instances", zap.Uint("process_id", processID), zap.Error(err))
+	}
+
+	s.logger.Info("Process deprecated successfully",
+		zap.Uint("process_id", processID),
+		zap.Int64("active_instances", active),
+	)
+	return active, nil
+}
+
+// ArchiveProcess archives a published or deprecated version once none of its instances are running or suspended
+func (s *ProcessService) ArchiveProcess(ctx context.Context, processID uint, userID uint) error {
+	s.logger.Info("Archiving process definition",
+		zap.Uint("process_id", processID),
+		zap.Uint("user_id", userID),
+	)
+
+	process, err := s.processRepo.GetByID(ctx, processID)
+	if err != nil {
+		return err
+	}
+
+	// Check ownership
+	if process.CreatedBy != userID {
+		return errors.New("只能归档自己创建的流程")
+	}
+
+	if process.Status != model.ProcessStatusPublished && process.Status != model.ProcessStatusDeprecated {
+		return errors.New("只能归档已发布或已弃用的流程版本")
+	}
+
+	if err := s.processRepo.ArchiveVersion(ctx, processID); err != nil {
+		if errors.Is(err, repository.ErrProcessHasActiveInstances) {
+			active, countErr := s.processRepo.CountActiveInstances(ctx, processID)
+			if countErr != nil {
+				return err
+			}
+			return fmt.Errorf("流程版本仍有 %d 个运行中或挂起的实例，请等待实例结束或迁移后再归档", active)
+		}
+		if errors.Is(err, repository.ErrProcessStatusChanged) {
+			return err
+		}
+		s.logger.Error("Failed to archive process", zap.Error(err))
+		return errors.New("归档流程失败")
+	}
+
+	s.logger.Info("Process archived successfully", zap.Uint("process_id", processID))
+	return nil
+}
+
+// GetProcessMetadata retrieves the metadata of a process definition
+func (s *ProcessService) GetProcessMetadata(ctx context.Context, processID uint) (*ProcessMetadataResponse, error) {
+	process, err := s.processRepo.GetByID(ctx, processID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.toProcessMetadataResponse(process)
+}
+
+// UpdateProcessMetadata updates the metadata of a process definition.
+// Metadata does not change execution semantics, so it may be changed on published versions as well.
+func (s *ProcessService) UpdateProcessMetadata(ctx context.Context, processID uint, userID uint, req *ProcessMetadataRequest) (*ProcessMetadataResponse, error) {
+	s.logger.Info("Updating process metadata",
+		zap.Uint("process_id", processID),
+		zap.Uint("user_id", userID),
+	)
+
+	process, err := s.processRepo.GetByID(ctx, processID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check ownership
+	if process.CreatedBy != userID {
+		return nil, errors.New("只能修改自己创建的流程")
+	}
+
+	definitionData, err := process.GetDefinitionData()
+	if err != nil {
+		return nil, errors.New("流程定义格式错误")
+	}
+
+	if err := s.validateDisplayLabels(&req.DisplayLabels, definitionData); err != nil {
+		return nil, fmt.Errorf("展示标签验证失败: %v", err)
+	}
+
+	if err := process.SetDisplayLabels(&req.DisplayLabels); err != nil {
+		return nil, errors.New("展示标签格式错误")
+	}
+
+	if req.DataClassification != "" {
+		if !model.IsValidDataClassification(req.DataClassification) {
+			return nil, fmt.Errorf("未知的数据分级 '%s'", req.DataClassification)
+		}
+		process.DataClassification = req.DataClassification
+	}
+
+	if req.ClaimExpiryHours != nil {
+		if *req.ClaimExpiryHours < 0 || *req.ClaimExpiryHours > 8760 {
+			return nil, errors.New("认领超时时长必须在0到8760小时之间")
+		}
+		process.ClaimExpiryHours = *req.ClaimExpiryHours
+	}
+
+	if req.DuplicateKeyVariables != nil {
+		if len(*req.DuplicateKeyVariables) > 10 {
+			return nil, errors.New("重复检测关键变量最多10个")
+		}
+		for _, name := range *req.DuplicateKeyVariables {
+			if strings.TrimSpace(name) == "" {
+				return nil, errors.New("重复检测关键变量名不能为空")
+			}
+		}
+		process.SetDuplicateKeyVariables(*req.DuplicateKeyVariables)
+	}
+
+	if req.Rollout != nil {
+		if err := s.applyRollout(process, req.Rollout); err != nil {
+			return nil, err
+		}
+	}
+
+	// Completion webhook is only changed when provided
+	if req.CompletionWebhook != nil {
+		if err := s.applyCompletionWebhook(process, req.CompletionWebhook); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.processRepo.Update(ctx, process); err != nil {
+		s.logger.Error("Failed to update process metadata", zap.Error(err))
+		return nil, errors.New("更新流程元数据失败")
+	}
+
+	s.logger.Info("Process metadata updated successfully", zap.Uint("process_id", processID))
+
+	return s.toProcessMetadataResponse(process)
+}
+
+// applyRollout validates and applies gradual rollout settings
+func (s *ProcessService) applyRollout(process *model.ProcessDefinition, settings *RolloutSettings) error {
+	if settings.Percentage < 0 || settings.Percentage > 100 {
+		return errors.New("灰度比例必须在0到100之间")
+	}
+	condition := strings.TrimSpace(settings.Condition)
+	if len(condition) > 500 {
+		return errors.New("灰度条件不能超过500个字符")
+	}
+	if condition != "" && !strings.Contains(condition, "${") {
+		return errors.New("灰度条件必须引用流程变量，例如 ${region} == east")
+	}
+
+	process.RolloutEnabled = settings.Enabled
+	process.RolloutPercentage = settings.Percentage
+	process.RolloutCondition = condition
+	return nil
+}
+
+// applyCompletionWebhook validates and applies completion callback settings
+func (s *ProcessService) applyCompletionWebhook(process *model.ProcessDefinition, settings *CompletionWebhookSettings) error {
+	if settings.URL == "" {
+		process.CompletionWebhookURL = ""
+		process.CompletionWebhookSecret = ""
+		return nil
+	}
+
+	if len(settings.URL) > 500 || len(settings.Secret) > 255 {
+		return errors.New("回调地址或密钥过长")
+	}
+
+	parsed, err := url.Parse(settings.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.New("回调地址必须是有效的HTTP(S)地址")
+	}
+
+	process.CompletionWebhookURL = settings.URL
+	if settings.Secret != "" {
+		process.CompletionWebhookSecret = settings.Secret
+	}
+	return nil
+}
+
+// validateDisplayLabels validates that labels only reference known statuses and nodes
+func (s *ProcessService) validateDisplayLabels(labels *model.DisplayLabels, definition *model.ProcessDefinitionData) error {
+	knownStatuses := map[string]bool{
+		model.InstanceStatusRunning:   true,
+		model.InstanceStatusSuspended: true,
+		model.InstanceStatusCompleted: true,
+		model.InstanceStatusFailed:    true,
+		model.InstanceStatusCancelled: true,
+		model.TaskStatusCreated:       true,
+		model.TaskStatusAssigned:      true,
+		model.TaskStatusClaimed:       true,
+		model.TaskStatusInProgress:    true,
+		model.TaskStatusSkipped:       true,
+		model.TaskStatusEscalated:     true,
+	}
+	for status, label := range labels.Statuses {
+		if !knownStatuses[status] {
+			return fmt.Errorf("未知的状态 '%s'", status)
+		}
+		if len(label) > 50 {
+			return fmt.Errorf("状态 '%s' 的标签过长", status)
+		}
+	}
+
+	nodeIDs := make(map[string]bool)
+	for _, node := range definition.Nodes {
+		nodeIDs[node.ID] = true
+	}
+	for nodeID, label := range labels.Nodes {
+		if !nodeIDs[nodeID] {
+			return fmt.Errorf("节点 '%s' 不存在", nodeID)
+		}
+		if len(label) > 100 {
+			return fmt.Errorf("节点 '%s' 的标签过长", nodeID)
+		}
+	}
+
+	return nil
+}
+
+// toProcessMetadataResponse converts ProcessDefinition to ProcessMetadataResponse
+func (s *ProcessService) toProcessMetadataResponse(process *model.ProcessDefinition) (*ProcessMetadataResponse, error) {
+	labels, err := process.GetDisplayLabels()
+	if err != nil {
+		s.logger.Error("Failed to parse display labels", zap.Uint("process_id", process.ID), zap.Error(err))
+		return nil, errors.New("展示标签格式错误")
+	}
+
+	return &ProcessMetadataResponse{
+		ID:            process.ID,
+		Key:           process.Key,
+		Version:       process.Version,
+		DisplayLabels: labels,
+		CompletionWebhook: CompletionWebhookResponse{
+			URL:       process.CompletionWebhookURL,
+			HasSecret: process.CompletionWebhookSecret != "",
+		},
+		DataPolicy:       process.DataPolicy(),
+		ClaimExpiryHours: process.ClaimExpiryHours,
+
+		DuplicateKeyVariables: process.GetDuplicateKeyVariables(),
+		Rollout: RolloutSettings{
+			Enabled:    process.RolloutEnabled,
+			Percentage: process.RolloutPercentage,
+			Condition:  process.RolloutCondition,
+		},
+	}, nil
+}
+
+// checkConnectorPolicy checks every service task against the definition's connector allowlist
+func (s *ProcessService) checkConnectorPolicy(ctx context.Context, key string, definition *model.ProcessDefinitionData) error {
+	policy, err := s.policyRepo.GetByDefinitionKey(ctx, key)
+	if err != nil {
+		return errors.New("获取连接器白名单失败")
+	}
+
+	for i := range definition.Nodes {
+		if definition.Nodes[i].Type != model.NodeTypeServiceTask {
+			continue
+		}
+		if err := policy.CheckNode(&definition.Nodes[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateDefinitionRequest represents a definition validation request
+type ValidateDefinitionRequest struct {
+	Definition model.ProcessDefinitionData `json:"definition"`
+}
+
+// DefinitionValidationResponse reports whether a definition can be saved. Issues locate
+// graph problems; Error also covers node configuration problems, which have no location.
+// Complexity carries the lint findings, which do not block saving.
+type DefinitionValidationResponse struct {
+	Valid      bool                        `json:"valid"`
+	Error      string                      `json:"error,omitempty"`
+	Issues     []model.GraphIssue          `json:"issues"`
+	Complexity *model.DefinitionComplexity `json:"complexity"`
+}
+
+// ValidateDefinition validates a definition without saving it
+func (s *ProcessService) ValidateDefinition(definition *model.ProcessDefinitionData) *DefinitionValidationResponse {
+	result := &DefinitionValidationResponse{
+		Valid:      true,
+		Issues:     []model.GraphIssue{},
+		Complexity: definition.Complexity(),
+	}
+	if err := s.validateProcessDefinition(definition); err != nil {
+		result.Valid = false
+		result.Error = err.Error()
+		var validationErr *DefinitionValidationError
+		if errors.As(err, &validationErr) {
+			result.Issues = validationErr.Issues
+		}
+	}
+	return result
+}
+
+// DefinitionValidationError reports the structural problems of a process definition
+// graph, with the nodes and flows to highlight in the designer
+type DefinitionValidationError struct {
+	Issues []model.GraphIssue
+}
+
+func (e *DefinitionValidationError) Error() string {
+	messages := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		messages[i] = issue.Message
+	}
+	return strings.Join(messages, "；")
+}
+
 // validateProcessDefinition validates a process definition
 func (s *ProcessService) validateProcessDefinition(definition *model.ProcessDefinitionData) error {
 	if len(definition.Nodes) == 0 {
@@ -398,52 +911,305 @@ func (s *ProcessService) validateProcessDefinition(definition *model.ProcessDefi
 		return errors.New("流程必须包含至少一个结束节点")
 	}
 
-	// Check node connections
+	// Check graph structure
+	if issues := definition.ValidateGraph(); len(issues) > 0 {
+		return &DefinitionValidationError{Issues: issues}
+	}
+
 	nodeMap := make(map[string]*model.ProcessNode)
 	for i := range definition.Nodes {
 		nodeMap[definition.Nodes[i].ID] = &definition.Nodes[i]
 	}
 
 	for _, node := range definition.Nodes {
-		if node.Type != model.NodeTypeEnd {
-			// Check outgoing flows
-			hasOutgoing := false
-			for _, flow := range definition.Flows {
-				if flow.From == node.ID {
-					hasOutgoing = true
-					break
-				}
+		if node.Type == model.NodeTypeUserTask {
+			if _, err := model.GetAutoRules(&node); err != nil {
+				return fmt.Errorf("节点 '%s' 的自动处理规则无效: %v", node.Name, err)
+			}
+			if err := validateAssigneeExpression(model.GetAssigneeExpression(&node)); err != nil {
+				return fmt.Errorf("节点 '%s' 的处理人表达式无效: %v", node.Name, err)
+			}
+			if err := validateBoundaryTimer(&node, definition.Flows); err != nil {
+				return fmt.Errorf("节点 '%s' 的边界定时器无效: %v", node.Name, err)
+			}
+			if err := validateVisitLimit(&node, definition.Flows); err != nil {
+				return fmt.Errorf("节点 '%s' 的访问上限无效: %v", node.Name, err)
 			}
-			if !hasOutgoing {
-				return fmt.Errorf("节点 '%s' 缺少出口连线", node.Name)
+			if _, err := model.GetMultiInstanceConfig(&node); err != nil {
+				return fmt.Errorf("节点 '%s' 的会签配置无效: %v", node.Name, err)
+			}
+			if _, err := model.GetCandidateConfig(&node); err != nil {
+				return fmt.Errorf("节点 '%s' 的候选人配置无效: %v", node.Name, err)
 			}
 		}
-
-		if node.Type != model.NodeTypeStart {
-			// Check incoming flows
-			hasIncoming := false
-			for _, flow := range definition.Flows {
-				if flow.To == node.ID {
-					hasIncoming = true
-					break
-				}
+		if node.Type == model.NodeTypeGateway {
+			if err := model.GatewayTypes.Validate(model.GetGatewayType(&node)); err != nil {
+				return fmt.Errorf("网关 '%s' 配置无效: %v", node.Name, err)
+			}
+		}
+		if node.Type == model.NodeTypeTimer {
+			if _, err := model.GetTimerSpec(&node); err != nil {
+				return fmt.Errorf("定时器节点 '%s' 配置无效: %v", node.Name, err)
+			}
+		}
+		if node.Type == model.NodeTypeMessageCatch {
+			if err := validateMessageCatch(&node); err != nil {
+				return fmt.Errorf("消息捕获节点 '%s' 配置无效: %v", node.Name, err)
 			}
-			if !hasIncoming {
-				return fmt.Errorf("节点 '%s' 缺少入口连线", node.Name)
+		}
+		if node.Type == model.NodeTypeCallActivity {
+			if _, err := model.GetCallActivityConfig(&node); err != nil {
+				return fmt.Errorf("调用活动 '%s' 配置无效: %v", node.Name, err)
 			}
 		}
+		if node.Type == model.NodeTypeScriptTask {
+			if err := validateScriptTask(&node); err != nil {
+				return fmt.Errorf("脚本任务 '%s' 配置无效: %v", node.Name, err)
+			}
+		}
+		if node.Type == model.NodeTypeMailTask {
+			if err := validateMailTask(&node); err != nil {
+				return fmt.Errorf("邮件任务 '%s' 配置无效: %v", node.Name, err)
+			}
+		}
+		if model.IsExternalTask(&node) {
+			if _, err := model.GetExternalTaskConfig(&node); err != nil {
+				return fmt.Errorf("外部任务 '%s' 配置无效: %v", node.Name, err)
+			}
+		}
+		if raw, ok := node.Props["async"]; ok && node.Type == model.NodeTypeServiceTask {
+			if _, isBool := raw.(bool); !isBool {
+				return fmt.Errorf("服务任务 '%s' 的 async 属性必须是布尔值", node.Name)
+			}
+		}
+		if raw, ok := node.Props["terminate"]; ok {
+			if _, isBool := raw.(bool); !isBool || node.Type != model.NodeTypeEnd {
+				return fmt.Errorf("节点 '%s' 的 terminate 属性只能是结束节点上的布尔值", node.Name)
+			}
+		}
+		if raw, ok := node.Props["estimatedHours"]; ok {
+			if hours, isNumber := raw.(float64); !isNumber || hours < 0 {
+				return fmt.Errorf("节点 '%s' 的预计时长必须是非负数", node.Name)
+			}
+		}
+		if err := validateLoop(&node); err != nil {
+			return fmt.Errorf("节点 '%s' 的循环配置无效: %v", node.Name, err)
+		}
+		if err := validateNodeInstructions(&node); err != nil {
+			return fmt.Errorf("节点 '%s' 的办理说明无效: %v", node.Name, err)
+		}
+		if node.Type == model.NodeTypeParallelReview {
+			if _, err := model.GetParallelReviewConfig(&node); err != nil {
+				return fmt.Errorf("节点 '%s' 的并行评审配置无效: %v", node.Name, err)
+			}
+		}
+
+		if model.IsCompensationHandler(&node) {
+			if err := validateCompensationHandler(&node, definition.Flows); err != nil {
+				return fmt.Errorf("补偿节点 '%s' 配置无效: %v", node.Name, err)
+			}
+			continue
+		}
+		if err := validateCompensation(&node, nodeMap); err != nil {
+			return fmt.Errorf("节点 '%s' 的补偿配置无效: %v", node.Name, err)
+		}
 	}
 
-	// Validate flows
+	// Validate flow conditions
 	for _, flow := range definition.Flows {
-		if _, exists := nodeMap[flow.From]; !exists {
-			return fmt.Errorf("连线的源节点 '%s' 不存在", flow.From)
+		if strings.TrimSpace(flow.Condition) != "" {
+			if _, err := expression.ParseCondition(flow.Condition); err != nil {
+				return fmt.Errorf("连线 '%s' 的条件表达式无效: %v", flow.ID, err)
+			}
 		}
-		if _, exists := nodeMap[flow.To]; !exists {
-			return fmt.Errorf("连线的目标节点 '%s' 不存在", flow.To)
+	}
+
+	return nil
+}
+
+// validateBoundaryTimer checks the boundary timer of a user task. The timeout flow
+// must leave the task, and another outgoing flow is needed for normal completion.
+func validateBoundaryTimer(node *model.ProcessNode, flows []model.ProcessFlow) error {
+	boundary, err := model.GetBoundaryTimer(node)
+	if err != nil || boundary == nil {
+		return err
+	}
+
+	found, others := false, 0
+	for _, flow := range flows {
+		if flow.From != node.ID {
+			continue
 		}
+		if flow.ID == boundary.Flow {
+			found = true
+		} else {
+			others++
+		}
+	}
+	if !found {
+		return fmt.Errorf("超时连线 '%s' 不是该节点的出口连线", boundary.Flow)
 	}
+	if others == 0 {
+		return errors.New("除超时连线外至少需要一条出口连线")
+	}
+	return nil
+}
 
+// validateCompensation checks the compensation declared by a service task, which
+// must name a compensation handler
+func validateCompensation(node *model.ProcessNode, nodeMap map[string]*model.ProcessNode) error {
+	handlerID, err := model.GetCompensation(node)
+	if err != nil || handlerID == "" {
+		return err
+	}
+	if node.Type != model.NodeTypeServiceTask {
+		return errors.New("只有服务任务可以声明补偿节点")
+	}
+	handler, ok := nodeMap[handlerID]
+	if !ok {
+		return fmt.Errorf("补偿节点 '%s' 不存在", handlerID)
+	}
+	if !model.IsCompensationHandler(handler) {
+		return fmt.Errorf("节点 '%s' 不是补偿节点", handlerID)
+	}
+	return nil
+}
+
+// validateCompensationHandler checks a compensation handler. Handlers are
+// synchronous service tasks that only run when compensating, so no flow may
+// enter or leave them.
+func validateCompensationHandler(node *model.ProcessNode, flows []model.ProcessFlow) error {
+	if node.Type != model.NodeTypeServiceTask || model.IsExternalTask(node) || model.IsAsyncNode(node) {
+		return errors.New("补偿节点必须是同步执行的服务任务")
+	}
+	for _, flow := range flows {
+		if flow.From == node.ID || flow.To == node.ID {
+			return errors.New("补偿节点不能连接连线")
+		}
+	}
+	return nil
+}
+
+// validateLoop checks the loop of a node. Only nodes that complete through tasks
+// can loop, and the loop condition must parse.
+func validateLoop(node *model.ProcessNode) error {
+	loop, err := model.GetLoop(node)
+	if err != nil || loop == nil {
+		return err
+	}
+	switch node.Type {
+	case model.NodeTypeUserTask, model.NodeTypeServiceTask, model.NodeTypeScriptTask, model.NodeTypeCallActivity:
+	default:
+		return fmt.Errorf("%s 节点不支持循环", node.Type)
+	}
+	if loop.Condition != "" {
+		if _, err := expression.ParseCondition(loop.Condition); err != nil {
+			return fmt.Errorf("循环条件无效: %v", err)
+		}
+	}
+	return nil
+}
+
+// validateVisitLimit checks the visit limit of a user task. The escalation flow
+// must leave the task and must not be the boundary timer flow, and another
+// outgoing flow is needed for normal completion.
+func validateVisitLimit(node *model.ProcessNode, flows []model.ProcessFlow) error {
+	limit, err := model.GetVisitLimit(node)
+	if err != nil || limit == nil || limit.Flow == "" {
+		return err
+	}
+	if boundary, _ := model.GetBoundaryTimer(node); boundary != nil && boundary.Flow == limit.Flow {
+		return errors.New("升级连线不能与超时连线相同")
+	}
+
+	found, others := false, 0
+	for _, flow := range flows {
+		if flow.From != node.ID {
+			continue
+		}
+		if flow.ID == limit.Flow {
+			found = true
+		} else {
+			others++
+		}
+	}
+	if !found {
+		return fmt.Errorf("升级连线 '%s' 不是该节点的出口连线", limit.Flow)
+	}
+	if others == 0 {
+		return errors.New("除升级连线外至少需要一条出口连线")
+	}
+	return nil
+}
+
+// validateScriptTask checks the script task props and parses the script.
+// Languages without a built-in runtime are rejected by the config.
+func validateScriptTask(node *model.ProcessNode) error {
+	cfg, err := model.GetScriptTaskConfig(node)
+	if err != nil {
+		return err
+	}
+	_, err = expression.ParseScript(cfg.Script)
+	return err
+}
+
+// validateMailTask checks the props of a mail task and the template syntax of
+// its recipients, subject and body
+func validateMailTask(node *model.ProcessNode) error {
+	cfg, err := model.GetMailTaskConfig(node)
+	if err != nil {
+		return err
+	}
+	for _, text := range append(append([]string{cfg.Subject, cfg.Body}, cfg.To...), cfg.Cc...) {
+		if err := notification.ValidateTemplate(text); err != nil {
+			return fmt.Errorf("模板语法错误: %v", err)
+		}
+	}
+	return nil
+}
+
+// validateAssigneeExpression checks the syntax of a user task assignee expression.
+// Fixed assignees are parsed directly; ${...} expressions are only parsed, since
+// they are evaluated against variables at task creation.
+func validateAssigneeExpression(source string) error {
+	if source == "" {
+		return nil
+	}
+	if expression.IsTemplate(source) {
+		_, err := expression.ParseTemplate(source)
+		return err
+	}
+	_, err := model.ParseAssigneeSpec(source)
+	return err
+}
+
+// validateMessageCatch checks the message name and the syntax of the correlation key expression
+func validateMessageCatch(node *model.ProcessNode) error {
+	cfg, err := model.GetMessageCatchConfig(node)
+	if err != nil {
+		return err
+	}
+	if expression.IsTemplate(cfg.CorrelationKey) {
+		_, err = expression.ParseTemplate(cfg.CorrelationKey)
+	}
+	return err
+}
+
+// validateNodeInstructions checks the instructions of a node and the syntax of
+// the ${...} expressions interpolated into them
+func validateNodeInstructions(node *model.ProcessNode) error {
+	instructions, err := model.GetNodeInstructions(node)
+	if err != nil || instructions == nil {
+		return err
+	}
+	for _, text := range instructions.Texts() {
+		if !expression.IsTemplate(*text) {
+			continue
+		}
+		if _, err := expression.ParseTemplate(*text); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -466,56 +1232,68 @@ func (s *ProcessService) toProcessResponse(process *model.ProcessDefinition) *Pr
 		}
 	}
 
+	labels, _ := process.GetDisplayLabels()
+	complexity, _ := process.GetComplexity()
+
 	return &ProcessResponse{
-		ID:          process.ID,
-		Key:         process.Key,
-		Name:        process.Name,
-		Version:     process.Version,
-		Description: process.Description,
-		Category:    process.Category,
-		Status:      process.Status,
-		Definition:  *definition,
-		CreatedBy:   process.CreatedBy,
-		CreatorName: creatorName,
-		CreatedAt:   process.CreatedAt,
-		UpdatedAt:   process.UpdatedAt,
+		ID:            process.ID,
+		Key:           process.Key,
+		Name:          process.Name,
+		Version:       process.Version,
+		Description:   process.Description,
+		Category:      process.Category,
+		Status:        process.Status,
+		Definition:    *definition,
+		DisplayLabels: labels,
+		DataPolicy:    process.DataPolicy(),
+		Complexity:    complexity,
+		CreatedBy:     process.CreatedBy,
+		CreatorName:   creatorName,
+		CreatedAt:     process.CreatedAt,
+		UpdatedAt:     process.UpdatedAt,
 	}
 }
 
 // GetProcessStats returns process statistics
-func (s *ProcessService) GetProcessStats() (map[string]int64, error) {
+func (s *ProcessService) GetProcessStats(ctx context.Context) (map[string]int64, error) {
 	stats := make(map[string]int64)
 
 	// Count by status
-	draftCount, err := s.processRepo.CountByStatus(model.ProcessStatusDraft)
+	draftCount, err := s.processRepo.CountByStatus(ctx, model.ProcessStatusDraft)
 	if err != nil {
 		return nil, err
 	}
 	stats["draft_count"] = draftCount
 
-	publishedCount, err := s.processRepo.CountByStatus(model.ProcessStatusPublished)
+	publishedCount, err := s.processRepo.CountByStatus(ctx, model.ProcessStatusPublished)
 	if err != nil {
 		return nil, err
 	}
 	stats["published_count"] = publishedCount
 
-	archivedCount, err := s.processRepo.CountByStatus(model.ProcessStatusArchived)
+	deprecatedCount, err := s.processRepo.CountByStatus(ctx, model.ProcessStatusDeprecated)
+	if err != nil {
+		return nil, err
+	}
+	stats["deprecated_count"] = deprecatedCount
+
+	archivedCount, err := s.processRepo.CountByStatus(ctx, model.ProcessStatusArchived)
 	if err != nil {
 		return nil, err
 	}
 	stats["archived_count"] = archivedCount
 
-	stats["total_count"] = draftCount + publishedCount + archivedCount
+	stats["total_count"] = draftCount + publishedCount + deprecatedCount + archivedCount
 
 	return stats, nil
 }
 
 // GetUserProcessStats returns user's process statistics
-func (s *ProcessService) GetUserProcessStats(userID uint) (map[string]int64, error) {
+func (s *ProcessService) GetUserProcessStats(ctx context.Context, userID uint) (map[string]int64, error) {
 	stats := make(map[string]int64)
 
 	// Count user's processes
-	userProcessCount, err := s.processRepo.CountByCreator(userID)
+	userProcessCount, err := s.processRepo.CountByCreator(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"miniflow/internal/repository"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// ReportingService maintains the read-only star schema used by BI tools
+type ReportingService struct {
+	reportingRepo *repository.ReportingRepository
+	logger        *logger.Logger
+
+	mu          sync.Mutex
+	refreshing  bool
+	lastRefresh *ReportingRefreshResult
+}
+
+// NewReportingService creates a new reporting service
+func NewReportingService(reportingRepo *repository.ReportingRepository, logger *logger.Logger) *ReportingService {
+	return &ReportingService{
+		reportingRepo: reportingRepo,
+		logger:        logger,
+	}
+}
+
+// ReportingRefreshResult describes the outcome of a reporting refresh
+type ReportingRefreshResult struct {
+	RefreshedAt time.Time `json:"refreshed_at"`
+	DurationMs  int64     `json:"duration_ms"`
+	Success     bool      `json:"success"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// ReportingStatusResponse represents reporting status response data
+type ReportingStatusResponse struct {
+	Refreshing  bool                        `json:"refreshing"`
+	LastRefresh *ReportingRefreshResult     `json:"last_refresh"`
+	Counts      *repository.ReportingCounts `json:"counts"`
+}
+
+// Refresh rebuilds the reporting tables from the operational tables
+func (s *ReportingService) Refresh(ctx context.Context) (*ReportingRefreshResult, error) {
+	s.mu.Lock()
+	if s.refreshing {
+		s.mu.Unlock()
+		return nil, errors.New("报表正在刷新中，请稍后再试")
+	}
+	s.refreshing = true
+	s.mu.Unlock()
+
+	started := time.Now()
+	err := s.rebuild(ctx, started)
+
+	result := &ReportingRefreshResult{
+		RefreshedAt: started,
+		DurationMs:  time.Since(started).Milliseconds(),
+		Success:     err == nil,
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	s.mu.Lock()
+	s.refreshing = false
+	s.lastRefresh = result
+	s.mu.Unlock()
+
+	if err != nil {
+		s.logger.Error("Failed to refresh reporting tables", zap.Error(err))
+		return nil, errors.New("刷新报表数据失败")
+	}
+
+	s.logger.Info("Reporting tables refreshed", zap.Int64("duration_ms", result.DurationMs))
+	return result, nil
+}
+
+// GetStatus returns the latest refresh result and reporting table sizes
+func (s *ReportingService) GetStatus(ctx context.Context) (*ReportingStatusResponse, error) {
+	counts, err := s.reportingRepo.GetCounts(ctx)
+	if err != nil {
+		s.logger.Error("Failed to count reporting tables", zap.Error(err))
+		return nil, errors.New("获取报表状态失败")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &ReportingStatusResponse{
+		Refreshing:  s.refreshing,
+		LastRefresh: s.lastRefresh,
+		Counts:      counts,
+	}, nil
+}
+
+// Start refreshes the reporting tables periodically until ctx is cancelled
+func (s *ReportingService) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Errors are already logged and kept in lastRefresh
+			_, _ = s.Refresh(ctx)
+		}
+	}
+}
+
+// rebuild fills the date dimension and rebuilds every other reporting table
+func (s *ReportingService) rebuild(ctx context.Context, now time.Time) error {
+	earliest, err := s.reportingRepo.GetEarliestActivity(ctx)
+	if err != nil {
+		return err
+	}
+
+	from := now
+	if earliest != nil && earliest.Before(now) {
+		from = *earliest
+	}
+	// 日期维度多生成一年，便于BI按截止日期等未来日期关联
+	if err := s.reportingRepo.EnsureDateRange(ctx, from, now.AddDate(1, 0, 0)); err != nil {
+		return err
+	}
+
+	return s.reportingRepo.Rebuild(ctx, now)
+}
@@ -0,0 +1,295 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/internal/repository"
+	"miniflow/pkg/cache"
+	"miniflow/pkg/config"
+	"miniflow/pkg/database"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Self-test check results; the report takes the worst result of its checks
+const (
+	SelfTestPass = "pass"
+	SelfTestWarn = "warn"
+	SelfTestFail = "fail"
+)
+
+const (
+	// selfTestTimerLag is how far past due a waiting timer may be before the scheduler is considered stalled
+	selfTestTimerLag = 5 * time.Minute
+	// selfTestClockSkewWarn and selfTestClockSkewFail bound the clock difference to the database server
+	selfTestClockSkewWarn = 2 * time.Second
+	selfTestClockSkewFail = 30 * time.Second
+)
+
+// SelfTestCheck is the result of a single deployment check
+type SelfTestCheck struct {
+	Name     string   `json:"name"`
+	Status   string   `json:"status"`
+	Message  string   `json:"message"`
+	Details  []string `json:"details,omitempty"`
+	Duration int64    `json:"duration_ms"`
+}
+
+// SelfTestReport is the pass/fail report used to verify a deployment
+type SelfTestReport struct {
+	Status    string          `json:"status"`
+	Checks    []SelfTestCheck `json:"checks"`
+	CheckedAt time.Time       `json:"checked_at"`
+}
+
+// Passed reports whether no check failed; warnings do not fail the report
+func (r *SelfTestReport) Passed() bool {
+	return r.Status != SelfTestFail
+}
+
+// SelfTestService runs the startup self-test shared by the doctor command and the admin API
+type SelfTestService struct {
+	db           *database.Database
+	cfg          *config.Config
+	processRepo  *repository.ProcessRepository
+	policyRepo   *repository.ConnectorPolicyRepository
+	instanceRepo *repository.ProcessInstanceRepository
+	logger       *logger.Logger
+}
+
+// NewSelfTestService creates a new self-test service
+func NewSelfTestService(
+	db *database.Database,
+	cfg *config.Config,
+	processRepo *repository.ProcessRepository,
+	policyRepo *repository.ConnectorPolicyRepository,
+	instanceRepo *repository.ProcessInstanceRepository,
+	logger *logger.Logger,
+) *SelfTestService {
+	return &SelfTestService{
+		db:           db,
+		cfg:          cfg,
+		processRepo:  processRepo,
+		policyRepo:   policyRepo,
+		instanceRepo: instanceRepo,
+		logger:       logger,
+	}
+}
+
+// Run executes every check and returns the report; a failing check does not stop the others
+func (s *SelfTestService) Run(ctx context.Context) *SelfTestReport {
+	checks := []struct {
+		name string
+		run  func(ctx context.Context) (string, string, []string)
+	}{
+		{"database.schema", s.checkSchema},
+		{"database.indexes", s.checkIndexes},
+		{"redis", s.checkRedis},
+		{"jwt.secret", s.checkJWTSecret},
+		{"scheduler", s.checkScheduler},
+		{"connector.allowlists", s.checkConnectorAllowlists},
+		{"clock.skew", s.checkClockSkew},
+	}
+
+	report := &SelfTestReport{Status: SelfTestPass, CheckedAt: time.Now()}
+	for _, check := range checks {
+		started := time.Now()
+		status, message, details := check.run(ctx)
+		report.Checks = append(report.Checks, SelfTestCheck{
+			Name:     check.name,
+			Status:   status,
+			Message:  message,
+			Details:  details,
+			Duration: time.Since(started).Milliseconds(),
+		})
+		if status == SelfTestFail || (status == SelfTestWarn && report.Status == SelfTestPass) {
+			report.Status = status
+		}
+	}
+
+	s.logger.Info("Self-test completed", zap.String("status", report.Status))
+	return report
+}
+
+// checkSchema verifies that every model table and column has been migrated
+func (s *SelfTestService) checkSchema(ctx context.Context) (string, string, []string) {
+	migrator := s.db.WithContext(ctx).Migrator()
+	var missing []string
+	count := 0
+	for _, m := range model.AllModels() {
+		stmt := &gorm.Statement{DB: s.db.DB}
+		if err := stmt.Parse(m); err != nil {
+			return SelfTestFail, fmt.Sprintf("解析模型失败: %v", err), nil
+		}
+		count++
+		if !migrator.HasTable(stmt.Schema.Table) {
+			missing = append(missing, "table "+stmt.Schema.Table)
+			continue
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" && !migrator.HasColumn(m, field.DBName) {
+				missing = append(missing, "column "+stmt.Schema.Table+"."+field.DBName)
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		return SelfTestFail, fmt.Sprintf("数据库结构落后于当前版本，缺少 %d 项，请执行数据库迁移", len(missing)), missing
+	}
+	return SelfTestPass, fmt.Sprintf("%d 张表的结构与当前版本一致", count), nil
+}
+
+// checkIndexes verifies that every index declared on the models exists
+func (s *SelfTestService) checkIndexes(ctx context.Context) (string, string, []string) {
+	migrator := s.db.WithContext(ctx).Migrator()
+	var missing []string
+	count := 0
+	for _, m := range model.AllModels() {
+		stmt := &gorm.Statement{DB: s.db.DB}
+		if err := stmt.Parse(m); err != nil {
+			return SelfTestFail, fmt.Sprintf("解析模型失败: %v", err), nil
+		}
+		for _, index := range stmt.Schema.ParseIndexes() {
+			count++
+			if !migrator.HasIndex(m, index.Name) {
+				missing = append(missing, stmt.Schema.Table+"."+index.Name)
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		return SelfTestFail, fmt.Sprintf("缺少 %d 个索引，请执行数据库迁移", len(missing)), missing
+	}
+	return SelfTestPass, fmt.Sprintf("%d 个索引均已创建", count), nil
+}
+
+// checkRedis sends a PING, authenticating first when a password is configured
+func (s *SelfTestService) checkRedis(ctx context.Context) (string, string, []string) {
+	addr := s.cfg.Redis.GetRedisAddr()
+	client := cache.NewRedisClient(&s.cfg.Redis)
+	defer client.Close()
+
+	if err := client.Ping(); err != nil {
+		return SelfTestFail, fmt.Sprintf("Redis %s 不可用: %v", addr, err), nil
+	}
+	return SelfTestPass, fmt.Sprintf("Redis %s 可以连接", addr), nil
+}
+
+// checkJWTSecret flags secrets that pass startup validation but are still weak
+func (s *SelfTestService) checkJWTSecret(ctx context.Context) (string, string, []string) {
+	reasons := s.cfg.JWT.WeakSecretReasons()
+	if len(reasons) == 0 {
+		return SelfTestPass, "JWT 密钥强度足够", nil
+	}
+	if s.cfg.Server.Debug {
+		return SelfTestWarn, "JWT 密钥强度不足，仅可用于调试环境", reasons
+	}
+	return SelfTestFail, "JWT 密钥强度不足", reasons
+}
+
+// checkScheduler detects a stalled or missing timer scheduler from timers left waiting past their due time.
+// Schedulers on every instance coordinate through conditional updates, so there is no leader to check.
+func (s *SelfTestService) checkScheduler(ctx context.Context) (string, string, []string) {
+	stale, err := s.instanceRepo.CountDueTimers(ctx, time.Now().Add(-selfTestTimerLag))
+	if err != nil {
+		return SelfTestFail, fmt.Sprintf("统计到期定时器失败: %v", err), nil
+	}
+	if stale > 0 {
+		return SelfTestWarn, fmt.Sprintf("%d 个定时器超过到期时间 %s 仍未触发，调度器可能没有运行", stale, selfTestTimerLag), nil
+	}
+	return SelfTestPass, "没有积压的到期定时器", nil
+}
+
+// checkConnectorAllowlists fails when connector mocks are enabled outside debug mode and
+// warns about published processes with service tasks but no connector allowlist
+func (s *SelfTestService) checkConnectorAllowlists(ctx context.Context) (string, string, []string) {
+	if s.cfg.Connector.Mock.Enabled && !s.cfg.Server.Debug {
+		return SelfTestFail, "生产环境开启了连接器模拟模式，服务任务不会调用真实的外部系统", nil
+	}
+
+	policies, err := s.policyRepo.List(ctx)
+	if err != nil {
+		return SelfTestFail, fmt.Sprintf("获取连接器白名单失败: %v", err), nil
+	}
+	restricted := make(map[string]bool, len(policies))
+	for _, policy := range policies {
+		restricted[policy.DefinitionKey] = true
+	}
+
+	processes, err := s.processRepo.GetPublishedProcesses(ctx)
+	if err != nil {
+		return SelfTestFail, fmt.Sprintf("获取已发布流程失败: %v", err), nil
+	}
+	unrestricted := make(map[string]bool)
+	for _, process := range processes {
+		if restricted[process.Key] || !hasServiceTask(process) {
+			continue
+		}
+		unrestricted[process.Key] = true
+	}
+
+	if len(unrestricted) > 0 {
+		keys := make([]string, 0, len(unrestricted))
+		for key := range unrestricted {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return SelfTestWarn, fmt.Sprintf("%d 个包含服务任务的已发布流程没有配置连接器白名单", len(keys)), keys
+	}
+	return SelfTestPass, fmt.Sprintf("%d 个连接器白名单已配置", len(policies)), nil
+}
+
+// hasServiceTask reports whether the definition calls external systems
+func hasServiceTask(process *model.ProcessDefinition) bool {
+	data, err := process.GetDefinitionData()
+	if err != nil {
+		return false
+	}
+	for _, node := range data.Nodes {
+		if node.Type == model.NodeTypeServiceTask {
+			return true
+		}
+	}
+	return false
+}
+
+// checkClockSkew compares the local clock with the database server clock
+func (s *SelfTestService) checkClockSkew(ctx context.Context) (string, string, []string) {
+	var query string
+	switch s.db.Dialect() {
+	case config.DriverPostgres:
+		query = "SELECT EXTRACT(EPOCH FROM clock_timestamp())"
+	case config.DriverSQLite:
+		// sqlite 嵌入在本进程内，使用的就是本机时钟
+		return SelfTestPass, "嵌入式数据库使用本机时钟", nil
+	default:
+		query = "SELECT UNIX_TIMESTAMP(NOW(3))"
+	}
+
+	var dbTime float64
+	started := time.Now()
+	if err := s.db.WithContext(ctx).Raw(query).Scan(&dbTime).Error; err != nil {
+		return SelfTestFail, fmt.Sprintf("读取数据库时间失败: %v", err), nil
+	}
+	// 以请求往返的中点作为数据库读取时间的本地时刻
+	local := started.Add(time.Since(started) / 2)
+	skew := time.Duration((float64(local.UnixNano())/1e9 - dbTime) * float64(time.Second))
+	if skew < 0 {
+		skew = -skew
+	}
+	skew = skew.Round(time.Millisecond)
+
+	switch {
+	case skew > selfTestClockSkewFail:
+		return SelfTestFail, fmt.Sprintf("与数据库的时钟偏差 %s 超过 %s，截止时间和定时器会出错", skew, selfTestClockSkewFail), nil
+	case skew > selfTestClockSkewWarn:
+		return SelfTestWarn, fmt.Sprintf("与数据库的时钟偏差 %s 超过 %s", skew, selfTestClockSkewWarn), nil
+	}
+	return SelfTestPass, fmt.Sprintf("与数据库的时钟偏差 %s", skew), nil
+}
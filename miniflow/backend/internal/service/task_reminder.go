@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/internal/notification"
+	"miniflow/internal/repository"
+	"miniflow/pkg/config"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// TaskReminderService reminds assignees of pending tasks before their due date
+type TaskReminderService struct {
+	taskRepo   *repository.TaskRepository
+	dispatcher *notification.Dispatcher
+	cfg        *config.ReminderConfig
+	logger     *logger.Logger
+}
+
+// NewTaskReminderService creates a new task reminder service
+func NewTaskReminderService(
+	taskRepo *repository.TaskRepository,
+	dispatcher *notification.Dispatcher,
+	cfg *config.ReminderConfig,
+	logger *logger.Logger,
+) *TaskReminderService {
+	return &TaskReminderService{
+		taskRepo:   taskRepo,
+		dispatcher: dispatcher,
+		cfg:        cfg,
+		logger:     logger,
+	}
+}
+
+// SendDueReminders reminds the assignee of every pending task whose due date is within a
+// configured offset and returns the number of reminders sent
+func (s *TaskReminderService) SendDueReminders(ctx context.Context, now time.Time) (int, error) {
+	offsets := s.cfg.GetOffsets()
+	if len(offsets) == 0 {
+		return 0, nil
+	}
+
+	tasks, err := s.taskRepo.GetTasksDueBefore(ctx, now, now.Add(offsets[0]))
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range tasks {
+		task := &tasks[i]
+		if task.DueDate == nil || task.AssigneeID == nil || task.Instance.Status != model.InstanceStatusRunning {
+			continue
+		}
+
+		// 只发送已到达的最近一个提醒时间点，错过的较早提醒不再补发
+		var offset time.Duration
+		for _, o := range offsets {
+			if !now.Before(task.DueDate.Add(-o)) {
+				offset = o
+			}
+		}
+		if offset == 0 {
+			continue
+		}
+
+		// 以任务、到期时间和提醒时间点去重，多个实例同时运行时也只发送一次
+		ok, err := s.taskRepo.RecordReminder(ctx, &model.TaskReminder{
+			InstanceID:    task.InstanceID,
+			TaskID:        task.ID,
+			DueDate:       *task.DueDate,
+			OffsetSeconds: int64(offset / time.Second),
+		})
+		if err != nil {
+			return sent, err
+		}
+		if !ok {
+			continue
+		}
+		sent++
+
+		data := notification.NewTemplateData(nil, &task.Instance, task)
+		data.Extra["remind_before"] = offset.String()
+		if err := s.dispatcher.Notify(ctx, *task.AssigneeID, model.NotificationEventTaskReminder, data); err != nil {
+			s.logger.Warn("Failed to send task reminder",
+				zap.Uint("task_id", task.ID),
+				zap.Uint("user_id", *task.AssigneeID),
+				zap.Error(err),
+			)
+		}
+	}
+
+	return sent, nil
+}
+
+// Start runs the reminder loop until ctx is cancelled
+func (s *TaskReminderService) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.GetInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := s.SendDueReminders(ctx, now); err != nil {
+				s.logger.Error("Failed to send task reminders", zap.Error(err))
+			}
+		}
+	}
+}
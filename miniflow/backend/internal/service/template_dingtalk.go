@@ -0,0 +1,358 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"miniflow/internal/model"
+)
+
+// dingTalkTemplate is the process export format of DingTalk OA approval: the form schema and
+// the node tree of the process designer
+type dingTalkTemplate struct {
+	Name          string          `json:"name"`
+	Description   string          `json:"description"`
+	SchemaContent *dingTalkSchema `json:"schemaContent"`
+	Process       *dingTalkNode   `json:"process"`
+}
+
+type dingTalkSchema struct {
+	Items []dingTalkComponent `json:"items"`
+}
+
+type dingTalkComponent struct {
+	ComponentName string `json:"componentName"`
+	Props         struct {
+		ID       string          `json:"id"`
+		Label    string          `json:"label"`
+		Required bool            `json:"required"`
+		Options  json.RawMessage `json:"options"`
+	} `json:"props"`
+}
+
+// dingTalkNode is a node of the designer tree; childNode is the next node and the condition
+// branches of a route node are in conditionNodes
+type dingTalkNode struct {
+	Type           string             `json:"type"`
+	Name           string             `json:"name"`
+	Properties     dingTalkProperties `json:"properties"`
+	ChildNode      *dingTalkNode      `json:"childNode"`
+	ConditionNodes []dingTalkNode     `json:"conditionNodes"`
+}
+
+type dingTalkProperties struct {
+	ActivateType  string             `json:"activateType"`
+	ActionerRules []dingTalkActioner `json:"actionerRules"`
+	IsDefault     bool               `json:"isDefault"`
+	Conditions    json.RawMessage    `json:"conditions"`
+}
+
+type dingTalkActioner struct {
+	Type      string `json:"type"`
+	Approvals []struct {
+		UserID string `json:"userId"`
+		Name   string `json:"name"`
+	} `json:"approvals"`
+	LabelNames string `json:"labelNames"`
+}
+
+type dingTalkCondition struct {
+	ParamKey        string          `json:"paramKey"`
+	ParamLabel      string          `json:"paramLabel"`
+	Type            string          `json:"type"`
+	LowerBound      string          `json:"lowerBound"`
+	LowerBoundEqual json.RawMessage `json:"lowerBoundEqual"`
+	UpperBound      string          `json:"upperBound"`
+	UpperBoundEqual json.RawMessage `json:"upperBoundEqual"`
+	ParamValue      string          `json:"paramValue"`
+	ParamValues     []string        `json:"paramValues"`
+}
+
+// templateTail is an unconnected outgoing flow waiting for the next node
+type templateTail struct {
+	from      string
+	condition string
+	label     string
+}
+
+// DingTalk form components mapped to miniflow form field types
+var dingTalkFieldTypes = map[string]string{
+	"TextField":          "text",
+	"TextareaField":      "textarea",
+	"NumberField":        "number",
+	"MoneyField":         "number",
+	"CalculateField":     "number",
+	"DDDateField":        "date",
+	"DDSelectField":      "select",
+	"DDMultiSelectField": "checkbox",
+}
+
+// convertDingTalk converts a DingTalk approval process export, including condition branches
+func (b *templateBuilder) convertDingTalk(raw json.RawMessage) error {
+	var tmpl dingTalkTemplate
+	if err := json.Unmarshal(raw, &tmpl); err != nil {
+		return fmt.Errorf("钉钉流程导出格式错误: %v", err)
+	}
+	if tmpl.Process == nil {
+		return errors.New("钉钉流程导出缺少 process 节点树")
+	}
+	b.name = strings.TrimSpace(tmpl.Name)
+	b.description = tmpl.Description
+
+	var fields []TemplateFormField
+	if tmpl.SchemaContent != nil {
+		for _, component := range tmpl.SchemaContent.Items {
+			if component.ComponentName == "TextNote" {
+				continue // 说明文字，不是输入项
+			}
+			fields = append(fields, b.dingTalkField(component))
+		}
+	}
+
+	start := b.addStart(fields)
+	tails, row, err := b.dingTalkChain(tmpl.Process, []templateTail{{from: start}}, 0, 1)
+	if err != nil {
+		return err
+	}
+	if b.counters["approval"] == 0 {
+		return errors.New("钉钉流程没有审批节点")
+	}
+
+	end := b.addNode(model.NodeTypeEnd, "end", "结束", 0, row, nil)
+	b.attach(tails, end)
+	return nil
+}
+
+// attach connects the pending flows to a node
+func (b *templateBuilder) attach(tails []templateTail, to string) {
+	for _, tail := range tails {
+		b.connect(tail.from, to, tail.condition, tail.label)
+	}
+}
+
+// dingTalkChain converts a node and its successors, returning the flows left open at the end of the chain
+func (b *templateBuilder) dingTalkChain(node *dingTalkNode, tails []templateTail, column, row int) ([]templateTail, int, error) {
+	for ; node != nil; node = node.ChildNode {
+		switch node.Type {
+		case "start":
+		case "approver":
+			approval := templateApproval{Name: node.Name, Mode: dingTalkApprovalMode(node.Properties.ActivateType)}
+			if approval.Name == "" {
+				approval.Name = fmt.Sprintf("审批 %d", b.counters["approval"]+1)
+			}
+			for _, actioner := range node.Properties.ActionerRules {
+				approval.Approvers = append(approval.Approvers, b.dingTalkApprovers(actioner, approval.Name)...)
+			}
+			first, last, next := b.addApproval(approval, column, row)
+			b.attach(tails, first)
+			tails, row = []templateTail{{from: last}}, next
+		case "notifier":
+			b.warn("抄送节点 %q 已忽略，miniflow 没有抄送步骤", node.Name)
+		case "route":
+			gateway := b.addNode(model.NodeTypeGateway, "gateway", "条件分支", column, row,
+				map[string]interface{}{"gatewayType": model.GatewayTypeExclusive})
+			b.attach(tails, gateway)
+
+			var merged []templateTail
+			end := row + 1
+			for i := range node.ConditionNodes {
+				branch := &node.ConditionNodes[i]
+				condition := ""
+				if !branch.Properties.IsDefault {
+					var err error
+					if condition, err = b.dingTalkCondition(branch); err != nil {
+						return nil, 0, err
+					}
+				}
+				branchTails, branchEnd, err := b.dingTalkChain(branch.ChildNode,
+					[]templateTail{{from: gateway, condition: condition, label: branch.Name}}, column+i, row+1)
+				if err != nil {
+					return nil, 0, err
+				}
+				merged = append(merged, branchTails...)
+				if branchEnd > end {
+					end = branchEnd
+				}
+			}
+			if len(merged) == 0 {
+				return nil, 0, fmt.Errorf("条件分支 %q 没有分支", node.Name)
+			}
+			tails, row = merged, end
+		default:
+			b.warn("节点 %q 的类型 %s 不受支持，已忽略", node.Name, node.Type)
+		}
+	}
+	return tails, row, nil
+}
+
+func (b *templateBuilder) dingTalkField(component dingTalkComponent) TemplateFormField {
+	field := TemplateFormField{
+		Name:     templateFieldName(component.Props.ID),
+		Label:    component.Props.Label,
+		Type:     dingTalkFieldTypes[component.ComponentName],
+		Required: component.Props.Required,
+	}
+	if field.Type == "" {
+		b.warn("表单控件 %q 的类型 %s 不受支持，转换为文本字段", field.Label, component.ComponentName)
+		field.Type = "text"
+	}
+	if (field.Type == "select" || field.Type == "checkbox") && len(component.Props.Options) > 0 {
+		// 选项可以是字符串列表，也可以是 {key, value} 对象列表
+		var values []string
+		var pairs []struct {
+			Key   string `json:"key"`
+			Value string `json:"value"`
+		}
+		if err := json.Unmarshal(component.Props.Options, &values); err == nil {
+			for _, value := range values {
+				field.Options = append(field.Options, TemplateFieldOption{Label: value, Value: value})
+			}
+		} else if err := json.Unmarshal(component.Props.Options, &pairs); err == nil {
+			for _, pair := range pairs {
+				field.Options = append(field.Options, TemplateFieldOption{Label: pair.Value, Value: pair.Value})
+			}
+		} else {
+			b.warn("表单控件 %q 的选项无法解析，已忽略", field.Label)
+		}
+	}
+	return field
+}
+
+// dingTalkApprovalMode maps a DingTalk activate type (ALL, ANY, ONE_BY_ONE) to an approval mode
+func dingTalkApprovalMode(activateType string) string {
+	switch strings.ToUpper(activateType) {
+	case "ANY", "OR":
+		return approvalModeOr
+	case "ONE_BY_ONE":
+		return approvalModeSequential
+	default:
+		return approvalModeAnd
+	}
+}
+
+func (b *templateBuilder) dingTalkApprovers(actioner dingTalkActioner, stepName string) []string {
+	switch actioner.Type {
+	case "target_approval":
+		specs := make([]string, 0, len(actioner.Approvals))
+		for _, approval := range actioner.Approvals {
+			specs = append(specs, b.userApprover(approval.UserID, approval.Name))
+		}
+		return specs
+	case "target_label":
+		var specs []string
+		for _, label := range strings.Split(actioner.LabelNames, ",") {
+			if strings.TrimSpace(label) != "" {
+				specs = append(specs, b.roleApprover(label))
+			}
+		}
+		return specs
+	case "target_management":
+		return []string{b.supervisorApprover(stepName)}
+	case "target_select":
+		return []string{b.selectedApprover(stepName)}
+	case "target_originator":
+		return []string{starterApprover()}
+	default:
+		b.warn("审批节点 %q 的审批人类型 %s 不受支持，已忽略", stepName, actioner.Type)
+		return nil
+	}
+}
+
+// dingTalkCondition converts the conditions of a branch into a flow condition: the groups are
+// alternatives and the conditions within a group must all hold
+func (b *templateBuilder) dingTalkCondition(branch *dingTalkNode) (string, error) {
+	var groups [][]dingTalkCondition
+	if len(branch.Properties.Conditions) > 0 {
+		if err := json.Unmarshal(branch.Properties.Conditions, &groups); err != nil {
+			var single []dingTalkCondition
+			if err := json.Unmarshal(branch.Properties.Conditions, &single); err != nil {
+				return "", fmt.Errorf("分支 %q 的条件格式错误: %v", branch.Name, err)
+			}
+			groups = [][]dingTalkCondition{single}
+		}
+	}
+
+	var alternatives []string
+	for _, group := range groups {
+		var terms []string
+		for _, condition := range group {
+			term := b.dingTalkTerm(condition, branch.Name)
+			if term != "" {
+				terms = append(terms, term)
+			}
+		}
+		if len(terms) > 0 {
+			alternatives = append(alternatives, strings.Join(terms, " && "))
+		}
+	}
+	switch len(alternatives) {
+	case 0:
+		return "", fmt.Errorf("分支 %q 没有可转换的条件", branch.Name)
+	case 1:
+		return alternatives[0], nil
+	}
+	for i, alternative := range alternatives {
+		alternatives[i] = "(" + alternative + ")"
+	}
+	return strings.Join(alternatives, " || "), nil
+}
+
+// dingTalkTerm converts a single range or value condition on a form field
+func (b *templateBuilder) dingTalkTerm(condition dingTalkCondition, branchName string) string {
+	name := templateFieldName(condition.ParamKey)
+	switch condition.Type {
+	case "dingtalk_actioner_range_condition":
+		var terms []string
+		if condition.LowerBound != "" {
+			op := ">"
+			if dingTalkFlag(condition.LowerBoundEqual) {
+				op = ">="
+			}
+			terms = append(terms, fmt.Sprintf("%s %s %s", name, op, quoteConditionValue(condition.LowerBound)))
+		}
+		if condition.UpperBound != "" {
+			op := "<"
+			if dingTalkFlag(condition.UpperBoundEqual) {
+				op = "<="
+			}
+			terms = append(terms, fmt.Sprintf("%s %s %s", name, op, quoteConditionValue(condition.UpperBound)))
+		}
+		return strings.Join(terms, " && ")
+	case "dingtalk_actioner_value_condition":
+		values := condition.ParamValues
+		if len(values) == 0 && condition.ParamValue != "" {
+			values = []string{condition.ParamValue}
+		}
+		var terms []string
+		for _, value := range values {
+			terms = append(terms, fmt.Sprintf("%s == %s", name, quoteConditionValue(value)))
+		}
+		if len(terms) > 1 {
+			return "(" + strings.Join(terms, " || ") + ")"
+		}
+		return strings.Join(terms, "")
+	default:
+		label := condition.ParamLabel
+		if label == "" {
+			label = condition.ParamKey
+		}
+		b.warn("分支 %q 中基于 %s 的条件类型 %s 不受支持，已忽略", branchName, label, condition.Type)
+		return ""
+	}
+}
+
+// dingTalkFlag reads a boolean that DingTalk exports either as a JSON boolean or as a string
+func dingTalkFlag(raw json.RawMessage) bool {
+	var flag bool
+	if err := json.Unmarshal(raw, &flag); err == nil {
+		return flag
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		flag, _ = strconv.ParseBool(text)
+	}
+	return flag
+}
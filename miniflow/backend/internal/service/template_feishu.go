@@ -0,0 +1,240 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"miniflow/internal/model"
+)
+
+// feishuI18nPrefix marks a Feishu text that is resolved through i18n_resources
+const feishuI18nPrefix = "@i18n@"
+
+// feishuTemplate is the approval definition format of the Feishu approval API
+type feishuTemplate struct {
+	ApprovalName  string               `json:"approval_name"`
+	Description   string               `json:"description"`
+	Form          json.RawMessage      `json:"form"`
+	NodeList      []feishuNode         `json:"node_list"`
+	I18nResources []feishuI18nResource `json:"i18n_resources"`
+}
+
+type feishuNode struct {
+	ID       string           `json:"id"`
+	NodeID   string           `json:"node_id"`
+	Name     string           `json:"name"`
+	NodeType string           `json:"node_type"`
+	Approver []feishuApprover `json:"approver"`
+}
+
+type feishuApprover struct {
+	Type   string `json:"type"`
+	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+}
+
+type feishuWidget struct {
+	ID       string          `json:"id"`
+	Name     string          `json:"name"`
+	Type     string          `json:"type"`
+	Required bool            `json:"required"`
+	Option   json.RawMessage `json:"option"`
+	Options  json.RawMessage `json:"options"`
+}
+
+type feishuI18nResource struct {
+	Locale    string `json:"locale"`
+	IsDefault bool   `json:"is_default"`
+	Texts     []struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	} `json:"texts"`
+}
+
+// Feishu widget types mapped to miniflow form field types
+var feishuFieldTypes = map[string]string{
+	"input":      "text",
+	"textarea":   "textarea",
+	"number":     "number",
+	"amount":     "number",
+	"formula":    "number",
+	"date":       "date",
+	"radio":      "select",
+	"radioV2":    "select",
+	"checkbox":   "checkbox",
+	"checkboxV2": "checkbox",
+}
+
+// convertFeishu converts a Feishu approval definition. Feishu definitions are a linear list of
+// approval nodes between START and END; cc nodes are dropped since miniflow has no cc step.
+func (b *templateBuilder) convertFeishu(raw json.RawMessage) error {
+	var tmpl feishuTemplate
+	if err := json.Unmarshal(raw, &tmpl); err != nil {
+		return fmt.Errorf("飞书审批定义格式错误: %v", err)
+	}
+	texts := tmpl.i18nTexts()
+	resolve := func(text string) string {
+		if value, ok := texts[text]; ok {
+			return value
+		}
+		return strings.TrimPrefix(text, feishuI18nPrefix)
+	}
+	b.name = resolve(tmpl.ApprovalName)
+	b.description = resolve(tmpl.Description)
+
+	widgets, err := parseFeishuForm(tmpl.Form)
+	if err != nil {
+		return err
+	}
+	fields := make([]TemplateFormField, 0, len(widgets))
+	for _, widget := range widgets {
+		fields = append(fields, b.feishuField(widget, resolve))
+	}
+
+	last := b.addStart(fields)
+	row := 1
+	approvals := 0
+	for _, node := range tmpl.NodeList {
+		id := node.ID
+		if id == "" {
+			id = node.NodeID
+		}
+		name := resolve(node.Name)
+		switch {
+		case strings.EqualFold(id, "START"), strings.EqualFold(id, "END"):
+			continue
+		case node.NodeType == "CC_SEND":
+			b.warn("抄送节点 %q 已忽略，miniflow 没有抄送步骤", name)
+			continue
+		}
+		if name == "" {
+			name = fmt.Sprintf("审批 %d", approvals+1)
+		}
+
+		approval := templateApproval{Name: name, Mode: feishuApprovalMode(node.NodeType)}
+		for _, approver := range node.Approver {
+			if spec := b.feishuApprover(approver, name); spec != "" {
+				approval.Approvers = append(approval.Approvers, spec)
+			}
+		}
+		first, end, next := b.addApproval(approval, 0, row)
+		b.connect(last, first, "", "")
+		last, row = end, next
+		approvals++
+	}
+	if approvals == 0 {
+		return errors.New("飞书审批定义没有审批节点")
+	}
+
+	end := b.addNode(model.NodeTypeEnd, "end", "结束", 0, row, nil)
+	b.connect(last, end, "", "")
+	return nil
+}
+
+// i18nTexts returns the texts of the default locale, falling back to the first locale
+func (t *feishuTemplate) i18nTexts() map[string]string {
+	texts := make(map[string]string)
+	if len(t.I18nResources) == 0 {
+		return texts
+	}
+	resource := t.I18nResources[0]
+	for _, candidate := range t.I18nResources {
+		if candidate.IsDefault {
+			resource = candidate
+			break
+		}
+	}
+	for _, text := range resource.Texts {
+		texts[text.Key] = text.Value
+	}
+	return texts
+}
+
+// parseFeishuForm decodes the form, which Feishu returns as a JSON string, an object with
+// form_content, or a widget array
+func parseFeishuForm(raw json.RawMessage) ([]feishuWidget, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var content string
+	if err := json.Unmarshal(raw, &content); err == nil {
+		raw = json.RawMessage(content)
+	} else {
+		var wrapper struct {
+			FormContent string `json:"form_content"`
+		}
+		if err := json.Unmarshal(raw, &wrapper); err == nil && wrapper.FormContent != "" {
+			raw = json.RawMessage(wrapper.FormContent)
+		}
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var widgets []feishuWidget
+	if err := json.Unmarshal(raw, &widgets); err != nil {
+		return nil, fmt.Errorf("飞书审批表单格式错误: %v", err)
+	}
+	return widgets, nil
+}
+
+func (b *templateBuilder) feishuField(widget feishuWidget, resolve func(string) string) TemplateFormField {
+	field := TemplateFormField{
+		Name:     templateFieldName(widget.ID),
+		Label:    resolve(widget.Name),
+		Type:     feishuFieldTypes[widget.Type],
+		Required: widget.Required,
+	}
+	if field.Type == "" {
+		b.warn("表单控件 %q 的类型 %s 不受支持，转换为文本字段", field.Label, widget.Type)
+		field.Type = "text"
+	}
+	if field.Type == "select" || field.Type == "checkbox" {
+		raw := widget.Option
+		if len(raw) == 0 {
+			raw = widget.Options
+		}
+		var options []struct {
+			Value string `json:"value"`
+			Text  string `json:"text"`
+		}
+		if len(raw) > 0 {
+			if err := json.Unmarshal(raw, &options); err != nil {
+				b.warn("表单控件 %q 的选项无法解析，已忽略", field.Label)
+			}
+		}
+		for _, option := range options {
+			field.Options = append(field.Options, TemplateFieldOption{Label: resolve(option.Text), Value: option.Value})
+		}
+	}
+	return field
+}
+
+// feishuApprovalMode maps a Feishu node type (AND, OR, SEQUENTIAL) to an approval mode
+func feishuApprovalMode(nodeType string) string {
+	switch nodeType {
+	case "OR":
+		return approvalModeOr
+	case "SEQUENTIAL":
+		return approvalModeSequential
+	default:
+		return approvalModeAnd
+	}
+}
+
+func (b *templateBuilder) feishuApprover(approver feishuApprover, stepName string) string {
+	switch approver.Type {
+	case "Personal":
+		return b.userApprover(approver.UserID, approver.Name)
+	case "Supervisor", "SupervisorTopDown", "DepartmentManager", "DepartmentManagerTopDown":
+		return b.supervisorApprover(stepName)
+	case "Free":
+		return b.selectedApprover(stepName)
+	case "Self":
+		return starterApprover()
+	default:
+		b.warn("审批节点 %q 的审批人类型 %s 不受支持，已忽略", stepName, approver.Type)
+		return ""
+	}
+}
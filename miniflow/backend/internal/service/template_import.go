@@ -0,0 +1,364 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// Supported approval template sources
+const (
+	TemplateSourceFeishu   = "feishu"
+	TemplateSourceDingTalk = "dingtalk"
+)
+
+// How the approvers of one approval step complete it
+const (
+	approvalModeAnd        = "and"        // every approver must approve (会签)
+	approvalModeOr         = "or"         // any one approver is enough (或签)
+	approvalModeSequential = "sequential" // approvers approve one after another (依次审批)
+)
+
+// Layout of imported nodes on the designer canvas
+const (
+	templateLayoutX        = 300.0
+	templateLayoutY        = 80.0
+	templateColumnWidth    = 240.0
+	templateRowHeight      = 120.0
+	templateSupervisorRole = "manager"
+)
+
+// ImportTemplateRequest represents a request to import an approval template from another OA platform
+type ImportTemplateRequest struct {
+	Source   string          `json:"source" validate:"required,oneof=feishu dingtalk"`
+	Key      string          `json:"key" validate:"required,min=3,max=100,alphanum_underscore"`
+	Name     string          `json:"name" validate:"omitempty,max=255"`
+	Category string          `json:"category"`
+	Template json.RawMessage `json:"template" validate:"required"`
+
+	// UserMapping maps user IDs of the source platform to miniflow assignees
+	// (a user ID, "user:<id>" or "username:<name>"); unmapped users are looked up by username
+	UserMapping map[string]string `json:"user_mapping"`
+	// RoleMapping maps role names of the source platform to miniflow roles; unmapped roles are kept as is
+	RoleMapping map[string]string `json:"role_mapping"`
+}
+
+// ImportTemplateResponse represents the draft definition created from an approval template
+type ImportTemplateResponse struct {
+	Process *ProcessResponse `json:"process"`
+	// Warnings lists the parts of the template that were approximated or dropped
+	Warnings []string `json:"warnings"`
+}
+
+// TemplateFormField is a form field converted from an approval template, stored under the "form" prop of the start node
+type TemplateFormField struct {
+	Name     string                `json:"name"`
+	Label    string                `json:"label"`
+	Type     string                `json:"type"`
+	Required bool                  `json:"required,omitempty"`
+	Options  []TemplateFieldOption `json:"options,omitempty"`
+}
+
+// TemplateFieldOption is an option of a select or checkbox form field
+type TemplateFieldOption struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// templateApproval is an approval step of a template with its approvers converted to assignee specs
+type templateApproval struct {
+	Name      string
+	Mode      string
+	Approvers []string
+}
+
+// templateBuilder accumulates the nodes and flows converted from an approval template
+type templateBuilder struct {
+	name        string
+	description string
+	definition  model.ProcessDefinitionData
+	warnings    []string
+	users       map[string]string
+	roles       map[string]string
+	counters    map[string]int
+}
+
+func newTemplateBuilder(req *ImportTemplateRequest) *templateBuilder {
+	return &templateBuilder{
+		definition: model.ProcessDefinitionData{Nodes: []model.ProcessNode{}, Flows: []model.ProcessFlow{}},
+		warnings:   []string{},
+		users:      req.UserMapping,
+		roles:      req.RoleMapping,
+		counters:   make(map[string]int),
+	}
+}
+
+// ImportTemplate converts a Feishu or DingTalk approval template into a draft process definition
+func (s *ProcessService) ImportTemplate(ctx context.Context, userID uint, req *ImportTemplateRequest) (*ImportTemplateResponse, error) {
+	builder := newTemplateBuilder(req)
+
+	var err error
+	switch req.Source {
+	case TemplateSourceFeishu:
+		err = builder.convertFeishu(req.Template)
+	case TemplateSourceDingTalk:
+		err = builder.convertDingTalk(req.Template)
+	default:
+		err = fmt.Errorf("不支持的模板来源: %s", req.Source)
+	}
+	if err != nil {
+		s.logger.Warn("Approval template conversion failed", zap.String("source", req.Source), zap.Error(err))
+		return nil, fmt.Errorf("模板转换失败: %v", err)
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = builder.name
+	}
+	if name == "" {
+		return nil, errors.New("模板没有名称，请指定流程名称")
+	}
+
+	process, err := s.CreateProcess(ctx, userID, &CreateProcessRequest{
+		Key:         req.Key,
+		Name:        name,
+		Description: builder.description,
+		Category:    req.Category,
+		Definition:  builder.definition,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Approval template imported",
+		zap.String("source", req.Source),
+		zap.Uint("process_id", process.ID),
+		zap.Int("nodes", len(builder.definition.Nodes)),
+		zap.Int("warnings", len(builder.warnings)),
+	)
+	return &ImportTemplateResponse{Process: process, Warnings: builder.warnings}, nil
+}
+
+func (b *templateBuilder) warn(format string, args ...interface{}) {
+	b.warnings = append(b.warnings, fmt.Sprintf(format, args...))
+}
+
+// addNode adds a node at the given grid position and returns its generated ID
+func (b *templateBuilder) addNode(nodeType, prefix, name string, column, row int, props map[string]interface{}) string {
+	id := prefix
+	if nodeType != model.NodeTypeStart {
+		b.counters[prefix]++
+		id = fmt.Sprintf("%s_%d", prefix, b.counters[prefix])
+	}
+	b.definition.Nodes = append(b.definition.Nodes, model.ProcessNode{
+		ID:    id,
+		Type:  nodeType,
+		Name:  name,
+		X:     templateLayoutX + float64(column)*templateColumnWidth,
+		Y:     templateLayoutY + float64(row)*templateRowHeight,
+		Props: props,
+	})
+	return id
+}
+
+func (b *templateBuilder) connect(from, to, condition, label string) {
+	b.definition.Flows = append(b.definition.Flows, model.ProcessFlow{
+		ID:        fmt.Sprintf("flow_%d", len(b.definition.Flows)+1),
+		From:      from,
+		To:        to,
+		Condition: condition,
+		Label:     label,
+	})
+}
+
+// addStart adds the start node carrying the converted form
+func (b *templateBuilder) addStart(fields []TemplateFormField) string {
+	var props map[string]interface{}
+	if len(fields) > 0 {
+		props = map[string]interface{}{"form": map[string]interface{}{"fields": fields}}
+	}
+	return b.addNode(model.NodeTypeStart, "start", "发起", 0, 0, props)
+}
+
+// addApproval adds the user tasks of an approval step starting at the given row and returns
+// the first and last node IDs and the row after the step
+func (b *templateBuilder) addApproval(approval templateApproval, column, row int) (string, string, int) {
+	approvers := approval.Approvers
+	switch {
+	case len(approvers) == 0:
+		b.warn("审批节点 %q 没有可转换的审批人，任务将进入任务池等待认领", approval.Name)
+		id := b.addNode(model.NodeTypeUserTask, "approval", approval.Name, column, row, nil)
+		return id, id, row + 1
+	case len(approvers) == 1:
+		id := b.addNode(model.NodeTypeUserTask, "approval", approval.Name, column, row,
+			map[string]interface{}{"assignee": approvers[0]})
+		return id, id, row + 1
+	}
+
+	if approval.Mode == approvalModeOr {
+		if props, ok := candidateProps(approvers); ok {
+			id := b.addNode(model.NodeTypeUserTask, "approval", approval.Name, column, row, props)
+			return id, id, row + 1
+		}
+		b.warn("审批节点 %q 的或签审批人包含表达式，只保留第一个审批人 %s", approval.Name, approvers[0])
+		id := b.addNode(model.NodeTypeUserTask, "approval", approval.Name, column, row,
+			map[string]interface{}{"assignee": approvers[0]})
+		return id, id, row + 1
+	}
+
+	if ids, ok := fixedUserIDs(approvers); ok {
+		mode := model.MultiInstanceParallel
+		if approval.Mode == approvalModeSequential {
+			mode = model.MultiInstanceSequential
+		}
+		id := b.addNode(model.NodeTypeUserTask, "approval", approval.Name, column, row, map[string]interface{}{
+			"multiInstance": map[string]interface{}{
+				"mode":       mode,
+				"assignees":  ids,
+				"completion": "all",
+			},
+		})
+		return id, id, row + 1
+	}
+
+	// 会签只支持固定的用户，按角色或表达式的审批人拆分为依次审批的任务
+	if approval.Mode == approvalModeAnd {
+		b.warn("审批节点 %q 的会签审批人包含角色或表达式，转换为依次审批", approval.Name)
+	}
+	var first, last string
+	for i, approver := range approvers {
+		name := fmt.Sprintf("%s (%d/%d)", approval.Name, i+1, len(approvers))
+		id := b.addNode(model.NodeTypeUserTask, "approval", name, column, row, map[string]interface{}{"assignee": approver})
+		if first == "" {
+			first = id
+		} else {
+			b.connect(last, id, "", "")
+		}
+		last = id
+		row++
+	}
+	return first, last, row
+}
+
+// candidateProps converts or-sign approvers to candidate users and roles, failing when an approver is an expression
+func candidateProps(approvers []string) (map[string]interface{}, bool) {
+	var users, roles []interface{}
+	for _, approver := range approvers {
+		switch {
+		case strings.HasPrefix(approver, model.AssigneePrefixRole):
+			roles = append(roles, strings.TrimPrefix(approver, model.AssigneePrefixRole))
+		case strings.HasPrefix(approver, model.AssigneePrefixUser), strings.HasPrefix(approver, model.AssigneePrefixUsername):
+			users = append(users, approver)
+		default:
+			return nil, false
+		}
+	}
+	props := make(map[string]interface{})
+	if len(users) > 0 {
+		props["candidateUsers"] = users
+	}
+	if len(roles) > 0 {
+		props["candidateRoles"] = roles
+	}
+	return props, true
+}
+
+// fixedUserIDs returns the user IDs of approvers that are all fixed "user:<id>" specs
+func fixedUserIDs(approvers []string) ([]interface{}, bool) {
+	ids := make([]interface{}, 0, len(approvers))
+	for _, approver := range approvers {
+		if !strings.HasPrefix(approver, model.AssigneePrefixUser) {
+			return nil, false
+		}
+		id, err := strconv.ParseUint(strings.TrimPrefix(approver, model.AssigneePrefixUser), 10, 32)
+		if err != nil {
+			return nil, false
+		}
+		ids = append(ids, float64(id))
+	}
+	return ids, true
+}
+
+// userApprover maps a user of the source platform to an assignee spec
+func (b *templateBuilder) userApprover(externalID, name string) string {
+	externalID = strings.TrimSpace(externalID)
+	if mapped, ok := b.users[externalID]; ok && strings.TrimSpace(mapped) != "" {
+		mapped = strings.TrimSpace(mapped)
+		if _, err := strconv.ParseUint(mapped, 10, 32); err == nil {
+			return model.AssigneePrefixUser + mapped
+		}
+		return mapped
+	}
+	label := externalID
+	if name != "" {
+		label = fmt.Sprintf("%s（%s）", name, externalID)
+	}
+	b.warn("用户 %s 没有映射，按同名用户名分配", label)
+	return model.AssigneePrefixUsername + externalID
+}
+
+// roleApprover maps a role of the source platform to an assignee spec
+func (b *templateBuilder) roleApprover(role string) string {
+	role = strings.TrimSpace(role)
+	if mapped, ok := b.roles[role]; ok && strings.TrimSpace(mapped) != "" {
+		return model.AssigneePrefixRole + strings.TrimSpace(mapped)
+	}
+	b.warn("角色 %s 没有映射，按同名角色分配", role)
+	return model.AssigneePrefixRole + role
+}
+
+// supervisorApprover approximates a reporting-line approver, which miniflow has no data for
+func (b *templateBuilder) supervisorApprover(stepName string) string {
+	role := templateSupervisorRole
+	if mapped, ok := b.roles[role]; ok && strings.TrimSpace(mapped) != "" {
+		role = strings.TrimSpace(mapped)
+	}
+	b.warn("审批节点 %q 按汇报关系选择审批人，miniflow 没有汇报关系，改为按 %s 角色分配", stepName, role)
+	return model.AssigneePrefixRole + role
+}
+
+// selectedApprover maps an approver chosen by the starter to a process variable the starter fills in
+func (b *templateBuilder) selectedApprover(stepName string) string {
+	variable := fmt.Sprintf("approver_%d", b.counters["approval"]+1)
+	b.warn("审批节点 %q 由发起人自选审批人，发起流程时需要在变量 %s 中指定审批人", stepName, variable)
+	return fmt.Sprintf("${%s}", variable)
+}
+
+// starterApprover assigns the step back to the starter
+func starterApprover() string {
+	return "${starter.id}"
+}
+
+// templateFieldName turns a component ID into a process variable name usable in conditions
+func templateFieldName(id string) string {
+	var sb strings.Builder
+	for i, r := range strings.TrimSpace(id) {
+		switch {
+		case r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z':
+			sb.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				sb.WriteRune('_')
+			}
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune('_')
+		}
+	}
+	return sb.String()
+}
+
+// quoteConditionValue formats a value for a condition, leaving numbers unquoted
+func quoteConditionValue(value string) string {
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", "\\'") + "'"
+}
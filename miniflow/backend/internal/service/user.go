@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -11,19 +12,22 @@ import (
 
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 // UserService handles user business logic
 type UserService struct {
 	userRepo   *repository.UserRepository
+	tokenRepo  *repository.TokenRepository
 	jwtManager *utils.JWTManager
 	logger     *logger.Logger
 }
 
 // NewUserService creates a new user service
-func NewUserService(userRepo *repository.UserRepository, jwtManager *utils.JWTManager, logger *logger.Logger) *UserService {
+func NewUserService(userRepo *repository.UserRepository, tokenRepo *repository.TokenRepository, jwtManager *utils.JWTManager, logger *logger.Logger) *UserService {
 	return &UserService{
 		userRepo:   userRepo,
+		tokenRepo:  tokenRepo,
 		jwtManager: jwtManager,
 		logger:     logger,
 	}
@@ -54,42 +58,58 @@ type UpdateProfileRequest struct {
 
 // UserResponse represents user response data
 type UserResponse struct {
-	ID          uint       `json:"id"`
-	Username    string     `json:"username"`
-	DisplayName string     `json:"display_name"`
-	Email       string     `json:"email"`
-	Phone       string     `json:"phone"`
-	Role        string     `json:"role"`
-	Status      string     `json:"status"`
-	Avatar      string     `json:"avatar"`
-	LastLoginAt *time.Time `json:"last_login_at"`
-	CreatedAt   time.Time  `json:"created_at"`
+	ID           uint       `json:"id"`
+	Username     string     `json:"username"`
+	DisplayName  string     `json:"display_name"`
+	Email        string     `json:"email"`
+	Phone        string     `json:"phone"`
+	Role         string     `json:"role"`
+	Status       string     `json:"status"`
+	Avatar       string     `json:"avatar"`
+	DepartmentID *uint      `json:"department_id"`
+	LastLoginAt  *time.Time `json:"last_login_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// RefreshTokenRequest represents access token refresh request
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// LogoutRequest represents logout request; the access token of the request is always revoked
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+	// AllSessions revokes every refresh token of the user
+	AllSessions bool `json:"all_sessions"`
 }
 
 // LoginResponse represents login response data
 type LoginResponse struct {
-	User  *UserResponse `json:"user"`
-	Token string        `json:"token"`
+	User             *UserResponse `json:"user"`
+	Token            string        `json:"token"`
+	ExpiresAt        time.Time     `json:"expires_at"`
+	RefreshToken     string        `json:"refresh_token"`
+	RefreshExpiresAt time.Time     `json:"refresh_expires_at"`
 }
 
 // Register registers a new user
-func (s *UserService) Register(req *RegisterRequest) (*UserResponse, error) {
+func (s *UserService) Register(ctx context.Context, req *RegisterRequest) (*UserResponse, error) {
 	s.logger.Info("User registration attempt", zap.String("username", req.Username))
 
 	// Check if username already exists
-	exists, err := s.userRepo.ExistsByUsername(req.Username)
+	exists, err := s.userRepo.ExistsByUsername(ctx, req.Username)
 	if err != nil {
 		s.logger.Error("Failed to check username existence", zap.Error(err))
 		return nil, errors.New("系统错误，请稍后重试")
 	}
-	if exists {
+	if exists || req.Username == model.TombstoneUsername {
 		s.logger.Warn("Registration failed: username already exists", zap.String("username", req.Username))
 		return nil, errors.New("用户名已存在")
 	}
 
 	// Check if email already exists (if provided)
 	if req.Email != "" {
-		exists, err := s.userRepo.ExistsByEmail(req.Email)
+		exists, err := s.userRepo.ExistsByEmail(ctx, req.Email)
 		if err != nil {
 			s.logger.Error("Failed to check email existence", zap.Error(err))
 			return nil, errors.New("系统错误，请稍后重试")
@@ -118,7 +138,7 @@ func (s *UserService) Register(req *RegisterRequest) (*UserResponse, error) {
 		Status:      "active",
 	}
 
-	if err := s.userRepo.Create(user); err != nil {
+	if err := s.userRepo.Create(ctx, user); err != nil {
 		s.logger.Error("Failed to create user", zap.Error(err))
 		return nil, errors.New("创建用户失败")
 	}
@@ -132,11 +152,11 @@ func (s *UserService) Register(req *RegisterRequest) (*UserResponse, error) {
 }
 
 // Login authenticates a user and returns a JWT token
-func (s *UserService) Login(req *LoginRequest) (*LoginResponse, error) {
+func (s *UserService) Login(ctx context.Context, req *LoginRequest) (*LoginResponse, error) {
 	s.logger.Info("User login attempt", zap.String("username", req.Username))
 
 	// Get user by username
-	user, err := s.userRepo.GetByUsername(req.Username)
+	user, err := s.userRepo.GetByUsername(ctx, req.Username)
 	if err != nil {
 		s.logger.Warn("Login failed: user not found", zap.String("username", req.Username))
 		return nil, errors.New("用户名或密码错误")
@@ -149,15 +169,13 @@ func (s *UserService) Login(req *LoginRequest) (*LoginResponse, error) {
 		return nil, errors.New("用户名或密码错误")
 	}
 
-	// Generate JWT token
-	token, err := s.jwtManager.GenerateToken(user.ID, user.Username)
+	resp, _, err := s.issueTokens(ctx, user)
 	if err != nil {
-		s.logger.Error("Failed to generate JWT token", zap.Error(err))
-		return nil, errors.New("生成登录凭证失败")
+		return nil, err
 	}
 
 	// Update last login time
-	if err := s.userRepo.UpdateLastLoginTime(user.ID); err != nil {
+	if err := s.userRepo.UpdateLastLoginTime(ctx, user.ID); err != nil {
 		s.logger.Warn("Failed to update last login time", zap.Error(err))
 		// Don't fail login for this
 	}
@@ -167,15 +185,122 @@ func (s *UserService) Login(req *LoginRequest) (*LoginResponse, error) {
 		zap.String("username", user.Username),
 	)
 
+	return resp, nil
+}
+
+// RefreshToken exchanges a refresh token for a new access token and a new
+// refresh token; the presented refresh token can't be used again. Presenting
+// an already revoked refresh token revokes every session of its user, since
+// it means the token was stolen or replayed.
+func (s *UserService) RefreshToken(ctx context.Context, req *RefreshTokenRequest) (*LoginResponse, error) {
+	now := time.Now()
+	stored, err := s.tokenRepo.GetRefreshTokenByHash(ctx, utils.HashRefreshToken(req.RefreshToken))
+	if err != nil {
+		return nil, errors.New("刷新令牌无效")
+	}
+	if stored.RevokedAt != nil {
+		s.logger.Warn("Revoked refresh token presented, revoking all sessions", zap.Uint("user_id", stored.UserID))
+		if _, err := s.tokenRepo.RevokeUserRefreshTokens(ctx, stored.UserID, now); err != nil {
+			s.logger.Error("Failed to revoke refresh tokens", zap.Uint("user_id", stored.UserID), zap.Error(err))
+		}
+		return nil, errors.New("刷新令牌已失效")
+	}
+	if !stored.IsActive(now) {
+		return nil, errors.New("刷新令牌已过期")
+	}
+
+	user, err := s.userRepo.GetByID(ctx, stored.UserID)
+	if err != nil || user.Status != "active" {
+		return nil, errors.New("用户不存在或已停用")
+	}
+
+	resp, replacement, err := s.issueTokens(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	revoked, err := s.tokenRepo.RevokeRefreshToken(ctx, stored.ID, &replacement.ID, now)
+	if err != nil {
+		s.logger.Error("Failed to revoke refresh token", zap.Uint("token_id", stored.ID), zap.Error(err))
+		return nil, errors.New("刷新登录凭证失败")
+	}
+	if !revoked {
+		// 并发请求已经用这个令牌刷新过，作废刚签发的令牌
+		if _, err := s.tokenRepo.RevokeRefreshToken(ctx, replacement.ID, nil, now); err != nil {
+			s.logger.Error("Failed to revoke refresh token", zap.Uint("token_id", replacement.ID), zap.Error(err))
+		}
+		return nil, errors.New("刷新令牌已失效")
+	}
+
+	s.logger.Info("Access token refreshed", zap.Uint("user_id", user.ID))
+	return resp, nil
+}
+
+// Logout revokes the access token tokenID of the request and the refresh
+// token in req, or every refresh token of the user when req.AllSessions is set
+func (s *UserService) Logout(ctx context.Context, userID uint, tokenID string, tokenExpiresAt time.Time, req *LogoutRequest) error {
+	now := time.Now()
+	if tokenID != "" {
+		if err := s.tokenRepo.RevokeAccessToken(ctx, tokenID, userID, tokenExpiresAt); err != nil {
+			s.logger.Error("Failed to revoke access token", zap.Uint("user_id", userID), zap.Error(err))
+			return errors.New("注销登录失败")
+		}
+	}
+
+	switch {
+	case req.AllSessions:
+		if _, err := s.tokenRepo.RevokeUserRefreshTokens(ctx, userID, now); err != nil {
+			s.logger.Error("Failed to revoke refresh tokens", zap.Uint("user_id", userID), zap.Error(err))
+			return errors.New("注销登录失败")
+		}
+	case req.RefreshToken != "":
+		stored, err := s.tokenRepo.GetRefreshTokenByHash(ctx, utils.HashRefreshToken(req.RefreshToken))
+		// 不属于当前用户的令牌忽略，避免借注销作废他人的会话
+		if err == nil && stored.UserID == userID {
+			if _, err := s.tokenRepo.RevokeRefreshToken(ctx, stored.ID, nil, now); err != nil {
+				s.logger.Error("Failed to revoke refresh token", zap.Uint("token_id", stored.ID), zap.Error(err))
+				return errors.New("注销登录失败")
+			}
+		}
+	}
+
+	s.logger.Info("User logged out", zap.Uint("user_id", userID), zap.Bool("all_sessions", req.AllSessions))
+	return nil
+}
+
+// issueTokens issues an access token and a persisted refresh token for user
+func (s *UserService) issueTokens(ctx context.Context, user *model.User) (*LoginResponse, *model.RefreshToken, error) {
+	token, expiresAt, err := s.jwtManager.IssueToken(user.ID, user.Username)
+	if err != nil {
+		s.logger.Error("Failed to generate JWT token", zap.Error(err))
+		return nil, nil, errors.New("生成登录凭证失败")
+	}
+
+	refreshToken, hash, refreshExpiresAt, err := s.jwtManager.GenerateRefreshToken()
+	if err != nil {
+		s.logger.Error("Failed to generate refresh token", zap.Error(err))
+		return nil, nil, errors.New("生成登录凭证失败")
+	}
+	stored := &model.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: hash,
+		ExpiresAt: refreshExpiresAt,
+	}
+	if err := s.tokenRepo.CreateRefreshToken(ctx, stored); err != nil {
+		return nil, nil, errors.New("生成登录凭证失败")
+	}
+
 	return &LoginResponse{
-		User:  s.toUserResponse(user),
-		Token: token,
-	}, nil
+		User:             s.toUserResponse(user),
+		Token:            token,
+		ExpiresAt:        expiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpiresAt,
+	}, stored, nil
 }
 
 // GetProfile retrieves user profile by ID
-func (s *UserService) GetProfile(userID uint) (*UserResponse, error) {
-	user, err := s.userRepo.GetByID(userID)
+func (s *UserService) GetProfile(ctx context.Context, userID uint) (*UserResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		s.logger.Error("Failed to get user profile", zap.Uint("user_id", userID), zap.Error(err))
 		return nil, err
@@ -185,18 +310,18 @@ func (s *UserService) GetProfile(userID uint) (*UserResponse, error) {
 }
 
 // UpdateProfile updates user profile
-func (s *UserService) UpdateProfile(userID uint, req *UpdateProfileRequest) (*UserResponse, error) {
+func (s *UserService) UpdateProfile(ctx context.Context, userID uint, req *UpdateProfileRequest) (*UserResponse, error) {
 	s.logger.Info("Updating user profile", zap.Uint("user_id", userID))
 
 	// Get existing user
-	user, err := s.userRepo.GetByID(userID)
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Check if email is being changed and if it already exists
 	if req.Email != "" && req.Email != user.Email {
-		exists, err := s.userRepo.ExistsByEmail(req.Email)
+		exists, err := s.userRepo.ExistsByEmail(ctx, req.Email)
 		if err != nil {
 			s.logger.Error("Failed to check email existence", zap.Error(err))
 			return nil, errors.New("系统错误，请稍后重试")
@@ -221,7 +346,7 @@ func (s *UserService) UpdateProfile(userID uint, req *UpdateProfileRequest) (*Us
 	}
 
 	// Save changes
-	if err := s.userRepo.Update(user); err != nil {
+	if err := s.userRepo.Update(ctx, user); err != nil {
 		s.logger.Error("Failed to update user profile", zap.Error(err))
 		return nil, errors.New("更新用户资料失败")
 	}
@@ -232,7 +357,7 @@ func (s *UserService) UpdateProfile(userID uint, req *UpdateProfileRequest) (*Us
 }
 
 // GetUsers retrieves users with pagination
-func (s *UserService) GetUsers(page, pageSize int) ([]*UserResponse, int64, error) {
+func (s *UserService) GetUsers(ctx context.Context, page, pageSize int) ([]*UserResponse, int64, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -241,7 +366,7 @@ func (s *UserService) GetUsers(page, pageSize int) ([]*UserResponse, int64, erro
 	}
 
 	offset := (page - 1) * pageSize
-	users, total, err := s.userRepo.List(offset, pageSize)
+	users, total, err := s.userRepo.List(ctx, offset, pageSize)
 	if err != nil {
 		s.logger.Error("Failed to get users list", zap.Error(err))
 		return nil, 0, err
@@ -256,11 +381,11 @@ func (s *UserService) GetUsers(page, pageSize int) ([]*UserResponse, int64, erro
 }
 
 // ChangePassword changes user password
-func (s *UserService) ChangePassword(userID uint, oldPassword, newPassword string) error {
+func (s *UserService) ChangePassword(ctx context.Context, userID uint, oldPassword, newPassword string) error {
 	s.logger.Info("Changing user password", zap.Uint("user_id", userID))
 
 	// Get user
-	user, err := s.userRepo.GetByID(userID)
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		return err
 	}
@@ -281,7 +406,7 @@ func (s *UserService) ChangePassword(userID uint, oldPassword, newPassword strin
 
 	// Update password
 	user.Password = string(hashedPassword)
-	if err := s.userRepo.Update(user); err != nil {
+	if err := s.userRepo.Update(ctx, user); err != nil {
 		s.logger.Error("Failed to update password", zap.Error(err))
 		return errors.New("密码更新失败")
 	}
@@ -291,43 +416,95 @@ func (s *UserService) ChangePassword(userID uint, oldPassword, newPassword strin
 }
 
 // DeactivateUser deactivates a user
-func (s *UserService) DeactivateUser(userID uint) error {
+func (s *UserService) DeactivateUser(ctx context.Context, userID uint) error {
 	s.logger.Info("Deactivating user", zap.Uint("user_id", userID))
 
-	user, err := s.userRepo.GetByID(userID)
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		return err
 	}
 
 	user.Status = "inactive"
-	if err := s.userRepo.Update(user); err != nil {
+	if err := s.userRepo.Update(ctx, user); err != nil {
 		s.logger.Error("Failed to deactivate user", zap.Error(err))
 		return errors.New("停用用户失败")
 	}
+	if _, err := s.tokenRepo.RevokeUserRefreshTokens(ctx, userID, time.Now()); err != nil {
+		s.logger.Error("Failed to revoke refresh tokens of deactivated user", zap.Uint("user_id", userID), zap.Error(err))
+	}
 
 	s.logger.Info("User deactivated successfully", zap.Uint("user_id", userID))
 	return nil
 }
 
+// EraseUserRequest represents the request to erase a user's personal data
+type EraseUserRequest struct {
+	Reason string `json:"reason" validate:"max=500"`
+}
+
+// Errors returned by EraseUser
+var (
+	ErrUserNotFound     = errors.New("用户不存在")
+	ErrEraseSelf        = errors.New("不能删除自己的账户")
+	ErrUserHasOpenTasks = repository.ErrUserHasOpenTasks
+	ErrTombstoneUser    = repository.ErrTombstoneUser
+)
+
+// EraseUser hard-deletes a user and their personal data for compliance.
+// Tokens, notifications, preferences, group memberships and saved filters are
+// deleted; instances, tasks, comments and other history the user took part in
+// are kept and handed over to the shared tombstone account. The user must not
+// have open tasks, so nothing is left waiting on an account that cannot act.
+// Comment bodies, form data and process variables are kept as written, since
+// they belong to the process record; references in archived instances are
+// rewritten separately by InstanceArchiver.ReassignArchivedUser.
+func (s *UserService) EraseUser(ctx context.Context, userID, erasedBy uint, reason string) (*model.UserErasure, error) {
+	if userID == erasedBy {
+		return nil, ErrEraseSelf
+	}
+	s.logger.Info("Erasing user", zap.Uint("user_id", userID), zap.Uint("erased_by", erasedBy))
+
+	erasure, err := s.userRepo.EraseUser(ctx, userID, erasedBy, reason)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	s.logger.Info("User erased",
+		zap.Uint("user_id", userID),
+		zap.Uint("erased_by", erasedBy),
+		zap.String("digest", erasure.Digest),
+		zap.String("rows", erasure.Rows),
+	)
+	return erasure, nil
+}
+
+// GetUserErasures returns the erasure records of a user
+func (s *UserService) GetUserErasures(ctx context.Context, userID uint) ([]model.UserErasure, error) {
+	return s.userRepo.GetUserErasures(ctx, userID)
+}
+
 // GetUserStats returns user statistics
-func (s *UserService) GetUserStats() (map[string]int64, error) {
+func (s *UserService) GetUserStats(ctx context.Context) (map[string]int64, error) {
 	stats := make(map[string]int64)
 
 	// Count total active users
-	activeUsers, err := s.userRepo.CountByRole("")
+	activeUsers, err := s.userRepo.CountByRole(ctx, "")
 	if err != nil {
 		return nil, err
 	}
 	stats["total_active"] = activeUsers
 
 	// Count by roles
-	adminCount, err := s.userRepo.CountByRole("admin")
+	adminCount, err := s.userRepo.CountByRole(ctx, "admin")
 	if err != nil {
 		return nil, err
 	}
 	stats["admin_count"] = adminCount
 
-	userCount, err := s.userRepo.CountByRole("user")
+	userCount, err := s.userRepo.CountByRole(ctx, "user")
 	if err != nil {
 		return nil, err
 	}
@@ -339,15 +516,16 @@ func (s *UserService) GetUserStats() (map[string]int64, error) {
 // toUserResponse converts User model to UserResponse
 func (s *UserService) toUserResponse(user *model.User) *UserResponse {
 	return &UserResponse{
-		ID:          user.ID,
-		Username:    user.Username,
-		DisplayName: user.DisplayName,
-		Email:       user.Email,
-		Phone:       user.Phone,
-		Role:        user.Role,
-		Status:      user.Status,
-		Avatar:      user.Avatar,
-		LastLoginAt: user.LastLoginAt,
-		CreatedAt:   user.CreatedAt,
+		ID:           user.ID,
+		Username:     user.Username,
+		DisplayName:  user.DisplayName,
+		Email:        user.Email,
+		Phone:        user.Phone,
+		Role:         user.Role,
+		Status:       user.Status,
+		Avatar:       user.Avatar,
+		DepartmentID: user.DepartmentID,
+		LastLoginAt:  user.LastLoginAt,
+		CreatedAt:    user.CreatedAt,
 	}
 }
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"miniflow/internal/model"
+
+	"go.uber.org/zap"
+)
+
+// User reference problems
+const (
+	UserReferenceInactive = "inactive" // the user is deactivated
+	UserReferenceMissing  = "missing"  // the user was deleted or never existed
+)
+
+// UserReferenceIssue represents a definition reference to a user who can no longer take work
+type UserReferenceIssue struct {
+	model.UserReference
+	Problem string `json:"problem"`
+}
+
+// DefinitionReferenceReport lists the problematic user references of a process definition
+type DefinitionReferenceReport struct {
+	ProcessID uint                 `json:"process_id"`
+	Key       string               `json:"key"`
+	Name      string               `json:"name"`
+	Version   int                  `json:"version"`
+	Status    string               `json:"status"`
+	Issues    []UserReferenceIssue `json:"issues"`
+}
+
+// UserReferenceMapping replaces references to one user with another user.
+// Only users are supported as replacement targets; there are no user groups yet.
+type UserReferenceMapping struct {
+	FromUserID uint `json:"from_user_id" validate:"required"`
+	ToUserID   uint `json:"to_user_id" validate:"required"`
+}
+
+// RemapUserReferencesRequest represents a bulk user reference remap request
+type RemapUserReferencesRequest struct {
+	Mappings []UserReferenceMapping `json:"mappings" validate:"required,min=1,dive"`
+	// ProcessIDs limits the remap to these definitions; empty means all draft and published definitions
+	ProcessIDs []uint `json:"process_ids"`
+}
+
+// RemappedDefinition lists the references replaced in a process definition
+type RemappedDefinition struct {
+	ProcessID uint                  `json:"process_id"`
+	Key       string                `json:"key"`
+	Version   int                   `json:"version"`
+	Replaced  []model.UserReference `json:"replaced"`
+}
+
+// ScanUserReferences reports draft and published definitions that reference inactive or
+// missing users. When userID is non-zero only references to that user are reported.
+func (s *ProcessService) ScanUserReferences(ctx context.Context, userID uint) ([]DefinitionReferenceReport, error) {
+	processes, err := s.processRepo.GetUnarchivedProcesses(ctx)
+	if err != nil {
+		s.logger.Error("Failed to list process definitions", zap.Error(err))
+		return nil, errors.New("获取流程定义失败")
+	}
+
+	reports := []DefinitionReferenceReport{}
+	for _, process := range processes {
+		issues, err := s.checkUserReferences(ctx, process)
+		if err != nil {
+			s.logger.Warn("Failed to check user references",
+				zap.Uint("process_id", process.ID),
+				zap.Error(err),
+			)
+			continue
+		}
+		if userID != 0 {
+			issues = filterIssuesByUser(issues, userID)
+		}
+		if len(issues) == 0 {
+			continue
+		}
+		reports = append(reports, DefinitionReferenceReport{
+			ProcessID: process.ID,
+			Key:       process.Key,
+			Name:      process.Name,
+			Version:   process.Version,
+			Status:    process.Status,
+			Issues:    issues,
+		})
+	}
+	return reports, nil
+}
+
+// RemapUserReferences replaces user references in process definitions in bulk
+func (s *ProcessService) RemapUserReferences(ctx context.Context, req *RemapUserReferencesRequest) ([]RemappedDefinition, error) {
+	remaps, err := s.loadUserRemaps(ctx, req.Mappings)
+	if err != nil {
+		return nil, err
+	}
+
+	var processes []*model.ProcessDefinition
+	if len(req.ProcessIDs) == 0 {
+		if processes, err = s.processRepo.GetUnarchivedProcesses(ctx); err != nil {
+			s.logger.Error("Failed to list process definitions", zap.Error(err))
+			return nil, errors.New("获取流程定义失败")
+		}
+	} else {
+		for _, id := range req.ProcessIDs {
+			process, err := s.processRepo.GetByID(ctx, id)
+			if err != nil {
+				return nil, fmt.Errorf("流程定义 %d 不存在", id)
+			}
+			processes = append(processes, process)
+		}
+	}
+
+	results := []RemappedDefinition{}
+	for _, process := range processes {
+		definitionData, err := process.GetDefinitionData()
+		if err != nil {
+			return nil, fmt.Errorf("流程定义 %d 格式错误", process.ID)
+		}
+
+		replaced := definitionData.RemapUserReferences(remaps)
+		for _, remap := range remaps {
+			if process.CreatedBy == remap.From.ID {
+				replaced = append(replaced, model.UserReference{Field: model.UserRefCreatedBy, UserID: process.CreatedBy})
+				process.CreatedBy = remap.To.ID
+				process.Creator = remap.To
+				break
+			}
+		}
+		if len(replaced) == 0 {
+			continue
+		}
+
+		if err := process.SetDefinitionData(definitionData); err != nil {
+			return nil, errors.New("流程定义格式错误")
+		}
+		if err := s.processRepo.Update(ctx, process); err != nil {
+			s.logger.Error("Failed to remap user references", zap.Uint("process_id", process.ID), zap.Error(err))
+			return nil, fmt.Errorf("更新流程定义 %d 失败", process.ID)
+		}
+
+		s.logger.Info("User references remapped",
+			zap.Uint("process_id", process.ID),
+			zap.Int("replaced", len(replaced)),
+		)
+		results = append(results, RemappedDefinition{
+			ProcessID: process.ID,
+			Key:       process.Key,
+			Version:   process.Version,
+			Replaced:  replaced,
+		})
+	}
+	return results, nil
+}
+
+// loadUserRemaps resolves the mappings; replacement users must exist and be active
+func (s *ProcessService) loadUserRemaps(ctx context.Context, mappings []UserReferenceMapping) ([]model.UserRemap, error) {
+	ids := make([]uint, 0, len(mappings)*2)
+	for _, mapping := range mappings {
+		if mapping.FromUserID == mapping.ToUserID {
+			return nil, fmt.Errorf("用户 %d 不能替换为自己", mapping.FromUserID)
+		}
+		ids = append(ids, mapping.FromUserID, mapping.ToUserID)
+	}
+
+	users, err := s.userRepo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, errors.New("获取用户失败")
+	}
+	byID := make(map[uint]model.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+
+	remaps := make([]model.UserRemap, 0, len(mappings))
+	for _, mapping := range mappings {
+		from, ok := byID[mapping.FromUserID]
+		if !ok {
+			// 从未存在的用户只能通过ID引用
+			from = model.User{BaseModel: model.BaseModel{ID: mapping.FromUserID}}
+		}
+		to, ok := byID[mapping.ToUserID]
+		if !ok || to.DeletedAt.Valid {
+			return nil, fmt.Errorf("替换用户 %d 不存在", mapping.ToUserID)
+		}
+		if to.Status != "active" {
+			return nil, fmt.Errorf("替换用户 %d 未激活", mapping.ToUserID)
+		}
+		remaps = append(remaps, model.UserRemap{From: from, To: to})
+	}
+	return remaps, nil
+}
+
+// checkUserReferences returns the references of a definition to inactive or missing users
+func (s *ProcessService) checkUserReferences(ctx context.Context, process *model.ProcessDefinition) ([]UserReferenceIssue, error) {
+	definitionData, err := process.GetDefinitionData()
+	if err != nil {
+		return nil, err
+	}
+	refs := append([]model.UserReference{{Field: model.UserRefCreatedBy, UserID: process.CreatedBy}},
+		definitionData.UserReferences()...)
+
+	var ids []uint
+	var usernames []string
+	for _, ref := range refs {
+		if ref.UserID != 0 {
+			ids = append(ids, ref.UserID)
+		} else {
+			usernames = append(usernames, ref.Username)
+		}
+	}
+	byID := make(map[uint]model.User)
+	byName := make(map[string]model.User)
+	users, err := s.userRepo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+	if users, err = s.userRepo.FindByUsernames(ctx, usernames); err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		byName[user.Username] = user
+	}
+
+	var issues []UserReferenceIssue
+	for _, ref := range refs {
+		user, ok := byID[ref.UserID]
+		if ref.UserID == 0 {
+			user, ok = byName[ref.Username]
+		}
+		switch {
+		case !ok || user.DeletedAt.Valid:
+			issues = append(issues, UserReferenceIssue{UserReference: ref, Problem: UserReferenceMissing})
+		case user.Status != "active":
+			if ref.UserID == 0 {
+				ref.UserID = user.ID
+			}
+			issues = append(issues, UserReferenceIssue{UserReference: ref, Problem: UserReferenceInactive})
+		}
+	}
+	return issues, nil
+}
+
+// formatReferenceIssues describes the issues for publish errors
+func formatReferenceIssues(issues []UserReferenceIssue) string {
+	parts := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		user := issue.Username
+		if user == "" {
+			user = fmt.Sprintf("%d", issue.UserID)
+		}
+		problem := "不存在"
+		if issue.Problem == UserReferenceInactive {
+			problem = "已停用"
+		}
+		if issue.NodeID == "" {
+			parts = append(parts, fmt.Sprintf("%s 用户 %s %s", issue.Field, user, problem))
+		} else {
+			parts = append(parts, fmt.Sprintf("节点 '%s' 的 %s 用户 %s %s", issue.NodeName, issue.Field, user, problem))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// filterIssuesByUser keeps the issues referencing the given user
+func filterIssuesByUser(issues []UserReferenceIssue, userID uint) []UserReferenceIssue {
+	var filtered []UserReferenceIssue
+	for _, issue := range issues {
+		if issue.UserID == userID {
+			filtered = append(filtered, issue)
+		}
+	}
+	return filtered
+}
@@ -7,6 +7,7 @@ import (
 	"miniflow/internal/engine"
 	"miniflow/internal/handler"
 	"miniflow/internal/middleware"
+	"miniflow/internal/notification"
 	"miniflow/internal/repository"
 	"miniflow/internal/server"
 	"miniflow/internal/service"
@@ -31,6 +32,19 @@ var ProviderSet = wire.NewSet(
 	ProvideLoggerConfig,
 	ProvideDatabaseConfig,
 	ProvideJWTConfig,
+	ProvideNotificationConfig,
+	ProvideConnectorConfig,
+	ProvideEscalationConfig,
+	ProvideReminderConfig,
+	ProvideRedisConfig,
+	ProvideVariablesConfig,
+	ProvideQueueConfig,
+	ProvideScriptConfig,
+	ProvideRecycleBinConfig,
+	ProvideJobExecutorConfig,
+	ProvideRBACConfig,
+	ProvideAttachmentConfig,
+	ProvideArchiveConfig,
 
 	// Infrastructure providers
 	ProvideLogger,
@@ -42,22 +56,80 @@ var ProviderSet = wire.NewSet(
 	repository.NewProcessRepository,
 	repository.NewTaskRepository,
 	repository.NewProcessInstanceRepository,
+	repository.NewNotificationRepository,
+	repository.NewAnnouncementRepository,
+	repository.NewConnectorPolicyRepository,
+	repository.NewComplexityBudgetRepository,
+	repository.NewIncidentRepository,
+	repository.NewReportingRepository,
+	repository.NewKPIRepository,
+	repository.NewCapacityRepository,
+	repository.NewDeploymentRepository,
+	repository.NewDuplicateRepository,
+	repository.NewExecutionLogRepository,
+	repository.NewIdempotencyRepository,
+	repository.NewJobRepository,
+	repository.NewWebhookSubscriptionRepository,
+	repository.NewOrganizationRepository,
+	repository.NewTokenRepository,
+	repository.NewAttachmentRepository,
+
+	// Notification providers
+	notification.NewRenderer,
+	notification.NewDispatcher,
+	ProvideMailSender,
 
 	// Engine providers (新增)
+	engine.NewEventSystem,
+	engine.NewVariableStore,
 	engine.NewProcessEngine,
 	engine.NewTaskAssignmentManager,
+	engine.NewTimerScheduler,
+	engine.NewJobExecutor,
+	engine.NewRecovery,
+	engine.NewOverdueScheduler,
+	engine.NewWebhookDispatcher,
+	engine.NewEventNotifier,
+	engine.NewJobDashboard,
+	engine.NewTaskQueue,
+	engine.NewRecycleBin,
+	engine.NewAttachmentManager,
+	engine.NewInstanceArchiver,
 
 	// Service providers
 	service.NewUserService,
 	service.NewProcessService,
+	service.NewNotificationService,
+	service.NewAnnouncementService,
+	service.NewConnectorPolicyService,
+	service.NewReportingService,
+	service.NewClaimExpiryService,
+	service.NewTaskReminderService,
+	service.NewKPIService,
+	service.NewCapacityService,
+	service.NewDeploymentService,
+	service.NewSelfTestService,
+	service.NewOrganizationService,
 
 	// Handler providers
 	handler.NewProcessExecutionHandler,
 	handler.NewTaskManagementHandler,
+	handler.NewIntegrationHandler,
+	handler.NewIncidentHandler,
+	handler.NewJobHandler,
+	handler.NewWebhookHandler,
+	handler.NewPublicStatusHandler,
+	handler.NewQueueHandler,
+	handler.NewRecycleBinHandler,
+	handler.NewExternalTaskHandler,
+	handler.NewMessageHandler,
+	handler.NewAttachmentHandler,
+	handler.NewArchiveHandler,
 	handler.NewRouter,
 
 	// Middleware providers
 	middleware.NewAuthMiddleware,
+	middleware.NewIdempotencyMiddleware,
 
 	// Server provider
 	server.NewServer,
@@ -87,6 +159,76 @@ func ProvideJWTConfig(cfg *config.Config) *config.JWTConfig {
 	return &cfg.JWT
 }
 
+// ProvideNotificationConfig provides notification configuration
+func ProvideNotificationConfig(cfg *config.Config) *config.NotificationConfig {
+	return &cfg.Notification
+}
+
+// ProvideConnectorConfig provides connector configuration
+func ProvideConnectorConfig(cfg *config.Config) *config.ConnectorConfig {
+	return &cfg.Connector
+}
+
+// ProvideEscalationConfig provides overdue task escalation configuration
+func ProvideEscalationConfig(cfg *config.Config) *config.EscalationConfig {
+	return &cfg.Escalation
+}
+
+// ProvideReminderConfig provides task due date reminder configuration
+func ProvideReminderConfig(cfg *config.Config) *config.ReminderConfig {
+	return &cfg.Reminder
+}
+
+// ProvideMailSender provides the email channel of the notification dispatcher to mail task nodes
+func ProvideMailSender(dispatcher *notification.Dispatcher) engine.MailSender {
+	return dispatcher
+}
+
+// ProvideRBACConfig provides the role-based access control permission matrix
+func ProvideRBACConfig(cfg *config.Config) *config.RBACConfig {
+	return &cfg.RBAC
+}
+
+// ProvideRedisConfig provides Redis configuration
+func ProvideRedisConfig(cfg *config.Config) *config.RedisConfig {
+	return &cfg.Redis
+}
+
+// ProvideVariablesConfig provides process variable store configuration
+func ProvideVariablesConfig(cfg *config.Config) *config.VariablesConfig {
+	return &cfg.Variables
+}
+
+// ProvideQueueConfig provides group task queue configuration
+func ProvideQueueConfig(cfg *config.Config) *config.QueueConfig {
+	return &cfg.Queue
+}
+
+// ProvideScriptConfig provides script task configuration
+func ProvideScriptConfig(cfg *config.Config) *config.ScriptConfig {
+	return &cfg.Script
+}
+
+// ProvideRecycleBinConfig provides cancelled instance recycle bin configuration
+func ProvideRecycleBinConfig(cfg *config.Config) *config.RecycleBinConfig {
+	return &cfg.RecycleBin
+}
+
+// ProvideJobExecutorConfig provides async job executor configuration
+func ProvideJobExecutorConfig(cfg *config.Config) *config.JobExecutorConfig {
+	return &cfg.JobExecutor
+}
+
+// ProvideAttachmentConfig provides attachment storage and upload limit configuration
+func ProvideAttachmentConfig(cfg *config.Config) *config.AttachmentConfig {
+	return &cfg.Attachment
+}
+
+// ProvideArchiveConfig provides ended instance archival configuration
+func ProvideArchiveConfig(cfg *config.Config) *config.ArchiveConfig {
+	return &cfg.Archive
+}
+
 // InitializeServer initializes the server with all dependencies
 func InitializeServer(cfg *config.Config) (*server.Server, error) {
 	wire.Build(ProviderSet)
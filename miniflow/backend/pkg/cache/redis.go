@@ -0,0 +1,219 @@
+// Package cache provides a minimal Redis client for read-through caches.
+//
+// Only the commands the engine needs are supported (PING, GET, SET with
+// expiry, DEL). Connections are pooled and each command is a single round trip.
+package cache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"miniflow/pkg/config"
+)
+
+const (
+	// redisTimeout bounds connecting and each command round trip
+	redisTimeout = 3 * time.Second
+	// redisMaxIdle is the number of idle connections kept for reuse
+	redisMaxIdle = 8
+)
+
+// ErrNil is returned by Get when the key does not exist
+var ErrNil = errors.New("redis: nil")
+
+// RedisClient is a pooled Redis client speaking the RESP protocol
+type RedisClient struct {
+	cfg  *config.RedisConfig
+	idle chan *redisConn
+}
+
+// redisConn is a single authenticated connection with its reader
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewRedisClient creates a Redis client; connections are opened on first use
+func NewRedisClient(cfg *config.RedisConfig) *RedisClient {
+	return &RedisClient{
+		cfg:  cfg,
+		idle: make(chan *redisConn, redisMaxIdle),
+	}
+}
+
+// Ping checks that the server is reachable and the credentials are accepted
+func (c *RedisClient) Ping() error {
+	reply, err := c.do("PING")
+	if err != nil {
+		return err
+	}
+	if reply != "PONG" {
+		return fmt.Errorf("redis: unexpected PING reply %q", reply)
+	}
+	return nil
+}
+
+// Get returns the value of key, or ErrNil if it does not exist
+func (c *RedisClient) Get(key string) ([]byte, error) {
+	reply, err := c.do("GET", key)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := reply.(string)
+	if !ok {
+		return nil, ErrNil
+	}
+	return []byte(value), nil
+}
+
+// Set stores value under key, expiring after ttl when ttl is positive
+func (c *RedisClient) Set(key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	_, err := c.do(args...)
+	return err
+}
+
+// Del removes the keys
+func (c *RedisClient) Del(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	_, err := c.do(append([]string{"DEL"}, keys...)...)
+	return err
+}
+
+// Close closes the idle connections
+func (c *RedisClient) Close() error {
+	for {
+		select {
+		case rc := <-c.idle:
+			rc.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// do sends a command and returns its reply: a string for simple and bulk
+// strings, an int64 for integers and nil for a nil bulk string
+func (c *RedisClient) do(args ...string) (interface{}, error) {
+	rc, err := c.get()
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := rc.command(args...)
+	var serverErr redisError
+	if err != nil && !errors.As(err, &serverErr) {
+		// The connection state is unknown after a network or protocol error; drop it
+		rc.conn.Close()
+		return nil, err
+	}
+	c.put(rc)
+	return reply, err
+}
+
+// get takes an idle connection or opens a new one
+func (c *RedisClient) get() (*redisConn, error) {
+	select {
+	case rc := <-c.idle:
+		return rc, nil
+	default:
+	}
+
+	conn, err := net.DialTimeout("tcp", c.cfg.GetRedisAddr(), redisTimeout)
+	if err != nil {
+		return nil, err
+	}
+	rc := &redisConn{conn: conn, reader: bufio.NewReader(conn)}
+	if c.cfg.Password != "" {
+		if _, err := rc.command("AUTH", c.cfg.Password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if c.cfg.DB != 0 {
+		if _, err := rc.command("SELECT", strconv.Itoa(c.cfg.DB)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return rc, nil
+}
+
+// put returns a connection to the pool, closing it when the pool is full
+func (c *RedisClient) put(rc *redisConn) {
+	select {
+	case c.idle <- rc:
+	default:
+		rc.conn.Close()
+	}
+}
+
+// redisError is an error reply from the server; the connection stays usable
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// command writes a command as a RESP array and reads one reply
+func (rc *redisConn) command(args ...string) (interface{}, error) {
+	if err := rc.conn.SetDeadline(time.Now().Add(redisTimeout)); err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := rc.conn.Write([]byte(b.String())); err != nil {
+		return nil, err
+	}
+	return rc.readReply()
+}
+
+// readReply reads a simple string, error, integer or bulk string reply
+func (rc *redisConn) readReply() (interface{}, error) {
+	line, err := rc.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		length, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid bulk length %q", line)
+		}
+		if length < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, length+2)
+		if _, err := io.ReadFull(rc.reader, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:length]), nil
+	}
+	return nil, fmt.Errorf("redis: unsupported reply %q", line)
+}
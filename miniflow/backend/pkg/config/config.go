@@ -1,19 +1,33 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Redis    RedisConfig    `mapstructure:"redis"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	Log      LogConfig      `mapstructure:"log"`
+	Server       ServerConfig       `mapstructure:"server"`
+	Database     DatabaseConfig     `mapstructure:"database"`
+	Redis        RedisConfig        `mapstructure:"redis"`
+	JWT          JWTConfig          `mapstructure:"jwt"`
+	Log          LogConfig          `mapstructure:"log"`
+	Notification NotificationConfig `mapstructure:"notification"`
+	Connector    ConnectorConfig    `mapstructure:"connector"`
+	Escalation   EscalationConfig   `mapstructure:"escalation"`
+	Reminder     ReminderConfig     `mapstructure:"reminder"`
+	Variables    VariablesConfig    `mapstructure:"variables"`
+	Queue        QueueConfig        `mapstructure:"queue"`
+	Script       ScriptConfig       `mapstructure:"script"`
+	RecycleBin   RecycleBinConfig   `mapstructure:"recycle_bin"`
+	Archive      ArchiveConfig      `mapstructure:"archive"`
+	JobExecutor  JobExecutorConfig  `mapstructure:"job_executor"`
+	RBAC         RBACConfig         `mapstructure:"rbac"`
+	Attachment   AttachmentConfig   `mapstructure:"attachment"`
 }
 
 type ServerConfig struct {
@@ -32,6 +46,7 @@ type DatabaseConfig struct {
 	Charset         string `mapstructure:"charset"`
 	ParseTime       bool   `mapstructure:"parse_time"`
 	Loc             string `mapstructure:"loc"`
+	SSLMode         string `mapstructure:"sslmode"`
 	MaxIdleConns    int    `mapstructure:"max_idle_conns"`
 	MaxOpenConns    int    `mapstructure:"max_open_conns"`
 	ConnMaxLifetime int    `mapstructure:"conn_max_lifetime"`
@@ -45,8 +60,9 @@ type RedisConfig struct {
 }
 
 type JWTConfig struct {
-	Secret       string `mapstructure:"secret"`
-	ExpiresHours int    `mapstructure:"expires_hours"`
+	Secret              string `mapstructure:"secret"`
+	ExpiresHours        int    `mapstructure:"expires_hours"`
+	RefreshExpiresHours int    `mapstructure:"refresh_expires_hours"`
 }
 
 type LogConfig struct {
@@ -55,9 +71,210 @@ type LogConfig struct {
 	Output string `mapstructure:"output"`
 }
 
+type NotificationConfig struct {
+	Email                EmailConfig `mapstructure:"email"`
+	Chat                 ChatConfig  `mapstructure:"chat"`
+	CriticalEvents       []string    `mapstructure:"critical_events"`
+	CriticalChannels     []string    `mapstructure:"critical_channels"`
+	DefaultLocale        string      `mapstructure:"default_locale"`
+	DigestHour           int         `mapstructure:"digest_hour"`
+	FlushIntervalSeconds int         `mapstructure:"flush_interval_seconds"`
+}
+
+type EmailConfig struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	SMTPHost string `mapstructure:"smtp_host"`
+	SMTPPort int    `mapstructure:"smtp_port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
+}
+
+type ChatConfig struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	WebhookURL string `mapstructure:"webhook_url"`
+}
+
+type ConnectorConfig struct {
+	Mock ConnectorMockConfig `mapstructure:"mock"`
+}
+
+// ConnectorMockConfig replaces outbound service task calls with stubbed or
+// recorded responses so process paths can be exercised without real downstream
+// systems. Connectors lists the connector types or topics to mock; when empty
+// every outbound call is mocked. Stubs are keyed by topic, connector type or
+// "default".
+type ConnectorMockConfig struct {
+	Enabled    bool                     `mapstructure:"enabled"`
+	Connectors []string                 `mapstructure:"connectors"`
+	Replay     bool                     `mapstructure:"replay"`
+	Stubs      map[string]ConnectorStub `mapstructure:"stubs"`
+}
+
+type ConnectorStub struct {
+	StatusCode int    `mapstructure:"status_code"`
+	Body       string `mapstructure:"body"`
+	DelayMs    int    `mapstructure:"delay_ms"`
+}
+
+// EscalationConfig controls the background scan of overdue tasks. Each time a
+// task misses its due date it is escalated once: its escalation level is bumped
+// and it is reassigned to the least loaded active user of Role. A non-empty
+// Group takes precedence over Role: the task goes back to the pool, only the
+// active members of the group may claim it, and they are notified. Empty Role
+// and Group only mark the task escalated and keep the current assignee.
+type EscalationConfig struct {
+	IntervalSeconds int    `mapstructure:"interval_seconds"`
+	Role            string `mapstructure:"role"`
+	Group           string `mapstructure:"group"`
+}
+
+// ReminderConfig controls reminders sent to the assignee before a task is due.
+// Offsets lists how long before the due date to remind, as durations such as
+// "24h" and "1h"; an empty list disables reminders. Each offset is sent at
+// most once per due date, and tasks that have been claimed or completed are
+// not reminded.
+type ReminderConfig struct {
+	IntervalSeconds int      `mapstructure:"interval_seconds"`
+	Offsets         []string `mapstructure:"offsets"`
+}
+
+// VariablesConfig selects where process variables are read from. "db" reads
+// the instance row on every access; "redis" caches each instance's variables
+// in Redis for CacheTTLSeconds and invalidates the entry on every write.
+type VariablesConfig struct {
+	Store           string `mapstructure:"store"`
+	CacheTTLSeconds int    `mapstructure:"cache_ttl_seconds"`
+}
+
+// QueueConfig controls fair-share dispatch from group task queues. A member's
+// share is their open tasks plus the tasks they completed in the last
+// FairShareWindowHours; members above the group's fair share get no new work.
+type QueueConfig struct {
+	FairShareWindowHours int `mapstructure:"fair_share_window_hours"`
+}
+
+// ScriptConfig limits how long script task nodes may run. A node without its
+// own timeoutSeconds prop gets TimeoutSeconds; node timeouts are capped at
+// MaxTimeoutSeconds.
+type ScriptConfig struct {
+	TimeoutSeconds    int `mapstructure:"timeout_seconds"`
+	MaxTimeoutSeconds int `mapstructure:"max_timeout_seconds"`
+}
+
+// RecycleBinConfig controls how long cancelled instances stay in the recycle
+// bin. Within UndoWindowHours of the cancellation an admin can undo it,
+// restoring the previous status and reopening the tasks it skipped.
+type RecycleBinConfig struct {
+	UndoWindowHours int `mapstructure:"undo_window_hours"`
+}
+
+// ArchiveConfig controls moving ended instances out of the hot tables. Every
+// IntervalSeconds up to BatchSize top-level instances that ended more than
+// RetentionDays ago are exported with their sub-instances as JSON documents to
+// the attachment storage backend and deleted from the database; zero
+// RetentionDays disables archival.
+type ArchiveConfig struct {
+	RetentionDays   int `mapstructure:"retention_days"`
+	IntervalSeconds int `mapstructure:"interval_seconds"`
+	BatchSize       int `mapstructure:"batch_size"`
+}
+
+// JobExecutorConfig sizes the background executor of async service tasks.
+// Workers jobs run concurrently; the executor polls for due jobs every
+// PollIntervalSeconds and holds each job's lock for LockTimeoutSeconds.
+type JobExecutorConfig struct {
+	Workers             int `mapstructure:"workers"`
+	PollIntervalSeconds int `mapstructure:"poll_interval_seconds"`
+	LockTimeoutSeconds  int `mapstructure:"lock_timeout_seconds"`
+}
+
+// Permissions checked by the RBAC middleware and the engine
+const (
+	PermissionAdmin            = "admin"
+	PermissionTaskStatus       = "task_status"
+	PermissionInstanceSuspend  = "instance_suspend"
+	PermissionInstanceCancel   = "instance_cancel"
+	PermissionDeployPromote    = "deployment_promote"
+	PermissionKPIManage        = "kpi_manage"
+	PermissionExternalTask     = "external_task"
+	PermissionMessageCorrelate = "message_correlate"
+	PermissionQueueNext        = "queue_next"
+	PermissionInstanceTrace    = "instance_trace"
+	PermissionInstanceMigrate  = "instance_migrate"
+	PermissionWebhookManage    = "webhook_manage"
+	PermissionArchiveView      = "archive_view"
+	PermissionContentModerate  = "content_moderate"
+	PermissionProcessMetadata  = "process_metadata"
+	PermissionDeployCreate     = "deployment_create"
+	PermissionDeployImport     = "deployment_import"
+	PermissionDeployExport     = "deployment_export"
+	PermissionCapacityView     = "capacity_view"
+)
+
+// RBACConfig is the permission matrix: Permissions maps each permission to the
+// user roles granted it. PermissionAdmin guards the /admin API,
+// PermissionTaskStatus the task lists by status, PermissionInstanceSuspend
+// suspending and resuming instances and PermissionInstanceCancel cancelling
+// them. The starter of an instance may always suspend, resume or cancel it.
+// PermissionDeployPromote guards promoting deployments between environments,
+// PermissionKPIManage creating, editing and deleting process KPIs,
+// PermissionExternalTask the external task API used by workers,
+// PermissionMessageCorrelate correlating messages to waiting instances and
+// PermissionQueueNext taking the next task from a group queue.
+// PermissionInstanceTrace guards enabling and reading execution traces,
+// PermissionInstanceMigrate migrating instances to another version,
+// PermissionWebhookManage the webhook subscriptions, PermissionArchiveView
+// reading the archived history of instances other users started and
+// PermissionContentModerate deleting other users' comments and attachments.
+// PermissionProcessMetadata guards changing process metadata (still limited
+// to the process creator), PermissionDeployCreate deploying a version,
+// PermissionDeployImport and PermissionDeployExport importing and exporting
+// deployment packages and PermissionCapacityView the capacity analytics.
+type RBACConfig struct {
+	Permissions map[string][]string `mapstructure:"permissions"`
+}
+
+// Attachment storage backends
+const (
+	StorageLocal = "local"
+	StorageS3    = "s3"
+)
+
+// AttachmentConfig controls files uploaded to tasks and instances. Storage
+// selects the backend: "local" keeps files under Local.Path, "s3" stores them
+// in an S3-compatible bucket. Uploads larger than MaxSizeMB or whose content
+// does not sniff as one of AllowedTypes are rejected.
+type AttachmentConfig struct {
+	Storage      string             `mapstructure:"storage"`
+	MaxSizeMB    int                `mapstructure:"max_size_mb"`
+	AllowedTypes []string           `mapstructure:"allowed_types"`
+	Local        LocalStorageConfig `mapstructure:"local"`
+	S3           S3StorageConfig    `mapstructure:"s3"`
+}
+
+type LocalStorageConfig struct {
+	Path string `mapstructure:"path"`
+}
+
+// S3StorageConfig addresses an S3-compatible bucket. With UsePathStyle the
+// bucket is part of the path (http://endpoint/bucket/key), as most
+// self-hosted servers such as MinIO expect; otherwise it is a subdomain.
+type S3StorageConfig struct {
+	Endpoint     string `mapstructure:"endpoint"`
+	Region       string `mapstructure:"region"`
+	Bucket       string `mapstructure:"bucket"`
+	AccessKey    string `mapstructure:"access_key"`
+	SecretKey    string `mapstructure:"secret_key"`
+	UsePathStyle bool   `mapstructure:"use_path_style"`
+}
+
 var AppConfig *Config
 
-// LoadConfig loads configuration from config file
+// LoadConfig loads configuration from the config file, applies defaults and
+// environment variable overrides, and validates the result. A missing config
+// file is allowed so deployments can be configured through the environment
+// alone; a malformed file or an invalid configuration is an error.
 func LoadConfig(configPath string) (*Config, error) {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
@@ -66,22 +283,26 @@ func LoadConfig(configPath string) (*Config, error) {
 	viper.AddConfigPath(".")
 
 	// Set default values
-	viper.SetDefault("server.port", 8080)
-	viper.SetDefault("server.host", "0.0.0.0")
-	viper.SetDefault("server.debug", true)
-	viper.SetDefault("database.driver", "mysql")
-	viper.SetDefault("database.charset", "utf8mb4")
-	viper.SetDefault("database.parse_time", true)
-	viper.SetDefault("database.loc", "Local")
-	viper.SetDefault("log.level", "info")
-	viper.SetDefault("log.format", "json")
-	viper.SetDefault("log.output", "stdout")
+	for _, s := range Settings {
+		if s.Default != nil {
+			viper.SetDefault(s.Key, s.Default)
+		}
+	}
 
 	// Read environment variables
-	viper.AutomaticEnv()
+	if err := bindEnv(); err != nil {
+		return nil, err
+	}
+	if err := applyEnvFiles(); err != nil {
+		return nil, err
+	}
 
 	if err := viper.ReadInConfig(); err != nil {
-		log.Printf("Warning: Could not read config file: %v", err)
+		var notFound viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFound) {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		log.Printf("Warning: no config file found in %s, using defaults and environment variables", configPath)
 	}
 
 	var config Config
@@ -89,12 +310,41 @@ func LoadConfig(configPath string) (*Config, error) {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
 	AppConfig = &config
 	return &config, nil
 }
 
-// GetDSN returns database connection string
+// Supported database drivers
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// GetDSN returns database connection string in the format expected by the
+// configured driver. For sqlite the database setting is the file path.
 func (c *DatabaseConfig) GetDSN() string {
+	switch c.Driver {
+	case DriverPostgres:
+		dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+			c.Host,
+			c.Port,
+			c.Username,
+			c.Password,
+			c.Database,
+			c.SSLMode,
+		)
+		if c.Loc != "" && c.Loc != "Local" {
+			dsn += " TimeZone=" + c.Loc
+		}
+		return dsn
+	case DriverSQLite:
+		return c.Database
+	}
 	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=%t&loc=%s",
 		c.Username,
 		c.Password,
@@ -122,7 +372,105 @@ func (c *RedisConfig) GetRedisAddr() string {
 	return fmt.Sprintf("%s:%d", c.Host, c.Port)
 }
 
+// GetSMTPAddr returns SMTP server address
+func (c *EmailConfig) GetSMTPAddr() string {
+	return fmt.Sprintf("%s:%d", c.SMTPHost, c.SMTPPort)
+}
+
+// GetFlushInterval returns notification queue flush interval as duration
+func (c *NotificationConfig) GetFlushInterval() time.Duration {
+	return time.Duration(c.FlushIntervalSeconds) * time.Second
+}
+
+// GetInterval returns the overdue task scan interval as duration
+func (c *EscalationConfig) GetInterval() time.Duration {
+	return time.Duration(c.IntervalSeconds) * time.Second
+}
+
+// GetInterval returns the reminder scan interval as duration
+func (c *ReminderConfig) GetInterval() time.Duration {
+	return time.Duration(c.IntervalSeconds) * time.Second
+}
+
+// GetOffsets returns the valid reminder offsets, longest first
+func (c *ReminderConfig) GetOffsets() []time.Duration {
+	var offsets []time.Duration
+	for _, raw := range c.Offsets {
+		if offset, err := time.ParseDuration(raw); err == nil && offset > 0 {
+			offsets = append(offsets, offset)
+		}
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] > offsets[j] })
+	return offsets
+}
+
+// GetCacheTTL returns the variable cache entry lifetime as duration
+func (c *VariablesConfig) GetCacheTTL() time.Duration {
+	return time.Duration(c.CacheTTLSeconds) * time.Second
+}
+
+// GetFairShareWindow returns how far back completed tasks count towards a member's share
+func (c *QueueConfig) GetFairShareWindow() time.Duration {
+	return time.Duration(c.FairShareWindowHours) * time.Hour
+}
+
+// GetTimeout returns the script run timeout, using the default when seconds is zero
+func (c *ScriptConfig) GetTimeout(seconds int) time.Duration {
+	if seconds <= 0 {
+		seconds = c.TimeoutSeconds
+	}
+	if seconds > c.MaxTimeoutSeconds {
+		seconds = c.MaxTimeoutSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// GetUndoWindow returns how long after cancellation an instance can be restored
+func (c *RecycleBinConfig) GetUndoWindow() time.Duration {
+	return time.Duration(c.UndoWindowHours) * time.Hour
+}
+
+// GetRetention returns how long ended instances stay in the hot tables
+func (c *ArchiveConfig) GetRetention() time.Duration {
+	return time.Duration(c.RetentionDays) * 24 * time.Hour
+}
+
+// GetInterval returns the archival scan interval as duration
+func (c *ArchiveConfig) GetInterval() time.Duration {
+	return time.Duration(c.IntervalSeconds) * time.Second
+}
+
+// GetPollInterval returns the interval between polls for due async jobs
+func (c *JobExecutorConfig) GetPollInterval() time.Duration {
+	return time.Duration(c.PollIntervalSeconds) * time.Second
+}
+
+// GetLockTimeout returns how long an executor holds the lock of a job it runs
+func (c *JobExecutorConfig) GetLockTimeout() time.Duration {
+	return time.Duration(c.LockTimeoutSeconds) * time.Second
+}
+
+// GetMaxSize returns the largest accepted attachment in bytes
+func (c *AttachmentConfig) GetMaxSize() int64 {
+	return int64(c.MaxSizeMB) << 20
+}
+
+// Allows reports whether role is granted permission
+func (c *RBACConfig) Allows(permission, role string) bool {
+	for _, granted := range c.Permissions[permission] {
+		if granted == role {
+			return true
+		}
+	}
+	return false
+}
+
 // GetJWTExpiration returns JWT expiration duration
 func (c *JWTConfig) GetJWTExpiration() time.Duration {
 	return time.Duration(c.ExpiresHours) * time.Hour
 }
+
+// GetRefreshExpiration returns refresh token expiration duration
+func (c *JWTConfig) GetRefreshExpiration() time.Duration {
+	return time.Duration(c.RefreshExpiresHours) * time.Hour
+}
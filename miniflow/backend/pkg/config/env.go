@@ -0,0 +1,46 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// bindEnv binds every setting to its environment variables
+func bindEnv() error {
+	for _, s := range Settings {
+		if err := viper.BindEnv(append([]string{s.Key}, s.EnvNames()...)...); err != nil {
+			return fmt.Errorf("failed to bind environment for %s: %w", s.Key, err)
+		}
+	}
+
+	// Keys present only in the config file use the same naming
+	viper.SetEnvPrefix(strings.TrimSuffix(EnvPrefix, "_"))
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+	return nil
+}
+
+// applyEnvFiles reads values from files named by <VAR>_FILE environment
+// variables. The values override the config file and other variables.
+func applyEnvFiles() error {
+	for _, s := range Settings {
+		fileEnv := s.FileEnvName()
+		path := os.Getenv(fileEnv)
+		if path == "" {
+			continue
+		}
+		if _, set := os.LookupEnv(s.EnvName()); set {
+			return fmt.Errorf("both %s and %s are set; use only one", s.EnvName(), fileEnv)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s from %s: %w", s.Key, fileEnv, err)
+		}
+		viper.Set(s.Key, strings.TrimRight(string(data), "\r\n"))
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Setting describes a supported configuration key. The settings table drives
+// defaults, environment variable binding and the generated documentation.
+type Setting struct {
+	Key         string
+	Default     interface{}
+	Required    bool
+	Secret      bool
+	Description string
+	// EnvAliases are additional legacy environment variables accepted for the key
+	EnvAliases []string
+}
+
+// EnvPrefix is the prefix of environment variables that override configuration keys
+const EnvPrefix = "MINIFLOW_"
+
+// EnvFileSuffix marks an environment variable naming a file that holds the value,
+// e.g. MINIFLOW_DATABASE_PASSWORD_FILE=/run/secrets/db_password
+const EnvFileSuffix = "_FILE"
+
+// Settings lists every supported configuration key
+var Settings = []Setting{
+	{Key: "server.host", Default: "0.0.0.0", Description: "Address the HTTP server listens on"},
+	{Key: "server.port", Default: 8080, Description: "HTTP server port (1-65535)"},
+	{Key: "server.debug", Default: true, Description: "Debug mode; must be false in production"},
+
+	{Key: "database.driver", Default: "mysql", Description: "Database driver: mysql, postgres or sqlite"},
+	{Key: "database.host", Required: true, Description: "Database host; unused for sqlite"},
+	{Key: "database.port", Default: 3306, Description: "Database port (1-65535)"},
+	{Key: "database.username", Required: true, Description: "Database user; unused for sqlite"},
+	{Key: "database.password", Secret: true, Description: "Database password"},
+	{Key: "database.database", Required: true, Description: "Database name, or the database file path for sqlite", EnvAliases: []string{"DATABASE_NAME"}},
+	{Key: "database.charset", Default: "utf8mb4", Description: "Connection character set (mysql only)"},
+	{Key: "database.parse_time", Default: true, Description: "Parse DATE and DATETIME columns into time values"},
+	{Key: "database.loc", Default: "Local", Description: "Time zone used to parse times, e.g. Local, UTC or Asia/Shanghai"},
+	{Key: "database.sslmode", Default: "disable", Description: "SSL mode for postgres: disable, allow, prefer, require, verify-ca or verify-full"},
+	{Key: "database.max_idle_conns", Default: 10, Description: "Maximum idle connections; must not exceed max_open_conns"},
+	{Key: "database.max_open_conns", Default: 100, Description: "Maximum open connections"},
+	{Key: "database.conn_max_lifetime", Default: 3600, Description: "Maximum connection lifetime in seconds"},
+
+	{Key: "redis.host", Default: "localhost", Description: "Redis host"},
+	{Key: "redis.port", Default: 6379, Description: "Redis port (1-65535)"},
+	{Key: "redis.password", Secret: true, Description: "Redis password"},
+	{Key: "redis.db", Default: 0, Description: "Redis database index"},
+
+	{Key: "jwt.secret", Required: true, Secret: true, Description: fmt.Sprintf("JWT signing secret, at least %d characters", minJWTSecretLength)},
+	{Key: "jwt.expires_hours", Default: 24, Description: "Token lifetime in hours"},
+	{Key: "jwt.refresh_expires_hours", Default: 168, Description: "Refresh token lifetime in hours; each refresh issues a new refresh token"},
+
+	{Key: "log.level", Default: "info", Description: "Log level: debug, info, warn or error"},
+	{Key: "log.format", Default: "json", Description: "Log format: json or console"},
+	{Key: "log.output", Default: "stdout", Description: "Log output: stdout, stderr or a file path"},
+
+	{Key: "notification.email.enabled", Default: false, Description: "Enable the email channel"},
+	{Key: "notification.email.smtp_host", Description: "SMTP host; required when email is enabled"},
+	{Key: "notification.email.smtp_port", Default: 25, Description: "SMTP port (1-65535)"},
+	{Key: "notification.email.username", Description: "SMTP user"},
+	{Key: "notification.email.password", Secret: true, Description: "SMTP password"},
+	{Key: "notification.email.from", Description: "Sender address; required when email is enabled"},
+	{Key: "notification.chat.enabled", Default: false, Description: "Enable the chat webhook channel"},
+	{Key: "notification.chat.webhook_url", Secret: true, Description: "Chat webhook URL; required when chat is enabled"},
+	{Key: "notification.critical_events", Default: []string{"task.overdue", "process.failed"}, Description: "Events delivered regardless of user preferences (comma separated)"},
+	{Key: "notification.critical_channels", Default: []string{"in_app"}, Description: "Channels used for critical events: in_app, email, chat (comma separated)"},
+	{Key: "notification.default_locale", Default: "zh-CN", Description: "Locale used when a user has none"},
+	{Key: "notification.digest_hour", Default: 9, Description: "Hour of day (0-23) the daily digest is sent"},
+	{Key: "notification.flush_interval_seconds", Default: 60, Description: "Notification queue flush interval in seconds"},
+
+	{Key: "connector.mock.enabled", Default: false, Description: "Replace outbound service task calls with stubbed or recorded responses; stubs are defined under connector.mock.stubs in config.yaml. Never enable in production"},
+	{Key: "connector.mock.connectors", Description: "Connector types or topics to mock (comma separated); empty mocks every call"},
+	{Key: "connector.mock.replay", Default: true, Description: "Replay the last recorded successful response for the same method and URL when no stub matches"},
+
+	{Key: "escalation.interval_seconds", Default: 60, Description: "Interval in seconds between scans for overdue tasks"},
+	{Key: "escalation.role", Default: "admin", Description: "Role whose least loaded active user receives escalated overdue tasks; empty keeps the current assignee"},
+	{Key: "escalation.group", Default: "", Description: "User group whose active members may claim escalated overdue tasks from the pool and are notified; takes precedence over escalation.role, empty disables"},
+
+	{Key: "reminder.interval_seconds", Default: 60, Description: "Interval in seconds between scans for tasks approaching their due date"},
+	{Key: "reminder.offsets", Default: []string{"24h", "1h"}, Description: "How long before the due date the assignee is reminded, as durations (comma separated); empty disables reminders"},
+
+	{Key: "variables.store", Default: "db", Description: "Process variable store: db, or redis to cache variables in Redis in front of the database"},
+	{Key: "variables.cache_ttl_seconds", Default: 300, Description: "Lifetime in seconds of cached process variables when variables.store is redis"},
+
+	{Key: "queue.fair_share_window_hours", Default: 24, Description: "Hours of completed tasks counted towards a member's share in fair-share queue dispatch"},
+
+	{Key: "script.timeout_seconds", Default: 5, Description: "Default run timeout in seconds of script task nodes"},
+	{Key: "script.max_timeout_seconds", Default: 60, Description: "Upper bound in seconds for the timeoutSeconds prop of script task nodes"},
+
+	{Key: "recycle_bin.undo_window_hours", Default: 72, Description: "Hours after cancellation during which an admin can restore a cancelled instance"},
+
+	{Key: "archive.retention_days", Default: 0, Description: "Days after an instance ends before it is archived to the attachment storage and removed from the database; 0 disables archival"},
+	{Key: "archive.interval_seconds", Default: 3600, Description: "Interval in seconds between scans for instances to archive"},
+	{Key: "archive.batch_size", Default: 100, Description: "Maximum number of top-level instances archived per scan"},
+
+	{Key: "job_executor.workers", Default: 4, Description: "Number of async service task jobs executed concurrently"},
+	{Key: "job_executor.poll_interval_seconds", Default: 1, Description: "Interval in seconds between polls for due async jobs"},
+	{Key: "job_executor.lock_timeout_seconds", Default: 300, Description: "Seconds an executor holds the lock of an async job it runs"},
+
+	{Key: "rbac.permissions.admin", Default: []string{"admin"}, Description: "Roles allowed to use the /admin API"},
+	{Key: "rbac.permissions.task_status", Default: []string{"admin"}, Description: "Roles allowed to list all tasks by status"},
+	{Key: "rbac.permissions.instance_suspend", Default: []string{"admin", "manager"}, Description: "Roles allowed to suspend and resume any instance; starters can always suspend their own"},
+	{Key: "rbac.permissions.instance_cancel", Default: []string{"admin", "manager"}, Description: "Roles allowed to cancel any instance; starters can always cancel their own"},
+	{Key: "rbac.permissions.deployment_promote", Default: []string{"admin"}, Description: "Roles allowed to promote deployments between environments"},
+	{Key: "rbac.permissions.kpi_manage", Default: []string{"admin", "manager"}, Description: "Roles allowed to create, edit and delete process KPIs"},
+	{Key: "rbac.permissions.external_task", Default: []string{"admin"}, Description: "Roles allowed to fetch, complete and fail external tasks"},
+	{Key: "rbac.permissions.message_correlate", Default: []string{"admin"}, Description: "Roles allowed to correlate messages to waiting instances"},
+	{Key: "rbac.permissions.queue_next", Default: []string{"admin", "manager", "user"}, Description: "Roles allowed to take the next task from a group queue; the user must also be a member of the group"},
+	{Key: "rbac.permissions.instance_trace", Default: []string{"admin"}, Description: "Roles allowed to start instances with execution tracing and read the traces"},
+	{Key: "rbac.permissions.instance_migrate", Default: []string{"admin"}, Description: "Roles allowed to migrate running instances to another published version"},
+	{Key: "rbac.permissions.webhook_manage", Default: []string{"admin"}, Description: "Roles allowed to list, register, change and delete webhook subscriptions and read their deliveries"},
+	{Key: "rbac.permissions.archive_view", Default: []string{"admin"}, Description: "Roles allowed to read the archived history of instances other users started; starters can always read their own"},
+	{Key: "rbac.permissions.content_moderate", Default: []string{"admin"}, Description: "Roles allowed to delete task comments and attachments of other users"},
+	{Key: "rbac.permissions.process_metadata", Default: []string{"admin", "manager"}, Description: "Roles allowed to change process metadata such as display labels, data classification and the completion webhook; only the process creator can change it"},
+	{Key: "rbac.permissions.deployment_create", Default: []string{"admin", "manager"}, Description: "Roles allowed to deploy a process version to an environment"},
+	{Key: "rbac.permissions.deployment_import", Default: []string{"admin"}, Description: "Roles allowed to import deployment packages"},
+	{Key: "rbac.permissions.deployment_export", Default: []string{"admin", "manager"}, Description: "Roles allowed to export deployment packages"},
+	{Key: "rbac.permissions.capacity_view", Default: []string{"admin", "manager"}, Description: "Roles allowed to read the capacity analytics"},
+
+	{Key: "attachment.storage", Default: "local", Description: "Attachment storage backend: local or s3"},
+	{Key: "attachment.max_size_mb", Default: 20, Description: "Largest accepted attachment in megabytes"},
+	{Key: "attachment.allowed_types", Default: []string{"image/png", "image/jpeg", "image/gif", "application/pdf", "text/plain", "application/zip"}, Description: "MIME types accepted for attachments, detected from the file content (comma separated); Office documents are detected as application/zip"},
+	{Key: "attachment.local.path", Default: "./data/attachments", Description: "Directory holding attachments when attachment.storage is local"},
+	{Key: "attachment.s3.endpoint", Description: "S3-compatible endpoint URL, e.g. https://s3.amazonaws.com; required when attachment.storage is s3"},
+	{Key: "attachment.s3.region", Default: "us-east-1", Description: "Region used to sign S3 requests"},
+	{Key: "attachment.s3.bucket", Description: "Bucket holding attachments; required when attachment.storage is s3"},
+	{Key: "attachment.s3.access_key", Secret: true, Description: "S3 access key ID"},
+	{Key: "attachment.s3.secret_key", Secret: true, Description: "S3 secret access key"},
+	{Key: "attachment.s3.use_path_style", Default: true, Description: "Address the bucket in the URL path instead of as a subdomain, as MinIO and most self-hosted servers expect"},
+}
+
+// EnvName returns the environment variable that overrides the setting
+func (s Setting) EnvName() string {
+	return EnvPrefix + s.legacyEnvName()
+}
+
+// FileEnvName returns the environment variable naming a file that holds the value
+func (s Setting) FileEnvName() string {
+	return s.EnvName() + EnvFileSuffix
+}
+
+// legacyEnvName returns the unprefixed environment variable accepted before EnvPrefix was introduced
+func (s Setting) legacyEnvName() string {
+	return strings.ToUpper(strings.ReplaceAll(s.Key, ".", "_"))
+}
+
+// EnvNames returns the environment variables accepted for the setting in
+// precedence order: the prefixed name, then the legacy names
+func (s Setting) EnvNames() []string {
+	return append([]string{s.EnvName(), s.legacyEnvName()}, s.EnvAliases...)
+}
+
+// lookupSetting returns the setting for a key
+func lookupSetting(key string) (Setting, bool) {
+	for _, s := range Settings {
+		if s.Key == key {
+			return s, true
+		}
+	}
+	return Setting{}, false
+}
+
+// DefaultRBACConfig returns the permission matrix with the documented defaults,
+// for engines built without LoadConfig
+func DefaultRBACConfig() RBACConfig {
+	cfg := RBACConfig{Permissions: make(map[string][]string)}
+	for _, s := range Settings {
+		if permission, ok := strings.CutPrefix(s.Key, "rbac.permissions."); ok {
+			cfg.Permissions[permission] = append([]string(nil), s.Default.([]string)...)
+		}
+	}
+	return cfg
+}
+
+// WriteEnvDocs writes the environment variable reference as a Markdown table
+func WriteEnvDocs(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# Configuration Reference\n\n")
+	b.WriteString("<!-- Generated by `go run ./cmd/configdoc`. Do not edit. -->\n\n")
+	b.WriteString("Every key can be set in `config/config.yaml` or overridden by its environment variable, so\n")
+	b.WriteString("container deployments can run without a mounted config file.\n\n")
+	b.WriteString("- Environment variables use the `" + EnvPrefix + "` prefix and take precedence over the config file.\n")
+	b.WriteString("- Any variable can instead be read from a file by appending `" + EnvFileSuffix + "`, e.g.\n")
+	b.WriteString("  `" + EnvPrefix + "DATABASE_PASSWORD" + EnvFileSuffix + "=/run/secrets/db_password` (Docker and Kubernetes secrets).\n")
+	b.WriteString("  A trailing newline is ignored. Setting both the variable and its file variant is an error.\n")
+	b.WriteString("- Unprefixed names (e.g. `DATABASE_HOST`) are still accepted for compatibility; the prefixed name wins.\n\n")
+	b.WriteString("The server refuses to start when required keys are missing or values are invalid.\n\n")
+	b.WriteString("| Key | Environment variable | Default | Required | Description |\n")
+	b.WriteString("| --- | --- | --- | --- | --- |\n")
+
+	for _, s := range Settings {
+		envs := []string{"`" + s.EnvName() + "`"}
+		if s.Secret {
+			envs = append(envs, "`"+s.FileEnvName()+"`")
+		}
+
+		def := ""
+		if s.Default != nil && !s.Secret {
+			def = "`" + formatDefault(s.Default) + "`"
+		}
+
+		required := ""
+		if s.Required {
+			required = "yes"
+		}
+
+		fmt.Fprintf(&b, "| `%s` | %s | %s | %s | %s |\n", s.Key, strings.Join(envs, ", "), def, required, s.Description)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// formatDefault renders a default value as it would be written in an environment variable
+func formatDefault(value interface{}) string {
+	if list, ok := value.([]string); ok {
+		return strings.Join(list, ",")
+	}
+	return fmt.Sprint(value)
+}
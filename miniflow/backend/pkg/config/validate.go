@@ -0,0 +1,347 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// minJWTSecretLength is the minimum JWT secret length (256 bits for HS256)
+	minJWTSecretLength = 32
+	// defaultJWTSecret is the placeholder shipped in config.yaml, rejected outside debug mode
+	defaultJWTSecret = "miniflow-secret-key-change-in-production"
+)
+
+// Problem is a single invalid setting
+type Problem struct {
+	Key     string
+	Message string
+}
+
+// ValidationError lists every invalid setting found in the loaded configuration
+type ValidationError struct {
+	Problems []Problem
+}
+
+// Error implements the error interface. Each problem names the config key and
+// the environment variable that can be used to fix it.
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid configuration (%d problems):", len(e.Problems))
+	for _, p := range e.Problems {
+		fmt.Fprintf(&b, "\n  - %s: %s", p.Key, p.Message)
+		if setting, ok := lookupSetting(p.Key); ok {
+			fmt.Fprintf(&b, " (set %s in config.yaml or %s)", p.Key, setting.EnvName())
+		}
+	}
+	return b.String()
+}
+
+// validator collects problems while checking a configuration
+type validator struct {
+	problems []Problem
+}
+
+func (v *validator) add(key, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{Key: key, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) required(key, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		v.add(key, "is required")
+		return false
+	}
+	return true
+}
+
+func (v *validator) port(key string, value int) {
+	if value < 1 || value > 65535 {
+		v.add(key, "must be between 1 and 65535, got %d", value)
+	}
+}
+
+func (v *validator) oneOf(key, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.add(key, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+}
+
+// Validate checks required fields, ranges and values that would otherwise
+// only fail at first use. All problems are reported at once.
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	c.Server.validate(v)
+	c.Database.validate(v)
+	c.Redis.validate(v)
+	c.JWT.validate(v, c.Server.Debug)
+	c.Log.validate(v)
+	c.Notification.validate(v)
+	c.Connector.validate(v)
+	c.Escalation.validate(v)
+	c.Reminder.validate(v)
+	c.Variables.validate(v)
+	c.Queue.validate(v)
+	c.Script.validate(v)
+	c.RecycleBin.validate(v)
+	c.Archive.validate(v, &c.RecycleBin)
+	c.JobExecutor.validate(v)
+	c.RBAC.validate(v)
+	c.Attachment.validate(v)
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+func (c *ServerConfig) validate(v *validator) {
+	v.port("server.port", c.Port)
+}
+
+func (c *DatabaseConfig) validate(v *validator) {
+	v.oneOf("database.driver", c.Driver, DriverMySQL, DriverPostgres, DriverSQLite)
+	if c.Driver == DriverSQLite {
+		// sqlite only needs the database file path, connection settings are unused
+		v.required("database.database", c.Database)
+	} else {
+		if v.required("database.host", c.Host) && strings.ContainsAny(c.Host, "/?@ ") {
+			v.add("database.host", "must be a host name or IP address without scheme, path or credentials, got %q", c.Host)
+		}
+		v.port("database.port", c.Port)
+		v.required("database.username", c.Username)
+		if v.required("database.database", c.Database) && strings.ContainsAny(c.Database, "/?&= ") {
+			v.add("database.database", "must be a plain database name, got %q", c.Database)
+		}
+	}
+	switch c.Driver {
+	case DriverMySQL:
+		v.required("database.charset", c.Charset)
+	case DriverPostgres:
+		v.oneOf("database.sslmode", c.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+	}
+	if _, err := time.LoadLocation(c.Loc); err != nil {
+		v.add("database.loc", "unknown time zone %q", c.Loc)
+	}
+	if c.MaxOpenConns < 0 {
+		v.add("database.max_open_conns", "must not be negative")
+	}
+	if c.MaxIdleConns < 0 {
+		v.add("database.max_idle_conns", "must not be negative")
+	}
+	if c.MaxOpenConns > 0 && c.MaxIdleConns > c.MaxOpenConns {
+		v.add("database.max_idle_conns", "must not exceed database.max_open_conns (%d > %d)", c.MaxIdleConns, c.MaxOpenConns)
+	}
+	if c.ConnMaxLifetime < 0 {
+		v.add("database.conn_max_lifetime", "must not be negative")
+	}
+}
+
+func (c *RedisConfig) validate(v *validator) {
+	if c.Host == "" {
+		return
+	}
+	v.port("redis.port", c.Port)
+	if c.DB < 0 {
+		v.add("redis.db", "must not be negative")
+	}
+}
+
+func (c *JWTConfig) validate(v *validator, debug bool) {
+	if v.required("jwt.secret", c.Secret) {
+		if len(c.Secret) < minJWTSecretLength {
+			v.add("jwt.secret", "must be at least %d characters, got %d", minJWTSecretLength, len(c.Secret))
+		}
+		if c.Secret == defaultJWTSecret && !debug {
+			v.add("jwt.secret", "is the example secret from config.yaml; generate a random one (e.g. openssl rand -base64 48)")
+		}
+	}
+	if c.ExpiresHours < 1 {
+		v.add("jwt.expires_hours", "must be at least 1, got %d", c.ExpiresHours)
+	}
+	if c.RefreshExpiresHours < c.ExpiresHours {
+		v.add("jwt.refresh_expires_hours", "must be at least jwt.expires_hours (%d), got %d", c.ExpiresHours, c.RefreshExpiresHours)
+	}
+}
+
+// minJWTSecretVariety is the fewest distinct characters a JWT secret should contain
+const minJWTSecretVariety = 10
+
+// WeakSecretReasons lists why the JWT secret is weak beyond what startup validation
+// rejects, e.g. the example secret accepted in debug mode or a repetitive secret
+func (c *JWTConfig) WeakSecretReasons() []string {
+	var reasons []string
+	if c.Secret == defaultJWTSecret {
+		reasons = append(reasons, "is the example secret from config.yaml")
+	}
+	if len(c.Secret) < minJWTSecretLength {
+		reasons = append(reasons, fmt.Sprintf("is shorter than %d characters", minJWTSecretLength))
+	}
+	variety := make(map[rune]bool)
+	for _, r := range c.Secret {
+		variety[r] = true
+	}
+	if len(variety) < minJWTSecretVariety {
+		reasons = append(reasons, fmt.Sprintf("has only %d distinct characters", len(variety)))
+	}
+	return reasons
+}
+
+func (c *LogConfig) validate(v *validator) {
+	v.oneOf("log.level", c.Level, "debug", "info", "warn", "error")
+	v.oneOf("log.format", c.Format, "json", "console")
+	v.required("log.output", c.Output)
+}
+
+func (c *NotificationConfig) validate(v *validator) {
+	if c.Email.Enabled {
+		v.required("notification.email.smtp_host", c.Email.SMTPHost)
+		v.port("notification.email.smtp_port", c.Email.SMTPPort)
+		if v.required("notification.email.from", c.Email.From) && !strings.Contains(c.Email.From, "@") {
+			v.add("notification.email.from", "must be an email address, got %q", c.Email.From)
+		}
+	}
+	if c.Chat.Enabled && v.required("notification.chat.webhook_url", c.Chat.WebhookURL) {
+		if u, err := url.Parse(c.Chat.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.add("notification.chat.webhook_url", "must be an http or https URL")
+		}
+	}
+	for _, channel := range c.CriticalChannels {
+		v.oneOf("notification.critical_channels", channel, "in_app", "email", "chat")
+	}
+	if c.DigestHour < 0 || c.DigestHour > 23 {
+		v.add("notification.digest_hour", "must be between 0 and 23, got %d", c.DigestHour)
+	}
+	if c.FlushIntervalSeconds < 1 {
+		v.add("notification.flush_interval_seconds", "must be at least 1, got %d", c.FlushIntervalSeconds)
+	}
+}
+
+func (c *ConnectorConfig) validate(v *validator) {
+	if !c.Mock.Enabled {
+		return
+	}
+	for name, stub := range c.Mock.Stubs {
+		key := "connector.mock.stubs." + name
+		if stub.StatusCode != 0 && (stub.StatusCode < 100 || stub.StatusCode > 599) {
+			v.add(key+".status_code", "must be a valid HTTP status code, got %d", stub.StatusCode)
+		}
+		if stub.DelayMs < 0 {
+			v.add(key+".delay_ms", "must not be negative")
+		}
+	}
+}
+
+func (c *EscalationConfig) validate(v *validator) {
+	if c.IntervalSeconds < 1 {
+		v.add("escalation.interval_seconds", "must be at least 1, got %d", c.IntervalSeconds)
+	}
+}
+
+func (c *ReminderConfig) validate(v *validator) {
+	if c.IntervalSeconds < 1 {
+		v.add("reminder.interval_seconds", "must be at least 1, got %d", c.IntervalSeconds)
+	}
+	for _, raw := range c.Offsets {
+		if offset, err := time.ParseDuration(raw); err != nil || offset <= 0 {
+			v.add("reminder.offsets", "must be positive durations such as 24h or 30m, got %q", raw)
+		}
+	}
+}
+
+func (c *VariablesConfig) validate(v *validator) {
+	v.oneOf("variables.store", c.Store, "db", "redis")
+	if c.Store == "redis" && c.CacheTTLSeconds < 1 {
+		v.add("variables.cache_ttl_seconds", "must be at least 1 when variables.store is redis, got %d", c.CacheTTLSeconds)
+	}
+}
+
+func (c *QueueConfig) validate(v *validator) {
+	if c.FairShareWindowHours < 1 {
+		v.add("queue.fair_share_window_hours", "must be at least 1, got %d", c.FairShareWindowHours)
+	}
+}
+
+func (c *ScriptConfig) validate(v *validator) {
+	if c.TimeoutSeconds < 1 {
+		v.add("script.timeout_seconds", "must be at least 1, got %d", c.TimeoutSeconds)
+	}
+	if c.MaxTimeoutSeconds < c.TimeoutSeconds {
+		v.add("script.max_timeout_seconds", "must be at least script.timeout_seconds (%d), got %d", c.TimeoutSeconds, c.MaxTimeoutSeconds)
+	}
+}
+
+func (c *RecycleBinConfig) validate(v *validator) {
+	if c.UndoWindowHours < 1 {
+		v.add("recycle_bin.undo_window_hours", "must be at least 1, got %d", c.UndoWindowHours)
+	}
+}
+
+func (c *ArchiveConfig) validate(v *validator, recycleBin *RecycleBinConfig) {
+	if c.RetentionDays < 0 {
+		v.add("archive.retention_days", "must not be negative, got %d", c.RetentionDays)
+	}
+	if c.RetentionDays > 0 && c.GetRetention() < recycleBin.GetUndoWindow() {
+		v.add("archive.retention_days", "must cover recycle_bin.undo_window_hours (%d), got %d days", recycleBin.UndoWindowHours, c.RetentionDays)
+	}
+	if c.IntervalSeconds < 1 {
+		v.add("archive.interval_seconds", "must be at least 1, got %d", c.IntervalSeconds)
+	}
+	if c.BatchSize < 1 {
+		v.add("archive.batch_size", "must be at least 1, got %d", c.BatchSize)
+	}
+}
+
+func (c *JobExecutorConfig) validate(v *validator) {
+	if c.Workers < 1 {
+		v.add("job_executor.workers", "must be at least 1, got %d", c.Workers)
+	}
+	if c.PollIntervalSeconds < 1 {
+		v.add("job_executor.poll_interval_seconds", "must be at least 1, got %d", c.PollIntervalSeconds)
+	}
+	if c.LockTimeoutSeconds < 1 {
+		v.add("job_executor.lock_timeout_seconds", "must be at least 1, got %d", c.LockTimeoutSeconds)
+	}
+}
+
+func (c *RBACConfig) validate(v *validator) {
+	for _, permission := range []string{
+		PermissionAdmin, PermissionTaskStatus, PermissionInstanceSuspend, PermissionInstanceCancel,
+		PermissionDeployPromote, PermissionKPIManage, PermissionExternalTask, PermissionMessageCorrelate, PermissionQueueNext,
+		PermissionInstanceTrace, PermissionInstanceMigrate, PermissionWebhookManage, PermissionArchiveView, PermissionContentModerate,
+		PermissionProcessMetadata, PermissionDeployCreate, PermissionDeployImport, PermissionDeployExport, PermissionCapacityView,
+	} {
+		if len(c.Permissions[permission]) == 0 {
+			v.add("rbac.permissions."+permission, "must grant at least one role")
+		}
+	}
+}
+
+func (c *AttachmentConfig) validate(v *validator) {
+	v.oneOf("attachment.storage", c.Storage, StorageLocal, StorageS3)
+	if c.MaxSizeMB < 1 {
+		v.add("attachment.max_size_mb", "must be at least 1, got %d", c.MaxSizeMB)
+	}
+	if len(c.AllowedTypes) == 0 {
+		v.add("attachment.allowed_types", "must allow at least one type")
+	}
+	switch c.Storage {
+	case StorageLocal:
+		v.required("attachment.local.path", c.Local.Path)
+	case StorageS3:
+		if v.required("attachment.s3.endpoint", c.S3.Endpoint) {
+			if u, err := url.Parse(c.S3.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				v.add("attachment.s3.endpoint", "must be an http or https URL")
+			}
+		}
+		v.required("attachment.s3.region", c.S3.Region)
+		v.required("attachment.s3.bucket", c.S3.Bucket)
+		v.required("attachment.s3.access_key", c.S3.AccessKey)
+		v.required("attachment.s3.secret_key", c.S3.SecretKey)
+	}
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"fmt"
 
 	"miniflow/pkg/config"
@@ -8,6 +9,8 @@ import (
 
 	"go.uber.org/zap"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	gormLogger "gorm.io/gorm/logger"
 	"gorm.io/gorm/schema"
@@ -38,8 +41,13 @@ func NewDatabase(cfg *config.DatabaseConfig, log *logger.Logger) (*Database, err
 		DisableForeignKeyConstraintWhenMigrating: false, // enable foreign key constraints
 	}
 
+	dialector, err := openDialector(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	// Connect to database
-	db, err := gorm.Open(mysql.Open(cfg.GetDSN()), gormConfig)
+	db, err := gorm.Open(dialector, gormConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -61,6 +69,7 @@ func NewDatabase(cfg *config.DatabaseConfig, log *logger.Logger) (*Database, err
 	}
 
 	log.Info("Database connected successfully",
+		zap.String("driver", cfg.Driver),
 		zap.String("host", cfg.Host),
 		zap.Int("port", cfg.Port),
 		zap.String("database", cfg.Database),
@@ -69,6 +78,25 @@ func NewDatabase(cfg *config.DatabaseConfig, log *logger.Logger) (*Database, err
 	return &Database{DB: db, logger: log}, nil
 }
 
+// openDialector selects the gorm dialector for the configured driver
+func openDialector(cfg *config.DatabaseConfig) (gorm.Dialector, error) {
+	switch cfg.Driver {
+	case config.DriverMySQL, "":
+		return mysql.Open(cfg.GetDSN()), nil
+	case config.DriverPostgres:
+		return postgres.Open(cfg.GetDSN()), nil
+	case config.DriverSQLite:
+		return sqlite.Open(cfg.GetDSN()), nil
+	}
+	return nil, fmt.Errorf("unsupported database driver %q", cfg.Driver)
+}
+
+// Wrap wraps an existing gorm connection, for callers that manage their own
+// connection pool such as applications embedding the engine
+func Wrap(db *gorm.DB, log *logger.Logger) *Database {
+	return &Database{DB: db, logger: log}
+}
+
 // Close closes the database connection
 func (d *Database) Close() error {
 	sqlDB, err := d.DB.DB()
@@ -107,11 +135,11 @@ func (d *Database) CreateIndexes() error {
 }
 
 // HealthCheck checks if database is healthy
-func (d *Database) HealthCheck() error {
+func (d *Database) HealthCheck(ctx context.Context) error {
 	sqlDB, err := d.DB.DB()
 	if err != nil {
 		return fmt.Errorf("failed to get underlying sql.DB: %w", err)
 	}
 
-	return sqlDB.Ping()
+	return sqlDB.PingContext(ctx)
 }
@@ -0,0 +1,42 @@
+package database
+
+import (
+	"fmt"
+
+	"miniflow/pkg/config"
+)
+
+// Dialect returns the name of the connected database driver
+func (d *Database) Dialect() string {
+	return d.DB.Dialector.Name()
+}
+
+// Quote quotes a table or column name for the connected database, for names
+// such as key that are reserved words in some databases
+func (d *Database) Quote(name string) string {
+	return d.DB.Statement.Quote(name)
+}
+
+// SecondsBetween returns an SQL expression for the whole number of seconds
+// from the start expression to the end expression
+func (d *Database) SecondsBetween(start, end string) string {
+	switch d.Dialect() {
+	case config.DriverPostgres:
+		return fmt.Sprintf("CAST(EXTRACT(EPOCH FROM (%s - %s)) AS BIGINT)", end, start)
+	case config.DriverSQLite:
+		return fmt.Sprintf("CAST((julianday(%s) - julianday(%s)) * 86400 AS INTEGER)", end, start)
+	}
+	return fmt.Sprintf("TIMESTAMPDIFF(SECOND, %s, %s)", start, end)
+}
+
+// DateKey returns an SQL expression converting a time expression to an
+// integer date key in the form YYYYMMDD
+func (d *Database) DateKey(expr string) string {
+	switch d.Dialect() {
+	case config.DriverPostgres:
+		return fmt.Sprintf("CAST(TO_CHAR(%s, 'YYYYMMDD') AS INTEGER)", expr)
+	case config.DriverSQLite:
+		return fmt.Sprintf("CAST(strftime('%%Y%%m%%d', %s) AS INTEGER)", expr)
+	}
+	return fmt.Sprintf("CAST(DATE_FORMAT(%s, '%%Y%%m%%d') AS UNSIGNED)", expr)
+}
@@ -0,0 +1,281 @@
+// Package engine embeds the miniflow process engine in another Go service.
+//
+// The engine runs against the caller's own gorm connection without the HTTP
+// server, JWT auth or Echo handlers; the REST API is just another consumer of
+// the same engine. A minimal embedding deploys a definition, starts an
+// instance and claims and completes its tasks:
+//
+//	flow, err := engine.New(engine.Config{DB: db, AutoMigrate: true})
+//	process, err := flow.Deploy(ctx, userID, &engine.DeployRequest{Key: "leave", Name: "Leave", Definition: def})
+//	instance, err := flow.StartProcessByKey(ctx, "leave", "LEAVE-1", vars, userID)
+//	tasks, _, err := flow.QueryTasks(ctx, &engine.TaskQuery{InstanceIDs: []uint{instance.ID}, Limit: 10})
+//	err = flow.ClaimTask(ctx, tasks[0].ID, userID)
+//	err = flow.CompleteTask(ctx, tasks[0].ID, userID, formData, "ok")
+//
+// Every call takes a context; cancelling it or reaching its deadline aborts
+// the database queries and service task calls made on its behalf.
+//
+// Timers, async service tasks and startup recovery only run while Run is
+// active, so long-lived embedders should call it in a goroutine.
+package engine
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	core "miniflow/internal/engine"
+	"miniflow/internal/migration"
+	"miniflow/internal/model"
+	"miniflow/internal/repository"
+	"miniflow/internal/service"
+	"miniflow/pkg/config"
+	"miniflow/pkg/database"
+	"miniflow/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Types shared with the engine, re-exported so embedders can name them
+type (
+	ProcessInstance       = model.ProcessInstance
+	TaskInstance          = model.TaskInstance
+	ProcessDefinition     = model.ProcessDefinition
+	ProcessDefinitionData = model.ProcessDefinitionData
+	ProcessNode           = model.ProcessNode
+	ProcessFlow           = model.ProcessFlow
+	StartProcessRequest   = core.StartProcessRequest
+	DeployRequest         = service.CreateProcessRequest
+	ProcessResponse       = service.ProcessResponse
+	TaskQuery             = repository.TaskQuery
+	ActivityHistoryQuery  = repository.ActivityHistoryQuery
+	MessageCorrelation    = core.MessageCorrelation
+	Event                 = core.Event
+	EventHandler          = core.EventHandler
+	Error                 = core.EngineError
+)
+
+// defaultTimerInterval matches the interval the server uses for its timer scheduler
+const defaultTimerInterval = 30 * time.Second
+
+// Config configures an embedded engine. DB is required; every other field
+// falls back to the defaults of the miniflow server.
+type Config struct {
+	// DB is the caller's connection; the engine never closes it
+	DB *gorm.DB
+	// Logger defaults to a no-op logger
+	Logger *logger.Logger
+	// AutoMigrate applies pending schema migrations to DB on New
+	AutoMigrate bool
+
+	Connector     config.ConnectorConfig
+	Script        config.ScriptConfig
+	JobExecutor   config.JobExecutorConfig
+	TimerInterval time.Duration
+	// RBAC is the permission matrix checked by operations restricted to some roles
+	RBAC config.RBACConfig
+
+	// MailSender sends the email of mail task nodes; without it mail tasks raise an incident
+	MailSender core.MailSender
+}
+
+// withDefaults fills unset limits with the defaults documented in config.Settings
+func (c Config) withDefaults() Config {
+	if c.Logger == nil {
+		c.Logger = &logger.Logger{Logger: zap.NewNop()}
+	}
+	if c.Script.TimeoutSeconds == 0 {
+		c.Script.TimeoutSeconds = 5
+	}
+	if c.Script.MaxTimeoutSeconds == 0 {
+		c.Script.MaxTimeoutSeconds = 60
+	}
+	if c.JobExecutor.Workers == 0 {
+		c.JobExecutor.Workers = 4
+	}
+	if c.JobExecutor.PollIntervalSeconds == 0 {
+		c.JobExecutor.PollIntervalSeconds = 1
+	}
+	if c.JobExecutor.LockTimeoutSeconds == 0 {
+		c.JobExecutor.LockTimeoutSeconds = 300
+	}
+	if c.TimerInterval == 0 {
+		c.TimerInterval = defaultTimerInterval
+	}
+	if c.RBAC.Permissions == nil {
+		c.RBAC = config.DefaultRBACConfig()
+	}
+	return c
+}
+
+// Engine is a process engine embedded in the calling service
+type Engine struct {
+	cfg       Config
+	engine    *core.ProcessEngine
+	processes *service.ProcessService
+	events    *core.EventSystem
+	jobs      *core.JobExecutor
+	timers    *core.TimerScheduler
+	recovery  *core.Recovery
+}
+
+// New creates an engine on cfg.DB, migrating the schema first when
+// cfg.AutoMigrate is set
+func New(cfg Config) (*Engine, error) {
+	if cfg.DB == nil {
+		return nil, errors.New("engine: Config.DB is required")
+	}
+	cfg = cfg.withDefaults()
+
+	db := database.Wrap(cfg.DB, cfg.Logger)
+	if cfg.AutoMigrate {
+		if _, err := migration.New(cfg.DB, cfg.Logger).Up(); err != nil {
+			return nil, err
+		}
+	}
+
+	instanceRepo := repository.NewProcessInstanceRepository(db, cfg.Logger)
+	processRepo := repository.NewProcessRepository(db, cfg.Logger)
+	userRepo := repository.NewUserRepository(db, cfg.Logger)
+	policyRepo := repository.NewConnectorPolicyRepository(db, cfg.Logger)
+	events := core.NewEventSystem(cfg.Logger)
+
+	processEngine := core.NewProcessEngine(
+		instanceRepo,
+		repository.NewTaskRepository(db, cfg.Logger),
+		processRepo,
+		userRepo,
+		policyRepo,
+		repository.NewIncidentRepository(db, cfg.Logger),
+		repository.NewDuplicateRepository(db, cfg.Logger),
+		repository.NewExecutionLogRepository(db, cfg.Logger),
+		&cfg.Connector,
+		&cfg.Script,
+		&cfg.RBAC,
+		db,
+		core.NewDBVariableStore(instanceRepo),
+		events,
+		cfg.MailSender,
+		cfg.Logger,
+	)
+
+	return &Engine{
+		cfg:    cfg,
+		engine: processEngine,
+		processes: service.NewProcessService(
+			processRepo,
+			userRepo,
+			policyRepo,
+			repository.NewComplexityBudgetRepository(db, cfg.Logger),
+			cfg.Logger,
+		),
+		events:   events,
+		jobs:     core.NewJobExecutor(processEngine, &cfg.JobExecutor, cfg.Logger),
+		timers:   core.NewTimerScheduler(processEngine, cfg.Logger),
+		recovery: core.NewRecovery(processEngine, cfg.Logger),
+	}, nil
+}
+
+// Run repairs instances left behind by a previous run, then executes async
+// service tasks and fires due timers until ctx is cancelled
+func (e *Engine) Run(ctx context.Context) {
+	e.recovery.Start(ctx)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e.timers.Start(ctx, e.cfg.TimerInterval)
+	}()
+	e.jobs.Start(ctx)
+	<-done
+}
+
+// ProcessEngine returns the underlying engine for operations the facade does not wrap
+func (e *Engine) ProcessEngine() *core.ProcessEngine {
+	return e.engine
+}
+
+// Subscribe registers handler for engine events of eventType, or for every event when eventType is empty
+func (e *Engine) Subscribe(eventType string, handler EventHandler) {
+	if eventType == "" {
+		e.events.SubscribeAll(handler)
+		return
+	}
+	e.events.Subscribe(eventType, handler)
+}
+
+// Deploy creates a process definition and publishes it as version 1
+func (e *Engine) Deploy(ctx context.Context, userID uint, req *DeployRequest) (*ProcessResponse, error) {
+	process, err := e.processes.CreateProcess(ctx, userID, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.processes.PublishProcess(ctx, process.ID, userID); err != nil {
+		return nil, err
+	}
+	return e.processes.GetProcess(ctx, process.ID)
+}
+
+// StartProcess starts an instance of the definition in req
+func (e *Engine) StartProcess(ctx context.Context, req *StartProcessRequest, starterID uint) (*ProcessInstance, error) {
+	return e.engine.StartProcess(ctx, req, starterID)
+}
+
+// StartProcessByKey starts an instance of the latest published version of the process key
+func (e *Engine) StartProcessByKey(ctx context.Context, key, businessKey string, variables map[string]interface{}, starterID uint) (*ProcessInstance, error) {
+	definition, err := e.engine.ResolvePublishedDefinition(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return e.engine.StartProcess(ctx, &StartProcessRequest{
+		DefinitionID: definition.ID,
+		BusinessKey:  businessKey,
+		Variables:    variables,
+	}, starterID)
+}
+
+// CompleteTask completes a user task and advances its instance
+func (e *Engine) CompleteTask(ctx context.Context, taskID, userID uint, formData map[string]interface{}, comment string) error {
+	return e.engine.CompleteTask(ctx, taskID, userID, formData, comment)
+}
+
+// ClaimTask assigns a pooled task to userID
+func (e *Engine) ClaimTask(ctx context.Context, taskID, userID uint) error {
+	return e.engine.ClaimTask(ctx, taskID, userID)
+}
+
+// CancelInstance cancels a running or suspended instance
+func (e *Engine) CancelInstance(ctx context.Context, instanceID, userID uint, reason string) error {
+	return e.engine.CancelInstance(ctx, instanceID, userID, reason)
+}
+
+// CorrelateMessage delivers a message to the instances waiting for it
+func (e *Engine) CorrelateMessage(ctx context.Context, messageName, correlationKey string, variables map[string]interface{}, userID uint) (*MessageCorrelation, error) {
+	return e.engine.CorrelateMessage(ctx, messageName, correlationKey, variables, userID)
+}
+
+// GetInstance returns an instance with its definition
+func (e *Engine) GetInstance(ctx context.Context, instanceID uint) (*ProcessInstance, error) {
+	return e.engine.GetInstance(ctx, instanceID)
+}
+
+// ListInstances returns a page of instances matching filters and the total count
+func (e *Engine) ListInstances(ctx context.Context, offset, limit int, filters map[string]interface{}) ([]ProcessInstance, int64, error) {
+	return e.engine.GetInstances(ctx, offset, limit, filters)
+}
+
+// GetTask returns a task
+func (e *Engine) GetTask(ctx context.Context, taskID uint) (*TaskInstance, error) {
+	return e.engine.GetTask(ctx, taskID)
+}
+
+// GetUserTasks returns a page of the tasks assigned to userID, optionally filtered by status
+func (e *Engine) GetUserTasks(ctx context.Context, userID uint, status string, offset, limit int) ([]TaskInstance, int64, error) {
+	return e.engine.GetUserTasks(ctx, userID, status, offset, limit)
+}
+
+// QueryTasks returns the tasks matching query and the total count
+func (e *Engine) QueryTasks(ctx context.Context, query *TaskQuery) ([]TaskInstance, int64, error) {
+	return e.engine.QueryTasks(ctx, query)
+}
@@ -0,0 +1,152 @@
+package engine_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"miniflow/internal/model"
+	"miniflow/pkg/engine"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// openTestDB opens the sqlite database an embedding service would hand to the engine
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "embedded.db") + "?_busy_timeout=5000&_txlock=immediate"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// approvalDefinition is submit → exclusive gateway → approve when amount exceeds 1000,
+// otherwise straight to the end; both tasks go to the starter
+func approvalDefinition() engine.ProcessDefinitionData {
+	starter := map[string]interface{}{"assignee": "${starter.id}"}
+	return engine.ProcessDefinitionData{
+		Nodes: []engine.ProcessNode{
+			{ID: "start", Type: model.NodeTypeStart, Name: "start"},
+			{ID: "submit", Type: model.NodeTypeUserTask, Name: "submit", Props: starter},
+			{ID: "check", Type: model.NodeTypeGateway, Name: "check", Props: map[string]interface{}{"gatewayType": "exclusive"}},
+			{ID: "approve", Type: model.NodeTypeUserTask, Name: "approve", Props: starter},
+			{ID: "end", Type: model.NodeTypeEnd, Name: "end"},
+		},
+		Flows: []engine.ProcessFlow{
+			{ID: "f1", From: "start", To: "submit"},
+			{ID: "f2", From: "submit", To: "check"},
+			{ID: "f3", From: "check", To: "approve", Condition: "${amount} > 1000"},
+			{ID: "f4", From: "check", To: "end"},
+			{ID: "f5", From: "approve", To: "end"},
+		},
+	}
+}
+
+// openTasks returns the open tasks of the instance through the facade
+func openTasks(t *testing.T, flow *engine.Engine, instanceID uint) []engine.TaskInstance {
+	t.Helper()
+
+	tasks, _, err := flow.QueryTasks(context.Background(), &engine.TaskQuery{
+		InstanceIDs: []uint{instanceID},
+		Statuses:    []string{model.TaskStatusCreated, model.TaskStatusAssigned, model.TaskStatusClaimed},
+		Limit:       10,
+	})
+	if err != nil {
+		t.Fatalf("query tasks: %v", err)
+	}
+	return tasks
+}
+
+func TestNewRequiresDB(t *testing.T) {
+	if _, err := engine.New(engine.Config{}); err == nil {
+		t.Fatal("New without a DB succeeded")
+	}
+}
+
+func TestEmbeddedEngineRunsProcess(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	flow, err := engine.New(engine.Config{DB: db, AutoMigrate: true})
+	if err != nil {
+		t.Fatalf("new engine: %v", err)
+	}
+
+	runCtx, stop := context.WithCancel(ctx)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		flow.Run(runCtx)
+	}()
+	t.Cleanup(func() {
+		stop()
+		select {
+		case <-stopped:
+		case <-time.After(10 * time.Second):
+			t.Error("Run did not return after its context was cancelled")
+		}
+	})
+
+	user := &model.User{Username: "embedder", Password: "x", Email: "embedder@example.com", Role: "user", Status: "active"}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	process, err := flow.Deploy(ctx, user.ID, &engine.DeployRequest{Key: "expense", Name: "Expense", Definition: approvalDefinition()})
+	if err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	if process.Status != model.ProcessStatusPublished {
+		t.Fatalf("deployed process status %q, want published", process.Status)
+	}
+
+	for _, tt := range []struct {
+		amount int
+		nodes  []string
+	}{
+		{amount: 5000, nodes: []string{"submit", "approve"}},
+		{amount: 200, nodes: []string{"submit"}},
+	} {
+		instance, err := flow.StartProcessByKey(ctx, "expense", "EXP-1", map[string]interface{}{"amount": tt.amount}, user.ID)
+		if err != nil {
+			t.Fatalf("amount %d: start: %v", tt.amount, err)
+		}
+
+		for _, node := range tt.nodes {
+			tasks := openTasks(t, flow, instance.ID)
+			if len(tasks) != 1 || tasks[0].NodeID != node {
+				t.Fatalf("amount %d: open tasks %+v, want one at %s", tt.amount, tasks, node)
+			}
+			if tasks[0].AssigneeID == nil || *tasks[0].AssigneeID != user.ID {
+				t.Fatalf("amount %d: task at %s assigned to %v, want the starter", tt.amount, node, tasks[0].AssigneeID)
+			}
+			if err := flow.ClaimTask(ctx, tasks[0].ID, user.ID); err != nil {
+				t.Fatalf("amount %d: claim %s: %v", tt.amount, node, err)
+			}
+			if err := flow.CompleteTask(ctx, tasks[0].ID, user.ID, nil, "ok"); err != nil {
+				t.Fatalf("amount %d: complete %s: %v", tt.amount, node, err)
+			}
+		}
+
+		if tasks := openTasks(t, flow, instance.ID); len(tasks) != 0 {
+			t.Fatalf("amount %d: open tasks %+v after the last node", tt.amount, tasks)
+		}
+		got, err := flow.GetInstance(ctx, instance.ID)
+		if err != nil {
+			t.Fatalf("amount %d: get instance: %v", tt.amount, err)
+		}
+		if got.Status != model.InstanceStatusCompleted {
+			t.Fatalf("amount %d: instance status %q, want completed", tt.amount, got.Status)
+		}
+	}
+}
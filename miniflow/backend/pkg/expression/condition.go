@@ -0,0 +1,62 @@
+package expression
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseCondition parses a boolean condition such as a gateway flow condition.
+// Variables may be written bare (amount > 1000) or wrapped in ${...}
+// (${amount} > 1000, ${amount > 1000}); each ${...} is parsed on its own and
+// then used as a parenthesized operand of the surrounding expression.
+// String literals must be quoted: ${level} == 'high'. An unquoted word is a
+// variable reference, so ${level} == high compares with the variable high and
+// fails when it is undefined; the substitution based evaluator this replaced
+// compared the text of both sides instead.
+func ParseCondition(source string) (*Expression, error) {
+	var sb strings.Builder
+	rest := source
+
+	for {
+		start := strings.Index(rest, "${")
+		if start < 0 {
+			sb.WriteString(rest)
+			break
+		}
+		end := strings.Index(rest[start:], "}")
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated ${ in %q", source)
+		}
+
+		inner := rest[start+2 : start+end]
+		if _, err := Parse(inner); err != nil {
+			return nil, fmt.Errorf("invalid expression in %q: %v", source, err)
+		}
+		sb.WriteString(rest[:start])
+		sb.WriteString("(")
+		sb.WriteString(inner)
+		sb.WriteString(")")
+		rest = rest[start+end+1:]
+	}
+
+	expr, err := Parse(sb.String())
+	if err != nil {
+		return nil, err
+	}
+	expr.source = source
+	return expr, nil
+}
+
+// EvaluateCondition parses and evaluates a condition in one step. An empty
+// condition is always true; any parse or evaluation error is returned rather
+// than being treated as a match or a miss.
+func EvaluateCondition(source string, variables map[string]interface{}) (bool, error) {
+	if strings.TrimSpace(source) == "" {
+		return true, nil
+	}
+	expr, err := ParseCondition(source)
+	if err != nil {
+		return false, err
+	}
+	return expr.EvaluateBool(variables)
+}
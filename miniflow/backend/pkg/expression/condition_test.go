@@ -0,0 +1,130 @@
+package expression
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEvaluateCondition(t *testing.T) {
+	variables := map[string]interface{}{
+		"amount":   1500,
+		"level":    "high",
+		"approved": true,
+		"nothing":  nil,
+		"order":    map[string]interface{}{"total": 200.0},
+	}
+
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		// 空条件总是成立
+		{"", true},
+		{"  ", true},
+
+		// 变量可以直接书写，也可以用 ${...} 包裹
+		{"amount > 1000", true},
+		{"${amount} > 1000", true},
+		{"${amount > 1000}", true},
+		{"${ amount } > 1000", true},
+		{"${amount} > 1000 && ${level} == 'high'", true},
+		{"${amount > 2000} || ${level == 'high'}", true},
+		{"${order.total} >= 200", true},
+		{"${approved}", true},
+		{"${approved} == false", false},
+		{"!${approved}", false},
+
+		// 每个 ${...} 作为带括号的操作数，不会改变外层优先级
+		{"${true || false} && false", false},
+		{"true || ${false && false}", true},
+		{"${1 + 1} * 2 == 4", true},
+
+		// 字符串必须加引号
+		{"${level} == 'high'", true},
+		{`${level} == "high"`, true},
+		{"${level} == 'low'", false},
+		{"level != 'low'", true},
+
+		// null
+		{"${nothing} == null", true},
+		{"nothing != null", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			got, err := EvaluateCondition(tt.source, variables)
+			if err != nil {
+				t.Fatalf("evaluate: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("result = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateConditionErrors(t *testing.T) {
+	variables := map[string]interface{}{
+		"amount": 1500,
+		"level":  "high",
+	}
+
+	tests := []struct {
+		source string
+		err    string
+	}{
+		// 不加引号的单词是变量引用，旧版本中会被当作字符串比较
+		{"${level} == high", `undefined variable "high"`},
+		{"approved == true", `undefined variable "approved"`},
+		{"${missing} == null", `undefined variable "missing"`},
+
+		// 结果必须是布尔值，不会把数字或字符串当作真假
+		{"amount", "returned number, expected bool"},
+		{"${level}", "returned string, expected bool"},
+		{"1", "returned number, expected bool"},
+
+		{"amount > '1000'", "cannot compare number with string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			_, err := EvaluateCondition(tt.source, variables)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestParseConditionErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{"${amount > 1000", "unterminated ${"},
+		{"${} == 1", "invalid expression"},
+		{"${amount >} == true", "invalid expression"},
+		{"${amount} >", "unexpected end of expression"},
+		{"amount >> 1", "unexpected"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			_, err := ParseCondition(tt.source)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestParseConditionKeepsSource(t *testing.T) {
+	source := "${amount} > 1000"
+	expr, err := ParseCondition(source)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if expr.String() != source {
+		t.Fatalf("source = %q, want %q", expr.String(), source)
+	}
+	if vars := expr.Variables(); len(vars) != 1 || vars[0] != "amount" {
+		t.Fatalf("variables = %v, want [amount]", vars)
+	}
+}
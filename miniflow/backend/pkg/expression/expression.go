@@ -0,0 +1,426 @@
+// Package expression implements a small, side-effect free expression language
+// used by process definitions (conditions, assignee expressions, rules).
+//
+// Supported syntax: number, string ('..' or ".."), true/false/null literals,
+// variable paths (a.b.c, a["b"], list[0]), arithmetic (+ - * / %), comparison
+// (== != < <= > >=), logical operators (&& || !) and the ternary operator (c ? a : b).
+// Evaluation never defaults silently: undefined variables and type mismatches are errors.
+package expression
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Expression is a parsed expression that can be evaluated repeatedly
+type Expression struct {
+	source string
+	root   node
+}
+
+// Parse parses the source into an expression
+func Parse(source string) (*Expression, error) {
+	if strings.TrimSpace(source) == "" {
+		return nil, fmt.Errorf("empty expression")
+	}
+
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+
+	return &Expression{source: source, root: root}, nil
+}
+
+// Evaluate parses and evaluates the source in one step
+func Evaluate(source string, variables map[string]interface{}) (interface{}, error) {
+	expr, err := Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	return expr.Evaluate(variables)
+}
+
+// String returns the expression source
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Evaluate evaluates the expression against the given variables
+func (e *Expression) Evaluate(variables map[string]interface{}) (interface{}, error) {
+	if variables == nil {
+		variables = map[string]interface{}{}
+	}
+	return e.root.eval(variables)
+}
+
+// EvaluateBool evaluates the expression and requires a boolean result
+func (e *Expression) EvaluateBool(variables map[string]interface{}) (bool, error) {
+	value, err := e.Evaluate(variables)
+	if err != nil {
+		return false, err
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression %q returned %s, expected bool", e.source, typeName(value))
+	}
+	return result, nil
+}
+
+// Variables returns the sorted top-level variable names referenced by the expression
+func (e *Expression) Variables() []string {
+	seen := make(map[string]bool)
+	e.root.collect(seen)
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// node is an expression tree node
+type node interface {
+	eval(variables map[string]interface{}) (interface{}, error)
+	collect(names map[string]bool)
+}
+
+// literalNode is a constant value
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(map[string]interface{}) (interface{}, error) { return n.value, nil }
+func (n *literalNode) collect(map[string]bool)                          {}
+
+// identNode is a top-level variable reference
+type identNode struct {
+	name string
+}
+
+func (n *identNode) eval(variables map[string]interface{}) (interface{}, error) {
+	value, ok := variables[n.name]
+	if !ok {
+		return nil, fmt.Errorf("undefined variable %q", n.name)
+	}
+	return normalize(value), nil
+}
+
+func (n *identNode) collect(names map[string]bool) { names[n.name] = true }
+
+// memberNode is a field or index access
+type memberNode struct {
+	target node
+	key    node
+}
+
+func (n *memberNode) eval(variables map[string]interface{}) (interface{}, error) {
+	target, err := n.target.eval(variables)
+	if err != nil {
+		return nil, err
+	}
+	key, err := n.key.eval(variables)
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := target.(type) {
+	case map[string]interface{}:
+		name, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("object key must be a string, got %s", typeName(key))
+		}
+		value, exists := t[name]
+		if !exists {
+			return nil, fmt.Errorf("field %q does not exist", name)
+		}
+		return normalize(value), nil
+	case []interface{}:
+		index, ok := key.(float64)
+		if !ok || index != math.Trunc(index) {
+			return nil, fmt.Errorf("list index must be an integer, got %v", key)
+		}
+		if index < 0 || int(index) >= len(t) {
+			return nil, fmt.Errorf("list index %d out of range", int(index))
+		}
+		return normalize(t[int(index)]), nil
+	case nil:
+		return nil, fmt.Errorf("cannot access %v of null", key)
+	default:
+		return nil, fmt.Errorf("cannot access %v of %s", key, typeName(target))
+	}
+}
+
+func (n *memberNode) collect(names map[string]bool) {
+	n.target.collect(names)
+	n.key.collect(names)
+}
+
+// unaryNode is a prefix operation
+type unaryNode struct {
+	op      string
+	operand node
+}
+
+func (n *unaryNode) eval(variables map[string]interface{}) (interface{}, error) {
+	value, err := n.operand.eval(variables)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "!":
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator ! requires bool, got %s", typeName(value))
+		}
+		return !b, nil
+	default:
+		f, ok := value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("operator - requires number, got %s", typeName(value))
+		}
+		return -f, nil
+	}
+}
+
+func (n *unaryNode) collect(names map[string]bool) { n.operand.collect(names) }
+
+// ternaryNode is a conditional expression
+type ternaryNode struct {
+	cond      node
+	then      node
+	otherwise node
+}
+
+func (n *ternaryNode) eval(variables map[string]interface{}) (interface{}, error) {
+	cond, err := n.cond.eval(variables)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := cond.(bool)
+	if !ok {
+		return nil, fmt.Errorf("condition of ?: must be bool, got %s", typeName(cond))
+	}
+	if b {
+		return n.then.eval(variables)
+	}
+	return n.otherwise.eval(variables)
+}
+
+func (n *ternaryNode) collect(names map[string]bool) {
+	n.cond.collect(names)
+	n.then.collect(names)
+	n.otherwise.collect(names)
+}
+
+// binaryNode is an infix operation
+type binaryNode struct {
+	op    string
+	left  node
+	right node
+}
+
+func (n *binaryNode) eval(variables map[string]interface{}) (interface{}, error) {
+	left, err := n.left.eval(variables)
+	if err != nil {
+		return nil, err
+	}
+
+	// Logical operators short-circuit
+	if n.op == "&&" || n.op == "||" {
+		l, ok := left.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator %s requires bool, got %s", n.op, typeName(left))
+		}
+		if (n.op == "&&" && !l) || (n.op == "||" && l) {
+			return l, nil
+		}
+		right, err := n.right.eval(variables)
+		if err != nil {
+			return nil, err
+		}
+		r, ok := right.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator %s requires bool, got %s", n.op, typeName(right))
+		}
+		return r, nil
+	}
+
+	right, err := n.right.eval(variables)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "<", "<=", ">", ">=":
+		return compare(n.op, left, right)
+	default:
+		return arithmetic(n.op, left, right)
+	}
+}
+
+func (n *binaryNode) collect(names map[string]bool) {
+	n.left.collect(names)
+	n.right.collect(names)
+}
+
+// equal compares two values; numbers compare numerically, other types must match exactly
+func equal(left, right interface{}) bool {
+	return reflect.DeepEqual(left, right)
+}
+
+// compare applies an ordering operator to two numbers or two strings
+func compare(op string, left, right interface{}) (bool, error) {
+	var cmp int
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return false, fmt.Errorf("cannot compare number with %s", typeName(right))
+		}
+		switch {
+		case l < r:
+			cmp = -1
+		case l > r:
+			cmp = 1
+		}
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return false, fmt.Errorf("cannot compare string with %s", typeName(right))
+		}
+		cmp = strings.Compare(l, r)
+	default:
+		return false, fmt.Errorf("operator %s is not supported for %s", op, typeName(left))
+	}
+
+	switch op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+// arithmetic applies an arithmetic operator; + also concatenates strings
+func arithmetic(op string, left, right interface{}) (interface{}, error) {
+	if op == "+" {
+		if l, ok := left.(string); ok {
+			return l + fmt.Sprint(displayValue(right)), nil
+		}
+		if r, ok := right.(string); ok {
+			return fmt.Sprint(displayValue(left)) + r, nil
+		}
+	}
+
+	l, lok := left.(float64)
+	r, rok := right.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("operator %s requires numbers, got %s and %s", op, typeName(left), typeName(right))
+	}
+
+	switch op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return l / r, nil
+	default:
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return math.Mod(l, r), nil
+	}
+}
+
+// normalize converts Go numeric types to float64 so values from JSON and code compare alike
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int8:
+		return float64(v)
+	case int16:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint:
+		return float64(v)
+	case uint8:
+		return float64(v)
+	case uint16:
+		return float64(v)
+	case uint32:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case []string:
+		list := make([]interface{}, len(v))
+		for i := range v {
+			list[i] = v[i]
+		}
+		return list
+	default:
+		return value
+	}
+}
+
+// displayValue formats whole numbers without a decimal point for string concatenation
+func displayValue(value interface{}) interface{} {
+	if f, ok := value.(float64); ok && f == math.Trunc(f) && math.Abs(f) < 1e15 {
+		return int64(f)
+	}
+	return value
+}
+
+// typeName returns a readable type name for error messages
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
@@ -0,0 +1,226 @@
+package expression
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	variables := map[string]interface{}{
+		"amount":  1500,
+		"rate":    float32(0.5),
+		"count":   int64(3),
+		"level":   "high",
+		"name":    "O'Brien",
+		"active":  true,
+		"nothing": nil,
+		"roles":   []string{"admin", "user"},
+		"order": map[string]interface{}{
+			"total": 200.0,
+			"items": []interface{}{"a", "b"},
+			"owner": map[string]interface{}{"name": "alice"},
+		},
+	}
+
+	tests := []struct {
+		source string
+		want   interface{}
+	}{
+		// 字面量
+		{"42", 42.0},
+		{"1.5", 1.5},
+		{"'text'", "text"},
+		{`"text"`, "text"},
+		{"true", true},
+		{"false", false},
+		{"null", nil},
+		{"nil", nil},
+
+		// 字符串引号与转义
+		{`'it\'s'`, "it's"},
+		{`"say \"hi\""`, `say "hi"`},
+		{`'a"b'`, `a"b`},
+		{`"a'b"`, "a'b"},
+		{`'back\\slash'`, `back\slash`},
+		{"name == \"O'Brien\"", true},
+		{`name == 'O\'Brien'`, true},
+
+		// 算术优先级与结合性
+		{"1 + 2 * 3", 7.0},
+		{"(1 + 2) * 3", 9.0},
+		{"10 - 4 - 3", 3.0},
+		{"12 / 3 / 2", 2.0},
+		{"7 % 4 * 2", 6.0},
+		{"-2 * 3", -6.0},
+		{"--2", 2.0},
+		{"2 - -2", 4.0},
+
+		// 比较与逻辑运算的优先级
+		{"1 + 1 == 2", true},
+		{"1 < 2 == true", true},
+		{"true || false && false", true},
+		{"(true || false) && false", false},
+		{"!false && true", true},
+		{"!(1 > 2)", true},
+		{"amount > 1000 && level == 'high'", true},
+		{"amount > 2000 || level != 'high'", false},
+
+		// 三元运算符
+		{"amount > 1000 ? 'big' : 'small'", "big"},
+		{"amount > 2000 ? 'big' : amount > 1000 ? 'medium' : 'small'", "medium"},
+		{"(amount > 2000 ? 1 : 2) + 1", 3.0},
+
+		// 类型：整数、浮点数与 JSON 数字比较时相等
+		{"amount == 1500", true},
+		{"amount == 1500.0", true},
+		{"rate == 0.5", true},
+		{"count * 2", 6.0},
+		{"amount > 999.5", true},
+		{"'abc' < 'abd'", true},
+		{"'b' >= 'a'", true},
+		{"1 == '1'", false},
+		{"true == 1", false},
+		{"'10' == 10", false},
+		{"active == true", true},
+		{"active", true},
+
+		// 字符串拼接
+		{"'n=' + 2", "n=2"},
+		{"1.5 + 'x'", "1.5x"},
+		{"level + '-' + count", "high-3"},
+
+		// null
+		{"nothing == null", true},
+		{"nothing != null", false},
+		{"level == null", false},
+
+		// 成员与下标访问
+		{"order.total", 200.0},
+		{"order['total']", 200.0},
+		{`order["owner"].name`, "alice"},
+		{"order.items[1]", "b"},
+		{"order.items[0 + 1]", "b"},
+		{"roles[0]", "admin"},
+		{"order.owner.name == 'alice'", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			got, err := Evaluate(tt.source, variables)
+			if err != nil {
+				t.Fatalf("evaluate: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("result = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	variables := map[string]interface{}{
+		"amount":  1500,
+		"level":   "high",
+		"nothing": nil,
+		"order":   map[string]interface{}{"items": []interface{}{"a"}},
+	}
+
+	tests := []struct {
+		source string
+		err    string
+	}{
+		// 未定义的变量和字段不会被当作 null 或字符串
+		{"missing", `undefined variable "missing"`},
+		{"missing == null", `undefined variable "missing"`},
+		{"level == high", `undefined variable "high"`},
+		{"order.missing", `field "missing" does not exist`},
+		{"nothing.name", "cannot access name of null"},
+		{"level.name", "cannot access name of string"},
+		{"order.items[1]", "list index 1 out of range"},
+		{"order.items[0.5]", "list index must be an integer"},
+		{"order[1]", "object key must be a string"},
+
+		// 类型不匹配
+		{"amount > 'a'", "cannot compare number with string"},
+		{"'a' < 1", "cannot compare string with number"},
+		{"true < false", "operator < is not supported for bool"},
+		{"nothing > 1", "operator > is not supported for null"},
+		{"amount - 'a'", "operator - requires numbers, got number and string"},
+		{"nothing + 1", "operator + requires numbers, got null and number"},
+		{"!amount", "operator ! requires bool, got number"},
+		{"-level", "operator - requires number, got string"},
+		{"amount && true", "operator && requires bool, got number"},
+		{"true || level", ""},
+		{"false || level", "operator || requires bool, got string"},
+		{"amount ? 1 : 2", "condition of ?: must be bool, got number"},
+		{"1 / 0", "division by zero"},
+		{"1 % 0", "division by zero"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			_, err := Evaluate(tt.source, variables)
+			if tt.err == "" {
+				// || 短路时不计算右侧
+				if err != nil {
+					t.Fatalf("evaluate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"   ",
+		"1 +",
+		"(1 + 2",
+		"1 + 2)",
+		"a b",
+		"'unterminated",
+		`"unterminated\"`,
+		"1.2.3",
+		"a ? 1",
+		"a.",
+		"a.1",
+		"a[0",
+		"a = 1",
+		"a & b",
+		"#",
+	}
+	for _, source := range tests {
+		t.Run(source, func(t *testing.T) {
+			if _, err := Parse(source); err == nil {
+				t.Fatal("parse succeeded")
+			}
+		})
+	}
+}
+
+func TestVariables(t *testing.T) {
+	tests := []struct {
+		source string
+		want   []string
+	}{
+		{"1 + 2", []string{}},
+		{"amount > 1000 && level == 'high'", []string{"amount", "level"}},
+		{"b.c + a[d] + b", []string{"a", "b", "d"}},
+		{"flag ? x : y", []string{"flag", "x", "y"}},
+		{"'level' == level", []string{"level"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			expr, err := Parse(tt.source)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if got := expr.Variables(); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("variables = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
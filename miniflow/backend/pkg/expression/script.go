@@ -0,0 +1,78 @@
+package expression
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// assignmentPattern matches the "name =" prefix of a script statement
+var assignmentPattern = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\s*=`)
+
+// Script is a sequence of assignments, one per line:
+//
+//	total = amount * quantity
+//	level = total > 10000 ? 'high' : 'low'
+//
+// Blank lines and lines starting with # or // are ignored. Each statement sees
+// the variables assigned by the statements before it. Like expressions, scripts
+// have no functions or other side effects beyond the assigned variables.
+type Script struct {
+	statements []statement
+}
+
+// statement assigns the value of an expression to a top-level variable
+type statement struct {
+	line   int
+	target string
+	value  *Expression
+}
+
+// ParseScript parses the source into a script
+func ParseScript(source string) (*Script, error) {
+	script := &Script{}
+	for i, line := range strings.Split(source, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		match := assignmentPattern.FindStringSubmatch(line)
+		if match == nil || strings.HasPrefix(line[len(match[0]):], "=") {
+			return nil, fmt.Errorf("line %d: expected an assignment such as name = expression", i+1)
+		}
+		value, err := Parse(line[len(match[0]):])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		script.statements = append(script.statements, statement{line: i + 1, target: match[1], value: value})
+	}
+	if len(script.statements) == 0 {
+		return nil, fmt.Errorf("empty script")
+	}
+	return script, nil
+}
+
+// Run executes the statements in order and returns the assigned variables.
+// The given variables are not modified; execution stops when ctx is done.
+func (s *Script) Run(ctx context.Context, variables map[string]interface{}) (map[string]interface{}, error) {
+	scope := make(map[string]interface{}, len(variables))
+	for key, value := range variables {
+		scope[key] = value
+	}
+
+	assigned := make(map[string]interface{})
+	for _, stmt := range s.statements {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		value, err := stmt.value.Evaluate(scope)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", stmt.line, err)
+		}
+		scope[stmt.target] = value
+		assigned[stmt.target] = value
+	}
+	return assigned, nil
+}
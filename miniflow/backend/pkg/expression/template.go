@@ -0,0 +1,79 @@
+package expression
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Template is a string with embedded ${...} expressions
+type Template struct {
+	literals    []string
+	expressions []*Expression
+}
+
+// ParseTemplate parses a string containing zero or more ${...} expressions
+func ParseTemplate(source string) (*Template, error) {
+	tmpl := &Template{}
+	rest := source
+
+	for {
+		start := strings.Index(rest, "${")
+		if start < 0 {
+			tmpl.literals = append(tmpl.literals, rest)
+			return tmpl, nil
+		}
+		end := strings.Index(rest[start:], "}")
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated ${ in %q", source)
+		}
+
+		expr, err := Parse(rest[start+2 : start+end])
+		if err != nil {
+			return nil, fmt.Errorf("invalid expression in %q: %v", source, err)
+		}
+		tmpl.literals = append(tmpl.literals, rest[:start])
+		tmpl.expressions = append(tmpl.expressions, expr)
+		rest = rest[start+end+1:]
+	}
+}
+
+// IsTemplate reports whether the string contains a ${...} expression
+func IsTemplate(source string) bool {
+	return strings.Contains(source, "${")
+}
+
+// Render evaluates the embedded expressions. A template consisting of a single
+// expression returns the raw value; otherwise the parts are joined as a string.
+func (t *Template) Render(variables map[string]interface{}) (interface{}, error) {
+	if len(t.expressions) == 1 && t.literals[0] == "" && t.literals[1] == "" {
+		return t.expressions[0].Evaluate(variables)
+	}
+
+	var sb strings.Builder
+	for i, literal := range t.literals {
+		sb.WriteString(literal)
+		if i >= len(t.expressions) {
+			continue
+		}
+		value, err := t.expressions[i].Evaluate(variables)
+		if err != nil {
+			return nil, err
+		}
+		if value != nil {
+			sb.WriteString(fmt.Sprint(displayValue(value)))
+		}
+	}
+	return sb.String(), nil
+}
+
+// RenderString evaluates the embedded expressions and always joins the result as a string
+func (t *Template) RenderString(variables map[string]interface{}) (string, error) {
+	value, err := t.Render(variables)
+	if err != nil {
+		return "", err
+	}
+	if value == nil {
+		return "", nil
+	}
+	return fmt.Sprint(displayValue(value)), nil
+}
@@ -0,0 +1,336 @@
+// Package listquery binds sort and filter query parameters of list endpoints to
+// whitelisted database columns.
+//
+// Sorting uses a comma separated list of API field names, a leading "-" sorts
+// descending:
+//
+//	?sort=-priority,created_at
+//
+// Filters use the filter[field] or filter[field][op] form:
+//
+//	?filter[status]=assigned&filter[priority][gte]=50&filter[status][in]=assigned,claimed
+//
+// Unknown fields and operators are rejected. Column names only ever come from
+// the schema and values are always bound as parameters, so nothing from the
+// request is interpolated into SQL.
+package listquery
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FieldType is the type a filter value is parsed into
+type FieldType int
+
+// Supported field types
+const (
+	String FieldType = iota
+	Int
+	Time
+	Bool
+)
+
+// Operator is a filter comparison operator
+type Operator string
+
+// Supported operators
+const (
+	OpEq   Operator = "eq"
+	OpNe   Operator = "ne"
+	OpGt   Operator = "gt"
+	OpGte  Operator = "gte"
+	OpLt   Operator = "lt"
+	OpLte  Operator = "lte"
+	OpIn   Operator = "in"
+	OpLike Operator = "like"
+)
+
+// operatorSQL maps operators to their SQL form
+var operatorSQL = map[Operator]string{
+	OpEq:   "=",
+	OpNe:   "<>",
+	OpGt:   ">",
+	OpGte:  ">=",
+	OpLt:   "<",
+	OpLte:  "<=",
+	OpIn:   "IN",
+	OpLike: "LIKE",
+}
+
+// maxInValues limits the number of values of an "in" filter
+const maxInValues = 100
+
+// Field describes an API field that may be sorted or filtered on
+type Field struct {
+	// Table and Column identify the database column; Table may be empty
+	Table  string
+	Column string
+	Type   FieldType
+	// Sortable allows the field in the sort parameter
+	Sortable bool
+	// Operators lists the allowed filter operators; the field is not filterable when empty
+	Operators []Operator
+	// Values restricts a String field to an enumerated set of values; any value is allowed when empty
+	Values []string
+}
+
+// Schema maps API field names to columns
+type Schema struct {
+	Fields map[string]Field
+	// DefaultSort is used when the request has no sort parameter
+	DefaultSort []Sort
+}
+
+// Sort is a validated ordering on a column
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// Filter is a validated condition on a column
+type Filter struct {
+	Field    string
+	Operator Operator
+	Value    interface{}
+}
+
+// Params is the result of binding a request against a schema
+type Params struct {
+	schema  *Schema
+	Sorts   []Sort
+	Filters []Filter
+}
+
+// Error is returned for sort or filter parameters the schema does not allow
+type Error struct {
+	Param   string
+	Message string
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Param, e.Message)
+}
+
+// Bind parses the sort and filter[...] parameters from a query string.
+// Parameters other than sort and filter[...] are ignored.
+func (s *Schema) Bind(values url.Values) (*Params, error) {
+	params := &Params{schema: s}
+
+	if raw := strings.TrimSpace(values.Get("sort")); raw != "" {
+		sorts, err := s.parseSort(raw)
+		if err != nil {
+			return nil, err
+		}
+		params.Sorts = sorts
+	}
+
+	// Iterate in a stable order so generated SQL is deterministic
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		if strings.HasPrefix(key, "filter[") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		name, op, err := parseFilterKey(key)
+		if err != nil {
+			return nil, err
+		}
+		for _, raw := range values[key] {
+			filter, err := s.parseFilter(key, name, op, raw)
+			if err != nil {
+				return nil, err
+			}
+			params.Filters = append(params.Filters, filter)
+		}
+	}
+
+	return params, nil
+}
+
+// parseSort validates a comma separated sort parameter
+func (s *Schema) parseSort(raw string) ([]Sort, error) {
+	var sorts []Sort
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		desc := strings.HasPrefix(part, "-")
+		name := strings.TrimPrefix(strings.TrimPrefix(part, "-"), "+")
+
+		field, ok := s.Fields[name]
+		if !ok || !field.Sortable {
+			return nil, &Error{Param: "sort", Message: fmt.Sprintf("field %q cannot be sorted on", name)}
+		}
+		if seen[name] {
+			return nil, &Error{Param: "sort", Message: fmt.Sprintf("field %q appears more than once", name)}
+		}
+		seen[name] = true
+		sorts = append(sorts, Sort{Field: name, Desc: desc})
+	}
+	return sorts, nil
+}
+
+// parseFilterKey splits filter[field] and filter[field][op] keys
+func parseFilterKey(key string) (string, Operator, error) {
+	rest := strings.TrimPrefix(key, "filter[")
+	end := strings.Index(rest, "]")
+	if end <= 0 {
+		return "", "", &Error{Param: key, Message: "malformed filter parameter"}
+	}
+	name, rest := rest[:end], rest[end+1:]
+	if rest == "" {
+		return name, OpEq, nil
+	}
+	if !strings.HasPrefix(rest, "[") || !strings.HasSuffix(rest, "]") || len(rest) < 3 {
+		return "", "", &Error{Param: key, Message: "malformed filter parameter"}
+	}
+	return name, Operator(rest[1 : len(rest)-1]), nil
+}
+
+// parseFilter validates a filter against the schema and converts its value
+func (s *Schema) parseFilter(key, name string, op Operator, raw string) (Filter, error) {
+	field, ok := s.Fields[name]
+	if !ok || len(field.Operators) == 0 {
+		return Filter{}, &Error{Param: key, Message: fmt.Sprintf("field %q cannot be filtered on", name)}
+	}
+	if !field.allows(op) {
+		return Filter{}, &Error{Param: key, Message: fmt.Sprintf("operator %q is not allowed on field %q", op, name)}
+	}
+
+	if op == OpIn {
+		parts := strings.Split(raw, ",")
+		if len(parts) > maxInValues {
+			return Filter{}, &Error{Param: key, Message: fmt.Sprintf("at most %d values are allowed", maxInValues)}
+		}
+		converted := make([]interface{}, 0, len(parts))
+		for _, part := range parts {
+			value, err := field.convert(strings.TrimSpace(part))
+			if err != nil {
+				return Filter{}, &Error{Param: key, Message: err.Error()}
+			}
+			converted = append(converted, value)
+		}
+		return Filter{Field: name, Operator: op, Value: converted}, nil
+	}
+
+	if op == OpLike {
+		return Filter{Field: name, Operator: op, Value: "%" + EscapeLike(raw) + "%"}, nil
+	}
+
+	value, err := field.convert(raw)
+	if err != nil {
+		return Filter{}, &Error{Param: key, Message: err.Error()}
+	}
+	return Filter{Field: name, Operator: op, Value: value}, nil
+}
+
+// allows reports whether the operator is allowed on the field
+func (f Field) allows(op Operator) bool {
+	for _, allowed := range f.Operators {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
+
+// convert parses a raw filter value into the field type
+func (f Field) convert(raw string) (interface{}, error) {
+	switch f.Type {
+	case Int:
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", raw)
+		}
+		return v, nil
+	case Time:
+		v, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an RFC 3339 time", raw)
+		}
+		return v, nil
+	case Bool:
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a boolean", raw)
+		}
+		return v, nil
+	default:
+		if len(f.Values) > 0 && !containsValue(f.Values, raw) {
+			return nil, fmt.Errorf("%q is not one of: %s", raw, strings.Join(f.Values, ", "))
+		}
+		return raw, nil
+	}
+}
+
+// containsValue reports whether values contains the value
+func containsValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// column returns the quoted column expression of a field
+func (f Field) column() clause.Column {
+	return clause.Column{Table: f.Table, Name: f.Column}
+}
+
+// Apply adds the filters and ordering to the query
+func (p *Params) Apply(db *gorm.DB) *gorm.DB {
+	return p.ApplySort(p.ApplyFilters(db))
+}
+
+// ApplySort adds the ordering to the query. The schema's default sort is used
+// when the request did not specify one.
+func (p *Params) ApplySort(db *gorm.DB) *gorm.DB {
+	sorts := p.Sorts
+	if len(sorts) == 0 {
+		sorts = p.schema.DefaultSort
+	}
+	for _, s := range sorts {
+		db = db.Order(clause.OrderByColumn{Column: p.schema.Fields[s.Field].column(), Desc: s.Desc})
+	}
+	return db
+}
+
+// ApplyFilters adds only the filters to the query, for count queries
+func (p *Params) ApplyFilters(db *gorm.DB) *gorm.DB {
+	for _, f := range p.Filters {
+		column := p.schema.Fields[f.Field].column()
+		switch f.Operator {
+		case OpIn:
+			db = db.Where(clause.IN{Column: column, Values: f.Value.([]interface{})})
+		case OpLike:
+			db = db.Where(clause.Expr{SQL: "? LIKE ? ESCAPE ?", Vars: []interface{}{column, f.Value, LikeEscape}})
+		default:
+			db = db.Where(clause.Expr{SQL: "? " + operatorSQL[f.Operator] + " ?", Vars: []interface{}{column, f.Value}})
+		}
+	}
+	return db
+}
+
+// LikeEscape is the escape character EscapeLike uses. Queries bind it as the
+// ESCAPE operand of LIKE: sqlite has no default escape character, and MySQL
+// reads a '\' literal in the statement as the start of an escape sequence.
+const LikeEscape = `\`
+
+// EscapeLike escapes LIKE wildcards in a user supplied value. The pattern must
+// be matched with ESCAPE LikeEscape.
+func EscapeLike(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(value)
+}
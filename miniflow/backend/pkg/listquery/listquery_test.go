@@ -0,0 +1,144 @@
+package listquery
+
+import (
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+type testRecord struct {
+	ID       uint
+	Name     string
+	Priority int
+}
+
+var testSchema = &Schema{
+	Fields: map[string]Field{
+		"name":     {Column: "name", Type: String, Sortable: true, Operators: []Operator{OpEq, OpLike}},
+		"priority": {Column: "priority", Type: Int, Sortable: true, Operators: []Operator{OpEq, OpGte, OpIn}},
+	},
+	DefaultSort: []Sort{{Field: "priority", Desc: true}},
+}
+
+// openTestDB creates the test_records table in a sqlite database in a temp directory
+func openTestDB(t *testing.T, names ...string) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "listquery.db")), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := db.AutoMigrate(&testRecord{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	for i, name := range names {
+		if err := db.Create(&testRecord{Name: name, Priority: i}).Error; err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+	}
+	return db
+}
+
+// find binds the query string and returns the names of the matching records
+func find(t *testing.T, db *gorm.DB, query string) []string {
+	t.Helper()
+
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		t.Fatalf("parse %s: %v", query, err)
+	}
+	params, err := testSchema.Bind(values)
+	if err != nil {
+		t.Fatalf("bind %s: %v", query, err)
+	}
+	var records []testRecord
+	if err := params.Apply(db.Model(&testRecord{})).Find(&records).Error; err != nil {
+		t.Fatalf("query %s: %v", query, err)
+	}
+	names := []string{}
+	for _, record := range records {
+		names = append(names, record.Name)
+	}
+	return names
+}
+
+func TestLikeFilterMatchesWildcardsLiterally(t *testing.T) {
+	db := openTestDB(t, "100%", "1000", "a_b", "axb", `c\d`, `c\\d`, "plain")
+
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"%", []string{"100%"}},
+		{"0%", []string{"100%"}},
+		{"_", []string{"a_b"}},
+		{"a_b", []string{"a_b"}},
+		{`\`, []string{`c\\d`, `c\d`}},
+		{`c\d`, []string{`c\d`}},
+		{`\\`, []string{`c\\d`}},
+		{"lai", []string{"plain"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got := find(t, db, url.Values{"filter[name][like]": {tt.value}}.Encode())
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("names = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyFiltersAndSorts(t *testing.T) {
+	db := openTestDB(t, "a", "b", "c", "d")
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"d", "c", "b", "a"}},
+		{"sort=name", []string{"a", "b", "c", "d"}},
+		{"filter[priority][gte]=2", []string{"d", "c"}},
+		{"filter[priority][in]=0,2&sort=-name", []string{"c", "a"}},
+		{"filter[name]=b", []string{"b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := find(t, db, tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("names = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindRejectsInvalidParameters(t *testing.T) {
+	tests := []string{
+		"sort=unknown",
+		"sort=name,-name",
+		"filter[unknown]=1",
+		"filter[name][gte]=a",
+		"filter[priority]=high",
+		"filter[name",
+		"filter[name]x=a",
+	}
+	for _, query := range tests {
+		t.Run(query, func(t *testing.T) {
+			values, err := url.ParseQuery(query)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if _, err := testSchema.Bind(values); err == nil {
+				t.Fatal("bind succeeded")
+			}
+		})
+	}
+}
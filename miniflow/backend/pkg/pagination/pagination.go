@@ -0,0 +1,185 @@
+// Package pagination parses and validates list pagination parameters and builds
+// the response envelope shared by list endpoints.
+//
+// Offset mode uses ?page=&page_size= and reports total counts. Cursor mode uses
+// ?cursor=&limit= (page_size is accepted as an alias) and reports the cursor of
+// the next page. Endpoints supporting both modes switch to cursor mode when the
+// request carries a cursor parameter; an empty cursor requests the first page.
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options configures page size defaults for an endpoint
+type Options struct {
+	DefaultPageSize int
+	MaxPageSize     int
+}
+
+// Default options used by most list endpoints
+var Default = Options{DefaultPageSize: 20, MaxPageSize: 100}
+
+// Request is a validated pagination request
+type Request struct {
+	Page     int
+	PageSize int
+	Cursor   string
+}
+
+// Error is returned for invalid pagination parameters
+type Error struct {
+	Param   string
+	Message string
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Param, e.Message)
+}
+
+// Parse reads offset pagination parameters. Missing parameters use defaults;
+// malformed or out of range values are rejected.
+func Parse(values url.Values, opts Options) (*Request, error) {
+	req := &Request{Page: 1}
+
+	if raw := strings.TrimSpace(values.Get("page")); raw != "" {
+		page, err := strconv.Atoi(raw)
+		if err != nil || page < 1 {
+			return nil, &Error{Param: "page", Message: "must be a positive integer"}
+		}
+		req.Page = page
+	}
+
+	size, err := parsePageSize(values, "page_size", opts)
+	if err != nil {
+		return nil, err
+	}
+	req.PageSize = size
+
+	return req, nil
+}
+
+// ParseCursor reads cursor pagination parameters. The cursor is returned as is;
+// callers decode it with the format they issued.
+func ParseCursor(values url.Values, opts Options) (*Request, error) {
+	param := "limit"
+	if values.Get(param) == "" && values.Get("page_size") != "" {
+		param = "page_size"
+	}
+
+	size, err := parsePageSize(values, param, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Request{
+		PageSize: size,
+		Cursor:   strings.TrimSpace(values.Get("cursor")),
+	}, nil
+}
+
+// UsesCursor reports whether the request selects cursor mode by sending a
+// cursor parameter, which is empty for the first page
+func UsesCursor(values url.Values) bool {
+	return values.Has("cursor")
+}
+
+// parsePageSize reads a page size parameter within the configured bounds
+func parsePageSize(values url.Values, param string, opts Options) (int, error) {
+	raw := strings.TrimSpace(values.Get(param))
+	if raw == "" {
+		return opts.DefaultPageSize, nil
+	}
+	size, err := strconv.Atoi(raw)
+	if err != nil || size < 1 || size > opts.MaxPageSize {
+		return 0, &Error{Param: param, Message: fmt.Sprintf("must be between 1 and %d", opts.MaxPageSize)}
+	}
+	return size, nil
+}
+
+// Offset returns the number of rows to skip for the requested page
+func (r *Request) Offset() int {
+	return (r.Page - 1) * r.PageSize
+}
+
+// Limit returns the number of rows to fetch
+func (r *Request) Limit() int {
+	return r.PageSize
+}
+
+// TotalPages returns the number of pages needed for total rows
+func TotalPages(total int64, pageSize int) int64 {
+	if pageSize <= 0 {
+		return 0
+	}
+	return (total + int64(pageSize) - 1) / int64(pageSize)
+}
+
+// Result builds the offset mode response data: the items under key plus total,
+// page, page_size and total_pages
+func (r *Request) Result(key string, items interface{}, total int64) map[string]interface{} {
+	return map[string]interface{}{
+		key:           items,
+		"total":       total,
+		"page":        r.Page,
+		"page_size":   r.PageSize,
+		"total_pages": TotalPages(total, r.PageSize),
+	}
+}
+
+// CursorResult builds the cursor mode response data: the items under key plus
+// page_size, next_cursor and has_more. An empty next cursor means the caller
+// should keep the cursor it sent.
+func (r *Request) CursorResult(key string, items interface{}, count int, nextCursor string) map[string]interface{} {
+	if nextCursor == "" {
+		nextCursor = r.Cursor
+	}
+	return map[string]interface{}{
+		key:           items,
+		"page_size":   r.PageSize,
+		"next_cursor": nextCursor,
+		"has_more":    count >= r.PageSize,
+	}
+}
+
+// EncodeCursor encodes a (timestamp, id) position as an opaque cursor
+func EncodeCursor(at time.Time, id uint) string {
+	raw := fmt.Sprintf("%d:%d", at.UnixNano(), id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor decodes a cursor produced by EncodeCursor. An empty cursor
+// decodes to the zero position.
+func DecodeCursor(cursor string) (time.Time, uint, error) {
+	if cursor == "" {
+		return time.Time{}, 0, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return time.Time{}, 0, errors.New("invalid cursor")
+	}
+
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	id, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+
+	return time.Unix(0, nanos), uint(id), nil
+}
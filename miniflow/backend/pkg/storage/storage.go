@@ -0,0 +1,38 @@
+// Package storage keeps uploaded files in a pluggable backend.
+//
+// Two backends are provided: the local file system and S3-compatible object
+// storage. Objects are addressed by slash-separated keys chosen by the caller.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"miniflow/pkg/config"
+)
+
+// ErrNotFound is returned by Open when no object is stored under the key
+var ErrNotFound = errors.New("storage: object not found")
+
+// Storage stores and retrieves objects by key
+type Storage interface {
+	// Put stores size bytes read from r under key, replacing any existing object
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open returns the content of the object stored under key
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object stored under key; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// New creates the backend selected by the attachment configuration
+func New(cfg *config.AttachmentConfig) (Storage, error) {
+	switch cfg.Storage {
+	case config.StorageLocal:
+		return NewLocalStorage(cfg.Local.Path), nil
+	case config.StorageS3:
+		return NewS3Storage(&cfg.S3)
+	}
+	return nil, fmt.Errorf("storage: unsupported backend %q", cfg.Storage)
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// isoDurationPattern matches ISO 8601 durations such as P1DT2H, PT30M or P2W
+var isoDurationPattern = regexp.MustCompile(`^P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// ISODuration is a parsed ISO 8601 duration. Calendar parts (years, months,
+// weeks, days) are applied with time.AddDate so "P1D" stays one calendar day
+// across daylight saving changes.
+type ISODuration struct {
+	Years  int
+	Months int
+	Days   int
+	Clock  time.Duration
+}
+
+// ParseISODuration parses an ISO 8601 duration like "P3D", "PT1H30M" or "P1W".
+// Fractional values and negative durations are not supported.
+func ParseISODuration(value string) (ISODuration, error) {
+	match := isoDurationPattern.FindStringSubmatch(value)
+	if match == nil || value == "P" || value == "PT" || value[len(value)-1] == 'T' {
+		return ISODuration{}, fmt.Errorf("invalid ISO 8601 duration %q", value)
+	}
+
+	parts := make([]int, len(match))
+	for i := 1; i < len(match); i++ {
+		if match[i] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(match[i])
+		if err != nil {
+			return ISODuration{}, fmt.Errorf("invalid ISO 8601 duration %q: %v", value, err)
+		}
+		parts[i] = n
+	}
+
+	return ISODuration{
+		Years:  parts[1],
+		Months: parts[2],
+		Days:   parts[3]*7 + parts[4],
+		Clock:  time.Duration(parts[5])*time.Hour + time.Duration(parts[6])*time.Minute + time.Duration(parts[7])*time.Second,
+	}, nil
+}
+
+// AddTo returns t advanced by the duration
+func (d ISODuration) AddTo(t time.Time) time.Time {
+	return t.AddDate(d.Years, d.Months, d.Days).Add(d.Clock)
+}
+
+// IsZero reports whether the duration is empty
+func (d ISODuration) IsZero() bool {
+	return d.Years == 0 && d.Months == 0 && d.Days == 0 && d.Clock == 0
+}
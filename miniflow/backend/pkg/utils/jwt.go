@@ -1,6 +1,9 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"time"
 
@@ -18,33 +21,75 @@ type Claims struct {
 
 // JWTManager manages JWT operations
 type JWTManager struct {
-	secret     []byte
-	expiration time.Duration
+	secret            []byte
+	expiration        time.Duration
+	refreshExpiration time.Duration
 }
 
 // NewJWTManager creates a new JWT manager
 func NewJWTManager(cfg *config.JWTConfig) *JWTManager {
 	return &JWTManager{
-		secret:     []byte(cfg.Secret),
-		expiration: cfg.GetJWTExpiration(),
+		secret:            []byte(cfg.Secret),
+		expiration:        cfg.GetJWTExpiration(),
+		refreshExpiration: cfg.GetRefreshExpiration(),
 	}
 }
 
 // GenerateToken generates a JWT token for user
 func (j *JWTManager) GenerateToken(userID uint, username string) (string, error) {
+	token, _, err := j.IssueToken(userID, username)
+	return token, err
+}
+
+// IssueToken generates a JWT access token for user and returns its expiry.
+// Every token carries a random ID (jti) so it can be revoked before it expires.
+func (j *JWTManager) IssueToken(userID uint, username string) (string, time.Time, error) {
+	tokenID, err := randomToken(16)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(j.expiration)
 	claims := Claims{
 		UserID:   userID,
 		Username: username,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.expiration)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ID:        tokenID,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    "miniflow",
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(j.secret)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(j.secret)
+	return token, expiresAt, err
+}
+
+// GenerateRefreshToken generates an opaque refresh token, the hash to persist
+// server-side, and its expiry
+func (j *JWTManager) GenerateRefreshToken() (token, hash string, expiresAt time.Time, err error) {
+	token, err = randomToken(32)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	return token, HashRefreshToken(token), time.Now().Add(j.refreshExpiration), nil
+}
+
+// HashRefreshToken returns the hex SHA-256 digest a refresh token is stored under
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomToken returns n random bytes hex encoded
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
 
 // ParseToken parses and validates a JWT token
@@ -116,3 +161,57 @@ func ParseJWT(tokenString string) (*Claims, error) {
 	}
 	return defaultJWTManager.ParseToken(tokenString)
 }
+
+// statusLinkAudience is the audience of public instance status link tokens
+const statusLinkAudience = "instance-status"
+
+// StatusLinkClaims represents the claims of a public instance status link
+type StatusLinkClaims struct {
+	InstanceID uint `json:"instance_id"`
+	jwt.RegisteredClaims
+}
+
+// statusLinkSecret derives a separate signing key so status link tokens can never
+// be accepted as login tokens and vice versa
+func (j *JWTManager) statusLinkSecret() []byte {
+	sum := sha256.Sum256(append(append([]byte{}, j.secret...), []byte(":"+statusLinkAudience)...))
+	return sum[:]
+}
+
+// GenerateStatusLinkToken generates a signed token granting read-only access to an instance status
+func (j *JWTManager) GenerateStatusLinkToken(instanceID uint, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	claims := StatusLinkClaims{
+		InstanceID: instanceID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    "miniflow",
+			Audience:  jwt.ClaimStrings{statusLinkAudience},
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(j.statusLinkSecret())
+	return token, expiresAt, err
+}
+
+// ParseStatusLinkToken validates a status link token and returns the instance ID
+func (j *JWTManager) ParseStatusLinkToken(tokenString string) (uint, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &StatusLinkClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("invalid signing method")
+		}
+		return j.statusLinkSecret(), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	claims, ok := token.Claims.(*StatusLinkClaims)
+	if !ok || !token.Valid || !claims.VerifyAudience(statusLinkAudience, true) || claims.InstanceID == 0 {
+		return 0, errors.New("无效的状态链接")
+	}
+	return claims.InstanceID, nil
+}
@@ -187,7 +187,16 @@ const ProcessEdit: React.FC = () => {
         setIsModified(false);
       }
     } catch (error: any) {
-      message.error(error.message || '保存失败');
+      // 后端流程图校验失败时返回结构错误的位置，在设计器中标出
+      const issues = error.response?.data?.issues;
+      if (Array.isArray(issues) && issues.length > 0) {
+        setValidationResult({
+          isValid: false,
+          errors: ProcessConverter.fromGraphIssues(issues),
+          warnings: validationResult?.warnings ?? [],
+        });
+      }
+      message.error(error.response?.data?.error || error.message || '保存失败');
     } finally {
       setSaving(false);
     }
@@ -254,6 +254,27 @@ describe('userApi', () => {
 
       expect(mockHttp.post).toHaveBeenCalledWith('/admin/users/123/deactivate');
     });
+
+    it('should call eraseUser API correctly', async () => {
+      const userId = 123;
+      const mockResponse = {
+        data: {
+          message: '用户数据已删除',
+          data: {
+            erasure: { id: 1, user_id: 123, tombstone_id: 7, digest: 'abc' },
+            impacted_definitions: [],
+            archives_rewritten: 0,
+          },
+        },
+      };
+
+      mockHttp.delete.mockResolvedValue(mockResponse);
+
+      const result = await userApi.eraseUser(userId, '员工离职');
+
+      expect(mockHttp.delete).toHaveBeenCalledWith('/admin/users/123', { data: { reason: '员工离职' } });
+      expect(result.erasure.tombstone_id).toBe(7);
+    });
   });
 
   describe('Error Handling', () => {
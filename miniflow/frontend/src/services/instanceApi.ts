@@ -7,19 +7,21 @@ import { http } from '../utils/http';
 import type { 
   ProcessInstance,
   InstanceListResponse,
+  InstanceCursorResponse,
   StartProcessRequest,
   SuspendInstanceRequest,
   CancelInstanceRequest,
-  InstanceHistory
+  InstanceHistory,
+  ArchivedInstanceHistory
 } from '../types/instance';
-import type { PaginationParams } from '../types/api';
+import type { PaginationParams, CursorPaginationParams } from '../types/api';
 
 export const instanceApi = {
   /**
    * 启动流程实例
    */
   async startProcess(processId: number, data: StartProcessRequest): Promise<ProcessInstance> {
-    const response = await http.post(`/process/${processId}/start`, data);
+    const response = await http.postIdempotent(`/process/${processId}/start`, data);
     return response.data;
   },
 
@@ -45,6 +47,20 @@ export const instanceApi = {
     return response.data;
   },
 
+  /**
+   * 按游标分页获取流程实例列表
+   */
+  async getInstancesByCursor(params: CursorPaginationParams & {
+    status?: string;
+    definition_id?: number;
+    starter_id?: number;
+    start_date?: string;
+    end_date?: string;
+  }): Promise<InstanceCursorResponse> {
+    const response = await http.get('/instances', { params });
+    return response.data;
+  },
+
   /**
    * 暂停流程实例
    */
@@ -72,5 +88,13 @@ export const instanceApi = {
   async getInstanceHistory(instanceId: number): Promise<InstanceHistory> {
     const response = await http.get(`/instance/${instanceId}/history`);
     return response.data;
+  },
+
+  /**
+   * 获取已归档实例的执行历史，实例归档后详情和历史接口返回 404
+   */
+  async getArchivedHistory(instanceId: number): Promise<ArchivedInstanceHistory> {
+    const response = await http.get(`/instance/${instanceId}/archive`);
+    return response.data as unknown as ArchivedInstanceHistory;
   }
 };
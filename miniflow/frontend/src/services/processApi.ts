@@ -9,7 +9,10 @@ import type {
   CreateProcessRequest, 
   UpdateProcessRequest,
   ProcessListResponse,
-  ProcessStats
+  ProcessStats,
+  DeprecateProcessResult,
+  BackendProcessDefinitionData,
+  DefinitionValidationResponse
 } from '../types/process';
 import type { PaginationParams } from '../types/api';
 
@@ -50,6 +53,17 @@ export const processApi = {
     return response.data.data;
   },
 
+  /**
+   * 验证流程定义但不保存，返回流程图结构错误的位置
+   */
+  async validateDefinition(definition: BackendProcessDefinitionData): Promise<DefinitionValidationResponse> {
+    const response = await http.post('/process/validate', { definition });
+    if (response.data.error) {
+      throw new Error(response.data.error);
+    }
+    return response.data.data;
+  },
+
   /**
    * 获取流程详情
    */
@@ -93,6 +107,38 @@ export const processApi = {
     return response.data.data;
   },
 
+  /**
+   * 基于流程标识的最新版本创建下一版本的草稿
+   */
+  async createNewVersion(id: number): Promise<ProcessDefinition> {
+    const response = await http.post(`/process/${id}/new-version`);
+    if (response.data.error) {
+      throw new Error(response.data.error);
+    }
+    return response.data.data;
+  },
+
+  /**
+   * 弃用已发布的流程版本，不能再启动新实例
+   */
+  async deprecateProcess(id: number): Promise<DeprecateProcessResult> {
+    const response = await http.post(`/process/${id}/deprecate`);
+    if (response.data.error) {
+      throw new Error(response.data.error);
+    }
+    return response.data.data;
+  },
+
+  /**
+   * 归档已发布或已弃用的流程版本，版本仍有运行中或挂起的实例时失败
+   */
+  async archiveProcess(id: number): Promise<void> {
+    const response = await http.post(`/process/${id}/archive`);
+    if (response.data.error) {
+      throw new Error(response.data.error);
+    }
+  },
+
   /**
    * 获取流程统计信息
    */
@@ -47,11 +47,27 @@ export class ProcessStateManager {
     },
     {
       from: 'published',
+      to: 'deprecated',
+      action: 'deprecate',
+      requiresConfirmation: true,
+      requiresPermission: ['process:archive'],
+      description: '弃用后不能再启动新实例，已启动的实例继续执行'
+    },
+    {
+      from: 'published',
+      to: 'archived',
+      action: 'archive',
+      requiresConfirmation: true,
+      requiresPermission: ['process:archive'],
+      description: '归档流程将停止其执行，但保留历史数据；仍有运行中的实例时不能归档'
+    },
+    {
+      from: 'deprecated',
       to: 'archived',
       action: 'archive',
       requiresConfirmation: true,
       requiresPermission: ['process:archive'],
-      description: '归档流程将停止其执行，但保留历史数据'
+      description: '已弃用版本的实例全部结束后可以归档'
     },
     {
       from: 'published',
@@ -165,6 +181,9 @@ export class ProcessStateManager {
         case 'publish':
           result = await this.publishProcess(processId);
           break;
+        case 'deprecate':
+          result = await this.deprecateProcess(processId);
+          break;
         case 'archive':
           result = await this.archiveProcess(processId);
           break;
@@ -207,10 +226,20 @@ export class ProcessStateManager {
    * 归档流程
    */
   private static async archiveProcess(processId: number): Promise<ProcessDefinition> {
-    const process = await processApi.getProcess(processId);
-    const updatedProcess = { ...process, status: 'archived' as const };
+    await processApi.archiveProcess(processId);
     message.success('流程归档成功');
-    return updatedProcess;
+    return processApi.getProcess(processId);
+  }
+
+  /**
+   * 弃用流程
+   */
+  private static async deprecateProcess(processId: number): Promise<ProcessDefinition> {
+    const { active_instances } = await processApi.deprecateProcess(processId);
+    message.success(active_instances > 0
+      ? `流程已弃用，${active_instances} 个运行中的实例将继续执行`
+      : '流程已弃用');
+    return processApi.getProcess(processId);
   }
 
   /**
@@ -250,6 +279,7 @@ export class ProcessStateManager {
     const statusNames = {
       draft: '草稿',
       published: '已发布',
+      deprecated: '已弃用',
       archived: '已归档',
     };
     return statusNames[status as keyof typeof statusNames] || status;
@@ -262,6 +292,7 @@ export class ProcessStateManager {
     const descriptions = {
       draft: '流程处于草稿状态，可以自由编辑，但不能执行',
       published: '流程已发布，可以创建实例并执行',
+      deprecated: '流程已弃用，不能创建新实例，已启动的实例继续执行',
       archived: '流程已归档，无法执行新实例，但保留历史数据',
     };
     return descriptions[status as keyof typeof descriptions] || '未知状态';
@@ -274,6 +305,7 @@ export class ProcessStateManager {
     const colors = {
       draft: '#fa8c16',
       published: '#52c41a',
+      deprecated: '#faad14',
       archived: '#8c8c8c',
     };
     return colors[status as keyof typeof colors] || '#d9d9d9';
@@ -7,26 +7,38 @@ import { http } from '../utils/http';
 import type { 
   TaskInstance,
   TaskListResponse,
+  TaskCursorResponse,
   TaskFormData,
   TaskFormDefinition,
   ClaimTaskRequest,
   CompleteTaskRequest,
-  DelegateTaskRequest
+  DelegateTaskRequest,
+  ResolveTaskRequest,
+  SavedTaskFilter,
+  SavedTaskFilterRequest,
+  UserTaskQueryParams
 } from '../types/task';
-import type { PaginationParams } from '../types/api';
+import type { PaginationParams, CursorPaginationParams } from '../types/api';
 
 export const taskApi = {
   /**
    * 获取用户任务列表
    */
-  async getUserTasks(params: PaginationParams & {
-    status?: string;
+  async getUserTasks(params: PaginationParams & UserTaskQueryParams & {
     priority?: string;
   }): Promise<TaskListResponse> {
     const response = await http.get('/user/tasks', { params });
     return response.data;
   },
 
+  /**
+   * 按游标分页获取用户任务列表，适合翻页较深的收件箱
+   */
+  async getUserTasksByCursor(params: CursorPaginationParams & UserTaskQueryParams): Promise<TaskCursorResponse> {
+    const response = await http.get('/user/tasks', { params });
+    return response.data;
+  },
+
   /**
    * 获取任务详情
    */
@@ -46,7 +58,7 @@ export const taskApi = {
    * 完成任务
    */
   async completeTask(taskId: number, data: CompleteTaskRequest): Promise<void> {
-    await http.post(`/task/${taskId}/complete`, data);
+    await http.postIdempotent(`/task/${taskId}/complete`, data);
   },
 
   /**
@@ -63,6 +75,13 @@ export const taskApi = {
     await http.post(`/task/${taskId}/delegate`, data);
   },
 
+  /**
+   * 受托人归还委派任务给任务所有人
+   */
+  async resolveTask(taskId: number, data: ResolveTaskRequest = {}): Promise<void> {
+    await http.post(`/task/${taskId}/resolve`, data);
+  },
+
   /**
    * 获取任务表单定义
    */
@@ -92,5 +111,36 @@ export const taskApi = {
   async getTasksByStatus(status: string, params: PaginationParams): Promise<TaskListResponse> {
     const response = await http.get(`/tasks/status/${status}`, { params });
     return response.data;
+  },
+
+  /**
+   * 获取当前用户保存的任务筛选器
+   */
+  async getSavedTaskFilters(): Promise<SavedTaskFilter[]> {
+    const response = await http.get('/user/task-filters');
+    return response.data;
+  },
+
+  /**
+   * 保存任务筛选器
+   */
+  async createSavedTaskFilter(data: SavedTaskFilterRequest): Promise<SavedTaskFilter> {
+    const response = await http.post('/user/task-filters', data);
+    return response.data;
+  },
+
+  /**
+   * 修改任务筛选器
+   */
+  async updateSavedTaskFilter(filterId: number, data: SavedTaskFilterRequest): Promise<SavedTaskFilter> {
+    const response = await http.put(`/user/task-filters/${filterId}`, data);
+    return response.data;
+  },
+
+  /**
+   * 删除任务筛选器
+   */
+  async deleteSavedTaskFilter(filterId: number): Promise<void> {
+    await http.delete(`/user/task-filters/${filterId}`);
   }
 };
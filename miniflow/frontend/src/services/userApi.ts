@@ -6,8 +6,11 @@ import type {
   UpdateProfileRequest, 
   ChangePasswordRequest,
   LoginResponse,
+  LogoutRequest,
   UserListResponse,
-  UserStats 
+  UserStats,
+  UserErasure,
+  EraseUserResponse
 } from '../types/user';
 import type { PaginationParams } from '../types/api';
 
@@ -21,6 +24,21 @@ export const userApi = {
     return response.data.data!;
   },
 
+  async refresh(refreshToken: string): Promise<LoginResponse> {
+    const response = await http.post<LoginResponse>('/auth/refresh', { refresh_token: refreshToken });
+    if ('error' in response.data) {
+      throw new Error(response.data.error);
+    }
+    return response.data.data!;
+  },
+
+  async logout(data: LogoutRequest = {}): Promise<void> {
+    const response = await http.post('/auth/logout', data);
+    if ('error' in response.data) {
+      throw new Error(response.data.error);
+    }
+  },
+
   async register(data: RegisterRequest): Promise<User> {
     const response = await http.post<User>('/auth/register', data);
     if ('error' in response.data) {
@@ -74,6 +92,23 @@ export const userApi = {
     }
   },
 
+  // Hard-deletes the user's personal data; their history is handed over to a placeholder account
+  async eraseUser(userId: number, reason?: string): Promise<EraseUserResponse> {
+    const response = await http.delete<EraseUserResponse>(`/admin/users/${userId}`, { data: { reason } });
+    if ('error' in response.data) {
+      throw new Error(response.data.error);
+    }
+    return response.data.data!;
+  },
+
+  async getUserErasures(userId: number): Promise<UserErasure[]> {
+    const response = await http.get<UserErasure[]>(`/admin/users/${userId}/erasures`);
+    if ('error' in response.data) {
+      throw new Error(response.data.error);
+    }
+    return response.data.data!;
+  },
+
   async getUserStats(): Promise<UserStats> {
     const response = await http.get<UserStats>('/admin/stats/users');
     if ('error' in response.data) {
@@ -92,5 +127,7 @@ export const {
   changePassword,
   getUsers,
   deactivateUser,
+  eraseUser,
+  getUserErasures,
   getUserStats,
 } = userApi;
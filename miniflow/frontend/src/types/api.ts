@@ -29,6 +29,18 @@ export interface PaginationParams {
   page_size?: number;
 }
 
+// 游标分页参数，cursor 为空字符串时返回第一页
+export interface CursorPaginationParams {
+  cursor: string;
+  limit?: number;
+}
+
+export interface CursorPage {
+  page_size: number;
+  next_cursor: string;
+  has_more: boolean;
+}
+
 export interface PaginatedResponse<T> {
   data: T[];
   total: number;
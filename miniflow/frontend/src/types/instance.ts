@@ -6,6 +6,7 @@
 import type { User } from './user';
 import type { ProcessDefinition } from './process';
 import type { TaskInstance } from './task';
+import type { CursorPage } from './api';
 
 // 流程实例类型定义
 export interface ProcessInstance {
@@ -64,6 +65,10 @@ export interface InstanceListResponse {
   total_pages: number;
 }
 
+export interface InstanceCursorResponse extends CursorPage {
+  instances: ProcessInstance[];
+}
+
 // 启动流程请求类型
 export interface StartProcessRequest {
   business_key: string;
@@ -85,16 +90,48 @@ export interface CancelInstanceRequest {
   reason: string;
 }
 
+// 活动历史类型
+export interface ActivityHistory {
+  id: number;
+  instance_id: number;
+  type: 'node_entered' | 'node_exited' | 'gateway_decision' | 'task_completed' | 'variable_changed' | 'state_transition' | 'execution_moved' | 'task_returned';
+  node_id?: string;
+  node_type?: string;
+  task_id?: number;
+  actor_id?: number;
+  detail?: string;
+  created_at: string;
+}
+
 // 执行历史类型
 export interface InstanceHistory {
   instance: ProcessInstance;
   tasks: TaskInstance[];
+  activities: ActivityHistory[];
   execution_path: string;
   created_at: string;
   start_time: string;
   end_time?: string;
 }
 
+// 已归档实例的执行历史，即归档时导出的文件内容
+export interface ArchivedInstanceHistory {
+  format_version: number;
+  archived_at: string;
+  instance: ProcessInstance;
+  child_instance_ids: number[];
+  tasks: TaskInstance[];
+  comments: Array<{
+    id: number;
+    task_id: number;
+    instance_id: number;
+    user_id: number;
+    body: string;
+    created_at: string;
+  }>;
+  activities: ActivityHistory[];
+}
+
 // 执行路径项类型
 export interface ExecutionPathItem {
   node: string;
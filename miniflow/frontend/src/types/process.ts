@@ -77,7 +77,7 @@ export interface ProcessDefinition {
   description?: string;
   category?: string;
   version: number;
-  status: 'draft' | 'published' | 'archived';
+  status: 'draft' | 'published' | 'deprecated' | 'archived';
   definition: BackendProcessDefinitionData;
   created_by: number;
   creator_name?: string;
@@ -110,10 +110,16 @@ export interface ProcessListResponse {
   page_size: number;
 }
 
+// 弃用流程版本的结果，弃用前启动的实例继续在该版本上执行
+export interface DeprecateProcessResult {
+  active_instances: number;
+}
+
 // 流程统计数据
 export interface ProcessStats {
   draft_count: number;
   published_count: number;
+  deprecated_count: number;
   archived_count: number;
   total_count: number;
 }
@@ -139,6 +145,33 @@ export interface ProcessValidationError {
   edgeId?: string;
 }
 
+// 后端流程图校验发现的结构错误，node_ids 和 flow_ids 是需要高亮的节点和连线
+export interface GraphIssue {
+  rule:
+    | 'missing-node-id'
+    | 'duplicate-node-id'
+    | 'duplicate-flow-id'
+    | 'unknown-flow-node'
+    | 'unreachable-node'
+    | 'no-path-to-end'
+    | 'infinite-cycle'
+    | 'gateway-missing-condition';
+  node_ids?: string[];
+  flow_ids?: string[];
+  message: string;
+}
+
+// 后端验证流程定义的结果，error 还包括没有位置的节点配置错误
+export interface DefinitionValidationResponse {
+  valid: boolean;
+  error?: string;
+  issues: GraphIssue[];
+  complexity: {
+    score: number;
+    findings: Array<{ rule: string; severity: 'warning' | 'info'; node_id?: string; message: string }>;
+  };
+}
+
 // 流程验证结果
 export interface ProcessValidationResult {
   isValid: boolean;
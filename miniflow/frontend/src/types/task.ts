@@ -5,6 +5,7 @@
 
 import type { User } from './user';
 import type { ProcessInstance } from './instance';
+import type { CursorPage } from './api';
 
 // 任务实例类型定义
 export interface TaskInstance {
@@ -13,6 +14,8 @@ export interface TaskInstance {
   node_id: string;
   name: string;
   assignee_id?: number;
+  owner_id?: number;
+  delegation_state?: 'pending' | 'resolved';
   status: TaskStatus;
   priority: number;
   due_date?: string;
@@ -85,6 +88,10 @@ export interface TaskListResponse {
   total_pages: number;
 }
 
+export interface TaskCursorResponse extends CursorPage {
+  tasks: TaskInstance[];
+}
+
 // 任务操作请求类型
 export interface ClaimTaskRequest {
   // 认领任务无需额外参数
@@ -100,6 +107,51 @@ export interface DelegateTaskRequest {
   comment?: string;
 }
 
+export interface ResolveTaskRequest {
+  form_data?: Record<string, any>;
+  comment?: string;
+}
+
+export interface TaskFilterCriteria {
+  statuses?: string[];
+  definition_keys?: string[];
+  node_ids?: string[];
+  min_priority?: number;
+  max_priority?: number;
+  due_after?: string;
+  due_before?: string;
+  business_key?: string;
+  text?: string;
+  started_by?: number;
+}
+
+export interface SavedTaskFilter {
+  id: number;
+  name: string;
+  criteria: TaskFilterCriteria;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface SavedTaskFilterRequest {
+  name: string;
+  criteria: TaskFilterCriteria;
+}
+
+export interface UserTaskQueryParams {
+  saved_filter?: number;
+  status?: string;
+  definition_key?: string;
+  node_id?: string;
+  priority_min?: number;
+  priority_max?: number;
+  due_after?: string;
+  due_before?: string;
+  business_key?: string;
+  q?: string;
+  started_by?: number | 'me';
+}
+
 export interface ReleaseTaskRequest {
   // 释放任务无需额外参数
 }
@@ -9,6 +9,7 @@ export interface User {
   role: string;
   status: string;
   avatar: string;
+  department_id: number | null;
   last_login_at: string | null;
   created_at: string;
 }
@@ -49,6 +50,14 @@ export interface ApiResponse<T = any> {
 export interface LoginResponse {
   user: User;
   token: string;
+  expires_at: string;
+  refresh_token: string;
+  refresh_expires_at: string;
+}
+
+export interface LogoutRequest {
+  refresh_token?: string;
+  all_sessions?: boolean;
 }
 
 export interface UserListResponse {
@@ -64,6 +73,25 @@ export interface UserStats {
   user_count: number;
 }
 
+// 用户数据删除记录，历史记录转给 tombstone_id 对应的占位账户
+export interface UserErasure {
+  id: number;
+  user_id: number;
+  username_hash: string;
+  tombstone_id: number;
+  erased_by: number;
+  erased_at: string;
+  reason: string;
+  rows: string;
+  digest: string;
+}
+
+export interface EraseUserResponse {
+  erasure: UserErasure;
+  impacted_definitions: unknown[];
+  archives_rewritten: number;
+}
+
 // Form validation types
 export interface FormErrors {
   [key: string]: string[];
@@ -120,4 +120,37 @@ describe('HttpClient', () => {
       // Note: Full upload test would require mock server setup
     });
   });
+
+  describe('Idempotent Requests', () => {
+    it('should retry an unanswered request with the same idempotency key', async () => {
+      const instance = httpClient.getInstance();
+      const timeout = Object.assign(new Error('timeout of 10000ms exceeded'), {
+        isAxiosError: true,
+        code: 'ECONNABORTED',
+      });
+      const post = vi.spyOn(instance, 'post')
+        .mockRejectedValueOnce(timeout)
+        .mockResolvedValueOnce({ data: { data: { id: 1 } } });
+
+      const response = await httpClient.postIdempotent('/process/1/start', { business_key: 'BK-1' });
+
+      expect(response.data).toEqual({ data: { id: 1 } });
+      expect(post).toHaveBeenCalledTimes(2);
+      const firstKey = post.mock.calls[0][2]?.headers?.['Idempotency-Key'];
+      expect(firstKey).toBeTruthy();
+      expect(post.mock.calls[1][2]?.headers?.['Idempotency-Key']).toBe(firstKey);
+    });
+
+    it('should not retry when the server responded', async () => {
+      const instance = httpClient.getInstance();
+      const badRequest = Object.assign(new Error('Request failed with status code 400'), {
+        isAxiosError: true,
+        response: { status: 400, data: { error: 'bad request' } },
+      });
+      const post = vi.spyOn(instance, 'post').mockRejectedValueOnce(badRequest);
+
+      await expect(httpClient.postIdempotent('/task/1/complete', {})).rejects.toBe(badRequest);
+      expect(post).toHaveBeenCalledTimes(1);
+    });
+  });
 });
@@ -0,0 +1,30 @@
+import { describe, it, expect } from 'vitest';
+import { ProcessConverter } from '../processConverter';
+
+describe('ProcessConverter', () => {
+  describe('fromGraphIssues', () => {
+    it('should report every node and flow of an issue', () => {
+      const errors = ProcessConverter.fromGraphIssues([
+        {
+          rule: 'infinite-cycle',
+          node_ids: ['a', 'g'],
+          flow_ids: ['f2'],
+          message: '循环 A → G 没有等待节点也没有条件，会无限执行',
+        },
+      ]);
+
+      expect(errors).toHaveLength(3);
+      expect(errors.map(e => e.nodeId).filter(Boolean)).toEqual(['a', 'g']);
+      expect(errors[2].edgeId).toBe('f2');
+      expect(errors.every(e => e.type === 'error')).toBe(true);
+    });
+
+    it('should keep issues without a location', () => {
+      const errors = ProcessConverter.fromGraphIssues([
+        { rule: 'missing-node-id', message: "节点 '审批' 缺少ID" },
+      ]);
+
+      expect(errors).toEqual([{ type: 'error', message: "节点 '审批' 缺少ID" }]);
+    });
+  });
+});
@@ -4,6 +4,8 @@ import type { ApiResponse, ApiError } from '../types/api';
 // HTTP client configuration
 const API_BASE_URL = import.meta.env.VITE_API_BASE_URL || 'http://localhost:8080/api/v1';
 const API_TIMEOUT = 10000;
+// Extra attempts for requests sent with an Idempotency-Key when no response arrives
+const IDEMPOTENT_RETRIES = 2;
 
 class HttpClient {
   private instance: AxiosInstance;
@@ -145,6 +147,32 @@ class HttpClient {
     return this.instance.delete(url, config);
   }
 
+  /**
+   * POST with an Idempotency-Key header. When the request times out or the
+   * network fails it is retried with the same key, so the server replays the
+   * original result instead of starting a process or completing a task twice.
+   */
+  public async postIdempotent<T = any>(
+    url: string,
+    data?: any,
+    config?: AxiosRequestConfig
+  ): Promise<AxiosResponse<ApiResponse<T>>> {
+    const key = crypto.randomUUID();
+    const headers = { ...config?.headers, 'Idempotency-Key': key };
+
+    for (let attempt = 0; ; attempt++) {
+      try {
+        return await this.instance.post(url, data, { ...config, headers });
+      } catch (error) {
+        // Only retry when the outcome is unknown; a server response is final
+        const unanswered = axios.isAxiosError(error) && !error.response;
+        if (!unanswered || attempt >= IDEMPOTENT_RETRIES) {
+          throw error;
+        }
+      }
+    }
+  }
+
   // Convenience methods for common patterns
   public async request<T = any>(config: AxiosRequestConfig): Promise<T> {
     try {
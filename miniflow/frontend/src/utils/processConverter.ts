@@ -9,7 +9,8 @@ import type {
   BackendProcessFlow, 
   BackendProcessDefinitionData,
   ProcessValidationResult,
-  ProcessValidationError
+  ProcessValidationError,
+  GraphIssue
 } from '../types/process';
 
 export class ProcessConverter {
@@ -248,6 +249,19 @@ export class ProcessConverter {
     };
   }
 
+  /**
+   * 将后端流程图校验的结构错误转换为设计器的验证错误，每个节点和连线各一条以便高亮
+   */
+  static fromGraphIssues(issues: GraphIssue[]): ProcessValidationError[] {
+    return issues.flatMap(issue => {
+      const located: ProcessValidationError[] = [
+        ...(issue.node_ids ?? []).map(nodeId => ({ type: 'error' as const, message: issue.message, nodeId })),
+        ...(issue.flow_ids ?? []).map(edgeId => ({ type: 'error' as const, message: issue.message, edgeId })),
+      ];
+      return located.length > 0 ? located : [{ type: 'error' as const, message: issue.message }];
+    });
+  }
+
   /**
    * 生成唯一的节点ID
    */
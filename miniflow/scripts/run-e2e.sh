@@ -0,0 +1,55 @@
+#!/bin/bash
+
+# MiniFlow End-to-End Test Runner
+# 启动 MySQL 和后端容器，等待服务就绪后运行集成测试，结束时清理容器
+
+set -u
+
+API_URL="${API_URL:-http://localhost:8080}"
+WAIT_SECONDS="${WAIT_SECONDS:-120}"
+KEEP_CONTAINERS="${KEEP_CONTAINERS:-false}"
+
+cd "$(dirname "$0")/.."
+
+cleanup() {
+    if [ "$KEEP_CONTAINERS" != "true" ]; then
+        echo "🧹 清理测试容器..."
+        docker compose down -v > /dev/null 2>&1
+    fi
+}
+trap cleanup EXIT
+
+echo "🚀 MiniFlow 端到端测试"
+echo "=================================="
+
+echo "🐳 启动 MySQL 和后端服务..."
+if ! docker compose up -d --build mysql redis backend; then
+    echo "❌ 服务启动失败"
+    exit 1
+fi
+
+echo "⏳ 等待后端服务就绪..."
+elapsed=0
+until curl -sf "$API_URL/health" > /dev/null 2>&1; do
+    if [ "$elapsed" -ge "$WAIT_SECONDS" ]; then
+        echo "❌ 后端服务在 ${WAIT_SECONDS}s 内未就绪"
+        docker compose logs --tail=50 backend
+        exit 1
+    fi
+    sleep 2
+    elapsed=$((elapsed + 2))
+done
+echo "✅ 后端服务已就绪"
+
+echo "🧪 运行集成测试..."
+(cd scripts/tests/api && python3 -m pytest integration/ -v --tb=short)
+status=$?
+
+if [ $status -eq 0 ]; then
+    echo "🎉 端到端测试通过"
+else
+    echo "❌ 端到端测试失败"
+    docker compose logs --tail=100 backend
+fi
+
+exit $status
@@ -0,0 +1,2495 @@
+"""
+流程端到端集成测试
+
+覆盖完整业务链路: 注册 → 登录 → 设计流程 → 发布 → 启动 → 认领 → 完成 → 网关分支 → 结束，
+并通过实例详情、任务详情和任务变更接口校验每一步落库后的状态与事件。
+菱形流程覆盖并行网关的分叉与汇聚：汇聚网关要等所有分支完成后才继续推进。
+会签流程覆盖多实例用户任务：满足 "2 of 3" 完成条件后跳过其余任务并推进。
+
+运行前需要启动 MySQL 和后端服务，可直接使用 scripts/run-e2e.sh。
+"""
+
+import json
+import random
+import string
+import time
+
+import pytest
+from lib.base_test import BaseAPITest
+
+
+def random_suffix(length: int = 6) -> str:
+    """生成随机后缀，避免与已有数据冲突"""
+    return ''.join(random.choices(string.ascii_lowercase + string.digits, k=length))
+
+
+def approval_definition() -> dict:
+    """
+    带排他网关的审批流程:
+    开始 → 提交申请 → 金额判断 → (level == high) 经理审批 → 结束
+                              → (默认)           结束
+    两个用户任务都分配给发起人，便于单个测试用户走完全部流程
+    """
+    return {
+        "nodes": [
+            {"id": "start", "type": "start", "name": "开始", "x": 100, "y": 100},
+            {"id": "submit", "type": "userTask", "name": "提交申请", "x": 250, "y": 100,
+             "props": {"assignee": "${starter.id}"}},
+            {"id": "check", "type": "gateway", "name": "金额判断", "x": 400, "y": 100,
+             "props": {"gatewayType": "exclusive"}},
+            {"id": "manager", "type": "userTask", "name": "经理审批", "x": 550, "y": 50,
+             "props": {"assignee": "${starter.id}"}},
+            {"id": "end", "type": "end", "name": "结束", "x": 700, "y": 100},
+        ],
+        "flows": [
+            {"id": "f1", "from": "start", "to": "submit"},
+            {"id": "f2", "from": "submit", "to": "check"},
+            {"id": "f3", "from": "check", "to": "manager", "condition": "${level} == 'high'", "label": "大额"},
+            {"id": "f4", "from": "check", "to": "end", "label": "默认"},
+            {"id": "f5", "from": "manager", "to": "end"},
+        ],
+    }
+
+
+def diamond_definition() -> dict:
+    """
+    菱形并行流程:
+    开始 → 并行分叉 → 财务审核 → 并行汇聚 → 归档 → 结束
+                   → 法务审核 →
+    所有用户任务都分配给发起人
+    """
+    return {
+        "nodes": [
+            {"id": "start", "type": "start", "name": "开始", "x": 100, "y": 100},
+            {"id": "fork", "type": "gateway", "name": "并行分叉", "x": 250, "y": 100,
+             "props": {"gatewayType": "parallel"}},
+            {"id": "finance", "type": "userTask", "name": "财务审核", "x": 400, "y": 50,
+             "props": {"assignee": "${starter.id}"}},
+            {"id": "legal", "type": "userTask", "name": "法务审核", "x": 400, "y": 150,
+             "props": {"assignee": "${starter.id}"}},
+            {"id": "join", "type": "gateway", "name": "并行汇聚", "x": 550, "y": 100,
+             "props": {"gatewayType": "parallel"}},
+            {"id": "archive", "type": "userTask", "name": "归档", "x": 700, "y": 100,
+             "props": {"assignee": "${starter.id}"}},
+            {"id": "end", "type": "end", "name": "结束", "x": 850, "y": 100},
+        ],
+        "flows": [
+            {"id": "f1", "from": "start", "to": "fork"},
+            {"id": "f2", "from": "fork", "to": "finance"},
+            {"id": "f3", "from": "fork", "to": "legal"},
+            {"id": "f4", "from": "finance", "to": "join"},
+            {"id": "f5", "from": "legal", "to": "join"},
+            {"id": "f6", "from": "join", "to": "archive"},
+            {"id": "f7", "from": "archive", "to": "end"},
+        ],
+    }
+
+
+
+def inclusive_diamond_definition() -> dict:
+    """
+    包容网关流程:
+    开始 → 包容分叉 → (默认)            财务审核 → 包容汇聚 → 归档 → 结束
+                   → (level == high) 法务审核 →
+    所有用户任务都分配给发起人
+    """
+    return {
+        "nodes": [
+            {"id": "start", "type": "start", "name": "开始", "x": 100, "y": 100},
+            {"id": "fork", "type": "gateway", "name": "包容分叉", "x": 250, "y": 100,
+             "props": {"gatewayType": "inclusive"}},
+            {"id": "finance", "type": "userTask", "name": "财务审核", "x": 400, "y": 50,
+             "props": {"assignee": "${starter.id}"}},
+            {"id": "legal", "type": "userTask", "name": "法务审核", "x": 400, "y": 150,
+             "props": {"assignee": "${starter.id}"}},
+            {"id": "join", "type": "gateway", "name": "包容汇聚", "x": 550, "y": 100,
+             "props": {"gatewayType": "inclusive"}},
+            {"id": "archive", "type": "userTask", "name": "归档", "x": 700, "y": 100,
+             "props": {"assignee": "${starter.id}"}},
+            {"id": "end", "type": "end", "name": "结束", "x": 850, "y": 100},
+        ],
+        "flows": [
+            {"id": "f1", "from": "start", "to": "fork"},
+            {"id": "f2", "from": "fork", "to": "finance"},
+            {"id": "f3", "from": "fork", "to": "legal", "condition": "${level} == 'high'"},
+            {"id": "f4", "from": "finance", "to": "join"},
+            {"id": "f5", "from": "legal", "to": "join"},
+            {"id": "f6", "from": "join", "to": "archive"},
+            {"id": "f7", "from": "archive", "to": "end"},
+        ],
+    }
+
+def countersign_definition() -> dict:
+    """
+    会签流程:
+    开始 → 部门会签（approvers 中三人并行，两人完成即通过）→ 结束
+    """
+    return {
+        "nodes": [
+            {"id": "start", "type": "start", "name": "开始", "x": 100, "y": 100},
+            {"id": "countersign", "type": "userTask", "name": "部门会签", "x": 250, "y": 100,
+             "props": {"multiInstance": {
+                 "mode": "parallel",
+                 "assigneesVariable": "approvers",
+                 "completion": "2 of 3",
+             }}},
+            {"id": "end", "type": "end", "name": "结束", "x": 400, "y": 100},
+        ],
+        "flows": [
+            {"id": "f1", "from": "start", "to": "countersign"},
+            {"id": "f2", "from": "countersign", "to": "end"},
+        ],
+    }
+
+
+class TestProcessFlow(BaseAPITest):
+    """流程端到端测试类"""
+
+    # 等待流程推进的最长时间（秒）
+    ADVANCE_TIMEOUT = 10
+
+    def _register_and_login(self):
+        """注册独立的测试用户并登录，测试之间互不影响"""
+        suffix = random_suffix()
+        username = f"e2e_user_{suffix}"
+        password = "testpass123"
+
+        success, response, status = self.make_request(
+            'POST', '/auth/register',
+            data={
+                "username": username,
+                "password": password,
+                "email": f"e2e_{suffix}@example.com",
+            },
+            expected_status=201,
+        )
+        assert success, f"用户注册失败: {response}"
+
+        success, response, status = self.make_request(
+            'POST', '/auth/login',
+            data={"username": username, "password": password},
+        )
+        assert success, f"用户登录失败: {response}"
+        self.token = response['data']['token']
+        self.test_user_id = response['data']['user']['id']
+
+    def _admin_request(self, method: str, endpoint: str, **kwargs):
+        """以管理员身份调用接口，调用后恢复当前测试用户的登录状态"""
+        token, user_id = self.token, self.test_user_id
+        assert self.login("admin"), "管理员登录失败"
+        try:
+            return self.make_request(method, endpoint, auth_required=True, **kwargs)
+        finally:
+            self.token, self.test_user_id = token, user_id
+
+    def _create_and_publish_process(self, definition: dict = None) -> int:
+        """设计并发布流程（默认为审批流程），返回流程定义ID"""
+        success, response, status = self.make_request(
+            'POST', '/process',
+            data={
+                "key": f"e2e_approval_{random_suffix()}",
+                "name": "端到端审批流程",
+                "description": "集成测试使用的网关分支流程",
+                "category": "test",
+                "definition": definition or approval_definition(),
+            },
+            expected_status=201,
+            auth_required=True,
+        )
+        assert success, f"创建流程失败: {response}"
+        process_id = response['data']['id']
+        assert response['data']['status'] == 'draft', "新建流程应为草稿状态"
+
+        success, response, status = self.make_request(
+            'POST', f'/process/{process_id}/publish', auth_required=True)
+        assert success, f"发布流程失败: {response}"
+
+        success, response, status = self.make_request(
+            'GET', f'/process/{process_id}', auth_required=True)
+        assert success, f"获取流程失败: {response}"
+        assert response['data']['status'] == 'published', "发布后流程应为已发布状态"
+
+        return process_id
+
+    def _start_instance(self, process_id: int, level: str, variables: dict = None) -> dict:
+        """启动流程实例，返回实例数据"""
+        success, response, status = self.make_request(
+            'POST', f'/process/{process_id}/start',
+            data={
+                "business_key": f"E2E-{random_suffix(10)}",
+                "title": "端到端测试申请",
+                "variables": {"level": level, **(variables or {})},
+                "priority": 50,
+            },
+            expected_status=201,
+            auth_required=True,
+        )
+        assert success, f"启动流程失败: {response}"
+        instance = response['data']
+        assert instance['status'] == 'running', "新启动的实例应为运行状态"
+        return instance
+
+    def _get_instance(self, instance_id: int) -> dict:
+        """获取流程实例详情"""
+        success, response, status = self.make_request(
+            'GET', f'/instance/{instance_id}', auth_required=True)
+        assert success, f"获取实例失败: {response}"
+        return response['data']
+
+    def _get_task(self, task_id: int) -> dict:
+        """获取任务详情"""
+        success, response, status = self.make_request(
+            'GET', f'/task/{task_id}', auth_required=True)
+        assert success, f"获取任务失败: {response}"
+        return response['data']
+
+    def _wait_for_task(self, instance_id: int, node_id: str) -> dict:
+        """等待实例在指定节点上生成待办任务"""
+        deadline = time.time() + self.ADVANCE_TIMEOUT
+        while time.time() < deadline:
+            success, response, status = self.make_request(
+                'GET', f'/user/tasks?filter[instance_id][eq]={instance_id}&filter[node_id][eq]={node_id}',
+                auth_required=True)
+            assert success, f"获取待办任务失败: {response}"
+            tasks = response['data']['tasks']
+            if tasks:
+                task = tasks[0]
+                assert task['status'] == 'assigned', "任务应按处理人表达式分配给发起人"
+                return task
+            time.sleep(0.5)
+        pytest.fail(f"实例 {instance_id} 未在节点 {node_id} 上生成任务")
+
+    def _wait_for_instance_status(self, instance_id: int, expected: str) -> dict:
+        """等待实例进入指定状态"""
+        deadline = time.time() + self.ADVANCE_TIMEOUT
+        instance = {}
+        while time.time() < deadline:
+            instance = self._get_instance(instance_id)
+            if instance['status'] == expected:
+                return instance
+            time.sleep(0.5)
+        pytest.fail(f"实例 {instance_id} 状态应为 {expected}，实际为 {instance.get('status')}")
+
+    def _node_task_count(self, instance_id: int, node_id: str) -> int:
+        """统计实例在指定节点上生成的任务数"""
+        success, response, status = self.make_request(
+            'GET', f'/user/tasks?filter[instance_id][eq]={instance_id}&filter[node_id][eq]={node_id}',
+            auth_required=True)
+        assert success, f"获取待办任务失败: {response}"
+        return response['data']['total']
+
+    def _task_cursor(self) -> int:
+        """获取任务变更的当前游标"""
+        success, response, status = self.make_request(
+            'GET', '/user/tasks/changes?wait=0', auth_required=True)
+        assert success, f"获取任务变更游标失败: {response}"
+        return response['data']['cursor']
+
+    def _task_events(self, cursor: int, task_id: int) -> list:
+        """获取游标之后指定任务的变更事件类型"""
+        success, response, status = self.make_request(
+            'GET', f'/user/tasks/changes?since={cursor}&wait=0', auth_required=True)
+        assert success, f"获取任务变更失败: {response}"
+        return [change['type'] for change in response['data']['changes'] if change['task_id'] == task_id]
+
+    def _claim_and_complete(self, task_id: int, comment: str):
+        """认领并完成任务，校验任务状态与变更事件"""
+        cursor = self._task_cursor()
+
+        success, response, status = self.make_request(
+            'POST', f'/task/{task_id}/claim', auth_required=True)
+        assert success, f"认领任务失败: {response}"
+
+        task = self._get_task(task_id)
+        assert task['status'] == 'claimed', "认领后任务应为已认领状态"
+        assert task['assignee_id'] == self.test_user_id, "认领后任务应分配给当前用户"
+
+        success, response, status = self.make_request(
+            'POST', f'/task/{task_id}/complete',
+            data={"comment": comment},
+            auth_required=True)
+        assert success, f"完成任务失败: {response}"
+
+        task = self._get_task(task_id)
+        assert task['status'] == 'completed', "完成后任务应为已完成状态"
+
+        events = self._task_events(cursor, task_id)
+        assert events[:1] == ['claimed'], f"应先记录认领事件，实际为 {events}"
+        assert 'completed' in events, f"应记录完成事件，实际为 {events}"
+
+        # 重复提交应被拒绝
+        success, response, status = self.make_request(
+            'POST', f'/task/{task_id}/complete',
+            data={"comment": comment},
+            expected_status=409,
+            auth_required=True)
+        assert success, f"重复完成任务应返回409，实际为 {status}"
+
+    def test_high_level_goes_through_manager_approval(self):
+        """测试网关条件命中时经过经理审批后结束"""
+        self.log("测试网关条件分支", "info")
+
+        self._register_and_login()
+        process_id = self._create_and_publish_process()
+        instance = self._start_instance(process_id, "high")
+        instance_id = instance['id']
+
+        submit_task = self._wait_for_task(instance_id, 'submit')
+        self._claim_and_complete(submit_task['id'], "提交申请")
+
+        # 网关命中条件，流程停在经理审批
+        manager_task = self._wait_for_task(instance_id, 'manager')
+        instance = self._get_instance(instance_id)
+        assert instance['status'] == 'running', "经理审批前实例应保持运行状态"
+        assert instance['current_node'] == 'manager', "网关应走向经理审批节点"
+
+        self._claim_and_complete(manager_task['id'], "同意")
+
+        instance = self._wait_for_instance_status(instance_id, 'completed')
+        assert instance['current_node'] == 'end', "流程应在结束节点完成"
+
+        self.log("网关条件分支测试通过", "success")
+
+    def test_default_branch_ends_without_approval(self):
+        """测试网关条件未命中时走默认路径直接结束"""
+        self.log("测试网关默认分支", "info")
+
+        self._register_and_login()
+        process_id = self._create_and_publish_process()
+        instance = self._start_instance(process_id, "low")
+        instance_id = instance['id']
+
+        submit_task = self._wait_for_task(instance_id, 'submit')
+        self._claim_and_complete(submit_task['id'], "提交申请")
+
+        instance = self._wait_for_instance_status(instance_id, 'completed')
+        assert instance['current_node'] == 'end', "流程应在结束节点完成"
+
+        # 默认路径不应生成经理审批任务
+        assert self._node_task_count(instance_id, 'manager') == 0, "默认路径不应生成经理审批任务"
+
+        self.log("网关默认分支测试通过", "success")
+
+    def test_parallel_join_waits_for_all_branches(self):
+        """测试菱形流程的并行汇聚网关等所有分支完成后才推进"""
+        self.log("测试并行网关汇聚", "info")
+
+        self._register_and_login()
+        process_id = self._create_and_publish_process(diamond_definition())
+        instance = self._start_instance(process_id, "normal")
+        instance_id = instance['id']
+
+        # 分叉后两个分支同时生成任务
+        finance_task = self._wait_for_task(instance_id, 'finance')
+        legal_task = self._wait_for_task(instance_id, 'legal')
+
+        # 只完成一个分支时汇聚网关不能推进
+        self._claim_and_complete(finance_task['id'], "财务通过")
+        time.sleep(1)
+        assert self._node_task_count(instance_id, 'archive') == 0, "只有一个分支完成时不应推进到归档"
+        instance = self._get_instance(instance_id)
+        assert instance['status'] == 'running', "等待其他分支时实例应保持运行状态"
+
+        # 最后一个分支完成后推进一次
+        self._claim_and_complete(legal_task['id'], "法务通过")
+        archive_task = self._wait_for_task(instance_id, 'archive')
+        assert self._node_task_count(instance_id, 'archive') == 1, "汇聚后只应生成一个归档任务"
+
+        self._claim_and_complete(archive_task['id'], "归档")
+        instance = self._wait_for_instance_status(instance_id, 'completed')
+        assert instance['current_node'] == 'end', "流程应在结束节点完成"
+
+        self.log("并行网关汇聚测试通过", "success")
+
+    def test_countersign_advances_when_condition_met(self):
+        """测试会签任务在 2 of 3 完成条件满足后跳过剩余任务并推进"""
+        self.log("测试多实例会签", "info")
+
+        # 三个会签人各自注册，最后登录的用户负责设计和启动流程
+        approvers = []
+        for _ in range(3):
+            self._register_and_login()
+            approvers.append((self.token, self.test_user_id))
+
+        process_id = self._create_and_publish_process(countersign_definition())
+        instance = self._start_instance(process_id, "normal", {
+            "approvers": [user_id for _, user_id in approvers],
+        })
+        instance_id = instance['id']
+
+        tasks = []
+        for token, user_id in approvers:
+            self.token, self.test_user_id = token, user_id
+            tasks.append(self._wait_for_task(instance_id, 'countersign'))
+
+        # 第一人完成后仍在等待
+        self.token, self.test_user_id = approvers[0]
+        self._claim_and_complete(tasks[0]['id'], "同意")
+        time.sleep(1)
+        instance = self._get_instance(instance_id)
+        assert instance['status'] == 'running', "只有一人完成时会签不应通过"
+
+        # 第二人完成后满足条件，第三人的任务被跳过
+        self.token, self.test_user_id = approvers[1]
+        self._claim_and_complete(tasks[1]['id'], "同意")
+        instance = self._wait_for_instance_status(instance_id, 'completed')
+        assert instance['current_node'] == 'end', "流程应在结束节点完成"
+
+        self.token, self.test_user_id = approvers[2]
+        task = self._get_task(tasks[2]['id'])
+        assert task['status'] == 'skipped', "满足完成条件后剩余会签任务应被跳过"
+
+        self.log("多实例会签测试通过", "success")
+
+    def test_condition_error_holds_instance_at_gateway(self):
+        """测试网关条件引用不存在的变量时不走任何分支，实例停留在网关"""
+        self.log("测试网关条件评估失败", "info")
+
+        definition = approval_definition()
+        for flow in definition['flows']:
+            if flow['id'] == 'f3':
+                flow['condition'] = "${amount} > 1000 && ${level} != 'low'"
+
+        self._register_and_login()
+        process_id = self._create_and_publish_process(definition)
+        instance = self._start_instance(process_id, "high")
+        instance_id = instance['id']
+
+        submit_task = self._wait_for_task(instance_id, 'submit')
+        self._claim_and_complete(submit_task['id'], "提交申请")
+
+        # 条件评估失败不能默认命中，也不能落到默认路径
+        time.sleep(1)
+        instance = self._get_instance(instance_id)
+        assert instance['status'] == 'running', "条件评估失败时实例应保持运行状态"
+        assert self._node_task_count(instance_id, 'manager') == 0, "条件评估失败时不应生成经理审批任务"
+
+        self.log("网关条件评估失败测试通过", "success")
+
+    def test_next_steps_preview_follows_gateway_condition(self):
+        """测试后续路径预览按当前变量评估网关条件"""
+        self.log("测试后续路径预览", "info")
+
+        self._register_and_login()
+        process_id = self._create_and_publish_process()
+        instance = self._start_instance(process_id, "high")
+        instance_id = instance['id']
+        self._wait_for_task(instance_id, 'submit')
+
+        success, response, status = self.make_request(
+            'GET', f'/instance/{instance_id}/next-steps', auth_required=True)
+        assert success, f"获取后续路径失败: {response}"
+
+        nodes = response['data']['nodes']
+        assert [node['node_id'] for node in nodes] == ['submit'], "活动节点应为提交申请"
+        steps = nodes[0]['steps']
+        assert steps[0]['node_id'] == 'check' and steps[0]['taken'], "提交后应进入金额判断网关"
+
+        gateway_steps = {step['node_id']: step for step in steps[0]['next']}
+        assert gateway_steps['manager']['taken'], "level 为 high 时应走经理审批"
+        assert gateway_steps['manager']['result'] == 'condition_met'
+        assert not gateway_steps['end']['taken'], "条件命中时不应走默认路径"
+
+        self.log("后续路径预览测试通过", "success")
+
+    def test_history_records_activities_in_order(self):
+        """测试执行历史按发生顺序记录节点进出、网关决策和状态转换"""
+        self.log("测试活动历史", "info")
+
+        self._register_and_login()
+        process_id = self._create_and_publish_process()
+        instance = self._start_instance(process_id, "low")
+        instance_id = instance['id']
+
+        submit_task = self._wait_for_task(instance_id, 'submit')
+        self._claim_and_complete(submit_task['id'], "提交申请")
+        self._wait_for_instance_status(instance_id, 'completed')
+
+        success, response, status = self.make_request(
+            'GET', f'/instance/{instance_id}/history', auth_required=True)
+        assert success, f"获取执行历史失败: {response}"
+
+        activities = response['data']['activities']
+        ids = [activity['id'] for activity in activities]
+        assert ids == sorted(ids), "活动历史应按发生顺序排列"
+
+        steps = [(activity['type'], activity.get('node_id')) for activity in activities
+                 if activity['type'] != 'variable_changed']
+        assert steps == [
+            ('state_transition', 'start'),
+            ('node_entered', 'start'),
+            ('node_exited', 'start'),
+            ('node_entered', 'submit'),
+            ('task_completed', 'submit'),
+            ('node_exited', 'submit'),
+            ('node_entered', 'check'),
+            ('gateway_decision', 'check'),
+            ('node_exited', 'check'),
+            ('node_entered', 'end'),
+            ('state_transition', 'end'),
+        ], f"活动历史顺序不符合预期: {steps}"
+
+        completed = next(a for a in activities if a['type'] == 'task_completed')
+        assert completed['task_id'] == submit_task['id'], "任务完成记录应关联任务"
+        assert completed.get('actor_id'), "任务完成记录应包含操作人"
+
+        success, response, status = self.make_request(
+            'GET', f'/instance/{instance_id}/history?types=unknown',
+            expected_status=400, auth_required=True)
+        assert success, f"非法的活动类型应返回400，实际为 {status}"
+
+        self.log("活动历史测试通过", "success")
+
+    def test_trace_requires_admin(self):
+        """测试普通用户不能开启或查看执行跟踪"""
+        self.log("测试执行跟踪权限", "info")
+
+        self._register_and_login()
+        process_id = self._create_and_publish_process()
+
+        success, response, status = self.make_request(
+            'POST', f'/process/{process_id}/start',
+            data={
+                "business_key": f"E2E-{random_suffix(10)}",
+                "variables": {"level": "low"},
+                "priority": 50,
+                "trace": True,
+            },
+            expected_status=403,
+            auth_required=True,
+        )
+        assert success, f"普通用户开启执行跟踪应返回403，实际为 {status}"
+
+        instance = self._start_instance(process_id, "low")
+        success, response, status = self.make_request(
+            'GET', f"/instance/{instance['id']}/trace", expected_status=403, auth_required=True)
+        assert success, f"普通用户查看执行跟踪应返回403，实际为 {status}"
+
+        # 管理员开启跟踪后可以看到实例创建和进入节点的记录
+        success, response, status = self._admin_request(
+            'POST', f'/process/{process_id}/start',
+            data={
+                "business_key": f"E2E-{random_suffix(10)}",
+                "variables": {"level": "low"},
+                "priority": 50,
+                "trace": True,
+            },
+            expected_status=201,
+        )
+        assert success, f"管理员开启执行跟踪启动流程失败: {response}"
+        traced_id = response['data']['id']
+
+        success, response, status = self._admin_request('GET', f'/instance/{traced_id}/trace')
+        assert success, f"管理员查看执行跟踪失败: {response}"
+        entries = response['data']
+        assert entries, "开启跟踪的实例应有跟踪记录"
+        assert all(entry['instance_id'] == traced_id for entry in entries), "跟踪记录应属于该实例"
+        assert 'submit' in {entry.get('node_id') for entry in entries}, "跟踪记录应包含进入提交申请节点"
+
+        self.log("执行跟踪权限测试通过", "success")
+
+    def test_deactivated_assignee_blocks_publish_until_remapped(self):
+        """测试引用已停用用户的流程不能发布，替换引用后可以发布"""
+        self.log("测试停用用户的引用检查与替换", "info")
+
+        self._register_and_login()
+        assignee_id = self.test_user_id
+        self._register_and_login()
+        owner_id = self.test_user_id
+
+        definition = approval_definition()
+        definition['nodes'][1]['props'] = {"assignee": f"user:{assignee_id}"}
+        success, response, status = self.make_request(
+            'POST', '/process',
+            data={
+                "key": f"e2e_refs_{random_suffix()}",
+                "name": "引用检查流程",
+                "category": "test",
+                "definition": definition,
+            },
+            expected_status=201,
+            auth_required=True,
+        )
+        assert success, f"创建流程失败: {response}"
+        process_id = response['data']['id']
+
+        # 停用处理人时报告受影响的流程定义
+        success, response, status = self._admin_request(
+            'POST', f'/admin/users/{assignee_id}/deactivate')
+        assert success, f"停用用户失败: {response}"
+        impacted = {item['process_id']: item for item in response['data']['impacted_definitions']}
+        assert process_id in impacted, "停用用户应报告引用该用户的流程定义"
+        issue = impacted[process_id]['issues'][0]
+        assert (issue['node_id'], issue['field'], issue['problem']) == ('submit', 'assignee', 'inactive')
+
+        success, response, status = self.make_request(
+            'POST', f'/process/{process_id}/publish', expected_status=400, auth_required=True)
+        assert success, f"引用停用用户的流程不应发布成功，实际为 {status}"
+
+        success, response, status = self._admin_request(
+            'POST', '/admin/user-references/remap',
+            data={
+                "mappings": [{"from_user_id": assignee_id, "to_user_id": owner_id}],
+                "process_ids": [process_id],
+            },
+        )
+        assert success, f"替换用户引用失败: {response}"
+        assert response['data'][0]['process_id'] == process_id
+
+        success, response, status = self.make_request(
+            'GET', f'/process/{process_id}', auth_required=True)
+        assert success, f"获取流程失败: {response}"
+        assignee = response['data']['definition']['nodes'][1]['props']['assignee']
+        assert assignee == f"user:{owner_id}", "替换后应保留 user:<id> 形式"
+
+        success, response, status = self.make_request(
+            'POST', f'/process/{process_id}/publish', auth_required=True)
+        assert success, f"替换引用后发布流程失败: {response}"
+
+        self.log("停用用户引用检查测试通过", "success")
+
+    def test_runtime_snapshot_requires_admin(self):
+        """测试普通用户不能导出或导入运行时快照"""
+        self.log("测试运行时快照权限", "info")
+
+        self._register_and_login()
+
+        success, response, status = self.make_request(
+            'GET', '/admin/runtime-snapshot', expected_status=403, auth_required=True)
+        assert success, f"普通用户导出运行时快照应返回403，实际为 {status}"
+
+        success, response, status = self.make_request(
+            'POST', '/admin/runtime-snapshot/import?dry_run=true',
+            data={"format": 1, "instances": []},
+            expected_status=403,
+            auth_required=True,
+        )
+        assert success, f"普通用户导入运行时快照应返回403，实际为 {status}"
+
+        # 管理员导出的快照包含运行中的实例，可以原样试导入
+        process_id = self._create_and_publish_process()
+        instance = self._start_instance(process_id, "low")
+
+        success, response, status = self._admin_request('GET', '/admin/runtime-snapshot')
+        assert success, f"管理员导出运行时快照失败: {response}"
+        snapshot = response['data']
+        assert snapshot['digest'], "快照应带有摘要"
+        assert instance['id'] in {item['id'] for item in snapshot['instances']}, "快照应包含运行中的实例"
+
+        success, response, status = self._admin_request(
+            'POST', '/admin/runtime-snapshot/import?dry_run=true', data=snapshot)
+        assert success, f"管理员试导入运行时快照失败: {response}"
+        assert response['data']['dry_run'] is True, "dry_run 导入不应写入数据"
+        assert response['data']['rows']['process_instances'] == len(snapshot['instances']), "试导入应报告快照中的实例数"
+
+        self.log("运行时快照权限测试通过", "success")
+
+    def test_webhook_subscription_requires_admin(self):
+        """测试普通用户不能注册或查看事件订阅"""
+        self.log("测试事件订阅权限", "info")
+
+        self._register_and_login()
+
+        success, response, status = self.make_request(
+            'POST', '/webhooks',
+            data={"url": "https://example.com/hooks/miniflow", "event_types": ["task.created"]},
+            expected_status=403,
+            auth_required=True,
+        )
+        assert success, f"普通用户注册事件订阅应返回403，实际为 {status}"
+
+        success, response, status = self.make_request(
+            'GET', '/webhooks', expected_status=403, auth_required=True)
+        assert success, f"普通用户查看事件订阅应返回403，实际为 {status}"
+
+        # 管理员注册的订阅出现在列表中，删除后不再出现
+        success, response, status = self._admin_request(
+            'POST', '/webhooks',
+            data={"url": "https://example.com/hooks/miniflow", "event_types": ["task.created"]},
+            expected_status=201,
+        )
+        assert success, f"管理员注册事件订阅失败: {response}"
+        subscription_id = response['data']['id']
+        assert response['data']['url'] == "https://example.com/hooks/miniflow"
+
+        success, response, status = self._admin_request('GET', '/webhooks')
+        assert success, f"管理员查看事件订阅失败: {response}"
+        assert subscription_id in {item['id'] for item in response['data']}, "新注册的订阅应出现在列表中"
+
+        success, response, status = self._admin_request('DELETE', f'/webhooks/{subscription_id}')
+        assert success, f"管理员删除事件订阅失败: {response}"
+
+        success, response, status = self._admin_request('GET', '/webhooks')
+        assert subscription_id not in {item['id'] for item in response['data']}, "删除后的订阅不应出现在列表中"
+
+        self.log("事件订阅权限测试通过", "success")
+
+    def test_complexity_budget_blocks_publish(self):
+        """测试保存时计算复杂度评分，超过复杂度预算的流程不能发布"""
+        self.log("测试复杂度评分与预算", "info")
+
+        self._register_and_login()
+
+        key = f"e2e_complexity_{random_suffix()}"
+        success, response, status = self.make_request(
+            'POST', '/process',
+            data={
+                "key": key,
+                "name": "复杂度流程",
+                "category": "test",
+                "definition": diamond_definition(),
+            },
+            expected_status=201,
+            auth_required=True,
+        )
+        assert success, f"创建流程失败: {response}"
+        process_id = response['data']['id']
+
+        complexity = response['data']['complexity']
+        assert complexity['node_count'] == 7
+        assert complexity['gateway_count'] == 2
+        assert complexity['max_path_length'] == 6
+        assert complexity['unbounded_loops'] == 0
+        assert complexity['score'] == 7 + 2 * 3 + 6
+        rules = {(finding['rule'], finding['node_id']) for finding in complexity['findings']}
+        assert ('single-path-gateway', 'join') in rules, "只有一条出口的汇聚网关应给出检查提示"
+
+        success, response, status = self._admin_request(
+            'PUT', f'/admin/complexity-budgets/{key}', data={"max_score": 10})
+        assert success, f"设置复杂度预算失败: {response}"
+
+        success, response, status = self.make_request(
+            'POST', f'/process/{process_id}/publish', expected_status=400, auth_required=True)
+        assert success, f"超过复杂度预算的流程不应发布成功，实际为 {status}"
+
+        success, response, status = self._admin_request(
+            'DELETE', f'/admin/complexity-budgets/{key}')
+        assert success, f"删除复杂度预算失败: {response}"
+
+        success, response, status = self.make_request(
+            'POST', f'/process/{process_id}/publish', auth_required=True)
+        assert success, f"删除预算后发布流程失败: {response}"
+
+        self.log("复杂度评分与预算测试通过", "success")
+
+    def test_candidate_pool_task_only_claimable_by_candidates(self):
+        """测试限定候选人的任务池任务只对候选人可见，其他用户不能认领"""
+        self.log("测试候选人任务池", "info")
+
+        self._register_and_login()
+        candidate_token, candidate_id = self.token, self.test_user_id
+        self._register_and_login()
+
+        definition = approval_definition()
+        definition['nodes'][1]['props'] = {"candidateUsers": [f"user:{candidate_id}"]}
+        process_id = self._create_and_publish_process(definition)
+        instance = self._start_instance(process_id, "low")
+        pool_query = f"/user/tasks?filter[instance_id][eq]={instance['id']}&filter[node_id][eq]=submit"
+
+        success, response, status = self.make_request('GET', pool_query, auth_required=True)
+        assert success, f"获取待办任务失败: {response}"
+        assert response['data']['total'] == 0, "非候选人不应看到候选人任务池中的任务"
+
+        self.token = candidate_token
+        success, response, status = self.make_request('GET', pool_query, auth_required=True)
+        assert success, f"获取待办任务失败: {response}"
+        assert response['data']['total'] == 1, "候选人应看到任务池中的任务"
+        task = response['data']['tasks'][0]
+        assert task['status'] == 'created' and task['assignee_id'] is None
+
+        self._register_and_login()
+        success, response, status = self.make_request(
+            'POST', f"/task/{task['id']}/claim", expected_status=403, auth_required=True)
+        assert success, f"非候选人认领任务应返回403，实际为 {status}"
+
+        self.token, self.test_user_id = candidate_token, candidate_id
+        success, response, status = self.make_request(
+            'POST', f"/task/{task['id']}/claim", auth_required=True)
+        assert success, f"候选人认领任务失败: {response}"
+        task = self._get_task(task['id'])
+        assert task['status'] == 'claimed' and task['assignee_id'] == candidate_id
+
+        self.log("候选人任务池测试通过", "success")
+
+    def test_large_responses_are_compressed(self):
+        """测试较大的响应按客户端的 Accept-Encoding 压缩，流式输出的执行历史仍是完整的 JSON"""
+        self.log("测试响应压缩", "info")
+
+        self._register_and_login()
+        process_id = self._create_and_publish_process()
+        instance = self._start_instance(process_id, "low")
+        instance_id = instance['id']
+        submit_task = self._wait_for_task(instance_id, 'submit')
+        self._claim_and_complete(submit_task['id'], "提交申请")
+        self._wait_for_instance_status(instance_id, 'completed')
+
+        headers = {'Authorization': f'Bearer {self.token}', 'Accept-Encoding': 'gzip'}
+        response = self.session.get(
+            f"{self.api_url}/instance/{instance_id}/history", headers=headers, timeout=self.timeout)
+        assert response.status_code == 200, f"获取执行历史失败: {response.text}"
+        assert response.headers.get('Content-Encoding') == 'gzip', "较大的执行历史响应应使用 gzip 压缩"
+        body = response.json()
+        assert body['success'] and body['data']['instance']['id'] == instance_id
+        assert len(body['data']['activities']) > 0, "流式输出的执行历史应包含活动记录"
+
+        # 同时接受 brotli 时优先使用 brotli；只检查响应头，requests 默认不能解码 br
+        br_headers = dict(headers, **{'Accept-Encoding': 'gzip, br'})
+        with self.session.get(f"{self.api_url}/instance/{instance_id}/history",
+                              headers=br_headers, timeout=self.timeout, stream=True) as response:
+            assert response.status_code == 200
+            assert response.headers.get('Content-Encoding') == 'br', "客户端接受 brotli 时应优先使用 brotli 压缩"
+
+        response = self.session.get(f"{self.api_url}/health", headers=headers, timeout=self.timeout)
+        assert response.status_code == 200
+        assert 'Content-Encoding' not in response.headers, "健康检查不应压缩"
+
+        self.log("响应压缩测试通过", "success")
+
+    def test_selftest_reports_every_check(self):
+        """测试部署自检返回每项检查的结果，检查失败时返回503"""
+        self.log("测试部署自检", "info")
+
+        assert self.login("admin"), "管理员登录失败"
+        response = self.session.get(
+            f"{self.api_url}/admin/selftest",
+            headers={'Authorization': f'Bearer {self.token}'}, timeout=self.timeout)
+        assert response.status_code in (200, 503), f"部署自检返回了意外的状态码 {response.status_code}"
+
+        report = response.json()['data']
+        names = {check['name'] for check in report['checks']}
+        assert names == {
+            'database.schema', 'database.indexes', 'redis', 'jwt.secret',
+            'scheduler', 'connector.allowlists', 'clock.skew',
+        }, f"自检项不完整: {names}"
+        assert all(check['status'] in ('pass', 'warn', 'fail') for check in report['checks'])
+        assert (report['status'] == 'fail') == (response.status_code == 503)
+        schema = next(check for check in report['checks'] if check['name'] == 'database.schema')
+        assert schema['status'] == 'pass', f"测试环境的数据库结构应已迁移: {schema}"
+
+        self.log("部署自检测试通过", "success")
+
+    def test_instance_migration_requires_admin(self):
+        """测试普通用户不能迁移流程实例，请求体缺少目标版本时返回400"""
+        self.log("测试流程实例版本迁移权限", "info")
+
+        self._register_and_login()
+        process_id = self._create_and_publish_process()
+        instance = self._start_instance(process_id, "low")
+        instance_id = instance['id']
+        self._wait_for_task(instance_id, 'submit')
+
+        success, response, status = self.make_request(
+            'POST', f'/instance/{instance_id}/migrate',
+            data={"target_version": 2, "node_mapping": {"submit": "submit"}},
+            expected_status=403, auth_required=True)
+        assert success, f"普通用户迁移实例应返回403，实际为 {status}"
+
+        success, response, status = self._admin_request(
+            'POST', f'/instance/{instance_id}/migrate',
+            data={"node_mapping": {}}, expected_status=400)
+        assert success, f"缺少目标版本应返回400，实际为 {status}"
+
+        instance = self._get_instance(instance_id)
+        assert instance['definition_id'] == process_id, "迁移被拒绝后实例应保持原版本"
+
+        self.log("流程实例版本迁移权限测试通过", "success")
+
+    def test_group_queue_next_task(self):
+        """测试从组队列获取下一个任务：建议不认领，assign 时认领给当前用户，非组成员返回403"""
+        self.log("测试组任务队列", "info")
+
+        self._register_and_login()
+        definition = approval_definition()
+        definition['nodes'][1]['props'] = {"candidateRoles": ["user"]}
+        process_id = self._create_and_publish_process(definition)
+        self._start_instance(process_id, "low")
+
+        success, response, status = self.make_request(
+            'POST', '/queues/user/next', data={"mode": "priority"}, auth_required=True)
+        assert success, f"获取队列任务建议失败: {response}"
+        dispatch = response['data']
+        assert dispatch['pending'] >= 1 and dispatch['task'] is not None, f"队列中应有待认领的任务: {dispatch}"
+        assert not dispatch['assigned'] and dispatch['task']['assignee_id'] is None, "只建议时不应认领任务"
+
+        success, response, status = self.make_request(
+            'POST', '/queues/user/next', data={"mode": "fair_share", "assign": True}, auth_required=True)
+        assert success, f"从队列认领任务失败: {response}"
+        dispatch = response['data']
+        assert dispatch['share']['share'] == 0 and dispatch['fair_share'] >= 1, f"新成员的份额应为0: {dispatch}"
+        assert dispatch['assigned'], f"份额低于公平份额时应分配任务: {dispatch}"
+        task = self._get_task(dispatch['task']['id'])
+        assert task['status'] == 'claimed' and task['assignee_id'] == self.test_user_id
+
+        success, response, status = self.make_request(
+            'POST', '/queues/user/next', data={"mode": "round_robin"}, expected_status=400, auth_required=True)
+        assert success, f"非法的分配模式应返回400，实际为 {status}"
+
+        success, response, status = self.make_request(
+            'POST', '/queues/admin/next', data={}, expected_status=403, auth_required=True)
+        assert success, f"非组成员获取队列任务应返回403，实际为 {status}"
+
+        self.log("组任务队列测试通过", "success")
+
+    def test_visit_limit_escalates_reject_loop(self):
+        """测试驳回循环超过节点访问上限后走升级连线，不再退回提交节点"""
+        self.log("测试节点访问上限", "info")
+
+        # 大额申请被退回提交节点，提交节点最多进入两次，第三次改走升级连线
+        definition = approval_definition()
+        definition['nodes'][1]['props']['visitLimit'] = {"maxVisits": 2, "flow": "f_escalate"}
+        definition['nodes'].append(
+            {"id": "escalate", "type": "userTask", "name": "升级处理", "x": 250, "y": 250,
+             "props": {"assignee": "${starter.id}"}})
+        for flow in definition['flows']:
+            if flow['id'] == 'f3':
+                flow['to'] = 'submit'
+                flow['label'] = '退回'
+        definition['flows'] = [flow for flow in definition['flows'] if flow['id'] != 'f5']
+        definition['flows'] += [
+            {"id": "f_escalate", "from": "submit", "to": "escalate", "label": "超过上限"},
+            {"id": "f6", "from": "escalate", "to": "end"},
+        ]
+
+        self._register_and_login()
+        process_id = self._create_and_publish_process(definition)
+        instance = self._start_instance(process_id, "high")
+        instance_id = instance['id']
+
+        for visit in range(2):
+            task = self._wait_for_open_task(instance_id, 'submit')
+            self._claim_and_complete(task['id'], f"第 {visit + 1} 次提交")
+
+        escalate_task = self._wait_for_task(instance_id, 'escalate')
+        assert self._node_task_count(instance_id, 'submit') == 2, "超过访问上限后不应再生成提交任务"
+        instance = self._get_instance(instance_id)
+        assert '"submit":3' in instance['node_visits'], f"应记录提交节点的进入次数: {instance['node_visits']}"
+
+        self._claim_and_complete(escalate_task['id'], "升级处理完成")
+        self._wait_for_instance_status(instance_id, 'completed')
+
+        # 升级连线必须是节点的出口连线
+        definition['nodes'][1]['props']['visitLimit'] = {"maxVisits": 2, "flow": "f6"}
+        success, response, status = self.make_request(
+            'POST', '/process', data={
+                "key": f"e2e_visit_limit_{random_suffix()}",
+                "name": "访问上限无效的流程",
+                "category": "test",
+                "definition": definition,
+            },
+            expected_status=400,
+            auth_required=True)
+        assert success, f"升级连线不是出口连线时应拒绝保存，实际为 {status}"
+
+        self.log("节点访问上限测试通过", "success")
+
+    def test_script_task_writes_variables(self):
+        """测试脚本任务按流程变量计算并写回变量，网关按写回的变量选择分支"""
+        self.log("测试脚本任务", "info")
+
+        # 开始 → 计算级别（脚本） → 提交申请 → 金额判断 …，脚本把级别改为按金额计算
+        definition = approval_definition()
+        definition['nodes'].append(
+            {"id": "calc", "type": "scriptTask", "name": "计算级别", "x": 175, "y": 200,
+             "props": {"language": "expression",
+                       "script": "# 金额加倍后超过 100 为大额\ntotal = amount * 2\nlevel = total > 100 ? 'high' : 'low'"}})
+        for flow in definition['flows']:
+            if flow['id'] == 'f1':
+                flow['to'] = 'calc'
+        definition['flows'].append({"id": "f_calc", "from": "calc", "to": "submit"})
+
+        self._register_and_login()
+        process_id = self._create_and_publish_process(definition)
+        instance = self._start_instance(process_id, "low", {"amount": 80})
+        instance_id = instance['id']
+
+        submit_task = self._wait_for_task(instance_id, 'submit')
+        instance = self._get_instance(instance_id)
+        variables = json.loads(instance['variables'])
+        assert variables['total'] == 160 and variables['level'] == 'high', f"脚本应写回计算结果: {variables}"
+
+        self._claim_and_complete(submit_task['id'], "提交申请")
+        self._wait_for_task(instance_id, 'manager')
+
+        # 脚本语法错误时拒绝保存
+        definition['nodes'][-1]['props']['script'] = "total == amount * 2"
+        success, response, status = self.make_request(
+            'POST', '/process', data={
+                "key": f"e2e_script_{random_suffix()}",
+                "name": "脚本无效的流程",
+                "category": "test",
+                "definition": definition,
+            },
+            expected_status=400,
+            auth_required=True)
+        assert success, f"脚本语法错误时应拒绝保存，实际为 {status}"
+
+        self.log("脚本任务测试通过", "success")
+
+    def test_engine_errors_report_kind(self):
+        """引擎错误按类别返回状态码、代码和故障方"""
+        success, response, status = self.make_request(
+            'GET', '/instance/999999999', expected_status=404, auth_required=True)
+        assert success, f"不存在的实例应返回404，实际为 {status}"
+        assert response['code'] == 'NOT_FOUND', f"不存在的实例应返回 NOT_FOUND: {response}"
+        assert response['fault'] == 'user', f"不存在的实例属于调用方错误: {response}"
+
+        success, response, status = self.make_request(
+            'GET', '/task/999999999', expected_status=404, auth_required=True)
+        assert success, f"不存在的任务应返回404，实际为 {status}"
+        assert response['code'] == 'NOT_FOUND', f"不存在的任务应返回 NOT_FOUND: {response}"
+
+        self.log("引擎错误分类测试通过", "success")
+
+    def test_cancel_records_undo_snapshot(self):
+        """取消实例记录取消时间并跳过任务，只有管理员可以撤销取消"""
+        process_id = self._create_and_publish_process()
+        instance = self._start_instance(process_id, "low")
+        instance_id = instance['id']
+        task = self._wait_for_task(instance_id, 'submit')
+
+        success, response, status = self.make_request(
+            'POST', f'/instance/{instance_id}/cancel',
+            data={"reason": "误操作测试"}, auth_required=True)
+        assert success, f"取消实例失败: {response}"
+
+        instance = self._get_instance(instance_id)
+        assert instance['status'] == 'cancelled', f"实例应为已取消状态，实际为 {instance['status']}"
+        assert instance.get('cancelled_at'), "取消后应记录取消时间"
+        assert self._get_task(task['id'])['status'] == 'skipped', "取消后未完成的任务应被跳过"
+
+        success, response, status = self.make_request(
+            'POST', f'/admin/recycle-bin/{instance_id}/restore', expected_status=403, auth_required=True)
+        assert success, f"普通用户撤销取消应返回403，实际为 {status}"
+        success, response, status = self.make_request(
+            'GET', '/admin/recycle-bin', expected_status=403, auth_required=True)
+        assert success, f"普通用户查看回收站应返回403，实际为 {status}"
+
+        self.log("回收站测试通过", "success")
+
+    def test_external_task_fetch_and_complete(self):
+        """外部处理程序按主题获取并锁定外部任务，只有持有锁的处理程序可以完成任务"""
+        topic = f"invoice-{random_suffix()}"
+        definition = {
+            "nodes": [
+                {"id": "start", "type": "start", "name": "开始", "x": 100, "y": 100},
+                {"id": "invoice", "type": "serviceTask", "name": "开具发票", "x": 250, "y": 100,
+                 "props": {"type": "external", "topic": topic, "retries": 2}},
+                {"id": "end", "type": "end", "name": "结束", "x": 400, "y": 100},
+            ],
+            "flows": [
+                {"id": "f1", "from": "start", "to": "invoice"},
+                {"id": "f2", "from": "invoice", "to": "end"},
+            ],
+        }
+        process_id = self._create_and_publish_process(definition)
+        instance = self._start_instance(process_id, "low")
+        instance_id = instance['id']
+
+        fetch = {"worker_id": "worker-a", "topics": [topic], "max_tasks": 5, "lock_duration_seconds": 60}
+        success, response, status = self.make_request(
+            'POST', '/external-tasks/fetch-and-lock', data=fetch, expected_status=403, auth_required=True)
+        assert success, f"没有外部任务权限的用户获取外部任务应返回403，实际为 {status}"
+
+        tasks = []
+        deadline = time.time() + self.ADVANCE_TIMEOUT
+        while time.time() < deadline and not tasks:
+            success, response, status = self._admin_request(
+                'POST', '/external-tasks/fetch-and-lock', data=fetch)
+            assert success, f"获取外部任务失败: {response}"
+            tasks = [t for t in response['data'] if t['instance_id'] == instance_id]
+            if not tasks:
+                time.sleep(0.5)
+        assert tasks, f"实例 {instance_id} 未生成主题为 {topic} 的外部任务"
+        task = tasks[0]
+        assert task['node_id'] == 'invoice' and task['worker_id'] == 'worker-a'
+        assert task['retries'] == 2, f"重试次数应取自节点配置，实际为 {task['retries']}"
+
+        success, response, status = self._admin_request(
+            'POST', '/external-tasks/fetch-and-lock', data=dict(fetch, worker_id="worker-b"))
+        assert success, f"获取外部任务失败: {response}"
+        assert all(t['id'] != task['id'] for t in response['data']), "已锁定的外部任务不应被其他处理程序获取"
+
+        success, response, status = self._admin_request(
+            'POST', f"/external-tasks/{task['id']}/complete",
+            data={"worker_id": "worker-b"}, expected_status=409)
+        assert success, f"未持有锁的处理程序完成任务应返回409，实际为 {status}"
+        assert response['code'] == 'EXTERNAL_TASK_NOT_LOCKED', f"错误码不符: {response}"
+
+        success, response, status = self._admin_request(
+            'POST', f"/external-tasks/{task['id']}/complete",
+            data={"worker_id": "worker-a", "variables": {"invoice_no": "INV-001"}})
+        assert success, f"完成外部任务失败: {response}"
+
+        instance = self._wait_for_instance_status(instance_id, 'completed')
+        variables = json.loads(instance['variables'])
+        assert variables.get('invoice_no') == 'INV-001', "外部任务返回的变量应合并到流程变量"
+
+        self.log("外部任务测试通过", "success")
+
+    def test_capacity_analytics(self):
+        """容量统计按流程定义版本返回每日序列，统计天数超出范围时返回400，普通用户无权查看"""
+        self._register_and_login()
+        success, response, status = self.make_request(
+            'GET', '/analytics/capacity?days=7', expected_status=403, auth_required=True)
+        assert success, f"普通用户查看容量统计应返回403，实际为 {status}"
+
+        success, response, status = self._admin_request('GET', '/analytics/capacity?days=7')
+        assert success, f"获取容量统计失败: {response}"
+        for series in response['data']:
+            assert {'definition_id', 'key', 'version', 'points'} <= set(series), f"容量序列字段不完整: {series}"
+            assert all(point['peak_active_tasks'] >= point['active_tasks'] for point in series['points'])
+
+        for days in ('0', '1000', 'abc'):
+            success, response, status = self._admin_request(
+                'GET', f'/analytics/capacity?days={days}', expected_status=400)
+            assert success, f"统计天数 {days} 应返回400，实际为 {status}"
+
+        self.log("容量统计测试通过", "success")
+
+    def test_async_service_task_definition(self):
+        """服务任务的 async 属性必须是布尔值，异步节点不阻塞启动实例的请求"""
+        def async_definition(async_flag):
+            return {
+                "nodes": [
+                    {"id": "start", "type": "start", "name": "开始", "x": 100, "y": 100},
+                    {"id": "notify", "type": "serviceTask", "name": "通知下游", "x": 250, "y": 100,
+                     "props": {"url": "http://127.0.0.1:9/unreachable", "method": "POST", "async": async_flag}},
+                    {"id": "end", "type": "end", "name": "结束", "x": 400, "y": 100},
+                ],
+                "flows": [
+                    {"id": "f1", "from": "start", "to": "notify"},
+                    {"id": "f2", "from": "notify", "to": "end"},
+                ],
+            }
+
+        success, response, status = self.make_request(
+            'POST', '/process',
+            data={
+                "key": f"e2e_async_{random_suffix()}",
+                "name": "异步服务任务流程",
+                "category": "test",
+                "definition": async_definition("yes"),
+            },
+            expected_status=400, auth_required=True)
+        assert success, f"async 属性不是布尔值时应返回400，实际为 {status}"
+
+        process_id = self._create_and_publish_process(async_definition(True))
+        instance = self._start_instance(process_id, "low")
+        instance = self._get_instance(instance['id'])
+        assert instance['status'] == 'running', f"异步服务任务由后台执行，实例应仍在运行，实际为 {instance['status']}"
+
+        success, response, status = self.make_request(
+            'GET', '/admin/jobs/async', expected_status=403, auth_required=True)
+        assert success, f"普通用户查看异步作业应返回403，实际为 {status}"
+
+        self.log("异步服务任务测试通过", "success")
+
+    def test_recovery_report_requires_admin(self):
+        """测试启动恢复报告和手动触发恢复只对管理员开放"""
+        self.log("测试启动恢复接口权限", "info")
+
+        self._register_and_login()
+
+        success, response, status = self.make_request(
+            'GET', '/admin/recovery', expected_status=403, auth_required=True)
+        assert success, f"普通用户查看恢复报告应返回403，实际为 {status}"
+
+        success, response, status = self.make_request(
+            'POST', '/admin/recovery/run', expected_status=403, auth_required=True)
+        assert success, f"普通用户触发恢复应返回403，实际为 {status}"
+
+        # 管理员手动触发恢复后，报告接口返回这次运行的结果
+        success, response, status = self._admin_request('POST', '/admin/recovery/run')
+        assert success, f"管理员触发恢复失败: {response}"
+        report = response['data']
+        assert report['started_at'] and report['finished_at'], "恢复报告应记录开始和结束时间"
+        assert isinstance(report['findings'], list), "恢复报告应列出每一处发现"
+        assert report['repaired'] <= report['stale_jobs'] + report['stalled_instances'], "修复数不应超过发现数"
+
+        success, response, status = self._admin_request('GET', '/admin/recovery')
+        assert success, f"管理员查看恢复报告失败: {response}"
+        assert response['data']['started_at'] == report['started_at'], "报告接口应返回最近一次恢复的结果"
+
+        self.log("启动恢复接口权限测试通过", "success")
+
+    def test_import_approval_templates(self):
+        """测试从飞书和钉钉审批模板导入草稿流程"""
+        self.log("测试审批模板导入", "info")
+
+        feishu_template = {
+            "approval_name": "@i18n@name",
+            "form": json.dumps([
+                {"id": "widget_days", "name": "@i18n@days", "type": "number", "required": True},
+                {"id": "widget_file", "name": "附件", "type": "attachmentV2"},
+            ]),
+            "node_list": [
+                {"id": "START"},
+                {"id": "n1", "name": "主管审批", "node_type": "AND", "approver": [{"type": "Supervisor"}]},
+                {"id": "n2", "name": "抄送", "node_type": "CC_SEND"},
+                {"id": "END"},
+            ],
+            "i18n_resources": [{"locale": "zh-CN", "is_default": True, "texts": [
+                {"key": "@i18n@name", "value": "请假"},
+                {"key": "@i18n@days", "value": "天数"},
+            ]}],
+        }
+        success, response, status = self.make_request(
+            'POST', '/process/import-template',
+            data={"source": "feishu", "key": f"feishu_{random_suffix()}", "template": feishu_template},
+            expected_status=201, auth_required=True)
+        assert success, f"导入飞书审批模板失败: {response}"
+        process = response['data']['process']
+        assert process['name'] == '请假', f"流程名称应取自模板，实际为 {process['name']}"
+        assert process['status'] == 'draft', "导入的流程应为草稿"
+        assert len(response['data']['warnings']) >= 2, "不支持的控件和抄送节点应产生警告"
+        nodes = {node['id']: node for node in process['definition']['nodes']}
+        assert nodes['approval_1']['props']['assignee'] == 'role:manager', "主管审批应按 manager 角色分配"
+        fields = nodes['start']['props']['form']['fields']
+        assert fields[0]['label'] == '天数' and fields[0]['type'] == 'number', f"表单字段转换错误: {fields}"
+
+        dingtalk_template = {
+            "name": "报销",
+            "schemaContent": {"items": [
+                {"componentName": "MoneyField", "props": {"id": "MoneyField-amount", "label": "金额", "required": True}},
+            ]},
+            "process": {"type": "start", "childNode": {
+                "type": "route",
+                "conditionNodes": [
+                    {"type": "condition", "name": "大额", "properties": {"conditions": [[{
+                        "paramKey": "MoneyField-amount", "type": "dingtalk_actioner_range_condition",
+                        "lowerBound": "1000", "lowerBoundEqual": "true"}]]},
+                     "childNode": {"type": "approver", "name": "财务审批",
+                                   "properties": {"actionerRules": [{"type": "target_label", "labelNames": "财务"}]}}},
+                    {"type": "condition", "name": "其他", "properties": {"isDefault": True}},
+                ],
+                "childNode": {"type": "approver", "name": "出纳",
+                              "properties": {"actionerRules": [{"type": "target_originator"}]}},
+            }},
+        }
+        success, response, status = self.make_request(
+            'POST', '/process/import-template',
+            data={"source": "dingtalk", "key": f"dingtalk_{random_suffix()}", "template": dingtalk_template,
+                  "role_mapping": {"财务": "finance"}},
+            expected_status=201, auth_required=True)
+        assert success, f"导入钉钉流程失败: {response}"
+        definition = response['data']['process']['definition']
+        nodes = {node['id']: node for node in definition['nodes']}
+        assert nodes['approval_1']['props']['assignee'] == 'role:finance', "角色应按映射转换"
+        conditions = [flow['condition'] for flow in definition['flows'] if flow.get('condition')]
+        assert conditions == ['MoneyField_amount >= 1000'], f"分支条件转换错误: {conditions}"
+
+        success, response, status = self.make_request(
+            'POST', '/process/import-template',
+            data={"source": "dingtalk", "key": f"dingtalk_{random_suffix()}", "template": {"name": "空"}},
+            expected_status=400, auth_required=True)
+        assert success, f"缺少节点树的模板应返回400，实际为 {status}"
+
+        self.log("审批模板导入测试通过", "success")
+
+    def test_message_catch_correlation(self):
+        """测试消息捕获节点等待外部消息，按业务键关联后继续推进"""
+        self.log("测试消息捕获与关联", "info")
+
+        message_name = f"payment_received_{random_suffix()}"
+        definition = {
+            "nodes": [
+                {"id": "start", "type": "start", "name": "开始", "x": 100, "y": 100},
+                {"id": "wait_payment", "type": "messageCatch", "name": "等待付款", "x": 250, "y": 100,
+                 "props": {"messageName": message_name}},
+                {"id": "end", "type": "end", "name": "结束", "x": 400, "y": 100},
+            ],
+            "flows": [
+                {"id": "f1", "from": "start", "to": "wait_payment"},
+                {"id": "f2", "from": "wait_payment", "to": "end"},
+            ],
+        }
+        process_id = self._create_and_publish_process(definition)
+        instance = self._start_instance(process_id, "low")
+        instance = self._get_instance(instance['id'])
+        assert instance['status'] == 'running', "等待消息的实例应保持运行"
+        assert instance['current_node'] == 'wait_payment', f"实例应停留在消息捕获节点，实际为 {instance['current_node']}"
+
+        success, response, status = self.make_request(
+            'POST', '/messages/correlate',
+            data={"message_name": message_name, "correlation_key": instance['business_key']},
+            expected_status=403, auth_required=True)
+        assert success, f"没有消息投递权限的用户投递消息应返回403，实际为 {status}"
+
+        success, response, status = self._admin_request(
+            'POST', '/messages/correlate',
+            data={"message_name": message_name, "correlation_key": "NO-SUCH-KEY"},
+            expected_status=404)
+        assert success, f"没有匹配的实例时应返回404，实际为 {status}"
+
+        success, response, status = self._admin_request(
+            'POST', '/messages/correlate',
+            data={"message_name": message_name, "correlation_key": instance['business_key'],
+                  "variables": {"paid_amount": 100}},
+            expected_status=200)
+        assert success, f"投递消息失败: {response}"
+        assert response['data']['instance_ids'] == [instance['id']], f"应唤醒等待的实例: {response}"
+
+        instance = self._wait_for_instance_status(instance['id'], 'completed')
+        assert json.loads(instance['variables'])['paid_amount'] == 100, "消息携带的变量应合并到流程变量"
+
+        success, response, status = self._admin_request(
+            'POST', '/messages/correlate',
+            data={"message_name": message_name, "correlation_key": instance['business_key']},
+            expected_status=404)
+        assert success, f"消息只能关联一次，实际为 {status}"
+
+        self.log("消息捕获与关联测试通过", "success")
+
+    def test_modify_execution_requires_admin(self):
+        """测试修改实例执行位置只对管理员开放且必须填写原因"""
+        self.log("测试修改执行位置接口", "info")
+
+        success, response, status = self.make_request(
+            'POST', '/admin/instance/1/modify-execution',
+            data={"target_node_id": "approve"}, expected_status=400, auth_required=True)
+        assert success, f"缺少原因应返回400，实际为 {status}"
+
+        success, response, status = self.make_request(
+            'POST', '/admin/instance/1/modify-execution',
+            data={"target_node_id": "approve", "reason": "审批人离职，退回重新审批"},
+            expected_status=403, auth_required=True)
+        assert success, f"普通用户修改执行位置应返回403，实际为 {status}"
+
+        self.log("修改执行位置接口测试通过", "success")
+
+    def test_return_task_to_previous_step(self):
+        """测试驳回任务退回上一个用户任务节点并沿用表单数据"""
+        self.log("测试任务驳回", "info")
+
+        definition = {
+            "nodes": [
+                {"id": "start", "type": "start", "name": "开始", "x": 100, "y": 100},
+                {"id": "submit", "type": "userTask", "name": "提交申请", "x": 250, "y": 100,
+                 "props": {"assignee": "${starter.id}"}},
+                {"id": "review", "type": "userTask", "name": "审核", "x": 400, "y": 100,
+                 "props": {"assignee": "${starter.id}"}},
+                {"id": "end", "type": "end", "name": "结束", "x": 550, "y": 100},
+            ],
+            "flows": [
+                {"id": "f1", "from": "start", "to": "submit"},
+                {"id": "f2", "from": "submit", "to": "review"},
+                {"id": "f3", "from": "review", "to": "end"},
+            ],
+        }
+        process_id = self._create_and_publish_process(definition)
+        instance = self._start_instance(process_id, "low")
+        instance_id = instance['id']
+
+        submit_task = self._wait_for_open_task(instance_id, 'submit')
+        success, response, status = self.make_request(
+            'POST', f'/task/{submit_task["id"]}/claim', auth_required=True)
+        assert success, f"认领提交任务失败: {response}"
+        success, response, status = self.make_request(
+            'POST', f'/task/{submit_task["id"]}/return', data={"comment": "没有上一步"},
+            expected_status=409, auth_required=True)
+        assert success, f"第一个任务没有可以退回的节点，应返回409，实际为 {status}"
+
+        success, response, status = self.make_request(
+            'POST', f'/task/{submit_task["id"]}/complete', data={"comment": "提交材料"},
+            auth_required=True)
+        assert success, f"完成提交任务失败: {response}"
+        review_task = self._wait_for_open_task(instance_id, 'review')
+        success, response, status = self.make_request(
+            'POST', f'/task/{review_task["id"]}/claim', auth_required=True)
+        assert success, f"认领审核任务失败: {response}"
+
+        success, response, status = self.make_request(
+            'POST', f'/task/{review_task["id"]}/return', data={"comment": "材料不全"},
+            auth_required=True)
+        assert success, f"驳回任务失败: {response}"
+        returned = response['data']
+        assert returned['node_id'] == 'submit', f"应退回提交节点: {returned}"
+        assert returned['comment'] == "提交材料", f"重新创建的任务应沿用上次的表单数据: {returned}"
+        assert returned['assignee_id'] == self.test_user_id, "重新创建的任务应交还给上次的处理人"
+
+        review = self._get_task(review_task['id'])
+        assert review['status'] == 'completed' and review['outcome'] == 'rejected', f"被驳回的任务应以驳回结果完成: {review}"
+
+        self._claim_and_complete(returned['id'], "补充材料")
+        review_task = self._wait_for_open_task(instance_id, 'review')
+        self._claim_and_complete(review_task['id'], "审核通过")
+        self._wait_for_instance_status(instance_id, 'completed')
+
+        self.log("任务驳回测试通过", "success")
+
+    def test_role_checks_on_admin_and_instance_routes(self):
+        """测试管理接口和按状态查询任务只允许配置的角色，普通用户只能挂起和取消自己发起的实例"""
+        self.log("测试基于角色的访问控制", "info")
+
+        self._register_and_login()
+        success, response, status = self.make_request(
+            'GET', '/admin/users', expected_status=403, auth_required=True)
+        assert success, f"普通用户访问管理接口应返回403，实际为 {status}"
+        assert response['code'] == 'PERMISSION_DENIED'
+
+        success, response, status = self.make_request(
+            'GET', '/tasks/status/created', expected_status=403, auth_required=True)
+        assert success, f"普通用户按状态查询任务应返回403，实际为 {status}"
+
+        success, response, status = self._admin_request('GET', '/tasks/status/created')
+        assert success, f"管理员按状态查询任务失败: {response}"
+
+        process_id = self._create_and_publish_process()
+        instance = self._start_instance(process_id, "low")
+        starter_token, starter_id = self.token, self.test_user_id
+
+        self._register_and_login()
+        success, response, status = self.make_request(
+            'POST', f"/instance/{instance['id']}/suspend", data={"reason": "无关用户挂起"},
+            expected_status=403, auth_required=True)
+        assert success, f"其他普通用户挂起实例应返回403，实际为 {status}"
+        success, response, status = self.make_request(
+            'POST', f"/instance/{instance['id']}/cancel", data={"reason": "无关用户取消"},
+            expected_status=403, auth_required=True)
+        assert success, f"其他普通用户取消实例应返回403，实际为 {status}"
+
+        self.token, self.test_user_id = starter_token, starter_id
+        success, response, status = self.make_request(
+            'POST', f"/instance/{instance['id']}/suspend", data={"reason": "发起人挂起"}, auth_required=True)
+        assert success, f"发起人挂起实例失败: {response}"
+
+        self.log("基于角色的访问控制测试通过", "success")
+
+    def test_assignee_by_group_and_department_manager(self):
+        """测试用户任务按用户组和发起人所在部门的负责人分配处理人"""
+        self.log("测试按组织架构分配处理人", "info")
+
+        self._register_and_login()
+        member_token, member_id = self.token, self.test_user_id
+        self._register_and_login()
+        suffix = random_suffix()
+
+        success, response, status = self._admin_request(
+            'POST', '/admin/groups',
+            data={"code": f"e2e_group_{suffix}", "name": "端到端测试组"}, expected_status=201)
+        assert success, f"创建用户组失败: {response}"
+        group_id = response['data']['id']
+        success, response, status = self._admin_request(
+            'POST', f'/admin/groups/{group_id}/members', data={"user_ids": [member_id]})
+        assert success, f"添加用户组成员失败: {response}"
+        assert [member['id'] for member in response['data']] == [member_id]
+
+        success, response, status = self._admin_request(
+            'POST', '/admin/departments',
+            data={"code": f"e2e_dept_{suffix}", "name": "端到端测试部门", "manager_id": member_id},
+            expected_status=201)
+        assert success, f"创建部门失败: {response}"
+        department_id = response['data']['id']
+        success, response, status = self._admin_request(
+            'PUT', f'/admin/users/{self.test_user_id}/department', data={"department_id": department_id})
+        assert success, f"设置用户部门失败: {response}"
+
+        success, response, status = self.make_request(
+            'POST', '/admin/groups', data={"code": f"e2e_group_{suffix}", "name": "无权限"},
+            expected_status=403, auth_required=True)
+        assert success, f"普通用户创建用户组应返回403，实际为 {status}"
+
+        starter_token, starter_id = self.token, self.test_user_id
+        for assignee in (f"group:e2e_group_{suffix}", "dept_manager:${starter.department}"):
+            definition = approval_definition()
+            definition['nodes'][1]['props'] = {"assignee": assignee}
+            process_id = self._create_and_publish_process(definition)
+            instance = self._start_instance(process_id, "low")
+
+            self.token, self.test_user_id = member_token, member_id
+            task = self._wait_for_open_task(instance['id'], 'submit')
+            assert task['assignee_id'] == member_id, f"{assignee} 应分配给 {member_id}"
+            self.token, self.test_user_id = starter_token, starter_id
+
+        self.log("按组织架构分配处理人测试通过", "success")
+
+    def test_refresh_token_rotation_and_logout(self):
+        """测试刷新令牌换取新令牌后旧令牌失效，退出登录后访问令牌和刷新令牌都不能再使用"""
+        self.log("测试刷新令牌和注销", "info")
+
+        suffix = random_suffix()
+        username, password = f"e2e_user_{suffix}", "testpass123"
+        success, response, status = self.make_request(
+            'POST', '/auth/register',
+            data={"username": username, "password": password, "email": f"e2e_{suffix}@example.com"},
+            expected_status=201)
+        assert success, f"用户注册失败: {response}"
+        success, response, status = self.make_request(
+            'POST', '/auth/login', data={"username": username, "password": password})
+        assert success, f"用户登录失败: {response}"
+        first_refresh = response['data']['refresh_token']
+        assert first_refresh, "登录应返回刷新令牌"
+
+        success, response, status = self.make_request(
+            'POST', '/auth/refresh', data={"refresh_token": first_refresh})
+        assert success, f"刷新登录凭证失败: {response}"
+        self.token = response['data']['token']
+        second_refresh = response['data']['refresh_token']
+        assert second_refresh != first_refresh, "每次刷新都应签发新的刷新令牌"
+
+        success, response, status = self.make_request(
+            'POST', '/auth/refresh', data={"refresh_token": first_refresh}, expected_status=401)
+        assert success, f"已使用的刷新令牌应返回401，实际为 {status}"
+        # 重放旧令牌会作废该用户的所有会话
+        success, response, status = self.make_request(
+            'POST', '/auth/refresh', data={"refresh_token": second_refresh}, expected_status=401)
+        assert success, f"重放刷新令牌后新的刷新令牌也应失效，实际为 {status}"
+
+        success, response, status = self.make_request(
+            'POST', '/auth/login', data={"username": username, "password": password})
+        assert success, f"用户登录失败: {response}"
+        self.token = response['data']['token']
+        refresh_token = response['data']['refresh_token']
+
+        success, response, status = self.make_request(
+            'POST', '/auth/logout', data={"refresh_token": refresh_token}, auth_required=True)
+        assert success, f"退出登录失败: {response}"
+
+        success, response, status = self.make_request(
+            'GET', '/user/profile', expected_status=401, auth_required=True)
+        assert success, f"注销后的访问令牌应返回401，实际为 {status}"
+        assert response['code'] == 'TOKEN_REVOKED'
+        success, response, status = self.make_request(
+            'POST', '/auth/refresh', data={"refresh_token": refresh_token}, expected_status=401)
+        assert success, f"注销后的刷新令牌应返回401，实际为 {status}"
+
+        self.log("刷新令牌和注销测试通过", "success")
+
+    def test_task_stream_pushes_inbox_changes(self):
+        """测试任务推送流：连接时推送待办摘要，新任务生成后推送 task 事件"""
+        self.log("测试任务推送流", "info")
+
+        self._register_and_login()
+        process_id = self._create_and_publish_process()
+
+        headers = {'Authorization': f'Bearer {self.token}', 'Accept': 'text/event-stream'}
+        response = self.session.get(
+            f"{self.api_url}/user/tasks/stream", headers=headers, stream=True, timeout=self.timeout)
+        assert response.status_code == 200, f"打开任务推送流失败: {response.text}"
+        assert response.headers.get('Content-Type', '').startswith('text/event-stream')
+
+        def read_events():
+            event = {}
+            for line in response.iter_lines(decode_unicode=True):
+                if line == '':
+                    if 'event' in event:
+                        yield event
+                    event = {}
+                elif not line.startswith(':'):
+                    field, _, value = line.partition(': ')
+                    event[field] = value
+
+        events = read_events()
+        try:
+            first = next(events)
+            assert first['event'] == 'inbox', "连接后第一条应为待办摘要"
+            assert 'active_count' in json.loads(first['data'])
+
+            instance = self._start_instance(process_id, "low")
+            task_event = None
+            for event in events:
+                if event['event'].startswith('task.'):
+                    data = json.loads(event['data'])
+                    if data['instance_id'] == instance['id']:
+                        task_event = event
+                        break
+            assert task_event is not None, "新任务生成后应推送任务事件"
+            assert task_event['event'] in ('task.created', 'task.assigned')
+            assert int(task_event['id']) == json.loads(task_event['data'])['id'], "事件 id 应为变更游标"
+        finally:
+            response.close()
+
+        self.log("任务推送流测试通过", "success")
+
+    def test_task_comment_thread(self):
+        """测试任务讨论：发表、修改、删除评论，完成意见记入讨论并出现在实例历史中"""
+        self.log("测试任务讨论", "info")
+
+        self._register_and_login()
+        process_id = self._create_and_publish_process()
+        instance = self._start_instance(process_id, "low")
+        instance_id = instance['id']
+        task = self._wait_for_task(instance_id, 'submit')
+
+        success, response, status = self.make_request(
+            'POST', f"/task/{task['id']}/comments", data={"body": "请补充发票"},
+            expected_status=201, auth_required=True)
+        assert success, f"发表评论失败: {response}"
+        comment_id = response['data']['id']
+        assert response['data']['user_id'] == self.test_user_id
+
+        success, response, status = self.make_request(
+            'POST', f"/task/{task['id']}/comments", data={"body": "   "},
+            expected_status=400, auth_required=True)
+        assert success, "空白评论应被拒绝"
+
+        success, response, status = self.make_request(
+            'PUT', f"/task/{task['id']}/comments/{comment_id}", data={"body": "请补充发票和合同"},
+            auth_required=True)
+        assert success, f"修改评论失败: {response}"
+        assert response['data']['body'] == "请补充发票和合同"
+
+        success, response, status = self.make_request(
+            'POST', f"/task/{task['id']}/comments", data={"body": "待删除"},
+            expected_status=201, auth_required=True)
+        assert success, f"发表评论失败: {response}"
+        success, response, status = self.make_request(
+            'DELETE', f"/task/{task['id']}/comments/{response['data']['id']}", auth_required=True)
+        assert success, f"删除评论失败: {response}"
+
+        self._claim_and_complete(task['id'], "已补充")
+
+        success, response, status = self.make_request(
+            'GET', f"/task/{task['id']}/comments", auth_required=True)
+        assert success, f"获取评论失败: {response}"
+        assert [c['body'] for c in response['data']] == ["请补充发票和合同", "已补充"], "完成意见应记入讨论"
+
+        success, response, status = self.make_request(
+            'GET', f'/instance/{instance_id}/history', auth_required=True)
+        assert success, f"获取执行历史失败: {response}"
+        assert [c['id'] for c in response['data']['comments']][0] == comment_id, "实例历史应包含任务讨论"
+
+        self.log("任务讨论测试通过", "success")
+
+    def test_task_and_instance_attachments(self):
+        """测试附件：上传到任务和实例、按内容校验类型、下载和删除"""
+        self.log("测试附件上传下载", "info")
+
+        self._register_and_login()
+        process_id = self._create_and_publish_process()
+        instance = self._start_instance(process_id, "low")
+        instance_id = instance['id']
+        task = self._wait_for_task(instance_id, 'submit')
+        headers = {'Authorization': f'Bearer {self.token}'}
+
+        content = b"%PDF-1.4\n" + b"0" * 2048
+        response = self.session.post(
+            f"{self.api_url}/task/{task['id']}/attachments", headers=headers,
+            files={'file': ('发票.pdf', content, 'application/pdf')}, timeout=self.timeout)
+        assert response.status_code == 201, f"上传任务附件失败: {response.text}"
+        task_attachment = response.json()['data']
+        assert task_attachment['content_type'] == 'application/pdf'
+        assert task_attachment['size'] == len(content)
+        assert task_attachment['task_id'] == task['id']
+
+        response = self.session.post(
+            f"{self.api_url}/instance/{instance_id}/attachments", headers=headers,
+            files={'file': ('notes.txt', b"hello", 'text/plain')}, timeout=self.timeout)
+        assert response.status_code == 201, f"上传实例附件失败: {response.text}"
+        instance_attachment = response.json()['data']
+
+        # 类型按内容识别，声明为 PDF 的可执行文件仍被拒绝
+        response = self.session.post(
+            f"{self.api_url}/instance/{instance_id}/attachments", headers=headers,
+            files={'file': ('fake.pdf', b"MZ\x90\x00" + b"\x00" * 64, 'application/pdf')}, timeout=self.timeout)
+        assert response.status_code == 400, "不允许的文件类型应被拒绝"
+
+        success, response, status = self.make_request(
+            'GET', f'/instance/{instance_id}/attachments', auth_required=True)
+        assert success, f"获取实例附件失败: {response}"
+        assert [a['id'] for a in response['data']] == [task_attachment['id'], instance_attachment['id']], \
+            "实例附件应包括任务的附件"
+
+        response = self.session.get(
+            f"{self.api_url}/attachments/{task_attachment['id']}/download", headers=headers, timeout=self.timeout)
+        assert response.status_code == 200, f"下载附件失败: {response.text}"
+        assert response.content == content, "下载的内容应与上传一致"
+        assert 'attachment' in response.headers.get('Content-Disposition', '')
+
+        success, response, status = self.make_request(
+            'DELETE', f"/attachments/{instance_attachment['id']}", auth_required=True)
+        assert success, f"删除附件失败: {response}"
+        success, response, status = self.make_request(
+            'GET', f"/attachments/{instance_attachment['id']}/download", expected_status=404, auth_required=True)
+        assert success, "删除后的附件应不存在"
+
+        self.log("附件上传下载测试通过", "success")
+
+    def test_task_form_data_kept_with_comment(self):
+        """测试完成任务时表单数据和处理意见分别保存"""
+        self.log("测试任务表单数据", "info")
+
+        self._register_and_login()
+        process_id = self._create_and_publish_process()
+        instance = self._start_instance(process_id, "low")
+        task = self._wait_for_task(instance['id'], 'submit')
+
+        success, response, status = self.make_request(
+            'POST', f"/task/{task['id']}/claim", auth_required=True)
+        assert success, f"认领任务失败: {response}"
+
+        success, response, status = self.make_request(
+            'POST', f"/task/{task['id']}/form",
+            data={"form_data": {"amount": 800}, "action": "save"},
+            auth_required=True)
+        assert success, f"保存表单失败: {response}"
+
+        success, response, status = self.make_request(
+            'POST', f"/task/{task['id']}/complete",
+            data={"form_data": {"amount": 1200, "reason": "差旅"}, "comment": "请审批"},
+            auth_required=True)
+        assert success, f"完成任务失败: {response}"
+
+        task = self._get_task(task['id'])
+        assert task['comment'] == "请审批", "处理意见不应被表单数据覆盖"
+        assert json.loads(task['form_data']) == {"amount": 1200, "reason": "差旅"}, "表单数据应随任务保存"
+
+        self.log("任务表单数据测试通过", "success")
+
+    def test_inclusive_join_waits_for_activated_branches(self):
+        """测试包容汇聚网关只等待分叉时激活的分支"""
+        self.log("测试包容网关汇聚", "info")
+
+        self._register_and_login()
+        process_id = self._create_and_publish_process(inclusive_diamond_definition())
+
+        # 只激活一个分支时，该分支完成后直接汇聚
+        instance = self._start_instance(process_id, "low")
+        instance_id = instance['id']
+        finance_task = self._wait_for_task(instance_id, 'finance')
+        assert self._node_task_count(instance_id, 'legal') == 0, "条件不满足的分支不应激活"
+        self._claim_and_complete(finance_task['id'], "财务通过")
+        self._wait_for_task(instance_id, 'archive')
+
+        # 两个分支都激活时，等两个分支都完成后才汇聚
+        instance = self._start_instance(process_id, "high")
+        instance_id = instance['id']
+        finance_task = self._wait_for_task(instance_id, 'finance')
+        legal_task = self._wait_for_task(instance_id, 'legal')
+        self._claim_and_complete(finance_task['id'], "财务通过")
+        time.sleep(1)
+        assert self._node_task_count(instance_id, 'archive') == 0, "激活的分支未全部完成时不应汇聚"
+        self._claim_and_complete(legal_task['id'], "法务通过")
+        self._wait_for_task(instance_id, 'archive')
+        assert self._node_task_count(instance_id, 'archive') == 1, "汇聚后只应生成一个归档任务"
+
+        self.log("包容网关汇聚测试通过", "success")
+
+    def test_loop_node_repeats_until_condition_fails(self):
+        """测试声明了循环的用户任务在条件成立时重新生成任务"""
+        self.log("测试循环节点", "info")
+
+        self._register_and_login()
+        definition = {
+            "nodes": [
+                {"id": "start", "type": "start", "name": "开始", "x": 100, "y": 100},
+                {"id": "submit", "type": "userTask", "name": "提交申请", "x": 250, "y": 100,
+                 "props": {"assignee": "${starter.id}",
+                           "loop": {"condition": "${loopCounter} < 2", "maxIterations": 5}}},
+                {"id": "end", "type": "end", "name": "结束", "x": 400, "y": 100},
+            ],
+            "flows": [
+                {"id": "f1", "from": "start", "to": "submit"},
+                {"id": "f2", "from": "submit", "to": "end"},
+            ],
+        }
+        process_id = self._create_and_publish_process(definition)
+        instance = self._start_instance(process_id, "low")
+        instance_id = instance['id']
+
+        first = self._wait_for_open_task(instance_id, 'submit')
+        self._claim_and_complete(first['id'], "第一次提交")
+        second = self._wait_for_open_task(instance_id, 'submit')
+        assert second['id'] != first['id'], "循环应重新生成任务"
+        assert self._get_instance(instance_id)['status'] == 'running', "循环未结束时实例应保持运行"
+
+        self._claim_and_complete(second['id'], "第二次提交")
+        instance = self._wait_for_instance_status(instance_id, 'completed')
+        assert instance['current_node'] == 'end', "循环结束后应沿出口连线推进"
+
+        self.log("循环节点测试通过", "success")
+
+    def test_cancel_compensates_completed_service_tasks(self):
+        """测试取消实例时按逆序执行已完成服务任务的补偿节点"""
+        self.log("测试服务任务补偿", "info")
+
+        self._register_and_login()
+        definition = {
+            "nodes": [
+                {"id": "start", "type": "start", "name": "开始", "x": 100, "y": 100},
+                {"id": "reserve", "type": "serviceTask", "name": "预留库存", "x": 250, "y": 100,
+                 "props": {"compensation": "release"}},
+                {"id": "charge", "type": "serviceTask", "name": "扣款", "x": 400, "y": 100,
+                 "props": {"compensation": "refund"}},
+                {"id": "approve", "type": "userTask", "name": "确认", "x": 550, "y": 100,
+                 "props": {"assignee": "${starter.id}"}},
+                {"id": "end", "type": "end", "name": "结束", "x": 700, "y": 100},
+                {"id": "release", "type": "serviceTask", "name": "释放库存", "x": 250, "y": 250,
+                 "props": {"isForCompensation": True}},
+                {"id": "refund", "type": "serviceTask", "name": "退款", "x": 400, "y": 250,
+                 "props": {"isForCompensation": True}},
+            ],
+            "flows": [
+                {"id": "f1", "from": "start", "to": "reserve"},
+                {"id": "f2", "from": "reserve", "to": "charge"},
+                {"id": "f3", "from": "charge", "to": "approve"},
+                {"id": "f4", "from": "approve", "to": "end"},
+            ],
+        }
+        process_id = self._create_and_publish_process(definition)
+        instance = self._start_instance(process_id, "low")
+        instance_id = instance['id']
+        self._wait_for_task(instance_id, 'approve')
+
+        success, response, status = self.make_request(
+            'POST', f'/instance/{instance_id}/cancel',
+            data={"reason": "订单取消"}, auth_required=True)
+        assert success, f"取消实例失败: {response}"
+
+        success, response, status = self.make_request(
+            'GET', f'/instance/{instance_id}/history', auth_required=True)
+        assert success, f"获取执行历史失败: {response}"
+        compensations = [t for t in response['data']['tasks'] if t['node_id'] in ('release', 'refund')]
+        assert [t['node_id'] for t in compensations] == ['refund', 'release'], "补偿应按完成顺序的逆序执行"
+        assert all(t['status'] == 'completed' for t in compensations), f"补偿任务应执行成功: {compensations}"
+
+        self.log("服务任务补偿测试通过", "success")
+
+    def test_end_event_waits_for_branches_unless_terminate(self):
+        """测试普通结束节点只结束当前分支，终止结束节点结束实例的全部分支"""
+        self.log("测试终止结束事件", "info")
+
+        self._register_and_login()
+
+        def definition(terminate: bool) -> dict:
+            return {
+                "nodes": [
+                    {"id": "start", "type": "start", "name": "开始", "x": 100, "y": 100},
+                    {"id": "fork", "type": "gateway", "name": "并行分叉", "x": 250, "y": 100,
+                     "props": {"gatewayType": "parallel"}},
+                    {"id": "finance", "type": "userTask", "name": "财务审核", "x": 400, "y": 50,
+                     "props": {"assignee": "${starter.id}"}},
+                    {"id": "legal", "type": "userTask", "name": "法务审核", "x": 400, "y": 150,
+                     "props": {"assignee": "${starter.id}"}},
+                    {"id": "finance_end", "type": "end", "name": "财务结束", "x": 550, "y": 50,
+                     "props": {"terminate": terminate}},
+                    {"id": "legal_end", "type": "end", "name": "法务结束", "x": 550, "y": 150},
+                ],
+                "flows": [
+                    {"id": "f1", "from": "start", "to": "fork"},
+                    {"id": "f2", "from": "fork", "to": "finance"},
+                    {"id": "f3", "from": "fork", "to": "legal"},
+                    {"id": "f4", "from": "finance", "to": "finance_end"},
+                    {"id": "f5", "from": "legal", "to": "legal_end"},
+                ],
+            }
+
+        # 普通结束节点：另一个分支仍有任务时实例继续运行
+        process_id = self._create_and_publish_process(definition(False))
+        instance_id = self._start_instance(process_id, "low")['id']
+        finance_task = self._wait_for_task(instance_id, 'finance')
+        legal_task = self._wait_for_task(instance_id, 'legal')
+        self._claim_and_complete(finance_task['id'], "财务通过")
+        time.sleep(1)
+        assert self._get_instance(instance_id)['status'] == 'running', "其他分支未结束时实例不应完成"
+        self._claim_and_complete(legal_task['id'], "法务通过")
+        self._wait_for_instance_status(instance_id, 'completed')
+
+        # 终止结束节点：到达后跳过其他分支的任务并完成实例
+        process_id = self._create_and_publish_process(definition(True))
+        instance_id = self._start_instance(process_id, "low")['id']
+        finance_task = self._wait_for_task(instance_id, 'finance')
+        legal_task = self._wait_for_task(instance_id, 'legal')
+        self._claim_and_complete(finance_task['id'], "财务通过")
+        instance = self._wait_for_instance_status(instance_id, 'completed')
+        assert instance['current_node'] == 'finance_end', "实例应在终止结束节点完成"
+        assert self._get_task(legal_task['id'])['status'] == 'skipped', "终止后其他分支的任务应被跳过"
+
+        self.log("终止结束事件测试通过", "success")
+
+    def test_notification_inbox(self):
+        """测试站内通知收件箱：分页列表、未读数和标记已读"""
+        self.log("测试站内通知收件箱", "info")
+
+        self._register_and_login()
+
+        success, response, _ = self.make_request(
+            'GET', '/user/notifications?page=1&page_size=5', expected_status=200, auth_required=True)
+        assert success, f"获取通知列表失败: {response}"
+        data = response['data']
+        assert isinstance(data['notifications'], list), "通知列表应为数组"
+        assert data['page_size'] == 5, "应返回请求的分页大小"
+        assert 'unread_count' in data, "通知列表应包含未读数"
+
+        success, response, _ = self.make_request(
+            'GET', '/user/notifications?page_size=1000', expected_status=400, auth_required=True)
+        assert success, "超出范围的分页大小应被拒绝"
+
+        success, response, _ = self.make_request(
+            'POST', '/user/notifications/read-all', expected_status=200, auth_required=True)
+        assert success, f"全部标记已读失败: {response}"
+
+        success, response, _ = self.make_request(
+            'GET', '/user/notifications/unread-count', expected_status=200, auth_required=True)
+        assert success and response['data']['unread_count'] == 0, "全部标记已读后未读数应为0"
+
+        success, response, _ = self.make_request(
+            'GET', '/user/notifications?unread=true', expected_status=200, auth_required=True)
+        assert success and response['data']['total'] == 0, "全部标记已读后不应有未读通知"
+
+        success, response, _ = self.make_request(
+            'POST', '/user/notifications/999999999/read', expected_status=404, auth_required=True)
+        assert success, "不存在的通知应返回404"
+
+        self.log("站内通知收件箱测试通过", "success")
+
+    def test_mail_task_sends_templated_email(self):
+        """测试邮件任务：发送成功后自动继续，邮件渠道不可用时生成异常事件并停留在邮件节点"""
+        self.log("测试邮件任务", "info")
+
+        self._register_and_login()
+
+        definition = {
+            "nodes": [
+                {"id": "start", "type": "start", "name": "开始", "x": 100, "y": 100},
+                {"id": "notify", "type": "mailTask", "name": "通知申请人", "x": 250, "y": 100,
+                 "props": {
+                     "to": "{{.Variables.applicantEmail}}",
+                     "cc": ["audit@example.com"],
+                     "subject": "申请 {{.Instance.business_key}} 已受理",
+                     "body": "级别：{{.Variables.level}}",
+                 }},
+                {"id": "review", "type": "userTask", "name": "审核", "x": 400, "y": 100,
+                 "props": {"assignee": "${starter.id}"}},
+                {"id": "end", "type": "end", "name": "结束", "x": 550, "y": 100},
+            ],
+            "flows": [
+                {"id": "f1", "from": "start", "to": "notify"},
+                {"id": "f2", "from": "notify", "to": "review"},
+                {"id": "f3", "from": "review", "to": "end"},
+            ],
+        }
+        process_id = self._create_and_publish_process(definition)
+        instance_id = self._start_instance(process_id, "low", {"applicantEmail": "applicant@example.com"})['id']
+
+        time.sleep(1)
+        instance = self._get_instance(instance_id)
+        mail_tasks = [t for t in instance.get('tasks') or [] if t['node_id'] == 'notify']
+        assert len(mail_tasks) == 1, f"应创建一个邮件任务: {instance.get('tasks')}"
+        if mail_tasks[0]['status'] == 'completed':
+            self._wait_for_task(instance_id, 'review')
+        else:
+            # 测试环境未启用邮件渠道时邮件任务失败，实例停留在邮件节点等待重试
+            assert mail_tasks[0]['status'] == 'failed', f"邮件任务状态异常: {mail_tasks[0]['status']}"
+            assert instance['status'] == 'running', "邮件发送失败时实例应继续运行等待重试"
+            assert self._node_task_count(instance_id, 'review') == 0, "邮件发送失败时不应进入下一节点"
+
+        # 缺少主题的邮件任务不能保存
+        del definition['nodes'][1]['props']['subject']
+        success, response, status = self.make_request(
+            'POST', '/process', data={
+                "key": f"e2e_mail_task_{random_suffix()}",
+                "name": "邮件配置无效的流程",
+                "category": "test",
+                "definition": definition,
+            },
+            expected_status=400,
+            auth_required=True)
+        assert success, f"邮件任务缺少主题时应拒绝保存，实际为 {status}"
+
+        self.log("邮件任务测试通过", "success")
+
+    def test_delegate_task_resolves_to_owner(self):
+        """测试委派的任务由受托人归还给所有人，所有人再完成任务"""
+        self.log("测试任务委派与归还", "info")
+
+        self._register_and_login()
+        delegate_token, delegate_id = self.token, self.test_user_id
+        self._register_and_login()
+        owner_token, owner_id = self.token, self.test_user_id
+
+        process_id = self._create_and_publish_process()
+        instance = self._start_instance(process_id, "low")
+        task = self._wait_for_task(instance['id'], 'submit')
+
+        success, response, status = self.make_request(
+            'POST', f"/task/{task['id']}/delegate",
+            data={"to_user_id": delegate_id, "comment": "请协助填写"}, auth_required=True)
+        assert success, f"委派任务失败: {response}"
+        task = self._get_task(task['id'])
+        assert task['assignee_id'] == delegate_id, "委派后任务应分配给受托人"
+        assert task['owner_id'] == owner_id, "委派后应记录任务所有人"
+        assert task['delegation_state'] == 'pending', "委派后任务应处于委派中"
+
+        self.token, self.test_user_id = delegate_token, delegate_id
+        success, response, status = self.make_request(
+            'POST', f"/task/{task['id']}/claim", auth_required=True)
+        assert success, f"受托人认领任务失败: {response}"
+        success, response, status = self.make_request(
+            'POST', f"/task/{task['id']}/complete",
+            data={"comment": "直接完成"}, expected_status=409, auth_required=True)
+        assert success, f"委派中的任务不应由受托人完成，实际为 {status}"
+
+        success, response, status = self.make_request(
+            'POST', f"/task/{task['id']}/resolve",
+            data={"form_data": {"note": "已补充"}, "comment": "已处理"}, auth_required=True)
+        assert success, f"归还任务失败: {response}"
+
+        self.token, self.test_user_id = owner_token, owner_id
+        task = self._get_task(task['id'])
+        assert task['assignee_id'] == owner_id, "归还后任务应回到所有人"
+        assert task['delegation_state'] == 'resolved', "归还后委派应已解决"
+        assert task['status'] == 'assigned', "归还后任务应等待所有人处理"
+
+        self._claim_and_complete(task['id'], "所有人确认")
+        self._wait_for_instance_status(instance['id'], 'completed')
+
+        success, response, status = self.make_request(
+            'GET', f"/instance/{instance['id']}/history?types=task_delegated,task_resolved",
+            auth_required=True)
+        assert success, f"获取执行历史失败: {response}"
+        types = [activity['type'] for activity in response['data']['activities']]
+        assert types == ['task_delegated', 'task_resolved'], f"委派活动记录不符合预期: {types}"
+
+        self.log("任务委派与归还测试通过", "success")
+
+    def test_saved_task_filters(self):
+        """测试任务收件箱的高级查询参数和保存的筛选器"""
+        self.log("测试任务筛选器", "info")
+
+        self._register_and_login()
+        owner_token, owner_id = self.token, self.test_user_id
+
+        process_id = self._create_and_publish_process()
+        first = self._start_instance(process_id, "low")
+        second = self._start_instance(process_id, "low")
+        self._wait_for_task(first['id'], 'submit')
+        self._wait_for_task(second['id'], 'submit')
+
+        success, response, status = self.make_request(
+            'GET', f"/user/tasks?business_key={first['business_key']}&started_by=me&node_id=submit",
+            auth_required=True)
+        assert success, f"按业务键查询任务失败: {response}"
+        assert [task['instance_id'] for task in response['data']['tasks']] == [first['id']], \
+            "按业务键应只返回该实例的任务"
+
+        success, response, status = self.make_request(
+            'GET', '/user/tasks?priority_min=80&priority_max=20', expected_status=400, auth_required=True)
+        assert success, f"优先级范围为空应返回400，实际为 {status}"
+        success, response, status = self.make_request(
+            'GET', '/user/tasks?status=unknown', expected_status=400, auth_required=True)
+        assert success, f"非法的任务状态应返回400，实际为 {status}"
+
+        name = f"我的筛选器-{random_suffix()}"
+        success, response, status = self.make_request(
+            'POST', '/user/task-filters',
+            data={"name": name, "criteria": {"business_key": first['business_key'], "statuses": ["assigned"]}},
+            expected_status=201, auth_required=True)
+        assert success, f"保存任务筛选器失败: {response}"
+        filter_id = response['data']['id']
+        assert response['data']['criteria']['business_key'] == first['business_key']
+
+        success, response, status = self.make_request(
+            'POST', '/user/task-filters', data={"name": name, "criteria": {}},
+            expected_status=400, auth_required=True)
+        assert success, f"重名的任务筛选器应返回400，实际为 {status}"
+
+        success, response, status = self.make_request(
+            'GET', f'/user/tasks?saved_filter={filter_id}', auth_required=True)
+        assert success, f"按筛选器查询任务失败: {response}"
+        assert [task['instance_id'] for task in response['data']['tasks']] == [first['id']], \
+            "保存的筛选器应只返回匹配的任务"
+
+        success, response, status = self.make_request(
+            'GET', f"/user/tasks?saved_filter={filter_id}&business_key={second['business_key']}",
+            auth_required=True)
+        assert success, f"覆盖筛选器条件查询任务失败: {response}"
+        assert [task['instance_id'] for task in response['data']['tasks']] == [second['id']], \
+            "请求参数应覆盖筛选器中的同名条件"
+
+        self._register_and_login()
+        success, response, status = self.make_request(
+            'GET', f'/user/tasks?saved_filter={filter_id}', expected_status=404, auth_required=True)
+        assert success, f"其他用户不应使用该筛选器，实际为 {status}"
+        self.token, self.test_user_id = owner_token, owner_id
+
+        success, response, status = self.make_request(
+            'PUT', f'/user/task-filters/{filter_id}',
+            data={"name": f"{name}-改", "criteria": {"started_by": owner_id}}, auth_required=True)
+        assert success, f"修改任务筛选器失败: {response}"
+        success, response, status = self.make_request('GET', '/user/task-filters', auth_required=True)
+        assert success, f"获取任务筛选器失败: {response}"
+        assert [f['name'] for f in response['data']] == [f"{name}-改"]
+
+        success, response, status = self.make_request(
+            'DELETE', f'/user/task-filters/{filter_id}', auth_required=True)
+        assert success, f"删除任务筛选器失败: {response}"
+        success, response, status = self.make_request(
+            'GET', f'/user/tasks?saved_filter={filter_id}', expected_status=404, auth_required=True)
+        assert success, f"删除后的筛选器应返回404，实际为 {status}"
+
+        self.log("任务筛选器测试通过", "success")
+
+    def test_cursor_pagination(self):
+        """测试任务和流程实例列表的游标分页，逐页读取不重复不遗漏，偏移分页保持不变"""
+        self.log("测试游标分页", "info")
+
+        self._register_and_login()
+        process_id = self._create_and_publish_process()
+        instances = [self._start_instance(process_id, "low") for _ in range(3)]
+        tasks = [self._wait_for_task(instance['id'], 'submit') for instance in instances]
+
+        def read_pages(path: str, key: str) -> list:
+            ids, cursor = [], ''
+            for _ in range(10):
+                success, response, status = self.make_request(
+                    'GET', f"{path}&limit=2&cursor={cursor}", auth_required=True)
+                assert success, f"游标分页查询失败: {response}"
+                data = response['data']
+                assert 'total' not in data, "游标分页不应返回总数"
+                ids.extend(item['id'] for item in data[key])
+                if not data['has_more']:
+                    return ids
+                cursor = data['next_cursor']
+            raise AssertionError("游标分页没有结束")
+
+        task_ids = read_pages('/user/tasks?started_by=me&node_id=submit', 'tasks')
+        assert task_ids == sorted((task['id'] for task in tasks), reverse=True), \
+            f"任务游标分页应按创建时间倒序返回全部任务: {task_ids}"
+
+        instance_ids = read_pages(f'/instances?starter_id={self.test_user_id}', 'instances')
+        assert instance_ids == sorted((instance['id'] for instance in instances), reverse=True), \
+            f"实例游标分页应按启动时间倒序返回全部实例: {instance_ids}"
+
+        success, response, status = self.make_request(
+            'GET', '/user/tasks?cursor=not-a-cursor', expected_status=400, auth_required=True)
+        assert success, f"非法的游标应返回400，实际为 {status}"
+        success, response, status = self.make_request(
+            'GET', '/user/tasks?cursor=&sort=priority', expected_status=400, auth_required=True)
+        assert success, f"游标分页不支持自定义排序，实际为 {status}"
+
+        success, response, status = self.make_request(
+            'GET', '/user/tasks?started_by=me&page=1&page_size=2', auth_required=True)
+        assert success, f"偏移分页查询失败: {response}"
+        assert response['data']['total'] == 3 and len(response['data']['tasks']) == 2
+
+        self.log("游标分页测试通过", "success")
+
+    def test_instance_archive(self):
+        """测试归档已结束的流程实例：运行数据被移除，执行历史可以从归档中读取"""
+        self.log("测试流程实例归档", "info")
+
+        self._register_and_login()
+        process_id = self._create_and_publish_process()
+        instance = self._start_instance(process_id, "low")
+        instance_id = instance['id']
+        task = self._wait_for_task(instance_id, 'submit')
+        self._claim_and_complete(task['id'], "提交后归档")
+        self._wait_for_task(instance_id, 'approve')
+
+        success, response, status = self._admin_request(
+            'POST', f'/admin/archives/{instance_id}', expected_status=400)
+        assert success, f"运行中的实例不能归档，实际为 {status}"
+
+        success, response, status = self.make_request(
+            'POST', f'/instance/{instance_id}/cancel',
+            data={"reason": "归档测试"}, auth_required=True)
+        assert success, f"取消实例失败: {response}"
+
+        success, response, status = self.make_request(
+            'POST', f'/admin/archives/{instance_id}', expected_status=403, auth_required=True)
+        assert success, f"普通用户不能归档实例，实际为 {status}"
+
+        success, response, status = self._admin_request('POST', f'/admin/archives/{instance_id}')
+        assert success, f"归档实例失败: {response}"
+        archive = response['data']['archives'][0]
+        assert archive['instance_id'] == instance_id and archive['final_status'] == 'cancelled'
+        assert 'storage_key' not in archive, "归档记录不应暴露存储位置"
+
+        success, response, status = self.make_request(
+            'GET', f'/instance/{instance_id}', expected_status=404, auth_required=True)
+        assert success, f"归档后实例应从运行表中移除，实际为 {status}"
+
+        success, response, status = self.make_request(
+            'GET', f'/instance/{instance_id}/archive', auth_required=True)
+        assert success, f"发起人读取归档历史失败: {response}"
+        assert response['instance']['id'] == instance_id
+        assert response['instance']['business_key'] == instance['business_key']
+        assert {t['node_id'] for t in response['tasks']} >= {'submit', 'approve'}, \
+            f"归档历史应包含实例的全部任务: {response['tasks']}"
+        assert response['activities'], "归档历史应包含活动记录"
+
+        success, response, status = self._admin_request(
+            'GET', f"/admin/archives?business_key={instance['business_key']}")
+        assert success, f"查询归档记录失败: {response}"
+        assert [a['instance_id'] for a in response['data']['archives']] == [instance_id]
+
+        self.log("流程实例归档测试通过", "success")
+
+    def test_erase_user(self):
+        """测试删除用户的个人数据：用户被物理删除，参与过的实例和任务转给占位账户"""
+        self.log("测试删除用户数据", "info")
+
+        self._register_and_login()
+        user_id = self.test_user_id
+        success, response, status = self.make_request('GET', '/user/profile', auth_required=True)
+        assert success, f"获取用户信息失败: {response}"
+        username = response['data']['username']
+
+        process_id = self._create_and_publish_process()
+        instance = self._start_instance(process_id, "low")
+        task = self._wait_for_task(instance['id'], 'submit')
+
+        success, response, status = self._admin_request(
+            'DELETE', f'/admin/users/{user_id}', data={"reason": "员工离职"}, expected_status=409)
+        assert success, f"用户有未完成的任务时不能删除，实际为 {status}"
+
+        self._claim_and_complete(task['id'], "离职前提交")
+        success, response, status = self.make_request(
+            'POST', f"/instance/{instance['id']}/cancel",
+            data={"reason": "离职前取消"}, auth_required=True)
+        assert success, f"取消实例失败: {response}"
+
+        archived = self._start_instance(process_id, "low")
+        archived_task = self._wait_for_task(archived['id'], 'submit')
+        self._claim_and_complete(archived_task['id'], "归档前提交")
+        success, response, status = self.make_request(
+            'POST', f"/instance/{archived['id']}/cancel",
+            data={"reason": "归档前取消"}, auth_required=True)
+        assert success, f"取消实例失败: {response}"
+        success, response, status = self._admin_request('POST', f"/admin/archives/{archived['id']}")
+        assert success, f"归档实例失败: {response}"
+
+        success, response, status = self._admin_request(
+            'DELETE', f'/admin/users/{user_id}', data={"reason": "员工离职"})
+        assert success, f"删除用户数据失败: {response}"
+        erasure = response['data']['erasure']
+        assert erasure['user_id'] == user_id and erasure['reason'] == "员工离职"
+        assert username not in str(erasure), "删除记录不应保存用户名"
+        assert response['data']['archives_rewritten'] >= 1, "引用该用户的归档文件应被改写"
+        tombstone_id = erasure['tombstone_id']
+
+        success, response, status = self._admin_request('GET', f"/instance/{archived['id']}/archive")
+        assert success, f"读取归档历史失败: {response}"
+        assert response['instance']['starter_id'] == tombstone_id, "归档实例的发起人应转给占位账户"
+        assert all(t.get('assignee_id') != user_id for t in response['tasks']), \
+            "归档任务的处理人不应再引用被删除的用户"
+        assert all(a.get('actor_id') != user_id for a in response['activities']), \
+            "归档活动的操作人不应再引用被删除的用户"
+
+        success, response, status = self._admin_request('GET', f"/instance/{instance['id']}")
+        assert success, f"获取实例失败: {response}"
+        assert response['data']['starter_id'] == tombstone_id, "实例发起人应转给占位账户"
+        assert all(t.get('assignee_id') != user_id for t in response['data']['tasks']), \
+            "任务处理人不应再引用被删除的用户"
+
+        success, response, status = self.make_request(
+            'POST', '/auth/login', data={"username": username, "password": "testpass123"},
+            expected_status=401)
+        assert success, f"被删除的用户不能再登录，实际为 {status}"
+
+        success, response, status = self._admin_request(
+            'DELETE', f'/admin/users/{user_id}', expected_status=404)
+        assert success, f"重复删除应返回404，实际为 {status}"
+        success, response, status = self._admin_request(
+            'DELETE', f'/admin/users/{tombstone_id}', expected_status=400)
+        assert success, f"占位账户不能删除，实际为 {status}"
+
+        success, response, status = self._admin_request('GET', f'/admin/users/{user_id}/erasures')
+        assert success, f"获取删除记录失败: {response}"
+        assert response['data'][0]['digest'] == erasure['digest']
+
+        self.log("删除用户数据测试通过", "success")
+
+    def test_idempotent_start_and_complete(self):
+        """测试带 Idempotency-Key 的重试：启动流程和完成任务返回首次请求的结果，不会重复执行"""
+        self.log("测试幂等的启动流程和完成任务", "info")
+
+        self._register_and_login()
+        process_id = self._create_and_publish_process()
+        payload = {
+            "business_key": f"E2E-{random_suffix(10)}",
+            "variables": {"level": "low"},
+        }
+        start_key = {"Idempotency-Key": f"start-{random_suffix()}"}
+
+        success, first, status = self.make_request(
+            'POST', f'/process/{process_id}/start', data=payload, headers=start_key,
+            expected_status=201, auth_required=True)
+        assert success, f"启动流程失败: {first}"
+        success, retried, status = self.make_request(
+            'POST', f'/process/{process_id}/start', data=payload, headers=start_key,
+            expected_status=201, auth_required=True)
+        assert success, f"重试启动流程失败: {retried}"
+        assert retried['data']['id'] == first['data']['id'], "重试应返回首次启动的实例"
+
+        success, response, status = self.make_request(
+            'GET', f'/instances?starter_id={self.test_user_id}', auth_required=True)
+        assert success, f"查询实例失败: {response}"
+        assert response['data']['total'] == 1, "重试不应启动新的实例"
+
+        success, response, status = self.make_request(
+            'POST', f'/process/{process_id}/start',
+            data={**payload, "business_key": f"E2E-{random_suffix(10)}"}, headers=start_key,
+            expected_status=422, auth_required=True)
+        assert success, f"幂等键用于不同的请求应返回422，实际为 {status}"
+
+        task = self._wait_for_task(first['data']['id'], 'submit')
+        success, response, status = self.make_request(
+            'POST', f"/task/{task['id']}/claim", auth_required=True)
+        assert success, f"认领任务失败: {response}"
+
+        complete_key = {"Idempotency-Key": f"complete-{random_suffix()}"}
+        for attempt in range(2):
+            success, response, status = self.make_request(
+                'POST', f"/task/{task['id']}/complete", data={"comment": "幂等提交"},
+                headers=complete_key, auth_required=True)
+            assert success, f"第 {attempt + 1} 次完成任务失败: {response}"
+        assert self._get_task(task['id'])['status'] == 'completed'
+
+        success, response, status = self.make_request(
+            'POST', f"/task/{task['id']}/complete", data={"comment": "幂等提交"},
+            expected_status=409, auth_required=True)
+        assert success, f"不带幂等键的重复提交仍应被拒绝，实际为 {status}"
+
+        self.log("幂等的启动流程和完成任务测试通过", "success")
+
+    def test_process_version_lifecycle(self):
+        """测试流程版本生命周期：创建新版本、弃用和归档"""
+        self.log("测试流程版本生命周期", "info")
+
+        self._register_and_login()
+        v1_id = self._create_and_publish_process()
+
+        success, response, status = self.make_request(
+            'GET', f'/process/{v1_id}', auth_required=True)
+        assert success, f"获取流程失败: {response}"
+        v1 = response['data']
+
+        success, response, status = self.make_request(
+            'PUT', f'/process/{v1_id}',
+            data={"name": v1['name'], "definition": v1['definition']},
+            expected_status=400, auth_required=True)
+        assert success, f"已发布的版本不能直接编辑，实际为 {status}"
+
+        success, response, status = self.make_request(
+            'POST', f'/process/{v1_id}/new-version', expected_status=201, auth_required=True)
+        assert success, f"创建新版本失败: {response}"
+        v2 = response['data']
+        assert v2['key'] == v1['key'], "新版本应沿用流程标识"
+        assert v2['version'] == v1['version'] + 1, f"新版本号应递增，实际为 {v2['version']}"
+        assert v2['status'] == 'draft', "新版本应为草稿状态"
+        assert v2['definition']['nodes'] == v1['definition']['nodes'], "新版本应复制最新版本的定义"
+
+        success, response, status = self.make_request(
+            'POST', f'/process/{v1_id}/new-version', expected_status=400, auth_required=True)
+        assert success, f"已有草稿版本时不能再创建新版本，实际为 {status}"
+
+        success, response, status = self.make_request(
+            'POST', f"/process/{v2['id']}/publish", auth_required=True)
+        assert success, f"发布新版本失败: {response}"
+
+        instance = self._start_instance(v1_id, "low")
+
+        success, response, status = self.make_request(
+            'POST', f'/process/{v1_id}/deprecate', auth_required=True)
+        assert success, f"弃用流程版本失败: {response}"
+        assert response['data']['active_instances'] == 1, f"应报告1个运行中的实例: {response}"
+
+        success, response, status = self.make_request(
+            'POST', f'/process/{v1_id}/start',
+            data={"business_key": f"E2E-{random_suffix(10)}", "variables": {"level": "low"}},
+            expected_status=409, auth_required=True)
+        assert success, f"已弃用的版本不能启动新实例，实际为 {status}"
+
+        success, response, status = self.make_request(
+            'POST', f'/process/{v1_id}/archive', expected_status=400, auth_required=True)
+        assert success, f"仍有运行中的实例时不能归档，实际为 {status}"
+
+        task = self._wait_for_task(instance['id'], 'submit')
+        self._claim_and_complete(task['id'], "弃用版本上的实例继续执行")
+        self._wait_for_instance_status(instance['id'], 'completed')
+
+        success, response, status = self.make_request(
+            'POST', f'/process/{v1_id}/archive', auth_required=True)
+        assert success, f"归档流程版本失败: {response}"
+
+        success, response, status = self.make_request(
+            'GET', f'/process/{v1_id}', auth_required=True)
+        assert success, f"获取流程失败: {response}"
+        assert response['data']['status'] == 'archived', "归档后流程应为已归档状态"
+
+        self._start_instance(v2['id'], "low")
+
+        self.log("流程版本生命周期测试通过", "success")
+
+    def test_graph_validation_locations(self):
+        """测试流程图校验：不可达节点、无限循环和排他网关条件返回可高亮的位置"""
+        self.log("测试流程图校验", "info")
+
+        self._register_and_login()
+
+        definition = {
+            "nodes": [
+                {"id": "start", "type": "start", "name": "开始", "x": 100, "y": 100},
+                {"id": "sync", "type": "serviceTask", "name": "同步数据", "x": 250, "y": 100,
+                 "props": {"url": "http://127.0.0.1:9/sync", "method": "POST"}},
+                {"id": "fork", "type": "gateway", "name": "并行分叉", "x": 400, "y": 100,
+                 "props": {"gatewayType": "parallel"}},
+                {"id": "choose", "type": "gateway", "name": "选择", "x": 400, "y": 250},
+                {"id": "orphan", "type": "userTask", "name": "孤立审批", "x": 550, "y": 250},
+                {"id": "end", "type": "end", "name": "结束", "x": 700, "y": 100},
+            ],
+            "flows": [
+                {"id": "f1", "from": "start", "to": "sync"},
+                {"id": "f2", "from": "sync", "to": "fork"},
+                {"id": "f3", "from": "fork", "to": "sync"},
+                {"id": "f4", "from": "fork", "to": "end"},
+                {"id": "f5", "from": "choose", "to": "orphan"},
+                {"id": "f6", "from": "choose", "to": "end"},
+                {"id": "f7", "from": "orphan", "to": "end"},
+            ],
+        }
+
+        success, response, status = self.make_request(
+            'POST', '/process/validate', data={"definition": definition}, auth_required=True)
+        assert success, f"验证流程定义失败: {response}"
+        result = response['data']
+        assert result['valid'] is False, "有结构错误的流程定义应验证失败"
+        by_rule = {issue['rule']: issue for issue in result['issues']}
+        assert set(by_rule['infinite-cycle']['node_ids']) == {"sync", "fork"}, f"应指出无限循环的节点: {by_rule}"
+        assert set(by_rule['gateway-missing-condition']['flow_ids']) == {"f5", "f6"}, f"应指出缺少条件的连线: {by_rule}"
+        unreachable = {issue['node_ids'][0] for issue in result['issues'] if issue['rule'] == 'unreachable-node'}
+        assert unreachable == {"choose", "orphan"}, f"应指出不可达的节点: {unreachable}"
+
+        success, response, status = self.make_request(
+            'POST', '/process',
+            data={
+                "key": f"e2e_graph_{random_suffix()}",
+                "name": "结构错误的流程",
+                "category": "test",
+                "definition": definition,
+            },
+            expected_status=400, auth_required=True)
+        assert success, f"有结构错误的流程定义应拒绝保存，实际为 {status}"
+        assert {issue['rule'] for issue in response['issues']} == set(by_rule), "保存失败时应返回同样的结构错误"
+
+        duplicated = approval_definition()
+        duplicated['nodes'].append(dict(duplicated['nodes'][1]))
+        success, response, status = self.make_request(
+            'POST', '/process/validate', data={"definition": duplicated}, auth_required=True)
+        assert success, f"验证流程定义失败: {response}"
+        assert [issue['rule'] for issue in response['data']['issues']] == ['duplicate-node-id'], \
+            f"重复的节点ID应单独报告: {response['data']['issues']}"
+
+        success, response, status = self.make_request(
+            'POST', '/process/validate', data={"definition": approval_definition()}, auth_required=True)
+        assert success, f"验证流程定义失败: {response}"
+        assert response['data']['valid'] is True and response['data']['issues'] == [], \
+            f"合法的流程定义应验证通过: {response['data']}"
+
+        self.log("流程图校验测试通过", "success")
+
+    def _wait_for_open_task(self, instance_id: int, node_id: str) -> dict:
+        """等待实例在指定节点上生成未完成的任务"""
+        deadline = time.time() + self.ADVANCE_TIMEOUT
+        while time.time() < deadline:
+            success, response, status = self.make_request(
+                'GET', f'/user/tasks?status=assigned&filter[instance_id][eq]={instance_id}&filter[node_id][eq]={node_id}',
+                auth_required=True)
+            assert success, f"获取待办任务失败: {response}"
+            tasks = response['data']['tasks']
+            if tasks:
+                return tasks[0]
+            time.sleep(0.5)
+        pytest.fail(f"实例 {instance_id} 未在节点 {node_id} 上生成未完成的任务")
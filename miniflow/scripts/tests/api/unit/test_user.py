@@ -155,6 +155,23 @@ class TestUser(BaseAPITest):
         
         self.log("获取用户任务列表测试通过", "success")
     
+    def test_get_user_tasks_invalid_status(self):
+        """测试使用未知状态过滤任务列表"""
+        self.log("测试未知任务状态过滤", "info")
+        
+        success, response, status = self.make_request(
+            'GET', '/user/tasks?status=asigned',
+            auth_required=True,
+            expected_status=400
+        )
+        
+        # 拼写错误的状态不能静默返回空列表
+        assert status == 400, "未知任务状态应该返回400状态码"
+        assert response.get('code') == 'INVALID_ENUM_VALUE', f"应返回取值非法错误码: {response}"
+        assert 'assigned' in response.get('allowed', []), "错误响应应列出允许的状态"
+        
+        self.log("未知任务状态过滤测试通过", "success")
+    
     def test_update_profile_invalid_data(self):
         """测试使用无效数据更新用户资料"""
         self.log("测试使用无效数据更新用户资料", "info")